@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	goflags "flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/component-base/version"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/collect"
+)
+
+var (
+	collectExample = `
+	# Collect from an installation made by the core Helm chart in a kind cluster named kubeflex
+	%[1]s collect --host-context kind-kubeflex --space-mgt-context kind-kubeflex
+
+	# Also collect the metrics of the placement translator, redacting customer names too
+	%[1]s collect --host-context kind-kubeflex --probe placement-translator=http://localhost:10204 --redaction-rules my-rules.yaml -f bundle.tar.gz
+`
+)
+
+func collectCommand() *cobra.Command {
+	options := collect.NewCollectOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	cmd := &cobra.Command{
+		Use:          "collect",
+		Short:        "Gather logs, objects and metrics of a KubeStellar installation into a redacted support bundle.",
+		Example:      fmt.Sprintf(collectExample, "kubectl kubestellar"),
+		SilenceUsage: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return base.Usagef("no arguments are accepted")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := options.Validate(); err != nil {
+				return err
+			}
+
+			if err := options.Complete(); err != nil {
+				return err
+			}
+
+			return options.Run(c.Context())
+		},
+	}
+
+	options.BindFlags(cmd)
+	base.SetUsageErrors(cmd)
+	cmd.AddCommand(base.NewCompletionCommand(cmd))
+
+	// setup klog
+	fs := goflags.NewFlagSet("klog", goflags.PanicOnError)
+	klog.InitFlags(fs)
+	cmd.PersistentFlags().AddGoFlagSet(fs)
+
+	if v := version.Get().String(); len(v) == 0 {
+		cmd.Version = "<unknown>"
+	} else {
+		cmd.Version = v
+	}
+
+	return cmd
+}
+
+func main() {
+	cmd := collectCommand()
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(base.ExitCodeFor(err))
+	}
+}
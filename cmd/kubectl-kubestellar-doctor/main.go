@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	goflags "flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/component-base/version"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/doctor"
+)
+
+var (
+	doctorExample = `
+	# Check an installation, with the KubeStellar core space as the current kubeconfig context
+	%[1]s doctor --space-mgt-kubeconfig ~/.kube/config --space-mgt-context kind-kubeflex
+
+	# Also probe the controllers, and print the findings as JSON
+	%[1]s doctor --probe mailbox-controller=http://localhost:10203 --probe where-resolver=http://localhost:10205 -o json
+`
+)
+
+func doctorCommand() *cobra.Command {
+	options := doctor.NewDoctorOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	cmd := &cobra.Command{
+		Use:          "doctor",
+		Short:        "Diagnose a KubeStellar installation and suggest remedies, most urgent first.",
+		Example:      fmt.Sprintf(doctorExample, "kubectl kubestellar"),
+		SilenceUsage: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return base.Usagef("no arguments are accepted")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := options.Validate(); err != nil {
+				return err
+			}
+
+			if err := options.Complete(); err != nil {
+				return err
+			}
+
+			return options.Run(c.Context())
+		},
+	}
+
+	options.BindFlags(cmd)
+	base.SetUsageErrors(cmd)
+	cmd.AddCommand(base.NewCompletionCommand(cmd))
+
+	// setup klog
+	fs := goflags.NewFlagSet("klog", goflags.PanicOnError)
+	klog.InitFlags(fs)
+	cmd.PersistentFlags().AddGoFlagSet(fs)
+
+	if v := version.Get().String(); len(v) == 0 {
+		cmd.Version = "<unknown>"
+	} else {
+		cmd.Version = v
+	}
+
+	return cmd
+}
+
+func main() {
+	cmd := doctorCommand()
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(base.ExitCodeFor(err))
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	goflags "flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/component-base/version"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/placements"
+	"github.com/kubestellar/kubestellar/pkg/placementops"
+)
+
+var (
+	placementsExample = `
+	# Stop changing the destinations of the payments team's EdgePlacements
+	%[1]s placements pause -l team=payments
+
+	# Let them follow their Locations and SyncTargets again
+	%[1]s placements resume -l team=payments
+
+	# Check whether two EdgePlacements could be re-evaluated, without doing it
+	%[1]s placements reevaluate --dry-run web-prod web-staging
+`
+)
+
+var shortHelp = map[placementops.Operation]string{
+	placementops.Pause:      "Freeze the destinations of EdgePlacements.",
+	placementops.Resume:     "Let paused EdgePlacements follow their Locations and SyncTargets again.",
+	placementops.Reevaluate: "Make the controllers process EdgePlacements again.",
+}
+
+func placementsCommand() *cobra.Command {
+	options := placements.NewPlacementsOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+
+	cmd := &cobra.Command{
+		Use:          "placements",
+		Short:        "Pause, resume, or re-evaluate many EdgePlacements at once.",
+		Example:      fmt.Sprintf(placementsExample, "kubectl kubestellar"),
+		SilenceUsage: true,
+	}
+	for _, op := range placementops.Operations {
+		op := op
+		sub := &cobra.Command{
+			Use:   string(op) + " [NAME...] [-l SELECTOR]",
+			Short: shortHelp[op],
+			Args:  cobra.ArbitraryArgs,
+			RunE: func(c *cobra.Command, args []string) error {
+				if err := options.Validate(args); err != nil {
+					return err
+				}
+				if err := options.Complete(); err != nil {
+					return err
+				}
+				return options.Run(c.Context(), op, args)
+			},
+		}
+		options.BindFlags(sub)
+		base.SetUsageErrors(sub)
+		cmd.AddCommand(sub)
+	}
+	base.SetUsageErrors(cmd)
+	cmd.AddCommand(base.NewCompletionCommand(cmd))
+
+	// setup klog
+	fs := goflags.NewFlagSet("klog", goflags.PanicOnError)
+	klog.InitFlags(fs)
+	cmd.PersistentFlags().AddGoFlagSet(fs)
+
+	if v := version.Get().String(); len(v) == 0 {
+		cmd.Version = "<unknown>"
+	} else {
+		cmd.Version = v
+	}
+
+	return cmd
+}
+
+func main() {
+	cmd := placementsCommand()
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(base.ExitCodeFor(err))
+	}
+}
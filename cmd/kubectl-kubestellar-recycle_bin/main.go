@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	goflags "flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/component-base/version"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/recyclebin"
+)
+
+var (
+	recycleBinExample = `
+	# List the objects scheduled for deletion, using the kubeconfig of a mailbox space
+	%[1]s recycle-bin list --kubeconfig mailbox.kubeconfig
+
+	# Keep an object at its destination after all
+	%[1]s recycle-bin rescue --kubeconfig mailbox.kubeconfig Deployment.apps shop/web --note "still serving the old tills"
+`
+)
+
+func recycleBinCommand() *cobra.Command {
+	options := recyclebin.NewRecycleBinOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	prepare := func() error {
+		if err := options.Validate(); err != nil {
+			return err
+		}
+		return options.Complete()
+	}
+
+	cmd := &cobra.Command{
+		Use:          "recycle-bin",
+		Short:        "List and rescue the objects that are scheduled for deletion from a destination.",
+		Example:      fmt.Sprintf(recycleBinExample, "kubectl kubestellar"),
+		SilenceUsage: true,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the objects in the mailbox space that are scheduled for deletion.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return base.Usagef("no arguments are accepted")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			if err := prepare(); err != nil {
+				return err
+			}
+			return options.RunList(c.Context())
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rescue KIND[.GROUP] [NAMESPACE/]NAME",
+		Short: "Cancel the scheduled deletion of an object, leaving it at its destination.",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ref, err := recyclebin.ParseObjectRef(args)
+			if err != nil {
+				return err
+			}
+			if err := prepare(); err != nil {
+				return err
+			}
+			return options.RunRescue(c.Context(), ref)
+		},
+	})
+
+	for _, sub := range cmd.Commands() {
+		options.BindFlags(sub)
+		base.SetUsageErrors(sub)
+	}
+	base.SetUsageErrors(cmd)
+	cmd.AddCommand(base.NewCompletionCommand(cmd))
+
+	// setup klog
+	fs := goflags.NewFlagSet("klog", goflags.PanicOnError)
+	klog.InitFlags(fs)
+	cmd.PersistentFlags().AddGoFlagSet(fs)
+
+	if v := version.Get().String(); len(v) == 0 {
+		cmd.Version = "<unknown>"
+	} else {
+		cmd.Version = v
+	}
+
+	return cmd
+}
+
+func main() {
+	cmd := recycleBinCommand()
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(base.ExitCodeFor(err))
+	}
+}
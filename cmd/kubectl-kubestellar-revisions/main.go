@@ -0,0 +1,186 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	goflags "flag"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/component-base/version"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/revisions"
+)
+
+var (
+	revisionsExample = `
+	# List the objects with recorded revisions, using the kubeconfig of a mailbox space
+	%[1]s revisions list --kubeconfig mailbox.kubeconfig
+
+	# Show the revisions of one object at one destination
+	%[1]s revisions history --kubeconfig ... edge1 Deployment.apps shop/web
+
+	# Show what changed in the latest revision, or between two revisions
+	%[1]s revisions diff --kubeconfig ... edge1 Deployment.apps shop/web
+	%[1]s revisions diff --kubeconfig ... edge1 Deployment.apps shop/web --from -3 --to 0
+
+	# Print a revision
+	%[1]s revisions show --kubeconfig ... edge1 Deployment.apps shop/web 3f2a9c1d0b7e
+
+	# Roll the object back to the previous revision, in the workload description space
+	%[1]s revisions rollback --kubeconfig ... --wds-kubeconfig wds.kubeconfig edge1 Deployment.apps shop/web
+`
+)
+
+func revisionsCommand() *cobra.Command {
+	options := revisions.NewRevisionsOptions(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
+	prepare := func() error {
+		if err := options.Validate(); err != nil {
+			return err
+		}
+		return options.Complete()
+	}
+
+	cmd := &cobra.Command{
+		Use:          "revisions",
+		Short:        "Inspect the revision history that the syncer keeps of downsynced objects, and roll back.",
+		Example:      fmt.Sprintf(revisionsExample, "kubectl kubestellar"),
+		SilenceUsage: true,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the objects that have recorded revisions.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return base.Usagef("no arguments are accepted")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			if err := prepare(); err != nil {
+				return err
+			}
+			return options.RunList(c.Context())
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "history DESTINATION KIND[.GROUP] [NAMESPACE/]NAME",
+		Short: "List the revisions of an object, newest first.",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ref, err := revisions.ParseObjectRef(args)
+			if err != nil {
+				return err
+			}
+			if err := prepare(); err != nil {
+				return err
+			}
+			return options.RunHistory(c.Context(), ref)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show DESTINATION KIND[.GROUP] [NAMESPACE/]NAME [REVISION]",
+		Short: "Print the content of a revision of an object; the default is the newest.",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			revision := ""
+			if len(args) == 4 {
+				revision, args = args[3], args[:3]
+			}
+			ref, err := revisions.ParseObjectRef(args)
+			if err != nil {
+				return err
+			}
+			if err := prepare(); err != nil {
+				return err
+			}
+			return options.RunShow(c.Context(), ref, revision)
+		},
+	})
+	var from, to string
+	diffCmd := &cobra.Command{
+		Use:   "diff DESTINATION KIND[.GROUP] [NAMESPACE/]NAME",
+		Short: "Print the differences between two revisions of an object.",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ref, err := revisions.ParseObjectRef(args)
+			if err != nil {
+				return err
+			}
+			if err := prepare(); err != nil {
+				return err
+			}
+			return options.RunDiff(c.Context(), ref, from, to)
+		},
+	}
+	diffCmd.Flags().StringVar(&from, "from", "-1", "Older revision: a hash, or 0 for the newest, -1 for the one before, and so on.")
+	diffCmd.Flags().StringVar(&to, "to", "0", "Newer revision, in the same form as --from.")
+	cmd.AddCommand(diffCmd)
+	var rollbackTo string
+	var dryRun bool
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback DESTINATION KIND[.GROUP] [NAMESPACE/]NAME",
+		Short: "Write a revision of an object to the workload description space, from where it goes to every destination.",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ref, err := revisions.ParseObjectRef(args)
+			if err != nil {
+				return err
+			}
+			if err := prepare(); err != nil {
+				return err
+			}
+			return options.RunRollback(c.Context(), ref, rollbackTo, dryRun)
+		},
+	}
+	rollbackCmd.Flags().StringVar(&rollbackTo, "to", "-1", "Revision to roll back to: a hash, or 0 for the newest, -1 for the one before, and so on.")
+	rollbackCmd.Flags().BoolVar(&dryRun, "dry-run", dryRun, "Print the object that would be written instead of writing it.")
+	cmd.AddCommand(rollbackCmd)
+
+	for _, sub := range cmd.Commands() {
+		options.BindFlags(sub)
+		base.SetUsageErrors(sub)
+	}
+	base.SetUsageErrors(cmd)
+	cmd.AddCommand(base.NewCompletionCommand(cmd))
+
+	// setup klog
+	fs := goflags.NewFlagSet("klog", goflags.PanicOnError)
+	klog.InitFlags(fs)
+	cmd.PersistentFlags().AddGoFlagSet(fs)
+
+	if v := version.Get().String(); len(v) == 0 {
+		cmd.Version = "<unknown>"
+	} else {
+		cmd.Version = v
+	}
+
+	return cmd
+}
+
+func main() {
+	cmd := revisionsCommand()
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(base.ExitCodeFor(err))
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"k8s.io/component-base/version"
 	"k8s.io/klog/v2"
 
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
 	plugin "github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/syncer-gen"
 )
 
@@ -51,9 +52,13 @@ func syncerGenCommand() *cobra.Command {
 		Short:        "Create service account and RBAC permissions in the workspace in kcp for Edge MC. Output a manifest to deploy a syncer in a WEC.",
 		Example:      fmt.Sprintf(syncerGenExample, "kubectl kubestellar"),
 		SilenceUsage: true,
+		// The manifest is written according to -o/--output-file, so this
+		// command does not take the usual --output format flag.
+		Args: cobra.ArbitraryArgs,
 		RunE: func(c *cobra.Command, args []string) error {
 			if len(args) != 1 {
-				return c.Help()
+				_ = c.Help()
+				return base.Usagef("exactly one argument, the name of the SyncTarget, is required")
 			}
 
 			if err := options.Complete(args); err != nil {
@@ -61,7 +66,7 @@ func syncerGenCommand() *cobra.Command {
 			}
 
 			if err := options.Validate(); err != nil {
-				return err
+				return &base.UsageError{Message: err.Error()}
 			}
 
 			return options.Run(c.Context())
@@ -69,6 +74,8 @@ func syncerGenCommand() *cobra.Command {
 	}
 
 	options.BindFlags(cmd)
+	base.SetUsageErrors(cmd)
+	cmd.AddCommand(base.NewCompletionCommand(cmd))
 
 	// setup klog
 	fs := goflags.NewFlagSet("klog", goflags.PanicOnError)
@@ -88,6 +95,6 @@ func main() {
 	cmd := syncerGenCommand()
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		os.Exit(base.ExitCodeFor(err))
 	}
 }
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/config/crds"
+	"github.com/kubestellar/kubestellar/pkg/bootstrap"
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/crdinstall"
+	spaceclientfactory "github.com/kubestellar/kubestellar/pkg/spaceclient"
+	spaceclientset "github.com/kubestellar/kubestellar/space-framework/pkg/client/clientset/versioned"
+	spaceclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
+	spacemanager "github.com/kubestellar/kubestellar/space-framework/pkg/space-manager"
+)
+
+func main() {
+	plan := bootstrap.Plan{
+		ProviderName:    "default",
+		CoreSpace:       "espw",
+		InventorySpaces: []string{"imw1"},
+		WorkloadSpaces:  []string{"wmw1"},
+		DefaultLocation: true,
+	}
+	identityNamespace := "kubestellar"
+	withIdentities := true
+	spaceReadyTimeout := bootstrap.DefaultSpaceReadyTimeout
+	externalAccess := false
+	fs := pflag.NewFlagSet("kubestellar-bootstrap", pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.StringVar(&plan.ProviderName, "space-provider", plan.ProviderName, "the name of the KubeStellar space provider")
+	fs.StringVar(&plan.CoreSpace, "core-space", plan.CoreSpace, "the name of the KubeStellar core space")
+	fs.StringSliceVar(&plan.InventorySpaces, "inventory-spaces", plan.InventorySpaces, "names of the inventory spaces")
+	fs.StringSliceVar(&plan.WorkloadSpaces, "workload-spaces", plan.WorkloadSpaces, "names of the workload description spaces")
+	fs.BoolVar(&plan.DefaultLocation, "default-location", plan.DefaultLocation, "make, in each inventory space, a Location named \"default\" that selects all the SyncTargets there")
+	fs.BoolVar(&withIdentities, "controller-identities", withIdentities, "make a ServiceAccount, ClusterRole and ClusterRoleBinding in the core space for each central controller")
+	fs.StringVar(&identityNamespace, "identity-namespace", identityNamespace, "namespace in the core space of the controllers' ServiceAccounts")
+	fs.DurationVar(&spaceReadyTimeout, "space-ready-timeout", spaceReadyTimeout, "how long to wait for each space to become Ready")
+	fs.BoolVar(&externalAccess, "external-access", externalAccess, "the access to the spaces. True when the space-provider is hosted in a space while this command is running outside of that space")
+
+	spaceMgtClientOpts := clientopts.NewClientOpts("space-mgt", "access to the space reference space")
+	spaceMgtClientOpts.AddFlags(fs)
+
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	var err error
+	plan.CRDs, err = crdinstall.Load(crds.FS)
+	if err != nil {
+		logger.Error(err, "Failed to load the embedded CRDs")
+		os.Exit(5)
+	}
+	plan.ProviderNS = spacemanager.ProviderNS(plan.ProviderName)
+	if withIdentities {
+		plan.Identities = bootstrap.DefaultIdentities(identityNamespace)
+	}
+
+	spaceManagementConfig, err := spaceMgtClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to create space management API client config from flags")
+		os.Exit(10)
+	}
+	spaceManagementConfig.UserAgent = "kubestellar-bootstrap"
+	spaceManagementClient, err := spaceclientset.NewForConfig(spaceManagementConfig)
+	if err != nil {
+		logger.Error(err, "Failed to create space management clientset")
+		os.Exit(15)
+	}
+	spaceConfigs, err := spaceclient.NewMultiSpace(ctx, spaceManagementConfig, externalAccess)
+	if err != nil {
+		logger.Error(err, "Failed to create space-aware client")
+		os.Exit(20)
+	}
+	spaceClients := spaceclientfactory.NewFactory(spaceConfigs, spaceclientfactory.Options{UserAgent: "kubestellar-bootstrap"})
+
+	bs := bootstrap.NewBootstrapper(logger, spaceManagementClient.SpaceV1alpha1(), spaceClients)
+	bs.SpaceReadyTimeout = spaceReadyTimeout
+	report, runErr := bs.Run(ctx, plan)
+	data, err := report.JSON()
+	if err != nil {
+		logger.Error(err, "Failed to encode the report")
+		os.Exit(25)
+	}
+	os.Stdout.Write(data)
+	if runErr != nil {
+		logger.Error(runErr, "Failed to bootstrap the hub")
+		os.Exit(30)
+	}
+	logger.Info("Bootstrapped the hub", "created", report.Count(bootstrap.ActionCreated), "updated", report.Count(bootstrap.ActionUpdated),
+		"unchanged", report.Count(bootstrap.ActionUnchanged))
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"regexp"
+
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/conformance"
+)
+
+func main() {
+	fs := pflag.NewFlagSet("kubestellar-conformance", pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	wdsOpts := clientopts.NewClientOpts("wds", "access to the workload description space")
+	wecOpts := clientopts.NewClientOpts("wec", "access to the workload execution cluster of the selected Location")
+	wdsOpts.AddFlags(fs)
+	wecOpts.AddFlags(fs)
+	env := conformance.Environment{Timeout: conformance.DefaultTimeout, Image: conformance.DefaultImage}
+	locationSelector := ""
+	focus := ""
+	reportFile := ""
+	keep := false
+	fs.StringVar(&locationSelector, "location-selector", locationSelector, "label selector that matches exactly the one Location whose cluster --wec-* reaches (required)")
+	fs.StringVar(&env.Namespace, "namespace", env.Namespace, "namespace that the suite creates and uses, and name of its EdgePlacement; it must not exist; empty means a generated name")
+	fs.DurationVar(&env.Timeout, "timeout", env.Timeout, "how long each case waits for the installation to do its part")
+	fs.StringVar(&env.Image, "image", env.Image, "container image of the Deployment with no replicas that checks the return of reported state")
+	fs.StringVar(&focus, "focus", focus, "regular expression selecting the cases to run, along with the cases they need; empty means all")
+	fs.StringVar(&reportFile, "report", reportFile, "path of a JUnit XML file in which to write the results; empty means not to")
+	fs.BoolVar(&keep, "keep", keep, "leave what the suite created in place, for inspection, instead of deleting it")
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	if locationSelector == "" {
+		logger.Error(nil, "The --location-selector flag is required")
+		os.Exit(2)
+	}
+	selector, err := metav1.ParseToLabelSelector(locationSelector)
+	if err != nil {
+		logger.Error(err, "Failed to parse --location-selector", "selector", locationSelector)
+		os.Exit(2)
+	}
+	env.LocationSelector = *selector
+	var focusRE *regexp.Regexp
+	if focus != "" {
+		focusRE, err = regexp.Compile(focus)
+		if err != nil {
+			logger.Error(err, "Failed to parse --focus", "focus", focus)
+			os.Exit(2)
+		}
+	}
+	if env.Namespace == "" {
+		env.Namespace = "kubestellar-conformance-" + rand.String(5)
+	}
+
+	wdsConfig, err := wdsOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make client config for the workload description space")
+		os.Exit(2)
+	}
+	wecConfig, err := wecOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make client config for the workload execution cluster")
+		os.Exit(2)
+	}
+	env.WDSKube = kubernetes.NewForConfigOrDie(wdsConfig)
+	env.WDSEdge = edgeclientset.NewForConfigOrDie(wdsConfig)
+	env.WECKube = kubernetes.NewForConfigOrDie(wecConfig)
+
+	logger.Info("Running the conformance suite", "namespace", env.Namespace)
+	results := conformance.Run(ctx, &env, conformance.DefaultCases(), focusRE)
+	if !keep {
+		if err := conformance.Cleanup(ctx, &env); err != nil {
+			logger.Error(err, "Failed to clean up", "namespace", env.Namespace)
+		}
+	}
+	if err := conformance.WriteSummary(os.Stdout, results); err != nil {
+		logger.Error(err, "Failed to write the summary")
+	}
+	if reportFile != "" {
+		if err := writeReport(reportFile, results); err != nil {
+			logger.Error(err, "Failed to write the report", "path", reportFile)
+			os.Exit(1)
+		}
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+func writeReport(path string, results []conformance.Result) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := conformance.WriteJUnit(file, "kubestellar-conformance", results); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/dynamic"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/config/crds"
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/crdinstall"
+)
+
+func main() {
+	establishTimeout := crdinstall.DefaultEstablishTimeout
+	conversionService := ""
+	conversionPath := "/convert"
+	conversionCAFile := ""
+	fs := pflag.NewFlagSet("kubestellar-crd-installer", pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.DurationVar(&establishTimeout, "establish-timeout", establishTimeout, "how long to wait for each CRD to be Established")
+	fs.StringVar(&conversionService, "conversion-service", conversionService, "NAMESPACE/NAME of the Service of the conversion webhook, for the CRDs that use one")
+	fs.StringVar(&conversionPath, "conversion-path", conversionPath, "URL path of the conversion webhook")
+	fs.StringVar(&conversionCAFile, "conversion-ca-file", conversionCAFile, "file holding the PEM-encoded CA bundle for the conversion webhook")
+
+	kcsOpts := clientopts.NewClientOpts("kcs", "access to the KubeStellar core space")
+	kcsOpts.AddFlags(fs)
+
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	toInstall, err := crdinstall.Load(crds.FS)
+	if err != nil {
+		logger.Error(err, "Failed to load the embedded CRDs")
+		os.Exit(5)
+	}
+
+	kcsRestConfig, err := kcsOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to create KubeStellar core space client config from flags")
+		os.Exit(10)
+	}
+	kcsRestConfig.UserAgent = "kubestellar-crd-installer"
+	apiextClientset, err := apiextclient.NewForConfig(kcsRestConfig)
+	if err != nil {
+		logger.Error(err, "Failed to create apiextensions clientset")
+		os.Exit(15)
+	}
+	dynamicClient, err := dynamic.NewForConfig(kcsRestConfig)
+	if err != nil {
+		logger.Error(err, "Failed to create dynamic client")
+		os.Exit(20)
+	}
+
+	installer := crdinstall.NewInstaller(logger, apiextClientset.ApiextensionsV1().CustomResourceDefinitions(), dynamicClient)
+	installer.EstablishTimeout = establishTimeout
+	if conversionService != "" {
+		namespace, name, ok := strings.Cut(conversionService, "/")
+		if !ok || namespace == "" || name == "" {
+			logger.Error(nil, "Invalid --conversion-service, must be NAMESPACE/NAME", "value", conversionService)
+			os.Exit(25)
+		}
+		installer.Conversion = &apiext.WebhookClientConfig{
+			Service: &apiext.ServiceReference{Namespace: namespace, Name: name, Path: &conversionPath},
+		}
+		if conversionCAFile != "" {
+			installer.Conversion.CABundle, err = os.ReadFile(conversionCAFile)
+			if err != nil {
+				logger.Error(err, "Failed to read conversion webhook CA bundle", "file", conversionCAFile)
+				os.Exit(30)
+			}
+		}
+	}
+
+	if err := installer.Install(ctx, toInstall); err != nil {
+		logger.Error(err, "Failed to install the KubeStellar CRDs")
+		os.Exit(35)
+	}
+	logger.Info("Installed the KubeStellar CRDs", "count", len(toInstall))
+}
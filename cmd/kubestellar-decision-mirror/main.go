@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+//
+// Import of k8s.io/component-base/metrics/prometheus/clientgo
+// makes the k8s client library produce Prometheus metrics.
+//
+// Import of github.com/lib/pq registers the "postgres" driver for
+// database/sql, which --postgres-dsn uses by default.
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"net/http"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/spf13/pflag"
+
+	"k8s.io/apiserver/pkg/server/mux"
+	"k8s.io/apiserver/pkg/server/routes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/component-base/metrics/legacyregistry"
+	_ "k8s.io/component-base/metrics/prometheus/clientgo"
+	"k8s.io/klog/v2"
+	utilflag "k8s.io/kubernetes/pkg/util/flag"
+	"k8s.io/utils/clock"
+
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
+	"github.com/kubestellar/kubestellar/pkg/decisionstore"
+)
+
+const mainName = "kubestellar-decision-mirror"
+
+func main() {
+	serverBindAddress := ":10213"
+	concurrency := 2
+	postgresDriver := "postgres"
+	postgresDSN := ""
+	postgresTable := decisionstore.DefaultTable
+	elasticsearchURL := ""
+	elasticsearchIndex := decisionstore.DefaultIndex
+	fs := pflag.NewFlagSet(mainName, pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve /metrics and /debug/pprof/")
+	fs.IntVar(&concurrency, "concurrency", concurrency, "number of syncs to run in parallel")
+	fs.StringVar(&postgresDriver, "postgres-driver", postgresDriver, "name of the database/sql driver with which to connect to PostgreSQL")
+	fs.StringVar(&postgresDSN, "postgres-dsn", postgresDSN, "data source name of the PostgreSQL database in which to record the decisions; empty for none")
+	fs.StringVar(&postgresTable, "postgres-table", postgresTable, "table in which to record the decisions in PostgreSQL")
+	fs.StringVar(&elasticsearchURL, "elasticsearch-url", elasticsearchURL, "URL of the Elasticsearch in which to record the decisions; empty for none")
+	fs.StringVar(&elasticsearchIndex, "elasticsearch-index", elasticsearchIndex, "index in which to record the decisions in Elasticsearch")
+
+	wdsClientOpts := clientopts.NewClientOpts("wds", "access to the workload description space")
+	wdsClientOpts.AddFlags(fs)
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	stores := []decisionstore.Store{}
+	if postgresDSN != "" {
+		db, err := sql.Open(postgresDriver, postgresDSN)
+		if err != nil {
+			logger.Error(err, "Failed to open PostgreSQL database", "driver", postgresDriver)
+			os.Exit(2)
+		}
+		store, err := decisionstore.NewPostgresStore(ctx, db, postgresTable)
+		if err != nil {
+			logger.Error(err, "Failed to prepare PostgreSQL store")
+			os.Exit(3)
+		}
+		stores = append(stores, store)
+	}
+	if elasticsearchURL != "" {
+		store, err := decisionstore.NewElasticsearchStore(nil, elasticsearchURL, elasticsearchIndex)
+		if err != nil {
+			logger.Error(err, "Failed to prepare Elasticsearch store")
+			os.Exit(2)
+		}
+		stores = append(stores, store)
+	}
+	if len(stores) == 0 {
+		logger.Error(nil, "No store given; give --postgres-dsn or --elasticsearch-url")
+		os.Exit(2)
+	}
+
+	mymux := mux.NewPathRecorderMux(mainName)
+	mymux.Handle("/metrics", legacyregistry.Handler())
+	routes.Profiling{}.Install(mymux)
+	go func() {
+		err := http.ListenAndServe(serverBindAddress, mymux)
+		if err != nil {
+			logger.Error(err, "Failure in web serving")
+			panic(err)
+		}
+	}()
+
+	wdsConfig, err := wdsClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make WDS client config")
+		os.Exit(2)
+	}
+	wdsConfig.UserAgent = mainName
+	wdsClient, err := edgeclientset.NewForConfig(wdsConfig)
+	if err != nil {
+		logger.Error(err, "Failed to make WDS client")
+		os.Exit(1)
+	}
+
+	wdsInformerFactory := edgeinformers.NewSharedScopedInformerFactoryWithOptions(wdsClient, 0)
+	placementAccess := wdsInformerFactory.Edge().V2alpha1().EdgePlacements()
+	sliceAccess := wdsInformerFactory.Edge().V2alpha1().SinglePlacementSlices()
+
+	mirror := decisionstore.NewMirror(ctx, clock.RealClock{}, placementAccess.Lister(), sliceAccess.Lister(), stores...)
+	placementAccess.Informer().AddEventHandler(mirror)
+	sliceAccess.Informer().AddEventHandler(mirror.SliceHandler())
+
+	wdsInformerFactory.Start(ctx.Done())
+	mirror.Run(concurrency, placementAccess.Informer().HasSynced, sliceAccess.Informer().HasSynced)
+}
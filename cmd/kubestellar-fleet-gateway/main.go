@@ -0,0 +1,346 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/apiserver/pkg/server/mux"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics/legacyregistry"
+	_ "k8s.io/component-base/metrics/prometheus/clientgo"
+	"k8s.io/klog/v2"
+	utilflag "k8s.io/kubernetes/pkg/util/flag"
+
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
+	"github.com/kubestellar/kubestellar/pkg/compliance"
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+	"github.com/kubestellar/kubestellar/pkg/gateway"
+	"github.com/kubestellar/kubestellar/pkg/kafkasink"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+	"github.com/kubestellar/kubestellar/pkg/timeline"
+)
+
+const mainName = "kubestellar-fleet-gateway"
+
+// summaryPeriod is how often the fleet is summarized when nothing changes,
+// so that overdue heartbeats and held-back health changes are noticed.
+const summaryPeriod = 10 * time.Second
+
+func main() {
+	serverBindAddress := "127.0.0.1:10206"
+	tlsCertFile := ""
+	tlsKeyFile := ""
+	tokenFile := ""
+	heartbeatTimeout := 2 * time.Minute
+	healthMinDuration := time.Duration(0)
+	timelineFile := ""
+	timelineRetention := 7 * 24 * time.Hour
+	configFile := ""
+	complianceDir := ""
+	compliancePeriod := 24 * time.Hour
+	complianceSelector := ""
+	complianceFormats := "json,csv"
+	complianceKeyFile := ""
+	complianceKeep := 30
+	kafkaBridgeURL := ""
+	kafkaBridgeTokenFile := ""
+	kafkaBridgeCAFile := ""
+	kafkaConfig := kafkasink.Config{SummaryTopic: "kubestellar.summaries", DecisionTopic: "kubestellar.decisions"}
+	kafkaSerialization := string(kafkasink.SerializationJSON)
+	fs := pflag.NewFlagSet(mainName, pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve the API, /metrics, /healthz and /readyz; the default is reachable only from the same host")
+	fs.StringVar(&tlsCertFile, "tls-cert-file", tlsCertFile, "file holding the x509 certificate (chain) to serve HTTPS with; empty means to serve plain HTTP")
+	fs.StringVar(&tlsKeyFile, "tls-private-key-file", tlsKeyFile, "file holding the private key matching --tls-cert-file")
+	fs.StringVar(&tokenFile, "token-file", tokenFile, "file holding the bearer token that clients have to present to use the API; empty means no authentication")
+	fs.DurationVar(&heartbeatTimeout, "heartbeat-timeout", heartbeatTimeout, "age of the last syncer heartbeat beyond which a destination is Stale; zero disables this test")
+	fs.DurationVar(&healthMinDuration, "health-min-duration", healthMinDuration, "how long a placement or destination has to stay in a new health state before the change is reported; zero reports every change immediately")
+	fs.StringVar(&timelineFile, "timeline-file", timelineFile, "file in which to keep the timeline of placement lifecycle events across restarts; empty means to keep it only in memory")
+	fs.DurationVar(&timelineRetention, "timeline-retention", timelineRetention, "how long to keep timeline events; zero means forever")
+	fs.StringVar(&configFile, "config", configFile, "path of a KubeStellarConfiguration file; flags given on the command line take precedence over it")
+	fs.StringVar(&complianceDir, "compliance-report-dir", complianceDir, "directory in which to write compliance reports periodically; empty means to write none")
+	fs.DurationVar(&compliancePeriod, "compliance-report-period", compliancePeriod, "how often to write a compliance report")
+	fs.StringVar(&complianceSelector, "compliance-placement-selector", complianceSelector, "label selector of the EdgePlacements to report on; empty means all")
+	fs.StringVar(&complianceFormats, "compliance-report-formats", complianceFormats, "comma-separated list of the formats (json, csv) in which to write each compliance report")
+	fs.StringVar(&complianceKeyFile, "compliance-signing-key-file", complianceKeyFile, "file holding the PEM-encoded Ed25519 private key (PKCS #8) that signs the compliance reports")
+	fs.IntVar(&complianceKeep, "compliance-report-keep", complianceKeep, "number of compliance reports to keep; older ones are deleted; zero keeps all")
+	fs.StringVar(&kafkaBridgeURL, "kafka-bridge-url", kafkaBridgeURL, "URL of the Strimzi Kafka Bridge through which to stream summaries and decision changes to Kafka; empty means not to stream them")
+	fs.StringVar(&kafkaBridgeTokenFile, "kafka-bridge-token-file", kafkaBridgeTokenFile, "file holding the bearer token to present to the Kafka Bridge; empty means none")
+	fs.StringVar(&kafkaBridgeCAFile, "kafka-bridge-ca-file", kafkaBridgeCAFile, "file holding the PEM-encoded CA bundle that verifies the Kafka Bridge; empty means the system roots")
+	fs.StringVar(&kafkaConfig.SummaryTopic, "kafka-summary-topic", kafkaConfig.SummaryTopic, "Kafka topic of the summary records; empty means not to send them")
+	fs.StringVar(&kafkaConfig.DecisionTopic, "kafka-decision-topic", kafkaConfig.DecisionTopic, "Kafka topic of the decision change records; empty means not to send them")
+	fs.StringVar(&kafkaSerialization, "kafka-serialization", kafkaSerialization, "encoding of the Kafka records: json or avro")
+	fs.Int32Var(&kafkaConfig.SummarySchemaID, "kafka-summary-schema-id", kafkaConfig.SummarySchemaID, "schema registry ID of the Avro schema of the summary records; positive selects the Confluent wire format")
+	fs.Int32Var(&kafkaConfig.DecisionSchemaID, "kafka-decision-schema-id", kafkaConfig.DecisionSchemaID, "schema registry ID of the Avro schema of the decision change records; positive selects the Confluent wire format")
+
+	wdsClientOpts := clientopts.NewClientOpts("wds", "access to the workload description space")
+	wdsClientOpts.AddFlags(fs)
+	inventoryClientOpts := clientopts.NewClientOpts("inventory", "access to the inventory space (default is the workload description space)")
+	inventoryClientOpts.AddFlags(fs)
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	var cfgReloader *componentconfig.Reloader
+	if configFile != "" {
+		var cfg *componentconfig.Configuration
+		var err error
+		cfg, cfgReloader, err = componentconfig.LoadForReload(logger.WithName("config"), configFile)
+		if err != nil {
+			logger.Error(err, "Failed to load configuration file", "path", configFile)
+			os.Exit(2)
+		}
+		if gc := cfg.Gateway; gc.HeartbeatTimeout != nil {
+			componentconfig.Override(fs, "heartbeat-timeout", func() { heartbeatTimeout = gc.HeartbeatTimeout.Duration })
+		}
+		if gc := cfg.Gateway; gc.HealthMinDuration != nil {
+			componentconfig.Override(fs, "health-min-duration", func() { healthMinDuration = gc.HealthMinDuration.Duration })
+		}
+		if !fs.Changed("v") {
+			if err := cfg.ApplyVerbosity(); err != nil {
+				logger.Error(err, "Failed to apply verbosity")
+				os.Exit(2)
+			}
+		}
+	}
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		logger.Error(nil, "--tls-cert-file and --tls-private-key-file must be given together")
+		os.Exit(2)
+	}
+	token := ""
+	if tokenFile != "" {
+		tokenBytes, err := os.ReadFile(tokenFile)
+		if err != nil {
+			logger.Error(err, "Failed to read token file", "path", tokenFile)
+			os.Exit(2)
+		}
+		token = strings.TrimSpace(string(tokenBytes))
+		if token == "" {
+			logger.Error(nil, "Token file is empty", "path", tokenFile)
+			os.Exit(2)
+		}
+	}
+	if host, _, err := net.SplitHostPort(serverBindAddress); err == nil {
+		if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && (tlsCertFile == "" || token == "") {
+			logger.Info("WARNING: serving beyond this host without both TLS and a bearer token; anyone who can reach the address can read the state of the fleet",
+				"address", serverBindAddress)
+		}
+	}
+
+	var complianceConfig compliance.Config
+	if complianceDir != "" {
+		if compliancePeriod <= 0 {
+			logger.Error(nil, "--compliance-report-period must be positive")
+			os.Exit(2)
+		}
+		if complianceKeyFile == "" {
+			logger.Error(nil, "--compliance-signing-key-file is required with --compliance-report-dir")
+			os.Exit(2)
+		}
+		selector, err := labels.Parse(complianceSelector)
+		if err != nil {
+			logger.Error(err, "Failed to parse --compliance-placement-selector")
+			os.Exit(2)
+		}
+		formats, err := compliance.ParseFormats(complianceFormats)
+		if err != nil {
+			logger.Error(err, "Failed to parse --compliance-report-formats")
+			os.Exit(2)
+		}
+		signer, err := compliance.LoadSigner(complianceKeyFile)
+		if err != nil {
+			logger.Error(err, "Failed to load compliance signing key", "path", complianceKeyFile)
+			os.Exit(2)
+		}
+		if err := os.MkdirAll(complianceDir, 0o755); err != nil {
+			logger.Error(err, "Failed to make compliance report directory", "path", complianceDir)
+			os.Exit(1)
+		}
+		complianceConfig = compliance.Config{Dir: complianceDir, Formats: formats, Selection: compliance.Selection{Selector: selector},
+			Signer: signer, Keep: complianceKeep, HeartbeatTimeout: heartbeatTimeout}
+	}
+
+	var kafkaProducer kafkasink.Producer
+	if kafkaBridgeURL != "" {
+		serialization, err := kafkasink.ParseSerialization(kafkaSerialization)
+		if err != nil {
+			logger.Error(err, "Failed to parse --kafka-serialization")
+			os.Exit(2)
+		}
+		kafkaConfig.Serialization = serialization
+		kafkaToken := ""
+		if kafkaBridgeTokenFile != "" {
+			tokenBytes, err := os.ReadFile(kafkaBridgeTokenFile)
+			if err != nil {
+				logger.Error(err, "Failed to read Kafka Bridge token file", "path", kafkaBridgeTokenFile)
+				os.Exit(2)
+			}
+			kafkaToken = strings.TrimSpace(string(tokenBytes))
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if kafkaBridgeCAFile != "" {
+			caBytes, err := os.ReadFile(kafkaBridgeCAFile)
+			if err != nil {
+				logger.Error(err, "Failed to read Kafka Bridge CA file", "path", kafkaBridgeCAFile)
+				os.Exit(2)
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(caBytes) {
+				logger.Error(nil, "No certificates in Kafka Bridge CA file", "path", kafkaBridgeCAFile)
+				os.Exit(2)
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+		}
+		kafkaProducer = kafkasink.NewBridgeProducer(kafkaBridgeURL, &http.Client{Transport: transport, Timeout: 30 * time.Second},
+			kafkaToken, kafkaConfig.Serialization == kafkasink.SerializationAvro)
+	}
+
+	wdsConfig, err := wdsClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make WDS client config")
+		os.Exit(2)
+	}
+	wdsConfig.UserAgent = mainName
+	wdsClient, err := edgeclientset.NewForConfig(wdsConfig)
+	if err != nil {
+		logger.Error(err, "Failed to make WDS client")
+		os.Exit(1)
+	}
+	inventoryClient := wdsClient
+	if inventoryClientOpts.Configured() {
+		inventoryConfig, err := inventoryClientOpts.ToRESTConfig()
+		if err != nil {
+			logger.Error(err, "Failed to make inventory client config")
+			os.Exit(2)
+		}
+		inventoryConfig.UserAgent = mainName
+		inventoryClient, err = edgeclientset.NewForConfig(inventoryConfig)
+		if err != nil {
+			logger.Error(err, "Failed to make inventory client")
+			os.Exit(1)
+		}
+	}
+
+	wdsInformerFactory := edgeinformers.NewSharedScopedInformerFactoryWithOptions(wdsClient, 0)
+	placementAccess := wdsInformerFactory.Edge().V2alpha1().EdgePlacements()
+	sliceAccess := wdsInformerFactory.Edge().V2alpha1().SinglePlacementSlices()
+	inventoryInformerFactory := edgeinformers.NewSharedScopedInformerFactoryWithOptions(inventoryClient, 0)
+	syncTargetAccess := inventoryInformerFactory.Edge().V2alpha1().SyncTargets()
+	synced := []cache.InformerSynced{placementAccess.Informer().HasSynced, sliceAccess.Informer().HasSynced, syncTargetAccess.Informer().HasSynced}
+
+	timelineStore, err := timeline.Open(logger.WithName("timeline"), timelineFile, timelineRetention)
+	if err != nil {
+		logger.Error(err, "Failed to open timeline")
+		os.Exit(1)
+	}
+	go timelineStore.Run(ctx, 10*time.Minute)
+	recorder := timeline.NewRecorder(logger.WithName("timeline-recorder"), timelineStore)
+	placementAccess.Informer().AddEventHandler(recorder.PlacementHandler())
+	sliceAccess.Informer().AddEventHandler(recorder.SliceHandler())
+
+	server := gateway.NewServer(logger.WithName("gateway"), placementAccess.Lister(), sliceAccess.Lister(), syncTargetAccess.Lister(), heartbeatTimeout)
+	server.SetHealthMinDuration(healthMinDuration)
+	if kafkaProducer != nil {
+		sink := kafkasink.NewSink(logger.WithName("kafka-sink"), kafkaProducer, kafkaConfig)
+		server.AddSummaryListener(sink.ObserveSummary)
+		timelineStore.AddListener(sink.ObserveEvent)
+		go sink.Run(ctx)
+	}
+	for _, informer := range []cache.SharedIndexInformer{placementAccess.Informer(), sliceAccess.Informer(), syncTargetAccess.Informer()} {
+		informer.AddEventHandler(server.EventHandler())
+	}
+	if cfgReloader != nil {
+		cfgReloader.OnChange(func(cfg *componentconfig.Configuration) {
+			if cfg.Logging.Verbosity != nil && !fs.Changed("v") {
+				if err := cfg.ApplyVerbosity(); err != nil {
+					logger.Error(err, "Failed to apply verbosity")
+				}
+			}
+			if cfg.Gateway.HealthMinDuration != nil && !fs.Changed("health-min-duration") {
+				server.SetHealthMinDuration(cfg.Gateway.HealthMinDuration.Duration)
+			}
+		})
+		go cfgReloader.Run(ctx)
+	}
+
+	apiMux := http.NewServeMux()
+	apiMux.Handle(gateway.APIPrefix+"timeline", &timeline.Handler{Store: timelineStore})
+	apiMux.Handle(gateway.APIPrefix, server)
+	var apiHandler http.Handler = apiMux
+	if token != "" {
+		apiHandler = gateway.RequireBearerToken(token, apiHandler)
+	}
+	mymux := mux.NewPathRecorderMux(mainName)
+	mymux.Handle("/metrics", legacyregistry.Handler())
+	mymux.HandlePrefix(gateway.APIPrefix, apiHandler)
+	probes.Install(mymux, []healthz.HealthChecker{probes.InformersSynced("informers", synced...)}, nil)
+
+	wdsInformerFactory.Start(ctx.Done())
+	inventoryInformerFactory.Start(ctx.Done())
+	go func() {
+		if cache.WaitForCacheSync(ctx.Done(), synced...) {
+			server.Run(ctx, summaryPeriod)
+		}
+	}()
+	if complianceDir != "" {
+		reporter := compliance.NewReporter(logger.WithName("compliance"), complianceConfig,
+			placementAccess.Lister(), sliceAccess.Lister(), syncTargetAccess.Lister(), timelineStore)
+		go func() {
+			if cache.WaitForCacheSync(ctx.Done(), synced...) {
+				reporter.Run(ctx, compliancePeriod)
+			}
+		}()
+	}
+	logger.Info("Serving", "address", serverBindAddress, "tls", tlsCertFile != "", "authenticated", token != "")
+	if tlsCertFile != "" {
+		err = http.ListenAndServeTLS(serverBindAddress, tlsCertFile, tlsKeyFile, mymux)
+	} else {
+		err = http.ListenAndServe(serverBindAddress, mymux)
+	}
+	if err != nil {
+		logger.Error(err, "Failure in web serving")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/discovery"
+	cachediscovery "k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/config/crds"
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/crdinstall"
+	"github.com/kubestellar/kubestellar/pkg/hubmanifests"
+)
+
+func main() {
+	params := hubmanifests.DefaultParams()
+	topology := string(params.Topology)
+	environment := string(params.Environment)
+	pullPolicy := string(params.ImagePullPolicy)
+	webhookCAFile := ""
+	apply := false
+	outputFile := "-"
+	fs := pflag.NewFlagSet("kubestellar-init-manifests", pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.StringVar(&topology, "topology", topology, "layout of the controllers, one of: single-hub|regional")
+	fs.StringVar(&environment, "environment", environment, "kind of the hosting cluster, one of: kubernetes|openshift|kind")
+	fs.StringSliceVar(&params.Regions, "regions", params.Regions, "values of the topology.kubernetes.io/region node label, one placement translator shard per region; only for --topology=regional")
+	fs.StringVar(&params.Namespace, "namespace", params.Namespace, "namespace of the controllers and the webhook")
+	fs.StringVar(&params.Image, "image", params.Image, "image of the controllers and the webhook")
+	fs.StringVar(&pullPolicy, "image-pull-policy", pullPolicy, "imagePullPolicy of the containers")
+	fs.StringVar(&params.CoreSpace, "core-space", params.CoreSpace, "the name of the KubeStellar core space, passed to the controllers")
+	fs.StringVar(&params.SpaceProvider, "space-provider", params.SpaceProvider, "the name of the KubeStellar space provider, passed to the controllers")
+	fs.IntVar(&params.Verbosity, "controller-verbosity", params.Verbosity, "log verbosity of the controllers and the webhook")
+	fs.StringVar(&webhookCAFile, "webhook-ca-file", webhookCAFile, "file holding the PEM-encoded CA certificate that signed the webhook's serving certificate; not used with --environment=openshift")
+	fs.BoolVar(&apply, "apply", apply, "apply the manifests to the hosting cluster, instead of writing them out")
+	fs.StringVarP(&outputFile, "output-file", "o", outputFile, "file to write the manifests to, when not applying; - means stdout")
+
+	hostOpts := clientopts.NewClientOpts("host", "access to the hosting cluster, with --apply")
+	hostOpts.AddFlags(fs)
+
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	params.Topology = hubmanifests.Topology(topology)
+	params.Environment = hubmanifests.Environment(environment)
+	params.ImagePullPolicy = corev1.PullPolicy(pullPolicy)
+	if webhookCAFile != "" {
+		var err error
+		params.WebhookCABundle, err = os.ReadFile(webhookCAFile)
+		if err != nil {
+			logger.Error(err, "Failed to read webhook CA bundle", "file", webhookCAFile)
+			os.Exit(2)
+		}
+	}
+	if err := params.Validate(); err != nil {
+		logger.Error(err, "Invalid parameters")
+		os.Exit(2)
+	}
+
+	toInstall, err := crdinstall.Load(crds.FS)
+	if err != nil {
+		logger.Error(err, "Failed to load the embedded CRDs")
+		os.Exit(5)
+	}
+	objs, err := hubmanifests.Render(params, toInstall)
+	if err != nil {
+		logger.Error(err, "Failed to render the manifests")
+		os.Exit(10)
+	}
+
+	if !apply {
+		var out io.Writer = os.Stdout
+		if outputFile != "-" {
+			file, err := os.Create(outputFile)
+			if err != nil {
+				logger.Error(err, "Failed to create output file", "file", outputFile)
+				os.Exit(15)
+			}
+			defer file.Close()
+			out = file
+		}
+		if err := hubmanifests.WriteYAML(out, objs); err != nil {
+			logger.Error(err, "Failed to write the manifests")
+			os.Exit(20)
+		}
+		return
+	}
+
+	hostConfig, err := hostOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to create hosting cluster client config from flags")
+		os.Exit(25)
+	}
+	hostConfig.UserAgent = "kubestellar-init-manifests"
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(hostConfig)
+	if err != nil {
+		logger.Error(err, "Failed to create discovery client")
+		os.Exit(30)
+	}
+	dynamicClient, err := dynamic.NewForConfig(hostConfig)
+	if err != nil {
+		logger.Error(err, "Failed to create dynamic client")
+		os.Exit(35)
+	}
+	applier := &hubmanifests.Applier{
+		Client:   dynamicClient,
+		Mapper:   restmapper.NewDeferredDiscoveryRESTMapper(cachediscovery.NewMemCacheClient(discoveryClient)),
+		Progress: os.Stdout,
+	}
+	if err := applier.Apply(ctx, objs); err != nil {
+		logger.Error(err, "Failed to apply the manifests")
+		os.Exit(40)
+	}
+	logger.Info("Applied the manifests", "topology", params.Topology, "environment", params.Environment, "count", len(objs))
+}
@@ -44,15 +44,17 @@ import (
 	kcpdynamic "github.com/kcp-dev/client-go/dynamic"
 	kcpscopedclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/logicalcluster/v3"
 
 	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
 	"github.com/kubestellar/kubestellar/pkg/mailboxwatch"
 )
 
 const mainName = "kubestellar-list-syncing-objects"
 
 var watch bool
-var outputJson bool
+var output = base.OutputYAML
 
 func main() {
 	fs := pflag.NewFlagSet(mainName, pflag.ExitOnError)
@@ -66,7 +68,10 @@ func main() {
 	fs.StringVar(&gvr.Resource, "api-resource", gvr.Resource, "API resource (lowercase plural) of objects to watch (defaults to lowercase(kind)+'s')")
 	fs.StringVar(&kind, "api-kind", kind, "kind of objects to watch")
 	fs.BoolVar(&watch, "watch", watch, "indicates whether to inform rather than just list")
+	var outputJson bool
 	fs.BoolVar(&outputJson, "json", outputJson, "indicates whether to output as lines of JSON rather than YAML")
+	fs.MarkDeprecated("json", "use --output=json instead")
+	fs.StringVarP((*string)(&output), "output", "o", string(output), base.OutputFlagUsage)
 
 	parentClientOpts := clientopts.NewClientOpts("parent", "access to the parent of mailbox workspaces")
 	parentClientOpts.SetDefaultCurrentContext("root")
@@ -77,13 +82,21 @@ func main() {
 	allClientOpts.AddFlags(fs)
 
 	fs.Parse(os.Args[1:])
+	if outputJson {
+		output = base.OutputJSON
+	}
 
 	ctx := context.Background()
 	logger := klog.Background()
 	ctx = klog.NewContext(ctx, logger)
 
+	if err := output.Validate(); err != nil {
+		logger.Error(err, "Invalid command line")
+		os.Exit(base.ExitUsage)
+	}
 	if len(kind) == 0 {
-		logger.Error(nil, "The --kind must not be the empty string")
+		logger.Error(nil, "The --api-kind must not be the empty string")
+		os.Exit(base.ExitUsage)
 	}
 	if len(gvr.Resource) == 0 {
 		gvr.Resource = strings.ToLower(kind) + "s"
@@ -92,7 +105,7 @@ func main() {
 	parentClientConfig, err := parentClientOpts.ToRESTConfig()
 	if err != nil {
 		logger.Error(err, "failed to make parent client config")
-		os.Exit(2)
+		os.Exit(base.ExitUsage)
 	}
 	parentClientConfig.UserAgent = mainName
 
@@ -101,14 +114,14 @@ func main() {
 	allClientConfig, err := allClientOpts.ToRESTConfig()
 	if err != nil {
 		logger.Error(err, "failed to make all-cluster client config")
-		os.Exit(2)
+		os.Exit(base.ExitUsage)
 	}
 	allClientConfig.UserAgent = mainName
 
 	dynamicClusterClientset, err := kcpdynamic.NewForConfig(allClientConfig)
 	if err != nil {
 		logger.Error(err, "failed to make all-cluster dynamic client")
-		os.Exit(3)
+		os.Exit(base.ExitFailure)
 	}
 	dynamicClusterResource := dynamicClusterClientset.Resource(gvr)
 
@@ -130,6 +143,9 @@ func main() {
 		UpdateFunc: func(oldObj, newObj any) { log(logger, "update", newObj) },
 		DeleteFunc: func(obj any) { log(logger, "delete", obj) },
 	})
+	if output == base.OutputTable {
+		printTableHeader()
+	}
 	parentInformerFactory.Start(ctx.Done())
 	upstreamcache.WaitForCacheSync(ctx.Done(), mbPreInformer.Informer().HasSynced)
 	go informer.Run(ctx.Done())
@@ -140,6 +156,7 @@ func main() {
 		if !upstreamcache.WaitForCacheSync(ctx.Done(),
 			informer.HasSynced) {
 			logger.Error(nil, "Impossible")
+			os.Exit(base.ExitUnavailable)
 		}
 		time.Sleep(15 * time.Second)
 	}
@@ -151,6 +168,10 @@ func log(logger klog.Logger, action string, obj any) {
 	objU := obj.(*unstructured.Unstructured)
 	logmu.Lock()
 	defer logmu.Unlock()
+	if output == base.OutputTable {
+		printTableRow(action, objU)
+		return
+	}
 	objData := objU.UnstructuredContent()
 	outData := objData
 	if watch {
@@ -164,7 +185,7 @@ func log(logger klog.Logger, action string, obj any) {
 		logger.Error(err, "Failed to marshal as JSON", "outData", outData)
 		return
 	}
-	if outputJson {
+	if output == base.OutputJSON {
 		objJS := string(objJ)
 		fmt.Println(objJS)
 		return
@@ -178,3 +199,21 @@ func log(logger klog.Logger, action string, obj any) {
 	objYS := string(objY)
 	fmt.Println(objYS)
 }
+
+// tableRowFormat lays out the columns of the table output.
+// Fixed widths are used because the rows arrive over time.
+const tableRowFormat = "%-20s %-30s %s\n"
+
+func printTableHeader() {
+	if watch {
+		fmt.Printf("%-7s ", "ACTION")
+	}
+	fmt.Printf(tableRowFormat, "CLUSTER", "NAMESPACE", "NAME")
+}
+
+func printTableRow(action string, objU *unstructured.Unstructured) {
+	if watch {
+		fmt.Printf("%-7s ", action)
+	}
+	fmt.Printf(tableRowFormat, logicalcluster.From(objU).String(), objU.GetNamespace(), objU.GetName())
+}
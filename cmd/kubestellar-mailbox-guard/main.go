@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+
+import (
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/apiserver/pkg/server/mux"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+	utilflag "k8s.io/kubernetes/pkg/util/flag"
+
+	"github.com/kubestellar/kubestellar/pkg/breakglass"
+	"github.com/kubestellar/kubestellar/pkg/mailboxguard"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+)
+
+const mainName = "kubestellar-mailbox-guard"
+
+func main() {
+	serverBindAddress := ":10212"
+	tlsCertFile := ""
+	tlsKeyFile := ""
+	config := mailboxguard.Config{Mode: mailboxguard.ModeEnforce, MaxBreakGlassWindow: breakglass.DefaultMaxWindow}
+	mode := string(config.Mode)
+	fs := pflag.NewFlagSet(mainName, pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve the webhook, /metrics, /healthz and /readyz")
+	fs.StringVar(&tlsCertFile, "tls-cert-file", tlsCertFile, "file holding the x509 certificate (chain) to serve HTTPS with (required)")
+	fs.StringVar(&tlsKeyFile, "tls-private-key-file", tlsKeyFile, "file holding the private key matching --tls-cert-file (required)")
+	fs.StringVar(&mode, "mode", mode, "what to do about a manual edit of a copy maintained by KubeStellar: \"enforce\" rejects it, \"warn\" admits it with a warning")
+	fs.StringSliceVar(&config.AllowedUsers, "allowed-users", config.AllowedUsers, "users that may change the copies, such as the placement translator's")
+	fs.StringSliceVar(&config.AllowedGroups, "allowed-groups", config.AllowedGroups, "groups whose members may change the copies, such as the syncers'")
+	fs.DurationVar(&config.MaxBreakGlassWindow, "max-break-glass-window", config.MaxBreakGlassWindow, "how far in the future a break-glass override may end, at most; zero means to reject such overrides")
+	fs.Parse(os.Args[1:])
+
+	logger := klog.Background()
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	if tlsCertFile == "" || tlsKeyFile == "" {
+		logger.Error(nil, "--tls-cert-file and --tls-private-key-file are required, because apiservers call webhooks only over HTTPS")
+		os.Exit(2)
+	}
+
+	config.Mode = mailboxguard.Mode(mode)
+	webhook, err := mailboxguard.NewWebhook(logger.WithName("webhook"), config)
+	if err != nil {
+		logger.Error(err, "Invalid --mode")
+		os.Exit(2)
+	}
+
+	mymux := mux.NewPathRecorderMux(mainName)
+	mymux.Handle("/metrics", legacyregistry.Handler())
+	mymux.Handle(mailboxguard.Path, webhook)
+	probes.Install(mymux, nil, nil)
+
+	logger.Info("Serving", "address", serverBindAddress, "mode", mode)
+	err = http.ListenAndServeTLS(serverBindAddress, tlsCertFile, tlsKeyFile, mymux)
+	if err != nil {
+		logger.Error(err, "Failure in web serving")
+		os.Exit(1)
+	}
+}
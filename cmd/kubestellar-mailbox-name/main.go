@@ -0,0 +1,36 @@
+/*
+Copyright 2022 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubestellar-mailbox-name prints the name of the mailbox space for a
+// SyncTarget, so that scripts compute it the same way the controllers do.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) != 2 || args[0] == "" || args[1] == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s <space ID> <SyncTarget UID>\n", os.Args[0])
+		os.Exit(base.ExitUsage)
+	}
+	fmt.Println(naming.MailboxSpaceName(args[0], args[1]))
+}
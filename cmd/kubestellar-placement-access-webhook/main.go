@@ -0,0 +1,129 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/apiserver/pkg/server/mux"
+	"k8s.io/client-go/kubernetes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/component-base/metrics/legacyregistry"
+	_ "k8s.io/component-base/metrics/prometheus/clientgo"
+	"k8s.io/klog/v2"
+	utilflag "k8s.io/kubernetes/pkg/util/flag"
+
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/customizercheck"
+	"github.com/kubestellar/kubestellar/pkg/placementauthz"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+)
+
+const mainName = "kubestellar-placement-access-webhook"
+
+func main() {
+	serverBindAddress := ":10211"
+	tlsCertFile := ""
+	tlsKeyFile := ""
+	mode := string(placementauthz.ModeEnforce)
+	broadMode := string(placementauthz.ModeWarn)
+	customizerMode := string(customizercheck.ModeWarn)
+	schemaTTL := 5 * time.Minute
+	fs := pflag.NewFlagSet(mainName, pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve the webhook, /metrics, /healthz and /readyz")
+	fs.StringVar(&tlsCertFile, "tls-cert-file", tlsCertFile, "file holding the x509 certificate (chain) to serve HTTPS with (required)")
+	fs.StringVar(&tlsKeyFile, "tls-private-key-file", tlsKeyFile, "file holding the private key matching --tls-cert-file (required)")
+	fs.StringVar(&mode, "mode", mode, "what to do about a placement that selects objects its author can not read: \"enforce\" rejects it, \"warn\" admits it with warnings")
+	fs.StringVar(&broadMode, "broad-selection-mode", broadMode, "what to do about a placement that selects very broadly without setting allowAll: \"enforce\" rejects it, \"warn\" admits it with warnings")
+	fs.StringVar(&customizerMode, "customizer-mode", customizerMode, "what to do about a Customizer whose paths fit none of the kinds of objects it may apply to: \"enforce\" rejects it, \"warn\" admits it with warnings")
+	fs.DurationVar(&schemaTTL, "schema-cache-ttl", schemaTTL, "how long to use the OpenAPI definitions of the WDS before fetching them again")
+	wdsClientOpts := clientopts.NewClientOpts("wds", "access to the workload description space")
+	wdsClientOpts.AddFlags(fs)
+	fs.Parse(os.Args[1:])
+
+	logger := klog.Background()
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	if tlsCertFile == "" || tlsKeyFile == "" {
+		logger.Error(nil, "--tls-cert-file and --tls-private-key-file are required, because apiservers call webhooks only over HTTPS")
+		os.Exit(2)
+	}
+
+	wdsConfig, err := wdsClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make WDS client config")
+		os.Exit(2)
+	}
+	wdsConfig.UserAgent = mainName
+	wdsClient, err := kubernetes.NewForConfig(wdsConfig)
+	if err != nil {
+		logger.Error(err, "Failed to make WDS client")
+		os.Exit(1)
+	}
+
+	wdsEdgeClient, err := edgeclientset.NewForConfig(wdsConfig)
+	if err != nil {
+		logger.Error(err, "Failed to make WDS edge client")
+		os.Exit(1)
+	}
+
+	webhook, err := placementauthz.NewWebhook(logger.WithName("webhook"), wdsClient.AuthorizationV1().SubjectAccessReviews(), placementauthz.Mode(mode))
+	if err != nil {
+		logger.Error(err, "Invalid --mode")
+		os.Exit(2)
+	}
+	if err := webhook.SetBroadSelectionMode(placementauthz.Mode(broadMode)); err != nil {
+		logger.Error(err, "Invalid --broad-selection-mode")
+		os.Exit(2)
+	}
+
+	schemas := apiwatch.NewOpenAPISchemaCache(wdsClient.Discovery().RESTClient(), schemaTTL)
+	customizerWebhook, err := customizercheck.NewWebhook(logger.WithName("customizer-webhook"), wdsEdgeClient.EdgeV2alpha1(), wdsClient.Discovery(), schemas, customizercheck.Mode(customizerMode))
+	if err != nil {
+		logger.Error(err, "Invalid --customizer-mode")
+		os.Exit(2)
+	}
+
+	mymux := mux.NewPathRecorderMux(mainName)
+	mymux.Handle("/metrics", legacyregistry.Handler())
+	mymux.Handle(placementauthz.Path, webhook)
+	mymux.Handle(customizercheck.Path, customizerWebhook)
+	probes.Install(mymux, nil, nil)
+
+	logger.Info("Serving", "address", serverBindAddress, "mode", mode, "broadSelectionMode", broadMode, "customizerMode", customizerMode)
+	err = http.ListenAndServeTLS(serverBindAddress, tlsCertFile, tlsKeyFile, mymux)
+	if err != nil {
+		logger.Error(err, "Failure in web serving")
+		os.Exit(1)
+	}
+}
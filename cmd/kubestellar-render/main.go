@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	machruntime "k8s.io/apimachinery/pkg/runtime"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/render"
+)
+
+func main() {
+	fs := pflag.NewFlagSet("kubestellar-render", pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	placementName := ""
+	locationName := ""
+	fs.StringVar(&placementName, "placement", placementName, "name of the EdgePlacement to render; may be omitted if the input has exactly one")
+	fs.StringVar(&locationName, "location", locationName, "name of the one Location to render for; empty means all selected Locations")
+	fs.Usage = func() {
+		os.Stderr.WriteString("Usage: kubestellar-render [flags] filename...\n")
+		os.Stderr.WriteString("Reads EdgePlacements, Locations, Customizers and workload objects from the given YAML files\n")
+		os.Stderr.WriteString("(\"-\" means stdin) and writes to stdout the objects that would be downsynced.\n")
+		fs.PrintDefaults()
+	}
+
+	ctx := context.Background()
+	logger := klog.FromContext(ctx)
+
+	err := fs.Parse(os.Args[1:])
+	if err != nil {
+		logger.Error(err, "Command line parse failed")
+		os.Exit(1)
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var input render.Input
+	var placements []*edgeapi.EdgePlacement
+	for _, filename := range fs.Args() {
+		objs, err := readObjects(filename)
+		if err != nil {
+			logger.Error(err, "Failed to read objects", "filename", filename)
+			os.Exit(10)
+		}
+		for _, obj := range objs {
+			if obj.GroupVersionKind().Group != edgeapi.SchemeGroupVersion.Group {
+				input.Objects = append(input.Objects, obj)
+				continue
+			}
+			switch obj.GetKind() {
+			case "EdgePlacement":
+				ep := &edgeapi.EdgePlacement{}
+				err = machruntime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ep)
+				placements = append(placements, ep)
+			case "Location":
+				loc := &edgeapi.Location{}
+				err = machruntime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, loc)
+				input.Locations = append(input.Locations, loc)
+			case "Customizer":
+				cust := &edgeapi.Customizer{}
+				err = machruntime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, cust)
+				input.Customizers = append(input.Customizers, cust)
+			default:
+				logger.V(2).Info("Ignoring edge API object", "kind", obj.GetKind(), "name", obj.GetName())
+			}
+			if err != nil {
+				logger.Error(err, "Failed to convert object", "filename", filename, "kind", obj.GetKind(), "name", obj.GetName())
+				os.Exit(20)
+			}
+		}
+	}
+
+	for _, ep := range placements {
+		if placementName == "" && len(placements) == 1 || ep.Name == placementName {
+			input.Placement = ep
+		}
+	}
+	if input.Placement == nil {
+		logger.Error(nil, "Failed to identify the EdgePlacement to render", "placement", placementName, "numPlacements", len(placements))
+		os.Exit(30)
+	}
+
+	rendered, err := render.Render(logger, input, locationName)
+	if err != nil {
+		logger.Error(err, "Failed to render")
+		os.Exit(40)
+	}
+	err = render.WriteYAML(os.Stdout, rendered)
+	if err != nil {
+		logger.Error(err, "Failed to write output")
+		os.Exit(99)
+	}
+}
+
+func readObjects(filename string) ([]*unstructured.Unstructured, error) {
+	var reader io.Reader = os.Stdin
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	decoder := k8syaml.NewYAMLOrJSONDecoder(reader, 4096)
+	var ans []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if errors.Is(err, io.EOF) {
+			return ans, nil
+		}
+		if err != nil {
+			return ans, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		ans = append(ans, obj)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	apiextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/storagemigration"
+)
+
+func main() {
+	group := edgev2alpha1.SchemeGroupVersion.Group
+	var resources []string
+	all := false
+	dryRun := false
+	var pageSize int64 = storagemigration.DefaultPageSize
+	fs := pflag.NewFlagSet("kubestellar-storage-migrator", pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.StringVar(&group, "group", group, "API group whose CRDs to migrate")
+	fs.StringSliceVar(&resources, "resource", resources, "plural name of a resource to migrate; may be repeated; default is all those of the group")
+	fs.BoolVar(&all, "all", all, "migrate every selected resource, not only those whose CRD lists a stored version other than the storage version")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "only report which resources need migration")
+	fs.Int64Var(&pageSize, "page-size", pageSize, "number of objects to list at a time")
+
+	kcsOpts := clientopts.NewClientOpts("kcs", "access to the KubeStellar core space")
+	kcsOpts.AddFlags(fs)
+
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	kcsRestConfig, err := kcsOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to create KubeStellar core space client config from flags")
+		os.Exit(10)
+	}
+	kcsRestConfig.UserAgent = "kubestellar-storage-migrator"
+	apiextClientset, err := apiextclient.NewForConfig(kcsRestConfig)
+	if err != nil {
+		logger.Error(err, "Failed to create apiextensions clientset")
+		os.Exit(15)
+	}
+	dynamicClient, err := dynamic.NewForConfig(kcsRestConfig)
+	if err != nil {
+		logger.Error(err, "Failed to create dynamic client")
+		os.Exit(20)
+	}
+	crdClient := apiextClientset.ApiextensionsV1().CustomResourceDefinitions()
+
+	crds, err := crdClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		logger.Error(err, "Failed to list CRDs")
+		os.Exit(25)
+	}
+	wanted := sets.NewString(resources...)
+	migrator := storagemigration.NewMigrator(dynamicClient)
+	migrator.PageSize = pageSize
+	migrator.OnProgress = func(prog storagemigration.Progress) {
+		logger.Info("Migration progress", "resource", prog.Resource, "migrated", prog.Migrated, "skipped", prog.Skipped, "remaining", prog.Remaining, "done", prog.Done)
+	}
+	var failed, migrated int
+	for idx := range crds.Items {
+		crd := &crds.Items[idx]
+		if crd.Spec.Group != group || (wanted.Len() > 0 && !wanted.Has(crd.Spec.Names.Plural)) {
+			continue
+		}
+		wanted.Delete(crd.Spec.Names.Plural)
+		storage, err := storagemigration.StorageVersion(crd)
+		if err != nil {
+			logger.Error(err, "Unable to migrate", "crd", crd.Name)
+			failed++
+			continue
+		}
+		needed := storagemigration.NeedsMigration(crd, storage)
+		if !needed && !all {
+			logger.V(1).Info("No migration needed", "crd", crd.Name, "storedVersions", crd.Status.StoredVersions)
+			continue
+		}
+		if dryRun {
+			logger.Info("Would migrate", "crd", crd.Name, "storedVersions", crd.Status.StoredVersions)
+			continue
+		}
+		prog, err := migrator.MigrateCRD(ctx, crdClient, crd.Name)
+		if err != nil {
+			logger.Error(err, "Failed to migrate", "crd", crd.Name, "progress", prog.String())
+			failed++
+			continue
+		}
+		migrated++
+		logger.Info("Migrated", "crd", crd.Name, "migrated", prog.Migrated, "skipped", prog.Skipped)
+	}
+	if wanted.Len() > 0 {
+		logger.Error(nil, "No CRD found for some resources", "group", group, "resources", wanted.List())
+		os.Exit(30)
+	}
+	if failed > 0 {
+		os.Exit(35)
+	}
+	logger.Info("Storage version migration finished", "group", group, "migratedCRDs", migrated)
+}
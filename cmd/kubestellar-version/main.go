@@ -17,26 +17,54 @@ limitations under the License.
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 
+	"github.com/spf13/pflag"
+
 	"k8s.io/client-go/pkg/version"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
 )
 
 func main() {
-	args := os.Args
+	fs := pflag.NewFlagSet(os.Args[0], pflag.ContinueOnError)
+	output := base.OutputJSON
+	fs.StringVarP((*string)(&output), "output", "o", string(output), base.OutputFlagUsage)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [buildDate|gitCommit|gitTreeState|platform] [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == pflag.ErrHelp {
+			os.Exit(base.ExitOK)
+		}
+		os.Exit(base.ExitUsage)
+	}
+	if err := output.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(base.ExitUsage)
+	}
+	args := fs.Args()
 	vi := version.Get()
 	switch len(args) {
-	case 1:
-		enc, err := json.Marshal(vi)
-		if err != nil {
-			panic(err)
+	case 0:
+		table := base.Table{Columns: []string{"FIELD", "VALUE"}, Rows: [][]string{
+			{"gitVersion", vi.GitVersion},
+			{"gitCommit", vi.GitCommit},
+			{"gitTreeState", vi.GitTreeState},
+			{"buildDate", vi.BuildDate},
+			{"goVersion", vi.GoVersion},
+			{"compiler", vi.Compiler},
+			{"platform", vi.Platform},
+		}}
+		if err := base.PrintObject(os.Stdout, output, vi, table); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(base.ExitFailure)
 		}
-		fmt.Println(string(enc))
 		return
-	case 2:
-		field := args[1]
+	case 1:
+		field := args[0]
 		var value string
 		switch field {
 		case "buildDate":
@@ -49,14 +77,14 @@ func main() {
 			value = vi.Platform
 		default:
 			fmt.Fprintf(os.Stderr, "Invalid component requested: %q\n", field)
-			goto Usage
+			fs.Usage()
+			os.Exit(base.ExitUsage)
 		}
 		fmt.Println(value)
 		return
 	default:
-		fmt.Fprintf(os.Stderr, "%s: too many arguments\n", args[0])
+		fmt.Fprintf(os.Stderr, "%s: too many arguments\n", os.Args[0])
+		fs.Usage()
+		os.Exit(base.ExitUsage)
 	}
-Usage:
-	fmt.Fprintf(os.Stderr, "Usage: %s [buildDate|gitCommit|gitTreeState|platform]\n", args[0])
-	os.Exit(1)
 }
@@ -18,12 +18,16 @@ package cmd
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"k8s.io/apiserver/pkg/server/healthz"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/component-base/featuregate"
 	"k8s.io/component-base/logs"
@@ -33,7 +37,12 @@ import (
 	resolveroptions "github.com/kubestellar/kubestellar/cmd/kubestellar-where-resolver/options"
 	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
 	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+	"github.com/kubestellar/kubestellar/pkg/events"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	spaceclientfactory "github.com/kubestellar/kubestellar/pkg/spaceclient"
 	wheresolver "github.com/kubestellar/kubestellar/pkg/where-resolver"
 	spaceclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
 	spacemanager "github.com/kubestellar/kubestellar/space-framework/pkg/space-manager"
@@ -52,9 +61,28 @@ func NewResolverCommand() *cobra.Command {
 				return err
 			}
 
+			var cfg *componentconfig.Configuration
+			var cfgReloader *componentconfig.Reloader
+			if options.ConfigFile != "" {
+				var err error
+				cfg, cfgReloader, err = componentconfig.LoadForReload(klog.Background(), options.ConfigFile)
+				if err != nil {
+					return err
+				}
+				if err := cfg.ApplyFeatureGates(featureGate); err != nil {
+					return err
+				}
+				options.ApplyConfig(cmd.Flags(), cfg)
+			}
+
 			if err := options.Logs.ValidateAndApply(featureGate); err != nil {
 				return err
 			}
+			if cfg != nil && !cmd.Flags().Changed("v") {
+				if err := cfg.ApplyVerbosity(); err != nil {
+					return err
+				}
+			}
 			if err := options.Complete(); err != nil {
 				return err
 			}
@@ -64,7 +92,7 @@ func NewResolverCommand() *cobra.Command {
 			}
 
 			ctx := context.Background()
-			if err := Run(ctx, options); err != nil {
+			if err := Run(ctx, options, cmd.Flags(), cfgReloader); err != nil {
 				return err
 			}
 
@@ -85,12 +113,28 @@ func NewResolverCommand() *cobra.Command {
 	return resolverCommand
 }
 
-func Run(ctx context.Context, options *resolveroptions.Options) error {
+// Run runs the where-resolver. If cfgReloader is not nil then the
+// tunables in the configuration file are applied when it changes,
+// except those given on the command line in fs.
+func Run(ctx context.Context, options *resolveroptions.Options, fs *pflag.FlagSet, cfgReloader *componentconfig.Reloader) error {
 	const resyncPeriod = 10 * time.Hour
-	const numThreads = 2
 
 	logger := klog.Background()
 	ctx = klog.NewContext(ctx, logger)
+	recovery.EnableBundles(options.PanicBundleDir, recovery.DefaultMaxBundles)
+
+	var mymux *http.ServeMux
+	if options.ServerBindAddress != "" {
+		mymux = http.NewServeMux()
+		mymux.Handle("/metrics", options.MetricsLabels.Handler())
+		go func() {
+			err := http.ListenAndServe(options.ServerBindAddress, mymux)
+			if err != nil {
+				logger.Error(err, "Failure in web serving")
+				panic(err)
+			}
+		}()
+	}
 
 	spaceManagementConfig, err := options.SpaceMgtClientOpts.ToRESTConfig()
 	if err != nil {
@@ -103,12 +147,15 @@ func Run(ctx context.Context, options *resolveroptions.Options) error {
 		return err
 	}
 	spaceProviderNs := spacemanager.ProviderNS(options.SpaceProvider)
+	spaceClients := spaceclientfactory.NewFactory(spaceClient, spaceclientfactory.Options{UserAgent: wheresolver.ControllerName, Adjust: options.ClientLimits.Apply})
 
 	kcsRestConfig, err := spaceClient.ConfigForSpace(options.KcsName, spaceProviderNs)
 	if err != nil {
 		logger.Error(err, "Failed to construct space config", "spacename", options.KcsName)
 		return err
 	}
+	kcsRestConfig = rest.CopyConfig(kcsRestConfig)
+	options.ClientLimits.Apply(kcsRestConfig)
 
 	edgeClientset, err := edgeclientset.NewForConfig(kcsRestConfig)
 	if err != nil {
@@ -127,7 +174,7 @@ func Run(ctx context.Context, options *resolveroptions.Options) error {
 
 	es, err := wheresolver.NewController(
 		ctx,
-		spaceClient,
+		spaceClients,
 		spaceProviderNs,
 		edgeSharedInformerFactory.Edge().V2alpha1().EdgePlacements(),
 		edgeSharedInformerFactory.Edge().V2alpha1().SinglePlacementSlices(),
@@ -140,6 +187,25 @@ func Run(ctx context.Context, options *resolveroptions.Options) error {
 		return err
 	}
 
+	// Events about an EdgePlacement go into the space of its consumer
+	spaceRecorders := events.NewSpaceRecorders(wheresolver.ControllerName, spaceClients, spaceProviderNs, 0)
+	go spaceRecorders.Run(ctx)
+	eventRecorder := events.NewRecorder(logger, spaceRecorders.For, events.DefaultAggregationWindow)
+	es.SetEventRecorder(eventRecorder)
+	go eventRecorder.Run(ctx)
+
+	if mymux != nil {
+		watchdog := probes.NewWatchdog("process-watchdog", options.WatchdogTimeout)
+		es.SetWatchdog(watchdog)
+		probes.Install(mymux,
+			[]healthz.HealthChecker{probes.InformersSynced("informers", kbSpaceRelation.InformerSynced,
+				edgeSharedInformerFactory.Edge().V2alpha1().EdgePlacements().Informer().HasSynced,
+				edgeSharedInformerFactory.Edge().V2alpha1().SinglePlacementSlices().Informer().HasSynced,
+				edgeSharedInformerFactory.Edge().V2alpha1().Locations().Informer().HasSynced,
+				edgeSharedInformerFactory.Edge().V2alpha1().SyncTargets().Informer().HasSynced)},
+			[]healthz.HealthChecker{watchdog})
+	}
+
 	// run where-resolver
 	doneCh := ctx.Done()
 
@@ -147,7 +213,23 @@ func Run(ctx context.Context, options *resolveroptions.Options) error {
 	cache.WaitForCacheSync(doneCh, kbSpaceRelation.InformerSynced)
 	edgeSharedInformerFactory.WaitForCacheSync(doneCh)
 
-	es.Run(numThreads)
+	if options.OrphanGCPeriod > 0 {
+		go es.RunOrphanGC(options.OrphanGCPeriod, options.OrphanGCDryRun)
+	}
+	if cfgReloader != nil {
+		cfgReloader.OnChange(func(cfg *componentconfig.Configuration) {
+			if cfg.Logging.Verbosity != nil && !fs.Changed("v") {
+				if err := cfg.ApplyVerbosity(); err != nil {
+					logger.Error(err, "Failed to apply verbosity")
+				}
+			}
+			if cfg.WhereResolver.OrphanGCDryRun != nil && !fs.Changed("orphan-gc-dry-run") {
+				es.SetOrphanGCDryRun(*cfg.WhereResolver.OrphanGCDryRun)
+			}
+		})
+		go cfgReloader.Run(ctx)
+	}
+	es.Run(options.Concurrency)
 
 	return nil
 }
@@ -17,18 +17,27 @@ limitations under the License.
 package options
 
 import (
+	"errors"
+	"time"
+
 	"github.com/spf13/pflag"
 
 	"k8s.io/component-base/config"
 	"k8s.io/component-base/logs"
 
 	clientoptions "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/clientlimits"
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+	"github.com/kubestellar/kubestellar/pkg/metricslabels"
+	"github.com/kubestellar/kubestellar/pkg/probes"
 )
 
 const (
 	defaultSpaceProviderName string = "default"
 	defaultKcsName           string = "espw"
 	externalAccess           bool   = false
+	defaultOrphanGCPeriod           = 5 * time.Minute
+	defaultConcurrency              = 2
 )
 
 type Options struct {
@@ -37,6 +46,35 @@ type Options struct {
 	SpaceProvider      string
 	KcsName            string
 	ExternalAccess     bool
+
+	// OrphanGCPeriod is how often to look for orphaned SinglePlacementSlices; zero means never.
+	OrphanGCPeriod time.Duration
+
+	// OrphanGCDryRun means to only log and count orphans rather than delete them.
+	// It is the default, so that deleting is a deliberate choice.
+	OrphanGCDryRun bool
+
+	// ServerBindAddress is where to serve /metrics, /readyz and /healthz; empty means not to.
+	ServerBindAddress string
+
+	// Concurrency is the number of reconciliation workers.
+	Concurrency int
+
+	// WatchdogTimeout is how long the processing of one queue item may
+	// take before /healthz fails; zero means no limit.
+	WatchdogTimeout time.Duration
+
+	// PanicBundleDir is where to write a diagnostic file for each recovered panic; empty means not to.
+	PanicBundleDir string
+
+	// ConfigFile is the path of a componentconfig file; empty means none.
+	ConfigFile string
+
+	// ClientLimits are the limits on the requests to the API servers.
+	ClientLimits *clientlimits.Options
+
+	// MetricsLabels is what to do with high-cardinality metric labels.
+	MetricsLabels *metricslabels.Options
 }
 
 func NewOptions() *Options {
@@ -50,6 +88,12 @@ func NewOptions() *Options {
 		SpaceProvider:      defaultSpaceProviderName,
 		KcsName:            defaultKcsName,
 		ExternalAccess:     externalAccess,
+		OrphanGCPeriod:     defaultOrphanGCPeriod,
+		OrphanGCDryRun:     true,
+		Concurrency:        defaultConcurrency,
+		WatchdogTimeout:    probes.DefaultWatchdogTimeout,
+		ClientLimits:       clientlimits.NewOptions("where-resolver"),
+		MetricsLabels:      metricslabels.NewOptions(),
 	}
 }
 
@@ -59,7 +103,32 @@ func (options *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&options.SpaceProvider, "space-provider", options.SpaceProvider, "the name of the KubeStellar space provider")
 	fs.StringVar(&options.KcsName, "core-space", options.KcsName, "the name of the KubeStellar Core space")
 	fs.BoolVar(&options.ExternalAccess, "external-access", options.ExternalAccess, "the access to the spaces. True when the space-provider is hosted in a space while the controller is running outside of that space")
+	fs.DurationVar(&options.OrphanGCPeriod, "orphan-gc-period", options.OrphanGCPeriod, "how often to look for SinglePlacementSlices whose EdgePlacement no longer exists; zero disables this garbage collection")
+	fs.BoolVar(&options.OrphanGCDryRun, "orphan-gc-dry-run", options.OrphanGCDryRun, "only log and count orphaned SinglePlacementSlices, do not delete them; set to false to let them be deleted")
+	fs.StringVar(&options.ServerBindAddress, "server-bind-address", options.ServerBindAddress, "The IP address with port at which to serve /metrics, /readyz and /healthz; empty means not to serve.")
+	fs.IntVar(&options.Concurrency, "concurrency", options.Concurrency, "number of reconciliation workers")
+	fs.DurationVar(&options.WatchdogTimeout, "watchdog-timeout", options.WatchdogTimeout, "how long the processing of one queue item may take before /healthz fails; zero disables this test")
+	fs.StringVar(&options.PanicBundleDir, "panic-bundle-dir", options.PanicBundleDir, "directory in which to write a diagnostic file for each recovered panic, up to 20 of them; empty means not to write them")
+	fs.StringVar(&options.ConfigFile, "config", options.ConfigFile, "path of a KubeStellarConfiguration file; flags given on the command line take precedence over it")
+	options.ClientLimits.AddFlags(fs)
+	options.MetricsLabels.AddFlags(fs)
+}
 
+// ApplyConfig takes the settings from the given configuration file that
+// were not given on the command line.
+func (options *Options) ApplyConfig(fs *pflag.FlagSet, cfg *componentconfig.Configuration) {
+	wrc := cfg.WhereResolver
+	if wrc.Concurrency != nil {
+		componentconfig.Override(fs, "concurrency", func() { options.Concurrency = *wrc.Concurrency })
+	}
+	if wrc.OrphanGCPeriod != nil {
+		componentconfig.Override(fs, "orphan-gc-period", func() { options.OrphanGCPeriod = wrc.OrphanGCPeriod.Duration })
+	}
+	if wrc.OrphanGCDryRun != nil {
+		componentconfig.Override(fs, "orphan-gc-dry-run", func() { options.OrphanGCDryRun = *wrc.OrphanGCDryRun })
+	}
+	options.ClientLimits.ApplyConfig(fs, cfg.TransportFor(wrc.Transport))
+	options.MetricsLabels.ApplyConfig(fs, cfg.Metrics)
 }
 
 func (options *Options) Complete() error {
@@ -67,5 +136,14 @@ func (options *Options) Complete() error {
 }
 
 func (options *Options) Validate() error {
-	return nil
+	if options.OrphanGCPeriod < 0 {
+		return errors.New("--orphan-gc-period must not be negative")
+	}
+	if options.Concurrency < 1 {
+		return errors.New("--concurrency must be positive")
+	}
+	if err := options.ClientLimits.Validate(); err != nil {
+		return err
+	}
+	return options.MetricsLabels.Validate()
 }
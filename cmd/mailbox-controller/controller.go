@@ -27,6 +27,7 @@ import (
 
 	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -34,17 +35,22 @@ import (
 	"k8s.io/klog/v2"
 
 	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
 	edgev2alpha1informers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions/edge/v2alpha1"
 	edgev2alpha1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/clientlimits"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	spaceclientfactory "github.com/kubestellar/kubestellar/pkg/spaceclient"
 	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
 	spaceclientset "github.com/kubestellar/kubestellar/space-framework/pkg/client/clientset/versioned"
 	spacev1alpha1informers "github.com/kubestellar/kubestellar/space-framework/pkg/client/informers/externalversions/space/v1alpha1"
 	spacev1a1listers "github.com/kubestellar/kubestellar/space-framework/pkg/client/listers/space/v1alpha1"
+	spaceclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
 )
 
-const mbsNameSep = "-mb-"
-
 // This identifies an index in the SyncTarget informer
 const mbsNameIndexKey = "mbsName"
 
@@ -57,13 +63,23 @@ type mbCtl struct {
 	syncTargetInformer    cache.SharedIndexInformer
 	synctargetLister      edgev2alpha1listers.SyncTargetLister
 	syncTargetIndexer     cache.Indexer
+	spsInformer           cache.SharedIndexInformer
+	spsIndexer            cache.Indexer
 	spaceInformer         cache.SharedIndexInformer
 	spaceLister           spacev1a1listers.SpaceNamespaceLister
 	spaceManagementClient spaceclientset.Clientset
 	spaceProvider         string
 	spaceProviderNs       string
 	kbSpaceRelation       kbuser.KubeBindSpaceRelation
+	spaceClient           spaceclient.KubestellarSpaceInterface
+	spaceClients          *spaceclientfactory.Factory
+	edgeClient            edgeclientset.Interface
+	kcsKubeClient         kubernetes.Interface
+	finalStatusNamespace  string
 	queue                 workqueue.RateLimitingInterface
+
+	// watchdog, if not nil, tracks each sync
+	watchdog *probes.Watchdog
 }
 
 type refSyncTarget string
@@ -75,13 +91,20 @@ var errNoSpaceId = errors.New("failed to retrive spaceID")
 // SyncTarget objects (not limited to one cluster).
 func newMailboxController(ctx context.Context,
 	syncTargetPreInformer edgev2alpha1informers.SyncTargetInformer,
+	spsPreInformer edgev2alpha1informers.SinglePlacementSliceInformer,
 	spacePreInformer spacev1alpha1informers.SpaceInformer,
 	spaceManagementClient *spaceclientset.Clientset,
 	spaceProvider string,
 	spaceProviderNs string,
 	kbSpaceRelation kbuser.KubeBindSpaceRelation,
+	spaceClient spaceclient.KubestellarSpaceInterface,
+	edgeClient edgeclientset.Interface,
+	kcsKubeClient kubernetes.Interface,
+	finalStatusNamespace string,
+	clientLimits *clientlimits.Options,
 ) *mbCtl {
 	syncTargetInformer := syncTargetPreInformer.Informer()
+	spsInformer := spsPreInformer.Informer()
 	spacesInformer := spacePreInformer.Informer()
 
 	ctl := &mbCtl{
@@ -89,18 +112,28 @@ func newMailboxController(ctx context.Context,
 		syncTargetInformer:    syncTargetInformer,
 		synctargetLister:      syncTargetPreInformer.Lister(),
 		syncTargetIndexer:     syncTargetInformer.GetIndexer(),
+		spsInformer:           spsInformer,
+		spsIndexer:            spsInformer.GetIndexer(),
 		spaceInformer:         spacesInformer,
 		spaceLister:           spacePreInformer.Lister().Spaces(spaceProviderNs),
 		spaceManagementClient: *spaceManagementClient,
 		spaceProvider:         spaceProvider,
 		spaceProviderNs:       spaceProviderNs,
 		kbSpaceRelation:       kbSpaceRelation,
+		spaceClient:           spaceClient,
+		spaceClients:          spaceclientfactory.NewFactory(spaceClient, spaceclientfactory.Options{UserAgent: "mailbox-controller", Adjust: clientLimits.Apply}),
+		edgeClient:            edgeClient,
+		kcsKubeClient:         kcsKubeClient,
+		finalStatusNamespace:  finalStatusNamespace,
 		queue:                 workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "mailbox-controller"),
 	}
 	syncTargetInformer.AddIndexers(cache.Indexers{mbsNameIndexKey: ctl.mbsNameOfObj})
+	spsInformer.AddIndexers(cache.Indexers{destinationMbsNameIndexKey: ctl.destinationMbsNames})
 
-	syncTargetInformer.AddEventHandler(ctl)
-	spacesInformer.AddEventHandler(ctl)
+	handler := recovery.Handler(klog.FromContext(ctx), "mailbox-controller", ctl)
+	syncTargetInformer.AddEventHandler(handler)
+	spacesInformer.AddEventHandler(handler)
+	spsInformer.AddEventHandler(handler)
 	return ctl
 }
 
@@ -111,7 +144,7 @@ func (ctl *mbCtl) Run(concurrency int) {
 	ctx := ctl.context
 	logger := klog.FromContext(ctx)
 	doneCh := ctx.Done()
-	if !cache.WaitForNamedCacheSync("mailbox-controller", doneCh, ctl.syncTargetInformer.HasSynced, ctl.spaceInformer.HasSynced) {
+	if !cache.WaitForNamedCacheSync("mailbox-controller", doneCh, ctl.syncTargetInformer.HasSynced, ctl.spsInformer.HasSynced, ctl.spaceInformer.HasSynced) {
 		logger.Error(nil, "Informer syncs not achieved")
 		return
 	}
@@ -131,6 +164,10 @@ func (ctl *mbCtl) OnAdd(obj any) {
 func (ctl *mbCtl) OnUpdate(oldObj, newObj any) {
 	logger := klog.FromContext(ctl.context)
 	logger.V(4).Info("Observed update", "oldObj", oldObj, "newObj", newObj)
+	if oldSPS, ok := oldObj.(*edgev2alpha1.SinglePlacementSlice); ok {
+		// Destinations that were removed may now be prunable
+		ctl.enqueue(oldSPS)
+	}
 	if newObj != nil {
 		ctl.enqueue(newObj)
 	} else if oldObj != nil {
@@ -152,6 +189,10 @@ func (ctl *mbCtl) enqueue(obj any) {
 	case *edgev2alpha1.SyncTarget:
 		logger.V(4).Info("Enqueuing SyncTarget reference", "syncTargetName", typed.Name)
 		ctl.queue.Add(refSyncTarget(typed.Name))
+	case *edgev2alpha1.SinglePlacementSlice:
+		ctl.enqueueOrphanDestinations(typed)
+	case cache.DeletedFinalStateUnknown:
+		ctl.enqueue(typed.Obj)
 	default:
 		logger.Error(nil, "Notified of object of unexpected type", "object", obj, "type", fmt.Sprintf("%T", obj))
 	}
@@ -181,9 +222,13 @@ func (ctl *mbCtl) syncLoop(ctx context.Context, worker int) {
 
 func (ctl *mbCtl) sync1(ctx context.Context, ref any) {
 	defer ctl.queue.Done(ref)
+	defer ctl.watchdog.Track()()
 	logger := klog.FromContext(ctx)
 	logger.V(4).Info("Dequeued reference", "ref", ref)
-	retry := ctl.sync(ctx, ref)
+	var retry bool
+	if recovery.Guard(logger, "mailbox-controller", ref, func() { retry = ctl.sync(ctx, ref) }) {
+		retry = true
+	}
 	if retry {
 		ctl.queue.AddRateLimited(ref)
 	} else {
@@ -218,8 +263,7 @@ func (ctl *mbCtl) sync(ctx context.Context, refany any) bool {
 		logger.Error(nil, "Sync expected a string", "ref", refany, "type", fmt.Sprintf("%T", refany))
 		return false
 	}
-	parts := strings.Split(mbsName, mbsNameSep)
-	if len(parts) != 2 {
+	if !naming.IsMailboxSpaceName(mbsName) {
 		logger.V(3).Info("Ignoring non-mailbox space name", "spaceName", mbsName)
 		return false
 	}
@@ -246,7 +290,25 @@ func (ctl *mbCtl) sync(ctx context.Context, refany any) bool {
 			logger.V(3).Info("Both SyncTarget and Mailbox space are absent or deleting, nothing to do", "mbsName", mbsName)
 			return false
 		}
-		err := ctl.spaceManagementClient.SpaceV1alpha1().Spaces(ctl.spaceProviderNs).Delete(ctx, mbsName, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &space.UID}})
+		blocker, err := ctl.pruneBlocker(space)
+		if err != nil {
+			logger.Error(err, "Failed to check whether mailbox space can be pruned", "mbsName", mbsName)
+			return true
+		}
+		if blocker != "" {
+			logger.V(3).Info("Not deleting unwanted space yet", "mbsName", mbsName, "reason", blocker)
+			if space.Annotations[PruneProtectionAnnotationKey] != "true" {
+				ctl.queue.AddAfter(mbsName, pruneRecheckPeriod)
+			}
+			return false
+		}
+		if ctl.finalStatusNamespace != "" {
+			if err := ctl.snapshotFinalStatus(ctx, space); err != nil {
+				logger.Error(err, "Failed to record final status of unwanted space", "mbsName", mbsName)
+				return true
+			}
+		}
+		err = ctl.spaceManagementClient.SpaceV1alpha1().Spaces(ctl.spaceProviderNs).Delete(ctx, mbsName, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &space.UID}})
 		if err == nil || k8sapierrors.IsNotFound(err) {
 			logger.V(2).Info("Deleted unwanted space", "mbsName", mbsName)
 			return false
@@ -293,8 +355,10 @@ func (ctl *mbCtl) sync(ctx context.Context, refany any) bool {
 		return true
 	}
 	logger.V(3).Info("Both SyncTarget and Mailbox space exist and are not being deleted, now check on the binding to edge", "mbsName", mbsName)
-	return ctl.ensureBinding(ctx, space.Name)
-
+	if ctl.ensureBinding(ctx, space.Name) {
+		return true
+	}
+	return ctl.syncClusterIdentity(ctx, syncTarget, space.Name)
 }
 
 func (ctl *mbCtl) ensureBinding(ctx context.Context, spacename string) bool {
@@ -383,7 +447,7 @@ func (ctl *mbCtl) mbsNameOfSynctarget(st *edgev2alpha1.SyncTarget) (string, erro
 		return "", errNoSpaceId
 	}
 	// Use consumer spaceID and provider st.UID
-	return spaceID + mbsNameSep + string(st.UID), nil
+	return naming.MailboxSpaceName(spaceID, string(st.UID)), nil
 }
 
 func (ctl *mbCtl) mbsNameOfObj(obj any) ([]string, error) {
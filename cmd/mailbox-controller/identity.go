@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/placement"
+)
+
+// clusterIdentityPollPeriod is how often the cluster identity and facts
+// that the syncer reports in its SyncerConfig are copied to the SyncTarget.
+// Polling is needed because changes in a mailbox space do not notify
+// this controller.
+const clusterIdentityPollPeriod = 5 * time.Minute
+
+// syncClusterIdentity copies the cluster ID and ClusterSet name that the
+// syncer reported (see package about), and the facts that EdgePlacement
+// requirements are checked against (Kubernetes version, node architectures
+// and CRDs), into the status of the SyncTarget. Facts that the syncer has
+// not reported are left as they are. Returns whether to retry.
+func (ctl *mbCtl) syncClusterIdentity(ctx context.Context, syncTarget *edgev2alpha1.SyncTarget, mbsName string) bool {
+	logger := klog.FromContext(ctx).WithValues("mbsName", mbsName, "syncTarget", syncTarget.Name)
+	defer ctl.queue.AddAfter(mbsName, clusterIdentityPollPeriod)
+	clients, err := ctl.spaceClients.For(mbsName, ctl.spaceProviderNs)
+	if err != nil {
+		logger.Error(err, "Failed to get clients for mailbox space")
+		return true
+	}
+	mbsClient := clients.Edge
+	syncerConfig, err := mbsClient.EdgeV2alpha1().SyncerConfigs().Get(ctx, placement.SyncerConfigName, metav1.GetOptions{})
+	if k8sapierrors.IsNotFound(err) {
+		logger.V(4).Info("No SyncerConfig in mailbox space yet")
+		return false
+	}
+	if err != nil {
+		if k8sapierrors.IsUnauthorized(err) {
+			ctl.spaceClients.Invalidate(mbsName, ctl.spaceProviderNs)
+		}
+		logger.Error(err, "Failed to get SyncerConfig from mailbox space")
+		return true
+	}
+	reported := syncerConfig.Status
+	syncTarget = syncTarget.DeepCopy()
+	var changed bool
+	if reported.ClusterID != "" && (reported.ClusterID != syncTarget.Status.ClusterID || reported.ClusterSet != syncTarget.Status.ClusterSet) {
+		syncTarget.Status.ClusterID, syncTarget.Status.ClusterSet = reported.ClusterID, reported.ClusterSet
+		changed = true
+	}
+	if reported.KubernetesVersion != "" && reported.KubernetesVersion != syncTarget.Status.KubernetesVersion {
+		syncTarget.Status.KubernetesVersion = reported.KubernetesVersion
+		changed = true
+	}
+	if len(reported.Architectures) > 0 && !apiequality.Semantic.DeepEqual(reported.Architectures, syncTarget.Status.Architectures) {
+		syncTarget.Status.Architectures = reported.Architectures
+		changed = true
+	}
+	if len(reported.CRDs) > 0 && !apiequality.Semantic.DeepEqual(reported.CRDs, syncTarget.Status.CRDs) {
+		syncTarget.Status.CRDs = reported.CRDs
+		changed = true
+	}
+	if !changed {
+		return false
+	}
+	if _, err := ctl.edgeClient.EdgeV2alpha1().SyncTargets().UpdateStatus(ctx, syncTarget, metav1.UpdateOptions{FieldManager: "mailbox-controller"}); err != nil {
+		logger.Error(err, "Failed to update cluster identity and facts of SyncTarget")
+		return true
+	}
+	logger.V(2).Info("Updated cluster identity and facts of SyncTarget", "clusterID", reported.ClusterID, "clusterSet", reported.ClusterSet,
+		"kubernetesVersion", reported.KubernetesVersion, "architectures", reported.Architectures, "crds", len(reported.CRDs))
+	return false
+}
@@ -32,12 +32,14 @@ import (
 
 	"github.com/spf13/pflag"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/apiserver/pkg/server/mux"
 	"k8s.io/apiserver/pkg/server/routes"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
 	cache "k8s.io/client-go/tools/cache"
-	"k8s.io/component-base/metrics/legacyregistry"
 	_ "k8s.io/component-base/metrics/prometheus/clientgo"
 	"k8s.io/klog/v2"
 	utilflag "k8s.io/kubernetes/pkg/util/flag"
@@ -45,7 +47,13 @@ import (
 	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
 	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
 	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
+	"github.com/kubestellar/kubestellar/pkg/clientlimits"
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/metricslabels"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
 	spaceclientset "github.com/kubestellar/kubestellar/space-framework/pkg/client/clientset/versioned"
 	spaceinformers "github.com/kubestellar/kubestellar/space-framework/pkg/client/informers/externalversions"
 	spaceclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
@@ -59,15 +67,28 @@ func main() {
 	kcsName := "espw"
 	spaceProvider := "default"
 	externalAccess := false
+	finalStatusNamespace := ""
+	watchdogTimeout := probes.DefaultWatchdogTimeout
+	panicBundleDir := ""
+	configFile := ""
+	clientLimits := clientlimits.NewOptions("mailbox-controller")
+	metricsLabels := metricslabels.NewOptions()
 	fs := pflag.NewFlagSet("mailbox-controller", pflag.ExitOnError)
 	klog.InitFlags(flag.CommandLine)
 	fs.AddGoFlagSet(flag.CommandLine)
-	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve /metrics and /debug/pprof/")
+	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve /metrics, /readyz, /healthz and /debug/pprof/")
+	fs.DurationVar(&watchdogTimeout, "watchdog-timeout", watchdogTimeout, "how long one sync may run before /healthz fails; zero disables this test")
+	fs.StringVar(&panicBundleDir, "panic-bundle-dir", panicBundleDir, "directory in which to write a diagnostic file for each recovered panic, up to 20 of them; empty means not to write them")
 
 	fs.IntVar(&concurrency, "concurrency", concurrency, "number of syncs to run in parallel")
 	fs.StringVar(&kcsName, "core-space", kcsName, "the name of the KubeStellar core space")
 	fs.StringVar(&spaceProvider, "space-provider", spaceProvider, "the name of the KubeStellar space provider")
+	fs.StringVar(&finalStatusNamespace, "final-status-namespace", finalStatusNamespace, "when not empty, the namespace in the KubeStellar core space where the final status of each pruned mailbox space is recorded")
 	fs.BoolVar(&externalAccess, "external-access", externalAccess, "the access to the spaces. True when the space-provider is hosted in a space while the controller is running outside of that space")
+	fs.StringVar(&configFile, "config", configFile, "path of a KubeStellarConfiguration file; flags given on the command line take precedence over it")
+
+	clientLimits.AddFlags(fs)
+	metricsLabels.AddFlags(fs)
 
 	spaceMgtOpts := clientopts.NewClientOpts("space-mgt", "access to the space reference space")
 	spaceMgtOpts.AddFlags(fs)
@@ -77,13 +98,46 @@ func main() {
 	ctx := context.Background()
 	logger := klog.Background()
 	ctx = klog.NewContext(ctx, logger)
+	recovery.EnableBundles(panicBundleDir, recovery.DefaultMaxBundles)
+
+	if configFile != "" {
+		cfg, cfgReloader, err := componentconfig.LoadForReload(logger.WithName("config"), configFile)
+		if err != nil {
+			logger.Error(err, "Failed to load configuration file", "path", configFile)
+			os.Exit(2)
+		}
+		if mcc := cfg.MailboxController; mcc.Concurrency != nil {
+			componentconfig.Override(fs, "concurrency", func() { concurrency = *mcc.Concurrency })
+		}
+		clientLimits.ApplyConfig(fs, cfg.TransportFor(cfg.MailboxController.Transport))
+		metricsLabels.ApplyConfig(fs, cfg.Metrics)
+		if !fs.Changed("v") {
+			if err := cfg.ApplyVerbosity(); err != nil {
+				logger.Error(err, "Failed to apply verbosity")
+				os.Exit(2)
+			}
+		}
+		cfgReloader.OnChange(func(cfg *componentconfig.Configuration) {
+			if cfg.Logging.Verbosity != nil && !fs.Changed("v") {
+				if err := cfg.ApplyVerbosity(); err != nil {
+					logger.Error(err, "Failed to apply verbosity")
+				}
+			}
+		})
+		go cfgReloader.Run(ctx)
+	}
 
 	fs.VisitAll(func(flg *pflag.Flag) {
 		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
 	})
 
+	if err := metricsLabels.Validate(); err != nil {
+		logger.Error(err, "Invalid metrics label policy")
+		os.Exit(2)
+	}
+
 	mymux := mux.NewPathRecorderMux("mailbox-controller")
-	mymux.Handle("/metrics", legacyregistry.Handler())
+	mymux.Handle("/metrics", metricsLabels.Handler())
 	routes.Profiling{}.Install(mymux)
 	go func() {
 		err := http.ListenAndServe(serverBindAddress, mymux)
@@ -93,6 +147,11 @@ func main() {
 		}
 	}()
 
+	if err := clientLimits.Validate(); err != nil {
+		logger.Error(err, "Invalid client limits")
+		os.Exit(2)
+	}
+
 	// create space-aware client
 	spaceManagementConfig, err := spaceMgtOpts.ToRESTConfig()
 	if err != nil {
@@ -111,6 +170,8 @@ func main() {
 		logger.Error(err, "Failed to construct space config", "spacename", kcsName)
 		os.Exit(15)
 	}
+	kcsRestConfig = rest.CopyConfig(kcsRestConfig)
+	clientLimits.Apply(kcsRestConfig)
 
 	edgeClientset, err := edgeclientset.NewForConfig(kcsRestConfig)
 	if err != nil {
@@ -120,11 +181,22 @@ func main() {
 	kcsRestConfig.UserAgent = "mailbox-controller"
 	edgeSharedInformerFactory := edgeinformers.NewSharedScopedInformerFactoryWithOptions(edgeClientset, resyncPeriod)
 	syncTargetPreInformer := edgeSharedInformerFactory.Edge().V2alpha1().SyncTargets()
+	spsPreInformer := edgeSharedInformerFactory.Edge().V2alpha1().SinglePlacementSlices()
 
 	managementClientset, err := spaceclientset.NewForConfig(spaceManagementConfig)
 	if err != nil {
 		logger.Error(err, "Failed to create clientset for space management")
 	}
+	providerDesc, err := managementClientset.SpaceV1alpha1().SpaceProviderDescs().Get(ctx, spaceProvider, metav1.GetOptions{})
+	if err != nil {
+		logger.Error(err, "Failed to get space provider", "spaceProvider", spaceProvider)
+		os.Exit(26)
+	}
+	if providerDesc.Spec.ProviderType == spacev1alpha1.NamespaceProviderType {
+		// The edge APIs are cluster-scoped, and a namespace space can neither reach them nor keep them apart from other spaces.
+		logger.Error(nil, "Mailbox spaces need the cluster-scoped edge APIs, which namespace spaces do not serve", "spaceProvider", spaceProvider)
+		os.Exit(27)
+	}
 
 	spaceInformerFactory := spaceinformers.NewSharedInformerFactory(managementClientset, resyncPeriod)
 	spacePreInformer := spaceInformerFactory.Space().V1alpha1().Spaces()
@@ -139,9 +211,15 @@ func main() {
 	doneCh := ctx.Done()
 	cache.WaitForCacheSync(doneCh, kbSpaceRelation.InformerSynced)
 
-	ctl := newMailboxController(ctx, syncTargetPreInformer, spacePreInformer,
+	ctl := newMailboxController(ctx, syncTargetPreInformer, spsPreInformer, spacePreInformer,
 		managementClientset, spaceProvider, spaceProviderNs, kbSpaceRelation,
+		spaceclient, edgeClientset, kubeClient, finalStatusNamespace, clientLimits,
 	)
+	ctl.watchdog = probes.NewWatchdog("sync-watchdog", watchdogTimeout)
+	probes.Install(mymux,
+		[]healthz.HealthChecker{probes.InformersSynced("informers", kbSpaceRelation.InformerSynced,
+			syncTargetPreInformer.Informer().HasSynced, spsPreInformer.Informer().HasSynced, spacePreInformer.Informer().HasSynced)},
+		[]healthz.HealthChecker{ctl.watchdog})
 
 	edgeSharedInformerFactory.Start(doneCh)
 
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	"github.com/kubestellar/kubestellar/pkg/placement"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+)
+
+// PruneProtectionAnnotationKey is the annotation that, with the value "true"
+// on a mailbox Space, keeps this controller from deleting the Space after
+// its SyncTarget is gone.
+const PruneProtectionAnnotationKey = "edge.kubestellar.io/prune-protection"
+
+// MailboxSpaceLabelKey labels a final status snapshot with the name
+// of the mailbox space that it came from.
+const MailboxSpaceLabelKey = "edge.kubestellar.io/mailbox-space"
+
+// FinalStatusConfigMapPrefix starts the name of a final status snapshot.
+const FinalStatusConfigMapPrefix = "mailbox-final-"
+
+// This identifies an index in the SinglePlacementSlice informer,
+// from mailbox space name to the slices that have it as a destination.
+const destinationMbsNameIndexKey = "destinationMbsName"
+
+// pruneRecheckPeriod is how soon to look again at a mailbox space
+// whose deletion was held up by references to it.
+const pruneRecheckPeriod = 30 * time.Second
+
+func (ctl *mbCtl) destinationMbsNames(obj any) ([]string, error) {
+	sps, ok := obj.(*edgev2alpha1.SinglePlacementSlice)
+	if !ok {
+		return nil, fmt.Errorf("expected a SinglePlacementSlice but got %T", obj)
+	}
+	ans := make([]string, 0, len(sps.Destinations))
+	for _, dest := range sps.Destinations {
+		ans = append(ans, placement.SPMailboxWorkspaceName(dest))
+	}
+	return ans, nil
+}
+
+// enqueueOrphanDestinations enqueues the destinations of the given slice
+// whose SyncTarget is gone, since a change in the slice may unblock
+// the pruning of their mailbox spaces.
+func (ctl *mbCtl) enqueueOrphanDestinations(sps *edgev2alpha1.SinglePlacementSlice) {
+	logger := klog.FromContext(ctl.context)
+	mbsNames, _ := ctl.destinationMbsNames(sps)
+	for _, mbsName := range mbsNames {
+		byIndex, err := ctl.syncTargetIndexer.ByIndex(mbsNameIndexKey, mbsName)
+		if err != nil || len(byIndex) > 0 {
+			continue
+		}
+		logger.V(4).Info("Enqueuing mailbox space due to SinglePlacementSlice", "mbsName", mbsName, "sps", sps.Name)
+		ctl.queue.Add(mbsName)
+	}
+}
+
+// pruneBlocker tells why the given mailbox space, whose SyncTarget is gone,
+// must not be deleted yet; the empty string means that it can be deleted.
+func (ctl *mbCtl) pruneBlocker(space *spacev1alpha1.Space) (string, error) {
+	if space.Annotations[PruneProtectionAnnotationKey] == "true" {
+		return "protected by annotation " + PruneProtectionAnnotationKey, nil
+	}
+	referrers, err := ctl.spsIndexer.ByIndex(destinationMbsNameIndexKey, space.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(referrers) > 0 {
+		sps := referrers[0].(*edgev2alpha1.SinglePlacementSlice)
+		return fmt.Sprintf("still a destination of %d SinglePlacementSlice(s), such as %q", len(referrers), sps.Name), nil
+	}
+	return "", nil
+}
+
+// snapshotFinalStatus records the SyncerConfig of the given mailbox space,
+// including the status reported by the syncer, in a ConfigMap in the
+// KubeStellar core space. A space that is not Ready has nothing to record.
+func (ctl *mbCtl) snapshotFinalStatus(ctx context.Context, space *spacev1alpha1.Space) error {
+	logger := klog.FromContext(ctx).WithValues("mbsName", space.Name)
+	if space.Status.Phase != spacev1alpha1.SpacePhaseReady {
+		logger.V(3).Info("Mailbox space is not ready, no final status to record")
+		return nil
+	}
+	clients, err := ctl.spaceClients.For(space.Name, ctl.spaceProviderNs)
+	if err != nil {
+		return err
+	}
+	mbsClient := clients.Edge
+	syncerConfig, err := mbsClient.EdgeV2alpha1().SyncerConfigs().Get(ctx, placement.SyncerConfigName, metav1.GetOptions{})
+	if k8sapierrors.IsNotFound(err) {
+		logger.V(3).Info("No SyncerConfig in mailbox space, no final status to record")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	syncerConfigJSON, err := json.Marshal(syncerConfig)
+	if err != nil {
+		return err
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        naming.Bounded(FinalStatusConfigMapPrefix+space.Name, naming.MaxNameLength),
+			Namespace:   ctl.finalStatusNamespace,
+			Labels:      map[string]string{MailboxSpaceLabelKey: naming.LabelValue(space.Name)},
+			Annotations: map[string]string{SyncTargetNameAnnotationKey: space.Annotations[SyncTargetNameAnnotationKey]},
+		},
+		Data: map[string]string{"syncerconfig.json": string(syncerConfigJSON)},
+	}
+	configMaps := ctl.kcsKubeClient.CoreV1().ConfigMaps(ctl.finalStatusNamespace)
+	_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{FieldManager: "mailbox-controller"})
+	if k8sapierrors.IsAlreadyExists(err) {
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{FieldManager: "mailbox-controller"})
+	}
+	if err == nil {
+		logger.V(2).Info("Recorded final status of mailbox space", "configMap", configMap.Name)
+	}
+	return err
+}
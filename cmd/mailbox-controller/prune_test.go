@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/placement"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+)
+
+func newPruneTestController(t *testing.T, slices ...*edgev2alpha1.SinglePlacementSlice) *mbCtl {
+	ctl := &mbCtl{context: context.Background()}
+	ctl.spsIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{destinationMbsNameIndexKey: ctl.destinationMbsNames})
+	for _, sps := range slices {
+		if err := ctl.spsIndexer.Add(sps); err != nil {
+			t.Fatalf("failed to add %s to indexer: %v", sps.Name, err)
+		}
+	}
+	return ctl
+}
+
+func TestDestinationMbsNamesIndex(t *testing.T) {
+	dest1 := edgev2alpha1.SinglePlacement{Cluster: "inv1", LocationName: "loc1", SyncTargetName: "st1", SyncTargetUID: "uid1"}
+	dest2 := edgev2alpha1.SinglePlacement{Cluster: "inv1", LocationName: "loc2", SyncTargetName: "st2", SyncTargetUID: "uid2"}
+	ctl := newPruneTestController(t,
+		&edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: "ep1"}, Destinations: []edgev2alpha1.SinglePlacement{dest1, dest2}},
+		&edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: "ep2"}, Destinations: []edgev2alpha1.SinglePlacement{dest2}},
+	)
+	for _, tc := range []struct {
+		dest     edgev2alpha1.SinglePlacement
+		expected int
+	}{{dest1, 1}, {dest2, 2}} {
+		mbsName := placement.SPMailboxWorkspaceName(tc.dest)
+		referrers, err := ctl.spsIndexer.ByIndex(destinationMbsNameIndexKey, mbsName)
+		if err != nil {
+			t.Fatalf("ByIndex(%q) failed: %v", mbsName, err)
+		}
+		if len(referrers) != tc.expected {
+			t.Errorf("expected %d slices referring to %q, got %d", tc.expected, mbsName, len(referrers))
+		}
+	}
+	if _, err := ctl.destinationMbsNames(&edgev2alpha1.SyncTarget{}); err == nil {
+		t.Error("expected an error for an object that is not a SinglePlacementSlice")
+	}
+}
+
+func TestPruneBlocker(t *testing.T) {
+	dest := edgev2alpha1.SinglePlacement{Cluster: "inv1", LocationName: "loc1", SyncTargetName: "st1", SyncTargetUID: "uid1"}
+	referenced := placement.SPMailboxWorkspaceName(dest)
+	ctl := newPruneTestController(t,
+		&edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: "ep1"}, Destinations: []edgev2alpha1.SinglePlacement{dest}})
+	for _, tc := range []struct {
+		name        string
+		space       *spacev1alpha1.Space
+		blockedWith string
+	}{
+		{name: "unreferenced", space: &spacev1alpha1.Space{ObjectMeta: metav1.ObjectMeta{Name: "inv1-mb-uid9"}}},
+		{name: "referenced", space: &spacev1alpha1.Space{ObjectMeta: metav1.ObjectMeta{Name: referenced}},
+			blockedWith: `such as "ep1"`},
+		{name: "protected", space: &spacev1alpha1.Space{ObjectMeta: metav1.ObjectMeta{Name: "inv1-mb-uid9",
+			Annotations: map[string]string{PruneProtectionAnnotationKey: "true"}}},
+			blockedWith: PruneProtectionAnnotationKey},
+		{name: "protection-not-true", space: &spacev1alpha1.Space{ObjectMeta: metav1.ObjectMeta{Name: "inv1-mb-uid9",
+			Annotations: map[string]string{PruneProtectionAnnotationKey: "false"}}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			blocker, err := ctl.pruneBlocker(tc.space)
+			if err != nil {
+				t.Fatalf("pruneBlocker failed: %v", err)
+			}
+			if tc.blockedWith == "" && blocker != "" {
+				t.Errorf("expected no blocker, got %q", blocker)
+			} else if tc.blockedWith != "" && !strings.Contains(blocker, tc.blockedWith) {
+				t.Errorf("expected blocker mentioning %q, got %q", tc.blockedWith, blocker)
+			}
+		})
+	}
+}
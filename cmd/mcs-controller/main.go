@@ -0,0 +1,199 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+//
+// Import of k8s.io/component-base/metrics/prometheus/clientgo
+// makes the k8s client library produce Prometheus metrics.
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	machschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/server/mux"
+	"k8s.io/apiserver/pkg/server/routes"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	upstreamcache "k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics/legacyregistry"
+	_ "k8s.io/component-base/metrics/prometheus/clientgo"
+	"k8s.io/klog/v2"
+	utilflag "k8s.io/kubernetes/pkg/util/flag"
+
+	kcpdynamic "github.com/kcp-dev/client-go/dynamic"
+	kcpscopedclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/logicalcluster/v3"
+
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/mailboxwatch"
+	"github.com/kubestellar/kubestellar/pkg/mcs"
+)
+
+const mainName = "mcs-controller"
+
+// syncTargetNameAnnotationKey is the annotation that the mailbox
+// controller puts on a mailbox space to identify its SyncTarget.
+const syncTargetNameAnnotationKey = "edge.kubestellar.io/sync-target-name"
+
+func main() {
+	serverBindAddress := ":10207"
+	concurrency := 4
+	hubNamespace := "kubestellar-mcs"
+	gatewayRef := ""
+	serviceSelector := ""
+	fs := pflag.NewFlagSet(mainName, pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve /metrics and /debug/pprof/")
+	fs.IntVar(&concurrency, "concurrency", concurrency, "number of syncs to run in parallel")
+	fs.StringVar(&hubNamespace, "hub-namespace", hubNamespace, "namespace in the hub cluster in which to maintain a Service and EndpointSlices for each exposed Service")
+	fs.StringVar(&gatewayRef, "gateway", gatewayRef, "NAMESPACE/NAME of a Gateway API Gateway in the hub cluster to attach an HTTPRoute to for each exposed Service; empty means not to")
+	fs.StringVar(&serviceSelector, "service-selector", serviceSelector, "label selector restricting the Services considered; empty means all")
+
+	parentClientOpts := clientopts.NewClientOpts("parent", "access to the parent of mailbox workspaces")
+	parentClientOpts.SetDefaultCurrentContext("root")
+	parentClientOpts.AddFlags(fs)
+
+	allClientOpts := clientopts.NewClientOpts("all", "access to the Services in all clusters")
+	allClientOpts.SetDefaultCurrentContext("system:admin")
+	allClientOpts.AddFlags(fs)
+
+	hubClientOpts := clientopts.NewClientOpts("hub", "access to the hub cluster that gets the aggregated Services")
+	hubClientOpts.AddFlags(fs)
+
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	selector, err := labels.Parse(serviceSelector)
+	if err != nil {
+		logger.Error(err, "Invalid --service-selector")
+		os.Exit(2)
+	}
+	var gateway *types.NamespacedName
+	if gatewayRef != "" {
+		namespace, name, found := strings.Cut(gatewayRef, "/")
+		if !found || namespace == "" || name == "" {
+			logger.Error(nil, "The --gateway must be NAMESPACE/NAME", "gateway", gatewayRef)
+			os.Exit(2)
+		}
+		gateway = &types.NamespacedName{Namespace: namespace, Name: name}
+	}
+
+	mymux := mux.NewPathRecorderMux(mainName)
+	mymux.Handle("/metrics", legacyregistry.Handler())
+	routes.Profiling{}.Install(mymux)
+	go func() {
+		err := http.ListenAndServe(serverBindAddress, mymux)
+		if err != nil {
+			logger.Error(err, "Failure in web serving")
+			panic(err)
+		}
+	}()
+
+	parentClientConfig, err := parentClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make parent client config")
+		os.Exit(3)
+	}
+	parentClientConfig.UserAgent = mainName
+	parentClient := kcpscopedclient.NewForConfigOrDie(parentClientConfig)
+
+	allClientConfig, err := allClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make all-cluster client config")
+		os.Exit(3)
+	}
+	allClientConfig.UserAgent = mainName
+	dynamicClusterClientset, err := kcpdynamic.NewForConfig(allClientConfig)
+	if err != nil {
+		logger.Error(err, "Failed to make all-cluster dynamic client")
+		os.Exit(5)
+	}
+
+	hubClientConfig, err := hubClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make hub client config")
+		os.Exit(3)
+	}
+	hubClientConfig.UserAgent = mainName
+	hub := &mcs.Hub{Client: kubernetes.NewForConfigOrDie(hubClientConfig), Namespace: hubNamespace, Gateway: gateway}
+	if gateway != nil {
+		hub.Dynamic = k8sdynamic.NewForConfigOrDie(hubClientConfig)
+	}
+	_, err = hub.Client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: hubNamespace}}, metav1.CreateOptions{FieldManager: "kubestellar"})
+	if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+		logger.Error(err, "Failed to ensure hub namespace", "namespace", hubNamespace)
+		os.Exit(10)
+	}
+
+	parentInformerFactory := kcpinformers.NewSharedScopedInformerFactory(parentClient, 0, "")
+	mbPreInformer := parentInformerFactory.Tenancy().V1alpha1().Workspaces()
+	mbLister := mbPreInformer.Lister()
+	destinationOf := func(cluster logicalcluster.Name) string {
+		workspaces, err := mbLister.List(labels.Everything())
+		if err == nil {
+			for _, ws := range workspaces {
+				if ws.Spec.Cluster != cluster.String() {
+					continue
+				}
+				if stName := ws.Annotations[syncTargetNameAnnotationKey]; stName != "" {
+					return stName
+				}
+				return ws.Name
+			}
+		}
+		return cluster.String()
+	}
+
+	gvr := corev1.SchemeGroupVersion.WithResource("services")
+	listGVK := machschema.GroupVersionKind{Version: "v1", Kind: "ServiceList"}
+	exampleObj := &unstructured.Unstructured{}
+	exampleObj.SetAPIVersion("v1")
+	exampleObj.SetKind("Service")
+	informer := mailboxwatch.NewSharedInformer[k8sdynamic.NamespaceableResourceInterface, *unstructured.UnstructuredList](ctx, listGVK, mbPreInformer, dynamicClusterClientset.Resource(gvr), exampleObj, 0, upstreamcache.Indexers{})
+
+	ctl := mcs.NewController(ctx, mcs.NewAggregator(), hub, selector, destinationOf)
+	informer.AddEventHandler(ctl)
+
+	parentInformerFactory.Start(ctx.Done())
+	go informer.Run(ctx.Done())
+	ctl.Run(concurrency, mbPreInformer.Informer().HasSynced, informer.HasSynced)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+// Import of k8s.io/client-go/plugin/pkg/client/auth ensures
+// that all in-tree Kubernetes client auth plugins
+// (e.g. Azure, GCP, OIDC, etc.)  are available.
+//
+// Import of k8s.io/component-base/metrics/prometheus/clientgo
+// makes the k8s client library produce Prometheus metrics.
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/server/mux"
+	"k8s.io/apiserver/pkg/server/routes"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/component-base/metrics/legacyregistry"
+	_ "k8s.io/component-base/metrics/prometheus/clientgo"
+	"k8s.io/klog/v2"
+	utilflag "k8s.io/kubernetes/pkg/util/flag"
+
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
+	"github.com/kubestellar/kubestellar/pkg/ocm"
+)
+
+const mainName = "ocm-placement-exporter"
+
+func main() {
+	serverBindAddress := ":10208"
+	concurrency := 2
+	hubNamespace := "default"
+	writeDecisions := true
+	fs := pflag.NewFlagSet(mainName, pflag.ExitOnError)
+	klog.InitFlags(flag.CommandLine)
+	fs.AddGoFlagSet(flag.CommandLine)
+	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve /metrics and /debug/pprof/")
+	fs.IntVar(&concurrency, "concurrency", concurrency, "number of syncs to run in parallel")
+	fs.StringVar(&hubNamespace, "hub-namespace", hubNamespace, "namespace in the OCM hub cluster in which to maintain the Placements")
+	fs.BoolVar(&writeDecisions, "write-decisions", writeDecisions, "whether to maintain PlacementDecisions that report the resolved destinations; turn off if the OCM placement controller makes the decisions")
+
+	wdsClientOpts := clientopts.NewClientOpts("wds", "access to the workload description space")
+	wdsClientOpts.AddFlags(fs)
+	hubClientOpts := clientopts.NewClientOpts("hub", "access to the OCM hub cluster")
+	hubClientOpts.AddFlags(fs)
+	fs.Parse(os.Args[1:])
+
+	ctx := context.Background()
+	logger := klog.Background()
+	ctx = klog.NewContext(ctx, logger)
+
+	fs.VisitAll(func(flg *pflag.Flag) {
+		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
+	})
+
+	mymux := mux.NewPathRecorderMux(mainName)
+	mymux.Handle("/metrics", legacyregistry.Handler())
+	routes.Profiling{}.Install(mymux)
+	go func() {
+		err := http.ListenAndServe(serverBindAddress, mymux)
+		if err != nil {
+			logger.Error(err, "Failure in web serving")
+			panic(err)
+		}
+	}()
+
+	wdsConfig, err := wdsClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make WDS client config")
+		os.Exit(2)
+	}
+	wdsConfig.UserAgent = mainName
+	wdsClient, err := edgeclientset.NewForConfig(wdsConfig)
+	if err != nil {
+		logger.Error(err, "Failed to make WDS client")
+		os.Exit(1)
+	}
+
+	hubConfig, err := hubClientOpts.ToRESTConfig()
+	if err != nil {
+		logger.Error(err, "Failed to make hub client config")
+		os.Exit(2)
+	}
+	hubConfig.UserAgent = mainName
+	hubClient := kubernetes.NewForConfigOrDie(hubConfig)
+	_, err = hubClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: hubNamespace}}, metav1.CreateOptions{FieldManager: "kubestellar"})
+	if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+		logger.Error(err, "Failed to ensure hub namespace", "namespace", hubNamespace)
+		os.Exit(10)
+	}
+
+	wdsInformerFactory := edgeinformers.NewSharedScopedInformerFactoryWithOptions(wdsClient, 0)
+	placementAccess := wdsInformerFactory.Edge().V2alpha1().EdgePlacements()
+	sliceAccess := wdsInformerFactory.Edge().V2alpha1().SinglePlacementSlices()
+
+	exporter := ocm.NewExporter(ctx, placementAccess.Lister(), sliceAccess.Lister(), k8sdynamic.NewForConfigOrDie(hubConfig), hubNamespace, writeDecisions)
+	placementAccess.Informer().AddEventHandler(exporter)
+	sliceAccess.Informer().AddEventHandler(exporter.SliceHandler())
+
+	wdsInformerFactory.Start(ctx.Done())
+	exporter.Run(concurrency, placementAccess.Informer().HasSynced, sliceAccess.Informer().HasSynced)
+}
@@ -22,6 +22,9 @@ package main
 //
 // Import of k8s.io/component-base/metrics/prometheus/clientgo
 // makes the k8s client library produce Prometheus metrics.
+//
+// Import of k8s.io/component-base/metrics/prometheus/workqueue
+// makes the work queues produce Prometheus metrics, including their depths.
 
 import (
 	"context"
@@ -32,6 +35,8 @@ import (
 
 	"github.com/spf13/pflag"
 
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apiserver/pkg/server/healthz"
 	"k8s.io/apiserver/pkg/server/mux"
 	"k8s.io/apiserver/pkg/server/routes"
 	"k8s.io/client-go/kubernetes"
@@ -39,15 +44,24 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/component-base/metrics/legacyregistry"
 	_ "k8s.io/component-base/metrics/prometheus/clientgo"
+	_ "k8s.io/component-base/metrics/prometheus/workqueue"
 	"k8s.io/klog/v2"
 	utilflag "k8s.io/kubernetes/pkg/util/flag"
 
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	"github.com/kubestellar/kubestellar/pkg/breakglass"
 	ksclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
 	emcinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
+	"github.com/kubestellar/kubestellar/pkg/clientlimits"
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+	"github.com/kubestellar/kubestellar/pkg/events"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/metricslabels"
 	"github.com/kubestellar/kubestellar/pkg/placement"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	spaceclientfactory "github.com/kubestellar/kubestellar/pkg/spaceclient"
 	spaceclientset "github.com/kubestellar/kubestellar/space-framework/pkg/client/clientset/versioned"
 	spaceinformers "github.com/kubestellar/kubestellar/space-framework/pkg/client/informers/externalversions"
 	spaceclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
@@ -90,14 +104,62 @@ func main() {
 	kcsName := "espw"
 	spaceProvider := "default"
 	externalAccess := false
+	bundleThreshold := 0
+	checkpointFile := ""
+	checkpointPeriod := 30 * time.Second
+	discoveryCacheDir := ""
+	deleteJournalFile := ""
+	softDeleteGrace := time.Duration(0)
+	ownershipGCPeriod := time.Duration(0)
+	shardCount := 1
+	shardIndex := -1
+	watchdogTimeout := probes.DefaultWatchdogTimeout
+	panicBundleDir := ""
+	writeLimits := placement.WriteAdmissionLimits{PerSpaceInFlight: 4, MaxWait: 5 * time.Second}
+	writeMemoryBudget := resource.QuantityValue{Quantity: resource.MustParse("256Mi")}
+	tenantUsagePeriod := time.Minute
+	revertManualEdits := false
+	maxBreakGlassWindow := breakglass.DefaultMaxWindow
+	immutableFieldPolicy := string(placement.ImmutableFieldFail)
+	immutableFieldPolicies := map[string]string{}
+	recreateBudget := 5
+	recreateBudgetPeriod := 10 * time.Minute
+	configFile := ""
+	clientLimits := clientlimits.NewOptions("placement-translator")
+	metricsLabels := metricslabels.NewOptions()
 	fs := pflag.NewFlagSet("placement-translator", pflag.ExitOnError)
 	klog.InitFlags(flag.CommandLine)
 	fs.AddGoFlagSet(flag.CommandLine)
-	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve /metrics and /debug/pprof/")
+	fs.Var(&utilflag.IPPortVar{Val: &serverBindAddress}, "server-bind-address", "The IP address with port at which to serve /metrics, /load, /apiwatch, /readyz, /healthz and /debug/pprof/")
+	fs.DurationVar(&watchdogTimeout, "watchdog-timeout", watchdogTimeout, "how long the workload projector may take on one queue item before /healthz fails; zero disables this test")
+	fs.StringVar(&panicBundleDir, "panic-bundle-dir", panicBundleDir, "directory in which to write a diagnostic file for each recovered panic, up to 20 of them; empty means not to write them")
 	fs.IntVar(&concurrency, "concurrency", concurrency, "number of syncs to run in parallel")
 	fs.StringVar(&kcsName, "core-space", kcsName, "the name of the KubeStellar core space")
 	fs.StringVar(&spaceProvider, "space-provider", spaceProvider, "the name of the KubeStellar space provider")
+	fs.IntVar(&bundleThreshold, "mailbox-bundle-threshold", bundleThreshold, "number of objects going to one destination above which they are packed into compressed bundles in the mailbox space; zero disables bundling")
+	fs.StringVar(&checkpointFile, "checkpoint-file", checkpointFile, "file in which to keep a checkpoint of what has been projected into mailbox spaces, so that a restart can skip re-diffing objects that have not changed; empty disables checkpointing")
+	fs.DurationVar(&checkpointPeriod, "checkpoint-period", checkpointPeriod, "how often to save the checkpoint")
+	fs.StringVar(&discoveryCacheDir, "discovery-cache-dir", discoveryCacheDir, "directory in which to keep the API resources discovered in each space, so that a restart can resume before discovering them again; empty disables this")
+	fs.StringVar(&deleteJournalFile, "delete-journal-file", deleteJournalFile, "file in which to record the deletions in mailbox spaces that are in progress, so that the ones interrupted by a restart are finished after it; each shard needs its own; empty disables the journal")
+	fs.DurationVar(&softDeleteGrace, "soft-delete-grace", softDeleteGrace, "how long a copy that a placement no longer selects stays in its mailbox space, and its edge cluster, scheduled for deletion and open to rescue; zero means to delete it right away")
+	fs.DurationVar(&ownershipGCPeriod, "ownership-gc-period", ownershipGCPeriod, "how often to sweep mailbox spaces for copies whose source object no longer exists; zero disables the sweep")
+	fs.IntVar(&shardCount, "shard-count", shardCount, "number of placement translators that split the mailbox spaces between them")
+	fs.IntVar(&shardIndex, "shard-index", shardIndex, "which of the shards this is, counting from zero; negative means to take it from the ordinal at the end of the hostname, as for a StatefulSet member")
+	fs.IntVar(&writeLimits.PerSpaceInFlight, "mailbox-write-concurrency", writeLimits.PerSpaceInFlight, "maximum number of writes in progress into one mailbox space; zero means no limit")
+	fs.Var(&writeMemoryBudget, "mailbox-write-memory-budget", "maximum total size of the objects being written into mailbox spaces; zero means no limit")
+	fs.DurationVar(&writeLimits.MaxWait, "mailbox-write-max-wait", writeLimits.MaxWait, "how long a write into a mailbox space may wait for admission before it is shed and retried later")
+	fs.DurationVar(&tenantUsagePeriod, "tenant-usage-period", tenantUsagePeriod, "how often to report the usage of each workload description space in metrics and in its TenantUsage object; zero disables the reports")
+	fs.BoolVar(&revertManualEdits, "revert-manual-edits", revertManualEdits, "undo the changes that others make to the copies in mailbox spaces")
+	fs.DurationVar(&maxBreakGlassWindow, "max-break-glass-window", maxBreakGlassWindow, "how long to leave alone a copy in a mailbox space under a break-glass override, at most; zero means to ignore such overrides")
+	fs.StringVar(&immutableFieldPolicy, "immutable-field-policy", immutableFieldPolicy, "what to do when an update of a copy in a mailbox space is rejected for changing an immutable field: Fail (keep retrying), Skip (leave the copy as it is) or Recreate (delete and create the copy); an EdgePlacement can override this with an annotation")
+	fs.StringToStringVar(&immutableFieldPolicies, "immutable-field-policy-for", immutableFieldPolicies, "comma-separated RESOURCE.GROUP=POLICY pairs (e.g., deployments.apps=Recreate,persistentvolumeclaims=Skip) that override --immutable-field-policy for particular resources")
+	fs.IntVar(&recreateBudget, "recreate-budget", recreateBudget, "maximum number of copies recreated in one mailbox space, because of immutable field changes, per --recreate-budget-period; zero means no limit")
+	fs.DurationVar(&recreateBudgetPeriod, "recreate-budget-period", recreateBudgetPeriod, "the period over which --recreate-budget applies")
 	fs.BoolVar(&externalAccess, "external-access", externalAccess, "the access to the spaces. True when the space-provider is hosted in a space while the controller is running outside of that space")
+	fs.StringVar(&configFile, "config", configFile, "path of a KubeStellarConfiguration file; flags given on the command line take precedence over it")
+
+	clientLimits.AddFlags(fs)
+	metricsLabels.AddFlags(fs)
 
 	spaceMgtClientOpts := NewClientOpts("space-mgt", "access to the space reference space")
 	spaceMgtClientOpts.AddFlags(fs)
@@ -106,13 +168,83 @@ func main() {
 	ctx := context.Background()
 	logger := klog.Background()
 	ctx = klog.NewContext(ctx, logger)
+	recovery.EnableBundles(panicBundleDir, recovery.DefaultMaxBundles)
+
+	if configFile != "" {
+		cfg, cfgReloader, err := componentconfig.LoadForReload(logger.WithName("config"), configFile)
+		if err != nil {
+			logger.Error(err, "Failed to load configuration file", "path", configFile)
+			os.Exit(2)
+		}
+		ptc := cfg.PlacementTranslator
+		if ptc.Concurrency != nil {
+			componentconfig.Override(fs, "concurrency", func() { concurrency = *ptc.Concurrency })
+		}
+		if ptc.OwnershipGCPeriod != nil {
+			componentconfig.Override(fs, "ownership-gc-period", func() { ownershipGCPeriod = ptc.OwnershipGCPeriod.Duration })
+		}
+		if ptc.MailboxWriteConcurrency != nil {
+			componentconfig.Override(fs, "mailbox-write-concurrency", func() { writeLimits.PerSpaceInFlight = *ptc.MailboxWriteConcurrency })
+		}
+		if ptc.MailboxWriteMaxWait != nil {
+			componentconfig.Override(fs, "mailbox-write-max-wait", func() { writeLimits.MaxWait = ptc.MailboxWriteMaxWait.Duration })
+		}
+		clientLimits.ApplyConfig(fs, cfg.TransportFor(ptc.Transport))
+		metricsLabels.ApplyConfig(fs, cfg.Metrics)
+		if !fs.Changed("v") {
+			if err := cfg.ApplyVerbosity(); err != nil {
+				logger.Error(err, "Failed to apply verbosity")
+				os.Exit(2)
+			}
+		}
+		cfgReloader.OnChange(func(cfg *componentconfig.Configuration) {
+			if cfg.Logging.Verbosity != nil && !fs.Changed("v") {
+				if err := cfg.ApplyVerbosity(); err != nil {
+					logger.Error(err, "Failed to apply verbosity")
+				}
+			}
+		})
+		go cfgReloader.Run(ctx)
+	}
 
 	fs.VisitAll(func(flg *pflag.Flag) {
 		logger.V(1).Info("Command line flag", flg.Name, flg.Value)
 	})
 
+	shard := placement.Shard{Index: shardIndex, Count: shardCount}
+	if shard.Sharded() && shardIndex < 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger.Error(err, "Failed to get hostname")
+			os.Exit(1)
+		}
+		var ok bool
+		shard.Index, ok = placement.ShardIndexFromHostname(hostname)
+		if !ok {
+			logger.Error(nil, "Hostname does not end with a shard index; use --shard-index", "hostname", hostname)
+			os.Exit(1)
+		}
+	}
+	if err := shard.Validate(); err != nil {
+		logger.Error(err, "Invalid sharding")
+		os.Exit(1)
+	}
+
+	if err := metricsLabels.Validate(); err != nil {
+		logger.Error(err, "Invalid metrics label policy")
+		os.Exit(1)
+	}
+
+	immutableFieldRules, err := placement.ParseImmutableFieldRules(immutableFieldPolicy, immutableFieldPolicies)
+	if err != nil {
+		logger.Error(err, "Invalid immutable field policy")
+		os.Exit(1)
+	}
+	immutableFieldRules.RecreateBudget = recreateBudget
+	immutableFieldRules.RecreateBudgetPeriod = recreateBudgetPeriod
+
 	mymux := mux.NewPathRecorderMux("placement-translator")
-	mymux.Handle("/metrics", legacyregistry.Handler())
+	mymux.Handle("/metrics", metricsLabels.Handler())
 	routes.Profiling{}.Install(mymux)
 	go func() {
 		err := http.ListenAndServe(serverBindAddress, mymux)
@@ -122,6 +254,11 @@ func main() {
 		}
 	}()
 
+	if err := clientLimits.Validate(); err != nil {
+		logger.Error(err, "Invalid client limits")
+		os.Exit(2)
+	}
+
 	spaceManagementConfig, err := spaceMgtClientOpts.ToRESTConfig()
 	if err != nil {
 		logger.Error(err, "Failed to create space management API client config from flags")
@@ -133,12 +270,15 @@ func main() {
 		os.Exit(4)
 	}
 	spaceProviderNs := spacemanager.ProviderNS(spaceProvider)
+	spaceClients := spaceclientfactory.NewFactory(spaceclient, spaceclientfactory.Options{UserAgent: "placement-translator", Adjust: clientLimits.Apply})
 
 	kcsRestConfig, err := spaceclient.ConfigForSpace(kcsName, spaceProviderNs)
 	if err != nil {
 		logger.Error(err, "Failed to construct space config", "spacename", kcsName)
 		os.Exit(5)
 	}
+	kcsRestConfig = rest.CopyConfig(kcsRestConfig)
+	clientLimits.Apply(kcsRestConfig)
 
 	edgeClientset, err := ksclientset.NewForConfig(kcsRestConfig)
 	if err != nil {
@@ -148,8 +288,6 @@ func main() {
 
 	edgeInformerFactory := emcinformers.NewSharedScopedInformerFactoryWithOptions(edgeClientset, resyncPeriod)
 	epPreInformer := edgeInformerFactory.Edge().V2alpha1().EdgePlacements()
-	spsPreInformer := edgeInformerFactory.Edge().V2alpha1().SinglePlacementSlices()
-	syncfgPreInformer := edgeInformerFactory.Edge().V2alpha1().SyncerConfigs()
 	locationPreInformer := edgeInformerFactory.Edge().V2alpha1().Locations()
 
 	managementClientset, err := spaceclientset.NewForConfig(spaceManagementConfig)
@@ -168,9 +306,37 @@ func main() {
 
 	doneCh := ctx.Done()
 
+	var discoveryCache apiwatch.DiscoveryCache
+	if discoveryCacheDir != "" {
+		discoveryCache = apiwatch.NewDirDiscoveryCache(discoveryCacheDir)
+	}
 	pt := placement.NewPlacementTranslator(concurrency, ctx,
-		locationPreInformer, epPreInformer, spsPreInformer, syncfgPreInformer,
-		spaceclient, spaceProviderNs, spacePreInformer, kbSpaceRelation)
+		locationPreInformer, epPreInformer,
+		spaceclient, spaceClients, spaceProviderNs, spacePreInformer, kbSpaceRelation, bundleThreshold,
+		checkpointFile, checkpointPeriod, discoveryCache, ownershipGCPeriod, shard)
+	mymux.Handle("/load", pt.LoadHandler())
+	mymux.Handle("/apiwatch", apiwatch.DefaultStatsRegistry)
+	watchdog := probes.NewWatchdog("projector-watchdog", watchdogTimeout)
+	pt.SetWatchdog(watchdog)
+	writeLimits.MemoryBudget = writeMemoryBudget.Value()
+	pt.SetWriteAdmission(writeLimits)
+	// Events about a workload object go into the space of that object
+	spaceRecorders := events.NewSpaceRecorders("placement-translator", spaceClients, spaceProviderNs, 0)
+	go spaceRecorders.Run(ctx)
+	eventRecorder := events.NewRecorder(logger, spaceRecorders.For, events.DefaultAggregationWindow)
+	pt.SetEventRecorder(eventRecorder)
+	pt.SetTenantUsagePeriod(tenantUsagePeriod)
+	pt.SetRevertManualEdits(revertManualEdits)
+	pt.SetMaxBreakGlassWindow(maxBreakGlassWindow)
+	pt.SetDeleteJournalFile(deleteJournalFile)
+	pt.SetSoftDeleteGrace(softDeleteGrace)
+	pt.SetImmutableFieldRules(immutableFieldRules)
+	go eventRecorder.Run(ctx)
+	probes.Install(mymux,
+		[]healthz.HealthChecker{probes.InformersSynced("informers", kbSpaceRelation.InformerSynced,
+			epPreInformer.Informer().HasSynced, locationPreInformer.Informer().HasSynced,
+			spacePreInformer.Informer().HasSynced, pt.ScopedInformersSynced)},
+		[]healthz.HealthChecker{watchdog})
 
 	cache.WaitForCacheSync(doneCh, kbSpaceRelation.InformerSynced)
 	edgeInformerFactory.Start(doneCh)
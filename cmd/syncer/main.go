@@ -19,17 +19,27 @@ package main
 import (
 	"context"
 	"flag"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/pflag"
 
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 
 	synceroptions "github.com/kubestellar/kubestellar/cmd/syncer/options"
+	"github.com/kubestellar/kubestellar/pkg/clientlimits"
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+	"github.com/kubestellar/kubestellar/pkg/credbroker"
 	"github.com/kubestellar/kubestellar/pkg/syncer"
+	"github.com/kubestellar/kubestellar/pkg/syncer/syncers"
 )
 
 func main() {
@@ -39,46 +49,173 @@ func main() {
 	fs.AddGoFlagSet(flag.CommandLine)
 	options.AddFlags(fs)
 	fs.Parse(os.Args[1:])
-	if err := options.Complete(); err != nil {
-		panic(err)
+	cfgReloader, err := loadConfig(fs, options)
+	if err != nil {
+		klog.Background().Error(err, "Invalid configuration", "configFile", options.ConfigFile)
+		klog.FlushAndExit(klog.ExitFlushTimeout, 2)
 	}
-	if err := options.Validate(); err != nil {
-		panic(err)
+
+	ctx := setupSignalContext()
+	logger := klog.FromContext(ctx)
+
+	memoryLimit, _ := options.MemoryLimitBytes() // already validated
+	syncer.ApplyMemorySettings(logger, options.LowMemory, memoryLimit)
+
+	if cfgReloader != nil {
+		cfgReloader.OnChange(func(cfg *componentconfig.Configuration) {
+			if cfg.Logging.Verbosity != nil && !fs.Changed("v") {
+				if err := cfg.ApplyVerbosity(); err != nil {
+					logger.Error(err, "Failed to apply verbosity")
+				}
+			}
+		})
+		go cfgReloader.Run(ctx)
+	}
+
+	if options.ServerBindAddress != "" {
+		mymux := http.NewServeMux()
+		mymux.Handle("/metrics", options.MetricsLabels.Handler())
+		go func() {
+			err := http.ListenAndServe(options.ServerBindAddress, mymux)
+			if err != nil {
+				logger.Error(err, "Failure in web serving")
+				panic(err)
+			}
+		}()
+	}
+
+	clientLimits := &clientlimits.Options{Component: "syncer", QPS: options.QPS, Burst: options.Burst}
+
+	// There is no -to cluster when the objects are kept in a manifest directory
+	var downstreamConfig *rest.Config
+	if options.ManifestDir == "" {
+		downstreamConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: options.ToKubeconfig},
+			&clientcmd.ConfigOverrides{
+				CurrentContext: options.ToContext,
+			}).ClientConfig()
+		if err != nil {
+			panic(err)
+		}
+
+		clientLimits.Apply(downstreamConfig)
+	}
+
+	var tokenStore credbroker.TokenStore
+	if options.FromTokenSecret != "" {
+		secretParts := strings.Split(options.FromTokenSecret, "/")
+		downstreamKubeClient, err := kubernetes.NewForConfig(downstreamConfig)
+		if err != nil {
+			panic(err)
+		}
+		tokenStore = &credbroker.SecretTokenStore{
+			Client:       downstreamKubeClient.CoreV1(),
+			Namespace:    secretParts[0],
+			Name:         secretParts[1],
+			FieldManager: "kubestellar-syncer",
+		}
 	}
 
 	kcpConfigOverrides := &clientcmd.ConfigOverrides{
 		CurrentContext: options.FromContext,
 	}
-	upstreamConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: options.FromKubeconfig},
-		kcpConfigOverrides).ClientConfig()
-	if err != nil {
-		panic(err)
+	loadUpstreamConfig := func() (*rest.Config, error) {
+		upstreamConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: options.FromKubeconfig},
+			kcpConfigOverrides).ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		clientLimits.Apply(upstreamConfig)
+		if err := options.FromConnectivity.ApplyTo(upstreamConfig); err != nil {
+			return nil, err
+		}
+		if options.FromTokenServiceAccount != "" {
+			// Bootstrap from the stored token, or else the one in the kubeconfig
+			if err := credbroker.BootstrapTokenFile(ctx, options.FromTokenFile, tokenStore, upstreamConfig.BearerToken, time.Now()); err != nil {
+				return nil, err
+			}
+			upstreamConfig.BearerToken = ""
+			upstreamConfig.BearerTokenFile = options.FromTokenFile
+		}
+		return upstreamConfig, nil
+	}
+	var upstreamConfig *rest.Config
+	if options.CredentialReloadPeriod > 0 {
+		var reloader *syncer.CredentialReloader
+		reloader, upstreamConfig, err = syncer.NewCredentialReloader(logger.WithName("credential-reloader"),
+			loadUpstreamConfig, options.CredentialReloadPeriod, options.CredentialExpiryWarning)
+		if err != nil {
+			panic(err)
+		}
+		go reloader.Run(ctx)
+	} else {
+		upstreamConfig, err = loadUpstreamConfig()
+		if err != nil {
+			panic(err)
+		}
+	}
+	if options.FromTokenServiceAccount != "" {
+		saParts := strings.Split(options.FromTokenServiceAccount, "/")
+		upstreamKubeClient, err := kubernetes.NewForConfig(upstreamConfig)
+		if err != nil {
+			panic(err)
+		}
+		refresher := &credbroker.TokenRefresher{
+			Logger:         logger.WithName("token-refresher"),
+			Client:         upstreamKubeClient.CoreV1(),
+			Namespace:      saParts[0],
+			ServiceAccount: saParts[1],
+			TTL:            options.FromTokenTTL,
+			TokenFile:      options.FromTokenFile,
+			FieldManager:   "kubestellar-syncer",
+			Store:          tokenStore,
+		}
+		go refresher.Run(ctx)
 	}
 
-	upstreamConfig.QPS = options.QPS
-	upstreamConfig.Burst = options.Burst
-
-	downstreamConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: options.ToKubeconfig},
-		&clientcmd.ConfigOverrides{
-			CurrentContext: options.ToContext,
-		}).ClientConfig()
-	if err != nil {
-		panic(err)
+	resourcePolicies := map[schema.GroupResource]syncer.ResourcePolicy{}
+	for _, spec := range options.ResourcePolicies {
+		gr, policy, err := syncer.ParseResourcePolicy(spec)
+		if err != nil {
+			panic(err)
+		}
+		resourcePolicies[gr] = policy
 	}
 
-	downstreamConfig.QPS = options.QPS
-	downstreamConfig.Burst = options.Burst
+	statusLimits := map[schema.GroupResource]syncers.StatusLimit{}
+	for _, spec := range options.StatusUpdateLimits {
+		gr, limit, err := syncer.ParseStatusLimit(spec)
+		if err != nil {
+			panic(err)
+		}
+		statusLimits[gr] = limit
+	}
 
 	syncerConfig := &syncer.SyncerConfig{
 		UpstreamConfig:   upstreamConfig,
 		DownstreamConfig: downstreamConfig,
 		SyncTargetName:   options.SyncTargetName,
 		SyncTargetUID:    options.SyncTargetUID,
+
+		InitialSyncParallelism:   options.InitialSyncParallelism,
+		InitialSyncPageSize:      options.InitialSyncPageSize,
+		ResourcePolicies:         resourcePolicies,
+		StatusLimit:              syncers.StatusLimit{Window: options.StatusUpdateWindow, QPS: options.StatusUpdateQPS},
+		StatusLimits:             statusLimits,
+		RevisionHistoryNamespace: options.RevisionHistoryNamespace,
+		RevisionHistoryLimit:     options.RevisionHistoryLimit,
+		ClusterID:                options.ClusterID,
+		ClusterSet:               options.ClusterSet,
+		LowMemory:                options.LowMemory,
+		ManifestDir:              options.ManifestDir,
+		LocalProbeMaxAge:         options.LocalProbeMaxAge,
+	}
+	if options.LocalOverridesConfigMap != "" {
+		cmParts := strings.Split(options.LocalOverridesConfigMap, "/")
+		syncerConfig.LocalOverridesNamespace, syncerConfig.LocalOverridesName = cmParts[0], cmParts[1]
 	}
 
-	ctx := setupSignalContext()
 	if err := syncer.RunSyncer(ctx, syncerConfig, 1); err != nil {
 		panic(err)
 	}
@@ -106,3 +243,32 @@ func setupSignalContext() context.Context {
 
 	return ctx
 }
+
+// loadConfig applies the configuration file, if any, under the flags
+// given on the command line, and completes and validates the options.
+// It returns the Reloader of the configuration file, or nil if there is none.
+func loadConfig(fs *pflag.FlagSet, options *synceroptions.Options) (*componentconfig.Reloader, error) {
+	var cfgReloader *componentconfig.Reloader
+	options.ApplyLowMemoryDefaults(fs)
+	if options.ConfigFile != "" {
+		var cfg *componentconfig.Configuration
+		var err error
+		cfg, cfgReloader, err = componentconfig.LoadForReload(klog.Background(), options.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		options.ApplyConfig(fs, cfg)
+		if !fs.Changed("v") {
+			if err := cfg.ApplyVerbosity(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := options.Complete(); err != nil {
+		return nil, err
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return cfgReloader, nil
+}
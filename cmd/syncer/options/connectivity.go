@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/client-go/rest"
+)
+
+// IPFamily values select which address family to use when connecting
+// to a dual-stack endpoint.
+const (
+	IPFamilyAny = "any"
+	IPFamilyV4  = "ipv4"
+	IPFamilyV6  = "ipv6"
+)
+
+// ConnectivityOptions modify how the syncer reaches an apiserver;
+// they are used for the connection to the hub, which edge sites
+// often can only reach through a proxy.
+type ConnectivityOptions struct {
+	// ProxyURL is the URL of an HTTP, HTTPS, or SOCKS5 proxy.
+	// Empty means to use the usual environment variables.
+	ProxyURL string
+
+	// CAFile is the pathname of a file holding a CA bundle to trust,
+	// overriding any in the kubeconfig.
+	CAFile string
+
+	// ServerName overrides the name used for SNI and to verify the
+	// server's certificate.
+	ServerName string
+
+	// IPFamily is one of IPFamilyAny, IPFamilyV4, IPFamilyV6.
+	IPFamily string
+}
+
+func NewConnectivityOptions() *ConnectivityOptions {
+	return &ConnectivityOptions{IPFamily: IPFamilyAny}
+}
+
+// AddFlags adds flags whose names start with the given prefix.
+func (options *ConnectivityOptions) AddFlags(fs *pflag.FlagSet, prefix string) {
+	fs.StringVar(&options.ProxyURL, prefix+"proxy-url", options.ProxyURL, "URL of the HTTP, HTTPS, or SOCKS5 proxy to use; if not set, the HTTPS_PROXY/NO_PROXY environment variables are used.")
+	fs.StringVar(&options.CAFile, prefix+"ca-file", options.CAFile, "File holding a CA bundle to trust, instead of the one in the kubeconfig.")
+	fs.StringVar(&options.ServerName, prefix+"tls-server-name", options.ServerName, "Server name to use for SNI and certificate verification, instead of the hostname in the server URL.")
+	fs.StringVar(&options.IPFamily, prefix+"ip-family", options.IPFamily, fmt.Sprintf("Address family to use for a dual-stack server: %q, %q, or %q.", IPFamilyAny, IPFamilyV4, IPFamilyV6))
+}
+
+func (options *ConnectivityOptions) Validate(prefix string) error {
+	switch options.IPFamily {
+	case IPFamilyAny, IPFamilyV4, IPFamilyV6:
+	default:
+		return fmt.Errorf("--%sip-family must be one of %q, %q, %q but is %q", prefix, IPFamilyAny, IPFamilyV4, IPFamilyV6, options.IPFamily)
+	}
+	if options.ProxyURL != "" {
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("--%sproxy-url is malformed: %w", prefix, err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("--%sproxy-url has unsupported scheme %q", prefix, proxyURL.Scheme)
+		}
+	}
+	return nil
+}
+
+// ApplyTo modifies the given config according to these options.
+// Validate should have succeeded first.
+func (options *ConnectivityOptions) ApplyTo(config *rest.Config) error {
+	if options.ProxyURL != "" {
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil {
+			return err
+		}
+		config.Proxy = http.ProxyURL(proxyURL)
+	}
+	if options.CAFile != "" {
+		config.TLSClientConfig.CAFile = options.CAFile
+		config.TLSClientConfig.CAData = nil
+	}
+	if options.ServerName != "" {
+		config.TLSClientConfig.ServerName = options.ServerName
+	}
+	if options.IPFamily != IPFamilyAny {
+		network := "tcp4"
+		if options.IPFamily == IPFamilyV6 {
+			network = "tcp6"
+		}
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		config.Dial = func(ctx context.Context, _, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		}
+	}
+	return nil
+}
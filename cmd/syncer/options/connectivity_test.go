@@ -0,0 +1,164 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestConnectivityValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		options  ConnectivityOptions
+		errorHas string
+	}{
+		{name: "defaults", options: *NewConnectivityOptions()},
+		{name: "ipv4", options: ConnectivityOptions{IPFamily: IPFamilyV4}},
+		{name: "ipv6", options: ConnectivityOptions{IPFamily: IPFamilyV6}},
+		{name: "bad ip family", options: ConnectivityOptions{IPFamily: "ipv5"}, errorHas: "--hub-ip-family"},
+		{name: "empty ip family", options: ConnectivityOptions{}, errorHas: "--hub-ip-family"},
+		{name: "http proxy", options: ConnectivityOptions{IPFamily: IPFamilyAny, ProxyURL: "http://proxy:3128"}},
+		{name: "https proxy", options: ConnectivityOptions{IPFamily: IPFamilyAny, ProxyURL: "https://proxy:3129"}},
+		{name: "socks5 proxy", options: ConnectivityOptions{IPFamily: IPFamilyAny, ProxyURL: "socks5://proxy:1080"}},
+		{name: "ftp proxy", options: ConnectivityOptions{IPFamily: IPFamilyAny, ProxyURL: "ftp://proxy:21"}, errorHas: "--hub-proxy-url has unsupported scheme \"ftp\""},
+		{name: "malformed proxy", options: ConnectivityOptions{IPFamily: IPFamilyAny, ProxyURL: "http://proxy:port"}, errorHas: "--hub-proxy-url is malformed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.options.Validate("hub-")
+			if tc.errorHas == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected an error mentioning %q, got none", tc.errorHas)
+			}
+			if !strings.Contains(err.Error(), tc.errorHas) {
+				t.Fatalf("Expected an error mentioning %q, got %v", tc.errorHas, err)
+			}
+		})
+	}
+}
+
+func TestConnectivityApplyTo(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	for _, tc := range []struct {
+		name    string
+		options ConnectivityOptions
+		check   func(*testing.T, *rest.Config)
+	}{
+		{name: "defaults", options: *NewConnectivityOptions(),
+			check: func(t *testing.T, config *rest.Config) {
+				if config.Proxy != nil {
+					t.Error("Proxy was set")
+				}
+				if config.Dial != nil {
+					t.Error("Dial was set")
+				}
+				if string(config.TLSClientConfig.CAData) != "original-ca" {
+					t.Errorf("CAData was changed to %q", config.TLSClientConfig.CAData)
+				}
+				if config.TLSClientConfig.ServerName != "" {
+					t.Errorf("ServerName was set to %q", config.TLSClientConfig.ServerName)
+				}
+			}},
+		{name: "ca file", options: ConnectivityOptions{IPFamily: IPFamilyAny, CAFile: "/etc/hub/ca.crt"},
+			check: func(t *testing.T, config *rest.Config) {
+				if config.TLSClientConfig.CAFile != "/etc/hub/ca.crt" {
+					t.Errorf("CAFile is %q", config.TLSClientConfig.CAFile)
+				}
+				if config.TLSClientConfig.CAData != nil {
+					t.Errorf("CAData was not cleared, is %q", config.TLSClientConfig.CAData)
+				}
+			}},
+		{name: "server name", options: ConnectivityOptions{IPFamily: IPFamilyAny, ServerName: "hub.example.com"},
+			check: func(t *testing.T, config *rest.Config) {
+				if config.TLSClientConfig.ServerName != "hub.example.com" {
+					t.Errorf("ServerName is %q", config.TLSClientConfig.ServerName)
+				}
+			}},
+		{name: "proxy", options: ConnectivityOptions{IPFamily: IPFamilyAny, ProxyURL: "socks5://proxy:1080"},
+			check: func(t *testing.T, config *rest.Config) {
+				if config.Proxy == nil {
+					t.Fatal("Proxy was not set")
+				}
+				req, err := http.NewRequest(http.MethodGet, "https://hub.example.com/api", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				proxyURL, err := config.Proxy(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if proxyURL == nil || proxyURL.String() != "socks5://proxy:1080" {
+					t.Errorf("Proxy returned %v", proxyURL)
+				}
+			}},
+		{name: "ipv4", options: ConnectivityOptions{IPFamily: IPFamilyV4},
+			check: func(t *testing.T, config *rest.Config) {
+				if config.Dial == nil {
+					t.Fatal("Dial was not set")
+				}
+				conn, err := config.Dial(context.Background(), "tcp", listener.Addr().String())
+				if err != nil {
+					t.Fatalf("Failed to dial IPv4 listener: %v", err)
+				}
+				conn.Close()
+			}},
+		{name: "ipv6", options: ConnectivityOptions{IPFamily: IPFamilyV6},
+			check: func(t *testing.T, config *rest.Config) {
+				if config.Dial == nil {
+					t.Fatal("Dial was not set")
+				}
+				conn, err := config.Dial(context.Background(), "tcp", listener.Addr().String())
+				if err == nil {
+					conn.Close()
+					t.Fatal("Dialing an IPv4 address succeeded despite IPv6 being required")
+				}
+			}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &rest.Config{Host: "https://hub:6443"}
+			config.TLSClientConfig.CAData = []byte("original-ca")
+			if err := tc.options.ApplyTo(config); err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, config)
+		})
+	}
+}
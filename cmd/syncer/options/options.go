@@ -19,8 +19,18 @@ package options
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+	"github.com/kubestellar/kubestellar/pkg/metricslabels"
+	"github.com/kubestellar/kubestellar/pkg/revisions"
+	"github.com/kubestellar/kubestellar/pkg/syncer"
+	"github.com/kubestellar/kubestellar/pkg/syncer/syncers"
 )
 
 type Options struct {
@@ -32,17 +42,116 @@ type Options struct {
 	ToContext      string
 	SyncTargetName string
 	SyncTargetUID  string
+
+	// FromConnectivity applies to the connection to the -from cluster (the hub).
+	FromConnectivity *ConnectivityOptions
+
+	// CredentialReloadPeriod is how often to check for new credentials
+	// for the -from cluster; zero means never.
+	CredentialReloadPeriod time.Duration
+
+	// CredentialExpiryWarning is how long before the expiration of the
+	// client certificate to start warning about it.
+	CredentialExpiryWarning time.Duration
+
+	// ServerBindAddress is where to serve /metrics; empty means not to.
+	ServerBindAddress string
+
+	// FromTokenServiceAccount, if not empty, is the "namespace/name" of the
+	// ServiceAccount in the -from cluster whose token the syncer keeps renewing.
+	FromTokenServiceAccount string
+
+	// FromTokenFile is where the renewed token is kept.
+	FromTokenFile string
+
+	// FromTokenTTL is the lifetime to request for each renewed token.
+	FromTokenTTL time.Duration
+
+	// FromTokenSecret, if not empty, is the "namespace/name" of a Secret
+	// in the -to cluster in which to keep the latest renewed token, so that
+	// a restarted syncer does not depend on the bootstrap token.
+	FromTokenSecret string
+
+	// InitialSyncParallelism is how many objects to apply at once in the
+	// bulk initial sync; zero means not to do a bulk initial sync.
+	InitialSyncParallelism int
+
+	// InitialSyncPageSize is how many objects to list per request in the
+	// bulk initial sync; zero means not to page.
+	InitialSyncPageSize int64
+
+	// ResourcePolicies are the unparsed --resource-sync-policy values.
+	ResourcePolicies []string
+
+	// StatusUpdateWindow and StatusUpdateQPS are the default limits on the
+	// status writes to the -from cluster.
+	StatusUpdateWindow time.Duration
+	StatusUpdateQPS    float32
+
+	// StatusUpdateLimits are the unparsed --status-update-limit values.
+	StatusUpdateLimits []string
+
+	// LocalOverridesConfigMap is the "namespace/name" of the ConfigMap in
+	// the -to cluster that holds the local override rules; empty means none.
+	LocalOverridesConfigMap string
+
+	// RevisionHistoryNamespace is the namespace, in the -from space, in which
+	// to keep the revisions of downsynced objects; empty means not to.
+	RevisionHistoryNamespace string
+
+	// RevisionHistoryLimit is how many revisions to keep per object.
+	RevisionHistoryLimit int
+
+	// ClusterID is the cluster ID to publish in the -to cluster; empty means
+	// the UID of its kube-system namespace.
+	ClusterID string
+
+	// ClusterSet is the ClusterSet name to publish in the -to cluster; empty means none.
+	ClusterSet string
+
+	// ConfigFile is the path of a componentconfig file; empty means none.
+	ConfigFile string
+
+	// LowMemory selects the reduced-footprint mode; see syncer.SyncerConfig.
+	LowMemory bool
+
+	// MemoryLimit is the unparsed soft memory limit of the process;
+	// empty means none.
+	MemoryLimit string
+
+	// ManifestDir, if not empty, is the directory in which to keep the
+	// downsynced objects as manifest files instead of using a -to cluster.
+	ManifestDir string
+
+	// LocalProbeMaxAge is how long a result of a LocalProbe is reused;
+	// zero disables the probes.
+	LocalProbeMaxAge time.Duration
+
+	// MetricsLabels is what to do with high-cardinality metric labels.
+	MetricsLabels *metricslabels.Options
 }
 
 func NewOptions() *Options {
 	return &Options{
-		QPS:   30,
-		Burst: 20,
+		QPS:              30,
+		Burst:            20,
+		FromConnectivity: NewConnectivityOptions(),
+
+		CredentialReloadPeriod:  time.Minute,
+		CredentialExpiryWarning: 24 * time.Hour,
+		FromTokenTTL:            time.Hour,
+		InitialSyncParallelism:  16,
+		InitialSyncPageSize:     500,
+		RevisionHistoryLimit:    revisions.DefaultLimit,
+		StatusUpdateWindow:      syncers.DefaultStatusLimit.Window,
+		StatusUpdateQPS:         syncers.DefaultStatusLimit.QPS,
+		LocalProbeMaxAge:        30 * time.Second,
+		MetricsLabels:           metricslabels.NewOptions(),
 	}
 }
 
 func (options *Options) AddFlags(fs *pflag.FlagSet) {
-	fs.Float32Var(&options.QPS, "qps", options.QPS, "QPS to use when talking to API servers.")
+	fs.Float32Var(&options.QPS, "qps", options.QPS, "QPS to use when talking to API servers; zero means no limit.")
 	fs.IntVar(&options.Burst, "burst", options.Burst, "Burst to use when talking to API servers.")
 	fs.StringVar(&options.FromKubeconfig, "from-kubeconfig", options.FromKubeconfig, "Kubeconfig file for -from cluster.")
 	fs.StringVar(&options.FromContext, "from-context", options.FromContext, "Context to use in the Kubeconfig file for -from cluster, instead of the current context.")
@@ -51,6 +160,83 @@ func (options *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&options.SyncTargetName, "sync-target-name", options.SyncTargetName,
 		fmt.Sprintf("ID of the -to cluster. Resources with this ID set in the %q label will be synced.", "<ClusterID>"))
 	fs.StringVar(&options.SyncTargetUID, "sync-target-uid", options.SyncTargetUID, "The UID from the SyncTarget resource in KCP.")
+	options.FromConnectivity.AddFlags(fs, "from-")
+	fs.DurationVar(&options.CredentialReloadPeriod, "credential-reload-period", options.CredentialReloadPeriod, "How often to check for new credentials in the -from kubeconfig and the files it references; zero disables reloading.")
+	fs.DurationVar(&options.CredentialExpiryWarning, "credential-expiry-warning", options.CredentialExpiryWarning, "How long before the -from client certificate expires to start logging warnings.")
+	fs.StringVar(&options.ServerBindAddress, "server-bind-address", options.ServerBindAddress, "The IP address with port at which to serve /metrics; empty means not to serve.")
+	fs.StringVar(&options.FromTokenServiceAccount, "from-token-service-account", options.FromTokenServiceAccount, "namespace/name of the ServiceAccount in the -from cluster whose short-lived token the syncer keeps renewing. If not set, the credentials in the -from kubeconfig are used as they are.")
+	fs.StringVar(&options.FromTokenFile, "from-token-file", options.FromTokenFile, "File in which to keep the renewed token for the -from cluster; must be writable.")
+	fs.DurationVar(&options.FromTokenTTL, "from-token-ttl", options.FromTokenTTL, "Lifetime to request for each renewed token for the -from cluster.")
+	fs.StringVar(&options.FromTokenSecret, "from-token-secret", options.FromTokenSecret, "namespace/name of a Secret in the -to cluster in which to keep the renewed token, so that it survives restarts of the syncer.")
+	fs.IntVar(&options.InitialSyncParallelism, "initial-sync-parallelism", options.InitialSyncParallelism, "How many objects to apply concurrently in the bulk sync done when the syncer first finds objects to downsync; zero disables the bulk sync.")
+	fs.Int64Var(&options.InitialSyncPageSize, "initial-sync-page-size", options.InitialSyncPageSize, "How many objects to list per request in the initial bulk sync; zero means no paging.")
+	fs.StringArrayVar(&options.ResourcePolicies, "resource-sync-policy", options.ResourcePolicies, "Priority and minimum sync interval for a resource, in the form RESOURCE[.GROUP]=PRIORITY[:INTERVAL] (e.g., secrets=100:5s). Higher priority resources are synced first in each pass; resources without a policy have priority 0 and are synced every pass. May be repeated.")
+	fs.DurationVar(&options.StatusUpdateWindow, "status-update-window", options.StatusUpdateWindow, "Minimum time between two status writes of one object to the -from cluster; the changes in between are written together.")
+	fs.Float32Var(&options.StatusUpdateQPS, "status-update-qps", options.StatusUpdateQPS, "Maximum status writes per second to the -from cluster, for the resources without a --status-update-limit; zero means no maximum.")
+	fs.StringArrayVar(&options.StatusUpdateLimits, "status-update-limit", options.StatusUpdateLimits, "Status write window and maximum rate for a resource, in the form RESOURCE[.GROUP]=WINDOW[:QPS] (e.g., jobs.batch=30s:2), instead of --status-update-window and --status-update-qps. May be repeated.")
+	fs.StringVar(&options.LocalOverridesConfigMap, "local-overrides-configmap", options.LocalOverridesConfigMap, "namespace/name of the ConfigMap in the -to cluster whose values are rules locking fields of downsynced objects to their local values. If not set, there are no local overrides.")
+	fs.StringVar(&options.RevisionHistoryNamespace, "revision-history-namespace", options.RevisionHistoryNamespace, "Namespace, in the -from space, in which to keep the recent revisions of the downsynced objects, for use with `kubectl kubestellar revisions`; empty means not to keep them. The usual one is \""+revisions.DefaultNamespace+"\".")
+	fs.IntVar(&options.RevisionHistoryLimit, "revision-history-limit", options.RevisionHistoryLimit, "How many revisions to keep per downsynced object.")
+	fs.StringVar(&options.ClusterID, "cluster-id", options.ClusterID, "Cluster ID to publish as the cluster.clusterset.k8s.io ClusterProperty in the -to cluster, unless it already has one. If not set, the UID of the kube-system namespace is used.")
+	fs.StringVar(&options.ClusterSet, "cluster-set", options.ClusterSet, "ClusterSet name to publish as the clusterset.k8s.io ClusterProperty in the -to cluster, unless it already has one. If not set, none is published.")
+	fs.StringVar(&options.ConfigFile, "config", options.ConfigFile, "Path of a KubeStellarConfiguration file; flags given on the command line take precedence over it.")
+	fs.BoolVar(&options.LowMemory, "low-memory", options.LowMemory, fmt.Sprintf("Reduce the memory footprint, for small edge machines: trim the informer caches, discover APIs on demand, collect garbage more often, and default --initial-sync-parallelism to %d and --initial-sync-page-size to %d.", syncer.LowMemoryInitialSyncParallelism, syncer.LowMemoryInitialSyncPageSize))
+	fs.StringVar(&options.MemoryLimit, "memory-limit", options.MemoryLimit, "Soft memory limit of the process (e.g., 200Mi), approaching which the garbage collector works harder; empty means none (or the GOMEMLIMIT environment variable).")
+	fs.StringVar(&options.ManifestDir, "manifest-dir", options.ManifestDir, "Directory in which to keep the downsynced objects as manifest files, for a WEC without an apiserver for the syncer (e.g., the auto-deploying manifests directory of k3s); the -to cluster is not used then.")
+	fs.DurationVar(&options.LocalProbeMaxAge, "local-probe-max-age", options.LocalProbeMaxAge, "How long to reuse a result of a local probe that an EdgePlacement asks for, before running the probe again; zero disables the local probes.")
+	options.MetricsLabels.AddFlags(fs)
+}
+
+// ApplyLowMemoryDefaults changes the defaults of the settings that the
+// low-memory mode affects and that were not given on the command line.
+// Call it before ApplyConfig, so that a configuration file still wins.
+func (options *Options) ApplyLowMemoryDefaults(fs *pflag.FlagSet) {
+	if !options.LowMemory {
+		return
+	}
+	if !fs.Changed("initial-sync-parallelism") {
+		options.InitialSyncParallelism = syncer.LowMemoryInitialSyncParallelism
+	}
+	if !fs.Changed("initial-sync-page-size") {
+		options.InitialSyncPageSize = syncer.LowMemoryInitialSyncPageSize
+	}
+}
+
+// MemoryLimitBytes returns the parsed --memory-limit; zero means none.
+func (options *Options) MemoryLimitBytes() (int64, error) {
+	if options.MemoryLimit == "" {
+		return 0, nil
+	}
+	quantity, err := resource.ParseQuantity(options.MemoryLimit)
+	if err != nil {
+		return 0, err
+	}
+	return quantity.Value(), nil
+}
+
+// ApplyConfig takes the settings from the given configuration file that
+// were not given on the command line.
+func (options *Options) ApplyConfig(fs *pflag.FlagSet, cfg *componentconfig.Configuration) {
+	if cfg.Transport.QPS != nil {
+		componentconfig.Override(fs, "qps", func() { options.QPS = *cfg.Transport.QPS })
+	}
+	if cfg.Transport.Burst != nil {
+		componentconfig.Override(fs, "burst", func() { options.Burst = *cfg.Transport.Burst })
+	}
+	sc := cfg.Syncer
+	if sc.CredentialReloadPeriod != nil {
+		componentconfig.Override(fs, "credential-reload-period", func() { options.CredentialReloadPeriod = sc.CredentialReloadPeriod.Duration })
+	}
+	if sc.InitialSyncParallelism != nil {
+		componentconfig.Override(fs, "initial-sync-parallelism", func() { options.InitialSyncParallelism = *sc.InitialSyncParallelism })
+	}
+	if sc.InitialSyncPageSize != nil {
+		componentconfig.Override(fs, "initial-sync-page-size", func() { options.InitialSyncPageSize = *sc.InitialSyncPageSize })
+	}
+	if sc.ResourceSyncPolicies != nil {
+		componentconfig.Override(fs, "resource-sync-policy", func() { options.ResourcePolicies = sc.ResourceSyncPolicies })
+	}
+	options.MetricsLabels.ApplyConfig(fs, cfg.Metrics)
 }
 
 func (options *Options) Complete() error {
@@ -64,5 +250,60 @@ func (options *Options) Validate() error {
 	if options.SyncTargetUID == "" {
 		return errors.New("--sync-target-uid is required")
 	}
-	return nil
+	if options.FromTokenServiceAccount != "" {
+		if len(strings.Split(options.FromTokenServiceAccount, "/")) != 2 {
+			return errors.New("--from-token-service-account must have the form namespace/name")
+		}
+		if options.FromTokenFile == "" {
+			return errors.New("--from-token-file is required when --from-token-service-account is given")
+		}
+	}
+	if options.FromTokenSecret != "" {
+		if options.FromTokenServiceAccount == "" {
+			return errors.New("--from-token-secret requires --from-token-service-account")
+		}
+		if len(strings.Split(options.FromTokenSecret, "/")) != 2 {
+			return errors.New("--from-token-secret must have the form namespace/name")
+		}
+	}
+	if options.ManifestDir != "" && options.FromTokenSecret != "" {
+		return errors.New("--from-token-secret can not be used with --manifest-dir")
+	}
+	if options.InitialSyncParallelism < 0 {
+		return errors.New("--initial-sync-parallelism must not be negative")
+	}
+	if options.InitialSyncPageSize < 0 {
+		return errors.New("--initial-sync-page-size must not be negative")
+	}
+	if options.RevisionHistoryLimit < 1 {
+		return errors.New("--revision-history-limit must be positive")
+	}
+	if options.LocalOverridesConfigMap != "" && len(strings.Split(options.LocalOverridesConfigMap, "/")) != 2 {
+		return errors.New("--local-overrides-configmap must have the form namespace/name")
+	}
+	for _, spec := range options.ResourcePolicies {
+		if _, _, err := syncer.ParseResourcePolicy(spec); err != nil {
+			return fmt.Errorf("--resource-sync-policy: %w", err)
+		}
+	}
+	if options.StatusUpdateWindow < 0 {
+		return errors.New("--status-update-window must not be negative")
+	}
+	if options.StatusUpdateQPS < 0 {
+		return errors.New("--status-update-qps must not be negative")
+	}
+	if limit, err := options.MemoryLimitBytes(); err != nil {
+		return fmt.Errorf("--memory-limit: %w", err)
+	} else if limit < 0 {
+		return errors.New("--memory-limit must not be negative")
+	}
+	for _, spec := range options.StatusUpdateLimits {
+		if _, _, err := syncer.ParseStatusLimit(spec); err != nil {
+			return fmt.Errorf("--status-update-limit: %w", err)
+		}
+	}
+	if err := options.MetricsLabels.Validate(); err != nil {
+		return err
+	}
+	return options.FromConnectivity.Validate("from-")
 }
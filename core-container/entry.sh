@@ -254,7 +254,7 @@ function run_mailbox_controller() {
 function run_where_resolver() {
     echo "--< Starting where-resolver >--"
     wait-kubestellar-ready
-    if ! kubestellar-where-resolver -v ${VERBOSITY} ; then
+    if ! kubestellar-where-resolver -v ${VERBOSITY} --server-bind-address :10205 ; then
         echoerr "unable to start kubestellar-where-resolver!"
         exit 1
     fi
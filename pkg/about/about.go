@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package about works with the ClusterProperty objects of the
+// sig-multicluster About API (KEP-2149), which identify a cluster and
+// the ClusterSet it belongs to, so that a KubeStellar fleet can
+// interoperate with other sig-multicluster tooling.
+//
+// The syncer publishes the ID of its WEC (by default the UID of the
+// kube-system namespace, as the KEP suggests) and, if given, the name
+// of its ClusterSet as ClusterProperty objects in the WEC. Values that
+// are already there are adopted rather than overwritten, since the KEP
+// requires the cluster ID to be stable. The effective values go into
+// the status of the SyncerConfig and from there into the status of the
+// SyncTarget, making them part of the SyncTarget's identity.
+//
+// The ClusterProperty CRD (from sigs.k8s.io/about-api) has to be
+// installed in the WEC for the properties to be published.
+package about
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// ClusterIDPropertyName is the name of the ClusterProperty that holds the cluster ID.
+	ClusterIDPropertyName = "cluster.clusterset.k8s.io"
+
+	// ClusterSetPropertyName is the name of the ClusterProperty that holds the ClusterSet name.
+	ClusterSetPropertyName = "clusterset.k8s.io"
+)
+
+// ClusterPropertyGVR identifies the ClusterProperty resource.
+var ClusterPropertyGVR = schema.GroupVersionResource{Group: "about.k8s.io", Version: "v1alpha1", Resource: "clusterproperties"}
+
+// ErrNotInstalled is returned when the ClusterProperty CRD is not installed.
+var ErrNotInstalled = errors.New("the ClusterProperty API (about.k8s.io) is not installed")
+
+var namespaceGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// DefaultClusterID returns the UID of the kube-system namespace, which
+// is what KEP-2149 suggests as a cluster ID.
+func DefaultClusterID(ctx context.Context, client dynamic.Interface) (string, error) {
+	ns, err := client.Resource(namespaceGVR).Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the kube-system namespace: %w", err)
+	}
+	return string(ns.GetUID()), nil
+}
+
+// Get returns the value of the named ClusterProperty; empty if there is none.
+func Get(ctx context.Context, client dynamic.Interface, name string) (string, error) {
+	obj, err := client.Resource(ClusterPropertyGVR).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case k8sapierrors.IsNotFound(err) && isNoKind(err):
+		return "", ErrNotInstalled
+	case k8sapierrors.IsNotFound(err):
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	value, _, err := unstructured.NestedString(obj.Object, "spec", "value")
+	return value, err
+}
+
+// Publish makes sure that the named ClusterProperty exists. If it
+// already has a value, that is kept. Returns the value in effect.
+func Publish(ctx context.Context, client dynamic.Interface, name, value string) (string, error) {
+	existing, err := Get(ctx, client, name)
+	if err != nil || existing != "" {
+		return existing, err
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"value": value},
+	}}
+	obj.SetAPIVersion(ClusterPropertyGVR.GroupVersion().String())
+	obj.SetKind("ClusterProperty")
+	obj.SetName(name)
+	_, err = client.Resource(ClusterPropertyGVR).Create(ctx, obj, metav1.CreateOptions{FieldManager: "kubestellar"})
+	if k8sapierrors.IsAlreadyExists(err) {
+		return Get(ctx, client, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// isNoKind tells whether a NotFound error is about the resource rather
+// than the object, i.e. whether the resource is not served at all.
+func isNoKind(err error) bool {
+	var status k8sapierrors.APIStatus
+	if !errors.As(err, &status) {
+		return false
+	}
+	details := status.Status().Details
+	return details == nil || details.Name == ""
+}
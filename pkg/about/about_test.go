@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package about
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newClient(objects ...runtime.Object) *fake.FakeDynamicClient {
+	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{ClusterPropertyGVR: "ClusterPropertyList", namespaceGVR: "NamespaceList"}, objects...)
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	kubeSystem := &unstructured.Unstructured{}
+	kubeSystem.SetAPIVersion("v1")
+	kubeSystem.SetKind("Namespace")
+	kubeSystem.SetName("kube-system")
+	kubeSystem.SetUID(types.UID("ks-uid"))
+	client := newClient(kubeSystem)
+
+	id, err := DefaultClusterID(ctx, client)
+	if err != nil || id != "ks-uid" {
+		t.Fatalf("Expected the kube-system UID, got %q, err=%v", id, err)
+	}
+	if value, err := Get(ctx, client, ClusterIDPropertyName); err != nil || value != "" {
+		t.Fatalf("Expected no property yet, got %q, err=%v", value, err)
+	}
+	if value, err := Publish(ctx, client, ClusterIDPropertyName, id); err != nil || value != id {
+		t.Fatalf("Expected to publish %q, got %q, err=%v", id, value, err)
+	}
+	// An existing value is kept
+	if value, err := Publish(ctx, client, ClusterIDPropertyName, "other"); err != nil || value != id {
+		t.Fatalf("Expected to keep %q, got %q, err=%v", id, value, err)
+	}
+}
+
+func TestNotInstalled(t *testing.T) {
+	client := newClient()
+	client.PrependReactor("get", "clusterproperties", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8sapierrors.NewNotFound(ClusterPropertyGVR.GroupResource(), "")
+	})
+	if _, err := Publish(context.Background(), client, ClusterSetPropertyName, "set1"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Expected ErrNotInstalled, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterRegistration exists in an inventory space and asks for a
+// workload execution cluster (WEC) to be joined to KubeStellar.
+// It is a declarative alternative to `kubectl kubestellar prep-for-cluster`,
+// meant to be created by infrastructure tools such as Crossplane or Terraform.
+//
+// The cluster registration controller drives the join flow to completion:
+// it creates a SyncTarget and a Location, both named like this object,
+// in the same space; waits for the mailbox controller to provision the
+// mailbox space; and issues the syncer's credentials in the mailbox space.
+// The result is a Secret holding the manifest to apply to the WEC in order
+// to run the syncer there. The progress is reported in the status.
+//
+// The SyncTarget, Location and Secret are owned by this object, so
+// deleting it removes them (and thereby the mailbox space).
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:resource:scope=Cluster,shortName=creg
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:labels="kube-bind.io/exported=true"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Mailbox",type=string,JSONPath=`.status.mailboxSpace`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ClusterRegistration struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterRegistrationSpec `json:"spec"`
+
+	// +optional
+	Status ClusterRegistrationStatus `json:"status,omitempty"`
+}
+
+// ClusterRegistrationSpec describes the WEC to join.
+type ClusterRegistrationSpec struct {
+	// `labels` are put on the SyncTarget and on the Location, in addition
+	// to the `id` label (whose value is the name of this object) that
+	// the Location uses to select its SyncTarget.
+	// These are the labels that EdgePlacements select on.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// `syncerImage` is the container image of the syncer to run in the WEC.
+	SyncerImage string `json:"syncerImage"`
+
+	// `tokenTTL`, if given, directs that the syncer use a short-lived token
+	// of this lifetime and keep renewing it, instead of a long-lived token.
+	// It has to be at least 10 minutes.
+	// +optional
+	TokenTTL *metav1.Duration `json:"tokenTTL,omitempty"`
+
+	// `manifestSecretNamespace` is the namespace, in this space, of the Secret
+	// that gets the manifest to apply to the WEC. Default is `default`.
+	// +optional
+	ManifestSecretNamespace string `json:"manifestSecretNamespace,omitempty"`
+}
+
+// ClusterRegistrationPhase is a coarse summary of how far a join has come.
+type ClusterRegistrationPhase string
+
+const (
+	// ClusterRegistrationPending means that the SyncTarget and Location
+	// are not yet there.
+	ClusterRegistrationPending ClusterRegistrationPhase = "Pending"
+
+	// ClusterRegistrationProvisioning means that the mailbox space is
+	// not yet ready.
+	ClusterRegistrationProvisioning ClusterRegistrationPhase = "Provisioning"
+
+	// ClusterRegistrationIssuingCredentials means that the syncer's
+	// credentials are not yet issued.
+	ClusterRegistrationIssuingCredentials ClusterRegistrationPhase = "IssuingCredentials"
+
+	// ClusterRegistrationAwaitingSyncer means that the manifest is ready
+	// but the syncer has not yet reported from the WEC.
+	ClusterRegistrationAwaitingSyncer ClusterRegistrationPhase = "AwaitingSyncer"
+
+	// ClusterRegistrationJoined means that the syncer has reported from the WEC.
+	ClusterRegistrationJoined ClusterRegistrationPhase = "Joined"
+)
+
+// Condition types for ClusterRegistration.
+const (
+	// ClusterRegistrationInventoryReady means that the SyncTarget and Location exist.
+	ClusterRegistrationInventoryReady string = "InventoryReady"
+
+	// ClusterRegistrationMailboxReady means that the mailbox space is ready.
+	ClusterRegistrationMailboxReady string = "MailboxReady"
+
+	// ClusterRegistrationCredentialsIssued means that the syncer's credentials
+	// have been issued and the manifest Secret written.
+	ClusterRegistrationCredentialsIssued string = "CredentialsIssued"
+
+	// ClusterRegistrationSyncerConnected means that the syncer has reported
+	// a heartbeat in the SyncTarget.
+	ClusterRegistrationSyncerConnected string = "SyncerConnected"
+)
+
+// ClusterRegistrationManifestKey is the key, in the data of the manifest
+// Secret, of the manifest to apply to the WEC.
+const ClusterRegistrationManifestKey = "manifest.yaml"
+
+// ClusterRegistrationStatus reports the progress of a join.
+type ClusterRegistrationStatus struct {
+	// `observedGeneration` is the generation of the spec that this status is about.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +optional
+	Phase ClusterRegistrationPhase `json:"phase,omitempty"`
+
+	// `syncTargetUID` is the UID of the SyncTarget.
+	// +optional
+	SyncTargetUID string `json:"syncTargetUID,omitempty"`
+
+	// `mailboxSpace` is the name of the mailbox space.
+	// +optional
+	MailboxSpace string `json:"mailboxSpace,omitempty"`
+
+	// `manifestSecretRef` identifies the Secret, in this space, that holds
+	// the manifest to apply to the WEC (under the key `manifest.yaml`).
+	// The manifest includes the syncer's credentials.
+	// +optional
+	ManifestSecretRef *corev1.SecretReference `json:"manifestSecretRef,omitempty"`
+
+	// `conditions` reports on the steps of the join, plus `Ready`.
+	// These are maintained and interpreted according to
+	// the conventions of the pkg/conditions library.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ClusterRegistrationList is the API type for a list of ClusterRegistration
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ClusterRegistrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterRegistration `json:"items"`
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v2alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -51,6 +53,7 @@ import (
 // +genclient
 // +genclient:nonNamespaced
 // +kubebuilder:resource:scope=Cluster,shortName=epl
+// +kubebuilder:subresource:status
 // +kubebuilder:metadata:labels="kube-bind.io/exported=true"
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type EdgePlacement struct {
@@ -94,12 +97,239 @@ type EdgePlacementSpec struct {
 	// +optional
 	WantSingletonReportedState bool `json:"wantSingletonReportedState,omitempty"`
 
+	// `includeDependencies`, when true, adds to the downsynced objects
+	// the ones that they refer to in their namespace: the ConfigMaps,
+	// Secrets and ServiceAccount used by the pod template of a workload,
+	// and the Services and TLS Secrets used by an Ingress.
+	// +optional
+	IncludeDependencies bool `json:"includeDependencies,omitempty"`
+
 	// `upsync` identifies objects to upsync.
 	// An object matches `upsync` if and only if it matches at least one member of `upsync`.
 	// +optional
 	Upsync []UpsyncSet `json:"upsync,omitempty"`
+
+	// `networkGuardrails`, when present, asks for NetworkPolicy objects to be
+	// generated and downsynced along with the workload.
+	// Omit this field to get no generated NetworkPolicies.
+	// +optional
+	NetworkGuardrails *NetworkGuardrails `json:"networkGuardrails,omitempty"`
+
+	// `requirements` restricts the destinations to those whose SyncTarget
+	// reports capabilities that satisfy these requirements.
+	// A Location's SyncTarget that fails the requirements is not a destination,
+	// and the reason is reported in the `RequirementsSatisfied` condition.
+	// +optional
+	Requirements *PlacementRequirements `json:"requirements,omitempty"`
+
+	// `trust`, when present, asks for trust material (CA certificates and
+	// image pull credentials) to be aggregated and downsynced along with the workload.
+	// Omit this field to get no generated trust objects.
+	// +optional
+	Trust *TrustDistribution `json:"trust,omitempty"`
+
+	// `localProbes` are health checks that the syncer runs in each
+	// destination, for each downsynced object that this EdgePlacement
+	// selects, to report the health of the application rather than just
+	// what its apiserver says. The results are reported on the object's
+	// copy in the mailbox space, in the ProbeResultsKey annotation.
+	// When multiple EdgePlacement objects match the same workload object,
+	// the union of their probes applies.
+	// +optional
+	LocalProbes []LocalProbe `json:"localProbes,omitempty"`
+
+	// `allowAll` acknowledges that this EdgePlacement selects very
+	// broadly: that a member of `downsync` matches every object in
+	// every namespace, or that a member of `locationSelectors` is empty
+	// and so matches every Location. The placement access webhook
+	// warns about, or rejects, such an EdgePlacement unless this is true.
+	// +optional
+	AllowAll bool `json:"allowAll,omitempty"`
+}
+
+// LocalProbe is a health check run by the syncer in a WEC.
+// Exactly one of `httpGet`, `exec` and `promQL` must be given.
+type LocalProbe struct {
+	// `name` identifies the probe among those of an object,
+	// and is the type of the condition that reports its result.
+	Name string `json:"name"`
+
+	// `httpGet` succeeds when a GET of the URL returns a 2xx or 3xx code.
+	// +optional
+	HTTPGet *HTTPGetProbe `json:"httpGet,omitempty"`
+
+	// `exec` succeeds when a command run in a container exits with code 0.
+	// +optional
+	Exec *ExecProbe `json:"exec,omitempty"`
+
+	// `promQL` succeeds when a query of a Prometheus server returns at
+	// least one sample and no sample is zero.
+	// +optional
+	PromQL *PromQLProbe `json:"promQL,omitempty"`
+
+	// `timeoutSeconds` bounds each run of the probe. Zero means 5.
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// HTTPGetProbe is an HTTP GET done from the syncer.
+type HTTPGetProbe struct {
+	// `url` is what to GET; it is resolved in the WEC's network as seen
+	// by the syncer (e.g., `http://web.shop.svc:8080/healthz`).
+	URL string `json:"url"`
+}
+
+// ExecProbe is a command run in a container of a pod in the WEC.
+type ExecProbe struct {
+	// `namespace` is the namespace of the pod. Empty means the
+	// namespace of the downsynced object.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// `podSelector` picks the pod: the first, by name, of the running
+	// pods that it selects.
+	PodSelector metav1.LabelSelector `json:"podSelector"`
+
+	// `container` names the container. Empty means the pod's first one.
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// `command` is the command to run, without a shell.
+	Command []string `json:"command"`
+}
+
+// PromQLProbe is an instant query of a Prometheus server in the WEC.
+type PromQLProbe struct {
+	// `url` is the base URL of the Prometheus server's HTTP API
+	// (e.g., `http://prometheus.monitoring.svc:9090`).
+	URL string `json:"url"`
+
+	// `query` is the PromQL expression to evaluate.
+	Query string `json:"query"`
+}
+
+// PlacementRequirements are constraints on the clusters that a workload can go to.
+// They are checked against the capability data in a SyncTarget's status.
+// A SyncTarget that does not report the data needed to check a requirement fails it.
+type PlacementRequirements struct {
+	// `kubernetesVersion` is a range of acceptable Kubernetes versions,
+	// expressed as a comma-separated list of constraints that all must hold.
+	// Each constraint is an operator (one of `=`, `!=`, `<`, `<=`, `>`, `>=`)
+	// followed by a version; for example, `>=1.25, <1.29`.
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// `architectures` lists acceptable node architectures (e.g., `amd64`, `arm64`).
+	// A cluster is acceptable if it has nodes of at least one of these architectures.
+	// Empty list is a special case, it accepts every cluster.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+
+	// `requiredCRDs` lists the names (in the form `{plural}.{group}`)
+	// of CustomResourceDefinitions that must be installed in the cluster.
+	// +optional
+	RequiredCRDs []string `json:"requiredCRDs,omitempty"`
+
+	// `minimumCapacity` is the amount of each resource that must be allocatable
+	// (or, if allocatable is not reported, in the capacity of) the cluster.
+	// +optional
+	MinimumCapacity corev1.ResourceList `json:"minimumCapacity,omitempty"`
+}
+
+// NetworkGuardrails describes NetworkPolicy objects to generate for an EdgePlacement.
+// In each relevant namespace there is one generated NetworkPolicy, which selects
+// every Pod in that namespace and allows only the traffic declared here;
+// with no allowances this is a default-deny policy.
+// The generated objects are written into the workload management space,
+// in the namespace that they govern, with the label
+// `edge.kubestellar.io/guardrail-for` whose value is the EdgePlacement's name;
+// they are downsynced to the EdgePlacement's Locations regardless of `downsync`.
+type NetworkGuardrails struct {
+	// `namespaces` lists the namespaces that get a generated NetworkPolicy.
+	// Empty list is a special case, it means the namespaces listed
+	// explicitly in the `namespaces` of the members of `downsync`.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// `allowIngress` lists the ingress traffic to allow.
+	// +optional
+	AllowIngress []networkingv1.NetworkPolicyIngressRule `json:"allowIngress,omitempty"`
+
+	// `allowEgress` lists the egress traffic to allow.
+	// +optional
+	AllowEgress []networkingv1.NetworkPolicyEgressRule `json:"allowEgress,omitempty"`
 }
 
+// TrustDistribution describes the trust material to distribute with the
+// workload of an EdgePlacement. The material is read from objects in the
+// workload management space and aggregated into generated objects, in each
+// relevant namespace, named `kubestellar-trust-{EdgePlacement name}` and
+// labeled with `edge.kubestellar.io/trust-for` whose value is the
+// EdgePlacement's name:
+//   - a ConfigMap whose `ca-bundle.crt` holds the distinct certificates of
+//     the `caBundles`, if there are any; and
+//   - a Secret of type `kubernetes.io/dockerconfigjson` that merges the
+//     credentials of the `imagePullSecrets`, if there are any.
+//
+// The generated objects are regenerated whenever a source changes (for
+// example, when a certificate is rotated) and are downsynced to the
+// EdgePlacement's Locations regardless of `downsync`.
+// A source that does not exist contributes nothing until it is created.
+type TrustDistribution struct {
+	// `namespaces` lists the namespaces that get the generated objects.
+	// Empty list is a special case, it means the namespaces listed
+	// explicitly in the `namespaces` of the members of `downsync`.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// `caBundles` lists the sources of PEM-encoded CA certificates.
+	// When several sources hold the same certificate, it appears once.
+	// +optional
+	CABundles []TrustSource `json:"caBundles,omitempty"`
+
+	// `imagePullSecrets` lists Secrets of type `kubernetes.io/dockerconfigjson`
+	// or `kubernetes.io/dockercfg`. When several of them hold credentials for
+	// the same registry, the one listed first wins.
+	// +optional
+	ImagePullSecrets []TrustObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// TrustSource identifies a key of a ConfigMap or Secret that holds PEM-encoded
+// CA certificates. Exactly one of `configMap` and `secret` must be given.
+type TrustSource struct {
+	// `configMap` identifies a ConfigMap and a key in its `data`.
+	// +optional
+	ConfigMap *TrustObjectReference `json:"configMap,omitempty"`
+
+	// `secret` identifies a Secret and a key in its `data`.
+	// +optional
+	Secret *TrustObjectReference `json:"secret,omitempty"`
+}
+
+// TrustObjectReference identifies an object, and a key in it, in the
+// workload management space.
+type TrustObjectReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// `key` is the key of the certificates in the object's data.
+	// It defaults to `ca.crt` and is ignored for image pull Secrets.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// TrustForLabelKey is the key of the label on a generated trust object
+// that identifies the EdgePlacement that it was generated for.
+const TrustForLabelKey = "edge.kubestellar.io/trust-for"
+
+// TrustBundleKey is the key, in the generated trust ConfigMap,
+// of the aggregated CA certificates.
+const TrustBundleKey = "ca-bundle.crt"
+
+// GuardrailForLabelKey is the key of the label on a generated NetworkPolicy
+// that identifies the EdgePlacement that it was generated for.
+const GuardrailForLabelKey = "edge.kubestellar.io/guardrail-for"
+
 // ExecutingCountKey is the name (AKA key) of an annotation on a workload object.
 // This annotation is written by the KubeStellar implementation to report on
 // the number of executing copies of that object.
@@ -113,6 +343,76 @@ type EdgePlacementSpec struct {
 // mailboxwatch library and the aspiration for summarization.
 const ExecutingCountKey string = "kubestellar.io/executing-count"
 
+// ExcludeAnnotationKey is the key of an annotation on a workload object.
+// When its value is "true" the object is not downsynced by any
+// EdgePlacement, even those whose what predicate matches it.
+const ExcludeAnnotationKey string = "kubestellar.io/exclude"
+
+// ReprocessAnnotationKey is the key of an annotation on an EdgePlacement.
+// Changing the value of this annotation makes the controllers process the
+// EdgePlacement again, as if its spec had changed. Other changes that do
+// not touch the spec are ignored.
+const ReprocessAnnotationKey = "edge.kubestellar.io/reprocess"
+
+// PausedAnnotationKey is the key of an annotation on an EdgePlacement.
+// While its value is "true" the where-resolver leaves the EdgePlacement's
+// SinglePlacementSlice alone, so the set of destinations stays as it was
+// when the pause began. The what-resolver and placement translator are
+// not affected: changes to the workload still propagate to the frozen
+// set of destinations.
+const PausedAnnotationKey = "edge.kubestellar.io/paused"
+
+// Paused tells whether the EdgePlacement is paused; see PausedAnnotationKey.
+func (in *EdgePlacement) Paused() bool {
+	return in.Annotations[PausedAnnotationKey] == "true"
+}
+
+// SchedulerNameAnnotationKey is the key of an annotation on an
+// EdgePlacement that names the scheduler responsible for choosing its
+// destinations. Absent, empty or DefaultSchedulerName means the
+// where-resolver. For any other name the where-resolver leaves the
+// EdgePlacement's SinglePlacementSlice and status alone, and the named
+// external scheduler writes them instead (see package externalscheduler).
+// The what-resolver and placement translator consume the
+// SinglePlacementSlice the same way whoever writes it.
+const SchedulerNameAnnotationKey = "edge.kubestellar.io/scheduler-name"
+
+// ImmutableFieldPolicyAnnotationKey is the key of an annotation on an
+// EdgePlacement that says what the placement translator does when an
+// update of a downsynced object is rejected because it changes a field
+// that can not be changed (e.g., a Deployment's selector). The value is
+// "Fail" (keep retrying and reporting the failure), "Skip" (leave the
+// copy as it is and report that) or "Recreate" (delete the copy and
+// create it anew). Absent means the placement translator's configured
+// policy for the resource. When several EdgePlacements that select the
+// same object say different things, the most cautious one (Fail, then
+// Skip, then Recreate) applies. A change in this annotation alone is
+// noticed when ReprocessAnnotationKey also changes or when the spec changes.
+const ImmutableFieldPolicyAnnotationKey = "edge.kubestellar.io/immutable-field-policy"
+
+// DefaultSchedulerName is the name of the where-resolver as a scheduler.
+const DefaultSchedulerName = "where-resolver"
+
+// ScheduledByAnnotationKey is the key of an annotation on a
+// SinglePlacementSlice that names the scheduler that last wrote its
+// destinations. External schedulers set it; the where-resolver does not.
+const ScheduledByAnnotationKey = "edge.kubestellar.io/scheduled-by"
+
+// SchedulerName returns the name of the scheduler responsible for the
+// EdgePlacement; see SchedulerNameAnnotationKey.
+func (in *EdgePlacement) SchedulerName() string {
+	if name := in.Annotations[SchedulerNameAnnotationKey]; name != "" {
+		return name
+	}
+	return DefaultSchedulerName
+}
+
+// ExternallyScheduled tells whether the EdgePlacement's destinations are
+// chosen by a scheduler other than the where-resolver.
+func (in *EdgePlacement) ExternallyScheduled() bool {
+	return in.SchedulerName() != DefaultSchedulerName
+}
+
 const validationErrorKeyPrefix string = "validation-error.kubestellar.io/"
 
 // DownsyncOverwriteKey is the name or key of an annotation that can be used
@@ -123,6 +423,23 @@ const validationErrorKeyPrefix string = "validation-error.kubestellar.io/"
 // give it a value of "false" to get "create-only" mode.
 const DownsyncOverwriteKey = "edge.kubestellar.io/downsync-overwrite"
 
+// LocalOverridesKey is the name or key of an annotation that the syncer puts on a
+// downsynced object, in the WEC and in the mailbox space, when some of its fields
+// are locked to WEC-local values that differ from the ones in the WDS.
+// The value is a comma-separated list of the paths of those fields.
+// The divergence is intentional: the fields are locked by the WEC's local overrides.
+const LocalOverridesKey = "edge.kubestellar.io/local-overrides"
+
+// LocalProbesKey is the name or key of an annotation that the placement translator
+// puts on a copy in a mailbox space, holding the JSON of the LocalProbes of the
+// EdgePlacements that select its workload object.
+const LocalProbesKey = "edge.kubestellar.io/local-probes"
+
+// ProbeResultsKey is the name or key of an annotation that the syncer puts on a
+// copy in a mailbox space, holding the JSON of a list of conditions, one per
+// LocalProbe, whose type is the probe's name.
+const ProbeResultsKey = "edge.kubestellar.io/probe-results"
+
 // DownsyncObjectTest is a set of criteria that characterize matching objects.
 // An object matches if:
 // - the `apiGroup` criterion is satisfied;
@@ -219,8 +536,40 @@ type EdgePlacementStatus struct {
 	// `matchingLocationCount` is the number of Locations that satisfy the spec's
 	// `locationSelectors`.
 	MatchingLocationCount int32 `json:"matchingLocationCount"`
+
+	// `conditions` reports on the progress of the binding.
+	// These are maintained and interpreted according to
+	// the conventions of the pkg/conditions library.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// `lastConvergenceDuration` is the time that it took for the most recently
+	// converged spec change to be propagated to all the destinations.
+	// +optional
+	LastConvergenceDuration *metav1.Duration `json:"lastConvergenceDuration,omitempty"`
+
+	// `processedSpecHash` is a hash of the spec, and of the value of the
+	// `edge.kubestellar.io/reprocess` annotation, that were most recently
+	// processed completely by the where-resolver.
+	// +optional
+	ProcessedSpecHash string `json:"processedSpecHash,omitempty"`
 }
 
+// Condition types for EdgePlacement.
+const (
+	// EdgePlacementLocationsResolved means that the Locations selected by
+	// `locationSelectors` have been identified.
+	// The where-resolver sets it to false when a selector is invalid.
+	EdgePlacementLocationsResolved string = "LocationsResolved"
+
+	// EdgePlacementRequirementsSatisfied means that every SyncTarget of
+	// the selected Locations satisfies the `requirements`.
+	// When false, the message explains which SyncTargets were excluded and why.
+	EdgePlacementRequirementsSatisfied string = "RequirementsSatisfied"
+)
+
 // EdgePlacementList is the API type for a list of EdgePlacement
 //
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
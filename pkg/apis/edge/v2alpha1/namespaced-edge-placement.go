@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NamespacedEdgePlacement is an EdgePlacement that can only distribute
+// the objects in its own namespace. It lets an app team that administers
+// a namespace of a workload description space (WDS) distribute its
+// workload without needing RBAC permissions on the cluster-scoped
+// EdgePlacement, which can select objects anywhere in the WDS.
+//
+// The namespaced placement controller derives, from each
+// NamespacedEdgePlacement, a cluster-scoped EdgePlacement in the same WDS
+// whose what-predicate is restricted to the NamespacedEdgePlacement's
+// namespace. The derived EdgePlacement is named
+// `ns-{namespace}-{name}` (shortened with a hash suffix if too long) and
+// bears the annotation `edge.kubestellar.io/namespaced-placement` whose
+// value is `{namespace}/{name}`.
+// Users should not edit the derived EdgePlacement, since the controller
+// will undo that; deleting the NamespacedEdgePlacement deletes it.
+//
+// +crd
+// +genclient
+// +kubebuilder:resource:scope=Namespaced,shortName=nepl
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:labels="kube-bind.io/exported=true"
+// +kubebuilder:printcolumn:name="EdgePlacement",type=string,JSONPath=`.status.edgePlacementName`
+// +kubebuilder:printcolumn:name="Locations",type=integer,JSONPath=`.status.matchingLocationCount`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type NamespacedEdgePlacement struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// `spec` is like that of an EdgePlacement, minus the parts that
+	// could reach outside of this object's namespace.
+	// +optional
+	Spec NamespacedEdgePlacementSpec `json:"spec,omitempty"`
+
+	// +optional
+	Status NamespacedEdgePlacementStatus `json:"status,omitempty"`
+}
+
+// NamespacedEdgePlacementSpec is an EdgePlacementSpec without upsync,
+// in which every namespace is implicitly the one of the NamespacedEdgePlacement.
+type NamespacedEdgePlacementSpec struct {
+	// `locationSelectors` identifies the relevant Location objects in terms of their labels,
+	// as in an EdgePlacement.
+	LocationSelectors []metav1.LabelSelector `json:"locationSelectors,omitempty"`
+
+	// `downsync` selects the objects to bind with the selected Locations for downsync,
+	// as in an EdgePlacement, except that the `namespaces` and `namespaceSelectors`
+	// of each test are ignored: only the objects in this object's namespace match.
+	// +optional
+	Downsync []DownsyncObjectTest `json:"downsync,omitempty"`
+
+	// `wantSingletonReportedState` is as in an EdgePlacement.
+	// +optional
+	WantSingletonReportedState bool `json:"wantSingletonReportedState,omitempty"`
+
+	// `includeDependencies` is as in an EdgePlacement.
+	// +optional
+	IncludeDependencies bool `json:"includeDependencies,omitempty"`
+
+	// `networkGuardrails` is as in an EdgePlacement, except that its `namespaces`
+	// is ignored: the one governed namespace is this object's.
+	// +optional
+	NetworkGuardrails *NetworkGuardrails `json:"networkGuardrails,omitempty"`
+
+	// `requirements` is as in an EdgePlacement.
+	// +optional
+	Requirements *PlacementRequirements `json:"requirements,omitempty"`
+
+	// `localProbes` is as in an EdgePlacement, except that the `namespace`
+	// of each `exec` probe is ignored: the pod is in this object's namespace.
+	// +optional
+	LocalProbes []LocalProbe `json:"localProbes,omitempty"`
+
+	// `allowAll` is as in an EdgePlacement; here only an empty member
+	// of `locationSelectors` counts as selecting very broadly.
+	// +optional
+	AllowAll bool `json:"allowAll,omitempty"`
+}
+
+// NamespacedPlacementAnnotationKey is the key of the annotation on an
+// EdgePlacement derived from a NamespacedEdgePlacement that identifies the
+// latter; the value is `{namespace}/{name}`.
+const NamespacedPlacementAnnotationKey string = "edge.kubestellar.io/namespaced-placement"
+
+// Condition types for NamespacedEdgePlacement.
+// Besides these, the conditions of the derived EdgePlacement are copied here.
+const (
+	// NamespacedEdgePlacementDerived means that the derived EdgePlacement
+	// is up to date with the spec.
+	NamespacedEdgePlacementDerived string = "Derived"
+)
+
+// NamespacedEdgePlacementStatus reports on the derived EdgePlacement.
+type NamespacedEdgePlacementStatus struct {
+	// `observedGeneration` is the generation of the spec that this status is about.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// `edgePlacementName` is the name of the derived EdgePlacement.
+	// +optional
+	EdgePlacementName string `json:"edgePlacementName,omitempty"`
+
+	// `matchingLocationCount` is copied from the status of the derived EdgePlacement.
+	// +optional
+	MatchingLocationCount int32 `json:"matchingLocationCount,omitempty"`
+
+	// `conditions` reports on the derivation, plus the conditions of the
+	// derived EdgePlacement.
+	// These are maintained and interpreted according to
+	// the conventions of the pkg/conditions library.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// NamespacedEdgePlacementList is the API type for a list of NamespacedEdgePlacement
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type NamespacedEdgePlacementList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []NamespacedEdgePlacement `json:"items"`
+}
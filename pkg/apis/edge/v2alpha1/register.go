@@ -59,6 +59,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&SyncTargetList{},
 		&Location{},
 		&LocationList{},
+		&ClusterRegistration{},
+		&ClusterRegistrationList{},
+		&TenantUsage{},
+		&TenantUsageList{},
+		&NamespacedEdgePlacement{},
+		&NamespacedEdgePlacementList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
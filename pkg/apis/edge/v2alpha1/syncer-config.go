@@ -145,6 +145,26 @@ type ClusterScopeDownsyncResource struct {
 }
 
 type SyncerConfigStatus struct {
+	// ClusterID is the ID of the WEC, as published in its `cluster.clusterset.k8s.io` ClusterProperty.
+	// +optional
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// ClusterSet is the name of the ClusterSet of the WEC, as published in its `clusterset.k8s.io` ClusterProperty.
+	// +optional
+	ClusterSet string `json:"clusterSet,omitempty"`
+
+	// KubernetesVersion is the version of Kubernetes that the WEC runs (e.g., `v1.27.3`).
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// Architectures lists the architectures of the WEC's nodes.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+
+	// CRDs lists the names of the CustomResourceDefinitions installed in the WEC.
+	// +optional
+	CRDs []string `json:"crds,omitempty"`
+
 	// A timestamp indicating when the syncer last reported status.
 	// +optional
 	LastSyncerHeartbeatTime *metav1.Time `json:"lastSyncerHeartbeatTime,omitempty"`
@@ -95,6 +95,28 @@ type SyncTargetStatus struct {
 	// +optional
 	Capacity *corev1.ResourceList `json:"capacity,omitempty"`
 
+	// KubernetesVersion is the version of Kubernetes that the cluster runs (e.g., `v1.27.3`).
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// Architectures lists the architectures of the cluster's nodes.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+
+	// CRDs lists the names of the CustomResourceDefinitions installed in the cluster.
+	// +optional
+	CRDs []string `json:"crds,omitempty"`
+
+	// ClusterID is the ID of the cluster, as given by the `cluster.clusterset.k8s.io`
+	// ClusterProperty of the sig-multicluster About API and reported by the syncer.
+	// +optional
+	ClusterID string `json:"clusterID,omitempty"`
+
+	// ClusterSet is the name of the ClusterSet that the cluster belongs to, as given
+	// by the `clusterset.k8s.io` ClusterProperty and reported by the syncer.
+	// +optional
+	ClusterSet string `json:"clusterSet,omitempty"`
+
 	// Current processing state of the SyncTarget.
 	// +optional
 	Conditions conditionsv1alpha1.Conditions `json:"conditions,omitempty"`
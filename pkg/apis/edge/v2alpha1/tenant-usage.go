@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TenantUsageName is the name of the one TenantUsage in a workload description space.
+const TenantUsageName = "usage"
+
+// TenantUsage reports how much of KubeStellar a workload description
+// space (WDS) is consuming, so that platform teams can do chargeback
+// and enforce fairness between tenants.
+// There is one, named `usage`, in each WDS that has EdgePlacements.
+// It is maintained by the placement translator, which periodically
+// replaces the status; users should not write it.
+// The same numbers are exposed as Prometheus metrics labeled by space.
+//
+// +crd
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:resource:scope=Cluster,shortName=tu
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Placements",type=integer,JSONPath=`.status.edgePlacements`
+// +kubebuilder:printcolumn:name="Destinations",type=integer,JSONPath=`.status.destinations`
+// +kubebuilder:printcolumn:name="Copies",type=integer,JSONPath=`.status.objectCopies`
+// +kubebuilder:printcolumn:name="Updated",type="date",JSONPath=".status.lastUpdateTime"
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type TenantUsage struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Status TenantUsageStatus `json:"status,omitempty"`
+}
+
+// TenantUsageStatus is one report of the consumption of a WDS.
+type TenantUsageStatus struct {
+	// `lastUpdateTime` is when this report was made.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// `edgePlacements` is the number of EdgePlacements in the WDS.
+	EdgePlacements int32 `json:"edgePlacements"`
+
+	// `destinations` is the number of mailbox spaces that
+	// objects from the WDS are going to.
+	Destinations int32 `json:"destinations"`
+
+	// `objects` is the number of objects in the WDS that are
+	// going to at least one destination.
+	Objects int32 `json:"objects"`
+
+	// `objectCopies` is the number of (object, destination) pairs,
+	// that is, the number of copies of objects from the WDS that
+	// are kept in mailbox spaces.
+	ObjectCopies int32 `json:"objectCopies"`
+
+	// `windowStart` is the start of the time window that
+	// `mailboxWriteBytes` covers; the window ends at `lastUpdateTime`.
+	// +optional
+	WindowStart *metav1.Time `json:"windowStart,omitempty"`
+
+	// `mailboxWriteBytes` is the total size, in bytes, of the objects
+	// written into mailbox spaces on behalf of the WDS during the window.
+	MailboxWriteBytes int64 `json:"mailboxWriteBytes"`
+}
+
+// TenantUsageList is the API type for a list of TenantUsage
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type TenantUsageList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []TenantUsage `json:"items"`
+}
@@ -27,6 +27,14 @@ const (
 
 	// PlacementAnnotationKey is the label key for the label holding a PlacementAnnotation struct.
 	PlacementAnnotationKey = "scheduling.kcp.io/placement"
+
+	// PodSecurityEnforceLabelKey is the key of a label on a Location that declares the
+	// Pod Security Standards level (privileged, baseline, or restricted) that
+	// the destination's admission enforces.
+	// When present, downsynced objects that bear a PodSpec violating that level
+	// are held back at the center rather than sent to the destination,
+	// and a DestinationHeldBack Event on the source object says why.
+	PodSecurityEnforceLabelKey = "edge.kubestellar.io/pod-security-enforce"
 )
 
 // Location represents a set of instances of a scheduling resource type acting a target
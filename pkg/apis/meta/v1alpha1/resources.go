@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // APIResource describes a kind of API object.
@@ -68,6 +69,39 @@ type APIResourceSpec struct {
 	// Typically 0 or 1 of these.
 	// +optional
 	Definers []Definer `json:"definers,omitempty" protobuf:"bytes,11,opt,name=definers"`
+
+	// deprecation is present if and only if this version of the resource is deprecated.
+	// +optional
+	Deprecation *APIResourceDeprecation `json:"deprecation,omitempty" protobuf:"bytes,12,opt,name=deprecation"`
+
+	// preferred tells whether this is the version of the resource that
+	// discovery prefers. Other versions are only listed when asked for.
+	// +optional
+	Preferred bool `json:"preferred,omitempty" protobuf:"varint,13,opt,name=preferred"`
+
+	// openAPISchema is the OpenAPI v3 schema of the resource's kind, as
+	// served by the cluster, with the references to other schemas
+	// replaced by what they refer to (except where that would recurse).
+	// Present only when the informer was asked to attach schemas and the
+	// cluster serves one; never present for a subresource.
+	// +optional
+	OpenAPISchema *runtime.RawExtension `json:"openAPISchema,omitempty" protobuf:"bytes,14,opt,name=openAPISchema"`
+}
+
+// APIResourceDeprecation describes the deprecation of a version of a resource.
+type APIResourceDeprecation struct {
+	// replacement identifies the group, version, and kind to use instead, if known.
+	// +optional
+	Replacement *metav1.GroupVersionKind `json:"replacement,omitempty"`
+
+	// removedInRelease is the Kubernetes release (e.g., "1.25") that
+	// no longer serves this version, if known.
+	// +optional
+	RemovedInRelease string `json:"removedInRelease,omitempty"`
+
+	// warning is the deprecation warning supplied by the definer, if any.
+	// +optional
+	Warning string `json:"warning,omitempty"`
 }
 
 // Definer is a reference to an object that defines a resource.
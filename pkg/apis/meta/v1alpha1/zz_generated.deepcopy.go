@@ -53,6 +53,27 @@ func (in *APIResource) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIResourceDeprecation) DeepCopyInto(out *APIResourceDeprecation) {
+	*out = *in
+	if in.Replacement != nil {
+		in, out := &in.Replacement, &out.Replacement
+		*out = new(v1.GroupVersionKind)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIResourceDeprecation.
+func (in *APIResourceDeprecation) DeepCopy() *APIResourceDeprecation {
+	if in == nil {
+		return nil
+	}
+	out := new(APIResourceDeprecation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *APIResourceList) DeepCopyInto(out *APIResourceList) {
 	*out = *in
@@ -105,6 +126,21 @@ func (in *APIResourceSpec) DeepCopyInto(out *APIResourceSpec) {
 			}
 		}
 	}
+	if in.Definers != nil {
+		in, out := &in.Definers, &out.Definers
+		*out = make([]Definer, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deprecation != nil {
+		in, out := &in.Deprecation, &out.Deprecation
+		*out = new(APIResourceDeprecation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OpenAPISchema != nil {
+		in, out := &in.OpenAPISchema, &out.OpenAPISchema
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
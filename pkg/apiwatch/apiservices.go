@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// APIServiceGVR identifies the resource of the APIServices that
+// register aggregated apiservers. Use it to make the dynamic informer
+// that an APIServiceAnalyzer wraps.
+var APIServiceGVR = schema.GroupVersionResource{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}
+
+// AllResources, as the Resource of a GroupVersionResource that a
+// ResourceDefinitionEnumerator reports, stands for every resource in
+// the GroupVersion.
+const AllResources = "*"
+
+// APIServiceAnalyzer is a ResourceDefinitionSupplier for APIServices,
+// delivered as *unstructured.Unstructured (e.g., by a dynamic informer
+// on APIServiceGVR). An APIService that delegates to a service (i.e.,
+// to an aggregated apiserver) defines every resource in its
+// GroupVersion; a local one (which the apiserver makes for its own
+// groups and for those of CRDs) defines nothing. Every change to an
+// APIService, notably in its Available condition, invalidates discovery.
+type APIServiceAnalyzer struct {
+	ObjectNotifier
+}
+
+var _ ResourceDefinitionSupplier = APIServiceAnalyzer{}
+
+func (asa APIServiceAnalyzer) GetGVK(obj any) schema.GroupVersionKind {
+	return APIServiceGVR.GroupVersion().WithKind("APIService")
+}
+
+func (asa APIServiceAnalyzer) EnumerateDefinedResources(obj any) ResourceDefinitionEnumerator {
+	apiService := obj.(*unstructured.Unstructured)
+	group, _, _ := unstructured.NestedString(apiService.Object, "spec", "group")
+	version, _, _ := unstructured.NestedString(apiService.Object, "spec", "version")
+	service, _, _ := unstructured.NestedMap(apiService.Object, "spec", "service")
+	if service == nil || version == "" {
+		return enumerateNothing
+	}
+	return func(consumer func(metav1.GroupVersionResource)) {
+		consumer(metav1.GroupVersionResource{Group: group, Version: version, Resource: AllResources})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+func testAPIService(name, group, version string, aggregated bool) *unstructured.Unstructured {
+	spec := map[string]any{"group": group, "version": version}
+	if aggregated {
+		spec["service"] = map[string]any{"namespace": "kube-system", "name": "metrics-server"}
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apiregistration.k8s.io/v1",
+		"kind":       "APIService",
+		"metadata":   map[string]any{"name": name},
+		"spec":       spec,
+	}}
+}
+
+func TestAPIServiceDefiners(t *testing.T) {
+	rlw := &resourcesListWatcher{
+		logger:      klog.Background(),
+		definitions: relindex.NewRelation2[objectID, metav1.GroupVersionResource](),
+	}
+	analyzer := APIServiceAnalyzer{}
+	aggregated := testAPIService("v1beta1.metrics.k8s.io", "metrics.k8s.io", "v1beta1", true)
+	local := testAPIService("v1.apps", "apps", "v1", false)
+	setDefiner := func(obj *unstructured.Unstructured, set bool) {
+		apiVersion, kind := analyzer.GetGVK(obj).ToAPIVersionAndKind()
+		var enumr ResourceDefinitionEnumerator = enumerateNothing
+		if set {
+			enumr = analyzer.EnumerateDefinedResources(obj)
+		}
+		rlw.setDefinerLocked(objectID{apiVersion, kind, obj.GetName()}, enumr)
+	}
+	setDefiner(aggregated, true)
+	setDefiner(local, true)
+	enumerate := func(gv schema.GroupVersion, names ...string) map[string][]ksmetav1a1.Definer {
+		mrs := []metav1.APIResource{}
+		for _, name := range names {
+			mrs = append(mrs, metav1.APIResource{Name: name})
+		}
+		ans := map[string][]ksmetav1a1.Definer{}
+		rlw.enumAPIResourcesLocked("1", gv, mrs, func(spec ksmetav1a1.APIResourceSpec) {
+			ans[spec.Name] = spec.Definers
+		})
+		return ans
+	}
+	metricsDefiner := []ksmetav1a1.Definer{{Kind: "APIService", Name: "v1beta1.metrics.k8s.io"}}
+	expected := map[string][]ksmetav1a1.Definer{"pods": metricsDefiner, "nodes": metricsDefiner}
+	if actual := enumerate(schema.GroupVersion{Group: "metrics.k8s.io", Version: "v1beta1"}, "pods", "nodes"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	expected = map[string][]ksmetav1a1.Definer{"deployments": {}}
+	if actual := enumerate(schema.GroupVersion{Group: "apps", Version: "v1"}, "deployments"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected a local APIService to define nothing, got %v", actual)
+	}
+	setDefiner(aggregated, false)
+	expected = map[string][]ksmetav1a1.Definer{"pods": {}}
+	if actual := enumerate(schema.GroupVersion{Group: "metrics.k8s.io", Version: "v1beta1"}, "pods"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected no definer after deletion, got %v", actual)
+	}
+}
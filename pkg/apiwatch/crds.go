@@ -20,6 +20,8 @@ import (
 	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
 )
 
 type CRDAnalyzer struct {
@@ -27,6 +29,7 @@ type CRDAnalyzer struct {
 }
 
 var _ ResourceDefinitionSupplier = CRDAnalyzer{}
+var _ ResourceDeprecationSupplier = CRDAnalyzer{}
 
 func (crda CRDAnalyzer) GetGVK(obj any) schema.GroupVersionKind {
 	return apiext.SchemeGroupVersion.WithKind("CustomResourceDefinition")
@@ -41,3 +44,20 @@ func (crda CRDAnalyzer) EnumerateDefinedResources(obj any) ResourceDefinitionEnu
 		}
 	}
 }
+
+// EnumerateDeprecations reports the versions that the CRD marks as deprecated.
+// A CRD does not say when a version will be removed nor what replaces it.
+func (crda CRDAnalyzer) EnumerateDeprecations(obj any, consumer func(metav1.GroupVersionResource, ksmetav1a1.APIResourceDeprecation)) {
+	crd := obj.(*apiext.CustomResourceDefinition)
+	for _, version := range crd.Spec.Versions {
+		if !version.Deprecated {
+			continue
+		}
+		gvr := metav1.GroupVersionResource{Group: crd.Spec.Group, Version: version.Name, Resource: crd.Status.AcceptedNames.Plural}
+		deprecation := ksmetav1a1.APIResourceDeprecation{}
+		if version.DeprecationWarning != nil {
+			deprecation.Warning = *version.DeprecationWarning
+		}
+		consumer(gvr, deprecation)
+	}
+}
@@ -24,40 +24,12 @@ import (
 )
 
 func (rlw *resourcesListWatcher) setDefinerLocked(oid objectID, enumr ResourceDefinitionEnumerator) {
-	oldRscs := ensureMap(rlw.definerToRscs[oid])
-	newRscs := GoSet[metav1.GroupVersionResource]{}
-	rlw.logger.V(4).Info("Start setDefinerLocked", "oid", oid, "oldRscs", oldRscs)
+	var newRscs []metav1.GroupVersionResource
 	enumr(func(gvr metav1.GroupVersionResource) {
-		newRscs[gvr] = Empty{}
-		if _, had := oldRscs[gvr]; !had {
-			definers := ensureMap(rlw.rscToDefiners[gvr])
-			definers[oid] = Empty{}
-			rlw.rscToDefiners[gvr] = definers
-			rlw.logger.V(4).Info("Adding definition", "gvr", gvr, "oid", oid)
-		}
+		newRscs = append(newRscs, gvr)
 	})
-	for oldRsc := range oldRscs {
-		if _, have := newRscs[oldRsc]; !have {
-			definers := rlw.rscToDefiners[oldRsc]
-			rlw.logger.V(4).Info("Removing definition", "oldRsc", oldRsc, "oid", oid)
-			delete(definers, oid)
-			if len(definers) == 0 {
-				delete(rlw.rscToDefiners, oldRsc)
-				rlw.logger.V(4).Info("No more definers", "oldRsc", oldRsc)
-			} else {
-				rlw.rscToDefiners[oldRsc] = definers
-			}
-		}
-	}
-	rlw.definerToRscs[oid] = newRscs
-	rlw.logger.V(4).Info("Finish setDefinerLocked", "oid", oid, "newRscs", newRscs)
-}
-
-func ensureMap[Key comparable](in GoSet[Key]) GoSet[Key] {
-	if in != nil {
-		return in
-	}
-	return GoSet[Key]{}
+	added, removed := rlw.definitions.SetSecondsOf(oid, newRscs)
+	rlw.logger.V(4).Info("Set definitions", "oid", oid, "added", added, "removed", removed)
 }
 
 func MarshalMap[Key comparable, Val any](it map[Key]Val) ([]byte, error) {
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// ResourceDeprecationSupplier is optionally implemented by a ResourceDefinitionSupplier
+// that can tell which of the resource versions defined by an object are deprecated.
+type ResourceDeprecationSupplier interface {
+	EnumerateDeprecations(definer any, consumer func(metav1.GroupVersionResource, ksmetav1a1.APIResourceDeprecation))
+}
+
+func deprecatedIn(removedInRelease, group, version, kind string) ksmetav1a1.APIResourceDeprecation {
+	ans := ksmetav1a1.APIResourceDeprecation{RemovedInRelease: removedInRelease}
+	if kind != "" {
+		ans.Replacement = &metav1.GroupVersionKind{Group: group, Version: version, Kind: kind}
+	}
+	return ans
+}
+
+// BuiltinDeprecations lists the deprecated versions of the built-in Kubernetes
+// resources, from https://kubernetes.io/docs/reference/using-api/deprecation-guide/ .
+var BuiltinDeprecations = map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", Resource: "flowschemas"}:                     deprecatedIn("1.29", "flowcontrol.apiserver.k8s.io", "v1", "FlowSchema"),
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", Resource: "prioritylevelconfigurations"}:     deprecatedIn("1.29", "flowcontrol.apiserver.k8s.io", "v1", "PriorityLevelConfiguration"),
+	{Group: "storage.k8s.io", Version: "v1beta1", Resource: "csistoragecapacities"}:                          deprecatedIn("1.27", "storage.k8s.io", "v1", "CSIStorageCapacity"),
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", Resource: "flowschemas"}:                     deprecatedIn("1.26", "flowcontrol.apiserver.k8s.io", "v1beta3", "FlowSchema"),
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", Resource: "prioritylevelconfigurations"}:     deprecatedIn("1.26", "flowcontrol.apiserver.k8s.io", "v1beta3", "PriorityLevelConfiguration"),
+	{Group: "autoscaling", Version: "v2beta2", Resource: "horizontalpodautoscalers"}:                         deprecatedIn("1.26", "autoscaling", "v2", "HorizontalPodAutoscaler"),
+	{Group: "batch", Version: "v1beta1", Resource: "cronjobs"}:                                               deprecatedIn("1.25", "batch", "v1", "CronJob"),
+	{Group: "discovery.k8s.io", Version: "v1beta1", Resource: "endpointslices"}:                              deprecatedIn("1.25", "discovery.k8s.io", "v1", "EndpointSlice"),
+	{Group: "events.k8s.io", Version: "v1beta1", Resource: "events"}:                                         deprecatedIn("1.25", "events.k8s.io", "v1", "Event"),
+	{Group: "autoscaling", Version: "v2beta1", Resource: "horizontalpodautoscalers"}:                         deprecatedIn("1.25", "autoscaling", "v2", "HorizontalPodAutoscaler"),
+	{Group: "policy", Version: "v1beta1", Resource: "poddisruptionbudgets"}:                                  deprecatedIn("1.25", "policy", "v1", "PodDisruptionBudget"),
+	{Group: "policy", Version: "v1beta1", Resource: "podsecuritypolicies"}:                                   deprecatedIn("1.25", "", "", ""),
+	{Group: "node.k8s.io", Version: "v1beta1", Resource: "runtimeclasses"}:                                   deprecatedIn("1.25", "node.k8s.io", "v1", "RuntimeClass"),
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", Resource: "mutatingwebhookconfigurations"}:   deprecatedIn("1.22", "admissionregistration.k8s.io", "v1", "MutatingWebhookConfiguration"),
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", Resource: "validatingwebhookconfigurations"}: deprecatedIn("1.22", "admissionregistration.k8s.io", "v1", "ValidatingWebhookConfiguration"),
+	{Group: "apiextensions.k8s.io", Version: "v1beta1", Resource: "customresourcedefinitions"}:               deprecatedIn("1.22", "apiextensions.k8s.io", "v1", "CustomResourceDefinition"),
+	{Group: "apiregistration.k8s.io", Version: "v1beta1", Resource: "apiservices"}:                           deprecatedIn("1.22", "apiregistration.k8s.io", "v1", "APIService"),
+	{Group: "authentication.k8s.io", Version: "v1beta1", Resource: "tokenreviews"}:                           deprecatedIn("1.22", "authentication.k8s.io", "v1", "TokenReview"),
+	{Group: "authorization.k8s.io", Version: "v1beta1", Resource: "subjectaccessreviews"}:                    deprecatedIn("1.22", "authorization.k8s.io", "v1", "SubjectAccessReview"),
+	{Group: "certificates.k8s.io", Version: "v1beta1", Resource: "certificatesigningrequests"}:               deprecatedIn("1.22", "certificates.k8s.io", "v1", "CertificateSigningRequest"),
+	{Group: "coordination.k8s.io", Version: "v1beta1", Resource: "leases"}:                                   deprecatedIn("1.22", "coordination.k8s.io", "v1", "Lease"),
+	{Group: "extensions", Version: "v1beta1", Resource: "ingresses"}:                                         deprecatedIn("1.22", "networking.k8s.io", "v1", "Ingress"),
+	{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"}:                                  deprecatedIn("1.22", "networking.k8s.io", "v1", "Ingress"),
+	{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingressclasses"}:                             deprecatedIn("1.22", "networking.k8s.io", "v1", "IngressClass"),
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Resource: "clusterroles"}:                       deprecatedIn("1.22", "rbac.authorization.k8s.io", "v1", "ClusterRole"),
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Resource: "clusterrolebindings"}:                deprecatedIn("1.22", "rbac.authorization.k8s.io", "v1", "ClusterRoleBinding"),
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Resource: "roles"}:                              deprecatedIn("1.22", "rbac.authorization.k8s.io", "v1", "Role"),
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Resource: "rolebindings"}:                       deprecatedIn("1.22", "rbac.authorization.k8s.io", "v1", "RoleBinding"),
+	{Group: "scheduling.k8s.io", Version: "v1beta1", Resource: "priorityclasses"}:                            deprecatedIn("1.22", "scheduling.k8s.io", "v1", "PriorityClass"),
+	{Group: "storage.k8s.io", Version: "v1beta1", Resource: "csidrivers"}:                                    deprecatedIn("1.22", "storage.k8s.io", "v1", "CSIDriver"),
+	{Group: "storage.k8s.io", Version: "v1beta1", Resource: "csinodes"}:                                      deprecatedIn("1.22", "storage.k8s.io", "v1", "CSINode"),
+	{Group: "storage.k8s.io", Version: "v1beta1", Resource: "storageclasses"}:                                deprecatedIn("1.22", "storage.k8s.io", "v1", "StorageClass"),
+	{Group: "storage.k8s.io", Version: "v1beta1", Resource: "volumeattachments"}:                             deprecatedIn("1.22", "storage.k8s.io", "v1", "VolumeAttachment"),
+}
+
+// setDeprecationsLocked records the deprecations declared by the given definer.
+func (rlw *resourcesListWatcher) setDeprecationsLocked(oid objectID, obj any, supplier ResourceDeprecationSupplier) {
+	if supplier == nil {
+		delete(rlw.definerToDeprecations, oid)
+		return
+	}
+	deprecations := map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{}
+	supplier.EnumerateDeprecations(obj, func(gvr metav1.GroupVersionResource, deprecation ksmetav1a1.APIResourceDeprecation) {
+		deprecations[gvr] = deprecation
+	})
+	if len(deprecations) == 0 {
+		delete(rlw.definerToDeprecations, oid)
+	} else {
+		rlw.definerToDeprecations[oid] = deprecations
+	}
+}
+
+// deprecationLocked returns the deprecation, if any, of the given resource version.
+// Deprecations declared by definers take precedence over the built-in table.
+func (rlw *resourcesListWatcher) deprecationLocked(gvr metav1.GroupVersionResource) *ksmetav1a1.APIResourceDeprecation {
+	for _, definer := range rlw.definitions.FirstsOf(gvr) {
+		if deprecation, found := rlw.definerToDeprecations[definer][gvr]; found {
+			return &deprecation
+		}
+	}
+	if deprecation, found := BuiltinDeprecations[gvr]; found {
+		return deprecation.DeepCopy()
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"reflect"
+	"testing"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+func TestCRDDeprecations(t *testing.T) {
+	warning := "use v2"
+	crd := &apiext.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+		Spec: apiext.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Versions: []apiext.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Deprecated: true},
+				{Name: "v1", Deprecated: true, DeprecationWarning: &warning},
+				{Name: "v2"},
+			},
+		},
+		Status: apiext.CustomResourceDefinitionStatus{AcceptedNames: apiext.CustomResourceDefinitionNames{Plural: "widgets"}},
+	}
+	got := map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{}
+	CRDAnalyzer{}.EnumerateDeprecations(crd, func(gvr metav1.GroupVersionResource, deprecation ksmetav1a1.APIResourceDeprecation) {
+		got[gvr] = deprecation
+	})
+	expected := map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{
+		{Group: "example.com", Version: "v1alpha1", Resource: "widgets"}: {},
+		{Group: "example.com", Version: "v1", Resource: "widgets"}:       {Warning: warning},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDeprecationLookup(t *testing.T) {
+	definer := objectID{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition", Name: "widgets.example.com"}
+	widgets := metav1.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	cronjobs := metav1.GroupVersionResource{Group: "batch", Version: "v1beta1", Resource: "cronjobs"}
+	deployments := metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	rlw := &resourcesListWatcher{
+		definitions: relindex.NewRelation2[objectID, metav1.GroupVersionResource](),
+		definerToDeprecations: map[objectID]map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{
+			definer: {
+				widgets:  {Warning: "use v2"},
+				cronjobs: {Warning: "from the definer"},
+			},
+		},
+	}
+	rlw.definitions.Add(definer, widgets)
+	rlw.definitions.Add(definer, cronjobs)
+	if dep := rlw.deprecationLocked(widgets); dep == nil || dep.Warning != "use v2" {
+		t.Errorf("Expected the definer's deprecation of %v, got %+v", widgets, dep)
+	}
+	if dep := rlw.deprecationLocked(cronjobs); dep == nil || dep.Warning != "from the definer" {
+		t.Errorf("Expected the definer's deprecation of %v to take precedence, got %+v", cronjobs, dep)
+	}
+	rlw.definitions.Remove(definer, cronjobs)
+	if dep := rlw.deprecationLocked(cronjobs); dep == nil || dep.RemovedInRelease != "1.25" {
+		t.Errorf("Expected the built-in deprecation of %v, got %+v", cronjobs, dep)
+	}
+	if dep := rlw.deprecationLocked(deployments); dep != nil {
+		t.Errorf("Expected no deprecation of %v, got %+v", deployments, dep)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// apiResourceFieldFuncs extract the fields of an APIResource that field
+// selectors may test.
+var apiResourceFieldFuncs = map[string]func(*ksmetav1a1.APIResource) string{
+	"metadata.name":   func(ar *ksmetav1a1.APIResource) string { return ar.Name },
+	"spec.name":       func(ar *ksmetav1a1.APIResource) string { return ar.Spec.Name },
+	"spec.group":      func(ar *ksmetav1a1.APIResource) string { return ar.Spec.Group },
+	"spec.version":    func(ar *ksmetav1a1.APIResource) string { return ar.Spec.Version },
+	"spec.kind":       func(ar *ksmetav1a1.APIResource) string { return ar.Spec.Kind },
+	"spec.namespaced": func(ar *ksmetav1a1.APIResource) string { return strconv.FormatBool(ar.Spec.Namespaced) },
+}
+
+// APIResourceFields returns the fields of the given APIResource that
+// field selectors may test.
+func APIResourceFields(ar *ksmetav1a1.APIResource) fields.Set {
+	ans := make(fields.Set, len(apiResourceFieldFuncs))
+	for field, get := range apiResourceFieldFuncs {
+		ans[field] = get(ar)
+	}
+	return ans
+}
+
+// fieldSelector returns the field selector that a List or Watch with
+// the given options has to apply: the one in the options and the one
+// in the informer's options, both. Fails with a BadRequest error if
+// either does not parse or tests an unsupported field.
+func (rlw *resourcesListWatcher) fieldSelector(opts metav1.ListOptions) (fields.Selector, error) {
+	sel := rlw.fieldSel
+	if sel == nil {
+		sel = fields.Everything()
+	}
+	if opts.FieldSelector != "" {
+		parsed, err := fields.ParseSelector(opts.FieldSelector)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid field selector %q: %v", opts.FieldSelector, err))
+		}
+		sel = fields.AndSelectors(sel, parsed)
+	}
+	for _, req := range sel.Requirements() {
+		if _, ok := apiResourceFieldFuncs[req.Field]; !ok {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("field selector on %q is not supported for APIResources; supported fields are %s", req.Field, supportedFields()))
+		}
+	}
+	return sel, nil
+}
+
+func supportedFields() string {
+	names := make([]string, 0, len(apiResourceFieldFuncs))
+	for field := range apiResourceFieldFuncs {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// filterByFields returns the items that the given selector matches,
+// reusing the given slice.
+func filterByFields(items []ksmetav1a1.APIResource, sel fields.Selector) []ksmetav1a1.APIResource {
+	if sel.Empty() {
+		return items
+	}
+	ans := items[:0]
+	for idx := range items {
+		if sel.Matches(APIResourceFields(&items[idx])) {
+			ans = append(ans, items[idx])
+		}
+	}
+	return ans
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/klog/v2"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+func TestFieldSelectors(t *testing.T) {
+	rlw := &resourcesListWatcher{logger: klog.Background(), allVersions: true, cache: newStubDiscovery(),
+		definitions: relindex.NewRelation2[objectID, metav1.GroupVersionResource]()}
+	list := func(selector string) []string {
+		obj, err := rlw.List(metav1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			t.Fatalf("List with %q: %v", selector, err)
+		}
+		var names []string
+		for _, item := range obj.(*ksmetav1a1.APIResourceList).Items {
+			names = append(names, item.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	for _, tc := range []struct {
+		selector string
+		expected []string
+	}{
+		{"", []string{"example.com:v1:widgets", "example.com:v1beta1:gadgets", "example.com:v1beta1:widgets"}},
+		{"spec.version=v1beta1", []string{"example.com:v1beta1:gadgets", "example.com:v1beta1:widgets"}},
+		{"spec.namespaced=false", []string{"example.com:v1beta1:gadgets"}},
+		{"spec.kind=Widget,spec.version!=v1beta1", []string{"example.com:v1:widgets"}},
+		{"spec.group=other.io", nil},
+	} {
+		if got := list(tc.selector); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Selector %q: expected %v, got %v", tc.selector, tc.expected, got)
+		}
+	}
+	if stats := rlw.getStats(); stats.Resources != 3 {
+		t.Errorf("Expected the stats to count every resource, got %+v", stats)
+	}
+
+	rlw.fieldSel = fields.OneTermEqualSelector("spec.group", "example.com")
+	if got := list("spec.kind=Gadget"); !reflect.DeepEqual(got, []string{"example.com:v1beta1:gadgets"}) {
+		t.Errorf("Expected the informer's selector and the List's to combine, got %v", got)
+	}
+	if _, err := rlw.List(metav1.ListOptions{FieldSelector: "spec.verbs=get"}); !apierrors.IsBadRequest(err) {
+		t.Errorf("Expected a BadRequest error for an unsupported field, got %v", err)
+	}
+	if _, err := rlw.Watch(metav1.ListOptions{FieldSelector: "spec.kind"}); !apierrors.IsBadRequest(err) {
+		t.Errorf("Expected a BadRequest error for a malformed selector, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	upstreamcache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// DynamicInformerManagerOptions are the options of a DynamicInformerManager.
+type DynamicInformerManagerOptions struct {
+	// Selects says which resources to watch. Only resources that can be
+	// listed and watched are considered. Nil selects them all.
+	// When what Selects says changes, call Resync.
+	Selects func(*ksmetav1a1.APIResource) bool
+
+	// Namespace restricts the informers to one namespace; empty means all.
+	Namespace string
+
+	ResyncPeriod     time.Duration
+	Indexers         upstreamcache.Indexers
+	TweakListOptions dynamicinformer.TweakListOptionsFunc
+
+	// OnStart is called each time an informer is started, before it
+	// has synced. The APIResource is the one it was started for.
+	OnStart func(ar *ksmetav1a1.APIResource, informer informers.GenericInformer)
+
+	// OnStop is called each time an informer is stopped: when its
+	// resource goes away (e.g., its CRD is deleted), is no longer
+	// selected, or changes its version or scope (in which case OnStart
+	// follows for the new informer).
+	OnStop func(gvr schema.GroupVersionResource)
+
+	// OnStart and OnStop are called with the manager's lock held, so
+	// they must not call the manager; they should only note or enqueue.
+}
+
+// DynamicInformerManager keeps a dynamic informer running for each
+// resource, delivered by an APIResource informer, that is selected;
+// and only for those. Resources are identified by group and resource
+// name; of the versions in which one is served the preferred is used.
+type DynamicInformerManager struct {
+	ctx    context.Context
+	logger klog.Logger
+	client dynamic.Interface
+	lister APIResourceLister
+	opts   DynamicInformerManagerOptions
+
+	mutex   sync.Mutex
+	running map[schema.GroupResource]*managedInformer
+}
+
+type managedInformer struct {
+	ar       *ksmetav1a1.APIResource
+	informer informers.GenericInformer
+	stop     context.CancelFunc
+}
+
+// NewDynamicInformerManager makes a DynamicInformerManager that follows
+// the given APIResource informer and lister (see
+// NewAPIResourceInformer). The informers it starts stop when the given
+// context is done.
+func NewDynamicInformerManager(ctx context.Context, client dynamic.Interface, apiInformer ObjectNotifier, apiLister APIResourceLister, opts DynamicInformerManagerOptions) *DynamicInformerManager {
+	dim := &DynamicInformerManager{
+		ctx:     ctx,
+		logger:  klog.FromContext(ctx),
+		client:  client,
+		lister:  apiLister,
+		opts:    opts,
+		running: map[schema.GroupResource]*managedInformer{},
+	}
+	apiInformer.AddEventHandler(upstreamcache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { dim.syncFor(obj) },
+		UpdateFunc: func(oldObj, newObj any) { dim.syncFor(newObj) },
+		DeleteFunc: func(obj any) {
+			if del, ok := obj.(upstreamcache.DeletedFinalStateUnknown); ok {
+				obj = del.Obj
+			}
+			dim.syncFor(obj)
+		},
+	})
+	go func() {
+		<-ctx.Done()
+		dim.mutex.Lock()
+		defer dim.mutex.Unlock()
+		for gr, mi := range dim.running {
+			mi.stop()
+			delete(dim.running, gr)
+		}
+	}()
+	return dim
+}
+
+// Informer returns the running informer for the given resource, and
+// the APIResource it was started for, if there is one.
+func (dim *DynamicInformerManager) Informer(gr schema.GroupResource) (informers.GenericInformer, *ksmetav1a1.APIResource, bool) {
+	dim.mutex.Lock()
+	defer dim.mutex.Unlock()
+	mi, found := dim.running[gr]
+	if !found {
+		return nil, nil, false
+	}
+	return mi.informer, mi.ar, true
+}
+
+// Resources returns the resources whose informers are running.
+func (dim *DynamicInformerManager) Resources() []schema.GroupVersionResource {
+	dim.mutex.Lock()
+	defer dim.mutex.Unlock()
+	ans := make([]schema.GroupVersionResource, 0, len(dim.running))
+	for gr, mi := range dim.running {
+		ans = append(ans, gr.WithVersion(mi.ar.Spec.Version))
+	}
+	return ans
+}
+
+// HasSynced tells whether every running informer has synced.
+func (dim *DynamicInformerManager) HasSynced() bool {
+	dim.mutex.Lock()
+	defer dim.mutex.Unlock()
+	for _, mi := range dim.running {
+		if !mi.informer.Informer().HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// Resync reconsiders every resource, as is needed when what Selects
+// says has changed.
+func (dim *DynamicInformerManager) Resync() {
+	dim.mutex.Lock()
+	defer dim.mutex.Unlock()
+	ars, err := dim.lister.List(labels.Everything())
+	if err != nil {
+		dim.logger.Error(err, "Failed to list APIResources")
+		return
+	}
+	grs := map[schema.GroupResource]Empty{}
+	for _, ar := range ars {
+		grs[schema.GroupResource{Group: ar.Spec.Group, Resource: ar.Spec.Name}] = Empty{}
+	}
+	for gr := range dim.running {
+		grs[gr] = Empty{}
+	}
+	for gr := range grs {
+		dim.syncLocked(gr, ars)
+	}
+}
+
+func (dim *DynamicInformerManager) syncFor(obj any) {
+	ar, ok := obj.(*ksmetav1a1.APIResource)
+	if !ok {
+		dim.logger.Error(nil, "Notified of something that is not an APIResource", "obj", obj)
+		return
+	}
+	// Listing under the lock keeps concurrent syncs from acting on stale lists out of order.
+	dim.mutex.Lock()
+	defer dim.mutex.Unlock()
+	ars, err := dim.lister.List(labels.Everything())
+	if err != nil {
+		dim.logger.Error(err, "Failed to list APIResources")
+		return
+	}
+	dim.syncLocked(schema.GroupResource{Group: ar.Spec.Group, Resource: ar.Spec.Name}, ars)
+}
+
+// syncLocked starts, restarts or stops the informer for the given
+// resource, according to the given current APIResources.
+func (dim *DynamicInformerManager) syncLocked(gr schema.GroupResource, ars []*ksmetav1a1.APIResource) {
+	logger := dim.logger.WithValues("resource", gr)
+	want := dim.chooseLocked(gr, ars)
+	have := dim.running[gr]
+	if have != nil && want != nil && have.ar.Spec.Version == want.Spec.Version && have.ar.Spec.Namespaced == want.Spec.Namespaced {
+		have.ar = want
+		return
+	}
+	if have != nil {
+		have.stop()
+		delete(dim.running, gr)
+		logger.V(2).Info("Stopped informer", "version", have.ar.Spec.Version)
+		if dim.opts.OnStop != nil {
+			dim.opts.OnStop(gr.WithVersion(have.ar.Spec.Version))
+		}
+	}
+	if want == nil {
+		return
+	}
+	gvr := gr.WithVersion(want.Spec.Version)
+	namespace := dim.opts.Namespace
+	if !want.Spec.Namespaced {
+		namespace = ""
+	}
+	informer := dynamicinformer.NewFilteredDynamicInformer(dim.client, gvr, namespace, dim.opts.ResyncPeriod, dim.opts.Indexers, dim.opts.TweakListOptions)
+	informerCtx, stop := context.WithCancel(dim.ctx)
+	dim.running[gr] = &managedInformer{ar: want, informer: informer, stop: stop}
+	go informer.Informer().Run(informerCtx.Done())
+	logger.V(2).Info("Started informer", "version", want.Spec.Version)
+	if dim.opts.OnStart != nil {
+		dim.opts.OnStart(want, informer)
+	}
+}
+
+// chooseLocked returns the APIResource to watch for the given resource, or nil if none.
+func (dim *DynamicInformerManager) chooseLocked(gr schema.GroupResource, ars []*ksmetav1a1.APIResource) *ksmetav1a1.APIResource {
+	var candidates []*ksmetav1a1.APIResource
+	for _, ar := range ars {
+		if ar.Spec.Group != gr.Group || ar.Spec.Name != gr.Resource || !hasVerb(ar.Spec.Verbs, "list") || !hasVerb(ar.Spec.Verbs, "watch") {
+			continue
+		}
+		if dim.opts.Selects != nil && !dim.opts.Selects(ar) {
+			continue
+		}
+		candidates = append(candidates, ar)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Spec.Preferred != candidates[j].Spec.Preferred {
+			return candidates[i].Spec.Preferred
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates[0]
+}
+
+func hasVerb(verbs []string, verb string) bool {
+	for _, have := range verbs {
+		if have == verb {
+			return true
+		}
+	}
+	return false
+}
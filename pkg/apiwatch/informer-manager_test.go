@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	upstreamcache "k8s.io/client-go/tools/cache"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// fakeAPIResources stands in for an APIResource informer.
+type fakeAPIResources struct {
+	store    upstreamcache.Store
+	handlers []upstreamcache.ResourceEventHandler
+}
+
+func (fa *fakeAPIResources) AddEventHandler(handler upstreamcache.ResourceEventHandler) {
+	fa.handlers = append(fa.handlers, handler)
+}
+
+func (fa *fakeAPIResources) add(ar *ksmetav1a1.APIResource) {
+	fa.store.Add(ar)
+	for _, handler := range fa.handlers {
+		handler.OnAdd(ar)
+	}
+}
+
+func (fa *fakeAPIResources) remove(ar *ksmetav1a1.APIResource) {
+	fa.store.Delete(ar)
+	for _, handler := range fa.handlers {
+		handler.OnDelete(ar)
+	}
+}
+
+func newAR(group, version, resource string, preferred bool) *ksmetav1a1.APIResource {
+	ar := specComplete(ksmetav1a1.APIResourceSpec{Name: resource, Group: group, Version: version, Kind: "Widget",
+		Namespaced: true, Verbs: metav1.Verbs{"get", "list", "watch"}, Preferred: preferred}, "1", schema.GroupVersion{Group: group, Version: version})
+	return &ar
+}
+
+func TestDynamicInformerManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gvrs := map[schema.GroupVersionResource]string{
+		{Group: "example.com", Version: "v1", Resource: "widgets"}:      "WidgetList",
+		{Group: "example.com", Version: "v1beta1", Resource: "widgets"}: "WidgetList",
+		{Group: "example.com", Version: "v1", Resource: "secrets"}:      "WidgetList",
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), gvrs)
+	apiResources := &fakeAPIResources{store: upstreamcache.NewStore(upstreamcache.MetaNamespaceKeyFunc)}
+	var mutex sync.Mutex
+	var started, stopped []string
+	dim := NewDynamicInformerManager(ctx, client, apiResources, resourceLister{apiResources.store}, DynamicInformerManagerOptions{
+		Selects: func(ar *ksmetav1a1.APIResource) bool { return ar.Spec.Name != "secrets" },
+		OnStart: func(ar *ksmetav1a1.APIResource, _ informers.GenericInformer) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			started = append(started, ar.Name)
+		},
+		OnStop: func(gvr schema.GroupVersionResource) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			stopped = append(stopped, gvr.String())
+		},
+	})
+	widgets := schema.GroupResource{Group: "example.com", Resource: "widgets"}
+
+	v1beta1 := newAR("example.com", "v1beta1", "widgets", false)
+	apiResources.add(v1beta1)
+	apiResources.add(newAR("example.com", "v1", "secrets", true))
+	if _, ar, found := dim.Informer(widgets); !found || ar.Spec.Version != "v1beta1" {
+		t.Fatalf("Expected an informer on v1beta1 widgets, got %v %v", found, ar)
+	}
+	if len(dim.Resources()) != 1 {
+		t.Errorf("Expected only widgets to be watched, got %v", dim.Resources())
+	}
+	informer, _, _ := dim.Informer(widgets)
+	if !upstreamcache.WaitForCacheSync(waitCh(t), dim.HasSynced) || !informer.Informer().HasSynced() {
+		t.Fatal("Informer did not sync")
+	}
+
+	// The preferred version takes over.
+	v1 := newAR("example.com", "v1", "widgets", true)
+	apiResources.add(v1)
+	if _, ar, _ := dim.Informer(widgets); ar.Spec.Version != "v1" {
+		t.Errorf("Expected the informer to move to v1, got %v", ar.Spec.Version)
+	}
+
+	// The preferred version goes away, and then the whole CRD.
+	apiResources.remove(v1)
+	apiResources.remove(v1beta1)
+	if _, _, found := dim.Informer(widgets); found {
+		t.Error("Expected no informer after the resource went away")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(started) != 3 || started[1] != v1.Name || started[2] != v1beta1.Name || len(stopped) != 3 {
+		t.Errorf("Unexpected starts %v and stops %v", started, stopped)
+	}
+}
+
+func waitCh(t *testing.T) <-chan struct{} {
+	ch := make(chan struct{})
+	timer := time.AfterFunc(10*time.Second, func() { close(ch) })
+	t.Cleanup(func() { timer.Stop() })
+	return ch
+}
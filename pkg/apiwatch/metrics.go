@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// The metrics here show discovery storms, such as when many CRDs are
+// installed or removed across many spaces. The "cluster" label can
+// have many values; see pkg/metricslabels for how to tame it.
+var (
+	relists = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_apiwatch",
+		Name:           "relists_total",
+		Help:           "Number of times an APIResource informer queried discovery, by cluster and by whether discovery was complete or partial",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"cluster", "result"})
+	relistDuration = metrics.NewHistogram(&metrics.HistogramOpts{
+		Subsystem:      "kubestellar_apiwatch",
+		Name:           "relist_duration_seconds",
+		Help:           "Time that an APIResource informer took to query discovery",
+		Buckets:        []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		StabilityLevel: metrics.ALPHA,
+	})
+	relistThrottle = metrics.NewHistogram(&metrics.HistogramOpts{
+		Subsystem:      "kubestellar_apiwatch",
+		Name:           "relist_throttle_seconds",
+		Help:           "Time that an APIResource informer waited for its discovery rate limiter before querying discovery",
+		Buckets:        []float64{0.001, 0.01, 0.1, 0.5, 1, 2.5, 5, 10, 30},
+		StabilityLevel: metrics.ALPHA,
+	})
+	invalidations = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_apiwatch",
+		Name:           "invalidations_total",
+		Help:           "Number of invalidations of APIResource informers, by the kind of the object that caused them (empty when explicit)",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"definer_kind"})
+	resourceCounts = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_apiwatch",
+		Name:           "resources",
+		Help:           "Number of APIResources last listed by an APIResource informer, by cluster and API group",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"cluster", "group"})
+	discoveryCacheLoads = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_apiwatch",
+		Name:           "discovery_cache_loads_total",
+		Help:           "Number of times an APIResource informer consulted its persistent discovery cache, by result (hit, miss, mismatch or error)",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"result"})
+)
+
+func init() {
+	legacyregistry.MustRegister(relists, relistDuration, relistThrottle, invalidations, resourceCounts, discoveryCacheLoads)
+}
+
+// recordRelist counts a query of discovery that started at the given time.
+func (rlw *resourcesListWatcher) recordRelist(start time.Time, discoveryErr error) {
+	relistDuration.Observe(time.Since(start).Seconds())
+	result := "complete"
+	if discoveryErr != nil {
+		result = "partial"
+	}
+	relists.WithLabelValues(rlw.clusterName, result).Inc()
+}
+
+// recordResourceCounts sets the resourceCounts of this informer's
+// cluster from the given APIResources. When more than one informer
+// watches the same cluster, the latest to list wins.
+func (rlw *resourcesListWatcher) recordResourceCounts(items []ksmetav1a1.APIResource) {
+	counts := map[string]int{}
+	for _, item := range items {
+		counts[item.Spec.Group]++
+	}
+	rlw.mutex.Lock()
+	defer rlw.mutex.Unlock()
+	for group := range rlw.groupCounts {
+		if _, found := counts[group]; !found {
+			resourceCounts.Delete(map[string]string{"cluster": rlw.clusterName, "group": group})
+		}
+	}
+	for group, count := range counts {
+		resourceCounts.WithLabelValues(rlw.clusterName, group).Set(float64(count))
+	}
+	rlw.groupCounts = counts
+}
+
+// forgetResourceCounts removes the resourceCounts of this informer's cluster.
+func (rlw *resourcesListWatcher) forgetResourceCounts() {
+	rlw.mutex.Lock()
+	defer rlw.mutex.Unlock()
+	for group := range rlw.groupCounts {
+		resourceCounts.Delete(map[string]string{"cluster": rlw.clusterName, "group": group})
+	}
+	rlw.groupCounts = nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cachediscovery "k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/klog/v2"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+func TestDiscoveryMetrics(t *testing.T) {
+	rlw := &resourcesListWatcher{
+		logger:                klog.Background(),
+		clusterName:           "metrics-test",
+		cache:                 cachediscovery.NewMemCacheClient(&fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}),
+		definitions:           relindex.NewRelation2[objectID, metav1.GroupVersionResource](),
+		definerToDeprecations: map[objectID]map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{},
+	}
+	rlw.cond = sync.NewCond(&rlw.mutex)
+
+	crdsBefore, _ := testutil.GetCounterMetricValue(invalidations.WithLabelValues("CustomResourceDefinition"))
+	explicitBefore, _ := testutil.GetCounterMetricValue(invalidations.WithLabelValues(""))
+	crd := &apiext.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"}}
+	rlw.InvalidateWithDefiner(crd, CRDAnalyzer{}, true)
+	rlw.Invalidate()
+	if got, _ := testutil.GetCounterMetricValue(invalidations.WithLabelValues("CustomResourceDefinition")); got != crdsBefore+1 {
+		t.Errorf("Expected %v invalidations by CRDs, got %v", crdsBefore+1, got)
+	}
+	if got, _ := testutil.GetCounterMetricValue(invalidations.WithLabelValues("")); got != explicitBefore+1 {
+		t.Errorf("Expected %v explicit invalidations, got %v", explicitBefore+1, got)
+	}
+
+	rlw.recordRelist(time.Now(), nil)
+	rlw.recordRelist(time.Now(), errors.New("group example.com failed"))
+	rlw.recordRelist(time.Now(), nil)
+	if got, _ := testutil.GetCounterMetricValue(relists.WithLabelValues("metrics-test", "complete")); got != 2 {
+		t.Errorf("Expected 2 complete relists, got %v", got)
+	}
+	if got, _ := testutil.GetCounterMetricValue(relists.WithLabelValues("metrics-test", "partial")); got != 1 {
+		t.Errorf("Expected 1 partial relist, got %v", got)
+	}
+
+	item := func(group, name string) ksmetav1a1.APIResource {
+		return ksmetav1a1.APIResource{Spec: ksmetav1a1.APIResourceSpec{Group: group, Name: name}}
+	}
+	rlw.recordResourceCounts([]ksmetav1a1.APIResource{item("", "pods"), item("", "configmaps"), item("example.com", "widgets")})
+	if got, _ := testutil.GetGaugeMetricValue(resourceCounts.WithLabelValues("metrics-test", "")); got != 2 {
+		t.Errorf("Expected 2 core resources, got %v", got)
+	}
+	rlw.recordResourceCounts([]ksmetav1a1.APIResource{item("", "pods")})
+	if len(rlw.groupCounts) != 1 || rlw.groupCounts[""] != 1 {
+		t.Errorf("Expected only the core group to be counted, got %v", rlw.groupCounts)
+	}
+	if got, _ := testutil.GetGaugeMetricValue(resourceCounts.WithLabelValues("metrics-test", "")); got != 1 {
+		t.Errorf("Expected 1 core resource, got %v", got)
+	}
+	rlw.forgetResourceCounts()
+	if rlw.groupCounts != nil {
+		t.Errorf("Expected the counts to be forgotten, got %v", rlw.groupCounts)
+	}
+}
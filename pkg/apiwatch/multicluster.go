@@ -0,0 +1,392 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	upstreamdiscovery "k8s.io/client-go/discovery"
+	upstreamcache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// ClusterSource is how a MultiClusterAPIResourceInformer reaches one cluster.
+type ClusterSource struct {
+	Client upstreamdiscovery.DiscoveryInterface
+
+	// InvalidationNotifiers are as for NewAPIResourceInformer.
+	InvalidationNotifiers []ObjectNotifier
+}
+
+// ClusterAPIResource is an APIResource of a given cluster.
+type ClusterAPIResource struct {
+	Cluster string
+	*ksmetav1a1.APIResource
+}
+
+// MultiClusterResourceEventHandler is told about the APIResources of
+// all the clusters of a MultiClusterAPIResourceInformer. The objects
+// must be treated as read-only.
+type MultiClusterResourceEventHandler interface {
+	OnAdd(cluster string, obj *ksmetav1a1.APIResource)
+	OnUpdate(cluster string, oldObj, newObj *ksmetav1a1.APIResource)
+	OnDelete(cluster string, obj *ksmetav1a1.APIResource)
+}
+
+// MultiClusterResourceEventHandlerFuncs is a MultiClusterResourceEventHandler
+// made of functions, any of which may be nil.
+type MultiClusterResourceEventHandlerFuncs struct {
+	AddFunc    func(cluster string, obj *ksmetav1a1.APIResource)
+	UpdateFunc func(cluster string, oldObj, newObj *ksmetav1a1.APIResource)
+	DeleteFunc func(cluster string, obj *ksmetav1a1.APIResource)
+}
+
+func (funcs MultiClusterResourceEventHandlerFuncs) OnAdd(cluster string, obj *ksmetav1a1.APIResource) {
+	if funcs.AddFunc != nil {
+		funcs.AddFunc(cluster, obj)
+	}
+}
+
+func (funcs MultiClusterResourceEventHandlerFuncs) OnUpdate(cluster string, oldObj, newObj *ksmetav1a1.APIResource) {
+	if funcs.UpdateFunc != nil {
+		funcs.UpdateFunc(cluster, oldObj, newObj)
+	}
+}
+
+func (funcs MultiClusterResourceEventHandlerFuncs) OnDelete(cluster string, obj *ksmetav1a1.APIResource) {
+	if funcs.DeleteFunc != nil {
+		funcs.DeleteFunc(cluster, obj)
+	}
+}
+
+// MultiClusterAPIResourceLister helps list the APIResources of many clusters.
+// All objects returned here must be treated as read-only.
+type MultiClusterAPIResourceLister interface {
+	// Get retrieves the APIResource of the given resource in the given cluster.
+	Get(cluster string, gvr metav1.GroupVersionResource) (*ksmetav1a1.APIResource, error)
+
+	// List lists the APIResources of the given cluster, or of all
+	// clusters if cluster is empty, ordered by cluster.
+	List(cluster string, selector labels.Selector) ([]ClusterAPIResource, error)
+}
+
+// MultiClusterAPIResourceInformer keeps an APIResource informer (see
+// NewAPIResourceInformer) running for each cluster of a changing set,
+// and presents them as one informer and one lister. A removed cluster
+// looks to the handlers as if all of its resources were deleted. The
+// handlers are called from one goroutine, in the order of the events.
+type MultiClusterAPIResourceInformer struct {
+	ctx    context.Context
+	logger klog.Logger
+	opts   APIResourceInformerOptions
+
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	clusters map[string]*clusterInformer
+	handlers []MultiClusterResourceEventHandler
+
+	// pending holds the notifications not yet delivered
+	pending []notification
+}
+
+type clusterInformer struct {
+	mci           *MultiClusterAPIResourceInformer
+	name          string
+	informer      upstreamcache.SharedInformer
+	lister        APIResourceLister
+	invalidatable Invalidatable
+	stop          context.CancelFunc
+}
+
+// notification is an event to deliver to the handlers that there were when it happened.
+type notification struct {
+	handlers []MultiClusterResourceEventHandler
+	deliver  func(MultiClusterResourceEventHandler)
+}
+
+var _ MultiClusterAPIResourceLister = &MultiClusterAPIResourceInformer{}
+
+// NewMultiClusterAPIResourceInformer makes a MultiClusterAPIResourceInformer
+// whose per-cluster informers have the given options. It starts with no
+// clusters; its informers stop when the given context is done.
+func NewMultiClusterAPIResourceInformer(ctx context.Context, opts APIResourceInformerOptions) *MultiClusterAPIResourceInformer {
+	mci := &MultiClusterAPIResourceInformer{
+		ctx:      ctx,
+		logger:   klog.FromContext(ctx),
+		opts:     opts,
+		clusters: map[string]*clusterInformer{},
+	}
+	mci.cond = sync.NewCond(&mci.mutex)
+	go func() {
+		<-ctx.Done()
+		mci.mutex.Lock()
+		defer mci.mutex.Unlock()
+		for name, ci := range mci.clusters {
+			ci.stop()
+			delete(mci.clusters, name)
+		}
+		mci.cond.Broadcast()
+	}()
+	go mci.deliver()
+	return mci
+}
+
+func (mci *MultiClusterAPIResourceInformer) deliver() {
+	for {
+		mci.mutex.Lock()
+		for len(mci.pending) == 0 && mci.ctx.Err() == nil {
+			mci.cond.Wait()
+		}
+		if mci.ctx.Err() != nil {
+			mci.mutex.Unlock()
+			return
+		}
+		next := mci.pending[0]
+		mci.pending[0] = notification{}
+		mci.pending = mci.pending[1:]
+		mci.mutex.Unlock()
+		for _, handler := range next.handlers {
+			next.deliver(handler)
+		}
+	}
+}
+
+// notifyLocked queues a notification for the given handlers.
+func (mci *MultiClusterAPIResourceInformer) notifyLocked(handlers []MultiClusterResourceEventHandler, deliver func(MultiClusterResourceEventHandler)) {
+	if len(handlers) == 0 {
+		return
+	}
+	mci.pending = append(mci.pending, notification{handlers: handlers, deliver: deliver})
+	mci.cond.Broadcast()
+}
+
+// SetCluster starts following the named cluster through the given
+// source, replacing what was following it before.
+func (mci *MultiClusterAPIResourceInformer) SetCluster(name string, source ClusterSource) {
+	mci.mutex.Lock()
+	defer mci.mutex.Unlock()
+	if mci.ctx.Err() != nil {
+		return
+	}
+	if old, found := mci.clusters[name]; found {
+		mci.removeLocked(old)
+	}
+	ctx, stop := context.WithCancel(mci.ctx)
+	ctx = klog.NewContext(ctx, mci.logger.WithValues("cluster", name))
+	ci := &clusterInformer{mci: mci, name: name, stop: stop}
+	ci.informer, ci.lister, ci.invalidatable = NewAPIResourceInformerWithOptions(ctx, name, source.Client, mci.opts, source.InvalidationNotifiers...)
+	ci.informer.AddEventHandler(ci)
+	mci.clusters[name] = ci
+	go ci.informer.Run(ctx.Done())
+	mci.logger.V(2).Info("Following APIResources of cluster", "cluster", name)
+}
+
+// RemoveCluster stops following the named cluster, if it was.
+func (mci *MultiClusterAPIResourceInformer) RemoveCluster(name string) {
+	mci.mutex.Lock()
+	defer mci.mutex.Unlock()
+	if ci, found := mci.clusters[name]; found {
+		mci.removeLocked(ci)
+	}
+}
+
+// removeLocked stops the given cluster's informer and tells the
+// handlers that its resources are gone.
+func (mci *MultiClusterAPIResourceInformer) removeLocked(ci *clusterInformer) {
+	delete(mci.clusters, ci.name)
+	ci.stop()
+	objs, _ := ci.lister.List(labels.Everything())
+	for _, obj := range objs {
+		obj := obj
+		mci.notifyLocked(mci.handlers, func(handler MultiClusterResourceEventHandler) { handler.OnDelete(ci.name, obj) })
+	}
+	mci.logger.V(2).Info("Stopped following APIResources of cluster", "cluster", ci.name)
+}
+
+// HasCluster tells whether the named cluster is followed.
+func (mci *MultiClusterAPIResourceInformer) HasCluster(name string) bool {
+	mci.mutex.Lock()
+	defer mci.mutex.Unlock()
+	_, found := mci.clusters[name]
+	return found
+}
+
+// Clusters returns the names of the followed clusters, in order.
+func (mci *MultiClusterAPIResourceInformer) Clusters() []string {
+	mci.mutex.Lock()
+	defer mci.mutex.Unlock()
+	names := make([]string, 0, len(mci.clusters))
+	for name := range mci.clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Invalidate invalidates the discovery cache of the named cluster, or of
+// all of them if name is empty.
+func (mci *MultiClusterAPIResourceInformer) Invalidate(name string) {
+	mci.mutex.Lock()
+	defer mci.mutex.Unlock()
+	for _, ci := range mci.clusters {
+		if name == "" || ci.name == name {
+			ci.invalidatable.Invalidate()
+		}
+	}
+}
+
+// HasSynced tells whether the informers of all the followed clusters have synced.
+func (mci *MultiClusterAPIResourceInformer) HasSynced() bool {
+	mci.mutex.Lock()
+	defer mci.mutex.Unlock()
+	for _, ci := range mci.clusters {
+		if !ci.informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// AddEventHandler adds a handler, which is first told about the
+// APIResources already known. As with a SharedInformer, a handler may be
+// told about an addition that it has already been told about, or a
+// deletion of an object that it was not told about.
+func (mci *MultiClusterAPIResourceInformer) AddEventHandler(handler MultiClusterResourceEventHandler) {
+	mci.mutex.Lock()
+	defer mci.mutex.Unlock()
+	mci.handlers = append(mci.handlers[:len(mci.handlers):len(mci.handlers)], handler)
+	only := []MultiClusterResourceEventHandler{handler}
+	for _, ci := range mci.clusters {
+		cluster := ci.name
+		objs, _ := ci.lister.List(labels.Everything())
+		for _, obj := range objs {
+			obj := obj
+			mci.notifyLocked(only, func(handler MultiClusterResourceEventHandler) { handler.OnAdd(cluster, obj) })
+		}
+	}
+}
+
+// Lister returns the lister of the APIResources of all the clusters.
+func (mci *MultiClusterAPIResourceInformer) Lister() MultiClusterAPIResourceLister {
+	return mci
+}
+
+func (mci *MultiClusterAPIResourceInformer) Get(cluster string, gvr metav1.GroupVersionResource) (*ksmetav1a1.APIResource, error) {
+	name := gvr.Group + ":" + gvr.Version + ":" + gvr.Resource
+	mci.mutex.Lock()
+	ci, found := mci.clusters[cluster]
+	mci.mutex.Unlock()
+	if !found {
+		gr := schema.GroupResource{Group: ksmetav1a1.SchemeGroupVersion.Group, Resource: "apiresources"}
+		return nil, apierrors.NewNotFound(gr, cluster+"/"+name)
+	}
+	return ci.lister.Get(name)
+}
+
+func (mci *MultiClusterAPIResourceInformer) List(cluster string, selector labels.Selector) ([]ClusterAPIResource, error) {
+	mci.mutex.Lock()
+	var followed []*clusterInformer
+	for name, ci := range mci.clusters {
+		if cluster == "" || name == cluster {
+			followed = append(followed, ci)
+		}
+	}
+	mci.mutex.Unlock()
+	sort.Slice(followed, func(i, j int) bool { return followed[i].name < followed[j].name })
+	var ans []ClusterAPIResource
+	for _, ci := range followed {
+		objs, err := ci.lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			ans = append(ans, ClusterAPIResource{Cluster: ci.name, APIResource: obj})
+		}
+	}
+	return ans, nil
+}
+
+// FollowClusters makes the set of clusters follow the objects that the
+// given notifier (typically an informer on SyncTargets or Locations)
+// tells about. clusterName names the cluster that an object stands for,
+// and source says how to reach it. A cluster is set up when its object
+// first appears and removed when the object is deleted; later updates
+// of the object are ignored (use SetCluster to change how a cluster is
+// reached). An error from source is logged and the cluster is tried
+// again upon the next update of the object.
+func (mci *MultiClusterAPIResourceInformer) FollowClusters(notifier ObjectNotifier, clusterName func(obj any) string, source func(obj any) (ClusterSource, error)) {
+	set := func(obj any) {
+		name := clusterName(obj)
+		if mci.HasCluster(name) {
+			return
+		}
+		src, err := source(obj)
+		if err != nil {
+			mci.logger.Error(err, "Failed to make the source of a cluster", "cluster", name)
+			return
+		}
+		mci.SetCluster(name, src)
+	}
+	notifier.AddEventHandler(upstreamcache.ResourceEventHandlerFuncs{
+		AddFunc:    set,
+		UpdateFunc: func(oldObj, newObj any) { set(newObj) },
+		DeleteFunc: func(obj any) {
+			if del, ok := obj.(upstreamcache.DeletedFinalStateUnknown); ok {
+				obj = del.Obj
+			}
+			mci.RemoveCluster(clusterName(obj))
+		},
+	})
+}
+
+// forward queues the delivery of an event from the given cluster
+// informer, unless that informer is no longer current.
+func (ci *clusterInformer) forward(deliver func(MultiClusterResourceEventHandler)) {
+	mci := ci.mci
+	mci.mutex.Lock()
+	defer mci.mutex.Unlock()
+	if mci.clusters[ci.name] != ci {
+		return
+	}
+	mci.notifyLocked(mci.handlers, deliver)
+}
+
+func (ci *clusterInformer) OnAdd(obj any) {
+	ar := obj.(*ksmetav1a1.APIResource)
+	ci.forward(func(handler MultiClusterResourceEventHandler) { handler.OnAdd(ci.name, ar) })
+}
+
+func (ci *clusterInformer) OnUpdate(oldObj, newObj any) {
+	oldAR, newAR := oldObj.(*ksmetav1a1.APIResource), newObj.(*ksmetav1a1.APIResource)
+	ci.forward(func(handler MultiClusterResourceEventHandler) { handler.OnUpdate(ci.name, oldAR, newAR) })
+}
+
+func (ci *clusterInformer) OnDelete(obj any) {
+	if del, ok := obj.(upstreamcache.DeletedFinalStateUnknown); ok {
+		obj = del.Obj
+	}
+	ar := obj.(*ksmetav1a1.APIResource)
+	ci.forward(func(handler MultiClusterResourceEventHandler) { handler.OnDelete(ci.name, ar) })
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+type clusterEvent struct {
+	verb    string
+	cluster string
+	name    string
+}
+
+func TestMultiClusterAPIResourceInformer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := func(groupVersion string, resource metav1.APIResource) ClusterSource {
+		return ClusterSource{Client: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+			{GroupVersion: groupVersion, APIResources: []metav1.APIResource{resource}},
+		}}}}
+	}
+	events := make(chan clusterEvent, 10)
+	handler := func(events chan clusterEvent) MultiClusterResourceEventHandler {
+		return MultiClusterResourceEventHandlerFuncs{
+			AddFunc: func(cluster string, obj *ksmetav1a1.APIResource) {
+				events <- clusterEvent{"add", cluster, obj.Name}
+			},
+			DeleteFunc: func(cluster string, obj *ksmetav1a1.APIResource) {
+				events <- clusterEvent{"delete", cluster, obj.Name}
+			},
+		}
+	}
+	expectEvents := func(events chan clusterEvent, expected ...clusterEvent) {
+		t.Helper()
+		got := map[clusterEvent]bool{}
+		for range expected {
+			select {
+			case event := <-events:
+				got[event] = true
+			case <-time.After(10 * time.Second):
+				t.Fatalf("Timed out waiting for %v, got %v", expected, got)
+			}
+		}
+		for _, event := range expected {
+			if !got[event] {
+				t.Errorf("Expected %v, got %v", expected, got)
+			}
+		}
+	}
+
+	mci := NewMultiClusterAPIResourceInformer(ctx, APIResourceInformerOptions{})
+	mci.AddEventHandler(handler(events))
+	mci.SetCluster("c1", source("v1", metav1.APIResource{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"get"}}))
+	mci.SetCluster("c2", source("apps/v1", metav1.APIResource{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: metav1.Verbs{"get"}}))
+	expectEvents(events, clusterEvent{"add", "c1", ":v1:configmaps"}, clusterEvent{"add", "c2", "apps:v1:deployments"})
+
+	deployments := metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	if ar, err := mci.Get("c2", deployments); err != nil || ar.Spec.Kind != "Deployment" {
+		t.Errorf("Expected deployments in c2, got %v and %v", ar, err)
+	}
+	if _, err := mci.Get("c1", deployments); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected deployments not to be found in c1, got %v", err)
+	}
+	if _, err := mci.Get("c3", deployments); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected nothing to be found in an unknown cluster, got %v", err)
+	}
+	all, err := mci.List("", labels.Everything())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for _, car := range all {
+		listed = append(listed, car.Cluster+"/"+car.Name)
+	}
+	if expected := []string{"c1/:v1:configmaps", "c2/apps:v1:deployments"}; !reflect.DeepEqual(listed, expected) {
+		t.Errorf("Expected to list %v, got %v", expected, listed)
+	}
+
+	mci.RemoveCluster("c1")
+	expectEvents(events, clusterEvent{"delete", "c1", ":v1:configmaps"})
+	if clusters := mci.Clusters(); !reflect.DeepEqual(clusters, []string{"c2"}) {
+		t.Errorf("Expected only c2 to remain, got %v", clusters)
+	}
+
+	late := make(chan clusterEvent, 10)
+	mci.AddEventHandler(handler(late))
+	expectEvents(late, clusterEvent{"add", "c2", "apps:v1:deployments"})
+}
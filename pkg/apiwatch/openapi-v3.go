@@ -0,0 +1,241 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// OpenAPIV3Schemas fetches, lazily, the OpenAPI v3 schemas of the kinds
+// of objects that a space serves. The v3 document is split by
+// GroupVersion, and each part is only fetched when a kind in it is asked
+// for, and fetched again only when the index says that it has changed.
+type OpenAPIV3Schemas struct {
+	client rest.Interface
+
+	mutex sync.Mutex
+
+	// urls maps the path of each GroupVersion's part (e.g., "apis/apps/v1")
+	// to its server-relative URL, which changes when the part does.
+	urls map[string]string
+
+	// parts holds the parts fetched so far, by path.
+	parts map[string]*openAPIV3Part
+}
+
+type openAPIV3Part struct {
+	url     string
+	schemas map[string]any
+	byKind  map[schema.GroupVersionKind]string
+}
+
+// schemaRefPrefix starts every reference to a schema in a part.
+const schemaRefPrefix = "#/components/schemas/"
+
+// maxSchemaDepth bounds how deeply references are replaced within
+// references.
+const maxSchemaDepth = 32
+
+// NewOpenAPIV3Schemas makes an OpenAPIV3Schemas that uses the given
+// client (e.g., the RESTClient of a discovery client).
+func NewOpenAPIV3Schemas(client rest.Interface) *OpenAPIV3Schemas {
+	return &OpenAPIV3Schemas{client: client, parts: map[string]*openAPIV3Part{}}
+}
+
+// Refresh fetches the index of the parts, and forgets the parts that
+// have changed or gone.
+func (oas *OpenAPIV3Schemas) Refresh(ctx context.Context) error {
+	data, err := oas.client.Get().AbsPath("/openapi/v3").SetHeader("Accept", "application/json").Do(ctx).Raw()
+	if err != nil {
+		return fmt.Errorf("failed to fetch OpenAPI v3 index: %w", err)
+	}
+	var index struct {
+		Paths map[string]struct {
+			ServerRelativeURL string `json:"serverRelativeURL"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("failed to parse OpenAPI v3 index: %w", err)
+	}
+	urls := make(map[string]string, len(index.Paths))
+	for path, entry := range index.Paths {
+		urls[path] = entry.ServerRelativeURL
+	}
+	oas.mutex.Lock()
+	defer oas.mutex.Unlock()
+	oas.urls = urls
+	for path, part := range oas.parts {
+		if urls[path] != part.url {
+			delete(oas.parts, path)
+		}
+	}
+	return nil
+}
+
+// ForKind returns the JSON of the schema of the given kind, with
+// references resolved; nil if the space serves no schema for it.
+// Refresh must have been called first.
+func (oas *OpenAPIV3Schemas) ForKind(ctx context.Context, gvk schema.GroupVersionKind) ([]byte, error) {
+	path := "apis/" + gvk.Group + "/" + gvk.Version
+	if gvk.Group == "" {
+		path = "api/" + gvk.Version
+	}
+	oas.mutex.Lock()
+	defer oas.mutex.Unlock()
+	part := oas.parts[path]
+	if part == nil {
+		partURL, found := oas.urls[path]
+		if !found {
+			return nil, nil
+		}
+		var err error
+		part, err = oas.fetchPart(ctx, partURL)
+		if err != nil {
+			return nil, err
+		}
+		oas.parts[path] = part
+	}
+	name, found := part.byKind[gvk]
+	if !found {
+		return nil, nil
+	}
+	resolved := resolveSchemaRefs(part.schemas[name], part.schemas, map[string]bool{name: true}, 0)
+	return json.Marshal(resolved)
+}
+
+func (oas *OpenAPIV3Schemas) fetchPart(ctx context.Context, partURL string) (*openAPIV3Part, error) {
+	parsed, err := url.Parse(partURL)
+	if err != nil {
+		return nil, fmt.Errorf("malformed OpenAPI v3 URL %q: %w", partURL, err)
+	}
+	req := oas.client.Get().AbsPath(parsed.Path).SetHeader("Accept", "application/json")
+	for key, values := range parsed.Query() {
+		for _, value := range values {
+			req = req.Param(key, value)
+		}
+	}
+	data, err := req.Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI v3 document %q: %w", parsed.Path, err)
+	}
+	return parseOpenAPIV3Part(partURL, data)
+}
+
+func parseOpenAPIV3Part(partURL string, data []byte) (*openAPIV3Part, error) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI v3 document: %w", err)
+	}
+	part := &openAPIV3Part{url: partURL, schemas: doc.Components.Schemas, byKind: map[schema.GroupVersionKind]string{}}
+	for name, def := range doc.Components.Schemas {
+		defMap, _ := def.(map[string]any)
+		gvks, _ := defMap["x-kubernetes-group-version-kind"].([]any)
+		for _, gvkAny := range gvks {
+			gvkMap, _ := gvkAny.(map[string]any)
+			group, _ := gvkMap["group"].(string)
+			version, _ := gvkMap["version"].(string)
+			kind, _ := gvkMap["kind"].(string)
+			part.byKind[schema.GroupVersionKind{Group: group, Version: version, Kind: kind}] = name
+		}
+	}
+	return part, nil
+}
+
+// resolveSchemaRefs returns a copy of the given node of a schema in
+// which each reference to a schema is replaced by that schema, except a
+// reference to one that is already being expanded (which would recurse
+// forever) or one beyond maxSchemaDepth. The other members of a
+// referring object (e.g., a description) override the referee's.
+func resolveSchemaRefs(node any, schemas map[string]any, expanding map[string]bool, depth int) any {
+	switch typed := node.(type) {
+	case map[string]any:
+		if ref, isString := typed["$ref"].(string); isString && strings.HasPrefix(ref, schemaRefPrefix) {
+			name := strings.TrimPrefix(ref, schemaRefPrefix)
+			referee, found := schemas[name]
+			if !found || expanding[name] || depth >= maxSchemaDepth {
+				return typed
+			}
+			expanding[name] = true
+			resolved := resolveSchemaRefs(referee, schemas, expanding, depth+1)
+			delete(expanding, name)
+			resolvedMap, isMap := resolved.(map[string]any)
+			if !isMap || len(typed) == 1 {
+				return resolved
+			}
+			ans := make(map[string]any, len(resolvedMap)+len(typed))
+			for key, val := range resolvedMap {
+				ans[key] = val
+			}
+			for key, val := range typed {
+				if key != "$ref" {
+					ans[key] = resolveSchemaRefs(val, schemas, expanding, depth)
+				}
+			}
+			return ans
+		}
+		ans := make(map[string]any, len(typed))
+		for key, val := range typed {
+			ans[key] = resolveSchemaRefs(val, schemas, expanding, depth)
+		}
+		return ans
+	case []any:
+		ans := make([]any, len(typed))
+		for idx, val := range typed {
+			ans[idx] = resolveSchemaRefs(val, schemas, expanding, depth)
+		}
+		return ans
+	default:
+		return node
+	}
+}
+
+// attachOpenAPISchemas puts in the given APIResources the schemas of
+// their kinds. Failing to get a schema is not fatal; the APIResource
+// just goes without one.
+func (rlw *resourcesListWatcher) attachOpenAPISchemas(items []ksmetav1a1.APIResource) {
+	if err := rlw.openAPI.Refresh(rlw.ctx); err != nil {
+		rlw.logger.V(3).Info("Not attaching OpenAPI schemas", "err", err.Error())
+		return
+	}
+	for idx := range items {
+		spec := &items[idx].Spec
+		gvk := schema.GroupVersionKind{Group: spec.Group, Version: spec.Version, Kind: spec.Kind}
+		data, err := rlw.openAPI.ForKind(rlw.ctx, gvk)
+		if err != nil {
+			rlw.logger.V(3).Info("Failed to get an OpenAPI schema", "gvk", gvk, "err", err.Error())
+			continue
+		}
+		if data != nil {
+			spec.OpenAPISchema = &k8sruntime.RawExtension{Raw: data}
+		}
+	}
+}
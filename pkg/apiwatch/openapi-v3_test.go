@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	upstreamdiscovery "k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+const testOpenAPIV3Part = `{
+  "openapi": "3.0.0",
+  "components": {"schemas": {
+    "io.k8s.api.core.v1.ConfigMap": {
+      "type": "object",
+      "properties": {
+        "data": {"type": "object", "additionalProperties": {"type": "string"}},
+        "metadata": {"allOf": [{"$ref": "#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}], "description": "Standard object metadata."}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "", "kind": "ConfigMap", "version": "v1"}]
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "ownerReferences": {"type": "array", "items": {"$ref": "#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.OwnerReference", "description": "An owner."}}
+      }
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.OwnerReference": {
+      "type": "object",
+      "description": "A reference to an owner.",
+      "properties": {"kind": {"type": "string"}}
+    },
+    "io.k8s.example.v1.Tree": {
+      "type": "object",
+      "properties": {"children": {"type": "array", "items": {"$ref": "#/components/schemas/io.k8s.example.v1.Tree"}}},
+      "x-kubernetes-group-version-kind": [{"group": "", "kind": "Tree", "version": "v1"}]
+    }
+  }}
+}`
+
+func TestOpenAPIV3Schemas(t *testing.T) {
+	hash := "one"
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/openapi/v3":
+			fmt.Fprintf(w, `{"paths": {"api/v1": {"serverRelativeURL": "/openapi/v3/api/v1?hash=%s"}}}`, hash)
+		case "/openapi/v3/api/v1":
+			if r.URL.Query().Get("hash") != hash {
+				t.Errorf("Expected hash %q, got %q", hash, r.URL.RawQuery)
+			}
+			fetches++
+			fmt.Fprint(w, testOpenAPIV3Part)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := upstreamdiscovery.NewDiscoveryClientForConfigOrDie(&rest.Config{Host: server.URL})
+	oas := NewOpenAPIV3Schemas(client.RESTClient())
+	ctx := context.Background()
+	if err := oas.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	data, err := oas.ForKind(ctx, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cm struct {
+		Properties struct {
+			Metadata struct {
+				Description string `json:"description"`
+				AllOf       []struct {
+					Properties struct {
+						OwnerReferences struct {
+							Items struct {
+								Ref         string `json:"$ref"`
+								Description string `json:"description"`
+								Properties  map[string]any
+							} `json:"items"`
+						} `json:"ownerReferences"`
+					} `json:"properties"`
+				} `json:"allOf"`
+			} `json:"metadata"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &cm); err != nil {
+		t.Fatal(err)
+	}
+	meta := cm.Properties.Metadata
+	if meta.Description != "Standard object metadata." || len(meta.AllOf) != 1 {
+		t.Fatalf("Expected metadata to be resolved, got %s", data)
+	}
+	owner := meta.AllOf[0].Properties.OwnerReferences.Items
+	if owner.Ref != "" || owner.Description != "An owner." || owner.Properties["kind"] == nil {
+		t.Errorf("Expected the owner reference to be resolved with its own description, got %s", data)
+	}
+	data, err = oas.ForKind(ctx, schema.GroupVersionKind{Version: "v1", Kind: "Tree"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"properties":{"children":{"items":{"$ref":"#/components/schemas/io.k8s.example.v1.Tree"},"type":"array"}},"type":"object","x-kubernetes-group-version-kind":[{"group":"","kind":"Tree","version":"v1"}]}`
+	if string(data) != expected {
+		t.Errorf("Expected a recursive reference to stay, got %s", data)
+	}
+	if data, err := oas.ForKind(ctx, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}); data != nil || err != nil {
+		t.Errorf("Expected nothing for an unserved kind, got %s and %v", data, err)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected one fetch of the part, got %d", fetches)
+	}
+	if err := oas.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	oas.ForKind(ctx, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	if fetches != 1 {
+		t.Errorf("Expected an unchanged part to be reused, got %d fetches", fetches)
+	}
+	hash = "two"
+	if err := oas.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	oas.ForKind(ctx, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	if fetches != 2 {
+		t.Errorf("Expected a changed part to be fetched again, got %d fetches", fetches)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// OpenAPISchema is the part of an OpenAPI v2 schema that tells the
+// shape of an object: which fields there are, and what is in them.
+type OpenAPISchema struct {
+	Ref         string                    `json:"$ref,omitempty"`
+	Type        string                    `json:"type,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Properties  map[string]*OpenAPISchema `json:"properties,omitempty"`
+
+	// Items is the schema of the elements of an array.
+	Items *OpenAPISchema `json:"-"`
+
+	// AdditionalProperties is the schema of the values of a map;
+	// the empty schema when any value is allowed.
+	AdditionalProperties *OpenAPISchema `json:"-"`
+
+	// PreserveUnknownFields says that the object may hold fields that the
+	// schema does not list.
+	PreserveUnknownFields bool `json:"x-kubernetes-preserve-unknown-fields,omitempty"`
+
+	GroupVersionKinds []schema.GroupVersionKind `json:"x-kubernetes-group-version-kind,omitempty"`
+}
+
+type openAPISchemaFields OpenAPISchema
+
+func (oas *OpenAPISchema) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		openAPISchemaFields
+		Items                json.RawMessage `json:"items,omitempty"`
+		AdditionalProperties json.RawMessage `json:"additionalProperties,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*oas = OpenAPISchema(raw.openAPISchemaFields)
+	var err error
+	if oas.Items, err = subSchema(raw.Items); err != nil {
+		return fmt.Errorf("items: %w", err)
+	}
+	if oas.AdditionalProperties, err = subSchema(raw.AdditionalProperties); err != nil {
+		return fmt.Errorf("additionalProperties: %w", err)
+	}
+	return nil
+}
+
+// subSchema parses what may be a schema, a list of schemas (of which
+// the first is taken) or a boolean (true meaning any value).
+func subSchema(data json.RawMessage) (*OpenAPISchema, error) {
+	trimmed := strings.TrimSpace(string(data))
+	switch {
+	case trimmed == "" || trimmed == "null" || trimmed == "false":
+		return nil, nil
+	case trimmed == "true":
+		return &OpenAPISchema{}, nil
+	case strings.HasPrefix(trimmed, "["):
+		var list []*OpenAPISchema
+		if err := json.Unmarshal(data, &list); err != nil || len(list) == 0 {
+			return nil, err
+		}
+		return list[0], nil
+	}
+	ans := &OpenAPISchema{}
+	return ans, json.Unmarshal(data, ans)
+}
+
+// OpenAPISchemas are the definitions in the OpenAPI v2 document that a
+// space serves, indexed by the kinds of objects that they define.
+type OpenAPISchemas struct {
+	definitions map[string]*OpenAPISchema
+	byKind      map[schema.GroupVersionKind]*OpenAPISchema
+}
+
+// ParseOpenAPISchemas parses an OpenAPI v2 document.
+func ParseOpenAPISchemas(data []byte) (*OpenAPISchemas, error) {
+	var doc struct {
+		Definitions map[string]*OpenAPISchema `json:"definitions"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI v2 document: %w", err)
+	}
+	ans := &OpenAPISchemas{definitions: doc.Definitions, byKind: map[schema.GroupVersionKind]*OpenAPISchema{}}
+	for _, def := range doc.Definitions {
+		for _, gvk := range def.GroupVersionKinds {
+			ans.byKind[gvk] = def
+		}
+	}
+	return ans, nil
+}
+
+// ForKind returns the schema of the given kind of object, nil if there is none.
+func (schemas *OpenAPISchemas) ForKind(gvk schema.GroupVersionKind) *OpenAPISchema {
+	return schemas.byKind[gvk]
+}
+
+// Resolve follows the given schema's reference, if any, to the
+// definition. Returns nil for a reference to nowhere.
+func (schemas *OpenAPISchemas) Resolve(oas *OpenAPISchema) *OpenAPISchema {
+	for hops := 0; oas != nil && oas.Ref != "" && hops < 10; hops++ {
+		oas = schemas.definitions[strings.TrimPrefix(oas.Ref, "#/definitions/")]
+	}
+	return oas
+}
+
+// OpenAPISchemaCache fetches the OpenAPI v2 document of a space, and
+// keeps its definitions for a while, because the document is big and
+// changes only when resources are defined or changed.
+type OpenAPISchemaCache struct {
+	client rest.Interface
+	ttl    time.Duration
+	now    func() time.Time
+
+	mutex   sync.Mutex
+	schemas *OpenAPISchemas
+	expires time.Time
+}
+
+// NewOpenAPISchemaCache makes a cache of the OpenAPI definitions
+// fetched with the given client (e.g., the RESTClient of a discovery
+// client), which are fetched again when older than ttl.
+func NewOpenAPISchemaCache(client rest.Interface, ttl time.Duration) *OpenAPISchemaCache {
+	return &OpenAPISchemaCache{client: client, ttl: ttl, now: time.Now}
+}
+
+// Get returns the definitions, fetching them if there are none or they have expired.
+func (cache *OpenAPISchemaCache) Get(ctx context.Context) (*OpenAPISchemas, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	now := cache.now()
+	if cache.schemas != nil && now.Before(cache.expires) {
+		return cache.schemas, nil
+	}
+	data, err := cache.client.Get().AbsPath("/openapi/v2").SetHeader("Accept", "application/json").Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI v2 document: %w", err)
+	}
+	schemas, err := ParseOpenAPISchemas(data)
+	if err != nil {
+		return nil, err
+	}
+	cache.schemas, cache.expires = schemas, now.Add(cache.ttl)
+	return schemas, nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testOpenAPIDoc = `{
+  "swagger": "2.0",
+  "definitions": {
+    "io.k8s.api.core.v1.ConfigMap": {
+      "type": "object",
+      "properties": {
+        "data": {"type": "object", "additionalProperties": {"type": "string"}},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "", "kind": "ConfigMap", "version": "v1"}]
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+      "type": "object",
+      "properties": {
+        "labels": {"type": "object", "additionalProperties": true},
+        "finalizers": {"type": "array", "items": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+func TestOpenAPISchemas(t *testing.T) {
+	schemas, err := ParseOpenAPISchemas([]byte(testOpenAPIDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := schemas.ForKind(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	if cm == nil || cm.Type != "object" {
+		t.Fatalf("Expected the ConfigMap schema, got %+v", cm)
+	}
+	if data := cm.Properties["data"]; data == nil || data.AdditionalProperties == nil || data.AdditionalProperties.Type != "string" {
+		t.Errorf("Expected data to be a map of strings, got %+v", data)
+	}
+	meta := schemas.Resolve(cm.Properties["metadata"])
+	if meta == nil || meta.Properties["finalizers"].Items.Type != "string" {
+		t.Fatalf("Expected metadata to resolve to ObjectMeta, got %+v", meta)
+	}
+	if labels := meta.Properties["labels"]; labels.AdditionalProperties == nil || labels.AdditionalProperties.Type != "" {
+		t.Errorf("Expected labels to allow any value, got %+v", labels)
+	}
+	if schemas.ForKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}) != nil {
+		t.Errorf("Expected no schema for an unknown kind")
+	}
+	if schemas.Resolve(&OpenAPISchema{Ref: "#/definitions/nowhere"}) != nil {
+		t.Errorf("Expected a dangling reference to resolve to nil")
+	}
+}
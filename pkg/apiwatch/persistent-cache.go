@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// DiscoveryCacheFormatVersion identifies the format of a CachedDiscovery.
+// It has to be increased whenever the meaning of what is cached changes,
+// including changes to APIResourceSpec, so that a new release does not
+// start from a cache written by an old one.
+const DiscoveryCacheFormatVersion = 1
+
+// DiscoveryCache keeps what APIResource informers learned from discovery
+// across restarts, so that a restarted informer can deliver the
+// resources right away and query discovery in the background.
+// See APIResourceInformerOptions.DiscoveryCache.
+type DiscoveryCache interface {
+	// Load returns what was stored under the given key; nil if nothing was.
+	Load(key string) (*CachedDiscovery, error)
+
+	// Store replaces what is stored under the given key.
+	Store(key string, cached *CachedDiscovery) error
+}
+
+// CachedDiscovery is what a DiscoveryCache holds for one informer.
+type CachedDiscovery struct {
+	FormatVersion int    `json:"formatVersion"`
+	Cluster       string `json:"cluster"`
+
+	// Options describes the options of the informer that affect what it
+	// lists; see discoveryFingerprint.
+	Options string `json:"options"`
+
+	// Time is when discovery was queried.
+	Time metav1.Time `json:"time"`
+
+	Items []ksmetav1a1.APIResource `json:"items"`
+}
+
+// DirDiscoveryCache is a DiscoveryCache that keeps each entry in a
+// gzipped JSON file in a directory. The files may be deleted at any
+// time, which only costs a slower restart.
+type DirDiscoveryCache struct {
+	dir string
+}
+
+var _ DiscoveryCache = &DirDiscoveryCache{}
+
+// NewDirDiscoveryCache makes a DirDiscoveryCache in the given directory.
+// The directory is created, if need be, by the first Store.
+func NewDirDiscoveryCache(dir string) *DirDiscoveryCache {
+	return &DirDiscoveryCache{dir: dir}
+}
+
+func (dc *DirDiscoveryCache) path(key string) string {
+	return filepath.Join(dc.dir, url.PathEscape(key)+".json.gz")
+}
+
+func (dc *DirDiscoveryCache) Load(key string) (*CachedDiscovery, error) {
+	file, err := os.Open(dc.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	var cached CachedDiscovery
+	if err := json.NewDecoder(gzr).Decode(&cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// Store replaces the file, by way of a temporary file in the same
+// directory so that a crash does not leave a partial one.
+func (dc *DirDiscoveryCache) Store(key string, cached *CachedDiscovery) error {
+	if err := os.MkdirAll(dc.dir, 0o755); err != nil {
+		return err
+	}
+	path := dc.path(key)
+	tmp, err := os.CreateTemp(dc.dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	gzw := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(gzw).Encode(cached); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gzw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// discoveryFingerprint describes the options that affect what the
+// informer lists, so that a cached list made with other options is not used.
+func (rlw *resourcesListWatcher) discoveryFingerprint() string {
+	return fmt.Sprintf("subresources=%t,allVersions=%t,schemas=%t,verbs=%s",
+		rlw.includeSubresources, rlw.allVersions, rlw.openAPI != nil, strings.Join(rlw.requiredVerbs.List(), "+"))
+}
+
+// discoveryCacheKey returns the key under which this informer's list is
+// cached. It involves the fingerprint so that informers on the same
+// cluster with different options do not overwrite each other's lists.
+func (rlw *resourcesListWatcher) discoveryCacheKey() string {
+	sum := sha256.Sum256([]byte(rlw.discoveryFingerprint()))
+	return rlw.clusterName + "." + hex.EncodeToString(sum[:4])
+}
+
+// loadCachedDiscovery returns the APIResources in the discovery cache,
+// with the given resource version, if this is the first call, the cache
+// has them, and they were made by this format version with the same
+// options for the same cluster. Otherwise it returns nil.
+// The Definers and Deprecation of the returned APIResources are as of
+// when they were stored, and may be stale until the relist that the
+// caller asks for with relistNow finishes; that relist delivers any
+// corrections as Modified events.
+func (rlw *resourcesListWatcher) loadCachedDiscovery(resourceVersionS string) *CachedDiscovery {
+	if rlw.discoveryCache == nil || rlw.discoveryCacheUsed {
+		return nil
+	}
+	rlw.discoveryCacheUsed = true
+	cached, err := rlw.discoveryCache.Load(rlw.discoveryCacheKey())
+	switch {
+	case err != nil:
+		rlw.logger.Error(err, "Failed to load cached discovery, querying discovery instead")
+		discoveryCacheLoads.WithLabelValues("error").Inc()
+		return nil
+	case cached == nil:
+		rlw.logger.V(2).Info("No cached discovery")
+		discoveryCacheLoads.WithLabelValues("miss").Inc()
+		return nil
+	case cached.FormatVersion != DiscoveryCacheFormatVersion || cached.Cluster != rlw.clusterName || cached.Options != rlw.discoveryFingerprint():
+		rlw.logger.V(2).Info("Ignoring cached discovery that does not match", "formatVersion", cached.FormatVersion, "cluster", cached.Cluster, "options", cached.Options)
+		discoveryCacheLoads.WithLabelValues("mismatch").Inc()
+		return nil
+	}
+	rlw.logger.V(2).Info("Using cached discovery", "time", cached.Time, "resources", len(cached.Items))
+	discoveryCacheLoads.WithLabelValues("hit").Inc()
+	for idx := range cached.Items {
+		cached.Items[idx].ResourceVersion = resourceVersionS
+	}
+	return cached
+}
+
+// storeDiscovery puts the given result of a complete query of discovery
+// in the discovery cache, if any.
+func (rlw *resourcesListWatcher) storeDiscovery(items []ksmetav1a1.APIResource) {
+	if rlw.discoveryCache == nil {
+		return
+	}
+	cached := &CachedDiscovery{
+		FormatVersion: DiscoveryCacheFormatVersion,
+		Cluster:       rlw.clusterName,
+		Options:       rlw.discoveryFingerprint(),
+		Time:          metav1.NewTime(time.Now()),
+		Items:         items,
+	}
+	if err := rlw.discoveryCache.Store(rlw.discoveryCacheKey(), cached); err != nil {
+		rlw.logger.Error(err, "Failed to store discovery in cache")
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	upstreamdiscovery "k8s.io/client-go/discovery"
+	cachediscovery "k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+func newCachingRLW(ctx context.Context, client upstreamdiscovery.DiscoveryInterface, cache DiscoveryCache) *resourcesListWatcher {
+	rlw := &resourcesListWatcher{
+		ctx:                   ctx,
+		logger:                klog.Background(),
+		clusterName:           "root:wds1",
+		cache:                 cachediscovery.NewMemCacheClient(client),
+		allVersions:           true,
+		discoveryCache:        cache,
+		definitions:           relindex.NewRelation2[objectID, metav1.GroupVersionResource](),
+		definerToDeprecations: map[objectID]map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{},
+	}
+	rlw.cond = sync.NewCond(&rlw.mutex)
+	return rlw
+}
+
+func TestDiscoveryCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache := NewDirDiscoveryCache(filepath.Join(t.TempDir(), "discovery"))
+	if cached, err := cache.Load("absent"); cached != nil || err != nil {
+		t.Fatalf("Expected nothing for an absent key, got %v and %v", cached, err)
+	}
+	fakeDiscovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	fakeDiscovery.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"get", "list", "watch"}}},
+	}}
+
+	first := newCachingRLW(ctx, fakeDiscovery, cache)
+	if first.loadCachedDiscovery("1") != nil {
+		t.Fatalf("Expected nothing cached at first")
+	}
+	if items, err := first.discover("1"); err != nil || len(items) != 1 {
+		t.Fatalf("discover returned %d items and %v", len(items), err)
+	}
+
+	// A restart finds the resources in the cache, and queries discovery right away
+	restarted := newCachingRLW(ctx, &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}, cache)
+	listed, err := restarted.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := listed.(*ksmetav1a1.APIResourceList).Items
+	if len(items) != 1 || items[0].Spec.Name != "configmaps" || items[0].ResourceVersion != "1" {
+		t.Errorf("Expected the cached configmaps at resource version 1, got %+v", items)
+	}
+	if !restarted.needRelist {
+		t.Errorf("Expected a relist to be pending after listing from the cache")
+	}
+	if stats := restarted.stats; !stats.FromCache || stats.Resources != 1 || stats.LastRefresh == nil {
+		t.Errorf("Expected stats of the cached resources, got %+v", stats)
+	}
+	restarted.refresh()
+	if stats := restarted.stats; stats.FromCache || stats.Resources != 0 {
+		t.Errorf("Expected stats of the relist after the cached List, got %+v", stats)
+	}
+	if restarted.loadCachedDiscovery("2") != nil {
+		t.Errorf("Expected the cache to be consulted only once")
+	}
+
+	// Other options make for another key
+	otherVerbs := newCachingRLW(ctx, fakeDiscovery, cache)
+	otherVerbs.requiredVerbs = sets.NewString(InformerVerbs...)
+	if otherVerbs.discoveryCacheKey() == first.discoveryCacheKey() {
+		t.Errorf("Expected different keys for different required verbs")
+	}
+	if otherVerbs.loadCachedDiscovery("1") != nil {
+		t.Errorf("Expected nothing cached for different required verbs")
+	}
+
+	// A cache from another format version is ignored
+	stale := newCachingRLW(ctx, fakeDiscovery, cache)
+	if err := cache.Store(stale.discoveryCacheKey(), &CachedDiscovery{
+		FormatVersion: DiscoveryCacheFormatVersion + 1,
+		Cluster:       stale.clusterName,
+		Options:       stale.discoveryFingerprint(),
+		Items:         items,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if stale.loadCachedDiscovery("1") != nil {
+		t.Errorf("Expected a cache of another format version to be ignored")
+	}
+}
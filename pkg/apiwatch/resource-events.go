@@ -0,0 +1,190 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// watchBacklog bounds the number of batches of events waiting for a
+// Watch's client. A Watch whose client falls further behind is ended,
+// which makes the client list again.
+const watchBacklog = 16
+
+// resourceChange is a change to one APIResource; old is nil for an
+// addition and new is nil for a deletion.
+type resourceChange struct {
+	old, new *ksmetav1a1.APIResource
+}
+
+// diffResources returns the changes from the last APIResources to the
+// current ones, in order of name. When the current ones are not
+// complete, because discovery partly failed, the missing ones are not
+// taken to be deleted.
+func diffResources(last map[string]*ksmetav1a1.APIResource, current []ksmetav1a1.APIResource, complete bool) []resourceChange {
+	var changes []resourceChange
+	seen := make(map[string]Empty, len(current))
+	for idx := range current {
+		item := &current[idx]
+		seen[item.Name] = Empty{}
+		old := last[item.Name]
+		if old == nil || !apiequality.Semantic.DeepEqual(old.Spec, item.Spec) {
+			changes = append(changes, resourceChange{old: old, new: item})
+		}
+	}
+	if complete {
+		for name, old := range last {
+			if _, have := seen[name]; !have {
+				changes = append(changes, resourceChange{old: old})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].name() < changes[j].name() })
+	return changes
+}
+
+func (rc resourceChange) name() string {
+	if rc.new != nil {
+		return rc.new.Name
+	}
+	return rc.old.Name
+}
+
+// setLast makes the given APIResources the last ones.
+func (rlw *resourcesListWatcher) setLast(items []ksmetav1a1.APIResource) {
+	last := make(map[string]*ksmetav1a1.APIResource, len(items))
+	for idx := range items {
+		item := items[idx].DeepCopy()
+		last[item.Name] = item
+	}
+	rlw.mutex.Lock()
+	defer rlw.mutex.Unlock()
+	rlw.last = last
+}
+
+// refresh discovers the APIResources again and sends what changed since
+// the last time to the open Watches, as one batch of events under a new
+// resource version. Nothing is sent, and the resource version stays,
+// if nothing changed.
+func (rlw *resourcesListWatcher) refresh() {
+	items, discoveryErr := rlw.discover("")
+	rlw.recordStats(items, discoveryErr)
+	rlw.mutex.Lock()
+	defer rlw.mutex.Unlock()
+	if rlw.last == nil {
+		// Nothing listed yet, so nothing to tell.
+		return
+	}
+	changes := diffResources(rlw.last, items, discoveryErr == nil)
+	if len(changes) == 0 {
+		rlw.logger.V(4).Info("Discovery revealed no change in APIResources")
+		return
+	}
+	rlw.resourceVersionI++
+	resourceVersionS := strconv.FormatInt(rlw.resourceVersionI, 10)
+	for idx, change := range changes {
+		if change.new != nil {
+			change.new = change.new.DeepCopy()
+			change.new.ResourceVersion = resourceVersionS
+			rlw.last[change.new.Name] = change.new
+		} else {
+			change.old = change.old.DeepCopy()
+			change.old.ResourceVersion = resourceVersionS
+			delete(rlw.last, change.old.Name)
+		}
+		changes[idx] = change
+	}
+	rlw.logger.V(3).Info("Sending APIResource changes to watches", "changes", len(changes), "watches", len(rlw.watches), "resourceVersion", resourceVersionS)
+	for rw := range rlw.watches {
+		batch := rw.eventsFor(changes, resourceVersionS)
+		if len(batch) == 0 {
+			continue
+		}
+		select {
+		case rw.batches <- batch:
+		default:
+			rlw.logger.V(2).Info("Ending an APIResource Watch whose client is too far behind")
+			rw.cancel()
+		}
+	}
+}
+
+// eventsFor returns the events that tell this Watch about the given
+// changes, as seen through its field selector. If there are none, and
+// the client accepts bookmarks, a bookmark tells it the new resource
+// version, so that it can watch again from there.
+func (rw *resourceWatch) eventsFor(changes []resourceChange, resourceVersionS string) []watch.Event {
+	var ans []watch.Event
+	for _, change := range changes {
+		oldIn := change.old != nil && rw.selector.Matches(APIResourceFields(change.old))
+		newIn := change.new != nil && rw.selector.Matches(APIResourceFields(change.new))
+		switch {
+		case oldIn && newIn:
+			ans = append(ans, watch.Event{Type: watch.Modified, Object: change.new})
+		case newIn:
+			ans = append(ans, watch.Event{Type: watch.Added, Object: change.new})
+		case oldIn:
+			gone := change.old
+			if change.new != nil {
+				// Changed so as to leave the selection
+				gone = change.new
+			}
+			ans = append(ans, watch.Event{Type: watch.Deleted, Object: gone})
+		}
+	}
+	if len(ans) == 0 && rw.bookmarks {
+		ans = append(ans, watch.Event{Type: watch.Bookmark, Object: &ksmetav1a1.APIResource{
+			TypeMeta:   metav1.TypeMeta{Kind: "APIResource", APIVersion: ksmetav1a1.SchemeGroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{ResourceVersion: resourceVersionS},
+		}})
+	}
+	return ans
+}
+
+// deliver sends the batches of events to the client until the Watch
+// ends, and then closes the result channel.
+func (rw *resourceWatch) deliver(ctx context.Context) {
+	defer func() {
+		rw.mutex.Lock()
+		delete(rw.watches, rw)
+		rw.mutex.Unlock()
+		rw.logger.V(3).Info("Ending an APIResource Watch")
+		close(rw.results)
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case batch := <-rw.batches:
+			for _, event := range batch {
+				select {
+				case rw.results <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
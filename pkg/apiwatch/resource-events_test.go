@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+func TestIncrementalEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sd := newStubDiscovery()
+	rlw := &resourcesListWatcher{ctx: ctx, logger: klog.Background(), allVersions: true, cache: sd, resourceVersionI: 1,
+		definitions: relindex.NewRelation2[objectID, metav1.GroupVersionResource]()}
+	obj, err := rlw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout := int64(60)
+	openWatch := func(selector string, bookmarks bool) watch.Interface {
+		w, err := rlw.Watch(metav1.ListOptions{ResourceVersion: obj.(*ksmetav1a1.APIResourceList).ResourceVersion,
+			TimeoutSeconds: &timeout, FieldSelector: selector, AllowWatchBookmarks: bookmarks})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+	all := openWatch("", false)
+	defer all.Stop()
+	gadgets := openWatch("spec.kind=Gadget", true)
+	defer gadgets.Stop()
+
+	rlw.refresh()
+	if rlw.resourceVersionI != 2 {
+		t.Errorf("Expected no new resource version for an unchanged discovery, got %d", rlw.resourceVersionI)
+	}
+
+	// widgets leave v1beta1, sprockets arrive there.
+	sd.resources[1].APIResources = []metav1.APIResource{
+		{Name: "gadgets", Kind: "Gadget"},
+		{Name: "sprockets", Namespaced: true, Kind: "Sprocket"},
+	}
+	rlw.refresh()
+	expected := []struct {
+		typ  watch.EventType
+		name string
+	}{{watch.Added, "example.com:v1beta1:sprockets"}, {watch.Deleted, "example.com:v1beta1:widgets"}}
+	for _, exp := range expected {
+		event := nextEvent(t, all)
+		if ar, ok := event.Object.(*ksmetav1a1.APIResource); event.Type != exp.typ || !ok || ar.Name != exp.name || ar.ResourceVersion != "3" {
+			t.Errorf("Expected %s of %s at version 3, got %s of %+v", exp.typ, exp.name, event.Type, event.Object)
+		}
+	}
+	if event := nextEvent(t, gadgets); event.Type != watch.Bookmark {
+		t.Errorf("Expected a bookmark for a watch that no change concerns, got %+v", event)
+	}
+
+	// gadgets become namespaced.
+	sd.resources[1].APIResources[0].Namespaced = true
+	rlw.refresh()
+	if event := nextEvent(t, gadgets); event.Type != watch.Modified || !event.Object.(*ksmetav1a1.APIResource).Spec.Namespaced {
+		t.Errorf("Expected gadgets to be modified, got %+v", event)
+	}
+}
+
+func nextEvent(t *testing.T, w watch.Interface) watch.Event {
+	select {
+	case event, ok := <-w.ResultChan():
+		if !ok {
+			t.Fatal("Watch ended")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return watch.Event{}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+
+	upstreamdiscovery "k8s.io/client-go/discovery"
+	upstreamcache "k8s.io/client-go/tools/cache"
+)
+
+// APIResourceInformer bundles the informer, lister and Invalidatable
+// of one cluster's APIResources, with some conveniences.
+// HasSynced and the rest of the SharedInformer methods are those of
+// the wrapped informer.
+type APIResourceInformer struct {
+	upstreamcache.SharedInformer
+	Invalidatable
+	Lister APIResourceLister
+}
+
+// NewAPIResourceInformerWrapped is NewAPIResourceInformerWithOptions
+// returning an APIResourceInformer. Use the ResyncPeriod and
+// ForcedRelistPeriod options to reconcile periodically against
+// discovery even without invalidation notifications.
+func NewAPIResourceInformerWrapped(ctx context.Context, clusterName string, client upstreamdiscovery.DiscoveryInterface, opts APIResourceInformerOptions, invalidationNotifiers ...ObjectNotifier) *APIResourceInformer {
+	return WrapAPIResourceInformer(NewAPIResourceInformerWithOptions(ctx, clusterName, client, opts, invalidationNotifiers...))
+}
+
+// WrapAPIResourceInformer bundles what NewAPIResourceInformer returns.
+func WrapAPIResourceInformer(informer upstreamcache.SharedInformer, lister APIResourceLister, invalidatable Invalidatable) *APIResourceInformer {
+	return &APIResourceInformer{SharedInformer: informer, Invalidatable: invalidatable, Lister: lister}
+}
+
+// WaitForSync waits until the informer has synced or ctx is done,
+// and tells whether it has synced. The informer must be running.
+func (ari *APIResourceInformer) WaitForSync(ctx context.Context) bool {
+	return upstreamcache.WaitForCacheSync(ctx.Done(), ari.HasSynced)
+}
@@ -18,7 +18,6 @@ package apiwatch
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -27,17 +26,22 @@ import (
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	upstreamdiscovery "k8s.io/client-go/discovery"
 	cachediscovery "k8s.io/client-go/discovery/cached/memory"
 	upstreamcache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
 	_ "k8s.io/component-base/metrics/prometheus/clientgo"
 	"k8s.io/klog/v2"
 
 	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
 )
 
 // Invalidatable is a cache that has to be explicitly invalidated
@@ -57,6 +61,8 @@ type ResourceDefinitionSupplier interface {
 	EnumerateDefinedResources(definer any) ResourceDefinitionEnumerator
 }
 
+// ResourceDefinitionEnumerator reports the resources that an object
+// defines. See AllResources.
 type ResourceDefinitionEnumerator func(func(metav1.GroupVersionResource))
 
 // APIResourceLister helps list APIResources.
@@ -70,9 +76,103 @@ type APIResourceLister interface {
 	Get(name string) (*ksmetav1a1.APIResource, error)
 }
 
+// APIResourceInformerOptions are the options of an APIResource informer.
+type APIResourceInformerOptions struct {
+	// IncludeSubresources makes each APIResource list the subresources
+	// of its resource.
+	IncludeSubresources bool
+
+	// AllVersions makes the informer deliver an APIResource for every
+	// version in which a resource is served, rather than only for the
+	// preferred one. The Preferred field of the spec marks the version
+	// that discovery prefers.
+	AllVersions bool
+
+	// FieldSelector, if not nil, limits the informer to the
+	// APIResources that it matches, in addition to any field selector
+	// in the options of each List and Watch. The fields that may be
+	// tested are metadata.name, spec.name, spec.group, spec.version,
+	// spec.kind and spec.namespaced ("true" or "false").
+	FieldSelector fields.Selector
+
+	// RequiredVerbs, if not empty, limits the informer to the resources
+	// that support all of these verbs (for example, InformerVerbs).
+	// The filtering happens right after each query of discovery, so the
+	// other resources get no schemas and do not appear in the stats or
+	// metrics of the informer. It does not look at subresources.
+	RequiredVerbs []string
+
+	// RelistDelay is how long the informer waits, after an invalidation,
+	// for the invalidations to stop before querying discovery again, so
+	// that the steps of one change to the set of resources are taken in
+	// together. Zero means DefaultRelistDelay.
+	RelistDelay time.Duration
+
+	// MaxRelistDelay, if positive, bounds how long a steady stream of
+	// invalidations can hold back the query that the first of them asked for.
+	MaxRelistDelay time.Duration
+
+	// ImmediateRelist makes the informer query discovery as soon as it
+	// is invalidated, ignoring RelistDelay and MaxRelistDelay.
+	// Invalidations that arrive during a query are still taken together.
+	ImmediateRelist bool
+
+	// AttachOpenAPISchemas makes the informer put in each APIResource the
+	// OpenAPI v3 schema of its kind (see APIResourceSpec.OpenAPISchema).
+	// The schemas of a GroupVersion are fetched when a resource in it is
+	// first listed, and again only when the cluster says that they changed.
+	AttachOpenAPISchemas bool
+
+	// NewDiscoveryRateLimiter, if not nil, is called once per informer,
+	// with the name of its cluster, for the rate limiter that bounds how
+	// often the informer queries discovery (each query makes a request
+	// per API group version). Return a shared limiter to bound several
+	// clusters together, or flowcontrol.NewFakeAlwaysRateLimiter() for no
+	// bound. Nil means a token bucket of DefaultDiscoveryQPS and
+	// DefaultDiscoveryBurst for each cluster.
+	NewDiscoveryRateLimiter func(clusterName string) flowcontrol.RateLimiter
+
+	// ResyncPeriod is the default resync period of the event handlers
+	// added to the informer; zero means no resync. See upstream
+	// SharedInformer.
+	ResyncPeriod time.Duration
+
+	// ForcedRelistPeriod, if positive, makes the informer invalidate
+	// itself about that often (with 10% jitter), so that it queries
+	// discovery even when no invalidation notifier tells it to. This
+	// catches changes to the set of resources that come from sources
+	// that no notifier follows.
+	ForcedRelistPeriod time.Duration
+
+	// DiscoveryCache, if not nil, keeps the result of each complete query
+	// of discovery, so that the first List after a restart can deliver
+	// that rather than wait for discovery. Discovery is then queried right
+	// away, and what changed meanwhile is delivered as events; until
+	// then the Definers and Deprecation of the delivered APIResources
+	// are as of when they were cached, and the informer's stats say
+	// FromCache. A cached result is not used if it was made by a
+	// different version of this package, or with options that change
+	// what is listed.
+	DiscoveryCache DiscoveryCache
+}
+
+// DefaultRelistDelay is the default RelistDelay, which suits a large
+// fleet better than a controller that needs to see new resources quickly.
+const DefaultRelistDelay = 20 * time.Second
+
+// DefaultDiscoveryQPS and DefaultDiscoveryBurst configure the default
+// per-cluster limit on queries of discovery. Relists triggered by
+// invalidations are already spaced by the RelistDelay, so this mostly
+// bounds the Lists made when an informer restarts its watch.
+const (
+	DefaultDiscoveryQPS   = 1
+	DefaultDiscoveryBurst = 3
+)
+
 // NewAPIResourceInformer creates an informer on the API resources
 // revealed by the given client.  The objects delivered by the
 // informer are of type `*ksmetav1a1.APIResource`.
+// Only the preferred version of each resource is delivered.
 //
 // The results from the given client are cached in memory and that
 // cache has to be explicitly invalidated.  Invalidation can be done
@@ -81,21 +181,57 @@ type APIResourceLister interface {
 // a notification of an object addition.  Re-querying the given client
 // is delayed by a few decaseconds (with Nagling) to support
 // invalidations based on events that merely trigger some process of
-// changing the set of API resources.
+// changing the set of API resources; NewAPIResourceInformerWithOptions
+// can shorten or bound that delay. What re-querying finds changed
+// is delivered to the informer as Added, Modified and Deleted events,
+// rather than by making it list everything again.
 func NewAPIResourceInformer(ctx context.Context, clusterName string, client upstreamdiscovery.DiscoveryInterface, includeSubresources bool, invalidationNotifiers ...ObjectNotifier) (upstreamcache.SharedInformer, APIResourceLister, Invalidatable) {
+	return NewAPIResourceInformerWithOptions(ctx, clusterName, client, APIResourceInformerOptions{IncludeSubresources: includeSubresources}, invalidationNotifiers...)
+}
+
+// NewAPIResourceInformerWithOptions is NewAPIResourceInformer with more options.
+func NewAPIResourceInformerWithOptions(ctx context.Context, clusterName string, client upstreamdiscovery.DiscoveryInterface, opts APIResourceInformerOptions, invalidationNotifiers ...ObjectNotifier) (upstreamcache.SharedInformer, APIResourceLister, Invalidatable) {
 	logger := klog.FromContext(ctx).WithValues("cluster", clusterName)
 	ctx = klog.NewContext(ctx, logger)
 	rlw := &resourcesListWatcher{
-		ctx:                 ctx,
-		logger:              logger,
-		includeSubresources: includeSubresources,
-		clusterName:         clusterName,
-		cache:               cachediscovery.NewMemCacheClient(client),
-		resourceVersionI:    1,
-		rscToDefiners:       GoMap[metav1.GroupVersionResource, GoSet[objectID]]{},
-		definerToRscs:       GoMap[objectID, GoSet[metav1.GroupVersionResource]]{},
+		ctx:                   ctx,
+		logger:                logger,
+		includeSubresources:   opts.IncludeSubresources,
+		allVersions:           opts.AllVersions,
+		fieldSel:              opts.FieldSelector,
+		requiredVerbs:         sets.NewString(opts.RequiredVerbs...),
+		relistDelay:           opts.RelistDelay,
+		maxRelistDelay:        opts.MaxRelistDelay,
+		immediateRelist:       opts.ImmediateRelist,
+		discoveryCache:        opts.DiscoveryCache,
+		clusterName:           clusterName,
+		cache:                 cachediscovery.NewMemCacheClient(client),
+		resourceVersionI:      1,
+		definitions:           relindex.NewRelation2[objectID, metav1.GroupVersionResource](),
+		definerToDeprecations: map[objectID]map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{},
+	}
+	if rlw.relistDelay <= 0 {
+		rlw.relistDelay = DefaultRelistDelay
+	}
+	if opts.NewDiscoveryRateLimiter != nil {
+		rlw.discoveryLimiter = opts.NewDiscoveryRateLimiter(clusterName)
+	} else {
+		rlw.discoveryLimiter = flowcontrol.NewTokenBucketRateLimiter(DefaultDiscoveryQPS, DefaultDiscoveryBurst)
+	}
+	if opts.AttachOpenAPISchemas {
+		if restClient := client.RESTClient(); restClient != nil {
+			rlw.openAPI = NewOpenAPIV3Schemas(restClient)
+		} else {
+			logger.Info("Not attaching OpenAPI schemas because the discovery client has no REST client")
+		}
 	}
 	rlw.cond = sync.NewCond(&rlw.mutex)
+	DefaultStatsRegistry.add(rlw)
+	go func() {
+		<-ctx.Done()
+		DefaultStatsRegistry.remove(rlw)
+		rlw.forgetResourceCounts()
+	}()
 	go func() {
 		doneCh := ctx.Done()
 		for {
@@ -105,6 +241,7 @@ func NewAPIResourceInformer(ctx context.Context, clusterName string, client upst
 			default:
 			}
 			var wait time.Duration
+			var refresh bool
 			func() {
 				rlw.mutex.Lock()
 				defer rlw.mutex.Unlock()
@@ -113,16 +250,17 @@ func NewAPIResourceInformer(ctx context.Context, clusterName string, client upst
 					if now.Before(rlw.relistAfter) {
 						wait = rlw.relistAfter.Sub(now)
 					} else {
-						logger.V(3).Info("Cycled APIResourceInformer")
-						for _, cancel := range rlw.cancels {
-							cancel()
-						}
 						rlw.needRelist = false
+						refresh = true
 					}
 					return
 				}
 				rlw.cond.Wait()
 			}()
+			if refresh {
+				logger.V(3).Info("Refreshing APIResourceInformer")
+				rlw.refresh()
+			}
 			if wait > 0 {
 				time.Sleep(wait)
 			}
@@ -148,16 +286,54 @@ func NewAPIResourceInformer(ctx context.Context, clusterName string, client upst
 			},
 		})
 	}
-	inf := upstreamcache.NewSharedInformer(rlw, &ksmetav1a1.APIResource{}, 0)
+	if opts.ForcedRelistPeriod > 0 {
+		go rlw.forceRelists(opts.ForcedRelistPeriod)
+	}
+	inf := upstreamcache.NewSharedInformer(rlw, &ksmetav1a1.APIResource{}, opts.ResyncPeriod)
 	return inf, resourceLister{inf.GetStore()}, rlw
 }
 
+// forceRelists invalidates rlw about every period, until rlw.ctx is done.
+func (rlw *resourcesListWatcher) forceRelists(period time.Duration) {
+	doneCh := rlw.ctx.Done()
+	for {
+		timer := time.NewTimer(wait.Jitter(period, 0.1))
+		select {
+		case <-doneCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		rlw.logger.V(4).Info("Forcing a relist")
+		rlw.Invalidate()
+	}
+}
+
 type resourcesListWatcher struct {
 	ctx                 context.Context
 	logger              klog.Logger
 	includeSubresources bool
+	allVersions         bool
+	fieldSel            fields.Selector
+	requiredVerbs       sets.String
 	clusterName         string
 	cache               upstreamdiscovery.CachedDiscoveryInterface
+	relistDelay         time.Duration
+	maxRelistDelay      time.Duration
+	immediateRelist     bool
+
+	// openAPI, if not nil, supplies the schemas to attach
+	openAPI *OpenAPIV3Schemas
+
+	// discoveryLimiter, if not nil, is waited on before each query of discovery
+	discoveryLimiter flowcontrol.RateLimiter
+
+	// discoveryCache, if not nil, keeps the results of discovery across restarts
+	discoveryCache DiscoveryCache
+
+	// discoveryCacheUsed tells whether the first List has consulted discoveryCache.
+	// Accessed only by List, which the informer does not call concurrently.
+	discoveryCacheUsed bool
 
 	mutex            sync.Mutex
 	cond             *sync.Cond
@@ -165,8 +341,29 @@ type resourcesListWatcher struct {
 	needRelist       bool
 	relistAfter      time.Time
 	cancels          []context.CancelFunc
-	rscToDefiners    GoMap[metav1.GroupVersionResource, GoSet[objectID]]
-	definerToRscs    GoMap[objectID, GoSet[metav1.GroupVersionResource]]
+
+	// relistPendingSince is when the first of the pending invalidations came
+	relistPendingSince time.Time
+
+	// watches are the open Watches
+	watches map[*resourceWatch]Empty
+
+	// last holds the APIResources of the latest List or refresh, by name,
+	// regardless of field selectors
+	last map[string]*ksmetav1a1.APIResource
+
+	// definitions relates each definer to the resources that it defines
+	definitions *relindex.Relation2[objectID, metav1.GroupVersionResource]
+
+	// definerToDeprecations holds the deprecations declared by definers
+	definerToDeprecations map[objectID]map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation
+
+	// stats summarizes the latest List
+	stats ClusterStats
+
+	// groupCounts holds the number of APIResources in each group that
+	// were last put in the resourceCounts metric
+	groupCounts map[string]int
 }
 
 // objectID identifies an object that defines resources
@@ -178,24 +375,6 @@ type objectID struct {
 
 type Empty struct{}
 
-// GoMap is the usual with a MarshalJSON method
-type GoMap[Key comparable, Val any] map[Key]Val
-
-// GoSet is the usual with a MarshalJSON method
-type GoSet[Key comparable] GoMap[Key, Empty]
-
-var _ json.Marshaler = GoMap[int, func()]{}
-
-func (this GoMap[Key, Val]) MarshalJSON() ([]byte, error) {
-	return MarshalMap(this)
-}
-
-var _ json.Marshaler = GoSet[int]{}
-
-func (this GoSet[Key]) MarshalJSON() ([]byte, error) {
-	return MarshalSet(this)
-}
-
 func (rlw *resourcesListWatcher) InvalidateWithDefiner(obj any, supplier ResourceDefinitionSupplier, set bool) {
 	rlw.mutex.Lock()
 	defer rlw.mutex.Unlock()
@@ -209,8 +388,16 @@ func (rlw *resourcesListWatcher) Invalidate() {
 }
 
 func (rlw *resourcesListWatcher) invalidateWithDefinerLocked(obj any, supplier ResourceDefinitionSupplier, set bool) {
-	rlw.resourceVersionI += 1
-	rlw.relistAfter = time.Now().Add(time.Second * 20)
+	definerKind := ""
+	if obj != nil && supplier != nil {
+		definerKind = supplier.GetGVK(obj).Kind
+	}
+	invalidations.WithLabelValues(definerKind).Inc()
+	now := time.Now()
+	if !rlw.needRelist {
+		rlw.relistPendingSince = now
+	}
+	rlw.relistAfter = rlw.relistTime(now)
 	rlw.needRelist = true
 	rlw.cache.Invalidate()
 	rlw.cond.Broadcast()
@@ -229,10 +416,42 @@ func (rlw *resourcesListWatcher) invalidateWithDefinerLocked(obj any, supplier R
 		panic(obj)
 	}
 	var enumr ResourceDefinitionEnumerator = enumerateNothing
+	var deprecationSupplier ResourceDeprecationSupplier
 	if set {
 		enumr = supplier.EnumerateDefinedResources(obj)
+		deprecationSupplier, _ = supplier.(ResourceDeprecationSupplier)
 	}
 	rlw.setDefinerLocked(oid, enumr)
+	rlw.setDeprecationsLocked(oid, obj, deprecationSupplier)
+}
+
+// relistNow makes the informer query discovery as soon as possible,
+// without invalidating the cached discovery client.
+func (rlw *resourcesListWatcher) relistNow() {
+	rlw.mutex.Lock()
+	defer rlw.mutex.Unlock()
+	now := time.Now()
+	if !rlw.needRelist {
+		rlw.relistPendingSince = now
+	}
+	rlw.relistAfter = now
+	rlw.needRelist = true
+	rlw.cond.Broadcast()
+}
+
+// relistTime returns when to query discovery after an invalidation at
+// the given time, given when the pending invalidations started.
+func (rlw *resourcesListWatcher) relistTime(now time.Time) time.Time {
+	if rlw.immediateRelist {
+		return now
+	}
+	after := now.Add(rlw.relistDelay)
+	if rlw.maxRelistDelay > 0 {
+		if deadline := rlw.relistPendingSince.Add(rlw.maxRelistDelay); deadline.Before(after) {
+			return deadline
+		}
+	}
+	return after
 }
 
 func enumerateNothing(func(metav1.GroupVersionResource)) {}
@@ -241,6 +460,15 @@ type resourceWatch struct {
 	*resourcesListWatcher
 	cancel  context.CancelFunc
 	results chan watch.Event
+
+	// selector is the field selector of the Watch
+	selector fields.Selector
+
+	// bookmarks tells whether the client accepts Bookmark events
+	bookmarks bool
+
+	// batches holds the batches of events waiting to be delivered
+	batches chan []watch.Event
 }
 
 func (rw *resourceWatch) ResultChan() <-chan watch.Event {
@@ -252,6 +480,10 @@ func (rw *resourceWatch) Stop() {
 }
 
 func (rlw *resourcesListWatcher) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	selector, err := rlw.fieldSelector(opts)
+	if err != nil {
+		return nil, err
+	}
 	rlw.mutex.Lock()
 	defer rlw.mutex.Unlock()
 	resourceVersionS := strconv.FormatInt(rlw.resourceVersionI, 10)
@@ -264,17 +496,24 @@ func (rlw *resourcesListWatcher) Watch(opts metav1.ListOptions) (watch.Interface
 		resourcesListWatcher: rlw,
 		cancel:               cancel,
 		results:              make(chan watch.Event),
+		selector:             selector,
+		bookmarks:            opts.AllowWatchBookmarks,
+		batches:              make(chan []watch.Event, watchBacklog),
 	}
 	rlw.cancels = append(rlw.cancels, cancel)
-	go func() {
-		<-ctx.Done()
-		rlw.logger.V(3).Info("Ending an APIResource Watch")
-		close(rw.results)
-	}()
+	if rlw.watches == nil {
+		rlw.watches = map[*resourceWatch]Empty{}
+	}
+	rlw.watches[rw] = Empty{}
+	go rw.deliver(ctx)
 	return rw, nil
 }
 
 func (rlw *resourcesListWatcher) List(opts metav1.ListOptions) (k8sruntime.Object, error) {
+	selector, err := rlw.fieldSelector(opts)
+	if err != nil {
+		return nil, err
+	}
 	resourceVersionI := func() int64 {
 		rlw.mutex.Lock()
 		defer rlw.mutex.Unlock()
@@ -292,13 +531,62 @@ func (rlw *resourcesListWatcher) List(opts metav1.ListOptions) (k8sruntime.Objec
 		},
 		ListMeta: metav1.ListMeta{ResourceVersion: resourceVersionS},
 	}
+	if cached := rlw.loadCachedDiscovery(resourceVersionS); cached != nil {
+		ans.Items = cached.Items
+		rlw.recordCachedStats(ans.Items, cached.Time.Time)
+		rlw.recordResourceCounts(ans.Items)
+		rlw.setLast(ans.Items)
+		rlw.relistNow()
+	} else {
+		// An incomplete discovery is not fatal; it is reported in the stats.
+		var discoveryErr error
+		ans.Items, discoveryErr = rlw.discover(resourceVersionS)
+		rlw.recordStats(ans.Items, discoveryErr)
+		rlw.setLast(ans.Items)
+	}
+	ans.Items = filterByFields(ans.Items, selector)
+	return &ans, nil
+}
+
+// discover lists the APIResources that discovery reveals now, with the
+// given resource version.
+func (rlw *resourcesListWatcher) discover(resourceVersionS string) ([]ksmetav1a1.APIResource, error) {
+	if err := rlw.waitForDiscovery(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var items []ksmetav1a1.APIResource
 	var err error
-	if rlw.includeSubresources {
-		ans.Items, err = rlw.listWithSubresources(rlw.logger, resourceVersionS)
+	if rlw.includeSubresources || rlw.allVersions {
+		items, err = rlw.listWithSubresources(rlw.logger, resourceVersionS)
 	} else {
-		ans.Items, err = rlw.listSansSubresources(resourceVersionS)
+		items, err = rlw.listSansSubresources(resourceVersionS)
 	}
-	return &ans, err
+	rlw.recordRelist(start, err)
+	items = filterByVerbs(items, rlw.requiredVerbs)
+	if rlw.openAPI != nil {
+		rlw.attachOpenAPISchemas(items)
+	}
+	rlw.recordResourceCounts(items)
+	if err == nil {
+		rlw.storeDiscovery(items)
+	}
+	return items, err
+}
+
+// waitForDiscovery waits until the rate limiter, if any, allows a query of discovery.
+func (rlw *resourcesListWatcher) waitForDiscovery() error {
+	if rlw.discoveryLimiter == nil {
+		return nil
+	}
+	start := time.Now()
+	err := rlw.discoveryLimiter.Wait(rlw.ctx)
+	waited := time.Since(start)
+	relistThrottle.Observe(waited.Seconds())
+	if waited > time.Millisecond {
+		rlw.logger.V(4).Info("Waited for discovery rate limiter", "waited", waited)
+	}
+	return err
 }
 
 // arMap maps from resource or subresource name (single step in pathname) to data for that name
@@ -338,6 +626,9 @@ func (am arMap) toList(logger klog.Logger, prefix []string, consume func(ksmetav
 	}
 }
 
+// listWithSubresources lists the resources of every group's preferred
+// version, or of every version if allVersions, with their subresources
+// if includeSubresources.
 func (rlw *resourcesListWatcher) listWithSubresources(logger klog.Logger, resourceVersionS string) ([]ksmetav1a1.APIResource, error) {
 	groupList, resourceList, err := rlw.cache.ServerGroupsAndResources()
 	if err != nil {
@@ -347,6 +638,7 @@ func (rlw *resourcesListWatcher) listWithSubresources(logger klog.Logger, resour
 	for _, ag := range groupList {
 		groupToVersion[ag.Name] = ag.PreferredVersion.Version
 	}
+	preferred := preferredVersions(groupList, resourceList)
 	ans := []ksmetav1a1.APIResource{}
 	rlw.mutex.Lock()
 	defer rlw.mutex.Unlock()
@@ -356,7 +648,7 @@ func (rlw *resourcesListWatcher) listWithSubresources(logger klog.Logger, resour
 			rlw.logger.Error(err, "Failed to parse a GroupVersion", "groupVersion", group.GroupVersion)
 			continue
 		}
-		if groupToVersion[gv.Group] != gv.Version {
+		if !rlw.allVersions && groupToVersion[gv.Group] != gv.Version {
 			rlw.logger.V(4).Info("Ignoring wrong version", "gv", gv, "rightVersion", groupToVersion[gv.Group])
 			continue
 		}
@@ -364,6 +656,10 @@ func (rlw *resourcesListWatcher) listWithSubresources(logger klog.Logger, resour
 		rlw.enumAPIResourcesLocked(resourceVersionS, gv, group.APIResources, func(ar ksmetav1a1.APIResourceSpec) {
 			rscName := ar.Name
 			nameParts := strings.Split(rscName, "/")
+			if !rlw.includeSubresources && len(nameParts) > 1 {
+				return
+			}
+			ar.Preferred = preferred[schema.GroupResource{Group: gv.Group, Resource: nameParts[0]}] == gv.Version
 			am.insert(nameParts, &ar)
 		})
 		am.toList(logger, []string{}, func(spec ksmetav1a1.APIResourceSpec) {
@@ -371,7 +667,38 @@ func (rlw *resourcesListWatcher) listWithSubresources(logger klog.Logger, resour
 			ans = append(ans, complete)
 		})
 	}
-	return ans, nil
+	return ans, err
+}
+
+// preferredVersions returns the version of each resource that
+// discovery prefers, choosing as ServerPreferredResources does: the
+// preferred version of the group if it serves the resource, otherwise
+// the first listed version that does.
+func preferredVersions(groupList []*metav1.APIGroup, resourceList []*metav1.APIResourceList) map[schema.GroupResource]string {
+	byGroupVersion := map[string]*metav1.APIResourceList{}
+	for _, rl := range resourceList {
+		byGroupVersion[rl.GroupVersion] = rl
+	}
+	ans := map[schema.GroupResource]string{}
+	for _, group := range groupList {
+		for _, version := range group.Versions {
+			rl := byGroupVersion[version.GroupVersion]
+			if rl == nil {
+				continue
+			}
+			for _, rsc := range rl.APIResources {
+				if strings.Contains(rsc.Name, "/") {
+					continue
+				}
+				gr := schema.GroupResource{Group: group.Name, Resource: rsc.Name}
+				if _, have := ans[gr]; have && version.Version != group.PreferredVersion.Version {
+					continue
+				}
+				ans[gr] = version.Version
+			}
+		}
+	}
+	return ans
 }
 
 func specComplete(spec ksmetav1a1.APIResourceSpec, resourceVersionS string, gv schema.GroupVersion) ksmetav1a1.APIResource {
@@ -395,7 +722,9 @@ func (rlw *resourcesListWatcher) enumAPIResourcesLocked(resourceVersionS string,
 			rscVersion = gv.Version
 		}
 		gvr := metav1.GroupVersionResource{Group: gv.Group, Version: rscVersion, Resource: rsc.Name}
-		definers := definersToSlice(rlw.rscToDefiners[gvr])
+		definerIDs := rlw.definitions.FirstsOf(gvr)
+		definerIDs = append(definerIDs, rlw.definitions.FirstsOf(metav1.GroupVersionResource{Group: gv.Group, Version: rscVersion, Resource: AllResources})...)
+		definers := definersToSlice(definerIDs)
 		rlw.logger.V(4).Info("Enumerating", "gvr", gvr, "definers", definers)
 		arSpec := ksmetav1a1.APIResourceSpec{
 			Name:         rsc.Name,
@@ -406,15 +735,16 @@ func (rlw *resourcesListWatcher) enumAPIResourcesLocked(resourceVersionS string,
 			Kind:         rsc.Kind,
 			Verbs:        rsc.Verbs,
 			Definers:     definers,
+			Deprecation:  rlw.deprecationLocked(gvr),
 		}
 		// rlw.logger.V(4).Info("Producing an APIResource", "ar", ar)
 		consumer(arSpec)
 	}
 }
 
-func definersToSlice(asSet map[objectID]Empty) []ksmetav1a1.Definer {
-	ans := make([]ksmetav1a1.Definer, 0, len(asSet))
-	for definer := range asSet {
+func definersToSlice(definers []objectID) []ksmetav1a1.Definer {
+	ans := make([]ksmetav1a1.Definer, 0, len(definers))
+	for _, definer := range definers {
 		ans = append(ans, ksmetav1a1.Definer{Kind: definer.Kind, Name: definer.Name})
 	}
 	return ans
@@ -435,11 +765,12 @@ func (rlw *resourcesListWatcher) listSansSubresources(resourceVersionS string) (
 			continue
 		}
 		rlw.enumAPIResourcesLocked(resourceVersionS, gv, group.APIResources, func(arSpec ksmetav1a1.APIResourceSpec) {
+			arSpec.Preferred = true
 			ar := specComplete(arSpec, resourceVersionS, gv)
 			ans = append(ans, ar)
 		})
 	}
-	return ans, nil
+	return ans, err
 }
 
 type resourceLister struct {
@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	cachediscovery "k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/klog/v2"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+func TestRelistTime(t *testing.T) {
+	start := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name     string
+		rlw      *resourcesListWatcher
+		now      time.Time
+		expected time.Time
+	}{
+		{name: "default delay",
+			rlw: &resourcesListWatcher{relistDelay: DefaultRelistDelay}, now: start.Add(time.Minute),
+			expected: start.Add(time.Minute + DefaultRelistDelay)},
+		{name: "short delay",
+			rlw: &resourcesListWatcher{relistDelay: time.Second}, now: start,
+			expected: start.Add(time.Second)},
+		{name: "below the bound",
+			rlw: &resourcesListWatcher{relistDelay: 5 * time.Second, maxRelistDelay: time.Minute}, now: start.Add(10 * time.Second),
+			expected: start.Add(15 * time.Second)},
+		{name: "at the bound",
+			rlw: &resourcesListWatcher{relistDelay: 5 * time.Second, maxRelistDelay: time.Minute}, now: start.Add(58 * time.Second),
+			expected: start.Add(time.Minute)},
+		{name: "immediate",
+			rlw: &resourcesListWatcher{relistDelay: DefaultRelistDelay, maxRelistDelay: time.Minute, immediateRelist: true}, now: start.Add(3 * time.Second),
+			expected: start.Add(3 * time.Second)},
+	} {
+		tc.rlw.relistPendingSince = start
+		if actual := tc.rlw.relistTime(tc.now); !actual.Equal(tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+// countingLimiter is a flowcontrol.RateLimiter that counts Waits and
+// fails them with err.
+type countingLimiter struct {
+	flowcontrol.RateLimiter
+	waits int
+	err   error
+}
+
+func (cl *countingLimiter) Wait(ctx context.Context) error {
+	cl.waits++
+	return cl.err
+}
+
+func TestDiscoveryRateLimiter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fakeDiscovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	fakeDiscovery.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"get", "list"}}},
+	}}
+	limiter := &countingLimiter{RateLimiter: flowcontrol.NewFakeAlwaysRateLimiter()}
+	rlw := &resourcesListWatcher{
+		ctx:                   ctx,
+		logger:                klog.Background(),
+		clusterName:           "limit-test",
+		cache:                 cachediscovery.NewMemCacheClient(fakeDiscovery),
+		allVersions:           true,
+		discoveryLimiter:      limiter,
+		definitions:           relindex.NewRelation2[objectID, metav1.GroupVersionResource](),
+		definerToDeprecations: map[objectID]map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{},
+	}
+	rlw.cond = sync.NewCond(&rlw.mutex)
+
+	items, err := rlw.discover("1")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("discover returned %d items and %v", len(items), err)
+	}
+	if limiter.waits != 1 {
+		t.Errorf("Expected 1 wait on the limiter, got %d", limiter.waits)
+	}
+	limiter.err = errors.New("context canceled")
+	items, err = rlw.discover("2")
+	if err != limiter.err || items != nil {
+		t.Errorf("Expected the limiter's error and no items, got %v and %d items", err, len(items))
+	}
+
+	var gotCluster string
+	informerCtx, stopInformer := context.WithCancel(ctx)
+	defer stopInformer()
+	NewAPIResourceInformerWithOptions(informerCtx, "cluster-a", fakeDiscovery, APIResourceInformerOptions{
+		NewDiscoveryRateLimiter: func(clusterName string) flowcontrol.RateLimiter {
+			gotCluster = clusterName
+			return flowcontrol.NewFakeAlwaysRateLimiter()
+		},
+	})
+	if gotCluster != "cluster-a" {
+		t.Errorf("Expected the limiter to be made for cluster-a, got %q", gotCluster)
+	}
+}
+
+func TestForcedRelist(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fakeDiscovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	fakeDiscovery.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"get", "list"}}},
+	}}
+	ari := NewAPIResourceInformerWrapped(ctx, "forced-relist-test", fakeDiscovery, APIResourceInformerOptions{
+		AllVersions:        true,
+		ImmediateRelist:    true,
+		ForcedRelistPeriod: 20 * time.Millisecond,
+		NewDiscoveryRateLimiter: func(string) flowcontrol.RateLimiter {
+			return flowcontrol.NewFakeAlwaysRateLimiter()
+		},
+	})
+	go ari.Run(ctx.Done())
+	syncCtx, cancelSync := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelSync()
+	if !ari.WaitForSync(syncCtx) {
+		t.Fatal("Informer did not sync")
+	}
+	if _, err := ari.Lister.Get(":v1:configmaps"); err != nil {
+		t.Errorf("Failed to get configmaps: %v", err)
+	}
+	err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+		count, err := testutil.GetCounterMetricValue(relists.WithLabelValues("forced-relist-test", "complete"))
+		return count >= 3, err
+	})
+	if err != nil {
+		t.Errorf("Informer did not relist by itself: %v", err)
+	}
+}
+
+func TestRequiredVerbs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fakeDiscovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	fakeDiscovery.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"create", "get", "list", "watch"}},
+			{Name: "bindings", Namespaced: true, Kind: "Binding", Verbs: metav1.Verbs{"create"}},
+			{Name: "componentstatuses", Kind: "ComponentStatus", Verbs: metav1.Verbs{"get", "list"}},
+		},
+	}}
+	rlw := &resourcesListWatcher{
+		ctx:                   ctx,
+		logger:                klog.Background(),
+		clusterName:           "verbs-test",
+		cache:                 cachediscovery.NewMemCacheClient(fakeDiscovery),
+		allVersions:           true,
+		definitions:           relindex.NewRelation2[objectID, metav1.GroupVersionResource](),
+		definerToDeprecations: map[objectID]map[metav1.GroupVersionResource]ksmetav1a1.APIResourceDeprecation{},
+	}
+	rlw.cond = sync.NewCond(&rlw.mutex)
+
+	items, err := rlw.discover("1")
+	if err != nil || len(items) != 3 {
+		t.Fatalf("Expected all 3 resources without required verbs, got %d items and %v", len(items), err)
+	}
+	for _, tc := range []struct {
+		verbs    []string
+		expected []string
+	}{
+		{InformerVerbs, []string{"configmaps"}},
+		{[]string{"get"}, []string{"configmaps", "componentstatuses"}},
+		{[]string{"create"}, []string{"configmaps", "bindings"}},
+		{[]string{"delete"}, []string{}},
+	} {
+		rlw.requiredVerbs = sets.NewString(tc.verbs...)
+		items, err := rlw.discover("2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := sets.NewString()
+		for _, item := range items {
+			got.Insert(item.Spec.Name)
+		}
+		if !got.Equal(sets.NewString(tc.expected...)) {
+			t.Errorf("With required verbs %v, expected %v, got %v", tc.verbs, tc.expected, got.List())
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// ClusterStats summarizes what an APIResource informer last learned
+// about the resources of one cluster.
+type ClusterStats struct {
+	Cluster string `json:"cluster"`
+
+	// IncludesSubresources tells whether the informer lists subresources.
+	IncludesSubresources bool `json:"includesSubresources"`
+
+	// AllVersions tells whether the informer lists every served version
+	// of each resource, rather than only the preferred one.
+	AllVersions bool `json:"allVersions,omitempty"`
+
+	// Groups is the number of distinct API groups listed.
+	Groups int `json:"groups"`
+
+	// Resources is the number of (top-level) resources listed.
+	Resources int `json:"resources"`
+
+	Namespaced    int `json:"namespaced"`
+	ClusterScoped int `json:"clusterScoped"`
+
+	// Subresources is the number of subresources listed.
+	Subresources int `json:"subresources"`
+
+	// Deprecated is the number of resources with a declared deprecation.
+	Deprecated int `json:"deprecated"`
+
+	// LastRefresh is when the resources were last listed; nil if never.
+	LastRefresh *time.Time `json:"lastRefresh,omitempty"`
+
+	// DiscoveryError is the error, if any, from the discovery in the last refresh.
+	// Some resources may be missing when this is not empty.
+	DiscoveryError string `json:"discoveryError,omitempty"`
+
+	// FromCache tells whether the resources came from the discovery
+	// cache rather than from discovery. It stays true until the relist
+	// that follows such a List finishes, and in the meantime LastRefresh
+	// is when the cached resources were discovered.
+	FromCache bool `json:"fromCache,omitempty"`
+
+	// RefreshPending tells whether an invalidation has yet to be followed by a refresh.
+	RefreshPending bool `json:"refreshPending"`
+}
+
+// StatsRegistry collects the ClusterStats of the APIResource informers
+// that are running.
+type StatsRegistry struct {
+	mutex    sync.Mutex
+	watchers map[*resourcesListWatcher]Empty
+}
+
+// DefaultStatsRegistry is where every APIResource informer registers itself,
+// for as long as its context is not done.
+var DefaultStatsRegistry = NewStatsRegistry()
+
+func NewStatsRegistry() *StatsRegistry {
+	return &StatsRegistry{watchers: map[*resourcesListWatcher]Empty{}}
+}
+
+func (sr *StatsRegistry) add(rlw *resourcesListWatcher) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.watchers[rlw] = Empty{}
+}
+
+func (sr *StatsRegistry) remove(rlw *resourcesListWatcher) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	delete(sr.watchers, rlw)
+}
+
+// Snapshot returns the current ClusterStats, sorted by cluster name.
+// The same cluster may appear more than once, when watched by more than one informer.
+func (sr *StatsRegistry) Snapshot() []ClusterStats {
+	sr.mutex.Lock()
+	watchers := make([]*resourcesListWatcher, 0, len(sr.watchers))
+	for rlw := range sr.watchers {
+		watchers = append(watchers, rlw)
+	}
+	sr.mutex.Unlock()
+	ans := make([]ClusterStats, 0, len(watchers))
+	for _, rlw := range watchers {
+		ans = append(ans, rlw.getStats())
+	}
+	sort.SliceStable(ans, func(i, j int) bool {
+		if ans[i].Cluster != ans[j].Cluster {
+			return ans[i].Cluster < ans[j].Cluster
+		}
+		return !ans[i].IncludesSubresources && ans[j].IncludesSubresources
+	})
+	return ans
+}
+
+// ServeHTTP writes the Snapshot as JSON.
+// A "cluster" query parameter restricts the answer to that cluster.
+func (sr *StatsRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	stats := sr.Snapshot()
+	if cluster := req.URL.Query().Get("cluster"); cluster != "" {
+		filtered := []ClusterStats{}
+		for _, cs := range stats {
+			if cs.Cluster == cluster {
+				filtered = append(filtered, cs)
+			}
+		}
+		stats = filtered
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (rlw *resourcesListWatcher) getStats() ClusterStats {
+	rlw.mutex.Lock()
+	defer rlw.mutex.Unlock()
+	ans := rlw.stats
+	ans.RefreshPending = rlw.needRelist
+	return ans
+}
+
+// recordStats notes the results of a refresh
+func (rlw *resourcesListWatcher) recordStats(items []ksmetav1a1.APIResource, discoveryErr error) {
+	stats := rlw.summarize(items, time.Now())
+	if discoveryErr != nil {
+		stats.DiscoveryError = discoveryErr.Error()
+	}
+	rlw.mutex.Lock()
+	defer rlw.mutex.Unlock()
+	rlw.stats = stats
+}
+
+// recordCachedStats is recordStats for APIResources that came from the
+// discovery cache, which were discovered at the given time.
+func (rlw *resourcesListWatcher) recordCachedStats(items []ksmetav1a1.APIResource, discovered time.Time) {
+	stats := rlw.summarize(items, discovered)
+	stats.FromCache = true
+	rlw.mutex.Lock()
+	defer rlw.mutex.Unlock()
+	rlw.stats = stats
+}
+
+func (rlw *resourcesListWatcher) summarize(items []ksmetav1a1.APIResource, listed time.Time) ClusterStats {
+	stats := summarize(items)
+	stats.Cluster = rlw.clusterName
+	stats.IncludesSubresources = rlw.includeSubresources
+	stats.AllVersions = rlw.allVersions
+	stats.LastRefresh = &listed
+	return stats
+}
+
+func summarize(items []ksmetav1a1.APIResource) ClusterStats {
+	ans := ClusterStats{}
+	groups := map[string]Empty{}
+	for _, item := range items {
+		groups[item.Spec.Group] = Empty{}
+		ans.Resources++
+		if item.Spec.Namespaced {
+			ans.Namespaced++
+		} else {
+			ans.ClusterScoped++
+		}
+		if item.Spec.Deprecation != nil {
+			ans.Deprecated++
+		}
+		ans.Subresources += countSubresources(item.Spec.SubResources)
+	}
+	ans.Groups = len(groups)
+	return ans
+}
+
+func countSubresources(specs []*ksmetav1a1.APIResourceSpec) int {
+	ans := len(specs)
+	for _, spec := range specs {
+		ans += countSubresources(spec.SubResources)
+	}
+	return ans
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"testing"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+func TestSummarize(t *testing.T) {
+	status := &ksmetav1a1.APIResourceSpec{Name: "status"}
+	items := []ksmetav1a1.APIResource{
+		{Spec: ksmetav1a1.APIResourceSpec{Name: "pods", Namespaced: true,
+			SubResources: []*ksmetav1a1.APIResourceSpec{status, {Name: "log"}}}},
+		{Spec: ksmetav1a1.APIResourceSpec{Name: "nodes", SubResources: []*ksmetav1a1.APIResourceSpec{status}}},
+		{Spec: ksmetav1a1.APIResourceSpec{Group: "apps", Name: "deployments", Namespaced: true}},
+		{Spec: ksmetav1a1.APIResourceSpec{Group: "policy", Name: "podsecuritypolicies",
+			Deprecation: &ksmetav1a1.APIResourceDeprecation{}}},
+	}
+	expected := ClusterStats{Groups: 3, Resources: 4, Namespaced: 2, ClusterScoped: 2, Subresources: 3, Deprecated: 1}
+	actual := summarize(items)
+	if actual != expected {
+		t.Errorf("Expected %#v, got %#v", expected, actual)
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
+)
+
+// InformerVerbs are the verbs that a resource has to support for a
+// client to make informers on it.
+var InformerVerbs = []string{"list", "watch"}
+
+// filterByVerbs returns the items that support all the required verbs,
+// reusing the given slice.
+func filterByVerbs(items []ksmetav1a1.APIResource, required sets.String) []ksmetav1a1.APIResource {
+	if required.Len() == 0 {
+		return items
+	}
+	ans := items[:0]
+	for idx := range items {
+		if sets.NewString(items[idx].Spec.Verbs...).IsSuperset(required) {
+			ans = append(ans, items[idx])
+		}
+	}
+	return ans
+}
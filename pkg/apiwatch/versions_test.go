@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiwatch
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	upstreamdiscovery "k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+// stubDiscovery serves fixed groups and resources.
+type stubDiscovery struct {
+	upstreamdiscovery.CachedDiscoveryInterface
+	groups    []*metav1.APIGroup
+	resources []*metav1.APIResourceList
+}
+
+func (sd *stubDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	return sd.groups, sd.resources, nil
+}
+
+func newStubDiscovery() *stubDiscovery {
+	version := func(gv, v string) metav1.GroupVersionForDiscovery {
+		return metav1.GroupVersionForDiscovery{GroupVersion: gv, Version: v}
+	}
+	return &stubDiscovery{
+		groups: []*metav1.APIGroup{{
+			Name:             "example.com",
+			Versions:         []metav1.GroupVersionForDiscovery{version("example.com/v1", "v1"), version("example.com/v1beta1", "v1beta1")},
+			PreferredVersion: version("example.com/v1", "v1"),
+		}},
+		resources: []*metav1.APIResourceList{
+			{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{
+				{Name: "widgets", Namespaced: true, Kind: "Widget"},
+				{Name: "widgets/status", Namespaced: true, Kind: "Widget"},
+			}},
+			{GroupVersion: "example.com/v1beta1", APIResources: []metav1.APIResource{
+				{Name: "widgets", Namespaced: true, Kind: "Widget"},
+				{Name: "gadgets", Kind: "Gadget"},
+			}},
+		},
+	}
+}
+
+func TestAllVersions(t *testing.T) {
+	sd := newStubDiscovery()
+	rlw := &resourcesListWatcher{logger: klog.Background(), allVersions: true, cache: sd,
+		definitions: relindex.NewRelation2[objectID, metav1.GroupVersionResource]()}
+	items, err := rlw.listWithSubresources(rlw.logger, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, item := range items {
+		got[item.Name] = item.Spec.Preferred
+		if len(item.Spec.SubResources) != 0 {
+			t.Errorf("Expected no subresources in %s, got %v", item.Name, item.Spec.SubResources)
+		}
+	}
+	expected := map[string]bool{
+		"example.com:v1:widgets":      true,
+		"example.com:v1beta1:widgets": false,
+		"example.com:v1beta1:gadgets": true, // only served there
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	rlw.allVersions, rlw.includeSubresources = false, true
+	items, err = rlw.listWithSubresources(rlw.logger, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+		if !item.Spec.Preferred {
+			t.Errorf("Expected %s to be preferred", item.Name)
+		}
+	}
+	sort.Strings(names)
+	if expectedNames := []string{"example.com:v1:widgets"}; !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected %v, got %v", expectedNames, names)
+	}
+	if subs := items[0].Spec.SubResources; len(subs) != 1 || subs[0].Name != "status" || !subs[0].Preferred {
+		t.Errorf("Expected the preferred status subresource, got %v", subs)
+	}
+}
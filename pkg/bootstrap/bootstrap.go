@@ -0,0 +1,427 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bootstrap initializes a KubeStellar hub: it makes the core
+// space, the inventory spaces and the workload description spaces,
+// installs the KubeStellar CRDs that each kind of space needs, makes a
+// default Location in each inventory space, and makes an identity for
+// each controller in the core space, with the RBAC that it needs.
+//
+// Bootstrapping is idempotent: what is already as desired is left
+// alone, so it can be run again after a failure, or after an upgrade
+// that changes what is desired. The Report tells what was done to each
+// object.
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/crdinstall"
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+	spaceclientv1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/client/clientset/versioned/typed/space/v1alpha1"
+)
+
+// FieldManager is the field manager of the writes made in bootstrapping.
+const FieldManager = "kubestellar-bootstrap"
+
+// DefaultLocationName is the name of the Location, made in each
+// inventory space, that selects all the SyncTargets there.
+const DefaultLocationName = "default"
+
+// DefaultSpaceReadyTimeout is how long, by default, to wait for a
+// space to become Ready.
+const DefaultSpaceReadyTimeout = 5 * time.Minute
+
+// InventoryResources are the resources of the CRDs that an inventory space needs.
+var InventoryResources = []string{"locations", "synctargets", "clusterregistrations"}
+
+// WorkloadResources are the resources of the CRDs that a workload
+// description space needs.
+var WorkloadResources = []string{"edgeplacements", "customizers", "namespacededgeplacements", "singleplacementslices"}
+
+// Identity is a controller identity: a ServiceAccount in the core space,
+// bound to a ClusterRole with the given rules. The ClusterRole and its
+// binding are named `kubestellar:<Name>`.
+type Identity struct {
+	Name      string
+	Namespace string
+	Rules     []rbacv1.PolicyRule
+}
+
+// ClusterRoleName returns the name of the ClusterRole, and of its binding, of the Identity.
+func (id Identity) ClusterRoleName() string {
+	return "kubestellar:" + id.Name
+}
+
+// DefaultIdentities returns the identities of the central controllers,
+// in the given namespace.
+func DefaultIdentities(namespace string) []Identity {
+	group := edgeapi.SchemeGroupVersion.Group
+	read := []string{"get", "list", "watch"}
+	write := []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+	events := rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create", "patch", "update"}}
+	return []Identity{{
+		Name: "where-resolver", Namespace: namespace,
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{group}, Resources: []string{"edgeplacements", "locations", "synctargets"}, Verbs: read},
+			{APIGroups: []string{group}, Resources: []string{"edgeplacements/status"}, Verbs: []string{"update", "patch"}},
+			{APIGroups: []string{group}, Resources: []string{"singleplacementslices"}, Verbs: write},
+			events,
+		},
+	}, {
+		Name: "mailbox-controller", Namespace: namespace,
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{group}, Resources: []string{"synctargets"}, Verbs: read},
+			events,
+		},
+	}, {
+		Name: "placement-translator", Namespace: namespace,
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{group}, Resources: []string{"*"}, Verbs: read},
+			{APIGroups: []string{group}, Resources: []string{"syncerconfigs", "tenantusages"}, Verbs: write},
+			{APIGroups: []string{group}, Resources: []string{"edgeplacements/status"}, Verbs: []string{"update", "patch"}},
+			events,
+		},
+	}}
+}
+
+// Plan is what a hub is to have.
+type Plan struct {
+	// ProviderName is the name of the space provider, and ProviderNS
+	// the namespace of its Spaces.
+	ProviderName string
+	ProviderNS   string
+
+	CoreSpace       string
+	InventorySpaces []string
+	WorkloadSpaces  []string
+
+	// CRDs are installed in full in the core space; the inventory and
+	// workload description spaces get the ones for InventoryResources
+	// and WorkloadResources.
+	CRDs []*apiext.CustomResourceDefinition
+
+	// DefaultLocation asks for a Location named DefaultLocationName
+	// in each inventory space.
+	DefaultLocation bool
+
+	Identities []Identity
+}
+
+// Action is what bootstrapping did to one object.
+type Action string
+
+const (
+	ActionCreated   Action = "Created"
+	ActionUpdated   Action = "Updated"
+	ActionUnchanged Action = "Unchanged"
+	ActionFailed    Action = "Failed"
+)
+
+// Step is what bootstrapping did to one object.
+type Step struct {
+	// Space is empty for a Space itself.
+	Space     string `json:"space,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Action    Action `json:"action"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is what bootstrapping did, in the order that it was done.
+type Report struct {
+	Steps []Step `json:"steps"`
+}
+
+// Failed returns the Steps that failed.
+func (report *Report) Failed() []Step {
+	var ans []Step
+	for _, step := range report.Steps {
+		if step.Action == ActionFailed {
+			ans = append(ans, step)
+		}
+	}
+	return ans
+}
+
+// Count returns the number of Steps with the given Action.
+func (report *Report) Count(action Action) int {
+	ans := 0
+	for _, step := range report.Steps {
+		if step.Action == action {
+			ans++
+		}
+	}
+	return ans
+}
+
+// JSON returns the Report as indented JSON.
+func (report *Report) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (report *Report) add(space, kind, namespace, name string, action Action, err error) {
+	step := Step{Space: space, Kind: kind, Namespace: namespace, Name: name, Action: action}
+	if err != nil {
+		step.Action, step.Error = ActionFailed, err.Error()
+	}
+	report.Steps = append(report.Steps, step)
+}
+
+// ClientSource hands out the clients of a space. *spaceclient.Factory is one.
+type ClientSource interface {
+	For(space, providerNS string) (*spaceclient.Clients, error)
+}
+
+// Bootstrapper carries out Plans.
+type Bootstrapper struct {
+	logger  klog.Logger
+	spaces  spaceclientv1alpha1.SpacesGetter
+	clients ClientSource
+
+	// SpaceReadyTimeout bounds the wait for each space to become Ready.
+	SpaceReadyTimeout time.Duration
+
+	// crdClient makes the client for the CRDs of a space.
+	crdClient func(*spaceclient.Clients) (apiextclient.CustomResourceDefinitionInterface, error)
+}
+
+// NewBootstrapper makes a Bootstrapper that makes Spaces with the given
+// client of the space management API and reaches into them with the
+// given ClientSource.
+func NewBootstrapper(logger klog.Logger, spaces spaceclientv1alpha1.SpacesGetter, clients ClientSource) *Bootstrapper {
+	return &Bootstrapper{
+		logger:            logger,
+		spaces:            spaces,
+		clients:           clients,
+		SpaceReadyTimeout: DefaultSpaceReadyTimeout,
+		crdClient: func(clients *spaceclient.Clients) (apiextclient.CustomResourceDefinitionInterface, error) {
+			client, err := apiextclient.NewForConfigAndClient(clients.Config, clients.HTTPClient)
+			if err != nil {
+				return nil, err
+			}
+			return client.CustomResourceDefinitions(), nil
+		},
+	}
+}
+
+// Run carries out the given Plan. It keeps going after a failure,
+// except that nothing is done in a space that could not be made Ready.
+// The returned error is non-nil if any Step failed.
+func (bs *Bootstrapper) Run(ctx context.Context, plan Plan) (*Report, error) {
+	report := &Report{}
+	inventory := selectCRDs(plan.CRDs, InventoryResources)
+	workload := selectCRDs(plan.CRDs, WorkloadResources)
+	if clients := bs.ensureSpace(ctx, report, plan, plan.CoreSpace); clients != nil {
+		bs.ensureCRDs(ctx, report, plan.CoreSpace, clients, plan.CRDs)
+		for _, id := range plan.Identities {
+			bs.ensureIdentity(ctx, report, plan.CoreSpace, clients, id)
+		}
+	}
+	for _, space := range plan.InventorySpaces {
+		clients := bs.ensureSpace(ctx, report, plan, space)
+		if clients == nil {
+			continue
+		}
+		bs.ensureCRDs(ctx, report, space, clients, inventory)
+		if plan.DefaultLocation {
+			bs.ensureDefaultLocation(ctx, report, space, clients)
+		}
+	}
+	for _, space := range plan.WorkloadSpaces {
+		if clients := bs.ensureSpace(ctx, report, plan, space); clients != nil {
+			bs.ensureCRDs(ctx, report, space, clients, workload)
+		}
+	}
+	if failed := report.Failed(); len(failed) > 0 {
+		return report, fmt.Errorf("%d of %d bootstrap steps failed; the first is %s %q in space %q: %s",
+			len(failed), len(report.Steps), failed[0].Kind, failed[0].Name, failed[0].Space, failed[0].Error)
+	}
+	return report, nil
+}
+
+// selectCRDs returns those of the given CRDs that define the given resources.
+func selectCRDs(crds []*apiext.CustomResourceDefinition, resources []string) []*apiext.CustomResourceDefinition {
+	want := sets.NewString(resources...)
+	var ans []*apiext.CustomResourceDefinition
+	for _, crd := range crds {
+		if want.Has(crd.Spec.Names.Plural) {
+			ans = append(ans, crd)
+		}
+	}
+	return ans
+}
+
+// ensureSpace makes sure that the given Space exists and is Ready, and
+// returns its clients; nil if that failed.
+func (bs *Bootstrapper) ensureSpace(ctx context.Context, report *Report, plan Plan, name string) *spaceclient.Clients {
+	logger := klog.LoggerWithValues(bs.logger, "space", name)
+	spaces := bs.spaces.Spaces(plan.ProviderNS)
+	action := ActionUnchanged
+	_, err := spaces.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logger.Info("Creating space")
+		space := &spacev1alpha1.Space{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: spacev1alpha1.SpaceSpec{
+				SpaceProviderDescName: plan.ProviderName,
+				Type:                  spacev1alpha1.SpaceTypeManaged,
+			},
+		}
+		_, err = spaces.Create(ctx, space, metav1.CreateOptions{FieldManager: FieldManager})
+		if err == nil {
+			action = ActionCreated
+		} else if apierrors.IsAlreadyExists(err) {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = wait.PollImmediateWithContext(ctx, time.Second, bs.SpaceReadyTimeout, func(ctx context.Context) (bool, error) {
+			space, err := spaces.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			return space.Status.Phase == spacev1alpha1.SpacePhaseReady, nil
+		})
+		if err == wait.ErrWaitTimeout {
+			err = fmt.Errorf("not Ready within %v", bs.SpaceReadyTimeout)
+		}
+	}
+	var clients *spaceclient.Clients
+	if err == nil {
+		clients, err = bs.clients.For(name, plan.ProviderNS)
+	}
+	report.add("", "Space", plan.ProviderNS, name, action, err)
+	if err != nil {
+		logger.Error(err, "Failed to ensure space")
+		return nil
+	}
+	return clients
+}
+
+func (bs *Bootstrapper) ensureCRDs(ctx context.Context, report *Report, space string, clients *spaceclient.Clients, crds []*apiext.CustomResourceDefinition) {
+	if len(crds) == 0 {
+		return
+	}
+	client, err := bs.crdClient(clients)
+	if err != nil {
+		for _, crd := range crds {
+			report.add(space, "CustomResourceDefinition", "", crd.Name, "", err)
+		}
+		return
+	}
+	installer := crdinstall.NewInstaller(klog.LoggerWithValues(bs.logger, "space", space), client, clients.Dynamic)
+	for _, result := range installer.InstallEach(ctx, crds) {
+		report.add(space, "CustomResourceDefinition", "", result.Name, Action(result.Outcome), result.Err)
+	}
+}
+
+func (bs *Bootstrapper) ensureDefaultLocation(ctx context.Context, report *Report, space string, clients *spaceclient.Clients) {
+	locations := clients.Edge.EdgeV2alpha1().Locations()
+	_, err := locations.Get(ctx, DefaultLocationName, metav1.GetOptions{})
+	action := ActionUnchanged
+	if apierrors.IsNotFound(err) {
+		location := &edgeapi.Location{
+			ObjectMeta: metav1.ObjectMeta{Name: DefaultLocationName},
+			Spec: edgeapi.LocationSpec{
+				Resource:         edgeapi.GroupVersionResource{Group: edgeapi.SchemeGroupVersion.Group, Version: edgeapi.SchemeGroupVersion.Version, Resource: "synctargets"},
+				InstanceSelector: &metav1.LabelSelector{},
+			},
+		}
+		_, err = locations.Create(ctx, location, metav1.CreateOptions{FieldManager: FieldManager})
+		action = ActionCreated
+	}
+	// An existing default Location is left as its owner made it.
+	report.add(space, "Location", "", DefaultLocationName, action, err)
+}
+
+func (bs *Bootstrapper) ensureIdentity(ctx context.Context, report *Report, space string, clients *spaceclient.Clients, id Identity) {
+	kube := clients.Kube
+	_, err := kube.CoreV1().Namespaces().Get(ctx, id.Namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = kube.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: id.Namespace}}, metav1.CreateOptions{FieldManager: FieldManager})
+		report.add(space, "Namespace", "", id.Namespace, ActionCreated, err)
+	} else if err != nil {
+		report.add(space, "Namespace", "", id.Namespace, "", err)
+	}
+
+	_, err = kube.CoreV1().ServiceAccounts(id.Namespace).Get(ctx, id.Name, metav1.GetOptions{})
+	action := ActionUnchanged
+	if apierrors.IsNotFound(err) {
+		_, err = kube.CoreV1().ServiceAccounts(id.Namespace).Create(ctx, &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: id.Name}}, metav1.CreateOptions{FieldManager: FieldManager})
+		action = ActionCreated
+	}
+	report.add(space, "ServiceAccount", id.Namespace, id.Name, action, err)
+
+	roleName := id.ClusterRoleName()
+	roles := kube.RbacV1().ClusterRoles()
+	role, err := roles.Get(ctx, roleName, metav1.GetOptions{})
+	action = ActionUnchanged
+	if apierrors.IsNotFound(err) {
+		_, err = roles.Create(ctx, &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: roleName}, Rules: id.Rules}, metav1.CreateOptions{FieldManager: FieldManager})
+		action = ActionCreated
+	} else if err == nil && !apiequality.Semantic.DeepEqual(role.Rules, id.Rules) {
+		role = role.DeepCopy()
+		role.Rules = id.Rules
+		_, err = roles.Update(ctx, role, metav1.UpdateOptions{FieldManager: FieldManager})
+		action = ActionUpdated
+	}
+	report.add(space, "ClusterRole", "", roleName, action, err)
+
+	desiredRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: roleName}
+	desiredSubjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: id.Namespace, Name: id.Name}}
+	bindings := kube.RbacV1().ClusterRoleBindings()
+	binding, err := bindings.Get(ctx, roleName, metav1.GetOptions{})
+	action = ActionUnchanged
+	if apierrors.IsNotFound(err) {
+		_, err = bindings.Create(ctx, &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: roleName}, RoleRef: desiredRef, Subjects: desiredSubjects}, metav1.CreateOptions{FieldManager: FieldManager})
+		action = ActionCreated
+	} else if err == nil && binding.RoleRef != desiredRef {
+		// The RoleRef of a binding cannot be changed, so it is replaced.
+		err = bindings.Delete(ctx, roleName, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &binding.UID}})
+		if err == nil {
+			_, err = bindings.Create(ctx, &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: roleName}, RoleRef: desiredRef, Subjects: desiredSubjects}, metav1.CreateOptions{FieldManager: FieldManager})
+		}
+		action = ActionUpdated
+	} else if err == nil && !apiequality.Semantic.DeepEqual(binding.Subjects, desiredSubjects) {
+		binding = binding.DeepCopy()
+		binding.Subjects = desiredSubjects
+		_, err = bindings.Update(ctx, binding, metav1.UpdateOptions{FieldManager: FieldManager})
+		action = ActionUpdated
+	}
+	report.add(space, "ClusterRoleBinding", "", roleName, action, err)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apiextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/config/crds"
+	edgefake "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/fake"
+	"github.com/kubestellar/kubestellar/pkg/crdinstall"
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+	spacefake "github.com/kubestellar/kubestellar/space-framework/pkg/client/clientset/versioned/fake"
+)
+
+const providerNS = "spaceprovider-default"
+
+type fakeSpace struct {
+	clients *spaceclient.Clients
+	apiext  *apiextfake.Clientset
+}
+
+type fakeSpaces map[string]*fakeSpace
+
+func (spaces fakeSpaces) For(space, providerNS string) (*spaceclient.Clients, error) {
+	if fs, ok := spaces[space]; ok {
+		return fs.clients, nil
+	}
+	return nil, fmt.Errorf("no space %q", space)
+}
+
+func newFakeSpace() *fakeSpace {
+	apiextClient := apiextfake.NewSimpleClientset()
+	crdGVR := apiext.SchemeGroupVersion.WithResource("customresourcedefinitions")
+	// Like the API server, establish a CRD when it is created.
+	apiextClient.PrependReactor("create", "customresourcedefinitions", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		crd := action.(clienttesting.CreateAction).GetObject().(*apiext.CustomResourceDefinition).DeepCopy()
+		crd.Status.Conditions = []apiext.CustomResourceDefinitionCondition{{Type: apiext.Established, Status: apiext.ConditionTrue}}
+		return true, crd, apiextClient.Tracker().Create(crdGVR, crd, "")
+	})
+	return &fakeSpace{
+		clients: &spaceclient.Clients{
+			Kube:    kubefake.NewSimpleClientset(),
+			Edge:    edgefake.NewSimpleClientset(),
+			Dynamic: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{}),
+		},
+		apiext: apiextClient,
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	loaded, err := crdinstall.Load(crds.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	core := &spacev1alpha1.Space{ObjectMeta: metav1.ObjectMeta{Namespace: providerNS, Name: "espw"},
+		Status: spacev1alpha1.SpaceStatus{Phase: spacev1alpha1.SpacePhaseReady}}
+	spaceClient := spacefake.NewSimpleClientset(core)
+	spaceGVR := spacev1alpha1.SchemeGroupVersion.WithResource("spaces")
+	// Like the space provider, make a Space Ready when it is created.
+	spaceClient.PrependReactor("create", "spaces", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		space := action.(clienttesting.CreateAction).GetObject().(*spacev1alpha1.Space).DeepCopy()
+		space.Namespace = action.GetNamespace()
+		space.Status.Phase = spacev1alpha1.SpacePhaseReady
+		return true, space, spaceClient.Tracker().Create(spaceGVR, space, action.GetNamespace())
+	})
+	spaces := fakeSpaces{"espw": newFakeSpace(), "imw1": newFakeSpace(), "wds1": newFakeSpace()}
+	bs := NewBootstrapper(klog.Background(), spaceClient.SpaceV1alpha1(), spaces)
+	bs.crdClient = func(clients *spaceclient.Clients) (apiextclient.CustomResourceDefinitionInterface, error) {
+		for _, fs := range spaces {
+			if fs.clients == clients {
+				return fs.apiext.ApiextensionsV1().CustomResourceDefinitions(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown clients")
+	}
+	plan := Plan{
+		ProviderName:    "default",
+		ProviderNS:      providerNS,
+		CoreSpace:       "espw",
+		InventorySpaces: []string{"imw1"},
+		WorkloadSpaces:  []string{"wds1"},
+		CRDs:            loaded,
+		DefaultLocation: true,
+		Identities:      DefaultIdentities("kubestellar"),
+	}
+
+	report, err := bs.Run(ctx, plan)
+	if err != nil {
+		t.Fatalf("Run failed: %v\n%+v", err, report.Steps)
+	}
+	// 2 new Spaces; all the CRDs in espw, 3 in imw1 and 4 in wds1; the
+	// default Location; a Namespace and 3 objects per identity.
+	expectedCreated := 2 + len(loaded) + len(InventoryResources) + len(WorkloadResources) + 1 + 1 + 3*len(plan.Identities)
+	if got := report.Count(ActionCreated); got != expectedCreated {
+		t.Errorf("Expected %d objects created, got %d: %+v", expectedCreated, got, report.Steps)
+	}
+	if report.Steps[0].Name != "espw" || report.Steps[0].Action != ActionUnchanged {
+		t.Errorf("Expected the existing core space to be unchanged, got %+v", report.Steps[0])
+	}
+	wdsCRDs, err := spaces["wds1"].apiext.ApiextensionsV1().CustomResourceDefinitions().List(ctx, metav1.ListOptions{})
+	if err != nil || len(wdsCRDs.Items) != len(WorkloadResources) {
+		t.Errorf("Expected %d CRDs in wds1, got %v, %v", len(WorkloadResources), wdsCRDs, err)
+	}
+	if _, err := spaces["imw1"].clients.Edge.EdgeV2alpha1().Locations().Get(ctx, DefaultLocationName, metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the default Location in imw1: %v", err)
+	}
+
+	// Running again changes nothing.
+	report, err = bs.Run(ctx, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created, updated := report.Count(ActionCreated), report.Count(ActionUpdated); created != 0 || updated != 0 {
+		t.Errorf("Expected nothing to be done again, got %+v", report.Steps)
+	}
+
+	// A ClusterRole that was changed by hand is restored.
+	roles := spaces["espw"].clients.Kube.RbacV1().ClusterRoles()
+	role, err := roles.Get(ctx, plan.Identities[0].ClusterRoleName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	role.Rules = []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}}
+	if _, err := roles.Update(ctx, role, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	report, err = bs.Run(ctx, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Count(ActionUpdated) != 1 {
+		t.Errorf("Expected the ClusterRole to be updated, got %+v", report.Steps)
+	}
+
+	// A space that does not come up fails, and nothing is done in it.
+	plan.WorkloadSpaces = append(plan.WorkloadSpaces, "missing")
+	report, err = bs.Run(ctx, plan)
+	if err == nil || len(report.Failed()) != 1 || report.Failed()[0].Name != "missing" {
+		t.Errorf("Expected only space missing to fail, got %v, %+v", err, report.Failed())
+	}
+}
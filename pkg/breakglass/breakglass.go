@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package breakglass defines the break-glass override, with which an
+// operator takes over, for a bounded time, one copy of a workload
+// object at one destination --- typically to apply an emergency hotfix
+// at the edge. The override is declared by two annotations on the copy
+// in the mailbox space: UntilAnnotationKey, whose value is the RFC 3339
+// time at which the override ends, and ReasonAnnotationKey, which says
+// why. While the override is in force the placement translator does
+// not update or revert that copy, and the mailbox guard lets anyone who
+// may write the copy change it. When it ends, the translator writes the
+// copy back to what its source says and removes the annotations.
+package breakglass
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// UntilAnnotationKey is the key of the annotation that holds the
+	// end of the override, in RFC 3339 format.
+	UntilAnnotationKey = "edge.kubestellar.io/break-glass-until"
+
+	// ReasonAnnotationKey is the key of the annotation that says why
+	// the override was made. It is required.
+	ReasonAnnotationKey = "edge.kubestellar.io/break-glass-reason"
+)
+
+// DefaultMaxWindow is the default bound on the length of an override.
+const DefaultMaxWindow = 24 * time.Hour
+
+// Override is a break-glass override.
+type Override struct {
+	Until  time.Time
+	Reason string
+}
+
+// Get returns the override declared on the given object, or nil if
+// there is none. An override that is malformed, or that would last
+// more than maxWindow after the given time, is an error.
+func Get(obj metav1.Object, now time.Time, maxWindow time.Duration) (*Override, error) {
+	annotations := obj.GetAnnotations()
+	untilS, have := annotations[UntilAnnotationKey]
+	if !have {
+		return nil, nil
+	}
+	until, err := time.Parse(time.RFC3339, untilS)
+	if err != nil {
+		return nil, fmt.Errorf("annotation %s is not an RFC 3339 time: %w", UntilAnnotationKey, err)
+	}
+	reason := annotations[ReasonAnnotationKey]
+	if reason == "" {
+		return nil, fmt.Errorf("annotation %s requires annotation %s", UntilAnnotationKey, ReasonAnnotationKey)
+	}
+	if until.Sub(now) > maxWindow {
+		return nil, fmt.Errorf("break-glass override until %s lasts more than the maximum of %s", untilS, maxWindow)
+	}
+	return &Override{Until: until, Reason: reason}, nil
+}
+
+// InForce tells whether the override has not yet ended at the given time.
+func (ovr *Override) InForce(now time.Time) bool {
+	return ovr != nil && now.Before(ovr.Until)
+}
+
+// Strip removes the override annotations from the given object.
+func Strip(obj metav1.Object) {
+	annotations := obj.GetAnnotations()
+	_, haveUntil := annotations[UntilAnnotationKey]
+	_, haveReason := annotations[ReasonAnnotationKey]
+	if !haveUntil && !haveReason {
+		return
+	}
+	delete(annotations, UntilAnnotationKey)
+	delete(annotations, ReasonAnnotationKey)
+	obj.SetAnnotations(annotations)
+}
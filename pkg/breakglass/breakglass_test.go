@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package breakglass
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGet(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name        string
+		annotations map[string]string
+		expectErr   bool
+		expectUntil string
+		inForce     bool
+	}{
+		{name: "none", annotations: map[string]string{"other": "x"}},
+		{name: "good", annotations: map[string]string{UntilAnnotationKey: "2023-09-01T14:00:00Z", ReasonAnnotationKey: "hotfix"},
+			expectUntil: "2023-09-01T14:00:00Z", inForce: true},
+		{name: "ended", annotations: map[string]string{UntilAnnotationKey: "2023-09-01T11:00:00Z", ReasonAnnotationKey: "hotfix"},
+			expectUntil: "2023-09-01T11:00:00Z"},
+		{name: "no reason", annotations: map[string]string{UntilAnnotationKey: "2023-09-01T14:00:00Z"}, expectErr: true},
+		{name: "malformed", annotations: map[string]string{UntilAnnotationKey: "tomorrow", ReasonAnnotationKey: "hotfix"}, expectErr: true},
+		{name: "too long", annotations: map[string]string{UntilAnnotationKey: "2023-09-03T12:00:00Z", ReasonAnnotationKey: "hotfix"}, expectErr: true},
+	} {
+		obj := &metav1.ObjectMeta{Annotations: tc.annotations}
+		ovr, err := Get(obj, now, DefaultMaxWindow)
+		if (err != nil) != tc.expectErr {
+			t.Errorf("%s: expected error=%v, got %v", tc.name, tc.expectErr, err)
+			continue
+		}
+		if tc.expectUntil == "" {
+			if ovr != nil {
+				t.Errorf("%s: expected no override, got %+v", tc.name, ovr)
+			}
+			continue
+		}
+		if ovr == nil || ovr.Until.Format(time.RFC3339) != tc.expectUntil || ovr.InForce(now) != tc.inForce {
+			t.Errorf("%s: expected override until %s in force=%v, got %+v", tc.name, tc.expectUntil, tc.inForce, ovr)
+		}
+	}
+	obj := &metav1.ObjectMeta{Annotations: map[string]string{UntilAnnotationKey: "x", ReasonAnnotationKey: "y", "other": "z"}}
+	Strip(obj)
+	if len(obj.Annotations) != 1 {
+		t.Errorf("Expected only the other annotation to remain, got %v", obj.Annotations)
+	}
+}
@@ -0,0 +1,325 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bundle packs many workload objects into a few compressed
+// ConfigMaps, to relieve the hub of a large number of objects in a mailbox space.
+// The placement translator writes the bundles and the syncer unpacks them.
+//
+// A set of bundled objects is represented by an index ConfigMap and some
+// part ConfigMaps, all in Namespace.
+// Each part holds a gzipped JSON array of objects and is named by a hash
+// of its content, so a part is never modified.
+// The writer creates the new parts, then updates the index, then
+// deletes the parts that are no longer referenced.
+package bundle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/kubestellar/kubestellar/pkg/objecthash"
+)
+
+const (
+	// Namespace is the namespace, in the mailbox space, that holds the bundle ConfigMaps.
+	Namespace = "kubestellar-bundles"
+
+	// IndexName is the name of the index ConfigMap.
+	IndexName = "index"
+
+	// PartNamePrefix starts the name of every part ConfigMap.
+	PartNamePrefix = "part-"
+
+	// LabelKey labels the bundle ConfigMaps, with a value of LabelValIndex or LabelValPart.
+	LabelKey      = "edge.kubestellar.io/bundle"
+	LabelValIndex = "index"
+	LabelValPart  = "part"
+
+	// IndexKey is the key, in the index ConfigMap's Data, of the JSON encoding of the Index.
+	IndexKey = "index.json"
+
+	// ObjectsKey is the key, in a part ConfigMap's BinaryData, of the gzipped JSON array of objects.
+	ObjectsKey = "objects.json.gz"
+
+	// AppliedName is the name of the ConfigMap in which the syncer records the
+	// objects that it has unpacked, so that it can delete those that leave the
+	// bundles even across a restart. It does not bear LabelKey, so the
+	// placement translator leaves it alone.
+	AppliedName = "applied"
+
+	// AppliedKey is the key, in the applied ConfigMap's Data, of the JSON array of ObjectRefs.
+	AppliedKey = "applied.json"
+
+	// UnbundledAnnotationKey is put on an object that the syncer unpacked from a bundle.
+	// The value is the ContentHash of the bundled object.
+	UnbundledAnnotationKey = "edge.kubestellar.io/unbundled"
+
+	// MaxPartBytes limits the uncompressed JSON in one part, keeping
+	// the part well under the apiserver's limit on object size.
+	MaxPartBytes = 768 * 1024
+)
+
+// ObjectRef identifies a bundled object.
+type ObjectRef struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// RefOf returns the ObjectRef of the given object, which is an instance of the given resource.
+func RefOf(resource string, obj *unstructured.Unstructured) ObjectRef {
+	gvk := obj.GroupVersionKind()
+	return ObjectRef{Group: gvk.Group, Version: gvk.Version, Resource: resource, Kind: gvk.Kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+func (ref ObjectRef) String() string {
+	return fmt.Sprintf("%s.%s/%s/%s/%s", ref.Kind, ref.Group, ref.Version, ref.Namespace, ref.Name)
+}
+
+func (ref ObjectRef) less(other ObjectRef) bool {
+	if ref.Group != other.Group {
+		return ref.Group < other.Group
+	}
+	if ref.Resource != other.Resource {
+		return ref.Resource < other.Resource
+	}
+	if ref.Namespace != other.Namespace {
+		return ref.Namespace < other.Namespace
+	}
+	if ref.Name != other.Name {
+		return ref.Name < other.Name
+	}
+	return ref.Version < other.Version
+}
+
+// Index lists the parts of a set of bundled objects.
+type Index struct {
+	Parts []IndexPart `json:"parts"`
+}
+
+// IndexPart describes one part ConfigMap.
+type IndexPart struct {
+	Name    string      `json:"name"`
+	Objects []ObjectRef `json:"objects"`
+}
+
+// ObjectCount returns the number of objects in the bundled set.
+func (index Index) ObjectCount() int {
+	var ans int
+	for _, part := range index.Parts {
+		ans += len(part.Objects)
+	}
+	return ans
+}
+
+// ContentHash returns a hash of the given object's content; see package objecthash.
+func ContentHash(obj *unstructured.Unstructured) (string, error) {
+	return objecthash.Of(obj)
+}
+
+func hashOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:20]
+}
+
+// Pack packs the given objects into parts, and returns the index ConfigMap and the part ConfigMaps.
+// The objects in a part are in the same order as their references in the index.
+func Pack(objs map[ObjectRef]*unstructured.Unstructured) (*corev1.ConfigMap, []*corev1.ConfigMap, error) {
+	refs := make([]ObjectRef, 0, len(objs))
+	for ref := range objs {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].less(refs[j]) })
+	index := Index{Parts: []IndexPart{}}
+	parts := []*corev1.ConfigMap{}
+	var pending []json.RawMessage
+	var pendingRefs []ObjectRef
+	var pendingBytes int
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		part, err := newPart(pending)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, part)
+		index.Parts = append(index.Parts, IndexPart{Name: part.Name, Objects: pendingRefs})
+		pending, pendingRefs, pendingBytes = nil, nil, 0
+		return nil
+	}
+	for _, ref := range refs {
+		objBytes, err := json.Marshal(objs[ref].Object)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal %s: %w", ref, err)
+		}
+		if len(objBytes) > MaxPartBytes {
+			return nil, nil, fmt.Errorf("object %s is too big to bundle (%d bytes)", ref, len(objBytes))
+		}
+		if pendingBytes+len(objBytes) > MaxPartBytes {
+			if err := flush(); err != nil {
+				return nil, nil, err
+			}
+		}
+		pending = append(pending, objBytes)
+		pendingRefs = append(pendingRefs, ref)
+		pendingBytes += len(objBytes)
+	}
+	if err := flush(); err != nil {
+		return nil, nil, err
+	}
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	indexCM := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: Namespace,
+			Name:      IndexName,
+			Labels:    map[string]string{LabelKey: LabelValIndex},
+		},
+		Data: map[string]string{IndexKey: string(indexBytes)},
+	}
+	return indexCM, parts, nil
+}
+
+// Packable returns an error if the given object can not be bundled.
+func Packable(obj *unstructured.Unstructured) error {
+	objBytes, err := json.Marshal(obj.Object)
+	if err != nil {
+		return fmt.Errorf("failed to marshal object: %w", err)
+	}
+	if len(objBytes) > MaxPartBytes {
+		return fmt.Errorf("object is too big to bundle (%d bytes)", len(objBytes))
+	}
+	return nil
+}
+
+func newPart(objs []json.RawMessage) (*corev1.ConfigMap, error) {
+	plain, err := json.Marshal(objs)
+	if err != nil {
+		return nil, err
+	}
+	var compressed bytes.Buffer
+	gzw := gzip.NewWriter(&compressed)
+	if _, err := gzw.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: Namespace,
+			Name:      PartNamePrefix + hashOf(plain),
+			Labels:    map[string]string{LabelKey: LabelValPart},
+		},
+		BinaryData: map[string][]byte{ObjectsKey: compressed.Bytes()},
+	}, nil
+}
+
+// ParseIndex extracts the Index from the index ConfigMap.
+func ParseIndex(indexCM *corev1.ConfigMap) (Index, error) {
+	var index Index
+	indexStr, found := indexCM.Data[IndexKey]
+	if !found {
+		return index, fmt.Errorf("ConfigMap %s/%s has no %q", indexCM.Namespace, indexCM.Name, IndexKey)
+	}
+	if err := json.Unmarshal([]byte(indexStr), &index); err != nil {
+		return index, fmt.Errorf("failed to parse index in ConfigMap %s/%s: %w", indexCM.Namespace, indexCM.Name, err)
+	}
+	return index, nil
+}
+
+// Unpack extracts the objects from a part ConfigMap.
+// They are in the same order as their references in the index.
+func Unpack(part *corev1.ConfigMap) ([]*unstructured.Unstructured, error) {
+	compressed, found := part.BinaryData[ObjectsKey]
+	if !found {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no %q", part.Namespace, part.Name, ObjectsKey)
+	}
+	gzr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress ConfigMap %s/%s: %w", part.Namespace, part.Name, err)
+	}
+	plain, err := io.ReadAll(gzr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress ConfigMap %s/%s: %w", part.Namespace, part.Name, err)
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(plain, &raws); err != nil {
+		return nil, fmt.Errorf("failed to parse objects in ConfigMap %s/%s: %w", part.Namespace, part.Name, err)
+	}
+	objs := make([]*unstructured.Unstructured, 0, len(raws))
+	for _, raw := range raws {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse object in ConfigMap %s/%s: %w", part.Namespace, part.Name, err)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// NewApplied returns the applied ConfigMap that records the given objects.
+func NewApplied(refs map[ObjectRef]struct{}) (*corev1.ConfigMap, error) {
+	sorted := make([]ObjectRef, 0, len(refs))
+	for ref := range refs {
+		sorted = append(sorted, ref)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].less(sorted[j]) })
+	refsBytes, err := json.Marshal(sorted)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: Namespace, Name: AppliedName},
+		Data:       map[string]string{AppliedKey: string(refsBytes)},
+	}, nil
+}
+
+// ParseApplied extracts the recorded objects from the applied ConfigMap.
+func ParseApplied(appliedCM *corev1.ConfigMap) (map[ObjectRef]struct{}, error) {
+	refsStr, found := appliedCM.Data[AppliedKey]
+	if !found {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no %q", appliedCM.Namespace, appliedCM.Name, AppliedKey)
+	}
+	var refs []ObjectRef
+	if err := json.Unmarshal([]byte(refsStr), &refs); err != nil {
+		return nil, fmt.Errorf("failed to parse applied objects in ConfigMap %s/%s: %w", appliedCM.Namespace, appliedCM.Name, err)
+	}
+	ans := make(map[ObjectRef]struct{}, len(refs))
+	for _, ref := range refs {
+		ans[ref] = struct{}{}
+	}
+	return ans, nil
+}
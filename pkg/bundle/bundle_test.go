@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newConfigMap(name string, size int) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"namespace": "ns1", "name": name},
+		"data":       map[string]any{"stuff": strings.Repeat("x", size)},
+	}}
+}
+
+func TestPackUnpack(t *testing.T) {
+	objs := map[ObjectRef]*unstructured.Unstructured{}
+	for i := 0; i < 40; i++ {
+		obj := newConfigMap(fmt.Sprintf("cm%02d", i), 64*1024)
+		objs[RefOf("configmaps", obj)] = obj
+	}
+	indexCM, parts, err := Pack(objs)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if len(parts) < 4 {
+		t.Errorf("Expected at least 4 parts, got %d", len(parts))
+	}
+	index, err := ParseIndex(indexCM)
+	if err != nil {
+		t.Fatalf("ParseIndex failed: %v", err)
+	}
+	if index.ObjectCount() != len(objs) {
+		t.Errorf("Index has %d objects, expected %d", index.ObjectCount(), len(objs))
+	}
+	unpacked := map[ObjectRef]*unstructured.Unstructured{}
+	for idx, part := range parts {
+		if part.Name != index.Parts[idx].Name {
+			t.Errorf("Part %d is named %q but index says %q", idx, part.Name, index.Parts[idx].Name)
+		}
+		partObjs, err := Unpack(part)
+		if err != nil {
+			t.Fatalf("Unpack failed: %v", err)
+		}
+		if len(partObjs) != len(index.Parts[idx].Objects) {
+			t.Errorf("Part %d has %d objects but index lists %d", idx, len(partObjs), len(index.Parts[idx].Objects))
+		}
+		for objIdx, obj := range partObjs {
+			unpacked[index.Parts[idx].Objects[objIdx]] = obj
+		}
+	}
+	for ref, obj := range objs {
+		if actual := unpacked[ref]; !apiequality.Semantic.DeepEqual(obj, actual) {
+			t.Errorf("Object %s did not survive the round trip", ref)
+		}
+	}
+
+	// Packing is deterministic
+	indexCM2, parts2, err := Pack(objs)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if !apiequality.Semantic.DeepEqual(indexCM, indexCM2) || !apiequality.Semantic.DeepEqual(parts, parts2) {
+		t.Errorf("Packing is not deterministic")
+	}
+}
+
+func TestPackTooBig(t *testing.T) {
+	obj := newConfigMap("huge", MaxPartBytes)
+	if _, _, err := Pack(map[ObjectRef]*unstructured.Unstructured{RefOf("configmaps", obj): obj}); err == nil {
+		t.Errorf("Expected an error for an object that is too big")
+	}
+	if err := Packable(obj); err == nil {
+		t.Errorf("Expected Packable to refuse an object that is too big")
+	}
+	if err := Packable(newConfigMap("small", 10)); err != nil {
+		t.Errorf("Expected Packable to accept a small object, got %v", err)
+	}
+}
+
+func TestApplied(t *testing.T) {
+	refs := map[ObjectRef]struct{}{
+		RefOf("configmaps", newConfigMap("a", 1)): {},
+		RefOf("configmaps", newConfigMap("b", 1)): {},
+	}
+	appliedCM, err := NewApplied(refs)
+	if err != nil {
+		t.Fatalf("NewApplied failed: %v", err)
+	}
+	parsed, err := ParseApplied(appliedCM)
+	if err != nil {
+		t.Fatalf("ParseApplied failed: %v", err)
+	}
+	if !apiequality.Semantic.DeepEqual(parsed, refs) {
+		t.Errorf("Expected %v, got %v", refs, parsed)
+	}
+}
@@ -61,6 +61,12 @@ func (opts *ClientOpts) AddFlags(flags *pflag.FlagSet) {
 
 }
 
+// Configured tells whether any of the settings bound by AddFlags is non-empty.
+func (opts *ClientOpts) Configured() bool {
+	return opts.loadingRules.ExplicitPath != "" || opts.overrides.CurrentContext != "" ||
+		opts.overrides.Context.AuthInfo != "" || opts.overrides.Context.Cluster != ""
+}
+
 func (opts *ClientOpts) ToRESTConfig() (*rest.Config, error) {
 	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(opts.loadingRules, &opts.overrides)
 	return clientConfig.ClientConfig()
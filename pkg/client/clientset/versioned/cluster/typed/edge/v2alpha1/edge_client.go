@@ -34,13 +34,16 @@ import (
 
 type EdgeV2alpha1ClusterInterface interface {
 	EdgeV2alpha1ClusterScoper
+	ClusterRegistrationsClusterGetter
 	CustomizersClusterGetter
 	EdgePlacementsClusterGetter
 	EdgeSyncConfigsClusterGetter
 	SinglePlacementSlicesClusterGetter
 	SyncerConfigsClusterGetter
 	SyncTargetsClusterGetter
+	TenantUsagesClusterGetter
 	LocationsClusterGetter
+	NamespacedEdgePlacementsClusterGetter
 }
 
 type EdgeV2alpha1ClusterScoper interface {
@@ -58,6 +61,10 @@ func (c *EdgeV2alpha1ClusterClient) Cluster(clusterPath logicalcluster.Path) edg
 	return c.clientCache.ClusterOrDie(clusterPath)
 }
 
+func (c *EdgeV2alpha1ClusterClient) ClusterRegistrations() ClusterRegistrationClusterInterface {
+	return &clusterRegistrationsClusterInterface{clientCache: c.clientCache}
+}
+
 func (c *EdgeV2alpha1ClusterClient) Customizers() CustomizerClusterInterface {
 	return &customizersClusterInterface{clientCache: c.clientCache}
 }
@@ -82,10 +89,18 @@ func (c *EdgeV2alpha1ClusterClient) SyncTargets() SyncTargetClusterInterface {
 	return &syncTargetsClusterInterface{clientCache: c.clientCache}
 }
 
+func (c *EdgeV2alpha1ClusterClient) TenantUsages() TenantUsageClusterInterface {
+	return &tenantUsagesClusterInterface{clientCache: c.clientCache}
+}
+
 func (c *EdgeV2alpha1ClusterClient) Locations() LocationClusterInterface {
 	return &locationsClusterInterface{clientCache: c.clientCache}
 }
 
+func (c *EdgeV2alpha1ClusterClient) NamespacedEdgePlacements() NamespacedEdgePlacementClusterInterface {
+	return &namespacededgeplacementsClusterInterface{clientCache: c.clientCache}
+}
+
 // NewForConfig creates a new EdgeV2alpha1ClusterClient for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -44,6 +44,10 @@ func (c *EdgeV2alpha1ClusterClient) Cluster(clusterPath logicalcluster.Path) edg
 	return &EdgeV2alpha1Client{Fake: c.Fake, ClusterPath: clusterPath}
 }
 
+func (c *EdgeV2alpha1ClusterClient) ClusterRegistrations() kcpedgev2alpha1.ClusterRegistrationClusterInterface {
+	return &clusterRegistrationsClusterClient{Fake: c.Fake}
+}
+
 func (c *EdgeV2alpha1ClusterClient) Customizers() kcpedgev2alpha1.CustomizerClusterInterface {
 	return &customizersClusterClient{Fake: c.Fake}
 }
@@ -68,10 +72,18 @@ func (c *EdgeV2alpha1ClusterClient) SyncTargets() kcpedgev2alpha1.SyncTargetClus
 	return &syncTargetsClusterClient{Fake: c.Fake}
 }
 
+func (c *EdgeV2alpha1ClusterClient) TenantUsages() kcpedgev2alpha1.TenantUsageClusterInterface {
+	return &tenantUsagesClusterClient{Fake: c.Fake}
+}
+
 func (c *EdgeV2alpha1ClusterClient) Locations() kcpedgev2alpha1.LocationClusterInterface {
 	return &locationsClusterClient{Fake: c.Fake}
 }
 
+func (c *EdgeV2alpha1ClusterClient) NamespacedEdgePlacements() kcpedgev2alpha1.NamespacedEdgePlacementClusterInterface {
+	return &namespacededgeplacementsClusterClient{Fake: c.Fake}
+}
+
 var _ edgev2alpha1.EdgeV2alpha1Interface = (*EdgeV2alpha1Client)(nil)
 
 type EdgeV2alpha1Client struct {
@@ -84,6 +96,10 @@ func (c *EdgeV2alpha1Client) RESTClient() rest.Interface {
 	return ret
 }
 
+func (c *EdgeV2alpha1Client) ClusterRegistrations() edgev2alpha1.ClusterRegistrationInterface {
+	return &clusterRegistrationsClient{Fake: c.Fake, ClusterPath: c.ClusterPath}
+}
+
 func (c *EdgeV2alpha1Client) Customizers(namespace string) edgev2alpha1.CustomizerInterface {
 	return &customizersClient{Fake: c.Fake, ClusterPath: c.ClusterPath, Namespace: namespace}
 }
@@ -108,6 +124,14 @@ func (c *EdgeV2alpha1Client) SyncTargets() edgev2alpha1.SyncTargetInterface {
 	return &syncTargetsClient{Fake: c.Fake, ClusterPath: c.ClusterPath}
 }
 
+func (c *EdgeV2alpha1Client) TenantUsages() edgev2alpha1.TenantUsageInterface {
+	return &tenantUsagesClient{Fake: c.Fake, ClusterPath: c.ClusterPath}
+}
+
 func (c *EdgeV2alpha1Client) Locations() edgev2alpha1.LocationInterface {
 	return &locationsClient{Fake: c.Fake, ClusterPath: c.ClusterPath}
 }
+
+func (c *EdgeV2alpha1Client) NamespacedEdgePlacements(namespace string) edgev2alpha1.NamespacedEdgePlacementInterface {
+	return &namespacededgeplacementsClient{Fake: c.Fake, ClusterPath: c.ClusterPath, Namespace: namespace}
+}
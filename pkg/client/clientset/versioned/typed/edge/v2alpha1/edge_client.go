@@ -29,13 +29,16 @@ import (
 
 type EdgeV2alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterRegistrationsGetter
 	CustomizersGetter
 	EdgePlacementsGetter
 	EdgeSyncConfigsGetter
 	LocationsGetter
+	NamespacedEdgePlacementsGetter
 	SinglePlacementSlicesGetter
 	SyncTargetsGetter
 	SyncerConfigsGetter
+	TenantUsagesGetter
 }
 
 // EdgeV2alpha1Client is used to interact with features provided by the edge.kubestellar.io group.
@@ -43,6 +46,10 @@ type EdgeV2alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *EdgeV2alpha1Client) ClusterRegistrations() ClusterRegistrationInterface {
+	return newClusterRegistrations(c)
+}
+
 func (c *EdgeV2alpha1Client) Customizers(namespace string) CustomizerInterface {
 	return newCustomizers(c, namespace)
 }
@@ -59,6 +66,10 @@ func (c *EdgeV2alpha1Client) Locations() LocationInterface {
 	return newLocations(c)
 }
 
+func (c *EdgeV2alpha1Client) NamespacedEdgePlacements(namespace string) NamespacedEdgePlacementInterface {
+	return newNamespacedEdgePlacements(c, namespace)
+}
+
 func (c *EdgeV2alpha1Client) SinglePlacementSlices() SinglePlacementSliceInterface {
 	return newSinglePlacementSlices(c)
 }
@@ -71,6 +82,10 @@ func (c *EdgeV2alpha1Client) SyncerConfigs() SyncerConfigInterface {
 	return newSyncerConfigs(c)
 }
 
+func (c *EdgeV2alpha1Client) TenantUsages() TenantUsageInterface {
+	return newTenantUsages(c)
+}
+
 // NewForConfig creates a new EdgeV2alpha1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
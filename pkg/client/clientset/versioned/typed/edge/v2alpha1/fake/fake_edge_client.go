@@ -29,6 +29,10 @@ type FakeEdgeV2alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeEdgeV2alpha1) ClusterRegistrations() v2alpha1.ClusterRegistrationInterface {
+	return &FakeClusterRegistrations{c}
+}
+
 func (c *FakeEdgeV2alpha1) Customizers(namespace string) v2alpha1.CustomizerInterface {
 	return &FakeCustomizers{c, namespace}
 }
@@ -45,6 +49,10 @@ func (c *FakeEdgeV2alpha1) Locations() v2alpha1.LocationInterface {
 	return &FakeLocations{c}
 }
 
+func (c *FakeEdgeV2alpha1) NamespacedEdgePlacements(namespace string) v2alpha1.NamespacedEdgePlacementInterface {
+	return &FakeNamespacedEdgePlacements{c, namespace}
+}
+
 func (c *FakeEdgeV2alpha1) SinglePlacementSlices() v2alpha1.SinglePlacementSliceInterface {
 	return &FakeSinglePlacementSlices{c}
 }
@@ -57,6 +65,10 @@ func (c *FakeEdgeV2alpha1) SyncerConfigs() v2alpha1.SyncerConfigInterface {
 	return &FakeSyncerConfigs{c}
 }
 
+func (c *FakeEdgeV2alpha1) TenantUsages() v2alpha1.TenantUsageInterface {
+	return &FakeTenantUsages{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeEdgeV2alpha1) RESTClient() rest.Interface {
@@ -18,6 +18,8 @@ limitations under the License.
 
 package v2alpha1
 
+type ClusterRegistrationExpansion interface{}
+
 type CustomizerExpansion interface{}
 
 type EdgePlacementExpansion interface{}
@@ -26,8 +28,12 @@ type EdgeSyncConfigExpansion interface{}
 
 type LocationExpansion interface{}
 
+type NamespacedEdgePlacementExpansion interface{}
+
 type SinglePlacementSliceExpansion interface{}
 
 type SyncTargetExpansion interface{}
 
 type SyncerConfigExpansion interface{}
+
+type TenantUsageExpansion interface{}
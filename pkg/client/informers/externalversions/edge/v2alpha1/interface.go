@@ -26,6 +26,8 @@ import (
 )
 
 type ClusterInterface interface {
+	// ClusterRegistrations returns a ClusterRegistrationClusterInformer
+	ClusterRegistrations() ClusterRegistrationClusterInformer
 	// Customizers returns a CustomizerClusterInformer
 	Customizers() CustomizerClusterInformer
 	// EdgePlacements returns a EdgePlacementClusterInformer
@@ -38,8 +40,12 @@ type ClusterInterface interface {
 	SyncerConfigs() SyncerConfigClusterInformer
 	// SyncTargets returns a SyncTargetClusterInformer
 	SyncTargets() SyncTargetClusterInformer
+	// TenantUsages returns a TenantUsageClusterInformer
+	TenantUsages() TenantUsageClusterInformer
 	// Locations returns a LocationClusterInformer
 	Locations() LocationClusterInformer
+	// NamespacedEdgePlacements returns a NamespacedEdgePlacementClusterInformer
+	NamespacedEdgePlacements() NamespacedEdgePlacementClusterInformer
 }
 
 type version struct {
@@ -52,6 +58,11 @@ func New(f internalinterfaces.SharedInformerFactory, tweakListOptions internalin
 	return &version{factory: f, tweakListOptions: tweakListOptions}
 }
 
+// ClusterRegistrations returns a ClusterRegistrationClusterInformer
+func (v *version) ClusterRegistrations() ClusterRegistrationClusterInformer {
+	return &clusterRegistrationClusterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Customizers returns a CustomizerClusterInformer
 func (v *version) Customizers() CustomizerClusterInformer {
 	return &customizerClusterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
@@ -82,12 +93,24 @@ func (v *version) SyncTargets() SyncTargetClusterInformer {
 	return &syncTargetClusterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// TenantUsages returns a TenantUsageClusterInformer
+func (v *version) TenantUsages() TenantUsageClusterInformer {
+	return &tenantUsageClusterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Locations returns a LocationClusterInformer
 func (v *version) Locations() LocationClusterInformer {
 	return &locationClusterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// NamespacedEdgePlacements returns a NamespacedEdgePlacementClusterInformer
+func (v *version) NamespacedEdgePlacements() NamespacedEdgePlacementClusterInformer {
+	return &namespacedEdgePlacementClusterInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 type Interface interface {
+	// ClusterRegistrations returns a ClusterRegistrationInformer
+	ClusterRegistrations() ClusterRegistrationInformer
 	// Customizers returns a CustomizerInformer
 	Customizers() CustomizerInformer
 	// EdgePlacements returns a EdgePlacementInformer
@@ -100,8 +123,12 @@ type Interface interface {
 	SyncerConfigs() SyncerConfigInformer
 	// SyncTargets returns a SyncTargetInformer
 	SyncTargets() SyncTargetInformer
+	// TenantUsages returns a TenantUsageInformer
+	TenantUsages() TenantUsageInformer
 	// Locations returns a LocationInformer
 	Locations() LocationInformer
+	// NamespacedEdgePlacements returns a NamespacedEdgePlacementInformer
+	NamespacedEdgePlacements() NamespacedEdgePlacementInformer
 }
 
 type scopedVersion struct {
@@ -115,6 +142,11 @@ func NewScoped(f internalinterfaces.SharedScopedInformerFactory, namespace strin
 	return &scopedVersion{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterRegistrations returns a ClusterRegistrationInformer
+func (v *scopedVersion) ClusterRegistrations() ClusterRegistrationInformer {
+	return &clusterRegistrationScopedInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Customizers returns a CustomizerInformer
 func (v *scopedVersion) Customizers() CustomizerInformer {
 	return &customizerScopedInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -145,7 +177,17 @@ func (v *scopedVersion) SyncTargets() SyncTargetInformer {
 	return &syncTargetScopedInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// TenantUsages returns a TenantUsageInformer
+func (v *scopedVersion) TenantUsages() TenantUsageInformer {
+	return &tenantUsageScopedInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // Locations returns a LocationInformer
 func (v *scopedVersion) Locations() LocationInformer {
 	return &locationScopedInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// NamespacedEdgePlacements returns a NamespacedEdgePlacementInformer
+func (v *scopedVersion) NamespacedEdgePlacements() NamespacedEdgePlacementInformer {
+	return &namespacedEdgePlacementScopedInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -87,6 +87,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericClusterInformer, error) {
 	switch resource {
 	// Group=edge.kubestellar.io, Version=V2alpha1
+	case edgev2alpha1.SchemeGroupVersion.WithResource("clusterregistrations"):
+		return &genericClusterInformer{resource: resource.GroupResource(), informer: f.Edge().V2alpha1().ClusterRegistrations().Informer()}, nil
 	case edgev2alpha1.SchemeGroupVersion.WithResource("customizers"):
 		return &genericClusterInformer{resource: resource.GroupResource(), informer: f.Edge().V2alpha1().Customizers().Informer()}, nil
 	case edgev2alpha1.SchemeGroupVersion.WithResource("edgeplacements"):
@@ -99,8 +101,12 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericClusterInformer{resource: resource.GroupResource(), informer: f.Edge().V2alpha1().SyncerConfigs().Informer()}, nil
 	case edgev2alpha1.SchemeGroupVersion.WithResource("synctargets"):
 		return &genericClusterInformer{resource: resource.GroupResource(), informer: f.Edge().V2alpha1().SyncTargets().Informer()}, nil
+	case edgev2alpha1.SchemeGroupVersion.WithResource("tenantusages"):
+		return &genericClusterInformer{resource: resource.GroupResource(), informer: f.Edge().V2alpha1().TenantUsages().Informer()}, nil
 	case edgev2alpha1.SchemeGroupVersion.WithResource("locations"):
 		return &genericClusterInformer{resource: resource.GroupResource(), informer: f.Edge().V2alpha1().Locations().Informer()}, nil
+	case edgev2alpha1.SchemeGroupVersion.WithResource("namespacededgeplacements"):
+		return &genericClusterInformer{resource: resource.GroupResource(), informer: f.Edge().V2alpha1().NamespacedEdgePlacements().Informer()}, nil
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
@@ -111,6 +117,9 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 func (f *sharedScopedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=edge.kubestellar.io, Version=V2alpha1
+	case edgev2alpha1.SchemeGroupVersion.WithResource("clusterregistrations"):
+		informer := f.Edge().V2alpha1().ClusterRegistrations().Informer()
+		return &genericInformer{lister: cache.NewGenericLister(informer.GetIndexer(), resource.GroupResource()), informer: informer}, nil
 	case edgev2alpha1.SchemeGroupVersion.WithResource("customizers"):
 		informer := f.Edge().V2alpha1().Customizers().Informer()
 		return &genericInformer{lister: cache.NewGenericLister(informer.GetIndexer(), resource.GroupResource()), informer: informer}, nil
@@ -129,9 +138,15 @@ func (f *sharedScopedInformerFactory) ForResource(resource schema.GroupVersionRe
 	case edgev2alpha1.SchemeGroupVersion.WithResource("synctargets"):
 		informer := f.Edge().V2alpha1().SyncTargets().Informer()
 		return &genericInformer{lister: cache.NewGenericLister(informer.GetIndexer(), resource.GroupResource()), informer: informer}, nil
+	case edgev2alpha1.SchemeGroupVersion.WithResource("tenantusages"):
+		informer := f.Edge().V2alpha1().TenantUsages().Informer()
+		return &genericInformer{lister: cache.NewGenericLister(informer.GetIndexer(), resource.GroupResource()), informer: informer}, nil
 	case edgev2alpha1.SchemeGroupVersion.WithResource("locations"):
 		informer := f.Edge().V2alpha1().Locations().Informer()
 		return &genericInformer{lister: cache.NewGenericLister(informer.GetIndexer(), resource.GroupResource()), informer: informer}, nil
+	case edgev2alpha1.SchemeGroupVersion.WithResource("namespacededgeplacements"):
+		informer := f.Edge().V2alpha1().NamespacedEdgePlacements().Informer()
+		return &genericInformer{lister: cache.NewGenericLister(informer.GetIndexer(), resource.GroupResource()), informer: informer}, nil
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
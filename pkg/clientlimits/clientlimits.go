@@ -0,0 +1,190 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clientlimits sets how fast a KubeStellar component may send
+// requests to the API servers, and measures how much it is held back.
+//
+// The client-go default of 5 requests per second, with bursts of 10,
+// is far too little for a hub that serves a large fleet: the central
+// controllers then spend most of their time waiting on their own rate
+// limiter. Options holds the client-side limits of one component, with
+// defaults sized for about a thousand WECs, and Apply puts them into a
+// rest.Config.
+//
+// The API servers also hold requests back, through API Priority and
+// Fairness, according to the FlowSchema that matches the requesting
+// identity. A client can not choose its FlowSchema, but the rest.Config
+// that Apply adjusts records which priority level the server put each
+// request in, and how many requests it rejected as too many, so that a
+// misclassified or starved component shows in its metrics.
+package clientlimits
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+)
+
+var (
+	rateLimiterWait = metrics.NewHistogramVec(&metrics.HistogramOpts{
+		Subsystem:      "kubestellar_client",
+		Name:           "rate_limiter_wait_seconds",
+		Help:           "Time requests waited for the client-side rate limiter, by component",
+		Buckets:        []float64{0.001, 0.005, 0.025, 0.1, 0.5, 1, 2.5, 10, 30},
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"component"})
+	serverRejections = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_client",
+		Name:           "throttled_responses_total",
+		Help:           "Number of requests that an API server answered with 429 Too Many Requests, by component",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"component"})
+	priorityLevelRequests = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_client",
+		Name:           "priority_level_requests_total",
+		Help:           "Number of requests by component and by the UID of the API Priority and Fairness priority level that the server put them in",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"component", "priority_level_uid"})
+)
+
+func init() {
+	legacyregistry.MustRegister(rateLimiterWait, serverRejections, priorityLevelRequests)
+}
+
+// The defaults for the central controllers.
+const (
+	DefaultQPS   = 100
+	DefaultBurst = 200
+)
+
+// The response header in which API Priority and Fairness names the priority level of a request.
+const priorityLevelHeader = "X-Kubernetes-PF-PriorityLevel-UID"
+
+// Options are the client-side limits of one component.
+type Options struct {
+	// Component labels the metrics.
+	Component string
+
+	// QPS and Burst are the sustained and momentary request rates.
+	// Zero QPS means no client-side limit.
+	QPS   float32
+	Burst int
+}
+
+// NewOptions returns the default Options for the given component.
+func NewOptions(component string) *Options {
+	return &Options{Component: component, QPS: DefaultQPS, Burst: DefaultBurst}
+}
+
+// AddFlags binds the --qps and --burst flags.
+func (opts *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.Float32Var(&opts.QPS, "qps", opts.QPS, "requests per second to each API server (for a space-aware client, to each space), sustained; zero means no limit")
+	fs.IntVar(&opts.Burst, "burst", opts.Burst, "requests to each API server (for a space-aware client, to each space) allowed in a burst above --qps")
+}
+
+// ApplyConfig takes the given transport settings that were not given
+// on the command line. See componentconfig.Configuration.TransportFor.
+func (opts *Options) ApplyConfig(fs *pflag.FlagSet, transport componentconfig.TransportConfiguration) {
+	if transport.QPS != nil {
+		componentconfig.Override(fs, "qps", func() { opts.QPS = *transport.QPS })
+	}
+	if transport.Burst != nil {
+		componentconfig.Override(fs, "burst", func() { opts.Burst = *transport.Burst })
+	}
+}
+
+// Validate checks the values.
+func (opts *Options) Validate() error {
+	if opts.QPS < 0 {
+		return errors.New("--qps must not be negative")
+	}
+	if opts.QPS > 0 && opts.Burst < 1 {
+		return errors.New("--burst must be positive when --qps is")
+	}
+	return nil
+}
+
+// Apply puts the limits into the given config, with a rate limiter of
+// its own, and makes the config's transport observe the server-side
+// throttling. Apply is a spaceclient.Options.Adjust.
+func (opts *Options) Apply(config *rest.Config) {
+	if opts.QPS > 0 {
+		config.QPS, config.Burst = opts.QPS, opts.Burst
+		config.RateLimiter = &meteredLimiter{RateLimiter: flowcontrol.NewTokenBucketRateLimiter(opts.QPS, opts.Burst),
+			wait: rateLimiterWait.WithLabelValues(opts.Component)}
+	} else {
+		config.QPS, config.Burst = -1, 0
+		config.RateLimiter = nil
+	}
+	component := opts.Component
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &observingRoundTripper{delegate: rt, component: component}
+	})
+}
+
+// meteredLimiter is a RateLimiter that measures how long callers wait.
+type meteredLimiter struct {
+	flowcontrol.RateLimiter
+	wait metrics.ObserverMetric
+}
+
+func (ml *meteredLimiter) Accept() {
+	start := time.Now()
+	ml.RateLimiter.Accept()
+	ml.wait.Observe(time.Since(start).Seconds())
+}
+
+func (ml *meteredLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := ml.RateLimiter.Wait(ctx)
+	ml.wait.Observe(time.Since(start).Seconds())
+	return err
+}
+
+// observingRoundTripper counts the responses by priority level, and the 429s.
+type observingRoundTripper struct {
+	delegate  http.RoundTripper
+	component string
+}
+
+func (ort *observingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := ort.delegate.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if level := resp.Header.Get(priorityLevelHeader); level != "" {
+		priorityLevelRequests.WithLabelValues(ort.component, level).Inc()
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		serverRejections.WithLabelValues(ort.component).Inc()
+	}
+	return resp, nil
+}
+
+// WrappedRoundTripper lets client-go see through the wrapper.
+func (ort *observingRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return ort.delegate
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientlimits
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+)
+
+func TestApply(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(priorityLevelHeader, "pl-1")
+		if req.URL.Path == "/busy" {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	opts := NewOptions("test-apply")
+	config := &rest.Config{Host: srv.URL}
+	opts.Apply(config)
+	if config.QPS != DefaultQPS || config.Burst != DefaultBurst || config.RateLimiter == nil {
+		t.Fatalf("Limits not applied: qps=%v burst=%v limiter=%v", config.QPS, config.Burst, config.RateLimiter)
+	}
+	client, err := rest.HTTPClientFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/ok", "/busy"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if got, err := testutil.GetCounterMetricValue(priorityLevelRequests.WithLabelValues("test-apply", "pl-1")); err != nil || got != 2 {
+		t.Errorf("Expected 2 requests in the priority level, got %v (%v)", got, err)
+	}
+	if got, err := testutil.GetCounterMetricValue(serverRejections.WithLabelValues("test-apply")); err != nil || got != 1 {
+		t.Errorf("Expected 1 throttled response, got %v (%v)", got, err)
+	}
+
+	unlimited := &Options{Component: "test-unlimited"}
+	config = &rest.Config{Host: srv.URL}
+	unlimited.Apply(config)
+	if config.QPS >= 0 || config.RateLimiter != nil {
+		t.Errorf("Expected no client-side limit, got qps=%v limiter=%v", config.QPS, config.RateLimiter)
+	}
+}
+
+func TestFlagsAndConfig(t *testing.T) {
+	opts := NewOptions("test-flags")
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	opts.AddFlags(fs)
+	if err := fs.Parse([]string{"--burst=50"}); err != nil {
+		t.Fatal(err)
+	}
+	qps, burst := float32(20), 500
+	cfg := &componentconfig.Configuration{Transport: componentconfig.TransportConfiguration{QPS: &qps, Burst: &burst}}
+	opts.ApplyConfig(fs, cfg.TransportFor(componentconfig.TransportConfiguration{}))
+	if opts.QPS != 20 || opts.Burst != 50 {
+		t.Errorf("Expected the flag to win over the file, got qps=%v burst=%v", opts.QPS, opts.Burst)
+	}
+	if err := opts.Validate(); err != nil {
+		t.Error(err)
+	}
+	opts.Burst = 0
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected a zero burst with a positive QPS to be invalid")
+	}
+}
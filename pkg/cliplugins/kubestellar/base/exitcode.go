@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+)
+
+// The exit codes of the KubeStellar commands.
+// These are a stable contract that scripts may depend on.
+const (
+	// ExitOK means the command did what was asked.
+	ExitOK = 0
+
+	// ExitFailure means the command failed for a reason not covered below.
+	ExitFailure = 1
+
+	// ExitUsage means the command line was invalid; retrying it will not help.
+	ExitUsage = 2
+
+	// ExitUnavailable means the command failed for a reason that is
+	// expected to go away on its own, such as an unreachable apiserver;
+	// retrying later may help.
+	ExitUnavailable = 3
+)
+
+// UsageError is an error in how a command was invoked.
+type UsageError struct {
+	Message string
+}
+
+func (err *UsageError) Error() string { return err.Message }
+
+// Usagef returns a UsageError with a message formatted from the arguments.
+func Usagef(format string, args ...any) error {
+	return &UsageError{Message: fmt.Sprintf(format, args...)}
+}
+
+// SetUsageErrors makes the given command, and its subcommands, report
+// errors in parsing flags as UsageErrors.
+func SetUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &UsageError{Message: err.Error()}
+	})
+}
+
+// ExitCodeFor returns the exit code for a command that ended with the given error.
+func ExitCodeFor(err error) int {
+	var usageErr *UsageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case kserrors.IsRetriable(err):
+		return ExitUnavailable
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/yaml"
+)
+
+// OutputFormat is a value of the --output flag of the KubeStellar commands.
+type OutputFormat string
+
+const (
+	// OutputJSON prints each result as one line of JSON.
+	OutputJSON OutputFormat = "json"
+
+	// OutputYAML prints each result as a YAML document, preceded by "---".
+	OutputYAML OutputFormat = "yaml"
+
+	// OutputTable prints a human-oriented table.
+	// Its layout is not part of the scripting contract.
+	OutputTable OutputFormat = "table"
+)
+
+// OutputFormats lists the supported values of the --output flag.
+var OutputFormats = []string{string(OutputJSON), string(OutputYAML), string(OutputTable)}
+
+// OutputFlagUsage is the usage text of the --output flag.
+var OutputFlagUsage = "Output format, one of: " + strings.Join(OutputFormats, "|")
+
+// Validate returns a usage error if the format is not supported.
+func (format OutputFormat) Validate() error {
+	for _, supported := range OutputFormats {
+		if string(format) == supported {
+			return nil
+		}
+	}
+	return Usagef("unsupported output format %q, must be one of: %s", string(format), strings.Join(OutputFormats, "|"))
+}
+
+// BindOutputFlag binds the --output (-o) flag to the given format and
+// registers its shell completion.
+func BindOutputFlag(cmd *cobra.Command, format *OutputFormat) {
+	cmd.Flags().StringVarP((*string)(format), "output", "o", string(*format), OutputFlagUsage)
+	_ = cmd.RegisterFlagCompletionFunc("output", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return OutputFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// Table is the tabular rendering of a result.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// PrintObject writes the given object to out in the given format.
+// For OutputTable the given table is written instead of the object.
+func PrintObject(out io.Writer, format OutputFormat, obj any, table Table) error {
+	switch format {
+	case OutputJSON:
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "---\n%s", data)
+		return err
+	case OutputTable:
+		return PrintTable(out, table)
+	default:
+		return format.Validate()
+	}
+}
+
+// PrintTable writes the given table to out, with aligned columns.
+// The header is omitted if the table has no columns.
+func PrintTable(out io.Writer, table Table) error {
+	tw := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	if len(table.Columns) > 0 {
+		fmt.Fprintln(tw, strings.Join(table.Columns, "\t"))
+	}
+	for _, row := range table.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+)
+
+func TestPrintObject(t *testing.T) {
+	obj := map[string]string{"name": "wec1"}
+	table := Table{Columns: []string{"NAME", "LOCATION"}, Rows: [][]string{{"wec1", "loc1"}}}
+	for _, tc := range []struct {
+		format   OutputFormat
+		expected string
+	}{
+		{OutputJSON, "{\"name\":\"wec1\"}\n"},
+		{OutputYAML, "---\nname: wec1\n"},
+		{OutputTable, "NAME   LOCATION\nwec1   loc1\n"},
+	} {
+		var buf bytes.Buffer
+		if err := PrintObject(&buf, tc.format, obj, table); err != nil {
+			t.Errorf("format %q: unexpected error %v", tc.format, err)
+			continue
+		}
+		if actual := buf.String(); actual != tc.expected {
+			t.Errorf("format %q: expected %q, got %q", tc.format, tc.expected, actual)
+		}
+	}
+	err := PrintObject(&bytes.Buffer{}, "xml", obj, table)
+	if ExitCodeFor(err) != ExitUsage {
+		t.Errorf("expected usage error for unsupported format, got %v", err)
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected int
+	}{
+		{nil, ExitOK},
+		{errors.New("boom"), ExitFailure},
+		{fmt.Errorf("parsing: %w", Usagef("bad flag")), ExitUsage},
+		{kserrors.New(kserrors.ReasonRetriableTransport, "connection refused"), ExitUnavailable},
+		{kserrors.New(kserrors.ReasonDestinationAdmissionDenied, "forbidden"), ExitFailure},
+	} {
+		if actual := ExitCodeFor(tc.err); actual != tc.expected {
+			t.Errorf("error %v: expected exit code %d, got %d", tc.err, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package collect gathers what is needed to diagnose a problem with
+// KubeStellar into one redacted tarball, a support bundle, to attach to
+// an issue.
+package collect
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+)
+
+// CollectOptions contains options for the `collect` command.
+// The base Options are for the KubeStellar core space.
+type CollectOptions struct {
+	*base.Options
+
+	// Host is for the cluster where the KubeStellar controllers run;
+	// when not configured their logs are not collected.
+	Host *clientopts.ClientOpts
+	// Namespace is where the KubeStellar controllers run in the Host cluster.
+	Namespace string
+	// Selector is a label selector for the Pods of the KubeStellar controllers.
+	Selector string
+	// TailLines limits how many lines are taken from the end of each log; zero means all.
+	TailLines int64
+
+	// SpaceMgt is for the space management API; when not configured
+	// the Spaces are not collected.
+	SpaceMgt *clientopts.ClientOpts
+
+	// Probes are NAME=URL pairs, for the controllers whose /metrics and /apiwatch to collect.
+	Probes []string
+	// Timeout bounds each HTTP request to a controller.
+	Timeout time.Duration
+
+	// OutputFile is where to write the bundle; empty means a name made from the time.
+	OutputFile string
+
+	// RuleFiles hold redaction rules to apply in addition to (or instead of) the default ones.
+	RuleFiles []string
+	// NoDefaultRules means not to apply DefaultRules.
+	NoDefaultRules bool
+
+	kcsKube      kubernetes.Interface
+	kcsDynamic   dynamic.Interface
+	hostKube     kubernetes.Interface
+	spaceDynamic dynamic.Interface
+	redactor     Redactor
+}
+
+// NewCollectOptions returns a new CollectOptions.
+func NewCollectOptions(streams genericclioptions.IOStreams) *CollectOptions {
+	return &CollectOptions{
+		Options:   base.NewOptions(streams),
+		Host:      clientopts.NewClientOpts("host", "access to the cluster where the KubeStellar controllers run"),
+		Namespace: "kubestellar",
+		Selector:  "app=kubestellar",
+		TailLines: 10000,
+		SpaceMgt:  clientopts.NewClientOpts("space-mgt", "access to the space management API"),
+		Timeout:   10 * time.Second,
+	}
+}
+
+// BindFlags binds fields CollectOptions as command line flags to cmd's flagset.
+func (o *CollectOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	o.Host.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.Namespace, "namespace", o.Namespace, "Namespace, in the host cluster, of the KubeStellar controllers.")
+	cmd.Flags().StringVar(&o.Selector, "selector", o.Selector, "Label selector for the Pods of the KubeStellar controllers.")
+	cmd.Flags().Int64Var(&o.TailLines, "tail", o.TailLines, "Number of lines to take from the end of each log; zero means all.")
+	o.SpaceMgt.AddFlags(cmd.Flags())
+	cmd.Flags().StringArrayVar(&o.Probes, "probe", o.Probes, "NAME=URL of a controller whose /metrics and /apiwatch to collect, e.g. placement-translator=http://localhost:10204. May be repeated.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "Time limit for each request to a controller.")
+	cmd.Flags().StringVarP(&o.OutputFile, "output-file", "f", o.OutputFile, "Where to write the bundle; defaults to kubestellar-support-TIMESTAMP.tar.gz in the current directory.")
+	cmd.Flags().StringArrayVar(&o.RuleFiles, "redaction-rules", o.RuleFiles, "File holding a YAML list of redaction rules, each with name, pattern, replacement and paths. May be repeated.")
+	cmd.Flags().BoolVar(&o.NoDefaultRules, "no-default-redaction", o.NoDefaultRules, "Apply only the rules from --redaction-rules, not the built-in ones.")
+}
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *CollectOptions) Complete() error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+	kcsConfig, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	o.kcsKube, err = kubernetes.NewForConfig(kcsConfig)
+	if err != nil {
+		return err
+	}
+	o.kcsDynamic, err = dynamic.NewForConfig(kcsConfig)
+	if err != nil {
+		return err
+	}
+	if o.Host.Configured() {
+		hostConfig, err := o.Host.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		o.hostKube, err = kubernetes.NewForConfig(hostConfig)
+		if err != nil {
+			return err
+		}
+	}
+	if o.SpaceMgt.Configured() {
+		spaceMgtConfig, err := o.SpaceMgt.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		o.spaceDynamic, err = dynamic.NewForConfig(spaceMgtConfig)
+		if err != nil {
+			return err
+		}
+	}
+	var rules RuleSet
+	if !o.NoDefaultRules {
+		rules = DefaultRules()
+	}
+	for _, filename := range o.RuleFiles {
+		more, err := LoadRules(filename)
+		if err != nil {
+			return &base.UsageError{Message: err.Error()}
+		}
+		rules = append(rules, more...)
+	}
+	o.redactor = rules
+	if o.OutputFile == "" {
+		o.OutputFile = "kubestellar-support-" + time.Now().UTC().Format("20060102T150405Z") + ".tar.gz"
+	}
+	return nil
+}
+
+// Validate validates the CollectOptions are complete and usable.
+func (o *CollectOptions) Validate() error {
+	var errs []error
+	if err := o.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, probe := range o.Probes {
+		if name, url, ok := strings.Cut(probe, "="); !ok || name == "" || url == "" {
+			errs = append(errs, fmt.Errorf("--probe %q is not of the form NAME=URL", probe))
+		}
+	}
+	if o.Timeout <= 0 {
+		errs = append(errs, errors.New("--timeout must be positive"))
+	}
+	if o.TailLines < 0 {
+		errs = append(errs, errors.New("--tail must not be negative"))
+	}
+	if o.NoDefaultRules && len(o.RuleFiles) == 0 {
+		errs = append(errs, errors.New("--no-default-redaction requires --redaction-rules"))
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return &base.UsageError{Message: err.Error()}
+	}
+	return nil
+}
+
+// Manifest describes a bundle; it is the bundle's manifest.json.
+type Manifest struct {
+	CollectedAt time.Time `json:"collectedAt"`
+	// Files lists the other files in the bundle.
+	Files []string `json:"files"`
+	// Problems are what could not be collected, and why.
+	Problems []string `json:"problems,omitempty"`
+	// RedactionRules names the rules that were applied.
+	RedactionRules []string `json:"redactionRules"`
+}
+
+// Run collects everything it can into the bundle.  A failure to
+// collect one thing is noted in the manifest rather than ending the run.
+func (o *CollectOptions) Run(ctx context.Context) error {
+	file, err := os.Create(o.OutputFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	bndl := newBundle(file, o.redactor)
+	bndl.manifest.CollectedAt = time.Now()
+	if rules, ok := o.redactor.(RuleSet); ok {
+		for _, rule := range rules {
+			bndl.manifest.RedactionRules = append(bndl.manifest.RedactionRules, rule.Name)
+		}
+	}
+	o.collectResources(ctx, bndl)
+	o.collectLogs(ctx, bndl)
+	o.collectProbes(ctx, bndl)
+	if err := bndl.close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", o.OutputFile, err)
+	}
+	fmt.Fprintf(o.Out, "Wrote support bundle %s with %d file(s).\n", o.OutputFile, len(bndl.manifest.Files))
+	for _, problem := range bndl.manifest.Problems {
+		fmt.Fprintf(o.ErrOut, "Not collected: %s\n", problem)
+	}
+	return nil
+}
+
+// collectResources adds the KubeStellar objects in the core space, and the Spaces.
+func (o *CollectOptions) collectResources(ctx context.Context, bndl *bundle) {
+	groupVersion := edgev2alpha1.SchemeGroupVersion
+	resources, err := o.kcsKube.Discovery().ServerResourcesForGroupVersion(groupVersion.String())
+	if err != nil {
+		bndl.problem("discovery of %s: %v", groupVersion, err)
+	} else {
+		for _, resource := range resources.APIResources {
+			if strings.Contains(resource.Name, "/") || !hasVerb(resource.Verbs, "list") {
+				continue
+			}
+			o.collectList(ctx, bndl, o.kcsDynamic, groupVersion.WithResource(resource.Name))
+		}
+	}
+	if o.spaceDynamic != nil {
+		o.collectList(ctx, bndl, o.spaceDynamic, spacev1alpha1.SchemeGroupVersion.WithResource("spaces"))
+	}
+}
+
+func (o *CollectOptions) collectList(ctx context.Context, bndl *bundle, client dynamic.Interface, gvr schema.GroupVersionResource) {
+	list, err := client.Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		bndl.problem("listing %s: %v", gvr.GroupResource(), err)
+		return
+	}
+	for idx := range list.Items {
+		// These are noise in a bug report
+		unstructured.RemoveNestedField(list.Items[idx].Object, "metadata", "managedFields")
+	}
+	content, err := yaml.Marshal(list.UnstructuredContent())
+	if err != nil {
+		bndl.problem("encoding %s: %v", gvr.GroupResource(), err)
+		return
+	}
+	bndl.add("resources/"+gvr.GroupResource().String()+".yaml", content)
+}
+
+// collectLogs adds the descriptions and logs of the Pods of the controllers.
+func (o *CollectOptions) collectLogs(ctx context.Context, bndl *bundle) {
+	if o.hostKube == nil {
+		bndl.problem("controller logs: the --host-* flags were not given")
+		return
+	}
+	pods, err := o.hostKube.CoreV1().Pods(o.Namespace).List(ctx, metav1.ListOptions{LabelSelector: o.Selector})
+	if err != nil {
+		bndl.problem("listing Pods in namespace %s: %v", o.Namespace, err)
+		return
+	}
+	if len(pods.Items) == 0 {
+		bndl.problem("controller logs: no Pods in namespace %s match %q", o.Namespace, o.Selector)
+	}
+	for idx := range pods.Items {
+		pod := &pods.Items[idx]
+		pod.ManagedFields = nil
+		if content, err := yaml.Marshal(pod); err == nil {
+			bndl.add("pods/"+pod.Name+"/pod.yaml", content)
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			o.collectLog(ctx, bndl, pod.Name, status.Name, false)
+			if status.RestartCount > 0 {
+				o.collectLog(ctx, bndl, pod.Name, status.Name, true)
+			}
+		}
+	}
+}
+
+func (o *CollectOptions) collectLog(ctx context.Context, bndl *bundle, podName, container string, previous bool) {
+	opts := &corev1.PodLogOptions{Container: container, Previous: previous}
+	if o.TailLines > 0 {
+		opts.TailLines = &o.TailLines
+	}
+	content, err := o.hostKube.CoreV1().Pods(o.Namespace).GetLogs(podName, opts).DoRaw(ctx)
+	filePath := "pods/" + podName + "/" + container + ".log"
+	if previous {
+		filePath = "pods/" + podName + "/" + container + ".previous.log"
+	}
+	if err != nil {
+		bndl.problem("%s: %v", filePath, err)
+		return
+	}
+	bndl.add(filePath, content)
+}
+
+// collectProbes adds /metrics and /apiwatch of each of the controllers given by --probe.
+func (o *CollectOptions) collectProbes(ctx context.Context, bndl *bundle) {
+	client := &http.Client{Timeout: o.Timeout}
+	for _, probe := range o.Probes {
+		name, baseURL, _ := strings.Cut(probe, "=")
+		for _, endpoint := range []struct{ path, filePath string }{
+			{"/metrics", "metrics/" + name + ".txt"},
+			{"/apiwatch", "apiwatch/" + name + ".json"},
+		} {
+			content, err := fetch(ctx, client, strings.TrimSuffix(baseURL, "/")+endpoint.path)
+			if err != nil {
+				bndl.problem("%s %s: %v", name, endpoint.path, err)
+				continue
+			}
+			bndl.add(endpoint.filePath, content)
+		}
+	}
+}
+
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func hasVerb(verbs []string, verb string) bool {
+	for _, v := range verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+// bundle writes redacted files into a gzipped tarball, and keeps the Manifest.
+type bundle struct {
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	redactor Redactor
+	manifest Manifest
+	err      error
+}
+
+func newBundle(out io.Writer, redactor Redactor) *bundle {
+	gz := gzip.NewWriter(out)
+	return &bundle{gz: gz, tw: tar.NewWriter(gz), redactor: redactor}
+}
+
+func (bndl *bundle) problem(format string, args ...any) {
+	bndl.manifest.Problems = append(bndl.manifest.Problems, fmt.Sprintf(format, args...))
+}
+
+func (bndl *bundle) add(filePath string, content []byte) {
+	if bndl.err != nil {
+		return
+	}
+	if bndl.redactor != nil {
+		content = bndl.redactor.Redact(filePath, content)
+	}
+	bndl.err = bndl.write(filePath, content)
+	if bndl.err == nil && filePath != manifestPath {
+		bndl.manifest.Files = append(bndl.manifest.Files, filePath)
+	}
+}
+
+const manifestPath = "manifest.json"
+
+func (bndl *bundle) write(filePath string, content []byte) error {
+	hdr := &tar.Header{Name: filePath, Mode: 0644, Size: int64(len(content)), ModTime: time.Now()}
+	if err := bndl.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := bndl.tw.Write(content)
+	return err
+}
+
+// close adds the manifest and finishes the tarball.
+func (bndl *bundle) close() error {
+	sort.Strings(bndl.manifest.Files)
+	content, err := json.MarshalIndent(bndl.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	bndl.add(manifestPath, content)
+	if bndl.err != nil {
+		return bndl.err
+	}
+	if err := bndl.tw.Close(); err != nil {
+		return err
+	}
+	return bndl.gz.Close()
+}
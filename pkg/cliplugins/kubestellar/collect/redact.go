@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collect
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Redactor removes sensitive content from a file before it goes into a bundle.
+type Redactor interface {
+	// Redact returns the given content of the file at the given path
+	// (within the bundle) with the sensitive parts replaced.
+	Redact(filePath string, content []byte) []byte
+}
+
+// Rule is one redaction rule: every match of Pattern, in a file whose
+// path matches one of Paths, is replaced by Replacement.
+type Rule struct {
+	Name string `json:"name"`
+
+	// Pattern is a regular expression in the syntax of Go's regexp package.
+	Pattern string `json:"pattern"`
+
+	// Replacement may refer to submatches as in regexp.Regexp.Expand,
+	// e.g. "${1}REDACTED" keeps the first one.
+	// Empty means "REDACTED".
+	Replacement string `json:"replacement,omitempty"`
+
+	// Paths are glob patterns, as in path.Match, on the path within the bundle.
+	// Empty means all files.
+	Paths []string `json:"paths,omitempty"`
+
+	compiled *regexp.Regexp
+}
+
+// RuleSet is a Redactor that applies its rules in order.
+type RuleSet []Rule
+
+var _ Redactor = RuleSet{}
+
+// DefaultRules are the rules applied unless told otherwise.
+// They cover credentials that commonly show up in logs and objects.
+func DefaultRules() RuleSet {
+	rules := RuleSet{
+		{Name: "bearer-token", Pattern: `(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`, Replacement: "${1}REDACTED"},
+		{Name: "kubeconfig-credential", Pattern: `((?:client-key-data|client-certificate-data|certificate-authority-data|token|password)"?\s*:\s*"?)[^"\s,}]+`, Replacement: "${1}REDACTED"},
+		{Name: "url-userinfo", Pattern: `(://)[^/@\s:]+:[^/@\s]+@`, Replacement: "${1}REDACTED@"},
+		{Name: "private-key", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	}
+	if err := rules.compile(); err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// LoadRules reads a YAML or JSON list of Rules from the given file.
+func LoadRules(filename string) (RuleSet, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var rules RuleSet
+	if err := yaml.Unmarshal(content, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction rules in %s: %w", filename, err)
+	}
+	if err := rules.compile(); err != nil {
+		return nil, fmt.Errorf("invalid redaction rule in %s: %w", filename, err)
+	}
+	return rules, nil
+}
+
+func (rules RuleSet) compile() error {
+	for idx := range rules {
+		rule := &rules[idx]
+		compiled, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		for _, pattern := range rule.Paths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %q: bad path pattern %q: %w", rule.Name, pattern, err)
+			}
+		}
+		rule.compiled = compiled
+	}
+	return nil
+}
+
+func (rules RuleSet) Redact(filePath string, content []byte) []byte {
+	for _, rule := range rules {
+		if !rule.appliesTo(filePath) {
+			continue
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = "REDACTED"
+		}
+		content = rule.compiled.ReplaceAll(content, []byte(replacement))
+	}
+	return content
+}
+
+func (rule Rule) appliesTo(filePath string) bool {
+	if len(rule.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range rule.Paths {
+		if matched, _ := path.Match(pattern, filePath); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package doctor checks the health of a KubeStellar installation, for
+// the `kubectl kubestellar doctor` command.
+//
+// The checks are functions of a Snapshot, which is read from the
+// KubeStellar core space, the space management API and the controllers'
+// probe endpoints, so that they can be tested without any of those.
+package doctor
+
+import (
+	"fmt"
+	"sort"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+)
+
+// Severity says how urgent a Finding is.
+type Severity string
+
+const (
+	// Critical means KubeStellar is not doing its job for some of the workload.
+	Critical Severity = "Critical"
+
+	// Warning means something is wrong but not yet harmful, or is
+	// harmful only in some cases.
+	Warning Severity = "Warning"
+
+	// Info is something to know that is not wrong.
+	Info Severity = "Info"
+)
+
+func (sev Severity) rank() int {
+	switch sev {
+	case Critical:
+		return 0
+	case Warning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Finding is one result of the checks.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Check names the check that made this finding.
+	Check string `json:"check"`
+	// Subject is what the finding is about.
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	// Hint suggests what to do about it.
+	Hint string `json:"hint,omitempty"`
+}
+
+// The names of the checks.
+const (
+	CheckAPIs        = "apis"
+	CheckControllers = "controllers"
+	CheckDecisions   = "decisions"
+	CheckMailboxes   = "mailboxes"
+	CheckWebhooks    = "webhooks"
+)
+
+// PruneProtectionAnnotationKey is as in the mailbox controller.
+const PruneProtectionAnnotationKey = "edge.kubestellar.io/prune-protection"
+
+// Snapshot is what the checks look at.
+// The objects are as found in the KubeStellar core space, where most
+// are kube-bind copies.
+type Snapshot struct {
+	// MissingResources are the KubeStellar API resources that the core space does not serve.
+	MissingResources []string
+
+	EdgePlacements []edgev2alpha1.EdgePlacement
+	Slices         []edgev2alpha1.SinglePlacementSlice
+	SyncTargets    []edgev2alpha1.SyncTarget
+
+	// KubeBind relates kube-bind IDs to space IDs.
+	KubeBind kbuser.KubeBindSpaceRelation
+
+	// Spaces are those of the space provider; nil when not read.
+	Spaces []spacev1alpha1.Space
+
+	Webhooks []WebhookStatus
+
+	// Probes are the results of probing the controllers; empty when none were given.
+	Probes []ProbeResult
+
+	// APIWatch holds the API resource stats of the probed controllers that serve them.
+	APIWatch []ControllerAPIStats
+}
+
+// WebhookStatus is about one admission webhook that intercepts KubeStellar objects.
+type WebhookStatus struct {
+	// Configuration is the kind and name of the webhook configuration.
+	Configuration string
+	Name          string
+	// FailClosed is whether a failure to call the webhook rejects the request.
+	FailClosed bool
+	// Service is the "namespace/name" of the webhook's Service; empty for a URL.
+	Service string
+	// Reachable tells whether the Service has ready endpoints.
+	Reachable bool
+}
+
+// ProbeResult is the result of probing one path of one controller.
+type ProbeResult struct {
+	Controller string
+	Path       string
+	// Err is nil when the probe succeeded.
+	Err error
+	// Unreachable means that there was no HTTP response at all.
+	Unreachable bool
+}
+
+// ControllerAPIStats is what one controller serves at /apiwatch.
+type ControllerAPIStats struct {
+	Controller string
+	Clusters   []apiwatch.ClusterStats
+}
+
+// Diagnose runs all the checks and returns the findings, most urgent first.
+func Diagnose(snap *Snapshot) []Finding {
+	var findings []Finding
+	for _, check := range []func(*Snapshot) []Finding{checkAPIs, checkControllers, checkDecisions, checkMailboxes, checkWebhooks} {
+		findings = append(findings, check(snap)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if ri, rj := findings[i].Severity.rank(), findings[j].Severity.rank(); ri != rj {
+			return ri < rj
+		}
+		if findings[i].Check != findings[j].Check {
+			return findings[i].Check < findings[j].Check
+		}
+		return findings[i].Subject < findings[j].Subject
+	})
+	return findings
+}
+
+func checkAPIs(snap *Snapshot) []Finding {
+	var findings []Finding
+	for _, resource := range snap.MissingResources {
+		findings = append(findings, Finding{Severity: Critical, Check: CheckAPIs, Subject: resource,
+			Message: "the KubeStellar core space does not serve this resource",
+			Hint:    "run `kubestellar init`, or redeploy the core Helm chart, to install the KubeStellar APIs"})
+	}
+	return findings
+}
+
+func checkControllers(snap *Snapshot) []Finding {
+	if len(snap.Probes) == 0 {
+		return []Finding{{Severity: Info, Check: CheckControllers, Subject: "controllers",
+			Message: "the controllers' /readyz and /healthz were not probed",
+			Hint:    "give --probe NAME=URL for each controller, e.g. --probe mailbox-controller=http://localhost:10203"}}
+	}
+	var findings []Finding
+	for _, probe := range snap.Probes {
+		if probe.Err == nil {
+			continue
+		}
+		subject := probe.Controller + " " + probe.Path
+		switch {
+		case probe.Unreachable:
+			findings = append(findings, Finding{Severity: Critical, Check: CheckControllers, Subject: subject,
+				Message: fmt.Sprintf("not reachable: %v", probe.Err),
+				Hint:    "check that the controller is running and that --server-bind-address is given and matches the URL"})
+		case probe.Path == "/readyz":
+			findings = append(findings, Finding{Severity: Critical, Check: CheckControllers, Subject: subject,
+				Message: fmt.Sprintf("not ready: %v", probe.Err),
+				Hint:    "the controller's informers have not synced; check its log for errors reaching the spaces"})
+		default:
+			findings = append(findings, Finding{Severity: Critical, Check: CheckControllers, Subject: subject,
+				Message: fmt.Sprintf("not healthy: %v", probe.Err),
+				Hint:    "a reconciliation is stuck; restart the controller and report the problem with its log"})
+		}
+	}
+	for _, ctlStats := range snap.APIWatch {
+		for _, cs := range ctlStats.Clusters {
+			subject := ctlStats.Controller + " cluster " + cs.Cluster
+			switch {
+			case cs.LastRefresh == nil:
+				findings = append(findings, Finding{Severity: Info, Check: CheckControllers, Subject: subject,
+					Message: "has not yet listed the API resources of this cluster"})
+			case cs.DiscoveryError != "":
+				findings = append(findings, Finding{Severity: Warning, Check: CheckControllers, Subject: subject,
+					Message: fmt.Sprintf("discovery was incomplete, so some resources may be ignored: %s", cs.DiscoveryError),
+					Hint:    "look for an APIService that is not available in that cluster"})
+			}
+		}
+	}
+	return findings
+}
+
+// consumerKey identifies an object in a consumer's space by the
+// consumer's kube-bind ID and the object's name there.
+type consumerKey struct {
+	kbSpaceID string
+	name      string
+}
+
+func checkDecisions(snap *Snapshot) []Finding {
+	var findings []Finding
+	placements := map[consumerKey]bool{}
+	for idx := range snap.EdgePlacements {
+		_, name, kbSpaceID, err := kbuser.AnalyzeObjectID(&snap.EdgePlacements[idx])
+		if err == nil {
+			placements[consumerKey{kbSpaceID, name}] = true
+		}
+	}
+	slices := map[consumerKey]*edgev2alpha1.SinglePlacementSlice{}
+	for idx := range snap.Slices {
+		sps := &snap.Slices[idx]
+		_, name, kbSpaceID, err := kbuser.AnalyzeObjectID(sps)
+		if err == nil {
+			slices[consumerKey{kbSpaceID, name}] = sps
+		}
+	}
+	syncTargets := map[string]*edgev2alpha1.SyncTarget{}
+	for idx := range snap.SyncTargets {
+		syncTargets[snap.SyncTargets[idx].Name] = &snap.SyncTargets[idx]
+	}
+	for key := range placements {
+		if slices[consumerKey{key.kbSpaceID, naming.SinglePlacementSliceName(key.name)}] == nil {
+			findings = append(findings, Finding{Severity: Critical, Check: CheckDecisions, Subject: "EdgePlacement " + describe(snap, key),
+				Message: "has no SinglePlacementSlice, so nothing is placed for it",
+				Hint:    "check that the where-resolver is running and ready"})
+		}
+	}
+	for key, sps := range slices {
+		if !placements[key] {
+			findings = append(findings, Finding{Severity: Warning, Check: CheckDecisions, Subject: "SinglePlacementSlice " + describe(snap, key),
+				Message: "its EdgePlacement no longer exists",
+				Hint:    "the where-resolver's orphan garbage collection deletes these unless --orphan-gc-period is zero"})
+			continue
+		}
+		for _, dest := range sps.Destinations {
+			kbID := snap.KubeBind.SpaceIDToKubeBind(dest.Cluster)
+			if kbID == "" {
+				continue
+			}
+			st := syncTargets[kbuser.ComposeClusterScopedName(kbID, dest.SyncTargetName)]
+			if st == nil || (dest.SyncTargetUID != "" && st.UID != dest.SyncTargetUID) {
+				findings = append(findings, Finding{Severity: Warning, Check: CheckDecisions, Subject: "SinglePlacementSlice " + describe(snap, key),
+					Message: fmt.Sprintf("is stale: destination SyncTarget %s in %s no longer exists", dest.SyncTargetName, dest.Cluster),
+					Hint:    "the where-resolver has not caught up; check that it is running and look for errors in its log"})
+			}
+		}
+	}
+	return findings
+}
+
+// describe names a consumer's object with its space ID, when known.
+func describe(snap *Snapshot, key consumerKey) string {
+	if spaceID := snap.KubeBind.SpaceIDFromKubeBind(key.kbSpaceID); spaceID != "" {
+		return spaceID + ":" + key.name
+	}
+	return key.kbSpaceID + "-" + key.name
+}
+
+func checkMailboxes(snap *Snapshot) []Finding {
+	if snap.Spaces == nil {
+		return []Finding{{Severity: Info, Check: CheckMailboxes, Subject: "mailbox spaces",
+			Message: "mailbox spaces were not checked",
+			Hint:    "give the --space-mgt-* flags for access to the space management API"}}
+	}
+	var findings []Finding
+	spaces := map[string]*spacev1alpha1.Space{}
+	for idx := range snap.Spaces {
+		spaces[snap.Spaces[idx].Name] = &snap.Spaces[idx]
+	}
+	expected := map[string]bool{}
+	for idx := range snap.SyncTargets {
+		st := &snap.SyncTargets[idx]
+		_, stName, kbSpaceID, err := kbuser.AnalyzeObjectID(st)
+		if err != nil {
+			continue
+		}
+		spaceID := snap.KubeBind.SpaceIDFromKubeBind(kbSpaceID)
+		subject := "SyncTarget " + describe(snap, consumerKey{kbSpaceID, stName})
+		if spaceID == "" {
+			findings = append(findings, Finding{Severity: Warning, Check: CheckMailboxes, Subject: subject,
+				Message: "its inventory space is not known from the kube-bind relation",
+				Hint:    "check that kube-bind is working for the inventory space"})
+			continue
+		}
+		mbsName := naming.MailboxSpaceName(spaceID, string(st.UID))
+		expected[mbsName] = true
+		space := spaces[mbsName]
+		if space == nil {
+			findings = append(findings, Finding{Severity: Critical, Check: CheckMailboxes, Subject: subject,
+				Message: fmt.Sprintf("has no mailbox space (expected %s)", mbsName),
+				Hint:    "check that the mailbox controller is running and ready"})
+		} else if space.Status.Phase != spacev1alpha1.SpacePhaseReady {
+			findings = append(findings, Finding{Severity: Warning, Check: CheckMailboxes, Subject: subject,
+				Message: fmt.Sprintf("its mailbox space %s is in phase %q", mbsName, space.Status.Phase),
+				Hint:    "check the space provider and the space manager's log"})
+		}
+	}
+	for name, space := range spaces {
+		if !naming.IsMailboxSpaceName(name) || expected[name] {
+			continue
+		}
+		if space.Annotations[PruneProtectionAnnotationKey] == "true" {
+			findings = append(findings, Finding{Severity: Info, Check: CheckMailboxes, Subject: "Space " + name,
+				Message: "mailbox space without a SyncTarget is kept by prune protection",
+				Hint:    "remove the " + PruneProtectionAnnotationKey + " annotation when it is no longer needed"})
+			continue
+		}
+		findings = append(findings, Finding{Severity: Warning, Check: CheckMailboxes, Subject: "Space " + name,
+			Message: "mailbox space has no SyncTarget",
+			Hint:    "the mailbox controller prunes it once no placement uses it; check the controller's log if it stays"})
+	}
+	return findings
+}
+
+func checkWebhooks(snap *Snapshot) []Finding {
+	var findings []Finding
+	for _, webhook := range snap.Webhooks {
+		subject := webhook.Configuration + " " + webhook.Name
+		if webhook.Service == "" {
+			findings = append(findings, Finding{Severity: Info, Check: CheckWebhooks, Subject: subject,
+				Message: "intercepts KubeStellar objects and is called by URL, which was not checked"})
+			continue
+		}
+		if webhook.Reachable {
+			continue
+		}
+		finding := Finding{Severity: Warning, Check: CheckWebhooks, Subject: subject,
+			Message: fmt.Sprintf("intercepts KubeStellar objects but Service %s has no ready endpoints", webhook.Service),
+			Hint:    "fix or delete the webhook"}
+		if webhook.FailClosed {
+			finding.Severity = Critical
+			finding.Message += ", and failures reject the requests"
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
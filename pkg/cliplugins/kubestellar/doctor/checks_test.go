@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+)
+
+// fakeRelation relates kube-bind ID "kb-X" to space ID "X".
+type fakeRelation map[string]string
+
+func (reln fakeRelation) SpaceIDToKubeBind(spaceID string) string {
+	for kbID, id := range reln {
+		if id == spaceID {
+			return kbID
+		}
+	}
+	return ""
+}
+
+func (reln fakeRelation) SpaceIDFromKubeBind(kubeBindID string) string {
+	return reln[kubeBindID]
+}
+
+func copyMeta(kbID, name string, uid types.UID) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: kbID + "-" + name, UID: uid,
+		Annotations: map[string]string{"kube-bind.io/cluster-namespace": kbID}}
+}
+
+func TestDiagnose(t *testing.T) {
+	snap := &Snapshot{
+		KubeBind: fakeRelation{"kb-wds": "wds", "kb-imw": "imw"},
+		EdgePlacements: []edgev2alpha1.EdgePlacement{
+			{ObjectMeta: copyMeta("kb-wds", "good", "")},
+			{ObjectMeta: copyMeta("kb-wds", "undecided", "")},
+		},
+		Slices: []edgev2alpha1.SinglePlacementSlice{
+			{ObjectMeta: copyMeta("kb-wds", "good", ""), Destinations: []edgev2alpha1.SinglePlacement{
+				{Cluster: "imw", SyncTargetName: "st1", SyncTargetUID: "uid1"},
+				{Cluster: "imw", SyncTargetName: "gone", SyncTargetUID: "uid9"},
+			}},
+			{ObjectMeta: copyMeta("kb-wds", "orphan", "")},
+		},
+		SyncTargets: []edgev2alpha1.SyncTarget{
+			{ObjectMeta: copyMeta("kb-imw", "st1", "uid1")},
+			{ObjectMeta: copyMeta("kb-imw", "st2", "uid2")},
+		},
+		Spaces: []spacev1alpha1.Space{
+			{ObjectMeta: metav1.ObjectMeta{Name: naming.MailboxSpaceName("imw", "uid1")},
+				Status: spacev1alpha1.SpaceStatus{Phase: spacev1alpha1.SpacePhaseReady}},
+			{ObjectMeta: metav1.ObjectMeta{Name: naming.MailboxSpaceName("imw", "uid7"),
+				Annotations: map[string]string{PruneProtectionAnnotationKey: "true"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: naming.MailboxSpaceName("imw", "uid8")}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "wds"}},
+		},
+		Webhooks: []WebhookStatus{
+			{Configuration: "ValidatingWebhookConfiguration v", Name: "down", FailClosed: true, Service: "ns/svc"},
+			{Configuration: "ValidatingWebhookConfiguration v", Name: "up", FailClosed: true, Service: "ns/svc2", Reachable: true},
+		},
+		Probes: []ProbeResult{
+			{Controller: "where-resolver", Path: "/readyz"},
+			{Controller: "where-resolver", Path: "/healthz", Err: errors.New("status 500")},
+		},
+	}
+	expected := []Finding{
+		{Severity: Critical, Check: CheckControllers, Subject: "where-resolver /healthz"},
+		{Severity: Critical, Check: CheckDecisions, Subject: "EdgePlacement wds:undecided"},
+		{Severity: Critical, Check: CheckMailboxes, Subject: "SyncTarget imw:st2"},
+		{Severity: Critical, Check: CheckWebhooks, Subject: "ValidatingWebhookConfiguration v down"},
+		{Severity: Warning, Check: CheckDecisions, Subject: "SinglePlacementSlice wds:good"},
+		{Severity: Warning, Check: CheckDecisions, Subject: "SinglePlacementSlice wds:orphan"},
+		{Severity: Warning, Check: CheckMailboxes, Subject: "Space " + naming.MailboxSpaceName("imw", "uid8")},
+		{Severity: Info, Check: CheckMailboxes, Subject: "Space " + naming.MailboxSpaceName("imw", "uid7")},
+	}
+	actual := Diagnose(snap)
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d findings, got %d: %#v", len(expected), len(actual), actual)
+	}
+	for idx, exp := range expected {
+		act := actual[idx]
+		if act.Severity != exp.Severity || act.Check != exp.Check || act.Subject != exp.Subject {
+			t.Errorf("Finding %d: expected %s %s %q, got %s %s %q", idx, exp.Severity, exp.Check, exp.Subject, act.Severity, act.Check, act.Subject)
+		}
+		if act.Message == "" {
+			t.Errorf("Finding %d has no message", idx)
+		}
+	}
+}
+
+func TestDiagnoseNotGathered(t *testing.T) {
+	snap := &Snapshot{KubeBind: fakeRelation{}, MissingResources: []string{"synctargets"}}
+	actual := Diagnose(snap)
+	if len(actual) != 3 || actual[0].Severity != Critical || actual[0].Check != CheckAPIs {
+		t.Errorf("Unexpected findings %#v", actual)
+	}
+	for _, finding := range actual[1:] {
+		if finding.Severity != Info {
+			t.Errorf("Expected Info for what was not gathered, got %#v", finding)
+		}
+	}
+}
+
+func TestDiagnoseAPIWatch(t *testing.T) {
+	now := time.Now()
+	snap := &Snapshot{
+		KubeBind: fakeRelation{},
+		Probes:   []ProbeResult{{Controller: "placement-translator", Path: "/readyz"}},
+		APIWatch: []ControllerAPIStats{{Controller: "placement-translator", Clusters: []apiwatch.ClusterStats{
+			{Cluster: "good", LastRefresh: &now, Resources: 40},
+			{Cluster: "partial", LastRefresh: &now, DiscoveryError: "unable to retrieve metrics.k8s.io/v1beta1"},
+			{Cluster: "new"},
+		}}},
+	}
+	var actual []Finding
+	for _, finding := range Diagnose(snap) {
+		if finding.Check == CheckControllers {
+			actual = append(actual, finding)
+		}
+	}
+	if len(actual) != 2 {
+		t.Fatalf("Expected 2 findings, got %#v", actual)
+	}
+	if actual[0].Severity != Warning || actual[0].Subject != "placement-translator cluster partial" {
+		t.Errorf("Unexpected first finding %#v", actual[0])
+	}
+	if actual[1].Severity != Info || actual[1].Subject != "placement-translator cluster new" {
+		t.Errorf("Unexpected second finding %#v", actual[1])
+	}
+}
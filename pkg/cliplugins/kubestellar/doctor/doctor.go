@@ -0,0 +1,366 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	spaceclientset "github.com/kubestellar/kubestellar/space-framework/pkg/client/clientset/versioned"
+	spacemanager "github.com/kubestellar/kubestellar/space-framework/pkg/space-manager"
+)
+
+// RequiredResources are the KubeStellar API resources that the core space must serve.
+var RequiredResources = []string{"edgeplacements", "singleplacementslices", "synctargets", "locations", "syncerconfigs"}
+
+// DoctorOptions contains options for the `doctor` command.
+// The base Options are for the KubeStellar core space.
+type DoctorOptions struct {
+	*base.Options
+
+	// SpaceMgt is for the space management API; when not configured
+	// the mailbox spaces are not checked.
+	SpaceMgt *clientopts.ClientOpts
+	// SpaceProvider is the name of the space provider of the mailbox spaces.
+	SpaceProvider string
+	// Probes are NAME=URL pairs, for the controllers to probe.
+	Probes []string
+	// Timeout bounds each probe and the wait for the kube-bind relation.
+	Timeout time.Duration
+	// Output is the format of the findings.
+	Output base.OutputFormat
+
+	kcsKube     kubernetes.Interface
+	kcsEdge     edgeclientset.Interface
+	spaceClient spaceclientset.Interface
+}
+
+// NewDoctorOptions returns a new DoctorOptions.
+func NewDoctorOptions(streams genericclioptions.IOStreams) *DoctorOptions {
+	return &DoctorOptions{
+		Options:       base.NewOptions(streams),
+		SpaceMgt:      clientopts.NewClientOpts("space-mgt", "access to the space management API"),
+		SpaceProvider: "default",
+		Timeout:       10 * time.Second,
+		Output:        base.OutputTable,
+	}
+}
+
+// BindFlags binds fields DoctorOptions as command line flags to cmd's flagset.
+func (o *DoctorOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	o.SpaceMgt.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.SpaceProvider, "space-provider", o.SpaceProvider, "Name of the space provider of the mailbox spaces.")
+	cmd.Flags().StringArrayVar(&o.Probes, "probe", o.Probes, "NAME=URL of a controller whose /readyz and /healthz to check, e.g. mailbox-controller=http://localhost:10203. May be repeated.")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "Time limit for each probe and for learning the kube-bind relation.")
+	base.BindOutputFlag(cmd, &o.Output)
+}
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *DoctorOptions) Complete() error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+	kcsConfig, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	o.kcsKube, err = kubernetes.NewForConfig(kcsConfig)
+	if err != nil {
+		return err
+	}
+	o.kcsEdge, err = edgeclientset.NewForConfig(kcsConfig)
+	if err != nil {
+		return err
+	}
+	if o.SpaceMgt.Configured() {
+		spaceMgtConfig, err := o.SpaceMgt.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		o.spaceClient, err = spaceclientset.NewForConfig(spaceMgtConfig)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates the DoctorOptions are complete and usable.
+func (o *DoctorOptions) Validate() error {
+	var errs []error
+	if err := o.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.Output.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, probe := range o.Probes {
+		if name, url, ok := strings.Cut(probe, "="); !ok || name == "" || url == "" {
+			errs = append(errs, fmt.Errorf("--probe %q is not of the form NAME=URL", probe))
+		}
+	}
+	if o.Timeout <= 0 {
+		errs = append(errs, errors.New("--timeout must be positive"))
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return &base.UsageError{Message: err.Error()}
+	}
+	return nil
+}
+
+// Run gathers a Snapshot, prints the findings, and returns an error if
+// any of them is Critical.
+func (o *DoctorOptions) Run(ctx context.Context) error {
+	snap, err := o.Gather(ctx)
+	if err != nil {
+		return err
+	}
+	findings := Diagnose(snap)
+	table := base.Table{Columns: []string{"SEVERITY", "CHECK", "SUBJECT", "MESSAGE", "HINT"}}
+	var critical int
+	for _, finding := range findings {
+		if finding.Severity == Critical {
+			critical++
+		}
+		table.Rows = append(table.Rows, []string{string(finding.Severity), finding.Check, finding.Subject, finding.Message, finding.Hint})
+	}
+	if len(findings) == 0 && o.Output == base.OutputTable {
+		fmt.Fprintln(o.Out, "No problems found.")
+	} else if err := base.PrintObject(o.Out, o.Output, findings, table); err != nil {
+		return err
+	}
+	if critical > 0 {
+		return fmt.Errorf("%d critical finding(s)", critical)
+	}
+	return nil
+}
+
+// Gather reads what the checks look at.
+func (o *DoctorOptions) Gather(ctx context.Context) (*Snapshot, error) {
+	snap := &Snapshot{}
+	var err error
+	snap.MissingResources, err = o.missingResources()
+	if err != nil {
+		return nil, err
+	}
+	served := sets.NewString(RequiredResources...).Difference(sets.NewString(snap.MissingResources...))
+	if served.Has("edgeplacements") {
+		list, err := o.kcsEdge.EdgeV2alpha1().EdgePlacements().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, kserrors.Classify(fmt.Errorf("failed to list EdgePlacements: %w", err))
+		}
+		snap.EdgePlacements = list.Items
+	}
+	if served.Has("singleplacementslices") {
+		list, err := o.kcsEdge.EdgeV2alpha1().SinglePlacementSlices().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, kserrors.Classify(fmt.Errorf("failed to list SinglePlacementSlices: %w", err))
+		}
+		snap.Slices = list.Items
+	}
+	if served.Has("synctargets") {
+		list, err := o.kcsEdge.EdgeV2alpha1().SyncTargets().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, kserrors.Classify(fmt.Errorf("failed to list SyncTargets: %w", err))
+		}
+		snap.SyncTargets = list.Items
+	}
+	relnCtx, stopReln := context.WithCancel(ctx)
+	defer stopReln()
+	reln := kbuser.NewKubeBindSpaceRelation(relnCtx, o.kcsKube)
+	waitCtx, cancelWait := context.WithTimeout(ctx, o.Timeout)
+	defer cancelWait()
+	if !cache.WaitForCacheSync(waitCtx.Done(), reln.InformerSynced) {
+		return nil, kserrors.Classify(errors.New("failed to learn the kube-bind relation in time"))
+	}
+	snap.KubeBind = reln
+	if o.spaceClient != nil {
+		list, err := o.spaceClient.SpaceV1alpha1().Spaces(spacemanager.ProviderNS(o.SpaceProvider)).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, kserrors.Classify(fmt.Errorf("failed to list Spaces: %w", err))
+		}
+		snap.Spaces = list.Items
+	}
+	snap.Webhooks, err = o.webhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap.Probes = o.probe(ctx)
+	snap.APIWatch = o.apiWatchStats(ctx)
+	return snap, nil
+}
+
+func (o *DoctorOptions) missingResources() ([]string, error) {
+	resources, err := o.kcsKube.Discovery().ServerResourcesForGroupVersion(edgev2alpha1.SchemeGroupVersion.String())
+	if apierrors.IsNotFound(err) {
+		return RequiredResources, nil
+	} else if err != nil {
+		return nil, kserrors.Classify(fmt.Errorf("failed to discover the KubeStellar APIs: %w", err))
+	}
+	served := sets.NewString()
+	for _, resource := range resources.APIResources {
+		served.Insert(resource.Name)
+	}
+	var missing []string
+	for _, resource := range RequiredResources {
+		if !served.Has(resource) {
+			missing = append(missing, resource)
+		}
+	}
+	return missing, nil
+}
+
+// webhooks returns the status of the admission webhooks that intercept KubeStellar objects.
+func (o *DoctorOptions) webhooks(ctx context.Context) ([]WebhookStatus, error) {
+	type webhook struct {
+		configuration string
+		name          string
+		rules         []admissionregistrationv1.RuleWithOperations
+		failurePolicy *admissionregistrationv1.FailurePolicyType
+		clientConfig  admissionregistrationv1.WebhookClientConfig
+	}
+	var hooks []webhook
+	validating, err := o.kcsKube.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, kserrors.Classify(fmt.Errorf("failed to list ValidatingWebhookConfigurations: %w", err))
+	}
+	for _, config := range validating.Items {
+		for _, hook := range config.Webhooks {
+			hooks = append(hooks, webhook{"ValidatingWebhookConfiguration " + config.Name, hook.Name, hook.Rules, hook.FailurePolicy, hook.ClientConfig})
+		}
+	}
+	mutating, err := o.kcsKube.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, kserrors.Classify(fmt.Errorf("failed to list MutatingWebhookConfigurations: %w", err))
+	}
+	for _, config := range mutating.Items {
+		for _, hook := range config.Webhooks {
+			hooks = append(hooks, webhook{"MutatingWebhookConfiguration " + config.Name, hook.Name, hook.Rules, hook.FailurePolicy, hook.ClientConfig})
+		}
+	}
+	var statuses []WebhookStatus
+	for _, hook := range hooks {
+		if !interceptsKubeStellar(hook.rules) {
+			continue
+		}
+		status := WebhookStatus{Configuration: hook.configuration, Name: hook.name,
+			// Fail is the default
+			FailClosed: hook.failurePolicy == nil || *hook.failurePolicy == admissionregistrationv1.Fail,
+		}
+		if svc := hook.clientConfig.Service; svc != nil {
+			status.Service = svc.Namespace + "/" + svc.Name
+			endpoints, err := o.kcsKube.CoreV1().Endpoints(svc.Namespace).Get(ctx, svc.Name, metav1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, kserrors.Classify(fmt.Errorf("failed to get Endpoints of webhook Service %s: %w", status.Service, err))
+			}
+			if err == nil {
+				for _, subset := range endpoints.Subsets {
+					if len(subset.Addresses) > 0 {
+						status.Reachable = true
+					}
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func interceptsKubeStellar(rules []admissionregistrationv1.RuleWithOperations) bool {
+	for _, rule := range rules {
+		for _, group := range rule.APIGroups {
+			if group == "*" || group == edgev2alpha1.SchemeGroupVersion.Group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// probe gets /readyz and /healthz of each of the controllers given by --probe.
+func (o *DoctorOptions) probe(ctx context.Context) []ProbeResult {
+	client := &http.Client{Timeout: o.Timeout}
+	var results []ProbeResult
+	for _, probe := range o.Probes {
+		name, baseURL, _ := strings.Cut(probe, "=")
+		for _, path := range []string{"/readyz", "/healthz"} {
+			result := ProbeResult{Controller: name, Path: path}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+path, nil)
+			if err != nil {
+				result.Err, result.Unreachable = err, true
+			} else if resp, err := client.Do(req); err != nil {
+				result.Err, result.Unreachable = err, true
+			} else {
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					result.Err = fmt.Errorf("status %s", resp.Status)
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// apiWatchStats gets /apiwatch of each of the controllers given by --probe.
+// Controllers that do not serve it, or cannot be reached, are skipped;
+// the latter are reported by the probes.
+func (o *DoctorOptions) apiWatchStats(ctx context.Context) []ControllerAPIStats {
+	client := &http.Client{Timeout: o.Timeout}
+	var results []ControllerAPIStats
+	for _, probe := range o.Probes {
+		name, baseURL, _ := strings.Cut(probe, "=")
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/apiwatch", nil)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		var clusters []apiwatch.ClusterStats
+		if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&clusters) == nil {
+			results = append(results, ControllerAPIStats{Controller: name, Clusters: clusters})
+		}
+		resp.Body.Close()
+	}
+	return results
+}
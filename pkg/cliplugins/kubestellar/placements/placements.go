@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package placements implements `kubectl kubestellar placements`, which
+// pauses, resumes, or re-evaluates many EdgePlacements at once.
+package placements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	"github.com/kubestellar/kubestellar/pkg/placementops"
+)
+
+// PlacementsOptions contains options for the `placements` subcommands.
+// The base Options are for the workload description space.
+type PlacementsOptions struct {
+	*base.Options
+
+	// Selector is a label selector for the EdgePlacements to operate on.
+	Selector string
+	// DryRun checks that all the changes would be accepted without making them.
+	DryRun bool
+	// Quiet suppresses the progress reports.
+	Quiet bool
+	// Output is the format of the result.
+	Output base.OutputFormat
+
+	selector labels.Selector
+	client   edgeclientset.Interface
+}
+
+// NewPlacementsOptions returns a new PlacementsOptions.
+func NewPlacementsOptions(streams genericclioptions.IOStreams) *PlacementsOptions {
+	return &PlacementsOptions{
+		Options: base.NewOptions(streams),
+		Output:  base.OutputTable,
+	}
+}
+
+// BindFlags binds fields of PlacementsOptions as command line flags to cmd's flagset.
+func (o *PlacementsOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Label selector for the EdgePlacements to operate on, in addition to any named ones.")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "Check that every change would be accepted, without making any.")
+	cmd.Flags().BoolVarP(&o.Quiet, "quiet", "q", o.Quiet, "Do not report progress on stderr.")
+	base.BindOutputFlag(cmd, &o.Output)
+}
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *PlacementsOptions) Complete() error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	o.client, err = edgeclientset.NewForConfig(config)
+	return err
+}
+
+// Validate validates the PlacementsOptions, given the names from the command line.
+func (o *PlacementsOptions) Validate(names []string) error {
+	var errs []error
+	if err := o.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.Output.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if o.Selector != "" {
+		selector, err := labels.Parse(o.Selector)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid --selector: %w", err))
+		}
+		o.selector = selector
+	} else if len(names) == 0 {
+		errs = append(errs, errors.New("give EdgePlacement names or a --selector"))
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return &base.UsageError{Message: err.Error()}
+	}
+	return nil
+}
+
+// Run applies the given operation to the selected EdgePlacements and prints the result.
+func (o *PlacementsOptions) Run(ctx context.Context, op placementops.Operation, names []string) error {
+	progress := func(p placementops.Progress) {
+		if o.Quiet {
+			return
+		}
+		status := "ok"
+		if p.Err != nil {
+			status = p.Err.Error()
+		}
+		fmt.Fprintf(o.ErrOut, "%s %d/%d %s: %s\n", p.Phase, p.Done, p.Total, p.Name, status)
+	}
+	result, err := placementops.Run(ctx, o.client, placementops.Request{
+		Operation: op,
+		Selector:  o.selector,
+		Names:     names,
+		DryRun:    o.DryRun,
+	}, progress)
+	if err != nil {
+		if len(result.RolledBack) > 0 {
+			fmt.Fprintf(o.ErrOut, "Rolled back %d EdgePlacement(s)\n", len(result.RolledBack))
+		}
+		return kserrors.Classify(err)
+	}
+	changed := "changed"
+	if o.DryRun {
+		changed = "would change"
+	}
+	table := base.Table{Columns: []string{"NAME", "RESULT"}}
+	for _, name := range result.Changed {
+		table.Rows = append(table.Rows, []string{name, changed})
+	}
+	for _, name := range result.Unchanged {
+		table.Rows = append(table.Rows, []string{name, "unchanged"})
+	}
+	if o.Output == base.OutputTable && len(table.Rows) == 0 {
+		fmt.Fprintln(o.ErrOut, "No EdgePlacements matched")
+		return nil
+	}
+	return base.PrintObject(o.Out, o.Output, result, table)
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package recyclebin implements `kubectl kubestellar recycle-bin`, which
+// lists the copies in a mailbox space that are scheduled for deletion
+// because their placement no longer selects that destination, and
+// rescues them from it. See package softdelete.
+package recyclebin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	cachediscovery "k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	"github.com/kubestellar/kubestellar/pkg/placement"
+	"github.com/kubestellar/kubestellar/pkg/softdelete"
+)
+
+// fieldManager is the field manager of the rescue writes.
+const fieldManager = "kubectl-kubestellar-recycle-bin"
+
+// RecycleBinOptions are the options common to the subcommands.
+// The base Options are for the mailbox space of the destination.
+type RecycleBinOptions struct {
+	*base.Options
+
+	// Note is the value of the rescue annotation.
+	Note string
+	// Output is the format of the results.
+	Output base.OutputFormat
+
+	discovery discovery.DiscoveryInterface
+	mapper    meta.RESTMapper
+	client    dynamic.Interface
+}
+
+// NewRecycleBinOptions returns a new RecycleBinOptions.
+func NewRecycleBinOptions(streams genericclioptions.IOStreams) *RecycleBinOptions {
+	return &RecycleBinOptions{
+		Options: base.NewOptions(streams),
+		Note:    "rescued with kubectl kubestellar recycle-bin",
+		Output:  base.OutputTable,
+	}
+}
+
+// BindFlags binds fields of RecycleBinOptions as command line flags to cmd's flagset.
+func (o *RecycleBinOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	cmd.Flags().StringVar(&o.Note, "note", o.Note, "Value of the rescue annotation, e.g., who rescued the object and why.")
+	base.BindOutputFlag(cmd, &o.Output)
+}
+
+// Validate checks the options.
+func (o *RecycleBinOptions) Validate() error {
+	if o.Note == "" {
+		return base.Usagef("--note must not be empty")
+	}
+	return o.Output.Validate()
+}
+
+// Complete makes the clients of the mailbox space.
+func (o *RecycleBinOptions) Complete() error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+	cached := cachediscovery.NewMemCacheClient(discoveryClient)
+	o.discovery = cached
+	o.mapper = restmapper.NewDeferredDiscoveryRESTMapper(cached)
+	o.client, err = dynamic.NewForConfig(config)
+	return err
+}
+
+// ObjectRef identifies an object in the mailbox space.
+type ObjectRef struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (ref ObjectRef) String() string {
+	kind := ref.Kind
+	if ref.Group != "" {
+		kind += "." + ref.Group
+	}
+	if ref.Namespace == "" {
+		return kind + " " + ref.Name
+	}
+	return kind + " " + ref.Namespace + "/" + ref.Name
+}
+
+// ParseObjectRef parses the arguments KIND[.GROUP] [NAMESPACE/]NAME.
+func ParseObjectRef(args []string) (ObjectRef, error) {
+	if len(args) != 2 {
+		return ObjectRef{}, base.Usagef("expected KIND[.GROUP] [NAMESPACE/]NAME but got %d arguments", len(args))
+	}
+	ref := ObjectRef{Name: args[1]}
+	ref.Kind, ref.Group, _ = strings.Cut(args[0], ".")
+	if namespace, name, found := strings.Cut(args[1], "/"); found {
+		ref.Namespace, ref.Name = namespace, name
+	}
+	return ref, nil
+}
+
+// Item is a copy that is, or was, scheduled for deletion.
+type Item struct {
+	ObjectRef
+	DeleteAt metav1.Time `json:"deleteAt"`
+	Rescued  string      `json:"rescued,omitempty"`
+}
+
+// RunList lists the copies in the mailbox space that are scheduled for deletion.
+func (o *RecycleBinOptions) RunList(ctx context.Context) error {
+	resourceLists, err := o.discovery.ServerPreferredResources()
+	if err != nil && len(resourceLists) == 0 {
+		return kserrors.Classify(err)
+	}
+	selector := placement.ProjectedLabelKey + "=" + placement.ProjectedLabelVal
+	items := []Item{}
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") || !hasVerb(resource, "list") {
+				continue
+			}
+			list, err := o.client.Resource(gv.WithResource(resource.Name)).List(ctx, metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				continue // e.g., forbidden; the others are still worth listing
+			}
+			for idx := range list.Items {
+				if item, ok := scheduledItem(gv.Group, &list.Items[idx]); ok {
+					items = append(items, item)
+				}
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].String() < items[j].String() })
+	table := base.Table{Columns: []string{"KIND", "NAMESPACE", "NAME", "DELETE AT", "IN", "RESCUED"}}
+	for _, item := range items {
+		in := time.Until(item.DeleteAt.Time).Round(time.Second).String()
+		table.Rows = append(table.Rows, []string{kindString(item.ObjectRef), item.Namespace, item.Name,
+			item.DeleteAt.UTC().Format(time.RFC3339), in, item.Rescued})
+	}
+	return base.PrintObject(o.Out, o.Output, items, table)
+}
+
+// RunRescue rescues the given copy from its scheduled deletion.
+func (o *RecycleBinOptions) RunRescue(ctx context.Context, ref ObjectRef) error {
+	mapping, err := o.mapper.RESTMapping(schema.GroupKind{Group: ref.Group, Kind: ref.Kind})
+	if err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to find the resource of %s: %w", kindString(ref), err))
+	}
+	var client dynamic.ResourceInterface = o.client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		client = o.client.Resource(mapping.Resource).Namespace(ref.Namespace)
+	}
+	obj, err := client.Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to read %s: %w", ref, err))
+	}
+	at, scheduled, err := softdelete.ScheduledAt(obj)
+	if err != nil {
+		return err
+	}
+	if !scheduled {
+		return fmt.Errorf("%s is not scheduled for deletion", ref)
+	}
+	if softdelete.Rescued(obj) {
+		fmt.Fprintf(o.Out, "%s was already rescued\n", ref)
+		return nil
+	}
+	if !time.Now().Before(at) {
+		fmt.Fprintf(o.ErrOut, "Warning: %s was due for deletion at %s and may already be gone from its edge cluster\n", ref, at.UTC().Format(time.RFC3339))
+	}
+	softdelete.Rescue(obj, o.Note)
+	if _, err := client.Update(ctx, obj, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to rescue %s: %w", ref, err))
+	}
+	fmt.Fprintf(o.Out, "Rescued %s\n", ref)
+	return nil
+}
+
+// scheduledItem returns the Item for the given object, if it is scheduled for deletion.
+func scheduledItem(group string, obj *unstructured.Unstructured) (Item, bool) {
+	at, scheduled, err := softdelete.ScheduledAt(obj)
+	if err != nil || !scheduled {
+		return Item{}, false
+	}
+	item := Item{
+		ObjectRef: ObjectRef{Group: group, Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()},
+		DeleteAt:  metav1.NewTime(at),
+	}
+	if softdelete.Rescued(obj) {
+		item.Rescued = obj.GetAnnotations()[softdelete.RescuedAnnotationKey]
+	}
+	return item, true
+}
+
+func hasVerb(resource metav1.APIResource, verb string) bool {
+	for _, have := range resource.Verbs {
+		if have == verb {
+			return true
+		}
+	}
+	return false
+}
+
+func kindString(ref ObjectRef) string {
+	if ref.Group == "" {
+		return ref.Kind
+	}
+	return ref.Kind + "." + ref.Group
+}
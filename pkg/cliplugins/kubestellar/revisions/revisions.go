@@ -0,0 +1,314 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package revisions implements `kubectl kubestellar revisions`, which reads
+// the revision history that the syncer keeps of downsynced objects, and
+// rolls objects back in the workload description space.
+package revisions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	cachediscovery "k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	"github.com/kubestellar/kubestellar/pkg/revisions"
+)
+
+// fieldManager is the field manager of the rollback writes.
+const fieldManager = "kubectl-kubestellar-revisions"
+
+// RevisionsOptions are the options common to the subcommands.
+// The base Options are for the mailbox space of the destination, where
+// the syncer keeps the history; the workload description space, where
+// rollbacks are written, is configured by the --wds-* flags.
+type RevisionsOptions struct {
+	*base.Options
+
+	WDS *clientopts.ClientOpts
+
+	// HistoryNamespace is the namespace of the history, as given to the
+	// syncer's --revision-history-namespace.
+	HistoryNamespace string
+	// Destination restricts `list` to one destination (SyncTarget name).
+	Destination string
+	// Output is the format of the results.
+	Output base.OutputFormat
+
+	store *revisions.Store
+}
+
+// NewRevisionsOptions returns a new RevisionsOptions.
+func NewRevisionsOptions(streams genericclioptions.IOStreams) *RevisionsOptions {
+	return &RevisionsOptions{
+		Options:          base.NewOptions(streams),
+		WDS:              clientopts.NewClientOpts("wds", "access to the workload description space, for rollback"),
+		HistoryNamespace: revisions.DefaultNamespace,
+		Output:           base.OutputTable,
+	}
+}
+
+// BindFlags binds fields of RevisionsOptions as command line flags to cmd's flagset.
+func (o *RevisionsOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	o.WDS.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&o.HistoryNamespace, "history-namespace", o.HistoryNamespace, "Namespace of the history in the mailbox space, as given to the syncer's --revision-history-namespace.")
+	cmd.Flags().StringVar(&o.Destination, "destination", o.Destination, "Restrict the listing to the destination with this SyncTarget name.")
+	base.BindOutputFlag(cmd, &o.Output)
+}
+
+// Validate checks the options.
+func (o *RevisionsOptions) Validate() error {
+	if o.HistoryNamespace == "" {
+		return base.Usagef("--history-namespace must not be empty")
+	}
+	return o.Output.Validate()
+}
+
+// Complete makes the client of the history.
+func (o *RevisionsOptions) Complete() error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	o.store = revisions.Open(client.CoreV1(), o.HistoryNamespace, 0)
+	return nil
+}
+
+// ParseObjectRef parses the arguments DESTINATION KIND[.GROUP] [NAMESPACE/]NAME.
+func ParseObjectRef(args []string) (revisions.ObjectRef, error) {
+	if len(args) != 3 {
+		return revisions.ObjectRef{}, base.Usagef("expected DESTINATION KIND[.GROUP] [NAMESPACE/]NAME but got %d arguments", len(args))
+	}
+	ref := revisions.ObjectRef{Destination: args[0], Name: args[2]}
+	ref.Kind, ref.Group, _ = strings.Cut(args[1], ".")
+	if namespace, name, found := strings.Cut(args[2], "/"); found {
+		ref.Namespace, ref.Name = namespace, name
+	}
+	return ref, nil
+}
+
+// RunList lists the objects that have revisions.
+func (o *RevisionsOptions) RunList(ctx context.Context) error {
+	refs, err := o.store.Refs(ctx)
+	if err != nil {
+		return kserrors.Classify(err)
+	}
+	type listItem struct {
+		revisions.ObjectRef
+		Revisions int              `json:"revisions"`
+		Latest    *revisions.Entry `json:"latest,omitempty"`
+	}
+	items := []listItem{}
+	table := base.Table{Columns: []string{"DESTINATION", "KIND", "NAMESPACE", "NAME", "REVISIONS", "LATEST", "AGE"}}
+	for _, ref := range refs {
+		if o.Destination != "" && ref.Destination != o.Destination {
+			continue
+		}
+		entries, err := o.store.List(ctx, ref)
+		if err != nil {
+			return kserrors.Classify(err)
+		}
+		item := listItem{ObjectRef: ref, Revisions: len(entries)}
+		latest, age := "", ""
+		if len(entries) > 0 {
+			item.Latest = &entries[0]
+			latest, age = shortHash(entries[0].Hash), sinceString(entries[0].Time.Time)
+		}
+		items = append(items, item)
+		table.Rows = append(table.Rows, []string{ref.Destination, kindString(ref), ref.Namespace, ref.Name, strconv.Itoa(len(entries)), latest, age})
+	}
+	return base.PrintObject(o.Out, o.Output, items, table)
+}
+
+// RunHistory lists the revisions of one object, newest first.
+func (o *RevisionsOptions) RunHistory(ctx context.Context, ref revisions.ObjectRef) error {
+	entries, err := o.store.List(ctx, ref)
+	if err != nil {
+		return kserrors.Classify(err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no revisions of %s", ref)
+	}
+	table := base.Table{Columns: []string{"REVISION", "HASH", "RECORDED", "AGE"}}
+	for idx, entry := range entries {
+		table.Rows = append(table.Rows, []string{strconv.Itoa(-idx), shortHash(entry.Hash), entry.Time.UTC().Format(time.RFC3339), sinceString(entry.Time.Time)})
+	}
+	return base.PrintObject(o.Out, o.Output, entries, table)
+}
+
+// RunShow prints the content of the given revision of the given object.
+func (o *RevisionsOptions) RunShow(ctx context.Context, ref revisions.ObjectRef, revision string) error {
+	hash, err := o.resolve(ctx, ref, revision, "0")
+	if err != nil {
+		return err
+	}
+	obj, err := o.store.Get(ctx, ref, hash)
+	if err != nil {
+		return kserrors.Classify(err)
+	}
+	format := o.Output
+	if format == base.OutputTable {
+		format = base.OutputYAML // an object has no table form
+	}
+	return base.PrintObject(o.Out, format, obj.Object, base.Table{})
+}
+
+// RunDiff prints the differences between two revisions of the given object.
+// Empty from and to mean -1 and 0.
+func (o *RevisionsOptions) RunDiff(ctx context.Context, ref revisions.ObjectRef, from, to string) error {
+	fromHash, err := o.resolve(ctx, ref, from, "-1")
+	if err != nil {
+		return err
+	}
+	toHash, err := o.resolve(ctx, ref, to, "0")
+	if err != nil {
+		return err
+	}
+	fromObj, err := o.store.Get(ctx, ref, fromHash)
+	if err != nil {
+		return kserrors.Classify(err)
+	}
+	toObj, err := o.store.Get(ctx, ref, toHash)
+	if err != nil {
+		return kserrors.Classify(err)
+	}
+	diff := revisions.Diff(fromObj, toObj)
+	if diff == "" {
+		fmt.Fprintf(o.Out, "No differences between %s and %s\n", shortHash(fromHash), shortHash(toHash))
+		return nil
+	}
+	fmt.Fprintf(o.Out, "--- %s\n+++ %s\n%s", shortHash(fromHash), shortHash(toHash), diff)
+	return nil
+}
+
+// RunRollback writes the given revision (empty means -1) of the given
+// object to the workload description space, which is the source of
+// truth, so that it flows down to every destination of the object.
+// With dryRun it prints what it would write instead.
+func (o *RevisionsOptions) RunRollback(ctx context.Context, ref revisions.ObjectRef, to string, dryRun bool) error {
+	if !o.WDS.Configured() {
+		return base.Usagef("rollback needs the --wds-kubeconfig or --wds-context of the workload description space")
+	}
+	hash, err := o.resolve(ctx, ref, to, "-1")
+	if err != nil {
+		return err
+	}
+	revision, err := o.store.Get(ctx, ref, hash)
+	if err != nil {
+		return kserrors.Classify(err)
+	}
+	wdsConfig, err := o.WDS.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(wdsConfig)
+	if err != nil {
+		return err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(cachediscovery.NewMemCacheClient(discoveryClient))
+	gvk := revision.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to find the resource of %s in the WDS: %w", gvk, err))
+	}
+	dynamicClient, err := dynamic.NewForConfig(wdsConfig)
+	if err != nil {
+		return err
+	}
+	var client dynamic.ResourceInterface = dynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		client = dynamicClient.Resource(mapping.Resource).Namespace(ref.Namespace)
+	}
+	current, err := client.Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to read %s in the WDS: %w", kindString(ref), err))
+	}
+	updated := revisions.RollbackContent(current, revision)
+	if dryRun {
+		format := o.Output
+		if format == base.OutputTable {
+			format = base.OutputYAML
+		}
+		return base.PrintObject(o.Out, format, updated.Object, base.Table{})
+	}
+	if _, err := client.Update(ctx, updated, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to roll back %s in the WDS: %w", kindString(ref), err))
+	}
+	fmt.Fprintf(o.Out, "Rolled back %s in the WDS to revision %s\n", ref, shortHash(hash))
+	return nil
+}
+
+// resolve returns the hash of the given revision of the given object.
+// A revision is given as a hash (or unique prefix), or as 0 (newest),
+// -1 (the one before), and so on; empty means dflt.
+func (o *RevisionsOptions) resolve(ctx context.Context, ref revisions.ObjectRef, spec, dflt string) (string, error) {
+	if spec == "" {
+		spec = dflt
+	}
+	rel, err := strconv.Atoi(spec)
+	if err != nil || rel > 0 {
+		return spec, nil
+	}
+	entries, err := o.store.List(ctx, ref)
+	if err != nil {
+		return "", kserrors.Classify(err)
+	}
+	if -rel >= len(entries) {
+		return "", fmt.Errorf("%s has only %d revisions", ref, len(entries))
+	}
+	return entries[-rel].Hash, nil
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func kindString(ref revisions.ObjectRef) string {
+	if ref.Group == "" {
+		return ref.Kind
+	}
+	return ref.Kind + "." + ref.Group
+}
+
+func sinceString(when time.Time) string {
+	return time.Since(when).Round(time.Second).String()
+}
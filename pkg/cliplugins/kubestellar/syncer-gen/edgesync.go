@@ -57,6 +57,7 @@ import (
 	"k8s.io/utils/pointer"
 
 	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	"github.com/kubestellar/kubestellar/pkg/credbroker"
 )
 
 //go:embed *.yaml
@@ -98,6 +99,19 @@ type EdgeSyncOptions struct {
 	// Set it short if you know the ServiceAccount Token controller will never do the job.
 	// Set it long if you know the ServiceAccount controller _is_ eventually going to do the job.
 	ServiceAccountTokenWait time.Duration
+	// TokenTTL, if not zero, directs that the syncer use a short-lived token of this lifetime
+	// and keep renewing it, instead of using a long-lived token.
+	TokenTTL time.Duration
+	// LowMemory directs that the syncer run in its reduced-footprint mode,
+	// for WECs with little memory.
+	LowMemory bool
+
+	// syncerID is the ID of the syncer made by the last GenerateManifest.
+	syncerID string
+
+	// tokenExpiry is when the short-lived token in the manifest made by
+	// the last GenerateManifest expires; zero if not using TokenTTL.
+	tokenExpiry time.Time
 }
 
 // NewSyncOptions returns a new EdgeSyncOptions.
@@ -128,6 +142,8 @@ func (o *EdgeSyncOptions) BindFlags(cmd *cobra.Command) {
 	cmd.Flags().StringSliceVar(&o.SyncTargetLabels, "labels", o.SyncTargetLabels, "Labels to apply on the SyncTarget created in kcp, each label should be in the format of key=value.")
 	cmd.Flags().DurationVar(&o.Lifetime, "lifetime", o.Lifetime, "Lifetime is the requested token lifetime. Optional. (default: 87600h (10 years)).")
 	cmd.Flags().DurationVar(&o.ServiceAccountTokenWait, "service-account-token-wait", o.ServiceAccountTokenWait, "Time to wait for the ServiceAccount Token controller to create a token Secret (default: 20 sec)")
+	cmd.Flags().DurationVar(&o.TokenTTL, "token-ttl", o.TokenTTL, "If not zero, the syncer uses a short-lived token of this lifetime and keeps renewing it, instead of a long-lived token.")
+	cmd.Flags().BoolVar(&o.LowMemory, "low-memory", o.LowMemory, "Run the syncer in its reduced-footprint mode, for WECs with little memory.")
 }
 
 // Complete ensures all dynamically populated fields are initialized.
@@ -169,6 +185,10 @@ func (o *EdgeSyncOptions) Validate() error {
 		errs = append(errs, errors.New("--output-file is required"))
 	}
 
+	if o.TokenTTL != 0 && o.TokenTTL < credbroker.MinimumTTL {
+		errs = append(errs, fmt.Errorf("--token-ttl must be zero or at least %v", credbroker.MinimumTTL))
+	}
+
 	for _, l := range o.SyncTargetLabels {
 		if len(strings.Split(l, "=")) != 2 {
 			errs = append(errs, fmt.Errorf("label '%s' is not in the format of key=value", l))
@@ -197,6 +217,24 @@ func (o *EdgeSyncOptions) Run(ctx context.Context) error {
 		defer outputFile.Close()
 	}
 
+	resources, err := o.GenerateManifest(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	_, err = outputFile.Write(resources)
+	if o.OutputFile != "-" {
+		fmt.Fprintf(o.ErrOut, "\nWrote workload execution cluster manifest to %s for namespace %q. Use\n\n  KUBECONFIG=<wec-config> kubectl apply -f %q\n\nto apply it. "+
+			"Use\n\n  KUBECONFIG=<wec-config> kubectl get deployment -n %q %s\n\nto verify the syncer pod is running.\n", o.OutputFile, o.DownstreamNamespace, o.OutputFile, o.DownstreamNamespace, o.syncerID)
+	}
+	return err
+}
+
+// GenerateManifest prepares the mailbox space that the given config
+// points to for use with a syncer, and returns the manifest to apply
+// to the WEC to deploy the syncer there.
+// Each call makes a new syncer identity, with its own credentials.
+func (o *EdgeSyncOptions) GenerateManifest(ctx context.Context, config *rest.Config) ([]byte, error) {
 	labels := map[string]string{}
 	for _, l := range o.SyncTargetLabels {
 		parts := strings.Split(l, "=")
@@ -208,12 +246,23 @@ func (o *EdgeSyncOptions) Run(ctx context.Context) error {
 
 	token, syncerID, edgeSyncTarget, err := o.enableSyncerForWorkspace(ctx, config, o.SyncTargetName, o.KCPNamespace, labels)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if o.TokenTTL != 0 {
+		kubeClient, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+		}
+		token, o.tokenExpiry, err = credbroker.MintToken(ctx, kubeClient.CoreV1(), o.KCPNamespace, syncerID, o.TokenTTL, fieldManager)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	configURL, err := parseApiServerURL(config.Host)
 	if err != nil {
-		return fmt.Errorf("current URL %q does not point to workspace", config.Host)
+		return nil, fmt.Errorf("current URL %q does not point to workspace", config.Host)
 	}
 
 	// Make sure the generated URL has the port specified correctly.
@@ -227,7 +276,7 @@ func (o *EdgeSyncOptions) Run(ctx context.Context) error {
 				configURL.Host = net.JoinHostPort(configURL.Host, "80")
 			}
 		} else {
-			return fmt.Errorf("failed to parse host %q: %w", configURL.Host, err)
+			return nil, fmt.Errorf("failed to parse host %q: %w", configURL.Host, err)
 		}
 	}
 
@@ -255,23 +304,23 @@ func (o *EdgeSyncOptions) Run(ctx context.Context) error {
 		Replicas: o.Replicas,
 		QPS:      o.QPS,
 		Burst:    o.Burst,
-	}
+		TokenTTL: o.TokenTTL,
 
-	resources, err := renderKubeStellarSyncerResources(input, syncerID)
-	if err != nil {
-		return err
+		LowMemory: o.LowMemory,
 	}
 
-	_, err = outputFile.Write(resources)
-	if o.OutputFile != "-" {
-		fmt.Fprintf(o.ErrOut, "\nWrote workload execution cluster manifest to %s for namespace %q. Use\n\n  KUBECONFIG=<wec-config> kubectl apply -f %q\n\nto apply it. "+
-			"Use\n\n  KUBECONFIG=<wec-config> kubectl get deployment -n %q %s\n\nto verify the syncer pod is running.\n", o.OutputFile, o.DownstreamNamespace, o.OutputFile, o.DownstreamNamespace, syncerID)
-	}
-	return err
+	o.syncerID = syncerID
+	return renderKubeStellarSyncerResources(input, syncerID)
 }
 
 // getKubeStellarSyncerID returns a unique ID for a syncer derived from the name and its UID. It's
 // a valid DNS segment and can be used as namespace or object names.
+// TokenExpiry returns the expiration time of the short-lived token in the
+// manifest made by the last GenerateManifest; zero if there is none.
+func (o *EdgeSyncOptions) TokenExpiry() time.Time {
+	return o.tokenExpiry
+}
+
 func getKubeStellarSyncerID(edgeSyncTarget *typeEdgeSyncTarget) string {
 	syncerHash := sha256.Sum224([]byte(edgeSyncTarget.UID))
 	base36hash := strings.ToLower(base36.EncodeBytes(syncerHash[:]))
@@ -613,6 +662,11 @@ type templateInputForEdge struct {
 	QPS float32
 	// Burst is the burst the syncer uses when talking to an apiserver.
 	Burst int
+	// TokenTTL, if not zero, is the lifetime of each short-lived token
+	// that the syncer keeps renewing.
+	TokenTTL time.Duration
+	// LowMemory is whether the syncer runs in its reduced-footprint mode.
+	LowMemory bool
 }
 
 // templateArgsForEdge represents the full set of arguments required to render the resources
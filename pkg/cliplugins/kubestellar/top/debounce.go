@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package top
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+)
+
+// FleetDebouncer holds back flapping changes in successive summaries:
+// a placement or destination has to stay in a new health state, and a
+// placement condition in a new status, for the minimum duration before
+// the change shows. Until then the previous state shows, and so do the
+// failures that go with it; a failure appears only once the health of
+// its placement or destination does.
+//
+// A change is noticed only when a summary containing it is given to
+// Apply, so the summaries should be made on every change of the
+// underlying objects and periodically, not just when someone looks.
+type FleetDebouncer struct {
+	health *conditions.Debouncer[debouncedHealth]
+	conds  *conditions.ConditionDebouncer
+}
+
+// debouncedHealth is what gets debounced for a placement or destination.
+type debouncedHealth struct {
+	health Health
+	reason string
+}
+
+// NewFleetDebouncer makes a FleetDebouncer with the given minimum
+// duration; zero lets every change show immediately.
+func NewFleetDebouncer(minDuration time.Duration) *FleetDebouncer {
+	return &FleetDebouncer{
+		health: conditions.NewDebouncer[debouncedHealth](minDuration),
+		conds:  conditions.NewConditionDebouncer(minDuration),
+	}
+}
+
+// SetMinDuration changes the minimum duration. May be called at any time.
+func (fd *FleetDebouncer) SetMinDuration(minDuration time.Duration) {
+	fd.health.SetMinDuration(minDuration)
+	fd.conds.SetMinDuration(minDuration)
+}
+
+// Apply records the given summary and replaces its health, conditions
+// and failures with the ones to show.
+func (fd *FleetDebouncer) Apply(fleet *FleetSummary) {
+	now := fleet.Time.Time
+	current := sets.NewString()
+	unhealthy := sets.NewString() // Failure.Kind + "/" + Failure.Name
+	for idx := range fleet.Placements {
+		ps := &fleet.Placements[idx]
+		key := "EdgePlacement/" + ps.Name
+		current.Insert(key)
+		ps.Health = fd.health.Filter(key, debouncedHealth{health: ps.Health}, now).health
+		ps.Conditions = fd.conds.FilterAll(key, ps.Conditions, now)
+		if ps.Health == HealthDegraded {
+			unhealthy.Insert(key)
+		}
+	}
+	for idx := range fleet.Destinations {
+		ds := &fleet.Destinations[idx]
+		key := "SyncTarget/" + ds.Key()
+		current.Insert(key)
+		shown := fd.health.Filter(key, debouncedHealth{health: ds.Health, reason: ds.Reason}, now)
+		ds.Health, ds.Reason = shown.health, shown.reason
+		if ds.Health != HealthStale {
+			ds.LastKnownHealth, ds.StaleFor = "", nil
+		}
+		if ds.Health != HealthReady {
+			unhealthy.Insert("SyncTarget/" + ds.SyncTargetName)
+		}
+	}
+	// Keep the per-placement stale counts consistent with the destination health shown.
+	for idx := range fleet.Placements {
+		ps := &fleet.Placements[idx]
+		ps.StaleDestinations = 0
+		for _, dest := range ps.Destinations {
+			if ds := fleet.FindDestinationOf(dest.Destination()); ds != nil && ds.Health == HealthStale {
+				ps.StaleDestinations++
+			}
+		}
+	}
+	failures := make([]Failure, 0, len(fleet.Failures))
+	for _, failure := range fleet.Failures {
+		if unhealthy.Has(failure.Kind + "/" + failure.Name) {
+			failures = append(failures, failure)
+		}
+	}
+	fleet.Failures = failures
+	fd.health.Retain(current.Has)
+	fd.conds.Retain(current.Has)
+}
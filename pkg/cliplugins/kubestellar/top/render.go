@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package top
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+)
+
+// RenderOverview writes the fleet-wide view: counts, the placements,
+// the unhealthy destinations, and the most recent failures.
+func RenderOverview(out io.Writer, fs *FleetSummary, maxRows int) error {
+	var placementsReady, destinationsReady, destinationsStale int
+	for _, ps := range fs.Placements {
+		if ps.Health == HealthReady {
+			placementsReady++
+		}
+	}
+	var unhealthy []DestinationSummary
+	for _, ds := range fs.Destinations {
+		if ds.Health == HealthReady {
+			destinationsReady++
+		} else {
+			unhealthy = append(unhealthy, ds)
+		}
+		if ds.Health == HealthStale {
+			destinationsStale++
+		}
+	}
+	fmt.Fprintf(out, "Placements: %d/%d ready   Destinations: %d/%d ready, %d stale   Failures: %d\n\n",
+		placementsReady, len(fs.Placements), destinationsReady, len(fs.Destinations), destinationsStale, len(fs.Failures))
+
+	placements := base.Table{Columns: []string{"PLACEMENT", "HEALTH", "LOCATIONS", "DESTINATIONS", "UNHEALTHY", "STALE", "CONVERGENCE"}}
+	for _, ps := range limit(fs.Placements, maxRows) {
+		var bad int
+		for _, dest := range ps.Destinations {
+			if ds := fs.FindDestinationOf(dest.Destination()); ds != nil && ds.Health != HealthReady {
+				bad++
+			}
+		}
+		placements.Rows = append(placements.Rows, []string{ps.Name, string(ps.Health),
+			strconv.Itoa(int(ps.MatchingLocationCount)), strconv.Itoa(len(ps.Destinations)), strconv.Itoa(bad), strconv.Itoa(ps.StaleDestinations), duration(ps.LastConvergence)})
+	}
+	if err := base.PrintTable(out, placements); err != nil {
+		return err
+	}
+	elided(out, len(fs.Placements), maxRows)
+	if len(unhealthy) > 0 {
+		fmt.Fprintln(out)
+		if err := base.PrintTable(out, destinationsTable(limit(unhealthy, maxRows), fs.Time)); err != nil {
+			return err
+		}
+		elided(out, len(unhealthy), maxRows)
+	}
+	if len(fs.Failures) > 0 {
+		fmt.Fprintln(out)
+		return RenderFailures(out, fs, maxRows)
+	}
+	return nil
+}
+
+// RenderFailures writes the most recent failures.
+func RenderFailures(out io.Writer, fs *FleetSummary, maxRows int) error {
+	table := base.Table{Columns: []string{"AGE", "KIND", "NAME", "REASON", "MESSAGE"}}
+	for _, failure := range limit(fs.Failures, maxRows) {
+		table.Rows = append(table.Rows, []string{age(&failure.Time, fs.Time), failure.Kind, failure.Name, failure.Reason, oneLine(failure.Message)})
+	}
+	if err := base.PrintTable(out, table); err != nil {
+		return err
+	}
+	elided(out, len(fs.Failures), maxRows)
+	return nil
+}
+
+// RenderPlacement writes the detailed view of one placement.
+func RenderPlacement(out io.Writer, fs *FleetSummary, name string) error {
+	ps := fs.FindPlacement(name)
+	if ps == nil {
+		_, err := fmt.Fprintf(out, "No EdgePlacement named %q\n", name)
+		return err
+	}
+	fmt.Fprintf(out, "EdgePlacement %s: %s, %d matching Locations, last convergence %s\n\n",
+		ps.Name, ps.Health, ps.MatchingLocationCount, duration(ps.LastConvergence))
+	if len(ps.Conditions) > 0 {
+		conds := base.Table{Columns: []string{"CONDITION", "STATUS", "AGE", "REASON", "MESSAGE"}}
+		for _, cond := range ps.Conditions {
+			conds.Rows = append(conds.Rows, []string{cond.Type, string(cond.Status), age(&cond.LastTransitionTime, fs.Time), cond.Reason, oneLine(cond.Message)})
+		}
+		if err := base.PrintTable(out, conds); err != nil {
+			return err
+		}
+		fmt.Fprintln(out)
+	}
+	var dests []DestinationSummary
+	for _, dest := range ps.Destinations {
+		if ds := fs.FindDestinationOf(dest.Destination()); ds != nil {
+			dests = append(dests, *ds)
+		}
+	}
+	return base.PrintTable(out, destinationsTable(dests, fs.Time))
+}
+
+// RenderDestination writes the detailed view of one destination.
+func RenderDestination(out io.Writer, fs *FleetSummary, syncTargetName string) error {
+	ds := fs.FindDestination(syncTargetName)
+	if ds == nil {
+		_, err := fmt.Fprintf(out, "No destination with SyncTarget named %q\n", syncTargetName)
+		return err
+	}
+	reason := ""
+	if ds.Reason != "" {
+		reason = " (" + ds.Reason + ")"
+	}
+	fmt.Fprintf(out, "SyncTarget %s: %s%s\n", ds.SyncTargetName, ds.Health, reason)
+	fmt.Fprintf(out, "  Location:           %s\n", ds.LocationName)
+	fmt.Fprintf(out, "  Kubernetes version: %s\n", ds.KubernetesVersion)
+	fmt.Fprintf(out, "  Last heartbeat:     %s ago\n", age(ds.LastHeartbeat, fs.Time))
+	if ds.Health == HealthStale {
+		fmt.Fprintf(out, "  Last known health:  %s\n", ds.LastKnownHealth)
+	}
+	fmt.Fprintln(out)
+	placements := base.Table{Columns: []string{"PLACEMENT", "HEALTH"}}
+	for _, name := range ds.Placements {
+		health := ""
+		if ps := fs.FindPlacement(name); ps != nil {
+			health = string(ps.Health)
+		}
+		placements.Rows = append(placements.Rows, []string{name, health})
+	}
+	if err := base.PrintTable(out, placements); err != nil {
+		return err
+	}
+	var failures []Failure
+	for _, failure := range fs.Failures {
+		if failure.Kind == "SyncTarget" && failure.Name == ds.SyncTargetName {
+			failures = append(failures, failure)
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+	return RenderFailures(out, &FleetSummary{Time: fs.Time, Failures: failures}, -1)
+}
+
+func destinationsTable(dests []DestinationSummary, now metav1.Time) base.Table {
+	table := base.Table{Columns: []string{"SYNCTARGET", "LOCATION", "HEALTH", "REASON", "HEARTBEAT", "PLACEMENTS"}}
+	for _, ds := range dests {
+		table.Rows = append(table.Rows, []string{ds.SyncTargetName, ds.LocationName, string(ds.Health), ds.Reason,
+			age(ds.LastHeartbeat, now), strconv.Itoa(len(ds.Placements))})
+	}
+	return table
+}
+
+// limit returns at most max of the given items; a negative max means no limit.
+func limit[Elt any](items []Elt, max int) []Elt {
+	if max >= 0 && len(items) > max {
+		return items[:max]
+	}
+	return items
+}
+
+func elided(out io.Writer, total, max int) {
+	if max >= 0 && total > max {
+		fmt.Fprintf(out, "... and %d more\n", total-max)
+	}
+}
+
+func age(then *metav1.Time, now metav1.Time) string {
+	if then == nil || then.IsZero() {
+		return "<none>"
+	}
+	return now.Sub(then.Time).Truncate(time.Second).String()
+}
+
+func duration(dur *metav1.Duration) string {
+	if dur == nil {
+		return "<none>"
+	}
+	return dur.Duration.Truncate(time.Millisecond).String()
+}
+
+func oneLine(message string) string {
+	return strings.ReplaceAll(message, "\n", " ")
+}
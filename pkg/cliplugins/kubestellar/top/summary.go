@@ -0,0 +1,259 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package top summarizes the state of a fleet, as seen from a workload
+// description space and an inventory space, for the `kubectl kubestellar top`
+// command.
+package top
+
+import (
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+	"github.com/kubestellar/kubestellar/pkg/destination"
+)
+
+// Health is the overall state of a placement or destination.
+type Health string
+
+const (
+	// HealthReady means nothing is known to be wrong.
+	HealthReady Health = "Ready"
+
+	// HealthDegraded means that some condition is not True.
+	HealthDegraded Health = "Degraded"
+
+	// HealthNoDestinations is for a placement that currently selects no SyncTarget.
+	HealthNoDestinations Health = "NoDestinations"
+
+	// HealthStale is for a destination whose syncer has not sent a heartbeat recently.
+	// The destination's last reported health is kept in LastKnownHealth.
+	HealthStale Health = "Stale"
+
+	// HealthMissing is for a destination whose SyncTarget is not in the inventory.
+	HealthMissing Health = "Missing"
+)
+
+// FleetSummary is a point-in-time summary of a fleet.
+type FleetSummary struct {
+	Time         metav1.Time          `json:"time"`
+	Placements   []PlacementSummary   `json:"placements"`
+	Destinations []DestinationSummary `json:"destinations"`
+
+	// Failures are the recent problems, most recent first.
+	Failures []Failure `json:"failures"`
+}
+
+// PlacementSummary summarizes one EdgePlacement.
+type PlacementSummary struct {
+	Name                  string             `json:"name"`
+	Health                Health             `json:"health"`
+	MatchingLocationCount int32              `json:"matchingLocationCount"`
+	LastConvergence       *metav1.Duration   `json:"lastConvergence,omitempty"`
+	Conditions            []metav1.Condition `json:"conditions,omitempty"`
+	Destinations          []DestinationRef   `json:"destinations"`
+
+	// StaleDestinations is the number of Destinations that are Stale.
+	StaleDestinations int `json:"staleDestinations"`
+}
+
+// DestinationRef identifies a destination of a placement.
+type DestinationRef struct {
+	ClusterID      string    `json:"clusterID,omitempty"`
+	LocationName   string    `json:"locationName"`
+	SyncTargetName string    `json:"syncTargetName"`
+	SyncTargetUID  types.UID `json:"syncTargetUID"`
+}
+
+// DestinationSummary summarizes one SyncTarget that is the destination of
+// some placement, or is in the inventory.
+type DestinationSummary struct {
+	// ClusterID is the ID of the inventory space of the SyncTarget,
+	// when known from the placement decisions that refer to it.
+	ClusterID         string       `json:"clusterID,omitempty"`
+	SyncTargetName    string       `json:"syncTargetName"`
+	LocationName      string       `json:"locationName,omitempty"`
+	Health            Health       `json:"health"`
+	Reason            string       `json:"reason,omitempty"`
+	LastHeartbeat     *metav1.Time `json:"lastHeartbeat,omitempty"`
+	KubernetesVersion string       `json:"kubernetesVersion,omitempty"`
+	Placements        []string     `json:"placements"`
+
+	// LastKnownHealth, for a Stale destination, is the health that was
+	// last reported for it (Ready or Degraded), retained while the WEC is
+	// disconnected rather than dropped from the aggregates.
+	LastKnownHealth Health `json:"lastKnownHealth,omitempty"`
+
+	// StaleFor, for a Stale destination that has ever sent a heartbeat,
+	// is how long ago that last heartbeat was.
+	StaleFor *metav1.Duration `json:"staleFor,omitempty"`
+}
+
+// Failure is a problem with a placement or destination.
+type Failure struct {
+	Time    metav1.Time `json:"time"`
+	Kind    string      `json:"kind"`
+	Name    string      `json:"name"`
+	Reason  string      `json:"reason"`
+	Message string      `json:"message,omitempty"`
+}
+
+// Summarize computes the summary of the given objects.
+// A destination whose last syncer heartbeat is older than heartbeatTimeout
+// is Stale, whatever its last reported health; zero disables that test.
+func Summarize(placements []edgev2alpha1.EdgePlacement, slices []edgev2alpha1.SinglePlacementSlice,
+	syncTargets []edgev2alpha1.SyncTarget, now time.Time, heartbeatTimeout time.Duration) *FleetSummary {
+	ans := &FleetSummary{Time: metav1.NewTime(now)}
+	destsByPlacement := map[string][]DestinationRef{}
+	for _, sps := range slices {
+		epName := owningPlacementName(&sps)
+		for _, dest := range sps.Destinations {
+			destsByPlacement[epName] = append(destsByPlacement[epName],
+				DestinationRef{ClusterID: dest.Cluster, LocationName: dest.LocationName, SyncTargetName: dest.SyncTargetName, SyncTargetUID: dest.SyncTargetUID})
+		}
+	}
+	destinations := map[types.UID]*DestinationSummary{}
+	for idx := range syncTargets {
+		st := &syncTargets[idx]
+		ds := &DestinationSummary{SyncTargetName: st.Name, Health: HealthReady, LastHeartbeat: st.Status.LastSyncerHeartbeatTime,
+			KubernetesVersion: st.Status.KubernetesVersion, Placements: []string{}}
+		var failureTime metav1.Time
+		for _, cond := range st.Status.Conditions {
+			if cond.Type == conditions.ReadyType && cond.Status != "True" {
+				ds.Health, ds.Reason = HealthDegraded, cond.Reason
+				failureTime = cond.LastTransitionTime
+				ans.Failures = append(ans.Failures, Failure{Time: failureTime, Kind: "SyncTarget", Name: st.Name, Reason: cond.Reason, Message: cond.Message})
+			}
+		}
+		if heartbeatTimeout > 0 && (ds.LastHeartbeat == nil || now.Sub(ds.LastHeartbeat.Time) > heartbeatTimeout) {
+			ds.LastKnownHealth = ds.Health
+			ds.Health, ds.Reason = HealthStale, "HeartbeatOverdue"
+			if ds.LastHeartbeat != nil {
+				failureTime = metav1.NewTime(ds.LastHeartbeat.Add(heartbeatTimeout))
+				ds.StaleFor = &metav1.Duration{Duration: now.Sub(ds.LastHeartbeat.Time)}
+			}
+			ans.Failures = append(ans.Failures, Failure{Time: failureTime, Kind: "SyncTarget", Name: st.Name, Reason: ds.Reason})
+		}
+		destinations[st.UID] = ds
+	}
+	for _, ep := range placements {
+		ps := PlacementSummary{Name: ep.Name, Health: HealthReady, MatchingLocationCount: ep.Status.MatchingLocationCount,
+			LastConvergence: ep.Status.LastConvergenceDuration, Conditions: ep.Status.Conditions, Destinations: destsByPlacement[ep.Name]}
+		if ps.Destinations == nil {
+			ps.Destinations = []DestinationRef{}
+		}
+		sort.Slice(ps.Destinations, func(i, j int) bool {
+			return ps.Destinations[i].Destination().Less(ps.Destinations[j].Destination())
+		})
+		for _, cond := range ep.Status.Conditions {
+			if cond.Status != metav1.ConditionTrue {
+				ps.Health = HealthDegraded
+				ans.Failures = append(ans.Failures, Failure{Time: cond.LastTransitionTime, Kind: "EdgePlacement", Name: ep.Name, Reason: cond.Reason, Message: cond.Message})
+			}
+		}
+		if ps.Health == HealthReady && len(ps.Destinations) == 0 {
+			ps.Health = HealthNoDestinations
+		}
+		for _, dest := range ps.Destinations {
+			ds := destinations[dest.SyncTargetUID]
+			if ds == nil {
+				ds = &DestinationSummary{ClusterID: dest.ClusterID, SyncTargetName: dest.SyncTargetName, Health: HealthMissing, Reason: "NotInInventory", Placements: []string{}}
+				destinations[dest.SyncTargetUID] = ds
+				ans.Failures = append(ans.Failures, Failure{Time: ans.Time, Kind: "SyncTarget", Name: dest.SyncTargetName, Reason: ds.Reason})
+			}
+			ds.ClusterID = dest.ClusterID
+			ds.LocationName = dest.LocationName
+			ds.Placements = append(ds.Placements, ep.Name)
+			if ds.Health == HealthStale {
+				ps.StaleDestinations++
+			}
+		}
+		ans.Placements = append(ans.Placements, ps)
+	}
+	for _, ds := range destinations {
+		sort.Strings(ds.Placements)
+		ans.Destinations = append(ans.Destinations, *ds)
+	}
+	sort.Slice(ans.Placements, func(i, j int) bool { return ans.Placements[i].Name < ans.Placements[j].Name })
+	sort.Slice(ans.Destinations, func(i, j int) bool { return ans.Destinations[i].Key() < ans.Destinations[j].Key() })
+	sort.SliceStable(ans.Failures, func(i, j int) bool { return ans.Failures[j].Time.Before(&ans.Failures[i].Time) })
+	if ans.Failures == nil {
+		ans.Failures = []Failure{}
+	}
+	return ans
+}
+
+// FindPlacement returns the summary of the named placement, or nil.
+func (fs *FleetSummary) FindPlacement(name string) *PlacementSummary {
+	for idx := range fs.Placements {
+		if fs.Placements[idx].Name == name {
+			return &fs.Placements[idx]
+		}
+	}
+	return nil
+}
+
+// FindDestination returns the summary of the named SyncTarget, or nil.
+func (fs *FleetSummary) FindDestination(syncTargetName string) *DestinationSummary {
+	for idx := range fs.Destinations {
+		if fs.Destinations[idx].SyncTargetName == syncTargetName {
+			return &fs.Destinations[idx]
+		}
+	}
+	return nil
+}
+
+// FindDestinationOf returns the summary of the given destination, or nil.
+func (fs *FleetSummary) FindDestinationOf(dest destination.Destination) *DestinationSummary {
+	for idx := range fs.Destinations {
+		if fs.Destinations[idx].Destination().Equal(dest) {
+			return &fs.Destinations[idx]
+		}
+	}
+	return nil
+}
+
+// Destination returns the identity of the referenced destination.
+func (dr DestinationRef) Destination() destination.Destination {
+	return destination.New(dr.ClusterID, dr.SyncTargetName)
+}
+
+// Destination returns the identity of the summarized destination.
+func (ds *DestinationSummary) Destination() destination.Destination {
+	return destination.New(ds.ClusterID, ds.SyncTargetName)
+}
+
+// Key returns the key of the summarized destination; the destinations
+// in a FleetSummary are in increasing order of it.
+func (ds *DestinationSummary) Key() string {
+	return ds.Destination().Key()
+}
+
+// owningPlacementName returns the name of the EdgePlacement that the
+// given slice belongs to.
+func owningPlacementName(sps *edgev2alpha1.SinglePlacementSlice) string {
+	for _, owner := range sps.OwnerReferences {
+		if owner.Kind == "EdgePlacement" && owner.APIVersion == edgev2alpha1.SchemeGroupVersion.String() {
+			return owner.Name
+		}
+	}
+	return sps.Name
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package top
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	recent := metav1.NewTime(now.Add(-30 * time.Second))
+	old := metav1.NewTime(now.Add(-time.Hour))
+	placements := []edgev2alpha1.EdgePlacement{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ep1"}, Status: edgev2alpha1.EdgePlacementStatus{MatchingLocationCount: 2}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ep2"}, Status: edgev2alpha1.EdgePlacementStatus{
+			Conditions: []metav1.Condition{{Type: edgev2alpha1.EdgePlacementRequirementsSatisfied, Status: metav1.ConditionFalse,
+				Reason: "SomeExcluded", LastTransitionTime: recent}}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ep3"}},
+	}
+	slices := []edgev2alpha1.SinglePlacementSlice{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ep1"}, Destinations: []edgev2alpha1.SinglePlacement{
+			{LocationName: "loc1", SyncTargetName: "st1", SyncTargetUID: "uid1"},
+			{LocationName: "loc2", SyncTargetName: "st2", SyncTargetUID: "uid2"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ep2"}, Destinations: []edgev2alpha1.SinglePlacement{
+			{LocationName: "loc3", SyncTargetName: "st3", SyncTargetUID: "uid3"},
+		}},
+	}
+	syncTargets := []edgev2alpha1.SyncTarget{
+		{ObjectMeta: metav1.ObjectMeta{Name: "st1", UID: "uid1"}, Status: edgev2alpha1.SyncTargetStatus{LastSyncerHeartbeatTime: &recent}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "st2", UID: "uid2"}, Status: edgev2alpha1.SyncTargetStatus{LastSyncerHeartbeatTime: &old}},
+	}
+	summary := Summarize(placements, slices, syncTargets, now, 2*time.Minute)
+
+	expectedPlacements := map[string]Health{"ep1": HealthReady, "ep2": HealthDegraded, "ep3": HealthNoDestinations}
+	for name, expected := range expectedPlacements {
+		if ps := summary.FindPlacement(name); ps == nil || ps.Health != expected {
+			t.Errorf("placement %s: expected health %s, got %#v", name, expected, ps)
+		}
+	}
+	expectedDestinations := map[string]Health{"st1": HealthReady, "st2": HealthStale, "st3": HealthMissing}
+	for name, expected := range expectedDestinations {
+		if ds := summary.FindDestination(name); ds == nil || ds.Health != expected {
+			t.Errorf("destination %s: expected health %s, got %#v", name, expected, ds)
+		}
+	}
+	if ds := summary.FindDestination("st2"); ds == nil || ds.LastKnownHealth != HealthReady || ds.StaleFor == nil || ds.StaleFor.Duration != time.Hour {
+		t.Errorf("destination st2: expected last known health Ready, stale for 1h, got %#v", ds)
+	}
+	if ps := summary.FindPlacement("ep1"); ps == nil || ps.StaleDestinations != 1 {
+		t.Errorf("placement ep1: expected 1 stale destination, got %#v", ps)
+	}
+	if ds := summary.FindDestination("st1"); ds != nil && (ds.LocationName != "loc1" || len(ds.Placements) != 1 || ds.Placements[0] != "ep1") {
+		t.Errorf("destination st1: unexpected %#v", ds)
+	}
+	if len(summary.Failures) != 3 {
+		t.Fatalf("expected 3 failures, got %#v", summary.Failures)
+	}
+	for idx := 1; idx < len(summary.Failures); idx++ {
+		if summary.Failures[idx-1].Time.Before(&summary.Failures[idx].Time) {
+			t.Errorf("failures not most recent first: %#v", summary.Failures)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := RenderOverview(&buf, summary, -1); err != nil {
+		t.Fatalf("RenderOverview failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Placements: 1/3 ready   Destinations: 1/3 ready, 1 stale   Failures: 3\n") {
+		t.Errorf("unexpected overview:\n%s", buf.String())
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	start := view{kind: "overview"}
+	for _, tc := range []struct {
+		command  string
+		expected view
+		quit     bool
+	}{
+		{"", start, false},
+		{"p ep1", view{kind: "placement", name: "ep1"}, false},
+		{"d st1", view{kind: "destination", name: "st1"}, false},
+		{"f", view{kind: "failures"}, false},
+		{"p", start, false},
+		{"x", start, false},
+		{"q", start, true},
+	} {
+		actual, quit := parseCommand(tc.command, start)
+		if actual != tc.expected || quit != tc.quit {
+			t.Errorf("command %q: expected %v, %v; got %v, %v", tc.command, tc.expected, tc.quit, actual, quit)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package top
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+)
+
+// TopOptions contains options for the `top` command.
+// The base Options are for the workload description space; the
+// inventory space is configured by the --inventory-* flags and
+// defaults to the same kubeconfig and context.
+type TopOptions struct {
+	*base.Options
+
+	Inventory *clientopts.ClientOpts
+
+	// Interval is the time between refreshes of the interactive display.
+	Interval time.Duration
+	// HeartbeatTimeout is how old a syncer heartbeat may be before its destination is Stale.
+	HeartbeatTimeout time.Duration
+	// HealthMinDuration is how long a change in health has to last
+	// before the interactive display shows it; see FleetDebouncer.
+	HealthMinDuration time.Duration
+	// MaxRows limits the length of each list in the interactive display.
+	MaxRows int
+	// Once prints the summary once, in the Output format, instead of running interactively.
+	Once bool
+	// Output is the format used with Once.
+	Output base.OutputFormat
+
+	wdsClient       edgeclientset.Interface
+	inventoryClient edgeclientset.Interface
+}
+
+// NewTopOptions returns a new TopOptions.
+func NewTopOptions(streams genericclioptions.IOStreams) *TopOptions {
+	return &TopOptions{
+		Options:          base.NewOptions(streams),
+		Inventory:        clientopts.NewClientOpts("inventory", "access to the inventory space (default is the workload description space)"),
+		Interval:         5 * time.Second,
+		HeartbeatTimeout: 2 * time.Minute,
+		MaxRows:          20,
+		Output:           base.OutputTable,
+	}
+}
+
+// BindFlags binds fields TopOptions as command line flags to cmd's flagset.
+func (o *TopOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	o.Inventory.AddFlags(cmd.Flags())
+	cmd.Flags().DurationVar(&o.Interval, "interval", o.Interval, "Time between refreshes of the interactive display.")
+	cmd.Flags().DurationVar(&o.HeartbeatTimeout, "heartbeat-timeout", o.HeartbeatTimeout, "Age of the last syncer heartbeat beyond which a destination is Stale; zero disables this test.")
+	cmd.Flags().DurationVar(&o.HealthMinDuration, "health-min-duration", o.HealthMinDuration, "How long a placement or destination has to stay in a new health state before the interactive display shows the change; zero shows every change immediately.")
+	cmd.Flags().IntVar(&o.MaxRows, "max-rows", o.MaxRows, "Maximum length of each list in the interactive display; negative means no limit.")
+	cmd.Flags().BoolVar(&o.Once, "once", o.Once, "Print the summary once, in the --output format, instead of running interactively.")
+	base.BindOutputFlag(cmd, &o.Output)
+}
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *TopOptions) Complete() error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+	wdsConfig, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	o.wdsClient, err = edgeclientset.NewForConfig(wdsConfig)
+	if err != nil {
+		return err
+	}
+	o.inventoryClient = o.wdsClient
+	if o.Inventory.Configured() {
+		inventoryConfig, err := o.Inventory.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		o.inventoryClient, err = edgeclientset.NewForConfig(inventoryConfig)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates the TopOptions are complete and usable.
+func (o *TopOptions) Validate() error {
+	var errs []error
+	if err := o.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.Output.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if o.Interval <= 0 {
+		errs = append(errs, errors.New("--interval must be positive"))
+	}
+	if o.HeartbeatTimeout < 0 {
+		errs = append(errs, errors.New("--heartbeat-timeout must not be negative"))
+	}
+	if o.HealthMinDuration < 0 {
+		errs = append(errs, errors.New("--health-min-duration must not be negative"))
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return &base.UsageError{Message: err.Error()}
+	}
+	return nil
+}
+
+// Summarize reads the current state of the fleet and summarizes it.
+func (o *TopOptions) Summarize(ctx context.Context) (*FleetSummary, error) {
+	placements, err := o.wdsClient.EdgeV2alpha1().EdgePlacements().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, kserrors.Classify(fmt.Errorf("failed to list EdgePlacements: %w", err))
+	}
+	slices, err := o.wdsClient.EdgeV2alpha1().SinglePlacementSlices().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, kserrors.Classify(fmt.Errorf("failed to list SinglePlacementSlices: %w", err))
+	}
+	syncTargets, err := o.inventoryClient.EdgeV2alpha1().SyncTargets().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, kserrors.Classify(fmt.Errorf("failed to list SyncTargets: %w", err))
+	}
+	return Summarize(placements.Items, slices.Items, syncTargets.Items, time.Now(), o.HeartbeatTimeout), nil
+}
+
+// Run prints the summary once or runs the interactive display until
+// the user quits or the context is done.
+func (o *TopOptions) Run(ctx context.Context) error {
+	if o.Once {
+		summary, err := o.Summarize(ctx)
+		if err != nil {
+			return err
+		}
+		if o.Output == base.OutputTable {
+			return RenderOverview(o.Out, summary, o.MaxRows)
+		}
+		return base.PrintObject(o.Out, o.Output, summary, base.Table{})
+	}
+	return o.interact(ctx)
+}
+
+// view is what the interactive display is focused on.
+type view struct {
+	kind string // one of "overview", "failures", "placement", "destination"
+	name string
+}
+
+const helpLine = "[o]verview  [f]ailures  [p] <placement>  [d] <synctarget>  [r]efresh  [q]uit"
+
+// interact repeatedly redraws the screen, reading commands a line at a
+// time from the input.
+func (o *TopOptions) interact(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	commands := make(chan string)
+	go func() {
+		defer close(commands)
+		scanner := bufio.NewScanner(o.In)
+		for scanner.Scan() {
+			select {
+			case commands <- strings.TrimSpace(scanner.Text()):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+	current := view{kind: "overview"}
+	debouncer := NewFleetDebouncer(o.HealthMinDuration)
+	var summary *FleetSummary
+	var lastErr error
+	for {
+		if fresh, err := o.Summarize(ctx); err != nil {
+			lastErr = err
+		} else {
+			debouncer.Apply(fresh)
+			summary, lastErr = fresh, nil
+		}
+		o.draw(current, summary, lastErr)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case command, ok := <-commands:
+			if !ok {
+				return nil
+			}
+			next, quit := parseCommand(command, current)
+			if quit {
+				return nil
+			}
+			current = next
+		}
+	}
+}
+
+// parseCommand returns the view selected by the given command, and whether
+// the command is to quit. An unrecognized command keeps the current view.
+func parseCommand(command string, current view) (view, bool) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return current, false
+	}
+	switch {
+	case fields[0] == "q":
+		return current, true
+	case fields[0] == "o":
+		return view{kind: "overview"}, false
+	case fields[0] == "f":
+		return view{kind: "failures"}, false
+	case fields[0] == "p" && len(fields) == 2:
+		return view{kind: "placement", name: fields[1]}, false
+	case fields[0] == "d" && len(fields) == 2:
+		return view{kind: "destination", name: fields[1]}, false
+	default:
+		return current, false
+	}
+}
+
+// draw clears the terminal and writes the current view in one write,
+// to avoid flicker.
+func (o *TopOptions) draw(current view, summary *FleetSummary, lastErr error) {
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[H\x1b[2J")
+	when := "never"
+	if summary != nil {
+		when = summary.Time.Format(time.RFC3339)
+	}
+	fmt.Fprintf(&buf, "KubeStellar fleet status, as of %s\n", when)
+	if lastErr != nil {
+		fmt.Fprintf(&buf, "Refresh failed: %v\n", lastErr)
+	}
+	fmt.Fprintln(&buf)
+	if summary != nil {
+		switch current.kind {
+		case "failures":
+			_ = RenderFailures(&buf, summary, -1)
+		case "placement":
+			_ = RenderPlacement(&buf, summary, current.name)
+		case "destination":
+			_ = RenderDestination(&buf, summary, current.name)
+		default:
+			_ = RenderOverview(&buf, summary, o.MaxRows)
+		}
+	}
+	fmt.Fprintf(&buf, "\n%s\n> ", helpLine)
+	_, _ = o.Out.Write(buf.Bytes())
+}
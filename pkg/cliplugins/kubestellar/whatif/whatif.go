@@ -0,0 +1,212 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package whatif implements `kubectl kubestellar what-if`, which shows
+// what a failure of some Locations and SyncTargets would do to the
+// EdgePlacements of a workload description space; see package whatif.
+package whatif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	clientopts "github.com/kubestellar/kubestellar/pkg/client-options"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/base"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	"github.com/kubestellar/kubestellar/pkg/whatif"
+)
+
+// ErrBelowRequired is returned by Run, with ExitOnBelowRequired, when
+// some EdgePlacement would have fewer destinations than it needs.
+var ErrBelowRequired = errors.New("some EdgePlacements would have fewer destinations than they need")
+
+// WhatIfOptions contains options for the `what-if` command.
+// The base Options are for the workload description space; the
+// inventory space is configured by the --inventory-* flags and
+// defaults to the same kubeconfig and context.
+type WhatIfOptions struct {
+	*base.Options
+
+	Inventory *clientopts.ClientOpts
+
+	// Selector selects the failing Locations and SyncTargets by their labels.
+	Selector string
+	// Locations and SyncTargets name more failing objects.
+	Locations   []string
+	SyncTargets []string
+	// PlacementSelector selects the EdgePlacements to consider; empty means all.
+	PlacementSelector string
+	// ExitOnBelowRequired makes Run fail if some EdgePlacement would
+	// have fewer destinations than it needs.
+	ExitOnBelowRequired bool
+	// Output is the format of the report.
+	Output base.OutputFormat
+
+	failure           whatif.Failure
+	placementSelector labels.Selector
+	wdsClient         edgeclientset.Interface
+	inventoryClient   edgeclientset.Interface
+}
+
+// NewWhatIfOptions returns a new WhatIfOptions.
+func NewWhatIfOptions(streams genericclioptions.IOStreams) *WhatIfOptions {
+	return &WhatIfOptions{
+		Options:   base.NewOptions(streams),
+		Inventory: clientopts.NewClientOpts("inventory", "access to the inventory space (default is the workload description space)"),
+		Output:    base.OutputTable,
+	}
+}
+
+// BindFlags binds fields of WhatIfOptions as command line flags to cmd's flagset.
+func (o *WhatIfOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	o.Inventory.AddFlags(cmd.Flags())
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Label selector for the Locations and SyncTargets that fail.")
+	cmd.Flags().StringSliceVar(&o.Locations, "location", o.Locations, "Names of more Locations that fail.")
+	cmd.Flags().StringSliceVar(&o.SyncTargets, "synctarget", o.SyncTargets, "Names of more SyncTargets that fail.")
+	cmd.Flags().StringVar(&o.PlacementSelector, "placement-selector", o.PlacementSelector, "Label selector for the EdgePlacements to consider; all are by default.")
+	cmd.Flags().BoolVar(&o.ExitOnBelowRequired, "exit-code", o.ExitOnBelowRequired, "Exit with a failure code if some EdgePlacement would have fewer destinations than it needs.")
+	base.BindOutputFlag(cmd, &o.Output)
+}
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *WhatIfOptions) Complete() error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+	wdsConfig, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	o.wdsClient, err = edgeclientset.NewForConfig(wdsConfig)
+	if err != nil {
+		return err
+	}
+	o.inventoryClient = o.wdsClient
+	if o.Inventory.Configured() {
+		inventoryConfig, err := o.Inventory.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		o.inventoryClient, err = edgeclientset.NewForConfig(inventoryConfig)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates the WhatIfOptions are complete and usable.
+func (o *WhatIfOptions) Validate() error {
+	var errs []error
+	if err := o.Options.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.Output.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	o.failure = whatif.Failure{LocationNames: o.Locations, SyncTargetNames: o.SyncTargets}
+	if o.Selector != "" {
+		selector, err := labels.Parse(o.Selector)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid --selector: %w", err))
+		}
+		o.failure.Selector = selector
+	} else if len(o.Locations) == 0 && len(o.SyncTargets) == 0 {
+		errs = append(errs, errors.New("say what fails with --selector, --location or --synctarget"))
+	}
+	if o.PlacementSelector != "" {
+		selector, err := labels.Parse(o.PlacementSelector)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid --placement-selector: %w", err))
+		}
+		o.placementSelector = selector
+	}
+	if err := utilerrors.NewAggregate(errs); err != nil {
+		return &base.UsageError{Message: err.Error()}
+	}
+	return nil
+}
+
+// Run takes a snapshot of the spaces, simulates the failure and prints the report.
+func (o *WhatIfOptions) Run(ctx context.Context) error {
+	listOptions := metav1.ListOptions{}
+	if o.placementSelector != nil {
+		listOptions.LabelSelector = o.placementSelector.String()
+	}
+	placements, err := o.wdsClient.EdgeV2alpha1().EdgePlacements().List(ctx, listOptions)
+	if err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to list EdgePlacements: %w", err))
+	}
+	locations, err := o.inventoryClient.EdgeV2alpha1().Locations().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to list Locations: %w", err))
+	}
+	syncTargets, err := o.inventoryClient.EdgeV2alpha1().SyncTargets().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return kserrors.Classify(fmt.Errorf("failed to list SyncTargets: %w", err))
+	}
+	report, err := whatif.Simulate(whatif.Inventory{Locations: locations.Items, SyncTargets: syncTargets.Items}, placements.Items, o.failure)
+	if err != nil {
+		return err
+	}
+	if o.Output == base.OutputTable {
+		fmt.Fprintf(o.ErrOut, "Failing: %d Location(s) [%s], %d SyncTarget(s) [%s]; %d EdgePlacement(s) unaffected\n",
+			len(report.FailedLocations), strings.Join(report.FailedLocations, ", "),
+			len(report.FailedSyncTargets), strings.Join(report.FailedSyncTargets, ", "), report.Unaffected)
+	}
+	table := base.Table{Columns: []string{"PLACEMENT", "REQUIRED", "BEFORE", "AFTER", "BELOW", "LOST", "REMAINING"}}
+	for _, impact := range report.Impacts {
+		table.Rows = append(table.Rows, []string{
+			impact.Placement,
+			strconv.Itoa(impact.Required),
+			strconv.Itoa(len(impact.Before)),
+			strconv.Itoa(len(impact.After)),
+			strconv.FormatBool(impact.BelowRequired),
+			syncTargetNames(impact.Lost),
+			syncTargetNames(impact.After),
+		})
+	}
+	if err := base.PrintObject(o.Out, o.Output, report, table); err != nil {
+		return err
+	}
+	if o.ExitOnBelowRequired && report.BelowRequired() > 0 {
+		return ErrBelowRequired
+	}
+	return nil
+}
+
+func syncTargetNames(dests []whatif.Destination) string {
+	if len(dests) == 0 {
+		return "<none>"
+	}
+	names := make([]string, len(dests))
+	for idx, dest := range dests {
+		names[idx] = dest.SyncTarget
+	}
+	return strings.Join(names, ",")
+}
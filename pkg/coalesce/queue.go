@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package coalesce provides a workqueue that coalesces the notifications
+// about each queue item.
+//
+// The client-go workqueue already holds each item at most once, so
+// repeated notifications for an item that is waiting are merged; this
+// Queue counts those merges, so that the redundant reconciliations
+// avoided in a busy fleet can be measured. It can also drop an item
+// whose object was added and then deleted before the item was
+// processed, as nothing needs to be done for an object that was never
+// seen. That is safe only where nothing but the processing of that
+// item acts on the object, so it is enabled per queue by Options.CanDrop.
+package coalesce
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	events = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_workqueue",
+		Name:           "events_total",
+		Help:           "Number of notifications given to a coalescing workqueue, by queue name",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"name"})
+	coalesced = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_workqueue",
+		Name:           "coalesced_events_total",
+		Help:           "Number of notifications that did not lead to a processing of their own, by queue name and outcome (merged or dropped)",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"name", "outcome"})
+)
+
+func init() {
+	legacyregistry.MustRegister(events, coalesced)
+}
+
+// Event says why an item is added.
+type Event string
+
+const (
+	// Added means that the item's object was created.
+	Added Event = "add"
+	// Updated means that the item's object was changed.
+	Updated Event = "update"
+	// Deleted means that the item's object was deleted.
+	Deleted Event = "delete"
+	// Other is any other reason, such as the processing of another item.
+	// An item added for another reason is never dropped.
+	Other Event = "other"
+)
+
+// Options configure a Queue.
+type Options struct {
+	// CanDrop, when not nil, tells whether the given item may be dropped
+	// when its object is added and then deleted before the item is
+	// processed.
+	CanDrop func(item any) bool
+}
+
+// Queue is a rate limiting workqueue that coalesces notifications.
+// Use AddEvent to add an item because of a notification about its object.
+// The other ways of adding count as Other.
+type Queue struct {
+	workqueue.RateLimitingInterface
+	name    string
+	canDrop func(item any) bool
+
+	mutex sync.Mutex
+	// pending holds the state of each item waiting in the queue
+	pending map[any]*pendingItem
+	// processing holds the items between Get and Done
+	processing map[any]struct{}
+	// retained holds the items added with delay and not yet forgotten;
+	// they are never dropped, as a retry may have work to finish
+	retained map[any]struct{}
+}
+
+type pendingItem struct {
+	droppable bool
+	// cancelled means the object was added and then deleted
+	cancelled bool
+}
+
+// NewNamedRateLimitingQueue makes a Queue whose metrics carry the given name.
+func NewNamedRateLimitingQueue(rateLimiter workqueue.RateLimiter, name string, opts Options) *Queue {
+	return &Queue{
+		RateLimitingInterface: workqueue.NewNamedRateLimitingQueue(rateLimiter, name),
+		name:                  name,
+		canDrop:               opts.CanDrop,
+		pending:               map[any]*pendingItem{},
+		processing:            map[any]struct{}{},
+		retained:              map[any]struct{}{},
+	}
+}
+
+// AddEvent adds the item because of the given event.
+func (q *Queue) AddEvent(item any, event Event) {
+	q.mutex.Lock()
+	events.WithLabelValues(q.name).Inc()
+	pending, merging := q.pending[item]
+	if merging {
+		coalesced.WithLabelValues(q.name, "merged").Inc()
+	} else {
+		_, processing := q.processing[item]
+		_, retained := q.retained[item]
+		pending = &pendingItem{droppable: event == Added && q.canDrop != nil && !processing && !retained && q.canDrop(item)}
+		q.pending[item] = pending
+	}
+	if event == Other {
+		pending.droppable = false
+	}
+	pending.cancelled = pending.droppable && event == Deleted
+	q.mutex.Unlock()
+	q.RateLimitingInterface.Add(item)
+}
+
+// Add adds the item for a reason other than a notification.
+func (q *Queue) Add(item any) {
+	q.AddEvent(item, Other)
+}
+
+// AddRateLimited adds the item after the rate limiter says it is ok.
+func (q *Queue) AddRateLimited(item any) {
+	q.retain(item)
+	q.RateLimitingInterface.AddRateLimited(item)
+}
+
+// AddAfter adds the item after the given duration.
+func (q *Queue) AddAfter(item any, duration time.Duration) {
+	q.retain(item)
+	q.RateLimitingInterface.AddAfter(item, duration)
+}
+
+func (q *Queue) retain(item any) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.retained[item] = struct{}{}
+	if pending := q.pending[item]; pending != nil {
+		pending.droppable, pending.cancelled = false, false
+	}
+}
+
+// Get returns the next item to process, skipping the dropped ones.
+func (q *Queue) Get() (any, bool) {
+	for {
+		item, shutdown := q.RateLimitingInterface.Get()
+		if shutdown {
+			return item, true
+		}
+		q.mutex.Lock()
+		pending := q.pending[item]
+		delete(q.pending, item)
+		if pending != nil && pending.cancelled {
+			q.mutex.Unlock()
+			coalesced.WithLabelValues(q.name, "dropped").Inc()
+			q.RateLimitingInterface.Forget(item)
+			q.RateLimitingInterface.Done(item)
+			continue
+		}
+		q.processing[item] = struct{}{}
+		q.mutex.Unlock()
+		return item, false
+	}
+}
+
+// Done marks the end of processing the item.
+func (q *Queue) Done(item any) {
+	q.mutex.Lock()
+	delete(q.processing, item)
+	q.mutex.Unlock()
+	q.RateLimitingInterface.Done(item)
+}
+
+// Forget tells the rate limiter, and this Queue, that the item needs no more retries.
+func (q *Queue) Forget(item any) {
+	q.mutex.Lock()
+	delete(q.retained, item)
+	q.mutex.Unlock()
+	q.RateLimitingInterface.Forget(item)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coalesce
+
+import (
+	"testing"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+func getAll(t *testing.T, q *Queue) []any {
+	var items []any
+	for q.Len() > 0 {
+		item, shutdown := q.Get()
+		if shutdown {
+			t.Fatal("Unexpected shutdown")
+		}
+		items = append(items, item)
+		q.Done(item)
+		q.Forget(item)
+	}
+	return items
+}
+
+func TestMergeAndDrop(t *testing.T) {
+	q := NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test-merge",
+		Options{CanDrop: func(item any) bool { return item != "keep" }})
+	defer q.ShutDown()
+
+	q.AddEvent("a", Added)
+	q.AddEvent("a", Updated)
+	q.AddEvent("a", Updated)
+	q.AddEvent("gone", Added)
+	q.AddEvent("gone", Updated)
+	q.AddEvent("gone", Deleted)
+	q.AddEvent("keep", Added)
+	q.AddEvent("keep", Deleted)
+	q.AddEvent("back", Added)
+	q.AddEvent("back", Deleted)
+	q.AddEvent("back", Added)
+	q.AddEvent("other", Added)
+	q.Add("other")
+	q.AddEvent("other", Deleted)
+	items := getAll(t, q)
+	expected := []any{"a", "keep", "back", "other"}
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, items)
+	}
+	for idx := range expected {
+		if items[idx] != expected[idx] {
+			t.Errorf("Expected %v, got %v", expected, items)
+			break
+		}
+	}
+}
+
+func TestNoDropWhileProcessing(t *testing.T) {
+	q := NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test-processing",
+		Options{CanDrop: func(item any) bool { return true }})
+	defer q.ShutDown()
+
+	q.AddEvent("x", Updated)
+	item, _ := q.Get()
+	// The processing of x may have seen the object added below.
+	q.AddEvent("x", Added)
+	q.AddEvent("x", Deleted)
+	q.Done(item)
+	q.Forget(item)
+	if items := getAll(t, q); len(items) != 1 {
+		t.Errorf("Expected x to be processed again, got %v", items)
+	}
+}
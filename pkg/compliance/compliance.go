@@ -0,0 +1,339 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package compliance produces reports, for auditors, of what a selected
+// set of EdgePlacements delivered where, when each delivery became ready,
+// and whether each destination is still in the desired state. A report is
+// built from the EdgePlacements, their SinglePlacementSlices, the
+// SyncTargets and the placement timeline (see package timeline); it is
+// written as JSON or CSV and signed with an Ed25519 key, so that a reader
+// with the public key can tell that it has not been altered.
+package compliance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/top"
+	"github.com/kubestellar/kubestellar/pkg/spechash"
+	"github.com/kubestellar/kubestellar/pkg/timeline"
+)
+
+// ReportKind is the kind of a Report, as written in JSON.
+const ReportKind = "ComplianceReport"
+
+// DriftStatus tells whether a destination is in the state that its
+// EdgePlacement asks for.
+type DriftStatus string
+
+const (
+	// DriftInSync means the current spec of the EdgePlacement has been
+	// processed, all its conditions are True, and the destination reports
+	// itself Ready.
+	DriftInSync DriftStatus = "InSync"
+
+	// DriftPending means the current spec of the EdgePlacement has not
+	// been fully delivered yet.
+	DriftPending DriftStatus = "Pending"
+
+	// DriftDrifted means the destination reports that it is not Ready.
+	DriftDrifted DriftStatus = "Drifted"
+
+	// DriftUnknown means the destination has not been heard from
+	// recently or is not in the inventory.
+	DriftUnknown DriftStatus = "Unknown"
+)
+
+// Selection picks the EdgePlacements to report on.
+type Selection struct {
+	// Selector selects EdgePlacements by their labels. Nil selects all.
+	Selector labels.Selector
+
+	// Names, when not empty, restricts the selection to these EdgePlacements.
+	Names []string
+}
+
+// Inputs are the objects that a Report is built from.
+type Inputs struct {
+	Placements  []edgev2alpha1.EdgePlacement
+	Slices      []edgev2alpha1.SinglePlacementSlice
+	SyncTargets []edgev2alpha1.SyncTarget
+
+	// Events is the placement timeline, oldest first.
+	Events []timeline.Event
+}
+
+// Report is what the selected EdgePlacements delivered where.
+type Report struct {
+	Kind        string      `json:"kind"`
+	GeneratedAt metav1.Time `json:"generatedAt"`
+
+	// TimelineStart is the time of the oldest event in the timeline.
+	// Deliveries and readiness before it are not known, and are left out.
+	TimelineStart *metav1.Time `json:"timelineStart,omitempty"`
+
+	// Placements are in the order of their names.
+	Placements []PlacementRecord `json:"placements"`
+}
+
+// PlacementRecord is what one EdgePlacement delivered.
+type PlacementRecord struct {
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid"`
+	Generation int64     `json:"generation"`
+
+	// SpecHash identifies the spec that is being delivered; see package spechash.
+	SpecHash string `json:"specHash"`
+
+	// Downsync is what the EdgePlacement delivers.
+	Downsync []edgev2alpha1.DownsyncObjectTest `json:"downsync,omitempty"`
+
+	// SpecChangedAt is when the current spec was written, if known.
+	SpecChangedAt *metav1.Time `json:"specChangedAt,omitempty"`
+
+	Health top.Health `json:"health"`
+
+	// Deliveries are in the order of their destinations.
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// Delivery is the delivery of an EdgePlacement to one destination.
+type Delivery struct {
+	// Destination is the Key of the destination (see package destination).
+	Destination    string `json:"destination"`
+	ClusterID      string `json:"clusterID,omitempty"`
+	LocationName   string `json:"locationName"`
+	SyncTargetName string `json:"syncTargetName"`
+
+	// DeliveredAt is when the destination was chosen, if known.
+	DeliveredAt *metav1.Time `json:"deliveredAt,omitempty"`
+
+	// ReadyAt is the first time, at or after DeliveredAt, at which all
+	// the conditions of the EdgePlacement were True, if there was one.
+	ReadyAt *metav1.Time `json:"readyAt,omitempty"`
+
+	DestinationHealth top.Health  `json:"destinationHealth"`
+	Drift             DriftStatus `json:"drift"`
+	DriftReason       string      `json:"driftReason,omitempty"`
+}
+
+// Build makes the Report on the selected EdgePlacements.
+// The heartbeatTimeout is passed to top.Summarize.
+func Build(inputs Inputs, selection Selection, now time.Time, heartbeatTimeout time.Duration) *Report {
+	selector := selection.Selector
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	names := sets.NewString(selection.Names...)
+	var placements []edgev2alpha1.EdgePlacement
+	for _, ep := range inputs.Placements {
+		if selector.Matches(labels.Set(ep.Labels)) && (names.Len() == 0 || names.Has(ep.Name)) {
+			placements = append(placements, ep)
+		}
+	}
+	fleet := top.Summarize(placements, inputs.Slices, inputs.SyncTargets, now, heartbeatTimeout)
+	report := &Report{Kind: ReportKind, GeneratedAt: metav1.NewTime(now), Placements: []PlacementRecord{}}
+	if len(inputs.Events) > 0 {
+		report.TimelineStart = &inputs.Events[0].Time
+	}
+	for idx := range placements {
+		ep := &placements[idx]
+		ps := fleet.FindPlacement(ep.Name)
+		history := replay(ep, inputs.Events)
+		record := PlacementRecord{
+			Name:          ep.Name,
+			UID:           ep.UID,
+			Generation:    ep.Generation,
+			SpecHash:      spechash.Of(ep),
+			Downsync:      ep.Spec.Downsync,
+			SpecChangedAt: history.specChangedAt,
+			Health:        ps.Health,
+			Deliveries:    []Delivery{},
+		}
+		pending, pendingReason := placementPending(ep, record.SpecHash)
+		for _, dest := range ps.Destinations {
+			key := dest.Destination().Key()
+			delivery := Delivery{
+				Destination:    key,
+				ClusterID:      dest.ClusterID,
+				LocationName:   dest.LocationName,
+				SyncTargetName: dest.SyncTargetName,
+				Drift:          DriftInSync,
+			}
+			if times, ok := history.deliveries[key]; ok {
+				delivery.DeliveredAt, delivery.ReadyAt = times.deliveredAt, times.readyAt
+			}
+			ds := fleet.FindDestinationOf(dest.Destination())
+			delivery.DestinationHealth = ds.Health
+			switch {
+			case ds.Health == top.HealthStale || ds.Health == top.HealthMissing:
+				delivery.Drift, delivery.DriftReason = DriftUnknown, ds.Reason
+			case ds.Health != top.HealthReady:
+				delivery.Drift, delivery.DriftReason = DriftDrifted, ds.Reason
+			case pending:
+				delivery.Drift, delivery.DriftReason = DriftPending, pendingReason
+			}
+			record.Deliveries = append(record.Deliveries, delivery)
+		}
+		report.Placements = append(report.Placements, record)
+	}
+	sort.Slice(report.Placements, func(i, j int) bool { return report.Placements[i].Name < report.Placements[j].Name })
+	return report
+}
+
+// placementPending tells whether the current spec of the given
+// EdgePlacement, whose hash is given, has yet to be delivered, and why.
+func placementPending(ep *edgev2alpha1.EdgePlacement, specHash string) (bool, string) {
+	if ep.Status.ProcessedSpecHash != "" && ep.Status.ProcessedSpecHash != specHash ||
+		ep.Status.SpecGeneration != 0 && int64(ep.Status.SpecGeneration) < ep.Generation {
+		return true, "SpecNotProcessed"
+	}
+	for _, cond := range ep.Status.Conditions {
+		if cond.Status != metav1.ConditionTrue {
+			return true, cond.Reason
+		}
+	}
+	return false, ""
+}
+
+type deliveryTimes struct {
+	deliveredAt *metav1.Time
+	readyAt     *metav1.Time
+}
+
+type placementHistory struct {
+	specChangedAt *metav1.Time
+	deliveries    map[string]deliveryTimes // by destination key
+}
+
+// replay goes through the timeline events of the given EdgePlacement.
+// Events of an earlier EdgePlacement with the same name are skipped.
+func replay(ep *edgev2alpha1.EdgePlacement, events []timeline.Event) placementHistory {
+	history := placementHistory{deliveries: map[string]deliveryTimes{}}
+	notTrue := sets.NewString() // the types of the conditions that are not True
+	for idx := range events {
+		event := &events[idx]
+		if event.Placement != ep.Name || event.UID != "" && event.UID != ep.UID {
+			continue
+		}
+		when := event.Time
+		switch event.Type {
+		case timeline.EventCreated, timeline.EventSpecChanged:
+			history.specChangedAt = &when
+		case timeline.EventDestinationsChanged:
+			for _, key := range event.Removed {
+				delete(history.deliveries, key)
+			}
+			for _, key := range event.Added {
+				times := deliveryTimes{deliveredAt: &when}
+				if notTrue.Len() == 0 {
+					times.readyAt = &when
+				}
+				history.deliveries[key] = times
+			}
+		case timeline.EventConditionChanged:
+			if event.Status != string(metav1.ConditionTrue) {
+				notTrue.Insert(event.Condition)
+				continue
+			}
+			notTrue.Delete(event.Condition)
+			if notTrue.Len() > 0 {
+				continue
+			}
+			for key, times := range history.deliveries {
+				if times.readyAt == nil {
+					times.readyAt = &when
+					history.deliveries[key] = times
+				}
+			}
+		}
+	}
+	return history
+}
+
+// JSON returns the Report as indented JSON.
+func (report *Report) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// csvHeader names the columns of the CSV form of a Report.
+var csvHeader = []string{"generatedAt", "placement", "uid", "generation", "specHash", "specChangedAt", "health",
+	"destination", "clusterID", "location", "syncTarget", "deliveredAt", "readyAt", "destinationHealth", "drift", "driftReason"}
+
+// CSV returns the Report as CSV, with a header line and one line per
+// Delivery. The Downsync of the EdgePlacements is only in the JSON form.
+func (report *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	generatedAt := timeString(&report.GeneratedAt)
+	for _, record := range report.Placements {
+		for _, delivery := range record.Deliveries {
+			row := []string{generatedAt, record.Name, string(record.UID), strconv.FormatInt(record.Generation, 10), record.SpecHash,
+				timeString(record.SpecChangedAt), string(record.Health), delivery.Destination, delivery.ClusterID,
+				delivery.LocationName, delivery.SyncTargetName, timeString(delivery.DeliveredAt), timeString(delivery.ReadyAt),
+				string(delivery.DestinationHealth), string(delivery.Drift), delivery.DriftReason}
+			if err := writer.Write(row); err != nil {
+				return nil, err
+			}
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func timeString(when *metav1.Time) string {
+	if when == nil {
+		return ""
+	}
+	return when.UTC().Format(time.RFC3339)
+}
+
+// Format is a form in which a Report is written.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// Encode returns the Report in the given Format.
+func (report *Report) Encode(format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return report.JSON()
+	case FormatCSV:
+		return report.CSV()
+	}
+	return nil, fmt.Errorf("unknown report format %q", format)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compliance
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/top"
+	"github.com/kubestellar/kubestellar/pkg/timeline"
+)
+
+func placement(name string, uid types.UID, generation int64, lbls map[string]string, specGeneration int32) edgev2alpha1.EdgePlacement {
+	return edgev2alpha1.EdgePlacement{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: uid, Generation: generation, Labels: lbls},
+		Status: edgev2alpha1.EdgePlacementStatus{SpecGeneration: specGeneration, Conditions: []metav1.Condition{
+			{Type: edgev2alpha1.EdgePlacementLocationsResolved, Status: metav1.ConditionTrue, Reason: "Resolved"},
+		}},
+	}
+}
+
+func slice(epName string, dests ...edgev2alpha1.SinglePlacement) edgev2alpha1.SinglePlacementSlice {
+	return edgev2alpha1.SinglePlacementSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: epName, OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: edgev2alpha1.SchemeGroupVersion.String(), Kind: "EdgePlacement", Name: epName},
+		}},
+		Destinations: dests,
+	}
+}
+
+func syncTarget(name string, uid types.UID, heartbeat time.Time, ready bool) edgev2alpha1.SyncTarget {
+	hb := metav1.NewTime(heartbeat)
+	st := edgev2alpha1.SyncTarget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: uid},
+		Status:     edgev2alpha1.SyncTargetStatus{LastSyncerHeartbeatTime: &hb},
+	}
+	if !ready {
+		st.Status.Conditions = conditionsv1alpha1.Conditions{{Type: conditionsv1alpha1.ReadyCondition, Status: corev1.ConditionFalse, Reason: "Syncer"}}
+	}
+	return st
+}
+
+func TestBuild(t *testing.T) {
+	t0 := time.Date(2023, 9, 1, 14, 0, 0, 0, time.UTC)
+	at := func(minutes int) metav1.Time { return metav1.NewTime(t0.Add(time.Duration(minutes) * time.Minute)) }
+	now := t0.Add(time.Hour)
+	inputs := Inputs{
+		Placements: []edgev2alpha1.EdgePlacement{
+			placement("web", "uid-web", 2, map[string]string{"audit": "yes"}, 2),
+			placement("db", "uid-db", 3, map[string]string{"audit": "yes"}, 2),
+			placement("scratch", "uid-scratch", 1, nil, 1),
+		},
+		Slices: []edgev2alpha1.SinglePlacementSlice{
+			slice("web",
+				edgev2alpha1.SinglePlacement{Cluster: "inv", LocationName: "loc", SyncTargetName: "edge1", SyncTargetUID: "uid-edge1"},
+				edgev2alpha1.SinglePlacement{Cluster: "inv", LocationName: "loc", SyncTargetName: "edge2", SyncTargetUID: "uid-edge2"},
+				edgev2alpha1.SinglePlacement{Cluster: "inv", LocationName: "loc", SyncTargetName: "edge3", SyncTargetUID: "uid-edge3"}),
+			slice("db", edgev2alpha1.SinglePlacement{Cluster: "inv", LocationName: "loc", SyncTargetName: "edge1", SyncTargetUID: "uid-edge1"}),
+		},
+		SyncTargets: []edgev2alpha1.SyncTarget{
+			syncTarget("edge1", "uid-edge1", now, true),
+			syncTarget("edge2", "uid-edge2", now.Add(-time.Hour), true),
+			syncTarget("edge3", "uid-edge3", now, false),
+		},
+		Events: []timeline.Event{
+			// An earlier EdgePlacement of the same name
+			{Time: at(0), Placement: "web", UID: "uid-old", Type: timeline.EventDestinationsChanged, Added: []string{"inv/edge3"}},
+			{Time: at(1), Placement: "web", UID: "uid-web", Type: timeline.EventCreated, Generation: 1},
+			{Time: at(1), Placement: "web", UID: "uid-web", Type: timeline.EventConditionChanged, Condition: "LocationsResolved", Status: "False"},
+			{Time: at(2), Placement: "web", UID: "uid-web", Type: timeline.EventDestinationsChanged, Added: []string{"inv/edge1", "inv/edge2"}},
+			{Time: at(3), Placement: "web", UID: "uid-web", Type: timeline.EventConditionChanged, Condition: "LocationsResolved", Status: "True"},
+			{Time: at(4), Placement: "web", UID: "uid-web", Type: timeline.EventSpecChanged, Generation: 2},
+			{Time: at(5), Placement: "web", UID: "uid-web", Type: timeline.EventDestinationsChanged, Added: []string{"inv/edge3"}, Removed: []string{"inv/edge2"}},
+			{Time: at(6), Placement: "web", UID: "uid-web", Type: timeline.EventDestinationsChanged, Added: []string{"inv/edge2"}},
+		},
+	}
+	report := Build(inputs, Selection{Selector: labels.SelectorFromSet(labels.Set{"audit": "yes"})}, now, 10*time.Minute)
+	if len(report.Placements) != 2 || report.Placements[0].Name != "db" || report.Placements[1].Name != "web" {
+		t.Fatalf("Expected db and web, got %+v", report.Placements)
+	}
+	if report.TimelineStart == nil || !report.TimelineStart.Equal(&inputs.Events[0].Time) {
+		t.Errorf("Expected the timeline to start at %v, got %v", inputs.Events[0].Time, report.TimelineStart)
+	}
+
+	db := report.Placements[0]
+	if len(db.Deliveries) != 1 || db.Deliveries[0].Drift != DriftPending || db.Deliveries[0].DriftReason != "SpecNotProcessed" {
+		t.Errorf("Expected db to be pending at edge1, got %+v", db.Deliveries)
+	}
+	if db.Deliveries[0].DeliveredAt != nil {
+		t.Errorf("Expected no delivery time for db, which is not in the timeline, got %v", db.Deliveries[0].DeliveredAt)
+	}
+
+	web := report.Placements[1]
+	if web.SpecChangedAt == nil || !web.SpecChangedAt.Equal(&metav1.Time{Time: t0.Add(4 * time.Minute)}) || web.SpecHash == "" {
+		t.Errorf("Unexpected spec of web: %+v", web)
+	}
+	expected := []struct {
+		dest               string
+		deliveredAt, ready int
+		health             top.Health
+		drift              DriftStatus
+	}{
+		{"inv/edge1", 2, 3, top.HealthReady, DriftInSync},
+		{"inv/edge2", 6, 6, top.HealthStale, DriftUnknown},
+		{"inv/edge3", 5, 5, top.HealthDegraded, DriftDrifted},
+	}
+	if len(web.Deliveries) != len(expected) {
+		t.Fatalf("Expected %d deliveries of web, got %+v", len(expected), web.Deliveries)
+	}
+	for idx, exp := range expected {
+		got := web.Deliveries[idx]
+		deliveredAt, readyAt := at(exp.deliveredAt), at(exp.ready)
+		if got.Destination != exp.dest || got.DestinationHealth != exp.health || got.Drift != exp.drift ||
+			got.DeliveredAt == nil || !got.DeliveredAt.Equal(&deliveredAt) || got.ReadyAt == nil || !got.ReadyAt.Equal(&readyAt) {
+			t.Errorf("Delivery %d: expected %+v, got %+v", idx, exp, got)
+		}
+	}
+
+	data, err := report.CSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 5 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Errorf("Expected a header and 4 rows, got\n%s", data)
+	}
+	if !strings.Contains(lines[2], ",inv/edge1,inv,loc,edge1,2023-09-01T14:02:00Z,2023-09-01T14:03:00Z,Ready,InSync,") {
+		t.Errorf("Unexpected row for web at edge1: %s", lines[2])
+	}
+}
+
+func TestSignAndPrune(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	rep := &Reporter{config: Config{Dir: dir, Formats: []Format{FormatJSON, FormatCSV}, Signer: NewSigner(privateKey), Keep: 2}}
+	report := &Report{Kind: ReportKind, Placements: []PlacementRecord{}}
+	for idx := 0; idx < 3; idx++ {
+		report.GeneratedAt = metav1.NewTime(time.Date(2023, 9, 1+idx, 0, 0, 0, 0, time.UTC))
+		base := filepath.Join(dir, FilePrefix+report.GeneratedAt.Format(fileTimeLayout))
+		for _, format := range rep.config.Formats {
+			data, err := report.Encode(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writeFile(base+"."+string(format), data); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeFile(base+"."+string(format)+SignatureSuffix, rep.config.Signer.Sign(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.prune(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	expected := "compliance-20230902T000000Z.csv compliance-20230902T000000Z.csv.sig compliance-20230902T000000Z.json compliance-20230902T000000Z.json.sig " +
+		"compliance-20230903T000000Z.csv compliance-20230903T000000Z.csv.sig compliance-20230903T000000Z.json compliance-20230903T000000Z.json.sig notes.txt"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	path := filepath.Join(dir, "compliance-20230903T000000Z.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := os.ReadFile(path + SignatureSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(publicKey, data, sig); err != nil {
+		t.Errorf("Expected the signature to verify, got %v", err)
+	}
+	if err := Verify(publicKey, append(data, ' '), sig); err == nil {
+		t.Error("Expected an altered report to fail verification")
+	}
+}
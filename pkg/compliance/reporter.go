@@ -0,0 +1,234 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/timeline"
+)
+
+// FilePrefix starts the name of every file that a Reporter writes.
+// The rest of the name is the UTC time of the report, as
+// 20060102T150405Z, and the extension of its Format.
+const FilePrefix = "compliance-"
+
+const fileTimeLayout = "20060102T150405Z"
+
+// ParseFormats parses a comma-separated list of Formats.
+func ParseFormats(formatsStr string) ([]Format, error) {
+	var ans []Format
+	for _, part := range strings.Split(formatsStr, ",") {
+		switch format := Format(strings.TrimSpace(part)); format {
+		case FormatJSON, FormatCSV:
+			ans = append(ans, format)
+		default:
+			return nil, fmt.Errorf("unknown report format %q; expected %q or %q", part, FormatJSON, FormatCSV)
+		}
+	}
+	return ans, nil
+}
+
+// Config configures a Reporter.
+type Config struct {
+	// Dir is the directory in which the reports are written.
+	Dir string
+
+	Formats   []Format
+	Selection Selection
+
+	// Signer signs the reports. Nil means the reports are not signed.
+	Signer *Signer
+
+	// Keep is the number of reports that are kept; older ones are
+	// deleted. Non-positive means all are kept.
+	Keep int
+
+	// HeartbeatTimeout is passed to top.Summarize.
+	HeartbeatTimeout time.Duration
+}
+
+// Reporter writes reports periodically.
+type Reporter struct {
+	logger      klog.Logger
+	config      Config
+	placements  edgelisters.EdgePlacementLister
+	slices      edgelisters.SinglePlacementSliceLister
+	syncTargets edgelisters.SyncTargetLister
+	events      *timeline.Store
+	now         func() time.Time
+}
+
+// NewReporter makes a Reporter that reads from the given listers and timeline.
+func NewReporter(logger klog.Logger, config Config, placements edgelisters.EdgePlacementLister,
+	slices edgelisters.SinglePlacementSliceLister, syncTargets edgelisters.SyncTargetLister, events *timeline.Store) *Reporter {
+	return &Reporter{
+		logger:      logger,
+		config:      config,
+		placements:  placements,
+		slices:      slices,
+		syncTargets: syncTargets,
+		events:      events,
+		now:         time.Now,
+	}
+}
+
+// Generate builds the current Report.
+func (rep *Reporter) Generate() (*Report, error) {
+	placements, err := rep.placements.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	slices, err := rep.slices.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	syncTargets, err := rep.syncTargets.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	inputs := Inputs{Events: rep.events.Events()}
+	for _, ep := range placements {
+		inputs.Placements = append(inputs.Placements, *ep)
+	}
+	for _, sps := range slices {
+		inputs.Slices = append(inputs.Slices, *sps)
+	}
+	for _, st := range syncTargets {
+		inputs.SyncTargets = append(inputs.SyncTargets, *st)
+	}
+	return Build(inputs, rep.config.Selection, rep.now(), rep.config.HeartbeatTimeout), nil
+}
+
+// WriteReport generates a Report and writes it, and its signatures,
+// in each of the configured Formats. It returns the paths of the reports.
+func (rep *Reporter) WriteReport() ([]string, error) {
+	report, err := rep.Generate()
+	if err != nil {
+		return nil, err
+	}
+	base := FilePrefix + report.GeneratedAt.UTC().Format(fileTimeLayout)
+	var paths []string
+	for _, format := range rep.config.Formats {
+		data, err := report.Encode(format)
+		if err != nil {
+			return paths, err
+		}
+		path := filepath.Join(rep.config.Dir, base+"."+string(format))
+		// The signature goes first, so that a report is never without it.
+		if rep.config.Signer != nil {
+			if err := writeFile(path+SignatureSuffix, rep.config.Signer.Sign(data)); err != nil {
+				return paths, err
+			}
+		}
+		if err := writeFile(path, data); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rep.prune()
+}
+
+// prune deletes the files of the reports beyond the newest Keep.
+func (rep *Reporter) prune() error {
+	if rep.config.Keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(rep.config.Dir)
+	if err != nil {
+		return err
+	}
+	stamps := sets.NewString()
+	for _, entry := range entries {
+		if stamp, ok := reportStamp(entry.Name()); ok {
+			stamps.Insert(stamp)
+		}
+	}
+	list := stamps.List()
+	sort.Sort(sort.Reverse(sort.StringSlice(list)))
+	if len(list) <= rep.config.Keep {
+		return nil
+	}
+	drop := sets.NewString(list[rep.config.Keep:]...)
+	for _, entry := range entries {
+		if stamp, ok := reportStamp(entry.Name()); ok && drop.Has(stamp) {
+			if err := os.Remove(filepath.Join(rep.config.Dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reportStamp returns the time stamp in the name of a file written by a Reporter.
+func reportStamp(name string) (string, bool) {
+	if !strings.HasPrefix(name, FilePrefix) {
+		return "", false
+	}
+	stamp, _, _ := strings.Cut(strings.TrimPrefix(name, FilePrefix), ".")
+	if _, err := time.Parse(fileTimeLayout, stamp); err != nil {
+		return "", false
+	}
+	return stamp, true
+}
+
+// Run writes a report every period until the context is done.
+// The first one is written right away.
+func (rep *Reporter) Run(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		paths, err := rep.WriteReport()
+		if err != nil {
+			rep.logger.Error(err, "Failed to write compliance report", "written", paths)
+			return
+		}
+		rep.logger.V(2).Info("Wrote compliance report", "paths", paths)
+	}, period)
+}
+
+// writeFile atomically replaces the content of the given file.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
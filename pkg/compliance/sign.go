@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compliance
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// SignatureSuffix is appended to the name of a report file to name the
+// file that holds its signature.
+const SignatureSuffix = ".sig"
+
+// Signer signs reports with an Ed25519 private key.
+// A signature is the base64 encoding of the Ed25519 signature of the
+// exact bytes of the report, followed by a newline.
+type Signer struct {
+	key ed25519.PrivateKey
+}
+
+// NewSigner returns a Signer that uses the given key.
+func NewSigner(key ed25519.PrivateKey) *Signer {
+	return &Signer{key: key}
+}
+
+// LoadSigner reads a PEM-encoded PKCS #8 Ed25519 private key from the
+// given file, as made by `openssl genpkey -algorithm ed25519`.
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key in %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key in %s is a %T, not Ed25519", path, parsed)
+	}
+	return NewSigner(key), nil
+}
+
+// Sign returns the signature of the given report.
+func (signer *Signer) Sign(report []byte) []byte {
+	sig := ed25519.Sign(signer.key, report)
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+// PublicKey returns the public key that verifies the signatures.
+func (signer *Signer) PublicKey() ed25519.PublicKey {
+	return signer.key.Public().(ed25519.PublicKey)
+}
+
+// LoadPublicKey reads a PEM-encoded PKIX Ed25519 public key from the
+// given file, as made by `openssl pkey -pubout`.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key in %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the public key in %s is a %T, not Ed25519", path, parsed)
+	}
+	return key, nil
+}
+
+// Verify checks that the given signature, as returned by Signer.Sign, is
+// a signature of the given report by the given public key.
+func Verify(publicKey ed25519.PublicKey, report, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if !ed25519.Verify(publicKey, report, sig) {
+		return errors.New("the signature does not match the report")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package componentconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/klog/v2"
+)
+
+const sample = `
+apiVersion: config.kubestellar.io/v1alpha1
+kind: KubeStellarConfiguration
+logging:
+  verbosity: 3
+whereResolver:
+  concurrency: 4
+  orphanGCDryRun: true
+placementTranslator:
+  mailboxWriteMaxWait: 10s
+mailboxController:
+  concurrency: 2
+gateway:
+  healthMinDuration: 30s
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if *cfg.WhereResolver.Concurrency != 4 || !*cfg.WhereResolver.OrphanGCDryRun || cfg.Gateway.HealthMinDuration.Duration != 30*time.Second {
+		t.Errorf("Parsed wrong: %+v", cfg)
+	}
+	if cfg.PlacementTranslator.MailboxWriteMaxWait.Duration != 10*time.Second || *cfg.MailboxController.Concurrency != 2 {
+		t.Errorf("Parsed wrong: %+v", cfg)
+	}
+	if cfg.Syncer.InitialSyncParallelism != nil || cfg.PlacementTranslator.Concurrency != nil {
+		t.Error("Unset field got a value")
+	}
+	for _, bad := range []string{
+		"apiVersion: v1\nkind: ConfigMap\n",
+		"apiVersion: config.kubestellar.io/v1alpha1\nkind: KubeStellarConfiguration\nwhereResolver:\n  concurency: 4\n",
+		"apiVersion: config.kubestellar.io/v1alpha1\nkind: KubeStellarConfiguration\nwhereResolver:\n  concurrency: 0\n",
+		"apiVersion: config.kubestellar.io/v1alpha1\nkind: KubeStellarConfiguration\nplacementTranslator:\n  mailboxWriteConcurrency: -1\n",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	var given, defaulted int
+	fs.IntVar(&given, "given", 1, "")
+	fs.IntVar(&defaulted, "defaulted", 1, "")
+	if err := fs.Parse([]string{"--given=2"}); err != nil {
+		t.Fatal(err)
+	}
+	Override(fs, "given", func() { given = 5 })
+	Override(fs, "defaulted", func() { defaulted = 5 })
+	if given != 2 || defaulted != 5 {
+		t.Errorf("Flag precedence wrong: given=%d, defaulted=%d", given, defaulted)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(sample), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, rl, err := LoadForReload(klog.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []*Configuration
+	rl.OnChange(func(cfg *Configuration) { seen = append(seen, cfg) })
+	rl.Check()
+	if len(seen) != 0 {
+		t.Error("Handler called for unchanged file")
+	}
+	os.WriteFile(path, []byte("apiVersion: bogus\n"), 0o644)
+	rl.Check()
+	if len(seen) != 0 {
+		t.Error("Handler called for invalid file")
+	}
+	os.WriteFile(path, []byte(sample+"  heartbeatTimeout: 1m\n"), 0o644)
+	rl.Check()
+	if len(seen) != 1 || seen[0].Gateway.HeartbeatTimeout.Duration != time.Minute {
+		t.Errorf("Handler not called properly: %v", seen)
+	}
+}
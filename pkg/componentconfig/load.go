@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package componentconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/pflag"
+
+	"k8s.io/component-base/featuregate"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Load reads and validates the configuration file at the given path.
+func Load(path string) (*Configuration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates the given configuration file content.
+// Unknown fields are errors, so that typos do not go unnoticed.
+func Parse(data []byte) (*Configuration, error) {
+	cfg := &Configuration{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the version and the values.
+func (cfg *Configuration) Validate() error {
+	if cfg.APIVersion != APIVersion || cfg.Kind != Kind {
+		return fmt.Errorf("configuration has apiVersion %q and kind %q, expected %q and %q", cfg.APIVersion, cfg.Kind, APIVersion, Kind)
+	}
+	if cfg.Logging.Verbosity != nil && *cfg.Logging.Verbosity < 0 {
+		return errors.New("logging.verbosity must not be negative")
+	}
+	for _, section := range []struct {
+		prefix    string
+		transport TransportConfiguration
+	}{
+		{"transport", cfg.Transport},
+		{"whereResolver.transport", cfg.WhereResolver.Transport},
+		{"placementTranslator.transport", cfg.PlacementTranslator.Transport},
+		{"mailboxController.transport", cfg.MailboxController.Transport},
+	} {
+		if section.transport.QPS != nil && *section.transport.QPS < 0 {
+			return fmt.Errorf("%s.qps must not be negative", section.prefix)
+		}
+		if section.transport.Burst != nil && *section.transport.Burst < 0 {
+			return fmt.Errorf("%s.burst must not be negative", section.prefix)
+		}
+	}
+	if cfg.WhereResolver.Concurrency != nil && *cfg.WhereResolver.Concurrency < 1 {
+		return errors.New("whereResolver.concurrency must be positive")
+	}
+	if cfg.WhereResolver.OrphanGCPeriod != nil && cfg.WhereResolver.OrphanGCPeriod.Duration < 0 {
+		return errors.New("whereResolver.orphanGCPeriod must not be negative")
+	}
+	if pt := cfg.PlacementTranslator; pt.Concurrency != nil && *pt.Concurrency < 1 {
+		return errors.New("placementTranslator.concurrency must be positive")
+	}
+	if pt := cfg.PlacementTranslator; pt.OwnershipGCPeriod != nil && pt.OwnershipGCPeriod.Duration < 0 {
+		return errors.New("placementTranslator.ownershipGCPeriod must not be negative")
+	}
+	if pt := cfg.PlacementTranslator; pt.MailboxWriteConcurrency != nil && *pt.MailboxWriteConcurrency < 0 {
+		return errors.New("placementTranslator.mailboxWriteConcurrency must not be negative")
+	}
+	if pt := cfg.PlacementTranslator; pt.MailboxWriteMaxWait != nil && pt.MailboxWriteMaxWait.Duration < 0 {
+		return errors.New("placementTranslator.mailboxWriteMaxWait must not be negative")
+	}
+	if cfg.MailboxController.Concurrency != nil && *cfg.MailboxController.Concurrency < 1 {
+		return errors.New("mailboxController.concurrency must be positive")
+	}
+	if cfg.Gateway.HealthMinDuration != nil && cfg.Gateway.HealthMinDuration.Duration < 0 {
+		return errors.New("gateway.healthMinDuration must not be negative")
+	}
+	return nil
+}
+
+// Override calls set unless the named flag was given on the command line,
+// which takes precedence over the configuration file.
+func Override(fs *pflag.FlagSet, flagName string, set func()) {
+	if flg := fs.Lookup(flagName); flg != nil && flg.Changed {
+		return
+	}
+	set()
+}
+
+// TransportFor returns the transport settings of the binary whose own
+// section has the given ones: each that is unset there is taken from
+// the common Transport.
+func (cfg *Configuration) TransportFor(own TransportConfiguration) TransportConfiguration {
+	if own.QPS == nil {
+		own.QPS = cfg.Transport.QPS
+	}
+	if own.Burst == nil {
+		own.Burst = cfg.Transport.Burst
+	}
+	return own
+}
+
+// ApplyFeatureGates sets the configured feature gates in the given gate.
+func (cfg *Configuration) ApplyFeatureGates(gate featuregate.MutableFeatureGate) error {
+	if len(cfg.FeatureGates) == 0 {
+		return nil
+	}
+	return gate.SetFromMap(cfg.FeatureGates)
+}
+
+// ApplyVerbosity sets the klog verbosity, if it is configured.
+func (cfg *Configuration) ApplyVerbosity() error {
+	if cfg.Logging.Verbosity == nil {
+		return nil
+	}
+	var level klog.Level
+	return level.Set(strconv.Itoa(*cfg.Logging.Verbosity))
+}
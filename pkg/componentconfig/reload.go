@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package componentconfig
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// DefaultPollPeriod is how often a Reloader checks the file for changes.
+const DefaultPollPeriod = 10 * time.Second
+
+// Reloader re-reads the configuration file when it changes, or when the
+// process gets SIGHUP, and passes the new Configuration to its handlers.
+// Polling rather than file notifications copes with the symlink swaps
+// that Kubernetes does when a mounted ConfigMap changes.
+// A file that fails to load is logged and otherwise ignored.
+type Reloader struct {
+	logger     klog.Logger
+	path       string
+	pollPeriod time.Duration
+
+	mutex    sync.Mutex
+	handlers []func(*Configuration)
+	lastData []byte
+}
+
+// NewReloader makes a Reloader for the given path, whose current content
+// is the given data. A pollPeriod of zero means DefaultPollPeriod.
+func NewReloader(logger klog.Logger, path string, data []byte, pollPeriod time.Duration) *Reloader {
+	if pollPeriod <= 0 {
+		pollPeriod = DefaultPollPeriod
+	}
+	return &Reloader{logger: logger, path: path, pollPeriod: pollPeriod, lastData: data}
+}
+
+// LoadForReload reads the file at the given path and returns both its
+// Configuration and a Reloader for it.
+func LoadForReload(logger klog.Logger, path string) (*Configuration, *Reloader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, NewReloader(logger, path, data, 0), nil
+}
+
+// OnChange adds a handler for new configurations. Handlers are called
+// one at a time, in the order added, and should only apply tunables.
+func (rl *Reloader) OnChange(handler func(*Configuration)) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.handlers = append(rl.handlers, handler)
+}
+
+// Run watches for changes until the context is done.
+func (rl *Reloader) Run(ctx context.Context) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	ticker := time.NewTicker(rl.pollPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			rl.logger.V(1).Info("Got SIGHUP, reloading configuration", "path", rl.path)
+			rl.Check()
+		case <-ticker.C:
+			rl.Check()
+		}
+	}
+}
+
+// Check re-reads the file and, if its content changed and is valid,
+// calls the handlers.
+func (rl *Reloader) Check() {
+	data, err := os.ReadFile(rl.path)
+	if err != nil {
+		rl.logger.Error(err, "Failed to read configuration file", "path", rl.path)
+		return
+	}
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	if bytes.Equal(data, rl.lastData) {
+		return
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		rl.logger.Error(err, "Ignoring invalid configuration file", "path", rl.path)
+		return
+	}
+	rl.lastData = data
+	rl.logger.Info("Applying changed configuration", "path", rl.path)
+	for _, handler := range rl.handlers {
+		handler(cfg)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package componentconfig is the configuration file that KubeStellar
+// binaries read through their --config flag.
+//
+// One versioned file serves all of the binaries; each reads the common
+// sections and its own. A setting that is given both in the file and by
+// a command line flag takes its value from the flag. Unset fields leave
+// the flag defaults alone.
+//
+// Some settings are tunables that take effect when the file changes,
+// without a restart (see Reloader); the others are read only at startup.
+// Each field says which it is.
+package componentconfig
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// GroupName is the API group of the configuration file.
+	GroupName = "config.kubestellar.io"
+
+	// APIVersion is the only version of the configuration file so far.
+	APIVersion = GroupName + "/v1alpha1"
+
+	// Kind is the kind of the configuration file.
+	Kind = "KubeStellarConfiguration"
+)
+
+// Configuration is the content of the configuration file.
+type Configuration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// FeatureGates maps feature names to whether they are enabled, for
+	// the binaries that have feature gates. Read at startup.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// Logging applies to every binary.
+	Logging LoggingConfiguration `json:"logging,omitempty"`
+
+	// Transport sets --qps and --burst for every binary that has them,
+	// except where the binary's own section sets them. Read at startup.
+	Transport TransportConfiguration `json:"transport,omitempty"`
+
+	// Metrics applies to every binary that serves /metrics.
+	Metrics MetricsConfiguration `json:"metrics,omitempty"`
+
+	WhereResolver       WhereResolverConfiguration       `json:"whereResolver,omitempty"`
+	PlacementTranslator PlacementTranslatorConfiguration `json:"placementTranslator,omitempty"`
+	MailboxController   MailboxControllerConfiguration   `json:"mailboxController,omitempty"`
+	Syncer              SyncerConfiguration              `json:"syncer,omitempty"`
+	Gateway             GatewayConfiguration             `json:"gateway,omitempty"`
+}
+
+type LoggingConfiguration struct {
+	// Verbosity is the klog verbosity (-v). Tunable.
+	Verbosity *int `json:"verbosity,omitempty"`
+}
+
+type TransportConfiguration struct {
+	QPS   *float32 `json:"qps,omitempty"`
+	Burst *int     `json:"burst,omitempty"`
+}
+
+type MetricsConfiguration struct {
+	// LabelPolicy is as --metrics-label-policy. Read at startup.
+	LabelPolicy map[string]string `json:"labelPolicy,omitempty"`
+}
+
+type WhereResolverConfiguration struct {
+	// Transport overrides the common Transport. Read at startup.
+	Transport TransportConfiguration `json:"transport,omitempty"`
+
+	// Concurrency is the number of reconciliation workers. Read at startup.
+	Concurrency *int `json:"concurrency,omitempty"`
+
+	// OrphanGCPeriod is as --orphan-gc-period. Read at startup.
+	OrphanGCPeriod *metav1.Duration `json:"orphanGCPeriod,omitempty"`
+
+	// OrphanGCDryRun is as --orphan-gc-dry-run. Tunable.
+	OrphanGCDryRun *bool `json:"orphanGCDryRun,omitempty"`
+}
+
+type PlacementTranslatorConfiguration struct {
+	// Transport overrides the common Transport. Read at startup.
+	Transport TransportConfiguration `json:"transport,omitempty"`
+
+	// Concurrency is the number of workload projector workers. Read at startup.
+	Concurrency *int `json:"concurrency,omitempty"`
+
+	// OwnershipGCPeriod is as --ownership-gc-period. Read at startup.
+	OwnershipGCPeriod *metav1.Duration `json:"ownershipGCPeriod,omitempty"`
+
+	// MailboxWriteConcurrency is as --mailbox-write-concurrency. Read at startup.
+	MailboxWriteConcurrency *int `json:"mailboxWriteConcurrency,omitempty"`
+
+	// MailboxWriteMaxWait is as --mailbox-write-max-wait. Read at startup.
+	MailboxWriteMaxWait *metav1.Duration `json:"mailboxWriteMaxWait,omitempty"`
+}
+
+type MailboxControllerConfiguration struct {
+	// Transport overrides the common Transport. Read at startup.
+	Transport TransportConfiguration `json:"transport,omitempty"`
+
+	// Concurrency is the number of reconciliation workers. Read at startup.
+	Concurrency *int `json:"concurrency,omitempty"`
+}
+
+type SyncerConfiguration struct {
+	// CredentialReloadPeriod is as --credential-reload-period. Read at startup.
+	CredentialReloadPeriod *metav1.Duration `json:"credentialReloadPeriod,omitempty"`
+
+	// InitialSyncParallelism is as --initial-sync-parallelism. Read at startup.
+	InitialSyncParallelism *int `json:"initialSyncParallelism,omitempty"`
+
+	// InitialSyncPageSize is as --initial-sync-page-size. Read at startup.
+	InitialSyncPageSize *int64 `json:"initialSyncPageSize,omitempty"`
+
+	// ResourceSyncPolicies are as the values of --resource-sync-policy. Read at startup.
+	ResourceSyncPolicies []string `json:"resourceSyncPolicies,omitempty"`
+}
+
+type GatewayConfiguration struct {
+	// HealthMinDuration is the debounce interval, as --health-min-duration. Tunable.
+	HealthMinDuration *metav1.Duration `json:"healthMinDuration,omitempty"`
+
+	// HeartbeatTimeout is as --heartbeat-timeout. Read at startup.
+	HeartbeatTimeout *metav1.Duration `json:"heartbeatTimeout,omitempty"`
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conditions manipulates the `conditions` in the status of
+// KubeStellar API objects.
+// These are standard `metav1.Condition` values, and this package
+// gives them uniform treatment so that integrators can interpret them
+// the same way that KubeStellar does.
+package conditions
+
+import (
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReadyType is the type of the condition that summarizes all the others.
+const ReadyType = "Ready"
+
+// Severity says how bad it is when a condition does not have its desired status.
+// Higher values are worse.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (sev Severity) String() string {
+	switch sev {
+	case SeverityInfo:
+		return "Info"
+	case SeverityWarning:
+		return "Warning"
+	case SeverityError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+// Find returns a pointer to the condition of the given type, or nil if there is none.
+func Find(conditions []metav1.Condition, condType string) *metav1.Condition {
+	for idx := range conditions {
+		if conditions[idx].Type == condType {
+			return &conditions[idx]
+		}
+	}
+	return nil
+}
+
+// IsTrue tells whether the condition of the given type is present with status True.
+func IsTrue(conditions []metav1.Condition, condType string) bool {
+	cond := Find(conditions, condType)
+	return cond != nil && cond.Status == metav1.ConditionTrue
+}
+
+// IsFalse tells whether the condition of the given type is present with status False.
+func IsFalse(conditions []metav1.Condition, condType string) bool {
+	cond := Find(conditions, condType)
+	return cond != nil && cond.Status == metav1.ConditionFalse
+}
+
+// Set adds or updates the condition with the type of the given one.
+// The LastTransitionTime is preserved if the status does not change;
+// otherwise it is taken from the given condition or, if that is zero, `now`.
+// Returns whether anything changed.
+func Set(conditions *[]metav1.Condition, newCond metav1.Condition, now metav1.Time) bool {
+	existing := Find(*conditions, newCond.Type)
+	if existing == nil {
+		if newCond.LastTransitionTime.IsZero() {
+			newCond.LastTransitionTime = now
+		}
+		*conditions = append(*conditions, newCond)
+		return true
+	}
+	changed := false
+	if existing.Status != newCond.Status {
+		existing.Status = newCond.Status
+		existing.LastTransitionTime = newCond.LastTransitionTime
+		if existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = now
+		}
+		changed = true
+	}
+	if existing.Reason != newCond.Reason {
+		existing.Reason = newCond.Reason
+		changed = true
+	}
+	if existing.Message != newCond.Message {
+		existing.Message = newCond.Message
+		changed = true
+	}
+	if existing.ObservedGeneration != newCond.ObservedGeneration {
+		existing.ObservedGeneration = newCond.ObservedGeneration
+		changed = true
+	}
+	return changed
+}
+
+// Remove deletes the condition of the given type, returning whether it was present.
+func Remove(conditions *[]metav1.Condition, condType string) bool {
+	for idx, cond := range *conditions {
+		if cond.Type == condType {
+			*conditions = append((*conditions)[:idx:idx], (*conditions)[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Merge folds the `from` conditions into `into`.
+// For a type present in both, the one with the higher ObservedGeneration wins;
+// for equal generations, the one with the later LastTransitionTime wins.
+// Returns whether `into` changed.
+func Merge(into *[]metav1.Condition, from []metav1.Condition, now metav1.Time) bool {
+	changed := false
+	for _, cond := range from {
+		existing := Find(*into, cond.Type)
+		if existing != nil && (existing.ObservedGeneration > cond.ObservedGeneration ||
+			existing.ObservedGeneration == cond.ObservedGeneration && cond.LastTransitionTime.Before(&existing.LastTransitionTime)) {
+			continue
+		}
+		changed = Set(into, cond, now) || changed
+	}
+	return changed
+}
+
+// AggregateReady computes a Ready condition from the conditions of the given types,
+// all of which are wanted to be True.
+// The map gives the severity of each type; when some are not True, the reason
+// of the summary is taken from the most severe of them (ties broken by type)
+// and the message lists them all.
+// A condition that is absent counts as Unknown.
+func AggregateReady(conditions []metav1.Condition, severities map[string]Severity, observedGeneration int64) metav1.Condition {
+	types := make([]string, 0, len(severities))
+	for condType := range severities {
+		types = append(types, condType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		si, sj := severities[types[i]], severities[types[j]]
+		return si > sj || si == sj && types[i] < types[j]
+	})
+	ans := metav1.Condition{Type: ReadyType, Status: metav1.ConditionTrue, Reason: "AllReady", ObservedGeneration: observedGeneration}
+	var problems []string
+	for _, condType := range types {
+		cond := Find(conditions, condType)
+		status := metav1.ConditionUnknown
+		reason := "Missing"
+		if cond != nil {
+			status, reason = cond.Status, cond.Reason
+		}
+		if status == metav1.ConditionTrue {
+			continue
+		}
+		if len(problems) == 0 {
+			ans.Reason = reason
+		}
+		if status == metav1.ConditionFalse {
+			ans.Status = metav1.ConditionFalse
+		} else if ans.Status == metav1.ConditionTrue {
+			ans.Status = metav1.ConditionUnknown
+		}
+		problems = append(problems, condType+" is "+string(status)+" ("+severities[condType].String()+": "+reason+")")
+	}
+	ans.Message = strings.Join(problems, "; ")
+	return ans
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetPreservesTransitionTime(t *testing.T) {
+	t0 := metav1.NewTime(time.Unix(1000, 0))
+	t1 := metav1.NewTime(time.Unix(2000, 0))
+	var conds []metav1.Condition
+	if !Set(&conds, metav1.Condition{Type: "A", Status: metav1.ConditionTrue, Reason: "R1"}, t0) {
+		t.Fatalf("Expected first Set to report a change")
+	}
+	if !Set(&conds, metav1.Condition{Type: "A", Status: metav1.ConditionTrue, Reason: "R2"}, t1) {
+		t.Fatalf("Expected reason change to be reported")
+	}
+	if got := conds[0].LastTransitionTime; !got.Equal(&t0) {
+		t.Errorf("Expected LastTransitionTime %v to be preserved, got %v", t0, got)
+	}
+	if Set(&conds, metav1.Condition{Type: "A", Status: metav1.ConditionTrue, Reason: "R2"}, t1) {
+		t.Errorf("Expected no change to be reported")
+	}
+	Set(&conds, metav1.Condition{Type: "A", Status: metav1.ConditionFalse, Reason: "R3"}, t1)
+	if got := conds[0].LastTransitionTime; !got.Equal(&t1) {
+		t.Errorf("Expected LastTransitionTime %v after status change, got %v", t1, got)
+	}
+	if !Remove(&conds, "A") || len(conds) != 0 {
+		t.Errorf("Expected Remove to delete the condition, got %v", conds)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	now := metav1.NewTime(time.Unix(3000, 0))
+	into := []metav1.Condition{{Type: "A", Status: metav1.ConditionTrue, Reason: "Old", ObservedGeneration: 2}}
+	from := []metav1.Condition{
+		{Type: "A", Status: metav1.ConditionFalse, Reason: "Stale", ObservedGeneration: 1},
+		{Type: "B", Status: metav1.ConditionTrue, Reason: "New", ObservedGeneration: 1},
+	}
+	if !Merge(&into, from, now) {
+		t.Fatalf("Expected Merge to report a change")
+	}
+	if !IsTrue(into, "A") || Find(into, "A").Reason != "Old" {
+		t.Errorf("Expected newer generation of A to win, got %v", Find(into, "A"))
+	}
+	if !IsTrue(into, "B") {
+		t.Errorf("Expected B to be merged in, got %v", into)
+	}
+}
+
+func TestAggregateReady(t *testing.T) {
+	severities := map[string]Severity{"Synced": SeverityError, "Healthy": SeverityWarning, "Cached": SeverityInfo}
+	conds := []metav1.Condition{
+		{Type: "Synced", Status: metav1.ConditionTrue, Reason: "Done"},
+		{Type: "Healthy", Status: metav1.ConditionFalse, Reason: "ProbeFailed"},
+	}
+	ready := AggregateReady(conds, severities, 7)
+	if ready.Status != metav1.ConditionFalse || ready.Reason != "ProbeFailed" || ready.ObservedGeneration != 7 {
+		t.Errorf("Unexpected aggregate %#v", ready)
+	}
+	conds[1].Status = metav1.ConditionTrue
+	conds = append(conds, metav1.Condition{Type: "Cached", Status: metav1.ConditionTrue, Reason: "Yes"})
+	if ready := AggregateReady(conds, severities, 7); ready.Status != metav1.ConditionTrue {
+		t.Errorf("Expected Ready=True, got %#v", ready)
+	}
+	if ready := AggregateReady(conds[:2], severities, 7); ready.Status != metav1.ConditionUnknown || ready.Reason != "Missing" {
+		t.Errorf("Expected Ready=Unknown for missing condition, got %#v", ready)
+	}
+}
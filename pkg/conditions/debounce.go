@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Debouncer suppresses flapping in reported states.
+// For each key it remembers the reported state; a different observed
+// state is only reported once it has been observed continuously for
+// the minimum duration. The first observation for a key is reported
+// immediately. A zero minimum duration makes the Debouncer report every
+// observation.
+type Debouncer[State comparable] struct {
+	mutex       sync.Mutex
+	minDuration time.Duration
+	entries     map[string]*debounceEntry[State]
+}
+
+type debounceEntry[State comparable] struct {
+	reported     State
+	pending      State
+	havePending  bool
+	pendingSince time.Time
+}
+
+// NewDebouncer makes a Debouncer with the given minimum duration.
+func NewDebouncer[State comparable](minDuration time.Duration) *Debouncer[State] {
+	return &Debouncer[State]{minDuration: minDuration, entries: map[string]*debounceEntry[State]{}}
+}
+
+// SetMinDuration changes the minimum duration. It may be called at any
+// time; changes already being held back are judged by the new duration.
+func (deb *Debouncer[State]) SetMinDuration(minDuration time.Duration) {
+	deb.mutex.Lock()
+	defer deb.mutex.Unlock()
+	deb.minDuration = minDuration
+}
+
+// Filter records the given observation and returns the state to report for the key.
+func (deb *Debouncer[State]) Filter(key string, observed State, now time.Time) State {
+	deb.mutex.Lock()
+	defer deb.mutex.Unlock()
+	if deb.minDuration <= 0 {
+		return observed
+	}
+	entry := deb.entries[key]
+	if entry == nil {
+		deb.entries[key] = &debounceEntry[State]{reported: observed}
+		return observed
+	}
+	if observed == entry.reported {
+		entry.havePending = false
+		return entry.reported
+	}
+	if !entry.havePending || observed != entry.pending {
+		entry.pending, entry.havePending, entry.pendingSince = observed, true, now
+	}
+	if now.Sub(entry.pendingSince) >= deb.minDuration {
+		entry.reported, entry.havePending = observed, false
+	}
+	return entry.reported
+}
+
+// Retain forgets the keys for which keep returns false.
+func (deb *Debouncer[State]) Retain(keep func(key string) bool) {
+	deb.mutex.Lock()
+	defer deb.mutex.Unlock()
+	for key := range deb.entries {
+		if !keep(key) {
+			delete(deb.entries, key)
+		}
+	}
+}
+
+// ConditionDebouncer applies a Debouncer to the Status of conditions.
+// While a change in Status is being held back, the previously reported
+// condition is reported in its place.
+type ConditionDebouncer struct {
+	statuses *Debouncer[metav1.ConditionStatus]
+
+	mutex    sync.Mutex
+	reported map[string]metav1.Condition
+}
+
+// NewConditionDebouncer makes a ConditionDebouncer with the given minimum duration.
+func NewConditionDebouncer(minDuration time.Duration) *ConditionDebouncer {
+	return &ConditionDebouncer{statuses: NewDebouncer[metav1.ConditionStatus](minDuration), reported: map[string]metav1.Condition{}}
+}
+
+// SetMinDuration changes the minimum duration; see Debouncer.SetMinDuration.
+func (cd *ConditionDebouncer) SetMinDuration(minDuration time.Duration) {
+	cd.statuses.SetMinDuration(minDuration)
+}
+
+// Filter records the given observed condition and returns the one to report.
+// The key identifies the object; the condition type is added to it.
+func (cd *ConditionDebouncer) Filter(key string, observed metav1.Condition, now time.Time) metav1.Condition {
+	fullKey := key + "\x00" + observed.Type
+	status := cd.statuses.Filter(fullKey, observed.Status, now)
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	if status == observed.Status {
+		cd.reported[fullKey] = observed
+		return observed
+	}
+	return cd.reported[fullKey]
+}
+
+// FilterAll applies Filter to each of the given conditions, returning a new slice.
+func (cd *ConditionDebouncer) FilterAll(key string, observed []metav1.Condition, now time.Time) []metav1.Condition {
+	if observed == nil {
+		return nil
+	}
+	ans := make([]metav1.Condition, len(observed))
+	for idx, cond := range observed {
+		ans[idx] = cd.Filter(key, cond, now)
+	}
+	return ans
+}
+
+// Retain forgets the objects for which keep returns false.
+func (cd *ConditionDebouncer) Retain(keep func(key string) bool) {
+	keepFull := func(fullKey string) bool {
+		key, _, _ := strings.Cut(fullKey, "\x00")
+		return keep(key)
+	}
+	cd.statuses.Retain(keepFull)
+	cd.mutex.Lock()
+	defer cd.mutex.Unlock()
+	for fullKey := range cd.reported {
+		if !keepFull(fullKey) {
+			delete(cd.reported, fullKey)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conditions
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDebouncer(t *testing.T) {
+	start := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	deb := NewDebouncer[string](time.Minute)
+	for idx, step := range []struct {
+		offset   time.Duration
+		observed string
+		expected string
+	}{
+		{0, "Ready", "Ready"},
+		{10 * time.Second, "Degraded", "Ready"},
+		{20 * time.Second, "Ready", "Ready"}, // flap suppressed
+		{30 * time.Second, "Degraded", "Ready"},
+		{80 * time.Second, "Degraded", "Ready"},
+		{90 * time.Second, "Degraded", "Degraded"}, // held for a minute
+		{100 * time.Second, "Ready", "Degraded"},
+	} {
+		if actual := deb.Filter("ep1", step.observed, start.Add(step.offset)); actual != step.expected {
+			t.Errorf("step %d: expected %q, got %q", idx, step.expected, actual)
+		}
+	}
+	deb.Retain(func(string) bool { return false })
+	if actual := deb.Filter("ep1", "Ready", start.Add(110*time.Second)); actual != "Ready" {
+		t.Errorf("expected a forgotten key to report immediately, got %q", actual)
+	}
+}
+
+func TestConditionDebouncer(t *testing.T) {
+	start := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	cd := NewConditionDebouncer(time.Minute)
+	good := metav1.Condition{Type: "Synced", Status: metav1.ConditionTrue, Reason: "AllSynced"}
+	bad := metav1.Condition{Type: "Synced", Status: metav1.ConditionFalse, Reason: "SomeFailed"}
+	if actual := cd.Filter("ep1", good, start); actual != good {
+		t.Errorf("expected %v, got %v", good, actual)
+	}
+	if actual := cd.Filter("ep1", bad, start.Add(time.Second)); actual != good {
+		t.Errorf("expected the change to be held back, got %v", actual)
+	}
+	if actual := cd.Filter("ep1", bad, start.Add(time.Minute+time.Second)); actual != bad {
+		t.Errorf("expected the change to be reported, got %v", actual)
+	}
+	if actual := cd.Filter("ep2", bad, start); actual != bad {
+		t.Errorf("expected other objects to be independent, got %v", actual)
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+// The names of the cases.
+const (
+	CasePlacement     = "placement-matching"
+	CaseDownsync      = "downsync"
+	CaseCustomization = "customization"
+	CaseStatusReturn  = "status-return"
+	CaseDeletion      = "deletion"
+)
+
+// SuiteLabelKey labels the objects that the suite creates.
+const SuiteLabelKey = "conformance.kubestellar.io/suite"
+
+// DefaultImage is the default Environment.Image.
+const DefaultImage = "registry.k8s.io/pause:3.9"
+
+const (
+	plainName      = "plain"
+	customizedName = "customized"
+	deploymentName = "reported"
+)
+
+// DefaultCases returns the cases of the suite, in the order to run them.
+func DefaultCases() []Case {
+	return []Case{{
+		Name:        CasePlacement,
+		Description: "an EdgePlacement gets exactly the one Location that its selector matches",
+		Run:         checkPlacement,
+	}, {
+		Name:        CaseDownsync,
+		Description: "a selected ConfigMap appears, as it is, in the workload execution cluster",
+		Needs:       []string{CasePlacement},
+		Run:         checkDownsync,
+	}, {
+		Name:        CaseCustomization,
+		Description: "a Customizer changes the copy of the object that refers to it",
+		Needs:       []string{CasePlacement},
+		Run:         checkCustomization,
+	}, {
+		Name:        CaseStatusReturn,
+		Description: "the state reported in the workload execution cluster comes back to the workload description space",
+		Needs:       []string{CasePlacement},
+		Run:         checkStatusReturn,
+	}, {
+		Name:        CaseDeletion,
+		Description: "deleting a selected object deletes its copy",
+		Needs:       []string{CaseDownsync},
+		Run:         checkDeletion,
+	}}
+}
+
+func suiteLabels() map[string]string {
+	return map[string]string{SuiteLabelKey: "true"}
+}
+
+func checkPlacement(ctx context.Context, env *Environment) error {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: env.Namespace, Labels: suiteLabels()}}
+	if _, err := env.WDSKube.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create namespace %q: %w", env.Namespace, err)
+	}
+	coreGroup, appsGroup := "", appsv1.GroupName
+	ep := &edgeapi.EdgePlacement{
+		ObjectMeta: metav1.ObjectMeta{Name: env.Namespace, Labels: suiteLabels()},
+		Spec: edgeapi.EdgePlacementSpec{
+			LocationSelectors: []metav1.LabelSelector{env.LocationSelector},
+			Downsync: []edgeapi.DownsyncObjectTest{
+				{APIGroup: &coreGroup, Resources: []string{"namespaces"}, ObjectNames: []string{env.Namespace}},
+				{APIGroup: &coreGroup, Resources: []string{"configmaps"}, Namespaces: []string{env.Namespace}},
+				{APIGroup: &appsGroup, Resources: []string{"deployments"}, Namespaces: []string{env.Namespace}},
+			},
+			WantSingletonReportedState: true,
+		},
+	}
+	if _, err := env.WDSEdge.EdgeV2alpha1().EdgePlacements().Create(ctx, ep, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create EdgePlacement %q: %w", ep.Name, err)
+	}
+	spsName := naming.SinglePlacementSliceName(ep.Name)
+	return env.eventually(ctx, "resolution of the EdgePlacement to one Location", func(ctx context.Context) (bool, error) {
+		sps, err := env.WDSEdge.EdgeV2alpha1().SinglePlacementSlices().Get(ctx, spsName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if len(sps.Destinations) != 1 {
+			return false, fmt.Errorf("SinglePlacementSlice %q has %d destinations", spsName, len(sps.Destinations))
+		}
+		return true, nil
+	})
+}
+
+func checkDownsync(ctx context.Context, env *Environment) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: env.Namespace, Name: plainName, Labels: suiteLabels()},
+		Data:       map[string]string{"greeting": "hello"},
+	}
+	if _, err := env.WDSKube.CoreV1().ConfigMaps(env.Namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create ConfigMap %q: %w", cm.Name, err)
+	}
+	return env.eventually(ctx, "downsync of ConfigMap "+cm.Name, func(ctx context.Context) (bool, error) {
+		return configMapHas(ctx, env, cm.Name, "greeting", "hello")
+	})
+}
+
+func checkCustomization(ctx context.Context, env *Environment) error {
+	customizer := &edgeapi.Customizer{
+		ObjectMeta:   metav1.ObjectMeta{Namespace: env.Namespace, Name: customizedName, Labels: suiteLabels()},
+		Replacements: []edgeapi.Replacement{{Path: "$.data.greeting", Value: `"customized"`}},
+	}
+	if _, err := env.WDSEdge.EdgeV2alpha1().Customizers(env.Namespace).Create(ctx, customizer, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create Customizer %q: %w", customizer.Name, err)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: env.Namespace, Name: customizedName, Labels: suiteLabels(),
+			Annotations: map[string]string{edgeapi.CustomizerAnnotationKey: customizer.Name}},
+		Data: map[string]string{"greeting": "hello"},
+	}
+	if _, err := env.WDSKube.CoreV1().ConfigMaps(env.Namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create ConfigMap %q: %w", cm.Name, err)
+	}
+	return env.eventually(ctx, "downsync of customized ConfigMap "+cm.Name, func(ctx context.Context) (bool, error) {
+		return configMapHas(ctx, env, cm.Name, "greeting", "customized")
+	})
+}
+
+// configMapHas tells whether the named ConfigMap in the WEC has the given value at the given key.
+func configMapHas(ctx context.Context, env *Environment, name, key, value string) (bool, error) {
+	cm, err := env.WECKube.CoreV1().ConfigMaps(env.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	if actual, found := cm.Data[key]; !found || actual != value {
+		return false, fmt.Errorf("ConfigMap %q has %s=%q rather than %q", name, key, actual, value)
+	}
+	return true, nil
+}
+
+// checkStatusReturn uses a Deployment with no replicas, whose status
+// the WEC's deployment controller sets without running anything.
+func checkStatusReturn(ctx context.Context, env *Environment) error {
+	labels := map[string]string{"app": deploymentName}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: env.Namespace, Name: deploymentName, Labels: suiteLabels()},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32(0),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "pause", Image: env.image()}}},
+			},
+		},
+	}
+	if _, err := env.WDSKube.AppsV1().Deployments(env.Namespace).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create Deployment %q: %w", deployment.Name, err)
+	}
+	return env.eventually(ctx, "return of the state of Deployment "+deployment.Name, func(ctx context.Context) (bool, error) {
+		wecCopy, err := env.WECKube.AppsV1().Deployments(env.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if wecCopy.Status.ObservedGeneration == 0 {
+			return false, fmt.Errorf("the copy of Deployment %q has no reported state yet", deployment.Name)
+		}
+		original, err := env.WDSKube.AppsV1().Deployments(env.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if original.Status.ObservedGeneration != wecCopy.Status.ObservedGeneration {
+			return false, fmt.Errorf("Deployment %q has observedGeneration %d while its copy has %d", deployment.Name,
+				original.Status.ObservedGeneration, wecCopy.Status.ObservedGeneration)
+		}
+		return true, nil
+	})
+}
+
+func checkDeletion(ctx context.Context, env *Environment) error {
+	if err := env.WDSKube.CoreV1().ConfigMaps(env.Namespace).Delete(ctx, plainName, metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to delete ConfigMap %q: %w", plainName, err)
+	}
+	return env.eventually(ctx, "deletion of the copy of ConfigMap "+plainName, func(ctx context.Context) (bool, error) {
+		_, err := env.WECKube.CoreV1().ConfigMaps(env.Namespace).Get(ctx, plainName, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return false, fmt.Errorf("the copy of ConfigMap %q still exists", plainName)
+	})
+}
+
+// Cleanup deletes, from the workload description space, what the suite
+// created there. KubeStellar then removes the copies.
+func Cleanup(ctx context.Context, env *Environment) error {
+	err := env.WDSEdge.EdgeV2alpha1().EdgePlacements().Delete(ctx, env.Namespace, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete EdgePlacement %q: %w", env.Namespace, err)
+	}
+	err = env.WDSKube.CoreV1().Namespaces().Delete(ctx, env.Namespace, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete namespace %q: %w", env.Namespace, err)
+	}
+	return nil
+}
+
+func (env *Environment) image() string {
+	if env.Image == "" {
+		return DefaultImage
+	}
+	return env.Image
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance is a suite of checks of the behavior that
+// KubeStellar promises its users, made against a live installation:
+// an EdgePlacement selects a Location, the selected workload objects go
+// down to its workload execution cluster, a Customizer changes them on
+// the way, the reported state comes back, and deleting an object
+// removes its copy. It does not care how the installation moves
+// objects, so it serves to validate transports and space providers.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+)
+
+// DefaultTimeout is the default limit on how long a case waits for the
+// installation to do its part.
+const DefaultTimeout = 2 * time.Minute
+
+// Environment is the installation under test.
+type Environment struct {
+	// WDSKube and WDSEdge are clients of the workload description
+	// space, in which the suite creates the EdgePlacement and the workload.
+	WDSKube kubernetes.Interface
+	WDSEdge edgeclientset.Interface
+
+	// WECKube is a client of the workload execution cluster of the
+	// selected Location.
+	WECKube kubernetes.Interface
+
+	// LocationSelector selects the one Location whose cluster WECKube reaches.
+	LocationSelector metav1.LabelSelector
+
+	// Namespace is the namespace, in both the WDS and the WEC, that the
+	// suite uses; it must not exist before. It is also the name of the
+	// EdgePlacement.
+	Namespace string
+
+	// Timeout limits how long one case waits. Zero means DefaultTimeout.
+	Timeout time.Duration
+
+	// Image is the container image of the Deployment used to check
+	// the return of reported state. It never runs.
+	Image string
+}
+
+// Case is one check of the suite.
+type Case struct {
+	Name string
+
+	// Description says what the case checks, for the report.
+	Description string
+
+	// Needs names the cases that must pass before this one can run.
+	Needs []string
+
+	Run func(ctx context.Context, env *Environment) error
+}
+
+// Result is the outcome of one Case.
+type Result struct {
+	Name        string
+	Description string
+	Duration    time.Duration
+
+	// Err is why the case failed; nil means it passed or was skipped.
+	Err error
+
+	// Skipped, if not empty, is why the case did not run.
+	Skipped string
+}
+
+// Passed tells whether the case ran and passed.
+func (result Result) Passed() bool {
+	return result.Err == nil && result.Skipped == ""
+}
+
+// Run runs the given cases in order, skipping those that do not match
+// the given focus (if not nil) and those that need a case that did not
+// pass. Each case has env.Timeout for its waiting.
+func Run(ctx context.Context, env *Environment, cases []Case, focus *regexp.Regexp) []Result {
+	logger := klog.FromContext(ctx)
+	passed := map[string]bool{}
+	results := make([]Result, 0, len(cases))
+	for _, tc := range cases {
+		result := Result{Name: tc.Name, Description: tc.Description}
+		if focus != nil && !focus.MatchString(tc.Name) && !neededByFocus(tc.Name, cases, focus) {
+			result.Skipped = "not in focus"
+			results = append(results, result)
+			continue
+		}
+		for _, need := range tc.Needs {
+			if !passed[need] {
+				result.Skipped = fmt.Sprintf("needs %s, which did not pass", need)
+				break
+			}
+		}
+		if result.Skipped == "" {
+			logger.Info("Running case", "case", tc.Name)
+			start := time.Now()
+			result.Err = tc.Run(ctx, env)
+			result.Duration = time.Since(start)
+			passed[tc.Name] = result.Err == nil
+			logger.Info("Ran case", "case", tc.Name, "duration", result.Duration, "err", result.Err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// neededByFocus tells whether a case in focus needs, directly or not, the named one.
+func neededByFocus(name string, cases []Case, focus *regexp.Regexp) bool {
+	needs := map[string][]string{}
+	for _, tc := range cases {
+		needs[tc.Name] = tc.Needs
+	}
+	var needed func(from string, seen map[string]bool) bool
+	needed = func(from string, seen map[string]bool) bool {
+		if seen[from] {
+			return false
+		}
+		seen[from] = true
+		for _, need := range needs[from] {
+			if need == name || needed(need, seen) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, tc := range cases {
+		if focus.MatchString(tc.Name) && needed(tc.Name, map[string]bool{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventually waits until the given condition holds, for at most the
+// environment's timeout. The error says what was awaited and, if the
+// condition gave one, why it did not hold at the end.
+func (env *Environment) eventually(ctx context.Context, what string, condition func(context.Context) (bool, error)) error {
+	timeout := env.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	var lastErr error
+	err := wait.PollImmediateWithContext(ctx, time.Second, timeout, func(ctx context.Context) (bool, error) {
+		done, err := condition(ctx)
+		lastErr = err
+		return done && err == nil, nil
+	})
+	if err == nil {
+		return nil
+	}
+	if lastErr != nil {
+		return fmt.Errorf("%s did not happen within %v: %w", what, timeout, lastErr)
+	}
+	return fmt.Errorf("%s did not happen within %v", what, timeout)
+}
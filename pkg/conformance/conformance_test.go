@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgefake "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/fake"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+func TestRun(t *testing.T) {
+	var ran []string
+	step := func(name string, err error) func(context.Context, *Environment) error {
+		return func(context.Context, *Environment) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+	cases := []Case{
+		{Name: "a", Run: step("a", nil)},
+		{Name: "b", Needs: []string{"a"}, Run: step("b", errors.New("broken"))},
+		{Name: "c", Needs: []string{"b"}, Run: step("c", nil)},
+		{Name: "d", Needs: []string{"a"}, Run: step("d", nil)},
+		{Name: "e", Run: step("e", nil)},
+	}
+	results := Run(context.Background(), &Environment{}, cases, regexp.MustCompile("^[bcd]$"))
+	if expected := "a,b,d"; strings.Join(ran, ",") != expected {
+		t.Errorf("Expected to run %s, ran %v", expected, ran)
+	}
+	outcomes := map[string]string{}
+	for _, result := range results {
+		switch {
+		case result.Skipped != "":
+			outcomes[result.Name] = "skip"
+		case result.Err != nil:
+			outcomes[result.Name] = "fail"
+		default:
+			outcomes[result.Name] = "pass"
+		}
+	}
+	expected := map[string]string{"a": "pass", "b": "fail", "c": "skip", "d": "pass", "e": "skip"}
+	for name, outcome := range expected {
+		if outcomes[name] != outcome {
+			t.Errorf("Expected %s to %s, got %v", name, outcome, outcomes)
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := []Result{
+		{Name: "a", Duration: 1500 * time.Millisecond},
+		{Name: "b", Duration: time.Second, Err: errors.New("broken")},
+		{Name: "c", Skipped: "needs b, which did not pass"},
+	}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "kubestellar", results); err != nil {
+		t.Fatal(err)
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse the report: %v\n%s", err, buf.String())
+	}
+	if len(report.Suites) != 1 {
+		t.Fatalf("Expected one suite, got %d", len(report.Suites))
+	}
+	suite := report.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 || suite.Time != "2.500" {
+		t.Errorf("Unexpected totals in %+v", suite)
+	}
+	if len(suite.Cases) != 3 || suite.Cases[1].Failure == nil || suite.Cases[1].Failure.Message != "broken" || suite.Cases[2].Skipped == nil {
+		t.Errorf("Unexpected cases in %+v", suite.Cases)
+	}
+}
+
+func TestCases(t *testing.T) {
+	ctx := context.Background()
+	env := &Environment{
+		WDSKube:   kubefake.NewSimpleClientset(),
+		WDSEdge:   edgefake.NewSimpleClientset(&edgeapi.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: naming.SinglePlacementSliceName("conformance")}, Destinations: []edgeapi.SinglePlacement{{LocationName: "loc1"}}}),
+		WECKube:   kubefake.NewSimpleClientset(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "conformance", Name: plainName}, Data: map[string]string{"greeting": "hello"}}),
+		Namespace: "conformance",
+		Timeout:   2 * time.Second,
+	}
+	if err := checkPlacement(ctx, env); err != nil {
+		t.Errorf("Placement failed: %v", err)
+	}
+	if _, err := env.WDSEdge.EdgeV2alpha1().EdgePlacements().Get(ctx, "conformance", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the EdgePlacement to be created: %v", err)
+	}
+	if err := checkDownsync(ctx, env); err != nil {
+		t.Errorf("Downsync failed: %v", err)
+	}
+	err := checkCustomization(ctx, env)
+	if err == nil || !strings.Contains(err.Error(), "downsync of customized ConfigMap") {
+		t.Errorf("Expected customization to time out, got %v", err)
+	}
+	if err := checkDeletion(ctx, env); err == nil || !strings.Contains(err.Error(), "still exists") {
+		t.Errorf("Expected deletion to time out on the remaining copy, got %v", err)
+	}
+	if err := Cleanup(ctx, env); err != nil {
+		t.Errorf("Cleanup failed: %v", err)
+	}
+}
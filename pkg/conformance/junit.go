@@ -0,0 +1,106 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conformance
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The JUnit XML that CI systems read.
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the given results as a JUnit XML report of one
+// test suite with the given name.
+func WriteJUnit(w io.Writer, suiteName string, results []Result) error {
+	suite := junitSuite{Name: suiteName, Tests: len(results)}
+	var total time.Duration
+	for _, result := range results {
+		total += result.Duration
+		jc := junitCase{Name: result.Name, ClassName: suiteName, Time: seconds(result.Duration), SystemOut: result.Description}
+		switch {
+		case result.Skipped != "":
+			suite.Skipped++
+			jc.Skipped = &junitMessage{Message: result.Skipped}
+		case result.Err != nil:
+			suite.Failures++
+			jc.Failure = &junitMessage{Message: result.Err.Error()}
+		}
+		suite.Cases = append(suite.Cases, jc)
+	}
+	suite.Time = seconds(total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteSummary writes one line per result, for people.
+func WriteSummary(w io.Writer, results []Result) error {
+	for _, result := range results {
+		var line string
+		switch {
+		case result.Skipped != "":
+			line = fmt.Sprintf("SKIP  %s: %s", result.Name, result.Skipped)
+		case result.Err != nil:
+			line = fmt.Sprintf("FAIL  %s (%s): %v", result.Name, result.Duration.Round(time.Millisecond), result.Err)
+		default:
+			line = fmt.Sprintf("PASS  %s (%s)", result.Name, result.Duration.Round(time.Millisecond))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,341 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crdinstall installs and upgrades CustomResourceDefinitions
+// so that an upgrade of KubeStellar does not need a careful sequence of
+// `kubectl apply` invocations.
+//
+// Upgrading a CRD can drop a version that objects are still stored
+// in; the API server refuses that. So an upgrade is done in steps.
+// First the new spec is written but with each dropped version that is
+// still in `status.storedVersions` kept, unserved. Then every existing
+// object is rewritten, which stores it in the new storage version, and
+// `status.storedVersions` is set to just that version. Finally the new
+// spec is written as given.
+//
+// The hash of each installed spec is recorded in an annotation, so
+// that a CRD which is already as desired is left alone.
+package crdinstall
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubestellar/kubestellar/pkg/storagemigration"
+)
+
+// SpecHashAnnotationKey is the annotation that records the hash of the
+// spec that the Installer last completely installed.
+const SpecHashAnnotationKey = "edge.kubestellar.io/crd-spec-hash"
+
+// DefaultEstablishTimeout is how long, by default, to wait for a
+// CRD to become Established.
+const DefaultEstablishTimeout = 2 * time.Minute
+
+// Load reads the CRDs in the YAML files directly in the given file system.
+// The CRDs are returned in the order of the file names.
+func Load(fsys fs.FS) ([]*apiext.CustomResourceDefinition, error) {
+	paths, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var crds []*apiext.CustomResourceDefinition
+	for _, path := range paths {
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		crd := &apiext.CustomResourceDefinition{}
+		if err := yaml.Unmarshal(content, crd); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if crd.Kind != "CustomResourceDefinition" || crd.Name == "" {
+			return nil, fmt.Errorf("%s does not hold a CustomResourceDefinition", path)
+		}
+		if _, err := storagemigration.StorageVersion(crd); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
+
+// Installer installs and upgrades CRDs in one space.
+type Installer struct {
+	logger  klog.Logger
+	client  apiextclient.CustomResourceDefinitionInterface
+	dynamic dynamic.Interface
+
+	// Conversion, when not nil, is the client config of the conversion
+	// webhook. It is put into each CRD whose conversion strategy is
+	// Webhook. Such a CRD without its own client config needs this.
+	Conversion *apiext.WebhookClientConfig
+
+	// EstablishTimeout bounds the wait for each CRD to be Established.
+	EstablishTimeout time.Duration
+}
+
+// NewInstaller makes an Installer that uses the given clients for the space.
+// The dynamic client is used to rewrite existing objects during migration.
+func NewInstaller(logger klog.Logger, client apiextclient.CustomResourceDefinitionInterface, dynamicClient dynamic.Interface) *Installer {
+	return &Installer{
+		logger:           logger,
+		client:           client,
+		dynamic:          dynamicClient,
+		EstablishTimeout: DefaultEstablishTimeout,
+	}
+}
+
+// Outcome is what an Installer did to one CRD.
+type Outcome string
+
+const (
+	OutcomeCreated   Outcome = "Created"
+	OutcomeUpdated   Outcome = "Updated"
+	OutcomeUnchanged Outcome = "Unchanged"
+	OutcomeFailed    Outcome = "Failed"
+)
+
+// Result is the Outcome for one CRD, and the error if it Failed.
+type Result struct {
+	Name    string
+	Outcome Outcome
+	Err     error
+}
+
+// Install brings each of the given CRDs up to date, in the given order.
+// A failure for one CRD does not stop the attempts for the others;
+// the returned error aggregates the failures.
+func (inst *Installer) Install(ctx context.Context, crds []*apiext.CustomResourceDefinition) error {
+	var errs []error
+	for _, result := range inst.InstallEach(ctx, crds) {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("CRD %s: %w", result.Name, result.Err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// InstallEach is like Install but returns the Result for each CRD,
+// in the given order.
+func (inst *Installer) InstallEach(ctx context.Context, crds []*apiext.CustomResourceDefinition) []Result {
+	results := make([]Result, 0, len(crds))
+	for _, crd := range crds {
+		outcome, err := inst.ensure(ctx, crd)
+		if err != nil {
+			outcome = OutcomeFailed
+		}
+		results = append(results, Result{Name: crd.Name, Outcome: outcome, Err: err})
+	}
+	return results
+}
+
+func (inst *Installer) ensure(ctx context.Context, crd *apiext.CustomResourceDefinition) (Outcome, error) {
+	logger := klog.LoggerWithValues(inst.logger, "crd", crd.Name)
+	desired, err := inst.prepare(crd)
+	if err != nil {
+		return "", err
+	}
+	storage, err := storagemigration.StorageVersion(desired)
+	if err != nil {
+		return "", err
+	}
+	existing, err := inst.client.Get(ctx, desired.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logger.Info("Creating CRD")
+		created, err := inst.client.Create(ctx, desired, metav1.CreateOptions{FieldManager: "kubestellar"})
+		if err != nil {
+			return "", err
+		}
+		return OutcomeCreated, inst.waitEstablished(ctx, created.Name)
+	} else if err != nil {
+		return "", err
+	}
+	// Compare with the storage version being installed, not the existing
+	// one: an upgrade that moves storage to a new version must migrate
+	// before it can drop the old one.
+	needsMigration := storagemigration.NeedsMigration(existing, storage)
+	if existing.Annotations[SpecHashAnnotationKey] == desired.Annotations[SpecHashAnnotationKey] && !needsMigration {
+		logger.V(2).Info("CRD is up to date")
+		return OutcomeUnchanged, nil
+	}
+	interim, retained := withRetainedVersions(desired, existing)
+	if len(retained) > 0 {
+		logger.Info("Keeping stored versions until migration", "versions", retained)
+		delete(interim.Annotations, SpecHashAnnotationKey)
+	}
+	logger.Info("Updating CRD", "storageVersion", storage)
+	if err := inst.update(ctx, interim); err != nil {
+		return "", err
+	}
+	if err := inst.waitEstablished(ctx, desired.Name); err != nil {
+		return "", err
+	}
+	if needsMigration {
+		migrator := storagemigration.NewMigrator(inst.dynamic)
+		prog, err := migrator.MigrateCRD(ctx, inst.client, desired.Name)
+		if err != nil {
+			return "", err
+		}
+		logger.Info("Migrated objects to the storage version", "storageVersion", storage, "migrated", prog.Migrated, "skipped", prog.Skipped)
+	}
+	if len(retained) > 0 {
+		logger.Info("Dropping migrated versions", "versions", retained)
+		return OutcomeUpdated, inst.update(ctx, desired)
+	}
+	return OutcomeUpdated, nil
+}
+
+// prepare returns a copy of the given CRD with the conversion webhook
+// filled in and the spec hash annotated.
+func (inst *Installer) prepare(crd *apiext.CustomResourceDefinition) (*apiext.CustomResourceDefinition, error) {
+	desired := crd.DeepCopy()
+	if conv := desired.Spec.Conversion; conv != nil && conv.Strategy == apiext.WebhookConverter {
+		if conv.Webhook == nil {
+			conv.Webhook = &apiext.WebhookConversion{ConversionReviewVersions: []string{"v1"}}
+		}
+		if inst.Conversion != nil {
+			conv.Webhook.ClientConfig = inst.Conversion.DeepCopy()
+		} else if conv.Webhook.ClientConfig == nil {
+			return nil, fmt.Errorf("uses a conversion webhook but none is configured")
+		}
+	}
+	hash, err := specHash(&desired.Spec)
+	if err != nil {
+		return nil, err
+	}
+	if desired.Annotations == nil {
+		desired.Annotations = map[string]string{}
+	}
+	desired.Annotations[SpecHashAnnotationKey] = hash
+	return desired, nil
+}
+
+// update writes the spec, labels and annotations of the given CRD
+// over those of the existing one, keeping other labels and annotations.
+func (inst *Installer) update(ctx context.Context, want *apiext.CustomResourceDefinition) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := inst.client.Get(ctx, want.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current.Spec = want.Spec
+		current.Labels = mergeStrings(current.Labels, want.Labels)
+		current.Annotations = mergeStrings(current.Annotations, want.Annotations)
+		if _, ok := want.Annotations[SpecHashAnnotationKey]; !ok {
+			delete(current.Annotations, SpecHashAnnotationKey)
+		}
+		_, err = inst.client.Update(ctx, current, metav1.UpdateOptions{FieldManager: "kubestellar"})
+		return err
+	})
+}
+
+func (inst *Installer) waitEstablished(ctx context.Context, name string) error {
+	err := wait.PollImmediateWithContext(ctx, time.Second, inst.EstablishTimeout, func(ctx context.Context) (bool, error) {
+		crd, err := inst.client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range crd.Status.Conditions {
+			if cond.Type == apiext.NamesAccepted && cond.Status == apiext.ConditionFalse {
+				return false, fmt.Errorf("names not accepted: %s", cond.Message)
+			}
+		}
+		for _, cond := range crd.Status.Conditions {
+			if cond.Type == apiext.Established && cond.Status == apiext.ConditionTrue {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("not Established within %v", inst.EstablishTimeout)
+	}
+	return err
+}
+
+// withRetainedVersions returns a copy of desired plus, unserved, each
+// version of existing that objects may still be stored in but that
+// desired lacks. Also returns the names of those versions.
+func withRetainedVersions(desired, existing *apiext.CustomResourceDefinition) (*apiext.CustomResourceDefinition, []string) {
+	interim := desired.DeepCopy()
+	var retained []string
+	for _, stored := range existing.Status.StoredVersions {
+		if hasVersion(desired, stored) {
+			continue
+		}
+		for _, version := range existing.Spec.Versions {
+			if version.Name != stored {
+				continue
+			}
+			version = *version.DeepCopy()
+			version.Served, version.Storage = false, false
+			interim.Spec.Versions = append(interim.Spec.Versions, version)
+			retained = append(retained, stored)
+		}
+	}
+	return interim, retained
+}
+
+func hasVersion(crd *apiext.CustomResourceDefinition, name string) bool {
+	for _, version := range crd.Spec.Versions {
+		if version.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func specHash(spec *apiext.CustomResourceDefinitionSpec) (string, error) {
+	content, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+func mergeStrings(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, val := range base {
+		merged[key] = val
+	}
+	for key, val := range overrides {
+		merged[key] = val
+	}
+	return merged
+}
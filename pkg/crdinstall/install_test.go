@@ -0,0 +1,205 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crdinstall
+
+import (
+	"context"
+	"testing"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/config/crds"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	loaded, err := Load(crds.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("No CRDs loaded")
+	}
+	for _, crd := range loaded {
+		if crd.Spec.Group != "edge.kubestellar.io" {
+			t.Errorf("CRD %s has group %q", crd.Name, crd.Spec.Group)
+		}
+	}
+}
+
+func version(name string, storage bool) apiext.CustomResourceDefinitionVersion {
+	return apiext.CustomResourceDefinitionVersion{Name: name, Served: true, Storage: storage,
+		Schema: &apiext.CustomResourceValidation{OpenAPIV3Schema: &apiext.JSONSchemaProps{Type: "object"}}}
+}
+
+func widgetCRD(versions ...apiext.CustomResourceDefinitionVersion) *apiext.CustomResourceDefinition {
+	return &apiext.CustomResourceDefinition{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+		Spec: apiext.CustomResourceDefinitionSpec{
+			Group:    "example.com",
+			Names:    apiext.CustomResourceDefinitionNames{Plural: "widgets", Kind: "Widget", ListKind: "WidgetList"},
+			Scope:    apiext.NamespaceScoped,
+			Versions: versions,
+		},
+	}
+}
+
+func TestUpgradeWithMigration(t *testing.T) {
+	ctx := context.Background()
+	existing := widgetCRD(version("v1", true))
+	existing.Annotations = map[string]string{"other": "kept"}
+	existing.Status.StoredVersions = []string{"v1"}
+	existing.Status.Conditions = []apiext.CustomResourceDefinitionCondition{{Type: apiext.Established, Status: apiext.ConditionTrue}}
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v2", "kind": "Widget",
+		"metadata": map[string]interface{}{"namespace": "ns", "name": "w1"},
+	}}
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v2", Resource: "widgets"}
+	apiextClient := apiextfake.NewSimpleClientset(existing)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "WidgetList"}, widget)
+	inst := NewInstaller(klog.Background(), apiextClient.ApiextensionsV1().CustomResourceDefinitions(), dynamicClient)
+
+	desired := widgetCRD(version("v2", true))
+	if err := inst.Install(ctx, []*apiext.CustomResourceDefinition{desired}); err != nil {
+		t.Fatal(err)
+	}
+	installed, err := apiextClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, desired.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installed.Spec.Versions) != 1 || installed.Spec.Versions[0].Name != "v2" {
+		t.Errorf("Expected only v2, got %v", installed.Spec.Versions)
+	}
+	if stored := installed.Status.StoredVersions; len(stored) != 1 || stored[0] != "v2" {
+		t.Errorf("Expected stored versions [v2], got %v", stored)
+	}
+	if installed.Annotations[SpecHashAnnotationKey] == "" || installed.Annotations["other"] != "kept" {
+		t.Errorf("Unexpected annotations %v", installed.Annotations)
+	}
+	var updates int
+	for _, action := range dynamicClient.Actions() {
+		if action.GetVerb() == "update" {
+			updates++
+		}
+	}
+	if updates != 1 {
+		t.Errorf("Expected 1 object rewrite, got %d", updates)
+	}
+
+	// Installing again changes nothing.
+	apiextClient.ClearActions()
+	if err := inst.Install(ctx, []*apiext.CustomResourceDefinition{desired}); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range apiextClient.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("Unexpected action %v", action)
+		}
+	}
+}
+
+// TestUpgradeDropsStoredVersion checks an upgrade that moves storage from
+// v1 to v2 and drops v1, against a client that, like the API server,
+// refuses to drop a version that is still in status.storedVersions.
+func TestUpgradeDropsStoredVersion(t *testing.T) {
+	ctx := context.Background()
+	existing := widgetCRD(version("v1", true))
+	existing.Status.StoredVersions = []string{"v1"}
+	existing.Status.Conditions = []apiext.CustomResourceDefinitionCondition{{Type: apiext.Established, Status: apiext.ConditionTrue}}
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v2", Resource: "widgets"}
+	apiextClient := apiextfake.NewSimpleClientset(existing)
+	apiextClient.PrependReactor("update", "customresourcedefinitions", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		update := action.(clienttesting.UpdateAction)
+		if update.GetSubresource() != "" {
+			return false, nil, nil
+		}
+		crd := update.GetObject().(*apiext.CustomResourceDefinition)
+		for _, stored := range crd.Status.StoredVersions {
+			found := false
+			for _, vers := range crd.Spec.Versions {
+				found = found || vers.Name == stored
+			}
+			if !found {
+				return true, nil, apierrors.NewBadRequest("stored version " + stored + " must remain in spec.versions")
+			}
+		}
+		return false, nil, nil
+	})
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "WidgetList"})
+	inst := NewInstaller(klog.Background(), apiextClient.ApiextensionsV1().CustomResourceDefinitions(), dynamicClient)
+
+	desired := widgetCRD(version("v2", true))
+	if err := inst.Install(ctx, []*apiext.CustomResourceDefinition{desired}); err != nil {
+		t.Fatal(err)
+	}
+	installed, err := apiextClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, desired.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installed.Spec.Versions) != 1 || installed.Spec.Versions[0].Name != "v2" {
+		t.Errorf("Expected only v2, got %v", installed.Spec.Versions)
+	}
+	if stored := installed.Status.StoredVersions; len(stored) != 1 || stored[0] != "v2" {
+		t.Errorf("Expected stored versions [v2], got %v", stored)
+	}
+}
+
+func TestWithRetainedVersions(t *testing.T) {
+	existing := widgetCRD(version("v1", false), version("v2", true))
+	existing.Status.StoredVersions = []string{"v1", "v2"}
+	desired := widgetCRD(version("v2", false), version("v3", true))
+	interim, retained := withRetainedVersions(desired, existing)
+	if len(retained) != 1 || retained[0] != "v1" {
+		t.Fatalf("Expected v1 retained, got %v", retained)
+	}
+	if len(interim.Spec.Versions) != 3 {
+		t.Fatalf("Expected 3 versions, got %v", interim.Spec.Versions)
+	}
+	if v1 := interim.Spec.Versions[2]; v1.Name != "v1" || v1.Served || v1.Storage {
+		t.Errorf("Retained version should be unserved and not storage, got %#v", v1)
+	}
+	if len(desired.Spec.Versions) != 2 {
+		t.Error("desired was modified")
+	}
+}
+
+func TestConversionWebhookRequired(t *testing.T) {
+	crd := widgetCRD(version("v1", false), version("v2", true))
+	crd.Spec.Conversion = &apiext.CustomResourceConversion{Strategy: apiext.WebhookConverter}
+	inst := NewInstaller(klog.Background(), nil, nil)
+	if _, err := inst.prepare(crd); err == nil {
+		t.Error("Expected an error for a missing conversion webhook")
+	}
+	path := "/convert"
+	inst.Conversion = &apiext.WebhookClientConfig{Service: &apiext.ServiceReference{Namespace: "ks", Name: "conv", Path: &path}}
+	prepared, err := inst.prepare(crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prepared.Spec.Conversion.Webhook.ClientConfig.Service.Name != "conv" {
+		t.Errorf("Conversion webhook not filled in: %#v", prepared.Spec.Conversion)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package credbroker deals in short-lived credentials for a syncer's
+// access to its mailbox space.
+// The hub side mints a token for the syncer's ServiceAccount with
+// the TokenRequest API, and the agent side keeps renewing that token
+// (using the token itself to authorize the renewal) before it expires,
+// writing each new token to a file that the syncer's client reads.
+// This replaces a long-lived static token in the syncer's kubeconfig.
+// The latest token is also kept in a TokenStore, such as a Secret in the
+// WEC, so that a restarted syncer can carry on after the bootstrap token
+// has expired.
+package credbroker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+// MinimumTTL is the smallest lifetime that apiservers will grant.
+const MinimumTTL = 10 * time.Minute
+
+// MintToken uses the TokenRequest API to get a token for the given ServiceAccount
+// with the given lifetime.
+// The apiserver may grant a different lifetime; the actual expiration time is returned.
+func MintToken(ctx context.Context, client corev1client.ServiceAccountsGetter, namespace, saName string, ttl time.Duration, fieldManager string) (string, time.Time, error) {
+	if ttl < MinimumTTL {
+		return "", time.Time{}, fmt.Errorf("requested token lifetime %v is less than the minimum %v", ttl, MinimumTTL)
+	}
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: pointer.Int64(int64(ttl / time.Second)),
+		},
+	}
+	granted, err := client.ServiceAccounts(namespace).CreateToken(ctx, saName, tokenRequest, metav1.CreateOptions{FieldManager: fieldManager})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create token for ServiceAccount %s/%s: %w", namespace, saName, err)
+	}
+	return granted.Status.Token, granted.Status.ExpirationTimestamp.Time, nil
+}
+
+// TokenRefresher keeps a file holding a fresh token for a ServiceAccount.
+// It renews the token once a given fraction of its lifetime has passed.
+type TokenRefresher struct {
+	Logger         klog.Logger
+	Client         corev1client.ServiceAccountsGetter
+	Namespace      string
+	ServiceAccount string
+	TTL            time.Duration
+	TokenFile      string
+	FieldManager   string
+
+	// Store, if not nil, is given each renewed token.
+	Store TokenStore
+
+	// RenewFraction is the fraction of the token lifetime after which
+	// to renew; zero means 2/3.
+	RenewFraction float64
+}
+
+// Run renews the token until the context is done.
+// The client should authenticate with the token in the file.
+func (tr *TokenRefresher) Run(ctx context.Context) {
+	fraction := tr.RenewFraction
+	if fraction <= 0 || fraction >= 1 {
+		fraction = 2.0 / 3.0
+	}
+	retryDelay := 10 * time.Second
+	for {
+		begin := time.Now()
+		delay := retryDelay
+		token, expiry, err := MintToken(ctx, tr.Client, tr.Namespace, tr.ServiceAccount, tr.TTL, tr.FieldManager)
+		if err == nil {
+			err = WriteTokenFile(tr.TokenFile, token)
+		}
+		if err == nil && tr.Store != nil {
+			err = tr.Store.Save(ctx, token, expiry)
+		}
+		if err != nil {
+			tr.Logger.Error(err, "Failed to renew token", "namespace", tr.Namespace, "serviceAccount", tr.ServiceAccount)
+		} else {
+			delay = time.Duration(float64(expiry.Sub(begin)) * fraction)
+			tr.Logger.V(2).Info("Renewed token", "namespace", tr.Namespace, "serviceAccount", tr.ServiceAccount, "expiry", expiry, "nextRenewal", begin.Add(delay))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// WriteTokenFile atomically replaces the content of the given file with the given token.
+func WriteTokenFile(filename, token string) error {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, ".token-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(token); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// BootstrapTokenFile makes sure that the given file holds a token to start
+// with. An existing file is left alone. Otherwise the token is taken from
+// the store, if it has one that has not expired, or else is the given
+// fallback (normally the token of the initial kubeconfig).
+func BootstrapTokenFile(ctx context.Context, filename string, store TokenStore, fallback string, now time.Time) error {
+	if _, err := os.Stat(filename); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	token := fallback
+	if store != nil {
+		stored, expiry, err := store.Load(ctx)
+		if err != nil {
+			return err
+		}
+		if stored != "" && expiry.After(now) {
+			token = stored
+		}
+	}
+	return WriteTokenFile(filename, token)
+}
+
+// TokenStore keeps the latest token somewhere that outlives the syncer's Pod.
+type TokenStore interface {
+	// Load returns the stored token and its expiration time;
+	// the empty string if there is none.
+	Load(ctx context.Context) (string, time.Time, error)
+
+	// Save replaces the stored token.
+	Save(ctx context.Context, token string, expiry time.Time) error
+}
+
+// ExpiryAnnotationKey is the annotation on a token Secret that holds
+// the expiration time of the token, in RFC 3339 format.
+const ExpiryAnnotationKey = "edge.kubestellar.io/token-expiry"
+
+// SecretTokenStore is a TokenStore that keeps the token in a Secret.
+type SecretTokenStore struct {
+	Client       corev1client.SecretsGetter
+	Namespace    string
+	Name         string
+	FieldManager string
+}
+
+var _ TokenStore = &SecretTokenStore{}
+
+func (ss *SecretTokenStore) Load(ctx context.Context) (string, time.Time, error) {
+	secret, err := ss.Client.Secrets(ss.Namespace).Get(ctx, ss.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", time.Time{}, nil
+	} else if err != nil {
+		return "", time.Time{}, err
+	}
+	expiry, err := time.Parse(time.RFC3339, secret.Annotations[ExpiryAnnotationKey])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("malformed expiry in Secret %s/%s: %w", ss.Namespace, ss.Name, err)
+	}
+	return string(secret.Data[corev1.ServiceAccountTokenKey]), expiry, nil
+}
+
+func (ss *SecretTokenStore) Save(ctx context.Context, token string, expiry time.Time) error {
+	secrets := ss.Client.Secrets(ss.Namespace)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ss.Name,
+			Namespace:   ss.Namespace,
+			Annotations: map[string]string{ExpiryAnnotationKey: expiry.UTC().Format(time.RFC3339)},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{corev1.ServiceAccountTokenKey: []byte(token)},
+	}
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{FieldManager: ss.FieldManager})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{FieldManager: ss.FieldManager})
+	}
+	return err
+}
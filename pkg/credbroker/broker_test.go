@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credbroker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestMintToken(t *testing.T) {
+	ctx := context.Background()
+	expiry := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	client := fake.NewSimpleClientset()
+	var requested int64
+	client.PrependReactor("create", "serviceaccounts", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		create := action.(clienttesting.CreateAction)
+		if create.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		tr := create.GetObject().(*authenticationv1.TokenRequest)
+		requested = *tr.Spec.ExpirationSeconds
+		tr = tr.DeepCopy()
+		tr.Status = authenticationv1.TokenRequestStatus{Token: "minted", ExpirationTimestamp: metav1.NewTime(expiry)}
+		return true, tr, nil
+	})
+
+	if _, _, err := MintToken(ctx, client.CoreV1(), "ns", "sa", time.Minute, "test"); err == nil {
+		t.Error("expected a lifetime below the minimum to be refused")
+	}
+	token, gotExpiry, err := MintToken(ctx, client.CoreV1(), "ns", "sa", time.Hour, "test")
+	if err != nil {
+		t.Fatalf("MintToken failed: %v", err)
+	}
+	if token != "minted" || !gotExpiry.Equal(expiry) {
+		t.Errorf("expected token %q expiring at %v, got %q expiring at %v", "minted", expiry, token, gotExpiry)
+	}
+	if requested != 3600 {
+		t.Errorf("expected a request for 3600 seconds, got %d", requested)
+	}
+}
+
+func TestWriteTokenFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "token")
+	for _, token := range []string{"first", "second"} {
+		if err := WriteTokenFile(filename, token); err != nil {
+			t.Fatalf("WriteTokenFile failed: %v", err)
+		}
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != token {
+			t.Errorf("expected file to hold %q, got %q", token, content)
+		}
+	}
+}
+
+func TestSecretTokenStore(t *testing.T) {
+	ctx := context.Background()
+	store := &SecretTokenStore{Client: fake.NewSimpleClientset().CoreV1(), Namespace: "ns", Name: "renewed", FieldManager: "test"}
+	token, _, err := store.Load(ctx)
+	if err != nil || token != "" {
+		t.Fatalf("expected no token from an empty store, got %q, %v", token, err)
+	}
+	expiry := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, saved := range []string{"first", "second"} {
+		if err := store.Save(ctx, saved, expiry); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		token, gotExpiry, err := store.Load(ctx)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if token != saved || !gotExpiry.Equal(expiry) {
+			t.Errorf("expected %q expiring at %v, got %q expiring at %v", saved, expiry, token, gotExpiry)
+		}
+		expiry = expiry.Add(time.Hour)
+	}
+}
+
+func TestBootstrapTokenFile(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name     string
+		existing string
+		stored   string
+		expiry   time.Time
+		expected string
+	}{
+		{name: "nothing stored", expected: "bootstrap"},
+		{name: "stored", stored: "renewed", expiry: now.Add(time.Minute), expected: "renewed"},
+		{name: "stored but expired", stored: "renewed", expiry: now.Add(-time.Minute), expected: "bootstrap"},
+		{name: "file exists", existing: "current", stored: "renewed", expiry: now.Add(time.Minute), expected: "current"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "token")
+			if tc.existing != "" {
+				if err := WriteTokenFile(filename, tc.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store := &SecretTokenStore{Client: fake.NewSimpleClientset().CoreV1(), Namespace: "ns", Name: "renewed"}
+			if tc.stored != "" {
+				if err := store.Save(ctx, tc.stored, tc.expiry); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := BootstrapTokenFile(ctx, filename, store, "bootstrap", now); err != nil {
+				t.Fatalf("BootstrapTokenFile failed: %v", err)
+			}
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crossref resolves references, held by objects in one space, to
+// objects that may be in another space: for example, the Customizer
+// named by the `edge.kubestellar.io/customizer` annotation of a workload
+// object, or a Secret that holds credentials.
+//
+// A reference is written `[<space>:][<namespace>/]<name>`; the space and
+// namespace default to those of the referencing object. Following a
+// reference into another space is allowed only if RBAC in that space
+// lets the referencing space read the object. The referencing space
+// acts as the user SpaceUser(space), in the group SpacesGroup; so, to
+// let the objects in space A use the Customizers in namespace shared of
+// space B, create in space B a Role granting `get` on customizers in
+// namespace shared and bind it to the user `kubestellar:space:A`.
+// References within a space are not checked; the authors of the
+// objects in a space are checked when they write them (see package
+// placementauthz).
+//
+// Resolved objects and access decisions are cached for a while, so that
+// the many objects that reference the same Customizer cost one read.
+package crossref
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/clock"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/placementauthz"
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+)
+
+// SpacesGroup is the group of the users that spaces act as when they
+// follow references into other spaces.
+const SpacesGroup = "kubestellar:spaces"
+
+// SpaceUser returns the name of the user that the given space acts as
+// when it follows a reference into another space.
+func SpaceUser(space string) string {
+	return "kubestellar:space:" + space
+}
+
+// Ref identifies an object in a space.
+type Ref struct {
+	Space     string
+	Namespace string
+	Name      string
+}
+
+func (ref Ref) String() string {
+	ans := ref.Name
+	if ref.Namespace != "" {
+		ans = ref.Namespace + "/" + ans
+	}
+	return ref.Space + ":" + ans
+}
+
+// Parse parses a reference of the form `[<space>:][<namespace>/]<name>`,
+// held by an object in the given space and namespace. A space name may
+// itself contain colons; the last one ends it.
+func Parse(value, space, namespace string) (Ref, error) {
+	ref := Ref{Space: space, Namespace: namespace, Name: value}
+	if idx := strings.LastIndex(value, ":"); idx >= 0 {
+		ref.Space, ref.Name = value[:idx], value[idx+1:]
+		if ref.Space == "" {
+			return Ref{}, fmt.Errorf("reference %q has an empty space", value)
+		}
+	}
+	if nsName, name, found := strings.Cut(ref.Name, "/"); found {
+		ref.Namespace, ref.Name = nsName, name
+		if errs := validation.IsDNS1123Label(ref.Namespace); len(errs) > 0 {
+			return Ref{}, fmt.Errorf("reference %q has an invalid namespace: %s", value, strings.Join(errs, "; "))
+		}
+	}
+	if errs := validation.IsDNS1123Subdomain(ref.Name); len(errs) > 0 {
+		return Ref{}, fmt.Errorf("reference %q has an invalid name: %s", value, strings.Join(errs, "; "))
+	}
+	return ref, nil
+}
+
+// ClientSource hands out the clients of a space. *spaceclient.Factory is one.
+type ClientSource interface {
+	For(space, providerNS string) (*spaceclient.Clients, error)
+}
+
+// Options tune a Resolver. Zero values mean the defaults.
+type Options struct {
+	// TTL is how long a resolved object, or an access decision, is
+	// reused. Default is DefaultTTL.
+	TTL time.Duration
+
+	// MaxEntries bounds the number of cached objects and decisions.
+	// Default is DefaultMaxEntries.
+	MaxEntries int
+}
+
+const (
+	DefaultTTL        = 30 * time.Second
+	DefaultMaxEntries = 1024
+)
+
+// Resolver follows references. It is safe for concurrent use.
+type Resolver struct {
+	clients    ClientSource
+	providerNS string
+	opts       Options
+	clock      clock.PassiveClock
+
+	mutex   sync.Mutex
+	entries map[cacheKey]*list.Element // values are *cacheEntry
+	lru     *list.List                 // most recently used at front
+}
+
+// cacheKey identifies a cached object (from is empty) or a decision
+// about whether the space from may read it.
+type cacheKey struct {
+	from     string
+	resource schema.GroupVersionResource
+	ref      Ref
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	obj     *unstructured.Unstructured // for an object
+	allowed bool                       // for a decision
+	expires time.Time
+}
+
+// NewResolver makes a Resolver that gets clients from the given source.
+func NewResolver(clients ClientSource, providerNS string, opts Options) *Resolver {
+	return newResolver(clients, providerNS, opts, clock.RealClock{})
+}
+
+func newResolver(clients ClientSource, providerNS string, opts Options, clk clock.PassiveClock) *Resolver {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultMaxEntries
+	}
+	return &Resolver{
+		clients:    clients,
+		providerNS: providerNS,
+		opts:       opts,
+		clock:      clk,
+		entries:    map[cacheKey]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Resolve returns the referenced object, of the given resource, on behalf
+// of an object in the space from. The returned object must be treated as
+// read-only. A reference into another space that RBAC there does not
+// allow fails with a Forbidden error.
+func (res *Resolver) Resolve(ctx context.Context, from string, resource schema.GroupVersionResource, ref Ref) (*unstructured.Unstructured, error) {
+	if ref.Space != from {
+		if err := res.authorize(ctx, from, resource, ref); err != nil {
+			return nil, err
+		}
+	}
+	objKey := cacheKey{resource: resource, ref: ref}
+	if entry := res.lookup(objKey); entry != nil {
+		return entry.obj, nil
+	}
+	clients, err := res.clients.For(ref.Space, res.providerNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clients for space %q: %w", ref.Space, err)
+	}
+	obj, err := clients.Dynamic.Resource(resource).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	res.store(&cacheEntry{key: objKey, obj: obj})
+	return obj, nil
+}
+
+// authorize checks that RBAC in the space of the given reference lets
+// the space from read the referenced object.
+func (res *Resolver) authorize(ctx context.Context, from string, resource schema.GroupVersionResource, ref Ref) error {
+	decisionKey := cacheKey{from: from, resource: resource, ref: ref}
+	entry := res.lookup(decisionKey)
+	if entry == nil {
+		clients, err := res.clients.For(ref.Space, res.providerNS)
+		if err != nil {
+			return fmt.Errorf("failed to get clients for space %q: %w", ref.Space, err)
+		}
+		user := authenticationv1.UserInfo{Username: SpaceUser(from), Groups: []string{SpacesGroup}}
+		attrs := authorizationv1.ResourceAttributes{Namespace: ref.Namespace, Verb: "get",
+			Group: resource.Group, Version: resource.Version, Resource: resource.Resource, Name: ref.Name}
+		denied, err := placementauthz.Review(ctx, clients.Kube.AuthorizationV1().SubjectAccessReviews(), user, []authorizationv1.ResourceAttributes{attrs})
+		if err != nil {
+			return err
+		}
+		entry = &cacheEntry{key: decisionKey, allowed: len(denied) == 0}
+		res.store(entry)
+	}
+	if !entry.allowed {
+		return apierrors.NewForbidden(resource.GroupResource(), ref.Name,
+			fmt.Errorf("space %q may not read %s; RBAC in space %q has to allow user %q to get it", from, ref, ref.Space, SpaceUser(from)))
+	}
+	return nil
+}
+
+func (res *Resolver) lookup(key cacheKey) *cacheEntry {
+	now := res.clock.Now()
+	res.mutex.Lock()
+	defer res.mutex.Unlock()
+	elt, ok := res.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elt.Value.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		res.lru.Remove(elt)
+		delete(res.entries, key)
+		return nil
+	}
+	res.lru.MoveToFront(elt)
+	return entry
+}
+
+func (res *Resolver) store(entry *cacheEntry) {
+	entry.expires = res.clock.Now().Add(res.opts.TTL)
+	res.mutex.Lock()
+	defer res.mutex.Unlock()
+	if elt, ok := res.entries[entry.key]; ok {
+		res.lru.Remove(elt)
+	}
+	res.entries[entry.key] = res.lru.PushFront(entry)
+	for res.lru.Len() > res.opts.MaxEntries {
+		oldest := res.lru.Back()
+		res.lru.Remove(oldest)
+		delete(res.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Forget drops what is cached about the referenced object, for example
+// because it is known to have changed.
+func (res *Resolver) Forget(ref Ref) {
+	res.mutex.Lock()
+	defer res.mutex.Unlock()
+	for key, elt := range res.entries {
+		if key.ref == ref {
+			res.lru.Remove(elt)
+			delete(res.entries, key)
+		}
+	}
+}
+
+var (
+	customizersResource = edgeapi.SchemeGroupVersion.WithResource("customizers")
+	secretsResource     = corev1.SchemeGroupVersion.WithResource("secrets")
+	configMapsResource  = corev1.SchemeGroupVersion.WithResource("configmaps")
+)
+
+// Customizer resolves the value of an `edge.kubestellar.io/customizer`
+// annotation of an object in the given space and namespace.
+func (res *Resolver) Customizer(ctx context.Context, space, namespace, value string) (*edgeapi.Customizer, error) {
+	ans := &edgeapi.Customizer{}
+	return ans, res.resolveTyped(ctx, space, namespace, value, customizersResource, ans)
+}
+
+// Secret resolves a reference to a Secret held by an object in the given space and namespace.
+func (res *Resolver) Secret(ctx context.Context, space, namespace, value string) (*corev1.Secret, error) {
+	ans := &corev1.Secret{}
+	return ans, res.resolveTyped(ctx, space, namespace, value, secretsResource, ans)
+}
+
+// ConfigMap resolves a reference to a ConfigMap held by an object in the given space and namespace.
+func (res *Resolver) ConfigMap(ctx context.Context, space, namespace, value string) (*corev1.ConfigMap, error) {
+	ans := &corev1.ConfigMap{}
+	return ans, res.resolveTyped(ctx, space, namespace, value, configMapsResource, ans)
+}
+
+func (res *Resolver) resolveTyped(ctx context.Context, space, namespace, value string, resource schema.GroupVersionResource, into any) error {
+	ref, err := Parse(value, space, namespace)
+	if err != nil {
+		return err
+	}
+	obj, err := res.Resolve(ctx, space, resource, ref)
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, into)
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crossref
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected Ref
+		bad      bool
+	}{
+		{value: "cust", expected: Ref{"home", "ns1", "cust"}},
+		{value: "ns2/cust", expected: Ref{"home", "ns2", "cust"}},
+		{value: "shared:ns2/cust", expected: Ref{"shared", "ns2", "cust"}},
+		{value: "root:org:shared:cust", expected: Ref{"root:org:shared", "ns1", "cust"}},
+		{value: ":cust", bad: true},
+		{value: "Bad_NS/cust", bad: true},
+		{value: "ns2/", bad: true},
+	} {
+		got, err := Parse(tc.value, "home", "ns1")
+		if tc.bad {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected, got %+v", tc.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.expected {
+			t.Errorf("Parse(%q): expected %+v, got %+v, %v", tc.value, tc.expected, got, err)
+		}
+	}
+}
+
+type fakeSpaces map[string]*spaceclient.Clients
+
+func (spaces fakeSpaces) For(space, providerNS string) (*spaceclient.Clients, error) {
+	if clients, ok := spaces[space]; ok {
+		return clients, nil
+	}
+	return nil, fmt.Errorf("no space %q", space)
+}
+
+func newCustomizer(namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(customizersResource.GroupVersion().String())
+	obj.SetKind("Customizer")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetAnnotations(map[string]string{"note": name})
+	return obj
+}
+
+func newSpace(allowedUser string, reviews *int, gets *int, objs ...runtime.Object) *spaceclient.Clients {
+	kube := fake.NewSimpleClientset()
+	kube.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		*reviews++
+		sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		sar.Status.Allowed = sar.Spec.User == allowedUser && sar.Spec.ResourceAttributes.Resource == "customizers"
+		return true, sar, nil
+	})
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{customizersResource: "CustomizerList"}, objs...)
+	dyn.PrependReactor("get", "customizers", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		*gets++
+		return false, nil, nil
+	})
+	return &spaceclient.Clients{Kube: kube, Dynamic: dyn}
+}
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+	var homeReviews, homeGets, sharedReviews, sharedGets int
+	spaces := fakeSpaces{
+		"home":   newSpace("", &homeReviews, &homeGets, newCustomizer("ns1", "local")),
+		"shared": newSpace(SpaceUser("home"), &sharedReviews, &sharedGets, newCustomizer("common", "remote")),
+	}
+	clk := clocktesting.NewFakePassiveClock(time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC))
+	res := newResolver(spaces, "", Options{TTL: time.Minute}, clk)
+
+	// A reference within a space is not checked.
+	cust, err := res.Customizer(ctx, "home", "ns1", "local")
+	if err != nil || cust.Name != "local" || cust.Annotations["note"] != "local" {
+		t.Fatalf("Expected the local Customizer, got %+v, %v", cust, err)
+	}
+	if homeReviews != 0 || homeGets != 1 {
+		t.Errorf("Expected 0 reviews and 1 get in home, got %d and %d", homeReviews, homeGets)
+	}
+
+	// A reference into another space is checked there, and both the
+	// decision and the object are cached.
+	for idx := 0; idx < 2; idx++ {
+		cust, err = res.Customizer(ctx, "home", "ns1", "shared:common/remote")
+		if err != nil || cust.Name != "remote" || cust.Namespace != "common" {
+			t.Fatalf("Expected the shared Customizer, got %+v, %v", cust, err)
+		}
+	}
+	if sharedReviews != 1 || sharedGets != 1 {
+		t.Errorf("Expected 1 review and 1 get in shared, got %d and %d", sharedReviews, sharedGets)
+	}
+
+	// Another space is not allowed by the RBAC of shared.
+	spaces["other"] = newSpace("", new(int), new(int))
+	if _, err := res.Customizer(ctx, "other", "ns1", "shared:common/remote"); !apierrors.IsForbidden(err) {
+		t.Errorf("Expected Forbidden for space other, got %v", err)
+	}
+	if _, err := res.Secret(ctx, "home", "ns1", "shared:common/creds"); !apierrors.IsForbidden(err) {
+		t.Errorf("Expected Forbidden for a Secret, got %v", err)
+	}
+
+	// After the TTL, the shared space is asked again.
+	clk.SetTime(clk.Now().Add(2 * time.Minute))
+	if _, err := res.Customizer(ctx, "home", "ns1", "shared:common/remote"); err != nil {
+		t.Fatal(err)
+	}
+	if sharedReviews != 4 || sharedGets != 2 {
+		t.Errorf("Expected 4 reviews and 2 gets in shared, got %d and %d", sharedReviews, sharedGets)
+	}
+
+	// Forget drops the cached object.
+	res.Forget(Ref{"home", "ns1", "local"})
+	if _, err := res.Customizer(ctx, "home", "ns1", "local"); err != nil {
+		t.Fatal(err)
+	}
+	if homeGets != 2 {
+		t.Errorf("Expected 2 gets in home, got %d", homeGets)
+	}
+
+	// Errors are not cached.
+	if _, err := res.Customizer(ctx, "home", "ns1", "missing"); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+	if _, err := res.Customizer(ctx, "home", "ns1", "missing"); !apierrors.IsNotFound(err) || homeGets != 4 {
+		t.Errorf("Expected NotFound again after another get, got %v and %d gets", err, homeGets)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	res := newResolver(fakeSpaces{}, "", Options{MaxEntries: 2}, clocktesting.NewFakePassiveClock(time.Now()))
+	for _, name := range []string{"a", "b", "c"} {
+		res.store(&cacheEntry{key: cacheKey{ref: Ref{Name: name}}})
+	}
+	if res.lookup(cacheKey{ref: Ref{Name: "a"}}) != nil || res.lookup(cacheKey{ref: Ref{Name: "c"}}) == nil || len(res.entries) != 2 {
+		t.Errorf("Expected the oldest entry to be evicted, have %v", res.entries)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package customizercheck checks, at admission time, that the paths in
+// the replacements of a Customizer exist in the schema of the kinds of
+// objects that the Customizer may be applied to. Without this check, a
+// typo in a path shows up only when the Customizer is applied on the way
+// to the edge, where it is logged and the object goes out uncustomized.
+//
+// The kinds that a Customizer may be applied to are those of the
+// resources that the EdgePlacements in the space may downsync from the
+// Customizer's namespace. A path passes if it fits at least one of them.
+// Nothing is checked when some placement may downsync every resource
+// there, and the parts of a path below a free-form part of a schema are
+// not checked either. Schemas come from the OpenAPI v2 document of the
+// space (see apiwatch.OpenAPISchemaCache).
+package customizercheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	"github.com/kubestellar/kubestellar/pkg/jsonpath"
+)
+
+// CheckPath returns nil if the given path fits the given schema,
+// otherwise an error that says where it does not.
+func CheckPath(schemas *apiwatch.OpenAPISchemas, root *apiwatch.OpenAPISchema, path jsonpath.Parsed) error {
+	return checkPath(schemas, root, path, "$")
+}
+
+func checkPath(schemas *apiwatch.OpenAPISchemas, oas *apiwatch.OpenAPISchema, path jsonpath.Parsed, where string) error {
+	oas = schemas.Resolve(oas)
+	if oas == nil || len(path) == 0 || oas.PreserveUnknownFields || freeForm(oas) {
+		return nil
+	}
+	sel, rest := path[0], path[1:]
+	switch sel.Type {
+	case jsonpath.SelectorName:
+		if prop, have := oas.Properties[sel.Name]; have {
+			return checkPath(schemas, prop, rest, where+"."+sel.Name)
+		}
+		if oas.AdditionalProperties != nil {
+			return checkPath(schemas, oas.AdditionalProperties, rest, where+"."+sel.Name)
+		}
+		if oas.Type != "" && oas.Type != "object" {
+			return fmt.Errorf("%s is of type %s, so it has no field %q", where, oas.Type, sel.Name)
+		}
+		return fmt.Errorf("%s has no field %q", where, sel.Name)
+	case jsonpath.SelectorRange:
+		if oas.Items != nil {
+			return checkPath(schemas, oas.Items, rest, where+"[]")
+		}
+		return fmt.Errorf("%s is not a list", where)
+	case jsonpath.SelectorList:
+		for _, sub := range sel.List {
+			if err := checkPath(schemas, oas, append(jsonpath.Parsed{sub}, rest...), where); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// A wildcard could match anything below here.
+	return nil
+}
+
+// freeForm tells whether the given schema says nothing about what is in it.
+func freeForm(oas *apiwatch.OpenAPISchema) bool {
+	return (oas.Type == "" || oas.Type == "object") && len(oas.Properties) == 0 && oas.Items == nil && oas.AdditionalProperties == nil
+}
+
+// Check returns a description of each problem with the replacements of
+// the given Customizer: a path that does not parse, a path that fits none
+// of the given kinds of objects, and a value that is not JSON. Kinds
+// without a schema are ignored; if none has one then paths are not
+// checked. Paths and values subject to parameter expansion are checked
+// only when they contain no parameter, since those depend on the
+// destination.
+func Check(customizer *edgeapi.Customizer, schemas *apiwatch.OpenAPISchemas, kinds []schema.GroupVersionKind) []string {
+	roots := map[schema.GroupVersionKind]*apiwatch.OpenAPISchema{}
+	for _, gvk := range kinds {
+		if oas := schemas.ForKind(gvk); oas != nil {
+			roots[gvk] = oas
+		}
+	}
+	sortedKinds := make([]schema.GroupVersionKind, 0, len(roots))
+	for gvk := range roots {
+		sortedKinds = append(sortedKinds, gvk)
+	}
+	sort.Slice(sortedKinds, func(i, j int) bool { return sortedKinds[i].String() < sortedKinds[j].String() })
+	expand := customizer.Annotations[edgeapi.ParameterExpansionAnnotationKey] == "true"
+	var problems []string
+	for _, repl := range customizer.Replacements {
+		if !(expand && strings.Contains(repl.Value, "%(")) {
+			var value any
+			if err := json.Unmarshal([]byte(repl.Value), &value); err != nil {
+				problems = append(problems, fmt.Sprintf("the value for path %q is not JSON: %v", repl.Path, err))
+			}
+		}
+		if expand && strings.Contains(repl.Path, "%(") {
+			continue
+		}
+		parsed, err := jsonpath.ParseString(repl.Path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("path %q does not parse: %v", repl.Path, err))
+			continue
+		}
+		if len(sortedKinds) == 0 {
+			continue
+		}
+		var whyNot []string
+		for _, gvk := range sortedKinds {
+			err := CheckPath(schemas, roots[gvk], parsed)
+			if err == nil {
+				whyNot = nil
+				break
+			}
+			whyNot = append(whyNot, fmt.Sprintf("in %s %v", gvk.Kind, err))
+		}
+		if len(whyNot) > 0 {
+			problems = append(problems, fmt.Sprintf("path %q fits none of the kinds of objects that this Customizer may apply to (%s)", repl.Path, strings.Join(whyNot, "; ")))
+		}
+	}
+	return problems
+}
+
+// Candidates returns the resources that the given placements may
+// downsync from the given namespace, with Group "*" for any group.
+// Returns all=true if some placement may downsync every resource there.
+func Candidates(placements []edgeapi.EdgePlacementSpec, namespace string) (resources []metav1.GroupResource, all bool) {
+	seen := map[metav1.GroupResource]struct{}{}
+	for _, spec := range placements {
+		for _, test := range spec.Downsync {
+			if !coversNamespace(test.Namespaces, namespace) {
+				continue
+			}
+			group := "*"
+			if test.APIGroup != nil {
+				group = *test.APIGroup
+			}
+			if len(test.Resources) == 0 {
+				return nil, true
+			}
+			for _, resource := range test.Resources {
+				if resource == "*" {
+					return nil, true
+				}
+				gr := metav1.GroupResource{Group: group, Resource: resource}
+				if _, have := seen[gr]; !have {
+					seen[gr] = struct{}{}
+					resources = append(resources, gr)
+				}
+			}
+		}
+	}
+	return resources, false
+}
+
+func coversNamespace(namespaces []string, namespace string) bool {
+	if len(namespaces) == 0 {
+		return true
+	}
+	for _, ns := range namespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// Kinds returns the kinds of objects of the given resources, according
+// to the given discovery information (e.g., ServerPreferredResources).
+func Kinds(lists []*metav1.APIResourceList, resources []metav1.GroupResource) []schema.GroupVersionKind {
+	wanted := map[metav1.GroupResource]struct{}{}
+	for _, gr := range resources {
+		wanted[gr] = struct{}{}
+	}
+	var ans []schema.GroupVersionKind
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, rsc := range list.APIResources {
+			if strings.Contains(rsc.Name, "/") {
+				continue
+			}
+			_, exact := wanted[metav1.GroupResource{Group: gv.Group, Resource: rsc.Name}]
+			_, anyGroup := wanted[metav1.GroupResource{Group: "*", Resource: rsc.Name}]
+			if exact || anyGroup {
+				ans = append(ans, gv.WithKind(rsc.Kind))
+			}
+		}
+	}
+	return ans
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customizercheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	edgefake "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/fake"
+)
+
+const testDocument = `{"definitions": {
+  "io.k8s.api.apps.v1.Deployment": {
+    "type": "object",
+    "properties": {
+      "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+      "spec": {"type": "object", "properties": {
+        "replicas": {"type": "integer"},
+        "template": {"type": "object", "properties": {
+          "spec": {"type": "object", "properties": {
+            "containers": {"type": "array", "items": {"$ref": "#/definitions/io.k8s.api.core.v1.Container"}}}}}}}}},
+    "x-kubernetes-group-version-kind": [{"group": "apps", "version": "v1", "kind": "Deployment"}]
+  },
+  "io.k8s.api.core.v1.ConfigMap": {
+    "type": "object",
+    "properties": {
+      "data": {"type": "object", "additionalProperties": {"type": "string"}},
+      "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}},
+    "x-kubernetes-group-version-kind": [{"group": "", "version": "v1", "kind": "ConfigMap"}]
+  },
+  "io.k8s.api.core.v1.Container": {"type": "object", "properties": {"image": {"type": "string"}}},
+  "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {"type": "object", "properties": {
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+    "annotations": {"type": "object", "additionalProperties": {"type": "string"}}}}
+}}`
+
+func TestCheck(t *testing.T) {
+	schemas, err := apiwatch.ParseOpenAPISchemas([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	customizer := &edgeapi.Customizer{Replacements: []edgeapi.Replacement{
+		{Path: "$.spec.replicas", Value: "2"},
+		{Path: "$.spec.template.spec.containers[0].image", Value: `"nginx"`},
+		{Path: "$.metadata.labels.tier", Value: `"edge"`},
+		{Path: `$.data["a","b"]`, Value: `"x"`},
+		{Path: "$.spec.replica", Value: "2"},
+		{Path: "$.spec.replicas.count", Value: "2"},
+		{Path: "$.spec.template.spec.containers.image", Value: "nginx"},
+		{Path: "$.spec[", Value: "1"},
+	}}
+	kinds := Kinds([]*metav1.APIResourceList{
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment"}, {Name: "deployments/scale", Kind: "Scale"}}},
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap"}, {Name: "secrets", Kind: "Secret"}}},
+	}, []metav1.GroupResource{{Group: "*", Resource: "deployments"}, {Group: "", Resource: "configmaps"}})
+	if len(kinds) != 2 {
+		t.Fatalf("Expected Deployment and ConfigMap, got %v", kinds)
+	}
+	problems := Check(customizer, schemas, kinds)
+	expected := []string{
+		`path "$.spec.replica" fits none`,
+		`path "$.spec.replicas.count" fits none`,
+		`the value for path "$.spec.template.spec.containers.image" is not JSON`,
+		`path "$.spec.template.spec.containers.image" fits none`,
+		`path "$.spec[" does not parse`,
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %q", len(expected), problems)
+	}
+	for idx, prefix := range expected {
+		if !strings.HasPrefix(problems[idx], prefix) {
+			t.Errorf("Problem %d: expected %q..., got %q", idx, prefix, problems[idx])
+		}
+	}
+	if !strings.Contains(problems[0], `in Deployment $.spec has no field "replica"`) {
+		t.Errorf("Expected the problem to say where the path goes wrong, got %q", problems[0])
+	}
+
+	customizer = &edgeapi.Customizer{
+		ObjectMeta:   metav1.ObjectMeta{Annotations: map[string]string{edgeapi.ParameterExpansionAnnotationKey: "true"}},
+		Replacements: []edgeapi.Replacement{{Path: "$.metadata.labels.%(zone)", Value: "%(replicas)"}},
+	}
+	if problems := Check(customizer, schemas, kinds); len(problems) != 0 {
+		t.Errorf("Expected parameterized replacements to pass, got %q", problems)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	apps := "apps"
+	specs := []edgeapi.EdgePlacementSpec{
+		{Downsync: []edgeapi.DownsyncObjectTest{{APIGroup: &apps, Resources: []string{"deployments"}, Namespaces: []string{"shop"}}}},
+		{Downsync: []edgeapi.DownsyncObjectTest{{Resources: []string{"configmaps", "deployments"}, Namespaces: []string{"*"}}}},
+		{Downsync: []edgeapi.DownsyncObjectTest{{Resources: []string{"*"}, Namespaces: []string{"other"}}}},
+	}
+	resources, all := Candidates(specs, "shop")
+	expected := []metav1.GroupResource{{Group: "apps", Resource: "deployments"}, {Group: "*", Resource: "configmaps"}, {Group: "*", Resource: "deployments"}}
+	if all || len(resources) != len(expected) {
+		t.Fatalf("Expected %v, got %v (all=%v)", expected, resources, all)
+	}
+	for idx := range expected {
+		if resources[idx] != expected[idx] {
+			t.Errorf("Resource %d: expected %v, got %v", idx, expected[idx], resources[idx])
+		}
+	}
+	if _, all := Candidates(specs, "other"); !all {
+		t.Error("Expected every resource to be a candidate in namespace other")
+	}
+}
+
+type fakeDiscovery struct {
+	discovery.DiscoveryInterface
+	lists []*metav1.APIResourceList
+}
+
+func (fd fakeDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	return fd.lists, nil
+}
+
+type staticSchemas struct{ schemas *apiwatch.OpenAPISchemas }
+
+func (ss staticSchemas) Get(ctx context.Context) (*apiwatch.OpenAPISchemas, error) {
+	return ss.schemas, nil
+}
+
+func TestWebhook(t *testing.T) {
+	schemas, err := apiwatch.ParseOpenAPISchemas([]byte(testDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps := "apps"
+	edgeClient := edgefake.NewSimpleClientset(&edgeapi.EdgePlacement{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec:       edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{APIGroup: &apps, Resources: []string{"deployments"}, Namespaces: []string{"shop"}}}},
+	})
+	disco := fakeDiscovery{lists: []*metav1.APIResourceList{
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment"}}},
+	}}
+	serve := func(mode Mode, customizer *edgeapi.Customizer) *admissionv1.AdmissionResponse {
+		wh, err := NewWebhook(klog.Background(), edgeClient.EdgeV2alpha1(), disco, staticSchemas{schemas}, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := json.Marshal(customizer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := json.Marshal(&admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{
+			UID:       "uid1",
+			Kind:      metav1.GroupVersionKind{Group: edgeapi.SchemeGroupVersion.Group, Version: edgeapi.SchemeGroupVersion.Version, Kind: "Customizer"},
+			Namespace: "shop",
+			Name:      "web",
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		wh.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body)))
+		var review admissionv1.AdmissionReview
+		if err := json.Unmarshal(rec.Body.Bytes(), &review); err != nil {
+			t.Fatal(err)
+		}
+		if review.Response == nil || review.Response.UID != "uid1" {
+			t.Fatalf("Bad response %+v", review.Response)
+		}
+		return review.Response
+	}
+
+	good := &edgeapi.Customizer{Replacements: []edgeapi.Replacement{{Path: "$.spec.replicas", Value: "3"}}}
+	if resp := serve(ModeEnforce, good); !resp.Allowed || len(resp.Warnings) != 0 {
+		t.Errorf("Expected a good Customizer to be admitted, got %+v", resp)
+	}
+	typo := &edgeapi.Customizer{Replacements: []edgeapi.Replacement{{Path: "$.spec.replica", Value: "3"}}}
+	if resp := serve(ModeEnforce, typo); resp.Allowed || resp.Result.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a Customizer with a typo to be rejected, got %+v", resp)
+	}
+	if resp := serve(ModeWarn, typo); !resp.Allowed || len(resp.Warnings) != 1 {
+		t.Errorf("Expected a Customizer with a typo to be admitted with a warning, got %+v", resp)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customizercheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	edgeclient "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/typed/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/nsplacement"
+)
+
+// Mode says what the webhook does about a Customizer with problems.
+type Mode string
+
+const (
+	// ModeEnforce rejects the Customizer.
+	ModeEnforce Mode = "enforce"
+
+	// ModeWarn admits the Customizer and returns warnings to the author.
+	ModeWarn Mode = "warn"
+)
+
+// Path is where the Webhook is served.
+const Path = "/validate-customizer"
+
+// SchemaSource supplies the OpenAPI definitions of a space.
+// An *apiwatch.OpenAPISchemaCache is one.
+type SchemaSource interface {
+	Get(ctx context.Context) (*apiwatch.OpenAPISchemas, error)
+}
+
+// Webhook is a validating admission webhook for Customizers. Only
+// creations and changes to the replacements are checked.
+type Webhook struct {
+	logger    klog.Logger
+	edge      edgeclient.EdgeV2alpha1Interface
+	discovery discovery.DiscoveryInterface
+	schemas   SchemaSource
+	mode      Mode
+}
+
+// NewWebhook makes a Webhook that reads placements, discovery
+// information and schemas through the given clients, which have to be
+// for the same space as the Customizers.
+func NewWebhook(logger klog.Logger, edge edgeclient.EdgeV2alpha1Interface, disco discovery.DiscoveryInterface, schemas SchemaSource, mode Mode) (*Webhook, error) {
+	if mode != ModeEnforce && mode != ModeWarn {
+		return nil, fmt.Errorf("mode must be %q or %q, not %q", ModeEnforce, ModeWarn, mode)
+	}
+	return &Webhook{logger: logger, edge: edge, discovery: disco, schemas: schemas, mode: mode}, nil
+}
+
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "request body is not an AdmissionReview", http.StatusBadRequest)
+		return
+	}
+	review.Response = wh.admit(req.Context(), review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&review); err != nil {
+		wh.logger.V(3).Info("Failed to write response", "err", err)
+	}
+}
+
+func (wh *Webhook) admit(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	logger := wh.logger.WithValues("namespace", req.Namespace, "name", req.Name, "user", req.UserInfo.Username)
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Kind.Kind != "Customizer" || (req.Operation != admissionv1.Create && req.Operation != admissionv1.Update) {
+		return allowed
+	}
+	var customizer edgeapi.Customizer
+	if err := json.Unmarshal(req.Object.Raw, &customizer); err != nil {
+		return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, "failed to parse Customizer: "+err.Error())
+	}
+	if req.Operation == admissionv1.Update {
+		var old edgeapi.Customizer
+		if err := json.Unmarshal(req.OldObject.Raw, &old); err == nil && apiequality.Semantic.DeepEqual(old.Replacements, customizer.Replacements) &&
+			old.Annotations[edgeapi.ParameterExpansionAnnotationKey] == customizer.Annotations[edgeapi.ParameterExpansionAnnotationKey] {
+			return allowed
+		}
+	}
+	problems, err := wh.review(ctx, &customizer, req.Namespace)
+	if err != nil {
+		logger.Error(err, "Failed to check Customizer")
+		allowed.Warnings = []string{"KubeStellar could not check the paths of this Customizer: " + err.Error()}
+		return allowed
+	}
+	if len(problems) == 0 {
+		return allowed
+	}
+	logger.V(2).Info("Customizer has problems", "problems", problems, "mode", wh.mode)
+	if wh.mode == ModeWarn {
+		allowed.Warnings = problems
+		return allowed
+	}
+	return denied(http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, "invalid Customizer: "+strings.Join(problems, "; "))
+}
+
+// review returns the problems with the given Customizer, in the given namespace.
+func (wh *Webhook) review(ctx context.Context, customizer *edgeapi.Customizer, namespace string) ([]string, error) {
+	eps, err := wh.edge.EdgePlacements().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EdgePlacements: %w", err)
+	}
+	neps, err := wh.edge.NamespacedEdgePlacements(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NamespacedEdgePlacements: %w", err)
+	}
+	specs := make([]edgeapi.EdgePlacementSpec, 0, len(eps.Items)+len(neps.Items))
+	for _, ep := range eps.Items {
+		specs = append(specs, ep.Spec)
+	}
+	for _, nep := range neps.Items {
+		specs = append(specs, nsplacement.DesiredEdgePlacement(nep.Namespace, nep.Name, nep.Spec).Spec)
+	}
+	resources, all := Candidates(specs, namespace)
+	if all || len(resources) == 0 {
+		// Any path could fit; still report what does not parse.
+		return Check(customizer, &apiwatch.OpenAPISchemas{}, nil), nil
+	}
+	lists, err := wh.discovery.ServerPreferredResources()
+	if err != nil && len(lists) == 0 {
+		return nil, fmt.Errorf("failed to discover resources: %w", err)
+	}
+	schemas, err := wh.schemas.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Check(customizer, schemas, Kinds(lists, resources)), nil
+}
+
+func denied(code int32, reason metav1.StatusReason, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Result: &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    code,
+		Reason:  reason,
+		Message: message,
+	}}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package decisionstore mirrors the placement decisions (the
+// destinations that the where-resolver chose for each EdgePlacement,
+// held in SinglePlacementSlices) and a summary of each EdgePlacement's
+// status into external stores, such as a SQL database or Elasticsearch.
+// Each change is added as a new Record, so the stores keep a history
+// that can be queried long after the apiserver has forgotten it.
+package decisionstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/destination"
+)
+
+// Record is what is known, at a moment, about the decisions for one EdgePlacement.
+type Record struct {
+	// Time is when the Record was made.
+	Time time.Time `json:"time"`
+
+	// EdgePlacement is the name of the EdgePlacement.
+	EdgePlacement string `json:"edgePlacement"`
+
+	// UID distinguishes the EdgePlacements that have had the same name.
+	UID string `json:"uid,omitempty"`
+
+	// Generation is the metadata.generation of the EdgePlacement.
+	Generation int64 `json:"generation,omitempty"`
+
+	// Deleted tells that the EdgePlacement is gone; the Record then
+	// has no destinations.
+	Deleted bool `json:"deleted,omitempty"`
+
+	// Destinations are the keys (see destination.Destination.Key) of
+	// the chosen destinations, sorted.
+	Destinations []string `json:"destinations"`
+
+	// MatchingLocationCount is from the EdgePlacement's status.
+	MatchingLocationCount int32 `json:"matchingLocationCount"`
+
+	// Conditions maps the type of each condition in the EdgePlacement's
+	// status to its status.
+	Conditions map[string]metav1.ConditionStatus `json:"conditions,omitempty"`
+}
+
+// Store keeps a history of Records.
+type Store interface {
+	// Put adds the given Record to the history.
+	Put(ctx context.Context, record Record) error
+}
+
+// RecordOf returns the Record, made at the given time, of the given
+// EdgePlacement and its SinglePlacementSlices.
+func RecordOf(ep *edgeapi.EdgePlacement, slices []*edgeapi.SinglePlacementSlice, now time.Time) Record {
+	record := Record{
+		Time:                  now,
+		EdgePlacement:         ep.Name,
+		UID:                   string(ep.UID),
+		Generation:            ep.Generation,
+		Destinations:          []string{},
+		MatchingLocationCount: ep.Status.MatchingLocationCount,
+	}
+	seen := map[string]bool{}
+	for _, slice := range slices {
+		for _, sp := range slice.Destinations {
+			key := destination.Of(sp).Key()
+			if !seen[key] {
+				seen[key] = true
+				record.Destinations = append(record.Destinations, key)
+			}
+		}
+	}
+	sort.Strings(record.Destinations)
+	if len(ep.Status.Conditions) > 0 {
+		record.Conditions = make(map[string]metav1.ConditionStatus, len(ep.Status.Conditions))
+		for _, cond := range ep.Status.Conditions {
+			record.Conditions[cond.Type] = cond.Status
+		}
+	}
+	return record
+}
+
+// DeletionRecord returns the Record, made at the given time, of the
+// deletion of the EdgePlacement last recorded in the given Record.
+func DeletionRecord(last Record, now time.Time) Record {
+	return Record{Time: now, EdgePlacement: last.EdgePlacement, UID: last.UID, Generation: last.Generation, Deleted: true, Destinations: []string{}}
+}
+
+// sameDecisions tells whether the two Records differ in nothing but their times.
+func sameDecisions(a, b Record) bool {
+	if a.EdgePlacement != b.EdgePlacement || a.UID != b.UID || a.Generation != b.Generation || a.Deleted != b.Deleted ||
+		a.MatchingLocationCount != b.MatchingLocationCount || len(a.Destinations) != len(b.Destinations) || len(a.Conditions) != len(b.Conditions) {
+		return false
+	}
+	for idx, dest := range a.Destinations {
+		if b.Destinations[idx] != dest {
+			return false
+		}
+	}
+	for condType, status := range a.Conditions {
+		if bStatus, found := b.Conditions[condType]; !found || bStatus != status {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decisionstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+)
+
+type fakeStore struct {
+	records []Record
+	err     error
+}
+
+func (fs *fakeStore) Put(ctx context.Context, record Record) error {
+	if fs.err != nil {
+		return fs.err
+	}
+	fs.records = append(fs.records, record)
+	return nil
+}
+
+func TestMirror(t *testing.T) {
+	placements := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	slices := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	ep := &edgeapi.EdgePlacement{
+		ObjectMeta: metav1.ObjectMeta{Name: "ep1", UID: "u1", Generation: 2},
+		Status: edgeapi.EdgePlacementStatus{MatchingLocationCount: 2,
+			Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue}}},
+	}
+	slice := &edgeapi.SinglePlacementSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: "ep1-0", Labels: map[string]string{edgeapi.SourcePlacementLabelKey: "ep1"}},
+		Destinations: []edgeapi.SinglePlacement{
+			{Cluster: "imw1", LocationName: "loc2", SyncTargetName: "edge-2"},
+			{Cluster: "imw1", LocationName: "loc1", SyncTargetName: "edge-1"},
+		},
+	}
+	placements.Add(ep)
+	slices.Add(slice)
+	clk := clocktesting.NewFakePassiveClock(time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC))
+	good, bad := &fakeStore{}, &fakeStore{err: errors.New("unavailable")}
+	mir := NewMirror(context.Background(), clk, edgelisters.NewEdgePlacementLister(placements), edgelisters.NewSinglePlacementSliceLister(slices), good, bad)
+	ctx, logger := context.Background(), klog.Background()
+	if !mir.sync(ctx, logger, "ep1") {
+		t.Error("Expected a retry for the failing store")
+	}
+	expected := []Record{{Time: clk.Now(), EdgePlacement: "ep1", UID: "u1", Generation: 2, Destinations: []string{"imw1/edge-1", "imw1/edge-2"},
+		MatchingLocationCount: 2, Conditions: map[string]metav1.ConditionStatus{"Ready": metav1.ConditionTrue}}}
+	if !reflect.DeepEqual(good.records, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, good.records)
+	}
+	bad.err = nil
+	clk.SetTime(clk.Now().Add(time.Minute))
+	if mir.sync(ctx, logger, "ep1") {
+		t.Error("Expected no retry")
+	}
+	if len(good.records) != 1 || len(bad.records) != 1 {
+		t.Fatalf("Expected the record once in each store, got %+v and %+v", good.records, bad.records)
+	}
+	slices.Delete(slice)
+	mir.sync(ctx, logger, "ep1")
+	if len(good.records) != 2 || len(good.records[1].Destinations) != 0 {
+		t.Fatalf("Expected a record with no destinations, got %+v", good.records)
+	}
+	placements.Delete(ep)
+	mir.sync(ctx, logger, "ep1")
+	mir.sync(ctx, logger, "ep1")
+	if len(good.records) != 3 || !good.records[2].Deleted || good.records[2].UID != "u1" {
+		t.Fatalf("Expected one deletion record, got %+v", good.records)
+	}
+}
+
+type fakeExecer struct {
+	queries []string
+	args    [][]any
+}
+
+func (fe *fakeExecer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	fe.queries = append(fe.queries, query)
+	fe.args = append(fe.args, args)
+	return nil, nil
+}
+
+func TestPostgresStore(t *testing.T) {
+	if _, err := NewPostgresStore(context.Background(), &fakeExecer{}, "decisions; DROP TABLE x"); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+	db := &fakeExecer{}
+	store, err := NewPostgresStore(context.Background(), db, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.queries) != 2 || !strings.HasPrefix(db.queries[0], "CREATE TABLE IF NOT EXISTS "+DefaultTable) {
+		t.Fatalf("Expected the table to be created, got %q", db.queries)
+	}
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.Put(context.Background(), Record{Time: now, EdgePlacement: "ep1", Destinations: []string{"imw1/edge-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	expectedArgs := []any{now, "ep1", "", int64(0), false, int32(0), `["imw1/edge-1"]`, `{}`}
+	if !strings.HasPrefix(db.queries[2], "INSERT INTO "+DefaultTable) || !reflect.DeepEqual(db.args[2], expectedArgs) {
+		t.Errorf("Expected an insert with %v, got %q with %v", expectedArgs, db.queries[2], db.args[2])
+	}
+}
+
+func TestElasticsearchStore(t *testing.T) {
+	var path, user string
+	var got Record
+	status := http.StatusCreated
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		user, _, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &got)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	if _, err := NewElasticsearchStore(nil, server.URL, "Bad/Index"); err == nil {
+		t.Error("Expected an invalid index name to be rejected")
+	}
+	store, err := NewElasticsearchStore(nil, strings.Replace(server.URL, "http://", "http://elastic:secret@", 1)+"/", DefaultIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := Record{Time: time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC), EdgePlacement: "ep1", Destinations: []string{"imw1/edge-1"}}
+	if err := store.Put(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/"+DefaultIndex+"/_doc" || user != "elastic" || !reflect.DeepEqual(got, record) {
+		t.Errorf("Unexpected request to %s by %q with %+v", path, user, got)
+	}
+	status = http.StatusBadRequest
+	if err := store.Put(context.Background(), record); err == nil {
+		t.Error("Expected an error for a rejected document")
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decisionstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultIndex is the default name of the index of an ElasticsearchStore.
+const DefaultIndex = "kubestellar-placement-decisions"
+
+// ElasticsearchStore keeps Records as documents in an Elasticsearch
+// index, added through the document API.
+type ElasticsearchStore struct {
+	client *http.Client
+	target string
+}
+
+var _ Store = &ElasticsearchStore{}
+
+// NewElasticsearchStore returns an ElasticsearchStore that adds documents
+// to the given index of the Elasticsearch at the given URL. Credentials
+// for basic authentication may be given in the URL.
+func NewElasticsearchStore(client *http.Client, baseURL, index string) (*ElasticsearchStore, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Elasticsearch URL %q: %w", baseURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("Elasticsearch URL %q is not http or https", baseURL)
+	}
+	if index == "" || strings.ContainsAny(index, `/\ *?"<>|,#`) || index != strings.ToLower(index) {
+		return nil, fmt.Errorf("invalid Elasticsearch index name %q", index)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &ElasticsearchStore{client: client, target: strings.TrimSuffix(baseURL, "/") + "/" + index + "/_doc"}, nil
+}
+
+func (es *ElasticsearchStore) Put(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, es.target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Elasticsearch returned %s: %s", resp.Status, detail)
+	}
+	return nil
+}
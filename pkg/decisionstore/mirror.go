@@ -0,0 +1,225 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decisionstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+)
+
+// Mirror puts a Record in each of its Stores whenever the decisions
+// for an EdgePlacement change. A Store that fails is retried (with
+// backoff) without repeating the Record in the others.
+//
+// What was last recorded is only remembered in memory, so after a
+// restart every EdgePlacement is recorded once more, and an
+// EdgePlacement deleted while the Mirror was not running is never
+// recorded as deleted.
+type Mirror struct {
+	context    context.Context
+	clock      clock.PassiveClock
+	placements edgelisters.EdgePlacementLister
+	slices     edgelisters.SinglePlacementSliceLister
+	stores     []Store
+
+	queue workqueue.RateLimitingInterface
+
+	mutex sync.Mutex
+
+	// last holds, for each Store, the latest Record put there for each EdgePlacement
+	last []map[string]Record
+}
+
+// NewMirror returns a Mirror that writes into the given Stores.
+// Add it as event handler to the EdgePlacement informer and, through
+// SliceHandler, to the SinglePlacementSlice informer.
+func NewMirror(ctx context.Context, clock clock.PassiveClock, placements edgelisters.EdgePlacementLister, slices edgelisters.SinglePlacementSliceLister, stores ...Store) *Mirror {
+	mir := &Mirror{
+		context:    ctx,
+		clock:      clock,
+		placements: placements,
+		slices:     slices,
+		stores:     stores,
+		queue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "decision-mirror"),
+		last:       make([]map[string]Record, len(stores)),
+	}
+	for idx := range stores {
+		mir.last[idx] = map[string]Record{}
+	}
+	return mir
+}
+
+// Run animates the Mirror, finishing and returning when its context is done.
+// Call this after the informers have been started.
+func (mir *Mirror) Run(concurrency int, hasSynced ...cache.InformerSynced) {
+	ctx := mir.context
+	logger := klog.FromContext(ctx)
+	doneCh := ctx.Done()
+	defer mir.queue.ShutDown()
+	if !cache.WaitForNamedCacheSync("decision-mirror", doneCh, hasSynced...) {
+		logger.Error(nil, "Informer syncs not achieved")
+		return
+	}
+	logger.V(1).Info("Informers synced")
+	for worker := 0; worker < concurrency; worker++ {
+		go mir.syncLoop(ctx, worker)
+	}
+	<-doneCh
+}
+
+func (mir *Mirror) OnAdd(obj any) {
+	mir.enqueue(obj)
+}
+
+func (mir *Mirror) OnUpdate(oldObj, newObj any) {
+	mir.enqueue(newObj)
+}
+
+func (mir *Mirror) OnDelete(obj any) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	mir.enqueue(obj)
+}
+
+func (mir *Mirror) enqueue(obj any) {
+	ep, ok := obj.(*edgeapi.EdgePlacement)
+	if !ok {
+		klog.FromContext(mir.context).Error(nil, "Notified of object of unexpected type", "object", obj, "type", fmt.Sprintf("%T", obj))
+		return
+	}
+	mir.queue.Add(ep.Name)
+}
+
+// SliceHandler returns the event handler for SinglePlacementSlices,
+// which enqueues the EdgePlacement that a slice belongs to.
+func (mir *Mirror) SliceHandler() cache.ResourceEventHandler {
+	enqueue := func(obj any) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		slice, ok := obj.(*edgeapi.SinglePlacementSlice)
+		if !ok {
+			klog.FromContext(mir.context).Error(nil, "Notified of object of unexpected type", "object", obj, "type", fmt.Sprintf("%T", obj))
+			return
+		}
+		if epName := slice.Labels[edgeapi.SourcePlacementLabelKey]; epName != "" {
+			mir.queue.Add(epName)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(oldObj, newObj any) { enqueue(newObj) },
+		DeleteFunc: enqueue,
+	}
+}
+
+func (mir *Mirror) syncLoop(ctx context.Context, worker int) {
+	logger := klog.FromContext(ctx).WithValues("worker", worker)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(4).Info("SyncLoop start")
+	for {
+		ref, shutdown := mir.queue.Get()
+		if shutdown {
+			logger.V(2).Info("Queue shutdown")
+			return
+		}
+		name := ref.(string)
+		if mir.sync(ctx, logger.WithValues("edgePlacement", name), name) {
+			mir.queue.AddRateLimited(ref)
+		} else {
+			mir.queue.Forget(ref)
+		}
+		mir.queue.Done(ref)
+	}
+}
+
+// sync brings the Stores up to date on the named EdgePlacement.
+// Returns whether to retry.
+func (mir *Mirror) sync(ctx context.Context, logger klog.Logger, name string) bool {
+	now := mir.clock.Now()
+	// current stays nil if the EdgePlacement is gone
+	var current *Record
+	ep, err := mir.placements.Get(name)
+	switch {
+	case k8sapierrors.IsNotFound(err):
+	case err != nil:
+		logger.Error(err, "Failed to get EdgePlacement")
+		return true
+	default:
+		slices, err := mir.slices.List(labels.SelectorFromSet(labels.Set{edgeapi.SourcePlacementLabelKey: name}))
+		if err != nil {
+			logger.Error(err, "Failed to list SinglePlacementSlices")
+			return true
+		}
+		record := RecordOf(ep, slices, now)
+		current = &record
+	}
+	var retry bool
+	for idx, store := range mir.stores {
+		record, changed := mir.recordFor(idx, name, current, now)
+		if !changed {
+			continue
+		}
+		if err := store.Put(ctx, record); err != nil {
+			logger.Error(err, "Failed to put Record in Store", "store", idx)
+			retry = true
+			continue
+		}
+		logger.V(2).Info("Put Record in Store", "store", idx, "deleted", record.Deleted, "destinations", len(record.Destinations))
+		mir.setLast(idx, name, record)
+	}
+	return retry
+}
+
+// recordFor returns the Record to put in the indexed Store, given the
+// current Record (nil if the EdgePlacement is gone), and whether there
+// is any.
+func (mir *Mirror) recordFor(storeIdx int, name string, current *Record, now time.Time) (Record, bool) {
+	mir.mutex.Lock()
+	defer mir.mutex.Unlock()
+	last, found := mir.last[storeIdx][name]
+	if current == nil {
+		if !found {
+			return Record{}, false
+		}
+		return DeletionRecord(last, now), true
+	}
+	return *current, !found || !sameDecisions(last, *current)
+}
+
+func (mir *Mirror) setLast(storeIdx int, name string, record Record) {
+	mir.mutex.Lock()
+	defer mir.mutex.Unlock()
+	if record.Deleted {
+		delete(mir.last[storeIdx], name)
+	} else {
+		mir.last[storeIdx][name] = record
+	}
+}
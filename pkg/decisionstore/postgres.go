@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decisionstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultTable is the default name of the table of a PostgresStore.
+const DefaultTable = "kubestellar_placement_decisions"
+
+// Execer is the part of a *sql.DB that a PostgresStore uses.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// PostgresStore keeps Records as rows of a table in a PostgreSQL
+// database, with the destinations and conditions in JSONB columns so
+// that they can be queried, e.g.:
+//
+//	SELECT recorded_at, edge_placement FROM kubestellar_placement_decisions
+//	WHERE destinations ? 'root:imw1/edge-1' ORDER BY recorded_at;
+//
+// The *sql.DB must be opened with a PostgreSQL driver, which the
+// program has to link in.
+type PostgresStore struct {
+	db     Execer
+	insert string
+}
+
+var _ Store = &PostgresStore{}
+
+var tableNameRE = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// NewPostgresStore returns a PostgresStore that uses the given table,
+// which it creates (with an index on EdgePlacement and time) if it does
+// not exist.
+func NewPostgresStore(ctx context.Context, db Execer, table string) (*PostgresStore, error) {
+	if !tableNameRE.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	create := `CREATE TABLE IF NOT EXISTS ` + table + ` (
+	id BIGSERIAL PRIMARY KEY,
+	recorded_at TIMESTAMPTZ NOT NULL,
+	edge_placement TEXT NOT NULL,
+	uid TEXT NOT NULL,
+	generation BIGINT NOT NULL,
+	deleted BOOLEAN NOT NULL,
+	matching_location_count INTEGER NOT NULL,
+	destinations JSONB NOT NULL,
+	conditions JSONB NOT NULL
+)`
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("failed to create table %s: %w", table, err)
+	}
+	index := `CREATE INDEX IF NOT EXISTS ` + table + `_ep_time ON ` + table + ` (edge_placement, recorded_at)`
+	if _, err := db.ExecContext(ctx, index); err != nil {
+		return nil, fmt.Errorf("failed to create index on table %s: %w", table, err)
+	}
+	return &PostgresStore{
+		db: db,
+		insert: `INSERT INTO ` + table + ` (recorded_at, edge_placement, uid, generation, deleted, matching_location_count, destinations, conditions)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+	}, nil
+}
+
+func (ps *PostgresStore) Put(ctx context.Context, record Record) error {
+	destinations, err := json.Marshal(record.Destinations)
+	if err != nil {
+		return err
+	}
+	conditions := record.Conditions
+	if conditions == nil {
+		conditions = map[string]metav1.ConditionStatus{}
+	}
+	conditionsJSON, err := json.Marshal(conditions)
+	if err != nil {
+		return err
+	}
+	_, err = ps.db.ExecContext(ctx, ps.insert, record.Time, record.EdgePlacement, record.UID, record.Generation, record.Deleted,
+		record.MatchingLocationCount, string(destinations), string(conditionsJSON))
+	return err
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dependencies finds the references from a workload object to
+// other objects in its namespace that it needs in order to work, such
+// as the ConfigMaps mounted by a Deployment's pods or the Services
+// behind an Ingress.
+package dependencies
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	configMaps      = schema.GroupResource{Resource: "configmaps"}
+	secrets         = schema.GroupResource{Resource: "secrets"}
+	serviceAccounts = schema.GroupResource{Resource: "serviceaccounts"}
+	services        = schema.GroupResource{Resource: "services"}
+	ingresses       = schema.GroupResource{Group: "networking.k8s.io", Resource: "ingresses"}
+)
+
+// podSpecPaths maps each resource whose objects hold a pod spec to the
+// path of that spec.
+var podSpecPaths = map[schema.GroupResource][]string{
+	{Resource: "pods"}:                        {"spec"},
+	{Group: "apps", Resource: "deployments"}:  {"spec", "template", "spec"},
+	{Group: "apps", Resource: "replicasets"}:  {"spec", "template", "spec"},
+	{Group: "apps", Resource: "statefulsets"}: {"spec", "template", "spec"},
+	{Group: "apps", Resource: "daemonsets"}:   {"spec", "template", "spec"},
+	{Group: "batch", Resource: "jobs"}:        {"spec", "template", "spec"},
+	{Group: "batch", Resource: "cronjobs"}:    {"spec", "jobTemplate", "spec", "template", "spec"},
+	{Resource: "replicationcontrollers"}:      {"spec", "template", "spec"},
+}
+
+// Ref identifies an object that another object refers to.
+type Ref struct {
+	Group     string
+	Resource  string
+	Namespace string
+	Name      string
+}
+
+// GroupResource returns the resource of the referenced object.
+func (ref Ref) GroupResource() schema.GroupResource {
+	return schema.GroupResource{Group: ref.Group, Resource: ref.Resource}
+}
+
+// IsReferrer tells whether Extract finds references in objects of the given resource.
+func IsReferrer(gr schema.GroupResource) bool {
+	return podSpecPaths[gr] != nil || gr == ingresses
+}
+
+// IsReferent tells whether Extract can return references to objects of the given resource.
+func IsReferent(gr schema.GroupResource) bool {
+	switch gr {
+	case configMaps, secrets, serviceAccounts, services:
+		return true
+	default:
+		return false
+	}
+}
+
+// Extract returns the references from the given object, of the given
+// resource, to other objects in its namespace. The result is sorted and
+// has no duplicates. An object of a resource that is not a referrer has
+// no references.
+func Extract(gr schema.GroupResource, obj *unstructured.Unstructured) []Ref {
+	refs := refSet{namespace: obj.GetNamespace(), refs: map[Ref]struct{}{}}
+	if path := podSpecPaths[gr]; path != nil {
+		if podSpec, found, _ := unstructured.NestedMap(obj.Object, path...); found {
+			refs.addPodSpec(podSpec)
+		}
+	} else if gr == ingresses {
+		refs.addIngressSpec(nestedMap(obj.Object, "spec"))
+	}
+	return refs.sorted()
+}
+
+type refSet struct {
+	namespace string
+	refs      map[Ref]struct{}
+}
+
+func (rs refSet) add(gr schema.GroupResource, name string) {
+	if name == "" {
+		return
+	}
+	rs.refs[Ref{Group: gr.Group, Resource: gr.Resource, Namespace: rs.namespace, Name: name}] = struct{}{}
+}
+
+func (rs refSet) sorted() []Ref {
+	ans := make([]Ref, 0, len(rs.refs))
+	for ref := range rs.refs {
+		ans = append(ans, ref)
+	}
+	sort.Slice(ans, func(i, j int) bool {
+		a, b := ans[i], ans[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.Name < b.Name
+	})
+	return ans
+}
+
+func (rs refSet) addPodSpec(podSpec map[string]any) {
+	serviceAccount := nestedString(podSpec, "serviceAccountName")
+	if serviceAccount == "" {
+		serviceAccount = nestedString(podSpec, "serviceAccount")
+	}
+	rs.add(serviceAccounts, serviceAccount)
+	for _, ips := range nestedMaps(podSpec, "imagePullSecrets") {
+		rs.add(secrets, nestedString(ips, "name"))
+	}
+	for _, volume := range nestedMaps(podSpec, "volumes") {
+		rs.add(configMaps, nestedString(volume, "configMap", "name"))
+		rs.add(secrets, nestedString(volume, "secret", "secretName"))
+		for _, source := range nestedMaps(volume, "projected", "sources") {
+			rs.add(configMaps, nestedString(source, "configMap", "name"))
+			rs.add(secrets, nestedString(source, "secret", "name"))
+		}
+	}
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		for _, container := range nestedMaps(podSpec, field) {
+			for _, envFrom := range nestedMaps(container, "envFrom") {
+				rs.add(configMaps, nestedString(envFrom, "configMapRef", "name"))
+				rs.add(secrets, nestedString(envFrom, "secretRef", "name"))
+			}
+			for _, env := range nestedMaps(container, "env") {
+				rs.add(configMaps, nestedString(env, "valueFrom", "configMapKeyRef", "name"))
+				rs.add(secrets, nestedString(env, "valueFrom", "secretKeyRef", "name"))
+			}
+		}
+	}
+}
+
+func (rs refSet) addIngressSpec(spec map[string]any) {
+	rs.add(services, nestedString(spec, "defaultBackend", "service", "name"))
+	for _, rule := range nestedMaps(spec, "rules") {
+		for _, path := range nestedMaps(rule, "http", "paths") {
+			rs.add(services, nestedString(path, "backend", "service", "name"))
+		}
+	}
+	for _, tls := range nestedMaps(spec, "tls") {
+		rs.add(secrets, nestedString(tls, "secretName"))
+	}
+}
+
+// nestedString returns the string at the given path, or "" if there is none.
+func nestedString(obj map[string]any, path ...string) string {
+	ans, _, _ := unstructured.NestedString(obj, path...)
+	return ans
+}
+
+// nestedMap returns the map at the given path, or nil if there is none.
+func nestedMap(obj map[string]any, path ...string) map[string]any {
+	ans, _, _ := unstructured.NestedMap(obj, path...)
+	return ans
+}
+
+// nestedMaps returns the members of the list at the given path that are maps.
+func nestedMaps(obj map[string]any, path ...string) []map[string]any {
+	list, _, _ := unstructured.NestedSlice(obj, path...)
+	ans := make([]map[string]any, 0, len(list))
+	for _, member := range list {
+		if memberMap, ok := member.(map[string]any); ok {
+			ans = append(ans, memberMap)
+		}
+	}
+	return ans
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dependencies
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+func mustParse(t *testing.T, text string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(text), &obj.Object); err != nil {
+		t.Fatalf("Failed to parse test object: %v", err)
+	}
+	return obj
+}
+
+func TestExtractDeployment(t *testing.T) {
+	deployment := mustParse(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata: {namespace: shop, name: web}
+spec:
+  template:
+    spec:
+      serviceAccountName: web-sa
+      imagePullSecrets: [{name: registry}]
+      volumes:
+      - name: config
+        configMap: {name: web-config}
+      - name: certs
+        secret: {secretName: web-certs}
+      - name: bundle
+        projected:
+          sources:
+          - configMap: {name: ca-bundle}
+          - secret: {name: web-certs}
+      containers:
+      - name: web
+        envFrom:
+        - configMapRef: {name: web-env}
+        env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef: {name: db, key: password}
+        - name: PLAIN
+          value: x
+`)
+	got := Extract(schema.GroupResource{Group: "apps", Resource: "deployments"}, deployment)
+	expected := []Ref{
+		{Resource: "configmaps", Namespace: "shop", Name: "ca-bundle"},
+		{Resource: "configmaps", Namespace: "shop", Name: "web-config"},
+		{Resource: "configmaps", Namespace: "shop", Name: "web-env"},
+		{Resource: "secrets", Namespace: "shop", Name: "db"},
+		{Resource: "secrets", Namespace: "shop", Name: "registry"},
+		{Resource: "secrets", Namespace: "shop", Name: "web-certs"},
+		{Resource: "serviceaccounts", Namespace: "shop", Name: "web-sa"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
+
+func TestExtractIngress(t *testing.T) {
+	ingress := mustParse(t, `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata: {namespace: shop, name: web}
+spec:
+  defaultBackend:
+    service: {name: fallback, port: {number: 80}}
+  tls: [{secretName: web-tls}]
+  rules:
+  - http:
+      paths:
+      - path: /
+        backend:
+          service: {name: web, port: {number: 80}}
+`)
+	got := Extract(ingresses, ingress)
+	expected := []Ref{
+		{Resource: "secrets", Namespace: "shop", Name: "web-tls"},
+		{Resource: "services", Namespace: "shop", Name: "fallback"},
+		{Resource: "services", Namespace: "shop", Name: "web"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
+
+func TestExtractOther(t *testing.T) {
+	cm := mustParse(t, `{apiVersion: v1, kind: ConfigMap, metadata: {namespace: shop, name: x}, data: {a: b}}`)
+	if got := Extract(configMaps, cm); len(got) != 0 {
+		t.Errorf("Expected no references but got %v", got)
+	}
+	if IsReferrer(configMaps) || !IsReferent(configMaps) {
+		t.Error("ConfigMaps are referents but not referrers")
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package destination defines the identity of a destination of workload.
+//
+// A destination is a SyncTarget, and a SyncTarget name is unique only
+// within its inventory space. So a destination is identified by the ID
+// of that space together with the name of the SyncTarget. Its Key is the
+// canonical string form, for map keys, event contents and metric labels;
+// Parse reverses it.
+package destination
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+// Separator separates the space ID from the SyncTarget name in a Key.
+// It can appear in neither.
+const Separator = "/"
+
+// Destination identifies a SyncTarget.
+type Destination struct {
+	// ClusterID is the ID of the inventory space that holds the SyncTarget.
+	ClusterID string
+
+	// SyncTargetName is the name of the SyncTarget in that space.
+	SyncTargetName string
+
+	// SpacePath is the path of the inventory space, when known.
+	// It is for display only and is not part of the identity.
+	SpacePath string
+}
+
+// New returns the Destination for the named SyncTarget in the space with the given ID.
+func New(clusterID, syncTargetName string) Destination {
+	return Destination{ClusterID: clusterID, SyncTargetName: syncTargetName}
+}
+
+// Of returns the Destination of the given SinglePlacement.
+func Of(sp edgeapi.SinglePlacement) Destination {
+	return New(sp.Cluster, sp.SyncTargetName)
+}
+
+// Key returns the canonical string form: the space ID, Separator, and the SyncTarget name.
+func (dest Destination) Key() string {
+	return dest.ClusterID + Separator + dest.SyncTargetName
+}
+
+func (dest Destination) String() string {
+	if dest.SpacePath != "" {
+		return dest.Key() + " (" + dest.SpacePath + ")"
+	}
+	return dest.Key()
+}
+
+// LabelValue returns a form that can be a label value. Unlike Key,
+// it is shortened if need be and thus can not always be parsed.
+func (dest Destination) LabelValue() string {
+	return naming.LabelValue(dest.ClusterID + "." + dest.SyncTargetName)
+}
+
+// Parse returns the Destination whose Key is given.
+func Parse(key string) (Destination, error) {
+	parts := strings.Split(key, Separator)
+	if len(parts) != 2 {
+		return Destination{}, fmt.Errorf("destination %q is not of the form <space ID>%s<SyncTarget name>", key, Separator)
+	}
+	dest := New(parts[0], parts[1])
+	return dest, dest.Validate()
+}
+
+// Validate returns an error if either part is missing or malformed.
+func (dest Destination) Validate() error {
+	if dest.ClusterID == "" {
+		return fmt.Errorf("destination %q has no space ID", dest.Key())
+	}
+	if strings.Contains(dest.ClusterID, Separator) {
+		return fmt.Errorf("destination space ID %q contains %q", dest.ClusterID, Separator)
+	}
+	if errs := validation.IsDNS1123Subdomain(dest.SyncTargetName); len(errs) > 0 {
+		return fmt.Errorf("destination SyncTarget name %q is invalid: %s", dest.SyncTargetName, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Equal tells whether the two identify the same SyncTarget.
+func (dest Destination) Equal(other Destination) bool {
+	return dest.ClusterID == other.ClusterID && dest.SyncTargetName == other.SyncTargetName
+}
+
+// Less orders destinations by space ID and then SyncTarget name.
+func (dest Destination) Less(other Destination) bool {
+	if dest.ClusterID != other.ClusterID {
+		return dest.ClusterID < other.ClusterID
+	}
+	return dest.SyncTargetName < other.SyncTargetName
+}
+
+// Is tells whether the given SinglePlacement goes to this destination.
+func (dest Destination) Is(sp edgeapi.SinglePlacement) bool {
+	return dest.Equal(Of(sp))
+}
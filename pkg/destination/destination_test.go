@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"testing"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestKeyRoundTrip(t *testing.T) {
+	dest := Of(edgeapi.SinglePlacement{Cluster: "1xyz", LocationName: "loc1", SyncTargetName: "st.one", SyncTargetUID: "u1"})
+	key := dest.Key()
+	if key != "1xyz/st.one" {
+		t.Errorf("Got key %q", key)
+	}
+	parsed, err := Parse(key)
+	if err != nil || !parsed.Equal(dest) {
+		t.Errorf("Parse(%q) = %v, %v", key, parsed, err)
+	}
+	withPath := dest
+	withPath.SpacePath = "root:imw1"
+	if !withPath.Equal(dest) || withPath.String() != "1xyz/st.one (root:imw1)" {
+		t.Errorf("SpacePath mishandled: %v", withPath)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, key := range []string{"", "st1", "/st1", "c1/", "c1/st1/x", "c1/ST_1"} {
+		if dest, err := Parse(key); err == nil {
+			t.Errorf("Parse(%q) unexpectedly succeeded with %v", key, dest)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	if !New("a", "z").Less(New("b", "a")) || !New("a", "a").Less(New("a", "b")) || New("a", "a").Less(New("a", "a")) {
+		t.Error("Wrong order")
+	}
+	if New("c1", "st1").Is(edgeapi.SinglePlacement{Cluster: "c2", SyncTargetName: "st1"}) {
+		t.Error("Destinations in different spaces are equal")
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package equivalence decides whether an object in an apiserver already
+// has the content that a writer wants it to have, even though the
+// apiserver has defaulted fields that the writer left out. Comparing
+// naively makes a writer rewrite such an object over and over.
+//
+// Both objects are first normalized: the content that the apiserver
+// manages is removed (see the normalize package) and kind-specific
+// Normalizers put equal values into the same form. If the desired
+// object is then not contained in the actual one, they differ. If it
+// is, the desired object is submitted as a dry-run update and the
+// result, which has the apiserver's defaults, is compared with the
+// actual object; that also catches fields that the writer removed.
+package equivalence
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kubestellar/kubestellar/pkg/normalize"
+	"github.com/kubestellar/kubestellar/pkg/podsecurity"
+)
+
+// Normalizer puts values of an object into a canonical form, in place.
+type Normalizer func(obj *unstructured.Unstructured)
+
+// DryRunFunc submits the given object as a dry-run update and returns
+// what the apiserver would store.
+type DryRunFunc func(desired *unstructured.Unstructured) (*unstructured.Unstructured, error)
+
+// Outcome is the result of a comparison.
+type Outcome string
+
+const (
+	// Identical means that the normalized objects are equal.
+	Identical Outcome = "identical"
+	// Equivalent means that the normalized objects differ only in what
+	// the apiserver adds to the desired object.
+	Equivalent Outcome = "equivalent"
+	// Different means that writing the desired object would change the actual one.
+	Different Outcome = "different"
+)
+
+// Comparer compares desired and actual objects. It is safe for concurrent use.
+type Comparer struct {
+	normalizers *normalize.Registry
+	mutex       sync.RWMutex
+	byKind      map[schema.GroupKind][]Normalizer
+}
+
+// NewComparer returns a Comparer that strips what the given Registry
+// describes and has the DefaultNormalizers.
+func NewComparer(normalizers *normalize.Registry) *Comparer {
+	cmp := &Comparer{normalizers: normalizers, byKind: map[schema.GroupKind][]Normalizer{}}
+	for gk, normalizer := range DefaultNormalizers() {
+		cmp.Register(gk, normalizer)
+	}
+	return cmp
+}
+
+// Register adds a Normalizer for objects of the given kind.
+func (cmp *Comparer) Register(gk schema.GroupKind, normalizer Normalizer) {
+	cmp.mutex.Lock()
+	defer cmp.mutex.Unlock()
+	cmp.byKind[gk] = append(cmp.byKind[gk], normalizer)
+}
+
+func (cmp *Comparer) normalized(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	cmp.normalizers.Normalize(obj)
+	cmp.mutex.RLock()
+	defer cmp.mutex.RUnlock()
+	for _, normalizer := range cmp.byKind[obj.GroupVersionKind().GroupKind()] {
+		normalizer(obj)
+	}
+	return obj
+}
+
+// Compare compares the desired object with the actual one. Without a
+// dryRun func, a desired object that is contained in the actual one is
+// considered Different, because it may lack fields that are meant to be
+// removed. An error is returned only if the dry run fails.
+func (cmp *Comparer) Compare(desired, actual *unstructured.Unstructured, dryRun DryRunFunc) (Outcome, error) {
+	normDesired, normActual := cmp.normalized(desired), cmp.normalized(actual)
+	if reflect.DeepEqual(normDesired.Object, normActual.Object) {
+		return Identical, nil
+	}
+	if dryRun == nil || !contains(normActual.Object, normDesired.Object) {
+		return Different, nil
+	}
+	result, err := dryRun(desired.DeepCopy())
+	if err != nil {
+		return Different, err
+	}
+	if reflect.DeepEqual(cmp.normalized(result).Object, normActual.Object) {
+		return Equivalent, nil
+	}
+	return Different, nil
+}
+
+// contains tells whether every field set in desired has the same value
+// in actual. List members are compared pairwise. An empty value in
+// desired matches an absent one in actual.
+func contains(actual, desired any) bool {
+	switch desiredT := desired.(type) {
+	case map[string]any:
+		actualT, ok := actual.(map[string]any)
+		if !ok {
+			return actual == nil && len(desiredT) == 0
+		}
+		for key, desiredVal := range desiredT {
+			if !contains(actualT[key], desiredVal) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		actualT, ok := actual.([]any)
+		if !ok {
+			return actual == nil && len(desiredT) == 0
+		}
+		if len(actualT) != len(desiredT) {
+			return false
+		}
+		for idx := range desiredT {
+			if !contains(actualT[idx], desiredT[idx]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return true
+	default:
+		return reflect.DeepEqual(actual, desired) || numbersEqual(actual, desired)
+	}
+}
+
+// numbersEqual tells whether both values are numbers with the same value,
+// as JSON decoding may produce int64 or float64 for the same number.
+func numbersEqual(a, b any) bool {
+	af, aok := asFloat(a)
+	bf, bok := asFloat(b)
+	return aok && bok && af == bf
+}
+
+func asFloat(val any) (float64, bool) {
+	switch valT := val.(type) {
+	case int64:
+		return float64(valT), true
+	case float64:
+		return valT, true
+	default:
+		return 0, false
+	}
+}
+
+// DefaultNormalizers returns the Normalizers that NewComparer registers.
+func DefaultNormalizers() map[schema.GroupKind]Normalizer {
+	ans := map[schema.GroupKind]Normalizer{}
+	for _, gk := range []schema.GroupKind{
+		{Kind: "Pod"}, {Kind: "ReplicationController"},
+		{Group: "apps", Kind: "Deployment"}, {Group: "apps", Kind: "ReplicaSet"},
+		{Group: "apps", Kind: "StatefulSet"}, {Group: "apps", Kind: "DaemonSet"},
+		{Group: "batch", Kind: "Job"}, {Group: "batch", Kind: "CronJob"},
+	} {
+		ans[gk] = canonicalizeContainerResources
+	}
+	return ans
+}
+
+// canonicalizeContainerResources writes the resource quantities of the
+// containers in a pod template in canonical form, so that for example
+// "0.5" and "500m" CPU compare equal.
+func canonicalizeContainerResources(obj *unstructured.Unstructured) {
+	gvk := obj.GroupVersionKind()
+	podSpecPath := podsecurity.PodSpecPath(gvk.Group, gvk.Kind)
+	if podSpecPath == nil {
+		return
+	}
+	podSpec, found, _ := unstructured.NestedFieldNoCopy(obj.Object, podSpecPath...)
+	podSpecMap, ok := podSpec.(map[string]any)
+	if !found || !ok {
+		return
+	}
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := podSpecMap[field].([]any)
+		for _, container := range containers {
+			containerMap, ok := container.(map[string]any)
+			if !ok {
+				continue
+			}
+			resources, _ := containerMap["resources"].(map[string]any)
+			for _, which := range []string{"limits", "requests"} {
+				quantities, _ := resources[which].(map[string]any)
+				for name, quantity := range quantities {
+					var str string
+					switch quantityT := quantity.(type) {
+					case string:
+						str = quantityT
+					case int64:
+						str = strconv.FormatInt(quantityT, 10)
+					case float64:
+						str = strconv.FormatFloat(quantityT, 'f', -1, 64)
+					default:
+						continue
+					}
+					if parsed, err := resource.ParseQuantity(str); err == nil {
+						quantities[name] = parsed.String()
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package equivalence
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/kubestellar/kubestellar/pkg/normalize"
+)
+
+func deployment(replicas int64, cpu any, extraSpec map[string]any) *unstructured.Unstructured {
+	spec := map[string]any{
+		"replicas": replicas,
+		"template": map[string]any{"spec": map[string]any{"containers": []any{
+			map[string]any{"name": "web", "image": "web:1", "resources": map[string]any{"requests": map[string]any{"cpu": cpu}}},
+		}}},
+	}
+	for key, val := range extraSpec {
+		spec[key] = val
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"namespace": "shop", "name": "web"},
+		"spec":       spec,
+	}}
+}
+
+// defaulted returns the given object as a server would store it.
+func defaulted(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	obj.SetResourceVersion("7")
+	obj.SetUID("1234")
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "revisionHistoryLimit"); !found {
+		_ = unstructured.SetNestedField(obj.Object, int64(10), "spec", "revisionHistoryLimit")
+	}
+	obj.Object["status"] = map[string]any{"replicas": int64(2)}
+	return obj
+}
+
+func TestCompare(t *testing.T) {
+	cmp := NewComparer(normalize.Default)
+	dryRuns := 0
+	dryRun := func(desired *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		dryRuns++
+		return defaulted(desired), nil
+	}
+	desired := deployment(2, "0.5", nil)
+	actual := defaulted(deployment(2, "500m", nil))
+
+	if outcome, err := cmp.Compare(desired, actual, dryRun); err != nil || outcome != Equivalent {
+		t.Errorf("Defaulted object: expected Equivalent but got %v, %v", outcome, err)
+	}
+	if outcome, _ := cmp.Compare(desired, actual, nil); outcome != Different {
+		t.Errorf("Without a dry run a contained object must be Different, got %v", outcome)
+	}
+	if outcome, _ := cmp.Compare(actual, actual, dryRun); outcome != Identical {
+		t.Errorf("Expected Identical but got %v", outcome)
+	}
+	dryRuns = 0
+	if outcome, _ := cmp.Compare(deployment(3, "0.5", nil), actual, dryRun); outcome != Different || dryRuns != 0 {
+		t.Errorf("Changed replicas: expected Different without a dry run but got %v after %d", outcome, dryRuns)
+	}
+	// The user removed a field that was set before
+	actualWithStrategy := defaulted(deployment(2, "500m", map[string]any{"minReadySeconds": int64(5)}))
+	if outcome, _ := cmp.Compare(desired, actualWithStrategy, dryRun); outcome != Different || dryRuns != 1 {
+		t.Errorf("Removed field: expected Different after a dry run but got %v after %d", outcome, dryRuns)
+	}
+	failing := func(*unstructured.Unstructured) (*unstructured.Unstructured, error) { return nil, errors.New("no") }
+	if outcome, err := cmp.Compare(desired, actual, failing); err == nil || outcome != Different {
+		t.Errorf("Failed dry run: expected Different and an error but got %v, %v", outcome, err)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package errors defines the classes of failure that KubeStellar
+// distinguishes, each identified by a machine-readable Reason.
+// The Reasons are suitable for use in the `reason` of a condition,
+// so that automation can branch on the class of a failure instead
+// of parsing message strings.
+//
+// Importers usually refer to this package as `kserrors`.
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// Reason identifies a class of failure.
+// The values are CamelCase so that they can be used as condition reasons.
+type Reason string
+
+const (
+	// ReasonUnknown is for failures that have not been classified.
+	ReasonUnknown Reason = "Unknown"
+
+	// ReasonRetriableTransport is for failures in communicating with an
+	// apiserver that are expected to go away on their own.
+	ReasonRetriableTransport Reason = "RetriableTransportError"
+
+	// ReasonConflict is for a write that lost a race with another write
+	// of the same object. Retrying from the current state of the object helps.
+	ReasonConflict Reason = "Conflict"
+
+	// ReasonDestinationAdmissionDenied is for when the apiserver of a
+	// destination refuses to admit an object.
+	ReasonDestinationAdmissionDenied Reason = "DestinationAdmissionDenied"
+
+	// ReasonTransformFailed is for when customization or another
+	// transformation of an object on its way to a destination fails.
+	ReasonTransformFailed Reason = "TransformFailed"
+
+	// ReasonSchemaIncompatible is for when the destination does not
+	// have the needed API resource, or has it in an incompatible form.
+	ReasonSchemaIncompatible Reason = "SchemaIncompatible"
+)
+
+// Error is an error that carries a Reason.
+type Error struct {
+	Reason Reason
+	Err    error
+}
+
+var _ error = &Error{}
+
+func (err *Error) Error() string {
+	if err.Err == nil {
+		return string(err.Reason)
+	}
+	return string(err.Reason) + ": " + err.Err.Error()
+}
+
+func (err *Error) Unwrap() error { return err.Err }
+
+// New returns an Error with the given Reason and a message formatted from the rest.
+func New(reason Reason, format string, args ...any) *Error {
+	return &Error{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap returns an Error with the given Reason and cause.
+// Returns nil if the cause is nil.
+func Wrap(reason Reason, cause error) error {
+	if cause == nil {
+		return nil
+	}
+	return &Error{Reason: reason, Err: cause}
+}
+
+// Classify returns an Error wrapping the given one, with a Reason
+// determined by ReasonOf.
+// Returns nil if given nil, and returns an existing Error unchanged.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		return err
+	}
+	return &Error{Reason: ReasonOf(err), Err: err}
+}
+
+// ReasonOf returns the Reason of the given error.
+// If the error is or wraps an Error then that Reason is returned;
+// otherwise errors from apiservers and the network are recognized.
+// Returns the empty string if given nil.
+func ReasonOf(err error) Reason {
+	if err == nil {
+		return ""
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Reason
+	}
+	var netErr net.Error
+	switch {
+	case meta.IsNoMatchError(err):
+		return ReasonSchemaIncompatible
+	case k8sapierrors.IsNotFound(err) && isResourceMissing(err):
+		return ReasonSchemaIncompatible
+	case k8sapierrors.IsForbidden(err), k8sapierrors.IsInvalid(err), k8sapierrors.IsBadRequest(err):
+		return ReasonDestinationAdmissionDenied
+	case k8sapierrors.IsConflict(err):
+		return ReasonConflict
+	case k8sapierrors.IsServerTimeout(err), k8sapierrors.IsTimeout(err), k8sapierrors.IsTooManyRequests(err),
+		k8sapierrors.IsServiceUnavailable(err), k8sapierrors.IsInternalError(err),
+		errors.As(err, &netErr):
+		return ReasonRetriableTransport
+	default:
+		return ReasonUnknown
+	}
+}
+
+// IsRetriable tells whether the given error is of a class that is
+// expected to go away on its own, or upon retrying.
+func IsRetriable(err error) bool {
+	reason := ReasonOf(err)
+	return reason == ReasonRetriableTransport || reason == ReasonConflict
+}
+
+// isResourceMissing tells whether a NotFound error is about the resource
+// (i.e., API type) rather than an object of that resource.
+func isResourceMissing(err error) bool {
+	status, ok := err.(k8sapierrors.APIStatus)
+	if !ok {
+		return false
+	}
+	details := status.Status().Details
+	return details == nil || details.Name == ""
+}
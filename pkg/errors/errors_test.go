@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestReasonOf(t *testing.T) {
+	gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	for _, testCase := range []struct {
+		err      error
+		expected Reason
+	}{
+		{nil, ""},
+		{errors.New("boom"), ReasonUnknown},
+		{New(ReasonTransformFailed, "bad path %q", "x"), ReasonTransformFailed},
+		{fmt.Errorf("wrapped: %w", Wrap(ReasonSchemaIncompatible, errors.New("x"))), ReasonSchemaIncompatible},
+		{k8sapierrors.NewForbidden(gr, "d1", errors.New("no")), ReasonDestinationAdmissionDenied},
+		{k8sapierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "d1", nil), ReasonDestinationAdmissionDenied},
+		{k8sapierrors.NewServiceUnavailable("later"), ReasonRetriableTransport},
+		{k8sapierrors.NewTooManyRequests("slow down", 1), ReasonRetriableTransport},
+		{k8sapierrors.NewConflict(gr, "d1", errors.New("changed")), ReasonConflict},
+		{k8sapierrors.NewNotFound(gr, "d1"), ReasonUnknown},
+	} {
+		if actual := ReasonOf(testCase.err); actual != testCase.expected {
+			t.Errorf("ReasonOf(%v) = %q, expected %q", testCase.err, actual, testCase.expected)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	if Classify(nil) != nil {
+		t.Errorf("Classify(nil) should be nil")
+	}
+	cause := k8sapierrors.NewServiceUnavailable("later")
+	classified := Classify(cause)
+	if !IsRetriable(classified) {
+		t.Errorf("Expected %v to be retriable", classified)
+	}
+	if !errors.Is(classified, cause) {
+		t.Errorf("Expected classified error to wrap its cause")
+	}
+	if conflict := Classify(k8sapierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "cm1", errors.New("changed"))); !IsRetriable(conflict) {
+		t.Errorf("Expected %v to be retriable", conflict)
+	}
+	if again := Classify(classified); again != classified {
+		t.Errorf("Expected Classify to leave an Error unchanged")
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events records Kubernetes Events about KubeStellar objects
+// with consistent reasons and messages. Identical failures at many
+// destinations are aggregated, so that a problem common to a large
+// fleet produces a few Events rather than one per destination.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+)
+
+// The reasons of the Events recorded here.
+const (
+	// ReasonPlacementScheduled is for an EdgePlacement whose set of destinations was decided.
+	ReasonPlacementScheduled = "PlacementScheduled"
+
+	// ReasonDestinationFailed is for a workload object that could not be written to a destination.
+	ReasonDestinationFailed = "DestinationFailed"
+
+	// ReasonTransformError is for a workload object that could not be customized for a destination.
+	ReasonTransformError = "TransformError"
+
+	// ReasonDestinationHeldBack is for a workload object that is deliberately
+	// not sent to a destination because the destination would refuse it.
+	ReasonDestinationHeldBack = "DestinationHeldBack"
+
+	// ReasonSingletonStateUnsupported is for a workload object whose
+	// EdgePlacement wants singleton reported state from a placement
+	// translator that can not return it.
+	ReasonSingletonStateUnsupported = "SingletonStateUnsupported"
+
+	// ReasonBreakGlassStarted is for a workload object whose copy at a
+	// destination was taken over by a break-glass override.
+	ReasonBreakGlassStarted = "BreakGlassStarted"
+
+	// ReasonBreakGlassEnded is for a workload object whose copy at a
+	// destination is managed again after a break-glass override.
+	ReasonBreakGlassEnded = "BreakGlassEnded"
+
+	// ReasonImmutableFieldSkipped is for a workload object whose copy at a
+	// destination was left as it is because updating it would change an
+	// immutable field.
+	ReasonImmutableFieldSkipped = "ImmutableFieldSkipped"
+
+	// ReasonRecreated is for a workload object whose copy at a destination
+	// was deleted, to be created anew, because updating it would change an
+	// immutable field.
+	ReasonRecreated = "Recreated"
+)
+
+// DefaultAggregationWindow is how long identical failures are gathered
+// into one Event.
+const DefaultAggregationWindow = time.Minute
+
+// maxSampleDestinations bounds how many destinations an aggregated Event names.
+const maxSampleDestinations = 3
+
+// RecorderFor returns the EventRecorder for objects in the given space.
+type RecorderFor func(space string) (record.EventRecorder, error)
+
+// Single returns a RecorderFor that uses the given EventRecorder for every space.
+func Single(recorder record.EventRecorder) RecorderFor {
+	return func(string) (record.EventRecorder, error) { return recorder, nil }
+}
+
+// Recorder records the KubeStellar Events.
+// A nil *Recorder records nothing.
+type Recorder struct {
+	logger      klog.Logger
+	recorderFor RecorderFor
+	window      time.Duration
+	now         func() time.Time
+
+	mutex      sync.Mutex
+	aggregates map[aggregateKey]*aggregate
+}
+
+// aggregateKey identifies a set of identical failures
+type aggregateKey struct {
+	space     string
+	uid       types.UID
+	namespace string
+	name      string
+	reason    string
+	message   string
+}
+
+// aggregate is a set of identical failures; the first has already been recorded
+type aggregate struct {
+	object  runtime.Object
+	since   time.Time
+	more    int
+	samples []string
+}
+
+// NewRecorder makes a Recorder that gathers identical failures
+// for the given window; see Run.
+func NewRecorder(logger klog.Logger, recorderFor RecorderFor, window time.Duration) *Recorder {
+	return &Recorder{
+		logger:      logger,
+		recorderFor: recorderFor,
+		window:      window,
+		now:         time.Now,
+		aggregates:  map[aggregateKey]*aggregate{},
+	}
+}
+
+// Run records the aggregated failures, once per window, until the context is done.
+func (rcdr *Recorder) Run(ctx context.Context) {
+	if rcdr == nil {
+		return
+	}
+	wait.UntilWithContext(ctx, func(context.Context) { rcdr.flush() }, rcdr.window)
+}
+
+// PlacementScheduled records that the destinations of the given
+// EdgePlacement, in the given space, were decided.
+func (rcdr *Recorder) PlacementScheduled(space string, placement runtime.Object, destinations int) {
+	if rcdr == nil {
+		return
+	}
+	rcdr.record(space, placement, corev1.EventTypeNormal, ReasonPlacementScheduled,
+		fmt.Sprintf("Scheduled to %d destination(s)", destinations))
+}
+
+// DestinationFailed records that the given workload object, in the
+// given space, could not be written to the given destination.
+// The message starts with the kserrors Reason of the failure.
+func (rcdr *Recorder) DestinationFailed(space string, obj runtime.Object, destination string, err error) {
+	rcdr.aggregated(space, obj, ReasonDestinationFailed, destination, fmt.Sprintf("Failed to write to destination: %v", kserrors.Classify(err)))
+}
+
+// TransformError records that the given workload object, in the given
+// space, could not be customized for the given destination.
+func (rcdr *Recorder) TransformError(space string, obj runtime.Object, destination string, err error) {
+	rcdr.aggregated(space, obj, ReasonTransformError, destination, fmt.Sprintf("Failed to customize for destination: %v", err))
+}
+
+// DestinationHeldBack records that the given workload object, in the
+// given space, is not sent to the given destination, for the given reason.
+func (rcdr *Recorder) DestinationHeldBack(space string, obj runtime.Object, destination string, why string) {
+	rcdr.aggregated(space, obj, ReasonDestinationHeldBack, destination, "Held back from destination: "+why)
+}
+
+// SingletonStateUnsupported records that the reported state of the given
+// workload object, in the given space, will not be returned from the
+// given destination, for the given reason.
+func (rcdr *Recorder) SingletonStateUnsupported(space string, obj runtime.Object, destination string, why string) {
+	rcdr.aggregated(space, obj, ReasonSingletonStateUnsupported, destination, "Singleton reported state is not returned: "+why)
+}
+
+// BreakGlassStarted records that the copy of the given workload
+// object, in the given space, at the given destination is under a
+// break-glass override until the given time. These are not aggregated,
+// as each is an audit record.
+func (rcdr *Recorder) BreakGlassStarted(space string, obj runtime.Object, destination string, until time.Time, reason string) {
+	if rcdr == nil {
+		return
+	}
+	rcdr.record(space, obj, corev1.EventTypeWarning, ReasonBreakGlassStarted,
+		fmt.Sprintf("Management of the copy at destination %s is suspended until %s: %s", destination, until.UTC().Format(time.RFC3339), reason))
+}
+
+// BreakGlassEnded records that the copy of the given workload object,
+// in the given space, at the given destination is managed again.
+func (rcdr *Recorder) BreakGlassEnded(space string, obj runtime.Object, destination string) {
+	if rcdr == nil {
+		return
+	}
+	rcdr.record(space, obj, corev1.EventTypeNormal, ReasonBreakGlassEnded,
+		fmt.Sprintf("Management of the copy at destination %s resumed", destination))
+}
+
+// ImmutableFieldSkipped records that the copy of the given workload
+// object, in the given space, at the given destination was not updated
+// because the update, rejected with the given error, changes an
+// immutable field.
+func (rcdr *Recorder) ImmutableFieldSkipped(space string, obj runtime.Object, destination string, err error) {
+	rcdr.aggregated(space, obj, ReasonImmutableFieldSkipped, destination, fmt.Sprintf("Left as it is at destination: %v", kserrors.Classify(err)))
+}
+
+// Recreated records that the copy of the given workload object, in the
+// given space, at the given destination was deleted to be created anew,
+// because its update was rejected with the given error. These are not
+// aggregated, as each is a disruption at the destination.
+func (rcdr *Recorder) Recreated(space string, obj runtime.Object, destination string, err error) {
+	if rcdr == nil {
+		return
+	}
+	rcdr.record(space, obj, corev1.EventTypeWarning, ReasonRecreated,
+		fmt.Sprintf("The copy at destination %s is being recreated: %v", destination, kserrors.Classify(err)))
+}
+
+func (rcdr *Recorder) aggregated(space string, obj runtime.Object, reason, destination, message string) {
+	if rcdr == nil {
+		return
+	}
+	key := aggregateKey{space: space, reason: reason, message: message}
+	if objM, err := meta.Accessor(obj); err == nil {
+		key.uid, key.namespace, key.name = objM.GetUID(), objM.GetNamespace(), objM.GetName()
+	}
+	rcdr.mutex.Lock()
+	agg, found := rcdr.aggregates[key]
+	if found {
+		agg.more++
+		if len(agg.samples) < maxSampleDestinations {
+			agg.samples = append(agg.samples, destination)
+		}
+		rcdr.mutex.Unlock()
+		return
+	}
+	rcdr.aggregates[key] = &aggregate{object: obj, since: rcdr.now()}
+	rcdr.mutex.Unlock()
+	rcdr.record(space, obj, corev1.EventTypeWarning, reason, fmt.Sprintf("%s (destination %s)", message, destination))
+}
+
+// flush records, and forgets, the aggregates whose window has passed
+func (rcdr *Recorder) flush() {
+	type summary struct {
+		key aggregateKey
+		agg *aggregate
+	}
+	var due []summary
+	now := rcdr.now()
+	rcdr.mutex.Lock()
+	for key, agg := range rcdr.aggregates {
+		if now.Sub(agg.since) >= rcdr.window {
+			delete(rcdr.aggregates, key)
+			if agg.more > 0 {
+				due = append(due, summary{key, agg})
+			}
+		}
+	}
+	rcdr.mutex.Unlock()
+	for _, sum := range due {
+		sort.Strings(sum.agg.samples)
+		sample := strings.Join(sum.agg.samples, ", ")
+		if sum.agg.more > len(sum.agg.samples) {
+			sample += ", ..."
+		}
+		rcdr.record(sum.key.space, sum.agg.object, corev1.EventTypeWarning, sum.key.reason,
+			fmt.Sprintf("%s (%d more destination(s): %s)", sum.key.message, sum.agg.more, sample))
+	}
+}
+
+func (rcdr *Recorder) record(space string, obj runtime.Object, eventType, reason, message string) {
+	recorder, err := rcdr.recorderFor(space)
+	if err != nil {
+		rcdr.logger.Error(err, "Failed to get EventRecorder", "space", space, "reason", reason)
+		return
+	}
+	recorder.Event(obj, eventType, reason, message)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func drain(recorder *record.FakeRecorder) []string {
+	var ans []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			ans = append(ans, event)
+		default:
+			return ans
+		}
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	fake := record.NewFakeRecorder(100)
+	rcdr := NewRecorder(klog.Background(), Single(fake), time.Minute)
+	now := time.Unix(1000, 0)
+	rcdr.now = func() time.Time { return now }
+	obj := &edgev2alpha1.Customizer{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "c", UID: "u1"}}
+	other := &edgev2alpha1.Customizer{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "d", UID: "u2"}}
+	failure := errors.New("forbidden")
+	for idx := 0; idx < 500; idx++ {
+		rcdr.DestinationFailed("wds1", obj, fmt.Sprintf("st%03d", idx), failure)
+	}
+	rcdr.DestinationFailed("wds1", other, "st000", failure)
+	rcdr.TransformError("wds1", obj, "st000", failure)
+	rcdr.PlacementScheduled("wds1", obj, 3)
+	expected := []string{
+		"Warning DestinationFailed Failed to write to destination: Unknown: forbidden (destination st000)",
+		"Warning DestinationFailed Failed to write to destination: Unknown: forbidden (destination st000)",
+		"Warning TransformError Failed to customize for destination: forbidden (destination st000)",
+		"Normal PlacementScheduled Scheduled to 3 destination(s)",
+	}
+	checkEvents(t, "before the window", drain(fake), expected)
+
+	now = now.Add(30 * time.Second)
+	rcdr.flush()
+	checkEvents(t, "within the window", drain(fake), nil)
+
+	now = now.Add(30 * time.Second)
+	rcdr.flush()
+	checkEvents(t, "after the window", drain(fake), []string{
+		"Warning DestinationFailed Failed to write to destination: Unknown: forbidden (499 more destination(s): st001, st002, st003, ...)",
+	})
+
+	rcdr.DestinationFailed("wds1", obj, "st007", failure)
+	checkEvents(t, "after the flush", drain(fake), []string{
+		"Warning DestinationFailed Failed to write to destination: Unknown: forbidden (destination st007)",
+	})
+}
+
+func TestNilRecorder(t *testing.T) {
+	var rcdr *Recorder
+	rcdr.PlacementScheduled("wds1", &edgev2alpha1.EdgePlacement{}, 1)
+	rcdr.DestinationFailed("wds1", &edgev2alpha1.EdgePlacement{}, "st1", errors.New("oops"))
+	rcdr.DestinationHeldBack("wds1", &edgev2alpha1.EdgePlacement{}, "st1", "why")
+}
+
+func TestSpaceRecordersExpire(t *testing.T) {
+	made := map[string]int{}
+	sr := newSpaceRecorders("test", func(space string) (kubernetes.Interface, error) {
+		made[space]++
+		return fake.NewSimpleClientset(), nil
+	}, time.Minute)
+	defer sr.Shutdown()
+	now := time.Unix(1000, 0)
+	sr.now = func() time.Time { return now }
+	for _, space := range []string{"s1", "s2", "s1"} {
+		if _, err := sr.For(space); err != nil {
+			t.Fatalf("For(%s) failed: %v", space, err)
+		}
+	}
+	if made["s1"] != 1 || made["s2"] != 1 || sr.Len() != 2 {
+		t.Errorf("expected one recorder per space, made %v, have %d", made, sr.Len())
+	}
+	now = now.Add(time.Minute)
+	if _, err := sr.For("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if made["s1"] != 2 {
+		t.Errorf("expected an expired recorder to be made again, made %v", made)
+	}
+	sr.evictExpired()
+	if sr.Len() != 1 {
+		t.Errorf("expected the recorder of s2 to be evicted, have %d", sr.Len())
+	}
+}
+
+func checkEvents(t *testing.T, when string, actual, expected []string) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("%s: expected %d events, got %#v", when, len(expected), actual)
+	}
+	for idx := range expected {
+		if actual[idx] != expected[idx] {
+			t.Errorf("%s: expected event %q, got %q", when, expected[idx], actual[idx])
+		}
+	}
+}
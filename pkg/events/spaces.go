@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	edgescheme "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/scheme"
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+)
+
+// scheme knows the Kubernetes and KubeStellar types, for making object references
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(edgescheme.AddToScheme(scheme))
+}
+
+// SpaceRecorders makes an EventRecorder for each space that records
+// into that space, and keeps it for a TTL. After that the recorder's
+// broadcaster is shut down and a later use makes a new one, with fresh
+// clients; so spaces that are no longer visited are let go of, and
+// credentials that change are picked up.
+type SpaceRecorders struct {
+	component  string
+	clientsFor func(space string) (kubernetes.Interface, error)
+	ttl        time.Duration
+	now        func() time.Time
+
+	mutex     sync.Mutex
+	recorders map[string]*spaceRecorder
+}
+
+type spaceRecorder struct {
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+	expires     time.Time
+}
+
+// NewSpaceRecorders returns a SpaceRecorders whose Events come from the
+// given component and that reaches each space through the given Factory,
+// with the given provider namespace. A ttl of zero means the Factory's
+// default TTL. Call Run to shut down expired recorders that are not used
+// again.
+func NewSpaceRecorders(component string, factory *spaceclient.Factory, providerNS string, ttl time.Duration) *SpaceRecorders {
+	return newSpaceRecorders(component, func(space string) (kubernetes.Interface, error) {
+		clients, err := factory.For(space, providerNS)
+		if err != nil {
+			return nil, err
+		}
+		return clients.Kube, nil
+	}, ttl)
+}
+
+func newSpaceRecorders(component string, clientsFor func(space string) (kubernetes.Interface, error), ttl time.Duration) *SpaceRecorders {
+	if ttl <= 0 {
+		ttl = spaceclient.DefaultTTL
+	}
+	return &SpaceRecorders{
+		component:  component,
+		clientsFor: clientsFor,
+		ttl:        ttl,
+		now:        time.Now,
+		recorders:  map[string]*spaceRecorder{},
+	}
+}
+
+// For returns the EventRecorder for the given space; it is a RecorderFor.
+func (sr *SpaceRecorders) For(space string) (record.EventRecorder, error) {
+	now := sr.now()
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	if spr, found := sr.recorders[space]; found {
+		if now.Before(spr.expires) {
+			return spr.recorder, nil
+		}
+		spr.broadcaster.Shutdown()
+		delete(sr.recorders, space)
+	}
+	client, err := sr.clientsFor(space)
+	if err != nil {
+		return nil, err
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: sr.component})
+	sr.recorders[space] = &spaceRecorder{broadcaster: broadcaster, recorder: recorder, expires: now.Add(sr.ttl)}
+	return recorder, nil
+}
+
+// Run shuts down the expired recorders, every TTL, until the context is
+// done, and then all of them.
+func (sr *SpaceRecorders) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(context.Context) { sr.evictExpired() }, sr.ttl)
+	sr.Shutdown()
+}
+
+func (sr *SpaceRecorders) evictExpired() {
+	now := sr.now()
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	for space, spr := range sr.recorders {
+		if !now.Before(spr.expires) {
+			spr.broadcaster.Shutdown()
+			delete(sr.recorders, space)
+		}
+	}
+}
+
+// Len returns the number of spaces that have a recorder.
+func (sr *SpaceRecorders) Len() int {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	return len(sr.recorders)
+}
+
+// Shutdown stops recording into every space.
+func (sr *SpaceRecorders) Shutdown() {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	for space, spr := range sr.recorders {
+		spr.broadcaster.Shutdown()
+		delete(sr.recorders, space)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package externalscheduler is the contract between KubeStellar and
+// schedulers other than the where-resolver, like the scheduler names
+// and extension points of kube-scheduler.
+//
+// An EdgePlacement is left to an external scheduler by giving it the
+// annotation edge.kubestellar.io/scheduler-name with the scheduler's
+// name (see edgeapi.SchedulerNameAnnotationKey). The where-resolver then
+// leaves the EdgePlacement alone, and the external scheduler is
+// responsible for two things in the workload description space (WDS):
+//
+//   - the SinglePlacementSlice named after the EdgePlacement, holding
+//     the chosen destinations, which the what-resolver and placement
+//     translator consume exactly as they consume the where-resolver's;
+//   - the EdgePlacement's status.processedSpecHash and its
+//     LocationsResolved condition, which tell users and tools that the
+//     current spec has been acted on.
+//
+// Scheduler.Decide does both, the way KubeStellar expects, so a
+// scheduler written in Go only has to choose destinations. A scheduler
+// in another language has to write the same objects: the slice with an
+// owner reference to the EdgePlacement and the annotation
+// edge.kubestellar.io/scheduled-by naming the scheduler.
+package externalscheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+	"github.com/kubestellar/kubestellar/pkg/destination"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+	"github.com/kubestellar/kubestellar/pkg/spechash"
+)
+
+// ReasonScheduledExternally is the reason of the LocationsResolved
+// condition that Decide sets.
+const ReasonScheduledExternally = "ScheduledExternally"
+
+// Decision is what an external scheduler decided for one EdgePlacement.
+type Decision struct {
+	Destinations []edgeapi.SinglePlacement
+
+	// Message explains the decision to users, in the LocationsResolved
+	// condition. Empty means a message that counts the destinations.
+	Message string
+}
+
+// Scheduler records the decisions of one external scheduler in one WDS.
+type Scheduler struct {
+	name    string
+	spaceID string
+	client  edgeclientset.Interface
+}
+
+// New makes a Scheduler with the given name, which EdgePlacements refer
+// to, that writes through the given client to the WDS with the given
+// space ID.
+func New(name, spaceID string, client edgeclientset.Interface) (*Scheduler, error) {
+	if name == "" || name == edgeapi.DefaultSchedulerName {
+		return nil, fmt.Errorf("an external scheduler needs a name other than %q", edgeapi.DefaultSchedulerName)
+	}
+	return &Scheduler{name: name, spaceID: spaceID, client: client}, nil
+}
+
+// Name returns the name of the scheduler.
+func (sched *Scheduler) Name() string {
+	return sched.name
+}
+
+// Responsible tells whether the given EdgePlacement is left to this scheduler.
+func (sched *Scheduler) Responsible(ep *edgeapi.EdgePlacement) bool {
+	return ep.SchedulerName() == sched.name
+}
+
+// Validate returns an error if the given destinations can not be
+// consumed: one lacks a space ID, SyncTarget name or Location name, or
+// two go to the same SyncTarget.
+func Validate(destinations []edgeapi.SinglePlacement) error {
+	seen := map[destination.Destination]struct{}{}
+	for _, sp := range destinations {
+		dest := destination.Of(sp)
+		if err := dest.Validate(); err != nil {
+			return err
+		}
+		if sp.LocationName == "" {
+			return fmt.Errorf("destination %s has no Location name", dest)
+		}
+		if _, have := seen[dest]; have {
+			return fmt.Errorf("destination %s is listed more than once", dest)
+		}
+		seen[dest] = struct{}{}
+	}
+	return nil
+}
+
+// Decide records the given decision for the given EdgePlacement, which
+// has to be left to this scheduler: it writes the SinglePlacementSlice
+// and then the status of the EdgePlacement.
+func (sched *Scheduler) Decide(ctx context.Context, ep *edgeapi.EdgePlacement, decision Decision) error {
+	if !sched.Responsible(ep) {
+		return fmt.Errorf("EdgePlacement %q is left to scheduler %q, not %q", ep.Name, ep.SchedulerName(), sched.name)
+	}
+	if err := Validate(decision.Destinations); err != nil {
+		return err
+	}
+	destinations := sorted(decision.Destinations)
+	owner := ownership.NewOwnerRef(sched.spaceID, edgeapi.SchemeGroupVersion.WithResource("edgeplacements"), ep)
+	spsName := naming.SinglePlacementSliceName(ep.Name)
+	slices := sched.client.EdgeV2alpha1().SinglePlacementSlices()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		sps, err := slices.Get(ctx, spsName, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			sps = &edgeapi.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{
+				Name: spsName,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: edgeapi.SchemeGroupVersion.String(),
+					Kind:       "EdgePlacement",
+					Name:       ep.Name,
+					UID:        ep.UID,
+				}},
+				Annotations: map[string]string{edgeapi.ScheduledByAnnotationKey: sched.name},
+			}, Destinations: destinations}
+			if err := ownership.SetOwner(sps, owner); err != nil {
+				return err
+			}
+			_, err = slices.Create(ctx, sps, metav1.CreateOptions{})
+			if k8serrors.IsAlreadyExists(err) {
+				// Treat like a conflict, to read the slice and try again.
+				return k8serrors.NewConflict(edgeapi.Resource("singleplacementslices"), spsName, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		current, _ := ownership.GetOwner(sps)
+		if sps.Annotations[edgeapi.ScheduledByAnnotationKey] == sched.name && current != nil && *current == owner &&
+			equalDestinations(sps.Destinations, destinations) {
+			return nil
+		}
+		sps = sps.DeepCopy()
+		if sps.Annotations == nil {
+			sps.Annotations = map[string]string{}
+		}
+		sps.Annotations[edgeapi.ScheduledByAnnotationKey] = sched.name
+		sps.Destinations = destinations
+		if err := ownership.SetOwner(sps, owner); err != nil {
+			return err
+		}
+		_, err = slices.Update(ctx, sps, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write SinglePlacementSlice %q: %w", spsName, err)
+	}
+	message := decision.Message
+	if message == "" {
+		message = fmt.Sprintf("%d destination(s) chosen by scheduler %s", len(destinations), sched.name)
+	}
+	return sched.updateStatus(ctx, ep, message)
+}
+
+func (sched *Scheduler) updateStatus(ctx context.Context, ep *edgeapi.EdgePlacement, message string) error {
+	ep = ep.DeepCopy()
+	hash := spechash.Of(ep)
+	changed := ep.Status.ProcessedSpecHash != hash
+	ep.Status.ProcessedSpecHash = hash
+	changed = conditions.Set(&ep.Status.Conditions, metav1.Condition{
+		Type:               edgeapi.EdgePlacementLocationsResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonScheduledExternally,
+		Message:            message,
+		ObservedGeneration: ep.Generation,
+	}, metav1.Now()) || changed
+	if !changed {
+		return nil
+	}
+	if _, err := sched.client.EdgeV2alpha1().EdgePlacements().UpdateStatus(ctx, ep, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update status of EdgePlacement %q: %w", ep.Name, err)
+	}
+	return nil
+}
+
+// sorted returns a copy of the given destinations in the order that the
+// where-resolver writes them.
+func sorted(destinations []edgeapi.SinglePlacement) []edgeapi.SinglePlacement {
+	ans := make([]edgeapi.SinglePlacement, len(destinations))
+	copy(ans, destinations)
+	sort.Slice(ans, func(i, j int) bool { return destination.Of(ans[i]).Less(destination.Of(ans[j])) })
+	return ans
+}
+
+func equalDestinations(left, right []edgeapi.SinglePlacement) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for idx := range left {
+		if left[idx] != right[idx] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externalscheduler
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgefake "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/fake"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+)
+
+func TestDecide(t *testing.T) {
+	ctx := context.Background()
+	ep := &edgeapi.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: "web", UID: "uid1", Generation: 3,
+		Annotations: map[string]string{edgeapi.SchedulerNameAnnotationKey: "gpu-packer"}}}
+	other := &edgeapi.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: "db"}}
+	client := edgefake.NewSimpleClientset(ep, other)
+
+	if _, err := New(edgeapi.DefaultSchedulerName, "wds1", client); err == nil {
+		t.Error("Expected the where-resolver's name to be refused")
+	}
+	sched, err := New("gpu-packer", "wds1", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sched.Responsible(ep) || sched.Responsible(other) || other.ExternallyScheduled() {
+		t.Error("Wrong responsibility")
+	}
+	if err := sched.Decide(ctx, other, Decision{}); err == nil {
+		t.Error("Expected a decision for another scheduler's EdgePlacement to fail")
+	}
+
+	edge2 := edgeapi.SinglePlacement{Cluster: "inv", LocationName: "default", SyncTargetName: "edge2"}
+	edge1 := edgeapi.SinglePlacement{Cluster: "inv", LocationName: "default", SyncTargetName: "edge1"}
+	if err := sched.Decide(ctx, ep, Decision{Destinations: []edgeapi.SinglePlacement{edge1, edge1}}); err == nil {
+		t.Error("Expected duplicate destinations to be refused")
+	}
+	if err := sched.Decide(ctx, ep, Decision{Destinations: []edgeapi.SinglePlacement{edge2, edge1}}); err != nil {
+		t.Fatal(err)
+	}
+	sps, err := client.EdgeV2alpha1().SinglePlacementSlices().Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sps.Destinations) != 2 || sps.Destinations[0] != edge1 || sps.Annotations[edgeapi.ScheduledByAnnotationKey] != "gpu-packer" {
+		t.Errorf("Unexpected SinglePlacementSlice %+v", sps)
+	}
+	if len(sps.OwnerReferences) != 1 || sps.OwnerReferences[0].UID != "uid1" {
+		t.Errorf("Expected an owner reference to the EdgePlacement, got %+v", sps.OwnerReferences)
+	}
+	if owner, err := ownership.GetOwner(sps); err != nil || owner == nil || owner.Space != "wds1" || owner.Name != "web" {
+		t.Errorf("Expected the EdgePlacement as virtual owner, got %+v, %v", owner, err)
+	}
+	got, err := client.EdgeV2alpha1().EdgePlacements().Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond := conditions.Find(got.Status.Conditions, edgeapi.EdgePlacementLocationsResolved)
+	if got.Status.ProcessedSpecHash == "" || cond == nil || cond.Reason != ReasonScheduledExternally || cond.ObservedGeneration != 3 {
+		t.Errorf("Unexpected status %+v", got.Status)
+	}
+
+	if err := sched.Decide(ctx, got, Decision{Destinations: []edgeapi.SinglePlacement{edge2}, Message: "packed"}); err != nil {
+		t.Fatal(err)
+	}
+	sps, err = client.EdgeV2alpha1().SinglePlacementSlices().Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sps.Destinations) != 1 || sps.Destinations[0] != edge2 {
+		t.Errorf("Expected the new decision, got %+v", sps.Destinations)
+	}
+}
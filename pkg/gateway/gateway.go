@@ -0,0 +1,446 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gateway serves a read-only HTTP/JSON view of the state of a fleet:
+// the placements, the placement decisions (SinglePlacementSlices), the
+// destinations and the recent failures, as summarized by the top package.
+// It lets web UIs and other integrations see that state without holding
+// Kubernetes credentials.
+//
+// All the list endpoints support pagination, through the `limit` and
+// `continue` query parameters, and field selection, through the `fields`
+// query parameter (a comma-separated list of top-level JSON field names).
+//
+// The API can be guarded by a bearer token; see RequireBearerToken.
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/top"
+	"github.com/kubestellar/kubestellar/pkg/destination"
+)
+
+// APIPrefix is the path prefix of all the endpoints.
+const APIPrefix = "/api/v1/"
+
+const (
+	// DefaultLimit is the page size when the request does not specify one.
+	DefaultLimit = 100
+
+	// MaxLimit is the largest page size allowed.
+	MaxLimit = 1000
+)
+
+// Decision is the placement decision for one EdgePlacement.
+type Decision struct {
+	Name         string                         `json:"name"`
+	Placement    string                         `json:"placement"`
+	Destinations []edgev2alpha1.SinglePlacement `json:"destinations"`
+}
+
+// Summary is the fleet-wide counts.
+type Summary struct {
+	Time              string `json:"time"`
+	Placements        int    `json:"placements"`
+	PlacementsReady   int    `json:"placementsReady"`
+	Destinations      int    `json:"destinations"`
+	DestinationsReady int    `json:"destinationsReady"`
+	DestinationsStale int    `json:"destinationsStale"`
+	Failures          int    `json:"failures"`
+}
+
+// List is the body of a response from a list endpoint.
+type List struct {
+	Kind  string `json:"kind"`
+	Items []any  `json:"items"`
+
+	// Continue, if not empty, is the value of the `continue` parameter
+	// that fetches the next page.
+	Continue string `json:"continue,omitempty"`
+}
+
+// Server serves the gateway API from the given listers.
+type Server struct {
+	logger           klog.Logger
+	placements       edgelisters.EdgePlacementLister
+	slices           edgelisters.SinglePlacementSliceLister
+	syncTargets      edgelisters.SyncTargetLister
+	heartbeatTimeout time.Duration
+	now              func() time.Time
+
+	// debouncer holds back flapping changes; see SetHealthMinDuration.
+	debouncer *top.FleetDebouncer
+
+	// changed is signalled, without blocking, when a watched object changes; see Run.
+	changed chan struct{}
+
+	// summaryListeners are called by Run with each summary; see AddSummaryListener.
+	summaryListeners []func(*top.FleetSummary)
+}
+
+// NewServer makes a Server. The heartbeatTimeout is passed to top.Summarize.
+func NewServer(logger klog.Logger, placements edgelisters.EdgePlacementLister, slices edgelisters.SinglePlacementSliceLister,
+	syncTargets edgelisters.SyncTargetLister, heartbeatTimeout time.Duration) *Server {
+	return &Server{
+		logger:           logger,
+		placements:       placements,
+		slices:           slices,
+		syncTargets:      syncTargets,
+		heartbeatTimeout: heartbeatTimeout,
+		now:              time.Now,
+		debouncer:        top.NewFleetDebouncer(0),
+		changed:          make(chan struct{}, 1),
+	}
+}
+
+// SetHealthMinDuration sets how long a placement or destination has to
+// stay in a new health state, or a placement condition in a new status,
+// before the Server reports the change. Until then the previous state is
+// reported, so that a few flapping WECs do not make the aggregated health
+// oscillate. The failures of a placement or destination are held back
+// along with its health. Zero, the default, reports every change
+// immediately. May be called at any time; see also Run.
+func (srv *Server) SetHealthMinDuration(minDuration time.Duration) {
+	srv.debouncer.SetMinDuration(minDuration)
+}
+
+// EventHandler returns the handler to add to the informers behind the
+// listers, so that Run sees every change.
+func (srv *Server) EventHandler() cache.ResourceEventHandler {
+	notify := func() {
+		select {
+		case srv.changed <- struct{}{}:
+		default:
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { notify() },
+		UpdateFunc: func(oldObj, newObj any) { notify() },
+		DeleteFunc: func(obj any) { notify() },
+	}
+}
+
+// AddSummaryListener adds a function that Run calls with each summary
+// that it makes, after debouncing. Must be called before Run.
+func (srv *Server) AddSummaryListener(listener func(*top.FleetSummary)) {
+	srv.summaryListeners = append(srv.summaryListeners, listener)
+}
+
+// Run summarizes the fleet whenever EventHandler reports a change, and
+// every period, until the context ends. This is what feeds the debouncing
+// of SetHealthMinDuration: without it, a change is noticed only when a
+// request comes in, so a state that flaps between requests would be
+// missed and one that was seen twice, far apart, would count as held.
+// The period bounds how late heartbeat timeouts are noticed.
+func (srv *Server) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-srv.changed:
+		case <-ticker.C:
+		}
+		fleet, err := srv.summarize()
+		if err != nil {
+			srv.logger.Error(err, "Failed to summarize fleet")
+			continue
+		}
+		for _, listener := range srv.summaryListeners {
+			listener(fleet)
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler for the paths under APIPrefix.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		srv.writeError(w, http.StatusMethodNotAllowed, "this API is read-only")
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, APIPrefix), "/")
+	parts := strings.Split(path, "/")
+	// Only a destination can be named by two segments: its Key.
+	if len(parts) > 3 || (len(parts) == 3 && parts[0] != "destinations") || parts[0] == "" {
+		srv.writeError(w, http.StatusNotFound, "no such path")
+		return
+	}
+	var name string
+	if len(parts) > 1 {
+		name = strings.Join(parts[1:], "/")
+	}
+	fields := parseFields(req.URL.Query().Get("fields"))
+	if parts[0] == "decisions" {
+		srv.serveDecisions(w, req, name, fields)
+		return
+	}
+	fleet, err := srv.summarize()
+	if err != nil {
+		srv.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	switch {
+	case parts[0] == "summary" && name == "":
+		srv.writeJSON(w, http.StatusOK, summaryOf(fleet))
+	case parts[0] == "placements" && name != "":
+		if ps := fleet.FindPlacement(name); ps != nil {
+			srv.writeJSON(w, http.StatusOK, selectFields(ps, fields))
+		} else {
+			srv.writeError(w, http.StatusNotFound, fmt.Sprintf("no EdgePlacement named %q", name))
+		}
+	case parts[0] == "placements":
+		keys := make([]string, len(fleet.Placements))
+		for idx, ps := range fleet.Placements {
+			keys[idx] = ps.Name
+		}
+		serveList(srv, w, req, "PlacementList", fleet.Placements, keys, fields)
+	case parts[0] == "destinations" && len(parts) == 3:
+		dest, err := destination.Parse(name)
+		if err != nil {
+			srv.writeError(w, http.StatusBadRequest, err.Error())
+		} else if ds := fleet.FindDestinationOf(dest); ds != nil {
+			srv.writeJSON(w, http.StatusOK, selectFields(ds, fields))
+		} else {
+			srv.writeError(w, http.StatusNotFound, fmt.Sprintf("no destination %s", dest))
+		}
+	case parts[0] == "destinations" && name != "":
+		if ds := fleet.FindDestination(name); ds != nil {
+			srv.writeJSON(w, http.StatusOK, selectFields(ds, fields))
+		} else {
+			srv.writeError(w, http.StatusNotFound, fmt.Sprintf("no destination with SyncTarget named %q", name))
+		}
+	case parts[0] == "destinations":
+		keys := make([]string, len(fleet.Destinations))
+		for idx, ds := range fleet.Destinations {
+			keys[idx] = ds.Key()
+		}
+		serveList(srv, w, req, "DestinationList", fleet.Destinations, keys, fields)
+	case parts[0] == "failures" && name == "":
+		// Failures are ordered by time, most recent first; they are paged by position.
+		keys := make([]string, len(fleet.Failures))
+		for idx := range fleet.Failures {
+			keys[idx] = fmt.Sprintf("%010d", idx)
+		}
+		serveList(srv, w, req, "FailureList", fleet.Failures, keys, fields)
+	default:
+		srv.writeError(w, http.StatusNotFound, "no such path")
+	}
+}
+
+func (srv *Server) serveDecisions(w http.ResponseWriter, req *http.Request, name string, fields []string) {
+	if name != "" {
+		sps, err := srv.slices.Get(name)
+		if k8serrors.IsNotFound(err) {
+			srv.writeError(w, http.StatusNotFound, fmt.Sprintf("no decision named %q", name))
+			return
+		} else if err != nil {
+			srv.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		srv.writeJSON(w, http.StatusOK, selectFields(decisionOf(sps), fields))
+		return
+	}
+	slices, err := srv.slices.List(labels.Everything())
+	if err != nil {
+		srv.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sort.Slice(slices, func(i, j int) bool { return slices[i].Name < slices[j].Name })
+	decisions := make([]Decision, len(slices))
+	keys := make([]string, len(slices))
+	for idx, sps := range slices {
+		decisions[idx] = decisionOf(sps)
+		keys[idx] = sps.Name
+	}
+	serveList(srv, w, req, "DecisionList", decisions, keys, fields)
+}
+
+// serveList writes the requested page of the given items.
+// The keys identify the items and are in increasing order;
+// the continue token is the encoded key of the last item of the previous page.
+func serveList[Item any](srv *Server, w http.ResponseWriter, req *http.Request, kind string, items []Item, keys []string, fields []string) {
+	query := req.URL.Query()
+	limit := DefaultLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > MaxLimit {
+			srv.writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be an integer from 1 to %d", MaxLimit))
+			return
+		}
+	}
+	start := 0
+	if continueStr := query.Get("continue"); continueStr != "" {
+		after, err := base64.RawURLEncoding.DecodeString(continueStr)
+		if err != nil {
+			srv.writeError(w, http.StatusBadRequest, "malformed continue token")
+			return
+		}
+		start = sort.Search(len(keys), func(idx int) bool { return keys[idx] > string(after) })
+	}
+	end := start + limit
+	list := List{Kind: kind}
+	if end < len(items) {
+		list.Continue = base64.RawURLEncoding.EncodeToString([]byte(keys[end-1]))
+	} else {
+		end = len(items)
+	}
+	list.Items = make([]any, 0, end-start)
+	for idx := start; idx < end; idx++ {
+		list.Items = append(list.Items, selectFields(&items[idx], fields))
+	}
+	srv.writeJSON(w, http.StatusOK, list)
+}
+
+func (srv *Server) summarize() (*top.FleetSummary, error) {
+	placements, err := srv.placements.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	slices, err := srv.slices.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	syncTargets, err := srv.syncTargets.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	fleet := top.Summarize(derefAll(placements), derefAll(slices), derefAll(syncTargets), srv.now(), srv.heartbeatTimeout)
+	srv.debouncer.Apply(fleet)
+	return fleet, nil
+}
+
+func summaryOf(fleet *top.FleetSummary) Summary {
+	ans := Summary{Time: fleet.Time.UTC().Format(time.RFC3339), Placements: len(fleet.Placements),
+		Destinations: len(fleet.Destinations), Failures: len(fleet.Failures)}
+	for _, ps := range fleet.Placements {
+		if ps.Health == top.HealthReady {
+			ans.PlacementsReady++
+		}
+	}
+	for _, ds := range fleet.Destinations {
+		switch ds.Health {
+		case top.HealthReady:
+			ans.DestinationsReady++
+		case top.HealthStale:
+			ans.DestinationsStale++
+		}
+	}
+	return ans
+}
+
+func decisionOf(sps *edgev2alpha1.SinglePlacementSlice) Decision {
+	ans := Decision{Name: sps.Name, Placement: sps.Name, Destinations: sps.Destinations}
+	for _, owner := range sps.OwnerReferences {
+		if owner.Kind == "EdgePlacement" && owner.APIVersion == edgev2alpha1.SchemeGroupVersion.String() {
+			ans.Placement = owner.Name
+		}
+	}
+	if ans.Destinations == nil {
+		ans.Destinations = []edgev2alpha1.SinglePlacement{}
+	}
+	return ans
+}
+
+func parseFields(fieldsStr string) []string {
+	var fields []string
+	for _, field := range strings.Split(fieldsStr, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// selectFields returns the given object, or a map holding just the given
+// top-level fields of its JSON encoding if any fields are given.
+func selectFields(obj any, fields []string) any {
+	if len(fields) == 0 {
+		return obj
+	}
+	objBytes, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	var full map[string]json.RawMessage
+	if err := json.Unmarshal(objBytes, &full); err != nil {
+		return obj
+	}
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if val, have := full[field]; have {
+			selected[field] = val
+		}
+	}
+	return selected
+}
+
+func derefAll[Obj any](ptrs []*Obj) []Obj {
+	ans := make([]Obj, len(ptrs))
+	for idx, ptr := range ptrs {
+		ans[idx] = *ptr
+	}
+	return ans
+}
+
+// RequireBearerToken returns a handler that passes to the given one only
+// the requests that carry the given token in an `Authorization: Bearer`
+// header, and answers the others with 401 Unauthorized.
+func RequireBearerToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authz := req.Header.Get("Authorization")
+		given := strings.TrimPrefix(authz, "Bearer ")
+		if given == authz || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kubestellar-fleet-gateway"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "a valid bearer token is required"})
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (srv *Server) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		srv.logger.V(3).Info("Failed to write response", "err", err)
+	}
+}
+
+func (srv *Server) writeError(w http.ResponseWriter, status int, message string) {
+	srv.writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+)
+
+func newTestServer(t *testing.T, numPlacements int) *Server {
+	placements := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	slices := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	syncTargets := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	heartbeat := metav1.NewTime(now)
+	if err := syncTargets.Add(&edgev2alpha1.SyncTarget{ObjectMeta: metav1.ObjectMeta{Name: "st1", UID: "uid1"},
+		Status: edgev2alpha1.SyncTargetStatus{LastSyncerHeartbeatTime: &heartbeat}}); err != nil {
+		t.Fatal(err)
+	}
+	for idx := 0; idx < numPlacements; idx++ {
+		name := fmt.Sprintf("ep%02d", idx)
+		if err := placements.Add(&edgev2alpha1.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := slices.Add(&edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: name},
+			Destinations: []edgev2alpha1.SinglePlacement{{LocationName: "loc1", SyncTargetName: "st1", SyncTargetUID: "uid1"}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := NewServer(klog.Background(), edgelisters.NewEdgePlacementLister(placements),
+		edgelisters.NewSinglePlacementSliceLister(slices), edgelisters.NewSyncTargetLister(syncTargets), time.Minute)
+	srv.now = func() time.Time { return now }
+	return srv
+}
+
+func get(t *testing.T, srv *Server, url string, expectedStatus int, body any) {
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+	if recorder.Code != expectedStatus {
+		t.Fatalf("GET %s: expected status %d, got %d with body %s", url, expectedStatus, recorder.Code, recorder.Body.String())
+	}
+	if body != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), body); err != nil {
+			t.Fatalf("GET %s: failed to parse body: %v", url, err)
+		}
+	}
+}
+
+func TestPagination(t *testing.T) {
+	srv := newTestServer(t, 5)
+	for _, kind := range []string{"placements", "decisions"} {
+		var names []string
+		cont := ""
+		for pages := 0; pages < 10; pages++ {
+			var list struct {
+				Items    []map[string]any `json:"items"`
+				Continue string           `json:"continue"`
+			}
+			get(t, srv, APIPrefix+kind+"?limit=2&fields=name&continue="+cont, http.StatusOK, &list)
+			for _, item := range list.Items {
+				if len(item) != 1 {
+					t.Errorf("%s: expected only the name field, got %v", kind, item)
+				}
+				names = append(names, item["name"].(string))
+			}
+			if cont = list.Continue; cont == "" {
+				break
+			}
+		}
+		if fmt.Sprint(names) != "[ep00 ep01 ep02 ep03 ep04]" {
+			t.Errorf("%s: unexpected names %v", kind, names)
+		}
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	srv := newTestServer(t, 2)
+	var summary Summary
+	get(t, srv, APIPrefix+"summary", http.StatusOK, &summary)
+	if summary.Placements != 2 || summary.PlacementsReady != 2 || summary.Destinations != 1 || summary.DestinationsReady != 1 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	var dest map[string]any
+	get(t, srv, APIPrefix+"destinations/st1", http.StatusOK, &dest)
+	if dest["health"] != "Ready" || len(dest["placements"].([]any)) != 2 {
+		t.Errorf("unexpected destination %v", dest)
+	}
+	get(t, srv, APIPrefix+"placements/nosuch", http.StatusNotFound, nil)
+	get(t, srv, APIPrefix+"placements?limit=0", http.StatusBadRequest, nil)
+	get(t, srv, APIPrefix+"bogus", http.StatusNotFound, nil)
+
+	recorder := httptest.NewRecorder()
+	srv.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, APIPrefix+"placements/ep00", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected DELETE to be refused, got %d", recorder.Code)
+	}
+}
+
+func TestHealthDebounce(t *testing.T) {
+	srv := newTestServer(t, 1)
+	srv.SetHealthMinDuration(5 * time.Minute)
+	clock := srv.now()
+	srv.now = func() time.Time { return clock }
+	expectHealth := func(expected string) {
+		t.Helper()
+		var dest map[string]any
+		get(t, srv, APIPrefix+"destinations/st1", http.StatusOK, &dest)
+		if dest["health"] != expected {
+			t.Errorf("at %s expected health %s, got %v", clock, expected, dest["health"])
+		}
+	}
+	expectFailures := func(expected int) {
+		t.Helper()
+		var summary Summary
+		get(t, srv, APIPrefix+"summary", http.StatusOK, &summary)
+		if summary.Failures != expected {
+			t.Errorf("at %s expected %d failures, got %d", clock, expected, summary.Failures)
+		}
+	}
+	expectHealth("Ready")
+	clock = clock.Add(2 * time.Minute) // heartbeat now overdue
+	expectHealth("Ready")
+	expectFailures(0)
+	clock = clock.Add(4 * time.Minute)
+	expectHealth("Ready")
+	clock = clock.Add(time.Minute)
+	expectHealth("Stale")
+	expectFailures(1)
+	var summary Summary
+	get(t, srv, APIPrefix+"summary", http.StatusOK, &summary)
+	if summary.DestinationsStale != 1 || summary.DestinationsReady != 0 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	var dest map[string]any
+	get(t, srv, APIPrefix+"destinations/st1?fields=lastKnownHealth,staleFor", http.StatusOK, &dest)
+	if dest["lastKnownHealth"] != "Ready" || dest["staleFor"] == nil {
+		t.Errorf("unexpected stale destination %v", dest)
+	}
+}
+
+func TestDestinationPagination(t *testing.T) {
+	srv := newTestServer(t, 1)
+	// Two SyncTargets with the same name in different inventory spaces.
+	slices := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := slices.Add(&edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: "ep00"},
+		Destinations: []edgev2alpha1.SinglePlacement{
+			{Cluster: "inv1", LocationName: "loc1", SyncTargetName: "st1", SyncTargetUID: "uid1"},
+			{Cluster: "inv1", LocationName: "loc2", SyncTargetName: "st2", SyncTargetUID: "uid2"},
+			{Cluster: "inv2", LocationName: "loc2", SyncTargetName: "st2", SyncTargetUID: "uid3"},
+		}}); err != nil {
+		t.Fatal(err)
+	}
+	srv.slices = edgelisters.NewSinglePlacementSliceLister(slices)
+	var keys []string
+	cont := ""
+	for pages := 0; pages < 10; pages++ {
+		var list struct {
+			Items    []map[string]any `json:"items"`
+			Continue string           `json:"continue"`
+		}
+		get(t, srv, APIPrefix+"destinations?limit=1&fields=clusterID,syncTargetName&continue="+cont, http.StatusOK, &list)
+		for _, item := range list.Items {
+			keys = append(keys, fmt.Sprintf("%v/%v", item["clusterID"], item["syncTargetName"]))
+		}
+		if cont = list.Continue; cont == "" {
+			break
+		}
+	}
+	if fmt.Sprint(keys) != "[inv1/st1 inv1/st2 inv2/st2]" {
+		t.Errorf("unexpected destinations %v", keys)
+	}
+	var dest map[string]any
+	get(t, srv, APIPrefix+"destinations/inv2/st2", http.StatusOK, &dest)
+	if dest["clusterID"] != "inv2" || dest["syncTargetName"] != "st2" {
+		t.Errorf("unexpected destination %v", dest)
+	}
+	get(t, srv, APIPrefix+"destinations/inv3/st2", http.StatusNotFound, nil)
+	get(t, srv, APIPrefix+"placements/ep00/st2", http.StatusNotFound, nil)
+}
+
+func TestRequireBearerToken(t *testing.T) {
+	srv := newTestServer(t, 1)
+	handler := RequireBearerToken("s3cret", srv)
+	for _, tc := range []struct {
+		authorization string
+		expected      int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusUnauthorized},
+		{"Bearer s3cret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, APIPrefix+"summary", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		if recorder.Code != tc.expected {
+			t.Errorf("with Authorization %q expected status %d, got %d", tc.authorization, tc.expected, recorder.Code)
+		}
+	}
+}
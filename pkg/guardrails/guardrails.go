@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package guardrails generates the NetworkPolicy objects requested by
+// the `networkGuardrails` of an EdgePlacement and maintains them in
+// the workload management space, from which they are downsynced.
+package guardrails
+
+import (
+	"context"
+	"sort"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+// PolicyNamePrefix starts the name of every generated NetworkPolicy.
+const PolicyNamePrefix = naming.GuardrailPolicyPrefix
+
+// PolicyName returns the name of the NetworkPolicy generated for the given EdgePlacement.
+func PolicyName(epName string) string {
+	return naming.GuardrailPolicyName(epName)
+}
+
+// Namespaces returns the namespaces that get a generated NetworkPolicy
+// for the given spec, in sorted order; nil if the spec asks for no guardrails.
+func Namespaces(spec *edgeapi.EdgePlacementSpec) []string {
+	if spec.NetworkGuardrails == nil {
+		return nil
+	}
+	namespaces := map[string]struct{}{}
+	if len(spec.NetworkGuardrails.Namespaces) > 0 {
+		for _, ns := range spec.NetworkGuardrails.Namespaces {
+			namespaces[ns] = struct{}{}
+		}
+	} else {
+		for _, objTest := range spec.Downsync {
+			for _, ns := range objTest.Namespaces {
+				if ns != "*" {
+					namespaces[ns] = struct{}{}
+				}
+			}
+		}
+	}
+	ans := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		ans = append(ans, ns)
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+// Generate returns the NetworkPolicy that the given guardrails call for
+// in the given namespace.
+func Generate(epName string, guardrails *edgeapi.NetworkGuardrails, namespace string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+			Kind:       "NetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      PolicyName(epName),
+			Labels:    map[string]string{edgeapi.GuardrailForLabelKey: naming.LabelValue(epName)},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress:     guardrails.AllowIngress,
+			Egress:      guardrails.AllowEgress,
+		},
+	}
+}
+
+// IsGuardrailFor tells whether the given object is a NetworkPolicy
+// generated for the named EdgePlacement.
+func IsGuardrailFor(group, resource string, objLabels map[string]string, epName string) bool {
+	return group == networkingv1.GroupName && resource == "networkpolicies" &&
+		objLabels[edgeapi.GuardrailForLabelKey] == naming.LabelValue(epName)
+}
+
+// Reconcile makes the NetworkPolicies generated for the named EdgePlacement
+// match the given spec, which is nil if the EdgePlacement does not exist.
+// Returns whether to retry.
+func Reconcile(ctx context.Context, logger klog.Logger, client kubernetes.Interface, epName string, spec *edgeapi.EdgePlacementSpec) bool {
+	desired := map[string]*networkingv1.NetworkPolicy{}
+	if spec != nil {
+		for _, ns := range Namespaces(spec) {
+			desired[ns] = Generate(epName, spec.NetworkGuardrails, ns)
+		}
+	}
+	selector := labels.SelectorFromSet(labels.Set{edgeapi.GuardrailForLabelKey: naming.LabelValue(epName)})
+	existing, err := client.NetworkingV1().NetworkPolicies(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logger.Error(err, "Failed to list generated NetworkPolicies", "edgePlacement", epName)
+		return true
+	}
+	retry := false
+	for idx := range existing.Items {
+		have := &existing.Items[idx]
+		want, wanted := desired[have.Namespace]
+		switch {
+		case !wanted || have.Name != want.Name:
+			err := client.NetworkingV1().NetworkPolicies(have.Namespace).Delete(ctx, have.Name, metav1.DeleteOptions{})
+			if err != nil && !k8sapierrors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete stale NetworkPolicy", "namespace", have.Namespace, "name", have.Name)
+				retry = true
+			} else {
+				logger.V(2).Info("Deleted stale NetworkPolicy", "namespace", have.Namespace, "name", have.Name)
+			}
+		case !apiequality.Semantic.DeepEqual(have.Spec, want.Spec):
+			have = have.DeepCopy()
+			have.Spec = want.Spec
+			_, err := client.NetworkingV1().NetworkPolicies(have.Namespace).Update(ctx, have, metav1.UpdateOptions{FieldManager: "kubestellar"})
+			if err != nil {
+				logger.Error(err, "Failed to update NetworkPolicy", "namespace", have.Namespace, "name", have.Name)
+				retry = true
+			} else {
+				logger.V(2).Info("Updated NetworkPolicy", "namespace", have.Namespace, "name", have.Name)
+			}
+			delete(desired, have.Namespace)
+		default:
+			delete(desired, have.Namespace)
+		}
+	}
+	for ns, want := range desired {
+		_, err := client.NetworkingV1().NetworkPolicies(ns).Create(ctx, want, metav1.CreateOptions{FieldManager: "kubestellar"})
+		if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+			logger.Error(err, "Failed to create NetworkPolicy", "namespace", ns, "name", want.Name)
+			retry = true
+		} else {
+			logger.V(2).Info("Created NetworkPolicy", "namespace", ns, "name", want.Name)
+		}
+	}
+	return retry
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package guardrails
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestNamespaces(t *testing.T) {
+	spec := &edgeapi.EdgePlacementSpec{
+		Downsync: []edgeapi.DownsyncObjectTest{
+			{Namespaces: []string{"b", "a"}},
+			{Namespaces: []string{"*", "b"}},
+		},
+	}
+	if actual := Namespaces(spec); actual != nil {
+		t.Errorf("Expected no namespaces without guardrails, got %v", actual)
+	}
+	spec.NetworkGuardrails = &edgeapi.NetworkGuardrails{}
+	if actual, expected := Namespaces(spec), []string{"a", "b"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	spec.NetworkGuardrails.Namespaces = []string{"c"}
+	if actual, expected := Namespaces(spec), []string{"c"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	logger := klog.Background()
+	client := fake.NewSimpleClientset()
+	spec := &edgeapi.EdgePlacementSpec{
+		Downsync: []edgeapi.DownsyncObjectTest{{Namespaces: []string{"a", "b"}}},
+		NetworkGuardrails: &edgeapi.NetworkGuardrails{
+			AllowEgress: []networkingv1.NetworkPolicyEgressRule{{}},
+		},
+	}
+	list := func() []networkingv1.NetworkPolicy {
+		nps, err := client.NetworkingV1().NetworkPolicies(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Failed to list: %v", err)
+		}
+		return nps.Items
+	}
+	if Reconcile(ctx, logger, client, "ep1", spec) {
+		t.Fatalf("Unexpected retry")
+	}
+	nps := list()
+	if len(nps) != 2 {
+		t.Fatalf("Expected 2 NetworkPolicies, got %v", nps)
+	}
+	for _, np := range nps {
+		if np.Name != PolicyName("ep1") || np.Labels[edgeapi.GuardrailForLabelKey] != "ep1" || len(np.Spec.Egress) != 1 || len(np.Spec.Ingress) != 0 {
+			t.Errorf("Unexpected NetworkPolicy %#v", np)
+		}
+	}
+
+	spec.NetworkGuardrails.Namespaces = []string{"b"}
+	spec.NetworkGuardrails.AllowEgress = nil
+	if Reconcile(ctx, logger, client, "ep1", spec) {
+		t.Fatalf("Unexpected retry")
+	}
+	nps = list()
+	if len(nps) != 1 || nps[0].Namespace != "b" || len(nps[0].Spec.Egress) != 0 {
+		t.Errorf("Expected only a default-deny policy in namespace b, got %v", nps)
+	}
+
+	if Reconcile(ctx, logger, client, "ep1", nil) {
+		t.Fatalf("Unexpected retry")
+	}
+	if nps = list(); len(nps) != 0 {
+		t.Errorf("Expected no NetworkPolicies after the EdgePlacement is gone, got %v", nps)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hubmanifests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Outcome is what applying did to one object.
+type Outcome string
+
+const (
+	OutcomeCreated    Outcome = "created"
+	OutcomeConfigured Outcome = "configured"
+	OutcomeUnchanged  Outcome = "unchanged"
+	OutcomeFailed     Outcome = "failed"
+)
+
+// WriteYAML writes the given objects as a multi-document YAML stream.
+func WriteYAML(dest io.Writer, objs []*unstructured.Unstructured) error {
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		if _, err := fmt.Fprintf(dest, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Applier applies objects with server-side apply, in order, and writes
+// a line to Progress for each.
+type Applier struct {
+	Client   dynamic.Interface
+	Mapper   meta.RESTMapper
+	Progress io.Writer
+}
+
+// Apply applies the given objects in order, stopping at the first failure.
+func (ap *Applier) Apply(ctx context.Context, objs []*unstructured.Unstructured) error {
+	for index, obj := range objs {
+		outcome, err := ap.applyOne(ctx, obj)
+		subject := obj.GetKind() + " " + obj.GetName()
+		if ns := obj.GetNamespace(); ns != "" {
+			subject = obj.GetKind() + " " + ns + "/" + obj.GetName()
+		}
+		fmt.Fprintf(ap.Progress, "[%d/%d] %s %s\n", index+1, len(objs), subject, outcome)
+		if err != nil {
+			return fmt.Errorf("failed to apply %s: %w", subject, err)
+		}
+	}
+	return nil
+}
+
+func (ap *Applier) applyOne(ctx context.Context, obj *unstructured.Unstructured) (Outcome, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := ap.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		// The kind may be defined by a CRD applied just before.
+		meta.MaybeResetRESTMapper(ap.Mapper)
+		mapping, err = ap.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return OutcomeFailed, err
+		}
+	}
+	var client dynamic.ResourceInterface = ap.Client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		client = ap.Client.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	}
+	oldRV := ""
+	existing, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err == nil {
+		oldRV = existing.GetResourceVersion()
+	} else if !apierrors.IsNotFound(err) {
+		return OutcomeFailed, err
+	}
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return OutcomeFailed, err
+	}
+	force := true
+	applied, err := client.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: FieldManager, Force: &force})
+	if err != nil {
+		return OutcomeFailed, err
+	}
+	switch {
+	case oldRV == "":
+		return OutcomeCreated, nil
+	case applied.GetResourceVersion() != oldRV:
+		return OutcomeConfigured, nil
+	default:
+		return OutcomeUnchanged, nil
+	}
+}
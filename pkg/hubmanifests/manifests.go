@@ -0,0 +1,450 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hubmanifests renders the manifests that deploy KubeStellar
+// into a hosting cluster: the CRDs, the central controllers, the
+// placement access webhook, and the RBAC that they need. The manifests
+// are parameterized by a Topology and an Environment. They can be
+// written out as YAML or applied directly.
+package hubmanifests
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/bootstrap"
+	"github.com/kubestellar/kubestellar/pkg/customizercheck"
+	"github.com/kubestellar/kubestellar/pkg/placementauthz"
+)
+
+// FieldManager is the field manager of the writes made when applying.
+const FieldManager = "kubestellar-init"
+
+// Topology says how the controllers are laid out.
+type Topology string
+
+const (
+	// TopologySingleHub runs one replica of each controller and of the webhook.
+	TopologySingleHub Topology = "single-hub"
+
+	// TopologyRegional runs one placement translator shard per region,
+	// pinned to the nodes of that region, and one webhook replica per
+	// region, spread across them.
+	TopologyRegional Topology = "regional"
+)
+
+// Topologies lists the supported values of Topology.
+var Topologies = []string{string(TopologySingleHub), string(TopologyRegional)}
+
+// Environment is the kind of cluster that hosts KubeStellar.
+type Environment string
+
+const (
+	// EnvironmentKubernetes is a plain Kubernetes cluster. The webhook's
+	// serving certificate must be provided in the Secret named
+	// WebhookTLSSecretName, and the CA that signed it in Params.WebhookCABundle.
+	EnvironmentKubernetes Environment = "kubernetes"
+
+	// EnvironmentOpenShift runs the containers with the restricted
+	// security context and gets the webhook's serving certificate, and
+	// its CA bundle, from the OpenShift service CA.
+	EnvironmentOpenShift Environment = "openshift"
+
+	// EnvironmentKind is like EnvironmentKubernetes except that nothing
+	// is pinned to a region, because kind nodes have no region labels.
+	EnvironmentKind Environment = "kind"
+)
+
+// Environments lists the supported values of Environment.
+var Environments = []string{string(EnvironmentKubernetes), string(EnvironmentOpenShift), string(EnvironmentKind)}
+
+// RegionLabel is the node label that says which region a node is in.
+const RegionLabel = "topology.kubernetes.io/region"
+
+// DefaultImage is the default image of the controllers and the webhook.
+const DefaultImage = "quay.io/kubestellar/kubestellar:latest"
+
+// WebhookName is the name of the Deployment, Service and identity of
+// the placement access webhook.
+const WebhookName = "placement-access-webhook"
+
+// WebhookTLSSecretName is the name of the Secret that holds the
+// webhook's serving certificate and key, as `tls.crt` and `tls.key`.
+const WebhookTLSSecretName = WebhookName + "-tls"
+
+// WebhookConfigurationName is the name of the ValidatingWebhookConfiguration
+// of the placement access webhook.
+const WebhookConfigurationName = "kubestellar-placement-access"
+
+const tlsMountPath = "/etc/kubestellar/tls"
+
+// Params are the parameters of the rendered manifests.
+type Params struct {
+	Topology    Topology
+	Environment Environment
+
+	// Namespace is where the controllers, the webhook and their
+	// ServiceAccounts go. It is created too.
+	Namespace string
+
+	Image           string
+	ImagePullPolicy corev1.PullPolicy
+
+	// CoreSpace and SpaceProvider are passed to the controllers.
+	CoreSpace     string
+	SpaceProvider string
+
+	// Verbosity is the log verbosity of the controllers and the webhook.
+	Verbosity int
+
+	// Regions are the regions of the regional topology, as values of RegionLabel.
+	Regions []string
+
+	// WebhookCABundle is the PEM encoding of the CA certificate that
+	// signed the webhook's serving certificate. Not used on OpenShift.
+	WebhookCABundle []byte
+}
+
+// DefaultParams returns the parameters for a single hub on plain Kubernetes.
+func DefaultParams() Params {
+	return Params{
+		Topology:        TopologySingleHub,
+		Environment:     EnvironmentKubernetes,
+		Namespace:       "kubestellar",
+		Image:           DefaultImage,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		CoreSpace:       "espw",
+		SpaceProvider:   "default",
+		Verbosity:       2,
+	}
+}
+
+// Validate checks the parameters.
+func (p Params) Validate() error {
+	var errs []error
+	switch p.Topology {
+	case TopologySingleHub:
+		if len(p.Regions) != 0 {
+			errs = append(errs, errors.New("regions are given only for the regional topology"))
+		}
+	case TopologyRegional:
+		if len(p.Regions) == 0 {
+			errs = append(errs, errors.New("the regional topology needs at least one region"))
+		}
+		seen := sets.NewString()
+		for _, region := range p.Regions {
+			if seen.Has(region) {
+				errs = append(errs, fmt.Errorf("region %q is given more than once", region))
+			}
+			seen.Insert(region)
+			if msgs := validation.IsDNS1123Label(region); len(msgs) != 0 {
+				errs = append(errs, fmt.Errorf("region %q is not usable in object names: %s", region, strings.Join(msgs, "; ")))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unsupported topology %q, must be one of: %s", p.Topology, strings.Join(Topologies, "|")))
+	}
+	switch p.Environment {
+	case EnvironmentKubernetes, EnvironmentOpenShift, EnvironmentKind:
+	default:
+		errs = append(errs, fmt.Errorf("unsupported environment %q, must be one of: %s", p.Environment, strings.Join(Environments, "|")))
+	}
+	if msgs := validation.IsDNS1123Label(p.Namespace); len(msgs) != 0 {
+		errs = append(errs, fmt.Errorf("invalid namespace %q: %s", p.Namespace, strings.Join(msgs, "; ")))
+	}
+	if p.Image == "" {
+		errs = append(errs, errors.New("the image must not be empty"))
+	}
+	if p.Verbosity < 0 {
+		errs = append(errs, errors.New("the verbosity must not be negative"))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// controller describes one of the central controllers.
+type controller struct {
+	name   string
+	binary string
+	port   int32
+}
+
+var (
+	whereResolver       = controller{name: "where-resolver", binary: "kubestellar-where-resolver", port: 10205}
+	mailboxController   = controller{name: "mailbox-controller", binary: "mailbox-controller", port: 10203}
+	placementTranslator = controller{name: "placement-translator", binary: "placement-translator", port: 10204}
+	webhook             = controller{name: WebhookName, binary: "kubestellar-placement-access-webhook", port: 10211}
+)
+
+// webhookIdentity is the identity of the placement access webhook.
+func webhookIdentity(namespace string) bootstrap.Identity {
+	return bootstrap.Identity{
+		Name: WebhookName, Namespace: namespace,
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{"authorization.k8s.io"}, Resources: []string{"subjectaccessreviews"}, Verbs: []string{"create"}},
+			{APIGroups: []string{edgeapi.SchemeGroupVersion.Group}, Resources: []string{"edgeplacements", "namespacededgeplacements"}, Verbs: []string{"get", "list", "watch"}},
+		},
+	}
+}
+
+// Render returns the manifests for the given parameters, in the order
+// in which to apply them, including the given CRDs.
+func Render(p Params, crds []*apiext.CustomResourceDefinition) ([]*unstructured.Unstructured, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	objs := []runtime.Object{&corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: p.Namespace, Labels: commonLabels("")},
+	}}
+	for _, crd := range crds {
+		crd = crd.DeepCopy()
+		crd.TypeMeta = metav1.TypeMeta{APIVersion: apiext.SchemeGroupVersion.String(), Kind: "CustomResourceDefinition"}
+		objs = append(objs, crd)
+	}
+	identities := append(bootstrap.DefaultIdentities(p.Namespace), webhookIdentity(p.Namespace))
+	for _, id := range identities {
+		objs = append(objs, &corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: id.Namespace, Name: id.Name, Labels: commonLabels(id.Name)},
+		})
+	}
+	for _, id := range identities {
+		objs = append(objs, &rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: id.ClusterRoleName(), Labels: commonLabels(id.Name)},
+			Rules:      id.Rules,
+		})
+	}
+	for _, id := range identities {
+		objs = append(objs, &rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: id.ClusterRoleName(), Labels: commonLabels(id.Name)},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: id.ClusterRoleName()},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: id.Namespace, Name: id.Name}},
+		})
+	}
+	objs = append(objs, webhookService(p))
+	objs = append(objs, controllerDeployment(p, whereResolver, whereResolver.name, nil), controllerDeployment(p, mailboxController, mailboxController.name, nil))
+	if p.Topology == TopologyRegional {
+		for index, region := range p.Regions {
+			objs = append(objs, controllerDeployment(p, placementTranslator, placementTranslator.name+"-"+region, &shard{index: index, count: len(p.Regions), region: region}))
+		}
+	} else {
+		objs = append(objs, controllerDeployment(p, placementTranslator, placementTranslator.name, nil))
+	}
+	objs = append(objs, webhookDeployment(p), webhookConfiguration(p))
+
+	ans := make([]*unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %T: %w", obj, err)
+		}
+		// Leave out what the server fills in.
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(content, "spec", "template", "metadata", "creationTimestamp")
+		delete(content, "status")
+		ans = append(ans, &unstructured.Unstructured{Object: content})
+	}
+	return ans, nil
+}
+
+// shard identifies one placement translator shard of the regional topology.
+type shard struct {
+	index  int
+	count  int
+	region string
+}
+
+func commonLabels(name string) map[string]string {
+	labels := map[string]string{
+		"app.kubernetes.io/part-of":    "kubestellar",
+		"app.kubernetes.io/managed-by": FieldManager,
+	}
+	if name != "" {
+		labels["app.kubernetes.io/name"] = name
+	}
+	return labels
+}
+
+func controllerDeployment(p Params, ctl controller, name string, shard *shard) *appsv1.Deployment {
+	args := []string{
+		"-v=" + strconv.Itoa(p.Verbosity),
+		"--server-bind-address=:" + strconv.Itoa(int(ctl.port)),
+		"--core-space=" + p.CoreSpace,
+		"--space-provider=" + p.SpaceProvider,
+	}
+	if shard != nil {
+		args = append(args, "--shard-count="+strconv.Itoa(shard.count), "--shard-index="+strconv.Itoa(shard.index))
+	}
+	deployment := deployment(p, ctl, name, args, corev1.URISchemeHTTP)
+	if shard != nil && p.Environment != EnvironmentKind {
+		deployment.Spec.Template.Spec.NodeSelector = map[string]string{RegionLabel: shard.region}
+	}
+	return deployment
+}
+
+func webhookDeployment(p Params) *appsv1.Deployment {
+	args := []string{
+		"-v=" + strconv.Itoa(p.Verbosity),
+		"--server-bind-address=:" + strconv.Itoa(int(webhook.port)),
+		"--tls-cert-file=" + tlsMountPath + "/tls.crt",
+		"--tls-private-key-file=" + tlsMountPath + "/tls.key",
+	}
+	deployment := deployment(p, webhook, webhook.name, args, corev1.URISchemeHTTPS)
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.Volumes = []corev1.Volume{{
+		Name:         "tls",
+		VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: WebhookTLSSecretName}},
+	}}
+	podSpec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "tls", MountPath: tlsMountPath, ReadOnly: true}}
+	if p.Topology == TopologyRegional {
+		replicas := int32(len(p.Regions))
+		deployment.Spec.Replicas = &replicas
+		podSpec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       RegionLabel,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     deployment.Spec.Selector,
+		}}
+	}
+	return deployment
+}
+
+func deployment(p Params, ctl controller, name string, args []string, probeScheme corev1.URIScheme) *appsv1.Deployment {
+	replicas := int32(1)
+	selector := map[string]string{"app.kubernetes.io/name": name}
+	container := corev1.Container{
+		Name:            ctl.name,
+		Image:           p.Image,
+		ImagePullPolicy: p.ImagePullPolicy,
+		Command:         []string{ctl.binary},
+		Args:            args,
+		Ports:           []corev1.ContainerPort{{Name: "http", ContainerPort: ctl.port, Protocol: corev1.ProtocolTCP}},
+		LivenessProbe:   httpProbe("/healthz", ctl.port, probeScheme),
+		ReadinessProbe:  httpProbe("/readyz", ctl.port, probeScheme),
+	}
+	if p.Environment == EnvironmentOpenShift {
+		yes, no := true, false
+		container.SecurityContext = &corev1.SecurityContext{
+			RunAsNonRoot:             &yes,
+			AllowPrivilegeEscalation: &no,
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}
+	}
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: p.Namespace, Name: name, Labels: commonLabels(name)},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: commonLabels(name)},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: ctl.name,
+					Containers:         []corev1.Container{container},
+				},
+			},
+		},
+	}
+}
+
+func httpProbe(path string, port int32, scheme corev1.URIScheme) *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler:  corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: path, Port: intstr.FromInt(int(port)), Scheme: scheme}},
+		PeriodSeconds: 10,
+	}
+}
+
+func webhookService(p Params) *corev1.Service {
+	service := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: p.Namespace, Name: WebhookName, Labels: commonLabels(WebhookName)},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app.kubernetes.io/name": WebhookName},
+			Ports:    []corev1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromInt(int(webhook.port)), Protocol: corev1.ProtocolTCP}},
+		},
+	}
+	if p.Environment == EnvironmentOpenShift {
+		service.Annotations = map[string]string{"service.beta.openshift.io/serving-cert-secret-name": WebhookTLSSecretName}
+	}
+	return service
+}
+
+func webhookConfiguration(p Params) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	fail, ignore := admissionregistrationv1.Fail, admissionregistrationv1.Ignore
+	port := int32(443)
+	clientConfig := func(path string) admissionregistrationv1.WebhookClientConfig {
+		cc := admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{Namespace: p.Namespace, Name: WebhookName, Path: &path, Port: &port},
+		}
+		if p.Environment != EnvironmentOpenShift {
+			cc.CABundle = p.WebhookCABundle
+		}
+		return cc
+	}
+	rule := func(resources ...string) []admissionregistrationv1.RuleWithOperations {
+		return []admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{edgeapi.SchemeGroupVersion.Group},
+				APIVersions: []string{edgeapi.SchemeGroupVersion.Version},
+				Resources:   resources,
+			},
+		}}
+	}
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta:   metav1.TypeMeta{APIVersion: admissionregistrationv1.SchemeGroupVersion.String(), Kind: "ValidatingWebhookConfiguration"},
+		ObjectMeta: metav1.ObjectMeta{Name: WebhookConfigurationName, Labels: commonLabels(WebhookName)},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name:                    "placement-access.edge.kubestellar.io",
+			AdmissionReviewVersions: []string{"v1"},
+			SideEffects:             &sideEffects,
+			FailurePolicy:           &fail,
+			ClientConfig:            clientConfig(placementauthz.Path),
+			Rules:                   rule("edgeplacements", "namespacededgeplacements"),
+		}, {
+			Name:                    "customizer-paths.edge.kubestellar.io",
+			AdmissionReviewVersions: []string{"v1"},
+			SideEffects:             &sideEffects,
+			FailurePolicy:           &ignore,
+			ClientConfig:            clientConfig(customizercheck.Path),
+			Rules:                   rule("customizers"),
+		}},
+	}
+	if p.Environment == EnvironmentOpenShift {
+		config.Annotations = map[string]string{"service.beta.openshift.io/inject-cabundle": "true"}
+	}
+	return config
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hubmanifests
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func findObject(objs []*unstructured.Unstructured, kind, name string) *unstructured.Unstructured {
+	for _, obj := range objs {
+		if obj.GetKind() == kind && obj.GetName() == name {
+			return obj
+		}
+	}
+	return nil
+}
+
+func TestRenderSingleHub(t *testing.T) {
+	crds := []*apiext.CustomResourceDefinition{{ObjectMeta: metav1.ObjectMeta{Name: "edgeplacements.edge.kubestellar.io"}}}
+	objs, err := Render(DefaultParams(), crds)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if first := objs[0]; first.GetKind() != "Namespace" || first.GetName() != "kubestellar" {
+		t.Errorf("first object is %s %s, want the Namespace", first.GetKind(), first.GetName())
+	}
+	if findObject(objs, "CustomResourceDefinition", crds[0].Name) == nil {
+		t.Errorf("CRD missing")
+	}
+	for _, name := range []string{"where-resolver", "mailbox-controller", "placement-translator", WebhookName} {
+		if findObject(objs, "Deployment", name) == nil {
+			t.Errorf("Deployment %s missing", name)
+		}
+		if findObject(objs, "ServiceAccount", name) == nil {
+			t.Errorf("ServiceAccount %s missing", name)
+		}
+		if findObject(objs, "ClusterRoleBinding", "kubestellar:"+name) == nil {
+			t.Errorf("ClusterRoleBinding kubestellar:%s missing", name)
+		}
+	}
+	translator := findObject(objs, "Deployment", "placement-translator")
+	containers, _, _ := unstructured.NestedSlice(translator.Object, "spec", "template", "spec", "containers")
+	container := containers[0].(map[string]any)
+	for _, arg := range container["args"].([]any) {
+		if strings.HasPrefix(arg.(string), "--shard-") {
+			t.Errorf("single hub translator has shard arg %v", arg)
+		}
+	}
+	if _, found := container["securityContext"]; found {
+		t.Errorf("plain Kubernetes container has a securityContext")
+	}
+	hooks, _, _ := unstructured.NestedSlice(findObject(objs, "ValidatingWebhookConfiguration", WebhookConfigurationName).Object, "webhooks")
+	if len(hooks) != 2 {
+		t.Errorf("got %d webhooks, want 2", len(hooks))
+	}
+	var buf bytes.Buffer
+	if err := WriteYAML(&buf, objs); err != nil {
+		t.Fatalf("WriteYAML: %v", err)
+	}
+	if got := strings.Count(buf.String(), "---\n"); got != len(objs) {
+		t.Errorf("YAML has %d documents, want %d", got, len(objs))
+	}
+	if strings.Contains(buf.String(), "creationTimestamp") {
+		t.Errorf("YAML has creationTimestamp")
+	}
+}
+
+func TestRenderRegionalOpenShift(t *testing.T) {
+	params := DefaultParams()
+	params.Topology = TopologyRegional
+	params.Environment = EnvironmentOpenShift
+	params.Regions = []string{"us-east", "eu-west"}
+	objs, err := Render(params, nil)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if findObject(objs, "Deployment", "placement-translator") != nil {
+		t.Errorf("regional topology has an unsharded placement translator")
+	}
+	for index, region := range params.Regions {
+		shard := findObject(objs, "Deployment", "placement-translator-"+region)
+		if shard == nil {
+			t.Fatalf("no placement translator for region %s", region)
+		}
+		nodeSelector, _, _ := unstructured.NestedStringMap(shard.Object, "spec", "template", "spec", "nodeSelector")
+		if nodeSelector[RegionLabel] != region {
+			t.Errorf("shard for %s has nodeSelector %v", region, nodeSelector)
+		}
+		containers, _, _ := unstructured.NestedSlice(shard.Object, "spec", "template", "spec", "containers")
+		container := containers[0].(map[string]any)
+		args := container["args"].([]any)
+		if !containsArg(args, "--shard-count=2") || !containsArg(args, "--shard-index="+strconv.Itoa(index)) {
+			t.Errorf("shard for %s has args %v", region, args)
+		}
+		if _, found := container["securityContext"]; !found {
+			t.Errorf("OpenShift container has no securityContext")
+		}
+	}
+	webhookDeployment := findObject(objs, "Deployment", WebhookName)
+	if replicas, _, _ := unstructured.NestedInt64(webhookDeployment.Object, "spec", "replicas"); replicas != 2 {
+		t.Errorf("webhook has %d replicas, want 2", replicas)
+	}
+	service := findObject(objs, "Service", WebhookName)
+	if service.GetAnnotations()["service.beta.openshift.io/serving-cert-secret-name"] != WebhookTLSSecretName {
+		t.Errorf("webhook Service annotations are %v", service.GetAnnotations())
+	}
+	config := findObject(objs, "ValidatingWebhookConfiguration", WebhookConfigurationName)
+	if config.GetAnnotations()["service.beta.openshift.io/inject-cabundle"] != "true" {
+		t.Errorf("webhook configuration annotations are %v", config.GetAnnotations())
+	}
+}
+
+func TestRenderRegionalKindIsNotPinned(t *testing.T) {
+	params := DefaultParams()
+	params.Topology = TopologyRegional
+	params.Environment = EnvironmentKind
+	params.Regions = []string{"a"}
+	objs, err := Render(params, nil)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if _, found, _ := unstructured.NestedStringMap(findObject(objs, "Deployment", "placement-translator-a").Object, "spec", "template", "spec", "nodeSelector"); found {
+		t.Errorf("kind shard is pinned to a region")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*Params)
+	}{
+		{"unknown topology", func(p *Params) { p.Topology = "mesh" }},
+		{"unknown environment", func(p *Params) { p.Environment = "mainframe" }},
+		{"regional without regions", func(p *Params) { p.Topology = TopologyRegional }},
+		{"regions for single hub", func(p *Params) { p.Regions = []string{"a"} }},
+		{"duplicate region", func(p *Params) { p.Topology, p.Regions = TopologyRegional, []string{"a", "a"} }},
+		{"bad region", func(p *Params) { p.Topology, p.Regions = TopologyRegional, []string{"US East"} }},
+		{"bad namespace", func(p *Params) { p.Namespace = "Kube_Stellar" }},
+		{"no image", func(p *Params) { p.Image = "" }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := DefaultParams()
+			tc.modify(&params)
+			if err := params.Validate(); err == nil {
+				t.Errorf("Validate accepted %+v", params)
+			}
+		})
+	}
+	if err := DefaultParams().Validate(); err != nil {
+		t.Errorf("Validate rejected the defaults: %v", err)
+	}
+}
+
+func containsArg(args []any, want string) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafkasink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Message is one Kafka message.
+type Message struct {
+	Key   string
+	Value []byte
+}
+
+// Producer sends messages to Kafka topics.
+type Producer interface {
+	// Produce sends the given messages, in order, to the given topic.
+	Produce(ctx context.Context, topic string, messages []Message) error
+}
+
+// BridgeProducer is a Producer that sends messages through the HTTP API
+// of the Strimzi Kafka Bridge.
+type BridgeProducer struct {
+	baseURL string
+	client  *http.Client
+	token   string
+	binary  bool
+}
+
+// NewBridgeProducer makes a BridgeProducer for the bridge at the given
+// URL. The values of the messages are JSON documents, embedded as they
+// are, unless binary is true, in which case they are sent base64
+// encoded. A non-empty token is sent as a bearer token.
+func NewBridgeProducer(baseURL string, client *http.Client, token string, binary bool) *BridgeProducer {
+	return &BridgeProducer{baseURL: strings.TrimSuffix(baseURL, "/"), client: client, token: token, binary: binary}
+}
+
+type bridgeRecord struct {
+	Key   any `json:"key"`
+	Value any `json:"value"`
+}
+
+type bridgeRequest struct {
+	Records []bridgeRecord `json:"records"`
+}
+
+type bridgeResponse struct {
+	Offsets []struct {
+		Partition int    `json:"partition"`
+		Offset    int64  `json:"offset"`
+		ErrorCode int    `json:"error_code,omitempty"`
+		Message   string `json:"message,omitempty"`
+	} `json:"offsets"`
+}
+
+// Produce implements Producer.
+func (bp *BridgeProducer) Produce(ctx context.Context, topic string, messages []Message) error {
+	request := bridgeRequest{Records: make([]bridgeRecord, len(messages))}
+	contentType := "application/vnd.kafka.json.v2+json"
+	if bp.binary {
+		contentType = "application/vnd.kafka.binary.v2+json"
+	}
+	for idx, msg := range messages {
+		if bp.binary {
+			// encoding/json writes a []byte in base64, as the bridge expects.
+			request.Records[idx] = bridgeRecord{Key: []byte(msg.Key), Value: msg.Value}
+		} else {
+			request.Records[idx] = bridgeRecord{Key: msg.Key, Value: json.RawMessage(msg.Value)}
+		}
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, bp.baseURL+"/topics/"+url.PathEscape(topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if bp.token != "" {
+		req.Header.Set("Authorization", "Bearer "+bp.token)
+	}
+	resp, err := bp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("the Kafka bridge answered %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	var parsed bridgeResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return fmt.Errorf("failed to parse the answer of the Kafka bridge: %w", err)
+	}
+	for idx, offset := range parsed.Offsets {
+		if offset.ErrorCode != 0 {
+			return fmt.Errorf("the Kafka bridge failed to send message %d of %d to topic %q: %d %s", idx+1, len(messages), topic, offset.ErrorCode, offset.Message)
+		}
+	}
+	return nil
+}
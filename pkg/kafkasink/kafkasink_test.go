@@ -0,0 +1,186 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafkasink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/top"
+	"github.com/kubestellar/kubestellar/pkg/timeline"
+)
+
+func TestAvro(t *testing.T) {
+	rec := &DecisionRecord{Time: "t", Placement: "ep", UID: "", Added: []string{"a", "bc"}}
+	got, err := encode(rec, SerializationAvro, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0, 0, 0, 0, 7, // wire format header
+		2, 't', 4, 'e', 'p', 0, // time, placement, uid
+		4, 2, 'a', 4, 'b', 'c', 0, // added: a block of 2, then the end
+		0} // removed: empty
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	var buf bytes.Buffer
+	avroLong(&buf, -65)
+	if !bytes.Equal(buf.Bytes(), []byte{0x81, 0x01}) {
+		t.Errorf("Wrong zig-zag encoding of -65: %v", buf.Bytes())
+	}
+}
+
+type fakeProducer struct {
+	mutex    sync.Mutex
+	failures int
+	sent     map[string][]Message
+}
+
+func (fp *fakeProducer) Produce(ctx context.Context, topic string, messages []Message) error {
+	fp.mutex.Lock()
+	defer fp.mutex.Unlock()
+	if fp.failures > 0 {
+		fp.failures--
+		return errors.New("broker unavailable")
+	}
+	fp.sent[topic] = append(fp.sent[topic], messages...)
+	return nil
+}
+
+func (fp *fakeProducer) count(topic string) int {
+	fp.mutex.Lock()
+	defer fp.mutex.Unlock()
+	return len(fp.sent[topic])
+}
+
+func TestSink(t *testing.T) {
+	producer := &fakeProducer{failures: 1, sent: map[string][]Message{}}
+	sink := NewSink(klog.Background(), producer, Config{SummaryTopic: "sums", DecisionTopic: "decs", Serialization: SerializationJSON, BatchSize: 2})
+	sink.backoff = time.Millisecond
+
+	fleet := &top.FleetSummary{
+		Time: metav1.NewTime(time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)),
+		Placements: []top.PlacementSummary{
+			{Name: "web", Health: top.HealthReady, Destinations: []top.DestinationRef{{SyncTargetName: "edge1"}}},
+		},
+		Destinations: []top.DestinationSummary{
+			{ClusterID: "inv", SyncTargetName: "edge1", Health: top.HealthReady, Placements: []string{"web"}},
+		},
+	}
+	sink.ObserveSummary(fleet)
+	sink.ObserveSummary(fleet) // no change
+	sink.ObserveEvent(timeline.Event{Time: fleet.Time, Placement: "web", Type: timeline.EventDestinationsChanged, Added: []string{"inv/edge1"}})
+	sink.ObserveEvent(timeline.Event{Time: fleet.Time, Placement: "web", Type: timeline.EventSpecChanged, Generation: 2})
+
+	// The destination goes stale, and the placement is deleted.
+	fleet.Placements = nil
+	fleet.Destinations[0].Health, fleet.Destinations[0].Reason = top.HealthStale, "NoHeartbeat"
+	sink.ObserveSummary(fleet)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sink.Run(ctx)
+	if err := wait(func() bool { return producer.count("sums") == 6 && producer.count("decs") == 1 }); err != nil {
+		t.Fatalf("Expected 6 summary and 1 decision records, got %+v", producer.sent)
+	}
+
+	var records []SummaryRecord
+	for _, msg := range producer.sent["sums"] {
+		var rec SummaryRecord
+		if err := json.Unmarshal(msg.Value, &rec); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+	expected := []struct{ kind, name, health string }{
+		{KindDestination, "inv/edge1", "Ready"}, {KindFleet, "", ""}, {KindPlacement, "web", "Ready"},
+		{KindDestination, "inv/edge1", "Stale"}, {KindFleet, "", ""}, {KindPlacement, "web", HealthDeleted},
+	}
+	for idx, exp := range expected {
+		if rec := records[idx]; rec.Kind != exp.kind || rec.Name != exp.name || rec.Health != exp.health || rec.Time != "2023-09-01T12:00:00Z" {
+			t.Errorf("Record %d: expected %+v, got %+v", idx, exp, rec)
+		}
+	}
+	if records[1].Placements != 1 || records[1].PlacementsReady != 1 || records[4].Placements != 0 || records[4].DestinationsStale != 1 {
+		t.Errorf("Wrong fleet counts: %+v and %+v", records[1], records[4])
+	}
+	if key := producer.sent["sums"][1].Key; key != FleetKey {
+		t.Errorf("Expected key %q for the fleet, got %q", FleetKey, key)
+	}
+}
+
+func wait(cond func() bool) error {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return nil
+		}
+	}
+	return errors.New("timed out")
+}
+
+func TestBridgeProducer(t *testing.T) {
+	var gotPath, gotType, gotAuth string
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotPath, gotType, gotAuth = req.URL.Path, req.Header.Get("Content-Type"), req.Header.Get("Authorization")
+		body, _ := io.ReadAll(req.Body)
+		gotBody = nil
+		_ = json.Unmarshal(body, &gotBody)
+		if req.URL.Path == "/topics/bad" {
+			w.Write([]byte(`{"offsets":[{"partition":0,"offset":1},{"error_code":40401,"message":"topic not found"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":1}]}`))
+	}))
+	defer srv.Close()
+
+	jsonProducer := NewBridgeProducer(srv.URL+"/", srv.Client(), "secret", false)
+	if err := jsonProducer.Produce(context.Background(), "sums", []Message{{Key: "web", Value: []byte(`{"health":"Ready"}`)}}); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/topics/sums" || gotType != "application/vnd.kafka.json.v2+json" || gotAuth != "Bearer secret" {
+		t.Errorf("Unexpected request: %s %s %s", gotPath, gotType, gotAuth)
+	}
+	record := gotBody["records"].([]any)[0].(map[string]any)
+	if record["key"] != "web" || record["value"].(map[string]any)["health"] != "Ready" {
+		t.Errorf("Unexpected JSON record: %v", record)
+	}
+
+	binaryProducer := NewBridgeProducer(srv.URL, srv.Client(), "", true)
+	if err := binaryProducer.Produce(context.Background(), "sums", []Message{{Key: "web", Value: []byte{0, 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	record = gotBody["records"].([]any)[0].(map[string]any)
+	if gotType != "application/vnd.kafka.binary.v2+json" || gotAuth != "" || record["key"] != "d2Vi" || record["value"] != "AAE=" {
+		t.Errorf("Unexpected binary request: %s %s %v", gotType, gotAuth, record)
+	}
+
+	if err := binaryProducer.Produce(context.Background(), "bad", []Message{{Key: "a"}, {Key: "b"}}); err == nil {
+		t.Error("Expected a failure for a record that the bridge did not send")
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafkasink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// Serialization is how records are encoded in Kafka messages.
+type Serialization string
+
+const (
+	// SerializationJSON encodes a record as a JSON object.
+	SerializationJSON Serialization = "json"
+
+	// SerializationAvro encodes a record in the Avro binary encoding,
+	// with SummaryAvroSchema or DecisionAvroSchema. With a schema ID it
+	// is in the Confluent wire format: a zero byte, the 4-byte
+	// big-endian schema ID, then the Avro encoding.
+	SerializationAvro Serialization = "avro"
+)
+
+// ParseSerialization parses the name of a Serialization.
+func ParseSerialization(name string) (Serialization, error) {
+	switch ser := Serialization(name); ser {
+	case SerializationJSON, SerializationAvro:
+		return ser, nil
+	}
+	return "", fmt.Errorf("unknown serialization %q; expected %q or %q", name, SerializationJSON, SerializationAvro)
+}
+
+// Kinds of SummaryRecord.
+const (
+	KindFleet       = "Fleet"
+	KindPlacement   = "Placement"
+	KindDestination = "Destination"
+)
+
+// HealthDeleted is the Health in the SummaryRecord of a placement or
+// destination that is no longer in the fleet.
+const HealthDeleted = "Deleted"
+
+// SummaryRecord is a change in the summary of the fleet, of one
+// placement, or of one destination. The counts that do not apply to
+// the Kind are zero.
+type SummaryRecord struct {
+	// Time is in RFC 3339 format.
+	Time string `json:"time"`
+	Kind string `json:"kind"`
+
+	// Name is the name of the placement, or the Key of the destination
+	// (see package destination); empty for the fleet.
+	Name   string `json:"name"`
+	Health string `json:"health"`
+	Reason string `json:"reason"`
+
+	Placements        int64 `json:"placements"`
+	PlacementsReady   int64 `json:"placementsReady"`
+	Destinations      int64 `json:"destinations"`
+	DestinationsReady int64 `json:"destinationsReady"`
+	DestinationsStale int64 `json:"destinationsStale"`
+	Failures          int64 `json:"failures"`
+}
+
+// SummaryAvroSchema is the Avro schema of a SummaryRecord.
+const SummaryAvroSchema = `{
+  "type": "record",
+  "name": "SummaryRecord",
+  "namespace": "io.kubestellar.edge",
+  "fields": [
+    {"name": "time", "type": "string"},
+    {"name": "kind", "type": "string"},
+    {"name": "name", "type": "string"},
+    {"name": "health", "type": "string"},
+    {"name": "reason", "type": "string"},
+    {"name": "placements", "type": "long"},
+    {"name": "placementsReady", "type": "long"},
+    {"name": "destinations", "type": "long"},
+    {"name": "destinationsReady", "type": "long"},
+    {"name": "destinationsStale", "type": "long"},
+    {"name": "failures", "type": "long"}
+  ]
+}`
+
+func (rec *SummaryRecord) appendAvro(buf *bytes.Buffer) {
+	for _, str := range []string{rec.Time, rec.Kind, rec.Name, rec.Health, rec.Reason} {
+		avroString(buf, str)
+	}
+	for _, num := range []int64{rec.Placements, rec.PlacementsReady, rec.Destinations, rec.DestinationsReady, rec.DestinationsStale, rec.Failures} {
+		avroLong(buf, num)
+	}
+}
+
+// DecisionRecord is a change in the destinations of a placement.
+type DecisionRecord struct {
+	// Time is in RFC 3339 format.
+	Time      string `json:"time"`
+	Placement string `json:"placement"`
+	UID       string `json:"uid"`
+
+	// Added and Removed are destination Keys (see package destination).
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// DecisionAvroSchema is the Avro schema of a DecisionRecord.
+const DecisionAvroSchema = `{
+  "type": "record",
+  "name": "DecisionRecord",
+  "namespace": "io.kubestellar.edge",
+  "fields": [
+    {"name": "time", "type": "string"},
+    {"name": "placement", "type": "string"},
+    {"name": "uid", "type": "string"},
+    {"name": "added", "type": {"type": "array", "items": "string"}},
+    {"name": "removed", "type": {"type": "array", "items": "string"}}
+  ]
+}`
+
+func (rec *DecisionRecord) appendAvro(buf *bytes.Buffer) {
+	for _, str := range []string{rec.Time, rec.Placement, rec.UID} {
+		avroString(buf, str)
+	}
+	avroStrings(buf, rec.Added)
+	avroStrings(buf, rec.Removed)
+}
+
+type avroRecord interface {
+	appendAvro(buf *bytes.Buffer)
+}
+
+// encode returns the given record in the given Serialization.
+// A positive schemaID selects the Confluent wire format for Avro.
+func encode(record avroRecord, ser Serialization, schemaID int32) ([]byte, error) {
+	if ser == SerializationJSON {
+		return json.Marshal(record)
+	}
+	var buf bytes.Buffer
+	if schemaID > 0 {
+		buf.WriteByte(0)
+		var id [4]byte
+		binary.BigEndian.PutUint32(id[:], uint32(schemaID))
+		buf.Write(id[:])
+	}
+	record.appendAvro(&buf)
+	return buf.Bytes(), nil
+}
+
+// avroLong appends the zig-zag varint encoding of the given number.
+func avroLong(buf *bytes.Buffer, num int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutVarint(tmp[:], num)])
+}
+
+func avroString(buf *bytes.Buffer, str string) {
+	avroLong(buf, int64(len(str)))
+	buf.WriteString(str)
+}
+
+// avroStrings appends an array of strings as one block.
+func avroStrings(buf *bytes.Buffer, strs []string) {
+	if len(strs) > 0 {
+		avroLong(buf, int64(len(strs)))
+		for _, str := range strs {
+			avroString(buf, str)
+		}
+	}
+	avroLong(buf, 0)
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kafkasink streams the summarized state of a fleet, and the
+// changes in placement decisions, into Kafka topics, so that data
+// platforms can build fleet analytics without reading the API servers.
+//
+// A SummaryRecord is sent whenever the health of the fleet, of a
+// placement or of a destination changes, as summarized by the top
+// package; the first summary sends a record for everything. A
+// DecisionRecord is sent for every change in the destinations of a
+// placement, as recorded in the timeline (see package timeline).
+// Messages are keyed by placement name or destination Key, so that the
+// records about one thing stay in order in one partition.
+//
+// Records are queued in memory, up to a bound beyond which new ones are
+// dropped, and sent in batches. A batch that fails is retried, with
+// backoff, until it is sent; so delivery is at least once.
+package kafkasink
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/top"
+	"github.com/kubestellar/kubestellar/pkg/destination"
+	"github.com/kubestellar/kubestellar/pkg/timeline"
+)
+
+var (
+	recordsSent = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_kafka_sink",
+		Name:           "records_sent_total",
+		Help:           "Number of records sent to Kafka, by topic",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"topic"})
+	recordsDropped = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_kafka_sink",
+		Name:           "records_dropped_total",
+		Help:           "Number of records dropped because the queue for Kafka was full",
+		StabilityLevel: metrics.ALPHA,
+	})
+	sendFailures = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_kafka_sink",
+		Name:           "send_failures_total",
+		Help:           "Number of failed attempts to send a batch of records to Kafka",
+		StabilityLevel: metrics.ALPHA,
+	})
+)
+
+func init() {
+	legacyregistry.MustRegister(recordsSent, recordsDropped, sendFailures)
+}
+
+// FleetKey is the key of the messages about the fleet as a whole.
+const FleetKey = "fleet"
+
+const (
+	DefaultQueueSize = 10000
+	DefaultBatchSize = 100
+)
+
+// Config configures a Sink.
+type Config struct {
+	// SummaryTopic receives the SummaryRecords; empty means not to send them.
+	SummaryTopic string
+
+	// DecisionTopic receives the DecisionRecords; empty means not to send them.
+	DecisionTopic string
+
+	Serialization Serialization
+
+	// SummarySchemaID and DecisionSchemaID, when positive, are the IDs
+	// of SummaryAvroSchema and DecisionAvroSchema in a schema registry;
+	// see SerializationAvro.
+	SummarySchemaID  int32
+	DecisionSchemaID int32
+
+	// QueueSize bounds the number of records waiting to be sent.
+	// Default is DefaultQueueSize.
+	QueueSize int
+
+	// BatchSize bounds the number of records sent at once.
+	// Default is DefaultBatchSize.
+	BatchSize int
+}
+
+type queued struct {
+	topic string
+	msg   Message
+}
+
+// Sink sends records to Kafka.
+type Sink struct {
+	logger   klog.Logger
+	producer Producer
+	config   Config
+	queue    chan queued
+
+	// backoff is the first wait after a failed send.
+	backoff time.Duration
+
+	mutex sync.Mutex
+	// last holds the last SummaryRecord sent for each key, with Time cleared.
+	last map[string]SummaryRecord
+}
+
+// NewSink makes a Sink that sends with the given Producer.
+func NewSink(logger klog.Logger, producer Producer, config Config) *Sink {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	return &Sink{
+		logger:   logger,
+		producer: producer,
+		config:   config,
+		queue:    make(chan queued, config.QueueSize),
+		backoff:  time.Second,
+		last:     map[string]SummaryRecord{},
+	}
+}
+
+// ObserveSummary queues a SummaryRecord for each thing whose summary
+// changed since the last call. See gateway.Server.AddSummaryListener.
+func (sink *Sink) ObserveSummary(fleet *top.FleetSummary) {
+	if sink.config.SummaryTopic == "" {
+		return
+	}
+	now := fleet.Time.UTC().Format(time.RFC3339)
+	current := map[string]SummaryRecord{}
+	fleetRec := SummaryRecord{Kind: KindFleet, Placements: int64(len(fleet.Placements)),
+		Destinations: int64(len(fleet.Destinations)), Failures: int64(len(fleet.Failures))}
+	for _, ps := range fleet.Placements {
+		rec := SummaryRecord{Kind: KindPlacement, Name: ps.Name, Health: string(ps.Health),
+			Destinations: int64(len(ps.Destinations)), DestinationsStale: int64(ps.StaleDestinations)}
+		for _, cond := range ps.Conditions {
+			if cond.Status != metav1.ConditionTrue && rec.Reason == "" {
+				rec.Reason = cond.Reason
+			}
+		}
+		if ps.Health == top.HealthReady {
+			fleetRec.PlacementsReady++
+		}
+		current[KindPlacement+"/"+ps.Name] = rec
+	}
+	for _, ds := range fleet.Destinations {
+		key := destination.New(ds.ClusterID, ds.SyncTargetName).Key()
+		rec := SummaryRecord{Kind: KindDestination, Name: key, Health: string(ds.Health), Reason: ds.Reason,
+			Placements: int64(len(ds.Placements))}
+		switch ds.Health {
+		case top.HealthReady:
+			fleetRec.DestinationsReady++
+		case top.HealthStale:
+			fleetRec.DestinationsStale++
+		}
+		current[KindDestination+"/"+key] = rec
+	}
+	current[KindFleet] = fleetRec
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	ids := make([]string, 0, len(current))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		rec := current[id]
+		if last, ok := sink.last[id]; ok && last == rec {
+			continue
+		}
+		sink.last[id] = rec
+		rec.Time = now
+		sink.enqueueSummary(rec)
+	}
+	for id, last := range sink.last {
+		if _, ok := current[id]; !ok {
+			delete(sink.last, id)
+			sink.enqueueSummary(SummaryRecord{Time: now, Kind: last.Kind, Name: last.Name, Health: HealthDeleted})
+		}
+	}
+}
+
+func (sink *Sink) enqueueSummary(rec SummaryRecord) {
+	key := rec.Name
+	if rec.Kind == KindFleet {
+		key = FleetKey
+	}
+	value, err := encode(&rec, sink.config.Serialization, sink.config.SummarySchemaID)
+	if err != nil {
+		sink.logger.Error(err, "Failed to encode summary record", "record", rec)
+		return
+	}
+	sink.enqueue(sink.config.SummaryTopic, Message{Key: key, Value: value})
+}
+
+// ObserveEvent queues a DecisionRecord if the given timeline event is a
+// change in destinations. See timeline.Store.AddListener.
+func (sink *Sink) ObserveEvent(event timeline.Event) {
+	if sink.config.DecisionTopic == "" || event.Type != timeline.EventDestinationsChanged {
+		return
+	}
+	rec := DecisionRecord{Time: event.Time.UTC().Format(time.RFC3339), Placement: event.Placement, UID: string(event.UID),
+		Added: append([]string{}, event.Added...), Removed: append([]string{}, event.Removed...)}
+	value, err := encode(&rec, sink.config.Serialization, sink.config.DecisionSchemaID)
+	if err != nil {
+		sink.logger.Error(err, "Failed to encode decision record", "record", rec)
+		return
+	}
+	sink.enqueue(sink.config.DecisionTopic, Message{Key: event.Placement, Value: value})
+}
+
+func (sink *Sink) enqueue(topic string, msg Message) {
+	select {
+	case sink.queue <- queued{topic: topic, msg: msg}:
+	default:
+		recordsDropped.Inc()
+		sink.logger.V(3).Info("Dropped record because the queue is full", "topic", topic, "key", msg.Key)
+	}
+}
+
+// Run sends the queued records until the context is done.
+func (sink *Sink) Run(ctx context.Context) {
+	for {
+		var first queued
+		select {
+		case <-ctx.Done():
+			return
+		case first = <-sink.queue:
+		}
+		// Take what else is queued for the same topic, up to a batch.
+		batch := []Message{first.msg}
+		var next *queued
+	fill:
+		for len(batch) < sink.config.BatchSize {
+			select {
+			case item := <-sink.queue:
+				if item.topic != first.topic {
+					next = &item
+					break fill
+				}
+				batch = append(batch, item.msg)
+			default:
+				break fill
+			}
+		}
+		if !sink.send(ctx, first.topic, batch) {
+			return
+		}
+		if next != nil && !sink.send(ctx, next.topic, []Message{next.msg}) {
+			return
+		}
+	}
+}
+
+// send sends the given batch, retrying until it succeeds or the
+// context is done. Returns false in the latter case.
+func (sink *Sink) send(ctx context.Context, topic string, batch []Message) bool {
+	backoff := wait.Backoff{Duration: sink.backoff, Factor: 2, Jitter: 0.1, Steps: 1 << 30, Cap: time.Minute}
+	for {
+		err := sink.producer.Produce(ctx, topic, batch)
+		if err == nil {
+			recordsSent.WithLabelValues(topic).Add(float64(len(batch)))
+			sink.logger.V(4).Info("Sent records", "topic", topic, "count", len(batch))
+			return true
+		}
+		sendFailures.Inc()
+		delay := backoff.Step()
+		sink.logger.Error(err, "Failed to send records to Kafka, will retry", "topic", topic, "count", len(batch), "delay", delay)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package labelttl lets a label be published with a time to live, for
+// hints that go stale unless refreshed, such as an agent's
+// "has-gpu-free" on a Location. The expiry times are kept in one
+// annotation, holding a JSON object that maps label keys to RFC 3339
+// times. A controller (the where-resolver, for Locations) removes each
+// such label, and its entry, once the time has passed; a publisher keeps
+// a label by calling Set again before then.
+package labelttl
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnnotationKey is the key of the annotation that holds the expiry times.
+const AnnotationKey = "edge.kubestellar.io/label-expiry"
+
+// Expiries returns the expiry time of each label of the given object
+// that has one.
+func Expiries(obj metav1.Object) (map[string]time.Time, error) {
+	value, have := obj.GetAnnotations()[AnnotationKey]
+	if !have || value == "" {
+		return nil, nil
+	}
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("annotation %s is not a JSON object of strings: %w", AnnotationKey, err)
+	}
+	ans := make(map[string]time.Time, len(raw))
+	for key, timeS := range raw {
+		expiry, err := time.Parse(time.RFC3339, timeS)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s has an invalid time for label %q: %w", AnnotationKey, key, err)
+		}
+		ans[key] = expiry
+	}
+	return ans, nil
+}
+
+// Set sets a label of the given object, to expire after the given time to live.
+func Set(obj metav1.Object, key, value string, ttl time.Duration, now time.Time) error {
+	expiries, err := Expiries(obj)
+	if err != nil {
+		return err
+	}
+	if expiries == nil {
+		expiries = map[string]time.Time{}
+	}
+	expiries[key] = now.Add(ttl)
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[key] = value
+	obj.SetLabels(labels)
+	setExpiries(obj, expiries)
+	return nil
+}
+
+// Due returns, in order, the keys of the labels of the given object that
+// have expired by the given time, and the earliest expiry after it (zero
+// if there is none). A label whose entry remains after the label itself
+// was removed counts as expired, so that the entry gets cleaned up.
+func Due(obj metav1.Object, now time.Time) (expired []string, next time.Time, err error) {
+	expiries, err := Expiries(obj)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	labels := obj.GetLabels()
+	for key, expiry := range expiries {
+		if _, have := labels[key]; !have || !now.Before(expiry) {
+			expired = append(expired, key)
+		} else if next.IsZero() || expiry.Before(next) {
+			next = expiry
+		}
+	}
+	sort.Strings(expired)
+	return expired, next, nil
+}
+
+// Remove removes the given labels of the given object, and their expiries.
+func Remove(obj metav1.Object, keys []string) {
+	expiries, _ := Expiries(obj)
+	labels := obj.GetLabels()
+	for _, key := range keys {
+		delete(labels, key)
+		delete(expiries, key)
+	}
+	obj.SetLabels(labels)
+	setExpiries(obj, expiries)
+}
+
+func setExpiries(obj metav1.Object, expiries map[string]time.Time) {
+	annotations := obj.GetAnnotations()
+	if len(expiries) == 0 {
+		delete(annotations, AnnotationKey)
+		obj.SetAnnotations(annotations)
+		return
+	}
+	raw := make(map[string]string, len(expiries))
+	for key, expiry := range expiries {
+		raw[key] = expiry.UTC().Format(time.RFC3339)
+	}
+	// Marshaling a map of strings cannot fail
+	value, _ := json.Marshal(raw)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationKey] = string(value)
+	obj.SetAnnotations(annotations)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package labelttl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestLabelTTL(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	loc := &edgeapi.Location{ObjectMeta: metav1.ObjectMeta{Name: "edge1", Labels: map[string]string{"env": "prod"}}}
+	if err := Set(loc, "has-gpu-free", "true", time.Minute, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(loc, "load", "low", 5*time.Minute, now); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"has-gpu-free":"2023-09-01T12:01:00Z","load":"2023-09-01T12:05:00Z"}`; loc.Annotations[AnnotationKey] != expected {
+		t.Errorf("Expected annotation %s, got %s", expected, loc.Annotations[AnnotationKey])
+	}
+
+	expired, next, err := Due(loc, now.Add(30*time.Second))
+	if err != nil || len(expired) != 0 || !next.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected nothing expired and the next expiry in a minute, got %v, %v, %v", expired, next, err)
+	}
+	expired, next, err = Due(loc, now.Add(time.Minute))
+	if err != nil || !reflect.DeepEqual(expired, []string{"has-gpu-free"}) || !next.Equal(now.Add(5*time.Minute)) {
+		t.Errorf("Expected has-gpu-free to expire, got %v, %v, %v", expired, next, err)
+	}
+	Remove(loc, expired)
+	if expected := map[string]string{"env": "prod", "load": "low"}; !reflect.DeepEqual(loc.Labels, expected) {
+		t.Errorf("Expected labels %v, got %v", expected, loc.Labels)
+	}
+
+	delete(loc.Labels, "load") // removed by hand
+	expired, next, _ = Due(loc, now)
+	if !reflect.DeepEqual(expired, []string{"load"}) || !next.IsZero() {
+		t.Errorf("Expected the entry of a missing label to be due, got %v, %v", expired, next)
+	}
+	Remove(loc, expired)
+	if _, have := loc.Annotations[AnnotationKey]; have {
+		t.Errorf("Expected the annotation to go with its last entry, got %v", loc.Annotations)
+	}
+
+	loc.Annotations = map[string]string{AnnotationKey: `{"a": "tomorrow"}`}
+	if _, _, err := Due(loc, now); err == nil {
+		t.Error("Expected an error for an invalid time")
+	}
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localprobes
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/websocket"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// execProtocol is the subprotocol of the pods/exec websocket: each
+// message starts with a channel number byte, and the error channel
+// carries a Status when the command ends.
+const execProtocol = "v4.channel.k8s.io"
+
+const (
+	stdoutChannel = 1
+	stderrChannel = 2
+	errorChannel  = 3
+)
+
+// maxStderr bounds how much of a command's stderr goes into a failure message.
+const maxStderr = 256
+
+// PodExecutor is an Executor that uses the pods/exec subresource of the
+// WEC's apiserver, over a websocket. It authenticates with the client
+// certificate or bearer token of its rest.Config; other means (such as
+// exec plugins) are not supported.
+type PodExecutor struct {
+	pods      corev1client.PodsGetter
+	server    *url.URL
+	tlsConfig *tls.Config
+	config    *rest.Config
+}
+
+var _ Executor = &PodExecutor{}
+
+// NewPodExecutor returns a PodExecutor for the apiserver of the given config.
+func NewPodExecutor(config *rest.Config) (*PodExecutor, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	host := config.Host
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	server, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("malformed apiserver address %q: %w", config.Host, err)
+	}
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return nil, err
+	}
+	return &PodExecutor{pods: client.CoreV1(), server: server, tlsConfig: tlsConfig, config: config}, nil
+}
+
+func (pe *PodExecutor) Exec(ctx context.Context, namespace string, selector labels.Selector, container string, command []string) error {
+	pod, err := pe.pickPod(ctx, namespace, selector)
+	if err != nil {
+		return err
+	}
+	if container == "" {
+		container = pod.Spec.Containers[0].Name
+	}
+	wsConfig, err := websocket.NewConfig(pe.execURL(namespace, pod.Name, container, command), pe.server.String())
+	if err != nil {
+		return err
+	}
+	wsConfig.Protocol = []string{execProtocol}
+	wsConfig.TlsConfig = pe.tlsConfig
+	if token, err := pe.bearerToken(); err != nil {
+		return err
+	} else if token != "" {
+		wsConfig.Header.Set("Authorization", "Bearer "+token)
+	}
+	conn, err := wsConfig.DialContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to exec in pod %s/%s: %w", namespace, pod.Name, err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+			conn.Close()
+		}
+	}()
+	var stderr []byte
+	for {
+		var message []byte
+		if err := websocket.Message.Receive(conn, &message); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("exec in pod %s/%s ended without a status", namespace, pod.Name)
+			}
+			return err
+		}
+		if len(message) == 0 {
+			continue
+		}
+		switch message[0] {
+		case stderrChannel:
+			stderr = append(stderr, message[1:]...)
+			if len(stderr) > maxStderr {
+				stderr = stderr[len(stderr)-maxStderr:]
+			}
+		case errorChannel:
+			var status metav1.Status
+			if err := json.Unmarshal(message[1:], &status); err != nil {
+				return fmt.Errorf("malformed exec status: %w", err)
+			}
+			if status.Status == metav1.StatusSuccess {
+				return nil
+			}
+			if len(stderr) > 0 {
+				return fmt.Errorf("%s: %s", status.Message, strings.TrimSpace(string(stderr)))
+			}
+			return errors.New(status.Message)
+		}
+	}
+}
+
+// pickPod returns the first, by name, of the running pods that the selector selects.
+func (pe *PodExecutor) pickPod(ctx context.Context, namespace string, selector labels.Selector) (*corev1.Pod, error) {
+	pods, err := pe.pods.Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	running := []*corev1.Pod{}
+	for idx := range pods.Items {
+		pod := &pods.Items[idx]
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil && len(pod.Spec.Containers) > 0 {
+			running = append(running, pod)
+		}
+	}
+	if len(running) == 0 {
+		return nil, fmt.Errorf("no running pod in namespace %q matches %q", namespace, selector.String())
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].Name < running[j].Name })
+	return running[0], nil
+}
+
+func (pe *PodExecutor) execURL(namespace, pod, container string, command []string) string {
+	execURL := *pe.server
+	if execURL.Scheme == "http" {
+		execURL.Scheme = "ws"
+	} else {
+		execURL.Scheme = "wss"
+	}
+	execURL.Path = strings.TrimSuffix(execURL.Path, "/") + "/api/v1/namespaces/" + namespace + "/pods/" + pod + "/exec"
+	query := url.Values{"container": {container}, "stdout": {"true"}, "stderr": {"true"}, "command": command}
+	execURL.RawQuery = query.Encode()
+	return execURL.String()
+}
+
+func (pe *PodExecutor) bearerToken() (string, error) {
+	if pe.config.BearerToken != "" || pe.config.BearerTokenFile == "" {
+		return pe.config.BearerToken, nil
+	}
+	token, err := os.ReadFile(pe.config.BearerTokenFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package localprobes runs, in a WEC, the LocalProbes that EdgePlacements
+// ask for, and reports their results as conditions. The syncer puts those
+// conditions on the copies in the mailbox space, in the
+// edgeapi.ProbeResultsKey annotation, so that the health of the
+// application is seen from the center and not only what the WEC's
+// apiserver says about it.
+package localprobes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/clock"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+)
+
+// DefaultTimeout bounds a run of a probe that does not say otherwise.
+const DefaultTimeout = 5 * time.Second
+
+// The reasons of the conditions that report probe results.
+const (
+	ReasonSucceeded    = "ProbeSucceeded"
+	ReasonFailed       = "ProbeFailed"
+	ReasonInvalidProbe = "InvalidProbe"
+)
+
+// maxResponseBytes bounds how much of a Prometheus response is read.
+const maxResponseBytes = 1 << 20
+
+// Executor runs commands in the pods of the WEC.
+type Executor interface {
+	// Exec runs the given command in the given container (empty means
+	// the first one) of the first, by name, of the running pods in the
+	// given namespace that the selector selects. It returns nil when
+	// the command exits with code 0.
+	Exec(ctx context.Context, namespace string, selector labels.Selector, container string, command []string) error
+}
+
+// Runner runs the probes and turns their results into conditions.
+// Results are reused for a while, because the syncer reads the objects often.
+type Runner struct {
+	clock      clock.PassiveClock
+	maxAge     time.Duration
+	httpClient *http.Client
+	executor   Executor
+
+	mutex   sync.Mutex
+	results map[string]map[string]result // by object, then by probe name
+}
+
+type result struct {
+	probe     edgeapi.LocalProbe
+	condition metav1.Condition
+	probedAt  time.Time
+}
+
+// NewRunner returns a Runner whose results are reused for maxAge.
+// The executor runs the `exec` probes; nil means that they fail.
+func NewRunner(clock clock.PassiveClock, maxAge time.Duration, executor Executor) *Runner {
+	return &Runner{
+		clock:      clock,
+		maxAge:     maxAge,
+		httpClient: &http.Client{},
+		executor:   executor,
+		results:    map[string]map[string]result{},
+	}
+}
+
+// Conditions returns the conditions that report the given probes of the
+// object identified by the given key, in the given namespace, sorted by
+// type. The given previous conditions are the ones reported before; a
+// condition keeps its LastTransitionTime while its status does not change.
+func (rnr *Runner) Conditions(ctx context.Context, key, namespace string, probes []edgeapi.LocalProbe, previous []metav1.Condition) []metav1.Condition {
+	now := rnr.clock.Now()
+	rnr.mutex.Lock()
+	cached := rnr.results[key]
+	rnr.mutex.Unlock()
+	fresh := make(map[string]result, len(probes))
+	ans := []metav1.Condition{}
+	for _, probe := range probes {
+		res, found := cached[probe.Name]
+		if !found || now.Sub(res.probedAt) >= rnr.maxAge || !equalProbes(res.probe, probe) {
+			res = result{probe: probe, condition: rnr.probe(ctx, namespace, probe), probedAt: now}
+		}
+		fresh[probe.Name] = res
+		cond := res.condition
+		if prev := conditions.Find(previous, cond.Type); prev != nil && prev.Status == cond.Status {
+			cond.LastTransitionTime = prev.LastTransitionTime
+		}
+		conditions.Set(&ans, cond, metav1.NewTime(now))
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Type < ans[j].Type })
+	rnr.mutex.Lock()
+	defer rnr.mutex.Unlock()
+	rnr.results[key] = fresh
+	return ans
+}
+
+// Forget drops what is remembered about the object identified by the given key.
+func (rnr *Runner) Forget(key string) {
+	rnr.mutex.Lock()
+	defer rnr.mutex.Unlock()
+	delete(rnr.results, key)
+}
+
+func equalProbes(a, b edgeapi.LocalProbe) bool {
+	aJSON, _ := json.Marshal(a)
+	bJSON, _ := json.Marshal(b)
+	return string(aJSON) == string(bJSON)
+}
+
+// probe runs the given probe and returns the condition that reports its result.
+func (rnr *Runner) probe(ctx context.Context, namespace string, probe edgeapi.LocalProbe) metav1.Condition {
+	cond := metav1.Condition{Type: probe.Name}
+	timeout := DefaultTimeout
+	if probe.TimeoutSeconds > 0 {
+		timeout = time.Duration(probe.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var err error
+	switch {
+	case probe.HTTPGet != nil && probe.Exec == nil && probe.PromQL == nil:
+		err = rnr.httpGet(ctx, probe.HTTPGet.URL)
+	case probe.Exec != nil && probe.HTTPGet == nil && probe.PromQL == nil:
+		err = rnr.exec(ctx, namespace, probe.Exec)
+	case probe.PromQL != nil && probe.HTTPGet == nil && probe.Exec == nil:
+		err = rnr.promQL(ctx, probe.PromQL)
+	default:
+		cond.Status, cond.Reason = metav1.ConditionUnknown, ReasonInvalidProbe
+		cond.Message = "exactly one of httpGet, exec and promQL must be given"
+		return cond
+	}
+	if err != nil {
+		cond.Status, cond.Reason, cond.Message = metav1.ConditionFalse, ReasonFailed, err.Error()
+	} else {
+		cond.Status, cond.Reason, cond.Message = metav1.ConditionTrue, ReasonSucceeded, "probe "+probe.Name+" succeeded"
+	}
+	return cond
+}
+
+func (rnr *Runner) httpGet(ctx context.Context, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("malformed probe URL %q: %w", target, err)
+	}
+	resp, err := rnr.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("GET %s returned %s", target, resp.Status)
+	}
+	return nil
+}
+
+func (rnr *Runner) exec(ctx context.Context, namespace string, probe *edgeapi.ExecProbe) error {
+	if rnr.executor == nil {
+		return fmt.Errorf("exec probes are not supported here")
+	}
+	if len(probe.Command) == 0 {
+		return fmt.Errorf("exec probe has no command")
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&probe.PodSelector)
+	if err != nil {
+		return fmt.Errorf("invalid pod selector: %w", err)
+	}
+	if probe.Namespace != "" {
+		namespace = probe.Namespace
+	}
+	return rnr.executor.Exec(ctx, namespace, selector, probe.Container, probe.Command)
+}
+
+// promQLResponse is the part of a Prometheus instant query response that is examined.
+type promQLResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type promQLSample struct {
+	Value []any `json:"value"`
+}
+
+func (rnr *Runner) promQL(ctx context.Context, probe *edgeapi.PromQLProbe) error {
+	target := probe.URL + "/api/v1/query?query=" + url.QueryEscape(probe.Query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("malformed Prometheus URL %q: %w", probe.URL, err)
+	}
+	resp, err := rnr.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+	var parsed promQLResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return fmt.Errorf("malformed Prometheus response (%s): %w", resp.Status, err)
+	}
+	if parsed.Status != "success" {
+		return fmt.Errorf("query %q failed: %s", probe.Query, parsed.Error)
+	}
+	var values []any
+	switch parsed.Data.ResultType {
+	case "vector":
+		var samples []promQLSample
+		if err := json.Unmarshal(parsed.Data.Result, &samples); err != nil {
+			return fmt.Errorf("malformed Prometheus vector: %w", err)
+		}
+		for _, sample := range samples {
+			if len(sample.Value) != 2 {
+				return fmt.Errorf("malformed Prometheus sample")
+			}
+			values = append(values, sample.Value[1])
+		}
+	case "scalar":
+		var sample []any
+		if err := json.Unmarshal(parsed.Data.Result, &sample); err != nil || len(sample) != 2 {
+			return fmt.Errorf("malformed Prometheus scalar")
+		}
+		values = append(values, sample[1])
+	default:
+		return fmt.Errorf("query %q returned a %s, not a vector or scalar", probe.Query, parsed.Data.ResultType)
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("query %q returned no samples", probe.Query)
+	}
+	for _, value := range values {
+		text, _ := value.(string)
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("query %q returned a malformed value %v", probe.Query, value)
+		}
+		if number == 0 {
+			return fmt.Errorf("query %q returned a zero sample", probe.Query)
+		}
+	}
+	return nil
+}
+
+// DecodeProbes parses the value of the edgeapi.LocalProbesKey annotation.
+func DecodeProbes(value string) ([]edgeapi.LocalProbe, error) {
+	var probes []edgeapi.LocalProbe
+	if err := json.Unmarshal([]byte(value), &probes); err != nil {
+		return nil, fmt.Errorf("malformed %s annotation: %w", edgeapi.LocalProbesKey, err)
+	}
+	return probes, nil
+}
+
+// EncodeResults formats the value of the edgeapi.ProbeResultsKey annotation.
+func EncodeResults(results []metav1.Condition) string {
+	encoded, _ := json.Marshal(results)
+	return string(encoded)
+}
+
+// DecodeResults parses the value of the edgeapi.ProbeResultsKey annotation.
+// A malformed value is treated as no results.
+func DecodeResults(value string) []metav1.Condition {
+	var results []metav1.Condition
+	if json.Unmarshal([]byte(value), &results) != nil {
+		return nil
+	}
+	return results
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localprobes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+type fakeExecutor struct {
+	calls     int
+	namespace string
+	selector  string
+	err       error
+}
+
+func (fe *fakeExecutor) Exec(ctx context.Context, namespace string, selector labels.Selector, container string, command []string) error {
+	fe.calls++
+	fe.namespace, fe.selector = namespace, selector.String()
+	return fe.err
+}
+
+func TestHTTPGetAndCaching(t *testing.T) {
+	status := http.StatusOK
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	clk := clocktesting.NewFakePassiveClock(time.Unix(1000, 0))
+	rnr := NewRunner(clk, time.Minute, nil)
+	probes := []edgeapi.LocalProbe{{Name: "Web", HTTPGet: &edgeapi.HTTPGetProbe{URL: server.URL + "/healthz"}}}
+	ctx := context.Background()
+	conds := rnr.Conditions(ctx, "k", "ns", probes, nil)
+	if len(conds) != 1 || conds[0].Status != metav1.ConditionTrue || conds[0].Reason != ReasonSucceeded {
+		t.Fatalf("Unexpected conditions %#v", conds)
+	}
+	firstTransition := conds[0].LastTransitionTime
+	status = http.StatusServiceUnavailable
+	clk.SetTime(clk.Now().Add(30 * time.Second))
+	conds = rnr.Conditions(ctx, "k", "ns", probes, conds)
+	if hits != 1 || conds[0].Status != metav1.ConditionTrue || conds[0].LastTransitionTime != firstTransition {
+		t.Fatalf("Expected a reused result, got hits=%d conditions=%#v", hits, conds)
+	}
+	clk.SetTime(clk.Now().Add(time.Minute))
+	conds = rnr.Conditions(ctx, "k", "ns", probes, conds)
+	if hits != 2 || conds[0].Status != metav1.ConditionFalse || conds[0].Reason != ReasonFailed {
+		t.Fatalf("Expected a fresh failure, got hits=%d conditions=%#v", hits, conds)
+	}
+	if !conds[0].LastTransitionTime.Time.Equal(clk.Now()) {
+		t.Errorf("Expected LastTransitionTime %v, got %v", clk.Now(), conds[0].LastTransitionTime)
+	}
+	rnr.Forget("k")
+	rnr.Conditions(ctx, "k", "ns", probes, conds)
+	if hits != 3 {
+		t.Errorf("Expected Forget to drop the cached result, got hits=%d", hits)
+	}
+}
+
+func TestExecProbe(t *testing.T) {
+	executor := &fakeExecutor{}
+	rnr := NewRunner(clocktesting.NewFakePassiveClock(time.Unix(1000, 0)), time.Minute, executor)
+	probes := []edgeapi.LocalProbe{
+		{Name: "Ping", Exec: &edgeapi.ExecProbe{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			Command:     []string{"pg_isready"},
+		}},
+	}
+	conds := rnr.Conditions(context.Background(), "k1", "ns1", probes, nil)
+	if conds[0].Status != metav1.ConditionTrue || executor.namespace != "ns1" || executor.selector != "app=db" {
+		t.Fatalf("Unexpected result %#v from %#v", conds, executor)
+	}
+	executor.err = errors.New("command terminated with exit code 1")
+	conds = rnr.Conditions(context.Background(), "k2", "ns1", probes, nil)
+	if conds[0].Status != metav1.ConditionFalse || conds[0].Message != executor.err.Error() {
+		t.Fatalf("Unexpected result %#v", conds)
+	}
+	conds = NewRunner(clocktesting.NewFakePassiveClock(time.Unix(1000, 0)), time.Minute, nil).Conditions(context.Background(), "k", "ns1", probes, nil)
+	if conds[0].Status != metav1.ConditionFalse {
+		t.Errorf("Expected failure without an executor, got %#v", conds)
+	}
+}
+
+func TestPromQLProbe(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		response string
+		expected metav1.ConditionStatus
+	}{
+		{"vector", `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"1"]},{"metric":{},"value":[1,"3"]}]}}`, metav1.ConditionTrue},
+		{"zero", `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"1"]},{"metric":{},"value":[1,"0"]}]}}`, metav1.ConditionFalse},
+		{"empty", `{"status":"success","data":{"resultType":"vector","result":[]}}`, metav1.ConditionFalse},
+		{"scalar", `{"status":"success","data":{"resultType":"scalar","result":[1,"2"]}}`, metav1.ConditionTrue},
+		{"matrix", `{"status":"success","data":{"resultType":"matrix","result":[]}}`, metav1.ConditionFalse},
+		{"error", `{"status":"error","error":"parse error"}`, metav1.ConditionFalse},
+		{"garbage", `not json`, metav1.ConditionFalse},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query().Get("query")
+				fmt.Fprint(w, tc.response)
+			}))
+			defer server.Close()
+			rnr := NewRunner(clocktesting.NewFakePassiveClock(time.Unix(1000, 0)), time.Minute, nil)
+			probes := []edgeapi.LocalProbe{{Name: "Up", PromQL: &edgeapi.PromQLProbe{URL: server.URL, Query: `up{job="app"}`}}}
+			conds := rnr.Conditions(context.Background(), "k", "ns", probes, nil)
+			if conds[0].Status != tc.expected {
+				t.Errorf("Expected status %s, got %#v", tc.expected, conds[0])
+			}
+			if query != `up{job="app"}` {
+				t.Errorf("Expected the query to arrive intact, got %q", query)
+			}
+		})
+	}
+}
+
+func TestInvalidProbes(t *testing.T) {
+	rnr := NewRunner(clocktesting.NewFakePassiveClock(time.Unix(1000, 0)), time.Minute, &fakeExecutor{})
+	probes := []edgeapi.LocalProbe{
+		{Name: "Z"},
+		{Name: "A", HTTPGet: &edgeapi.HTTPGetProbe{URL: "http://x"}, PromQL: &edgeapi.PromQLProbe{URL: "http://y", Query: "up"}},
+	}
+	conds := rnr.Conditions(context.Background(), "k", "ns", probes, nil)
+	if len(conds) != 2 || conds[0].Type != "A" || conds[1].Type != "Z" {
+		t.Fatalf("Expected conditions sorted by type, got %#v", conds)
+	}
+	for _, cond := range conds {
+		if cond.Status != metav1.ConditionUnknown || cond.Reason != ReasonInvalidProbe {
+			t.Errorf("Expected an invalid probe, got %#v", cond)
+		}
+	}
+}
+
+func TestResultsRoundTrip(t *testing.T) {
+	results := []metav1.Condition{{Type: "Web", Status: metav1.ConditionTrue, Reason: ReasonSucceeded, LastTransitionTime: metav1.Unix(1000, 0)}}
+	decoded := DecodeResults(EncodeResults(results))
+	if len(decoded) != 1 || decoded[0].Type != "Web" || !decoded[0].LastTransitionTime.Equal(&results[0].LastTransitionTime) {
+		t.Errorf("Round trip changed %#v into %#v", results, decoded)
+	}
+	if DecodeResults("{") != nil {
+		t.Error("Expected nil for a malformed value")
+	}
+	if _, err := DecodeProbes("["); err == nil {
+		t.Error("Expected an error for malformed probes")
+	}
+}
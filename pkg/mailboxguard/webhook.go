@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mailboxguard keeps people from changing, by hand, the copies
+// that the placement translator maintains in mailbox spaces. Such an
+// edit is either undone by the translator at some arbitrary later time
+// or, worse, lives on unnoticed while the workload description says
+// something else.
+//
+// The Webhook is a validating admission webhook for mailbox spaces.
+// It judges updates and deletions of the objects that carry the
+// placement translator's projected label, and lets through only those
+// made by the configured users and groups (which should include the
+// placement translator and the syncers) and those that change nothing
+// but status and bookkeeping metadata. A copy under a break-glass
+// override (see package breakglass) may be changed by anyone, with a
+// warning that says until when, and so may a copy that is scheduled for
+// deletion be rescued (see package softdelete).
+package mailboxguard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/breakglass"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+	"github.com/kubestellar/kubestellar/pkg/placement"
+	"github.com/kubestellar/kubestellar/pkg/softdelete"
+)
+
+// Mode says what the webhook does about a manual edit.
+type Mode string
+
+const (
+	// ModeEnforce rejects the edit.
+	ModeEnforce Mode = "enforce"
+
+	// ModeWarn admits the edit and returns a warning to its author.
+	ModeWarn Mode = "warn"
+)
+
+// Path is where the Webhook is served.
+const Path = "/validate-mailbox-object"
+
+// Config configures a Webhook.
+type Config struct {
+	Mode Mode
+
+	// AllowedUsers and AllowedGroups may change the copies freely.
+	AllowedUsers  []string
+	AllowedGroups []string
+
+	// MaxBreakGlassWindow bounds how far in the future a break-glass
+	// override may end; zero means that such overrides are rejected.
+	MaxBreakGlassWindow time.Duration
+}
+
+// Webhook is a validating admission webhook for mailbox spaces.
+type Webhook struct {
+	logger        klog.Logger
+	mode          Mode
+	allowedUsers  sets.String
+	allowedGroups sets.String
+	maxBreakGlass time.Duration
+}
+
+// NewWebhook makes a Webhook.
+func NewWebhook(logger klog.Logger, config Config) (*Webhook, error) {
+	if config.Mode != ModeEnforce && config.Mode != ModeWarn {
+		return nil, fmt.Errorf("mode must be %q or %q, not %q", ModeEnforce, ModeWarn, config.Mode)
+	}
+	return &Webhook{logger: logger, mode: config.Mode,
+		allowedUsers:  sets.NewString(config.AllowedUsers...),
+		allowedGroups: sets.NewString(config.AllowedGroups...),
+		maxBreakGlass: config.MaxBreakGlassWindow,
+	}, nil
+}
+
+// IsManaged tells whether the given object is a copy maintained by the placement translator.
+func IsManaged(obj metav1.Object) bool {
+	return obj.GetLabels()[placement.ProjectedLabelKey] == placement.ProjectedLabelVal
+}
+
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "request body is not an AdmissionReview", http.StatusBadRequest)
+		return
+	}
+	review.Response = wh.admit(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&review); err != nil {
+		wh.logger.V(3).Info("Failed to write response", "err", err)
+	}
+}
+
+func (wh *Webhook) admit(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	logger := wh.logger.WithValues("kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name, "user", req.UserInfo.Username, "operation", req.Operation)
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Operation != admissionv1.Update && req.Operation != admissionv1.Delete || req.SubResource == "status" {
+		return allowed
+	}
+	if len(req.OldObject.Raw) == 0 {
+		// Deletions in apiservers older than 1.16 do not carry the object.
+		return allowed
+	}
+	oldObj := &unstructured.Unstructured{}
+	if err := oldObj.UnmarshalJSON(req.OldObject.Raw); err != nil {
+		return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, fmt.Sprintf("failed to parse oldObject: %v", err))
+	}
+	if !IsManaged(oldObj) {
+		return allowed
+	}
+	if wh.allowedUsers.Has(req.UserInfo.Username) || wh.allowedGroups.HasAny(req.UserInfo.Groups...) {
+		return allowed
+	}
+	var newObj *unstructured.Unstructured
+	if req.Operation == admissionv1.Update {
+		newObj = &unstructured.Unstructured{}
+		if err := newObj.UnmarshalJSON(req.Object.Raw); err != nil {
+			return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, fmt.Sprintf("failed to parse object: %v", err))
+		}
+		if apiequality.Semantic.DeepEqual(essence(oldObj), essence(newObj)) {
+			return allowed
+		}
+	}
+	if isRescue(oldObj, newObj) {
+		logger.Info("Rescue of a copy scheduled for deletion", "rescued", newObj.GetAnnotations()[softdelete.RescuedAnnotationKey])
+		allowed.Warnings = []string{"this object is maintained by KubeStellar, which now leaves it alone, here and in its edge cluster, until it is deleted or its placement selects it again"}
+		return allowed
+	}
+	if override, err := wh.breakGlass(oldObj, newObj); err != nil {
+		return denied(http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, err.Error())
+	} else if override != nil {
+		logger.Info("Break-glass edit of a managed copy", "until", override.Until, "reason", override.Reason)
+		allowed.Warnings = []string{fmt.Sprintf("this object is maintained by KubeStellar, which leaves it alone until %s because of a break-glass override; after that it will be written back from its source",
+			override.Until.UTC().Format(time.RFC3339))}
+		return allowed
+	}
+	message := "this object is maintained by KubeStellar"
+	if owner, err := ownership.GetOwner(oldObj); err == nil && owner != nil {
+		message += fmt.Sprintf(" as a copy of %s %s/%s in space %s; change that one instead", owner.Resource, owner.Namespace, owner.Name, owner.Space)
+	} else {
+		message += "; change its source in the workload description space instead"
+	}
+	logger.V(2).Info("Manual edit of a managed copy", "mode", wh.mode)
+	if wh.mode == ModeWarn {
+		allowed.Warnings = []string{message + ", because KubeStellar may undo this change at any time"}
+		return allowed
+	}
+	return denied(http.StatusForbidden, metav1.StatusReasonForbidden, message)
+}
+
+// breakGlass returns the break-glass override in force on the old or
+// the new version of a copy (nil for a deletion), if any. An invalid
+// override on the new version is an error.
+func (wh *Webhook) breakGlass(oldObj, newObj *unstructured.Unstructured) (*breakglass.Override, error) {
+	if wh.maxBreakGlass == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	if newObj != nil {
+		override, err := breakglass.Get(newObj, now, wh.maxBreakGlass)
+		if err != nil || override.InForce(now) {
+			return override, err
+		}
+	}
+	// The old version was admitted, but may have ended or been invalid all along.
+	override, err := breakglass.Get(oldObj, now, wh.maxBreakGlass)
+	if err != nil || !override.InForce(now) {
+		return nil, nil
+	}
+	return override, nil
+}
+
+// isRescue tells whether the given update does nothing but rescue a copy
+// that is scheduled for deletion (see package softdelete).
+func isRescue(oldObj, newObj *unstructured.Unstructured) bool {
+	if newObj == nil || !softdelete.Rescued(newObj) {
+		return false
+	}
+	if _, scheduled, err := softdelete.ScheduledAt(oldObj); err != nil || !scheduled {
+		return false
+	}
+	unrescued := newObj.DeepCopy()
+	annotations := unrescued.GetAnnotations()
+	delete(annotations, softdelete.RescuedAnnotationKey)
+	unrescued.SetAnnotations(annotations)
+	return apiequality.Semantic.DeepEqual(essence(oldObj), essence(unrescued))
+}
+
+// essence returns the content of the given object without status and
+// without the metadata that apiservers and other controllers maintain.
+func essence(obj *unstructured.Unstructured) map[string]any {
+	ans := obj.DeepCopy()
+	delete(ans.Object, "status")
+	for _, field := range []string{"resourceVersion", "generation", "managedFields", "finalizers", "ownerReferences",
+		"uid", "selfLink", "creationTimestamp", "deletionTimestamp", "deletionGracePeriodSeconds"} {
+		unstructured.RemoveNestedField(ans.Object, "metadata", field)
+	}
+	return ans.Object
+}
+
+func denied(code int32, reason metav1.StatusReason, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Result: &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    code,
+		Reason:  reason,
+		Message: message,
+	}}
+}
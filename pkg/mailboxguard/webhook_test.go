@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mailboxguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/breakglass"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+	"github.com/kubestellar/kubestellar/pkg/placement"
+	"github.com/kubestellar/kubestellar/pkg/softdelete"
+)
+
+func newConfigMap(managed bool, data string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("shop")
+	obj.SetName("settings")
+	obj.SetResourceVersion("1")
+	if managed {
+		obj.SetLabels(map[string]string{placement.ProjectedLabelKey: placement.ProjectedLabelVal})
+		owner := ownership.OwnerRef{Space: "wds1", Version: "v1", Resource: "configmaps", Namespace: "shop", Name: "settings", UID: "u1"}
+		if err := ownership.SetOwner(obj, owner); err != nil {
+			panic(err)
+		}
+	}
+	unstructured.SetNestedField(obj.Object, data, "data", "color")
+	return obj
+}
+
+func serve(t *testing.T, wh *Webhook, user string, op admissionv1.Operation, subresource string, obj, oldObj *unstructured.Unstructured) *admissionv1.AdmissionResponse {
+	req := &admissionv1.AdmissionRequest{UID: "uid1", Operation: op, SubResource: subresource, Namespace: "shop", Name: "settings",
+		UserInfo: authenticationv1.UserInfo{Username: user, Groups: []string{"system:authenticated"}}}
+	for _, pair := range []struct {
+		obj *unstructured.Unstructured
+		raw *[]byte
+	}{{obj, &req.Object.Raw}, {oldObj, &req.OldObject.Raw}} {
+		if pair.obj == nil {
+			continue
+		}
+		raw, err := pair.obj.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		*pair.raw = raw
+	}
+	body, err := json.Marshal(&admissionv1.AdmissionReview{Request: req})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	wh.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body)))
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &review); err != nil {
+		t.Fatal(err)
+	}
+	if review.Response == nil || review.Response.UID != "uid1" {
+		t.Fatalf("Bad response %+v", review.Response)
+	}
+	return review.Response
+}
+
+func TestWebhook(t *testing.T) {
+	wh, err := NewWebhook(klog.Background(), Config{Mode: ModeEnforce, AllowedUsers: []string{"translator"}, AllowedGroups: []string{"syncers"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := newConfigMap(true, "red")
+	edited := newConfigMap(true, "blue")
+	resp := serve(t, wh, "alice", admissionv1.Update, "", edited, old)
+	if resp.Allowed || resp.Result.Code != http.StatusForbidden || !strings.Contains(resp.Result.Message, "configmaps shop/settings in space wds1") {
+		t.Errorf("Expected a manual edit to be rejected, got %+v", resp)
+	}
+	if resp := serve(t, wh, "alice", admissionv1.Delete, "", nil, old); resp.Allowed {
+		t.Errorf("Expected a manual deletion to be rejected")
+	}
+	if resp := serve(t, wh, "translator", admissionv1.Update, "", edited, old); !resp.Allowed {
+		t.Errorf("Expected the translator's edit to be admitted, got %+v", resp.Result)
+	}
+	if resp := serve(t, wh, "alice", admissionv1.Update, "status", edited, old); !resp.Allowed {
+		t.Errorf("Expected a status update to be admitted, got %+v", resp.Result)
+	}
+	bookkeeping := old.DeepCopy()
+	bookkeeping.SetResourceVersion("2")
+	bookkeeping.SetFinalizers([]string{"example.com/cleanup"})
+	unstructured.SetNestedField(bookkeeping.Object, "Ready", "status", "phase")
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", bookkeeping, old); !resp.Allowed {
+		t.Errorf("Expected a bookkeeping change to be admitted, got %+v", resp.Result)
+	}
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", newConfigMap(false, "blue"), newConfigMap(false, "red")); !resp.Allowed {
+		t.Errorf("Expected an edit of an unmanaged object to be admitted, got %+v", resp.Result)
+	}
+
+	wh.mode = ModeWarn
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", edited, old); !resp.Allowed || len(resp.Warnings) != 1 {
+		t.Errorf("Expected the edit to be admitted with a warning, got %+v", resp)
+	}
+}
+
+func TestBreakGlass(t *testing.T) {
+	wh, err := NewWebhook(klog.Background(), Config{Mode: ModeEnforce, MaxBreakGlassWindow: breakglass.DefaultMaxWindow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withOverride := func(obj *unstructured.Unstructured, until time.Time) *unstructured.Unstructured {
+		obj.SetAnnotations(map[string]string{breakglass.UntilAnnotationKey: until.UTC().Format(time.RFC3339), breakglass.ReasonAnnotationKey: "hotfix"})
+		return obj
+	}
+	old := newConfigMap(true, "red")
+	soon := time.Now().Add(time.Hour)
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", withOverride(newConfigMap(true, "blue"), soon), old); !resp.Allowed || len(resp.Warnings) != 1 {
+		t.Errorf("Expected the break-glass edit to be admitted with a warning, got %+v", resp)
+	}
+	underOverride := withOverride(newConfigMap(true, "blue"), soon)
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", newConfigMap(true, "green"), underOverride); !resp.Allowed {
+		t.Errorf("Expected an edit that ends the override to be admitted, got %+v", resp.Result)
+	}
+	if resp := serve(t, wh, "alice", admissionv1.Delete, "", nil, underOverride); !resp.Allowed {
+		t.Errorf("Expected a deletion under override to be admitted, got %+v", resp.Result)
+	}
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", withOverride(newConfigMap(true, "blue"), time.Now().Add(-time.Minute)), old); resp.Allowed {
+		t.Errorf("Expected an edit with an ended override to be rejected")
+	}
+	tooLong := withOverride(newConfigMap(true, "blue"), time.Now().Add(breakglass.DefaultMaxWindow+time.Hour))
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", tooLong, old); resp.Allowed || resp.Result.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected an override that is too long to be rejected as invalid, got %+v", resp)
+	}
+	wh.maxBreakGlass = 0
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", withOverride(newConfigMap(true, "blue"), soon), old); resp.Allowed {
+		t.Errorf("Expected the break-glass edit to be rejected when overrides are disabled")
+	}
+}
+
+func TestRescue(t *testing.T) {
+	wh, err := NewWebhook(klog.Background(), Config{Mode: ModeEnforce})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduled := newConfigMap(true, "red")
+	softdelete.Schedule(scheduled, time.Now().Add(time.Hour))
+	rescued := scheduled.DeepCopy()
+	softdelete.Rescue(rescued, "by alice")
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", rescued, scheduled); !resp.Allowed || len(resp.Warnings) != 1 {
+		t.Errorf("Expected the rescue to be admitted with a warning, got %+v", resp)
+	}
+	rescuedAndEdited := newConfigMap(true, "blue")
+	rescuedAndEdited.SetAnnotations(rescued.GetAnnotations())
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", rescuedAndEdited, scheduled); resp.Allowed {
+		t.Errorf("Expected a rescue that also changes the content to be rejected")
+	}
+	notScheduled := newConfigMap(true, "red")
+	rescuedAnyway := notScheduled.DeepCopy()
+	softdelete.Rescue(rescuedAnyway, "by alice")
+	if resp := serve(t, wh, "alice", admissionv1.Update, "", rescuedAnyway, notScheduled); resp.Allowed {
+		t.Errorf("Expected the rescue of a copy that is not scheduled for deletion to be rejected")
+	}
+}
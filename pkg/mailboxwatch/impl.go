@@ -20,7 +20,6 @@ import (
 	"context"
 	"fmt"
 	"strconv"
-	"strings"
 	"sync"
 
 	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -37,7 +36,7 @@ import (
 	tenancyv1a1informers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
 	"github.com/kcp-dev/logicalcluster/v3"
 
-	"github.com/kubestellar/kubestellar/pkg/placement"
+	"github.com/kubestellar/kubestellar/pkg/naming"
 )
 
 func newCrossClusterListerWatcher[Scoped ScopedListerWatcher[ListType], ListType runtime.Object](
@@ -96,8 +95,7 @@ func (clw *crossClusterListerWatcher[Scoped, ListType]) OnDelete(obj any) {
 func (clw *crossClusterListerWatcher[Scoped, ListType]) setInclusion(obj any, include bool) {
 	ws := obj.(*tenancyv1a1.Workspace)
 	mbwsName := ws.Name
-	mbwsNameParts := strings.Split(mbwsName, placement.MBspaceNameSep)
-	if len(mbwsNameParts) != 2 {
+	if !naming.IsMailboxSpaceName(mbwsName) {
 		// Only accept the workspace if its name looks like a mailbox workspace name
 		include = false
 	}
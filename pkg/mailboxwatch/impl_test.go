@@ -44,7 +44,7 @@ import (
 	edgefakeclient "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/cluster/fake"
 	edgeclusterclient "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/cluster/typed/edge/v2alpha1"
 	edgescopedclient "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/typed/edge/v2alpha1"
-	"github.com/kubestellar/kubestellar/pkg/placement"
+	"github.com/kubestellar/kubestellar/pkg/naming"
 )
 
 var _ edgeapi.SyncerConfig
@@ -246,7 +246,7 @@ func TestMailboxInformer(t *testing.T) {
 				invWSClusterS := fmt.Sprintf("ic%d", invWSNum)
 				syncTargetNum := rand.Intn(int(math.Sqrt(float64(iteration))))
 				syncTargetUID := fmt.Sprintf("beef-%d", syncTargetNum)
-				mbwsName := invWSClusterS + placement.MBspaceNameSep + syncTargetUID
+				mbwsName := naming.MailboxSpaceName(invWSClusterS, syncTargetUID)
 				mbwsNum := invWSNum*100 + syncTargetNum
 				mbwsClusterS := fmt.Sprintf("mc%d", mbwsNum)
 				mbwsClusterN := logicalcluster.Name(mbwsClusterS)
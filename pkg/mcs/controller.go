@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mcs
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+)
+
+// Controller feeds the Services in the mailbox spaces, as delivered to
+// its event handler methods, into an Aggregator and has a Hub follow that.
+type Controller struct {
+	context    context.Context
+	aggregator *Aggregator
+	hub        *Hub
+	selector   labels.Selector
+
+	// destinationOf maps the logical cluster of a mailbox space to the
+	// name of its destination.
+	destinationOf func(logicalcluster.Name) string
+
+	queue workqueue.RateLimitingInterface
+}
+
+// NewController returns a Controller that considers the Services that
+// match the given selector.
+func NewController(ctx context.Context, aggregator *Aggregator, hub *Hub, selector labels.Selector, destinationOf func(logicalcluster.Name) string) *Controller {
+	return &Controller{
+		context:       ctx,
+		aggregator:    aggregator,
+		hub:           hub,
+		selector:      selector,
+		destinationOf: destinationOf,
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "mcs-controller"),
+	}
+}
+
+// Run animates the controller, finishing and returning when its context is done.
+// Call this after the informers have been started.
+func (ctl *Controller) Run(concurrency int, hasSynced ...cache.InformerSynced) {
+	ctx := ctl.context
+	logger := klog.FromContext(ctx)
+	doneCh := ctx.Done()
+	defer ctl.queue.ShutDown()
+	if !cache.WaitForNamedCacheSync("mcs-controller", doneCh, hasSynced...) {
+		logger.Error(nil, "Informer syncs not achieved")
+		return
+	}
+	logger.V(1).Info("Informers synced")
+	for worker := 0; worker < concurrency; worker++ {
+		go ctl.syncLoop(ctx, worker)
+	}
+	<-doneCh
+}
+
+func (ctl *Controller) OnAdd(obj any) {
+	ctl.observe(obj, false)
+}
+
+func (ctl *Controller) OnUpdate(oldObj, newObj any) {
+	ctl.observe(newObj, false)
+}
+
+func (ctl *Controller) OnDelete(obj any) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ctl.observe(obj, true)
+}
+
+func (ctl *Controller) observe(obj any, deleted bool) {
+	logger := klog.FromContext(ctl.context)
+	objU, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		logger.Error(nil, "Notified of object of unexpected type", "object", obj, "type", fmt.Sprintf("%T", obj))
+		return
+	}
+	svc := &corev1.Service{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(objU.Object, svc); err != nil {
+		logger.Error(err, "Failed to convert to Service", "namespace", objU.GetNamespace(), "name", objU.GetName())
+		return
+	}
+	destination := ctl.destinationOf(logicalcluster.From(objU))
+	key := types.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}
+	var changed bool
+	if deleted || !ctl.selector.Matches(labels.Set(svc.Labels)) {
+		changed = ctl.aggregator.Remove(destination, key)
+	} else {
+		changed = ctl.aggregator.Set(destination, svc)
+	}
+	if changed {
+		logger.V(4).Info("Service exposure changed", "service", key.String(), "destination", destination)
+		ctl.queue.Add(key)
+	}
+}
+
+func (ctl *Controller) syncLoop(ctx context.Context, worker int) {
+	logger := klog.FromContext(ctx).WithValues("worker", worker)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(4).Info("SyncLoop start")
+	for {
+		ref, shutdown := ctl.queue.Get()
+		if shutdown {
+			logger.V(2).Info("Queue shutdown")
+			return
+		}
+		key := ref.(types.NamespacedName)
+		var exposure *ServiceExposure
+		if have, ok := ctl.aggregator.Get(key); ok {
+			exposure = &have
+		}
+		if ctl.hub.Reconcile(ctx, logger, key, exposure) {
+			ctl.queue.AddRateLimited(ref)
+		} else {
+			ctl.queue.Forget(ref)
+		}
+		ctl.queue.Done(ref)
+	}
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mcs
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+const (
+	// ManagedBy is the value of the managed-by labels of the generated objects.
+	ManagedBy = "kubestellar-mcs-controller"
+
+	// DestinationLabelKey labels an EndpointSlice with the destination whose addresses it holds.
+	DestinationLabelKey = "edge.kubestellar.io/destination"
+
+	// DestinationsAnnotationKey annotates a hub-side Service with the
+	// comma-separated list of destinations that expose it.
+	DestinationsAnnotationKey = "edge.kubestellar.io/destinations"
+
+	// SourceAnnotationKey annotates a hub-side Service with the namespace/name
+	// of the Service it stands for.
+	SourceAnnotationKey = "edge.kubestellar.io/source-service"
+
+	fieldManager = "kubestellar"
+)
+
+// HTTPRouteGVR identifies the Gateway API HTTPRoutes that a Hub programs.
+var HTTPRouteGVR = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1beta1", Resource: "httproutes"}
+
+// Hub maintains the hub-side objects for exposed Services.
+type Hub struct {
+	Client kubernetes.Interface
+
+	// Namespace is where the hub-side objects go.
+	Namespace string
+
+	// Gateway, if not nil, is the Gateway to which an HTTPRoute is
+	// attached for each exposed Service. Dynamic must then be set.
+	Gateway *types.NamespacedName
+	Dynamic dynamic.Interface
+}
+
+// DesiredService returns the hub-side Service for the given exposure.
+// It has no selector; its endpoints are given by DesiredEndpointSlices.
+func (hub *Hub) DesiredService(exposure ServiceExposure) *corev1.Service {
+	destinations := make([]string, 0, len(exposure.Destinations))
+	ports := map[string]corev1.ServicePort{}
+	for _, dest := range exposure.Destinations {
+		destinations = append(destinations, dest.Destination)
+		for _, port := range dest.Ports {
+			if _, have := ports[port.Name]; !have {
+				ports[port.Name] = port
+			}
+		}
+	}
+	svc := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: hub.Namespace,
+			Name:      naming.HubServiceName(exposure.Namespace, exposure.Name),
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": ManagedBy},
+			Annotations: map[string]string{
+				SourceAnnotationKey:       exposure.Namespace + "/" + exposure.Name,
+				DestinationsAnnotationKey: strings.Join(destinations, ","),
+			},
+		},
+	}
+	for _, port := range ports {
+		// Spell out what the server would default, so that comparisons work
+		port.TargetPort = intstr.FromInt(int(port.Port))
+		svc.Spec.Ports = append(svc.Spec.Ports, port)
+	}
+	sort.Slice(svc.Spec.Ports, func(i, j int) bool { return svc.Spec.Ports[i].Port < svc.Spec.Ports[j].Port })
+	return svc
+}
+
+// DesiredEndpointSlices returns the EndpointSlices of the hub-side Service
+// for the given exposure: one per destination and address type.
+func (hub *Hub) DesiredEndpointSlices(exposure ServiceExposure) []*discoveryv1.EndpointSlice {
+	svcName := naming.HubServiceName(exposure.Namespace, exposure.Name)
+	ready := true
+	ans := []*discoveryv1.EndpointSlice{}
+	for _, dest := range exposure.Destinations {
+		byType := map[discoveryv1.AddressType][]discoveryv1.Endpoint{}
+		for _, address := range dest.Addresses {
+			addrType := AddressType(address)
+			byType[addrType] = append(byType[addrType], discoveryv1.Endpoint{
+				Addresses:  []string{address},
+				Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+			})
+		}
+		ports := make([]discoveryv1.EndpointPort, 0, len(dest.Ports))
+		for idx := range dest.Ports {
+			port := &dest.Ports[idx]
+			ports = append(ports, discoveryv1.EndpointPort{Name: &port.Name, Protocol: &port.Protocol, Port: &port.Port, AppProtocol: port.AppProtocol})
+		}
+		for _, addrType := range []discoveryv1.AddressType{discoveryv1.AddressTypeIPv4, discoveryv1.AddressTypeIPv6, discoveryv1.AddressTypeFQDN} {
+			endpoints := byType[addrType]
+			if len(endpoints) == 0 {
+				continue
+			}
+			ans = append(ans, &discoveryv1.EndpointSlice{
+				TypeMeta: metav1.TypeMeta{APIVersion: discoveryv1.SchemeGroupVersion.String(), Kind: "EndpointSlice"},
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: hub.Namespace,
+					Name:      naming.HubEndpointSliceName(svcName, dest.Destination, string(addrType)),
+					Labels: map[string]string{
+						discoveryv1.LabelServiceName: svcName,
+						discoveryv1.LabelManagedBy:   ManagedBy,
+						DestinationLabelKey:          naming.LabelValue(dest.Destination),
+					},
+				},
+				AddressType: addrType,
+				Endpoints:   endpoints,
+				Ports:       ports,
+			})
+		}
+	}
+	return ans
+}
+
+// DesiredHTTPRoute returns the HTTPRoute from the Gateway to the hub-side
+// Service for the given exposure; nil if there is no Gateway or no TCP port.
+func (hub *Hub) DesiredHTTPRoute(exposure ServiceExposure) *unstructured.Unstructured {
+	if hub.Gateway == nil {
+		return nil
+	}
+	svc := hub.DesiredService(exposure)
+	var backendPort *corev1.ServicePort
+	for idx, port := range svc.Spec.Ports {
+		if port.Protocol == corev1.ProtocolTCP {
+			backendPort = &svc.Spec.Ports[idx]
+			break
+		}
+	}
+	if backendPort == nil {
+		return nil
+	}
+	// The defaults are spelled out, so that comparisons work
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"parentRefs": []interface{}{map[string]interface{}{
+				"group":     HTTPRouteGVR.Group,
+				"kind":      "Gateway",
+				"namespace": hub.Gateway.Namespace,
+				"name":      hub.Gateway.Name,
+			}},
+			"rules": []interface{}{map[string]interface{}{
+				"matches": []interface{}{map[string]interface{}{
+					"path": map[string]interface{}{"type": "PathPrefix", "value": "/"},
+				}},
+				"backendRefs": []interface{}{map[string]interface{}{
+					"group":  "",
+					"kind":   "Service",
+					"name":   svc.Name,
+					"port":   int64(backendPort.Port),
+					"weight": int64(1),
+				}},
+			}},
+		},
+	}}
+	route.SetAPIVersion(HTTPRouteGVR.GroupVersion().String())
+	route.SetKind("HTTPRoute")
+	route.SetNamespace(hub.Namespace)
+	route.SetName(svc.Name)
+	route.SetLabels(svc.Labels)
+	return route
+}
+
+// Reconcile makes the hub-side objects for the given Service match the
+// given exposure, which is nil if the Service is exposed nowhere.
+// The EndpointSlices and HTTPRoute are owned by the hub-side Service, so
+// deleting that takes them along. Returns whether to retry.
+func (hub *Hub) Reconcile(ctx context.Context, logger klog.Logger, key types.NamespacedName, exposure *ServiceExposure) bool {
+	svcName := naming.HubServiceName(key.Namespace, key.Name)
+	logger = logger.WithValues("service", key.String(), "hubService", svcName)
+	services := hub.Client.CoreV1().Services(hub.Namespace)
+	if exposure == nil || len(exposure.Destinations) == 0 {
+		err := services.Delete(ctx, svcName, metav1.DeleteOptions{})
+		if err != nil && !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete hub-side Service")
+			return true
+		}
+		logger.V(2).Info("Deleted hub-side Service, if it existed")
+		return false
+	}
+	want := hub.DesiredService(*exposure)
+	have, err := services.Get(ctx, svcName, metav1.GetOptions{})
+	switch {
+	case k8sapierrors.IsNotFound(err):
+		have, err = services.Create(ctx, want, metav1.CreateOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error(err, "Failed to create hub-side Service")
+			return true
+		}
+		logger.V(2).Info("Created hub-side Service")
+	case err != nil:
+		logger.Error(err, "Failed to get hub-side Service")
+		return true
+	case have.Annotations[SourceAnnotationKey] != want.Annotations[SourceAnnotationKey]:
+		logger.Error(nil, "Hub-side Service name is taken by another Service", "otherSource", have.Annotations[SourceAnnotationKey])
+		return false
+	case !apiequality.Semantic.DeepEqual(have.Spec.Ports, want.Spec.Ports) || !apiequality.Semantic.DeepEqual(have.Annotations, want.Annotations):
+		have = have.DeepCopy()
+		have.Spec.Ports = want.Spec.Ports
+		have.Annotations = want.Annotations
+		have, err = services.Update(ctx, have, metav1.UpdateOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error(err, "Failed to update hub-side Service")
+			return true
+		}
+		logger.V(2).Info("Updated hub-side Service")
+	}
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "Service", Name: have.Name, UID: have.UID}
+	retry := hub.reconcileEndpointSlices(ctx, logger, svcName, owner, hub.DesiredEndpointSlices(*exposure))
+	if route := hub.DesiredHTTPRoute(*exposure); route != nil {
+		route.SetOwnerReferences([]metav1.OwnerReference{owner})
+		retry = hub.reconcileHTTPRoute(ctx, logger, route) || retry
+	}
+	return retry
+}
+
+func (hub *Hub) reconcileEndpointSlices(ctx context.Context, logger klog.Logger, svcName string, owner metav1.OwnerReference, slices []*discoveryv1.EndpointSlice) bool {
+	client := hub.Client.DiscoveryV1().EndpointSlices(hub.Namespace)
+	desired := map[string]*discoveryv1.EndpointSlice{}
+	for _, slice := range slices {
+		slice.OwnerReferences = []metav1.OwnerReference{owner}
+		desired[slice.Name] = slice
+	}
+	selector := labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: svcName, discoveryv1.LabelManagedBy: ManagedBy})
+	existing, err := client.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logger.Error(err, "Failed to list hub-side EndpointSlices")
+		return true
+	}
+	retry := false
+	for idx := range existing.Items {
+		have := &existing.Items[idx]
+		want, wanted := desired[have.Name]
+		switch {
+		case !wanted || have.AddressType != want.AddressType:
+			err := client.Delete(ctx, have.Name, metav1.DeleteOptions{})
+			if err != nil && !k8sapierrors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete stale EndpointSlice", "name", have.Name)
+				retry = true
+			} else {
+				logger.V(2).Info("Deleted stale EndpointSlice", "name", have.Name)
+			}
+		case !apiequality.Semantic.DeepEqual(have.Endpoints, want.Endpoints) || !apiequality.Semantic.DeepEqual(have.Ports, want.Ports) ||
+			!apiequality.Semantic.DeepEqual(have.Labels, want.Labels):
+			have = have.DeepCopy()
+			have.Endpoints, have.Ports, have.Labels = want.Endpoints, want.Ports, want.Labels
+			if _, err := client.Update(ctx, have, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+				logger.Error(err, "Failed to update EndpointSlice", "name", have.Name)
+				retry = true
+			} else {
+				logger.V(2).Info("Updated EndpointSlice", "name", have.Name)
+			}
+			delete(desired, have.Name)
+		default:
+			delete(desired, have.Name)
+		}
+	}
+	for name, want := range desired {
+		_, err := client.Create(ctx, want, metav1.CreateOptions{FieldManager: fieldManager})
+		if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+			logger.Error(err, "Failed to create EndpointSlice", "name", name)
+			retry = true
+		} else {
+			logger.V(2).Info("Created EndpointSlice", "name", name)
+		}
+	}
+	return retry
+}
+
+func (hub *Hub) reconcileHTTPRoute(ctx context.Context, logger klog.Logger, want *unstructured.Unstructured) bool {
+	client := hub.Dynamic.Resource(HTTPRouteGVR).Namespace(hub.Namespace)
+	have, err := client.Get(ctx, want.GetName(), metav1.GetOptions{})
+	switch {
+	case k8sapierrors.IsNotFound(err):
+		if _, err := client.Create(ctx, want, metav1.CreateOptions{FieldManager: fieldManager}); err != nil {
+			logger.Error(err, "Failed to create HTTPRoute")
+			return true
+		}
+		logger.V(2).Info("Created HTTPRoute")
+	case err != nil:
+		logger.Error(err, "Failed to get HTTPRoute")
+		return true
+	case !apiequality.Semantic.DeepEqual(have.Object["spec"], want.Object["spec"]):
+		have = have.DeepCopy()
+		have.Object["spec"] = want.Object["spec"]
+		if _, err := client.Update(ctx, have, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+			logger.Error(err, "Failed to update HTTPRoute")
+			return true
+		}
+		logger.V(2).Info("Updated HTTPRoute")
+	}
+	return false
+}
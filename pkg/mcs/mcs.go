@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mcs keeps track, on the hub, of where the Services that
+// placements downsync are exposed at the edge.
+//
+// The copy of a Service in a mailbox space gets the status of the
+// Service in the WEC back from the syncer. An Aggregator collects, for
+// each Service, the externally reachable addresses (load balancer
+// ingress and external IPs) reported by each destination. A Hub then
+// maintains, in a namespace of a hub cluster, a selector-less Service
+// for each exposed Service, with one EndpointSlice per destination.
+// Those answer "which edges expose this service" with ordinary
+// Kubernetes objects and give hub-side clients a way to reach them.
+// Optionally the Hub also programs a Gateway API HTTPRoute from a
+// given Gateway to each such Service.
+package mcs
+
+import (
+	"net"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DestinationEndpoints is where one destination exposes a Service.
+type DestinationEndpoints struct {
+	// Destination identifies the destination, normally by SyncTarget name.
+	Destination string `json:"destination"`
+
+	// Addresses are the IP addresses and host names at which the Service
+	// is reachable from outside the WEC, sorted.
+	Addresses []string `json:"addresses"`
+
+	Ports []corev1.ServicePort `json:"ports,omitempty"`
+}
+
+// ServiceExposure is where a Service is exposed across the destinations.
+type ServiceExposure struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+
+	// Destinations are sorted by Destination.
+	Destinations []DestinationEndpoints `json:"destinations"`
+}
+
+// EndpointsOf returns where the given Service, as reported from the given
+// destination, is exposed; false if it is not reachable from outside.
+func EndpointsOf(destination string, svc *corev1.Service) (DestinationEndpoints, bool) {
+	addresses := map[string]struct{}{}
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			addresses[ingress.IP] = struct{}{}
+		} else if ingress.Hostname != "" {
+			addresses[ingress.Hostname] = struct{}{}
+		}
+	}
+	for _, ip := range svc.Spec.ExternalIPs {
+		addresses[ip] = struct{}{}
+	}
+	if len(addresses) == 0 {
+		return DestinationEndpoints{}, false
+	}
+	ans := DestinationEndpoints{Destination: destination, Addresses: make([]string, 0, len(addresses))}
+	for address := range addresses {
+		ans.Addresses = append(ans.Addresses, address)
+	}
+	sort.Strings(ans.Addresses)
+	for _, port := range svc.Spec.Ports {
+		// The WEC-local details do not matter from outside
+		port.NodePort = 0
+		port.TargetPort = intstr.IntOrString{}
+		if port.Protocol == "" {
+			port.Protocol = corev1.ProtocolTCP
+		}
+		ans.Ports = append(ans.Ports, port)
+	}
+	return ans, true
+}
+
+// AddressType returns the EndpointSlice address type of the given address.
+func AddressType(address string) discoveryv1.AddressType {
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		return discoveryv1.AddressTypeFQDN
+	case ip.To4() != nil:
+		return discoveryv1.AddressTypeIPv4
+	default:
+		return discoveryv1.AddressTypeIPv6
+	}
+}
+
+// Aggregator collects where Services are exposed, from the reports of
+// the destinations. It is safe for concurrent use.
+type Aggregator struct {
+	mutex    sync.Mutex
+	services map[types.NamespacedName]map[string]DestinationEndpoints
+}
+
+// NewAggregator returns an empty Aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{services: map[types.NamespacedName]map[string]DestinationEndpoints{}}
+}
+
+// Set records the given Service as reported from the given destination.
+// It returns whether that changed where the Service is exposed.
+func (agg *Aggregator) Set(destination string, svc *corev1.Service) bool {
+	key := types.NamespacedName{Namespace: svc.Namespace, Name: svc.Name}
+	endpoints, exposed := EndpointsOf(destination, svc)
+	if !exposed {
+		return agg.Remove(destination, key)
+	}
+	agg.mutex.Lock()
+	defer agg.mutex.Unlock()
+	perDestination := agg.services[key]
+	if perDestination == nil {
+		perDestination = map[string]DestinationEndpoints{}
+		agg.services[key] = perDestination
+	}
+	if have, ok := perDestination[destination]; ok && equality.Semantic.DeepEqual(have, endpoints) {
+		return false
+	}
+	perDestination[destination] = endpoints
+	return true
+}
+
+// Remove forgets the given Service at the given destination.
+// It returns whether that changed where the Service is exposed.
+func (agg *Aggregator) Remove(destination string, key types.NamespacedName) bool {
+	agg.mutex.Lock()
+	defer agg.mutex.Unlock()
+	perDestination := agg.services[key]
+	if _, ok := perDestination[destination]; !ok {
+		return false
+	}
+	delete(perDestination, destination)
+	if len(perDestination) == 0 {
+		delete(agg.services, key)
+	}
+	return true
+}
+
+// Get returns where the given Service is exposed; false if nowhere.
+func (agg *Aggregator) Get(key types.NamespacedName) (ServiceExposure, bool) {
+	agg.mutex.Lock()
+	defer agg.mutex.Unlock()
+	perDestination, ok := agg.services[key]
+	if !ok {
+		return ServiceExposure{}, false
+	}
+	ans := ServiceExposure{Namespace: key.Namespace, Name: key.Name, Destinations: make([]DestinationEndpoints, 0, len(perDestination))}
+	for _, endpoints := range perDestination {
+		ans.Destinations = append(ans.Destinations, endpoints)
+	}
+	sort.Slice(ans.Destinations, func(i, j int) bool { return ans.Destinations[i].Destination < ans.Destinations[j].Destination })
+	return ans, true
+}
+
+// Keys returns the Services that are exposed somewhere, sorted.
+func (agg *Aggregator) Keys() []types.NamespacedName {
+	agg.mutex.Lock()
+	defer agg.mutex.Unlock()
+	ans := make([]types.NamespacedName, 0, len(agg.services))
+	for key := range agg.services {
+		ans = append(ans, key)
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].String() < ans[j].String() })
+	return ans
+}
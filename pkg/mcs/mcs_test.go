@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mcs
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2"
+)
+
+func exposedService(addresses ...string) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 31234}},
+		},
+	}
+	for _, address := range addresses {
+		ingress := corev1.LoadBalancerIngress{IP: address}
+		if AddressType(address) == discoveryv1.AddressTypeFQDN {
+			ingress = corev1.LoadBalancerIngress{Hostname: address}
+		}
+		svc.Status.LoadBalancer.Ingress = append(svc.Status.LoadBalancer.Ingress, ingress)
+	}
+	return svc
+}
+
+func TestAggregator(t *testing.T) {
+	agg := NewAggregator()
+	key := types.NamespacedName{Namespace: "shop", Name: "web"}
+	if agg.Set("edge1", exposedService()) {
+		t.Errorf("A Service without addresses changed the aggregate")
+	}
+	if !agg.Set("edge2", exposedService("10.0.0.2", "lb.example.com")) || !agg.Set("edge1", exposedService("10.0.0.1")) {
+		t.Errorf("Exposed Services did not change the aggregate")
+	}
+	if agg.Set("edge1", exposedService("10.0.0.1")) {
+		t.Errorf("An unchanged Service changed the aggregate")
+	}
+	exposure, ok := agg.Get(key)
+	if !ok || len(exposure.Destinations) != 2 || exposure.Destinations[0].Destination != "edge1" {
+		t.Fatalf("Unexpected exposure %+v", exposure)
+	}
+	if actual, expected := exposure.Destinations[1].Addresses, []string{"10.0.0.2", "lb.example.com"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected addresses %v, got %v", expected, actual)
+	}
+	if port := exposure.Destinations[0].Ports[0]; port.NodePort != 0 || port.Protocol != corev1.ProtocolTCP {
+		t.Errorf("Port was not cleaned up: %+v", port)
+	}
+	if !agg.Remove("edge1", key) || !agg.Set("edge2", exposedService()) {
+		t.Errorf("Removals did not change the aggregate")
+	}
+	if _, ok := agg.Get(key); ok || len(agg.Keys()) != 0 {
+		t.Errorf("Service is still exposed after removals")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	logger := klog.Background()
+	client := fake.NewSimpleClientset()
+	hub := &Hub{Client: client, Namespace: "mcs"}
+	agg := NewAggregator()
+	key := types.NamespacedName{Namespace: "shop", Name: "web"}
+	agg.Set("edge1", exposedService("10.0.0.1", "fd00::1"))
+	agg.Set("edge2", exposedService("lb.example.com"))
+	exposure, _ := agg.Get(key)
+	if hub.Reconcile(ctx, logger, key, &exposure) {
+		t.Fatalf("Reconcile asked to retry")
+	}
+	svc, err := client.CoreV1().Services("mcs").Get(ctx, "shop-web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Hub-side Service not created: %v", err)
+	}
+	if actual, expected := svc.Annotations[DestinationsAnnotationKey], "edge1,edge2"; actual != expected {
+		t.Errorf("Expected destinations %q, got %q", expected, actual)
+	}
+	slices, _ := client.DiscoveryV1().EndpointSlices("mcs").List(ctx, metav1.ListOptions{})
+	if len(slices.Items) != 3 {
+		t.Fatalf("Expected 3 EndpointSlices (IPv4, IPv6, FQDN), got %d", len(slices.Items))
+	}
+
+	agg.Remove("edge1", key)
+	exposure, _ = agg.Get(key)
+	if hub.Reconcile(ctx, logger, key, &exposure) {
+		t.Fatalf("Reconcile asked to retry")
+	}
+	slices, _ = client.DiscoveryV1().EndpointSlices("mcs").List(ctx, metav1.ListOptions{})
+	if len(slices.Items) != 1 || slices.Items[0].Labels[DestinationLabelKey] != "edge2" {
+		t.Errorf("Expected only the EndpointSlice of edge2, got %v", slices.Items)
+	}
+
+	if hub.Reconcile(ctx, logger, key, nil) {
+		t.Fatalf("Reconcile asked to retry")
+	}
+	if _, err := client.CoreV1().Services("mcs").Get(ctx, "shop-web", metav1.GetOptions{}); err == nil {
+		t.Errorf("Hub-side Service not deleted")
+	}
+}
+
+func TestDesiredHTTPRoute(t *testing.T) {
+	hub := &Hub{Namespace: "mcs"}
+	exposure := ServiceExposure{Namespace: "shop", Name: "web", Destinations: []DestinationEndpoints{{Destination: "edge1", Addresses: []string{"10.0.0.1"},
+		Ports: []corev1.ServicePort{{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP}, {Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}}}}
+	if route := hub.DesiredHTTPRoute(exposure); route != nil {
+		t.Errorf("Got an HTTPRoute without a Gateway")
+	}
+	hub.Gateway = &types.NamespacedName{Namespace: "gw", Name: "public"}
+	route := hub.DesiredHTTPRoute(exposure)
+	if route == nil || route.GetName() != "shop-web" {
+		t.Fatalf("Unexpected HTTPRoute %v", route)
+	}
+	rules := route.Object["spec"].(map[string]interface{})["rules"].([]interface{})
+	backend := rules[0].(map[string]interface{})["backendRefs"].([]interface{})[0].(map[string]interface{})
+	if backend["port"] != int64(80) {
+		t.Errorf("Expected the TCP port as backend, got %v", backend)
+	}
+}
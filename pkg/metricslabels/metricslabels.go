@@ -0,0 +1,264 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metricslabels keeps the cardinality of the metrics that a
+// KubeStellar binary serves in check.
+//
+// A policy names the metric labels whose values must not go to the
+// scraper as they are, typically the ones that carry the names of spaces,
+// namespaces or objects. The values of a dropped label disappear, and the
+// series that differed only in them are added together. The values of a
+// hashed label are replaced by one of a fixed number of buckets, which
+// bounds the number of series while keeping some of their spread.
+// The policy applies to everything served at /metrics, including the
+// metrics of the libraries.
+package metricslabels
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/spf13/pflag"
+
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"github.com/kubestellar/kubestellar/pkg/componentconfig"
+)
+
+// Action is what to do with the values of a label.
+type Action string
+
+const (
+	Keep Action = "keep"
+	Drop Action = "drop"
+	Hash Action = "hash"
+)
+
+// DefaultHashBuckets is the number of buckets of "hash" without a count.
+const DefaultHashBuckets = 32
+
+// Rule is what to do with the values of one label.
+type Rule struct {
+	Action Action
+
+	// Buckets is the number of values that Hash maps to.
+	Buckets uint32
+}
+
+// Policy maps label names to rules. Labels not in it are kept.
+type Policy map[string]Rule
+
+// ParsePolicy parses the given label names and actions. An action is
+// "keep", "drop", "hash" or "hash:<buckets>".
+func ParsePolicy(specs map[string]string) (Policy, error) {
+	policy := make(Policy, len(specs))
+	for label, spec := range specs {
+		rule, err := parseRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("label %q: %w", label, err)
+		}
+		if rule.Action != Keep {
+			policy[label] = rule
+		}
+	}
+	return policy, nil
+}
+
+func parseRule(spec string) (Rule, error) {
+	action, count, hasCount := strings.Cut(spec, ":")
+	switch Action(action) {
+	case Keep, Drop:
+		if hasCount {
+			return Rule{}, fmt.Errorf("action %q takes no bucket count", action)
+		}
+		return Rule{Action: Action(action)}, nil
+	case Hash:
+		if !hasCount {
+			return Rule{Action: Hash, Buckets: DefaultHashBuckets}, nil
+		}
+		buckets, err := strconv.ParseUint(count, 10, 32)
+		if err != nil || buckets < 1 {
+			return Rule{}, fmt.Errorf("bucket count %q is not a positive integer", count)
+		}
+		return Rule{Action: Hash, Buckets: uint32(buckets)}, nil
+	default:
+		return Rule{}, fmt.Errorf("unknown action %q, expected keep, drop or hash", action)
+	}
+}
+
+// BucketOf returns the bucket, out of the given number, to which Hash maps the given value.
+func BucketOf(value string, buckets uint32) string {
+	hasher := fnv.New32a()
+	hasher.Write([]byte(value))
+	return strconv.FormatUint(uint64(hasher.Sum32()%buckets), 10)
+}
+
+// Options are the metric label policy of one binary.
+type Options struct {
+	// Labels maps label names to actions, as in ParsePolicy.
+	Labels map[string]string
+}
+
+// NewOptions returns the default Options, which keep every label.
+func NewOptions() *Options {
+	return &Options{Labels: map[string]string{}}
+}
+
+// AddFlags binds the --metrics-label-policy flag.
+func (opts *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringToStringVar(&opts.Labels, "metrics-label-policy", opts.Labels, "what to do with the values of the given metric labels before serving them, as label=action pairs; the action is keep, drop (remove the label and add up the series) or hash[:<buckets>] (replace the value by one of that many buckets, "+strconv.Itoa(DefaultHashBuckets)+" by default)")
+}
+
+// ApplyConfig takes the policy from the given configuration unless it
+// was given on the command line.
+func (opts *Options) ApplyConfig(fs *pflag.FlagSet, metrics componentconfig.MetricsConfiguration) {
+	if metrics.LabelPolicy != nil {
+		componentconfig.Override(fs, "metrics-label-policy", func() { opts.Labels = metrics.LabelPolicy })
+	}
+}
+
+// Validate checks the values.
+func (opts *Options) Validate() error {
+	if _, err := ParsePolicy(opts.Labels); err != nil {
+		return fmt.Errorf("--metrics-label-policy: %w", err)
+	}
+	return nil
+}
+
+// Handler returns the handler for /metrics, which serves the metrics of
+// the legacy registry under the policy. Call it after Validate.
+func (opts *Options) Handler() http.Handler {
+	policy, _ := ParsePolicy(opts.Labels) // already validated
+	if len(policy) == 0 {
+		return legacyregistry.Handler()
+	}
+	return promhttp.HandlerFor(NewGatherer(legacyregistry.DefaultGatherer, policy),
+		promhttp.HandlerOpts{ErrorHandling: promhttp.HTTPErrorOnError})
+}
+
+// NewGatherer returns a Gatherer that applies the given policy to what
+// the given Gatherer gathers.
+func NewGatherer(delegate prometheus.Gatherer, policy Policy) prometheus.Gatherer {
+	return &relabelingGatherer{delegate: delegate, policy: policy}
+}
+
+type relabelingGatherer struct {
+	delegate prometheus.Gatherer
+	policy   Policy
+}
+
+func (rg *relabelingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := rg.delegate.Gather()
+	for _, family := range families {
+		rg.relabel(family)
+	}
+	return families, err
+}
+
+// relabel applies the policy to the metrics of one family, in place,
+// and adds up the metrics that end up with the same labels.
+func (rg *relabelingGatherer) relabel(family *dto.MetricFamily) {
+	if !rg.applies(family) {
+		return
+	}
+	merged := make(map[string]*dto.Metric, len(family.Metric))
+	kept := family.Metric[:0]
+	for _, metric := range family.Metric {
+		labels := metric.Label[:0]
+		for _, pair := range metric.Label {
+			rule, found := rg.policy[pair.GetName()]
+			switch {
+			case !found:
+			case rule.Action == Drop:
+				continue
+			case rule.Action == Hash:
+				bucket := BucketOf(pair.GetValue(), rule.Buckets)
+				pair.Value = &bucket
+			}
+			labels = append(labels, pair)
+		}
+		metric.Label = labels
+		key := labelsKey(labels)
+		if into, found := merged[key]; found {
+			add(into, metric)
+			continue
+		}
+		merged[key] = metric
+		kept = append(kept, metric)
+	}
+	family.Metric = kept
+}
+
+// applies tells whether any of the family's labels is in the policy.
+// All the metrics of a family have the same label names.
+func (rg *relabelingGatherer) applies(family *dto.MetricFamily) bool {
+	if len(family.Metric) == 0 {
+		return false
+	}
+	for _, pair := range family.Metric[0].Label {
+		if _, found := rg.policy[pair.GetName()]; found {
+			return true
+		}
+	}
+	return false
+}
+
+func labelsKey(labels []*dto.LabelPair) string {
+	parts := make([]string, 0, len(labels))
+	for _, pair := range labels {
+		parts = append(parts, pair.GetName()+"="+strconv.Quote(pair.GetValue()))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// add adds the value of one metric into another of the same type.
+// Quantiles of summaries can not be added, so a sum of summaries has none.
+func add(into, metric *dto.Metric) {
+	switch {
+	case into.Counter != nil && metric.Counter != nil:
+		value := into.Counter.GetValue() + metric.Counter.GetValue()
+		into.Counter.Value = &value
+	case into.Gauge != nil && metric.Gauge != nil:
+		value := into.Gauge.GetValue() + metric.Gauge.GetValue()
+		into.Gauge.Value = &value
+	case into.Untyped != nil && metric.Untyped != nil:
+		value := into.Untyped.GetValue() + metric.Untyped.GetValue()
+		into.Untyped.Value = &value
+	case into.Summary != nil && metric.Summary != nil:
+		count := into.Summary.GetSampleCount() + metric.Summary.GetSampleCount()
+		sum := into.Summary.GetSampleSum() + metric.Summary.GetSampleSum()
+		into.Summary.SampleCount, into.Summary.SampleSum = &count, &sum
+		into.Summary.Quantile = nil
+	case into.Histogram != nil && metric.Histogram != nil:
+		count := into.Histogram.GetSampleCount() + metric.Histogram.GetSampleCount()
+		sum := into.Histogram.GetSampleSum() + metric.Histogram.GetSampleSum()
+		into.Histogram.SampleCount, into.Histogram.SampleSum = &count, &sum
+		if len(into.Histogram.Bucket) == len(metric.Histogram.Bucket) {
+			for index, bucket := range into.Histogram.Bucket {
+				cumulative := bucket.GetCumulativeCount() + metric.Histogram.Bucket[index].GetCumulativeCount()
+				bucket.CumulativeCount = &cumulative
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metricslabels
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestParsePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		specs    map[string]string
+		expected Policy
+		wantErr  bool
+	}{
+		{specs: map[string]string{"space": "drop"}, expected: Policy{"space": {Action: Drop}}},
+		{specs: map[string]string{"space": "hash"}, expected: Policy{"space": {Action: Hash, Buckets: DefaultHashBuckets}}},
+		{specs: map[string]string{"space": "hash:8", "name": "keep"}, expected: Policy{"space": {Action: Hash, Buckets: 8}}},
+		{specs: map[string]string{"space": "hash:0"}, wantErr: true},
+		{specs: map[string]string{"space": "drop:3"}, wantErr: true},
+		{specs: map[string]string{"space": "mangle"}, wantErr: true},
+	} {
+		policy, err := ParsePolicy(tc.specs)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParsePolicy(%v) = %v, expected an error", tc.specs, policy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePolicy(%v) failed: %v", tc.specs, err)
+			continue
+		}
+		if len(policy) != len(tc.expected) {
+			t.Errorf("ParsePolicy(%v) = %v, expected %v", tc.specs, policy, tc.expected)
+			continue
+		}
+		for label, rule := range tc.expected {
+			if policy[label] != rule {
+				t.Errorf("ParsePolicy(%v) = %v, expected %v", tc.specs, policy, tc.expected)
+			}
+		}
+	}
+}
+
+func TestGatherer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	writes := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "writes_total"}, []string{"space", "outcome"})
+	waits := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "wait_seconds", Buckets: []float64{1, 10}}, []string{"edgeplacement"})
+	objects := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "objects"}, []string{"outcome"})
+	registry.MustRegister(writes, waits, objects)
+	writes.WithLabelValues("s1", "ok").Add(1)
+	writes.WithLabelValues("s2", "ok").Add(2)
+	writes.WithLabelValues("s3", "failed").Add(4)
+	waits.WithLabelValues("ep1").Observe(0.5)
+	waits.WithLabelValues("ep2").Observe(5)
+	waits.WithLabelValues("ep3").Observe(50)
+	objects.WithLabelValues("ok").Set(7)
+
+	gatherer := NewGatherer(registry, Policy{"space": {Action: Drop}, "edgeplacement": {Action: Hash, Buckets: 1}})
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	byName := map[string]*dto.MetricFamily{}
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+
+	counters := map[string]float64{}
+	for _, metric := range byName["writes_total"].Metric {
+		if len(metric.Label) != 1 || metric.Label[0].GetName() != "outcome" {
+			t.Errorf("Expected only the outcome label, got %v", metric.Label)
+			continue
+		}
+		counters[metric.Label[0].GetValue()] = metric.Counter.GetValue()
+	}
+	if len(counters) != 2 || counters["ok"] != 3 || counters["failed"] != 4 {
+		t.Errorf("Expected ok=3 and failed=4, got %v", counters)
+	}
+
+	histograms := byName["wait_seconds"].Metric
+	if len(histograms) != 1 {
+		t.Fatalf("Expected the histograms to share one bucket, got %d of them", len(histograms))
+	}
+	histogram := histograms[0].Histogram
+	if value := histograms[0].Label[0].GetValue(); value != "0" {
+		t.Errorf("Expected edgeplacement=0, got %q", value)
+	}
+	if histogram.GetSampleCount() != 3 || histogram.GetSampleSum() != 55.5 {
+		t.Errorf("Expected 3 samples adding up to 55.5, got %d adding up to %v", histogram.GetSampleCount(), histogram.GetSampleSum())
+	}
+	if len(histogram.Bucket) != 2 || histogram.Bucket[0].GetCumulativeCount() != 1 || histogram.Bucket[1].GetCumulativeCount() != 2 {
+		t.Errorf("Expected cumulative counts 1 and 2, got %v", histogram.Bucket)
+	}
+
+	if gauges := byName["objects"].Metric; len(gauges) != 1 || gauges[0].Gauge.GetValue() != 7 {
+		t.Errorf("Expected the unaffected gauge to stay as it was, got %v", gauges)
+	}
+}
+
+func TestBucketOf(t *testing.T) {
+	if BucketOf("space-a", 16) != BucketOf("space-a", 16) {
+		t.Error("Expected the same value to land in the same bucket")
+	}
+	seen := map[string]bool{}
+	for _, value := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		seen[BucketOf(value, 4)] = true
+	}
+	if len(seen) > 4 {
+		t.Errorf("Expected at most 4 buckets, got %v", seen)
+	}
+}
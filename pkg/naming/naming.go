@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package naming computes the names of the objects that KubeStellar generates.
+// Every name here is a pure function of the identity of what it is
+// generated for, so the same name is computed by every controller and
+// across restarts, and an integrator can compute it too.
+//
+// A name that would be too long is shortened by truncating it and
+// appending a dash and a hash (HashLength hex digits) of the full name.
+// A name that fits is used as it is, so that names are unchanged from
+// before this shortening was introduced.
+//
+// Objects copied into a mailbox space keep the names they have in
+// their source space, except as changed by kube-bind.
+// Bundle parts are named by the hash of their content; see package bundle.
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// HashLength is the number of hex digits in a hash suffix.
+	HashLength = 10
+
+	// MaxNameLength is the maximum length of an object name that is a DNS subdomain.
+	MaxNameLength = 253
+
+	// MaxLabelLength is the maximum length of a DNS label, and of a label value.
+	MaxLabelLength = 63
+
+	// MailboxSpaceSeparator separates the two parts of a mailbox space name.
+	MailboxSpaceSeparator = "-mb-"
+
+	// GuardrailPolicyPrefix starts the name of every generated guardrail NetworkPolicy.
+	GuardrailPolicyPrefix = "kubestellar-guardrail-"
+
+	// TrustObjectPrefix starts the name of every generated trust ConfigMap and Secret.
+	TrustObjectPrefix = "kubestellar-trust-"
+)
+
+// Hash returns the first HashLength hex digits of a SHA-256 hash of the given strings.
+// Each string is length-prefixed, so different lists of strings do not run together.
+func Hash(parts ...string) string {
+	hasher := sha256.New()
+	var lenBytes [8]byte
+	for _, part := range parts {
+		binary.BigEndian.PutUint64(lenBytes[:], uint64(len(part)))
+		hasher.Write(lenBytes[:])
+		hasher.Write([]byte(part))
+	}
+	return hex.EncodeToString(hasher.Sum(nil))[:HashLength]
+}
+
+// Bounded returns the given name if it is no longer than maxLen, otherwise
+// a prefix of it followed by a dash and the Hash of the whole name,
+// maxLen characters long in total.
+// The prefix does not end with a dash or dot.
+// maxLen must be more than HashLength+1.
+func Bounded(name string, maxLen int) string {
+	if len(name) <= maxLen {
+		return name
+	}
+	prefix := strings.TrimRight(name[:maxLen-HashLength-1], "-.")
+	return prefix + "-" + Hash(name)
+}
+
+// LabelValue returns the given value, shortened if needed to be usable as a label value.
+func LabelValue(value string) string {
+	return Bounded(value, MaxLabelLength)
+}
+
+// MailboxSpaceName returns the name of the mailbox space for the SyncTarget
+// with the given UID that is in the space with the given ID.
+// The name is a DNS label; if needed, the space ID part is shortened.
+func MailboxSpaceName(spaceID, syncTargetUID string) string {
+	name := spaceID + MailboxSpaceSeparator + syncTargetUID
+	if len(name) <= MaxLabelLength {
+		return name
+	}
+	idLen := MaxLabelLength - len(MailboxSpaceSeparator) - len(syncTargetUID)
+	if idLen <= HashLength+1 {
+		return Bounded(name, MaxLabelLength)
+	}
+	return Bounded(spaceID, idLen) + MailboxSpaceSeparator + syncTargetUID
+}
+
+// IsMailboxSpaceName tells whether the given space name looks like the name of a mailbox space.
+func IsMailboxSpaceName(spaceName string) bool {
+	return len(strings.Split(spaceName, MailboxSpaceSeparator)) == 2
+}
+
+// SinglePlacementSliceName returns the name of the SinglePlacementSlice
+// that holds the scheduling decisions for the named EdgePlacement.
+func SinglePlacementSliceName(edgePlacementName string) string {
+	return edgePlacementName
+}
+
+// GuardrailPolicyName returns the name of the NetworkPolicy generated
+// for the named EdgePlacement.
+func GuardrailPolicyName(edgePlacementName string) string {
+	return Bounded(GuardrailPolicyPrefix+edgePlacementName, MaxNameLength)
+}
+
+// TrustObjectName returns the name of the ConfigMap and of the Secret
+// generated for the trust material of the named EdgePlacement.
+func TrustObjectName(edgePlacementName string) string {
+	return Bounded(TrustObjectPrefix+edgePlacementName, MaxNameLength)
+}
+
+// HubServiceName returns the name of the hub-side Service that stands for
+// the Service with the given namespace and name at the edge.
+// The name is a DNS label, as Service names have to be.
+func HubServiceName(namespace, name string) string {
+	return Bounded(namespace+"-"+name, MaxLabelLength)
+}
+
+// HubEndpointSliceName returns the name of the EndpointSlice of the named
+// hub-side Service that holds the addresses of the given type at the
+// named destination.
+func HubEndpointSliceName(hubServiceName, destination, addressType string) string {
+	return Bounded(hubServiceName+"-"+destination+"-"+strings.ToLower(addressType), MaxNameLength)
+}
+
+// RegistrationManifestSecretName returns the name of the Secret that holds
+// the syncer manifest for the named ClusterRegistration.
+func RegistrationManifestSecretName(registrationName string) string {
+	return Bounded(registrationName+"-syncer", MaxNameLength)
+}
+
+// NamespacedPlacementName returns the name of the EdgePlacement derived
+// from the NamespacedEdgePlacement with the given namespace and name.
+func NamespacedPlacementName(namespace, name string) string {
+	return Bounded("ns-"+namespace+"-"+name, MaxNameLength)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package naming
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	if len(Hash("a")) != HashLength {
+		t.Errorf("Hash has length %d, expected %d", len(Hash("a")), HashLength)
+	}
+	if Hash("ab", "c") == Hash("a", "bc") {
+		t.Errorf("Hash lets parts run together")
+	}
+	if Hash("a", "b") != Hash("a", "b") {
+		t.Errorf("Hash is not deterministic")
+	}
+}
+
+func TestBounded(t *testing.T) {
+	if got := Bounded("short", 63); got != "short" {
+		t.Errorf("Bounded changed a short name to %q", got)
+	}
+	long1 := strings.Repeat("x", 60) + "-aaaaa"
+	long2 := strings.Repeat("x", 60) + "-bbbbb"
+	got1, got2 := Bounded(long1, 63), Bounded(long2, 63)
+	if len(got1) > 63 || len(got2) > 63 {
+		t.Errorf("Bounded names are too long: %q, %q", got1, got2)
+	}
+	if got1 == got2 {
+		t.Errorf("Bounded names collide: %q", got1)
+	}
+	if got1 != Bounded(long1, 63) {
+		t.Errorf("Bounded is not deterministic")
+	}
+	dashy := strings.Repeat("y", 51) + "-----------------"
+	if got := Bounded(dashy, 63); strings.Contains(got, "--") {
+		t.Errorf("Bounded left a trailing dash on the prefix: %q", got)
+	}
+}
+
+func TestMailboxSpaceName(t *testing.T) {
+	uid := "8a3e9c2b-2f7c-4b70-9d3e-5f1c6a7b8c9d"
+	if got, expected := MailboxSpaceName("abc123", uid), "abc123-mb-"+uid; got != expected {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+	longID := strings.Repeat("s", 40)
+	got := MailboxSpaceName(longID, uid)
+	if len(got) > MaxLabelLength {
+		t.Errorf("Mailbox space name %q is too long", got)
+	}
+	if !IsMailboxSpaceName(got) || !strings.HasSuffix(got, MailboxSpaceSeparator+uid) {
+		t.Errorf("Shortened mailbox space name %q does not look like one", got)
+	}
+	if IsMailboxSpaceName("just-a-space") {
+		t.Errorf("Non-mailbox name taken for mailbox space name")
+	}
+}
+
+func TestHubServiceName(t *testing.T) {
+	if got, expected := HubServiceName("shop", "web"), "shop-web"; got != expected {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+	if got := HubServiceName(strings.Repeat("n", 40), strings.Repeat("s", 40)); len(got) > MaxLabelLength {
+		t.Errorf("Hub Service name %q is too long", got)
+	}
+}
+
+func TestRegistrationManifestSecretName(t *testing.T) {
+	if got, expected := RegistrationManifestSecretName("edge1"), "edge1-syncer"; got != expected {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+	if got := RegistrationManifestSecretName(strings.Repeat("r", MaxNameLength)); len(got) > MaxNameLength {
+		t.Errorf("Manifest Secret name %q is too long", got)
+	}
+}
+
+func TestNamespacedPlacementName(t *testing.T) {
+	if got, expected := NamespacedPlacementName("shop", "web"), "ns-shop-web"; got != expected {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+	if got := NamespacedPlacementName(strings.Repeat("n", 63), strings.Repeat("p", MaxNameLength)); len(got) > MaxNameLength {
+		t.Errorf("Derived EdgePlacement name %q is too long", got)
+	}
+}
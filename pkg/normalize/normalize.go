@@ -0,0 +1,252 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package normalize removes from workload objects the content that is
+// specific to the apiserver they were read from (the hub), so that they
+// can be written into another one (a mailbox space or a WEC). The
+// content to remove is described by Rules, which a Registry applies to
+// the objects of the kinds that each Rule matches.
+package normalize
+
+import (
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+)
+
+// Rule describes content to remove from objects of some kinds.
+type Rule struct {
+	// Name identifies the Rule in a Registry; registering a Rule
+	// replaces any earlier one of the same name.
+	Name string
+
+	// Kinds restricts the Rule to objects of these kinds.
+	// Leave it empty to apply the Rule to objects of all kinds.
+	Kinds []schema.GroupKind
+
+	// Fields are paths of fields to remove, such as {"metadata", "uid"}.
+	Fields [][]string
+
+	// IsHubOnlyKey, if not nil, identifies the label and annotation keys to remove.
+	IsHubOnlyKey func(key string) bool
+
+	// Func, if not nil, makes further changes to the object in place.
+	Func func(obj *unstructured.Unstructured)
+}
+
+func (rule Rule) applies(gk schema.GroupKind) bool {
+	if len(rule.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range rule.Kinds {
+		if kind == gk {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry holds the Rules to apply. It is safe for concurrent use.
+type Registry struct {
+	mutex sync.RWMutex
+	rules []Rule
+}
+
+// NewRegistry returns a Registry holding the given Rules.
+func NewRegistry(rules ...Rule) *Registry {
+	reg := &Registry{}
+	for _, rule := range rules {
+		reg.Register(rule)
+	}
+	return reg
+}
+
+// Default holds the DefaultRules. Components that need to strip more
+// from some kind of object register their Rules here.
+var Default = NewRegistry(DefaultRules()...)
+
+// Register adds the given Rule, replacing any earlier Rule of the same name.
+func (reg *Registry) Register(rule Rule) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+	for idx := range reg.rules {
+		if reg.rules[idx].Name == rule.Name {
+			reg.rules[idx] = rule
+			return
+		}
+	}
+	reg.rules = append(reg.rules, rule)
+}
+
+// Names returns the names of the registered Rules, in the order they are applied.
+func (reg *Registry) Names() []string {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+	ans := make([]string, len(reg.rules))
+	for idx, rule := range reg.rules {
+		ans[idx] = rule.Name
+	}
+	return ans
+}
+
+// Normalize removes, in place, the content described by the Rules that
+// apply to the kind of the given object.
+func (reg *Registry) Normalize(obj *unstructured.Unstructured) {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+	gk := obj.GroupVersionKind().GroupKind()
+	for _, rule := range reg.rules {
+		if !rule.applies(gk) {
+			continue
+		}
+		for _, path := range rule.Fields {
+			unstructured.RemoveNestedField(obj.Object, path...)
+		}
+		if rule.IsHubOnlyKey != nil {
+			if labels, changed := withoutKeys(obj.GetLabels(), rule.IsHubOnlyKey); changed {
+				obj.SetLabels(labels)
+			}
+			if annotations, changed := withoutKeys(obj.GetAnnotations(), rule.IsHubOnlyKey); changed {
+				obj.SetAnnotations(annotations)
+			}
+		}
+		if rule.Func != nil {
+			rule.Func(obj)
+		}
+	}
+}
+
+// IsHubOnlyKey tells whether a Rule that applies to the given kind of
+// object removes labels or annotations with the given key.
+func (reg *Registry) IsHubOnlyKey(gk schema.GroupKind, key string) bool {
+	reg.mutex.RLock()
+	defer reg.mutex.RUnlock()
+	for _, rule := range reg.rules {
+		if rule.IsHubOnlyKey != nil && rule.applies(gk) && rule.IsHubOnlyKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutKeys returns a copy of the given map without the keys that
+// drop selects, and whether there were any; the copy is nil if empty.
+func withoutKeys(kvs map[string]string, drop func(string) bool) (map[string]string, bool) {
+	changed := false
+	ans := map[string]string{}
+	for key, val := range kvs {
+		if drop(key) {
+			changed = true
+		} else {
+			ans[key] = val
+		}
+	}
+	if len(ans) == 0 {
+		ans = nil
+	}
+	return ans, changed
+}
+
+// IsKCPKey tells whether the given label or annotation key belongs to kcp.
+func IsKCPKey(key string) bool {
+	return strings.Contains(key, ".kcp.io/") || strings.HasPrefix(key, "kcp.io/")
+}
+
+// IsOwnerKey tells whether the given label or annotation key records a
+// virtual owner. A copy has an owner of its own, if any, so it must not
+// inherit the one of the object it is copied from. The adoption opt-in
+// is not an owner key; it is meant to travel with the workload.
+func IsOwnerKey(key string) bool {
+	return key == ownership.OwnerLabelKey || key == ownership.OwnerAnnotationKey
+}
+
+// DefaultRules returns the Rules that Default starts with.
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			Name: "server-metadata",
+			Fields: [][]string{
+				{"metadata", "uid"},
+				{"metadata", "resourceVersion"},
+				{"metadata", "selfLink"},
+				{"metadata", "creationTimestamp"},
+				{"metadata", "deletionTimestamp"},
+				{"metadata", "deletionGracePeriodSeconds"},
+				{"metadata", "generation"},
+				{"metadata", "managedFields"},
+				{"metadata", "clusterName"},
+				// Owner UIDs are not transported
+				{"metadata", "ownerReferences"},
+			},
+		},
+		{
+			Name:   "status",
+			Fields: [][]string{{"status"}},
+		},
+		{
+			Name:         "kcp-keys",
+			IsHubOnlyKey: IsKCPKey,
+		},
+		{
+			Name:         "virtual-owner",
+			IsHubOnlyKey: IsOwnerKey,
+		},
+		{
+			// The syncer reports probe results upstream; they are not sent back down.
+			Name:         "probe-results",
+			IsHubOnlyKey: func(key string) bool { return key == edgeapi.ProbeResultsKey },
+		},
+		{
+			// The cluster IPs are allocated by the hub; each destination allocates its own.
+			// "None" is kept because it makes the Service headless.
+			Name:  "service-cluster-ips",
+			Kinds: []schema.GroupKind{{Kind: "Service"}},
+			Func: func(obj *unstructured.Unstructured) {
+				if clusterIP, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); clusterIP == "None" {
+					return
+				}
+				unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+				unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+			},
+		},
+		{
+			// The bound PersistentVolume exists only in the hub.
+			Name:   "pvc-volume-name",
+			Kinds:  []schema.GroupKind{{Kind: "PersistentVolumeClaim"}},
+			Fields: [][]string{{"spec", "volumeName"}},
+		},
+		{
+			// The hub generates a selector that refers to the Job's UID there;
+			// each destination generates its own.
+			Name:  "job-generated-selector",
+			Kinds: []schema.GroupKind{{Group: "batch", Kind: "Job"}},
+			Func: func(obj *unstructured.Unstructured) {
+				if manual, _, _ := unstructured.NestedBool(obj.Object, "spec", "manualSelector"); manual {
+					return
+				}
+				unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+				for _, key := range []string{"controller-uid", "batch.kubernetes.io/controller-uid", "job-name", "batch.kubernetes.io/job-name"} {
+					unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", key)
+				}
+			},
+		},
+	}
+}
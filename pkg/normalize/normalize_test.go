@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package normalize
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNormalize(t *testing.T) {
+	svc := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]any{
+			"name":              "web",
+			"namespace":         "shop",
+			"uid":               "1234",
+			"resourceVersion":   "99",
+			"creationTimestamp": "2023-09-01T14:00:00Z",
+			"ownerReferences":   []any{map[string]any{"kind": "X", "name": "x", "uid": "5678"}},
+			"labels":            map[string]any{"app": "web", "ownership.kubestellar.io/owner": "abc"},
+			"annotations": map[string]any{"kcp.io/cluster": "root:wds1", "note": "keep",
+				"ownership.kubestellar.io/owner-ref": "{}", "ownership.kubestellar.io/adoptable": "true"},
+		},
+		"spec":   map[string]any{"clusterIP": "10.0.0.7", "clusterIPs": []any{"10.0.0.7"}, "ports": []any{}},
+		"status": map[string]any{"loadBalancer": map[string]any{}},
+	}}
+	Default.Normalize(svc)
+	expected := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]any{
+			"name":        "web",
+			"namespace":   "shop",
+			"labels":      map[string]any{"app": "web"},
+			"annotations": map[string]any{"note": "keep", "ownership.kubestellar.io/adoptable": "true"},
+		},
+		"spec": map[string]any{"ports": []any{}},
+	}
+	if !reflect.DeepEqual(svc.Object, expected) {
+		t.Errorf("Expected %v but got %v", expected, svc.Object)
+	}
+
+	headless := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]any{"name": "db"},
+		"spec":       map[string]any{"clusterIP": "None"},
+	}}
+	Default.Normalize(headless)
+	if ip, _, _ := unstructured.NestedString(headless.Object, "spec", "clusterIP"); ip != "None" {
+		t.Errorf("Headless Service lost its clusterIP: %v", headless.Object)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	reg := NewRegistry(DefaultRules()...)
+	deployment := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	if reg.IsHubOnlyKey(deployment, "example.com/hub") {
+		t.Error("Key is hub-only before its Rule is registered")
+	}
+	reg.Register(Rule{
+		Name:         "example",
+		Kinds:        []schema.GroupKind{deployment},
+		Fields:       [][]string{{"spec", "paused"}},
+		IsHubOnlyKey: func(key string) bool { return key == "example.com/hub" },
+	})
+	if !reg.IsHubOnlyKey(deployment, "example.com/hub") || reg.IsHubOnlyKey(schema.GroupKind{Kind: "ConfigMap"}, "example.com/hub") {
+		t.Error("Registered Rule does not apply to exactly its kinds")
+	}
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"name": "web", "labels": map[string]any{"example.com/hub": "x"}},
+		"spec":       map[string]any{"paused": true, "replicas": int64(2)},
+	}}
+	reg.Normalize(obj)
+	expected := map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"name": "web"},
+		"spec":       map[string]any{"replicas": int64(2)},
+	}
+	if !reflect.DeepEqual(obj.Object, expected) {
+		t.Errorf("Expected %v but got %v", expected, obj.Object)
+	}
+	reg.Register(Rule{Name: "example"})
+	if len(reg.Names()) != len(DefaultRules())+1 {
+		t.Errorf("Re-registering a Rule must replace it, got %v", reg.Names())
+	}
+}
@@ -0,0 +1,306 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nsplacement
+
+import (
+	"context"
+	"fmt"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	edgeclient "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/typed/edge/v2alpha1"
+	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions/edge/v2alpha1"
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+)
+
+const controllerName = "namespaced-placement-controller"
+
+const fieldManager = "kubestellar"
+
+// ref identifies a NamespacedEdgePlacement as the consumer knows it,
+// along with the kube-bind ID of the consumer.
+type ref struct {
+	kbSpaceID string
+	namespace string
+	name      string
+}
+
+// Controller maintains the EdgePlacements derived from the NamespacedEdgePlacements
+// in the workload description spaces.
+// It works off the provider's copies of the NamespacedEdgePlacements and
+// EdgePlacements, which kube-bind puts in the KubeStellar core space.
+type Controller struct {
+	context         context.Context
+	neplInformer    cache.SharedIndexInformer
+	neplLister      edgelisters.NamespacedEdgePlacementLister
+	epInformer      cache.SharedIndexInformer
+	epLister        edgelisters.EdgePlacementLister
+	spaceProviderNs string
+	kbSpaceRelation kbuser.KubeBindSpaceRelation
+	spaceClients    *spaceclient.Factory
+	coreEdgeClient  edgeclientset.Interface
+	queue           workqueue.RateLimitingInterface
+}
+
+// NewController makes a new Controller.
+// The NamespacedEdgePlacement and EdgePlacement informers are on the KubeStellar core space.
+func NewController(ctx context.Context,
+	neplPreInformer edgeinformers.NamespacedEdgePlacementInformer,
+	epPreInformer edgeinformers.EdgePlacementInformer,
+	spaceProviderNs string,
+	kbSpaceRelation kbuser.KubeBindSpaceRelation,
+	spaceClients *spaceclient.Factory,
+	coreEdgeClient edgeclientset.Interface,
+) *Controller {
+	ctl := &Controller{
+		context:         ctx,
+		neplInformer:    neplPreInformer.Informer(),
+		neplLister:      neplPreInformer.Lister(),
+		epInformer:      epPreInformer.Informer(),
+		epLister:        epPreInformer.Lister(),
+		spaceProviderNs: spaceProviderNs,
+		kbSpaceRelation: kbSpaceRelation,
+		spaceClients:    spaceClients,
+		coreEdgeClient:  coreEdgeClient,
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+	}
+	ctl.neplInformer.AddEventHandler(ctl)
+	ctl.epInformer.AddEventHandler(ctl)
+	return ctl
+}
+
+// Run animates the controller, finishing and returning when the context of
+// the controller is done.
+// Call this after the informers have been started.
+func (ctl *Controller) Run(concurrency int) {
+	ctx := ctl.context
+	logger := klog.FromContext(ctx)
+	doneCh := ctx.Done()
+	if !cache.WaitForNamedCacheSync(controllerName, doneCh, ctl.neplInformer.HasSynced, ctl.epInformer.HasSynced) {
+		logger.Error(nil, "Informer syncs not achieved")
+		return
+	}
+	logger.V(1).Info("Informers synced")
+	for worker := 0; worker < concurrency; worker++ {
+		go ctl.syncLoop(ctx, worker)
+	}
+	<-doneCh
+}
+
+func (ctl *Controller) OnAdd(obj any) {
+	ctl.enqueue(obj)
+}
+
+func (ctl *Controller) OnUpdate(oldObj, newObj any) {
+	ctl.enqueue(newObj)
+}
+
+func (ctl *Controller) OnDelete(obj any) {
+	if dfsu, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = dfsu.Obj
+	}
+	ctl.enqueue(obj)
+}
+
+// enqueue adds the reference to the relevant NamespacedEdgePlacement, if any.
+// For an EdgePlacement that is the one that it was derived from.
+// Deletions of derived EdgePlacements are enqueued too, so that they get re-created.
+func (ctl *Controller) enqueue(obj any) {
+	logger := klog.FromContext(ctl.context)
+	switch typed := obj.(type) {
+	case *edgeapi.NamespacedEdgePlacement:
+		namespace, name, kbSpaceID, err := kbuser.AnalyzeObjectID(typed)
+		if err != nil {
+			logger.Error(err, "Object does not appear to be a provider's copy of a consumer's object", "namespace", typed.Namespace, "name", typed.Name)
+			return
+		}
+		ctl.queue.Add(ref{kbSpaceID: kbSpaceID, namespace: namespace, name: name})
+	case *edgeapi.EdgePlacement:
+		namespace, name, ok := DerivedFrom(typed)
+		if !ok {
+			return
+		}
+		_, _, kbSpaceID, err := kbuser.AnalyzeObjectID(typed)
+		if err != nil {
+			logger.Error(err, "Object does not appear to be a provider's copy of a consumer's object", "name", typed.Name)
+			return
+		}
+		ctl.queue.Add(ref{kbSpaceID: kbSpaceID, namespace: namespace, name: name})
+	default:
+		logger.Error(nil, "Notified of object of unexpected type", "object", obj, "type", fmt.Sprintf("%T", obj))
+	}
+}
+
+func (ctl *Controller) syncLoop(ctx context.Context, worker int) {
+	doneCh := ctx.Done()
+	logger := klog.FromContext(ctx).WithValues("worker", worker)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(4).Info("SyncLoop start")
+	for {
+		select {
+		case <-doneCh:
+			logger.V(2).Info("SyncLoop done")
+			return
+		default:
+			item, shutdown := ctl.queue.Get()
+			if shutdown {
+				logger.V(2).Info("Queue shutdown")
+				return
+			}
+			ctl.sync1(ctx, item.(ref))
+		}
+	}
+}
+
+func (ctl *Controller) sync1(ctx context.Context, item ref) {
+	defer ctl.queue.Done(item)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("Dequeued reference", "ref", item)
+	if ctl.sync(ctx, item) {
+		ctl.queue.AddRateLimited(item)
+		return
+	}
+	ctl.queue.Forget(item)
+}
+
+// sync makes the derived EdgePlacement in the WDS agree with the
+// NamespacedEdgePlacement, and reports in the latter's status.
+// It returns whether to retry.
+func (ctl *Controller) sync(ctx context.Context, item ref) bool {
+	logger := klog.FromContext(ctx).WithValues("kbSpaceID", item.kbSpaceID, "namespace", item.namespace, "name", item.name)
+	spaceID := ctl.kbSpaceRelation.SpaceIDFromKubeBind(item.kbSpaceID)
+	if spaceID == "" {
+		logger.V(3).Info("Consumer space ID not known yet")
+		return true
+	}
+	logger = logger.WithValues("spaceID", spaceID)
+	ctx = klog.NewContext(ctx, logger)
+	epName := naming.NamespacedPlacementName(item.namespace, item.name)
+	wdsClients, err := ctl.spaceClients.For(spaceID, ctl.spaceProviderNs)
+	if err != nil {
+		logger.Error(err, "Failed to get clients for workload description space")
+		return true
+	}
+	epClient := wdsClients.Edge.EdgeV2alpha1().EdgePlacements()
+
+	providerNamespace := kbuser.ComposeClusterScopedName(item.kbSpaceID, item.namespace)
+	nepl, err := ctl.neplLister.NamespacedEdgePlacements(providerNamespace).Get(item.name)
+	if err != nil && !k8sapierrors.IsNotFound(err) {
+		logger.Error(err, "Failed to fetch NamespacedEdgePlacement from local cache")
+		return false
+	}
+	if err != nil || nepl.DeletionTimestamp != nil {
+		return ctl.deleteDerived(ctx, epClient, epName, item)
+	}
+
+	want := DesiredEdgePlacement(item.namespace, item.name, nepl.Spec)
+	status := nepl.Status.DeepCopy()
+	status.ObservedGeneration = nepl.Generation
+	status.EdgePlacementName = epName
+	now := metav1.Now()
+	setDerived := func(ok bool, reason, message string) {
+		conditions.Set(&status.Conditions, condition(edgeapi.NamespacedEdgePlacementDerived, ok, reason, message, nepl.Generation), now)
+	}
+	retry := false
+	have, err := epClient.Get(ctx, epName, metav1.GetOptions{})
+	switch {
+	case k8sapierrors.IsNotFound(err):
+		_, err = epClient.Create(ctx, want, metav1.CreateOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error(err, "Failed to create derived EdgePlacement", "edgePlacement", epName)
+			setDerived(false, "Failed", err.Error())
+			retry = true
+		} else {
+			logger.V(2).Info("Created derived EdgePlacement", "edgePlacement", epName)
+			setDerived(true, "Derived", "")
+		}
+	case err != nil:
+		logger.Error(err, "Failed to read derived EdgePlacement", "edgePlacement", epName)
+		return true
+	default:
+		if namespace, name, ok := DerivedFrom(have); !ok || namespace != item.namespace || name != item.name {
+			setDerived(false, "NameConflict", fmt.Sprintf("EdgePlacement %q exists and was not derived from this object", epName))
+			break
+		}
+		if !apiequality.Semantic.DeepEqual(have.Spec, want.Spec) {
+			have = have.DeepCopy()
+			have.Spec = want.Spec
+			if _, err := epClient.Update(ctx, have, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+				logger.Error(err, "Failed to update derived EdgePlacement", "edgePlacement", epName)
+				setDerived(false, "Failed", err.Error())
+				retry = true
+				break
+			}
+			logger.V(2).Info("Updated derived EdgePlacement", "edgePlacement", epName)
+		}
+		setDerived(true, "Derived", "")
+	}
+
+	// The where-resolver writes the EdgePlacement's status on the provider's copy.
+	if providerEP, err := ctl.epLister.Get(kbuser.ComposeClusterScopedName(item.kbSpaceID, epName)); err == nil {
+		if namespace, name, ok := DerivedFrom(providerEP); ok && namespace == item.namespace && name == item.name {
+			ReportEdgePlacementStatus(status, providerEP, nepl.Generation, now)
+		}
+	}
+	if !apiequality.Semantic.DeepEqual(status, &nepl.Status) {
+		neplCopy := nepl.DeepCopy()
+		neplCopy.Status = *status
+		_, err = ctl.coreEdgeClient.EdgeV2alpha1().NamespacedEdgePlacements(providerNamespace).UpdateStatus(ctx, neplCopy, metav1.UpdateOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error(err, "Failed to update status of NamespacedEdgePlacement")
+			return true
+		}
+		logger.V(2).Info("Updated status of NamespacedEdgePlacement")
+	}
+	return retry
+}
+
+// deleteDerived deletes the EdgePlacement derived from the given
+// NamespacedEdgePlacement, which is gone, if that EdgePlacement still exists.
+// It returns whether to retry.
+func (ctl *Controller) deleteDerived(ctx context.Context, epClient edgeclient.EdgePlacementInterface, epName string, item ref) bool {
+	logger := klog.FromContext(ctx)
+	have, err := epClient.Get(ctx, epName, metav1.GetOptions{})
+	if k8sapierrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		logger.Error(err, "Failed to read derived EdgePlacement", "edgePlacement", epName)
+		return true
+	}
+	if namespace, name, ok := DerivedFrom(have); !ok || namespace != item.namespace || name != item.name {
+		return false
+	}
+	err = epClient.Delete(ctx, epName, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &have.UID}})
+	if err != nil && !k8sapierrors.IsNotFound(err) && !k8sapierrors.IsConflict(err) {
+		logger.Error(err, "Failed to delete derived EdgePlacement", "edgePlacement", epName)
+		return true
+	}
+	logger.V(2).Info("Deleted derived EdgePlacement", "edgePlacement", epName)
+	return false
+}
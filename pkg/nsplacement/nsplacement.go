@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nsplacement maintains, for each NamespacedEdgePlacement, the
+// cluster-scoped EdgePlacement derived from it.
+//
+// The controller runs against the KubeStellar core space, where
+// kube-bind brings the NamespacedEdgePlacements and EdgePlacements of
+// all the workload description spaces and where it writes their status.
+// It writes the derived EdgePlacements in the workload description
+// space itself, where the where-resolver and placement translator find
+// them like any other EdgePlacement.
+// Because a cluster-scoped object can not be owned by a namespaced one,
+// the controller deletes the derived EdgePlacement itself.
+package nsplacement
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+// DesiredEdgePlacement returns the EdgePlacement derived from the
+// NamespacedEdgePlacement with the given namespace, name and spec.
+// Every namespace in the derived spec is the given one, so the derived
+// EdgePlacement only selects objects in that namespace (plus, as for
+// any EdgePlacement, the definers of those objects).
+// The given spec is not modified.
+func DesiredEdgePlacement(namespace, name string, spec edgeapi.NamespacedEdgePlacementSpec) *edgeapi.EdgePlacement {
+	spec = *spec.DeepCopy()
+	ep := &edgeapi.EdgePlacement{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        naming.NamespacedPlacementName(namespace, name),
+			Annotations: map[string]string{edgeapi.NamespacedPlacementAnnotationKey: namespace + "/" + name},
+		},
+		Spec: edgeapi.EdgePlacementSpec{
+			LocationSelectors:          spec.LocationSelectors,
+			Downsync:                   spec.Downsync,
+			WantSingletonReportedState: spec.WantSingletonReportedState,
+			IncludeDependencies:        spec.IncludeDependencies,
+			NetworkGuardrails:          spec.NetworkGuardrails,
+			Requirements:               spec.Requirements,
+			LocalProbes:                spec.LocalProbes,
+			AllowAll:                   spec.AllowAll,
+		},
+	}
+	for idx := range ep.Spec.Downsync {
+		ep.Spec.Downsync[idx].Namespaces = []string{namespace}
+		ep.Spec.Downsync[idx].NamespaceSelectors = nil
+	}
+	if ep.Spec.NetworkGuardrails != nil {
+		ep.Spec.NetworkGuardrails.Namespaces = []string{namespace}
+	}
+	for idx := range ep.Spec.LocalProbes {
+		if exec := ep.Spec.LocalProbes[idx].Exec; exec != nil {
+			exec.Namespace = namespace
+		}
+	}
+	return ep
+}
+
+// DerivedFrom returns the namespace and name of the NamespacedEdgePlacement
+// that the given EdgePlacement was derived from, and false if it was not.
+func DerivedFrom(ep metav1.Object) (namespace, name string, ok bool) {
+	value, has := ep.GetAnnotations()[edgeapi.NamespacedPlacementAnnotationKey]
+	if !has {
+		return "", "", false
+	}
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// ReportEdgePlacementStatus copies the relevant parts of the status of
+// the derived EdgePlacement into the given status of the NamespacedEdgePlacement
+// that has the given generation.
+func ReportEdgePlacementStatus(status *edgeapi.NamespacedEdgePlacementStatus, ep *edgeapi.EdgePlacement, generation int64, now metav1.Time) {
+	status.MatchingLocationCount = ep.Status.MatchingLocationCount
+	for _, cond := range ep.Status.Conditions {
+		cond.ObservedGeneration = generation
+		conditions.Set(&status.Conditions, cond, now)
+	}
+}
+
+func condition(condType string, ok bool, reason, message string, generation int64) metav1.Condition {
+	status := metav1.ConditionFalse
+	if ok {
+		status = metav1.ConditionTrue
+	}
+	return metav1.Condition{Type: condType, Status: status, Reason: reason, Message: message, ObservedGeneration: generation}
+}
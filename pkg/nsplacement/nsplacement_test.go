@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nsplacement
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+)
+
+func TestDesiredEdgePlacement(t *testing.T) {
+	spec := edgeapi.NamespacedEdgePlacementSpec{
+		LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"env": "prod"}}},
+		Downsync: []edgeapi.DownsyncObjectTest{
+			{Resources: []string{"deployments"}, Namespaces: []string{"*"}},
+			{ObjectNames: []string{"cfg"}, NamespaceSelectors: []metav1.LabelSelector{{}}},
+		},
+		NetworkGuardrails: &edgeapi.NetworkGuardrails{Namespaces: []string{"kube-system"}},
+		LocalProbes: []edgeapi.LocalProbe{{Name: "db",
+			Exec: &edgeapi.ExecProbe{Namespace: "kube-system", Command: []string{"true"}}}},
+	}
+	ep := DesiredEdgePlacement("shop", "web", spec)
+	if ep.Name != "ns-shop-web" {
+		t.Errorf("Unexpected name %q", ep.Name)
+	}
+	for idx, test := range ep.Spec.Downsync {
+		if diff := cmp.Diff([]string{"shop"}, test.Namespaces); diff != "" || test.NamespaceSelectors != nil {
+			t.Errorf("Downsync test %d is not restricted to the namespace: %+v", idx, test)
+		}
+	}
+	if diff := cmp.Diff([]string{"shop"}, ep.Spec.NetworkGuardrails.Namespaces); diff != "" {
+		t.Errorf("Guardrails are not restricted to the namespace (-want +got):\n%s", diff)
+	}
+	if namespace := ep.Spec.LocalProbes[0].Exec.Namespace; namespace != "shop" {
+		t.Errorf("Exec probe is not restricted to the namespace: %q", namespace)
+	}
+	if diff := cmp.Diff(spec.LocationSelectors, ep.Spec.LocationSelectors); diff != "" {
+		t.Errorf("Wrong location selectors (-want +got):\n%s", diff)
+	}
+	if spec.Downsync[0].Namespaces[0] != "*" || spec.NetworkGuardrails.Namespaces[0] != "kube-system" ||
+		spec.LocalProbes[0].Exec.Namespace != "kube-system" {
+		t.Error("The spec was modified")
+	}
+	if namespace, name, ok := DerivedFrom(ep); !ok || namespace != "shop" || name != "web" {
+		t.Errorf("DerivedFrom gave %q, %q, %v", namespace, name, ok)
+	}
+	if _, _, ok := DerivedFrom(&edgeapi.EdgePlacement{}); ok {
+		t.Error("DerivedFrom accepted an EdgePlacement without the annotation")
+	}
+}
+
+func TestReportEdgePlacementStatus(t *testing.T) {
+	now := metav1.Now()
+	status := &edgeapi.NamespacedEdgePlacementStatus{}
+	conditions.Set(&status.Conditions, condition(edgeapi.NamespacedEdgePlacementDerived, true, "Derived", "", 3), now)
+	ep := &edgeapi.EdgePlacement{Status: edgeapi.EdgePlacementStatus{
+		MatchingLocationCount: 2,
+		Conditions:            []metav1.Condition{{Type: edgeapi.EdgePlacementLocationsResolved, Status: metav1.ConditionTrue, Reason: "Resolved", ObservedGeneration: 7}},
+	}}
+	ReportEdgePlacementStatus(status, ep, 3, now)
+	if status.MatchingLocationCount != 2 {
+		t.Errorf("Unexpected matchingLocationCount %d", status.MatchingLocationCount)
+	}
+	cond := conditions.Find(status.Conditions, edgeapi.EdgePlacementLocationsResolved)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.ObservedGeneration != 3 {
+		t.Errorf("Unexpected copied condition %+v", cond)
+	}
+	if !conditions.IsTrue(status.Conditions, edgeapi.NamespacedEdgePlacementDerived) {
+		t.Error("Lost the Derived condition")
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package objecthash computes the content hashes by which KubeStellar
+// tells whether an object changed, for example to skip re-applying an
+// object whose desired content is what was applied last time. A hash is
+// the SHA-256 of the object's JSON encoding, whose map keys are sorted,
+// so it does not depend on field order or on the Go types involved.
+// Transports and agents that compare hashes must compute them with this
+// package, and the golden tests here pin the hashes so that they also
+// agree across releases.
+package objecthash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultLength is the default number of hex digits in a hash.
+const DefaultLength = 20
+
+// Options say what a hash covers and how long it is.
+type Options struct {
+	// ExcludeFields are the fields that the hash ignores, each given as
+	// its path of field names from the top of the object, for example
+	// {"metadata", "annotations"}. Paths that are not in an object are
+	// ignored.
+	ExcludeFields [][]string
+
+	// Length is the number of hex digits in the hash, at most 64.
+	// Zero means DefaultLength.
+	Length int
+}
+
+// ServerFields are the fields that apiservers set, which do not say
+// anything about what a client asked for.
+var ServerFields = [][]string{
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "deletionGracePeriodSeconds"},
+	{"metadata", "managedFields"},
+	{"metadata", "selfLink"},
+	{"status"},
+}
+
+// Of returns the hash of the whole content of the given object, with
+// DefaultLength hex digits.
+func Of(obj runtime.Object) (string, error) {
+	return Options{}.Hash(obj)
+}
+
+// Hash returns the hash of the given object with these options.
+// A typed object hashes the same as its unstructured equivalent,
+// provided both have the same apiVersion and kind.
+func (opts Options) Hash(obj runtime.Object) (string, error) {
+	length := opts.Length
+	if length == 0 {
+		length = DefaultLength
+	}
+	if length < 0 || length > 2*sha256.Size {
+		return "", fmt.Errorf("hash length must be between 1 and %d, not %d", 2*sha256.Size, length)
+	}
+	content, err := contentOf(obj)
+	if err != nil {
+		return "", err
+	}
+	for _, path := range opts.ExcludeFields {
+		content = without(content, path)
+	}
+	contentBytes, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(contentBytes)
+	return hex.EncodeToString(sum[:])[:length], nil
+}
+
+func contentOf(obj runtime.Object) (map[string]any, error) {
+	if objU, isU := obj.(*unstructured.Unstructured); isU {
+		return objU.Object, nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+// without returns the given content minus the field at the given path,
+// copying rather than modifying the maps along the path.
+func without(content map[string]any, path []string) map[string]any {
+	if len(path) == 0 {
+		return content
+	}
+	value, has := content[path[0]]
+	if !has {
+		return content
+	}
+	ans := make(map[string]any, len(content))
+	for key, val := range content {
+		ans[key] = val
+	}
+	if len(path) == 1 {
+		delete(ans, path[0])
+		return ans
+	}
+	if valueM, isMap := value.(map[string]any); isMap {
+		ans[path[0]] = without(valueM, path[1:])
+	}
+	return ans
+}
+
+// Hasher hashes objects with options that depend on their kind.
+// It is safe for concurrent use.
+type Hasher struct {
+	defaults Options
+
+	mutex  sync.RWMutex
+	byKind map[schema.GroupVersionKind]Options
+}
+
+// NewHasher makes a Hasher that uses the given options for the objects
+// of every kind that has no options of its own.
+func NewHasher(defaults Options) *Hasher {
+	return &Hasher{defaults: defaults, byKind: map[schema.GroupVersionKind]Options{}}
+}
+
+// SetOptions sets the options for the objects of the given kind.
+// An empty Version stands for every version of the kind that has no
+// options of its own.
+func (hasher *Hasher) SetOptions(gvk schema.GroupVersionKind, opts Options) {
+	hasher.mutex.Lock()
+	defer hasher.mutex.Unlock()
+	hasher.byKind[gvk] = opts
+}
+
+// OptionsFor returns the options used for the objects of the given kind.
+func (hasher *Hasher) OptionsFor(gvk schema.GroupVersionKind) Options {
+	hasher.mutex.RLock()
+	defer hasher.mutex.RUnlock()
+	if opts, has := hasher.byKind[gvk]; has {
+		return opts
+	}
+	if opts, has := hasher.byKind[gvk.GroupKind().WithVersion("")]; has {
+		return opts
+	}
+	return hasher.defaults
+}
+
+// Hash returns the hash of the given object, with the options for its kind.
+func (hasher *Hasher) Hash(obj runtime.Object) (string, error) {
+	return hasher.OptionsFor(obj.GetObjectKind().GroupVersionKind()).Hash(obj)
+}
+
+// Changed tells whether the hashes of the given objects differ.
+// Objects that can not be hashed are always considered changed.
+func (hasher *Hasher) Changed(oldObj, newObj runtime.Object) bool {
+	oldHash, err := hasher.Hash(oldObj)
+	if err != nil {
+		return true
+	}
+	newHash, err := hasher.Hash(newObj)
+	if err != nil {
+		return true
+	}
+	return oldHash != newHash
+}
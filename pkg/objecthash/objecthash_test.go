@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objecthash
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func configMap() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":      "cfg",
+			"namespace": "shop",
+			"labels":    map[string]any{"app": "shop"},
+		},
+		"data": map[string]any{"a": "1", "b": "2"},
+	}}
+}
+
+func deployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"name": "web", "namespace": "shop"},
+		"spec": map[string]any{
+			"replicas": int64(3),
+			"selector": map[string]any{"matchLabels": map[string]any{"app": "web"}},
+			"paused":   false,
+		},
+	}}
+}
+
+// TestGolden pins the hashes. If this test fails then hashes computed by
+// different releases disagree, which makes every hash-guarded object
+// get applied again after an upgrade; do not just update the expectations.
+func TestGolden(t *testing.T) {
+	withServerFields := configMap()
+	withServerFields.SetResourceVersion("42")
+	withServerFields.SetUID("0c8f1a52-97a4-4b5e-b7c4-5e1a7f9f3d11")
+	withServerFields.SetGeneration(7)
+	withServerFields.Object["status"] = map[string]any{"phase": "Ready"}
+	typed := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: "shop", Labels: map[string]string{"app": "shop"}, ResourceVersion: "9"},
+		Data:       map[string]string{"b": "2", "a": "1"},
+	}
+	for _, tc := range []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		opts     Options
+		expected string
+	}{
+		{"configmap", configMap(), Options{}, "c31cefce464119a5bede"},
+		{"server fields excluded", withServerFields, Options{ExcludeFields: ServerFields}, "c31cefce464119a5bede"},
+		{"full length", deployment(), Options{Length: 64}, "c9552e1e929354e08a5ade4736134491eddeba966958b5e17b3b88d556b1958d"},
+		{"replicas excluded", deployment(), Options{ExcludeFields: [][]string{{"spec", "replicas"}}}, "9721867fd25ec5c0e7a1"},
+	} {
+		got, err := tc.opts.Hash(tc.obj)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		} else if got != tc.expected {
+			t.Errorf("%s: expected hash %s, got %s", tc.name, tc.expected, got)
+		}
+	}
+	got, err := Options{ExcludeFields: ServerFields}.Hash(typed)
+	if err != nil || got != "c31cefce464119a5bede" {
+		t.Errorf("Expected a typed ConfigMap to hash like its unstructured equivalent, got %q and %v", got, err)
+	}
+	if withServerFields.GetResourceVersion() != "42" || withServerFields.Object["status"] == nil {
+		t.Errorf("Hashing must not modify the object, got %v", withServerFields.Object)
+	}
+	if _, err := (Options{Length: 65}).Hash(configMap()); err == nil {
+		t.Errorf("Expected an error for too long a hash")
+	}
+}
+
+func TestHasher(t *testing.T) {
+	hasher := NewHasher(Options{ExcludeFields: ServerFields})
+	hasher.SetOptions(schema.GroupVersionKind{Group: "apps", Kind: "Deployment"}, Options{ExcludeFields: [][]string{{"spec", "replicas"}}})
+	hasher.SetOptions(schema.GroupVersionKind{Group: "apps", Version: "v1beta1", Kind: "Deployment"}, Options{Length: 10})
+
+	scaled := deployment()
+	scaled.Object["spec"].(map[string]any)["replicas"] = int64(5)
+	if hasher.Changed(deployment(), scaled) {
+		t.Errorf("Expected a change of replicas to be ignored for apps/v1 Deployments")
+	}
+	oldBeta, newBeta := deployment(), scaled.DeepCopy()
+	oldBeta.SetAPIVersion("apps/v1beta1")
+	newBeta.SetAPIVersion("apps/v1beta1")
+	if !hasher.Changed(oldBeta, newBeta) {
+		t.Errorf("Expected a change of replicas to be noticed for apps/v1beta1 Deployments")
+	}
+	if hash, _ := hasher.Hash(oldBeta); len(hash) != 10 {
+		t.Errorf("Expected a 10-digit hash for apps/v1beta1 Deployments, got %q", hash)
+	}
+
+	relabeled := configMap()
+	relabeled.SetResourceVersion("43")
+	if hasher.Changed(configMap(), relabeled) {
+		t.Errorf("Expected a change of resourceVersion to be ignored")
+	}
+	relabeled.SetLabels(map[string]string{"app": "store"})
+	if !hasher.Changed(configMap(), relabeled) {
+		t.Errorf("Expected a change of labels to be noticed")
+	}
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ocm converts between KubeStellar EdgePlacements and the
+// Placement API of Open Cluster Management (OCM), for shops that
+// migrate between the two systems or run them side by side.
+//
+// The `locationSelectors` of an EdgePlacement correspond to the
+// `predicates` of a Placement (in both, a cluster is selected if it
+// passes any of them), and `wantSingletonReportedState` corresponds to
+// `numberOfClusters: 1`. An EdgePlacement also says what to downsync
+// and upsync, which in OCM is the business of ManifestWorks rather than
+// the Placement; those parts of the spec are carried in the
+// EdgePlacementSpecAnnotationKey annotation of the Placement so that a
+// round trip loses nothing. The resolved destinations, held in
+// SinglePlacementSlices, correspond to the `decisions` in the status of
+// PlacementDecisions; an OCM cluster name is a SyncTarget name.
+//
+// The OCM types are mirrored in this package rather than imported, and
+// the objects are exchanged as unstructured.Unstructured.
+package ocm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/destination"
+)
+
+// EdgePlacementSpecAnnotationKey is the annotation on a Placement that
+// holds, in JSON, the parts of the EdgePlacement spec that have no
+// counterpart in the Placement spec.
+const EdgePlacementSpecAnnotationKey = "edge.kubestellar.io/edge-placement-spec"
+
+// MaxDecisionsPerObject is the number of decisions that OCM puts in one
+// PlacementDecision before starting another.
+const MaxDecisionsPerObject = 100
+
+// PlacementFromEdgePlacement returns the Placement, in the given
+// namespace, that corresponds to the given EdgePlacement.
+func PlacementFromEdgePlacement(ep *edgeapi.EdgePlacement, namespace string) (*unstructured.Unstructured, error) {
+	placement := Placement{
+		TypeMeta: metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "Placement"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      ep.Name,
+			Labels:    ep.Labels,
+		},
+	}
+	for _, selector := range ep.Spec.LocationSelectors {
+		placement.Spec.Predicates = append(placement.Spec.Predicates, ClusterPredicate{
+			RequiredClusterSelector: ClusterSelector{LabelSelector: selector}})
+	}
+	switch {
+	case len(ep.Spec.LocationSelectors) == 0:
+		// An EdgePlacement without selectors selects nothing,
+		// a Placement without predicates selects everything.
+		placement.Spec.NumberOfClusters = new(int32)
+	case ep.Spec.WantSingletonReportedState:
+		one := int32(1)
+		placement.Spec.NumberOfClusters = &one
+	}
+	rest := ep.Spec.DeepCopy()
+	rest.LocationSelectors = nil
+	rest.WantSingletonReportedState = false
+	restJSON, err := json.Marshal(rest)
+	if err != nil {
+		return nil, err
+	}
+	if string(restJSON) != "{}" {
+		placement.Annotations = map[string]string{EdgePlacementSpecAnnotationKey: string(restJSON)}
+	}
+	return toUnstructured(&placement)
+}
+
+// EdgePlacementFromPlacement returns the EdgePlacement that corresponds
+// to the given Placement. The warnings describe the parts of the
+// Placement that the EdgePlacement can not express.
+func EdgePlacementFromPlacement(obj *unstructured.Unstructured) (*edgeapi.EdgePlacement, []string, error) {
+	placement := Placement{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &placement); err != nil {
+		return nil, nil, err
+	}
+	ep := &edgeapi.EdgePlacement{
+		TypeMeta: metav1.TypeMeta{APIVersion: edgeapi.SchemeGroupVersion.String(), Kind: "EdgePlacement"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   placement.Name,
+			Labels: placement.Labels,
+		},
+	}
+	var warnings []string
+	if restJSON, ok := placement.Annotations[EdgePlacementSpecAnnotationKey]; ok {
+		if err := json.Unmarshal([]byte(restJSON), &ep.Spec); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s annotation: %w", EdgePlacementSpecAnnotationKey, err)
+		}
+	} else {
+		warnings = append(warnings, "the Placement says nothing about what to downsync or upsync")
+	}
+	for key, value := range placement.Annotations {
+		if key == EdgePlacementSpecAnnotationKey {
+			continue
+		}
+		if ep.Annotations == nil {
+			ep.Annotations = map[string]string{}
+		}
+		ep.Annotations[key] = value
+	}
+	numberOfClusters := placement.Spec.NumberOfClusters
+	switch {
+	case numberOfClusters != nil && *numberOfClusters == 0:
+	case len(placement.Spec.Predicates) == 0:
+		ep.Spec.LocationSelectors = []metav1.LabelSelector{{}}
+	default:
+		for idx, predicate := range placement.Spec.Predicates {
+			if claims := predicate.RequiredClusterSelector.ClaimSelector; claims != nil && len(claims.MatchExpressions) > 0 {
+				warnings = append(warnings, fmt.Sprintf("the claimSelector of predicate %d is ignored", idx))
+			}
+			ep.Spec.LocationSelectors = append(ep.Spec.LocationSelectors, predicate.RequiredClusterSelector.LabelSelector)
+		}
+	}
+	if numberOfClusters != nil && *numberOfClusters == 1 {
+		ep.Spec.WantSingletonReportedState = true
+	} else if numberOfClusters != nil && *numberOfClusters > 1 {
+		warnings = append(warnings, fmt.Sprintf("numberOfClusters=%d is ignored, every selected Location is used", *numberOfClusters))
+	}
+	if len(placement.Spec.ClusterSets) > 0 {
+		warnings = append(warnings, "clusterSets is ignored")
+	}
+	for _, field := range []string{"prioritizerPolicy", "spreadPolicy", "tolerations", "decisionStrategy"} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", field); found {
+			warnings = append(warnings, field+" is ignored")
+		}
+	}
+	return ep, warnings, nil
+}
+
+// DecisionsFromSlices returns the PlacementDecisions, in the given
+// namespace, that report the destinations in the given
+// SinglePlacementSlices of the named EdgePlacement. There is always at
+// least one; the decisions are sorted by cluster name.
+func DecisionsFromSlices(placementName, namespace string, slices []*edgeapi.SinglePlacementSlice) ([]*unstructured.Unstructured, error) {
+	// An OCM cluster name is the SyncTarget name, which is unique only
+	// within its inventory space.
+	names := map[string]destination.Destination{}
+	for _, slice := range slices {
+		for _, sp := range slice.Destinations {
+			dest := destination.Of(sp)
+			if other, have := names[dest.SyncTargetName]; have && !other.Equal(dest) {
+				return nil, fmt.Errorf("destinations %s and %s would both be cluster %q", other, dest, dest.SyncTargetName)
+			}
+			names[dest.SyncTargetName] = dest
+		}
+	}
+	decisions := make([]ClusterDecision, 0, len(names))
+	for name := range names {
+		decisions = append(decisions, ClusterDecision{ClusterName: name})
+	}
+	sort.Slice(decisions, func(i, j int) bool { return decisions[i].ClusterName < decisions[j].ClusterName })
+	ans := []*unstructured.Unstructured{}
+	for index := 0; index == 0 || index*MaxDecisionsPerObject < len(decisions); index++ {
+		end := (index + 1) * MaxDecisionsPerObject
+		if end > len(decisions) {
+			end = len(decisions)
+		}
+		decision := PlacementDecision{
+			TypeMeta: metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "PlacementDecision"},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      DecisionName(placementName, index),
+				Labels:    map[string]string{PlacementLabelKey: placementName},
+			},
+			Status: PlacementDecisionStatus{Decisions: decisions[index*MaxDecisionsPerObject : end]},
+		}
+		obj, err := toUnstructured(&decision)
+		if err != nil {
+			return nil, err
+		}
+		ans = append(ans, obj)
+	}
+	return ans, nil
+}
+
+// DecisionName returns the name of the PlacementDecision with the given
+// index (counting from zero) for the named Placement, as OCM names them.
+func DecisionName(placementName string, index int) string {
+	return fmt.Sprintf("%s-decision-%d", placementName, index+1)
+}
+
+// SliceFromDecisions returns the SinglePlacementSlice that reports the
+// decisions in the given PlacementDecisions as destinations of the
+// named EdgePlacement. The resolve func maps a cluster name to a
+// destination; clusters that it does not know are left out and
+// reported in the warnings.
+func SliceFromDecisions(edgePlacementName string, objs []*unstructured.Unstructured, resolve func(clusterName string) (edgeapi.SinglePlacement, bool)) (*edgeapi.SinglePlacementSlice, []string, error) {
+	slice := &edgeapi.SinglePlacementSlice{
+		TypeMeta: metav1.TypeMeta{APIVersion: edgeapi.SchemeGroupVersion.String(), Kind: "SinglePlacementSlice"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   edgePlacementName,
+			Labels: map[string]string{edgeapi.SourcePlacementLabelKey: edgePlacementName},
+		},
+		Destinations: []edgeapi.SinglePlacement{},
+	}
+	var warnings []string
+	seen := map[string]struct{}{}
+	for _, obj := range objs {
+		decision := PlacementDecision{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &decision); err != nil {
+			return nil, nil, fmt.Errorf("invalid PlacementDecision %s: %w", obj.GetName(), err)
+		}
+		for _, cd := range decision.Status.Decisions {
+			if _, dup := seen[cd.ClusterName]; dup {
+				continue
+			}
+			seen[cd.ClusterName] = struct{}{}
+			dest, ok := resolve(cd.ClusterName)
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("cluster %q has no SyncTarget", cd.ClusterName))
+				continue
+			}
+			slice.Destinations = append(slice.Destinations, dest)
+		}
+	}
+	sort.Slice(slice.Destinations, func(i, j int) bool {
+		return destination.Of(slice.Destinations[i]).Less(destination.Of(slice.Destinations[j]))
+	})
+	return slice, warnings, nil
+}
+
+func toUnstructured(obj any) (*unstructured.Unstructured, error) {
+	objM, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: objM}, nil
+}
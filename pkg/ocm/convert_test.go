@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestPlacementRoundTrip(t *testing.T) {
+	group := "apps"
+	for idx, spec := range []edgeapi.EdgePlacementSpec{
+		{},
+		{LocationSelectors: []metav1.LabelSelector{{}}},
+		{
+			LocationSelectors: []metav1.LabelSelector{
+				{MatchLabels: map[string]string{"env": "prod"}},
+				{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "region", Operator: metav1.LabelSelectorOpIn, Values: []string{"eu", "us"}}}},
+			},
+			WantSingletonReportedState: true,
+			Downsync:                   []edgeapi.DownsyncObjectTest{{APIGroup: &group, Resources: []string{"deployments"}, Namespaces: []string{"shop"}}},
+			Upsync:                     []edgeapi.UpsyncSet{{APIGroup: "group1.test", Resources: []string{"sprockets"}, Names: []string{"*"}}},
+		},
+	} {
+		t.Run(fmt.Sprint(idx), func(t *testing.T) {
+			ep := &edgeapi.EdgePlacement{
+				TypeMeta:   metav1.TypeMeta{APIVersion: edgeapi.SchemeGroupVersion.String(), Kind: "EdgePlacement"},
+				ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"team": "a"}},
+				Spec:       spec,
+			}
+			placement, err := PlacementFromEdgePlacement(ep, "fleet")
+			if err != nil {
+				t.Fatalf("PlacementFromEdgePlacement: %v", err)
+			}
+			if placement.GetNamespace() != "fleet" || placement.GetName() != "shop" {
+				t.Errorf("Wrong Placement identity %s/%s", placement.GetNamespace(), placement.GetName())
+			}
+			back, warnings, err := EdgePlacementFromPlacement(placement)
+			if err != nil {
+				t.Fatalf("EdgePlacementFromPlacement: %v", err)
+			}
+			if idx == 2 && len(warnings) > 0 {
+				t.Errorf("Unexpected warnings %v", warnings)
+			}
+			if diff := cmp.Diff(ep, back); diff != "" {
+				t.Errorf("Round trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlacementFromOCM(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": GroupVersion.String(),
+		"kind":       "Placement",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "p1"},
+		"spec": map[string]interface{}{
+			"clusterSets":      []interface{}{"global"},
+			"numberOfClusters": int64(3),
+			"predicates": []interface{}{map[string]interface{}{
+				"requiredClusterSelector": map[string]interface{}{
+					"labelSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"env": "dev"}},
+					"claimSelector": map[string]interface{}{"matchExpressions": []interface{}{
+						map[string]interface{}{"key": "platform.open-cluster-management.io", "operator": "In", "values": []interface{}{"AWS"}},
+					}},
+				},
+			}},
+			"prioritizerPolicy": map[string]interface{}{"mode": "Additive"},
+		},
+	}}
+	ep, warnings, err := EdgePlacementFromPlacement(obj)
+	if err != nil {
+		t.Fatalf("EdgePlacementFromPlacement: %v", err)
+	}
+	wantSelectors := []metav1.LabelSelector{{MatchLabels: map[string]string{"env": "dev"}}}
+	if diff := cmp.Diff(wantSelectors, ep.Spec.LocationSelectors); diff != "" {
+		t.Errorf("Wrong locationSelectors (-want +got):\n%s", diff)
+	}
+	if ep.Spec.WantSingletonReportedState {
+		t.Error("Unexpected wantSingletonReportedState")
+	}
+	wantWarnings := []string{
+		"the Placement says nothing about what to downsync or upsync",
+		"the claimSelector of predicate 0 is ignored",
+		"numberOfClusters=3 is ignored, every selected Location is used",
+		"clusterSets is ignored",
+		"prioritizerPolicy is ignored",
+	}
+	if diff := cmp.Diff(wantWarnings, warnings); diff != "" {
+		t.Errorf("Wrong warnings (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecisions(t *testing.T) {
+	var dests []edgeapi.SinglePlacement
+	for idx := 0; idx < MaxDecisionsPerObject+1; idx++ {
+		name := fmt.Sprintf("st%03d", idx)
+		dests = append(dests, edgeapi.SinglePlacement{Cluster: "inv", LocationName: name, SyncTargetName: name})
+	}
+	slices := []*edgeapi.SinglePlacementSlice{{Destinations: dests[:50]}, {Destinations: dests[50:]}}
+	decisions, err := DecisionsFromSlices("shop", "fleet", slices)
+	if err != nil {
+		t.Fatalf("DecisionsFromSlices: %v", err)
+	}
+	if len(decisions) != 2 {
+		t.Fatalf("Expected 2 PlacementDecisions, got %d", len(decisions))
+	}
+	if name := decisions[1].GetName(); name != "shop-decision-2" {
+		t.Errorf("Wrong name %q", name)
+	}
+	if decisions[0].GetLabels()[PlacementLabelKey] != "shop" {
+		t.Errorf("Missing placement label in %v", decisions[0].GetLabels())
+	}
+	resolve := func(clusterName string) (edgeapi.SinglePlacement, bool) {
+		if clusterName == "st100" {
+			return edgeapi.SinglePlacement{}, false
+		}
+		return edgeapi.SinglePlacement{Cluster: "inv", LocationName: clusterName, SyncTargetName: clusterName}, true
+	}
+	slice, warnings, err := SliceFromDecisions("shop", decisions, resolve)
+	if err != nil {
+		t.Fatalf("SliceFromDecisions: %v", err)
+	}
+	if diff := cmp.Diff(dests[:MaxDecisionsPerObject], slice.Destinations); diff != "" {
+		t.Errorf("Wrong destinations (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{`cluster "st100" has no SyncTarget`}, warnings); diff != "" {
+		t.Errorf("Wrong warnings (-want +got):\n%s", diff)
+	}
+
+	clash := []*edgeapi.SinglePlacementSlice{{Destinations: []edgeapi.SinglePlacement{
+		{Cluster: "inv1", SyncTargetName: "st"}, {Cluster: "inv2", SyncTargetName: "st"}}}}
+	if _, err := DecisionsFromSlices("clash", "fleet", clash); err == nil {
+		t.Error("Expected an error for two destinations with the same SyncTarget name")
+	}
+
+	empty, err := DecisionsFromSlices("idle", "fleet", nil)
+	if err != nil {
+		t.Fatalf("DecisionsFromSlices: %v", err)
+	}
+	if len(empty) != 1 {
+		t.Errorf("Expected one empty PlacementDecision, got %d", len(empty))
+	}
+}
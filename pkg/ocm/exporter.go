@@ -0,0 +1,332 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocm
+
+import (
+	"context"
+	"fmt"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+)
+
+// ManagedBy is the value of the managed-by label of the exported objects.
+const ManagedBy = "kubestellar-ocm-exporter"
+
+const managedByLabelKey = "app.kubernetes.io/managed-by"
+
+const fieldManager = "kubestellar"
+
+// Exporter maintains, in a namespace of an OCM hub cluster, a Placement
+// for each EdgePlacement and, unless told not to, PlacementDecisions
+// that report the EdgePlacement's destinations.
+// Objects in that namespace that it did not create are left alone.
+type Exporter struct {
+	context    context.Context
+	placements edgelisters.EdgePlacementLister
+	slices     edgelisters.SinglePlacementSliceLister
+	client     dynamic.Interface
+	namespace  string
+
+	// writeDecisions tells whether to maintain the PlacementDecisions.
+	// Turn this off when the OCM placement controller makes the decisions.
+	writeDecisions bool
+
+	queue workqueue.RateLimitingInterface
+}
+
+// NewExporter returns an Exporter that writes into the given namespace.
+// Add it as event handler to the EdgePlacement informer and, through
+// SliceHandler, to the SinglePlacementSlice informer.
+func NewExporter(ctx context.Context, placements edgelisters.EdgePlacementLister, slices edgelisters.SinglePlacementSliceLister, client dynamic.Interface, namespace string, writeDecisions bool) *Exporter {
+	return &Exporter{
+		context:        ctx,
+		placements:     placements,
+		slices:         slices,
+		client:         client,
+		namespace:      namespace,
+		writeDecisions: writeDecisions,
+		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ocm-exporter"),
+	}
+}
+
+// Run animates the exporter, finishing and returning when its context is done.
+// Call this after the informers have been started.
+func (exp *Exporter) Run(concurrency int, hasSynced ...cache.InformerSynced) {
+	ctx := exp.context
+	logger := klog.FromContext(ctx)
+	doneCh := ctx.Done()
+	defer exp.queue.ShutDown()
+	if !cache.WaitForNamedCacheSync("ocm-exporter", doneCh, hasSynced...) {
+		logger.Error(nil, "Informer syncs not achieved")
+		return
+	}
+	logger.V(1).Info("Informers synced")
+	for worker := 0; worker < concurrency; worker++ {
+		go exp.syncLoop(ctx, worker)
+	}
+	<-doneCh
+}
+
+func (exp *Exporter) OnAdd(obj any) {
+	exp.enqueue(obj)
+}
+
+func (exp *Exporter) OnUpdate(oldObj, newObj any) {
+	exp.enqueue(newObj)
+}
+
+func (exp *Exporter) OnDelete(obj any) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	exp.enqueue(obj)
+}
+
+func (exp *Exporter) enqueue(obj any) {
+	ep, ok := obj.(*edgeapi.EdgePlacement)
+	if !ok {
+		klog.FromContext(exp.context).Error(nil, "Notified of object of unexpected type", "object", obj, "type", fmt.Sprintf("%T", obj))
+		return
+	}
+	exp.queue.Add(ep.Name)
+}
+
+// SliceHandler returns the event handler for SinglePlacementSlices,
+// which enqueues the EdgePlacement that a slice belongs to.
+func (exp *Exporter) SliceHandler() cache.ResourceEventHandler {
+	enqueue := func(obj any) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		slice, ok := obj.(*edgeapi.SinglePlacementSlice)
+		if !ok {
+			klog.FromContext(exp.context).Error(nil, "Notified of object of unexpected type", "object", obj, "type", fmt.Sprintf("%T", obj))
+			return
+		}
+		if epName := slice.Labels[edgeapi.SourcePlacementLabelKey]; epName != "" {
+			exp.queue.Add(epName)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(oldObj, newObj any) { enqueue(newObj) },
+		DeleteFunc: enqueue,
+	}
+}
+
+func (exp *Exporter) syncLoop(ctx context.Context, worker int) {
+	logger := klog.FromContext(ctx).WithValues("worker", worker)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(4).Info("SyncLoop start")
+	for {
+		ref, shutdown := exp.queue.Get()
+		if shutdown {
+			logger.V(2).Info("Queue shutdown")
+			return
+		}
+		name := ref.(string)
+		if exp.sync(ctx, logger.WithValues("edgePlacement", name), name) {
+			exp.queue.AddRateLimited(ref)
+		} else {
+			exp.queue.Forget(ref)
+		}
+		exp.queue.Done(ref)
+	}
+}
+
+// sync brings the exported objects of the named EdgePlacement up to
+// date. Returns whether to retry.
+func (exp *Exporter) sync(ctx context.Context, logger klog.Logger, name string) bool {
+	placements := exp.client.Resource(PlacementGVR).Namespace(exp.namespace)
+	ep, err := exp.placements.Get(name)
+	if k8sapierrors.IsNotFound(err) {
+		have, err := placements.Get(ctx, name, metav1.GetOptions{})
+		if k8sapierrors.IsNotFound(err) {
+			return false
+		} else if err != nil {
+			logger.Error(err, "Failed to get Placement")
+			return true
+		}
+		if have.GetLabels()[managedByLabelKey] != ManagedBy {
+			return false
+		}
+		// The PlacementDecisions go along, by garbage collection
+		uid := have.GetUID()
+		err = placements.Delete(ctx, name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}})
+		if err != nil && !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete Placement")
+			return true
+		}
+		logger.V(2).Info("Deleted Placement")
+		return false
+	} else if err != nil {
+		logger.Error(err, "Failed to get EdgePlacement")
+		return true
+	}
+	want, err := PlacementFromEdgePlacement(ep, exp.namespace)
+	if err != nil {
+		logger.Error(err, "Failed to convert EdgePlacement")
+		return false
+	}
+	setLabel(want, managedByLabelKey, ManagedBy)
+	have, err := placements.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case k8sapierrors.IsNotFound(err):
+		have, err = placements.Create(ctx, want, metav1.CreateOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error(err, "Failed to create Placement")
+			return true
+		}
+		logger.V(2).Info("Created Placement")
+	case err != nil:
+		logger.Error(err, "Failed to get Placement")
+		return true
+	case have.GetLabels()[managedByLabelKey] != ManagedBy:
+		logger.Error(nil, "Placement name is taken by a Placement that KubeStellar does not manage", "namespace", exp.namespace)
+		return false
+	case !ownedSpecEqual(have, want) ||
+		!apiequality.Semantic.DeepEqual(have.GetLabels(), want.GetLabels()) ||
+		!apiequality.Semantic.DeepEqual(have.GetAnnotations(), want.GetAnnotations()):
+		have = have.DeepCopy()
+		for _, field := range ownedSpecFields {
+			if value, found, _ := unstructured.NestedFieldCopy(want.Object, "spec", field); found {
+				unstructured.SetNestedField(have.Object, value, "spec", field)
+			} else {
+				unstructured.RemoveNestedField(have.Object, "spec", field)
+			}
+		}
+		have.SetLabels(want.GetLabels())
+		have.SetAnnotations(want.GetAnnotations())
+		have, err = placements.Update(ctx, have, metav1.UpdateOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error(err, "Failed to update Placement")
+			return true
+		}
+		logger.V(2).Info("Updated Placement")
+	}
+	if !exp.writeDecisions {
+		return false
+	}
+	slices, err := exp.slices.List(labels.SelectorFromSet(labels.Set{edgeapi.SourcePlacementLabelKey: name}))
+	if err != nil {
+		logger.Error(err, "Failed to list SinglePlacementSlices")
+		return true
+	}
+	decisions, err := DecisionsFromSlices(name, exp.namespace, slices)
+	if err != nil {
+		logger.Error(err, "Failed to convert SinglePlacementSlices")
+		return false
+	}
+	owner := metav1.OwnerReference{APIVersion: GroupVersion.String(), Kind: "Placement", Name: have.GetName(), UID: have.GetUID()}
+	return exp.reconcileDecisions(ctx, logger, name, owner, decisions)
+}
+
+func (exp *Exporter) reconcileDecisions(ctx context.Context, logger klog.Logger, placementName string, owner metav1.OwnerReference, decisions []*unstructured.Unstructured) bool {
+	client := exp.client.Resource(PlacementDecisionGVR).Namespace(exp.namespace)
+	wantNames := sets.NewString()
+	for _, want := range decisions {
+		wantNames.Insert(want.GetName())
+	}
+	selector := labels.SelectorFromSet(labels.Set{PlacementLabelKey: placementName, managedByLabelKey: ManagedBy})
+	existing, err := client.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logger.Error(err, "Failed to list PlacementDecisions")
+		return true
+	}
+	var retry bool
+	for _, have := range existing.Items {
+		if wantNames.Has(have.GetName()) {
+			continue
+		}
+		if err := client.Delete(ctx, have.GetName(), metav1.DeleteOptions{}); err != nil && !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete PlacementDecision", "placementDecision", have.GetName())
+			retry = true
+		}
+	}
+	for _, want := range decisions {
+		logger := logger.WithValues("placementDecision", want.GetName())
+		setLabel(want, managedByLabelKey, ManagedBy)
+		want.SetOwnerReferences([]metav1.OwnerReference{owner})
+		have, err := client.Get(ctx, want.GetName(), metav1.GetOptions{})
+		switch {
+		case k8sapierrors.IsNotFound(err):
+			// The status is not set by create
+			have, err = client.Create(ctx, want, metav1.CreateOptions{FieldManager: fieldManager})
+			if err != nil {
+				logger.Error(err, "Failed to create PlacementDecision")
+				retry = true
+				continue
+			}
+			logger.V(2).Info("Created PlacementDecision")
+		case err != nil:
+			logger.Error(err, "Failed to get PlacementDecision")
+			retry = true
+			continue
+		case have.GetLabels()[managedByLabelKey] != ManagedBy:
+			logger.Error(nil, "PlacementDecision name is taken by a PlacementDecision that KubeStellar does not manage")
+			continue
+		}
+		if apiequality.Semantic.DeepEqual(have.Object["status"], want.Object["status"]) {
+			continue
+		}
+		have = have.DeepCopy()
+		have.Object["status"] = want.Object["status"]
+		if _, err := client.UpdateStatus(ctx, have, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+			logger.Error(err, "Failed to update PlacementDecision status")
+			retry = true
+			continue
+		}
+		logger.V(2).Info("Updated PlacementDecision status")
+	}
+	return retry
+}
+
+// ownedSpecFields are the fields of a Placement's spec that are set from
+// the EdgePlacement. The others are left to the defaulting in the hub.
+var ownedSpecFields = []string{"clusterSets", "numberOfClusters", "predicates"}
+
+func ownedSpecEqual(have, want *unstructured.Unstructured) bool {
+	for _, field := range ownedSpecFields {
+		haveVal, _, _ := unstructured.NestedFieldNoCopy(have.Object, "spec", field)
+		wantVal, _, _ := unstructured.NestedFieldNoCopy(want.Object, "spec", field)
+		if !apiequality.Semantic.DeepEqual(haveVal, wantVal) {
+			return false
+		}
+	}
+	return true
+}
+
+func setLabel(obj *unstructured.Unstructured, key, value string) {
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	objLabels[key] = value
+	obj.SetLabels(objLabels)
+}
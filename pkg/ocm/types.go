@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ocm
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The types here mirror the parts of the OCM cluster API
+// (open-cluster-management.io/api/cluster/v1beta1) that the converters
+// deal with. They are only used to read and write unstructured objects;
+// fields that are not mirrored are preserved by neither direction.
+
+// GroupVersion is the API group and version of the OCM Placement API.
+var GroupVersion = schema.GroupVersion{Group: "cluster.open-cluster-management.io", Version: "v1beta1"}
+
+// PlacementGVR identifies the OCM Placement resource.
+var PlacementGVR = GroupVersion.WithResource("placements")
+
+// PlacementDecisionGVR identifies the OCM PlacementDecision resource.
+var PlacementDecisionGVR = GroupVersion.WithResource("placementdecisions")
+
+// PlacementLabelKey is the label with which OCM ties a
+// PlacementDecision to its Placement.
+const PlacementLabelKey = "cluster.open-cluster-management.io/placement"
+
+// Placement mirrors the OCM Placement type.
+type Placement struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PlacementSpec `json:"spec"`
+}
+
+// PlacementSpec mirrors the OCM PlacementSpec type.
+type PlacementSpec struct {
+	// ClusterSets restricts the clusters to those in the named ManagedClusterSets.
+	ClusterSets []string `json:"clusterSets,omitempty"`
+
+	// NumberOfClusters is the desired number of clusters to select; nil means all.
+	NumberOfClusters *int32 `json:"numberOfClusters,omitempty"`
+
+	// Predicates select the clusters that pass at least one of them.
+	// Empty list selects every cluster.
+	Predicates []ClusterPredicate `json:"predicates,omitempty"`
+}
+
+// ClusterPredicate mirrors the OCM ClusterPredicate type.
+type ClusterPredicate struct {
+	RequiredClusterSelector ClusterSelector `json:"requiredClusterSelector,omitempty"`
+}
+
+// ClusterSelector mirrors the OCM ClusterSelector type.
+type ClusterSelector struct {
+	LabelSelector metav1.LabelSelector  `json:"labelSelector,omitempty"`
+	ClaimSelector *ClusterClaimSelector `json:"claimSelector,omitempty"`
+}
+
+// ClusterClaimSelector mirrors the OCM ClusterClaimSelector type.
+type ClusterClaimSelector struct {
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// PlacementDecision mirrors the OCM PlacementDecision type.
+type PlacementDecision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status PlacementDecisionStatus `json:"status,omitempty"`
+}
+
+// PlacementDecisionStatus mirrors the OCM PlacementDecisionStatus type.
+type PlacementDecisionStatus struct {
+	Decisions []ClusterDecision `json:"decisions"`
+}
+
+// ClusterDecision mirrors the OCM ClusterDecision type.
+type ClusterDecision struct {
+	ClusterName string `json:"clusterName"`
+	Reason      string `json:"reason"`
+}
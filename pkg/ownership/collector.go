@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownership
+
+import (
+	"context"
+	"sync"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	msclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
+)
+
+var (
+	dependentsDeleted = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_ownership",
+		Name:           "dependents_deleted_total",
+		Help:           "Number of dependents deleted because their virtual owner is gone",
+		StabilityLevel: metrics.ALPHA,
+	})
+	dependentsAdopted = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_ownership",
+		Name:           "dependents_adopted_total",
+		Help:           "Number of dependents adopted by a re-created virtual owner",
+		StabilityLevel: metrics.ALPHA,
+	})
+)
+
+func init() {
+	legacyregistry.MustRegister(dependentsDeleted, dependentsAdopted)
+}
+
+// OwnerLookup reports whether the referenced owner exists and, if so, its UID.
+// The UID in the reference is ignored.
+// An error means that existence could not be determined.
+type OwnerLookup func(ctx context.Context, owner OwnerRef) (exists bool, uid types.UID, err error)
+
+// SpaceOwnerLookup returns an OwnerLookup that reads owners through the given space client.
+func SpaceOwnerLookup(spaceClient msclient.KubestellarSpaceInterface, spaceProviderNs string) OwnerLookup {
+	var mutex sync.Mutex
+	clients := map[string]k8sdynamic.Interface{}
+	return func(ctx context.Context, owner OwnerRef) (bool, types.UID, error) {
+		mutex.Lock()
+		client, have := clients[owner.Space]
+		if !have {
+			config, err := spaceClient.ConfigForSpace(owner.Space, spaceProviderNs)
+			if err != nil {
+				mutex.Unlock()
+				return false, "", err
+			}
+			client, err = k8sdynamic.NewForConfig(config)
+			if err != nil {
+				mutex.Unlock()
+				return false, "", err
+			}
+			clients[owner.Space] = client
+		}
+		mutex.Unlock()
+		var rscClient k8sdynamic.ResourceInterface = client.Resource(owner.GroupVersionResource())
+		if owner.Namespace != "" {
+			rscClient = client.Resource(owner.GroupVersionResource()).Namespace(owner.Namespace)
+		}
+		obj, err := rscClient.Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			if k8sapierrors.IsNotFound(err) {
+				return false, "", nil
+			}
+			return false, "", err
+		}
+		return true, obj.GetUID(), nil
+	}
+}
+
+// SweepResult counts what a Sweep did.
+type SweepResult struct {
+	Kept, Deleted, Adopted, Undetermined int
+}
+
+// Collector enforces the cascade and adoption rules on dependents.
+type Collector struct {
+	logger klog.Logger
+	lookup OwnerLookup
+	dryRun bool
+}
+
+// NewCollector makes a Collector that uses the given lookup to find owners.
+// With dryRun, the Collector only logs what it would do.
+func NewCollector(logger klog.Logger, lookup OwnerLookup, dryRun bool) *Collector {
+	return &Collector{logger: logger.WithName("ownership-collector"), lookup: lookup, dryRun: dryRun}
+}
+
+type lookupResult struct {
+	exists bool
+	uid    types.UID
+	err    error
+}
+
+// Sweep applies the rules to the dependents, among instances of the given
+// resources, in the space accessed by the given client.
+// A dependent whose owner's existence can not be determined is left alone.
+func (col *Collector) Sweep(ctx context.Context, client k8sdynamic.Interface, gvrs []schema.GroupVersionResource) (SweepResult, error) {
+	var result SweepResult
+	var lastErr error
+	looked := map[string]lookupResult{}
+	for _, gvr := range gvrs {
+		logger := col.logger.WithValues("resource", gvr)
+		list, err := client.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: AnyDependentSelector().String()})
+		if err != nil {
+			logger.Error(err, "Failed to list dependents")
+			lastErr = err
+			continue
+		}
+		for idx := range list.Items {
+			dependent := &list.Items[idx]
+			logger := logger.WithValues("namespace", dependent.GetNamespace(), "name", dependent.GetName())
+			owner, err := GetOwner(dependent)
+			if err != nil || owner == nil {
+				logger.V(3).Info("Ignoring dependent with malformed owner", "err", err)
+				result.Undetermined++
+				continue
+			}
+			key := owner.LabelValue()
+			found, have := looked[key]
+			if !have {
+				found.exists, found.uid, found.err = col.lookup(ctx, *owner)
+				looked[key] = found
+			}
+			if found.err != nil {
+				logger.V(3).Info("Could not determine whether owner exists", "owner", owner, "err", found.err)
+				result.Undetermined++
+				continue
+			}
+			rscClient := client.Resource(gvr).Namespace(dependent.GetNamespace())
+			switch Decide(dependent, *owner, found.exists, found.uid) {
+			case Keep:
+				result.Kept++
+			case Delete:
+				if col.dryRun {
+					logger.Info("Would delete dependent of missing owner", "owner", owner)
+					result.Deleted++
+					continue
+				}
+				uid, rv := dependent.GetUID(), dependent.GetResourceVersion()
+				err := rscClient.Delete(ctx, dependent.GetName(), metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid, ResourceVersion: &rv}})
+				if err != nil && !k8sapierrors.IsNotFound(err) {
+					logger.Error(err, "Failed to delete dependent of missing owner", "owner", owner)
+					lastErr = err
+					continue
+				}
+				dependentsDeleted.Inc()
+				logger.V(2).Info("Deleted dependent of missing owner", "owner", owner)
+				result.Deleted++
+			case Adopt:
+				newOwner := *owner
+				newOwner.UID = found.uid
+				if col.dryRun {
+					logger.Info("Would let re-created owner adopt dependent", "owner", newOwner)
+					result.Adopted++
+					continue
+				}
+				if err := SetOwner(dependent, newOwner); err != nil {
+					lastErr = err
+					continue
+				}
+				if _, err := rscClient.Update(ctx, dependent, metav1.UpdateOptions{}); err != nil {
+					logger.Error(err, "Failed to let re-created owner adopt dependent", "owner", newOwner)
+					lastErr = err
+					continue
+				}
+				dependentsAdopted.Inc()
+				logger.V(2).Info("Re-created owner adopted dependent", "owner", newOwner)
+				result.Adopted++
+			}
+		}
+	}
+	return result, lastErr
+}
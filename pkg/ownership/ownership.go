@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ownership implements virtual ownership, which plays the role
+// of ownerReferences for objects whose owner is in a different space
+// (ownerReferences do not cross kcp workspaces or clusters).
+//
+// A dependent object carries OwnerLabelKey, whose value is a hash of its
+// owner's identity so that all the dependents of an owner can be selected,
+// and OwnerAnnotationKey, whose value is the JSON encoding of the OwnerRef.
+// A Collector enforces the cascade: it deletes dependents whose owner is gone.
+// If the owner has been deleted and re-created (the UID differs), the
+// dependent is deleted unless it has AdoptAnnotationKey set to "true",
+// in which case the new owner adopts it.
+package ownership
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+const (
+	// OwnerLabelKey is the key of the label that identifies a dependent's owner.
+	OwnerLabelKey = "ownership.kubestellar.io/owner"
+
+	// OwnerAnnotationKey is the key of the annotation that holds the dependent's OwnerRef.
+	OwnerAnnotationKey = "ownership.kubestellar.io/owner-ref"
+
+	// AdoptAnnotationKey, when "true" on a dependent, lets a re-created owner adopt it.
+	AdoptAnnotationKey = "ownership.kubestellar.io/adoptable"
+)
+
+// OwnerRef identifies an owner, which may be in a different space than its dependents.
+type OwnerRef struct {
+	Space     string    `json:"space"`
+	Group     string    `json:"group,omitempty"`
+	Version   string    `json:"version"`
+	Resource  string    `json:"resource"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	UID       types.UID `json:"uid"`
+}
+
+// NewOwnerRef returns a reference to the given object, which is in the given space
+// and is an instance of the given resource.
+func NewOwnerRef(space string, gvr schema.GroupVersionResource, obj metav1.Object) OwnerRef {
+	return OwnerRef{Space: space, Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource,
+		Namespace: obj.GetNamespace(), Name: obj.GetName(), UID: obj.GetUID()}
+}
+
+// GroupVersionResource returns the resource of the owner.
+func (ref OwnerRef) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: ref.Group, Version: ref.Version, Resource: ref.Resource}
+}
+
+// LabelValue returns the value of OwnerLabelKey on the owner's dependents.
+// It does not depend on the owner's UID or the version of its resource.
+func (ref OwnerRef) LabelValue() string {
+	return naming.Hash(ref.Space, ref.Group, ref.Resource, ref.Namespace, ref.Name)
+}
+
+func (ref OwnerRef) String() string {
+	return fmt.Sprintf("%s:%s.%s/%s/%s/%s(%s)", ref.Space, ref.Resource, ref.Group, ref.Version, ref.Namespace, ref.Name, ref.UID)
+}
+
+// SetOwner marks the given object as a dependent of the given owner.
+func SetOwner(obj metav1.Object, owner OwnerRef) error {
+	ownerBytes, err := json.Marshal(owner)
+	if err != nil {
+		return err
+	}
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = map[string]string{}
+	}
+	objLabels[OwnerLabelKey] = owner.LabelValue()
+	obj.SetLabels(objLabels)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[OwnerAnnotationKey] = string(ownerBytes)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// GetOwner returns the owner of the given object, or nil if it has none.
+func GetOwner(obj metav1.Object) (*OwnerRef, error) {
+	ownerStr, have := obj.GetAnnotations()[OwnerAnnotationKey]
+	if !have {
+		return nil, nil
+	}
+	var owner OwnerRef
+	if err := json.Unmarshal([]byte(ownerStr), &owner); err != nil {
+		return nil, fmt.Errorf("failed to parse %s annotation: %w", OwnerAnnotationKey, err)
+	}
+	if obj.GetLabels()[OwnerLabelKey] != owner.LabelValue() {
+		return nil, fmt.Errorf("%s label does not match %s annotation", OwnerLabelKey, OwnerAnnotationKey)
+	}
+	return &owner, nil
+}
+
+// DependentsSelector selects the dependents of the given owner.
+func DependentsSelector(owner OwnerRef) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{OwnerLabelKey: owner.LabelValue()})
+}
+
+// AnyDependentSelector selects every object that has a virtual owner.
+func AnyDependentSelector() labels.Selector {
+	req, err := labels.NewRequirement(OwnerLabelKey, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+	return labels.NewSelector().Add(*req)
+}
+
+// Decision is what to do with a dependent.
+type Decision string
+
+const (
+	Keep   Decision = "Keep"
+	Delete Decision = "Delete"
+	Adopt  Decision = "Adopt"
+)
+
+// Decide applies the cascade and adoption rules to a dependent, given
+// whether its owner exists and, if so, the owner's current UID.
+func Decide(dependent metav1.Object, owner OwnerRef, ownerExists bool, ownerUID types.UID) Decision {
+	switch {
+	case !ownerExists:
+		return Delete
+	case ownerUID == owner.UID:
+		return Keep
+	case dependent.GetAnnotations()[AdoptAnnotationKey] == "true":
+		return Adopt
+	default:
+		return Delete
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownership
+
+import (
+	"context"
+	"testing"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/klog/v2"
+)
+
+var cmGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func newDependent(name string, owner *OwnerRef, adoptable bool) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"namespace": "ns1", "name": name, "uid": "dep-" + name},
+	}}
+	if owner != nil {
+		if err := SetOwner(obj, *owner); err != nil {
+			panic(err)
+		}
+	}
+	if adoptable {
+		annotations := obj.GetAnnotations()
+		annotations[AdoptAnnotationKey] = "true"
+		obj.SetAnnotations(annotations)
+	}
+	return obj
+}
+
+func TestSetGetOwner(t *testing.T) {
+	owner := OwnerRef{Space: "wds1", Group: "apps", Version: "v1", Resource: "deployments", Namespace: "ns1", Name: "d1", UID: "u1"}
+	obj := newDependent("cm1", &owner, false)
+	got, err := GetOwner(obj)
+	if err != nil || got == nil || *got != owner {
+		t.Fatalf("GetOwner returned %v, %v; expected %v", got, err, owner)
+	}
+	if !DependentsSelector(owner).Matches(labels.Set(obj.GetLabels())) || !AnyDependentSelector().Matches(labels.Set(obj.GetLabels())) {
+		t.Errorf("Selectors do not match dependent")
+	}
+	other := owner
+	other.Name = "d2"
+	if DependentsSelector(other).Matches(labels.Set(obj.GetLabels())) {
+		t.Errorf("Selector for another owner matches dependent")
+	}
+	if got, err := GetOwner(newDependent("cm2", nil, false)); got != nil || err != nil {
+		t.Errorf("Expected no owner, got %v, %v", got, err)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	present := OwnerRef{Space: "wds1", Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "present", UID: "u1"}
+	gone := OwnerRef{Space: "wds1", Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "gone", UID: "u2"}
+	recreated := OwnerRef{Space: "wds1", Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "recreated", UID: "u3"}
+	unknown := OwnerRef{Space: "wds2", Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "unknown", UID: "u4"}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newDependent("keep", &present, false),
+		newDependent("orphan", &gone, false),
+		newDependent("stale", &recreated, false),
+		newDependent("adopt", &recreated, true),
+		newDependent("unsure", &unknown, false),
+		newDependent("unowned", nil, false),
+	)
+	lookup := func(ctx context.Context, owner OwnerRef) (bool, types.UID, error) {
+		switch owner.Name {
+		case "present":
+			return true, "u1", nil
+		case "recreated":
+			return true, "u3-new", nil
+		case "unknown":
+			return false, "", k8sapierrors.NewServiceUnavailable("space unreachable")
+		default:
+			return false, "", nil
+		}
+	}
+	result, err := NewCollector(klog.Background(), lookup, false).Sweep(context.Background(), client, []schema.GroupVersionResource{cmGVR})
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	expected := SweepResult{Kept: 1, Deleted: 2, Adopted: 1, Undetermined: 1}
+	if result != expected {
+		t.Errorf("Sweep result %+v, expected %+v", result, expected)
+	}
+	cmClient := client.Resource(cmGVR).Namespace("ns1")
+	for name, shouldExist := range map[string]bool{"keep": true, "orphan": false, "stale": false, "adopt": true, "unsure": true, "unowned": true} {
+		_, err := cmClient.Get(context.Background(), name, metav1.GetOptions{})
+		if exists := err == nil; exists != shouldExist {
+			t.Errorf("Object %s exists=%v, expected %v (err=%v)", name, exists, shouldExist, err)
+		}
+	}
+	adopted, err := cmClient.Get(context.Background(), "adopt", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get adopted object: %v", err)
+	}
+	if owner, err := GetOwner(adopted); err != nil || owner == nil || owner.UID != "u3-new" {
+		t.Errorf("Adopted object has owner %v, %v; expected UID u3-new", owner, err)
+	}
+}
+
+func TestSweepDryRun(t *testing.T) {
+	gone := OwnerRef{Space: "wds1", Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "gone", UID: "u1"}
+	recreated := OwnerRef{Space: "wds1", Version: "v1", Resource: "secrets", Namespace: "ns1", Name: "recreated", UID: "u2"}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newDependent("orphan", &gone, false),
+		newDependent("adopt", &recreated, true),
+	)
+	lookups := 0
+	lookup := func(ctx context.Context, owner OwnerRef) (bool, types.UID, error) {
+		lookups++
+		if owner.Name == "recreated" {
+			return true, "u2-new", nil
+		}
+		return false, "", nil
+	}
+	result, err := NewCollector(klog.Background(), lookup, true).Sweep(context.Background(), client, []schema.GroupVersionResource{cmGVR})
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	expected := SweepResult{Deleted: 1, Adopted: 1}
+	if result != expected {
+		t.Errorf("Sweep result %+v, expected %+v", result, expected)
+	}
+	if lookups != 2 {
+		t.Errorf("Expected one lookup per owner, got %d", lookups)
+	}
+	cmClient := client.Resource(cmGVR).Namespace("ns1")
+	if _, err := cmClient.Get(context.Background(), "orphan", metav1.GetOptions{}); err != nil {
+		t.Errorf("Dry run deleted a dependent: %v", err)
+	}
+	adopted, err := cmClient.Get(context.Background(), "adopt", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get dependent: %v", err)
+	}
+	if owner, err := GetOwner(adopted); err != nil || owner == nil || owner.UID != "u2" {
+		t.Errorf("Dry run changed owner to %v, %v; expected UID u2", owner, err)
+	}
+}
@@ -33,6 +33,7 @@ import (
 
 	ksmetav1a1 "github.com/kubestellar/kubestellar/pkg/apis/meta/v1alpha1"
 	apiwatch "github.com/kubestellar/kubestellar/pkg/apiwatch"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
 	msclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
 )
 
@@ -42,6 +43,8 @@ func NewAPIWatchMapProvider(ctx context.Context,
 	numThreads int,
 	spaceclient msclient.KubestellarSpaceInterface,
 	spaceProviderNs string,
+	// may be nil
+	discoveryCache apiwatch.DiscoveryCache,
 ) APIWatchMapProvider {
 	awp := &apiWatchProvider{
 		context:         ctx,
@@ -50,6 +53,7 @@ func NewAPIWatchMapProvider(ctx context.Context,
 		perCluster:      NewMapMap[string, *apiWatchProviderPerCluster](nil),
 		spaceclient:     spaceclient,
 		spaceProviderNs: spaceProviderNs,
+		discoveryCache:  discoveryCache,
 	}
 	return awp
 }
@@ -69,6 +73,7 @@ type apiWatchProvider struct {
 	perCluster      MutableMap[string, *apiWatchProviderPerCluster]
 	spaceclient     msclient.KubestellarSpaceInterface
 	spaceProviderNs string
+	discoveryCache  apiwatch.DiscoveryCache // may be nil
 }
 
 func (awp *apiWatchProvider) AddReceivers(clusterName string,
@@ -106,8 +111,9 @@ func (awp *apiWatchProvider) AddReceivers(clusterName string,
 		crdInformer := apiextFactory.Apiextensions().V1().CustomResourceDefinitions().Informer()
 		apiextFactory.Start(ctx.Done())
 
-		wpc.informer, wpc.lister, _ = apiwatch.NewAPIResourceInformer(ctx, clusterName, discoveryScopedClient, false, crdInformer)
-		wpc.informer.AddEventHandler(wpc)
+		wpc.informer, wpc.lister, _ = apiwatch.NewAPIResourceInformerWithOptions(ctx, clusterName, discoveryScopedClient,
+			apiwatch.APIResourceInformerOptions{DiscoveryCache: awp.discoveryCache}, crdInformer)
+		wpc.informer.AddEventHandler(recovery.Handler(logger, recoveryNameAPIWatch, wpc))
 		go wpc.informer.Run(ctx.Done())
 		return wpc
 	})
@@ -218,7 +224,10 @@ func (ctl *apiWatchProvider) sync1(ctx context.Context, ref any) {
 	defer ctl.queue.Done(ref)
 	logger := klog.FromContext(ctx)
 	logger.V(4).Info("Dequeued reference", "ref", ref)
-	retry := ctl.sync(ctx, ref)
+	var retry bool
+	if recovery.Guard(logger, recoveryNameAPIWatch, ref, func() { retry = ctl.sync(ctx, ref) }) {
+		retry = true
+	}
 	if retry {
 		ctl.queue.AddRateLimited(ref)
 	} else {
@@ -265,6 +274,10 @@ func (awp *apiWatchProvider) syncResourceRef(ctx context.Context, rr resourceRef
 // into a receiver of the external representation from apiwatch.
 func externalizeReceiver(receiver MappingReceiver[metav1.GroupResource, ResourceDetails]) func(metarsc *ksmetav1a1.APIResource) {
 	return func(metarsc *ksmetav1a1.APIResource) {
+		if !metarsc.Spec.Preferred {
+			// Only the preferred version is of interest here
+			return
+		}
 		key := metav1.GroupResource{Group: metarsc.Spec.Group, Resource: metarsc.Spec.Name}
 		val := ResourceDetails{
 			Namespaced:        metarsc.Spec.Namespaced,
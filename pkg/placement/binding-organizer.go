@@ -174,6 +174,8 @@ func detailsSansEP(versions Map[ObjectName /*epName*/, WorkloadPartDetails]) (Wo
 		ans.APIVersion = pair.Second.APIVersion // should be the same for every pair in versions
 		ans.ReturnSingletonState = ans.ReturnSingletonState || pair.Second.ReturnSingletonState
 		ans.CreateOnly = ans.CreateOnly || pair.Second.CreateOnly
+		ans.ImmutableFieldPolicy = combineImmutableFieldPolicies(ans.ImmutableFieldPolicy, pair.Second.ImmutableFieldPolicy)
+		ans.LocalProbes = combineLocalProbes(ans.LocalProbes, pair.Second.LocalProbes)
 		return nil
 	})
 	return ans, true
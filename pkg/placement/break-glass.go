@@ -0,0 +1,97 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/breakglass"
+)
+
+// breakGlassTracker remembers the break-glass overrides in force on
+// copies in mailbox spaces. See package breakglass.
+type breakGlassTracker struct {
+	// maxWindow bounds how long one override lasts, counting from when
+	// it was noticed. Zero means that overrides are not honored.
+	maxWindow time.Duration
+	now       func() time.Time
+
+	mutex sync.Mutex
+	// noticed maps each copy under override to when the override was noticed
+	noticed map[mailboxWriteKey]time.Time
+}
+
+func newBreakGlassTracker() *breakGlassTracker {
+	return &breakGlassTracker{maxWindow: breakglass.DefaultMaxWindow, now: time.Now, noticed: map[mailboxWriteKey]time.Time{}}
+}
+
+// setMaxBreakGlassWindow bounds how long the workload projector honors
+// a break-glass override on a copy in a mailbox space. Zero means that
+// such overrides are ignored.
+func (wp *workloadProjector) setMaxBreakGlassWindow(maxWindow time.Duration) {
+	wp.breakGlass.maxWindow = maxWindow
+}
+
+// breakGlassUntil tells whether the given copy is under a break-glass
+// override, and if so then until when. The start and end of each
+// override are recorded in Events on the source object, and the source
+// is enqueued to be synced again when the override ends.
+func (wp *workloadProjector) breakGlassUntil(logger klog.Logger, soRef sourceObjectRef, wkey mailboxWriteKey, srcObj mrObject, destObj metav1.Object) (time.Time, bool) {
+	bg := wp.breakGlass
+	if bg.maxWindow == 0 {
+		return time.Time{}, false
+	}
+	now := bg.now()
+	override, err := breakglass.Get(destObj, now, bg.maxWindow)
+	if err != nil {
+		logger.Error(err, "Ignoring invalid break-glass override of object in mailbox workspace")
+	}
+	bg.mutex.Lock()
+	defer bg.mutex.Unlock()
+	noticed, known := bg.noticed[wkey]
+	if !override.InForce(now) || known && now.Sub(noticed) >= bg.maxWindow {
+		if known {
+			delete(bg.noticed, wkey)
+			logger.Info("Break-glass override of object in mailbox workspace ended")
+			wp.events.BreakGlassEnded(soRef.Cluster, srcObj, destinationName(wkey.Destination))
+		}
+		return time.Time{}, false
+	}
+	if !known {
+		noticed = now
+		bg.noticed[wkey] = now
+		logger.Info("Break-glass override of object in mailbox workspace started", "until", override.Until, "reason", override.Reason)
+		wp.events.BreakGlassStarted(soRef.Cluster, srcObj, destinationName(wkey.Destination), override.Until, override.Reason)
+	}
+	until := override.Until
+	if limit := noticed.Add(bg.maxWindow); limit.Before(until) {
+		until = limit
+	}
+	wp.queue.AddAfter(soRef, until.Sub(now))
+	return until, true
+}
+
+// forget drops what is remembered about the given copy, which is going away.
+func (bg *breakGlassTracker) forget(wkey mailboxWriteKey) {
+	bg.mutex.Lock()
+	defer bg.mutex.Unlock()
+	delete(bg.noticed, wkey)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copyMeta of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/breakglass"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+)
+
+func TestBreakGlassUntil(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	wp := &workloadProjector{
+		queue:      coalesce.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test-break-glass", coalesce.Options{}),
+		breakGlass: newBreakGlassTracker(),
+	}
+	defer wp.queue.ShutDown()
+	wp.breakGlass.maxWindow = 2 * time.Hour
+	wp.breakGlass.now = func() time.Time { return now }
+	logger := klog.Background()
+	soRef := sourceObjectRef{Cluster: "wds1", Namespace: "shop", Name: "settings"}
+	wkey := mailboxWriteKey{Destination: SinglePlacement{Cluster: "inv1", SyncTargetName: "edge1"}, Namespace: "shop", Name: "settings"}
+	copyMeta := &metav1.ObjectMeta{Annotations: map[string]string{
+		breakglass.UntilAnnotationKey:  "2023-09-01T13:30:00Z",
+		breakglass.ReasonAnnotationKey: "hotfix",
+	}}
+
+	until, overridden := wp.breakGlassUntil(logger, soRef, wkey, nil, copyMeta)
+	if !overridden || !until.Equal(now.Add(90*time.Minute)) {
+		t.Errorf("Expected an override until 13:30, got %v %v", overridden, until)
+	}
+
+	// Pushing the end out is capped at the window from when the override was noticed.
+	copyMeta.Annotations[breakglass.UntilAnnotationKey] = "2023-09-01T15:00:00Z"
+	now = now.Add(time.Hour)
+	until, overridden = wp.breakGlassUntil(logger, soRef, wkey, nil, copyMeta)
+	if !overridden || !until.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected the override to be capped at 14:00, got %v %v", overridden, until)
+	}
+	now = now.Add(time.Hour)
+	if _, overridden = wp.breakGlassUntil(logger, soRef, wkey, nil, copyMeta); overridden {
+		t.Error("Expected the override to have ended at the end of the window")
+	}
+
+	// Without a reason there is no override.
+	delete(copyMeta.Annotations, breakglass.ReasonAnnotationKey)
+	if _, overridden = wp.breakGlassUntil(logger, soRef, wkey, nil, copyMeta); overridden {
+		t.Error("Expected an override without a reason to be ignored")
+	}
+}
@@ -0,0 +1,350 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+
+	k8scorev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	k8scorev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/bundle"
+)
+
+// When the number of objects going to a destination exceeds the
+// workload projector's bundleThreshold, the projector puts new and
+// changed objects into compressed bundles (see package bundle) in the
+// mailbox space instead of writing them individually, and removes the
+// individual copies.  The syncer unpacks the bundles.
+// Objects that ask for singleton reported state or are create-only are
+// never bundled, because those features work through the individual copy.
+
+// bundleKey identifies an object in a destination's bundled set.
+// Namespace is noNamespace for a cluster-scoped object.
+type bundleKey struct {
+	GroupResource metav1.GroupResource
+	Namespace     string
+	Name          string
+}
+
+func (key bundleKey) namespaced() bool {
+	return key.Namespace != noNamespace
+}
+
+type bundledObject struct {
+	obj *unstructured.Unstructured
+
+	// individualMayExist is true when the mailbox space may still hold
+	// an individual copy of the object.
+	individualMayExist bool
+}
+
+// destinationBundleRef is a queue item saying to rewrite the bundles of a destination.
+type destinationBundleRef struct {
+	Destination SinglePlacement
+}
+
+// shouldBundleLocked tells whether new and changed objects going to this destination should be bundled.
+func (wpd *wpPerDestination) shouldBundleLocked() bool {
+	threshold := wpd.wp.bundleThreshold
+	return threshold > 0 && wpd.nsdDistributions.Len()+wpd.nnsDistributions.Len() > threshold
+}
+
+// setBundled puts the given object into, or if obj is nil removes it from,
+// the destination's bundled set.  Returns whether the set changed.
+func (wpd *wpPerDestination) setBundled(key bundleKey, obj *unstructured.Unstructured) bool {
+	wpd.bundleMutex.Lock()
+	defer wpd.bundleMutex.Unlock()
+	old, had := wpd.bundled[key]
+	if obj == nil {
+		delete(wpd.bundled, key)
+		return had
+	}
+	if had && apiequality.Semantic.DeepEqual(old.obj, obj) {
+		return false
+	}
+	wpd.bundled[key] = bundledObject{obj: obj, individualMayExist: !had || old.individualMayExist}
+	return true
+}
+
+// bundleTrier returns the work, to be done outside the mutex, of
+// putting the given source object into the destination's bundled set.
+// Returns `retry bool`.
+func (wpd *wpPerDestination) bundleTrier(ctx context.Context, logger klog.Logger, soRef sourceObjectRef, srcMRObject mrObject, deleted bool, clientReadyChan <-chan struct{}) func() bool {
+	wp := wpd.wp
+	key := bundleKey{GroupResource: soRef.GroupResource, Namespace: soRef.Namespace, Name: soRef.Name}
+	return func() bool {
+		if deleted {
+			if wpd.setBundled(key, nil) {
+				logger.V(3).Info("Removed object from bundle")
+				wp.queue.Add(destinationBundleRef{wpd.destination})
+			}
+			return false
+		}
+		if key.namespaced() && wpd.ensureNamespace(ctx, logger, soRef.Namespace, clientReadyChan) {
+			return true
+		}
+		destObj := wp.xformForDestination(soRef.Cluster, wpd.destination, srcMRObject)
+		if !wp.podSecurityAdmits(logger, soRef.Cluster, srcMRObject, wpd.destination, destObj) {
+			return false
+		}
+		if err := bundle.Packable(destObj); err != nil {
+			logger.Error(err, "Can not put object in bundle")
+			wp.events.DestinationFailed(soRef.Cluster, srcMRObject, destinationName(wpd.destination), err)
+			return false
+		}
+		if wpd.setBundled(key, destObj) {
+			logger.V(3).Info("Put object in bundle")
+			wp.usage.recordWrite(soRef.Cluster, destObj)
+			wp.queue.Add(destinationBundleRef{wpd.destination})
+		}
+		return false
+	}
+}
+
+// unbundleLocked removes the given object from the bundled set, if it is there,
+// because it is now being projected individually.
+func (wpd *wpPerDestination) unbundleLocked(key bundleKey) {
+	if wpd.setBundled(key, nil) {
+		wpd.wp.queue.Add(destinationBundleRef{wpd.destination})
+	}
+}
+
+// wantsLocked tells whether any source wants the given object to go to this destination.
+func (wpd *wpPerDestination) wantsLocked(key bundleKey) bool {
+	var sourcesWants sourcesWantReturns
+	var haveSources bool
+	if key.namespaced() {
+		sourcesWants, haveSources = wpd.nsdDistributions.GetIndex().Get(NewPair(key.GroupResource, NamespacedName{NamespaceName(key.Namespace), ObjectName(key.Name)}))
+	} else {
+		sourcesWants, haveSources = wpd.nnsDistributions.GetIndex().Get(NewPair(key.GroupResource, ObjectName(key.Name)))
+	}
+	return haveSources && !sourcesWants.IsEmpty()
+}
+
+// syncBundles brings the bundles in the destination's mailbox space up to date.
+// Returns `retry bool`.
+func (wp *workloadProjector) syncBundles(ctx context.Context, ref destinationBundleRef) bool {
+	logger := klog.FromContext(ctx).WithValues("destination", ref.Destination)
+	var wpd *wpPerDestination
+	var configMapClient k8scorev1client.ConfigMapInterface
+	var namespaceClient k8scorev1client.NamespaceInterface
+	var dynamicClient k8sdynamic.Interface
+	func() {
+		wp.Lock()
+		defer wp.Unlock()
+		var have bool
+		wpd, have = wp.perDestination.Get(ref.Destination)
+		if have {
+			configMapClient, namespaceClient, dynamicClient = wpd.configMapClient, wpd.namespaceClient, wpd.dynamicClient
+		}
+	}()
+	if wpd == nil {
+		logger.V(4).Info("Ignoring bundles of unknown destination")
+		return false
+	}
+	if configMapClient == nil {
+		logger.V(4).Info("Mailbox space clients not made yet")
+		return true
+	}
+	if wpd.loadBundles(ctx, logger, configMapClient) {
+		return true
+	}
+	wp.dropUnwantedBundled(logger, wpd)
+	objs := map[bundle.ObjectRef]*unstructured.Unstructured{}
+	individuals := map[bundleKey]*unstructured.Unstructured{}
+	func() {
+		wpd.bundleMutex.Lock()
+		defer wpd.bundleMutex.Unlock()
+		for key, bo := range wpd.bundled {
+			objs[bundle.RefOf(key.GroupResource.Resource, bo.obj)] = bo.obj
+			if bo.individualMayExist {
+				individuals[key] = bo.obj
+			}
+		}
+	}()
+	if len(objs) == 0 {
+		err := configMapClient.DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: bundle.LabelKey})
+		if err != nil && !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete bundles from mailbox space")
+			return true
+		}
+		logger.V(3).Info("No bundled objects remain")
+		return false
+	}
+	indexCM, parts, err := bundle.Pack(objs)
+	if err != nil {
+		// Every bundled object passed bundle.Packable, so this is unexpected
+		logger.Error(err, "Failed to pack bundles")
+		return true
+	}
+	nsObj := &k8scorev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: bundle.Namespace}}
+	if _, err := namespaceClient.Create(ctx, nsObj, metav1.CreateOptions{FieldManager: FieldManager}); err != nil && !k8sapierrors.IsAlreadyExists(err) {
+		logger.Error(err, "Failed to create namespace for bundles in mailbox space")
+		return true
+	}
+	oldParts, err := configMapClient.List(ctx, metav1.ListOptions{LabelSelector: bundle.LabelKey + "=" + bundle.LabelValPart})
+	if err != nil {
+		logger.Error(err, "Failed to list bundle parts in mailbox space")
+		return true
+	}
+	stale := map[string]struct{}{}
+	for _, part := range oldParts.Items {
+		stale[part.Name] = struct{}{}
+	}
+	for _, part := range parts {
+		if _, has := stale[part.Name]; has {
+			delete(stale, part.Name)
+			continue
+		}
+		_, err := configMapClient.Create(ctx, part, metav1.CreateOptions{FieldManager: FieldManager})
+		if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+			logger.Error(err, "Failed to create bundle part in mailbox space", "part", part.Name)
+			return true
+		}
+	}
+	oldIndex, err := configMapClient.Get(ctx, bundle.IndexName, metav1.GetOptions{})
+	if err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to fetch bundle index from mailbox space")
+			return true
+		}
+		if _, err := configMapClient.Create(ctx, indexCM, metav1.CreateOptions{FieldManager: FieldManager}); err != nil {
+			logger.Error(err, "Failed to create bundle index in mailbox space")
+			return true
+		}
+	} else if !apiequality.Semantic.DeepEqual(oldIndex.Data, indexCM.Data) {
+		indexCM.ResourceVersion = oldIndex.ResourceVersion
+		if _, err := configMapClient.Update(ctx, indexCM, metav1.UpdateOptions{FieldManager: FieldManager}); err != nil {
+			logger.Error(err, "Failed to update bundle index in mailbox space")
+			return true
+		}
+	}
+	logger.V(3).Info("Wrote bundles", "objects", len(objs), "parts", len(parts))
+	retry := false
+	for partName := range stale {
+		err := configMapClient.Delete(ctx, partName, metav1.DeleteOptions{})
+		if err != nil && !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete stale bundle part from mailbox space", "part", partName)
+			retry = true
+		}
+	}
+	for key, obj := range individuals {
+		wp.checkpointer.Forget(checkpointKeyFor(wpd.destination, key.GroupResource, key.Namespace, key.Name))
+		rscClient := dynamicClient.Resource(MetaGroupResourceToSchema(key.GroupResource).WithVersion(obj.GroupVersionKind().Version))
+		var err error
+		if key.namespaced() {
+			err = rscClient.Namespace(key.Namespace).Delete(ctx, key.Name, metav1.DeleteOptions{})
+		} else {
+			err = rscClient.Delete(ctx, key.Name, metav1.DeleteOptions{})
+		}
+		if err != nil && !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete individual copy of bundled object", "key", key)
+			retry = true
+			continue
+		}
+		wpd.clearIndividual(key, obj)
+	}
+	return retry
+}
+
+// clearIndividual notes that the individual copy of the given bundled object is gone,
+// unless the bundled object has changed meanwhile.
+func (wpd *wpPerDestination) clearIndividual(key bundleKey, obj *unstructured.Unstructured) {
+	wpd.bundleMutex.Lock()
+	defer wpd.bundleMutex.Unlock()
+	if bo, has := wpd.bundled[key]; has && bo.obj == obj {
+		bo.individualMayExist = false
+		wpd.bundled[key] = bo
+	}
+}
+
+// dropUnwantedBundled removes from the bundled set the objects that no source wants any more.
+func (wp *workloadProjector) dropUnwantedBundled(logger klog.Logger, wpd *wpPerDestination) {
+	wp.Lock()
+	defer wp.Unlock()
+	wpd.bundleMutex.Lock()
+	defer wpd.bundleMutex.Unlock()
+	for key := range wpd.bundled {
+		if !wpd.wantsLocked(key) {
+			logger.V(3).Info("Dropping undesired object from bundle", "key", key)
+			delete(wpd.bundled, key)
+		}
+	}
+}
+
+// loadBundles reads the existing bundles from the mailbox space, the first time
+// this is called for the destination, so that a restart of the projector does
+// not forget objects that it bundled earlier.  An object already in the
+// bundled set is not overwritten.
+// Returns `retry bool`.
+func (wpd *wpPerDestination) loadBundles(ctx context.Context, logger klog.Logger, configMapClient k8scorev1client.ConfigMapInterface) bool {
+	wpd.bundleMutex.Lock()
+	loaded := wpd.bundlesLoaded
+	wpd.bundleMutex.Unlock()
+	if loaded {
+		return false
+	}
+	found := map[bundleKey]*unstructured.Unstructured{}
+	indexCM, err := configMapClient.Get(ctx, bundle.IndexName, metav1.GetOptions{})
+	if err != nil && !k8sapierrors.IsNotFound(err) {
+		logger.Error(err, "Failed to fetch bundle index from mailbox space")
+		return true
+	} else if err == nil {
+		index, err := bundle.ParseIndex(indexCM)
+		if err != nil {
+			logger.Error(err, "Ignoring malformed bundle index")
+			index = bundle.Index{}
+		}
+		for _, indexPart := range index.Parts {
+			part, err := configMapClient.Get(ctx, indexPart.Name, metav1.GetOptions{})
+			if err != nil {
+				logger.Error(err, "Failed to fetch bundle part from mailbox space", "part", indexPart.Name)
+				return true
+			}
+			objs, err := bundle.Unpack(part)
+			if err != nil || len(objs) != len(indexPart.Objects) {
+				logger.Error(err, "Ignoring malformed bundle part", "part", indexPart.Name)
+				continue
+			}
+			for idx, obj := range objs {
+				ref := indexPart.Objects[idx]
+				namespace := ref.Namespace
+				if namespace == "" {
+					namespace = noNamespace
+				}
+				found[bundleKey{metav1.GroupResource{Group: ref.Group, Resource: ref.Resource}, namespace, ref.Name}] = obj
+			}
+		}
+	}
+	wpd.bundleMutex.Lock()
+	defer wpd.bundleMutex.Unlock()
+	for key, obj := range found {
+		if _, has := wpd.bundled[key]; !has {
+			wpd.bundled[key] = bundledObject{obj: obj}
+		}
+	}
+	wpd.bundlesLoaded = true
+	logger.V(3).Info("Loaded existing bundles", "objects", len(found))
+	return false
+}
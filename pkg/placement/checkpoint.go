@@ -0,0 +1,295 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8scache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/bundle"
+)
+
+// checkpointVersion identifies the format of the checkpoint file.
+// Version 1 had no resourceVersion.
+const checkpointVersion = 2
+
+// checkpointKey identifies an object that was projected into a mailbox space.
+type checkpointKey struct {
+	Mailbox   string `json:"mailbox"`
+	Group     string `json:"group,omitempty"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func checkpointKeyFor(destination SinglePlacement, gr metav1.GroupResource, namespace, name string) checkpointKey {
+	if namespace == noNamespace {
+		namespace = ""
+	}
+	return checkpointKey{Mailbox: SPMailboxWorkspaceName(destination), Group: gr.Group, Resource: gr.Resource, Namespace: namespace, Name: name}
+}
+
+// checkpointState is what was written for one object: the hash of the
+// content and the resourceVersion that the mailbox space gave the result.
+type checkpointState struct {
+	Hash            string `json:"hash"`
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+type checkpointEntry struct {
+	checkpointKey
+	checkpointState
+}
+
+type checkpointFile struct {
+	Version int               `json:"version"`
+	Entries []checkpointEntry `json:"entries"`
+}
+
+// checkpointer remembers, across restarts of the placement translator,
+// the hash of what was last written for each object projected into a
+// mailbox space, and the resourceVersion of the result.
+// After a restart, an object whose desired state still has the remembered
+// hash, and whose copy in the mailbox space still has the remembered
+// resourceVersion (so nobody else has changed it), does not need to be
+// fetched from its mailbox space and compared, which lets the translator
+// get through the whole fleet quickly.
+// The record is kept in a gzipped JSON file that is rewritten periodically.
+//
+// Only this per-object record is kept. The resolved placement maps (which
+// objects go to which destinations) are not: they are rebuilt after a
+// restart from the informer caches, without any writes, which is cheap
+// next to fetching and comparing every copy in every mailbox space.
+//
+// An entry from the previous run is consulted at most once; after that the
+// regular fetch-and-compare is done.
+// Entries that are not revisited before the next save are dropped,
+// which only costs a slower restart.
+type checkpointer struct {
+	logger klog.Logger
+	path   string
+	period time.Duration
+
+	mutex    sync.Mutex
+	restored map[checkpointKey]checkpointState
+	current  map[checkpointKey]checkpointState
+	dirty    bool
+}
+
+// newCheckpointer loads the checkpoint in the given file, if any.
+// The file is saved every period once Run.
+// Returns nil if path is empty.
+func newCheckpointer(logger klog.Logger, path string, period time.Duration) *checkpointer {
+	if path == "" {
+		return nil
+	}
+	cp := &checkpointer{
+		logger:   logger.WithValues("checkpointFile", path),
+		path:     path,
+		period:   period,
+		restored: map[checkpointKey]checkpointState{},
+		current:  map[checkpointKey]checkpointState{},
+	}
+	if err := cp.load(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			cp.logger.V(2).Info("No checkpoint to restore")
+		} else {
+			cp.logger.Error(err, "Failed to load checkpoint, starting without it")
+		}
+	} else {
+		cp.logger.V(2).Info("Restored checkpoint", "entries", len(cp.restored))
+	}
+	return cp
+}
+
+func (cp *checkpointer) load() error {
+	file, err := os.Open(cp.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	var contents checkpointFile
+	if err := json.NewDecoder(gzr).Decode(&contents); err != nil {
+		return err
+	}
+	if contents.Version != checkpointVersion {
+		return fmt.Errorf("checkpoint has version %d but only %d is supported", contents.Version, checkpointVersion)
+	}
+	for _, entry := range contents.Entries {
+		cp.restored[entry.checkpointKey] = entry.checkpointState
+	}
+	return nil
+}
+
+// Restored tells whether the previous run recorded the given hash for the
+// given object, with the given resourceVersion of its copy in the mailbox space.
+// If so then the entry is carried over into the current record.
+func (cp *checkpointer) Restored(key checkpointKey, hash, resourceVersion string) bool {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	restored, have := cp.restored[key]
+	if !have {
+		return false
+	}
+	delete(cp.restored, key)
+	if restored != (checkpointState{Hash: hash, ResourceVersion: resourceVersion}) {
+		return false
+	}
+	cp.current[key] = restored
+	cp.dirty = true
+	return true
+}
+
+// Record notes that the object in the mailbox space is up to date with the
+// given hash, and has the given resourceVersion.
+// Does nothing if cp is nil or hash or resourceVersion is empty.
+func (cp *checkpointer) Record(key checkpointKey, hash, resourceVersion string) {
+	if cp == nil || hash == "" || resourceVersion == "" {
+		return
+	}
+	state := checkpointState{Hash: hash, ResourceVersion: resourceVersion}
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	delete(cp.restored, key)
+	if cp.current[key] == state {
+		return
+	}
+	cp.current[key] = state
+	cp.dirty = true
+}
+
+// Forget notes that the object is no longer in the mailbox space, or is in an unknown state.
+// Does nothing if cp is nil.
+func (cp *checkpointer) Forget(key checkpointKey) {
+	if cp == nil {
+		return
+	}
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	delete(cp.restored, key)
+	if _, have := cp.current[key]; !have {
+		return
+	}
+	delete(cp.current, key)
+	cp.dirty = true
+}
+
+// Run saves the checkpoint periodically, while there are changes, until the context is done.
+func (cp *checkpointer) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := cp.save(); err != nil {
+			cp.logger.Error(err, "Failed to save checkpoint")
+		}
+	}, cp.period)
+	if err := cp.save(); err != nil {
+		cp.logger.Error(err, "Failed to save final checkpoint")
+	}
+}
+
+func (cp *checkpointer) save() error {
+	cp.mutex.Lock()
+	if !cp.dirty {
+		cp.mutex.Unlock()
+		return nil
+	}
+	contents := checkpointFile{Version: checkpointVersion, Entries: make([]checkpointEntry, 0, len(cp.current))}
+	for key, state := range cp.current {
+		contents.Entries = append(contents.Entries, checkpointEntry{key, state})
+	}
+	cp.dirty = false
+	cp.mutex.Unlock()
+	err := cp.write(contents)
+	if err != nil {
+		cp.mutex.Lock()
+		cp.dirty = true
+		cp.mutex.Unlock()
+		return err
+	}
+	cp.logger.V(4).Info("Saved checkpoint", "entries", len(contents.Entries))
+	return nil
+}
+
+// write replaces the checkpoint file, by way of a temporary file in the same directory
+// so that a crash does not leave a partial checkpoint.
+func (cp *checkpointer) write(contents checkpointFile) error {
+	tmp, err := os.CreateTemp(filepath.Dir(cp.path), filepath.Base(cp.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	gzw := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(gzw).Encode(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gzw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cp.path)
+}
+
+// desiredHash returns the hash of what the given source object should look like in the given destination,
+// or empty string if that can not be computed.
+func (wp *workloadProjector) desiredHash(logger klog.Logger, sourceCluster string, destination SinglePlacement, srcObj mrObject) string {
+	hash, err := bundle.ContentHash(wp.xformForDestination(sourceCluster, destination, srcObj))
+	if err != nil {
+		logger.Error(err, "Failed to hash desired object for checkpoint")
+		return ""
+	}
+	return hash
+}
+
+// cachedResourceVersion returns the resourceVersion of the given object
+// in the informer's cache, waiting for the informer to sync if necessary;
+// empty if the object is not there.
+// May not be called on the duo for namespaces in a wpd.
+func (duo *dynamicDuo) cachedResourceVersion(ctx context.Context, namespaced bool, namespace, name string) string {
+	if !k8scache.WaitForCacheSync(ctx.Done(), duo.preInformer.Informer().HasSynced) {
+		return ""
+	}
+	_, getter := duo.clientAndGetterForMaybeNamespace(namespaced, namespace)
+	obj, err := getter.Get(name)
+	if err != nil {
+		return ""
+	}
+	objM, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return objM.GetResourceVersion()
+}
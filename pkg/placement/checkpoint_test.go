@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	logger := klog.Background()
+	path := filepath.Join(t.TempDir(), "checkpoint.gz")
+	if cp := newCheckpointer(logger, "", time.Second); cp != nil {
+		t.Fatalf("Expected nil checkpointer for empty path")
+	}
+	keyA := checkpointKey{Mailbox: "mb1", Resource: "configmaps", Namespace: "ns1", Name: "a"}
+	keyB := checkpointKey{Mailbox: "mb1", Group: "apps", Resource: "deployments", Namespace: "ns1", Name: "b"}
+	keyC := checkpointKey{Mailbox: "mb2", Resource: "namespaces", Name: "c"}
+	cp1 := newCheckpointer(logger, path, time.Second)
+	cp1.Record(keyA, "hashA", "10")
+	cp1.Record(keyB, "hashB", "11")
+	cp1.Record(keyC, "hashC", "12")
+	cp1.Forget(keyC)
+	if err := cp1.save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	cp2 := newCheckpointer(logger, path, time.Second)
+	if len(cp2.restored) != 2 {
+		t.Errorf("Expected 2 restored entries, got %v", cp2.restored)
+	}
+	if !cp2.Restored(keyA, "hashA", "10") {
+		t.Errorf("Expected keyA to be restored")
+	}
+	if cp2.Restored(keyA, "hashA", "10") {
+		t.Errorf("Expected restored keyA to be consulted only once")
+	}
+	if cp2.Restored(keyB, "otherHash", "11") {
+		t.Errorf("Expected keyB with different hash not to be restored")
+	}
+	if cp2.Restored(keyC, "hashC", "12") {
+		t.Errorf("Expected forgotten keyC not to be restored")
+	}
+	if err := cp2.save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	cp3 := newCheckpointer(logger, path, time.Second)
+	if len(cp3.restored) != 1 || cp3.restored[keyA] != (checkpointState{Hash: "hashA", ResourceVersion: "10"}) {
+		t.Errorf("Expected only keyA to be carried over, got %v", cp3.restored)
+	}
+}
+
+func TestCheckpointDetectsOtherWriters(t *testing.T) {
+	logger := klog.Background()
+	path := filepath.Join(t.TempDir(), "checkpoint.gz")
+	key := checkpointKey{Mailbox: "mb1", Resource: "configmaps", Namespace: "ns1", Name: "a"}
+	cp1 := newCheckpointer(logger, path, time.Second)
+	cp1.Record(key, "hashA", "10")
+	cp1.Record(key, "hashA", "") // no resourceVersion, nothing to trust
+	if err := cp1.save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	cp2 := newCheckpointer(logger, path, time.Second)
+	// The copy in the mailbox space was changed by someone else since.
+	if cp2.Restored(key, "hashA", "13") {
+		t.Errorf("Expected a copy with a different resourceVersion not to be restored")
+	}
+	if cp2.Restored(key, "hashA", "") {
+		t.Errorf("Expected an absent copy not to be restored")
+	}
+}
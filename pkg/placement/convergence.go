@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	spaceclientfactory "github.com/kubestellar/kubestellar/pkg/spaceclient"
+)
+
+var convergenceDuration = metrics.NewHistogramVec(&metrics.HistogramOpts{
+	Subsystem:      "kubestellar_placement",
+	Name:           "convergence_duration_seconds",
+	Help:           "Time from a change to an EdgePlacement's spec until the placement translator has finished propagating the consequences to all destinations",
+	Buckets:        []float64{1, 2, 5, 10, 15, 30, 60, 120, 300, 600, 1800},
+	StabilityLevel: metrics.ALPHA,
+}, []string{"space", "edgeplacement"})
+
+func init() {
+	legacyregistry.MustRegister(convergenceDuration)
+}
+
+// convergenceSettleTime is how long a spec change that caused no
+// projection work has to wait before being considered converged.
+const convergenceSettleTime = 10 * time.Second
+
+// convergenceTracker measures, for each EdgePlacement, the time from a change
+// to its spec until the workload projector is quiescent after having
+// taken in the consequences of that change.
+// When the projector is quiescent every destination is up to date
+// with everything the projector has been told, so this is a
+// conservative measure of when the change has landed in all the mailbox spaces.
+// A change waits for quiescence only if the projector has since been given
+// work for the EdgePlacement's space; otherwise it converges after settling,
+// so that churn in other spaces does not hold it up.
+type convergenceTracker struct {
+	spaceClients    *spaceclientfactory.Factory
+	spaceProviderNs string
+
+	// report is called outside the mutex to report a convergence
+	report func(ctx context.Context, epRef ExternalName, duration time.Duration)
+
+	mutex sync.Mutex
+
+	// pending maps the (space, name) of an EdgePlacement to the unconverged change
+	pending map[ExternalName]*pendingConvergence
+}
+
+type pendingConvergence struct {
+	// start is the time of the earliest unconverged spec change
+	start time.Time
+
+	// armed is set when the projector has been given work for the
+	// EdgePlacement's space since `start`
+	armed bool
+}
+
+func newConvergenceTracker(spaceClients *spaceclientfactory.Factory, spaceProviderNs string) *convergenceTracker {
+	ct := &convergenceTracker{
+		spaceClients:    spaceClients,
+		spaceProviderNs: spaceProviderNs,
+		pending:         map[ExternalName]*pendingConvergence{},
+	}
+	ct.report = ct.reportDuration
+	return ct
+}
+
+// SpecChanged records that the spec of the given EdgePlacement changed at the given time.
+// If an earlier change has not yet converged then the clock keeps running from that one.
+func (ct *convergenceTracker) SpecChanged(epRef ExternalName, now time.Time) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+	if _, has := ct.pending[epRef]; !has {
+		ct.pending[epRef] = &pendingConvergence{start: now}
+	}
+}
+
+// Forget stops tracking the given EdgePlacement, which has been deleted.
+func (ct *convergenceTracker) Forget(epRef ExternalName) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+	delete(ct.pending, epRef)
+}
+
+// Transacted records that the projector has been given work
+// for the given source spaces.
+func (ct *convergenceTracker) Transacted(sources Visitable[string]) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+	sources.Visit(func(source string) error {
+		for epRef, pc := range ct.pending {
+			if epRef.Cluster == source {
+				pc.armed = true
+			}
+		}
+		return nil
+	})
+}
+
+// Lag returns the age, at the given time, of the oldest spec change that has
+// not yet converged; zero if there is none.
+func (ct *convergenceTracker) Lag(now time.Time) time.Duration {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+	var lag time.Duration
+	for _, pc := range ct.pending {
+		if age := now.Sub(pc.start); age > lag {
+			lag = age
+		}
+	}
+	return lag
+}
+
+// Quiescent is called when the projector has no work queued or in progress,
+// and reports the convergence of the pending changes.
+func (ct *convergenceTracker) Quiescent(ctx context.Context, now time.Time) {
+	converged := map[ExternalName]time.Duration{}
+	func() {
+		ct.mutex.Lock()
+		defer ct.mutex.Unlock()
+		for epRef, pc := range ct.pending {
+			if pc.armed || now.Sub(pc.start) >= convergenceSettleTime {
+				converged[epRef] = now.Sub(pc.start)
+				delete(ct.pending, epRef)
+			}
+		}
+	}()
+	for epRef, duration := range converged {
+		convergenceDuration.WithLabelValues(epRef.Cluster, string(epRef.Name)).Observe(duration.Seconds())
+		ct.report(ctx, epRef, duration)
+	}
+}
+
+// reportDuration writes the given duration into the status of the consumer's EdgePlacement.
+func (ct *convergenceTracker) reportDuration(ctx context.Context, epRef ExternalName, duration time.Duration) {
+	logger := klog.FromContext(ctx).WithValues("space", epRef.Cluster, "edgePlacement", epRef.Name)
+	clients, err := ct.spaceClients.For(epRef.Cluster, ct.spaceProviderNs)
+	if err != nil {
+		logger.Error(err, "Failed to get clients for space")
+		return
+	}
+	edgeClient := clients.Edge
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ep, err := edgeClient.EdgeV2alpha1().EdgePlacements().Get(ctx, string(epRef.Name), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		ep = ep.DeepCopy()
+		ep.Status.LastConvergenceDuration = &metav1.Duration{Duration: duration}
+		_, err = edgeClient.EdgeV2alpha1().EdgePlacements().UpdateStatus(ctx, ep, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		logger.Error(err, "Failed to record convergence duration in EdgePlacement status")
+		return
+	}
+	logger.V(2).Info("Recorded convergence", "duration", duration)
+}
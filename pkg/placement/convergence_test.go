@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConvergenceTracker(t *testing.T) {
+	ctx := context.Background()
+	reported := map[ExternalName]time.Duration{}
+	ct := newConvergenceTracker(nil, "")
+	ct.report = func(_ context.Context, epRef ExternalName, duration time.Duration) {
+		reported[epRef] = duration
+	}
+	ep1 := ExternalName{Cluster: "wds1", Name: "ep1"}
+	ep2 := ExternalName{Cluster: "wds1", Name: "ep2"}
+	t0 := time.Now()
+	ct.SpecChanged(ep1, t0)
+	ct.SpecChanged(ep1, t0.Add(time.Second)) // clock keeps running from the first change
+	ct.SpecChanged(ep2, t0.Add(2*time.Second))
+
+	if lag := ct.Lag(t0.Add(3 * time.Second)); lag != 3*time.Second {
+		t.Fatalf("Expected lag of 3s, got %v", lag)
+	}
+
+	// Not converged until the projector has been given work, or has settled
+	ct.Quiescent(ctx, t0.Add(3*time.Second))
+	if len(reported) != 0 {
+		t.Fatalf("Premature convergence: %v", reported)
+	}
+	ct.Transacted(NewMapSet("wds1"))
+	ct.Quiescent(ctx, t0.Add(4*time.Second))
+	if reported[ep1] != 4*time.Second || reported[ep2] != 2*time.Second {
+		t.Fatalf("Unexpected convergence durations: %v", reported)
+	}
+	if lag := ct.Lag(t0.Add(4 * time.Second)); lag != 0 {
+		t.Fatalf("Expected no lag after convergence, got %v", lag)
+	}
+
+	// A change that causes no projection work converges after settling
+	delete(reported, ep1)
+	ct.SpecChanged(ep1, t0.Add(5*time.Second))
+	ct.Quiescent(ctx, t0.Add(5*time.Second+convergenceSettleTime))
+	if reported[ep1] != convergenceSettleTime {
+		t.Fatalf("Unexpected convergence durations: %v", reported)
+	}
+
+	// Work for another space does not arm a change, which converges after settling
+	delete(reported, ep1)
+	ct.SpecChanged(ep1, t0.Add(10*time.Second))
+	ct.Transacted(NewMapSet("wds2"))
+	ct.Quiescent(ctx, t0.Add(11*time.Second))
+	if _, has := reported[ep1]; has {
+		t.Fatalf("Change converged before settling: %v", reported)
+	}
+	ct.Quiescent(ctx, t0.Add(10*time.Second+convergenceSettleTime))
+	if reported[ep1] != convergenceSettleTime {
+		t.Fatalf("Unexpected convergence durations: %v", reported)
+	}
+
+	// A deleted EdgePlacement is forgotten
+	delete(reported, ep1)
+	ct.SpecChanged(ep1, t0.Add(20*time.Second))
+	ct.Forget(ep1)
+	ct.Transacted(NewMapSet("wds1"))
+	ct.Quiescent(ctx, t0.Add(21*time.Second))
+	if _, has := reported[ep1]; has {
+		t.Fatalf("Forgotten EdgePlacement was reported")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sdynamicinformer "k8s.io/client-go/dynamic/dynamicinformer"
+	k8scache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+	"github.com/kubestellar/kubestellar/pkg/crossref"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+// Changes to a Customizer are propagated to the copies rendered with it.
+// The workload projector notes, each time it syncs a source object to a
+// destination, which Customizer (if any) that copy is rendered with, in
+// an index from the copy to the Customizer. Customizers are watched in
+// every space that some copy refers into, for as long as some copy does.
+// When a Customizer is added, changed or deleted, the resolver's cached
+// copy is forgotten and every source object rendered with it is
+// enqueued, so that its copies are rendered again and, if that changes
+// them, delivered again. A copy whose Customizer is gone is delivered
+// in its unrendered form, and the failure to find the Customizer is
+// reported as a TransformError Event on the source object.
+
+var customizersGVR = edgeapi.SchemeGroupVersion.WithResource("customizers")
+
+// customizedObject identifies the copy of a source object that goes to one destination.
+type customizedObject struct {
+	Source      sourceObjectRef
+	Destination SinglePlacement
+}
+
+// customizerTracker holds the relation between copies and Customizers,
+// and the watches on the Customizers.
+type customizerTracker struct {
+	// uses relates each copy to the Customizer that it is rendered with
+	uses *relindex.Relation2[customizedObject, crossref.Ref]
+
+	// watches holds the cancel func of the watch on the Customizers of
+	// each space, for as long as some copy refers into that space
+	watches *RefCounted[string, context.CancelFunc]
+}
+
+func (wp *workloadProjector) newCustomizerTracker() *customizerTracker {
+	return &customizerTracker{
+		uses:    relindex.NewRelation2[customizedObject, crossref.Ref](),
+		watches: NewRefCounted(wp.watchCustomizers, func(_ string, cancel context.CancelFunc) { cancel() }),
+	}
+}
+
+// noteCustomizerUse records which Customizer, if any, the copy of the
+// given source object at the given destination is rendered with.
+func (wp *workloadProjector) noteCustomizerUse(logger klog.Logger, soRef sourceObjectRef, destination SinglePlacement, srcObj mrObject, deleted bool) {
+	var refs []crossref.Ref
+	if !deleted {
+		if value := srcObj.GetAnnotations()[edgeapi.CustomizerAnnotationKey]; value != "" {
+			// A malformed reference is reported when rendering.
+			if ref, err := crossref.Parse(value, soRef.Cluster, srcObj.GetNamespace()); err == nil {
+				refs = []crossref.Ref{ref}
+			}
+		}
+	}
+	added, removed := wp.customizers.uses.SetSecondsOf(customizedObject{Source: soRef, Destination: destination}, refs)
+	for _, ref := range added {
+		logger.V(4).Info("Copy is rendered with Customizer", "customizer", ref)
+		wp.customizers.watches.Acquire(ref.Space)
+	}
+	for _, ref := range removed {
+		logger.V(4).Info("Copy is no longer rendered with Customizer", "customizer", ref)
+		wp.customizers.watches.Release(ref.Space)
+	}
+}
+
+// watchCustomizers starts watching the Customizers in the given space,
+// and returns the func that stops it.
+func (wp *workloadProjector) watchCustomizers(space string) context.CancelFunc {
+	logger := klog.FromContext(wp.ctx).WithValues("space", space)
+	ctx, cancel := context.WithCancel(wp.ctx)
+	clients, err := wp.spaceClients.For(space, wp.spaceProviderNs)
+	if err != nil {
+		// The TTL of the resolver's cache still bounds how long a change goes unnoticed.
+		logger.Error(err, "Failed to get clients for watching Customizers")
+		return cancel
+	}
+	informer := k8sdynamicinformer.NewFilteredDynamicInformer(clients.Dynamic, customizersGVR, metav1.NamespaceAll, 0, k8scache.Indexers{}, nil).Informer()
+	informer.AddEventHandler(recovery.Handler(logger, recoveryNameProjector, k8scache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { wp.customizerChanged(logger, space, obj, "add") },
+		UpdateFunc: func(oldObj, newObj any) { wp.customizerChanged(logger, space, newObj, "update") },
+		DeleteFunc: func(obj any) { wp.customizerChanged(logger, space, obj, "delete") },
+	}))
+	logger.V(3).Info("Watching Customizers")
+	go informer.Run(ctx.Done())
+	return cancel
+}
+
+// customizerChanged enqueues the source objects rendered with the given Customizer.
+func (wp *workloadProjector) customizerChanged(logger klog.Logger, space string, obj any, action string) {
+	if dfu, ok := obj.(k8scache.DeletedFinalStateUnknown); ok {
+		obj = dfu.Obj
+	}
+	objm := obj.(metav1.Object)
+	ref := crossref.Ref{Space: space, Namespace: objm.GetNamespace(), Name: objm.GetName()}
+	wp.refResolver.Forget(ref)
+	sources := map[sourceObjectRef]Empty{}
+	for _, use := range wp.customizers.uses.FirstsOf(ref) {
+		sources[use.Source] = Empty{}
+	}
+	for soRef := range sources {
+		logger.V(3).Info("Enqueuing reference to source object whose Customizer changed", "customizer", ref, "ref", soRef, "action", action)
+		wp.queue.AddEvent(soRef, coalesce.Updated)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8scache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+	"github.com/kubestellar/kubestellar/pkg/crossref"
+	"github.com/kubestellar/kubestellar/pkg/relindex"
+)
+
+func TestCustomizerTracking(t *testing.T) {
+	logger := klog.Background()
+	watching := map[string]bool{}
+	wp := &workloadProjector{
+		queue:       coalesce.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test-customizers", coalesce.Options{}),
+		refResolver: crossref.NewResolver(nil, "", crossref.Options{}),
+		customizers: &customizerTracker{
+			uses: relindex.NewRelation2[customizedObject, crossref.Ref](),
+			watches: NewRefCounted(func(space string) context.CancelFunc {
+				watching[space] = true
+				return func() { watching[space] = false }
+			}, func(_ string, cancel context.CancelFunc) { cancel() }),
+		},
+	}
+	defer wp.queue.ShutDown()
+	soRef := sourceObjectRef{Cluster: "wds1", GroupResource: metav1.GroupResource{Resource: "configmaps"}, Namespace: "ns", Name: "cm"}
+	srcObj := &unstructured.Unstructured{}
+	srcObj.SetNamespace("ns")
+	srcObj.SetName("cm")
+	srcObj.SetAnnotations(map[string]string{edgeapi.CustomizerAnnotationKey: "common:shared/cust"})
+	dest1 := SinglePlacement{Cluster: "imw1", SyncTargetName: "st1"}
+	dest2 := SinglePlacement{Cluster: "imw1", SyncTargetName: "st2"}
+	wp.noteCustomizerUse(logger, soRef, dest1, srcObj, false)
+	wp.noteCustomizerUse(logger, soRef, dest2, srcObj, false)
+	if !watching["common"] || wp.customizers.watches.Users("common") != 2 {
+		t.Fatalf("Expected a watch in space common with 2 users, got %v and %d", watching, wp.customizers.watches.Users("common"))
+	}
+
+	// A change to an unrelated Customizer enqueues nothing.
+	other := &unstructured.Unstructured{}
+	other.SetNamespace("shared")
+	other.SetName("other")
+	wp.customizerChanged(logger, "common", other, "update")
+	if wp.queue.Len() != 0 {
+		t.Errorf("Expected nothing enqueued, got %d items", wp.queue.Len())
+	}
+
+	// Deleting the Customizer enqueues the source object once.
+	cust := &unstructured.Unstructured{}
+	cust.SetNamespace("shared")
+	cust.SetName("cust")
+	wp.customizerChanged(logger, "common", k8scache.DeletedFinalStateUnknown{Key: "shared/cust", Obj: cust}, "delete")
+	if wp.queue.Len() != 1 {
+		t.Fatalf("Expected 1 item enqueued, got %d", wp.queue.Len())
+	}
+	if item, _ := wp.queue.Get(); item != soRef {
+		t.Errorf("Expected %v enqueued, got %v", soRef, item)
+	}
+
+	// Dropping the annotation, and deleting the object, end the watch.
+	srcObj.SetAnnotations(nil)
+	wp.noteCustomizerUse(logger, soRef, dest1, srcObj, false)
+	wp.noteCustomizerUse(logger, soRef, dest2, nil, true)
+	if watching["common"] || wp.customizers.uses.Len() != 0 {
+		t.Errorf("Expected no watch and no uses, got %v and %v", watching, wp.customizers.uses.Snapshot())
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"k8s.io/klog/v2"
 
 	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/naming"
 )
 
 // This file contains declarations of the interfaces between the main parts
@@ -108,6 +109,8 @@ type NonNamespacedDistributionTuple = Pair[ProjectionModeKey, ExternalName /*of
 type DistributionBits struct {
 	ReturnSingletonState bool
 	CreateOnly           bool
+	ImmutableFieldPolicy ImmutableFieldPolicy
+	LocalProbes          string
 }
 
 type ProjectionModeKey struct {
@@ -211,15 +214,31 @@ type WorkloadPartDetails struct {
 	// When multiple EdgePlacement objects provide different values for this bit,
 	// they are combined by OR.
 	CreateOnly bool
+
+	// ImmutableFieldPolicy is what the EdgePlacement objects ask for when
+	// an update of the object is rejected for changing an immutable field;
+	// empty means they do not say.
+	// When multiple EdgePlacement objects provide different values for this,
+	// the most cautious one wins (see combineImmutableFieldPolicies).
+	ImmutableFieldPolicy ImmutableFieldPolicy
+
+	// LocalProbes is the canonical JSON of the LocalProbes that the
+	// EdgePlacement objects ask for; empty means none (see local-probes.go).
+	// When multiple EdgePlacement objects provide different values for this,
+	// they are combined by union.
+	LocalProbes string
 }
 
 func (wpd WorkloadPartDetails) distributionBits() DistributionBits {
-	return DistributionBits{ReturnSingletonState: wpd.ReturnSingletonState, CreateOnly: wpd.CreateOnly}
+	return DistributionBits{ReturnSingletonState: wpd.ReturnSingletonState, CreateOnly: wpd.CreateOnly,
+		ImmutableFieldPolicy: wpd.ImmutableFieldPolicy, LocalProbes: wpd.LocalProbes}
 }
 
 func (wpd WorkloadPartDetails) setDistributionBits(bits DistributionBits) WorkloadPartDetails {
 	wpd.ReturnSingletonState = bits.ReturnSingletonState
 	wpd.CreateOnly = bits.CreateOnly
+	wpd.ImmutableFieldPolicy = bits.ImmutableFieldPolicy
+	wpd.LocalProbes = bits.LocalProbes
 	return wpd
 }
 
@@ -420,10 +439,14 @@ func (rscMode ResourceMode) GoesToEdge() bool {
 }
 
 func SPMailboxWorkspaceName(sp SinglePlacement) string {
-	return sp.Cluster + MBspaceNameSep + string(sp.SyncTargetUID)
+	return naming.MailboxSpaceName(sp.Cluster, string(sp.SyncTargetUID))
 }
 
-const MBspaceNameSep = "-mb-"
+// MBspaceNameSep is the separator in mailbox space names.
+//
+// Deprecated: a mailbox space name is not always the concatenation of
+// its parts; use naming.MailboxSpaceName or SPMailboxWorkspaceName.
+const MBspaceNameSep = naming.MailboxSpaceSeparator
 
 // EventHandler can be given Event objects.
 type EventHandler interface {
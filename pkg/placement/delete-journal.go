@@ -0,0 +1,286 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// deleteJournalVersion identifies the format of the delete journal file.
+const deleteJournalVersion = 1
+
+// pendingDelete is a deletion of a copy in a mailbox space that the
+// workload projector has decided on but not yet seen through.
+// It is also the queue item that finishes such a deletion after a restart.
+type pendingDelete struct {
+	checkpointKey
+	Version string `json:"version"`
+
+	// Source is the source space of the copy, when the deletion is because
+	// the source object was deleted; empty when the copy is merely no
+	// longer wanted in its mailbox space.
+	Source string `json:"source,omitempty"`
+
+	// ResourceVersion, if not empty, is the resourceVersion of the copy
+	// that was found to be unwanted; the deletion is conditioned on it.
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type deleteJournalFile struct {
+	Version int             `json:"version"`
+	Pending []pendingDelete `json:"pending"`
+}
+
+// deleteJournal durably records the deletions in mailbox spaces that are
+// in progress, so that one that is interrupted by a restart of the
+// placement translator is finished after it. Without this, a copy whose
+// source object was deleted while the translator was down or just before
+// it went down could be left behind, because after the restart nothing
+// refers to that copy any more.
+//
+// A deletion is written to the journal file before it is issued and
+// removed after it succeeds (or is found to be moot). The file is
+// rewritten, by way of a temporary file, on every change; deletions are
+// rare next to the other writes, and the file only holds the ones in
+// progress.
+//
+// After a restart each recorded deletion is finished, unless the copy
+// is wanted again: a deletion because of a deleted source object is
+// dropped if the source object exists again, and a deletion of an
+// unwanted copy is conditioned on the resourceVersion that was seen
+// unwanted. So a deletion is issued at least once and, barring races
+// with that re-check, at most once.
+type deleteJournal struct {
+	logger klog.Logger
+	path   string
+
+	mutex   sync.Mutex
+	pending map[checkpointKey]pendingDelete
+}
+
+// newDeleteJournal loads the delete journal in the given file, if any.
+// Returns nil if path is empty.
+func newDeleteJournal(logger klog.Logger, path string) *deleteJournal {
+	if path == "" {
+		return nil
+	}
+	dj := &deleteJournal{
+		logger:  logger.WithValues("deleteJournalFile", path),
+		path:    path,
+		pending: map[checkpointKey]pendingDelete{},
+	}
+	if err := dj.load(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			dj.logger.V(2).Info("No delete journal to restore")
+		} else {
+			dj.logger.Error(err, "Failed to load delete journal, starting without it")
+		}
+	} else {
+		dj.logger.V(2).Info("Restored delete journal", "pending", len(dj.pending))
+	}
+	return dj
+}
+
+func (dj *deleteJournal) load() error {
+	data, err := os.ReadFile(dj.path)
+	if err != nil {
+		return err
+	}
+	var contents deleteJournalFile
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return err
+	}
+	if contents.Version != deleteJournalVersion {
+		return fmt.Errorf("delete journal has version %d but only %d is supported", contents.Version, deleteJournalVersion)
+	}
+	for _, pd := range contents.Pending {
+		dj.pending[pd.checkpointKey] = pd
+	}
+	return nil
+}
+
+// Pending returns the deletions that are recorded and not yet finished.
+// Returns nil if dj is nil.
+func (dj *deleteJournal) Pending() []pendingDelete {
+	if dj == nil {
+		return nil
+	}
+	dj.mutex.Lock()
+	defer dj.mutex.Unlock()
+	return dj.listLocked()
+}
+
+func (dj *deleteJournal) listLocked() []pendingDelete {
+	ans := make([]pendingDelete, 0, len(dj.pending))
+	for _, pd := range dj.pending {
+		ans = append(ans, pd)
+	}
+	sort.Slice(ans, func(i, j int) bool { return fmt.Sprint(ans[i].checkpointKey) < fmt.Sprint(ans[j].checkpointKey) })
+	return ans
+}
+
+// Begin records the given deletion, returning only after it is in the file.
+// An error means that the deletion should not be issued yet.
+// Does nothing if dj is nil.
+func (dj *deleteJournal) Begin(pd pendingDelete) error {
+	if dj == nil {
+		return nil
+	}
+	dj.mutex.Lock()
+	defer dj.mutex.Unlock()
+	if dj.pending[pd.checkpointKey] == pd {
+		return nil
+	}
+	prev, had := dj.pending[pd.checkpointKey]
+	dj.pending[pd.checkpointKey] = pd
+	if err := dj.writeLocked(); err != nil {
+		if had {
+			dj.pending[pd.checkpointKey] = prev
+		} else {
+			delete(dj.pending, pd.checkpointKey)
+		}
+		return err
+	}
+	return nil
+}
+
+// End notes that the deletion of the given object is finished or moot.
+// A failure to record this only costs a re-check after a restart.
+// Does nothing if dj is nil.
+func (dj *deleteJournal) End(key checkpointKey) {
+	if dj == nil {
+		return
+	}
+	dj.mutex.Lock()
+	defer dj.mutex.Unlock()
+	if _, have := dj.pending[key]; !have {
+		return
+	}
+	delete(dj.pending, key)
+	if err := dj.writeLocked(); err != nil {
+		dj.logger.Error(err, "Failed to remove finished deletion from journal", "key", key)
+	}
+}
+
+// writeLocked replaces the journal file, by way of a synced temporary
+// file in the same directory so that a crash leaves either the old or
+// the new journal.
+func (dj *deleteJournal) writeLocked() error {
+	data, err := json.Marshal(deleteJournalFile{Version: deleteJournalVersion, Pending: dj.listLocked()})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dj.path), filepath.Base(dj.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dj.path)
+}
+
+func (wp *workloadProjector) setDeleteJournal(journal *deleteJournal) {
+	wp.deletes = journal
+}
+
+// beginDelete records, in the delete journal, that the given copy in a
+// mailbox space is about to be deleted. Returns `proceed bool`.
+func (wp *workloadProjector) beginDelete(logger klog.Logger, pd pendingDelete) bool {
+	if err := wp.deletes.Begin(pd); err != nil {
+		logger.Error(err, "Failed to record pending deletion in journal, will retry")
+		return false
+	}
+	return true
+}
+
+// finishDelete finishes a deletion that was recorded in the delete journal
+// by an earlier run of the placement translator.
+// Returns `retry bool`.
+func (wp *workloadProjector) finishDelete(ctx context.Context, pd pendingDelete) bool {
+	logger := klog.FromContext(ctx).WithValues("pendingDelete", pd)
+	gvr := schema.GroupVersionResource{Group: pd.Group, Version: pd.Version, Resource: pd.Resource}
+	if pd.Source != "" {
+		clients, err := wp.spaceClients.For(pd.Source, wp.spaceProviderNs)
+		if err != nil {
+			logger.Error(err, "Failed to get clients for source space")
+			return true
+		}
+		_, err = resourceInterface(clients.Dynamic, gvr, pd.Namespace).Get(ctx, pd.Name, metav1.GetOptions{})
+		if err == nil {
+			logger.V(3).Info("Dropping pending deletion because the source object exists again")
+			wp.deletes.End(pd.checkpointKey)
+			return false
+		}
+		if !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to check whether the source object exists")
+			return true
+		}
+	}
+	clients, err := wp.spaceClients.For(pd.Mailbox, wp.spaceProviderNs)
+	if err != nil {
+		logger.Error(err, "Failed to get clients for mailbox space")
+		return true
+	}
+	opts := metav1.DeleteOptions{}
+	if pd.ResourceVersion != "" {
+		opts.Preconditions = &metav1.Preconditions{ResourceVersion: &pd.ResourceVersion}
+	}
+	err = resourceInterface(clients.Dynamic, gvr, pd.Namespace).Delete(ctx, pd.Name, opts)
+	switch {
+	case err == nil:
+		logger.V(2).Info("Finished deletion in mailbox space that was pending at restart")
+	case k8sapierrors.IsNotFound(err):
+		logger.V(3).Info("Pending deletion was already done")
+	case k8sapierrors.IsConflict(err):
+		logger.V(3).Info("Dropping pending deletion because the copy has changed since")
+	default:
+		logger.Error(err, "Failed to finish deletion in mailbox space that was pending at restart")
+		return true
+	}
+	wp.deletes.End(pd.checkpointKey)
+	return false
+}
+
+func resourceInterface(client k8sdynamic.Interface, gvr schema.GroupVersionResource, namespace string) k8sdynamic.ResourceInterface {
+	if namespace == "" {
+		return client.Resource(gvr)
+	}
+	return client.Resource(gvr).Namespace(namespace)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestDeleteJournal(t *testing.T) {
+	logger := klog.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deletes.json")
+	if dj := newDeleteJournal(logger, ""); dj != nil {
+		t.Fatalf("Expected nil journal for empty path")
+	}
+	var nilJournal *deleteJournal
+	if err := nilJournal.Begin(pendingDelete{}); err != nil || nilJournal.Pending() != nil {
+		t.Errorf("Expected nil journal to do nothing")
+	}
+	nilJournal.End(checkpointKey{})
+
+	pdA := pendingDelete{checkpointKey: checkpointKey{Mailbox: "mb1", Resource: "configmaps", Namespace: "ns1", Name: "a"}, Version: "v1", Source: "wds1"}
+	pdB := pendingDelete{checkpointKey: checkpointKey{Mailbox: "mb2", Group: "apps", Resource: "deployments", Namespace: "ns1", Name: "b"}, Version: "v1", ResourceVersion: "12"}
+	dj1 := newDeleteJournal(logger, path)
+	for _, pd := range []pendingDelete{pdA, pdB} {
+		if err := dj1.Begin(pd); err != nil {
+			t.Fatalf("Failed to begin %v: %v", pd, err)
+		}
+	}
+	dj1.End(pdB.checkpointKey)
+	dj1.End(pdB.checkpointKey) // again, harmlessly
+
+	// A restart finds only the unfinished deletion.
+	dj2 := newDeleteJournal(logger, path)
+	if got, expected := dj2.Pending(), []pendingDelete{pdA}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v after restart, got %v", expected, got)
+	}
+	dj2.End(pdA.checkpointKey)
+	if got := newDeleteJournal(logger, path).Pending(); len(got) != 0 {
+		t.Errorf("Expected nothing pending after the last End, got %v", got)
+	}
+
+	// A journal that can not be written refuses to begin a deletion.
+	if err := os.Chmod(dir, 0o500); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0o700)
+	if os.Getuid() != 0 {
+		if err := dj2.Begin(pdB); err == nil {
+			t.Errorf("Expected failure to write the journal in a read-only directory")
+		}
+		if got := dj2.Pending(); len(got) != 0 {
+			t.Errorf("Expected failed Begin to leave nothing pending, got %v", got)
+		}
+	}
+}
@@ -39,6 +39,7 @@ package placement
 // - Func11Compose11: composes two 1-arg 1-result functions.
 // - Identity1: 1-arg identity function.
 // - NewThunk: creates a 0-arg 1-result function.
+// - RefCounted: values created on first Acquire and destroyed on last Release.
 // - Rotator: a bijection between two types.
 // - Factorer: a bijection between a Pair and something isomorphic to it.
 // - Runnable: something that Runs with a given Context.
@@ -16,7 +16,78 @@ limitations under the License.
 
 package placement
 
+import (
+	"sync"
+)
+
 // Identity1 is useful in reducers where the accumulator has the same type as the result
 func Identity1[Val any](val Val) Val { return val }
 
 func NewThunk[Val any](val Val) func() Val { return func() Val { return val } }
+
+// RefCounted holds values derived from keys, each kept while it has
+// users. The first Acquire of a key creates its value; the Release that
+// balances the last Acquire destroys it. It is safe for concurrent use;
+// create and destroy are called with the lock held, so they must not
+// call back into the RefCounted.
+type RefCounted[Key comparable, Val any] struct {
+	create  func(Key) Val
+	destroy func(Key, Val)
+
+	mutex  sync.Mutex
+	values map[Key]*refCountedEntry[Val]
+}
+
+type refCountedEntry[Val any] struct {
+	val   Val
+	count int
+}
+
+// NewRefCounted makes a RefCounted. destroy may be nil.
+func NewRefCounted[Key comparable, Val any](create func(Key) Val, destroy func(Key, Val)) *RefCounted[Key, Val] {
+	return &RefCounted[Key, Val]{create: create, destroy: destroy, values: map[Key]*refCountedEntry[Val]{}}
+}
+
+// Acquire returns the value for the given key, creating it if it has no users.
+func (rc *RefCounted[Key, Val]) Acquire(key Key) Val {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	entry, ok := rc.values[key]
+	if !ok {
+		entry = &refCountedEntry[Val]{val: rc.create(key)}
+		rc.values[key] = entry
+	}
+	entry.count++
+	return entry.val
+}
+
+// Release gives up one use of the value for the given key, and destroys
+// the value if that was the last use. Returns whether it was destroyed.
+// Releasing a key that has no users does nothing.
+func (rc *RefCounted[Key, Val]) Release(key Key) bool {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	entry, ok := rc.values[key]
+	if !ok {
+		return false
+	}
+	entry.count--
+	if entry.count > 0 {
+		return false
+	}
+	delete(rc.values, key)
+	if rc.destroy != nil {
+		rc.destroy(key, entry.val)
+	}
+	return true
+}
+
+// Users returns the number of users of the value for the given key.
+func (rc *RefCounted[Key, Val]) Users(key Key) int {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if entry, ok := rc.values[key]; ok {
+		return entry.count
+	}
+	return 0
+}
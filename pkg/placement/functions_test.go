@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"testing"
+)
+
+func TestRefCounted(t *testing.T) {
+	var destroyed []string
+	created := 0
+	rc := NewRefCounted(func(key string) *int {
+		created++
+		val := created
+		return &val
+	}, func(key string, _ *int) { destroyed = append(destroyed, key) })
+	first := rc.Acquire("x")
+	if second := rc.Acquire("x"); second != first || created != 1 || rc.Users("x") != 2 {
+		t.Fatalf("Expected one shared value with 2 users, created %d, users %d", created, rc.Users("x"))
+	}
+	if rc.Release("x") || len(destroyed) != 0 {
+		t.Errorf("Expected no destruction while a user remains")
+	}
+	if !rc.Release("x") || len(destroyed) != 1 || rc.Users("x") != 0 {
+		t.Errorf("Expected destruction on last release, got %v", destroyed)
+	}
+	if rc.Release("x") {
+		t.Errorf("Expected release of an unused key to do nothing")
+	}
+	if third := rc.Acquire("x"); *third != 2 {
+		t.Errorf("Expected a new value after destruction, got %d", *third)
+	}
+}
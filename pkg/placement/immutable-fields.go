@@ -0,0 +1,285 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+// ImmutableFieldPolicy says what the workload projector does when an
+// update of a copy in a mailbox space is rejected because it changes a
+// field that can not be changed, such as a Deployment's selector or
+// (downward) the size of a PersistentVolumeClaim.
+// See also edgeapi.ImmutableFieldPolicyAnnotationKey.
+type ImmutableFieldPolicy string
+
+const (
+	// ImmutableFieldFail keeps retrying the update and recording
+	// DestinationFailed Events. This is the default.
+	ImmutableFieldFail ImmutableFieldPolicy = "Fail"
+
+	// ImmutableFieldSkip leaves the copy as it is and records an
+	// ImmutableFieldSkipped Event. The update is tried again only when
+	// the source object changes again.
+	ImmutableFieldSkip ImmutableFieldPolicy = "Skip"
+
+	// ImmutableFieldRecreate deletes the copy and creates it anew,
+	// within the recreate budget of the destination.
+	ImmutableFieldRecreate ImmutableFieldPolicy = "Recreate"
+)
+
+// ParseImmutableFieldPolicy parses the name of an ImmutableFieldPolicy,
+// ignoring case.
+func ParseImmutableFieldPolicy(name string) (ImmutableFieldPolicy, error) {
+	for _, policy := range []ImmutableFieldPolicy{ImmutableFieldFail, ImmutableFieldSkip, ImmutableFieldRecreate} {
+		if strings.EqualFold(name, string(policy)) {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("immutable field policy %q is not one of Fail, Skip or Recreate", name)
+}
+
+// caution orders the policies from the most to the least disruptive;
+// the empty policy means none was given.
+func (policy ImmutableFieldPolicy) caution() int {
+	switch policy {
+	case ImmutableFieldRecreate:
+		return 1
+	case ImmutableFieldSkip:
+		return 2
+	case ImmutableFieldFail:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// combineImmutableFieldPolicies returns the more cautious of the two
+// policies, for an object selected by several EdgePlacements.
+func combineImmutableFieldPolicies(a, b ImmutableFieldPolicy) ImmutableFieldPolicy {
+	if b.caution() > a.caution() {
+		return b
+	}
+	return a
+}
+
+// immutableFieldPolicyOf returns the policy that the given EdgePlacement
+// asks for in its annotation, or the empty policy if it does not
+// (validly) ask for one.
+func immutableFieldPolicyOf(logger klog.Logger, ep *edgeapi.EdgePlacement) ImmutableFieldPolicy {
+	name, found := ep.Annotations[edgeapi.ImmutableFieldPolicyAnnotationKey]
+	if !found {
+		return ""
+	}
+	policy, err := ParseImmutableFieldPolicy(name)
+	if err != nil {
+		logger.Error(err, "Ignoring invalid annotation on EdgePlacement", "key", edgeapi.ImmutableFieldPolicyAnnotationKey)
+	}
+	return policy
+}
+
+// ImmutableFieldRules configure the handling of immutable field changes.
+// A policy given by the EdgePlacements that select an object takes
+// precedence over the one for its resource, which takes precedence
+// over the default.
+type ImmutableFieldRules struct {
+	// Default applies to the resources that have no policy of their
+	// own. Empty means ImmutableFieldFail.
+	Default ImmutableFieldPolicy
+
+	// ByResource holds the policies of particular resources.
+	ByResource map[metav1.GroupResource]ImmutableFieldPolicy
+
+	// RecreateBudget is the maximum number of copies recreated in
+	// one mailbox space during any RecreateBudgetPeriod; zero means no limit.
+	RecreateBudget int
+
+	RecreateBudgetPeriod time.Duration
+}
+
+// ParseImmutableFieldRules parses the default policy and the policies by
+// resource, which are keyed by "RESOURCE.GROUP" (or just "RESOURCE" for
+// the core API group) as in "deployments.apps".
+func ParseImmutableFieldRules(defaultPolicy string, byResource map[string]string) (ImmutableFieldRules, error) {
+	var rules ImmutableFieldRules
+	var err error
+	if rules.Default, err = ParseImmutableFieldPolicy(defaultPolicy); err != nil {
+		return rules, err
+	}
+	rules.ByResource = make(map[metav1.GroupResource]ImmutableFieldPolicy, len(byResource))
+	for resource, name := range byResource {
+		policy, err := ParseImmutableFieldPolicy(name)
+		if err != nil {
+			return rules, fmt.Errorf("policy for %q: %w", resource, err)
+		}
+		gr := schema.ParseGroupResource(resource)
+		if gr.Resource == "" {
+			return rules, fmt.Errorf("policy for %q: no resource is named", resource)
+		}
+		rules.ByResource[SchemaGroupResourceToMeta(gr)] = policy
+	}
+	return rules, nil
+}
+
+var immutableFieldConflicts = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "kubestellar_placement",
+	Name:           "immutable_field_conflicts_total",
+	Help:           "Number of updates of copies in mailbox spaces rejected for changing an immutable field, by outcome (failed, skipped, recreated or deferred)",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"outcome"})
+
+func init() {
+	legacyregistry.MustRegister(immutableFieldConflicts)
+}
+
+// immutableFieldTracker applies the ImmutableFieldRules, and keeps
+// track of the recreates done in each mailbox space.
+type immutableFieldTracker struct {
+	rules ImmutableFieldRules
+	now   func() time.Time
+
+	mutex sync.Mutex
+	// recreates holds, for each destination, the times of its recent recreates, oldest first
+	recreates map[SinglePlacement][]time.Time
+}
+
+func newImmutableFieldTracker() *immutableFieldTracker {
+	return &immutableFieldTracker{now: time.Now, recreates: map[SinglePlacement][]time.Time{}}
+}
+
+// setImmutableFieldRules configures how the workload projector handles
+// updates that are rejected for changing an immutable field.
+func (wp *workloadProjector) setImmutableFieldRules(rules ImmutableFieldRules) {
+	wp.immutableFields.rules = rules
+}
+
+// policyFor returns the policy that applies to an object of the given
+// resource distributed with the given bits.
+func (ift *immutableFieldTracker) policyFor(gr metav1.GroupResource, bits DistributionBits) ImmutableFieldPolicy {
+	if bits.ImmutableFieldPolicy != "" {
+		return bits.ImmutableFieldPolicy
+	}
+	if policy, found := ift.rules.ByResource[gr]; found {
+		return policy
+	}
+	if ift.rules.Default != "" {
+		return ift.rules.Default
+	}
+	return ImmutableFieldFail
+}
+
+// takeRecreate tells whether the recreate budget of the given
+// destination allows one more recreate now, and if so then counts it.
+// If not then it also returns how long until the budget allows one.
+func (ift *immutableFieldTracker) takeRecreate(destination SinglePlacement) (time.Duration, bool) {
+	if ift.rules.RecreateBudget <= 0 {
+		return 0, true
+	}
+	now := ift.now()
+	ift.mutex.Lock()
+	defer ift.mutex.Unlock()
+	recent := ift.recreates[destination]
+	for len(recent) > 0 && now.Sub(recent[0]) >= ift.rules.RecreateBudgetPeriod {
+		recent = recent[1:]
+	}
+	if len(recent) >= ift.rules.RecreateBudget {
+		ift.recreates[destination] = recent
+		return recent[0].Add(ift.rules.RecreateBudgetPeriod).Sub(now), false
+	}
+	ift.recreates[destination] = append(recent, now)
+	return 0, true
+}
+
+// isImmutableFieldError tells whether the given error from an update
+// says that the update changes a field that can not be changed.
+func isImmutableFieldError(err error) bool {
+	if !k8sapierrors.IsInvalid(err) {
+		return false
+	}
+	var status k8sapierrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Details == nil {
+		return false
+	}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type == metav1.CauseType(field.ErrorTypeForbidden) || strings.Contains(cause.Message, "immutable") {
+			return true
+		}
+	}
+	return false
+}
+
+// handleImmutableFieldError deals, according to the given policy, with
+// the rejection of an update of the given copy for changing an
+// immutable field. Returns whether to retry the queue item; after a
+// recreate's deletion, the retry creates the new copy.
+func (wp *workloadProjector) handleImmutableFieldError(ctx context.Context, logger klog.Logger,
+	rscClient k8sdynamic.ResourceInterface, soRef sourceObjectRef, srcObj mrObject,
+	wkey mailboxWriteKey, ckey checkpointKey, destObj *unstructured.Unstructured,
+	policy ImmutableFieldPolicy, err error) bool {
+	logger = logger.WithValues("policy", policy)
+	switch policy {
+	case ImmutableFieldSkip:
+		immutableFieldConflicts.WithLabelValues("skipped").Inc()
+		logger.Info("Leaving object in mailbox workspace as it is, because the update changes an immutable field", "err", err)
+		wp.events.ImmutableFieldSkipped(soRef.Cluster, srcObj, destinationName(wkey.Destination), err)
+		return false
+	case ImmutableFieldRecreate:
+		if destObj.GetDeletionTimestamp() != nil {
+			logger.V(3).Info("Waiting for deletion of object in mailbox workspace to finish before recreating it")
+			return true
+		}
+		if wait, ok := wp.immutableFields.takeRecreate(wkey.Destination); !ok {
+			immutableFieldConflicts.WithLabelValues("deferred").Inc()
+			logger.V(2).Info("Deferring recreate of object in mailbox workspace, recreate budget is exhausted", "wait", wait)
+			wp.events.DestinationHeldBack(soRef.Cluster, srcObj, destinationName(wkey.Destination), "recreate budget exhausted")
+			wp.queue.AddAfter(soRef, wait)
+			return false
+		}
+		uid := destObj.GetUID()
+		delErr := rscClient.Delete(ctx, destObj.GetName(), metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}})
+		if delErr != nil && !k8sapierrors.IsNotFound(delErr) {
+			logger.Error(delErr, "Failed to delete object in mailbox workspace for recreate", "reason", countMailboxFailure("delete", delErr))
+			return true
+		}
+		wp.checkpointer.Forget(ckey)
+		immutableFieldConflicts.WithLabelValues("recreated").Inc()
+		logger.Info("Deleted object in mailbox workspace to recreate it, because the update changes an immutable field")
+		wp.events.Recreated(soRef.Cluster, srcObj, destinationName(wkey.Destination), err)
+		return true
+	default:
+		immutableFieldConflicts.WithLabelValues("failed").Inc()
+		wp.events.DestinationFailed(soRef.Cluster, srcObj, destinationName(wkey.Destination), err)
+		return true
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+)
+
+func TestParseImmutableFieldRules(t *testing.T) {
+	rules, err := ParseImmutableFieldRules("skip", map[string]string{"deployments.apps": "Recreate", "persistentvolumeclaims": "fail"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rules.Default != ImmutableFieldSkip {
+		t.Errorf("Expected default Skip, got %q", rules.Default)
+	}
+	deployments := metav1.GroupResource{Group: "apps", Resource: "deployments"}
+	pvcs := metav1.GroupResource{Resource: "persistentvolumeclaims"}
+	if rules.ByResource[deployments] != ImmutableFieldRecreate || rules.ByResource[pvcs] != ImmutableFieldFail {
+		t.Errorf("Wrong policies by resource: %v", rules.ByResource)
+	}
+	if _, err := ParseImmutableFieldRules("Fail", map[string]string{"deployments.apps": "Replace"}); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+	if _, err := ParseImmutableFieldRules("Ignore", nil); err == nil {
+		t.Error("Expected an error for an unknown default policy")
+	}
+}
+
+func TestImmutableFieldPolicyFor(t *testing.T) {
+	deployments := metav1.GroupResource{Group: "apps", Resource: "deployments"}
+	services := metav1.GroupResource{Resource: "services"}
+	ift := newImmutableFieldTracker()
+	if policy := ift.policyFor(services, DistributionBits{}); policy != ImmutableFieldFail {
+		t.Errorf("Expected Fail when nothing is configured, got %q", policy)
+	}
+	ift.rules = ImmutableFieldRules{Default: ImmutableFieldSkip,
+		ByResource: map[metav1.GroupResource]ImmutableFieldPolicy{deployments: ImmutableFieldRecreate}}
+	for _, tc := range []struct {
+		gr       metav1.GroupResource
+		bits     DistributionBits
+		expected ImmutableFieldPolicy
+	}{
+		{services, DistributionBits{}, ImmutableFieldSkip},
+		{deployments, DistributionBits{}, ImmutableFieldRecreate},
+		{deployments, DistributionBits{ImmutableFieldPolicy: ImmutableFieldFail}, ImmutableFieldFail},
+	} {
+		if policy := ift.policyFor(tc.gr, tc.bits); policy != tc.expected {
+			t.Errorf("For %v with %+v expected %q, got %q", tc.gr, tc.bits, tc.expected, policy)
+		}
+	}
+	if combined := combineImmutableFieldPolicies(ImmutableFieldRecreate, ImmutableFieldSkip); combined != ImmutableFieldSkip {
+		t.Errorf("Expected Skip to beat Recreate, got %q", combined)
+	}
+	if combined := combineImmutableFieldPolicies(ImmutableFieldFail, ""); combined != ImmutableFieldFail {
+		t.Errorf("Expected Fail to beat no policy, got %q", combined)
+	}
+}
+
+func TestTakeRecreate(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	ift := newImmutableFieldTracker()
+	ift.now = func() time.Time { return now }
+	ift.rules.RecreateBudget = 2
+	ift.rules.RecreateBudgetPeriod = 10 * time.Minute
+	dest1 := SinglePlacement{Cluster: "inv1", SyncTargetName: "edge1"}
+	dest2 := SinglePlacement{Cluster: "inv1", SyncTargetName: "edge2"}
+	if _, ok := ift.takeRecreate(dest1); !ok {
+		t.Error("Expected the first recreate to be allowed")
+	}
+	now = now.Add(4 * time.Minute)
+	if _, ok := ift.takeRecreate(dest1); !ok {
+		t.Error("Expected the second recreate to be allowed")
+	}
+	now = now.Add(time.Minute)
+	if wait, ok := ift.takeRecreate(dest1); ok || wait != 5*time.Minute {
+		t.Errorf("Expected the third recreate to wait 5m, got %v %v", ok, wait)
+	}
+	if _, ok := ift.takeRecreate(dest2); !ok {
+		t.Error("Expected another destination to have its own budget")
+	}
+	now = now.Add(5 * time.Minute)
+	if _, ok := ift.takeRecreate(dest1); !ok {
+		t.Error("Expected a recreate to be allowed once the oldest one left the period")
+	}
+}
+
+func TestIsImmutableFieldError(t *testing.T) {
+	gk := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	immutable := k8sapierrors.NewInvalid(gk, "web", field.ErrorList{
+		field.Invalid(field.NewPath("spec", "selector"), "app=web", "field is immutable")})
+	shrink := k8sapierrors.NewInvalid(schema.GroupKind{Kind: "PersistentVolumeClaim"}, "data", field.ErrorList{
+		field.Forbidden(field.NewPath("spec", "resources", "requests", "storage"), "field can not be less than previous value")})
+	otherInvalid := k8sapierrors.NewInvalid(gk, "web", field.ErrorList{
+		field.Required(field.NewPath("spec", "template"), "")})
+	conflict := k8sapierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, "web", nil)
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"immutable", immutable, true},
+		{"shrink", shrink, true},
+		{"other invalid", otherInvalid, false},
+		{"conflict", conflict, false},
+	} {
+		if actual := isImmutableFieldError(tc.err); actual != tc.expected {
+			t.Errorf("For %s expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestHandleImmutableFieldError(t *testing.T) {
+	ctx := context.Background()
+	logger := klog.Background()
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	destObj := &unstructured.Unstructured{}
+	destObj.SetAPIVersion("apps/v1")
+	destObj.SetKind("Deployment")
+	destObj.SetNamespace("shop")
+	destObj.SetName("web")
+	destObj.SetUID("1234")
+	soRef := sourceObjectRef{Cluster: "wds1", GroupResource: SchemaGroupResourceToMeta(gvr.GroupResource()), Namespace: "shop", Name: "web"}
+	destination := SinglePlacement{Cluster: "inv1", SyncTargetName: "edge1"}
+	wkey := mailboxWriteKey{Destination: destination, GroupResource: soRef.GroupResource, Namespace: "shop", Name: "web"}
+	ckey := checkpointKeyFor(destination, soRef.GroupResource, "shop", "web")
+	rejection := k8sapierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "web", field.ErrorList{
+		field.Invalid(field.NewPath("spec", "selector"), "app=web", "field is immutable")})
+
+	newWP := func() *workloadProjector {
+		return &workloadProjector{
+			queue:           coalesce.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test-immutable-fields", coalesce.Options{}),
+			immutableFields: newImmutableFieldTracker(),
+		}
+	}
+	newClient := func() *dynamicfake.FakeDynamicClient {
+		return dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), destObj.DeepCopy())
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		wp := newWP()
+		defer wp.queue.ShutDown()
+		client := newClient()
+		rscClient := client.Resource(gvr).Namespace("shop")
+		if retry := wp.handleImmutableFieldError(ctx, logger, rscClient, soRef, destObj, wkey, ckey, destObj, ImmutableFieldSkip, rejection); retry {
+			t.Error("Expected no retry for Skip")
+		}
+		if _, err := rscClient.Get(ctx, "web", metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected the copy to be left in place, got %v", err)
+		}
+	})
+
+	t.Run("recreate", func(t *testing.T) {
+		wp := newWP()
+		defer wp.queue.ShutDown()
+		wp.immutableFields.rules.RecreateBudget = 1
+		wp.immutableFields.rules.RecreateBudgetPeriod = time.Hour
+		client := newClient()
+		rscClient := client.Resource(gvr).Namespace("shop")
+		if retry := wp.handleImmutableFieldError(ctx, logger, rscClient, soRef, destObj, wkey, ckey, destObj, ImmutableFieldRecreate, rejection); !retry {
+			t.Error("Expected a retry to create the copy anew")
+		}
+		if _, err := rscClient.Get(ctx, "web", metav1.GetOptions{}); !k8sapierrors.IsNotFound(err) {
+			t.Errorf("Expected the copy to be deleted, got %v", err)
+		}
+
+		// The budget of the destination is now spent.
+		client = newClient()
+		rscClient = client.Resource(gvr).Namespace("shop")
+		if retry := wp.handleImmutableFieldError(ctx, logger, rscClient, soRef, destObj, wkey, ckey, destObj, ImmutableFieldRecreate, rejection); retry {
+			t.Error("Expected a deferral rather than a retry")
+		}
+		if _, err := rscClient.Get(ctx, "web", metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected the copy to be left in place while the budget is spent, got %v", err)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		wp := newWP()
+		defer wp.queue.ShutDown()
+		client := newClient()
+		rscClient := client.Resource(gvr).Namespace("shop")
+		if retry := wp.handleImmutableFieldError(ctx, logger, rscClient, soRef, destObj, wkey, ckey, destObj, ImmutableFieldFail, rejection); !retry {
+			t.Error("Expected a retry for Fail")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// The load signals below are meant for autoscaling (e.g., by an HPA
+// through a Prometheus adapter, or by KEDA). The depths of the work
+// queues are exposed by the standard workqueue metrics.
+var (
+	fleetSize = metrics.NewGauge(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "fleet_size",
+		Help:           "Number of destinations (mailbox spaces) that this placement translator is projecting to",
+		StabilityLevel: metrics.ALPHA,
+	})
+	edgePlacementCount = metrics.NewGauge(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "edgeplacements",
+		Help:           "Number of EdgePlacements that this placement translator is handling",
+		StabilityLevel: metrics.ALPHA,
+	})
+	convergenceLag = metrics.NewGauge(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "convergence_lag_seconds",
+		Help:           "Age of the oldest change to an EdgePlacement's spec that has not yet converged",
+		StabilityLevel: metrics.ALPHA,
+	})
+)
+
+func init() {
+	legacyregistry.MustRegister(fleetSize, edgePlacementCount, convergenceLag)
+}
+
+// LoadReport is a snapshot of the load on one placement translator.
+// It is served as JSON, for autoscalers (such as the KEDA metrics-api scaler)
+// that read a value from an HTTP endpoint.
+type LoadReport struct {
+	Shard                 int     `json:"shard"`
+	ShardCount            int     `json:"shardCount"`
+	QueueDepth            int     `json:"queueDepth"`
+	FleetSize             int     `json:"fleetSize"`
+	EdgePlacements        int     `json:"edgePlacements"`
+	ConvergenceLagSeconds float64 `json:"convergenceLagSeconds"`
+}
+
+// loadMonitor periodically samples the load signals, sets the gauges,
+// and keeps the latest LoadReport.
+type loadMonitor struct {
+	shard          Shard
+	fleetSize      func() int
+	edgePlacements func() int
+	convergence    *convergenceTracker // may be nil
+
+	mutex  sync.Mutex
+	queues map[string]func() int
+	latest LoadReport
+}
+
+func newLoadMonitor(shard Shard, fleetSize, edgePlacements func() int, convergence *convergenceTracker) *loadMonitor {
+	return &loadMonitor{
+		shard:          shard,
+		fleetSize:      fleetSize,
+		edgePlacements: edgePlacements,
+		convergence:    convergence,
+		queues:         map[string]func() int{},
+	}
+}
+
+// queueDepther is implemented by the parts of the translator that have a work queue.
+type queueDepther interface {
+	queueDepth() int
+}
+
+// AddQueue includes the named queue in the reported queue depth.
+func (lm *loadMonitor) AddQueue(name string, depth func() int) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lm.queues[name] = depth
+}
+
+func (lm *loadMonitor) sample(now time.Time) LoadReport {
+	report := LoadReport{Shard: lm.shard.Index, ShardCount: lm.shard.Count}
+	if report.ShardCount < 1 {
+		report.ShardCount = 1
+	}
+	lm.mutex.Lock()
+	for _, depth := range lm.queues {
+		report.QueueDepth += depth()
+	}
+	lm.mutex.Unlock()
+	report.FleetSize = lm.fleetSize()
+	report.EdgePlacements = lm.edgePlacements()
+	if lm.convergence != nil {
+		report.ConvergenceLagSeconds = lm.convergence.Lag(now).Seconds()
+	}
+	fleetSize.Set(float64(report.FleetSize))
+	edgePlacementCount.Set(float64(report.EdgePlacements))
+	convergenceLag.Set(report.ConvergenceLagSeconds)
+	lm.mutex.Lock()
+	lm.latest = report
+	lm.mutex.Unlock()
+	return report
+}
+
+// Run samples the load every period until the context is done.
+func (lm *loadMonitor) Run(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) { lm.sample(time.Now()) }, period)
+}
+
+// ServeHTTP writes the latest LoadReport as JSON.
+func (lm *loadMonitor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	lm.mutex.Lock()
+	report := lm.latest
+	lm.mutex.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"encoding/json"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+// The LocalProbes of the EdgePlacements travel through the what-resolver,
+// the set-binder and the workload projector in DistributionBits, which
+// must be comparable; so they are carried as canonical JSON: the probes
+// sorted by name, empty string for none.
+
+// localProbesOf returns the canonical JSON of the LocalProbes of the given EdgePlacement.
+func localProbesOf(logger klog.Logger, ep *edgeapi.EdgePlacement) string {
+	return encodeLocalProbes(logger, ep.Spec.LocalProbes)
+}
+
+func encodeLocalProbes(logger klog.Logger, probes []edgeapi.LocalProbe) string {
+	if len(probes) == 0 {
+		return ""
+	}
+	probes = append([]edgeapi.LocalProbe{}, probes...)
+	sort.SliceStable(probes, func(i, j int) bool { return probes[i].Name < probes[j].Name })
+	encoded, err := json.Marshal(probes)
+	if err != nil {
+		logger.Error(err, "Impossible: failed to encode LocalProbes")
+		return ""
+	}
+	return string(encoded)
+}
+
+// combineLocalProbes returns the canonical JSON of the union of the two
+// given sets of probes. When both have a probe of the same name, the one
+// whose JSON sorts first is kept, so that the answer does not depend
+// on the order of combination.
+func combineLocalProbes(a, b string) string {
+	if a == "" || a == b {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	byName := map[string]edgeapi.LocalProbe{}
+	encodedByName := map[string]string{}
+	for _, encoded := range []string{a, b} {
+		var probes []edgeapi.LocalProbe
+		if err := json.Unmarshal([]byte(encoded), &probes); err != nil {
+			continue
+		}
+		for _, probe := range probes {
+			probeJSON, _ := json.Marshal(probe)
+			if have, found := encodedByName[probe.Name]; found && have <= string(probeJSON) {
+				continue
+			}
+			byName[probe.Name] = probe
+			encodedByName[probe.Name] = string(probeJSON)
+		}
+	}
+	union := make([]edgeapi.LocalProbe, 0, len(byName))
+	for _, probe := range byName {
+		union = append(union, probe)
+	}
+	return encodeLocalProbes(klog.Background(), union)
+}
+
+// setLocalProbes sets or, for no probes, removes the LocalProbesKey
+// annotation of the given copy in a mailbox space.
+func setLocalProbes(obj *unstructured.Unstructured, probes string) {
+	annotations := obj.GetAnnotations()
+	if probes == "" {
+		if _, found := annotations[edgeapi.LocalProbesKey]; found {
+			delete(annotations, edgeapi.LocalProbesKey)
+			obj.SetAnnotations(annotations)
+		}
+		return
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[edgeapi.LocalProbesKey] = probes
+	obj.SetAnnotations(annotations)
+}
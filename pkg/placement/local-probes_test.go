@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestCombineLocalProbes(t *testing.T) {
+	logger := klog.Background()
+	web := edgeapi.LocalProbe{Name: "web", HTTPGet: &edgeapi.HTTPGetProbe{URL: "http://a/healthz"}}
+	webToo := edgeapi.LocalProbe{Name: "web", HTTPGet: &edgeapi.HTTPGetProbe{URL: "http://b/healthz"}}
+	up := edgeapi.LocalProbe{Name: "up", PromQL: &edgeapi.PromQLProbe{URL: "http://prom", Query: "up"}}
+	a := encodeLocalProbes(logger, []edgeapi.LocalProbe{webToo, up})
+	b := encodeLocalProbes(logger, []edgeapi.LocalProbe{web})
+	ab, ba := combineLocalProbes(a, b), combineLocalProbes(b, a)
+	if ab != ba {
+		t.Fatalf("Combination depends on order: %s vs %s", ab, ba)
+	}
+	expected := encodeLocalProbes(logger, []edgeapi.LocalProbe{up, web})
+	if ab != expected {
+		t.Errorf("Expected %s, got %s", expected, ab)
+	}
+	if combineLocalProbes("", b) != b || combineLocalProbes(a, "") != a {
+		t.Error("Combining with none should change nothing")
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	setLocalProbes(obj, ab)
+	if obj.GetAnnotations()[edgeapi.LocalProbesKey] != ab {
+		t.Errorf("Expected annotation %s, got %v", ab, obj.GetAnnotations())
+	}
+	setLocalProbes(obj, "")
+	if _, found := obj.GetAnnotations()[edgeapi.LocalProbesKey]; found {
+		t.Errorf("Expected the annotation to be removed, got %v", obj.GetAnnotations())
+	}
+}
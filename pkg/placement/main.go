@@ -18,82 +18,305 @@ package placement
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"os"
+	"time"
 
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	k8scache "k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
 	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/apiwatch"
+	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
 	edgev1a1informers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions/edge/v2alpha1"
-	edgev1a1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/events"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+	"github.com/kubestellar/kubestellar/pkg/scopedinformer"
+	spaceclientfactory "github.com/kubestellar/kubestellar/pkg/spaceclient"
+	spaceapi "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
 	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/client/informers/externalversions/space/v1alpha1"
 	spacev1a1listers "github.com/kubestellar/kubestellar/space-framework/pkg/client/listers/space/v1alpha1"
 	msclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
 )
 
 type placementTranslator struct {
-	context        context.Context
-	apiProvider    APIWatchMapProvider
-	spsInformer    k8scache.SharedIndexInformer
-	syncfgInformer k8scache.SharedIndexInformer
-	syncfgLister   edgev1a1listers.SyncerConfigLister
-	spaceInformer  k8scache.SharedIndexInformer
-	spaceLister    spacev1a1listers.SpaceLister
+	context       context.Context
+	apiProvider   APIWatchMapProvider
+	spaceInformer k8scache.SharedIndexInformer
+	spaceLister   spacev1a1listers.SpaceLister
+
+	// spsSynced and syncfgSynced tell whether the scoped informers on
+	// SinglePlacementSlices and SyncerConfigs have caught up
+	spsSynced    k8scache.InformerSynced
+	syncfgSynced k8scache.InformerSynced
 
 	workloadProjector interface {
 		WorkloadProjector
 		Runnable
+		queueDepther
+		destinationCount() int
+		setWatchdog(*probes.Watchdog)
+		setWriteAdmission(WriteAdmissionLimits)
+		setEventRecorder(*events.Recorder)
+		setRevertManualEdits(bool)
+		setMaxBreakGlassWindow(time.Duration)
+		setDeleteJournal(*deleteJournal)
+		setSoftDeleteGrace(time.Duration)
+		setImmutableFieldRules(ImmutableFieldRules)
+		setUsageReporter(*usageReporter)
+		usageBySource() map[string]projectedCounts
 	}
 
 	whatResolver  WhatResolver
 	whereResolver WhereResolver
+
+	load *loadMonitor
+
+	usage       *usageReporter
+	usagePeriod time.Duration
 }
 
+// Names under which the parts of the placement translator report recovered panics.
+const (
+	recoveryNameWhat      = "placement-translator/what-resolver"
+	recoveryNameWhere     = "placement-translator/where-resolver"
+	recoveryNameAPIWatch  = "placement-translator/api-watch"
+	recoveryNameProjector = "placement-translator/workload-projector"
+)
+
+// loadSamplePeriod is how often the load signals are sampled.
+const loadSamplePeriod = 10 * time.Second
+
 func NewPlacementTranslator(
 	numThreads int,
 	ctx context.Context,
 	locationPreInformer edgev1a1informers.LocationInformer,
-	// pre-informer on SinglePlacementSlice objects
+	// pre-informer on EdgePlacement objects
 	epPreInformer edgev1a1informers.EdgePlacementInformer,
-	// pre-informer on SinglePlacementSlice objects
-	spsPreInformer edgev1a1informers.SinglePlacementSliceInformer,
-	// pre-informer on syncer config objects, should be in mailbox spaces
-	syncfgPreInformer edgev1a1informers.SyncerConfigInformer,
 
 	spaceclient msclient.KubestellarSpaceInterface,
+	// shared clients for the spaces, resolved through spaceclient
+	spaceClients *spaceclientfactory.Factory,
 	spaceProviderNs string,
 	spacePreInformer spacev1alpha1.SpaceInformer,
 	kbSpaceRelation kbuser.KubeBindSpaceRelation,
+	// number of objects going to a destination above which they are bundled; zero disables bundling
+	bundleThreshold int,
+	// file in which to checkpoint what was projected, for a fast restart; empty disables checkpointing
+	checkpointFile string,
+	checkpointPeriod time.Duration,
+	// where to keep the results of API discovery across restarts; nil disables that
+	discoveryCache apiwatch.DiscoveryCache,
+	// how often to delete mailbox copies whose source object is gone; zero disables this
+	ownershipGCPeriod time.Duration,
+	// which part of the mailbox spaces to handle
+	shard Shard,
 ) *placementTranslator {
-	amp := NewAPIWatchMapProvider(ctx, numThreads, spaceclient, spaceProviderNs)
+	amp := NewAPIWatchMapProvider(ctx, numThreads, spaceclient, spaceProviderNs, discoveryCache)
+	convergence := newConvergenceTracker(spaceClients, spaceProviderNs)
+	if shard.Sharded() {
+		// Each shard sees only its part of the convergence,
+		// so none of them can say when the whole has converged.
+		convergence.report = func(context.Context, ExternalName, time.Duration) {}
+		klog.FromContext(ctx).Info("Sharded: not maintaining executing counts, singleton reported state, nor convergence durations in status", "shard", shard.Index, "shardCount", shard.Count)
+	}
+	epInformer := epPreInformer.Informer()
+	spaceInformer := spacePreInformer.Informer()
+	spaceLister := spacePreInformer.Lister()
+
+	// The SinglePlacementSlices are read from the workload description
+	// spaces that have EdgePlacements, and the SyncerConfigs from the
+	// mailbox spaces of this shard, each through that space's own client,
+	// rather than from the kube-bind copies of every space's objects.
+	spsInformer := scopedinformer.NewMultiSpaceInformer(ctx,
+		scopedinformer.EdgeInformerFunc(spaceClients, spaceProviderNs, 0, func(factory edgeinformers.SharedScopedInformerFactory) k8scache.SharedIndexInformer {
+			return factory.Edge().V2alpha1().SinglePlacementSlices().Informer()
+		}), k8scache.Indexers{})
+	spsSynced := scopedinformer.TrackSpaces(ctx, spsInformer, epInformer, func() (sets.String, error) {
+		return edgePlacementSpaces(epInformer.GetStore().List(), kbSpaceRelation)
+	})
+	syncfgInformer := scopedinformer.NewMultiSpaceInformer(ctx,
+		scopedinformer.EdgeInformerFunc(spaceClients, spaceProviderNs, 0, func(factory edgeinformers.SharedScopedInformerFactory) k8scache.SharedIndexInformer {
+			return factory.Edge().V2alpha1().SyncerConfigs().Informer()
+		}), k8scache.Indexers{})
+	syncfgSynced := scopedinformer.TrackSpaces(ctx, syncfgInformer, spaceInformer, func() (sets.String, error) {
+		return mailboxSpaces(spaceLister, shard)
+	})
+
 	pt := &placementTranslator{
-		context:        ctx,
-		apiProvider:    amp,
-		spsInformer:    spsPreInformer.Informer(),
-		syncfgInformer: syncfgPreInformer.Informer(),
-		syncfgLister:   syncfgPreInformer.Lister(),
-		spaceInformer:  spacePreInformer.Informer(),
-		spaceLister:    spacePreInformer.Lister(),
-
-		whatResolver:  NewWhatResolver(ctx, epPreInformer, spaceclient, spaceProviderNs, kbSpaceRelation, numThreads),
-		whereResolver: NewWhereResolver(ctx, spsPreInformer, kbSpaceRelation, numThreads),
+		context:       ctx,
+		apiProvider:   amp,
+		spaceInformer: spaceInformer,
+		spaceLister:   spaceLister,
+		spsSynced:     spsSynced,
+		syncfgSynced:  syncfgSynced,
+
+		whatResolver:  NewWhatResolver(ctx, epPreInformer, spaceclient, spaceProviderNs, kbSpaceRelation, convergence, discoveryCache, numThreads),
+		whereResolver: NewWhereResolver(ctx, spsInformer, spsSynced, shard, numThreads),
 	}
 	pt.workloadProjector = NewWorkloadProjector(ctx, numThreads, DefaultResourceModes,
-		pt.spaceInformer, pt.spaceLister, pt.syncfgInformer,
-		spaceclient, spaceProviderNs, kbSpaceRelation)
+		pt.spaceInformer, pt.spaceLister, syncfgInformer, locationPreInformer.Lister(),
+		spaceclient, spaceClients, spaceProviderNs, kbSpaceRelation, convergence, bundleThreshold,
+		newCheckpointer(klog.FromContext(ctx), checkpointFile, checkpointPeriod), ownershipGCPeriod, shard)
+	pt.load = newLoadMonitor(shard, pt.workloadProjector.destinationCount,
+		func() int { return len(epInformer.GetStore().ListKeys()) }, convergence)
+	pt.load.AddQueue("workload-projector", pt.workloadProjector.queueDepth)
+	pt.usage = newUsageReporter(spaceClients, spaceProviderNs,
+		func() map[string]int { return edgePlacementsBySpace(epInformer.GetStore().List(), kbSpaceRelation) },
+		pt.workloadProjector.usageBySource)
+	if shard.Sharded() {
+		// No one shard knows the whole usage of a space
+		pt.usage.write = nil
+	}
+	pt.workloadProjector.setUsageReporter(pt.usage)
 
 	return pt
 }
 
+// edgePlacementsBySpace counts the given provider-side copies of
+// EdgePlacements by the consumer's space.
+func edgePlacementsBySpace(objs []any, kbSpaceRelation kbuser.KubeBindSpaceRelation) map[string]int {
+	ans := map[string]int{}
+	for _, obj := range objs {
+		ep, ok := obj.(*edgeapi.EdgePlacement)
+		if !ok {
+			continue
+		}
+		_, _, kbSpaceID, err := kbuser.AnalyzeObjectID(ep)
+		if err != nil {
+			continue
+		}
+		if spaceID := kbSpaceRelation.SpaceIDFromKubeBind(kbSpaceID); spaceID != "" {
+			ans[spaceID]++
+		}
+	}
+	return ans
+}
+
+// edgePlacementSpaces returns the consumer spaces of the given
+// provider-side copies of EdgePlacements. The error reports the copies
+// whose space is not known yet; the returned set is good for the others.
+func edgePlacementSpaces(objs []any, kbSpaceRelation kbuser.KubeBindSpaceRelation) (sets.String, error) {
+	ans := sets.NewString()
+	unknown := 0
+	for _, obj := range objs {
+		ep, ok := obj.(*edgeapi.EdgePlacement)
+		if !ok {
+			continue
+		}
+		_, _, kbSpaceID, err := kbuser.AnalyzeObjectID(ep)
+		if err != nil {
+			continue
+		}
+		if spaceID := kbSpaceRelation.SpaceIDFromKubeBind(kbSpaceID); spaceID != "" {
+			ans.Insert(spaceID)
+		} else {
+			unknown++
+		}
+	}
+	if unknown > 0 {
+		return ans, fmt.Errorf("the space of %d EdgePlacement(s) is not known yet", unknown)
+	}
+	return ans, nil
+}
+
+// mailboxSpaces returns the names of the ready mailbox spaces that the given shard handles.
+func mailboxSpaces(spaceLister spacev1a1listers.SpaceLister, shard Shard) (sets.String, error) {
+	spaces, err := spaceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	ans := sets.NewString()
+	for _, space := range spaces {
+		if looksLikeMBWSName(space.Name) && shard.OwnsMailbox(space.Name) && space.Status.Phase == spaceapi.SpacePhaseReady {
+			ans.Insert(space.Name)
+		}
+	}
+	return ans, nil
+}
+
+// ScopedInformersSynced tells whether the informers that the placement
+// translator keeps on a changing set of spaces have caught up.
+func (pt *placementTranslator) ScopedInformersSynced() bool {
+	return pt.spsSynced() && pt.syncfgSynced()
+}
+
+// SetWatchdog makes the given Watchdog track the processing of each
+// item in the workload projector's queue. Must be called before Run.
+func (pt *placementTranslator) SetWatchdog(watchdog *probes.Watchdog) {
+	pt.workloadProjector.setWatchdog(watchdog)
+}
+
+// SetWriteAdmission limits the writes into mailbox spaces, so that a
+// slow mailbox apiserver applies back-pressure. Must be called before Run.
+func (pt *placementTranslator) SetWriteAdmission(limits WriteAdmissionLimits) {
+	pt.workloadProjector.setWriteAdmission(limits)
+}
+
+// SetEventRecorder makes the placement translator record Events about
+// workload objects that fail to reach their destinations. Must be called before Run.
+func (pt *placementTranslator) SetEventRecorder(rcdr *events.Recorder) {
+	pt.workloadProjector.setEventRecorder(rcdr)
+}
+
+// SetRevertManualEdits makes the placement translator undo the changes
+// that others make to the copies it maintains in mailbox spaces.
+// Must be called before Run.
+func (pt *placementTranslator) SetRevertManualEdits(revert bool) {
+	pt.workloadProjector.setRevertManualEdits(revert)
+}
+
+// SetMaxBreakGlassWindow bounds how long the placement translator
+// leaves alone a copy under a break-glass override (see package
+// breakglass); zero means that such overrides are ignored.
+// Must be called before Run.
+func (pt *placementTranslator) SetMaxBreakGlassWindow(maxWindow time.Duration) {
+	pt.workloadProjector.setMaxBreakGlassWindow(maxWindow)
+}
+
+// SetDeleteJournalFile makes the placement translator record, in the
+// given file, the deletions in mailbox spaces that are in progress, and
+// finish after a restart the ones that were interrupted; empty path means
+// not to. Must be called before Run.
+func (pt *placementTranslator) SetDeleteJournalFile(path string) {
+	pt.workloadProjector.setDeleteJournal(newDeleteJournal(klog.FromContext(pt.context), path))
+}
+
+// SetSoftDeleteGrace makes the placement translator, when a placement
+// no longer selects a destination, leave the copies there for the given
+// time, scheduled for deletion, before deleting them (see package
+// softdelete); zero means to delete them right away.
+// Must be called before Run.
+func (pt *placementTranslator) SetSoftDeleteGrace(grace time.Duration) {
+	pt.workloadProjector.setSoftDeleteGrace(grace)
+}
+
+// SetImmutableFieldRules configures what the placement translator does
+// when an update of a copy in a mailbox space is rejected for changing
+// an immutable field. Must be called before Run.
+func (pt *placementTranslator) SetImmutableFieldRules(rules ImmutableFieldRules) {
+	pt.workloadProjector.setImmutableFieldRules(rules)
+}
+
+// SetTenantUsagePeriod makes the placement translator report the usage
+// of each workload description space, in metrics and in its TenantUsage
+// object, every period; zero means never. Must be called before Run.
+func (pt *placementTranslator) SetTenantUsagePeriod(period time.Duration) {
+	pt.usagePeriod = period
+}
+
 func (pt *placementTranslator) Run() {
 	ctx := pt.context
 	logger := klog.FromContext(ctx)
 
 	doneCh := ctx.Done()
-	if !(k8scache.WaitForNamedCacheSync("placement-translator(sps)", doneCh, pt.spsInformer.HasSynced) &&
+	if !(k8scache.WaitForNamedCacheSync("placement-translator(sps)", doneCh, pt.spsSynced) &&
 		k8scache.WaitForNamedCacheSync("placement-translator(space)", doneCh, pt.spaceInformer.HasSynced) &&
-		k8scache.WaitForNamedCacheSync("placement-translator(sync)", doneCh, pt.syncfgInformer.HasSynced) &&
+		k8scache.WaitForNamedCacheSync("placement-translator(sync)", doneCh, pt.syncfgSynced) &&
 		true) {
 		logger.Error(nil, "Informer syncs not achieved")
 		os.Exit(100)
@@ -101,11 +324,19 @@ func (pt *placementTranslator) Run() {
 
 	whatResolver := func(mr MappingReceiver[ExternalName, ResolvedWhat]) Runnable {
 		fork := MappingReceiverFork[ExternalName, ResolvedWhat]{NewLoggingMappingReceiver[ExternalName, ResolvedWhat]("what", logger), mr}
-		return pt.whatResolver(fork)
+		runnable := pt.whatResolver(fork)
+		if qd, ok := runnable.(queueDepther); ok {
+			pt.load.AddQueue("what-resolver", qd.queueDepth)
+		}
+		return runnable
 	}
 	whereResolver := func(mr MappingReceiver[ExternalName, ResolvedWhere]) Runnable {
 		fork := MappingReceiverFork[ExternalName, ResolvedWhere]{NewLoggingMappingReceiver[ExternalName, ResolvedWhere]("where", logger), mr}
-		return pt.whereResolver(fork)
+		runnable := pt.whereResolver(fork)
+		if qd, ok := runnable.(queueDepther); ok {
+			pt.load.AddQueue("where-resolver", qd.queueDepth)
+		}
+		return runnable
 	}
 	setBinder := NewSetBinder(logger, NewWorkloadPartsDifferencer, NewUpsyncDifferencer, NewResolvedWhereDifferencer,
 		SimpleBindingOrganizer(logger),
@@ -118,9 +349,18 @@ func (pt *placementTranslator) Run() {
 	// TODO: move all that stuff up before Run
 	go pt.apiProvider.Run(ctx)       // TODO: also wait for this to finish
 	go pt.workloadProjector.Run(ctx) // TODO: also wait for this to finish
+	go pt.load.Run(ctx, loadSamplePeriod)
+	if pt.usagePeriod > 0 {
+		go pt.usage.Run(ctx, pt.usagePeriod)
+	}
 	runner.Run(ctx)
 }
 
+// LoadHandler returns an HTTP handler that serves the latest LoadReport as JSON.
+func (pt *placementTranslator) LoadHandler() http.Handler {
+	return pt.load
+}
+
 func NewUpsyncDifferencer(eltReceiver SetChangeReceiver[edgeapi.UpsyncSet]) Receiver[ /*immutable*/ []edgeapi.UpsyncSet] {
 	return NewSliceDifferencerParametric(UpsyncSetEqual, eltReceiver, nil)
 }
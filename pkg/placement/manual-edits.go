@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// setRevertManualEdits makes the workload projector undo, as soon as
+// it notices them, the changes that others make to the copies it
+// maintains in mailbox spaces. See also package mailboxguard, which
+// rejects such changes instead.
+func (wp *workloadProjector) setRevertManualEdits(revert bool) {
+	wp.revertManualEdits = revert
+}
+
+// editedByOthers tells whether, according to its managedFields, someone
+// other than the placement translator has changed the given copy other
+// than through its status subresource.
+func editedByOthers(obj metav1.Object) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != FieldManager && entry.Subresource == "" &&
+			(entry.Operation == metav1.ManagedFieldsOperationUpdate || entry.Operation == metav1.ManagedFieldsOperationApply) {
+			return true
+		}
+	}
+	return false
+}
+
+// revertLocked enqueues the sources of the given copy, so that syncing
+// them writes the copy back to what it should be.
+func (wp *workloadProjector) revertLocked(logger klog.Logger, doRef destinationObjectRef, sources Visitable[Pair[string, DistributionBits]]) {
+	sources.Visit(func(sourceWant Pair[string, DistributionBits]) error {
+		if sourceWant.Second.CreateOnly {
+			return nil
+		}
+		soRef := sourceObjectRef{Cluster: sourceWant.First, GroupResource: doRef.GroupResource, Namespace: doRef.Namespace, Name: string(doRef.Name)}
+		logger.V(3).Info("Enqueuing source of manually edited copy", "source", soRef)
+		wp.queue.Add(soRef)
+		return nil
+	})
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEditedByOthers(t *testing.T) {
+	ours := metav1.ManagedFieldsEntry{Manager: FieldManager, Operation: metav1.ManagedFieldsOperationUpdate}
+	syncer := metav1.ManagedFieldsEntry{Manager: "syncer", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "status"}
+	kubectl := metav1.ManagedFieldsEntry{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate}
+	for idx, tc := range []struct {
+		entries  []metav1.ManagedFieldsEntry
+		expected bool
+	}{
+		{nil, false},
+		{[]metav1.ManagedFieldsEntry{ours, syncer}, false},
+		{[]metav1.ManagedFieldsEntry{ours, syncer, kubectl}, true},
+	} {
+		obj := &metav1.ObjectMeta{ManagedFields: tc.entries}
+		if got := editedByOthers(obj); got != tc.expected {
+			t.Errorf("Case %d: expected %v, got %v", idx, tc.expected, got)
+		}
+	}
+}
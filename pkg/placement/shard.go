@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+// Shard identifies the part of the work that one placement translator
+// does when several of them share the load of a large fleet.
+// The work is split by mailbox space: each mailbox space is handled
+// by exactly one shard, so there is exactly one writer of each SyncerConfig
+// and of each workload copy. Every shard resolves every EdgePlacement.
+// A Count of 0 or 1 means there is no sharding.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// Sharded tells whether the work is split.
+func (shard Shard) Sharded() bool {
+	return shard.Count > 1
+}
+
+// Validate returns an error if the Index is out of range.
+func (shard Shard) Validate() error {
+	if shard.Sharded() && (shard.Index < 0 || shard.Index >= shard.Count) {
+		return fmt.Errorf("shard index %d is not in [0, %d)", shard.Index, shard.Count)
+	}
+	return nil
+}
+
+// OwnsMailbox tells whether the named mailbox space is handled by this shard.
+func (shard Shard) OwnsMailbox(mbsName string) bool {
+	if !shard.Sharded() {
+		return true
+	}
+	hasher := fnv.New32a()
+	hasher.Write([]byte(mbsName))
+	return int(hasher.Sum32()%uint32(shard.Count)) == shard.Index
+}
+
+// Owns tells whether the given destination is handled by this shard.
+func (shard Shard) Owns(sp SinglePlacement) bool {
+	return shard.OwnsMailbox(SPMailboxWorkspaceName(sp))
+}
+
+// FilterSlice returns the given SinglePlacementSlice restricted
+// to the destinations handled by this shard.
+// The given slice is not modified.
+func (shard Shard) FilterSlice(sps *edgeapi.SinglePlacementSlice) *edgeapi.SinglePlacementSlice {
+	if !shard.Sharded() {
+		return sps
+	}
+	filtered := *sps
+	filtered.Destinations = []SinglePlacement{}
+	for _, dest := range sps.Destinations {
+		if shard.Owns(dest) {
+			filtered.Destinations = append(filtered.Destinations, dest)
+		}
+	}
+	return &filtered
+}
+
+// ShardIndexFromHostname extracts the ordinal from the hostname of a
+// StatefulSet member (e.g., 2 from "placement-translator-2").
+func ShardIndexFromHostname(hostname string) (int, bool) {
+	lastDash := strings.LastIndex(hostname, "-")
+	if lastDash < 0 {
+		return 0, false
+	}
+	index, err := strconv.Atoi(hostname[lastDash+1:])
+	if err != nil || index < 0 {
+		return 0, false
+	}
+	return index, true
+}
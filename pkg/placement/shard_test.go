@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestShardPartition(t *testing.T) {
+	shards := []Shard{{Index: 0, Count: 3}, {Index: 1, Count: 3}, {Index: 2, Count: 3}}
+	sps := &edgeapi.SinglePlacementSlice{}
+	for idx := 0; idx < 30; idx++ {
+		sps.Destinations = append(sps.Destinations, SinglePlacement{Cluster: "inv1", SyncTargetName: fmt.Sprintf("st%d", idx), SyncTargetUID: types.UID(fmt.Sprintf("uid-%d", idx))})
+	}
+	total := 0
+	for _, shard := range shards {
+		filtered := shard.FilterSlice(sps)
+		if len(filtered.Destinations) == 0 {
+			t.Errorf("Shard %d got nothing", shard.Index)
+		}
+		for _, dest := range filtered.Destinations {
+			owners := 0
+			for _, other := range shards {
+				if other.Owns(dest) {
+					owners++
+				}
+			}
+			if owners != 1 {
+				t.Errorf("Destination %v has %d owners", dest, owners)
+			}
+		}
+		total += len(filtered.Destinations)
+	}
+	if total != len(sps.Destinations) {
+		t.Errorf("Shards got %d destinations in total, expected %d", total, len(sps.Destinations))
+	}
+	if len(sps.Destinations) != 30 {
+		t.Error("The given slice was modified")
+	}
+	if unsharded := (Shard{}).FilterSlice(sps); unsharded != sps {
+		t.Error("Unsharded filter did not return the given slice")
+	}
+}
+
+func TestShardIndexFromHostname(t *testing.T) {
+	for hostname, expected := range map[string]int{"placement-translator-0": 0, "placement-translator-12": 12} {
+		if index, ok := ShardIndexFromHostname(hostname); !ok || index != expected {
+			t.Errorf("For %q got %d, %v", hostname, index, ok)
+		}
+	}
+	for _, hostname := range []string{"translator", "placement-translator-x", "pt--1"} {
+		if index, ok := ShardIndexFromHostname(hostname); ok {
+			t.Errorf("For %q got unexpected %d", hostname, index)
+		}
+	}
+	if err := (Shard{Index: 3, Count: 3}).Validate(); err == nil {
+		t.Error("Out of range index was accepted")
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"time"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sdynamic "k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/softdelete"
+)
+
+// setSoftDeleteGrace makes the projector schedule the deletion of a copy
+// that is no longer wanted in its mailbox space for the given time later,
+// instead of deleting it right away (see package softdelete); zero
+// means to delete right away.
+func (wp *workloadProjector) setSoftDeleteGrace(grace time.Duration) {
+	wp.softDeleteGrace = grace
+}
+
+// softDelete handles the given copy, which is no longer wanted in its
+// mailbox space, when soft deletion is enabled.
+// Returns `(retry, handled bool)`; when not handled, the copy is due for
+// deletion and the caller should delete it.
+func (wp *workloadProjector) softDelete(ctx context.Context, logger klog.Logger, doRef destinationObjectRef,
+	client k8sdynamic.ResourceInterface, obj *unstructured.Unstructured) (bool, bool) {
+	if wp.softDeleteGrace <= 0 {
+		return false, false
+	}
+	if softdelete.Rescued(obj) {
+		logger.V(3).Info("Leaving rescued object in mailbox workspace")
+		return false, true
+	}
+	now := time.Now()
+	at, scheduled, err := softdelete.ScheduledAt(obj)
+	if err != nil {
+		logger.V(2).Info("Rescheduling deletion of object with malformed schedule", "err", err)
+	}
+	if scheduled {
+		if now.Before(at) {
+			wp.queue.AddAfter(doRef, at.Sub(now))
+			return false, true
+		}
+		return false, false
+	}
+	at = now.Add(wp.softDeleteGrace)
+	revised := obj.DeepCopy()
+	softdelete.Schedule(revised, at)
+	_, err = client.Update(ctx, revised, metav1.UpdateOptions{FieldManager: FieldManager})
+	if err == nil {
+		logger.V(2).Info("Scheduled deletion of undesired object in mailbox workspace", "at", at)
+	} else if k8sapierrors.IsNotFound(err) {
+		logger.V(4).Info("Undesired object in mailbox workspace was deleted concurrently")
+		return false, true
+	} else {
+		logger.Error(err, "Failed to schedule deletion of undesired object in mailbox workspace")
+		return true, true
+	}
+	wp.queue.AddAfter(doRef, wp.softDeleteGrace)
+	return false, true
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	spaceclientfactory "github.com/kubestellar/kubestellar/pkg/spaceclient"
+)
+
+// The per-tenant usage signals below are for chargeback and fairness.
+// A tenant is a workload description space (WDS).
+// When the translator is sharded, each shard reports on its own part
+// of the destinations, so the destination and copy counts and the
+// written bytes add up across shards while the others do not.
+var (
+	tenantEdgePlacements = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "tenant_edgeplacements",
+		Help:           "Number of EdgePlacements in a workload description space",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"space"})
+	tenantDestinations = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "tenant_destinations",
+		Help:           "Number of mailbox spaces that objects from a workload description space are going to",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"space"})
+	tenantObjects = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "tenant_objects",
+		Help:           "Number of objects in a workload description space that are going to at least one destination",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"space"})
+	tenantObjectCopies = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "tenant_object_copies",
+		Help:           "Number of copies of objects from a workload description space kept in mailbox spaces",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"space"})
+	tenantMailboxWriteBytes = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "tenant_mailbox_write_bytes_total",
+		Help:           "Total size of the objects written into mailbox spaces on behalf of a workload description space",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"space"})
+)
+
+func init() {
+	legacyregistry.MustRegister(tenantEdgePlacements, tenantDestinations, tenantObjects, tenantObjectCopies, tenantMailboxWriteBytes)
+}
+
+// projectedCounts is what the workload projector knows about the
+// consumption of one WDS.
+type projectedCounts struct {
+	destinations int
+	objects      int
+	objectCopies int
+}
+
+// usageReporter periodically aggregates the consumption of each WDS
+// into the metrics above and into the WDS' TenantUsage object.
+// A nil *usageReporter records nothing.
+type usageReporter struct {
+	spaceClients    *spaceclientfactory.Factory
+	spaceProviderNs string
+
+	// edgePlacements returns the number of EdgePlacements, by space
+	edgePlacements func() map[string]int
+
+	// projected returns the projector's counts, by source space
+	projected func() map[string]projectedCounts
+
+	// write is called outside the mutex to write a TenantUsage; nil means not to
+	write func(ctx context.Context, space string, status edgeapi.TenantUsageStatus) error
+
+	mutex sync.Mutex
+
+	// written is the bytes written into mailbox spaces since windowStart, by source space
+	written     map[string]int64
+	windowStart time.Time
+
+	// reported is the spaces with non-zero usage in the previous report.
+	// Accessed only by the reporting goroutine.
+	reported map[string]struct{}
+}
+
+func newUsageReporter(spaceClients *spaceclientfactory.Factory, spaceProviderNs string, edgePlacements func() map[string]int, projected func() map[string]projectedCounts) *usageReporter {
+	ur := &usageReporter{
+		spaceClients:    spaceClients,
+		spaceProviderNs: spaceProviderNs,
+		edgePlacements:  edgePlacements,
+		projected:       projected,
+		written:         map[string]int64{},
+		windowStart:     time.Now(),
+		reported:        map[string]struct{}{},
+	}
+	ur.write = ur.writeTenantUsage
+	return ur
+}
+
+// recordWrite records that the given object was written into a
+// mailbox space on behalf of the given source space.
+func (ur *usageReporter) recordWrite(space string, obj *unstructured.Unstructured) {
+	if ur == nil {
+		return
+	}
+	size := objectSize(obj)
+	tenantMailboxWriteBytes.WithLabelValues(space).Add(float64(size))
+	ur.mutex.Lock()
+	defer ur.mutex.Unlock()
+	ur.written[space] += size
+}
+
+// Run reports every period until the context is done.
+func (ur *usageReporter) Run(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) { ur.report(ctx, time.Now()) }, period)
+}
+
+// report aggregates the usage of every space that has some, or had some
+// in the previous report, and reports it. A space whose usage has
+// dropped to zero gets one final report and then its metrics are removed.
+func (ur *usageReporter) report(ctx context.Context, now time.Time) {
+	logger := klog.FromContext(ctx)
+	placements := ur.edgePlacements()
+	projected := ur.projected()
+	var written map[string]int64
+	var windowStart time.Time
+	func() {
+		ur.mutex.Lock()
+		defer ur.mutex.Unlock()
+		written, windowStart = ur.written, ur.windowStart
+		ur.written, ur.windowStart = map[string]int64{}, now
+	}()
+	spaces := ur.reported
+	ur.reported = map[string]struct{}{}
+	for space := range placements {
+		spaces[space] = struct{}{}
+	}
+	for space := range projected {
+		spaces[space] = struct{}{}
+	}
+	for space := range written {
+		spaces[space] = struct{}{}
+	}
+	for space := range spaces {
+		counts := projected[space]
+		status := edgeapi.TenantUsageStatus{
+			LastUpdateTime:    &metav1.Time{Time: now},
+			EdgePlacements:    int32(placements[space]),
+			Destinations:      int32(counts.destinations),
+			Objects:           int32(counts.objects),
+			ObjectCopies:      int32(counts.objectCopies),
+			WindowStart:       &metav1.Time{Time: windowStart},
+			MailboxWriteBytes: written[space],
+		}
+		if status.EdgePlacements == 0 && counts == (projectedCounts{}) && status.MailboxWriteBytes == 0 {
+			tenantEdgePlacements.DeleteLabelValues(space)
+			tenantDestinations.DeleteLabelValues(space)
+			tenantObjects.DeleteLabelValues(space)
+			tenantObjectCopies.DeleteLabelValues(space)
+			tenantMailboxWriteBytes.DeleteLabelValues(space)
+		} else {
+			ur.reported[space] = struct{}{}
+			tenantEdgePlacements.WithLabelValues(space).Set(float64(status.EdgePlacements))
+			tenantDestinations.WithLabelValues(space).Set(float64(status.Destinations))
+			tenantObjects.WithLabelValues(space).Set(float64(status.Objects))
+			tenantObjectCopies.WithLabelValues(space).Set(float64(status.ObjectCopies))
+		}
+		if ur.write == nil {
+			continue
+		}
+		if err := ur.write(ctx, space, status); err != nil {
+			logger.Error(err, "Failed to write TenantUsage", "space", space)
+		}
+	}
+}
+
+// writeTenantUsage puts the given status in the TenantUsage of the given space,
+// creating that object if necessary.
+func (ur *usageReporter) writeTenantUsage(ctx context.Context, space string, status edgeapi.TenantUsageStatus) error {
+	clients, err := ur.spaceClients.For(space, ur.spaceProviderNs)
+	if err != nil {
+		return err
+	}
+	client := clients.Edge.EdgeV2alpha1().TenantUsages()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		tu, err := client.Get(ctx, edgeapi.TenantUsageName, metav1.GetOptions{})
+		if k8sapierrors.IsNotFound(err) {
+			tu, err = client.Create(ctx, &edgeapi.TenantUsage{ObjectMeta: metav1.ObjectMeta{Name: edgeapi.TenantUsageName}}, metav1.CreateOptions{FieldManager: FieldManager})
+		}
+		if err != nil {
+			return err
+		}
+		tu = tu.DeepCopy()
+		tu.Status = status
+		_, err = client.UpdateStatus(ctx, tu, metav1.UpdateOptions{FieldManager: FieldManager})
+		return err
+	})
+}
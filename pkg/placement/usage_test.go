@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestUsageReporter(t *testing.T) {
+	ctx := context.Background()
+	placements := map[string]int{"wds1": 2, "wds2": 1}
+	projected := map[string]projectedCounts{"wds1": {destinations: 3, objects: 4, objectCopies: 12}}
+	ur := newUsageReporter(nil, "",
+		func() map[string]int { return placements },
+		func() map[string]projectedCounts { return projected })
+	written := map[string]edgeapi.TenantUsageStatus{}
+	ur.write = func(_ context.Context, space string, status edgeapi.TenantUsageStatus) error {
+		written[space] = status
+		return nil
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap"}}
+	ur.recordWrite("wds1", obj)
+	ur.recordWrite("wds1", obj)
+	size := objectSize(obj)
+
+	t0 := time.Now()
+	ur.report(ctx, t0)
+	got := written["wds1"]
+	if got.EdgePlacements != 2 || got.Destinations != 3 || got.Objects != 4 || got.ObjectCopies != 12 || got.MailboxWriteBytes != 2*size {
+		t.Errorf("Unexpected usage of wds1: %+v", got)
+	}
+	if got = written["wds2"]; got.EdgePlacements != 1 || got.ObjectCopies != 0 || got.MailboxWriteBytes != 0 {
+		t.Errorf("Unexpected usage of wds2: %+v", got)
+	}
+
+	// The written bytes are per window
+	written = map[string]edgeapi.TenantUsageStatus{}
+	ur.report(ctx, t0.Add(time.Minute))
+	if got = written["wds1"]; got.MailboxWriteBytes != 0 || !got.WindowStart.Time.Equal(t0) {
+		t.Errorf("Unexpected window for wds1: %+v", got)
+	}
+
+	// A space whose usage drops to zero gets one final report
+	delete(placements, "wds2")
+	written = map[string]edgeapi.TenantUsageStatus{}
+	ur.report(ctx, t0.Add(2*time.Minute))
+	if got, has := written["wds2"]; !has || got.EdgePlacements != 0 {
+		t.Errorf("Expected a final report of zero for wds2, got %+v, %v", got, has)
+	}
+	written = map[string]edgeapi.TenantUsageStatus{}
+	ur.report(ctx, t0.Add(3*time.Minute))
+	if got, has := written["wds2"]; has {
+		t.Errorf("Expected no more reports for wds2, got %+v", got)
+	}
+	if _, has := written["wds1"]; !has {
+		t.Error("Expected a report for wds1")
+	}
+
+	// A nil reporter records nothing
+	var nilReporter *usageReporter
+	nilReporter.recordWrite("wds1", obj)
+}
@@ -28,6 +28,7 @@ import (
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -35,9 +36,12 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
-	kubedynamicinformer "k8s.io/client-go/dynamic/dynamicinformer"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	upstreamcache "k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog/v2"
 
 	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
@@ -45,7 +49,13 @@ import (
 	"github.com/kubestellar/kubestellar/pkg/apiwatch"
 	edgev2alpha1informers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions/edge/v2alpha1"
 	edgev2alpha1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+	"github.com/kubestellar/kubestellar/pkg/dependencies"
+	"github.com/kubestellar/kubestellar/pkg/guardrails"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	"github.com/kubestellar/kubestellar/pkg/spechash"
+	"github.com/kubestellar/kubestellar/pkg/trust"
 	msclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
 )
 
@@ -53,7 +63,7 @@ type whatResolver struct {
 	ctx        context.Context
 	logger     klog.Logger
 	numThreads int
-	queue      workqueue.RateLimitingInterface
+	queue      *coalesce.Queue
 	receiver   MappingReceiver[ExternalName, ResolvedWhat]
 
 	edgePlacementInformer upstreamcache.SharedIndexInformer
@@ -62,6 +72,8 @@ type whatResolver struct {
 	spaceclient     msclient.KubestellarSpaceInterface
 	spaceProviderNs string
 	kbSpaceRelation kbuser.KubeBindSpaceRelation
+	convergence     *convergenceTracker     // may be nil
+	discoveryCache  apiwatch.DiscoveryCache // may be nil
 
 	// Hold this while accessing data listed below
 	sync.Mutex
@@ -74,11 +86,14 @@ type whatResolver struct {
 type workspaceDetails struct {
 	ctx context.Context
 	// placements maps name of relevant EdgePlacement object to that object
-	placements             map[ObjectName]*edgeapi.EdgePlacement
-	stop                   func()
-	apiInformer            upstreamcache.SharedInformer
-	apiLister              apiwatch.APIResourceLister
-	dynamicInformerFactory kubedynamicinformer.DynamicSharedInformerFactory
+	placements  map[ObjectName]*edgeapi.EdgePlacement
+	stop        func()
+	apiInformer upstreamcache.SharedInformer
+	apiLister   apiwatch.APIResourceLister
+	// informers keeps an informer running on each resource that downsyncs
+	informers *apiwatch.DynamicInformerManager
+	// kubeClient is used to maintain generated guardrail objects
+	kubeClient kubernetes.Interface
 	// resources maps APIResource.Name to data for that resource,
 	// and formerly only contains entries for non-namespaced resources
 	resources map[string]*resourceResolver
@@ -92,12 +107,18 @@ type resourceResolver struct {
 	gvr      schema.GroupVersionResource
 	informer upstreamcache.SharedInformer
 	lister   upstreamcache.GenericLister
-	stop     func()
 
 	// byObjName maps object namespace (if namespaced) and name to relevant details
 	byObjName map[NamespacedName]*objectDetails
 
 	definers Set[ksmetav1a1.Definer]
+
+	// deprecation is non-nil if this version of the resource is deprecated
+	deprecation *ksmetav1a1.APIResourceDeprecation
+
+	// deprecationLogged tells whether a use of this deprecated version
+	// has been logged at the default level
+	deprecationLogged bool
 }
 
 type NamespacedName = Pair[NamespaceName, ObjectName]
@@ -109,6 +130,17 @@ type objectDetails struct {
 	PlacementBits MutableMap[ObjectName, DistributionBits]
 }
 
+var whatExclusions = metrics.NewCounter(&metrics.CounterOpts{
+	Subsystem:      "kubestellar_placement",
+	Name:           "what_exclusions_total",
+	Help:           "Number of times an object was kept out of an EdgePlacement that matches it because of the object's exclusion annotation",
+	StabilityLevel: metrics.ALPHA,
+})
+
+func init() {
+	legacyregistry.MustRegister(whatExclusions)
+}
+
 // NewWhatResolver returns a WhatResolver;
 // invoke that function after the namespace informer has synced.
 func NewWhatResolver(
@@ -117,6 +149,9 @@ func NewWhatResolver(
 	spaceclient msclient.KubestellarSpaceInterface,
 	spaceProviderNs string,
 	kbSpaceRelation kbuser.KubeBindSpaceRelation,
+	convergence *convergenceTracker,
+	// may be nil
+	discoveryCache apiwatch.DiscoveryCache,
 	numThreads int,
 ) WhatResolver {
 	controllerName := "what-resolver"
@@ -126,17 +161,19 @@ func NewWhatResolver(
 		ctx:                   ctx,
 		logger:                logger,
 		numThreads:            numThreads,
-		queue:                 workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		queue:                 coalesce.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName, coalesce.Options{}),
 		edgePlacementInformer: edgePlacementPreInformer.Informer(),
 		edgePlacementLister:   edgePlacementPreInformer.Lister(),
 		spaceclient:           spaceclient,
 		spaceProviderNs:       spaceProviderNs,
 		kbSpaceRelation:       kbSpaceRelation,
+		convergence:           convergence,
+		discoveryCache:        discoveryCache,
 		workspaceDetails:      map[string]*workspaceDetails{},
 	}
 	return func(receiver MappingReceiver[ExternalName, ResolvedWhat]) Runnable {
 		wr.receiver = receiver
-		wr.edgePlacementInformer.AddEventHandler(WhatResolverClusterHandler{wr, mkgk(edgeapi.SchemeGroupVersion.Group, "EdgePlacement")})
+		wr.edgePlacementInformer.AddEventHandler(recovery.Handler(logger, recoveryNameWhat, WhatResolverClusterHandler{wr, mkgk(edgeapi.SchemeGroupVersion.Group, "EdgePlacement")}))
 		if !upstreamcache.WaitForNamedCacheSync(controllerName, ctx.Done(), wr.edgePlacementInformer.HasSynced) {
 			logger.Info("Failed to sync EdgePlacements in time")
 		}
@@ -144,6 +181,10 @@ func NewWhatResolver(
 	}
 }
 
+func (wr *whatResolver) queueDepth() int {
+	return wr.queue.Len()
+}
+
 func (wr *whatResolver) Run(ctx context.Context) {
 	var wg sync.WaitGroup
 	wg.Add(wr.numThreads)
@@ -168,18 +209,22 @@ type WhatResolverClusterHandler struct {
 }
 
 func (wrh WhatResolverClusterHandler) OnAdd(obj any) {
-	wrh.enqueue(wrh.gk, obj)
+	wrh.enqueue(wrh.gk, obj, coalesce.Added)
 }
 
 func (wrh WhatResolverClusterHandler) OnUpdate(oldObj, newObj any) {
-	wrh.enqueue(wrh.gk, newObj)
+	if !spechash.Changed(oldObj, newObj) {
+		wrh.logger.V(4).Info("Ignoring update that changes neither spec nor reprocess annotation", "edgePlacement", newObj.(*edgeapi.EdgePlacement).Name)
+		return
+	}
+	wrh.enqueue(wrh.gk, newObj, coalesce.Updated)
 }
 
 func (wrh WhatResolverClusterHandler) OnDelete(obj any) {
-	wrh.enqueue(wrh.gk, obj)
+	wrh.enqueue(wrh.gk, obj, coalesce.Deleted)
 }
 
-func (wr *whatResolver) enqueue(gk schema.GroupKind, objAny any) {
+func (wr *whatResolver) enqueue(gk schema.GroupKind, objAny any, event coalesce.Event) {
 	key, err := upstreamcache.DeletionHandlingMetaNamespaceKeyFunc(objAny)
 	if err != nil {
 		wr.logger.Error(err, "Failed to extract object reference", "object", objAny)
@@ -195,7 +240,7 @@ func (wr *whatResolver) enqueue(gk schema.GroupKind, objAny any) {
 	// Enqueuewith empty cluster. Set it later
 	item := namespacedQueueItem{GK: gk, Cluster: "", NN: NewPair(NamespaceName(metav1.NamespaceNone), ObjectName(name))}
 	wr.logger.V(4).Info("Enqueuing", "item", item)
-	wr.queue.Add(item)
+	wr.queue.AddEvent(item, event)
 }
 
 type WhatResolverScopedHandler struct {
@@ -205,18 +250,18 @@ type WhatResolverScopedHandler struct {
 }
 
 func (wrh WhatResolverScopedHandler) OnAdd(obj any) {
-	wrh.enqueueScoped(wrh.gk, wrh.cluster, obj)
+	wrh.enqueueScoped(wrh.gk, wrh.cluster, obj, coalesce.Added)
 }
 
 func (wrh WhatResolverScopedHandler) OnUpdate(oldObj, newObj any) {
-	wrh.enqueueScoped(wrh.gk, wrh.cluster, newObj)
+	wrh.enqueueScoped(wrh.gk, wrh.cluster, newObj, coalesce.Updated)
 }
 
 func (wrh WhatResolverScopedHandler) OnDelete(obj any) {
-	wrh.enqueueScoped(wrh.gk, wrh.cluster, obj)
+	wrh.enqueueScoped(wrh.gk, wrh.cluster, obj, coalesce.Deleted)
 }
 
-func (wr *whatResolver) enqueueScoped(gk schema.GroupKind, cluster string, objAny any) {
+func (wr *whatResolver) enqueueScoped(gk schema.GroupKind, cluster string, objAny any, event coalesce.Event) {
 	key, err := upstreamcache.DeletionHandlingMetaNamespaceKeyFunc(objAny)
 	if err != nil {
 		wr.logger.Error(err, "Failed to extract object reference", "object", objAny)
@@ -229,12 +274,13 @@ func (wr *whatResolver) enqueueScoped(gk schema.GroupKind, cluster string, objAn
 	nn := NewPair(NamespaceName(namespace), ObjectName(name))
 	item := namespacedQueueItem{GK: gk, Cluster: cluster, NN: nn}
 	wr.logger.V(4).Info("Enqueuing", "item", item)
-	wr.queue.Add(item)
+	wr.queue.AddEvent(item, event)
 }
 
 func (wr *whatResolver) getPartsLocked(wldCluster string, epName ObjectName) ResolvedWhat {
 	parts := WorkloadParts{}
 	var upsyncs []edgeapi.UpsyncSet
+	var includeDependencies bool
 	wsDetails, found := wr.workspaceDetails[wldCluster]
 	var definers MutableSet[ksmetav1a1.Definer] = NewEmptyMapSet[ksmetav1a1.Definer]()
 	if !found {
@@ -242,6 +288,7 @@ func (wr *whatResolver) getPartsLocked(wldCluster string, epName ObjectName) Res
 	}
 	if ep, found := wsDetails.placements[epName]; found {
 		upsyncs = ep.Spec.Upsync
+		includeDependencies = ep.Spec.IncludeDependencies
 	}
 	for _, rr := range wsDetails.resources {
 		var gotSome bool
@@ -261,6 +308,9 @@ func (wr *whatResolver) getPartsLocked(wldCluster string, epName ObjectName) Res
 			SetAddAll[ksmetav1a1.Definer](definers, rr.definers)
 		}
 	}
+	if includeDependencies {
+		wr.addDependenciesLocked(wsDetails, parts)
+	}
 	definers.Visit(func(definer ksmetav1a1.Definer) error {
 		nsName := NamespaceName("")
 		objName := ObjectName(definer.Name)
@@ -278,6 +328,78 @@ func (wr *whatResolver) getPartsLocked(wldCluster string, epName ObjectName) Res
 	return ResolvedWhat{parts, upsyncs}
 }
 
+// addDependenciesLocked adds to the given parts the objects that they
+// refer to (see the dependencies package), except those that are
+// missing, system objects, or excluded.
+func (wr *whatResolver) addDependenciesLocked(wsDetails *workspaceDetails, parts WorkloadParts) {
+	byGR := map[schema.GroupResource]*resourceResolver{}
+	for _, rr := range wsDetails.resources {
+		byGR[rr.gvr.GroupResource()] = rr
+	}
+	additions := WorkloadParts{}
+	for partID := range parts {
+		gr := MetaGroupResourceToSchema(partID.First)
+		if !dependencies.IsReferrer(gr) {
+			continue
+		}
+		obj := getUnstructured(byGR[gr], partID.Second, partID.Third)
+		if obj == nil {
+			continue
+		}
+		for _, ref := range dependencies.Extract(gr, obj) {
+			refRR := byGR[ref.GroupResource()]
+			refID := NewTriple(SchemaGroupResourceToMeta(ref.GroupResource()), NamespaceName(ref.Namespace), ObjectName(ref.Name))
+			if _, found := parts[refID]; found {
+				continue
+			}
+			refObj := getUnstructured(refRR, refID.Second, refID.Third)
+			if refObj == nil || ObjectIsSystem(refObj) || isExcluded(refObj) {
+				continue
+			}
+			refDetails := WorkloadPartDetails{APIVersion: refRR.gvr.Version, CreateOnly: isCreateOnly(refObj)}
+			wr.logger.V(4).Info("Adding dependency", "partID", refID, "referrer", partID, "details", refDetails)
+			additions[refID] = refDetails
+		}
+	}
+	for partID, partDetails := range additions {
+		parts[partID] = partDetails
+	}
+}
+
+// getUnstructured returns the object with the given name from the given
+// resource's lister, or nil if there is no such resource or object.
+func getUnstructured(rr *resourceResolver, namespace NamespaceName, name ObjectName) *unstructured.Unstructured {
+	if rr == nil {
+		return nil
+	}
+	var lister upstreamcache.GenericNamespaceLister = rr.lister
+	if len(namespace) > 0 {
+		lister = rr.lister.ByNamespace(string(namespace))
+	}
+	obj, err := lister.Get(string(name))
+	if err != nil {
+		return nil
+	}
+	objU, _ := obj.(*unstructured.Unstructured)
+	return objU
+}
+
+// dependencyPlacements returns the EdgePlacements in the given WDS that
+// include dependencies and whose parts may change when an object of the
+// given resource changes, even if its own matches do not.
+func dependencyPlacements(wsDetails *workspaceDetails, gr schema.GroupResource) MapSet[ObjectName] {
+	ans := NewEmptyMapSet[ObjectName]()
+	if !(dependencies.IsReferrer(gr) || dependencies.IsReferent(gr)) {
+		return ans
+	}
+	for epName, ep := range wsDetails.placements {
+		if ep.Spec.IncludeDependencies {
+			ans.Add(epName)
+		}
+	}
+	return ans
+}
+
 var definerKindToPieces = map[string]Pair[metav1.GroupResource, WorkloadPartDetails]{
 	"CustomResourceDefinition": NewPair(metav1.GroupResource{Group: apiext.GroupName, Resource: "customresourcedefinitions"}, WorkloadPartDetails{APIVersion: apiext.SchemeGroupVersion.Version}),
 }
@@ -313,7 +435,9 @@ func (wr *whatResolver) processNextWorkItem() bool {
 	ctx := klog.NewContext(wr.ctx, logger)
 	logger.V(4).Info("Started processing queueItem")
 
-	if wr.process(ctx, item) {
+	var done bool
+	recovery.Guard(logger, recoveryNameWhat, item, func() { done = wr.process(ctx, item) })
+	if done {
 		logger.V(4).Info("Finished processing queueItem")
 		wr.queue.Forget(itemAny)
 	} else {
@@ -385,17 +509,64 @@ func (wr *whatResolver) processCenterObject(ctx context.Context, cluster string,
 	// TODO: make a way to not enumerate two when the bits change
 	MapEnumerateDifferences[ObjectName, DistributionBits](newDetails.PlacementBits, oldDetails.PlacementBits, MappingReceiverDiscardsPrevious[ObjectName, DistributionBits](changedPlacements))
 	MapEnumerateDifferences[ObjectName, DistributionBits](oldDetails.PlacementBits, newDetails.PlacementBits, MappingReceiverDiscardsPrevious[ObjectName, DistributionBits](changedPlacements))
-	logger.V(4).Info("Processed object", "newDetails", newDetails, "changedPlacements", changedPlacements)
-	if changedPlacements.IsEmpty() {
+	wr.enqueueTrustReadersLocked(wsDetails, rr.gvr.GroupResource(), objName)
+	depPlacements := dependencyPlacements(wsDetails, rr.gvr.GroupResource())
+	logger.V(4).Info("Processed object", "newDetails", newDetails, "changedPlacements", changedPlacements, "dependencyPlacements", depPlacements)
+	if changedPlacements.IsEmpty() && depPlacements.IsEmpty() {
 		return true
 	}
 	if rObj != nil {
 		rr.byObjName[objName] = newDetails
 	}
-	wr.notifyReceiversOfPlacements(cluster, MapKeySet[ObjectName, DistributionBits](changedPlacements))
+	if rr.deprecation != nil {
+		newDetails.PlacementBits.Visit(func(tup Pair[ObjectName, DistributionBits]) error {
+			if _, had := oldDetails.PlacementBits.Get(tup.First); !had {
+				rr.logDeprecatedUse(logger, tup.First, objName)
+			}
+			return nil
+		})
+	}
+	SetAddAll[ObjectName](depPlacements, MapKeySet[ObjectName, DistributionBits](changedPlacements))
+	wr.notifyReceiversOfPlacements(cluster, depPlacements)
 	return true
 }
 
+// enqueueTrustReadersLocked enqueues the EdgePlacements whose trust
+// material is read from the given object, so that the generated trust
+// objects follow changes to it, such as rotation of a certificate.
+func (wr *whatResolver) enqueueTrustReadersLocked(wsDetails *workspaceDetails, gr schema.GroupResource, objName NamespacedName) {
+	for _, ep := range wsDetails.placements {
+		if trust.IsSource(ep.Spec.Trust, gr.Group, gr.Resource, string(objName.First), string(objName.Second)) {
+			item := namespacedQueueItem{GK: mkgk(edgeapi.SchemeGroupVersion.Group, "EdgePlacement"), NN: NewPair(NamespaceName(metav1.NamespaceNone), ObjectName(ep.Name))}
+			wr.logger.V(4).Info("Enqueuing for change in trust source", "item", item, "resource", gr, "namespace", objName.First, "name", objName.Second)
+			wr.queue.AddEvent(item, coalesce.Updated)
+		}
+	}
+}
+
+// logDeprecatedUse reports that the given EdgePlacement downsyncs an object
+// through the deprecated version of the resource. Only the first such use is
+// logged at the default level, so that a resource with many objects does not
+// flood the log; the rest are logged at V(2).
+func (rr *resourceResolver) logDeprecatedUse(logger klog.Logger, epName ObjectName, objName NamespacedName) {
+	values := []any{"gvr", rr.gvr, "edgePlacement", epName, "namespace", objName.First, "name", objName.Second}
+	if rr.deprecation.RemovedInRelease != "" {
+		values = append(values, "removedInRelease", rr.deprecation.RemovedInRelease)
+	}
+	if rr.deprecation.Replacement != nil {
+		values = append(values, "replacement", *rr.deprecation.Replacement)
+	}
+	if rr.deprecation.Warning != "" {
+		values = append(values, "warning", rr.deprecation.Warning)
+	}
+	if !rr.deprecationLogged {
+		rr.deprecationLogged = true
+		logger.Info("EdgePlacement selects objects of a deprecated resource version; further uses are logged at V(2)", values...)
+		return
+	}
+	logger.V(2).Info("EdgePlacement selects an object of a deprecated resource version", values...)
+}
+
 func newObjectDetails() *objectDetails {
 	return &objectDetails{PlacementBits: NewMapMap[ObjectName, DistributionBits](nil)}
 }
@@ -426,7 +597,7 @@ func (wr *whatResolver) processResource(ctx context.Context, cluster string, arN
 			logger.V(4).Info("Nothing to do for resource", "isNil", ar == nil, "isNamespaced", ar != nil && ar.Spec.Namespaced)
 			return true
 		}
-		rr.stop()
+		// The informer manager has stopped the informer.
 		delete(wsDetails.resources, arName)
 		changedPlacements := NewEmptyMapSet[ObjectName]()
 		for _, objDetails := range rr.byObjName {
@@ -446,30 +617,41 @@ func (wr *whatResolver) processResource(ctx context.Context, cluster string, arN
 		Resource: ar.Spec.Name,
 	}
 	logger = logger.WithValues("gvr", gvr, "arName", arName, "definers", ar.Spec.Definers)
-	if _, found := GRsNotSupported[gr]; found {
-		logger.V(4).Info("Ignoring unsupported resource")
-		return true
-	}
-	if _, found := GRsNotForEdge[gr]; found || GroupsNotForEdge.Has(gr.Group) {
-		logger.V(4).Info("Ignoring resource that does not downsync")
+	if !downsyncable(gr) {
+		logger.V(4).Info("Ignoring resource that is not supported or does not downsync")
 		return true
 	}
 	gk := schema.GroupKind{Group: ar.Spec.Group, Kind: ar.Spec.Kind}
 	logger = logger.WithValues("gk", gk)
+	preInformer, informedAR, found := wsDetails.informers.Informer(gr)
+	if !found || informedAR.Name != arName {
+		// The informer manager has not caught up yet; it enqueues the
+		// resource again when it starts the informer.
+		logger.V(4).Info("No informer for resource yet")
+		return true
+	}
+	if rr != nil && rr.informer != preInformer.Informer() {
+		// The informer was restarted, e.g. because the CRD was deleted
+		// and created again; start over with the new one.
+		changedPlacements := NewEmptyMapSet[ObjectName]()
+		for _, objDetails := range rr.byObjName {
+			SetAddAll[ObjectName](changedPlacements, MapKeySet[ObjectName, DistributionBits](objDetails.PlacementBits))
+		}
+		logger.V(3).Info("Informer for resource was restarted", "changedPlacements", changedPlacements)
+		wr.notifyReceiversOfPlacements(cluster, changedPlacements)
+		rr = nil
+	}
 	if rr == nil {
-		informerCtx, stopInformer := context.WithCancel(wsDetails.ctx)
-		preInformer := wsDetails.dynamicInformerFactory.ForResource(gvr)
 		objInformer := preInformer.Informer()
-		objInformer.AddEventHandler(WhatResolverScopedHandler{wr, gk, cluster})
+		objInformer.AddEventHandler(recovery.Handler(logger, recoveryNameWhat, WhatResolverScopedHandler{wr, gk, cluster}))
 		rr = &resourceResolver{
 			gvr:       gvr,
 			informer:  objInformer,
 			lister:    preInformer.Lister(),
-			stop:      stopInformer,
 			byObjName: map[NamespacedName]*objectDetails{},
 			definers:  NewSliceSet(ar.Spec.Definers...),
 		}
-		go rr.informer.Run(informerCtx.Done())
+		rr.deprecation = ar.Spec.Deprecation
 		logger.V(3).Info("Started to watch resource")
 		wsDetails.resources[arName] = rr
 		wsDetails.gkToARName[gk] = arName
@@ -477,6 +659,10 @@ func (wr *whatResolver) processResource(ctx context.Context, cluster string, arN
 		var newDefiners Set[ksmetav1a1.Definer] = NewSliceSet(ar.Spec.Definers...)
 		changed := !SetEqual(rr.definers, newDefiners)
 		logger.V(4).Info("Continuing to watch resource", "changed", changed)
+		if !apiequality.Semantic.DeepEqual(rr.deprecation, ar.Spec.Deprecation) {
+			rr.deprecationLogged = false
+		}
+		rr.deprecation = ar.Spec.Deprecation
 		if changed {
 			rr.definers = newDefiners
 			changedPlacements := NewEmptyMapSet[ObjectName]()
@@ -514,28 +700,78 @@ func (wr *whatResolver) processEdgePlacement(ctx context.Context, epName ObjectN
 	}
 	epName = ObjectName(epOriginalName)
 
+	// Reconciling the guardrails and trust objects takes calls to the
+	// apiserver, so it is done outside the mutex, with a client taken under it.
+	if !epFound {
+		if generatedClient := wr.generatedClientForDeleted(spaceID, epName); generatedClient != nil {
+			retryGuardrails := guardrails.Reconcile(ctx, logger, generatedClient, string(epName), nil)
+			retryTrust := trust.Reconcile(ctx, logger, generatedClient, string(epName), nil)
+			if retryGuardrails || retryTrust {
+				return false
+			}
+		}
+	}
+	success, generatedClient := wr.updateEdgePlacementState(ctx, spaceID, epName, ep, epFound)
+	if generatedClient != nil {
+		retryGuardrails := guardrails.Reconcile(ctx, logger, generatedClient, string(epName), &ep.Spec)
+		retryTrust := trust.Reconcile(ctx, logger, generatedClient, string(epName), &ep.Spec)
+		if retryGuardrails || retryTrust {
+			return false
+		}
+	}
+	return success
+}
+
+// generatedClientForDeleted returns the client with which to delete the
+// guardrails and trust objects of the named EdgePlacement, which no
+// longer exists; nil if it had none.
+func (wr *whatResolver) generatedClientForDeleted(spaceID string, epName ObjectName) kubernetes.Interface {
+	wr.Lock()
+	defer wr.Unlock()
+	wsDetails := wr.workspaceDetails[spaceID]
+	if wsDetails == nil {
+		return nil
+	}
+	prevEp := wsDetails.placements[epName]
+	if prevEp == nil || prevEp.Spec.NetworkGuardrails == nil && prevEp.Spec.Trust == nil {
+		return nil
+	}
+	return wsDetails.kubeClient
+}
+
+// updateEdgePlacementState updates, under the mutex, the what-resolver's
+// state for the given EdgePlacement, which is nil if not found. Returns true
+// on success or unrecoverable error, false to retry; and, if the EdgePlacement's
+// guardrails or trust objects need reconciling, the client with which to do that.
+func (wr *whatResolver) updateEdgePlacementState(ctx context.Context, spaceID string, epName ObjectName, ep *edgeapi.EdgePlacement, epFound bool) (bool, kubernetes.Interface) {
+	logger := klog.FromContext(ctx)
 	wr.Lock()
 	defer wr.Unlock()
 	wsDetails, wsDetailsFound := wr.workspaceDetails[spaceID]
 	if !wsDetailsFound {
 		if !epFound {
 			logger.V(4).Info(`Both workspaceDetails and EdgePlacement were not found`)
-			return true
+			return true, nil
 		}
 		config, err := wr.spaceclient.ConfigForSpace(spaceID, wr.spaceProviderNs)
 		if err != nil {
 			logger.Error(err, "Failed to get space config", "space", spaceID)
-			return true
+			return true, nil
 		}
 		discoveryScopedClient, err := discovery.NewDiscoveryClientForConfig(config)
 		if err != nil {
 			logger.Error(err, "Failed to create discovery client", "space", spaceID)
-			return true
+			return true, nil
 		}
 		scopedDynamic, err := dynamic.NewForConfig(config)
 		if err != nil {
 			logger.Error(err, "Failed to create dynamic client", "space", spaceID)
-			return true
+			return true, nil
+		}
+		kubeClient, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			logger.Error(err, "Failed to create kube clientset", "space", spaceID)
+			return true, nil
 		}
 		apiextClient, err := apiextclient.NewForConfig(config)
 		if err != nil {
@@ -548,36 +784,47 @@ func (wr *whatResolver) processEdgePlacement(ctx context.Context, epName ObjectN
 		doneCh := wsCtx.Done()
 		apiextFactory.Start(doneCh)
 
-		apiInformer, apiLister, _ := apiwatch.NewAPIResourceInformer(wsCtx, spaceID, discoveryScopedClient, false,
+		// Only resources that can be informed on can be downsynced
+		apiInformer, apiLister, _ := apiwatch.NewAPIResourceInformerWithOptions(wsCtx, spaceID, discoveryScopedClient,
+			apiwatch.APIResourceInformerOptions{RequiredVerbs: apiwatch.InformerVerbs, DiscoveryCache: wr.discoveryCache},
 			apiwatch.CRDAnalyzer{ObjectNotifier: crdInformer})
-		dynamicInformerFactory := kubedynamicinformer.NewDynamicSharedInformerFactory(scopedDynamic, 0)
+		apiHandler := WhatResolverScopedHandler{wr, mkgk(ksmetav1a1.SchemeGroupVersion.Group, "APIResource"), spaceID}
+		informerManager := apiwatch.NewDynamicInformerManager(wsCtx, scopedDynamic, apiInformer, apiLister, apiwatch.DynamicInformerManagerOptions{
+			Selects: func(ar *ksmetav1a1.APIResource) bool {
+				return downsyncable(schema.GroupResource{Group: ar.Spec.Group, Resource: ar.Spec.Name})
+			},
+			OnStart: func(ar *ksmetav1a1.APIResource, _ kubeinformers.GenericInformer) { apiHandler.OnUpdate(nil, ar) },
+		})
 		wsDetails = &workspaceDetails{
-			ctx:                    wsCtx,
-			placements:             map[ObjectName]*edgeapi.EdgePlacement{},
-			stop:                   stopWS,
-			apiInformer:            apiInformer,
-			apiLister:              apiLister,
-			dynamicInformerFactory: dynamicInformerFactory,
-			resources:              map[string]*resourceResolver{},
-			gkToARName:             map[schema.GroupKind]string{},
+			ctx:         wsCtx,
+			placements:  map[ObjectName]*edgeapi.EdgePlacement{},
+			stop:        stopWS,
+			apiInformer: apiInformer,
+			apiLister:   apiLister,
+			informers:   informerManager,
+			kubeClient:  kubeClient,
+			resources:   map[string]*resourceResolver{},
+			gkToARName:  map[schema.GroupKind]string{},
 		}
 		wr.workspaceDetails[spaceID] = wsDetails
-		apiInformer.AddEventHandler(WhatResolverScopedHandler{wr, mkgk(ksmetav1a1.SchemeGroupVersion.Group, "APIResource"), spaceID})
+		apiInformer.AddEventHandler(recovery.Handler(logger, recoveryNameWhat, apiHandler))
 		logger.V(2).Info("Started watching space")
 		go apiInformer.Run(doneCh)
-		dynamicInformerFactory.Start(doneCh)
 		if !upstreamcache.WaitForCacheSync(doneCh, apiInformer.HasSynced) {
 			logger.Error(nil, "Failed to sync API informer in time")
-			return true
+			return true, nil
 		}
 	}
 	if wsDetailsFound && !epFound {
 		_, wasIncluded := wsDetails.placements[epName]
 		if !wasIncluded {
 			logger.V(4).Info(`Absent EdgePlacement is already irrelevant`)
-			return true
+			return true, nil
 		}
 		delete(wsDetails.placements, epName)
+		if wr.convergence != nil {
+			wr.convergence.Forget(ExternalName{Cluster: spaceID, Name: epName})
+		}
 		for _, rr := range wsDetails.resources {
 			for objName, objDetails := range rr.byObjName {
 				objDetails.PlacementBits.Delete(epName)
@@ -595,22 +842,34 @@ func (wr *whatResolver) processEdgePlacement(ctx context.Context, epName ObjectN
 			wr.notifyReceivers(spaceID, epName)
 		}
 		wr.notifyReceivers(spaceID, epName)
-		return true
+		return true, nil
 	}
 	// Now we know that ep != nil
 	prevEp := wsDetails.placements[epName]
 	wsDetails.placements[epName] = ep
+	if wr.convergence != nil && (prevEp == nil || prevEp.Generation != ep.Generation) {
+		wr.convergence.SpecChanged(ExternalName{Cluster: spaceID, Name: epName}, time.Now())
+	}
+	completeSuccess := true
+	var generatedClient kubernetes.Interface
+	if ep.Spec.NetworkGuardrails != nil || ep.Spec.Trust != nil ||
+		prevEp != nil && (prevEp.Spec.NetworkGuardrails != nil || prevEp.Spec.Trust != nil) {
+		generatedClient = wsDetails.kubeClient
+	}
 	if prevEp == nil {
 		logger.V(3).Info("Starting watching EdgePlacement")
 	} else {
-		whatPredicateUnChanged := apiequality.Semantic.DeepEqual(prevEp.Spec.Downsync, ep.Spec.Downsync)
+		whatPredicateUnChanged := apiequality.Semantic.DeepEqual(prevEp.Spec.Downsync, ep.Spec.Downsync) &&
+			apiequality.Semantic.DeepEqual(prevEp.Spec.LocalProbes, ep.Spec.LocalProbes) &&
+			(prevEp.Spec.NetworkGuardrails == nil) == (ep.Spec.NetworkGuardrails == nil) &&
+			(prevEp.Spec.Trust == nil) == (ep.Spec.Trust == nil) &&
+			prevEp.Annotations[edgeapi.ImmutableFieldPolicyAnnotationKey] == ep.Annotations[edgeapi.ImmutableFieldPolicyAnnotationKey]
 		if whatPredicateUnChanged {
 			logger.V(4).Info(`No change in "what" predicate`)
-			return true
+			return completeSuccess, generatedClient
 		}
 	}
 	anyChange := false
-	completeSuccess := true
 	for _, rr := range wsDetails.resources {
 		logger := logger.WithValues("gvr", rr.gvr)
 		rObjs, err := rr.lister.List(labels.Everything())
@@ -626,7 +885,7 @@ func (wr *whatResolver) processEdgePlacement(ctx context.Context, epName ObjectN
 			if objDetails == nil {
 				objDetails = newObjectDetails()
 			}
-			objChange, success := objDetails.setByMatch(logger, wsDetails, &ep.Spec, epName, rr.gvr.Resource, mrObj)
+			objChange, success := objDetails.setByMatch(logger, wsDetails, ep, epName, rr.gvr.Resource, mrObj)
 			logger.V(5).Info("From objDetails.setByMatch", "objNN", objNN, "found", found, "objChange", objChange, "success", success)
 			if !success {
 				completeSuccess = false
@@ -642,7 +901,7 @@ func (wr *whatResolver) processEdgePlacement(ctx context.Context, epName ObjectN
 	if anyChange {
 		wr.notifyReceivers(spaceID, epName)
 	}
-	return completeSuccess
+	return completeSuccess, generatedClient
 }
 
 type mrObject interface {
@@ -654,7 +913,7 @@ type mrObject interface {
 func whatMatchingPlacements(logger klog.Logger, wsd *workspaceDetails, candidates map[ObjectName]*edgeapi.EdgePlacement, whatResource string, whatObj mrObject) *objectDetails {
 	ans := newObjectDetails()
 	for epName, ep := range candidates {
-		_, success := ans.setByMatch(logger, wsd, &ep.Spec, epName, whatResource, whatObj)
+		_, success := ans.setByMatch(logger, wsd, ep, epName, whatResource, whatObj)
 		if !success {
 			return nil
 		}
@@ -663,11 +922,14 @@ func whatMatchingPlacements(logger klog.Logger, wsd *workspaceDetails, candidate
 }
 
 // returns `(changed bool, success bool)`
-func (od *objectDetails) setByMatch(logger klog.Logger, wsd *workspaceDetails, spec *edgeapi.EdgePlacementSpec, epName ObjectName, whatResource string, whatObj mrObject) (bool, bool) {
+func (od *objectDetails) setByMatch(logger klog.Logger, wsd *workspaceDetails, ep *edgeapi.EdgePlacement, epName ObjectName, whatResource string, whatObj mrObject) (bool, bool) {
+	spec := &ep.Spec
 	oldDistrBits, found := od.PlacementBits.Get(epName)
 	newDistrBits := DistributionBits{ReturnSingletonState: spec.WantSingletonReportedState,
-		CreateOnly: whatObj != nil && isCreateOnly(whatObj)}
-	objMatch, success := whatMatches(logger, wsd, spec, whatResource, whatObj)
+		CreateOnly:           whatObj != nil && isCreateOnly(whatObj),
+		ImmutableFieldPolicy: immutableFieldPolicyOf(logger, ep),
+		LocalProbes:          localProbesOf(logger, ep)}
+	objMatch, success := whatMatches(logger, wsd, spec, epName, whatResource, whatObj)
 	if !success {
 		return false, false
 	}
@@ -690,10 +952,30 @@ func isCreateOnly(whatObj mrObject) bool {
 	return annotations[edgeapi.DownsyncOverwriteKey] == "false"
 }
 
+// isExcluded tells whether the object opts out of all EdgePlacements;
+// see edgeapi.ExcludeAnnotationKey.
+func isExcluded(whatObj mrObject) bool {
+	return whatObj.GetAnnotations()[edgeapi.ExcludeAnnotationKey] == "true"
+}
+
 // whatMatches tests the given object against the "what predicate" of an EdgePlacementSpec.
 // The first returned bool indicates whether there is a match.
 // The second indicates whether an accurate answer was found.
-func whatMatches(logger klog.Logger, wsd *workspaceDetails, spec *edgeapi.EdgePlacementSpec, whatResource string, whatObj mrObject) (bool, bool) {
+// The NetworkPolicies generated for the EdgePlacement's `networkGuardrails`
+// and the objects generated for its `trust` always match.
+// An object that is excluded never matches.
+func whatMatches(logger klog.Logger, wsd *workspaceDetails, spec *edgeapi.EdgePlacementSpec, epName ObjectName, whatResource string, whatObj mrObject) (bool, bool) {
+	match, ok := whatPredicateMatches(logger, wsd, spec, epName, whatResource, whatObj)
+	if match && ok && isExcluded(whatObj) {
+		logger.V(4).Info("Excluding object from EdgePlacement", "edgePlacement", epName, "resource", whatResource,
+			"namespace", whatObj.GetNamespace(), "name", whatObj.GetName())
+		whatExclusions.Inc()
+		return false, true
+	}
+	return match, ok
+}
+
+func whatPredicateMatches(logger klog.Logger, wsd *workspaceDetails, spec *edgeapi.EdgePlacementSpec, epName ObjectName, whatResource string, whatObj mrObject) (bool, bool) {
 	if ObjectIsSystem(whatObj) {
 		return false, true
 	}
@@ -701,6 +983,12 @@ func whatMatches(logger klog.Logger, wsd *workspaceDetails, spec *edgeapi.EdgePl
 	objNS := whatObj.GetNamespace()
 	objName := whatObj.GetName()
 	objLabels := whatObj.GetLabels()
+	if spec.NetworkGuardrails != nil && guardrails.IsGuardrailFor(gvk.Group, whatResource, objLabels, string(epName)) {
+		return true, true
+	}
+	if spec.Trust != nil && trust.IsTrustFor(gvk.Group, whatResource, objLabels, string(epName)) {
+		return true, true
+	}
 	match, ok := downsyncMatches(logger, wsd, spec.Downsync, whatResource, gvk, objNS, objName, objLabels)
 	if !(match && ok) {
 		return match, ok
@@ -864,6 +1152,11 @@ var GRsNotForEdge = NewMapSet(
 	mkgr("", "nodes"),
 )
 
+// downsyncable tells whether objects of the given resource can be downsynced.
+func downsyncable(gr schema.GroupResource) bool {
+	return !GRsNotSupported.Has(gr) && !GroupsNotForEdge.Has(gr.Group) && !GRsNotForEdge.Has(gr)
+}
+
 func DefaultResourceModes(mgr metav1.GroupResource) ResourceMode {
 	sgr := MetaGroupResourceToSchema(mgr)
 	builtin := strings.HasSuffix(sgr.Group, ".k8s.io") || !strings.Contains(sgr.Group, ".")
@@ -25,10 +25,14 @@ import (
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	machruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	upstreamdiscovery "k8s.io/client-go/discovery"
 	fakeupkube "k8s.io/client-go/kubernetes/fake"
+	upstreamcache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
 
 	clusterdynamicinformer "github.com/kcp-dev/client-go/dynamic/dynamicinformer"
 	fakekube "github.com/kcp-dev/client-go/kubernetes/fake"
@@ -97,6 +101,15 @@ func TestWhatResolver(t *testing.T) {
 				edgeapi.DownsyncOverwriteKey: "false"},
 			Labels: map[string]string{"foo": "baz"},
 		}}
+	cm4 := &k8scorev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "cm4",
+			Annotations: map[string]string{logicalcluster.AnnotationKey: wds1N,
+				edgeapi.ExcludeAnnotationKey: "true"},
+			Labels: map[string]string{"foo": "bar"},
+		}}
 	ep1 := &edgeapi.EdgePlacement{
 		TypeMeta: metav1.TypeMeta{Kind: "EdgePlacement", APIVersion: edgeapi.SchemeGroupVersion.String()},
 		ObjectMeta: metav1.ObjectMeta{
@@ -108,7 +121,7 @@ func TestWhatResolver(t *testing.T) {
 				{APIGroup: &emptyString,
 					Resources:          []string{"configmaps"},
 					NamespaceSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"kubernetes.io/metadata.name": "default"}}},
-					ObjectNames:        []string{"cm1", "cmx", "cm3"},
+					ObjectNames:        []string{"cm1", "cmx", "cm3", "cm4"},
 					LabelSelectors:     []metav1.LabelSelector{{MatchLabels: map[string]string{"foo": "baz"}}, {MatchLabels: map[string]string{"foo": "bar"}}},
 				},
 				{APIGroup: &emptyString,
@@ -139,7 +152,7 @@ func TestWhatResolver(t *testing.T) {
 	//fakeKCPClient := fakeclusterkcp.NewSimpleClientset()
 	//bindingFactory := bindingfactory.NewSharedInformerFactory(fakeKCPClient, 0)
 	//bindingClusterPreInformer := bindingFactory.Apis().V1alpha1().APIBindings()
-	fakeDynamicClusterClientset := fakeclusterdynamic.NewSimpleDynamicClient(scheme, ns1, cm1, ns2, cm2, cm3)
+	fakeDynamicClusterClientset := fakeclusterdynamic.NewSimpleDynamicClient(scheme, ns1, cm1, ns2, cm2, cm3, cm4)
 	dynamicClusterInformerFactory := clusterdynamicinformer.NewDynamicSharedInformerFactory(fakeDynamicClusterClientset, 0)
 	// crdClusterPreInformer := dynamicClusterInformerFactory.ForResource(apiextensionsv1.SchemeGroupVersion.WithResource("customresourcedefinitions"))
 	// bindingClusterPreInformer := dynamicClusterInformerFactory.ForResource(kcpapisv1alpha1.SchemeGroupVersion.WithResource("apibindings"))
@@ -154,7 +167,7 @@ func TestWhatResolver(t *testing.T) {
 	// TODO fake
 	spaceclient, _ := msclient.NewMultiSpace(ctx, nil, true)
 
-	whatResolver := NewWhatResolver(ctx, epPreInformer, spaceclient, spaceProviderNs, kbSpaceRelation, 3)
+	whatResolver := NewWhatResolver(ctx, epPreInformer, spaceclient, spaceProviderNs, kbSpaceRelation, nil, nil, 3)
 	edgeInformerFactory.Start(ctx.Done())
 	dynamicClusterInformerFactory.Start(ctx.Done())
 	rcvr := NewMapMap[ExternalName, ResolvedWhat](nil)
@@ -193,3 +206,58 @@ type FakeClusterDisco struct {
 func (fcd FakeClusterDisco) Cluster(lc logicalcluster.Path) upstreamdiscovery.DiscoveryInterface {
 	return &fakekubediscovery.FakeDiscovery{Fake: fcd.kubeClusterClientset.Fake, ClusterPath: lc}
 }
+
+func TestAddDependencies(t *testing.T) {
+	newIndexer := func(objs ...*unstructured.Unstructured) upstreamcache.Indexer {
+		indexer := upstreamcache.NewIndexer(upstreamcache.MetaNamespaceKeyFunc, upstreamcache.Indexers{})
+		for _, obj := range objs {
+			if err := indexer.Add(obj); err != nil {
+				t.Fatalf("Failed to add %v: %v", obj, err)
+			}
+		}
+		return indexer
+	}
+	newObj := func(apiVersion, kind, name string, annotations map[string]string, content map[string]any) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: content}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace("shop")
+		obj.SetName(name)
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+	deployment := newObj("apps/v1", "Deployment", "web", nil, map[string]any{
+		"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+			"serviceAccountName": "default",
+			"volumes": []any{
+				map[string]any{"name": "a", "configMap": map[string]any{"name": "web-config"}},
+				map[string]any{"name": "b", "configMap": map[string]any{"name": "missing"}},
+				map[string]any{"name": "c", "configMap": map[string]any{"name": "private"}},
+				map[string]any{"name": "d", "secret": map[string]any{"secretName": "web-certs"}},
+			},
+		}}},
+	})
+	webConfig := newObj("v1", "ConfigMap", "web-config", map[string]string{edgeapi.DownsyncOverwriteKey: "false"}, map[string]any{})
+	private := newObj("v1", "ConfigMap", "private", map[string]string{edgeapi.ExcludeAnnotationKey: "true"}, map[string]any{})
+	defaultSA := newObj("v1", "ServiceAccount", "default", nil, map[string]any{})
+	resolver := func(group, version, resource string, objs ...*unstructured.Unstructured) *resourceResolver {
+		gvr := schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+		return &resourceResolver{gvr: gvr, lister: upstreamcache.NewGenericLister(newIndexer(objs...), gvr.GroupResource())}
+	}
+	wsDetails := &workspaceDetails{resources: map[string]*resourceResolver{
+		"deployments.apps": resolver("apps", "v1", "deployments", deployment),
+		"configmaps":       resolver("", "v1", "configmaps", webConfig, private),
+		"serviceaccounts":  resolver("", "v1", "serviceaccounts", defaultSA),
+	}}
+	deploymentID := WorkloadPartID{metav1.GroupResource{Group: "apps", Resource: "deployments"}, "shop", "web"}
+	parts := WorkloadParts{deploymentID: WorkloadPartDetails{APIVersion: "v1"}}
+	wr := &whatResolver{logger: klog.Background()}
+	wr.addDependenciesLocked(wsDetails, parts)
+	expected := WorkloadParts{
+		deploymentID: WorkloadPartDetails{APIVersion: "v1"},
+		WorkloadPartID{metav1.GroupResource{Resource: "configmaps"}, "shop", "web-config"}: WorkloadPartDetails{APIVersion: "v1", CreateOnly: true},
+	}
+	if !apiequality.Semantic.DeepEqual(expected, parts) {
+		t.Errorf("Expected %v but got %v", expected, parts)
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"sync"
 	"time"
 
-	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	upstreamcache "k8s.io/client-go/tools/cache"
@@ -29,20 +28,23 @@ import (
 	"k8s.io/klog/v2"
 
 	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
-	edgev2alpha1informers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions/edge/v2alpha1"
-	edgev2alpha1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
-	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	"github.com/kubestellar/kubestellar/pkg/scopedinformer"
 )
 
 type whereResolver struct {
 	ctx        context.Context
 	logger     klog.Logger
 	numThreads int
-	queue      workqueue.RateLimitingInterface
+	queue      *coalesce.Queue
 
-	spsInformer     upstreamcache.SharedIndexInformer
-	spsLister       edgev2alpha1listers.SinglePlacementSliceLister
-	kbSpaceRelation kbuser.KubeBindSpaceRelation
+	// spsInformer holds the SinglePlacementSlices of the workload
+	// description spaces, read from those spaces themselves
+	spsInformer *scopedinformer.MultiSpaceInformer
+
+	// shard restricts the destinations passed on
+	shard Shard
 
 	// resolutions maps EdgePlacement name to its ResolvedWhere
 	resolutions RelayMap[ExternalName, ResolvedWhere]
@@ -59,29 +61,35 @@ func (qi queueItem) toExternalName() ExternalName {
 }
 
 // NewWhereResolver returns a WhereResolver.
+// The given informer holds the SinglePlacementSlices of the workload
+// description spaces; spsSynced tells whether it has caught up.
 func NewWhereResolver(
 	ctx context.Context,
-	spsPreInformer edgev2alpha1informers.SinglePlacementSliceInformer,
-	kbSpaceRelation kbuser.KubeBindSpaceRelation,
+	spsInformer *scopedinformer.MultiSpaceInformer,
+	spsSynced upstreamcache.InformerSynced,
+	shard Shard,
 	numThreads int,
 ) WhereResolver {
 	return func(receiver MappingReceiver[ExternalName, ResolvedWhere]) Runnable {
 		controllerName := "where-resolver"
 		logger := klog.FromContext(ctx).WithValues("part", controllerName)
 		ctx = klog.NewContext(ctx, logger)
+		// An item's processing is all that looks at its SinglePlacementSlice,
+		// so an item whose slice came and went unseen can be dropped.
+		queue := coalesce.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName,
+			coalesce.Options{CanDrop: func(any) bool { return true }})
 		wr := &whereResolver{
-			ctx:             ctx,
-			logger:          logger,
-			numThreads:      numThreads,
-			queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
-			spsInformer:     spsPreInformer.Informer(),
-			spsLister:       spsPreInformer.Lister(),
-			kbSpaceRelation: kbSpaceRelation,
-			resolutions:     NewRelayMap[ExternalName, ResolvedWhere](false),
+			ctx:         ctx,
+			logger:      logger,
+			numThreads:  numThreads,
+			queue:       queue,
+			spsInformer: spsInformer,
+			shard:       shard,
+			resolutions: NewRelayMap[ExternalName, ResolvedWhere](false),
 		}
 		wr.resolutions.AddReceiver(receiver, false)
 		wr.spsInformer.AddEventHandler(WhereResolverClusterHandler{wr, mkgk(edgeapi.SchemeGroupVersion.Group, "SinglePlacementSlice")})
-		if !upstreamcache.WaitForNamedCacheSync(controllerName, ctx.Done(), wr.spsInformer.HasSynced) {
+		if !upstreamcache.WaitForNamedCacheSync(controllerName, ctx.Done(), spsSynced) {
 			logger.Info("Failed to sync SinglePlacementSlices in time")
 		}
 		return wr
@@ -105,32 +113,41 @@ type WhereResolverClusterHandler struct {
 	gk schema.GroupKind
 }
 
-func (wrh WhereResolverClusterHandler) OnAdd(obj any) {
-	wrh.enqueue(wrh.gk, obj)
+var _ scopedinformer.SpaceEventHandler = WhereResolverClusterHandler{}
+
+func (wrh WhereResolverClusterHandler) OnAdd(space string, obj any) {
+	wrh.enqueue(wrh.gk, space, obj, coalesce.Added)
 }
 
-func (wrh WhereResolverClusterHandler) OnUpdate(oldObj, newObj any) {
-	wrh.enqueue(wrh.gk, newObj)
+func (wrh WhereResolverClusterHandler) OnUpdate(space string, oldObj, newObj any) {
+	wrh.enqueue(wrh.gk, space, newObj, coalesce.Updated)
 }
 
-func (wrh WhereResolverClusterHandler) OnDelete(obj any) {
-	wrh.enqueue(wrh.gk, obj)
+func (wrh WhereResolverClusterHandler) OnDelete(space string, obj any) {
+	wrh.enqueue(wrh.gk, space, obj, coalesce.Deleted)
 }
 
-func (wr *whereResolver) enqueue(gk schema.GroupKind, objAny any) {
-	key, err := upstreamcache.DeletionHandlingMetaNamespaceKeyFunc(objAny)
-	if err != nil {
-		wr.logger.Error(err, "Failed to extract object reference", "object", objAny)
-		return
-	}
-	_, name, err := upstreamcache.SplitMetaNamespaceKey(key)
-	if err != nil {
-		wr.logger.Error(err, "Impossible! SplitMetaClusterNamespaceKey failed", "key", key)
-	}
-	// enqueue with empty cluster. set it later
-	item := queueItem{GK: gk, Cluster: "", Name: name}
-	wr.logger.V(4).Info("Enqueuing", "item", item)
-	wr.queue.Add(item)
+func (wr *whereResolver) enqueue(gk schema.GroupKind, space string, objAny any, event coalesce.Event) {
+	// The scoped informer does not recover panics in its handlers,
+	// as recovery.Handler does for a plain informer.
+	recovery.Guard(wr.logger, recoveryNameWhere, space, func() {
+		key, err := upstreamcache.DeletionHandlingMetaNamespaceKeyFunc(objAny)
+		if err != nil {
+			wr.logger.Error(err, "Failed to extract object reference", "object", objAny)
+			return
+		}
+		_, name, err := upstreamcache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			wr.logger.Error(err, "Impossible! SplitMetaClusterNamespaceKey failed", "key", key)
+		}
+		item := queueItem{GK: gk, Cluster: space, Name: name}
+		wr.logger.V(4).Info("Enqueuing", "item", item)
+		wr.queue.AddEvent(item, event)
+	})
+}
+
+func (wr *whereResolver) queueDepth() int {
+	return wr.queue.Len()
 }
 
 func (wr *whereResolver) AddReceiver(receiver MappingReceiver[ExternalName, ResolvedWhere], notifyCurrent bool) {
@@ -159,7 +176,9 @@ func (wr *whereResolver) processNextWorkItem() bool {
 	ctx := klog.NewContext(wr.ctx, logger)
 	logger.V(4).Info("processing queueItem")
 
-	if wr.process(ctx, item) {
+	var done bool
+	recovery.Guard(logger, recoveryNameWhere, item, func() { done = wr.process(ctx, item) })
+	if done {
 		wr.queue.Forget(itemAny)
 	} else {
 		wr.queue.AddRateLimited(itemAny)
@@ -169,30 +188,11 @@ func (wr *whereResolver) processNextWorkItem() bool {
 
 // process returns true on success or unrecoverable error, false to retry
 func (wr *whereResolver) process(ctx context.Context, item queueItem) bool {
-	logger := klog.FromContext(ctx)
-	epName := item.Name
-	sps, err := wr.spsLister.Get(epName)
-	if err != nil && !k8sapierrors.IsNotFound(err) {
-		logger.Error(err, "Failed to fetch SinglePlacementSlice from local cache", "epName", epName)
-		return true // I think these errors are not transient
-	}
-	//change to consumer SpaceID
-	_, spsOriginalName, kbSpaceID, err := kbuser.AnalyzeObjectID(sps)
-	if err != nil {
-		logger.Error(err, "Object does not appear to be a provider's copy of a consumer's object")
-		return true
-	}
-	spaceID := wr.kbSpaceRelation.SpaceIDFromKubeBind(kbSpaceID)
-	if spaceID == "" {
-		logger.Error(nil, "Failed to get consumer space ID from a provider's copy")
-		return false
-	}
-	item.Name = spsOriginalName
-	item.Cluster = spaceID
+	// The SinglePlacementSlice has the name of its EdgePlacement
 	objName := item.toExternalName()
-
-	if err == nil {
-		wr.resolutions.Put(objName, []*edgeapi.SinglePlacementSlice{sps})
+	if obj, found := wr.spsInformer.Get(item.Cluster, "", item.Name); found {
+		sps := obj.(*edgeapi.SinglePlacementSlice)
+		wr.resolutions.Put(objName, []*edgeapi.SinglePlacementSlice{wr.shard.FilterSlice(sps)})
 	} else {
 		wr.resolutions.Delete(objName)
 	}
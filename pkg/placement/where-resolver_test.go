@@ -23,13 +23,14 @@ import (
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
-	fakeupkube "k8s.io/client-go/kubernetes/fake"
+	upstreamcache "k8s.io/client-go/tools/cache"
 
 	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
 	fakeedge "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/fake"
 	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
-	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/scopedinformer"
 )
 
 func TestWhereResolver(t *testing.T) {
@@ -51,14 +52,14 @@ func TestWhereResolver(t *testing.T) {
 		Destinations: []edgeapi.SinglePlacement{sp1},
 	}
 	ep1EN := ExternalName{wds1N, ObjectName(sps1.Name)}
-	edgeViewClusterClientset := fakeedge.NewSimpleClientset(sps1)
-	edgeInformerFactory := edgeinformers.NewSharedScopedInformerFactoryWithOptions(edgeViewClusterClientset, 0)
-	spsClusterPreInformer := edgeInformerFactory.Edge().V2alpha1().SinglePlacementSlices()
-	spsClusterPreInformer.Informer() // get the informer created so that it will start
-	fakeUpKubeClient := fakeupkube.NewSimpleClientset()
-	kbSpaceRelation := kbuser.NewKubeBindSpaceRelation(ctx, fakeUpKubeClient)
-	whereResolver := NewWhereResolver(ctx, spsClusterPreInformer, kbSpaceRelation, 3)
-	edgeInformerFactory.Start(ctx.Done())
+	wds1Clientset := fakeedge.NewSimpleClientset(sps1)
+	spsInformer := scopedinformer.NewMultiSpaceInformer(ctx, func(space string) (upstreamcache.SharedIndexInformer, error) {
+		return edgeinformers.NewSharedScopedInformerFactoryWithOptions(wds1Clientset, 0).Edge().V2alpha1().SinglePlacementSlices().Informer(), nil
+	}, upstreamcache.Indexers{})
+	if err := spsInformer.SetSpaces(sets.NewString(wds1N)); err != nil {
+		t.Fatal(err)
+	}
+	whereResolver := NewWhereResolver(ctx, spsInformer, spsInformer.HasSynced, Shard{}, 3)
 	rcvr := NewMapMap[ExternalName, ResolvedWhere](nil)
 	runnable := whereResolver(rcvr)
 	go runnable.Run(ctx)
@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	k8scorev1 "k8s.io/api/core/v1"
@@ -32,21 +33,38 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	machruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/wait"
 	k8sdynamic "k8s.io/client-go/dynamic"
 	k8sdynamicinformer "k8s.io/client-go/dynamic/dynamicinformer"
 	upstreaminformers "k8s.io/client-go/informers"
 	k8scorev1informers "k8s.io/client-go/informers/core/v1"
-	k8sclient "k8s.io/client-go/kubernetes"
 	k8scorev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	k8scache "k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
 	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
-	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/breakglass"
+	"github.com/kubestellar/kubestellar/pkg/bundle"
+	edgev1a1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+	"github.com/kubestellar/kubestellar/pkg/crossref"
 	"github.com/kubestellar/kubestellar/pkg/customize"
+	"github.com/kubestellar/kubestellar/pkg/destination"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	"github.com/kubestellar/kubestellar/pkg/events"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	"github.com/kubestellar/kubestellar/pkg/normalize"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+	"github.com/kubestellar/kubestellar/pkg/podsecurity"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	"github.com/kubestellar/kubestellar/pkg/scopedinformer"
+	"github.com/kubestellar/kubestellar/pkg/softdelete"
+	spaceclientfactory "github.com/kubestellar/kubestellar/pkg/spaceclient"
 	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
 	spacev1a1listers "github.com/kubestellar/kubestellar/space-framework/pkg/client/listers/space/v1alpha1"
 	msclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
@@ -81,22 +99,40 @@ func NewWorkloadProjector(
 	resourceModes ResourceModes,
 	spaceInformer k8scache.SharedIndexInformer,
 	spaceLister spacev1a1listers.SpaceLister,
-	syncfgInformer k8scache.SharedIndexInformer,
+	// informer on the SyncerConfigs in the mailbox spaces of this shard
+	syncfgInformer *scopedinformer.MultiSpaceInformer,
+	// lister of the provider-side copies of the Locations
+	locationLister edgev1a1listers.LocationLister,
 	spaceclient msclient.KubestellarSpaceInterface,
+	spaceClients *spaceclientfactory.Factory,
 	spaceProviderNs string,
 	kbsr kbuser.KubeBindSpaceRelation,
+	convergence *convergenceTracker,
+	bundleThreshold int,
+	checkpointer *checkpointer,
+	ownershipGCPeriod time.Duration,
+	shard Shard,
 ) *workloadProjector {
 	wp := &workloadProjector{
 		// delay:                 2 * time.Second,
 		ctx:               ctx,
 		configConcurrency: configConcurrency,
 		resourceModes:     resourceModes,
-		queue:             workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		queue:             coalesce.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "workload-projector", coalesce.Options{}),
 		spaceLister:       spaceLister,
 		syncfgInformer:    syncfgInformer,
+		locationLister:    locationLister,
 		spaceclient:       spaceclient,
+		spaceClients:      spaceClients,
 		spaceProviderNs:   spaceProviderNs,
+		refResolver:       crossref.NewResolver(spaceClients, spaceProviderNs, crossref.Options{}),
 		kbsr:              kbsr,
+		convergence:       convergence,
+		bundleThreshold:   bundleThreshold,
+		checkpointer:      checkpointer,
+		ownershipGCPeriod: ownershipGCPeriod,
+		shard:             shard,
+		retrying:          map[any]struct{}{},
 
 		mbwsNameToSP: WrapMapWithMutex[string, SinglePlacement](NewMapMap[string, SinglePlacement](nil)),
 
@@ -106,6 +142,9 @@ func NewWorkloadProjector(
 		upsyncs: NewHashRelation2[SinglePlacement, edgeapi.UpsyncSet](
 			HashSinglePlacement{}, HashUpsyncSet{}),
 	}
+	wp.customizers = wp.newCustomizerTracker()
+	wp.breakGlass = newBreakGlassTracker()
+	wp.immutableFields = newImmutableFieldTracker()
 	wp.nsdDistributionsForProj = NewGenericFactoredMap[NamespacedDistributionTuple,
 		string, Triple[metav1.GroupResource, NamespacedName, SinglePlacement], DistributionBits,
 		wpPerSourceNSDistributions, wpPerSourceNSDistributions](
@@ -170,13 +209,17 @@ func NewWorkloadProjector(
 	wp.nsModesForProj = NewFactoredMapMap[ProjectionModeKey, metav1.GroupResource, SinglePlacement, ProjectionModeVal](factorProjectionModeKeyForProj, nil, nil, nil)
 	wp.nnsModesForProj = NewFactoredMapMap[ProjectionModeKey, metav1.GroupResource, SinglePlacement, ProjectionModeVal](factorProjectionModeKeyForProj, nil, nil, nil)
 	logger := klog.FromContext(ctx)
-	spaceInformer.AddEventHandler(k8scache.ResourceEventHandlerFuncs{
+	spaceInformer.AddEventHandler(recovery.Handler(logger, recoveryNameProjector, k8scache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj any) {
 			space := obj.(*spacev1alpha1.Space)
 			if !looksLikeMBWSName(space.Name) {
 				logger.V(4).Info("Ignoring non-mailbox space", "spaceName", space.Name)
 				return
 			}
+			if !wp.shard.OwnsMailbox(space.Name) {
+				logger.V(4).Info("Ignoring mailbox space of another shard", "spaceName", space.Name)
+				return
+			}
 			if space.Status.Phase != spacev1alpha1.SpacePhaseReady {
 				logger.V(4).Info("Ignoring mailbox space that is not ready", "spaceName", space.Name, "phase", space.Status.Phase)
 				return
@@ -192,6 +235,10 @@ func NewWorkloadProjector(
 				logger.V(4).Info("Ignoring non-mailbox workspace", "spaceName", space.Name)
 				return
 			}
+			if !wp.shard.OwnsMailbox(space.Name) {
+				logger.V(4).Info("Ignoring mailbox space of another shard", "spaceName", space.Name)
+				return
+			}
 			if space.Status.Phase != spacev1alpha1.SpacePhaseReady {
 				logger.V(4).Info("Ignoring mailbox space that is not ready", "spaceName", space.Name, "phase", space.Status.Phase)
 				return
@@ -201,31 +248,24 @@ func NewWorkloadProjector(
 			logger.V(4).Info("Enqueuing reference to SyncerConfig of modified space", "spaceName", space.Name)
 			wp.queue.Add(scRef)
 		},
-	})
-	enqueueSCRef := func(obj any, event string) {
-		dfu, ok := obj.(k8scache.DeletedFinalStateUnknown)
-		if ok {
-			obj = dfu.Obj
-		}
-		syncfg := obj.(*edgeapi.SyncerConfig)
-		_, sourceName, kbIDForMBS, err := kbuser.AnalyzeObjectID(syncfg)
-		if err != nil {
-			logger.Error(err, "SyncerConfig name does not look like kube-bind projection", "name", syncfg.Name)
-			return
-		}
-		if sourceName != SyncerConfigName {
-			logger.V(4).Info("Ignoring SyncerConfig with non-standard name", "sourceName", sourceName, "kbIDForMBS", kbIDForMBS, "standardName", SyncerConfigName)
-			return
-		}
-		// enqueue with empty cluster. set later
-		scRef := syncerConfigProjectionRef{kbIDForMBS, ObjectName(sourceName)}
-		logger.V(4).Info("Enqueuing reference to SyncerConfig from informer", "scRef", scRef, "event", event)
-		wp.queue.Add(scRef)
+	}))
+	// The informer covers only the mailbox spaces of this shard
+	enqueueSCRef := func(space string, obj any, event string) {
+		recovery.Guard(logger, recoveryNameProjector, space, func() {
+			syncfg := obj.(*edgeapi.SyncerConfig)
+			if syncfg.Name != SyncerConfigName {
+				logger.V(4).Info("Ignoring SyncerConfig with non-standard name", "spaceName", space, "name", syncfg.Name, "standardName", SyncerConfigName)
+				return
+			}
+			scRef := syncerConfigRef{space, ObjectName(syncfg.Name)}
+			logger.V(4).Info("Enqueuing reference to SyncerConfig from informer", "scRef", scRef, "event", event)
+			wp.queue.AddEvent(scRef, eventOfAction(event))
+		})
 	}
-	syncfgInformer.AddEventHandler(k8scache.ResourceEventHandlerFuncs{
-		AddFunc:    func(obj any) { enqueueSCRef(obj, "add") },
-		UpdateFunc: func(oldObj, newObj any) { enqueueSCRef(newObj, "update") },
-		DeleteFunc: func(obj any) { enqueueSCRef(obj, "delete") },
+	syncfgInformer.AddEventHandler(scopedinformer.SpaceEventHandlerFuncs{
+		AddFunc:    func(space string, obj any) { enqueueSCRef(space, obj, "add") },
+		UpdateFunc: func(space string, oldObj, newObj any) { enqueueSCRef(space, newObj, "update") },
+		DeleteFunc: func(space string, obj any) { enqueueSCRef(space, obj, "delete") },
 	})
 	return wp
 }
@@ -253,12 +293,69 @@ type workloadProjector struct {
 	configConcurrency int
 	resourceModes     ResourceModes
 	delay             time.Duration // to slow down for debugging
-	queue             workqueue.RateLimitingInterface
+	queue             *coalesce.Queue
 	spaceLister       spacev1a1listers.SpaceLister
-	syncfgInformer    k8scache.SharedIndexInformer
+	syncfgInformer    *scopedinformer.MultiSpaceInformer
+	locationLister    edgev1a1listers.LocationLister
 	spaceclient       msclient.KubestellarSpaceInterface
+	spaceClients      *spaceclientfactory.Factory
 	spaceProviderNs   string
+	refResolver       *crossref.Resolver
+	customizers       *customizerTracker
+	breakGlass        *breakGlassTracker
+	immutableFields   *immutableFieldTracker
 	kbsr              kbuser.KubeBindSpaceRelation
+	convergence       *convergenceTracker // may be nil
+
+	// bundleThreshold is the number of objects going to a destination
+	// above which they are bundled; zero means never bundle
+	bundleThreshold int
+
+	// checkpointer remembers what was projected, for a fast restart; may be nil
+	checkpointer *checkpointer
+
+	// deletes records the deletions in mailbox spaces that are in progress,
+	// so that they are finished after a restart; may be nil
+	deletes *deleteJournal
+
+	// ownershipGCPeriod is how often to sweep the mailbox spaces for copies
+	// whose source object is gone; zero means never
+	ownershipGCPeriod time.Duration
+
+	// softDeleteGrace is how long a copy that is no longer wanted stays
+	// in its mailbox space, scheduled for deletion; zero means not at all
+	softDeleteGrace time.Duration
+
+	// revertManualEdits says whether to undo the changes that others make
+	// to the copies in mailbox spaces
+	revertManualEdits bool
+
+	// shard restricts the mailbox spaces handled by this projector.
+	// When sharded, the write-backs to source objects that depend on the
+	// total number of destinations (executing count and singleton
+	// reported state) are not done, because no one shard knows that number;
+	// a source object that wants them gets a SingletonStateUnsupported
+	// Event and an error in the log instead.
+	shard Shard
+
+	// inFlight is the number of queue items being processed
+	inFlight atomic.Int32
+
+	// watchdog, if not nil, tracks the processing of each queue item
+	watchdog *probes.Watchdog
+
+	// writeAdmitter, if not nil, limits the writes into mailbox spaces
+	writeAdmitter *writeAdmitter
+
+	// events, if not nil, records Events about workload objects
+	events *events.Recorder
+
+	// usage, if not nil, is told of the writes into mailbox spaces
+	usage *usageReporter
+
+	// retryingMutex guards retrying, the set of queue items waiting to be retried
+	retryingMutex sync.Mutex
+	retrying      map[any]struct{}
 
 	mbwsNameToSP MutableMap[string /*mailbox workspace name*/, SinglePlacement]
 
@@ -305,6 +402,7 @@ func (wp *workloadProjector) newPerDestinationLocked(destination SinglePlacement
 		nsdDistributions: NewSingleIndexedMapMap2[GroupResourceNamespacedName, string, DistributionBits](),
 		nnsDistributions: NewSingleIndexedMapMap2[GroupResourceObjectName, string, DistributionBits](),
 		preInformers:     NewMapMap[metav1.GroupResource, dynamicDuo](nil),
+		bundled:          map[bundleKey]bundledObject{},
 	}
 	return wpd
 }
@@ -326,6 +424,15 @@ type wpPerDestination struct {
 	dynamicClient          k8sdynamic.Interface
 	dynamicInformerFactory k8sdynamicinformer.DynamicSharedInformerFactory
 	preInformers           MutableMap[metav1.GroupResource, dynamicDuo]
+
+	// configMapClient accesses the bundles namespace of the mailbox space
+	configMapClient k8scorev1client.ConfigMapInterface
+
+	// bundleMutex guards bundled and bundlesLoaded.
+	// If both are to be locked, lock the wp mutex first.
+	bundleMutex   sync.Mutex
+	bundled       map[bundleKey]bundledObject
+	bundlesLoaded bool
 }
 
 type dynamicDuo struct {
@@ -360,15 +467,11 @@ func (wpd *wpPerDestination) getDynamicDuoLocked(gr metav1.GroupResource, apiVer
 	if wpd.dynamicClient == nil {
 		wpd.logger.V(4).Info("Creating dynamicClient")
 		mbwsName := SPMailboxWorkspaceName(wpd.destination)
-		// create dynamic client with space-aware client
-		config, err := wpd.wp.spaceclient.ConfigForSpace(mbwsName, wpd.wp.spaceProviderNs)
-		if err != nil {
-			return dynamicDuo{}, nil, err
-		}
-		wpd.dynamicClient, err = k8sdynamic.NewForConfig(config)
+		clients, err := wpd.wp.spaceClients.For(mbwsName, wpd.wp.spaceProviderNs)
 		if err != nil {
 			return dynamicDuo{}, nil, err
 		}
+		wpd.dynamicClient = clients.Dynamic
 
 		justMine, err := labels.NewRequirement(ProjectedLabelKey, selection.Equals, []string{ProjectedLabelVal})
 		if err != nil {
@@ -383,11 +486,9 @@ func (wpd *wpPerDestination) getDynamicDuoLocked(gr metav1.GroupResource, apiVer
 					opts.LabelSelector = opts.LabelSelector + "," + justMineStr
 				}
 			})
-		mbsClient, err := k8sclient.NewForConfig(config)
-		if err != nil {
-			return dynamicDuo{}, nil, err
-		}
+		mbsClient := clients.Kube
 		wpd.namespaceClient = mbsClient.CoreV1().Namespaces()
+		wpd.configMapClient = mbsClient.CoreV1().ConfigMaps(bundle.Namespace)
 		k8sInformerFactory := upstreaminformers.NewSharedInformerFactory(mbsClient, 0)
 		wpd.namespacePreInformer = k8sInformerFactory.Core().V1().Namespaces()
 		k8sInformerFactory.Start(wpd.wp.ctx.Done())
@@ -422,10 +523,10 @@ func (wpd *wpPerDestination) getDynamicDuoLocked(gr metav1.GroupResource, apiVer
 			// so no point in reacting to them.
 		} else {
 			duo.preInformer = wpd.dynamicInformerFactory.ForResource(sgvr)
-			duo.preInformer.Informer().AddEventHandler(k8scache.ResourceEventHandlerFuncs{
+			duo.preInformer.Informer().AddEventHandler(recovery.Handler(klog.FromContext(wpd.wp.ctx), recoveryNameProjector, k8scache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj any) { wpd.enqueueDestinationObject(gr, namespaced, obj, "add") },
 				UpdateFunc: func(oldObj, newObj any) { wpd.enqueueDestinationObject(gr, namespaced, newObj, "update") },
-				DeleteFunc: func(obj any) { wpd.enqueueDestinationObject(gr, namespaced, obj, "delete") }})
+				DeleteFunc: func(obj any) { wpd.enqueueDestinationObject(gr, namespaced, obj, "delete") }}))
 			go duo.preInformer.Informer().Run(wpd.wp.ctx.Done())
 
 		}
@@ -464,15 +565,12 @@ type sourcesWantReturns = Map[string, DistributionBits]
 // Constructs the data structure specific to a workload management workspace
 func (wp *workloadProjector) newPerSourceLocked(source string) *wpPerSource {
 	logger := klog.FromContext(wp.ctx).WithValues("source", source)
-	config, err := wp.spaceclient.ConfigForSpace(source, wp.spaceProviderNs)
-	if err != nil {
-		logger.Error(err, "Failed to get config for space", "space", source)
-		// TODO more
-	}
-	dynamicClient, err := k8sdynamic.NewForConfig(config)
-	if err != nil {
-		logger.Error(err, "failed to create clientset from config", "space", source)
+	var dynamicClient k8sdynamic.Interface
+	if clients, err := wp.spaceClients.For(source, wp.spaceProviderNs); err != nil {
+		logger.Error(err, "Failed to get clients for space", "space", source)
 		// TODO more
+	} else {
+		dynamicClient = clients.Dynamic
 	}
 	dynamicInformerFactory := k8sdynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
 	wps := &wpPerSource{wp: wp, source: source,
@@ -539,13 +637,6 @@ type ObjectNameToObjectDestinations = GenericFactoredMap[Pair[ObjectName, Single
 // syncerConfigRef is a workqueue item that refers to a SyncerConfig in a mailbox workspace
 type syncerConfigRef ExternalName
 
-// syncerConfigProjectionRef is a workqueue item that refers to a SyncerConfig in the KCS.
-// The components are the result of kbuser.AnalyzeObjectID(object in KCS).
-type syncerConfigProjectionRef struct {
-	KBIDForMBS string
-	SourceName ObjectName
-}
-
 // sourceObjectRef refers to an namespaced object in a workload management workspace
 type sourceObjectRef struct {
 	Cluster       string
@@ -573,9 +664,99 @@ func (wp *workloadProjector) Run(ctx context.Context) {
 	for worker := 0; worker < wp.configConcurrency; worker++ {
 		go wp.configSyncLoop(ctx, worker)
 	}
+	if wp.convergence != nil {
+		go wait.UntilWithContext(ctx, func(ctx context.Context) {
+			if wp.isQuiescent() {
+				wp.convergence.Quiescent(ctx, time.Now())
+			}
+		}, time.Second)
+	}
+	if wp.checkpointer != nil {
+		go wp.checkpointer.Run(ctx)
+	}
+	for _, pd := range wp.deletes.Pending() {
+		wp.queue.Add(pd)
+	}
+	if wp.ownershipGCPeriod > 0 {
+		collector := ownership.NewCollector(klog.FromContext(ctx), ownership.SpaceOwnerLookup(wp.spaceclient, wp.spaceProviderNs), false)
+		go wait.UntilWithContext(ctx, func(ctx context.Context) { wp.sweepMailboxes(ctx, collector) }, wp.ownershipGCPeriod)
+	}
 	<-doneCh
 }
 
+// queueDepth returns the number of items waiting in the queue.
+func (wp *workloadProjector) queueDepth() int {
+	return wp.queue.Len()
+}
+
+// destinationCount returns the number of destinations that this projector knows of.
+func (wp *workloadProjector) destinationCount() int {
+	wp.Lock()
+	defer wp.Unlock()
+	return wp.perDestination.Len()
+}
+
+// isQuiescent tells whether there is no work queued, in progress, or waiting to be retried.
+func (wp *workloadProjector) isQuiescent() bool {
+	wp.retryingMutex.Lock()
+	defer wp.retryingMutex.Unlock()
+	return wp.queue.Len() == 0 && wp.inFlight.Load() == 0 && len(wp.retrying) == 0
+}
+
+func (wp *workloadProjector) setWatchdog(watchdog *probes.Watchdog) {
+	wp.watchdog = watchdog
+}
+
+func (wp *workloadProjector) setWriteAdmission(limits WriteAdmissionLimits) {
+	if limits.Enabled() {
+		wp.writeAdmitter = newWriteAdmitter(limits)
+	} else {
+		wp.writeAdmitter = nil
+	}
+}
+
+func (wp *workloadProjector) setEventRecorder(rcdr *events.Recorder) {
+	wp.events = rcdr
+}
+
+func (wp *workloadProjector) setUsageReporter(usage *usageReporter) {
+	wp.usage = usage
+}
+
+// usageBySource counts, for each source space that has objects going
+// somewhere, the objects and their destinations.
+func (wp *workloadProjector) usageBySource() map[string]projectedCounts {
+	wp.Lock()
+	defer wp.Unlock()
+	ans := map[string]projectedCounts{}
+	wp.perSource.Visit(func(tup Pair[string, *wpPerSource]) error {
+		wps := tup.Second
+		destinations := map[SinglePlacement]struct{}{}
+		nsObjects := map[Pair[metav1.GroupResource, NamespacedName]]struct{}{}
+		nnsObjects := map[Pair[metav1.GroupResource, ObjectName]]struct{}{}
+		wps.nsdDistributions.Visit(func(dist Pair[Triple[metav1.GroupResource, NamespacedName, SinglePlacement], DistributionBits]) error {
+			destinations[dist.First.Third] = struct{}{}
+			nsObjects[NewPair(dist.First.First, dist.First.Second)] = struct{}{}
+			return nil
+		})
+		wps.nnsDistributions.Visit(func(dist Pair[Triple[metav1.GroupResource, ObjectName, SinglePlacement], DistributionBits]) error {
+			destinations[dist.First.Third] = struct{}{}
+			nnsObjects[NewPair(dist.First.First, dist.First.Second)] = struct{}{}
+			return nil
+		})
+		copies := wps.nsdDistributions.Len() + wps.nnsDistributions.Len()
+		if copies > 0 {
+			ans[wps.source] = projectedCounts{
+				destinations: len(destinations),
+				objects:      len(nsObjects) + len(nnsObjects),
+				objectCopies: copies,
+			}
+		}
+		return nil
+	})
+	return ans
+}
+
 func (wp *workloadProjector) configSyncLoop(ctx context.Context, worker int) {
 	doneCh := ctx.Done()
 	logger := klog.FromContext(ctx)
@@ -599,28 +780,45 @@ func (wp *workloadProjector) configSyncLoop(ctx context.Context, worker int) {
 }
 
 func (wp *workloadProjector) sync1Config(ctx context.Context, ref any) {
+	wp.inFlight.Add(1)
+	defer wp.inFlight.Add(-1)
 	defer wp.queue.Done(ref)
+	defer wp.watchdog.Track()()
 	logger := klog.FromContext(ctx)
 	logger.V(4).Info("Dequeued reference", "ref", ref, "type", fmt.Sprintf("%T", ref))
 	var retry bool
+	if recovery.Guard(logger, recoveryNameProjector, ref, func() { retry = wp.sync1ConfigRef(ctx, ref) }) {
+		retry = true
+	}
+	wp.retryingMutex.Lock()
+	if retry {
+		wp.retrying[ref] = struct{}{}
+		wp.queue.AddRateLimited(ref)
+	} else {
+		delete(wp.retrying, ref)
+		wp.queue.Forget(ref)
+	}
+	wp.retryingMutex.Unlock()
+}
+
+// Returns `retry bool`.
+func (wp *workloadProjector) sync1ConfigRef(ctx context.Context, ref any) bool {
 	switch typed := ref.(type) {
 	case SinglePlacement:
-		retry = wp.syncConfigDestination(ctx, typed)
+		return wp.syncConfigDestination(ctx, typed)
 	case syncerConfigRef:
-		retry = wp.syncConfigObject(ctx, typed)
-	case syncerConfigProjectionRef:
-		retry = wp.syncConfigObjectProjection(ctx, typed)
+		return wp.syncConfigObject(ctx, typed)
 	case sourceObjectRef:
-		retry = wp.syncSourceObject(ctx, typed)
+		return wp.syncSourceObject(ctx, typed)
 	case destinationObjectRef:
-		retry = wp.syncDestinationObject(ctx, typed)
+		return wp.syncDestinationObject(ctx, typed)
+	case destinationBundleRef:
+		return wp.syncBundles(ctx, typed)
+	case pendingDelete:
+		return wp.finishDelete(ctx, typed)
 	default:
-		logger.Error(nil, "Dequeued unexpected type of reference", "type", fmt.Sprintf("%T", ref), "val", ref)
-	}
-	if retry {
-		wp.queue.AddRateLimited(ref)
-	} else {
-		wp.queue.Forget(ref)
+		klog.FromContext(ctx).Error(nil, "Dequeued unexpected type of reference", "type", fmt.Sprintf("%T", ref), "val", ref)
+		return false
 	}
 }
 
@@ -634,20 +832,6 @@ func (wp *workloadProjector) syncConfigDestination(ctx context.Context, destinat
 	return false
 }
 
-// Returns `retry bool`.
-func (wp *workloadProjector) syncConfigObjectProjection(ctx context.Context, scRef syncerConfigProjectionRef) bool {
-	mbsName := wp.kbsr.SpaceIDFromKubeBind(scRef.KBIDForMBS)
-	if mbsName == "" {
-		logger := klog.FromContext(ctx)
-		logger.Info("Can not yet map kube-bind cluster namespace to mailbox space name", "scRef", scRef)
-		return true
-	}
-	// Run it through the queue rather than call syncConfigObject directly, to preserve
-	// the property that only one goroutine is processing it at a time.
-	wp.queue.Add(syncerConfigRef{Cluster: mbsName, Name: scRef.SourceName})
-	return false
-}
-
 // Returns `retry bool`.
 func (wp *workloadProjector) syncConfigObject(ctx context.Context, scRef syncerConfigRef) bool {
 	logger := klog.FromContext(ctx)
@@ -664,17 +848,12 @@ func (wp *workloadProjector) syncConfigObject(ctx context.Context, scRef syncerC
 	}
 	logger = logger.WithValues("destination", sp)
 
-	config, err := wp.spaceclient.ConfigForSpace(mbwsName, wp.spaceProviderNs)
+	clients, err := wp.spaceClients.For(mbwsName, wp.spaceProviderNs)
 	if err != nil {
-		logger.Error(err, "Failed to fetch space config", "spacename", mbwsName)
+		logger.Error(err, "Failed to get clients for space", "spacename", mbwsName)
 		return true
 	}
-	edgeClientset, err := edgeclientset.NewForConfig(config)
-	if err != nil {
-		logger.Error(err, "Failed to create edge clientset for space", "spacename", mbwsName)
-		return false
-	}
-	client := edgeClientset.EdgeV2alpha1().SyncerConfigs()
+	client := clients.Edge.EdgeV2alpha1().SyncerConfigs()
 	syncfg, err := client.Get(wp.ctx, string(scRef.Name), metav1.GetOptions{})
 	if err != nil {
 		if k8sapierrors.IsNotFound(err) {
@@ -767,9 +946,16 @@ func (wp *workloadProjector) syncDestinationObject(ctx context.Context, doRef de
 		}
 		if haveSources && !sourcesWants.IsEmpty() {
 			if !present {
+				if wp.revertManualEdits {
+					wp.revertLocked(logger, doRef, sourcesWants)
+					return returnFalse
+				}
 				logger.V(4).Info("Ignoring destination object that is being deleted", "namespaced", namespaced)
 				return returnFalse
 			}
+			if wp.revertManualEdits && editedByOthers(objM) {
+				wp.revertLocked(logger, doRef, sourcesWants)
+			}
 			logger.V(4).Info("Retaining destination object", "namespaced", namespaced, "sources", VisitableToSlice[Pair[string, DistributionBits]](sourcesWants))
 			if doRef.GroupResource == crdGR {
 				return returnFalse
@@ -778,7 +964,7 @@ func (wp *workloadProjector) syncDestinationObject(ctx context.Context, doRef de
 			addRetry := false
 			// accumulate reported state return tasks
 			sourcesWants.Visit(func(sourceWant Pair[string, DistributionBits]) error {
-				if !sourceWant.Second.ReturnSingletonState {
+				if !sourceWant.Second.ReturnSingletonState || wp.shard.Sharded() {
 					return nil
 				}
 				wps, haveWPS := wp.perSource.Get(sourceWant.First)
@@ -841,7 +1027,23 @@ func (wp *workloadProjector) syncDestinationObject(ctx context.Context, doRef de
 		}
 		resourceVersion := objM.GetResourceVersion()
 		rscClient := duo.clientForMaybeNamespace(namespaced, doRef.Namespace)
+		apiVersion := duo.apiVersion
+		objU := objM.(*unstructured.Unstructured)
 		return func() bool {
+			ckey := checkpointKeyFor(doRef.Destination, doRef.GroupResource, doRef.Namespace, string(doRef.Name))
+			wp.checkpointer.Forget(ckey)
+			wkey := mailboxWriteKey{Destination: doRef.Destination, GroupResource: doRef.GroupResource, Namespace: doRef.Namespace, Name: string(doRef.Name)}
+			release, proceed, retry := wp.admitWrite(ctx, logger, wkey, nil)
+			if !proceed {
+				return retry
+			}
+			defer release()
+			if retry, handled := wp.softDelete(ctx, logger, doRef, rscClient, objU); handled {
+				return retry
+			}
+			if !wp.beginDelete(logger, pendingDelete{checkpointKey: ckey, Version: apiVersion, ResourceVersion: resourceVersion}) {
+				return true
+			}
 			err := rscClient.Delete(ctx, string(doRef.Name),
 				metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: &resourceVersion}})
 			if err == nil {
@@ -850,8 +1052,13 @@ func (wp *workloadProjector) syncDestinationObject(ctx context.Context, doRef de
 				logger.V(4).Info("Undesired object in mailbox workspace was deleted concurrently", "resourceVersion", resourceVersion)
 			} else {
 				logger.Error(err, "Failed to delete unwanted object in mailbox workspace", "resourceVersion", resourceVersion)
+				if k8sapierrors.IsConflict(err) {
+					// The retry decides afresh whether the changed copy is wanted.
+					wp.deletes.End(ckey)
+				}
 				return true
 			}
+			wp.deletes.End(ckey)
 			return false
 		}
 	}()
@@ -963,6 +1170,7 @@ func (wps *wpPerSource) syncSourceToDestLocked(ctx context.Context, logger klog.
 		logger.Error(nil, "Impossible: object going to unknown destination")
 		return true, nil
 	}
+	wp.noteCustomizerUse(logger, soRef, destination, srcMRObject, deleted)
 	pmv, have := modesForSync.Get(ProjectionModeKey{soRef.GroupResource, destination})
 	if !have {
 		logger.Error(nil, "Missing version")
@@ -973,11 +1181,27 @@ func (wps *wpPerSource) syncSourceToDestLocked(ctx context.Context, logger klog.
 		logger.Error(err, "Failed to wpd.getDynamicDuoLocked")
 		return true, nil
 	}
+	if !distributionBits.ReturnSingletonState && !distributionBits.CreateOnly && distributionBits.LocalProbes == "" && wpd.shouldBundleLocked() {
+		return false, wpd.bundleTrier(ctx, logger, soRef, srcMRObject, deleted, clientReadyChan)
+	}
+	wpd.unbundleLocked(bundleKey{GroupResource: soRef.GroupResource, Namespace: soRef.Namespace, Name: soRef.Name})
 	return false, func() bool {
 		// sgvr := MetaGroupResourceToSchema(soRef.groupResource).WithVersion(pmv.APIVersion)
 		rscClient := destDuo.clientForMaybeNamespace(namespaced, soRef.Namespace)
+		ckey := checkpointKeyFor(destination, soRef.GroupResource, soRef.Namespace, soRef.Name)
+		wkey := mailboxWriteKey{Destination: destination, GroupResource: soRef.GroupResource, Namespace: soRef.Namespace, Name: soRef.Name}
 		if deleted { // propagate deletion
+			wp.checkpointer.Forget(ckey)
+			wp.breakGlass.forget(wkey)
 			time.Sleep(wp.delay)
+			release, proceed, retry := wp.admitWrite(ctx, logger, wkey, nil)
+			if !proceed {
+				return retry
+			}
+			defer release()
+			if !wp.beginDelete(logger, pendingDelete{checkpointKey: ckey, Version: pmv.APIVersion, Source: soRef.Cluster}) {
+				return true
+			}
 			err := rscClient.Delete(ctx, soRef.Name, metav1.DeleteOptions{})
 			if err == nil {
 				logger.V(3).Info("Deleted object in mailbox workspace")
@@ -987,46 +1211,35 @@ func (wps *wpPerSource) syncSourceToDestLocked(ctx context.Context, logger klog.
 			} else {
 				logger.V(3).Info("Deletion already propagated")
 			}
+			wp.deletes.End(ckey)
 			return false
 		}
-		if distributionBits.ReturnSingletonState {
+		if distributionBits.ReturnSingletonState && wp.shard.Sharded() {
+			logger.Error(nil, "Singleton reported state is wanted but not supported by a sharded placement translator", "shard", wp.shard.Index, "shardCount", wp.shard.Count)
+			wp.events.SingletonStateUnsupported(soRef.Cluster, srcMRObject, destinationName(destination), singletonStateShardedWhy)
+		} else if distributionBits.ReturnSingletonState {
 			if !wp.ensureDestCount(ctx, logger, srcClient, srcMRObject, numDestinations) {
 				return false
 			}
 		}
-		if namespaced {
-			<-clientReadyChan
-			nsObj, err := wpd.namespacePreInformer.Lister().Get(soRef.Namespace)
-			if err != nil {
-				if !k8sapierrors.IsNotFound(err) {
-					logger.Error(err, "Failed to lookup namespace in local cache")
-					return true
-				}
-				nsObj = nil
-			}
-			if nsObj == nil {
-				nsObj = &k8scorev1.Namespace{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:   soRef.Namespace,
-						Labels: map[string]string{ProjectedLabelKey: ProjectedLabelVal},
-					}}
-				_, err := wpd.namespaceClient.Create(ctx, nsObj, metav1.CreateOptions{FieldManager: FieldManager})
-				if err == nil {
-					logger.V(3).Info("Created namespace in mailbox workspace")
-				} else if k8sapierrors.IsAlreadyExists(err) {
-					logger.V(4).Info("Something else created needed namespace concurrently")
-				} else {
-					logger.Error(err, "Failed to create needed namespace in mailbox workspace")
-					return true
-				}
+		if namespaced && wpd.ensureNamespace(ctx, logger, soRef.Namespace, clientReadyChan) {
+			return true
+		}
+		var desiredHash string
+		// The checkpoint's hash covers only the source object, not the probes
+		if wp.checkpointer != nil && !distributionBits.ReturnSingletonState && !distributionBits.CreateOnly && distributionBits.LocalProbes == "" {
+			desiredHash = wp.desiredHash(logger, soRef.Cluster, destination, srcMRObject)
+			if desiredHash != "" && wp.checkpointer.Restored(ckey, desiredHash, destDuo.cachedResourceVersion(ctx, namespaced, soRef.Namespace, soRef.Name)) {
+				logger.V(4).Info("Object in mailbox workspace is up to date according to checkpoint")
+				return false
 			}
 		}
 		destObj, err := rscClient.Get(ctx, soRef.Name, metav1.GetOptions{})
 		if err != nil && !k8sapierrors.IsNotFound(err) {
-			logger.Error(err, "Failed to fetch object from mailbox workspace")
+			logger.Error(err, "Failed to fetch object from mailbox workspace", "reason", countMailboxFailure("get", err))
 			return true
 		} else if err == nil {
-			if distributionBits.ReturnSingletonState && numDestinations == 1 {
+			if distributionBits.ReturnSingletonState && numDestinations == 1 && !wp.shard.Sharded() {
 				srcU := srcMRObject.(*unstructured.Unstructured)
 				if !wps.reportSingletonState(ctx, logger, srcClient, srcU, destObj) {
 					return false
@@ -1036,15 +1249,39 @@ func (wps *wpPerSource) syncSourceToDestLocked(ctx context.Context, logger klog.
 				logger.V(4).Info("Not considering update of create-only object in mailbox workspace")
 				return false
 			}
+			if until, overridden := wp.breakGlassUntil(logger, soRef, wkey, srcMRObject, destObj); overridden {
+				logger.V(3).Info("Not updating object in mailbox workspace under break-glass override", "until", until)
+				return false
+			}
 			revisedDestObj := wpd.wp.genericObjectMerge(soRef.Cluster, destination, srcMRObject, destObj)
+			breakglass.Strip(revisedDestObj)
+			softdelete.Strip(revisedDestObj)
+			setLocalProbes(revisedDestObj, distributionBits.LocalProbes)
+			wp.setVirtualOwner(logger, revisedDestObj, soRef, pmv.APIVersion, srcMRObject)
 			if apiequality.Semantic.DeepEqual(destObj, revisedDestObj) {
 				logger.V(4).Info("No need to update object in mailbox workspace")
+				wp.checkpointer.Record(ckey, desiredHash, destObj.GetResourceVersion())
+				return false
+			}
+			if !wpd.wp.podSecurityAdmits(logger, soRef.Cluster, srcMRObject, destination, revisedDestObj) {
 				return false
 			}
 			time.Sleep(wp.delay)
+			release, proceed, retry := wp.admitWrite(ctx, logger, wkey, revisedDestObj)
+			if !proceed {
+				return retry
+			}
+			defer release()
 			asUpdated, err := rscClient.Update(ctx, revisedDestObj, metav1.UpdateOptions{FieldManager: FieldManager})
 			if err != nil {
-				logger.V(2).Info("Failed to update object in mailbox workspace", "resourceVersion", revisedDestObj.GetResourceVersion(), "err", err)
+				logger.V(2).Info("Failed to update object in mailbox workspace", "resourceVersion", revisedDestObj.GetResourceVersion(), "reason", countMailboxFailure("update", err), "err", err)
+				if isImmutableFieldError(err) {
+					policy := wp.immutableFields.policyFor(soRef.GroupResource, distributionBits)
+					return wp.handleImmutableFieldError(ctx, logger, rscClient, soRef, srcMRObject, wkey, ckey, destObj, policy, err)
+				}
+				if !k8sapierrors.IsConflict(err) {
+					wp.events.DestinationFailed(soRef.Cluster, srcMRObject, destinationName(destination), err)
+				}
 				return true
 			}
 			if logger.V(5).Enabled() {
@@ -1053,20 +1290,123 @@ func (wps *wpPerSource) syncSourceToDestLocked(ctx context.Context, logger klog.
 			logger.V(3).Info("Updated object in mailbox workspace",
 				"oldResourceVersion", revisedDestObj.GetResourceVersion(),
 				"newResourceVersion", asUpdated.GetResourceVersion())
+			wp.usage.recordWrite(soRef.Cluster, revisedDestObj)
+			wp.checkpointer.Record(ckey, desiredHash, asUpdated.GetResourceVersion())
 			return false
 		}
 		destObj = wpd.wp.xformForDestination(soRef.Cluster, destination, srcMRObject)
+		setLocalProbes(destObj, distributionBits.LocalProbes)
+		wp.setVirtualOwner(logger, destObj, soRef, pmv.APIVersion, srcMRObject)
+		if !wpd.wp.podSecurityAdmits(logger, soRef.Cluster, srcMRObject, destination, destObj) {
+			return false
+		}
 		time.Sleep(time.Second)
+		release, proceed, retry := wp.admitWrite(ctx, logger, wkey, destObj)
+		if !proceed {
+			return retry
+		}
+		defer release()
 		asCreated, err := rscClient.Create(ctx, destObj, metav1.CreateOptions{FieldManager: FieldManager})
 		if err != nil {
-			logger.Error(err, "Failed to create object in mailbox workspace")
+			logger.Error(err, "Failed to create object in mailbox workspace", "reason", countMailboxFailure("create", err))
+			// AlreadyExists only means the informer has not caught up yet;
+			// the retry will update instead.
+			if !k8sapierrors.IsAlreadyExists(err) {
+				wp.events.DestinationFailed(soRef.Cluster, srcMRObject, destinationName(destination), err)
+			}
 			return true
 		}
 		logger.V(3).Info("Created object in mailbox workspace", "resourceVersion", asCreated.GetResourceVersion())
+		wp.usage.recordWrite(soRef.Cluster, destObj)
+		wp.checkpointer.Record(ckey, desiredHash, asCreated.GetResourceVersion())
 		return false
 	}
 }
 
+// destinationName identifies the destination in Events.
+func destinationName(sp SinglePlacement) string {
+	return destination.Of(sp).Key()
+}
+
+// setVirtualOwner marks the given copy in a mailbox space as a dependent of its source object.
+func (wp *workloadProjector) setVirtualOwner(logger klog.Logger, destObj *unstructured.Unstructured, soRef sourceObjectRef, apiVersion string, srcObj mrObject) {
+	owner := ownership.NewOwnerRef(soRef.Cluster, MetaGroupResourceToSchema(soRef.GroupResource).WithVersion(apiVersion), srcObj)
+	if err := ownership.SetOwner(destObj, owner); err != nil {
+		logger.Error(err, "Failed to set virtual owner of object in mailbox workspace")
+	}
+}
+
+// sweepMailboxes deletes, from every mailbox space, the copies whose source object is gone.
+func (wp *workloadProjector) sweepMailboxes(ctx context.Context, collector *ownership.Collector) {
+	type sweep struct {
+		destination SinglePlacement
+		client      k8sdynamic.Interface
+		gvrs        []schema.GroupVersionResource
+	}
+	sweeps := []sweep{}
+	func() {
+		wp.Lock()
+		defer wp.Unlock()
+		wp.perDestination.Visit(func(tup Pair[SinglePlacement, *wpPerDestination]) error {
+			wpd := tup.Second
+			if wpd.dynamicClient == nil {
+				return nil
+			}
+			gvrs := []schema.GroupVersionResource{}
+			wpd.preInformers.Visit(func(duoTup Pair[metav1.GroupResource, dynamicDuo]) error {
+				if !mgrIsNamespace(duoTup.First) {
+					gvrs = append(gvrs, MetaGroupResourceToSchema(duoTup.First).WithVersion(duoTup.Second.apiVersion))
+				}
+				return nil
+			})
+			sweeps = append(sweeps, sweep{tup.First, wpd.dynamicClient, gvrs})
+			return nil
+		})
+	}()
+	logger := klog.FromContext(ctx)
+	for _, sweep := range sweeps {
+		result, err := collector.Sweep(ctx, sweep.client, sweep.gvrs)
+		if err != nil {
+			logger.V(2).Info("Trouble sweeping mailbox space for orphaned copies", "destination", sweep.destination, "err", err)
+		}
+		logger.V(4).Info("Swept mailbox space for orphaned copies", "destination", sweep.destination, "result", result)
+	}
+}
+
+// ensureNamespace creates the given namespace in the mailbox space if it is not already there.
+// Returns `retry bool`.
+func (wpd *wpPerDestination) ensureNamespace(ctx context.Context, logger klog.Logger, namespace string, clientReadyChan <-chan struct{}) bool {
+	<-clientReadyChan
+	nsObj, err := wpd.namespacePreInformer.Lister().Get(namespace)
+	if err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to lookup namespace in local cache")
+			return true
+		}
+		nsObj = nil
+	}
+	if nsObj == nil {
+		nsObj = &k8scorev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   namespace,
+				Labels: map[string]string{ProjectedLabelKey: ProjectedLabelVal},
+			}}
+		_, err := wpd.namespaceClient.Create(ctx, nsObj, metav1.CreateOptions{FieldManager: FieldManager})
+		if err == nil {
+			logger.V(3).Info("Created namespace in mailbox workspace")
+		} else if k8sapierrors.IsAlreadyExists(err) {
+			logger.V(4).Info("Something else created needed namespace concurrently")
+		} else {
+			logger.Error(err, "Failed to create needed namespace in mailbox workspace")
+			return true
+		}
+	}
+	return false
+}
+
+// singletonStateShardedWhy is why a sharded projector does not return singleton reported state.
+const singletonStateShardedWhy = "the placement translator is sharded (--shard-count > 1), so no one shard knows whether there is a single destination"
+
 func (wp *workloadProjector) ensureDestCount(ctx context.Context, logger klog.Logger,
 	srcClient k8sdynamic.ResourceInterface, srcMRObject mrObject, numDestinations int,
 ) bool /* OK */ {
@@ -1145,19 +1485,22 @@ func (wp *workloadProjector) xformForDestination(sourceCluster string, destSP Si
 	destObj := destObjR.(*unstructured.Unstructured)
 	// customize.Customize(wp.ctx, srcObjU.UnstructuredContent(), customizer, log)
 	destObj.SetUnstructuredContent(srcObjU.UnstructuredContent())
-	destObj.SetManagedFields([]metav1.ManagedFieldsEntry{})
-	destObj.SetOwnerReferences([]metav1.OwnerReference{}) // we do not transport owner UIDs
-	destObj.SetResourceVersion("")
-	destObj.SetSelfLink("")
-	destObj.SetUID("")
-	destObj.SetZZZ_DeprecatedClusterName("")
+	StripForDestination(destObj)
+	return destObj
+}
+
+// StripForDestination removes from the given object the content that
+// is specific to the source apiserver, according to normalize.Default,
+// and marks the object as projected.
+// The object is modified in place.
+func StripForDestination(destObj *unstructured.Unstructured) {
+	normalize.Default.Normalize(destObj)
 	labels := destObj.GetLabels()
 	if labels == nil {
 		labels = map[string]string{}
 	}
 	labels[ProjectedLabelKey] = ProjectedLabelVal
 	destObj.SetLabels(labels)
-	return destObj
 }
 
 func (wp *workloadProjector) genericObjectMerge(sourceCluster string, destSP SinglePlacement,
@@ -1173,6 +1516,7 @@ func (wp *workloadProjector) genericObjectMerge(sourceCluster string, destSP Sin
 	outputDestR := inputDest.NewEmptyInstance()
 	outputDestU := outputDestR.(*unstructured.Unstructured)
 	inputDest = inputDest.DeepCopy() // because the following only swings the top-level pointer
+	gk := srcObjU.GroupVersionKind().GroupKind()
 	outputDestU.SetUnstructuredContent(inputDest.UnstructuredContent())
 	kvMerge := func(which string, src, inputDest map[string]string) map[string]string {
 		outputDest := map[string]string{}
@@ -1180,7 +1524,7 @@ func (wp *workloadProjector) genericObjectMerge(sourceCluster string, destSP Sin
 			outputDest[key] = val
 		}
 		for key, val := range src {
-			if kvIsSystem(which, key) {
+			if normalize.Default.IsHubOnlyKey(gk, key) {
 				continue
 			}
 			if oval, have := outputDest[key]; have && oval != val {
@@ -1216,41 +1560,22 @@ func (wp *workloadProjector) customizeOrCopy(logger klog.Logger, srcCluster stri
 	customizerRef := srcAnnotations[edgeapi.CustomizerAnnotationKey]
 	var customizer *edgeapi.Customizer
 	if len(customizerRef) != 0 {
-		refParts := strings.SplitN(customizerRef, "/", 2)
-		custNS := refParts[0]
-		custName := refParts[len(refParts)-1]
-		if len(refParts) == 1 {
-			custNS = srcObjU.GetNamespace()
-		}
-		//TODO get customizer from Lister
-		config, err := wp.spaceclient.ConfigForSpace(srcCluster, wp.spaceProviderNs)
-		if err != nil {
-			logger.Error(err, "Failed to fetch space config", "spacename", srcCluster)
-		}
-		edgeClientset, err := edgeclientset.NewForConfig(config)
+		var err error
+		customizer, err = wp.refResolver.Customizer(wp.ctx, srcCluster, srcObjU.GetNamespace(), customizerRef)
 		if err != nil {
-			logger.Error(err, "Failed to create edge clientset for space", "spacename", srcCluster)
-		}
-		customizer, err = edgeClientset.EdgeV2alpha1().Customizers(custNS).Get(wp.ctx, custName, metav1.GetOptions{})
-		if err != nil {
-			logger.Error(err, "Failed to find referenced Customizer")
+			logger.Error(err, "Failed to find referenced Customizer", "reason", kserrors.ReasonTransformFailed)
+			wp.events.TransformError(srcCluster, srcObjU, destinationName(destSP), fmt.Errorf("failed to get Customizer %s: %w", customizerRef, err))
 		} else {
 			expandParameters = expandParameters || customizer.Annotations[edgeapi.ParameterExpansionAnnotationKey] == "true"
 		}
 	}
 	var location *edgeapi.Location
 	if expandParameters {
-		config, err := wp.spaceclient.ConfigForSpace(destSP.Cluster, wp.spaceProviderNs)
+		var err error
+		location, err = wp.getLocation(logger, destSP)
 		if err != nil {
-			logger.Error(err, "Failed to fetch space config", "spacename", destSP.Cluster)
-		}
-		edgeClientset, err := edgeclientset.NewForConfig(config)
-		if err != nil {
-			logger.Error(err, "Failed to create edge clientset for space", "spacename", destSP.Cluster)
-		}
-		location, err = edgeClientset.EdgeV2alpha1().Locations().Get(wp.ctx, destSP.LocationName, metav1.GetOptions{})
-		if err != nil {
-			logger.Error(err, "Failed to find referenced Location")
+			logger.Error(err, "Failed to find referenced Location", "reason", kserrors.ReasonTransformFailed)
+			wp.events.TransformError(srcCluster, srcObjU, destinationName(destSP), fmt.Errorf("failed to get Location for parameter expansion: %w", err))
 		}
 	}
 	if (len(customizerRef) != 0 || expandParameters) &&
@@ -1264,8 +1589,47 @@ func (wp *workloadProjector) customizeOrCopy(logger klog.Logger, srcCluster stri
 	return srcObjU.DeepCopy()
 }
 
-func kvIsSystem(which, key string) bool {
-	return (strings.Contains(key, ".kcp.io/") || strings.HasPrefix(key, "kcp.io/"))
+// getLocation returns the Location of the given destination, from the
+// local cache of the provider-side copies of the Locations.
+func (wp *workloadProjector) getLocation(logger klog.Logger, destSP edgeapi.SinglePlacement) (*edgeapi.Location, error) {
+	kbSpaceID := wp.kbsr.SpaceIDToKubeBind(destSP.Cluster)
+	if kbSpaceID == "" {
+		return nil, fmt.Errorf("no kube-bind identity for space %q", destSP.Cluster)
+	}
+	return wp.locationLister.Get(kbuser.ComposeClusterScopedName(kbSpaceID, destSP.LocationName))
+}
+
+// podSecurityAdmits checks the given object against the Pod Security Standards level,
+// if any, declared by the destination's Location.
+// Returns false, after logging the violations and recording an Event
+// on the source object, if the object should be held back.
+func (wp *workloadProjector) podSecurityAdmits(logger klog.Logger, srcCluster string, srcObj mrObject, destSP edgeapi.SinglePlacement, destObj *unstructured.Unstructured) bool {
+	gvk := destObj.GroupVersionKind()
+	if podsecurity.PodSpecPath(gvk.Group, gvk.Kind) == nil {
+		return true
+	}
+	location, err := wp.getLocation(logger, destSP)
+	if err != nil {
+		logger.Error(err, "Failed to fetch Location to check pod security level; not holding object back")
+		return true
+	}
+	levelName, has := location.Labels[edgeapi.PodSecurityEnforceLabelKey]
+	if !has {
+		return true
+	}
+	level, err := podsecurity.ParseLevel(levelName)
+	if err != nil {
+		logger.Error(err, "Location has malformed pod security label; not holding object back", "location", location.Name)
+		return true
+	}
+	if violations := podsecurity.Check(destObj, level); len(violations) != 0 {
+		logger.Error(nil, "Holding back object that violates destination's pod security level",
+			"level", level, "violations", violations, "reason", kserrors.ReasonDestinationAdmissionDenied)
+		wp.events.DestinationHeldBack(srcCluster, srcObj, destinationName(destSP),
+			fmt.Sprintf("violates pod security level %q: %s", level, strings.Join(violations, "; ")))
+		return false
+	}
+	return true
 }
 
 func (wp *workloadProjector) Transact(xn func(WorkloadProjectionSections)) {
@@ -1415,6 +1779,9 @@ func (wp *workloadProjector) Transact(xn func(WorkloadProjectionSections)) {
 	})
 	logger.V(3).Info("End transaction")
 	wp.changedDestinations = nil
+	if wp.convergence != nil {
+		wp.convergence.Transacted(changedSources)
+	}
 }
 
 func (wps *wpPerSource) getDynamicDuoLocked(logger klog.Logger, gr metav1.GroupResource, apiVersion string, namespaced bool) dynamicDuo {
@@ -1427,11 +1794,11 @@ func (wps *wpPerSource) getDynamicDuoLocked(logger klog.Logger, gr metav1.GroupR
 			preInformer: wps.dynamicInformerFactory.ForResource(sgvr),
 			client:      wps.dynamicClient.Resource(sgvr)}
 		wps.preInformers.Put(gr, duo)
-		duo.preInformer.Informer().AddEventHandler(k8scache.ResourceEventHandlerFuncs{
+		duo.preInformer.Informer().AddEventHandler(recovery.Handler(klog.FromContext(wps.wp.ctx), recoveryNameProjector, k8scache.ResourceEventHandlerFuncs{
 			AddFunc:    func(obj any) { wps.enqueueSourceObject(gr, namespaced, obj, "add") },
 			UpdateFunc: func(oldObj, newObj any) { wps.enqueueSourceObject(gr, namespaced, newObj, "update") },
 			DeleteFunc: func(obj any) { wps.enqueueSourceObject(gr, namespaced, obj, "delete") },
-		})
+		}))
 		go duo.preInformer.Informer().Run(wps.wp.ctx.Done())
 		time.Sleep(wps.wp.delay)
 	}
@@ -1465,7 +1832,7 @@ func (wps *wpPerSource) enqueueSourceObject(gr metav1.GroupResource, namespaced
 	}
 	ref := sourceObjectRef{wps.source, gr, namespace, objm.GetName()}
 	wps.logger.V(4).Info("Enqueuing reference to source object", "ref", ref)
-	wps.wp.queue.Add(ref)
+	wps.wp.queue.AddEvent(ref, eventOfAction(action))
 }
 
 // enqueueDestinationObject enqueues a reference to a particular object in a particular MBWS.
@@ -1486,7 +1853,22 @@ func (wpd *wpPerDestination) enqueueDestinationObject(gr metav1.GroupResource, n
 	}
 	ref := destinationObjectRef{wpd.destination, gr, namespace, ObjectName(objm.GetName())}
 	wpd.logger.V(4).Info("Enqueuing reference to destination object", "ref", ref)
-	wpd.wp.queue.Add(ref)
+	wpd.wp.queue.AddEvent(ref, eventOfAction(action))
+}
+
+// eventOfAction maps the action names used in informer notifications
+// to the events that the queue coalesces.
+func eventOfAction(action string) coalesce.Event {
+	switch action {
+	case "add":
+		return coalesce.Added
+	case "update":
+		return coalesce.Updated
+	case "delete":
+		return coalesce.Deleted
+	default:
+		return coalesce.Other
+	}
 }
 
 func ObjectIsSystem(objm metav1.Object) bool {
@@ -1795,6 +2177,5 @@ func csrEqual(a, b Pair[ProjectionModeVal, MutableSet[ObjectName]]) bool {
 }
 
 func looksLikeMBWSName(spaceName string) bool {
-	mbsNameParts := strings.Split(spaceName, MBspaceNameSep)
-	return len(mbsNameParts) == 2
+	return naming.IsMailboxSpaceName(spaceName)
 }
@@ -0,0 +1,244 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+)
+
+// Writes into mailbox spaces are admitted before they are made, so that
+// a slow mailbox apiserver does not lead to an unbounded number of
+// objects held in memory by writes waiting on it. A write that can not
+// be admitted soon enough is shed: the queue item that wanted it is
+// retried later, with backoff, and at that time re-reads the source.
+// Because the queue holds references rather than objects, the writes
+// that pile up during an incident are merged by the queue. A write
+// that is still waiting when a later write for the same mailbox
+// object asks for admission is superseded and dropped.
+
+var (
+	mailboxWritesInFlight = metrics.NewGauge(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "mailbox_writes_in_flight",
+		Help:           "Number of admitted writes into mailbox spaces that are in progress",
+		StabilityLevel: metrics.ALPHA,
+	})
+	mailboxWriteBytesInFlight = metrics.NewGauge(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "mailbox_write_bytes_in_flight",
+		Help:           "Estimated size of the objects being written into mailbox spaces",
+		StabilityLevel: metrics.ALPHA,
+	})
+	mailboxWritesNotAdmitted = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "mailbox_writes_not_admitted_total",
+		Help:           "Number of writes into mailbox spaces that were not admitted, by outcome (shed or superseded)",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"outcome"})
+	mailboxWriteFailures = metrics.NewCounterVec(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_placement",
+		Name:           "mailbox_write_failures_total",
+		Help:           "Number of failed reads and writes of objects in mailbox spaces, by operation (get, create, update or delete) and reason",
+		StabilityLevel: metrics.ALPHA,
+	}, []string{"operation", "reason"})
+)
+
+func init() {
+	legacyregistry.MustRegister(mailboxWritesInFlight, mailboxWriteBytesInFlight, mailboxWritesNotAdmitted, mailboxWriteFailures)
+}
+
+// countMailboxFailure counts the given failure of the given operation on
+// an object in a mailbox space, and returns its kserrors Reason.
+func countMailboxFailure(operation string, err error) kserrors.Reason {
+	reason := kserrors.ReasonOf(err)
+	mailboxWriteFailures.WithLabelValues(operation, string(reason)).Inc()
+	return reason
+}
+
+// WriteAdmissionLimits bound the writes into mailbox spaces.
+type WriteAdmissionLimits struct {
+	// PerSpaceInFlight is the maximum number of writes in progress
+	// into one mailbox space; zero means no limit.
+	PerSpaceInFlight int
+
+	// MemoryBudget is the maximum total estimated size, in bytes, of the
+	// objects being written; zero means no limit. A single object larger
+	// than the budget is admitted when nothing else is in flight.
+	MemoryBudget int64
+
+	// MaxWait is how long a write may wait for admission before it is shed.
+	MaxWait time.Duration
+}
+
+// Enabled tells whether these limits limit anything.
+func (limits WriteAdmissionLimits) Enabled() bool {
+	return limits.PerSpaceInFlight > 0 || limits.MemoryBudget > 0
+}
+
+// mailboxWriteKey identifies an object in a mailbox space.
+type mailboxWriteKey struct {
+	Destination   SinglePlacement
+	GroupResource metav1.GroupResource
+	Namespace     string // == noNamespace iff not namespaced
+	Name          string
+}
+
+type admission int
+
+const (
+	admitted admission = iota
+	shed
+	superseded
+)
+
+// writeAdmitter admits writes into mailbox spaces.
+// A nil *writeAdmitter admits everything immediately.
+type writeAdmitter struct {
+	limits WriteAdmissionLimits
+
+	mutex         sync.Mutex
+	inFlight      map[SinglePlacement]int
+	bytesInFlight int64
+	// latest maps each waiting key to the ticket of the latest write waiting for it
+	latest map[mailboxWriteKey]uint64
+	ticket uint64
+	// changed is closed, and replaced, when a waiter should look again
+	changed chan struct{}
+}
+
+func newWriteAdmitter(limits WriteAdmissionLimits) *writeAdmitter {
+	return &writeAdmitter{
+		limits:   limits,
+		inFlight: map[SinglePlacement]int{},
+		latest:   map[mailboxWriteKey]uint64{},
+		changed:  make(chan struct{}),
+	}
+}
+
+// admit waits until the write of the given size to the given object may
+// proceed, or until it is superseded, or until MaxWait or ctx runs out.
+// When admitted, the caller must call the returned func when the write is done.
+func (wa *writeAdmitter) admit(ctx context.Context, key mailboxWriteKey, size int64) (func(), admission) {
+	if wa == nil {
+		return func() {}, admitted
+	}
+	timer := time.NewTimer(wa.limits.MaxWait)
+	defer timer.Stop()
+	wa.mutex.Lock()
+	wa.ticket++
+	mine := wa.ticket
+	wa.latest[key] = mine
+	wa.broadcastLocked()
+	for {
+		if wa.latest[key] != mine {
+			wa.mutex.Unlock()
+			mailboxWritesNotAdmitted.WithLabelValues("superseded").Inc()
+			return nil, superseded
+		}
+		if wa.fitsLocked(key.Destination, size) {
+			delete(wa.latest, key)
+			wa.inFlight[key.Destination]++
+			wa.bytesInFlight += size
+			mailboxWritesInFlight.Inc()
+			mailboxWriteBytesInFlight.Add(float64(size))
+			wa.mutex.Unlock()
+			return func() { wa.release(key.Destination, size) }, admitted
+		}
+		changed := wa.changed
+		wa.mutex.Unlock()
+		select {
+		case <-changed:
+			wa.mutex.Lock()
+			continue
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		wa.mutex.Lock()
+		if wa.latest[key] == mine {
+			delete(wa.latest, key)
+		}
+		wa.mutex.Unlock()
+		mailboxWritesNotAdmitted.WithLabelValues("shed").Inc()
+		return nil, shed
+	}
+}
+
+func (wa *writeAdmitter) fitsLocked(destination SinglePlacement, size int64) bool {
+	if wa.limits.PerSpaceInFlight > 0 && wa.inFlight[destination] >= wa.limits.PerSpaceInFlight {
+		return false
+	}
+	return wa.limits.MemoryBudget <= 0 || wa.bytesInFlight == 0 || wa.bytesInFlight+size <= wa.limits.MemoryBudget
+}
+
+func (wa *writeAdmitter) release(destination SinglePlacement, size int64) {
+	wa.mutex.Lock()
+	defer wa.mutex.Unlock()
+	if wa.inFlight[destination] <= 1 {
+		delete(wa.inFlight, destination)
+	} else {
+		wa.inFlight[destination]--
+	}
+	wa.bytesInFlight -= size
+	mailboxWritesInFlight.Dec()
+	mailboxWriteBytesInFlight.Sub(float64(size))
+	wa.broadcastLocked()
+}
+
+func (wa *writeAdmitter) broadcastLocked() {
+	close(wa.changed)
+	wa.changed = make(chan struct{})
+}
+
+// admitWrite asks for admission of a write of the given object (nil for
+// a deletion) into a mailbox space. When proceed is true the caller must
+// call release after the write. Otherwise retry says whether the queue
+// item should be retried.
+func (wp *workloadProjector) admitWrite(ctx context.Context, logger klog.Logger, key mailboxWriteKey, obj *unstructured.Unstructured) (release func(), proceed, retry bool) {
+	release, outcome := wp.writeAdmitter.admit(ctx, key, objectSize(obj))
+	switch outcome {
+	case admitted:
+		return release, true, false
+	case superseded:
+		logger.V(3).Info("Write into mailbox space superseded by a later one for the same object")
+		return nil, false, false
+	default:
+		logger.V(2).Info("Write into mailbox space shed because mailbox writes are at their limit; will retry")
+		return nil, false, true
+	}
+}
+
+// objectSize estimates the memory held by the given object while it is written.
+func objectSize(obj *unstructured.Unstructured) int64 {
+	if obj == nil {
+		return 0
+	}
+	content, err := obj.MarshalJSON()
+	if err != nil {
+		return 0
+	}
+	return int64(len(content))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWriteAdmitter(t *testing.T) {
+	ctx := context.Background()
+	dest1 := SinglePlacement{Cluster: "imw1", SyncTargetName: "st1"}
+	dest2 := SinglePlacement{Cluster: "imw1", SyncTargetName: "st2"}
+	key := func(dest SinglePlacement, name string) mailboxWriteKey {
+		return mailboxWriteKey{Destination: dest, Namespace: "ns", Name: name}
+	}
+	wa := newWriteAdmitter(WriteAdmissionLimits{PerSpaceInFlight: 1, MemoryBudget: 100, MaxWait: 50 * time.Millisecond})
+
+	release1, outcome := wa.admit(ctx, key(dest1, "a"), 10)
+	if outcome != admitted {
+		t.Fatalf("First write not admitted: %v", outcome)
+	}
+	if _, outcome := wa.admit(ctx, key(dest1, "b"), 10); outcome != shed {
+		t.Errorf("Expected shed at the per-space limit, got %v", outcome)
+	}
+	if _, outcome := wa.admit(ctx, key(dest2, "c"), 95); outcome != shed {
+		t.Errorf("Expected shed beyond the memory budget, got %v", outcome)
+	}
+	release2, outcome := wa.admit(ctx, key(dest2, "c"), 90)
+	if outcome != admitted {
+		t.Fatalf("Write to another space within budget not admitted: %v", outcome)
+	}
+	release2()
+
+	// An older waiter is superseded by a later write for the same object.
+	wa.limits.MaxWait = time.Minute
+	older := make(chan admission)
+	go func() {
+		_, outcome := wa.admit(ctx, key(dest1, "d"), 10)
+		older <- outcome
+	}()
+	time.Sleep(10 * time.Millisecond)
+	newer := make(chan admission)
+	go func() {
+		release, outcome := wa.admit(ctx, key(dest1, "d"), 10)
+		if release != nil {
+			release()
+		}
+		newer <- outcome
+	}()
+	if outcome := <-older; outcome != superseded {
+		t.Errorf("Expected the older write to be superseded, got %v", outcome)
+	}
+	release1()
+	if outcome := <-newer; outcome != admitted {
+		t.Errorf("Expected the newer write to be admitted after release, got %v", outcome)
+	}
+
+	var nilAdmitter *writeAdmitter
+	if _, outcome := nilAdmitter.admit(ctx, key(dest1, "e"), 1000); outcome != admitted {
+		t.Errorf("Nil admitter did not admit: %v", outcome)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package placementauthz checks that the author of an EdgePlacement can
+// read everything that its what-predicate selects, now and in the future.
+// Without this check, a user who can write EdgePlacements but can not
+// read, say, the Secrets of a namespace could have them downsynced to a
+// WEC that the user controls.
+//
+// The check is done at admission time, by a validating webhook that the
+// apiserver of a workload description space calls when an EdgePlacement
+// or NamespacedEdgePlacement is created or its spec updated. The webhook
+// asks that apiserver, through SubjectAccessReviews, whether the author
+// may do each of the reads that the predicate implies; label selectors
+// do not narrow those reads, since the labels can change.
+package placementauthz
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+// dependencyResources are the resources of the objects that
+// `includeDependencies` can add, in the namespaces of the selected objects.
+var dependencyResources = []string{"configmaps", "secrets", "serviceaccounts", "services"}
+
+// RequiredAccess returns the reads that the author of an EdgePlacement
+// with the given spec has to be allowed to do, sorted and without duplicates.
+// In the returned attributes, "*" stands for every group, resource or
+// name and an empty namespace stands for every namespace (and for no
+// namespace, for cluster-scoped objects), as in a SubjectAccessReview.
+// An object test that lists names needs `get` of those names; others need `list`.
+// The sources of the trust material need `get`.
+func RequiredAccess(spec edgeapi.EdgePlacementSpec) []authorizationv1.ResourceAttributes {
+	seen := map[authorizationv1.ResourceAttributes]struct{}{}
+	add := func(attrs authorizationv1.ResourceAttributes) {
+		seen[attrs] = struct{}{}
+	}
+	for _, test := range spec.Downsync {
+		groups := []string{"*"}
+		if test.APIGroup != nil {
+			groups = []string{*test.APIGroup}
+		}
+		resources := allOrThese(test.Resources)
+		namespaces := allOrThese(test.Namespaces)
+		if namespaces[0] == "*" {
+			namespaces = []string{""}
+		}
+		names := allOrThese(test.ObjectNames)
+		for _, group := range groups {
+			for _, resource := range resources {
+				for _, namespace := range namespaces {
+					for _, name := range names {
+						attrs := authorizationv1.ResourceAttributes{Namespace: namespace, Verb: "list", Group: group, Resource: resource}
+						if name != "*" {
+							attrs.Verb, attrs.Name = "get", name
+						}
+						add(attrs)
+					}
+				}
+			}
+		}
+		if spec.IncludeDependencies {
+			for _, namespace := range namespaces {
+				for _, resource := range dependencyResources {
+					add(authorizationv1.ResourceAttributes{Namespace: namespace, Verb: "get", Resource: resource})
+				}
+			}
+		}
+	}
+	if spec.Trust != nil {
+		getSource := func(resource string, ref *edgeapi.TrustObjectReference) {
+			if ref != nil {
+				add(authorizationv1.ResourceAttributes{Namespace: ref.Namespace, Verb: "get", Resource: resource, Name: ref.Name})
+			}
+		}
+		for _, source := range spec.Trust.CABundles {
+			getSource("configmaps", source.ConfigMap)
+			getSource("secrets", source.Secret)
+		}
+		for idx := range spec.Trust.ImagePullSecrets {
+			getSource("secrets", &spec.Trust.ImagePullSecrets[idx])
+		}
+	}
+	ans := make([]authorizationv1.ResourceAttributes, 0, len(seen))
+	for attrs := range seen {
+		ans = append(ans, attrs)
+	}
+	sort.Slice(ans, func(i, j int) bool { return Describe(ans[i]) < Describe(ans[j]) })
+	return ans
+}
+
+// allOrThese returns `["*"]` if the given list matches everything
+// (is empty or contains "*") and the list itself otherwise.
+func allOrThese(list []string) []string {
+	if len(list) == 0 {
+		return []string{"*"}
+	}
+	for _, item := range list {
+		if item == "*" {
+			return []string{"*"}
+		}
+	}
+	return list
+}
+
+// Describe renders the given attributes for humans, in the style of
+// Kubernetes' "cannot list resource" messages.
+func Describe(attrs authorizationv1.ResourceAttributes) string {
+	var sb strings.Builder
+	sb.WriteString(attrs.Verb)
+	sb.WriteString(" ")
+	sb.WriteString(attrs.Resource)
+	if attrs.Group != "" {
+		sb.WriteString(".")
+		sb.WriteString(attrs.Group)
+	}
+	if attrs.Name != "" {
+		sb.WriteString(" ")
+		sb.WriteString(attrs.Name)
+	}
+	if attrs.Namespace == "" {
+		sb.WriteString(" in all namespaces")
+	} else {
+		sb.WriteString(" in namespace ")
+		sb.WriteString(attrs.Namespace)
+	}
+	return sb.String()
+}
+
+// Review returns those of the given reads that the given user may not do,
+// asking through the given client.
+func Review(ctx context.Context, sars authorizationclient.SubjectAccessReviewInterface, user authenticationv1.UserInfo, required []authorizationv1.ResourceAttributes) ([]authorizationv1.ResourceAttributes, error) {
+	var extra map[string]authorizationv1.ExtraValue
+	if len(user.Extra) > 0 {
+		extra = make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+		for key, value := range user.Extra {
+			extra[key] = authorizationv1.ExtraValue(value)
+		}
+	}
+	var denied []authorizationv1.ResourceAttributes
+	for _, attrs := range required {
+		attrs := attrs
+		sar := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attrs,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		}}
+		result, err := sars.Create(ctx, sar, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review %s: %w", Describe(attrs), err)
+		}
+		if !result.Status.Allowed {
+			denied = append(denied, attrs)
+		}
+	}
+	return denied, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placementauthz
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+// BroadSelections describes the parts of the given spec that select
+// very broadly: each member of `downsync` that matches every object in
+// every namespace, and each empty member of `locationSelectors`, which
+// matches every Location. These are what `allowAll` acknowledges.
+func BroadSelections(spec edgeapi.EdgePlacementSpec) []string {
+	var ans []string
+	for idx, test := range spec.Downsync {
+		if matchesEverything(test) {
+			ans = append(ans, fmt.Sprintf("downsync[%d] matches every object in every namespace", idx))
+		}
+	}
+	for idx, selector := range spec.LocationSelectors {
+		if isEmptySelector(selector) {
+			ans = append(ans, fmt.Sprintf("locationSelectors[%d] is empty and so matches every Location", idx))
+		}
+	}
+	return ans
+}
+
+// matchesEverything tells whether the given test lets every object through.
+func matchesEverything(test edgeapi.DownsyncObjectTest) bool {
+	return test.APIGroup == nil &&
+		allOrEmpty(test.Resources) &&
+		allOrEmpty(test.Namespaces) &&
+		anyEmptySelector(test.NamespaceSelectors) &&
+		allOrEmpty(test.ObjectNames) &&
+		anyEmptySelector(test.LabelSelectors)
+}
+
+func allOrEmpty(list []string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// anyEmptySelector tells whether the given list of selectors matches
+// every set of labels: it is empty, or one of them is.
+func anyEmptySelector(selectors []metav1.LabelSelector) bool {
+	if len(selectors) == 0 {
+		return true
+	}
+	for _, selector := range selectors {
+		if isEmptySelector(selector) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptySelector(selector metav1.LabelSelector) bool {
+	return len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0
+}
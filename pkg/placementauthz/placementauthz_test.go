@@ -0,0 +1,231 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placementauthz
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestRequiredAccess(t *testing.T) {
+	apps := "apps"
+	spec := edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{
+		{APIGroup: &apps, Resources: []string{"deployments"}, Namespaces: []string{"shop"}},
+		{Resources: []string{"configmaps"}, Namespaces: []string{"shop"}, ObjectNames: []string{"cfg"},
+			LabelSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"a": "b"}}}},
+		{Resources: []string{"*"}, NamespaceSelectors: []metav1.LabelSelector{{}}},
+	}}
+	expected := []authorizationv1.ResourceAttributes{
+		{Namespace: "shop", Verb: "get", Resource: "configmaps", Name: "cfg", Group: "*"},
+		{Namespace: "", Verb: "list", Resource: "*", Group: "*"},
+		{Namespace: "shop", Verb: "list", Resource: "deployments", Group: "apps"},
+	}
+	if diff := cmp.Diff(expected, RequiredAccess(spec)); diff != "" {
+		t.Errorf("Wrong required access (-want +got):\n%s", diff)
+	}
+
+	spec = edgeapi.EdgePlacementSpec{IncludeDependencies: true, Downsync: []edgeapi.DownsyncObjectTest{
+		{APIGroup: &apps, Resources: []string{"deployments"}, Namespaces: []string{"shop"}},
+	}}
+	got := RequiredAccess(spec)
+	if len(got) != 1+len(dependencyResources) {
+		t.Errorf("Expected the dependencies to be required too, got %v", got)
+	}
+
+	spec = edgeapi.EdgePlacementSpec{Trust: &edgeapi.TrustDistribution{
+		CABundles:        []edgeapi.TrustSource{{ConfigMap: &edgeapi.TrustObjectReference{Namespace: "certs", Name: "root-ca"}}},
+		ImagePullSecrets: []edgeapi.TrustObjectReference{{Namespace: "certs", Name: "registry"}},
+	}}
+	expected = []authorizationv1.ResourceAttributes{
+		{Namespace: "certs", Verb: "get", Resource: "configmaps", Name: "root-ca"},
+		{Namespace: "certs", Verb: "get", Resource: "secrets", Name: "registry"},
+	}
+	if diff := cmp.Diff(expected, RequiredAccess(spec)); diff != "" {
+		t.Errorf("Wrong required access for trust sources (-want +got):\n%s", diff)
+	}
+}
+
+func newReview(t *testing.T, kind string, op admissionv1.Operation, obj, oldObj runtime.Object) []byte {
+	req := &admissionv1.AdmissionRequest{
+		UID:       "uid1",
+		Kind:      metav1.GroupVersionKind{Group: edgeapi.SchemeGroupVersion.Group, Version: edgeapi.SchemeGroupVersion.Version, Kind: kind},
+		Namespace: "shop",
+		Name:      "web",
+		Operation: op,
+		UserInfo:  authenticationv1.UserInfo{Username: "alice", Groups: []string{"shop-admins"}},
+	}
+	for _, pair := range []struct {
+		obj runtime.Object
+		ext *runtime.RawExtension
+	}{{obj, &req.Object}, {oldObj, &req.OldObject}} {
+		if pair.obj == nil {
+			continue
+		}
+		raw, err := json.Marshal(pair.obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pair.ext.Raw = raw
+	}
+	body, err := json.Marshal(&admissionv1.AdmissionReview{Request: req})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func serve(t *testing.T, wh *Webhook, body []byte) *admissionv1.AdmissionResponse {
+	rec := httptest.NewRecorder()
+	wh.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected HTTP status %d", rec.Code)
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &review); err != nil {
+		t.Fatal(err)
+	}
+	if review.Response == nil || review.Response.UID != "uid1" {
+		t.Fatalf("Bad response %+v", review.Response)
+	}
+	return review.Response
+}
+
+func TestWebhook(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	var reviews int
+	// alice may read anything in her namespace and nothing else
+	client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		sar.Status.Allowed = sar.Spec.User == "alice" && sar.Spec.ResourceAttributes.Namespace == "shop"
+		return true, sar, nil
+	})
+	wh, err := NewWebhook(klog.Background(), client.AuthorizationV1().SubjectAccessReviews(), ModeEnforce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	everywhere := &edgeapi.EdgePlacement{Spec: edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{Resources: []string{"secrets"}}}}}
+	if resp := serve(t, wh, newReview(t, "EdgePlacement", admissionv1.Create, everywhere, nil)); resp.Allowed || resp.Result.Code != http.StatusForbidden {
+		t.Errorf("Expected an EdgePlacement reaching beyond the author's namespace to be rejected, got %+v", resp)
+	}
+
+	// The same spec is restricted to the namespace in a NamespacedEdgePlacement
+	nepl := &edgeapi.NamespacedEdgePlacement{Spec: edgeapi.NamespacedEdgePlacementSpec{Downsync: everywhere.Spec.Downsync}}
+	if resp := serve(t, wh, newReview(t, "NamespacedEdgePlacement", admissionv1.Create, nepl, nil)); !resp.Allowed {
+		t.Errorf("Expected the NamespacedEdgePlacement to be admitted, got %+v", resp.Result)
+	}
+
+	// An update that does not change the spec is not reviewed
+	reviews = 0
+	if resp := serve(t, wh, newReview(t, "EdgePlacement", admissionv1.Update, everywhere, everywhere)); !resp.Allowed || reviews != 0 {
+		t.Errorf("Expected an update without spec change to be admitted without review, got %+v after %d reviews", resp.Result, reviews)
+	}
+
+	wh.mode = ModeWarn
+	if resp := serve(t, wh, newReview(t, "EdgePlacement", admissionv1.Create, everywhere, nil)); !resp.Allowed || len(resp.Warnings) != 1 {
+		t.Errorf("Expected the EdgePlacement to be admitted with a warning, got %+v", resp)
+	}
+}
+
+func TestBroadSelections(t *testing.T) {
+	core := ""
+	for _, tc := range []struct {
+		name     string
+		spec     edgeapi.EdgePlacementSpec
+		expected int
+	}{
+		{"empty test", edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{}}}, 1},
+		{"wildcards", edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{
+			Resources: []string{"*"}, Namespaces: []string{"*"}, ObjectNames: []string{"*"},
+			LabelSelectors: []metav1.LabelSelector{{}},
+		}}}, 1},
+		{"one group", edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{APIGroup: &core}}}, 0},
+		{"one namespace", edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{Namespaces: []string{"shop"}}}}, 0},
+		{"labeled namespaces", edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{
+			NamespaceSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"team": "shop"}}},
+		}}}, 0},
+		{"labeled objects", edgeapi.EdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{
+			LabelSelectors: []metav1.LabelSelector{{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpExists}}}},
+		}}}, 0},
+		{"every location", edgeapi.EdgePlacementSpec{LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"env": "prod"}}, {}}}, 1},
+		{"both", edgeapi.EdgePlacementSpec{LocationSelectors: []metav1.LabelSelector{{}}, Downsync: []edgeapi.DownsyncObjectTest{{}}}, 2},
+	} {
+		if got := BroadSelections(tc.spec); len(got) != tc.expected {
+			t.Errorf("%s: expected %d broad selections, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestWebhookBroadSelections(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		sar.Status.Allowed = true
+		return true, sar, nil
+	})
+	wh, err := NewWebhook(klog.Background(), client.AuthorizationV1().SubjectAccessReviews(), ModeEnforce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fleetWide := &edgeapi.EdgePlacement{Spec: edgeapi.EdgePlacementSpec{
+		LocationSelectors: []metav1.LabelSelector{{}},
+		Downsync:          []edgeapi.DownsyncObjectTest{{}},
+	}}
+	if resp := serve(t, wh, newReview(t, "EdgePlacement", admissionv1.Create, fleetWide, nil)); !resp.Allowed || len(resp.Warnings) != 2 {
+		t.Errorf("Expected a fleet-wide EdgePlacement to be admitted with 2 warnings by default, got %+v", resp)
+	}
+	if err := wh.SetBroadSelectionMode("sometimes"); err == nil {
+		t.Errorf("Expected an invalid mode to be rejected")
+	}
+	if err := wh.SetBroadSelectionMode(ModeEnforce); err != nil {
+		t.Fatal(err)
+	}
+	if resp := serve(t, wh, newReview(t, "EdgePlacement", admissionv1.Create, fleetWide, nil)); resp.Allowed || resp.Result.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a fleet-wide EdgePlacement to be rejected, got %+v", resp)
+	}
+	acknowledged := fleetWide.DeepCopy()
+	acknowledged.Spec.AllowAll = true
+	if resp := serve(t, wh, newReview(t, "EdgePlacement", admissionv1.Create, acknowledged, nil)); !resp.Allowed || len(resp.Warnings) != 0 {
+		t.Errorf("Expected an acknowledged fleet-wide EdgePlacement to be admitted quietly, got %+v", resp)
+	}
+
+	// A NamespacedEdgePlacement never reaches every namespace, but can reach every Location
+	nepl := &edgeapi.NamespacedEdgePlacement{Spec: edgeapi.NamespacedEdgePlacementSpec{Downsync: []edgeapi.DownsyncObjectTest{{}}}}
+	if resp := serve(t, wh, newReview(t, "NamespacedEdgePlacement", admissionv1.Create, nepl, nil)); !resp.Allowed {
+		t.Errorf("Expected a NamespacedEdgePlacement of its whole namespace to be admitted, got %+v", resp.Result)
+	}
+	nepl.Spec.LocationSelectors = []metav1.LabelSelector{{}}
+	if resp := serve(t, wh, newReview(t, "NamespacedEdgePlacement", admissionv1.Create, nepl, nil)); resp.Allowed {
+		t.Errorf("Expected a NamespacedEdgePlacement for every Location to be rejected")
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placementauthz
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/nsplacement"
+)
+
+// Mode says what the webhook does about a placement whose author lacks
+// some of the required read access.
+type Mode string
+
+const (
+	// ModeEnforce rejects the placement.
+	ModeEnforce Mode = "enforce"
+
+	// ModeWarn admits the placement and returns warnings to the author.
+	ModeWarn Mode = "warn"
+)
+
+// Path is where the Webhook is served.
+const Path = "/validate-placement"
+
+// Webhook is a validating admission webhook for EdgePlacements and
+// NamespacedEdgePlacements. Only creations and spec changes are checked.
+type Webhook struct {
+	logger klog.Logger
+	sars   authorizationclient.SubjectAccessReviewInterface
+	mode   Mode
+
+	// broadMode says what to do about a placement that selects very
+	// broadly (see BroadSelections) without `allowAll`.
+	broadMode Mode
+}
+
+// NewWebhook makes a Webhook that asks for SubjectAccessReviews through
+// the given client, which has to be for the same space as the placements.
+func NewWebhook(logger klog.Logger, sars authorizationclient.SubjectAccessReviewInterface, mode Mode) (*Webhook, error) {
+	if mode != ModeEnforce && mode != ModeWarn {
+		return nil, fmt.Errorf("mode must be %q or %q, not %q", ModeEnforce, ModeWarn, mode)
+	}
+	return &Webhook{logger: logger, sars: sars, mode: mode, broadMode: ModeWarn}, nil
+}
+
+// SetBroadSelectionMode says what to do about a placement that selects
+// very broadly without acknowledging that in `allowAll`: ModeWarn (the
+// default) admits it with warnings, ModeEnforce rejects it.
+func (wh *Webhook) SetBroadSelectionMode(mode Mode) error {
+	if mode != ModeEnforce && mode != ModeWarn {
+		return fmt.Errorf("mode must be %q or %q, not %q", ModeEnforce, ModeWarn, mode)
+	}
+	wh.broadMode = mode
+	return nil
+}
+
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "request body is not an AdmissionReview", http.StatusBadRequest)
+		return
+	}
+	review.Response = wh.admit(req, review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&review); err != nil {
+		wh.logger.V(3).Info("Failed to write response", "err", err)
+	}
+}
+
+func (wh *Webhook) admit(httpReq *http.Request, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	logger := wh.logger.WithValues("kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name, "user", req.UserInfo.Username)
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return allowed
+	}
+	newSpec, err := placementSpec(req.Kind.Kind, req.Namespace, req.Name, req.Object.Raw)
+	if err != nil {
+		return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error())
+	}
+	if newSpec == nil {
+		return allowed
+	}
+	if req.Operation == admissionv1.Update {
+		oldSpec, err := placementSpec(req.Kind.Kind, req.Namespace, req.Name, req.OldObject.Raw)
+		if err == nil && oldSpec != nil && apiequality.Semantic.DeepEqual(oldSpec, newSpec) {
+			return allowed
+		}
+	}
+	if broad := BroadSelections(*newSpec); len(broad) > 0 && !newSpec.AllowAll {
+		logger.V(2).Info("Placement selects very broadly without allowAll", "broad", broad, "mode", wh.broadMode)
+		if wh.broadMode == ModeEnforce {
+			return denied(http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, "this placement selects very broadly: "+strings.Join(broad, "; ")+"; set spec.allowAll to true if that is intended")
+		}
+		for _, description := range broad {
+			allowed.Warnings = append(allowed.Warnings, "this placement selects very broadly: "+description+"; set spec.allowAll to true if that is intended")
+		}
+	}
+	missing, err := Review(httpReq.Context(), wh.sars, req.UserInfo, RequiredAccess(*newSpec))
+	if err != nil {
+		logger.Error(err, "Failed to review access")
+		if wh.mode == ModeWarn {
+			allowed.Warnings = append(allowed.Warnings, "KubeStellar could not check that you can read everything this placement selects: "+err.Error())
+			return allowed
+		}
+		return denied(http.StatusInternalServerError, metav1.StatusReasonInternalError, err.Error())
+	}
+	if len(missing) == 0 {
+		return allowed
+	}
+	logger.V(2).Info("Author lacks read access to what the placement selects", "missing", len(missing), "mode", wh.mode)
+	if wh.mode == ModeWarn {
+		for _, attrs := range missing {
+			allowed.Warnings = append(allowed.Warnings, "this placement selects objects that you can not read: you can not "+Describe(attrs))
+		}
+		return allowed
+	}
+	return denied(http.StatusForbidden, metav1.StatusReasonForbidden, "this placement selects objects that you can not read: you can not "+describeAll(missing))
+}
+
+// placementSpec returns the spec of the EdgePlacement that the given object
+// amounts to, or nil if the object is of some other kind.
+func placementSpec(kind, namespace, name string, raw []byte) (*edgeapi.EdgePlacementSpec, error) {
+	switch kind {
+	case "EdgePlacement":
+		var ep edgeapi.EdgePlacement
+		if err := json.Unmarshal(raw, &ep); err != nil {
+			return nil, fmt.Errorf("failed to parse EdgePlacement: %w", err)
+		}
+		return &ep.Spec, nil
+	case "NamespacedEdgePlacement":
+		var nepl edgeapi.NamespacedEdgePlacement
+		if err := json.Unmarshal(raw, &nepl); err != nil {
+			return nil, fmt.Errorf("failed to parse NamespacedEdgePlacement: %w", err)
+		}
+		return &nsplacement.DesiredEdgePlacement(namespace, name, nepl.Spec).Spec, nil
+	}
+	return nil, nil
+}
+
+func describeAll(list []authorizationv1.ResourceAttributes) string {
+	descriptions := make([]string, len(list))
+	for idx, attrs := range list {
+		descriptions[idx] = Describe(attrs)
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+func denied(code int32, reason metav1.StatusReason, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Result: &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    code,
+		Reason:  reason,
+		Message: message,
+	}}
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package placementops changes many EdgePlacements at once: pausing,
+// resuming, or re-evaluating all those that match a label selector.
+//
+// The changes are made with server-side apply, each operation using its
+// own field manager so that it owns only its own annotation. An
+// operation is all-or-nothing as far as the apiserver allows: every
+// change is first submitted as a dry run, and only if all of those pass
+// are the changes made for real; if one of those then fails, the ones
+// already made are undone.
+package placementops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+)
+
+// Operation is something that can be done to many EdgePlacements at once.
+type Operation string
+
+const (
+	// Pause stops the where-resolver from changing the destinations of
+	// the EdgePlacements; see edgev2alpha1.PausedAnnotationKey.
+	Pause Operation = "pause"
+	// Resume undoes Pause.
+	Resume Operation = "resume"
+	// Reevaluate makes the controllers process the EdgePlacements again;
+	// see edgev2alpha1.ReprocessAnnotationKey.
+	Reevaluate Operation = "reevaluate"
+)
+
+// Operations lists the valid values of Operation.
+var Operations = []Operation{Pause, Resume, Reevaluate}
+
+// FieldManagerPrefix starts the name of the field manager used for
+// each operation.
+const FieldManagerPrefix = "kubestellar-placements-"
+
+// Request says what to do to which EdgePlacements. The EdgePlacements
+// are those matching the Selector (if not nil) plus those named.
+type Request struct {
+	Operation Operation
+	Selector  labels.Selector
+	Names     []string
+	// DryRun stops after checking that all the changes would be accepted.
+	DryRun bool
+}
+
+// Phase is a step in carrying out a Request.
+type Phase string
+
+const (
+	// PhaseValidate is the dry run of every change.
+	PhaseValidate Phase = "validate"
+	// PhaseApply is making the changes.
+	PhaseApply Phase = "apply"
+	// PhaseRollback is undoing the changes made before a failure.
+	PhaseRollback Phase = "rollback"
+)
+
+// Progress reports that one EdgePlacement has been handled in a Phase.
+type Progress struct {
+	Phase Phase
+	Name  string
+	// Done counts the EdgePlacements handled so far in this Phase, including this one.
+	Done  int
+	Total int
+	Err   error
+}
+
+// Result says what happened to each selected EdgePlacement.
+type Result struct {
+	Operation Operation `json:"operation"`
+	DryRun    bool      `json:"dryRun,omitempty"`
+	// Changed lists the EdgePlacements that were (or, for a dry run, would be) changed.
+	Changed []string `json:"changed"`
+	// Unchanged lists the EdgePlacements that were already as requested.
+	Unchanged []string `json:"unchanged"`
+	// RolledBack lists the changes that were undone after a failure.
+	RolledBack []string `json:"rolledBack,omitempty"`
+}
+
+// change is the plan for one EdgePlacement.
+type change struct {
+	name     string
+	previous string // value of the annotation before the change, "" if absent
+}
+
+// Run carries out the given Request, calling progress (if not nil) as
+// each EdgePlacement is handled. If an error is returned then nothing
+// was changed, except as listed in the Result when rollback also fails.
+func Run(ctx context.Context, client edgeclientset.Interface, req Request, progress func(Progress)) (Result, error) {
+	result := Result{Operation: req.Operation, DryRun: req.DryRun, Changed: []string{}, Unchanged: []string{}}
+	key, value, err := annotationFor(req.Operation, time.Now())
+	if err != nil {
+		return result, err
+	}
+	if progress == nil {
+		progress = func(Progress) {}
+	}
+	eps, err := selectPlacements(ctx, client, req)
+	if err != nil {
+		return result, err
+	}
+	changes := []change{}
+	for _, ep := range eps {
+		if alreadyDone(req.Operation, ep) {
+			result.Unchanged = append(result.Unchanged, ep.Name)
+			continue
+		}
+		changes = append(changes, change{name: ep.Name, previous: ep.Annotations[key]})
+	}
+
+	apply := func(name, value string, dryRun bool) error {
+		return applyAnnotation(ctx, client, req.Operation, name, key, value, dryRun)
+	}
+
+	var errs []error
+	for idx, chg := range changes {
+		err := apply(chg.name, value, true)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot %s EdgePlacement %q: %w", req.Operation, chg.name, err))
+		}
+		progress(Progress{Phase: PhaseValidate, Name: chg.name, Done: idx + 1, Total: len(changes), Err: err})
+	}
+	if len(errs) > 0 {
+		return result, utilerrors.NewAggregate(errs)
+	}
+	if req.DryRun {
+		for _, chg := range changes {
+			result.Changed = append(result.Changed, chg.name)
+		}
+		return result, nil
+	}
+
+	for idx, chg := range changes {
+		err := apply(chg.name, value, false)
+		progress(Progress{Phase: PhaseApply, Name: chg.name, Done: idx + 1, Total: len(changes), Err: err})
+		if err == nil {
+			result.Changed = append(result.Changed, chg.name)
+			continue
+		}
+		err = fmt.Errorf("failed to %s EdgePlacement %q: %w", req.Operation, chg.name, err)
+		if req.Operation == Reevaluate {
+			// Another re-evaluation is all that undoing one could achieve
+			return result, err
+		}
+		done := changes[:idx]
+		errs := []error{err}
+		for jdx, prev := range done {
+			rbErr := apply(prev.name, prev.previous, false)
+			progress(Progress{Phase: PhaseRollback, Name: prev.name, Done: jdx + 1, Total: len(done), Err: rbErr})
+			if rbErr != nil {
+				errs = append(errs, fmt.Errorf("failed to roll back EdgePlacement %q: %w", prev.name, rbErr))
+				continue
+			}
+			result.RolledBack = append(result.RolledBack, prev.name)
+		}
+		return result, utilerrors.NewAggregate(errs)
+	}
+	return result, nil
+}
+
+// annotationFor returns the annotation that the given operation sets and
+// the value to set it to.
+func annotationFor(op Operation, now time.Time) (key, value string, err error) {
+	switch op {
+	case Pause:
+		return edgev2alpha1.PausedAnnotationKey, "true", nil
+	case Resume:
+		return edgev2alpha1.PausedAnnotationKey, "false", nil
+	case Reevaluate:
+		return edgev2alpha1.ReprocessAnnotationKey, now.UTC().Format(time.RFC3339Nano), nil
+	default:
+		return "", "", fmt.Errorf("unknown operation %q", op)
+	}
+}
+
+// alreadyDone tells whether the given operation would not change the given EdgePlacement.
+func alreadyDone(op Operation, ep *edgev2alpha1.EdgePlacement) bool {
+	switch op {
+	case Pause:
+		return ep.Paused()
+	case Resume:
+		return !ep.Paused()
+	default:
+		return false
+	}
+}
+
+// selectPlacements returns the EdgePlacements selected by the Request, sorted by name.
+func selectPlacements(ctx context.Context, client edgeclientset.Interface, req Request) ([]*edgev2alpha1.EdgePlacement, error) {
+	byName := map[string]*edgev2alpha1.EdgePlacement{}
+	if req.Selector != nil {
+		list, err := client.EdgeV2alpha1().EdgePlacements().List(ctx, metav1.ListOptions{LabelSelector: req.Selector.String()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list EdgePlacements: %w", err)
+		}
+		for idx := range list.Items {
+			byName[list.Items[idx].Name] = &list.Items[idx]
+		}
+	}
+	for _, name := range req.Names {
+		if _, found := byName[name]; found {
+			continue
+		}
+		ep, err := client.EdgeV2alpha1().EdgePlacements().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil, fmt.Errorf("EdgePlacement %q not found", name)
+			}
+			return nil, fmt.Errorf("failed to get EdgePlacement %q: %w", name, err)
+		}
+		byName[name] = ep
+	}
+	ans := make([]*edgev2alpha1.EdgePlacement, 0, len(byName))
+	for _, ep := range byName {
+		ans = append(ans, ep)
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Name < ans[j].Name })
+	return ans, nil
+}
+
+// applyConfig returns the server-side apply configuration that sets the
+// given annotation, or owns no annotation if value is empty.
+func applyConfig(name, key, value string) []byte {
+	metadata := map[string]any{"name": name}
+	if value != "" {
+		metadata["annotations"] = map[string]string{key: value}
+	}
+	// Marshaling maps of strings cannot fail
+	content, _ := json.Marshal(map[string]any{
+		"apiVersion": edgev2alpha1.SchemeGroupVersion.String(),
+		"kind":       "EdgePlacement",
+		"metadata":   metadata,
+	})
+	return content
+}
+
+// applyAnnotation sets (or, for an empty value, releases) the given
+// annotation of the named EdgePlacement by server-side apply.
+func applyAnnotation(ctx context.Context, client edgeclientset.Interface, op Operation, name, key, value string, dryRun bool) error {
+	force := true
+	opts := metav1.PatchOptions{FieldManager: FieldManagerPrefix + string(fieldOwner(op)), Force: &force}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	_, err := client.EdgeV2alpha1().EdgePlacements().Patch(ctx, name, types.ApplyPatchType, applyConfig(name, key, value), opts)
+	return err
+}
+
+// fieldOwner returns the operation whose field manager owns the
+// annotation set by the given operation, so that a Resume can take back
+// what a Pause set.
+func fieldOwner(op Operation) Operation {
+	if op == Resume {
+		return Pause
+	}
+	return op
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placementops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgefake "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/fake"
+)
+
+type appliedPatch struct {
+	name        string
+	annotations map[string]string
+}
+
+// newClient returns a fake client holding the given EdgePlacements that
+// records the apply patches it receives and fails the failAt'th one
+// (counting from 1; 0 means none fails).
+// The fake does not reveal PatchOptions, so dry runs are told apart by position.
+func newClient(t *testing.T, failAt int, eps ...*edgev2alpha1.EdgePlacement) (*edgefake.Clientset, *[]appliedPatch) {
+	objs := make([]runtime.Object, len(eps))
+	for idx, ep := range eps {
+		objs[idx] = ep
+	}
+	client := edgefake.NewSimpleClientset(objs...)
+	patches := []appliedPatch{}
+	client.PrependReactor("patch", "edgeplacements", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		var config struct {
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(patch.GetPatch(), &config); err != nil {
+			t.Fatalf("Invalid apply configuration: %v", err)
+		}
+		if len(patches)+1 == failAt {
+			return true, nil, errors.New("injected failure")
+		}
+		patches = append(patches, appliedPatch{name: patch.GetName(), annotations: config.Metadata.Annotations})
+		return true, &edgev2alpha1.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: patch.GetName()}}, nil
+	})
+	return client, &patches
+}
+
+func newEP(name string, team string, annotations map[string]string) *edgev2alpha1.EdgePlacement {
+	return &edgev2alpha1.EdgePlacement{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Labels:      map[string]string{"team": team},
+		Annotations: annotations,
+	}}
+}
+
+func TestPause(t *testing.T) {
+	paused := map[string]string{edgev2alpha1.PausedAnnotationKey: "true"}
+	client, patches := newClient(t, 0,
+		newEP("a", "payments", nil),
+		newEP("b", "payments", paused),
+		newEP("c", "payments", nil),
+		newEP("d", "search", nil))
+	progress := []Progress{}
+	result, err := Run(context.Background(), client, Request{
+		Operation: Pause,
+		Selector:  labels.SelectorFromSet(labels.Set{"team": "payments"}),
+	}, func(p Progress) { progress = append(progress, p) })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Changed, []string{"a", "c"}) || !reflect.DeepEqual(result.Unchanged, []string{"b"}) {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	expected := []appliedPatch{
+		{name: "a", annotations: paused},
+		{name: "c", annotations: paused},
+		{name: "a", annotations: paused},
+		{name: "c", annotations: paused},
+	}
+	if !reflect.DeepEqual(*patches, expected) {
+		t.Errorf("Expected patches %+v but got %+v", expected, *patches)
+	}
+	if len(progress) != 4 || progress[3] != (Progress{Phase: PhaseApply, Name: "c", Done: 2, Total: 2}) {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	client, patches := newClient(t, 0, newEP("a", "payments", map[string]string{edgev2alpha1.PausedAnnotationKey: "true"}))
+	result, err := Run(context.Background(), client, Request{Operation: Resume, Names: []string{"a"}, DryRun: true}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Changed, []string{"a"}) {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if len(*patches) != 1 {
+		t.Errorf("Expected only the validating patch but got %+v", *patches)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	// Patches 1-3 validate, 4 and 5 change a and b, 6 fails to change c
+	client, patches := newClient(t, 6,
+		newEP("a", "payments", nil),
+		newEP("b", "payments", map[string]string{edgev2alpha1.PausedAnnotationKey: "false"}),
+		newEP("c", "payments", nil))
+	result, err := Run(context.Background(), client, Request{
+		Operation: Pause,
+		Selector:  labels.SelectorFromSet(labels.Set{"team": "payments"}),
+	}, nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !reflect.DeepEqual(result.RolledBack, []string{"a", "b"}) {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	rollbacks := (*patches)[5:]
+	expected := []appliedPatch{
+		{name: "a"},
+		{name: "b", annotations: map[string]string{edgev2alpha1.PausedAnnotationKey: "false"}},
+	}
+	if !reflect.DeepEqual(rollbacks, expected) {
+		t.Errorf("Expected rollback patches %+v but got %+v", expected, rollbacks)
+	}
+}
+
+func TestMissingName(t *testing.T) {
+	client, patches := newClient(t, 0, newEP("a", "payments", nil))
+	if _, err := Run(context.Background(), client, Request{Operation: Reevaluate, Names: []string{"a", "zz"}}, nil); err == nil {
+		t.Error("Expected an error for a missing EdgePlacement")
+	}
+	if len(*patches) != 0 {
+		t.Errorf("Expected no patches but got %+v", *patches)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podsecurity evaluates workload objects against the
+// Pod Security Standards (https://kubernetes.io/docs/concepts/security/pod-security-standards/)
+// so that objects that a destination's admission would reject can be
+// held back at the center instead of littering the fleet.
+// This is a pragmatic subset of the checks done by the
+// PodSecurity admission plugin, covering the controls that
+// commonly trip up workloads.
+package podsecurity
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Level is a Pod Security Standards level.
+type Level string
+
+const (
+	LevelPrivileged Level = "privileged"
+	LevelBaseline   Level = "baseline"
+	LevelRestricted Level = "restricted"
+)
+
+// ParseLevel returns the Level with the given name, or an error.
+func ParseLevel(name string) (Level, error) {
+	switch level := Level(name); level {
+	case LevelPrivileged, LevelBaseline, LevelRestricted:
+		return level, nil
+	default:
+		return "", fmt.Errorf("unknown pod security level %q", name)
+	}
+}
+
+// PodSpecPath returns the path to the PodSpec in an object of the given kind,
+// or nil if that kind is not known to bear a PodSpec.
+func PodSpecPath(group, kind string) []string {
+	switch {
+	case group == "" && kind == "Pod":
+		return []string{"spec"}
+	case group == "" && kind == "ReplicationController",
+		group == "apps" && (kind == "Deployment" || kind == "ReplicaSet" || kind == "StatefulSet" || kind == "DaemonSet"),
+		group == "batch" && kind == "Job":
+		return []string{"spec", "template", "spec"}
+	case group == "batch" && kind == "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+}
+
+// baselineCapabilities are the capabilities that the baseline level allows to be added.
+var baselineCapabilities = map[string]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true,
+	"KILL": true, "MKNOD": true, "NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true,
+	"SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+// restrictedVolumeTypes are the volume sources that the restricted level allows.
+var restrictedVolumeTypes = map[string]bool{
+	"configMap": true, "csi": true, "downwardAPI": true, "emptyDir": true, "ephemeral": true,
+	"persistentVolumeClaim": true, "projected": true, "secret": true,
+}
+
+// Check returns the violations of the given level by the given object.
+// Objects that do not bear a PodSpec have no violations.
+func Check(obj *unstructured.Unstructured, level Level) []string {
+	if level == LevelPrivileged {
+		return nil
+	}
+	gvk := obj.GroupVersionKind()
+	path := PodSpecPath(gvk.Group, gvk.Kind)
+	if path == nil {
+		return nil
+	}
+	podSpec, found, err := unstructured.NestedMap(obj.Object, path...)
+	if err != nil || !found {
+		return nil
+	}
+	var violations []string
+	add := func(format string, args ...any) { violations = append(violations, fmt.Sprintf(format, args...)) }
+	for _, field := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if val, _, _ := unstructured.NestedBool(podSpec, field); val {
+			add("%s is true", field)
+		}
+	}
+	volumes, _, _ := unstructured.NestedSlice(podSpec, "volumes")
+	for _, volAny := range volumes {
+		vol, ok := volAny.(map[string]any)
+		if !ok {
+			continue
+		}
+		for key := range vol {
+			if key == "name" {
+				continue
+			}
+			if key == "hostPath" {
+				add("volume %v uses hostPath", vol["name"])
+			} else if level == LevelRestricted && !restrictedVolumeTypes[key] {
+				add("volume %v uses restricted type %s", vol["name"], key)
+			}
+		}
+	}
+	podRunAsNonRoot, podRunAsNonRootSet, _ := unstructured.NestedBool(podSpec, "securityContext", "runAsNonRoot")
+	podSeccomp, _, _ := unstructured.NestedString(podSpec, "securityContext", "seccompProfile", "type")
+	for _, containerField := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		containers, _, _ := unstructured.NestedSlice(podSpec, containerField)
+		for _, contAny := range containers {
+			cont, ok := contAny.(map[string]any)
+			if !ok {
+				continue
+			}
+			name := cont["name"]
+			if val, _, _ := unstructured.NestedBool(cont, "securityContext", "privileged"); val {
+				add("container %v is privileged", name)
+			}
+			ports, _, _ := unstructured.NestedSlice(cont, "ports")
+			for _, portAny := range ports {
+				if port, ok := portAny.(map[string]any); ok {
+					if hostPort, _, _ := unstructured.NestedInt64(port, "hostPort"); hostPort != 0 {
+						add("container %v uses hostPort %d", name, hostPort)
+					}
+				}
+			}
+			added, _, _ := unstructured.NestedStringSlice(cont, "securityContext", "capabilities", "add")
+			for _, capability := range added {
+				allowed := baselineCapabilities[capability]
+				if level == LevelRestricted {
+					allowed = capability == "NET_BIND_SERVICE"
+				}
+				if !allowed {
+					add("container %v adds capability %s", name, capability)
+				}
+			}
+			if level != LevelRestricted {
+				continue
+			}
+			if val, found, _ := unstructured.NestedBool(cont, "securityContext", "allowPrivilegeEscalation"); !found || val {
+				add("container %v does not set allowPrivilegeEscalation=false", name)
+			}
+			dropped, _, _ := unstructured.NestedStringSlice(cont, "securityContext", "capabilities", "drop")
+			if !containsString(dropped, "ALL") {
+				add("container %v does not drop ALL capabilities", name)
+			}
+			runAsNonRoot, runAsNonRootSet, _ := unstructured.NestedBool(cont, "securityContext", "runAsNonRoot")
+			if !(runAsNonRootSet && runAsNonRoot || !runAsNonRootSet && podRunAsNonRootSet && podRunAsNonRoot) {
+				add("container %v does not set runAsNonRoot=true", name)
+			}
+			seccomp, _, _ := unstructured.NestedString(cont, "securityContext", "seccompProfile", "type")
+			if seccomp == "" {
+				seccomp = podSeccomp
+			}
+			if seccomp != "RuntimeDefault" && seccomp != "Localhost" {
+				add("container %v does not use a RuntimeDefault or Localhost seccomp profile", name)
+			}
+		}
+	}
+	return violations
+}
+
+func containsString(slice []string, seek string) bool {
+	for _, elt := range slice {
+		if elt == seek {
+			return true
+		}
+	}
+	return false
+}
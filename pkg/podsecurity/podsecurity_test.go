@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podsecurity
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func deployment(podSpec map[string]any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"name": "d1", "namespace": "ns1"},
+		"spec": map[string]any{
+			"template": map[string]any{"spec": podSpec},
+		},
+	}}
+}
+
+func TestCheck(t *testing.T) {
+	plain := deployment(map[string]any{
+		"containers": []any{map[string]any{"name": "c1", "image": "nginx"}},
+	})
+	hardened := deployment(map[string]any{
+		"securityContext": map[string]any{
+			"runAsNonRoot":   true,
+			"seccompProfile": map[string]any{"type": "RuntimeDefault"},
+		},
+		"containers": []any{map[string]any{
+			"name":  "c1",
+			"image": "nginx",
+			"securityContext": map[string]any{
+				"allowPrivilegeEscalation": false,
+				"capabilities":             map[string]any{"drop": []any{"ALL"}},
+			},
+		}},
+	})
+	privileged := deployment(map[string]any{
+		"hostNetwork": true,
+		"volumes":     []any{map[string]any{"name": "v1", "hostPath": map[string]any{"path": "/"}}},
+		"containers": []any{map[string]any{
+			"name":            "c1",
+			"image":           "nginx",
+			"securityContext": map[string]any{"privileged": true},
+		}},
+	})
+	configMap := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": "cm1", "namespace": "ns1"},
+	}}
+	for _, testCase := range []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		level    Level
+		expected int
+	}{
+		{"plain-privileged", plain, LevelPrivileged, 0},
+		{"plain-baseline", plain, LevelBaseline, 0},
+		{"plain-restricted", plain, LevelRestricted, 4},
+		{"hardened-restricted", hardened, LevelRestricted, 0},
+		{"privileged-privileged", privileged, LevelPrivileged, 0},
+		{"privileged-baseline", privileged, LevelBaseline, 3},
+		{"configmap-restricted", configMap, LevelRestricted, 0},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			violations := Check(testCase.obj, testCase.level)
+			if len(violations) != testCase.expected {
+				t.Errorf("Expected %d violations, got %v", testCase.expected, violations)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel("baseline"); err != nil || level != LevelBaseline {
+		t.Errorf("ParseLevel(baseline) = %q, %v", level, err)
+	}
+	if _, err := ParseLevel("lax"); err == nil {
+		t.Errorf("Expected error for unknown level")
+	}
+}
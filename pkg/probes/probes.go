@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package probes has the checks behind the /readyz and /healthz
+// endpoints of the KubeStellar controllers.
+//
+// A controller is ready once its informers have synced, and healthy
+// as long as none of its reconcile loops is stuck.
+package probes
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultWatchdogTimeout is the default for the --watchdog-timeout flags.
+const DefaultWatchdogTimeout = 10 * time.Minute
+
+// Mux is what Install needs from a mux.
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Install adds /readyz with the given readiness checks and /healthz
+// with the given liveness checks to the given mux. Each path also gets
+// a sub-path per check, such as /healthz/ping.
+func Install(mux Mux, ready []healthz.HealthChecker, live []healthz.HealthChecker) {
+	healthz.InstallReadyzHandler(mux, append([]healthz.HealthChecker{healthz.PingHealthz}, ready...)...)
+	healthz.InstallHandler(mux, append([]healthz.HealthChecker{healthz.PingHealthz}, live...)...)
+}
+
+// InformersSynced returns a readiness check that passes once all of the
+// given informers have synced. Once passed it keeps passing.
+func InformersSynced(name string, synced ...cache.InformerSynced) healthz.HealthChecker {
+	var allSynced atomic.Bool
+	return healthz.NamedCheck(name, func(*http.Request) error {
+		if allSynced.Load() {
+			return nil
+		}
+		for _, hasSynced := range synced {
+			if !hasSynced() {
+				return fmt.Errorf("informers not synced")
+			}
+		}
+		allSynced.Store(true)
+		return nil
+	})
+}
+
+// Watchdog is a liveness check that fails while some tracked piece of
+// work, normally one reconciliation, has been running for longer than
+// the timeout. A nil *Watchdog tracks nothing, and so does one with a
+// timeout of zero.
+type Watchdog struct {
+	name    string
+	timeout time.Duration
+	now     func() time.Time
+
+	mutex    sync.Mutex
+	nextID   uint64
+	inflight map[uint64]time.Time
+}
+
+var _ healthz.HealthChecker = &Watchdog{}
+
+// NewWatchdog makes a Watchdog with the given name and timeout.
+func NewWatchdog(name string, timeout time.Duration) *Watchdog {
+	return &Watchdog{name: name, timeout: timeout, now: time.Now, inflight: map[uint64]time.Time{}}
+}
+
+// Track records the start of a piece of work; call the returned func
+// when the work ends.
+func (wd *Watchdog) Track() (done func()) {
+	if wd == nil || wd.timeout <= 0 {
+		return func() {}
+	}
+	wd.mutex.Lock()
+	defer wd.mutex.Unlock()
+	id := wd.nextID
+	wd.nextID++
+	wd.inflight[id] = wd.now()
+	return func() {
+		wd.mutex.Lock()
+		defer wd.mutex.Unlock()
+		delete(wd.inflight, id)
+	}
+}
+
+// Name implements healthz.HealthChecker.
+func (wd *Watchdog) Name() string {
+	return wd.name
+}
+
+// Check implements healthz.HealthChecker.
+func (wd *Watchdog) Check(*http.Request) error {
+	if wd.timeout <= 0 {
+		return nil
+	}
+	wd.mutex.Lock()
+	defer wd.mutex.Unlock()
+	now := wd.now()
+	for _, started := range wd.inflight {
+		if age := now.Sub(started); age > wd.timeout {
+			return fmt.Errorf("a reconciliation has been running for %v, more than %v", age.Round(time.Second), wd.timeout)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package probes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	now := time.Unix(1000, 0)
+	wd := NewWatchdog("test", time.Minute)
+	wd.now = func() time.Time { return now }
+	done1 := wd.Track()
+	now = now.Add(30 * time.Second)
+	done2 := wd.Track()
+	if err := wd.Check(nil); err != nil {
+		t.Errorf("Unexpected failure: %v", err)
+	}
+	now = now.Add(45 * time.Second)
+	if err := wd.Check(nil); err == nil {
+		t.Error("Stuck work not noticed")
+	}
+	done1()
+	if err := wd.Check(nil); err != nil {
+		t.Errorf("Unexpected failure after first done: %v", err)
+	}
+	done2()
+	now = now.Add(time.Hour)
+	if err := wd.Check(nil); err != nil {
+		t.Errorf("Unexpected failure when idle: %v", err)
+	}
+	var nilWD *Watchdog
+	nilWD.Track()()
+}
+
+func TestInformersSynced(t *testing.T) {
+	synced := false
+	check := InformersSynced("informers", func() bool { return true }, func() bool { return synced })
+	if check.Check(nil) == nil {
+		t.Error("Ready before sync")
+	}
+	synced = true
+	if err := check.Check(nil); err != nil {
+		t.Errorf("Not ready after sync: %v", err)
+	}
+	synced = false
+	if err := check.Check(nil); err != nil {
+		t.Errorf("Readiness did not stick: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package recovery keeps a panic while handling one object from
+// crashing a whole controller.
+//
+// Guard runs one reconciliation and Handler wraps an informer event
+// handler. When either recovers a panic it logs the controller, the
+// object and the stack, counts the panic in a metric, and, if bundles
+// are enabled, writes a diagnostic bundle file.
+//
+// Recovery does not undo what the panicking code left half done. In
+// particular a mutex that was locked without a deferred unlock stays
+// locked; the watchdog in package probes notices the resulting stuck
+// reconciliations.
+package recovery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v3"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+// DefaultMaxBundles is how many bundles a process writes, by default,
+// before it stops writing them.
+const DefaultMaxBundles = 20
+
+var panics = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "kubestellar",
+	Name:           "recovered_panics_total",
+	Help:           "Number of panics recovered, by controller and by where they happened",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"controller", "site"})
+
+func init() {
+	legacyregistry.MustRegister(panics)
+}
+
+var bundles struct {
+	sync.Mutex
+	dir     string
+	max     int
+	written int
+}
+
+// EnableBundles makes every recovered panic write a diagnostic bundle
+// into the given directory, until maxBundles have been written by this
+// process. An empty dir disables bundles, which is the default.
+func EnableBundles(dir string, maxBundles int) {
+	bundles.Lock()
+	defer bundles.Unlock()
+	bundles.dir, bundles.max = dir, maxBundles
+}
+
+// Guard calls fn, recovering a panic from it.
+// The controller and key identify what fn was doing.
+// Returns whether fn panicked.
+func Guard(logger klog.Logger, controller string, key any, fn func()) (panicked bool) {
+	defer func() {
+		if val := recover(); val != nil {
+			panicked = true
+			report(logger, controller, "reconcile", key, val)
+		}
+	}()
+	fn()
+	return false
+}
+
+// Handler wraps the given event handler so that a panic in it is
+// recovered and reported rather than crashing the process.
+func Handler(logger klog.Logger, controller string, handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	return guardedHandler{logger: logger, controller: controller, handler: handler}
+}
+
+type guardedHandler struct {
+	logger     klog.Logger
+	controller string
+	handler    cache.ResourceEventHandler
+}
+
+func (gh guardedHandler) OnAdd(obj interface{}) {
+	defer gh.recover(obj)
+	gh.handler.OnAdd(obj)
+}
+
+func (gh guardedHandler) OnUpdate(oldObj, newObj interface{}) {
+	defer gh.recover(newObj)
+	gh.handler.OnUpdate(oldObj, newObj)
+}
+
+func (gh guardedHandler) OnDelete(obj interface{}) {
+	defer gh.recover(obj)
+	gh.handler.OnDelete(obj)
+}
+
+func (gh guardedHandler) recover(obj any) {
+	if val := recover(); val != nil {
+		report(gh.logger, gh.controller, "event-handler", objectKey(obj), val)
+	}
+}
+
+// objectKey describes an informer's object for logging.
+func objectKey(obj any) string {
+	if dfsu, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return dfsu.Key
+	}
+	if mObj, ok := obj.(metav1.Object); ok {
+		key := mObj.GetName()
+		if ns := mObj.GetNamespace(); ns != "" {
+			key = ns + "/" + key
+		}
+		if cluster := logicalcluster.From(mObj); !cluster.Empty() {
+			key = cluster.String() + "|" + key
+		}
+		return fmt.Sprintf("%T %s", obj, key)
+	}
+	return fmt.Sprintf("%T", obj)
+}
+
+func report(logger klog.Logger, controller, site string, key any, val any) {
+	stack := debug.Stack()
+	panics.WithLabelValues(controller, site).Inc()
+	logger.Error(fmt.Errorf("panic: %v", val), "Recovered from panic", "controller", controller, "site", site, "key", key, "stack", string(stack))
+	path, err := writeBundle(controller, site, key, val, stack)
+	if err != nil {
+		logger.Error(err, "Failed to write panic diagnostic bundle", "controller", controller)
+	} else if path != "" {
+		logger.Info("Wrote panic diagnostic bundle", "controller", controller, "path", path)
+	}
+}
+
+// writeBundle writes a file with the panic and all the goroutine stacks.
+// Returns the path written, or "" if bundles are disabled or used up.
+func writeBundle(controller, site string, key any, val any, stack []byte) (string, error) {
+	bundles.Lock()
+	defer bundles.Unlock()
+	if bundles.dir == "" || bundles.written >= bundles.max {
+		return "", nil
+	}
+	bundles.written++
+	now := time.Now()
+	var content strings.Builder
+	fmt.Fprintf(&content, "time: %s\ncontroller: %s\nsite: %s\nkey: %v\npanic: %v\n\n%s\n", now.Format(time.RFC3339Nano), controller, site, key, val, stack)
+	allStacks := make([]byte, 1<<20)
+	allStacks = allStacks[:runtime.Stack(allStacks, true)]
+	fmt.Fprintf(&content, "all goroutines:\n%s\n", allStacks)
+	if err := os.MkdirAll(bundles.dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(bundles.dir, fmt.Sprintf("panic-%s-%s-%d.txt", controller, now.UTC().Format("20060102T150405Z"), bundles.written))
+	return path, os.WriteFile(path, []byte(content.String()), 0o644)
+}
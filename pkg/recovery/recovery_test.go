@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recovery
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+func TestGuard(t *testing.T) {
+	dir := t.TempDir()
+	EnableBundles(dir, 1)
+	defer EnableBundles("", 0)
+	logger := klog.Background()
+	if Guard(logger, "test", "k1", func() {}) {
+		t.Error("Reported a panic that did not happen")
+	}
+	for i := 0; i < 2; i++ {
+		if !Guard(logger, "test", "k1", func() { panic("oops") }) {
+			t.Error("Did not report a panic")
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 bundle, got %d", len(entries))
+	}
+	content, err := os.ReadFile(dir + "/" + entries[0].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"controller: test", "key: k1", "panic: oops", "all goroutines:"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Bundle lacks %q", expected)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var deleted bool
+	handler := Handler(klog.Background(), "test", cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { panic("bad add") },
+		DeleteFunc: func(obj interface{}) { deleted = true },
+	})
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cm"}}
+	handler.OnAdd(cm)
+	handler.OnDelete(cm)
+	if !deleted {
+		t.Error("Delete not passed through")
+	}
+	if key := objectKey(cm); key != "*v1.ConfigMap ns/cm" {
+		t.Errorf("Got key %q", key)
+	}
+}
@@ -0,0 +1,478 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions/edge/v2alpha1"
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	plugin "github.com/kubestellar/kubestellar/pkg/cliplugins/kubestellar/syncer-gen"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+	"github.com/kubestellar/kubestellar/pkg/credbroker"
+	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+	spaceinformers "github.com/kubestellar/kubestellar/space-framework/pkg/client/informers/externalversions/space/v1alpha1"
+	spacelisters "github.com/kubestellar/kubestellar/space-framework/pkg/client/listers/space/v1alpha1"
+)
+
+const controllerName = "cluster-registration-controller"
+
+const fieldManager = "kubestellar"
+
+// SyncTargetNameAnnotationKey identifies the annotation on a mailbox space
+// that holds the name of the corresponding SyncTarget.
+// This is the key used by the mailbox controller.
+const SyncTargetNameAnnotationKey = "edge.kubestellar.io/sync-target-name"
+
+// originalNameIndexKey identifies the index, in the ClusterRegistration informer,
+// on the name of the consumer's object.
+const originalNameIndexKey = "originalName"
+
+// pollPeriod is how long to wait before looking again at a join that is
+// waiting on something that does not notify this controller.
+const pollPeriod = 30 * time.Second
+
+// manifestTimeout bounds the time spent generating one syncer manifest.
+const manifestTimeout = 2 * time.Minute
+
+// Controller drives the ClusterRegistrations in the inventory spaces.
+// It works off the provider's copies of the ClusterRegistrations and SyncTargets,
+// which kube-bind puts in the KubeStellar core space; the queue holds the
+// names of those copies (a ClusterRegistration and its SyncTarget have the same one).
+type Controller struct {
+	context         context.Context
+	regInformer     cache.SharedIndexInformer
+	regLister       edgelisters.ClusterRegistrationLister
+	stInformer      cache.SharedIndexInformer
+	stLister        edgelisters.SyncTargetLister
+	spaceInformer   cache.SharedIndexInformer
+	spaceLister     spacelisters.SpaceNamespaceLister
+	spaceProviderNs string
+	kbSpaceRelation kbuser.KubeBindSpaceRelation
+	spaceClients    *spaceclient.Factory
+	coreEdgeClient  edgeclientset.Interface
+	queue           workqueue.RateLimitingInterface
+}
+
+// NewController makes a new Controller.
+// The ClusterRegistration and SyncTarget informers are on the KubeStellar core space.
+func NewController(ctx context.Context,
+	regPreInformer edgeinformers.ClusterRegistrationInformer,
+	stPreInformer edgeinformers.SyncTargetInformer,
+	spacePreInformer spaceinformers.SpaceInformer,
+	spaceProviderNs string,
+	kbSpaceRelation kbuser.KubeBindSpaceRelation,
+	spaceClients *spaceclient.Factory,
+	coreEdgeClient edgeclientset.Interface,
+) *Controller {
+	ctl := &Controller{
+		context:         ctx,
+		regInformer:     regPreInformer.Informer(),
+		regLister:       regPreInformer.Lister(),
+		stInformer:      stPreInformer.Informer(),
+		stLister:        stPreInformer.Lister(),
+		spaceInformer:   spacePreInformer.Informer(),
+		spaceLister:     spacePreInformer.Lister().Spaces(spaceProviderNs),
+		spaceProviderNs: spaceProviderNs,
+		kbSpaceRelation: kbSpaceRelation,
+		spaceClients:    spaceClients,
+		coreEdgeClient:  coreEdgeClient,
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+	}
+	ctl.regInformer.AddIndexers(cache.Indexers{originalNameIndexKey: originalNameOfObj})
+	ctl.regInformer.AddEventHandler(ctl)
+	ctl.stInformer.AddEventHandler(ctl)
+	ctl.spaceInformer.AddEventHandler(ctl)
+	return ctl
+}
+
+// Run animates the controller, finishing and returning when the context of
+// the controller is done.
+// Call this after the informers have been started.
+func (ctl *Controller) Run(concurrency int) {
+	ctx := ctl.context
+	logger := klog.FromContext(ctx)
+	doneCh := ctx.Done()
+	if !cache.WaitForNamedCacheSync(controllerName, doneCh, ctl.regInformer.HasSynced, ctl.stInformer.HasSynced, ctl.spaceInformer.HasSynced) {
+		logger.Error(nil, "Informer syncs not achieved")
+		return
+	}
+	logger.V(1).Info("Informers synced")
+	for worker := 0; worker < concurrency; worker++ {
+		go ctl.syncLoop(ctx, worker)
+	}
+	<-doneCh
+}
+
+func (ctl *Controller) OnAdd(obj any) {
+	ctl.enqueue(obj)
+}
+
+func (ctl *Controller) OnUpdate(oldObj, newObj any) {
+	ctl.enqueue(newObj)
+}
+
+func (ctl *Controller) OnDelete(obj any) {
+	if dfsu, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = dfsu.Obj
+	}
+	ctl.enqueue(obj)
+}
+
+func (ctl *Controller) enqueue(obj any) {
+	logger := klog.FromContext(ctl.context)
+	switch typed := obj.(type) {
+	case *edgeapi.ClusterRegistration:
+		ctl.queue.Add(typed.Name)
+	case *edgeapi.SyncTarget:
+		ctl.queue.Add(typed.Name)
+	case *spacev1alpha1.Space:
+		stName := typed.Annotations[SyncTargetNameAnnotationKey]
+		if stName == "" {
+			return
+		}
+		regs, err := ctl.regInformer.GetIndexer().ByIndex(originalNameIndexKey, stName)
+		if err != nil {
+			logger.Error(err, "Failed to lookup ClusterRegistrations by name", "name", stName)
+			return
+		}
+		for _, reg := range regs {
+			ctl.queue.Add(reg.(*edgeapi.ClusterRegistration).Name)
+		}
+	default:
+		logger.Error(nil, "Notified of object of unexpected type", "object", obj, "type", fmt.Sprintf("%T", obj))
+	}
+}
+
+func originalNameOfObj(obj any) ([]string, error) {
+	reg := obj.(*edgeapi.ClusterRegistration)
+	_, name, _, err := kbuser.AnalyzeObjectID(reg)
+	if err != nil {
+		return []string{}, nil
+	}
+	return []string{name}, nil
+}
+
+func (ctl *Controller) syncLoop(ctx context.Context, worker int) {
+	doneCh := ctx.Done()
+	logger := klog.FromContext(ctx).WithValues("worker", worker)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(4).Info("SyncLoop start")
+	for {
+		select {
+		case <-doneCh:
+			logger.V(2).Info("SyncLoop done")
+			return
+		default:
+			ref, shutdown := ctl.queue.Get()
+			if shutdown {
+				logger.V(2).Info("Queue shutdown")
+				return
+			}
+			ctl.sync1(ctx, ref.(string))
+		}
+	}
+}
+
+func (ctl *Controller) sync1(ctx context.Context, key string) {
+	defer ctl.queue.Done(key)
+	logger := klog.FromContext(ctx)
+	logger.V(4).Info("Dequeued reference", "key", key)
+	retry, requeueAfter := ctl.sync(ctx, key)
+	if retry {
+		ctl.queue.AddRateLimited(key)
+		return
+	}
+	ctl.queue.Forget(key)
+	if requeueAfter > 0 {
+		ctl.queue.AddAfter(key, requeueAfter)
+	}
+}
+
+// sync drives one ClusterRegistration as far as it can go.
+// It returns whether to retry, and how long to wait before looking
+// again (zero for not at all) because the join is waiting on something
+// that does not notify or the manifest Secret will need renewal.
+func (ctl *Controller) sync(ctx context.Context, key string) (retry bool, requeueAfter time.Duration) {
+	logger := klog.FromContext(ctx).WithValues("key", key)
+	reg, err := ctl.regLister.Get(key)
+	if err != nil {
+		if k8sapierrors.IsNotFound(err) {
+			logger.V(4).Info("ClusterRegistration not found, nothing to do")
+			return false, 0
+		}
+		logger.Error(err, "Failed to fetch ClusterRegistration from local cache")
+		return false, 0
+	}
+	if reg.DeletionTimestamp != nil {
+		// The owner references take care of the rest.
+		return false, 0
+	}
+	_, name, kbSpaceID, err := kbuser.AnalyzeObjectID(reg)
+	if err != nil {
+		logger.Error(err, "Object does not appear to be a provider's copy of a consumer's object")
+		return false, 0
+	}
+	spaceID := ctl.kbSpaceRelation.SpaceIDFromKubeBind(kbSpaceID)
+	if spaceID == "" {
+		logger.V(3).Info("Consumer space ID not known yet", "kbSpaceID", kbSpaceID)
+		return true, 0
+	}
+	logger = logger.WithValues("spaceID", spaceID, "name", name)
+	ctx = klog.NewContext(ctx, logger)
+	status := reg.Status.DeepCopy()
+	gen := reg.Generation
+	retry, renewAt := ctl.join(ctx, reg, name, spaceID, status)
+	now := metav1.Now()
+	Summarize(status, gen, now)
+	if !apiequality.Semantic.DeepEqual(status, &reg.Status) {
+		regCopy := reg.DeepCopy()
+		regCopy.Status = *status
+		_, err = ctl.coreEdgeClient.EdgeV2alpha1().ClusterRegistrations().UpdateStatus(ctx, regCopy, metav1.UpdateOptions{FieldManager: fieldManager})
+		if err != nil {
+			logger.Error(err, "Failed to update status of ClusterRegistration")
+			return true, 0
+		}
+		logger.V(2).Info("Updated status of ClusterRegistration", "phase", status.Phase)
+	}
+	if retry {
+		return true, 0
+	}
+	if status.Phase != edgeapi.ClusterRegistrationJoined {
+		requeueAfter = pollPeriod
+	}
+	if !renewAt.IsZero() {
+		untilRenewal := time.Until(renewAt)
+		if untilRenewal <= 0 {
+			untilRenewal = time.Second
+		}
+		if requeueAfter == 0 || untilRenewal < requeueAfter {
+			requeueAfter = untilRenewal
+		}
+	}
+	return false, requeueAfter
+}
+
+// join does the steps of the join flow, recording the outcomes in the given status,
+// and returns whether to retry and when the manifest Secret has to be
+// re-issued (zero for never).
+func (ctl *Controller) join(ctx context.Context, reg *edgeapi.ClusterRegistration, name, spaceID string, status *edgeapi.ClusterRegistrationStatus) (bool, time.Time) {
+	logger := klog.FromContext(ctx)
+	now := metav1.Now()
+	gen := reg.Generation
+	setCond := func(condType string, ok bool, reason, message string) {
+		conditions.Set(&status.Conditions, condition(condType, ok, reason, message, gen), now)
+	}
+	invClients, err := ctl.spaceClients.For(spaceID, ctl.spaceProviderNs)
+	if err != nil {
+		logger.Error(err, "Failed to get clients for inventory space")
+		return true, time.Time{}
+	}
+	invEdgeClient, invKubeClient := invClients.Edge, invClients.Kube
+
+	// The consumer's object, whose UID goes in the owner references.
+	consumerReg, err := invEdgeClient.EdgeV2alpha1().ClusterRegistrations().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		logger.Error(err, "Failed to read ClusterRegistration in inventory space")
+		return true, time.Time{}
+	}
+	owner := metav1.OwnerReference{
+		APIVersion: edgeapi.SchemeGroupVersion.String(),
+		Kind:       "ClusterRegistration",
+		Name:       consumerReg.Name,
+		UID:        consumerReg.UID,
+	}
+
+	// Step 1: SyncTarget and Location.
+	if err := ctl.ensureInventory(ctx, invEdgeClient, name, reg.Spec, owner); err != nil {
+		setCond(edgeapi.ClusterRegistrationInventoryReady, false, "Failed", err.Error())
+		return true, time.Time{}
+	}
+	setCond(edgeapi.ClusterRegistrationInventoryReady, true, "Ensured", "")
+
+	// Step 2: mailbox space, made by the mailbox controller.
+	providerST, err := ctl.stLister.Get(reg.Name)
+	if err != nil {
+		if !k8sapierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to fetch SyncTarget from local cache")
+		}
+		setCond(edgeapi.ClusterRegistrationMailboxReady, false, "AwaitingSyncTarget", "the SyncTarget has not yet reached the core space")
+		return false, time.Time{}
+	}
+	status.SyncTargetUID = string(providerST.UID)
+	mbsName := naming.MailboxSpaceName(spaceID, string(providerST.UID))
+	status.MailboxSpace = mbsName
+	space, err := ctl.spaceLister.Get(mbsName)
+	if err != nil || space.Status.Phase != spacev1alpha1.SpacePhaseReady {
+		setCond(edgeapi.ClusterRegistrationMailboxReady, false, "NotReady", fmt.Sprintf("mailbox space %q is not ready", mbsName))
+		return false, time.Time{}
+	}
+	setCond(edgeapi.ClusterRegistrationMailboxReady, true, "Ready", "")
+
+	// Step 3: syncer credentials and the manifest Secret.
+	secretNS := reg.Spec.ManifestSecretNamespace
+	if secretNS == "" {
+		secretNS = DefaultManifestSecretNamespace
+	}
+	secretName := naming.RegistrationManifestSecretName(name)
+	status.ManifestSecretRef = &corev1.SecretReference{Namespace: secretNS, Name: secretName}
+	var ttl time.Duration
+	if reg.Spec.TokenTTL != nil {
+		ttl = reg.Spec.TokenTTL.Duration
+	}
+	secret, err := invKubeClient.CoreV1().Secrets(secretNS).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil && !k8sapierrors.IsNotFound(err) {
+		logger.Error(err, "Failed to read manifest Secret", "namespace", secretNS, "secret", secretName)
+		return true, time.Time{}
+	}
+	var renewAt time.Time
+	var renew bool
+	if err == nil {
+		renewAt, renew = ManifestRenewalTime(secret, ttl)
+	}
+	if k8sapierrors.IsNotFound(err) || renew && !renewAt.After(time.Now()) {
+		secret, err = ctl.issueCredentials(ctx, invKubeClient, reg, mbsName, secretNS, secretName, owner)
+		if err != nil {
+			logger.Error(err, "Failed to issue syncer credentials", "mbsName", mbsName)
+			setCond(edgeapi.ClusterRegistrationCredentialsIssued, false, "Failed", err.Error())
+			return true, time.Time{}
+		}
+		renewAt, renew = ManifestRenewalTime(secret, ttl)
+		logger.V(2).Info("Issued syncer credentials", "mbsName", mbsName, "namespace", secretNS, "secret", secretName, "renewAt", renewAt)
+	}
+	if !renew {
+		renewAt = time.Time{}
+	}
+	setCond(edgeapi.ClusterRegistrationCredentialsIssued, true, "Issued", "")
+
+	// Step 4: the syncer reports in.
+	if SyncerConnected(providerST) {
+		setCond(edgeapi.ClusterRegistrationSyncerConnected, true, "Heartbeat", "")
+	} else {
+		setCond(edgeapi.ClusterRegistrationSyncerConnected, false, "NoHeartbeat", "apply the manifest in the Secret to the WEC")
+	}
+	return false, renewAt
+}
+
+func (ctl *Controller) ensureInventory(ctx context.Context, invEdgeClient edgeclientset.Interface, name string, spec edgeapi.ClusterRegistrationSpec, owner metav1.OwnerReference) error {
+	logger := klog.FromContext(ctx)
+	stClient := invEdgeClient.EdgeV2alpha1().SyncTargets()
+	wantST := DesiredSyncTarget(name, spec, owner)
+	st, err := stClient.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case k8sapierrors.IsNotFound(err):
+		_, err = stClient.Create(ctx, wantST, metav1.CreateOptions{FieldManager: fieldManager})
+		if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create SyncTarget: %w", err)
+		}
+		logger.V(2).Info("Created SyncTarget")
+	case err != nil:
+		return fmt.Errorf("failed to read SyncTarget: %w", err)
+	default:
+		if labels, changed := MergeLabels(st.Labels, wantST.Labels); changed {
+			st = st.DeepCopy()
+			st.Labels = labels
+			if _, err := stClient.Update(ctx, st, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+				return fmt.Errorf("failed to update SyncTarget: %w", err)
+			}
+			logger.V(2).Info("Updated labels of SyncTarget")
+		}
+	}
+
+	locClient := invEdgeClient.EdgeV2alpha1().Locations()
+	wantLoc := DesiredLocation(name, spec, owner)
+	loc, err := locClient.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case k8sapierrors.IsNotFound(err):
+		_, err = locClient.Create(ctx, wantLoc, metav1.CreateOptions{FieldManager: fieldManager})
+		if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create Location: %w", err)
+		}
+		logger.V(2).Info("Created Location")
+	case err != nil:
+		return fmt.Errorf("failed to read Location: %w", err)
+	default:
+		labels, changed := MergeLabels(loc.Labels, wantLoc.Labels)
+		if changed || !apiequality.Semantic.DeepEqual(loc.Spec, wantLoc.Spec) {
+			loc = loc.DeepCopy()
+			loc.Labels = labels
+			loc.Spec = wantLoc.Spec
+			if _, err := locClient.Update(ctx, loc, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+				return fmt.Errorf("failed to update Location: %w", err)
+			}
+			logger.V(2).Info("Updated Location")
+		}
+	}
+	return nil
+}
+
+// issueCredentials generates a syncer manifest, with fresh credentials, and
+// puts it in the manifest Secret, replacing any previous one.
+func (ctl *Controller) issueCredentials(ctx context.Context, invKubeClient kubernetes.Interface, reg *edgeapi.ClusterRegistration, mbsName, secretNS, secretName string, owner metav1.OwnerReference) (*corev1.Secret, error) {
+	opts := plugin.NewEdgeSyncOptions(genericclioptions.IOStreams{Out: io.Discard, ErrOut: io.Discard})
+	opts.SyncerImage = reg.Spec.SyncerImage
+	opts.SyncTargetName = reg.Name
+	if reg.Spec.TokenTTL != nil {
+		opts.TokenTTL = reg.Spec.TokenTTL.Duration
+		if opts.TokenTTL < credbroker.MinimumTTL {
+			return nil, fmt.Errorf("tokenTTL must be at least %v", credbroker.MinimumTTL)
+		}
+	}
+	if opts.SyncerImage == "" {
+		return nil, fmt.Errorf("syncerImage is required")
+	}
+	mbClients, err := ctl.spaceClients.For(mbsName, ctl.spaceProviderNs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clients for mailbox space: %w", err)
+	}
+	mbConfig := rest.CopyConfig(mbClients.Config)
+	genCtx, cancel := context.WithTimeout(ctx, manifestTimeout)
+	defer cancel()
+	manifest, err := opts.GenerateManifest(genCtx, mbConfig)
+	if err != nil {
+		return nil, err
+	}
+	secret := ManifestSecret(secretNS, secretName, manifest, owner, opts.TokenExpiry())
+	secrets := invKubeClient.CoreV1().Secrets(secretNS)
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{FieldManager: fieldManager})
+	if k8sapierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{FieldManager: fieldManager})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write manifest Secret: %w", err)
+	}
+	return secret, nil
+}
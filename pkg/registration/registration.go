@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registration drives ClusterRegistration objects through the
+// join flow: SyncTarget and Location, mailbox space, syncer credentials.
+//
+// The controller runs against the KubeStellar core space, where
+// kube-bind brings the ClusterRegistrations of all the inventory spaces
+// (as it does their SyncTargets and Locations) and where it writes
+// their status. It creates the SyncTarget, Location and manifest Secret
+// in the inventory space itself, as `kubectl kubestellar prep-for-cluster`
+// does, and works in the mailbox space the way syncer-gen does.
+package registration
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+	"github.com/kubestellar/kubestellar/pkg/credbroker"
+)
+
+// IDLabelKey is the label that a Location uses to select its SyncTarget.
+const IDLabelKey = "id"
+
+// DefaultManifestSecretNamespace is where the manifest Secret goes when
+// the ClusterRegistration does not say.
+const DefaultManifestSecretNamespace = "default"
+
+// ManifestExpiryAnnotationKey is the annotation on the manifest Secret that
+// holds the expiration time, in RFC 3339 format, of the short-lived token
+// in the manifest.
+const ManifestExpiryAnnotationKey = credbroker.ExpiryAnnotationKey
+
+// severities of the conditions that make up Ready.
+var severities = map[string]conditions.Severity{
+	edgeapi.ClusterRegistrationInventoryReady:    conditions.SeverityError,
+	edgeapi.ClusterRegistrationMailboxReady:      conditions.SeverityWarning,
+	edgeapi.ClusterRegistrationCredentialsIssued: conditions.SeverityError,
+	edgeapi.ClusterRegistrationSyncerConnected:   conditions.SeverityInfo,
+}
+
+// DesiredLabels returns the labels of the SyncTarget and Location of the
+// given ClusterRegistration, whose name (without kube-bind prefix) is given.
+func DesiredLabels(name string, spec edgeapi.ClusterRegistrationSpec) map[string]string {
+	ans := map[string]string{}
+	for key, val := range spec.Labels {
+		ans[key] = val
+	}
+	ans[IDLabelKey] = name
+	return ans
+}
+
+// DesiredSyncTarget returns the SyncTarget for the named ClusterRegistration.
+func DesiredSyncTarget(name string, spec edgeapi.ClusterRegistrationSpec, owner metav1.OwnerReference) *edgeapi.SyncTarget {
+	return &edgeapi.SyncTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Labels:          DesiredLabels(name, spec),
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+	}
+}
+
+// DesiredLocation returns the Location for the named ClusterRegistration.
+func DesiredLocation(name string, spec edgeapi.ClusterRegistrationSpec, owner metav1.OwnerReference) *edgeapi.Location {
+	return &edgeapi.Location{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Labels:          DesiredLabels(name, spec),
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: edgeapi.LocationSpec{
+			Resource:         edgeapi.GroupVersionResource{Group: edgeapi.SchemeGroupVersion.Group, Version: edgeapi.SchemeGroupVersion.Version, Resource: "synctargets"},
+			InstanceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{IDLabelKey: name}},
+		},
+	}
+}
+
+// MergeLabels returns the given labels with the wanted ones put in,
+// and whether that changed anything. Other labels are kept, as
+// `kubectl kubestellar ensure location` keeps them.
+func MergeLabels(have, want map[string]string) (map[string]string, bool) {
+	changed := false
+	ans := make(map[string]string, len(have)+len(want))
+	for key, val := range have {
+		ans[key] = val
+	}
+	for key, val := range want {
+		if have[key] != val {
+			ans[key] = val
+			changed = true
+		}
+	}
+	return ans, changed
+}
+
+// Summarize sets the Ready condition and the phase of the given status
+// from its other conditions.
+func Summarize(status *edgeapi.ClusterRegistrationStatus, generation int64, now metav1.Time) {
+	conditions.Set(&status.Conditions, conditions.AggregateReady(status.Conditions, severities, generation), now)
+	switch {
+	case !conditions.IsTrue(status.Conditions, edgeapi.ClusterRegistrationInventoryReady):
+		status.Phase = edgeapi.ClusterRegistrationPending
+	case !conditions.IsTrue(status.Conditions, edgeapi.ClusterRegistrationMailboxReady):
+		status.Phase = edgeapi.ClusterRegistrationProvisioning
+	case !conditions.IsTrue(status.Conditions, edgeapi.ClusterRegistrationCredentialsIssued):
+		status.Phase = edgeapi.ClusterRegistrationIssuingCredentials
+	case !conditions.IsTrue(status.Conditions, edgeapi.ClusterRegistrationSyncerConnected):
+		status.Phase = edgeapi.ClusterRegistrationAwaitingSyncer
+	default:
+		status.Phase = edgeapi.ClusterRegistrationJoined
+	}
+	status.ObservedGeneration = generation
+}
+
+// ManifestSecret returns the Secret that holds the given manifest, whose
+// token expires at the given time (zero for a long-lived token).
+func ManifestSecret(namespace, name string, manifest []byte, owner metav1.OwnerReference, expiry time.Time) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       namespace,
+			Name:            name,
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Data: map[string][]byte{edgeapi.ClusterRegistrationManifestKey: manifest},
+	}
+	if !expiry.IsZero() {
+		secret.Annotations = map[string]string{ManifestExpiryAnnotationKey: expiry.UTC().Format(time.RFC3339)}
+	}
+	return secret
+}
+
+// ManifestRenewalTime returns when the given manifest Secret has to be
+// re-issued so that the token in it does not expire, which is once two
+// thirds of the lifetime of the token have passed; and false if it never
+// has to be (because the ClusterRegistration does not ask for
+// short-lived tokens). A Secret that does not say when its token
+// expires is due for renewal right away.
+func ManifestRenewalTime(secret *corev1.Secret, ttl time.Duration) (time.Time, bool) {
+	if ttl == 0 {
+		return time.Time{}, false
+	}
+	expiry, err := time.Parse(time.RFC3339, secret.Annotations[ManifestExpiryAnnotationKey])
+	if err != nil {
+		return time.Time{}, true
+	}
+	return expiry.Add(-ttl / 3), true
+}
+
+// SyncerConnected tells whether the syncer has reported in the given SyncTarget.
+func SyncerConnected(st *edgeapi.SyncTarget) bool {
+	return st.Status.LastSyncerHeartbeatTime != nil || st.Status.ClusterID != ""
+}
+
+func condition(condType string, ok bool, reason, message string, generation int64) metav1.Condition {
+	status := metav1.ConditionFalse
+	if ok {
+		status = metav1.ConditionTrue
+	}
+	return metav1.Condition{Type: condType, Status: status, Reason: reason, Message: message, ObservedGeneration: generation}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+)
+
+func TestDesiredInventory(t *testing.T) {
+	spec := edgeapi.ClusterRegistrationSpec{Labels: map[string]string{"env": "prod", IDLabelKey: "spoofed"}}
+	owner := metav1.OwnerReference{Kind: "ClusterRegistration", Name: "edge1", UID: "u1"}
+	st := DesiredSyncTarget("edge1", spec, owner)
+	loc := DesiredLocation("edge1", spec, owner)
+	wantLabels := map[string]string{"env": "prod", IDLabelKey: "edge1"}
+	if diff := cmp.Diff(wantLabels, st.Labels); diff != "" {
+		t.Errorf("Wrong SyncTarget labels (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantLabels, loc.Labels); diff != "" {
+		t.Errorf("Wrong Location labels (-want +got):\n%s", diff)
+	}
+	sel, err := metav1.LabelSelectorAsSelector(loc.Spec.InstanceSelector)
+	if err != nil {
+		t.Fatalf("Bad instance selector: %v", err)
+	}
+	if !sel.Matches(labels.Set(st.Labels)) {
+		t.Errorf("Location selector %s does not match SyncTarget labels %v", sel, st.Labels)
+	}
+	if spec.Labels[IDLabelKey] != "spoofed" {
+		t.Error("The spec was modified")
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	got, changed := MergeLabels(map[string]string{"a": "1", "b": "2"}, map[string]string{"b": "2"})
+	if changed || len(got) != 2 {
+		t.Errorf("Unexpected change: %v", got)
+	}
+	got, changed = MergeLabels(map[string]string{"a": "1"}, map[string]string{"a": "3", "c": "4"})
+	if diff := cmp.Diff(map[string]string{"a": "3", "c": "4"}, got); !changed || diff != "" {
+		t.Errorf("Wrong merge, changed=%v (-want +got):\n%s", changed, diff)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	now := metav1.Now()
+	steps := []string{
+		edgeapi.ClusterRegistrationInventoryReady,
+		edgeapi.ClusterRegistrationMailboxReady,
+		edgeapi.ClusterRegistrationCredentialsIssued,
+		edgeapi.ClusterRegistrationSyncerConnected,
+	}
+	phases := []edgeapi.ClusterRegistrationPhase{
+		edgeapi.ClusterRegistrationPending,
+		edgeapi.ClusterRegistrationProvisioning,
+		edgeapi.ClusterRegistrationIssuingCredentials,
+		edgeapi.ClusterRegistrationAwaitingSyncer,
+		edgeapi.ClusterRegistrationJoined,
+	}
+	for done := 0; done <= len(steps); done++ {
+		status := &edgeapi.ClusterRegistrationStatus{}
+		for idx, step := range steps {
+			conditions.Set(&status.Conditions, condition(step, idx < done, "Test", "", 3), now)
+		}
+		Summarize(status, 3, now)
+		if status.Phase != phases[done] {
+			t.Errorf("With %d steps done expected phase %q, got %q", done, phases[done], status.Phase)
+		}
+		if status.ObservedGeneration != 3 {
+			t.Errorf("Wrong observedGeneration %d", status.ObservedGeneration)
+		}
+		if ready := conditions.IsTrue(status.Conditions, conditions.ReadyType); ready != (done == len(steps)) {
+			t.Errorf("With %d steps done got Ready=%v", done, ready)
+		}
+	}
+}
+
+func TestManifestRenewalTime(t *testing.T) {
+	owner := metav1.OwnerReference{Kind: "ClusterRegistration", Name: "edge1", UID: "u1"}
+	expiry := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	secret := ManifestSecret("default", "edge1-manifest", []byte("manifest"), owner, expiry)
+	if string(secret.Data[edgeapi.ClusterRegistrationManifestKey]) != "manifest" {
+		t.Errorf("Manifest missing from Secret %+v", secret)
+	}
+	renewAt, renew := ManifestRenewalTime(secret, 3*time.Hour)
+	if want := expiry.Add(-time.Hour); !renew || !renewAt.Equal(want) {
+		t.Errorf("Expected renewal at %v, got %v, %v", want, renewAt, renew)
+	}
+	if _, renew := ManifestRenewalTime(ManifestSecret("default", "edge1-manifest", nil, owner, time.Time{}), 0); renew {
+		t.Error("Expected no renewal for a long-lived token")
+	}
+	// A Secret issued before expiry was recorded is renewed right away.
+	renewAt, renew = ManifestRenewalTime(&corev1.Secret{}, 3*time.Hour)
+	if !renew || renewAt.After(time.Now()) {
+		t.Errorf("Expected immediate renewal, got %v, %v", renewAt, renew)
+	}
+}
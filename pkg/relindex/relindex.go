@@ -0,0 +1,462 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package relindex provides relations --- sets of tuples --- that are
+// indexed on every column and safe for concurrent use.
+//
+// A Relation2 is a set of pairs and a Relation3 is a set of triples.
+// Both can be queried by the value in any one column, and an ordered
+// relation (made by NewOrderedRelation2 or NewOrderedRelation3) can
+// also be queried by a range of values in its first column. Reads
+// share a sync.RWMutex; the query results are copies, and Visit
+// iterates over a snapshot, so callers may change a relation while
+// going through what they got from it.
+package relindex
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+type Empty struct{}
+
+type Pair[First, Second any] struct {
+	First  First
+	Second Second
+}
+
+type Triple[First, Second, Third any] struct {
+	First  First
+	Second Second
+	Third  Third
+}
+
+// Relation2 is a concurrency-safe set of pairs, indexed by both columns.
+// The zero value is not usable; use NewRelation2 or NewOrderedRelation2.
+type Relation2[First, Second comparable] struct {
+	mutex    sync.RWMutex
+	byFirst  map[First]map[Second]Empty
+	bySecond map[Second]map[First]Empty
+	size     int
+	// order is nil for an unordered relation
+	order *sortedKeys[First]
+}
+
+// NewRelation2 makes an empty, unordered Relation2.
+func NewRelation2[First, Second comparable]() *Relation2[First, Second] {
+	return &Relation2[First, Second]{
+		byFirst:  map[First]map[Second]Empty{},
+		bySecond: map[Second]map[First]Empty{},
+	}
+}
+
+// NewOrderedRelation2 makes an empty Relation2 whose first column is
+// ordered by the given function, which supports Range and makes
+// Snapshot and Visit go in that order.
+func NewOrderedRelation2[First, Second comparable](less func(First, First) bool) *Relation2[First, Second] {
+	rel := NewRelation2[First, Second]()
+	rel.order = &sortedKeys[First]{less: less}
+	return rel
+}
+
+// Add adds the given pair, returning whether it was not already present.
+func (rel *Relation2[First, Second]) Add(first First, second Second) bool {
+	rel.mutex.Lock()
+	defer rel.mutex.Unlock()
+	return rel.addLocked(first, second)
+}
+
+func (rel *Relation2[First, Second]) addLocked(first First, second Second) bool {
+	added, newFirst := addToIndex(rel.byFirst, first, second)
+	if !added {
+		return false
+	}
+	if newFirst && rel.order != nil {
+		rel.order.insert(first)
+	}
+	addToIndex(rel.bySecond, second, first)
+	rel.size++
+	return true
+}
+
+// Remove removes the given pair, returning whether it was present.
+func (rel *Relation2[First, Second]) Remove(first First, second Second) bool {
+	rel.mutex.Lock()
+	defer rel.mutex.Unlock()
+	return rel.removeLocked(first, second)
+}
+
+func (rel *Relation2[First, Second]) removeLocked(first First, second Second) bool {
+	removed, firstGone := removeFromIndex(rel.byFirst, first, second)
+	if !removed {
+		return false
+	}
+	if firstGone && rel.order != nil {
+		rel.order.remove(first)
+	}
+	removeFromIndex(rel.bySecond, second, first)
+	rel.size--
+	return true
+}
+
+// SetSecondsOf makes the given seconds be the ones paired with the given
+// first, and returns the ones that were added and removed.
+func (rel *Relation2[First, Second]) SetSecondsOf(first First, seconds []Second) (added, removed []Second) {
+	rel.mutex.Lock()
+	defer rel.mutex.Unlock()
+	wanted := make(map[Second]Empty, len(seconds))
+	for _, second := range seconds {
+		wanted[second] = Empty{}
+		if rel.addLocked(first, second) {
+			added = append(added, second)
+		}
+	}
+	for second := range rel.byFirst[first] {
+		if _, keep := wanted[second]; !keep {
+			rel.removeLocked(first, second)
+			removed = append(removed, second)
+		}
+	}
+	return
+}
+
+// RemoveFirst removes all the pairs with the given first, and returns
+// their seconds.
+func (rel *Relation2[First, Second]) RemoveFirst(first First) []Second {
+	_, removed := rel.SetSecondsOf(first, nil)
+	return removed
+}
+
+func (rel *Relation2[First, Second]) Has(first First, second Second) bool {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	_, has := rel.byFirst[first][second]
+	return has
+}
+
+func (rel *Relation2[First, Second]) Len() int {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	return rel.size
+}
+
+// SecondsOf returns the seconds paired with the given first.
+func (rel *Relation2[First, Second]) SecondsOf(first First) []Second {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	return keysOf(rel.byFirst[first])
+}
+
+// FirstsOf returns the firsts paired with the given second.
+func (rel *Relation2[First, Second]) FirstsOf(second Second) []First {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	return keysOf(rel.bySecond[second])
+}
+
+// Range returns the pairs whose first is at least low and less than
+// high, in order of first. Panics if the relation is not ordered.
+func (rel *Relation2[First, Second]) Range(low, high First) []Pair[First, Second] {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	if rel.order == nil {
+		panic("relindex: Range of an unordered Relation2")
+	}
+	var ans []Pair[First, Second]
+	for _, first := range rel.order.between(low, high) {
+		ans = rel.appendPairsLocked(ans, first)
+	}
+	return ans
+}
+
+// Snapshot returns all the pairs; in order of first if the relation is ordered.
+func (rel *Relation2[First, Second]) Snapshot() []Pair[First, Second] {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	ans := make([]Pair[First, Second], 0, rel.size)
+	if rel.order != nil {
+		for _, first := range rel.order.keys {
+			ans = rel.appendPairsLocked(ans, first)
+		}
+		return ans
+	}
+	for first := range rel.byFirst {
+		ans = rel.appendPairsLocked(ans, first)
+	}
+	return ans
+}
+
+func (rel *Relation2[First, Second]) appendPairsLocked(ans []Pair[First, Second], first First) []Pair[First, Second] {
+	for second := range rel.byFirst[first] {
+		ans = append(ans, Pair[First, Second]{first, second})
+	}
+	return ans
+}
+
+// Visit calls the given function on each pair of a Snapshot, stopping
+// early if the function returns false.
+func (rel *Relation2[First, Second]) Visit(visitor func(Pair[First, Second]) bool) {
+	for _, pair := range rel.Snapshot() {
+		if !visitor(pair) {
+			return
+		}
+	}
+}
+
+var _ json.Marshaler = &Relation2[int, string]{}
+
+// MarshalJSON renders the Snapshot, which makes a Relation2 readable in logs.
+func (rel *Relation2[First, Second]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rel.Snapshot())
+}
+
+// Relation3 is a concurrency-safe set of triples, indexed by each column.
+// The zero value is not usable; use NewRelation3 or NewOrderedRelation3.
+type Relation3[First, Second, Third comparable] struct {
+	mutex    sync.RWMutex
+	all      map[Triple[First, Second, Third]]Empty
+	byFirst  map[First]map[Triple[First, Second, Third]]Empty
+	bySecond map[Second]map[Triple[First, Second, Third]]Empty
+	byThird  map[Third]map[Triple[First, Second, Third]]Empty
+	// order is nil for an unordered relation
+	order *sortedKeys[First]
+}
+
+// NewRelation3 makes an empty, unordered Relation3.
+func NewRelation3[First, Second, Third comparable]() *Relation3[First, Second, Third] {
+	return &Relation3[First, Second, Third]{
+		all:      map[Triple[First, Second, Third]]Empty{},
+		byFirst:  map[First]map[Triple[First, Second, Third]]Empty{},
+		bySecond: map[Second]map[Triple[First, Second, Third]]Empty{},
+		byThird:  map[Third]map[Triple[First, Second, Third]]Empty{},
+	}
+}
+
+// NewOrderedRelation3 makes an empty Relation3 whose first column is
+// ordered by the given function; see NewOrderedRelation2.
+func NewOrderedRelation3[First, Second, Third comparable](less func(First, First) bool) *Relation3[First, Second, Third] {
+	rel := NewRelation3[First, Second, Third]()
+	rel.order = &sortedKeys[First]{less: less}
+	return rel
+}
+
+// Add adds the given triple, returning whether it was not already present.
+func (rel *Relation3[First, Second, Third]) Add(tup Triple[First, Second, Third]) bool {
+	rel.mutex.Lock()
+	defer rel.mutex.Unlock()
+	if _, has := rel.all[tup]; has {
+		return false
+	}
+	rel.all[tup] = Empty{}
+	if _, newFirst := addToIndex(rel.byFirst, tup.First, tup); newFirst && rel.order != nil {
+		rel.order.insert(tup.First)
+	}
+	addToIndex(rel.bySecond, tup.Second, tup)
+	addToIndex(rel.byThird, tup.Third, tup)
+	return true
+}
+
+// Remove removes the given triple, returning whether it was present.
+func (rel *Relation3[First, Second, Third]) Remove(tup Triple[First, Second, Third]) bool {
+	rel.mutex.Lock()
+	defer rel.mutex.Unlock()
+	return rel.removeLocked(tup)
+}
+
+func (rel *Relation3[First, Second, Third]) removeLocked(tup Triple[First, Second, Third]) bool {
+	if _, has := rel.all[tup]; !has {
+		return false
+	}
+	delete(rel.all, tup)
+	if _, firstGone := removeFromIndex(rel.byFirst, tup.First, tup); firstGone && rel.order != nil {
+		rel.order.remove(tup.First)
+	}
+	removeFromIndex(rel.bySecond, tup.Second, tup)
+	removeFromIndex(rel.byThird, tup.Third, tup)
+	return true
+}
+
+// RemoveFirst removes all the triples with the given first, and returns them.
+func (rel *Relation3[First, Second, Third]) RemoveFirst(first First) []Triple[First, Second, Third] {
+	rel.mutex.Lock()
+	defer rel.mutex.Unlock()
+	removed := keysOf(rel.byFirst[first])
+	for _, tup := range removed {
+		rel.removeLocked(tup)
+	}
+	return removed
+}
+
+func (rel *Relation3[First, Second, Third]) Has(tup Triple[First, Second, Third]) bool {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	_, has := rel.all[tup]
+	return has
+}
+
+func (rel *Relation3[First, Second, Third]) Len() int {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	return len(rel.all)
+}
+
+// WithFirst returns the triples that have the given first.
+func (rel *Relation3[First, Second, Third]) WithFirst(first First) []Triple[First, Second, Third] {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	return keysOf(rel.byFirst[first])
+}
+
+// WithSecond returns the triples that have the given second.
+func (rel *Relation3[First, Second, Third]) WithSecond(second Second) []Triple[First, Second, Third] {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	return keysOf(rel.bySecond[second])
+}
+
+// WithThird returns the triples that have the given third.
+func (rel *Relation3[First, Second, Third]) WithThird(third Third) []Triple[First, Second, Third] {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	return keysOf(rel.byThird[third])
+}
+
+// Range returns the triples whose first is at least low and less than
+// high, in order of first. Panics if the relation is not ordered.
+func (rel *Relation3[First, Second, Third]) Range(low, high First) []Triple[First, Second, Third] {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	if rel.order == nil {
+		panic("relindex: Range of an unordered Relation3")
+	}
+	var ans []Triple[First, Second, Third]
+	for _, first := range rel.order.between(low, high) {
+		for tup := range rel.byFirst[first] {
+			ans = append(ans, tup)
+		}
+	}
+	return ans
+}
+
+// Snapshot returns all the triples; in order of first if the relation is ordered.
+func (rel *Relation3[First, Second, Third]) Snapshot() []Triple[First, Second, Third] {
+	rel.mutex.RLock()
+	defer rel.mutex.RUnlock()
+	if rel.order == nil {
+		return keysOf(rel.all)
+	}
+	ans := make([]Triple[First, Second, Third], 0, len(rel.all))
+	for _, first := range rel.order.keys {
+		for tup := range rel.byFirst[first] {
+			ans = append(ans, tup)
+		}
+	}
+	return ans
+}
+
+// Visit calls the given function on each triple of a Snapshot, stopping
+// early if the function returns false.
+func (rel *Relation3[First, Second, Third]) Visit(visitor func(Triple[First, Second, Third]) bool) {
+	for _, tup := range rel.Snapshot() {
+		if !visitor(tup) {
+			return
+		}
+	}
+}
+
+var _ json.Marshaler = &Relation3[int, string, bool]{}
+
+// MarshalJSON renders the Snapshot, which makes a Relation3 readable in logs.
+func (rel *Relation3[First, Second, Third]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rel.Snapshot())
+}
+
+// addToIndex adds val to the set that index maps key to, and returns
+// whether it was added and whether the key is new to the index.
+func addToIndex[Key, Val comparable](index map[Key]map[Val]Empty, key Key, val Val) (added, newKey bool) {
+	vals, has := index[key]
+	if !has {
+		vals = map[Val]Empty{}
+		index[key] = vals
+	} else if _, had := vals[val]; had {
+		return false, false
+	}
+	vals[val] = Empty{}
+	return true, !has
+}
+
+// removeFromIndex removes val from the set that index maps key to, and
+// returns whether it was removed and whether the key is now gone from the index.
+func removeFromIndex[Key, Val comparable](index map[Key]map[Val]Empty, key Key, val Val) (removed, keyGone bool) {
+	vals := index[key]
+	if _, has := vals[val]; !has {
+		return false, false
+	}
+	delete(vals, val)
+	if len(vals) > 0 {
+		return true, false
+	}
+	delete(index, key)
+	return true, true
+}
+
+func keysOf[Key comparable](set map[Key]Empty) []Key {
+	if len(set) == 0 {
+		return nil
+	}
+	ans := make([]Key, 0, len(set))
+	for key := range set {
+		ans = append(ans, key)
+	}
+	return ans
+}
+
+// sortedKeys is a sorted slice of distinct keys.
+type sortedKeys[Key any] struct {
+	less func(Key, Key) bool
+	keys []Key
+}
+
+// search returns the index of the first key that is not less than the given one.
+func (sk *sortedKeys[Key]) search(key Key) int {
+	return sort.Search(len(sk.keys), func(idx int) bool { return !sk.less(sk.keys[idx], key) })
+}
+
+func (sk *sortedKeys[Key]) insert(key Key) {
+	idx := sk.search(key)
+	var zero Key
+	sk.keys = append(sk.keys, zero)
+	copy(sk.keys[idx+1:], sk.keys[idx:])
+	sk.keys[idx] = key
+}
+
+func (sk *sortedKeys[Key]) remove(key Key) {
+	idx := sk.search(key)
+	if idx < len(sk.keys) && !sk.less(key, sk.keys[idx]) {
+		sk.keys = append(sk.keys[:idx], sk.keys[idx+1:]...)
+	}
+}
+
+// between returns the keys that are at least low and less than high.
+// The result shares memory with sk.
+func (sk *sortedKeys[Key]) between(low, high Key) []Key {
+	start, end := sk.search(low), sk.search(high)
+	if end < start {
+		return nil
+	}
+	return sk.keys[start:end]
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package relindex
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func lessString(a, b string) bool { return a < b }
+
+func sorted[Elt any](elts []Elt) []Elt {
+	sort.Slice(elts, func(i, j int) bool { return fmt.Sprint(elts[i]) < fmt.Sprint(elts[j]) })
+	return elts
+}
+
+func TestRelation2(t *testing.T) {
+	rel := NewOrderedRelation2[string, int](lessString)
+	for _, pair := range []Pair[string, int]{{"b", 1}, {"a", 1}, {"a", 2}, {"c", 3}, {"d", 1}} {
+		if !rel.Add(pair.First, pair.Second) {
+			t.Errorf("Expected %v to be new", pair)
+		}
+	}
+	if rel.Add("a", 1) || rel.Len() != 5 || !rel.Has("c", 3) || rel.Has("c", 1) {
+		t.Fatalf("Wrong membership: %v", rel.Snapshot())
+	}
+	if got := sorted(rel.FirstsOf(1)); !reflect.DeepEqual(got, []string{"a", "b", "d"}) {
+		t.Errorf("Wrong FirstsOf(1): %v", got)
+	}
+	if got := sorted(rel.SecondsOf("a")); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("Wrong SecondsOf(a): %v", got)
+	}
+	if got := rel.Range("b", "d"); !reflect.DeepEqual(got, []Pair[string, int]{{"b", 1}, {"c", 3}}) {
+		t.Errorf("Wrong Range(b, d): %v", got)
+	}
+	added, removed := rel.SetSecondsOf("a", []int{2, 3})
+	if !reflect.DeepEqual(added, []int{3}) || !reflect.DeepEqual(removed, []int{1}) {
+		t.Errorf("Wrong SetSecondsOf changes: added %v, removed %v", added, removed)
+	}
+	if got := sorted(rel.FirstsOf(3)); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("Wrong FirstsOf(3): %v", got)
+	}
+	rel.RemoveFirst("c")
+	if !rel.Remove("b", 1) || rel.Remove("b", 1) {
+		t.Errorf("Wrong results from Remove")
+	}
+	// Visit goes over a snapshot, so the visitor can change the relation.
+	var visited []string
+	rel.Visit(func(pair Pair[string, int]) bool {
+		visited = append(visited, pair.First)
+		rel.Remove(pair.First, pair.Second)
+		return true
+	})
+	if !reflect.DeepEqual(visited, []string{"a", "a", "d"}) || rel.Len() != 0 || len(rel.Range("a", "z")) != 0 {
+		t.Errorf("Wrong visit %v leaving %v", visited, rel.Snapshot())
+	}
+}
+
+func TestRelation3(t *testing.T) {
+	rel := NewOrderedRelation3[string, int, bool](lessString)
+	tuples := []Triple[string, int, bool]{{"x", 1, true}, {"x", 2, false}, {"y", 1, false}, {"z", 3, true}}
+	for _, tup := range tuples {
+		rel.Add(tup)
+	}
+	if rel.Add(tuples[0]) || rel.Len() != 4 || !rel.Has(tuples[2]) {
+		t.Fatalf("Wrong membership: %v", rel.Snapshot())
+	}
+	if got := sorted(rel.WithSecond(1)); !reflect.DeepEqual(got, []Triple[string, int, bool]{tuples[0], tuples[2]}) {
+		t.Errorf("Wrong WithSecond(1): %v", got)
+	}
+	if got := sorted(rel.WithThird(false)); !reflect.DeepEqual(got, []Triple[string, int, bool]{tuples[1], tuples[2]}) {
+		t.Errorf("Wrong WithThird(false): %v", got)
+	}
+	if got := rel.Range("y", "zz"); !reflect.DeepEqual(got, tuples[2:]) {
+		t.Errorf("Wrong Range(y, zz): %v", got)
+	}
+	if removed := rel.RemoveFirst("x"); len(removed) != 2 || len(rel.WithSecond(2)) != 0 || len(rel.WithFirst("x")) != 0 {
+		t.Errorf("Wrong RemoveFirst: removed %v, leaving %v", removed, rel.Snapshot())
+	}
+	if !reflect.DeepEqual(rel.Snapshot(), tuples[2:]) {
+		t.Errorf("Wrong Snapshot: %v", rel.Snapshot())
+	}
+	if data, err := rel.MarshalJSON(); err != nil || string(data) != `[{"First":"y","Second":1,"Third":false},{"First":"z","Second":3,"Third":true}]` {
+		t.Errorf("Wrong JSON %s, err=%v", data, err)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	rel := NewRelation2[int, int]()
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for idx := 0; idx < 1000; idx++ {
+				rel.Add(worker, idx)
+				rel.SecondsOf(worker)
+				if idx%2 == 1 {
+					rel.Remove(worker, idx)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+	if rel.Len() != 8*500 || len(rel.FirstsOf(0)) != 8 {
+		t.Errorf("Expected 4000 pairs, got %d", rel.Len())
+	}
+}
+
+func BenchmarkRelation2Add(b *testing.B) {
+	rel := NewRelation2[int, int]()
+	for idx := 0; idx < b.N; idx++ {
+		rel.Add(idx%1000, idx)
+	}
+}
+
+func BenchmarkOrderedRelation2Add(b *testing.B) {
+	rel := NewOrderedRelation2[int, int](func(x, y int) bool { return x < y })
+	for idx := 0; idx < b.N; idx++ {
+		rel.Add(idx%1000, idx)
+	}
+}
+
+func BenchmarkRelation2FirstsOfParallel(b *testing.B) {
+	rel := NewRelation2[int, int]()
+	for idx := 0; idx < 10000; idx++ {
+		rel.Add(idx, idx%100)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		idx := 0
+		for pb.Next() {
+			rel.FirstsOf(idx % 100)
+			idx++
+		}
+	})
+}
+
+func BenchmarkRelation3WithSecond(b *testing.B) {
+	rel := NewRelation3[int, int, int]()
+	for idx := 0; idx < 10000; idx++ {
+		rel.Add(Triple[int, int, int]{idx, idx % 100, idx % 7})
+	}
+	b.ResetTimer()
+	for idx := 0; idx < b.N; idx++ {
+		rel.WithSecond(idx % 100)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package render computes, without a live hub, the manifests that an
+// EdgePlacement would downsync to each of its destinations.
+// This lets KubeStellar serve as a fleet templating engine in CI.
+package render
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	machjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/customize"
+	"github.com/kubestellar/kubestellar/pkg/placement"
+)
+
+// Input is everything that the rendering needs to know about the
+// workload description space and the inventory.
+type Input struct {
+	Placement   *edgeapi.EdgePlacement
+	Locations   []*edgeapi.Location
+	Customizers []*edgeapi.Customizer
+
+	// Objects are the workload objects that are candidates for downsync.
+	// Namespace objects among them supply the labels used to evaluate
+	// `namespaceSelectors`.
+	Objects []*unstructured.Unstructured
+}
+
+// Rendered is the result for one destination.
+type Rendered struct {
+	Location *edgeapi.Location
+	Objects  []*unstructured.Unstructured
+}
+
+// Render returns the transformed objects for every Location selected by the
+// EdgePlacement, or only for the Location with the given name if that is not empty.
+// The results are sorted by Location name and, within a Location,
+// by (apiVersion, kind, namespace, name).
+func Render(logger klog.Logger, input Input, locationName string) ([]Rendered, error) {
+	if input.Placement == nil {
+		return nil, fmt.Errorf("no EdgePlacement given")
+	}
+	nsLabels := map[string]map[string]string{}
+	for _, obj := range input.Objects {
+		if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Namespace" {
+			nsLabels[obj.GetName()] = obj.GetLabels()
+		}
+	}
+	customizers := map[string]*edgeapi.Customizer{}
+	for _, cust := range input.Customizers {
+		customizers[cust.Namespace+"/"+cust.Name] = cust
+	}
+	var selected []*unstructured.Unstructured
+	for _, obj := range input.Objects {
+		if placement.ObjectIsSystem(obj) {
+			continue
+		}
+		if ObjectMatches(logger, &input.Placement.Spec, obj, nsLabels) {
+			selected = append(selected, obj)
+		}
+	}
+	var ans []Rendered
+	found := false
+	for _, loc := range input.Locations {
+		if locationName != "" && loc.Name != locationName {
+			continue
+		}
+		found = true
+		if !labelsMatchAny(logger, loc.Labels, input.Placement.Spec.LocationSelectors) {
+			continue
+		}
+		rendered := Rendered{Location: loc}
+		for _, obj := range selected {
+			destObj, err := transform(logger, obj, customizers, loc)
+			if err != nil {
+				return nil, err
+			}
+			rendered.Objects = append(rendered.Objects, destObj)
+		}
+		sort.Slice(rendered.Objects, func(i, j int) bool {
+			return objectSortKey(rendered.Objects[i]) < objectSortKey(rendered.Objects[j])
+		})
+		ans = append(ans, rendered)
+	}
+	if locationName != "" && !found {
+		return nil, fmt.Errorf("no Location named %q", locationName)
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Location.Name < ans[j].Location.Name })
+	return ans, nil
+}
+
+// ObjectMatches tests the given object against the downsync and upsync
+// parts of the given spec, using the given map from namespace name to labels
+// to evaluate `namespaceSelectors`.
+// An object that matches the upsync part does not match.
+func ObjectMatches(logger klog.Logger, spec *edgeapi.EdgePlacementSpec, obj *unstructured.Unstructured, nsLabels map[string]map[string]string) bool {
+	gvk := obj.GroupVersionKind()
+	resource := meta.UnsafeGuessKindToResource(gvk).Resource
+	objNS := obj.GetNamespace()
+	objName := obj.GetName()
+	downsync := false
+	for _, objTest := range spec.Downsync {
+		if objTest.APIGroup != nil && (*objTest.APIGroup) != gvk.Group {
+			continue
+		}
+		if !listMatches(objTest.Resources, resource) || !listMatches(objTest.Namespaces, objNS) {
+			continue
+		}
+		if len(objTest.NamespaceSelectors) > 0 {
+			theseLabels := map[string]string{}
+			if objNS != "" {
+				theseLabels = nsLabels[objNS]
+			}
+			if !labelsMatchAny(logger, theseLabels, objTest.NamespaceSelectors) {
+				continue
+			}
+		}
+		if !listMatches(objTest.ObjectNames, objName) {
+			continue
+		}
+		if len(objTest.LabelSelectors) > 0 && !labelsMatchAny(logger, obj.GetLabels(), objTest.LabelSelectors) {
+			continue
+		}
+		downsync = true
+		break
+	}
+	if !downsync {
+		return false
+	}
+	for _, upsync := range spec.Upsync {
+		if upsync.APIGroup == gvk.Group && nonEmptyListMatches(upsync.Resources, resource) &&
+			(objNS == "" || nonEmptyListMatches(upsync.Namespaces, objNS)) &&
+			nonEmptyListMatches(upsync.Names, objName) {
+			return false
+		}
+	}
+	return true
+}
+
+// listMatches implements the DownsyncObjectTest convention that an empty list matches everything.
+func listMatches(list []string, val string) bool {
+	return len(list) == 0 || placement.SliceContains(list, "*") || placement.SliceContains(list, val)
+}
+
+// nonEmptyListMatches implements the UpsyncSet convention that an empty list matches nothing.
+func nonEmptyListMatches(list []string, val string) bool {
+	return placement.SliceContains(list, "*") || placement.SliceContains(list, val)
+}
+
+func labelsMatchAny(logger klog.Logger, labelSet map[string]string, selectors []metav1.LabelSelector) bool {
+	for _, ls := range selectors {
+		sel, err := metav1.LabelSelectorAsSelector(&ls)
+		if err != nil {
+			logger.Info("Failed to convert LabelSelector to labels.Selector", "ls", ls, "err", err)
+			continue
+		}
+		if sel.Matches(labels.Set(labelSet)) {
+			return true
+		}
+	}
+	return false
+}
+
+func transform(logger klog.Logger, srcObj *unstructured.Unstructured, customizers map[string]*edgeapi.Customizer, loc *edgeapi.Location) (*unstructured.Unstructured, error) {
+	var customizer *edgeapi.Customizer
+	if customizerRef := srcObj.GetAnnotations()[edgeapi.CustomizerAnnotationKey]; customizerRef != "" {
+		refParts := strings.SplitN(customizerRef, "/", 2)
+		if len(refParts) == 1 {
+			refParts = []string{srcObj.GetNamespace(), customizerRef}
+		}
+		customizer = customizers[refParts[0]+"/"+refParts[1]]
+		if customizer == nil {
+			return nil, fmt.Errorf("object %s %s/%s references missing Customizer %q", srcObj.GroupVersionKind(), srcObj.GetNamespace(), srcObj.GetName(), customizerRef)
+		}
+	}
+	destObj := customize.Customize(logger, srcObj, customizer, loc)
+	if destObj == srcObj {
+		destObj = srcObj.DeepCopy()
+	}
+	placement.StripForDestination(destObj)
+	return destObj, nil
+}
+
+func objectSortKey(obj *unstructured.Unstructured) string {
+	return strings.Join([]string{obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName()}, "\x00")
+}
+
+// WriteYAML writes the given rendered objects as a multi-document YAML stream.
+// Each document is preceded by a comment naming its destination Location.
+func WriteYAML(dest io.Writer, rendered []Rendered) error {
+	ser := machjson.NewYAMLSerializer(machjson.DefaultMetaFactory, nil, nil)
+	for _, destination := range rendered {
+		for _, obj := range destination.Objects {
+			if _, err := fmt.Fprintf(dest, "---\n# location: %s\n", destination.Location.Name); err != nil {
+				return err
+			}
+			if err := ser.Encode(obj, dest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/placement"
+)
+
+func TestRender(t *testing.T) {
+	logger := klog.Background()
+	ep := &edgeapi.EdgePlacement{
+		ObjectMeta: metav1.ObjectMeta{Name: "ep1"},
+		Spec: edgeapi.EdgePlacementSpec{
+			LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"env": "prod"}}},
+			Downsync: []edgeapi.DownsyncObjectTest{{
+				Resources:  []string{"configmaps"},
+				Namespaces: []string{"ns1"},
+			}},
+		},
+	}
+	loc1 := &edgeapi.Location{ObjectMeta: metav1.ObjectMeta{Name: "loc1", Labels: map[string]string{"env": "prod", "region": "east"}}}
+	loc2 := &edgeapi.Location{ObjectMeta: metav1.ObjectMeta{Name: "loc2", Labels: map[string]string{"env": "test"}}}
+	cm := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":            "cm1",
+			"namespace":       "ns1",
+			"uid":             "1234",
+			"resourceVersion": "5",
+			"annotations":     map[string]any{edgeapi.ParameterExpansionAnnotationKey: "true"},
+		},
+		"data": map[string]any{"region": "%(region)"},
+	}}
+	other := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": "cm2", "namespace": "ns2"},
+	}}
+	input := Input{
+		Placement: ep,
+		Locations: []*edgeapi.Location{loc2, loc1},
+		Objects:   []*unstructured.Unstructured{other, cm},
+	}
+	rendered, err := Render(logger, input, "")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(rendered) != 1 || rendered[0].Location.Name != "loc1" {
+		t.Fatalf("Expected exactly loc1 to be selected, got %#v", rendered)
+	}
+	if len(rendered[0].Objects) != 1 {
+		t.Fatalf("Expected exactly 1 object, got %d", len(rendered[0].Objects))
+	}
+	obj := rendered[0].Objects[0]
+	if actual, _, _ := unstructured.NestedString(obj.Object, "data", "region"); actual != "east" {
+		t.Errorf("Expected parameter expansion to yield %q, got %q", "east", actual)
+	}
+	if obj.GetUID() != "" || obj.GetResourceVersion() != "" {
+		t.Errorf("Expected UID and resourceVersion to be stripped, got %q and %q", obj.GetUID(), obj.GetResourceVersion())
+	}
+	if obj.GetLabels()[placement.ProjectedLabelKey] != placement.ProjectedLabelVal {
+		t.Errorf("Expected projected label, got labels %v", obj.GetLabels())
+	}
+	if cm.GetUID() != "1234" {
+		t.Errorf("Input object was modified")
+	}
+	var buf bytes.Buffer
+	if err := WriteYAML(&buf, rendered); err != nil {
+		t.Fatalf("WriteYAML failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "# location: loc1") || !strings.Contains(buf.String(), "region: east") {
+		t.Errorf("Unexpected YAML output:\n%s", buf.String())
+	}
+	if _, err := Render(logger, input, "nosuch"); err == nil {
+		t.Errorf("Expected error for missing Location")
+	}
+}
@@ -0,0 +1,396 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package revisions keeps the recent revisions of the objects downsynced
+// to each destination, for rollback, diff-to-previous, and audit.
+//
+// The history is kept in the API, in ConfigMaps in one namespace of the
+// mailbox space of the destination, so that it survives restarts and
+// rescheduling of the syncer. Each object with revisions has its own
+// ConfigMap, labeled with HistoryLabelKey and annotated with the
+// reference to the object. Its `index` data item lists the hashes of the
+// object's last few revisions, newest first. Its binary data holds the
+// gzipped JSON content of each of those revisions, keyed by the SHA-256
+// hash of that content.
+//
+// The revisions are what was downsynced. The source of truth is the
+// workload description space, so a rollback writes a revision there
+// (see RollbackContent) and the change flows down as usual.
+package revisions
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// DefaultLimit is the number of revisions kept per object by default.
+const DefaultLimit = 10
+
+// DefaultNamespace is the usual namespace, in the mailbox space, of the history.
+const DefaultNamespace = "kubestellar-revisions"
+
+// HistoryLabelKey is the label, with value "true", on each history ConfigMap.
+const HistoryLabelKey = "edge.kubestellar.io/revision-history"
+
+// RefAnnotationKey is the annotation on a history ConfigMap that holds
+// the JSON form of the ObjectRef of the object.
+const RefAnnotationKey = "edge.kubestellar.io/revisions-of"
+
+// indexKey is the data item of a history ConfigMap that holds the index.
+const indexKey = "index"
+
+// maxConfigMapBytes bounds the content of a history ConfigMap, leaving
+// room under the apiserver's limit of 1 MiB; the oldest revisions are
+// dropped to stay within it.
+const maxConfigMapBytes = 900 * 1024
+
+// ObjectRef identifies a downsynced object at a destination.
+type ObjectRef struct {
+	Destination string `json:"destination"`
+	Group       string `json:"group"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+}
+
+func (ref ObjectRef) String() string {
+	gk := ref.Kind
+	if ref.Group != "" {
+		gk += "." + ref.Group
+	}
+	if ref.Namespace == "" {
+		return fmt.Sprintf("%s: %s/%s", ref.Destination, gk, ref.Name)
+	}
+	return fmt.Sprintf("%s: %s %s/%s", ref.Destination, gk, ref.Namespace, ref.Name)
+}
+
+// GroupKind returns the API group and kind of the object.
+func (ref ObjectRef) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: ref.Group, Kind: ref.Kind}
+}
+
+// RefTo returns the reference to the given object at the given destination.
+func RefTo(destination string, obj *unstructured.Unstructured) ObjectRef {
+	return ObjectRef{Destination: destination, Group: obj.GroupVersionKind().Group, Kind: obj.GetKind(),
+		Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
+
+// Entry is one revision of an object.
+type Entry struct {
+	Hash string      `json:"hash"`
+	Time metav1.Time `json:"time"`
+}
+
+// Store is the revision history in one namespace.
+type Store struct {
+	client    corev1client.CoreV1Interface
+	namespace string
+	limit     int
+
+	mutex sync.Mutex
+	// latest caches the hash of the newest revision of each object, to make
+	// recording an unchanged object cheap.
+	latest map[ObjectRef]string
+}
+
+// Open returns the Store in the given namespace.
+// Each object keeps at most limit revisions; non-positive means DefaultLimit.
+func Open(client corev1client.CoreV1Interface, namespace string, limit int) *Store {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &Store{client: client, namespace: namespace, limit: limit, latest: map[ObjectRef]string{}}
+}
+
+// EnsureNamespace creates the namespace of the Store if it does not exist.
+func (store *Store) EnsureNamespace(ctx context.Context) error {
+	_, err := store.client.Namespaces().Get(ctx, store.namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = store.client.Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: store.namespace}}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			err = nil
+		}
+	}
+	return err
+}
+
+// Normalize returns a copy of the given object without the fields that the
+// server maintains, which are not part of a revision.
+func Normalize(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	ans := obj.DeepCopy()
+	for _, field := range []string{"resourceVersion", "uid", "generation", "creationTimestamp", "managedFields", "selfLink"} {
+		unstructured.RemoveNestedField(ans.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(ans.Object, "status")
+	return ans
+}
+
+// Record adds the normalized content of the given object as the newest
+// revision, unless it is the same as the newest revision already.
+// It returns the hash of the content and whether a revision was added.
+func (store *Store) Record(ctx context.Context, ref ObjectRef, obj *unstructured.Unstructured, now time.Time) (string, bool, error) {
+	content, err := json.Marshal(Normalize(obj).Object)
+	if err != nil {
+		return "", false, err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.latest[ref] == hash {
+		return hash, false, nil
+	}
+	blob, err := compress(content)
+	if err != nil {
+		return "", false, err
+	}
+	configMaps := store.client.ConfigMaps(store.namespace)
+	added := false
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, ConfigMapName(ref), metav1.GetOptions{})
+		create := apierrors.IsNotFound(err)
+		if create {
+			cm, err = store.newConfigMap(ref)
+		}
+		if err != nil {
+			return err
+		}
+		entries, err := readIndex(cm)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 && entries[0].Hash == hash {
+			added = false
+			return nil
+		}
+		entries = append([]Entry{{Hash: hash, Time: metav1.NewTime(now)}}, entries...)
+		if cm.BinaryData == nil {
+			cm.BinaryData = map[string][]byte{}
+		}
+		cm.BinaryData[hash] = blob
+		if err := setIndex(cm, entries, store.limit); err != nil {
+			return err
+		}
+		if create {
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Someone else created it meanwhile; start over.
+				return apierrors.NewConflict(corev1.Resource("configmaps"), cm.Name, err)
+			}
+		} else {
+			_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		}
+		added = err == nil
+		return err
+	})
+	if err != nil {
+		return "", false, err
+	}
+	store.latest[ref] = hash
+	return hash, added, nil
+}
+
+// List returns the revisions of the given object, newest first.
+func (store *Store) List(ctx context.Context, ref ObjectRef) ([]Entry, error) {
+	cm, err := store.client.ConfigMaps(store.namespace).Get(ctx, ConfigMapName(ref), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readIndex(cm)
+}
+
+// Get returns the revision of the given object with the given hash.
+// A unique prefix of the hash, of at least 7 characters, is also accepted.
+func (store *Store) Get(ctx context.Context, ref ObjectRef, hash string) (*unstructured.Unstructured, error) {
+	if len(hash) < 7 {
+		return nil, fmt.Errorf("revision hash %q is too short", hash)
+	}
+	cm, err := store.client.ConfigMaps(store.namespace).Get(ctx, ConfigMapName(ref), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("no revisions of %s", ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for key := range cm.BinaryData {
+		if strings.HasPrefix(key, hash) {
+			matches = append(matches, key)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no revision %s of %s", hash, ref)
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("revision hash prefix %q is ambiguous", hash)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(cm.BinaryData[matches[0]]))
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(content); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Refs returns the objects that have revisions, sorted.
+func (store *Store) Refs(ctx context.Context) ([]ObjectRef, error) {
+	cms, err := store.client.ConfigMaps(store.namespace).List(ctx, metav1.ListOptions{LabelSelector: HistoryLabelKey + "=true"})
+	if err != nil {
+		return nil, err
+	}
+	refs := []ObjectRef{}
+	for _, cm := range cms.Items {
+		var ref ObjectRef
+		if err := json.Unmarshal([]byte(cm.Annotations[RefAnnotationKey]), &ref); err != nil {
+			return nil, fmt.Errorf("malformed %s annotation on ConfigMap %s/%s: %w", RefAnnotationKey, cm.Namespace, cm.Name, err)
+		}
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+	return refs, nil
+}
+
+// Diff returns a human-readable description of the differences from the
+// old to the new content, or the empty string if there are none.
+func Diff(old, new *unstructured.Unstructured) string {
+	return cmp.Diff(old.Object, new.Object)
+}
+
+// RollbackContent returns what to write to the workload description space
+// to roll the given current object back to the given revision: the
+// content of the revision with the metadata and status of the current object.
+func RollbackContent(current, revision *unstructured.Unstructured) *unstructured.Unstructured {
+	ans := revision.DeepCopy()
+	ans.Object["metadata"] = current.DeepCopy().Object["metadata"]
+	if status, found := current.Object["status"]; found {
+		ans.Object["status"] = runtime.DeepCopyJSONValue(status)
+	} else {
+		delete(ans.Object, "status")
+	}
+	return ans
+}
+
+// ConfigMapName returns the name of the ConfigMap that holds the
+// revisions of the given object.
+func ConfigMapName(ref ObjectRef) string {
+	sum := sha256.Sum256([]byte(ref.String()))
+	return "revisions-" + hex.EncodeToString(sum[:])[:40]
+}
+
+func (store *Store) newConfigMap(ref ObjectRef) (*corev1.ConfigMap, error) {
+	refJSON, err := json.Marshal(ref)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   store.namespace,
+			Name:        ConfigMapName(ref),
+			Labels:      map[string]string{HistoryLabelKey: "true"},
+			Annotations: map[string]string{RefAnnotationKey: string(refJSON)},
+		},
+	}, nil
+}
+
+func readIndex(cm *corev1.ConfigMap) ([]Entry, error) {
+	entries := []Entry{}
+	data, found := cm.Data[indexKey]
+	if !found {
+		return entries, nil
+	}
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, fmt.Errorf("malformed index in ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+	return entries, nil
+}
+
+// setIndex writes the given entries, newest first, as the index of the
+// given ConfigMap, dropping the oldest ones beyond the limit or the size
+// bound, and drops the content that the index no longer refers to.
+func setIndex(cm *corev1.ConfigMap, entries []Entry, limit int) error {
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	for {
+		inIndex := map[string]bool{}
+		size := 0
+		for _, entry := range entries {
+			inIndex[entry.Hash] = true
+			size += len(cm.BinaryData[entry.Hash])
+		}
+		for key := range cm.BinaryData {
+			if !inIndex[key] {
+				delete(cm.BinaryData, key)
+			}
+		}
+		if size <= maxConfigMapBytes || len(entries) == 1 {
+			break
+		}
+		entries = entries[:len(entries)-1]
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[indexKey] = string(data)
+	return nil
+}
+
+func compress(content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(content); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revisions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func configMap(value string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"namespace":       "ns",
+			"name":            "cm",
+			"resourceVersion": value, // differs even when the content does not
+		},
+		"data": map[string]interface{}{"key": value},
+	}}
+	return obj
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	store := Open(client.CoreV1(), DefaultNamespace, 2)
+	if err := store.EnsureNamespace(ctx); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	ref := RefTo("edge1", configMap("a"))
+	hashA, added, err := store.Record(ctx, ref, configMap("a"), now)
+	if err != nil || !added {
+		t.Fatalf("first record: added=%v err=%v", added, err)
+	}
+	unchanged := configMap("a")
+	unchanged.SetResourceVersion("other")
+	if hash, added, err := store.Record(ctx, ref, unchanged, now); err != nil || added || hash != hashA {
+		t.Fatalf("recording unchanged content: hash=%s added=%v err=%v", hash, added, err)
+	}
+	hashB, _, _ := store.Record(ctx, ref, configMap("b"), now.Add(time.Minute))
+	hashC, _, _ := store.Record(ctx, ref, configMap("c"), now.Add(2*time.Minute))
+
+	entries, err := store.List(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Hash != hashC || entries[1].Hash != hashB {
+		t.Fatalf("expected the newest two revisions, got %v", entries)
+	}
+
+	// The history is in the API, so a new Store (e.g. after a restart) sees it.
+	store = Open(client.CoreV1(), DefaultNamespace, 2)
+	refs, err := store.Refs(ctx)
+	if err != nil || len(refs) != 1 || refs[0] != ref {
+		t.Fatalf("unexpected refs %v, err=%v", refs, err)
+	}
+	if _, added, err := store.Record(ctx, ref, configMap("c"), now.Add(3*time.Minute)); err != nil || added {
+		t.Fatalf("recording the latest content after reopening: added=%v err=%v", added, err)
+	}
+
+	obj, err := store.Get(ctx, ref, hashB[:7])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _, _ := unstructured.NestedString(obj.Object, "data", "key"); value != "b" {
+		t.Errorf("expected revision b, got %v", obj.Object)
+	}
+	if _, found, _ := unstructured.NestedString(obj.Object, "metadata", "resourceVersion"); found {
+		t.Errorf("resourceVersion was not normalized away")
+	}
+	if _, err := store.Get(ctx, ref, hashB[:6]); err == nil {
+		t.Errorf("expected a too-short prefix to be rejected")
+	}
+	// The content of revisions dropped from the index is dropped too.
+	if _, err := store.Get(ctx, ref, hashA); err == nil {
+		t.Errorf("revision a survived")
+	}
+	cm, err := client.CoreV1().ConfigMaps(DefaultNamespace).Get(ctx, ConfigMapName(ref), metav1.GetOptions{})
+	if err != nil || len(cm.BinaryData) != 2 {
+		t.Errorf("expected the content of two revisions in the ConfigMap, got %v, %v", cm, err)
+	}
+
+	latest, _ := store.Get(ctx, ref, hashC)
+	if Diff(obj, latest) == "" || Diff(latest, latest) != "" {
+		t.Errorf("unexpected diffs")
+	}
+}
+
+func TestRollbackContent(t *testing.T) {
+	current := configMap("c")
+	current.SetResourceVersion("42")
+	current.SetLabels(map[string]string{"app": "shop"})
+	revision := Normalize(configMap("b"))
+	revision.SetLabels(map[string]string{"app": "old"})
+	ans := RollbackContent(current, revision)
+	if value, _, _ := unstructured.NestedString(ans.Object, "data", "key"); value != "b" {
+		t.Errorf("expected the content of the revision, got %v", ans.Object)
+	}
+	if ans.GetResourceVersion() != "42" || ans.GetLabels()["app"] != "shop" {
+		t.Errorf("expected the metadata of the current object, got %v", ans.Object["metadata"])
+	}
+	if current.GetResourceVersion() != "42" || revision.GetLabels()["app"] != "old" {
+		t.Error("the inputs were modified")
+	}
+}
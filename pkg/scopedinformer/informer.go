@@ -0,0 +1,357 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scopedinformer maintains informers on a changing set of spaces
+// behind one indexer, so that a controller need not watch through the
+// wildcard cluster. Each space's informer uses that space's own client,
+// so the controller needs access only to the spaces it actually serves.
+package scopedinformer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	upstreamcache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// SpaceIndexName is the name of the index, present in every
+// MultiSpaceInformer's indexer, that maps a space name to the
+// objects from that space.
+const SpaceIndexName = "space"
+
+// keySeparator separates the space from the object key in the keys
+// of the unified indexer. It can not appear in a space name.
+const keySeparator = "|"
+
+// InformerFunc makes the informer for the given space.
+// The returned informer must not have been started yet.
+type InformerFunc func(space string) (upstreamcache.SharedIndexInformer, error)
+
+// SpaceObject is what the unified indexer holds: an object from
+// one of the per-space informers, together with the space it came from.
+type SpaceObject struct {
+	Space  string
+	Object any
+}
+
+// SpaceEventHandler is notified of changes in the unified view.
+// Notifications are delivered one at a time, and a handler must not
+// call back into the MultiSpaceInformer that is notifying it.
+type SpaceEventHandler interface {
+	OnAdd(space string, obj any)
+	OnUpdate(space string, oldObj, newObj any)
+	OnDelete(space string, obj any)
+}
+
+// SpaceEventHandlerFuncs is a convenient implementation of SpaceEventHandler.
+// Nil fields mean no-op.
+type SpaceEventHandlerFuncs struct {
+	AddFunc    func(space string, obj any)
+	UpdateFunc func(space string, oldObj, newObj any)
+	DeleteFunc func(space string, obj any)
+}
+
+var _ SpaceEventHandler = SpaceEventHandlerFuncs{}
+
+func (funcs SpaceEventHandlerFuncs) OnAdd(space string, obj any) {
+	if funcs.AddFunc != nil {
+		funcs.AddFunc(space, obj)
+	}
+}
+
+func (funcs SpaceEventHandlerFuncs) OnUpdate(space string, oldObj, newObj any) {
+	if funcs.UpdateFunc != nil {
+		funcs.UpdateFunc(space, oldObj, newObj)
+	}
+}
+
+func (funcs SpaceEventHandlerFuncs) OnDelete(space string, obj any) {
+	if funcs.DeleteFunc != nil {
+		funcs.DeleteFunc(space, obj)
+	}
+}
+
+// MultiSpaceInformer runs one informer per space in a dynamically
+// maintained set of spaces and merges their contents into one indexer.
+// When a space is removed from the set, its informer is stopped and
+// its objects are deleted from the indexer (with OnDelete notifications).
+type MultiSpaceInformer struct {
+	ctx         context.Context
+	newInformer InformerFunc
+	indexer     upstreamcache.Indexer
+
+	mutex    sync.Mutex
+	spaces   map[string]*perSpace
+	handlers []SpaceEventHandler
+}
+
+type perSpace struct {
+	msi      *MultiSpaceInformer
+	space    string
+	informer upstreamcache.SharedIndexInformer
+	cancel   context.CancelFunc
+
+	// removed is set, while holding msi.mutex, when this space is
+	// removed; later events from the stopping informer are ignored.
+	removed bool
+}
+
+// NewMultiSpaceInformer makes a MultiSpaceInformer with no spaces.
+// The given indexers are applied to the objects from the per-space
+// informers; SpaceIndexName is added automatically.
+// Informers started by this MultiSpaceInformer stop when ctx is done.
+func NewMultiSpaceInformer(ctx context.Context, newInformer InformerFunc, indexers upstreamcache.Indexers) *MultiSpaceInformer {
+	allIndexers := upstreamcache.Indexers{SpaceIndexName: indexBySpace}
+	for name, indexFunc := range indexers {
+		allIndexers[name] = liftIndexFunc(indexFunc)
+	}
+	return &MultiSpaceInformer{
+		ctx:         ctx,
+		newInformer: newInformer,
+		indexer:     upstreamcache.NewIndexer(keyFunc, allIndexers),
+		spaces:      map[string]*perSpace{},
+	}
+}
+
+// AddEventHandler adds a handler. It is first told about the objects
+// already in the indexer.
+func (msi *MultiSpaceInformer) AddEventHandler(handler SpaceEventHandler) {
+	msi.mutex.Lock()
+	defer msi.mutex.Unlock()
+	msi.handlers = append(msi.handlers, handler)
+	for _, obj := range msi.indexer.List() {
+		sobj := obj.(*SpaceObject)
+		handler.OnAdd(sobj.Space, sobj.Object)
+	}
+}
+
+// GetIndexer returns the unified indexer. Its objects are *SpaceObject.
+// The indexer is maintained by the MultiSpaceInformer; do not modify it.
+func (msi *MultiSpaceInformer) GetIndexer() upstreamcache.Indexer {
+	return msi.indexer
+}
+
+// Get returns the object with the given namespace and name in the given space.
+func (msi *MultiSpaceInformer) Get(space, namespace, name string) (any, bool) {
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	obj, exists, _ := msi.indexer.GetByKey(space + keySeparator + key)
+	if !exists {
+		return nil, false
+	}
+	return obj.(*SpaceObject).Object, true
+}
+
+// ListSpace returns the objects from the given space.
+func (msi *MultiSpaceInformer) ListSpace(space string) []any {
+	objs, _ := msi.indexer.ByIndex(SpaceIndexName, space)
+	ans := make([]any, 0, len(objs))
+	for _, obj := range objs {
+		ans = append(ans, obj.(*SpaceObject).Object)
+	}
+	return ans
+}
+
+// Spaces returns the current set of spaces.
+func (msi *MultiSpaceInformer) Spaces() sets.String {
+	msi.mutex.Lock()
+	defer msi.mutex.Unlock()
+	ans := sets.NewString()
+	for space := range msi.spaces {
+		ans.Insert(space)
+	}
+	return ans
+}
+
+// HasSynced tells whether the informer of every current space has synced.
+func (msi *MultiSpaceInformer) HasSynced() bool {
+	msi.mutex.Lock()
+	defer msi.mutex.Unlock()
+	for _, ps := range msi.spaces {
+		if !ps.informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// SetSpaces makes the set of spaces equal to the given set, starting
+// and stopping per-space informers as needed.
+// Spaces whose informer could not be made are left out; their errors
+// are combined into the returned error, and a later call will retry them.
+func (msi *MultiSpaceInformer) SetSpaces(spaces sets.String) error {
+	msi.mutex.Lock()
+	defer msi.mutex.Unlock()
+	for space := range msi.spaces {
+		if !spaces.Has(space) {
+			msi.removeSpaceLocked(space)
+		}
+	}
+	var errs []string
+	for _, space := range spaces.List() {
+		if err := msi.addSpaceLocked(space); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to start informers for %d space(s): %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// AddSpace adds the given space, if it is not already present.
+func (msi *MultiSpaceInformer) AddSpace(space string) error {
+	msi.mutex.Lock()
+	defer msi.mutex.Unlock()
+	return msi.addSpaceLocked(space)
+}
+
+// RemoveSpace removes the given space, if it is present.
+func (msi *MultiSpaceInformer) RemoveSpace(space string) {
+	msi.mutex.Lock()
+	defer msi.mutex.Unlock()
+	msi.removeSpaceLocked(space)
+}
+
+func (msi *MultiSpaceInformer) addSpaceLocked(space string) error {
+	if _, ok := msi.spaces[space]; ok {
+		return nil
+	}
+	if strings.Contains(space, keySeparator) {
+		return fmt.Errorf("space name %q contains %q", space, keySeparator)
+	}
+	informer, err := msi.newInformer(space)
+	if err != nil {
+		return fmt.Errorf("failed to make informer for space %q: %w", space, err)
+	}
+	ctx, cancel := context.WithCancel(msi.ctx)
+	ps := &perSpace{msi: msi, space: space, informer: informer, cancel: cancel}
+	informer.AddEventHandler(ps)
+	msi.spaces[space] = ps
+	go informer.Run(ctx.Done())
+	klog.FromContext(msi.ctx).V(3).Info("Started informer for space", "space", space)
+	return nil
+}
+
+func (msi *MultiSpaceInformer) removeSpaceLocked(space string) {
+	ps, ok := msi.spaces[space]
+	if !ok {
+		return
+	}
+	ps.removed = true
+	ps.cancel()
+	delete(msi.spaces, space)
+	objs, _ := msi.indexer.ByIndex(SpaceIndexName, space)
+	for _, obj := range objs {
+		sobj := obj.(*SpaceObject)
+		if err := msi.indexer.Delete(sobj); err != nil {
+			klog.FromContext(msi.ctx).Error(err, "Failed to delete object of removed space", "space", space)
+			continue
+		}
+		for _, handler := range msi.handlers {
+			handler.OnDelete(space, sobj.Object)
+		}
+	}
+	klog.FromContext(msi.ctx).V(3).Info("Stopped informer for space", "space", space, "numObjects", len(objs))
+}
+
+var _ upstreamcache.ResourceEventHandler = &perSpace{}
+
+func (ps *perSpace) OnAdd(obj any) {
+	ps.msi.upsert(ps, nil, obj)
+}
+
+func (ps *perSpace) OnUpdate(oldObj, newObj any) {
+	ps.msi.upsert(ps, oldObj, newObj)
+}
+
+func (ps *perSpace) OnDelete(obj any) {
+	if dfsu, ok := obj.(upstreamcache.DeletedFinalStateUnknown); ok {
+		obj = dfsu.Obj
+	}
+	msi := ps.msi
+	msi.mutex.Lock()
+	defer msi.mutex.Unlock()
+	if ps.removed {
+		return
+	}
+	sobj := &SpaceObject{Space: ps.space, Object: obj}
+	if err := msi.indexer.Delete(sobj); err != nil {
+		klog.FromContext(msi.ctx).Error(err, "Failed to delete object", "space", ps.space)
+		return
+	}
+	for _, handler := range msi.handlers {
+		handler.OnDelete(ps.space, obj)
+	}
+}
+
+func (msi *MultiSpaceInformer) upsert(ps *perSpace, oldObj, newObj any) {
+	msi.mutex.Lock()
+	defer msi.mutex.Unlock()
+	if ps.removed {
+		return
+	}
+	sobj := &SpaceObject{Space: ps.space, Object: newObj}
+	if err := msi.indexer.Update(sobj); err != nil {
+		klog.FromContext(msi.ctx).Error(err, "Failed to store object", "space", ps.space)
+		return
+	}
+	for _, handler := range msi.handlers {
+		if oldObj == nil {
+			handler.OnAdd(ps.space, newObj)
+		} else {
+			handler.OnUpdate(ps.space, oldObj, newObj)
+		}
+	}
+}
+
+// SplitKey splits a key of the unified indexer into the space and
+// the usual namespace/name key of the object.
+func SplitKey(key string) (space, objectKey string, err error) {
+	parts := strings.SplitN(key, keySeparator, 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("key %q has no space part", key)
+	}
+	return parts[0], parts[1], nil
+}
+
+func keyFunc(obj any) (string, error) {
+	sobj, ok := obj.(*SpaceObject)
+	if !ok {
+		return "", fmt.Errorf("expected a *SpaceObject but got %T", obj)
+	}
+	objectKey, err := upstreamcache.MetaNamespaceKeyFunc(sobj.Object)
+	if err != nil {
+		return "", err
+	}
+	return sobj.Space + keySeparator + objectKey, nil
+}
+
+func indexBySpace(obj any) ([]string, error) {
+	return []string{obj.(*SpaceObject).Space}, nil
+}
+
+func liftIndexFunc(indexFunc upstreamcache.IndexFunc) upstreamcache.IndexFunc {
+	return func(obj any) ([]string, error) {
+		return indexFunc(obj.(*SpaceObject).Object)
+	}
+}
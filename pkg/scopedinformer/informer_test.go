@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scopedinformer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	upstreamcache "k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+
+	"github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+type recordingHandler struct {
+	sync.Mutex
+	added   sets.String
+	deleted sets.String
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{added: sets.NewString(), deleted: sets.NewString()}
+}
+
+func (rh *recordingHandler) OnAdd(space string, obj any) {
+	rh.Lock()
+	defer rh.Unlock()
+	rh.added.Insert(space + "/" + obj.(*corev1.ConfigMap).Name)
+}
+
+func (rh *recordingHandler) OnUpdate(space string, oldObj, newObj any) {}
+
+func (rh *recordingHandler) OnDelete(space string, obj any) {
+	rh.Lock()
+	defer rh.Unlock()
+	rh.deleted.Insert(space + "/" + obj.(*corev1.ConfigMap).Name)
+}
+
+func (rh *recordingHandler) counts() (int, int) {
+	rh.Lock()
+	defer rh.Unlock()
+	return rh.added.Len(), rh.deleted.Len()
+}
+
+func newConfigMap(name, color string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Labels: map[string]string{"color": color}},
+	}
+}
+
+func TestMultiSpaceInformer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sources := map[string]*fcache.FakeControllerSource{
+		"a": fcache.NewFakeControllerSource(),
+		"b": fcache.NewFakeControllerSource(),
+	}
+	sources["a"].Add(newConfigMap("x", "red"))
+	sources["a"].Add(newConfigMap("y", "blue"))
+	sources["b"].Add(newConfigMap("x", "red"))
+	newInformer := func(space string) (upstreamcache.SharedIndexInformer, error) {
+		return upstreamcache.NewSharedIndexInformer(sources[space], &corev1.ConfigMap{}, 0, upstreamcache.Indexers{}), nil
+	}
+	byColor := func(obj any) ([]string, error) {
+		return []string{obj.(*corev1.ConfigMap).Labels["color"]}, nil
+	}
+	msi := NewMultiSpaceInformer(ctx, newInformer, upstreamcache.Indexers{"color": byColor})
+	handler := newRecordingHandler()
+	msi.AddEventHandler(handler)
+	if err := msi.SetSpaces(sets.NewString("a", "b")); err != nil {
+		t.Fatalf("SetSpaces: %v", err)
+	}
+	waitFor(t, func() bool { added, _ := handler.counts(); return msi.HasSynced() && added == 3 })
+
+	if _, ok := msi.Get("b", "ns", "x"); !ok {
+		t.Errorf("Get(b, ns, x) found nothing")
+	}
+	if _, ok := msi.Get("b", "ns", "y"); ok {
+		t.Errorf("Get(b, ns, y) found an object from another space")
+	}
+	reds, err := msi.GetIndexer().ByIndex("color", "red")
+	if err != nil || len(reds) != 2 {
+		t.Errorf("ByIndex(color, red) = %d objects, %v; want 2", len(reds), err)
+	}
+	if got := len(msi.ListSpace("a")); got != 2 {
+		t.Errorf("ListSpace(a) has %d objects, want 2", got)
+	}
+	space, objectKey, err := SplitKey("a|ns/x")
+	if err != nil || space != "a" || objectKey != "ns/x" {
+		t.Errorf("SplitKey = %q, %q, %v", space, objectKey, err)
+	}
+
+	msi.RemoveSpace("a")
+	if _, deleted := handler.counts(); deleted != 2 {
+		t.Errorf("got %d deletions after removing space a, want 2", deleted)
+	}
+	if !handler.deleted.Equal(sets.NewString("a/x", "a/y")) {
+		t.Errorf("deleted = %v", handler.deleted.List())
+	}
+	if got := len(msi.ListSpace("a")); got != 0 {
+		t.Errorf("ListSpace(a) has %d objects after removal", got)
+	}
+	if !msi.Spaces().Equal(sets.NewString("b")) {
+		t.Errorf("Spaces() = %v, want [b]", msi.Spaces().List())
+	}
+
+	// Events from the removed space's source are ignored.
+	sources["a"].Add(newConfigMap("z", "red"))
+	sources["b"].Add(newConfigMap("z", "red"))
+	waitFor(t, func() bool { _, ok := msi.Get("b", "ns", "z"); return ok })
+	if _, ok := msi.Get("a", "ns", "z"); ok {
+		t.Errorf("object from removed space a was stored")
+	}
+
+	// A late handler is told about what is already there.
+	late := newRecordingHandler()
+	msi.AddEventHandler(late)
+	if !late.added.Equal(sets.NewString("b/x", "b/z")) {
+		t.Errorf("late handler added = %v", late.added.List())
+	}
+}
+
+func TestMailboxSpaces(t *testing.T) {
+	now := metav1.Now()
+	sts := []*v2alpha1.SyncTarget{
+		{ObjectMeta: metav1.ObjectMeta{Name: "st1", UID: "u1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "st2", UID: "u2", DeletionTimestamp: &now}},
+	}
+	got := MailboxSpaces("inv", sts)
+	want := sets.NewString(naming.MailboxSpaceName("inv", "u1"))
+	if !got.Equal(want) {
+		t.Errorf("MailboxSpaces = %v, want %v", got.List(), want.List())
+	}
+}
+
+func TestTrackSpaces(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The names of the ConfigMaps in the trigger are the spaces to track.
+	triggerSource := fcache.NewFakeControllerSource()
+	triggerSource.Add(newConfigMap("a", "red"))
+	trigger := upstreamcache.NewSharedIndexInformer(triggerSource, &corev1.ConfigMap{}, 0, upstreamcache.Indexers{})
+	spaces := func() (sets.String, error) {
+		ans := sets.NewString()
+		for _, obj := range trigger.GetStore().List() {
+			ans.Insert(obj.(*corev1.ConfigMap).Name)
+		}
+		return ans, nil
+	}
+	newInformer := func(space string) (upstreamcache.SharedIndexInformer, error) {
+		source := fcache.NewFakeControllerSource()
+		source.Add(newConfigMap("x", "red"))
+		return upstreamcache.NewSharedIndexInformer(source, &corev1.ConfigMap{}, 0, upstreamcache.Indexers{}), nil
+	}
+	msi := NewMultiSpaceInformer(ctx, newInformer, upstreamcache.Indexers{})
+	synced := TrackSpaces(ctx, msi, trigger, spaces)
+	if synced() {
+		t.Errorf("synced before the trigger informer ran")
+	}
+	go trigger.Run(ctx.Done())
+	waitFor(t, func() bool { return synced() && msi.Spaces().Equal(sets.NewString("a")) })
+	if _, ok := msi.Get("a", "ns", "x"); !ok {
+		t.Errorf("Get(a, ns, x) found nothing")
+	}
+
+	triggerSource.Add(newConfigMap("b", "blue"))
+	triggerSource.Delete(newConfigMap("a", "red"))
+	waitFor(t, func() bool { return msi.Spaces().Equal(sets.NewString("b")) })
+	waitFor(t, func() bool { _, ok := msi.Get("b", "ns", "x"); return ok })
+	if got := len(msi.ListSpace("a")); got != 0 {
+		t.Errorf("ListSpace(a) has %d objects after the space stopped being tracked", got)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	if err := wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) { return cond(), nil }); err != nil {
+		t.Fatalf("condition not reached: %v", err)
+	}
+}
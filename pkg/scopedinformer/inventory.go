@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scopedinformer
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	upstreamcache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
+	edgelisters "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+)
+
+// retryDelay is how long TrackSpaces waits before trying again after
+// a failure to determine the spaces or to make their informers.
+const retryDelay = 10 * time.Second
+
+// MailboxSpaces returns the names of the mailbox spaces of the given
+// SyncTargets, which are in the inventory space with the given ID.
+func MailboxSpaces(inventorySpaceID string, syncTargets []*v2alpha1.SyncTarget) sets.String {
+	ans := sets.NewString()
+	for _, st := range syncTargets {
+		if st.DeletionTimestamp != nil {
+			continue
+		}
+		ans.Insert(naming.MailboxSpaceName(inventorySpaceID, string(st.UID)))
+	}
+	return ans
+}
+
+// TrackMailboxSpaces keeps the spaces of the given MultiSpaceInformer
+// equal to the mailbox spaces of the SyncTargets in the inventory.
+// It returns immediately; the tracking continues until ctx is done.
+// The returned func is as for TrackSpaces.
+func TrackMailboxSpaces(ctx context.Context, msi *MultiSpaceInformer, inventorySpaceID string,
+	syncTargetInformer upstreamcache.SharedIndexInformer, syncTargetLister edgelisters.SyncTargetLister) func() bool {
+	return TrackSpaces(ctx, msi, syncTargetInformer, func() (sets.String, error) {
+		syncTargets, err := syncTargetLister.List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list SyncTargets: %w", err)
+		}
+		return MailboxSpaces(inventorySpaceID, syncTargets), nil
+	})
+}
+
+// TrackSpaces keeps the spaces of the given MultiSpaceInformer equal to
+// the set returned by the given func, calling it again after each
+// notification from the given informer. The func may return an error
+// along with a non-nil set, which is then applied anyway; on any error,
+// the tracking tries again after a delay.
+// It returns immediately; the tracking continues until ctx is done.
+// The returned func tells whether the given informer has synced, the
+// spaces have been set from it, and the informers of those spaces have synced.
+func TrackSpaces(ctx context.Context, msi *MultiSpaceInformer, trigger upstreamcache.SharedInformer, spaces func() (sets.String, error)) func() bool {
+	logger := klog.FromContext(ctx)
+	var tracking atomic.Bool
+	pokes := make(chan struct{}, 1)
+	poke := func() {
+		select {
+		case pokes <- struct{}{}:
+		default:
+		}
+	}
+	trigger.AddEventHandler(upstreamcache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { poke() },
+		UpdateFunc: func(oldObj, newObj any) { poke() },
+		DeleteFunc: func(obj any) { poke() },
+	})
+	go func() {
+		if !upstreamcache.WaitForCacheSync(ctx.Done(), trigger.HasSynced) {
+			return
+		}
+		poke()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-pokes:
+			}
+			want, err := spaces()
+			if err != nil {
+				logger.Error(err, "Failed to determine all the spaces to track, will retry", "delay", retryDelay)
+				time.AfterFunc(retryDelay, poke)
+				if want == nil {
+					continue
+				}
+			}
+			if err := msi.SetSpaces(want); err != nil {
+				logger.Error(err, "Failed to track some spaces, will retry", "delay", retryDelay)
+				time.AfterFunc(retryDelay, poke)
+			}
+			tracking.Store(true)
+		}
+	}()
+	return func() bool { return tracking.Load() && msi.HasSynced() }
+}
+
+// DynamicInformerFunc returns an InformerFunc that makes, for each space,
+// an informer on the given resource using that space's own dynamic client
+// from the given Factory. The informers hold *unstructured.Unstructured objects.
+func DynamicInformerFunc(factory *spaceclient.Factory, providerNS string, gvr schema.GroupVersionResource, resync time.Duration) InformerFunc {
+	return func(space string) (upstreamcache.SharedIndexInformer, error) {
+		clients, err := factory.For(space, providerNS)
+		if err != nil {
+			return nil, err
+		}
+		return dynamicinformer.NewFilteredDynamicInformer(clients.Dynamic, gvr, metav1.NamespaceAll, resync, upstreamcache.Indexers{}, nil).Informer(), nil
+	}
+}
+
+// TypedInformerFunc returns an InformerFunc that makes, for each space,
+// an informer from the given ListerWatcher constructor. Use this with a
+// typed client from the space's Clients, e.g., for ConfigMaps:
+//
+//	func(clients *spaceclient.Clients) upstreamcache.ListerWatcher {
+//		return upstreamcache.NewListWatchFromClient(clients.Kube.CoreV1().RESTClient(), "configmaps", metav1.NamespaceAll, fields.Everything())
+//	}
+func TypedInformerFunc(factory *spaceclient.Factory, providerNS string, exampleObject k8sruntime.Object, resync time.Duration,
+	newListerWatcher func(*spaceclient.Clients) upstreamcache.ListerWatcher) InformerFunc {
+	return func(space string) (upstreamcache.SharedIndexInformer, error) {
+		clients, err := factory.For(space, providerNS)
+		if err != nil {
+			return nil, err
+		}
+		return upstreamcache.NewSharedIndexInformer(newListerWatcher(clients), exampleObject, resync, upstreamcache.Indexers{}), nil
+	}
+}
+
+// EdgeInformerFunc returns an InformerFunc that makes, for each space,
+// an informer on a KubeStellar resource using that space's own edge
+// clientset from the given Factory. The given func picks the informer
+// from an informer factory on that clientset, e.g.:
+//
+//	func(f edgeinformers.SharedScopedInformerFactory) upstreamcache.SharedIndexInformer {
+//		return f.Edge().V2alpha1().SyncerConfigs().Informer()
+//	}
+func EdgeInformerFunc(factory *spaceclient.Factory, providerNS string, resync time.Duration,
+	pick func(edgeinformers.SharedScopedInformerFactory) upstreamcache.SharedIndexInformer) InformerFunc {
+	return func(space string) (upstreamcache.SharedIndexInformer, error) {
+		clients, err := factory.For(space, providerNS)
+		if err != nil {
+			return nil, err
+		}
+		return pick(edgeinformers.NewSharedScopedInformerFactoryWithOptions(clients.Edge, resync)), nil
+	}
+}
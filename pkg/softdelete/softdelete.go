@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package softdelete defines the scheduled deletion of a copy of a
+// workload object at a destination that the object's placement no
+// longer selects. When the placement translator is given a grace
+// window, it does not delete such a copy from its mailbox space right
+// away; it marks the copy with DeleteAtAnnotationKey, whose value is
+// the RFC 3339 time at which the copy is due for deletion, and deletes
+// the copy after that time. The syncer treats a copy that is due as
+// deleted, so the workload object leaves the edge cluster at that time
+// even if the translator is late.
+//
+// Until then the copy may be rescued by adding RescuedAnnotationKey to
+// it (see `kubectl kubestellar recycle-bin`). A rescued copy is left
+// alone, in the mailbox space and in the edge cluster, until someone
+// deletes it or its placement selects it again, in which case the
+// translator manages it again and removes both annotations.
+package softdelete
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DeleteAtAnnotationKey is the key of the annotation that holds the
+	// time at which the copy is due for deletion, in RFC 3339 format.
+	DeleteAtAnnotationKey = "edge.kubestellar.io/scheduled-deletion-at"
+
+	// RescuedAnnotationKey is the key of the annotation that cancels the
+	// scheduled deletion. Its value is free-form, e.g., who rescued the
+	// copy and why.
+	RescuedAnnotationKey = "edge.kubestellar.io/rescued"
+)
+
+// ScheduledAt returns the time at which the given object is due for
+// deletion, and whether there is one. A malformed time is an error.
+func ScheduledAt(obj metav1.Object) (time.Time, bool, error) {
+	atS, have := obj.GetAnnotations()[DeleteAtAnnotationKey]
+	if !have {
+		return time.Time{}, false, nil
+	}
+	at, err := time.Parse(time.RFC3339, atS)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("annotation %s is not an RFC 3339 time: %w", DeleteAtAnnotationKey, err)
+	}
+	return at, true, nil
+}
+
+// Rescued tells whether the scheduled deletion of the given object has been cancelled.
+func Rescued(obj metav1.Object) bool {
+	_, have := obj.GetAnnotations()[RescuedAnnotationKey]
+	return have
+}
+
+// Due tells whether the given object is scheduled for deletion at or
+// before the given time, and has not been rescued. An object whose
+// annotation is malformed is not due.
+func Due(obj metav1.Object, now time.Time) bool {
+	at, scheduled, err := ScheduledAt(obj)
+	return err == nil && scheduled && !Rescued(obj) && !now.Before(at)
+}
+
+// Schedule marks the given object for deletion at the given time.
+func Schedule(obj metav1.Object, at time.Time) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[DeleteAtAnnotationKey] = at.UTC().Format(time.RFC3339)
+	obj.SetAnnotations(annotations)
+}
+
+// Rescue cancels the scheduled deletion of the given object, noting the given value.
+func Rescue(obj metav1.Object, note string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[RescuedAnnotationKey] = note
+	obj.SetAnnotations(annotations)
+}
+
+// Strip removes the scheduled deletion and rescue annotations from the given object.
+func Strip(obj metav1.Object) {
+	annotations := obj.GetAnnotations()
+	_, haveAt := annotations[DeleteAtAnnotationKey]
+	_, haveRescued := annotations[RescuedAnnotationKey]
+	if !haveAt && !haveRescued {
+		return
+	}
+	delete(annotations, DeleteAtAnnotationKey)
+	delete(annotations, RescuedAnnotationKey)
+	obj.SetAnnotations(annotations)
+}
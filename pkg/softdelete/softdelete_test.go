@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package softdelete
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDue(t *testing.T) {
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	obj := &metav1.ObjectMeta{Annotations: map[string]string{"other": "x"}}
+	if _, scheduled, err := ScheduledAt(obj); scheduled || err != nil || Due(obj, now) {
+		t.Errorf("Expected an unmarked object not to be scheduled, got scheduled=%v err=%v", scheduled, err)
+	}
+	Schedule(obj, now.Add(time.Hour))
+	if at, scheduled, err := ScheduledAt(obj); !scheduled || err != nil || !at.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected deletion at %s, got %s scheduled=%v err=%v", now.Add(time.Hour), at, scheduled, err)
+	}
+	if Due(obj, now) || !Due(obj, now.Add(time.Hour)) {
+		t.Errorf("Expected the object to be due only from its scheduled time")
+	}
+	Rescue(obj, "by ops")
+	if !Rescued(obj) || Due(obj, now.Add(2*time.Hour)) {
+		t.Errorf("Expected a rescued object never to be due")
+	}
+	Strip(obj)
+	if len(obj.Annotations) != 1 {
+		t.Errorf("Expected only the other annotation to remain, got %v", obj.Annotations)
+	}
+
+	malformed := &metav1.ObjectMeta{Annotations: map[string]string{DeleteAtAnnotationKey: "soon"}}
+	if _, _, err := ScheduledAt(malformed); err == nil || Due(malformed, now) {
+		t.Errorf("Expected a malformed time to be an error and not due")
+	}
+}
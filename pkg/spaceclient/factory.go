@@ -0,0 +1,272 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package spaceclient provides clients for spaces by name.
+// A Factory resolves a space name to a rest.Config through the space
+// framework and keeps, for a while, one HTTP client per space that all
+// the clients for that space share. This spares controllers from building
+// a new connection pool every time they visit a space.
+package spaceclient
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+)
+
+// ConfigSource resolves a space to a rest.Config.
+// The space-aware client of the space framework
+// (msclientlib.KubestellarSpaceInterface) is one.
+type ConfigSource interface {
+	ConfigForSpace(name string, providerNS string) (*rest.Config, error)
+}
+
+// Options bound the resources that a Factory uses.
+// Zero values mean the defaults.
+type Options struct {
+	// TTL is how long the clients for a space are kept before the
+	// space's rest.Config is resolved again. Default is DefaultTTL.
+	TTL time.Duration
+
+	// MaxSpaces is the number of spaces whose clients are kept.
+	// The least recently used are dropped beyond that.
+	// Default is DefaultMaxSpaces.
+	MaxSpaces int
+
+	// MaxInflightPerSpace limits the number of requests to one space
+	// that are awaiting their response at once. A watch holds its slot
+	// only until its response starts. Zero means no limit.
+	MaxInflightPerSpace int
+
+	// QPS and Burst, when positive, set one client-side rate limit
+	// that all the clients for a space share.
+	QPS   float32
+	Burst int
+
+	// UserAgent, when not empty, replaces the one in the resolved configs.
+	UserAgent string
+
+	// Adjust, when not nil, is applied to the config for each space
+	// after the other options (e.g., clientlimits.Options.Apply).
+	Adjust func(*rest.Config)
+}
+
+const (
+	DefaultTTL       = 10 * time.Minute
+	DefaultMaxSpaces = 256
+)
+
+// Clients are the clients for one space. They share one HTTP client.
+type Clients struct {
+	Space      string
+	ProviderNS string
+
+	// Config is the rest.Config of the clients, after the Options
+	// were applied. Use it with HTTPClient to make other typed clients,
+	// e.g., apiextclient.NewForConfigAndClient(Config, HTTPClient).
+	Config     *rest.Config
+	HTTPClient *http.Client
+
+	Kube      kubernetes.Interface
+	Edge      edgeclientset.Interface
+	Dynamic   dynamic.Interface
+	Discovery discovery.DiscoveryInterface
+}
+
+// Factory hands out Clients by space name. It is safe for concurrent use.
+type Factory struct {
+	source ConfigSource
+	opts   Options
+	now    func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element // values are *entry
+	lru     *list.List               // most recently used at front
+}
+
+type entry struct {
+	key     string
+	clients *Clients
+	expires time.Time
+}
+
+// NewFactory makes a Factory that resolves spaces through the given source.
+func NewFactory(source ConfigSource, opts Options) *Factory {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.MaxSpaces <= 0 {
+		opts.MaxSpaces = DefaultMaxSpaces
+	}
+	return &Factory{
+		source:  source,
+		opts:    opts,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func spaceKey(space, providerNS string) string {
+	return providerNS + "/" + space
+}
+
+// For returns the Clients for the given space, making them if
+// there are none or they have expired.
+func (fac *Factory) For(space, providerNS string) (*Clients, error) {
+	key := spaceKey(space, providerNS)
+	now := fac.now()
+	fac.mutex.Lock()
+	if elt, ok := fac.entries[key]; ok {
+		ent := elt.Value.(*entry)
+		if now.Before(ent.expires) {
+			fac.lru.MoveToFront(elt)
+			fac.mutex.Unlock()
+			return ent.clients, nil
+		}
+		fac.removeLocked(elt)
+	}
+	fac.mutex.Unlock()
+
+	// Resolve and build without holding the lock; the source may do I/O.
+	clients, err := fac.newClients(space, providerNS)
+	if err != nil {
+		return nil, err
+	}
+
+	fac.mutex.Lock()
+	defer fac.mutex.Unlock()
+	if elt, ok := fac.entries[key]; ok {
+		// Made concurrently; keep the one that is already shared.
+		clients.HTTPClient.CloseIdleConnections()
+		fac.lru.MoveToFront(elt)
+		return elt.Value.(*entry).clients, nil
+	}
+	fac.entries[key] = fac.lru.PushFront(&entry{key: key, clients: clients, expires: now.Add(fac.opts.TTL)})
+	for fac.lru.Len() > fac.opts.MaxSpaces {
+		fac.removeLocked(fac.lru.Back())
+	}
+	return clients, nil
+}
+
+// Invalidate drops the Clients for the given space, if any.
+// Call this when the space's credentials or endpoint may have changed,
+// e.g., after an Unauthorized error.
+func (fac *Factory) Invalidate(space, providerNS string) {
+	fac.mutex.Lock()
+	defer fac.mutex.Unlock()
+	if elt, ok := fac.entries[spaceKey(space, providerNS)]; ok {
+		fac.removeLocked(elt)
+	}
+}
+
+// Len returns the number of spaces whose Clients are kept.
+func (fac *Factory) Len() int {
+	fac.mutex.Lock()
+	defer fac.mutex.Unlock()
+	return fac.lru.Len()
+}
+
+func (fac *Factory) removeLocked(elt *list.Element) {
+	ent := fac.lru.Remove(elt).(*entry)
+	delete(fac.entries, ent.key)
+	ent.clients.HTTPClient.CloseIdleConnections()
+}
+
+func (fac *Factory) newClients(space, providerNS string) (*Clients, error) {
+	resolved, err := fac.source.ConfigForSpace(space, providerNS)
+	if err != nil {
+		return nil, err
+	}
+	config := rest.CopyConfig(resolved)
+	if fac.opts.UserAgent != "" {
+		config.UserAgent = fac.opts.UserAgent
+	}
+	if fac.opts.QPS > 0 && fac.opts.Burst > 0 {
+		config.QPS, config.Burst = fac.opts.QPS, fac.opts.Burst
+		config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(fac.opts.QPS, fac.opts.Burst)
+	}
+	if fac.opts.MaxInflightPerSpace > 0 {
+		config.Wrap(newInflightLimiter(fac.opts.MaxInflightPerSpace))
+	}
+	if fac.opts.Adjust != nil {
+		fac.opts.Adjust(config)
+	}
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make HTTP client for space %s: %w", space, err)
+	}
+	kube, err := kubernetes.NewForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make kube clientset for space %s: %w", space, err)
+	}
+	edge, err := edgeclientset.NewForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make edge clientset for space %s: %w", space, err)
+	}
+	dyn, err := dynamic.NewForConfigAndClient(config, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make dynamic client for space %s: %w", space, err)
+	}
+	return &Clients{
+		Space:      space,
+		ProviderNS: providerNS,
+		Config:     config,
+		HTTPClient: httpClient,
+		Kube:       kube,
+		Edge:       edge,
+		Dynamic:    dyn,
+		Discovery:  kube.Discovery(),
+	}, nil
+}
+
+// newInflightLimiter returns a wrapper that limits the number of
+// requests in progress through the wrapped RoundTripper.
+func newInflightLimiter(max int) func(http.RoundTripper) http.RoundTripper {
+	slots := make(chan struct{}, max)
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &inflightLimiter{slots: slots, delegate: rt}
+	}
+}
+
+type inflightLimiter struct {
+	slots    chan struct{}
+	delegate http.RoundTripper
+}
+
+func (il *inflightLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case il.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-il.slots }()
+	return il.delegate.RoundTrip(req)
+}
+
+func (il *inflightLimiter) WrappedRoundTripper() http.RoundTripper {
+	return il.delegate
+}
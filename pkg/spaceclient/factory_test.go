@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spaceclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+type fakeSource struct {
+	resolved map[string]int
+}
+
+func (fs *fakeSource) ConfigForSpace(name string, providerNS string) (*rest.Config, error) {
+	if name == "missing" {
+		return nil, errors.New("space is not ready")
+	}
+	fs.resolved[spaceKey(name, providerNS)]++
+	return &rest.Config{Host: "https://" + name + ".example.com"}, nil
+}
+
+func TestFactoryCaching(t *testing.T) {
+	source := &fakeSource{resolved: map[string]int{}}
+	now := time.Unix(1700000000, 0)
+	fac := NewFactory(source, Options{TTL: time.Minute, MaxSpaces: 2, MaxInflightPerSpace: 4, QPS: 10, Burst: 20})
+	fac.now = func() time.Time { return now }
+
+	first, err := fac.For("s1", "ns")
+	if err != nil {
+		t.Fatalf("Failed to get clients: %v", err)
+	}
+	if first.Config.RateLimiter == nil || first.Config.Host != "https://s1.example.com" {
+		t.Errorf("Options not applied to config %+v", first.Config)
+	}
+	if again, _ := fac.For("s1", "ns"); again != first {
+		t.Error("Clients were not reused")
+	}
+	if source.resolved["ns/s1"] != 1 {
+		t.Errorf("Space resolved %d times, expected 1", source.resolved["ns/s1"])
+	}
+
+	now = now.Add(2 * time.Minute)
+	if expired, _ := fac.For("s1", "ns"); expired == first {
+		t.Error("Expired clients were reused")
+	}
+
+	fac.For("s2", "ns")
+	fac.For("s3", "ns")
+	if fac.Len() != 2 {
+		t.Errorf("Kept %d spaces, expected 2", fac.Len())
+	}
+	fac.For("s1", "ns")
+	if source.resolved["ns/s1"] != 3 {
+		t.Errorf("Least recently used space was not dropped; resolved %d times", source.resolved["ns/s1"])
+	}
+
+	fac.Invalidate("s1", "ns")
+	if fac.Len() != 1 {
+		t.Errorf("Kept %d spaces after Invalidate, expected 1", fac.Len())
+	}
+	if _, err := fac.For("missing", "ns"); err == nil {
+		t.Error("Expected error for unresolvable space")
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package spechash identifies what the controllers act on in an
+// EdgePlacement, so that updates that change none of it (such as
+// re-applying identical YAML, or writing status) can be ignored.
+// The hash is computed by package objecthash, like the other content
+// hashes used for change detection.
+package spechash
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/objecthash"
+)
+
+// hashOptions leave out everything that the reduced EdgePlacement made
+// by Of has apart from its spec and annotations.
+var hashOptions = objecthash.Options{ExcludeFields: objecthash.ServerFields, Length: 32}
+
+// hashedAnnotations are the annotations whose values the hash covers.
+var hashedAnnotations = []string{edgev2alpha1.ReprocessAnnotationKey, edgev2alpha1.PausedAnnotationKey}
+
+// Of returns a hash of the spec of the given EdgePlacement and of the
+// values of its reprocess and paused annotations. An empty annotation
+// counts as absent. The hash changed when it moved to package
+// objecthash, so a `processedSpecHash` written by an older release
+// looks out of date until the where-resolver processes the
+// EdgePlacement again, which it does for all of them when it starts.
+func Of(ep *edgev2alpha1.EdgePlacement) string {
+	reduced := &edgev2alpha1.EdgePlacement{Spec: ep.Spec}
+	for _, key := range hashedAnnotations {
+		if value := ep.Annotations[key]; value != "" {
+			metav1.SetMetaDataAnnotation(&reduced.ObjectMeta, key, value)
+		}
+	}
+	// Hashing an EdgePlacement cannot fail
+	hash, _ := hashOptions.Hash(reduced)
+	return hash
+}
+
+// Changed tells whether an update from oldObj to newObj calls for
+// processing the EdgePlacement again. Arguments that are not
+// EdgePlacements are always considered changed.
+func Changed(oldObj, newObj any) bool {
+	oldEP, isEP := oldObj.(*edgev2alpha1.EdgePlacement)
+	if !isEP {
+		return true
+	}
+	newEP, isEP := newObj.(*edgev2alpha1.EdgePlacement)
+	if !isEP {
+		return true
+	}
+	return Of(oldEP) != Of(newEP)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spechash
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestChanged(t *testing.T) {
+	base := &edgev2alpha1.EdgePlacement{
+		ObjectMeta: metav1.ObjectMeta{Name: "ep", ResourceVersion: "1"},
+		Spec: edgev2alpha1.EdgePlacementSpec{
+			LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"env": "prod"}}},
+		},
+	}
+	noop := base.DeepCopy()
+	noop.ResourceVersion = "2"
+	noop.Labels = map[string]string{"team": "a"}
+	noop.Status.MatchingLocationCount = 3
+	if Changed(base, noop) {
+		t.Error("Changes outside the spec must be ignored")
+	}
+	specChange := base.DeepCopy()
+	specChange.Spec.LocationSelectors[0].MatchLabels["env"] = "dev"
+	if !Changed(base, specChange) {
+		t.Error("A spec change must be noticed")
+	}
+	reprocess := base.DeepCopy()
+	reprocess.Annotations = map[string]string{edgev2alpha1.ReprocessAnnotationKey: "1"}
+	if !Changed(base, reprocess) {
+		t.Error("A change to the reprocess annotation must be noticed")
+	}
+	paused := base.DeepCopy()
+	paused.Annotations = map[string]string{edgev2alpha1.PausedAnnotationKey: "true"}
+	if !Changed(base, paused) || !Changed(paused, base) {
+		t.Error("Pausing and resuming must be noticed")
+	}
+	emptyAnnotation := base.DeepCopy()
+	emptyAnnotation.Annotations = map[string]string{edgev2alpha1.PausedAnnotationKey: ""}
+	if Changed(base, emptyAnnotation) {
+		t.Error("An empty annotation must count as absent")
+	}
+	if hash := Of(base); len(hash) != 32 {
+		t.Errorf("Expected a hash of 32 hex digits, got %q", hash)
+	}
+	if !Changed("a", "a") {
+		t.Error("Non-EdgePlacements must be considered changed")
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package storagemigration rewrites stored objects so that the API
+// server stores them in the current storage version of their resource,
+// in the manner of kube-storage-version-migrator. This must be done
+// before an old version can be dropped from a CustomResourceDefinition.
+package storagemigration
+
+import (
+	"context"
+	"fmt"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/typed/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// DefaultPageSize is the number of objects listed at a time, by default.
+const DefaultPageSize = 500
+
+var migratedObjects = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "kubestellar",
+	Name:           "storage_migrated_objects_total",
+	Help:           "Number of objects rewritten by storage version migration, by resource",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"resource"})
+
+func init() {
+	legacyregistry.MustRegister(migratedObjects)
+}
+
+// Progress reports how far the migration of one resource has gotten.
+type Progress struct {
+	Resource schema.GroupVersionResource
+	// Migrated is the number of objects rewritten so far.
+	Migrated int
+	// Skipped is the number of objects that were deleted, or written by
+	// someone else, before they could be rewritten; either way they need
+	// no more attention.
+	Skipped int
+	// Remaining is the server's estimate of the number of objects not
+	// yet listed; nil when the server does not say.
+	Remaining *int64
+	// Done tells whether all the objects have been handled.
+	Done bool
+}
+
+func (prog Progress) String() string {
+	remaining := "unknown"
+	if prog.Remaining != nil {
+		remaining = fmt.Sprint(*prog.Remaining)
+	}
+	return fmt.Sprintf("%s: migrated=%d skipped=%d remaining=%s done=%v", prog.Resource, prog.Migrated, prog.Skipped, remaining, prog.Done)
+}
+
+// Migrator rewrites stored objects.
+type Migrator struct {
+	client dynamic.Interface
+
+	// PageSize is the number of objects listed at a time.
+	PageSize int64
+
+	// OnProgress, when not nil, is called after each page of objects
+	// and when a resource is done.
+	OnProgress func(Progress)
+}
+
+// NewMigrator makes a Migrator that uses the given client.
+func NewMigrator(client dynamic.Interface) *Migrator {
+	return &Migrator{client: client, PageSize: DefaultPageSize}
+}
+
+// Migrate rewrites every object of the given resource, whose version
+// must be the storage version.
+func (mig *Migrator) Migrate(ctx context.Context, gvr schema.GroupVersionResource) (Progress, error) {
+	client := mig.client.Resource(gvr)
+	prog := Progress{Resource: gvr}
+	opts := metav1.ListOptions{Limit: mig.PageSize}
+	for {
+		list, err := client.List(ctx, opts)
+		if err != nil {
+			return prog, fmt.Errorf("failed to list %s: %w", gvr, err)
+		}
+		for idx := range list.Items {
+			obj := &list.Items[idx]
+			var objClient dynamic.ResourceInterface = client
+			if ns := obj.GetNamespace(); ns != "" {
+				objClient = client.Namespace(ns)
+			}
+			_, err := objClient.Update(ctx, obj, metav1.UpdateOptions{FieldManager: "kubestellar-storage-migration"})
+			switch {
+			case err == nil:
+				prog.Migrated++
+				migratedObjects.WithLabelValues(gvr.GroupResource().String()).Inc()
+			// A conflict means that someone else wrote the object,
+			// which stored it in the storage version too.
+			case apierrors.IsConflict(err) || apierrors.IsNotFound(err):
+				prog.Skipped++
+			default:
+				return prog, fmt.Errorf("failed to rewrite %s %s/%s: %w", gvr, obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+		prog.Remaining = list.GetRemainingItemCount()
+		opts.Continue = list.GetContinue()
+		prog.Done = opts.Continue == ""
+		if mig.OnProgress != nil {
+			mig.OnProgress(prog)
+		}
+		if prog.Done {
+			return prog, nil
+		}
+	}
+}
+
+// NeedsMigration tells whether the given CRD's objects may be stored in
+// a version other than the given storage version. That is normally the
+// CRD's own storage version, but during an upgrade it is the storage
+// version of the spec being installed.
+func NeedsMigration(crd *apiext.CustomResourceDefinition, storage string) bool {
+	for _, stored := range crd.Status.StoredVersions {
+		if stored != storage {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateCRD rewrites every object of the named CRD and then records,
+// in the CRD's status, that they are all stored in the storage version.
+func (mig *Migrator) MigrateCRD(ctx context.Context, crdClient apiextclient.CustomResourceDefinitionInterface, name string) (Progress, error) {
+	crd, err := crdClient.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return Progress{}, err
+	}
+	storage, err := StorageVersion(crd)
+	if err != nil {
+		return Progress{}, fmt.Errorf("CRD %s %w", name, err)
+	}
+	gvr := schema.GroupVersionResource{Group: crd.Spec.Group, Version: storage, Resource: crd.Spec.Names.Plural}
+	prog, err := mig.Migrate(ctx, gvr)
+	if err != nil {
+		return prog, err
+	}
+	return prog, retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := crdClient.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current.Status.StoredVersions = []string{storage}
+		_, err = crdClient.UpdateStatus(ctx, current, metav1.UpdateOptions{FieldManager: "kubestellar-storage-migration"})
+		return err
+	})
+}
+
+// StorageVersion returns the name of the CRD's storage version.
+func StorageVersion(crd *apiext.CustomResourceDefinition) (string, error) {
+	var found []string
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			found = append(found, version.Name)
+		}
+	}
+	if len(found) != 1 {
+		return "", fmt.Errorf("has %d storage versions %v, not 1", len(found), found)
+	}
+	return found[0], nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagemigration
+
+import (
+	"context"
+	"testing"
+
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestMigrate(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "edge.kubestellar.io", Version: "v2alpha1", Resource: "locations"}
+	var objs []runtime.Object
+	for _, name := range []string{"a", "b", "c"} {
+		objs = append(objs, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "edge.kubestellar.io/v2alpha1", "kind": "Location",
+			"metadata": map[string]interface{}{"name": name},
+		}})
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "LocationList"}, objs...)
+	migrator := NewMigrator(client)
+	var reports []Progress
+	migrator.OnProgress = func(prog Progress) { reports = append(reports, prog) }
+	prog, err := migrator.Migrate(context.Background(), gvr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prog.Migrated != 3 || prog.Skipped != 0 || !prog.Done {
+		t.Errorf("Unexpected final progress %s", prog)
+	}
+	if len(reports) == 0 || !reports[len(reports)-1].Done {
+		t.Errorf("Progress not reported as done: %v", reports)
+	}
+}
+
+func TestNeedsMigration(t *testing.T) {
+	crd := &apiext.CustomResourceDefinition{Spec: apiext.CustomResourceDefinitionSpec{Versions: []apiext.CustomResourceDefinitionVersion{
+		{Name: "v1alpha1", Served: true},
+		{Name: "v2alpha1", Served: true, Storage: true},
+	}}}
+	for _, testCase := range []struct {
+		stored   []string
+		expected bool
+	}{
+		{nil, false},
+		{[]string{"v2alpha1"}, false},
+		{[]string{"v1alpha1", "v2alpha1"}, true},
+		{[]string{"v1alpha1"}, true},
+	} {
+		crd.Status.StoredVersions = testCase.stored
+		if actual := NeedsMigration(crd, "v2alpha1"); actual != testCase.expected {
+			t.Errorf("For stored versions %v expected %v, got %v", testCase.stored, testCase.expected, actual)
+		}
+	}
+	crd.Status.StoredVersions = []string{"v2alpha1"}
+	if !NeedsMigration(crd, "v3") {
+		t.Error("Expected migration to be needed for a new storage version")
+	}
+}
@@ -33,6 +33,12 @@ type Client struct {
 	hasStatusInSubresources bool
 }
 
+// NewClient returns a Client that accesses objects through the given
+// dynamic client, which is for a resource of the given scope.
+func NewClient(resourceClient dynamic.NamespaceableResourceInterface, scope meta.RESTScope, hasStatusInSubresources bool) Client {
+	return Client{ResourceClient: resourceClient, scope: scope, hasStatusInSubresources: hasStatusInSubresources}
+}
+
 func (c *Client) IsNamespaced() bool {
 	return c.scope == meta.RESTScopeNamespace
 }
@@ -74,6 +80,33 @@ func (c *Client) List(resource edgev2alpha1.EdgeSyncConfigResource) (*unstructur
 	return unstListObj, err
 }
 
+// ListPage lists one page of the given resource; limit zero means no paging.
+func (c *Client) ListPage(resource edgev2alpha1.EdgeSyncConfigResource, limit int64, continueToken string) (*unstructured.UnstructuredList, error) {
+	opts := v1.ListOptions{Limit: limit, Continue: continueToken}
+	if c.IsNamespaced() {
+		return c.ResourceClient.Namespace(resource.Namespace).List(context.Background(), opts)
+	}
+	return c.ResourceClient.List(context.Background(), opts)
+}
+
+// ListAll lists the given resource a page at a time, passing each object to the consumer.
+func (c *Client) ListAll(resource edgev2alpha1.EdgeSyncConfigResource, pageSize int64, consumer func(*unstructured.Unstructured)) error {
+	var continueToken string
+	for {
+		page, err := c.ListPage(resource, pageSize, continueToken)
+		if err != nil {
+			return err
+		}
+		for idx := range page.Items {
+			consumer(&page.Items[idx])
+		}
+		continueToken = page.GetContinue()
+		if continueToken == "" {
+			return nil
+		}
+	}
+}
+
 func (c *Client) Update(resource edgev2alpha1.EdgeSyncConfigResource, unstObj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 	var updatedObj *unstructured.Unstructured
 	var err error
@@ -86,6 +119,16 @@ func (c *Client) Update(resource edgev2alpha1.EdgeSyncConfigResource, unstObj *u
 	return updatedObj, err
 }
 
+// DryRunUpdate submits the given object as an update without persisting
+// it, and returns what the apiserver would store.
+func (c *Client) DryRunUpdate(resource edgev2alpha1.EdgeSyncConfigResource, unstObj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	opts := v1.UpdateOptions{DryRun: []string{v1.DryRunAll}}
+	if c.IsNamespaced() {
+		return c.ResourceClient.Namespace(resource.Namespace).Update(context.Background(), unstObj, opts)
+	}
+	return c.ResourceClient.Update(context.Background(), unstObj, opts)
+}
+
 func (c *Client) UpdateStatus(resource edgev2alpha1.EdgeSyncConfigResource, unstObj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 	var updatedObj *unstructured.Unstructured
 	var err error
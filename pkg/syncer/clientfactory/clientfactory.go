@@ -19,6 +19,7 @@ package clientfactory
 import (
 	"fmt"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
@@ -32,6 +33,10 @@ type ClientFactory struct {
 	logger          klog.Logger
 	discoveryClient discovery.DiscoveryInterface
 	dyClient        dynamic.Interface
+
+	// onDemandDiscovery tells whether to discover only the API group of
+	// each requested kind, rather than every API group, to save memory.
+	onDemandDiscovery bool
 }
 
 func NewClientFactory(logger klog.Logger, dyClient dynamic.Interface, discoveryClient discovery.DiscoveryInterface) (ClientFactory, error) {
@@ -43,24 +48,69 @@ func NewClientFactory(logger klog.Logger, dyClient dynamic.Interface, discoveryC
 	return clientFactory, nil
 }
 
+// SetOnDemandDiscovery sets whether the clients are made by discovering
+// only the API group of the requested kind. This takes less memory on a
+// cluster with many API groups, at the cost of more requests.
+func (cf *ClientFactory) SetOnDemandDiscovery(onDemand bool) {
+	cf.onDemandDiscovery = onDemand
+}
+
 func (cf *ClientFactory) GetAPIGroupResources() ([]*restmapper.APIGroupResources, error) {
 	return restmapper.GetAPIGroupResources(cf.discoveryClient)
 }
 
+// getGroupResources returns the discovered resources of the given API group
+// when discovering on demand, and of every API group otherwise.
+// The answer is empty if the group is not served.
+func (cf *ClientFactory) getGroupResources(group string) ([]*restmapper.APIGroupResources, error) {
+	if !cf.onDemandDiscovery {
+		return cf.GetAPIGroupResources()
+	}
+	groups, err := cf.discoveryClient.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+	for _, apiGroup := range groups.Groups {
+		if apiGroup.Name != group {
+			continue
+		}
+		groupResources := &restmapper.APIGroupResources{Group: apiGroup, VersionedResources: map[string][]metav1.APIResource{}}
+		for _, version := range apiGroup.Versions {
+			resources, err := cf.discoveryClient.ServerResourcesForGroupVersion(version.GroupVersion)
+			if err != nil {
+				return nil, err
+			}
+			groupResources.VersionedResources[version.Version] = resources.APIResources
+		}
+		return []*restmapper.APIGroupResources{groupResources}, nil
+	}
+	return nil, nil
+}
+
 func (cf *ClientFactory) GetResourceClient(group string, kind string) (Client, error) {
+	return cf.GetVersionedResourceClient(group, "", kind)
+}
+
+// GetVersionedResourceClient returns a client for the given version of the given kind;
+// an empty version means the preferred one.
+func (cf *ClientFactory) GetVersionedResourceClient(group string, version string, kind string) (Client, error) {
 	var resourceClient Client
 	var client dynamic.NamespaceableResourceInterface
 	gk := schema.GroupKind{
 		Group: group,
 		Kind:  kind,
 	}
-	groupResources, err := cf.GetAPIGroupResources()
+	groupResources, err := cf.getGroupResources(group)
 	if err != nil {
 		cf.logger.Error(err, "failed to get APIGroupResource")
 		return resourceClient, err
 	}
 	restMapper := restmapper.NewDiscoveryRESTMapper(groupResources)
-	mappings, err := restMapper.RESTMappings(gk)
+	var versions []string
+	if version != "" {
+		versions = append(versions, version)
+	}
+	mappings, err := restMapper.RESTMappings(gk, versions...)
 	if err != nil {
 		cf.logger.Error(err, fmt.Sprintf("failed to get restMapping %s", gk.String()))
 		return resourceClient, err
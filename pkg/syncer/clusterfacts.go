@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"sort"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1client "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/typed/edge/v2alpha1"
+	edgev2alpha1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+)
+
+var (
+	nodesGVR = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	crdsGVR  = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+)
+
+// clusterFacts are the properties of the WEC that EdgePlacement
+// requirements are checked against.
+type clusterFacts struct {
+	kubernetesVersion string
+	architectures     []string
+	crds              []string
+}
+
+// gatherClusterFacts reads the facts from the WEC. A fact that can not be
+// read is left empty, which the where-resolver treats as unknown.
+func gatherClusterFacts(ctx context.Context, discoveryClient discovery.ServerVersionInterface, downstreamClient dynamic.Interface) clusterFacts {
+	logger := klog.FromContext(ctx)
+	var facts clusterFacts
+	if info, err := discoveryClient.ServerVersion(); err != nil {
+		logger.Error(err, "Failed to get Kubernetes version of WEC")
+	} else {
+		facts.kubernetesVersion = info.GitVersion
+	}
+	if nodes, err := downstreamClient.Resource(nodesGVR).List(ctx, metav1.ListOptions{}); err != nil {
+		logger.Error(err, "Failed to list nodes of WEC")
+	} else {
+		archs := sets.NewString()
+		for _, node := range nodes.Items {
+			if arch, _, _ := unstructured.NestedString(node.Object, "status", "nodeInfo", "architecture"); arch != "" {
+				archs.Insert(arch)
+			}
+		}
+		facts.architectures = archs.List()
+	}
+	if crds, err := downstreamClient.Resource(crdsGVR).List(ctx, metav1.ListOptions{}); err != nil {
+		logger.Error(err, "Failed to list CRDs of WEC")
+	} else {
+		for _, crd := range crds.Items {
+			facts.crds = append(facts.crds, crd.GetName())
+		}
+		sort.Strings(facts.crds)
+	}
+	return facts
+}
+
+// reportClusterFacts reports the facts of the WEC in the status of the
+// SyncerConfig, from where the mailbox controller copies them to the SyncTarget.
+func reportClusterFacts(ctx context.Context, discoveryClient discovery.ServerVersionInterface, downstreamClient dynamic.Interface,
+	syncerConfigClient edgev2alpha1client.SyncerConfigInterface, syncerConfigLister edgev2alpha1listers.SyncerConfigLister) {
+	logger := klog.FromContext(ctx)
+	facts := gatherClusterFacts(ctx, discoveryClient, downstreamClient)
+	syncerConfigs, err := syncerConfigLister.List(labels.Everything())
+	if err != nil {
+		logger.Error(err, "Failed to list SyncerConfigs")
+		return
+	}
+	for _, syncerConfig := range syncerConfigs {
+		status := &syncerConfig.Status
+		if status.KubernetesVersion == facts.kubernetesVersion &&
+			apiequality.Semantic.DeepEqual(status.Architectures, facts.architectures) &&
+			apiequality.Semantic.DeepEqual(status.CRDs, facts.crds) {
+			continue
+		}
+		syncerConfig = syncerConfig.DeepCopy()
+		syncerConfig.Status.KubernetesVersion = facts.kubernetesVersion
+		syncerConfig.Status.Architectures = facts.architectures
+		syncerConfig.Status.CRDs = facts.crds
+		if _, err := syncerConfigClient.UpdateStatus(ctx, syncerConfig, metav1.UpdateOptions{}); err != nil {
+			logger.Error(err, "Failed to report cluster facts", "syncerConfigName", syncerConfig.Name)
+		} else {
+			logger.V(2).Info("Reported cluster facts", "syncerConfigName", syncerConfig.Name, "kubernetesVersion", facts.kubernetesVersion,
+				"architectures", facts.architectures, "crds", len(facts.crds))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/about"
+	edgev2alpha1client "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/typed/edge/v2alpha1"
+	edgev2alpha1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+)
+
+// publishClusterIdentity publishes the cluster ID and ClusterSet name as
+// ClusterProperties in the WEC, adopting any values already there, and
+// reports the values in effect in the status of the SyncerConfig.
+func publishClusterIdentity(ctx context.Context, cfg *SyncerConfig, downstreamClient dynamic.Interface,
+	syncerConfigClient edgev2alpha1client.SyncerConfigInterface, syncerConfigLister edgev2alpha1listers.SyncerConfigLister) {
+	logger := klog.FromContext(ctx)
+	clusterID := cfg.ClusterID
+	if clusterID == "" {
+		var err error
+		if clusterID, err = about.DefaultClusterID(ctx, downstreamClient); err != nil {
+			logger.Error(err, "Failed to determine cluster ID")
+			return
+		}
+	}
+	clusterSet := cfg.ClusterSet
+	publish := func(name, value string) string {
+		var effective string
+		var err error
+		if value == "" {
+			effective, err = about.Get(ctx, downstreamClient, name)
+		} else {
+			effective, err = about.Publish(ctx, downstreamClient, name, value)
+		}
+		switch {
+		case errors.Is(err, about.ErrNotInstalled):
+			logger.V(3).Info("ClusterProperty API is not installed, not publishing", "property", name)
+			return value
+		case err != nil:
+			logger.Error(err, "Failed to publish ClusterProperty", "property", name)
+			return value
+		case value != "" && effective != value:
+			logger.Info("WEC already has a different ClusterProperty value; keeping it", "property", name, "existing", effective, "configured", value)
+		}
+		return effective
+	}
+	clusterID = publish(about.ClusterIDPropertyName, clusterID)
+	clusterSet = publish(about.ClusterSetPropertyName, clusterSet)
+
+	syncerConfigs, err := syncerConfigLister.List(labels.Everything())
+	if err != nil {
+		logger.Error(err, "Failed to list SyncerConfigs")
+		return
+	}
+	for _, syncerConfig := range syncerConfigs {
+		if syncerConfig.Status.ClusterID == clusterID && syncerConfig.Status.ClusterSet == clusterSet {
+			continue
+		}
+		syncerConfig = syncerConfig.DeepCopy()
+		syncerConfig.Status.ClusterID, syncerConfig.Status.ClusterSet = clusterID, clusterSet
+		if _, err := syncerConfigClient.UpdateStatus(ctx, syncerConfig, metav1.UpdateOptions{}); err != nil {
+			logger.Error(err, "Failed to report cluster identity", "syncerConfigName", syncerConfig.Name)
+		} else {
+			logger.V(2).Info("Reported cluster identity", "syncerConfigName", syncerConfig.Name, "clusterID", clusterID, "clusterSet", clusterSet)
+		}
+	}
+}
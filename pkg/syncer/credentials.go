@@ -0,0 +1,223 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+var (
+	credentialRotations = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_syncer",
+		Name:           "credential_rotations_total",
+		Help:           "Number of times that new credentials for the upstream were picked up without restart",
+		StabilityLevel: metrics.ALPHA,
+	})
+	credentialReloadFailures = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_syncer",
+		Name:           "credential_reload_failures_total",
+		Help:           "Number of failed attempts to reload credentials for the upstream",
+		StabilityLevel: metrics.ALPHA,
+	})
+	credentialExpiry = metrics.NewGauge(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_syncer",
+		Name:           "credential_expiry_timestamp_seconds",
+		Help:           "Expiration time, in seconds since the Unix epoch, of the client certificate used for the upstream; zero if there is none",
+		StabilityLevel: metrics.ALPHA,
+	})
+)
+
+func init() {
+	legacyregistry.MustRegister(credentialRotations, credentialReloadFailures, credentialExpiry)
+}
+
+// CredentialReloader keeps the credentials used for an apiserver up to date
+// with the files that they come from, so that rotating credentials
+// does not require restarting the syncer.
+// The reloader periodically calls a given function to load the client config
+// and, when the credentials in it change, switches all the clients made from
+// the wrapped config over to the new credentials.
+type CredentialReloader struct {
+	logger         klog.Logger
+	load           func() (*rest.Config, error)
+	period         time.Duration
+	warnBeforeTime time.Duration
+
+	mutex       sync.RWMutex
+	fingerprint string
+	notAfter    time.Time
+	transport   http.RoundTripper
+}
+
+// NewCredentialReloader does an initial load and returns the reloader and the
+// config to use for making clients.
+// Returns a warning in the log when the client certificate is to expire
+// within `warnBeforeTime`.
+func NewCredentialReloader(logger klog.Logger, load func() (*rest.Config, error), period, warnBeforeTime time.Duration) (*CredentialReloader, *rest.Config, error) {
+	cr := &CredentialReloader{
+		logger:         logger,
+		load:           load,
+		period:         period,
+		warnBeforeTime: warnBeforeTime,
+	}
+	config, err := load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cr.install(config); err != nil {
+		return nil, nil, err
+	}
+	wrapped := rest.CopyConfig(config)
+	// The credentials come from the transport of the reloader. Any left in
+	// the wrapped config would be added outside of that transport and
+	// override the reloaded ones.
+	wrapped.BearerToken, wrapped.BearerTokenFile = "", ""
+	wrapped.Username, wrapped.Password = "", ""
+	wrapped.WrapTransport = func(http.RoundTripper) http.RoundTripper { return cr }
+	return cr, wrapped, nil
+}
+
+// RoundTrip implements http.RoundTripper by delegating to the transport
+// made from the latest credentials.
+func (cr *CredentialReloader) RoundTrip(req *http.Request) (*http.Response, error) {
+	cr.mutex.RLock()
+	transport := cr.transport
+	cr.mutex.RUnlock()
+	return transport.RoundTrip(req)
+}
+
+// Run polls for changes in the credentials until the context is done.
+func (cr *CredentialReloader) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		config, err := cr.load()
+		if err != nil {
+			credentialReloadFailures.Inc()
+			cr.logger.Error(err, "Failed to reload client config")
+			return
+		}
+		if err := cr.install(config); err != nil {
+			credentialReloadFailures.Inc()
+			cr.logger.Error(err, "Failed to install reloaded client config")
+		}
+		cr.checkExpiry()
+	}, cr.period)
+}
+
+func (cr *CredentialReloader) install(config *rest.Config) error {
+	fingerprint, notAfter := credentialFingerprint(config)
+	cr.mutex.RLock()
+	same := fingerprint == cr.fingerprint
+	cr.mutex.RUnlock()
+	if same {
+		return nil
+	}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return err
+	}
+	cr.mutex.Lock()
+	oldTransport := cr.transport
+	if oldTransport != nil {
+		credentialRotations.Inc()
+		cr.logger.Info("Switched to new credentials", "notAfter", notAfter)
+	}
+	cr.fingerprint = fingerprint
+	cr.notAfter = notAfter
+	cr.transport = transport
+	if notAfter.IsZero() {
+		credentialExpiry.Set(0)
+	} else {
+		credentialExpiry.Set(float64(notAfter.Unix()))
+	}
+	cr.mutex.Unlock()
+	if oldTransport != nil {
+		// Requests in flight finish on the old transport, but its idle
+		// connections would otherwise stay open for the rest of the run.
+		utilnet.CloseIdleConnectionsFor(oldTransport)
+	}
+	return nil
+}
+
+func (cr *CredentialReloader) checkExpiry() {
+	cr.mutex.RLock()
+	notAfter := cr.notAfter
+	cr.mutex.RUnlock()
+	if notAfter.IsZero() {
+		return
+	}
+	if remaining := time.Until(notAfter); remaining < cr.warnBeforeTime {
+		cr.logger.Info("WARNING: client certificate is about to expire", "notAfter", notAfter, "remaining", remaining)
+	}
+}
+
+// credentialFingerprint returns a digest of the credentials in the given
+// config, including the contents of referenced files, and the expiration
+// time of the client certificate (zero if none or unparseable).
+func credentialFingerprint(config *rest.Config) (string, time.Time) {
+	hasher := sha256.New()
+	certData := config.TLSClientConfig.CertData
+	for _, part := range [][]byte{
+		[]byte(config.Host),
+		[]byte(config.BearerToken),
+		readFileOrNil(config.BearerTokenFile),
+		certData,
+		readFileOrNil(config.TLSClientConfig.CertFile),
+		config.TLSClientConfig.KeyData,
+		readFileOrNil(config.TLSClientConfig.KeyFile),
+		config.TLSClientConfig.CAData,
+		readFileOrNil(config.TLSClientConfig.CAFile),
+	} {
+		hasher.Write(part)
+		hasher.Write([]byte{0})
+	}
+	if len(certData) == 0 {
+		certData = readFileOrNil(config.TLSClientConfig.CertFile)
+	}
+	var notAfter time.Time
+	if block, _ := pem.Decode(certData); block != nil {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			notAfter = cert.NotAfter
+		}
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), notAfter
+}
+
+func readFileOrNil(filename string) []byte {
+	if filename == "" {
+		return nil
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	return content
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+func TestCredentialReloaderRotatesToken(t *testing.T) {
+	var mutex sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		seen = append(seen, req.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	token := "first"
+	load := func() (*rest.Config, error) {
+		return &rest.Config{Host: server.URL, BearerToken: token}, nil
+	}
+	reloader, wrapped, err := NewCredentialReloader(klog.Background(), load, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := rest.HTTPClientFor(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func() {
+		t.Helper()
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	get()
+	token = "second"
+	config, _ := load()
+	if err := reloader.install(config); err != nil {
+		t.Fatal(err)
+	}
+	get()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(seen) != 2 || seen[0] != "Bearer first" || seen[1] != "Bearer second" {
+		t.Errorf("expected the rotated token on the second request, got %q", seen)
+	}
+}
+
+func TestCredentialReloaderClosesIdleConnections(t *testing.T) {
+	var closed int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			atomic.AddInt32(&closed, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	token := "first"
+	load := func() (*rest.Config, error) {
+		return &rest.Config{Host: server.URL, BearerToken: token}, nil
+	}
+	reloader, wrapped, err := NewCredentialReloader(klog.Background(), load, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := rest.HTTPClientFor(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt32(&closed); got != 0 {
+		t.Fatalf("expected the connection to stay open before the rotation, got %d closed", got)
+	}
+
+	token = "second"
+	config, _ := load()
+	if err := reloader.install(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return atomic.LoadInt32(&closed) > 0, nil
+	}); err != nil {
+		t.Errorf("expected the idle connection of the old transport to be closed after the rotation")
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"os"
+	"runtime/debug"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// The settings of the low-memory mode, meant for single-node edge
+// machines with less than 1 GB of RAM. In this mode the syncer also
+// trims its informer caches (see TrimForCache) and discovers APIs on
+// demand (see clientfactory.ClientFactory.SetOnDemandDiscovery).
+const (
+	// LowMemoryInitialSyncParallelism is the default number of objects
+	// that the bulk initial sync applies at once in low-memory mode.
+	LowMemoryInitialSyncParallelism = 2
+
+	// LowMemoryInitialSyncPageSize is the default number of objects that
+	// the bulk initial sync lists per request in low-memory mode.
+	LowMemoryInitialSyncPageSize = 50
+
+	// LowMemoryGCPercent is the garbage collection target percentage
+	// used in low-memory mode unless the GOGC environment variable is set.
+	LowMemoryGCPercent = 50
+)
+
+// TrimForCache is an informer transform that drops the parts of an
+// object's metadata that the syncer never reads, so that the informer
+// caches take less memory.
+func TrimForCache(obj any) (any, error) {
+	if mObj, ok := obj.(metav1.Object); ok {
+		mObj.SetManagedFields(nil)
+		if annotations := mObj.GetAnnotations(); annotations != nil {
+			delete(annotations, corev1.LastAppliedConfigAnnotation)
+		}
+	}
+	return obj, nil
+}
+
+// ApplyMemorySettings configures the Go runtime for the given mode.
+// A positive memoryLimit, in bytes, becomes the soft memory limit of
+// the process; the garbage collector works harder as it is approached.
+func ApplyMemorySettings(logger klog.Logger, lowMemory bool, memoryLimit int64) {
+	if memoryLimit > 0 {
+		debug.SetMemoryLimit(memoryLimit)
+		logger.V(1).Info("Set soft memory limit", "bytes", memoryLimit)
+	}
+	if lowMemory {
+		if _, set := os.LookupEnv("GOGC"); !set {
+			debug.SetGCPercent(LowMemoryGCPercent)
+		}
+		logger.V(1).Info("Running in low-memory mode")
+	}
+}
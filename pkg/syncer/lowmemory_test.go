@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestTrimForCache(t *testing.T) {
+	sc := &edgev2alpha1.SyncerConfig{ObjectMeta: metav1.ObjectMeta{
+		Name:          "the-one",
+		Annotations:   map[string]string{corev1.LastAppliedConfigAnnotation: "{}", "keep": "me"},
+		ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+	}}
+	got, err := TrimForCache(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trimmed := got.(*edgev2alpha1.SyncerConfig)
+	if trimmed.Name != "the-one" || len(trimmed.ManagedFields) != 0 || len(trimmed.Annotations) != 1 || trimmed.Annotations["keep"] != "me" {
+		t.Errorf("Unexpected trimmed object %#v", trimmed.ObjectMeta)
+	}
+
+	// Tombstones are passed through
+	tombstone := cache.DeletedFinalStateUnknown{Key: "x", Obj: sc}
+	if got, err := TrimForCache(tombstone); err != nil || got != tombstone {
+		t.Errorf("Expected the tombstone back, got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,478 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package manifestdir lets the syncer serve a WEC that has no apiserver
+// for it to talk to, by keeping the downsynced objects as manifest files
+// in a directory that something else applies. For example, k3s applies
+// the files in /var/lib/rancher/k3s/server/manifests, and a standalone
+// kubelet runs the Pods in its static pod directory.
+//
+// A Store offers the dynamic and discovery clients that the syncer uses
+// for the WEC. Only the resources in Resources are supported. Since
+// nothing reports the status of an object in the directory, the status
+// of the workload objects is synthesized: the "Applied" condition says
+// that the manifest is in the directory and the "Ready" condition comes
+// from the local probe named by the object's ProbeAnnotationKey
+// annotation (see Prober).
+package manifestdir
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// filePrefix starts the name of every file that a Store writes;
+	// other files in the directory are left alone.
+	filePrefix = "kubestellar_"
+
+	// revisionAnnotationKey and uidAnnotationKey hold, in the files, the
+	// resourceVersion and uid of the objects. These can not be kept in
+	// the metadata, where they would get in the way of applying the files.
+	revisionAnnotationKey = "manifests.edge.kubestellar.io/revision"
+	uidAnnotationKey      = "manifests.edge.kubestellar.io/uid"
+)
+
+var verbs = metav1.Verbs{"create", "delete", "get", "list", "update"}
+
+// Resources are the resources that a Store supports. The workload
+// resources have a status subresource, whose content is synthesized.
+var Resources = []*metav1.APIResourceList{
+	{GroupVersion: "v1", APIResources: []metav1.APIResource{
+		{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: verbs},
+		{Name: "namespaces", Kind: "Namespace", Verbs: verbs},
+		{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: verbs},
+		{Name: "pods/status", Kind: "Pod", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+		{Name: "secrets", Kind: "Secret", Namespaced: true, Verbs: verbs},
+		{Name: "serviceaccounts", Kind: "ServiceAccount", Namespaced: true, Verbs: verbs},
+		{Name: "services", Kind: "Service", Namespaced: true, Verbs: verbs},
+	}},
+	{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+		{Name: "daemonsets", Kind: "DaemonSet", Namespaced: true, Verbs: verbs},
+		{Name: "daemonsets/status", Kind: "DaemonSet", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+		{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: verbs},
+		{Name: "deployments/status", Kind: "Deployment", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+		{Name: "statefulsets", Kind: "StatefulSet", Namespaced: true, Verbs: verbs},
+		{Name: "statefulsets/status", Kind: "StatefulSet", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+	}},
+	{GroupVersion: "batch/v1", APIResources: []metav1.APIResource{
+		{Name: "jobs", Kind: "Job", Namespaced: true, Verbs: verbs},
+		{Name: "jobs/status", Kind: "Job", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+	}},
+}
+
+// Store keeps objects as manifest files in a directory.
+type Store struct {
+	dir    string
+	prober *Prober
+
+	// mutex serializes the writes, so that the revisions are consistent.
+	mutex sync.Mutex
+}
+
+// NewStore returns a Store for the given directory, which is created if
+// it does not exist. The status of the workload objects comes from the
+// given Prober.
+func NewStore(dir string, prober *Prober) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to make manifest directory: %w", err)
+	}
+	return &Store{dir: dir, prober: prober}, nil
+}
+
+// Dynamic returns a dynamic client for the objects in the Store.
+// The Watch, Patch, Apply and DeleteCollection methods are not supported.
+func (st *Store) Dynamic() dynamic.Interface {
+	return storeDynamic{st}
+}
+
+// Discovery returns a discovery client that serves Resources.
+// Only the methods that list groups and resources are implemented.
+func (st *Store) Discovery() discovery.DiscoveryInterface {
+	return staticDiscovery{}
+}
+
+type storeDynamic struct {
+	store *Store
+}
+
+func (sd storeDynamic) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	rc := &resourceClient{store: sd.store, gvr: gvr}
+	for _, list := range Resources {
+		if list.GroupVersion != gvr.GroupVersion().String() {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			switch resource.Name {
+			case gvr.Resource:
+				rc.supported, rc.kind, rc.namespaced = true, resource.Kind, resource.Namespaced
+			case gvr.Resource + "/status":
+				rc.hasStatus = true
+			}
+		}
+	}
+	return rc
+}
+
+// resourceClient is the client of one resource, in one namespace or in
+// all namespaces.
+type resourceClient struct {
+	store      *Store
+	gvr        schema.GroupVersionResource
+	supported  bool
+	kind       string
+	namespaced bool
+	hasStatus  bool
+	namespace  string
+}
+
+var _ dynamic.NamespaceableResourceInterface = &resourceClient{}
+
+func (rc *resourceClient) Namespace(namespace string) dynamic.ResourceInterface {
+	ans := *rc
+	ans.namespace = namespace
+	return &ans
+}
+
+// fileName returns the name of the file of the object of the given name.
+// The parts are joined with underscores, which can not occur in them.
+func (rc *resourceClient) fileName(name string) string {
+	return rc.filePrefix() + rc.namespace + "_" + name + ".yaml"
+}
+
+func (rc *resourceClient) filePrefix() string {
+	group := rc.gvr.Group
+	if group == "" {
+		group = "core"
+	}
+	return filePrefix + group + "_" + rc.gvr.Resource + "_"
+}
+
+func (rc *resourceClient) check(name string, subresources []string) error {
+	if !rc.supported {
+		return apierrors.NewBadRequest(fmt.Sprintf("resource %s is not supported in a manifest directory", rc.gvr))
+	}
+	if len(subresources) > 0 {
+		return apierrors.NewMethodNotSupported(rc.gvr.GroupResource(), strings.Join(subresources, "/"))
+	}
+	if rc.namespaced != (rc.namespace != "") {
+		return apierrors.NewBadRequest(fmt.Sprintf("%s %q: namespace is required for namespaced resources and forbidden for others", rc.gvr.Resource, name))
+	}
+	return nil
+}
+
+func (rc *resourceClient) Create(ctx context.Context, obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := rc.check(obj.GetName(), subresources); err != nil {
+		return nil, err
+	}
+	rc.store.mutex.Lock()
+	defer rc.store.mutex.Unlock()
+	if _, err := rc.read(obj.GetName()); err == nil {
+		return nil, apierrors.NewAlreadyExists(rc.gvr.GroupResource(), obj.GetName())
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	obj = obj.DeepCopy()
+	obj.SetUID(uuid.NewUUID())
+	obj.SetCreationTimestamp(metav1.Now())
+	obj.SetResourceVersion("1")
+	return rc.write(obj, len(options.DryRun) > 0)
+}
+
+func (rc *resourceClient) Update(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := rc.check(obj.GetName(), subresources); err != nil {
+		return nil, err
+	}
+	rc.store.mutex.Lock()
+	defer rc.store.mutex.Unlock()
+	current, err := rc.read(obj.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if rv := obj.GetResourceVersion(); rv != "" && rv != current.GetResourceVersion() {
+		return nil, apierrors.NewConflict(rc.gvr.GroupResource(), obj.GetName(), fmt.Errorf("the object has been modified"))
+	}
+	revision, _ := strconv.ParseInt(current.GetResourceVersion(), 10, 64)
+	obj = obj.DeepCopy()
+	obj.SetUID(current.GetUID())
+	obj.SetCreationTimestamp(current.GetCreationTimestamp())
+	obj.SetResourceVersion(strconv.FormatInt(revision+1, 10))
+	return rc.write(obj, len(options.DryRun) > 0)
+}
+
+// UpdateStatus is not supported, since the status is synthesized.
+func (rc *resourceClient) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return nil, apierrors.NewMethodNotSupported(rc.gvr.GroupResource(), "updatestatus")
+}
+
+func (rc *resourceClient) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	if err := rc.check(name, subresources); err != nil {
+		return err
+	}
+	rc.store.mutex.Lock()
+	defer rc.store.mutex.Unlock()
+	if _, err := rc.read(name); err != nil {
+		return err
+	}
+	if len(options.DryRun) > 0 {
+		return nil
+	}
+	rc.store.prober.Forget(rc.probeKey(rc.namespace, name))
+	return os.Remove(filepath.Join(rc.store.dir, rc.fileName(name)))
+}
+
+func (rc *resourceClient) DeleteCollection(ctx context.Context, options metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	return apierrors.NewMethodNotSupported(rc.gvr.GroupResource(), "deletecollection")
+}
+
+func (rc *resourceClient) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if len(subresources) == 1 && subresources[0] == "status" && rc.hasStatus {
+		subresources = nil
+	}
+	if err := rc.check(name, subresources); err != nil {
+		return nil, err
+	}
+	obj, err := rc.read(name)
+	if err != nil {
+		return nil, err
+	}
+	rc.addStatus(obj)
+	return obj, nil
+}
+
+// List lists all the objects at once; the limit of the options is ignored.
+func (rc *resourceClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if !rc.supported {
+		return nil, rc.check("", nil)
+	}
+	selector := labels.Everything()
+	if opts.LabelSelector != "" {
+		var err error
+		if selector, err = labels.Parse(opts.LabelSelector); err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	}
+	prefix := rc.filePrefix()
+	if rc.namespace != "" {
+		prefix += rc.namespace + "_"
+	}
+	entries, err := os.ReadDir(rc.store.dir)
+	if err != nil {
+		return nil, err
+	}
+	ans := &unstructured.UnstructuredList{}
+	ans.SetAPIVersion(rc.gvr.GroupVersion().String())
+	ans.SetKind(rc.kind + "List")
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		obj, err := readFile(filepath.Join(rc.store.dir, entry.Name()))
+		if err != nil {
+			if os.IsNotExist(err) { // deleted since the directory was read
+				continue
+			}
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		rc.addStatus(obj)
+		ans.Items = append(ans.Items, *obj)
+	}
+	sort.Slice(ans.Items, func(i, j int) bool {
+		return ans.Items[i].GetNamespace()+"/"+ans.Items[i].GetName() < ans.Items[j].GetNamespace()+"/"+ans.Items[j].GetName()
+	})
+	return ans, nil
+}
+
+func (rc *resourceClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return nil, apierrors.NewMethodNotSupported(rc.gvr.GroupResource(), "watch")
+}
+
+func (rc *resourceClient) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, apierrors.NewMethodNotSupported(rc.gvr.GroupResource(), "patch")
+}
+
+func (rc *resourceClient) Apply(ctx context.Context, name string, obj *unstructured.Unstructured, options metav1.ApplyOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, apierrors.NewMethodNotSupported(rc.gvr.GroupResource(), "apply")
+}
+
+func (rc *resourceClient) ApplyStatus(ctx context.Context, name string, obj *unstructured.Unstructured, options metav1.ApplyOptions) (*unstructured.Unstructured, error) {
+	return nil, apierrors.NewMethodNotSupported(rc.gvr.GroupResource(), "applystatus")
+}
+
+// read returns the stored object of the given name, without status.
+func (rc *resourceClient) read(name string) (*unstructured.Unstructured, error) {
+	obj, err := readFile(filepath.Join(rc.store.dir, rc.fileName(name)))
+	if os.IsNotExist(err) {
+		return nil, apierrors.NewNotFound(rc.gvr.GroupResource(), name)
+	}
+	return obj, err
+}
+
+// write stores the given object, unless dryRun, and returns what is stored.
+// The file is replaced by renaming, so that its readers never see a partial one.
+func (rc *resourceClient) write(obj *unstructured.Unstructured, dryRun bool) (*unstructured.Unstructured, error) {
+	obj.SetAPIVersion(rc.gvr.GroupVersion().String())
+	obj.SetKind(rc.kind)
+	obj.SetNamespace(rc.namespace)
+	if dryRun {
+		rc.addStatus(obj)
+		return obj, nil
+	}
+	content := obj.DeepCopy()
+	unstructured.RemoveNestedField(content.Object, "status")
+	annotations := content.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[revisionAnnotationKey] = content.GetResourceVersion()
+	annotations[uidAnnotationKey] = string(content.GetUID())
+	content.SetAnnotations(annotations)
+	content.SetResourceVersion("")
+	content.SetUID("")
+	content.SetGeneration(0)
+	content.SetManagedFields(nil)
+	data, err := yaml.Marshal(content.Object)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(rc.store.dir, rc.fileName(obj.GetName()))
+	// A suffix other than .yaml, so that the appliers ignore the temporary file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, err
+	}
+	rc.addStatus(obj)
+	return obj, nil
+}
+
+func readFile(path string) (*unstructured.Unstructured, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(jsonData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	annotations := obj.GetAnnotations()
+	obj.SetResourceVersion(annotations[revisionAnnotationKey])
+	obj.SetUID(types.UID(annotations[uidAnnotationKey]))
+	delete(annotations, revisionAnnotationKey)
+	delete(annotations, uidAnnotationKey)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+	return obj, nil
+}
+
+// addStatus sets the synthesized status of the given object, if its
+// resource has a status.
+func (rc *resourceClient) addStatus(obj *unstructured.Unstructured) {
+	if !rc.hasStatus {
+		return
+	}
+	applied := metav1.Condition{
+		Type:               "Applied",
+		Status:             metav1.ConditionTrue,
+		Reason:             "InManifestDirectory",
+		Message:            "the manifest is in " + rc.store.dir,
+		LastTransitionTime: obj.GetCreationTimestamp(),
+	}
+	ready := rc.store.prober.Condition(rc.probeKey(obj.GetNamespace(), obj.GetName()), obj.GetAnnotations()[ProbeAnnotationKey])
+	conditions := []interface{}{conditionToUnstructured(applied), conditionToUnstructured(ready)}
+	_ = unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions")
+}
+
+func (rc *resourceClient) probeKey(namespace, name string) string {
+	return rc.gvr.GroupResource().String() + "/" + namespace + "/" + name
+}
+
+func conditionToUnstructured(cond metav1.Condition) map[string]interface{} {
+	return map[string]interface{}{
+		"type":               cond.Type,
+		"status":             string(cond.Status),
+		"reason":             cond.Reason,
+		"message":            cond.Message,
+		"lastTransitionTime": cond.LastTransitionTime.UTC().Format(time.RFC3339),
+	}
+}
+
+// staticDiscovery serves Resources. The methods that it does not
+// implement are those of the nil embedded interface, and so panic.
+type staticDiscovery struct {
+	discovery.DiscoveryInterface
+}
+
+func (staticDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	ans := &metav1.APIGroupList{}
+	for _, list := range Resources {
+		gv, _ := schema.ParseGroupVersion(list.GroupVersion)
+		version := metav1.GroupVersionForDiscovery{GroupVersion: list.GroupVersion, Version: gv.Version}
+		ans.Groups = append(ans.Groups, metav1.APIGroup{Name: gv.Group, Versions: []metav1.GroupVersionForDiscovery{version}, PreferredVersion: version})
+	}
+	return ans, nil
+}
+
+func (staticDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	for _, list := range Resources {
+		if list.GroupVersion == groupVersion {
+			return list.DeepCopy(), nil
+		}
+	}
+	gv, _ := schema.ParseGroupVersion(groupVersion)
+	return nil, apierrors.NewNotFound(gv.WithResource("").GroupResource(), "")
+}
+
+func (sd staticDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	groupList, _ := sd.ServerGroups()
+	groups := make([]*metav1.APIGroup, len(groupList.Groups))
+	resources := make([]*metav1.APIResourceList, len(Resources))
+	for idx := range groupList.Groups {
+		groups[idx] = &groupList.Groups[idx]
+		resources[idx] = Resources[idx].DeepCopy()
+	}
+	return groups, resources, nil
+}
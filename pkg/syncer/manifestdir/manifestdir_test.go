@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifestdir
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var deployments = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+func newDeployment(name, probe string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": name, "labels": map[string]interface{}{"app": name}},
+		"spec":       map[string]interface{}{"replicas": int64(1)},
+	}}
+	if probe != "" {
+		obj.SetAnnotations(map[string]string{ProbeAnnotationKey: probe})
+	}
+	return obj
+}
+
+func readyCondition(t *testing.T, obj *unstructured.Unstructured) map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, cond := range conditions {
+		if cond := cond.(map[string]interface{}); cond["type"] == "Ready" {
+			return cond
+		}
+	}
+	t.Fatalf("No Ready condition in %v", obj.Object)
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+	store, err := NewStore(dir, NewProber(clock, time.Second, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := store.Dynamic().Resource(deployments).Namespace("shop")
+	readyFile := filepath.Join(dir, "ready")
+
+	created, err := client.Create(ctx, newDeployment("web", "file://"+readyFile), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.GetResourceVersion() != "1" || created.GetUID() == "" || created.GetNamespace() != "shop" {
+		t.Errorf("Unexpected created object %v", created.Object)
+	}
+	if cond := readyCondition(t, created); cond["status"] != string(metav1.ConditionFalse) {
+		t.Errorf("Expected a failed probe before the file exists, got %v", cond)
+	}
+	if _, err := client.Create(ctx, newDeployment("web", ""), metav1.CreateOptions{}); !apierrors.IsAlreadyExists(err) {
+		t.Errorf("Expected AlreadyExists, got %v", err)
+	}
+
+	// The file holds an applicable manifest
+	data, err := os.ReadFile(filepath.Join(dir, "kubestellar_apps_deployments_shop_web.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := string(data); strings.Contains(text, "resourceVersion") || strings.Contains(text, "status") {
+		t.Errorf("Expected no resourceVersion or status in the manifest, got\n%s", text)
+	}
+
+	// Probe results are reused until they are old
+	if err := os.WriteFile(readyFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond := readyCondition(t, got); cond["status"] != string(metav1.ConditionFalse) {
+		t.Errorf("Expected the cached probe result, got %v", cond)
+	}
+	clock.SetTime(clock.Now().Add(2 * time.Minute))
+	got, err = client.Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond := readyCondition(t, got); cond["status"] != string(metav1.ConditionTrue) {
+		t.Errorf("Expected a succeeded probe, got %v", cond)
+	}
+
+	stale := got.DeepCopy()
+	got.Object["spec"] = map[string]interface{}{"replicas": int64(2)}
+	updated, err := client.Update(ctx, got, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetResourceVersion() != "2" || updated.GetUID() != created.GetUID() {
+		t.Errorf("Unexpected updated object %v", updated.Object)
+	}
+	if _, err := client.Update(ctx, stale, metav1.UpdateOptions{}); !apierrors.IsConflict(err) {
+		t.Errorf("Expected a Conflict, got %v", err)
+	}
+
+	if _, err := store.Dynamic().Resource(deployments).Namespace("other").Create(ctx, newDeployment("db", ""), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := store.Dynamic().Resource(deployments).List(ctx, metav1.ListOptions{LabelSelector: "app=web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].GetName() != "web" {
+		t.Errorf("Expected only web, got %v", list.Items)
+	}
+	if list, err = client.List(ctx, metav1.ListOptions{}); err != nil || len(list.Items) != 1 {
+		t.Errorf("Expected one Deployment in namespace shop, got %v, %v", list, err)
+	}
+
+	if err := client.Delete(ctx, "web", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(ctx, "web", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+
+	crontabs := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "crontabs"}
+	if _, err := store.Dynamic().Resource(crontabs).Namespace("shop").List(ctx, metav1.ListOptions{}); err == nil {
+		t.Error("Expected an unsupported resource to be rejected")
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	store, err := NewStore(t.TempDir(), NewProber(clocktesting.NewFakePassiveClock(time.Now()), time.Second, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupResources, err := restmapper.GetAPIGroupResources(store.Discovery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: "apps", Kind: "Deployment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.Resource != deployments {
+		t.Errorf("Expected %v, got %v", deployments, mapping.Resource)
+	}
+	if _, err := mapper.RESTMapping(schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}); err == nil {
+		t.Error("Expected ReplicaSets not to be served")
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifestdir
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+)
+
+// ProbeAnnotationKey is the annotation of a workload object that names
+// its local probe, as a URL. An "http" or "https" URL succeeds when a GET
+// returns a 2xx or 3xx code; a "tcp://host:port" URL succeeds when a
+// connection can be made; a "file:///path" URL succeeds when the file exists.
+const ProbeAnnotationKey = "edge.kubestellar.io/probe"
+
+// Prober runs the local probes and turns their results into "Ready"
+// conditions. Results are reused for a while, because the syncer reads
+// the objects often.
+type Prober struct {
+	clock      clock.PassiveClock
+	timeout    time.Duration
+	maxAge     time.Duration
+	httpClient *http.Client
+
+	mutex   sync.Mutex
+	results map[string]probeResult // by object
+}
+
+type probeResult struct {
+	target    string
+	condition metav1.Condition
+	probedAt  time.Time
+}
+
+// NewProber returns a Prober whose probes take at most the given timeout
+// and whose results are reused for maxAge.
+func NewProber(clock clock.PassiveClock, timeout, maxAge time.Duration) *Prober {
+	return &Prober{
+		clock:      clock,
+		timeout:    timeout,
+		maxAge:     maxAge,
+		httpClient: &http.Client{Timeout: timeout},
+		results:    map[string]probeResult{},
+	}
+}
+
+// Condition returns the "Ready" condition of the object identified by the
+// given key, whose probe is the given target; an empty target means none.
+func (pr *Prober) Condition(key, target string) metav1.Condition {
+	now := pr.clock.Now()
+	pr.mutex.Lock()
+	previous, found := pr.results[key]
+	pr.mutex.Unlock()
+	if found && previous.target == target && now.Sub(previous.probedAt) < pr.maxAge {
+		return previous.condition
+	}
+	cond := metav1.Condition{Type: "Ready", Status: metav1.ConditionUnknown, Reason: "NoProbe",
+		Message: "the object has no " + ProbeAnnotationKey + " annotation"}
+	if target != "" {
+		if err := pr.probe(target); err != nil {
+			cond.Status, cond.Reason, cond.Message = metav1.ConditionFalse, "ProbeFailed", err.Error()
+		} else {
+			cond.Status, cond.Reason, cond.Message = metav1.ConditionTrue, "ProbeSucceeded", "probe "+target+" succeeded"
+		}
+	}
+	cond.LastTransitionTime = metav1.NewTime(now)
+	if found && previous.condition.Status == cond.Status {
+		cond.LastTransitionTime = previous.condition.LastTransitionTime
+	}
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.results[key] = probeResult{target: target, condition: cond, probedAt: now}
+	return cond
+}
+
+// Forget drops what is remembered about the object identified by the given key.
+func (pr *Prober) Forget(key string) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	delete(pr.results, key)
+}
+
+func (pr *Prober) probe(target string) error {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("malformed probe %q: %w", target, err)
+	}
+	switch parsed.Scheme {
+	case "http", "https":
+		resp, err := pr.httpClient.Get(target)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("probe %s returned %s", target, resp.Status)
+		}
+		return nil
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", parsed.Host, pr.timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "file":
+		_, err := os.Stat(parsed.Path)
+		return err
+	}
+	return fmt.Errorf("probe %q has an unsupported scheme", target)
+}
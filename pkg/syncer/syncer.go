@@ -22,17 +22,24 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/version"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
 	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
 	edgeinformers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions"
+	"github.com/kubestellar/kubestellar/pkg/localprobes"
+	"github.com/kubestellar/kubestellar/pkg/revisions"
 	"github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
 	"github.com/kubestellar/kubestellar/pkg/syncer/controller"
+	"github.com/kubestellar/kubestellar/pkg/syncer/manifestdir"
 	"github.com/kubestellar/kubestellar/pkg/syncer/syncers"
 )
 
@@ -42,12 +49,77 @@ type SyncerConfig struct {
 	SyncTargetName   string
 	SyncTargetUID    string
 	Interval         time.Duration
+
+	// InitialSyncParallelism is how many objects the bulk initial sync
+	// applies at once; zero means not to do a bulk initial sync.
+	InitialSyncParallelism int
+
+	// InitialSyncPageSize is how many objects the bulk initial sync lists
+	// per request; zero means not to page.
+	InitialSyncPageSize int64
+
+	// ResourcePolicies give the priority and sync interval of particular
+	// resources; see ResourcePolicy.
+	ResourcePolicies map[schema.GroupResource]ResourcePolicy
+
+	// LocalOverridesNamespace and LocalOverridesName identify the ConfigMap
+	// in the WEC that holds the local override rules; see
+	// syncers.LocalOverrideRule. An empty name means no local overrides.
+	LocalOverridesNamespace string
+	LocalOverridesName      string
+
+	// RevisionHistoryNamespace, if not empty, is the namespace in the
+	// upstream space in which to keep the recent revisions of the
+	// downsynced objects; see package revisions.
+	RevisionHistoryNamespace string
+
+	// RevisionHistoryLimit is the number of revisions kept per object.
+	RevisionHistoryLimit int
+
+	// StatusLimit limits the status writes to the upstream space for the
+	// resources not in StatusLimits; see syncers.StatusLimiter.
+	StatusLimit syncers.StatusLimit
+
+	// StatusLimits are the status limits of particular resources.
+	StatusLimits map[schema.GroupResource]syncers.StatusLimit
+
+	// ClusterID and ClusterSet are published as ClusterProperties in the
+	// WEC; see package about. An empty ClusterID means the default one.
+	ClusterID  string
+	ClusterSet string
+
+	// LowMemory asks for the informer caches to be trimmed and for APIs
+	// to be discovered on demand, to save memory; see lowmemory.go.
+	LowMemory bool
+
+	// ManifestDir, if not empty, is the directory in which to keep the
+	// downsynced objects as manifest files, for a WEC without an apiserver
+	// for the syncer; see package manifestdir. DownstreamConfig is not
+	// used then.
+	ManifestDir string
+
+	// LocalProbeMaxAge is how long a result of a LocalProbe is reused
+	// (see package localprobes); zero disables the probes. The `exec`
+	// probes fail when ManifestDir is used.
+	LocalProbeMaxAge time.Duration
 }
 
 const (
-	resyncPeriod    = 10 * time.Hour
-	defaultInterval = time.Second * 15
-	minimumInterval = time.Second * 1
+	resyncPeriod = 10 * time.Hour
+	// clusterIdentityPeriod is how often the ClusterProperties and the
+	// cluster facts are checked, so that later changes are noticed.
+	clusterIdentityPeriod = 5 * time.Minute
+	defaultInterval       = time.Second * 15
+	minimumInterval       = time.Second * 1
+	// policyResolvePeriod is how often the resource policies and status
+	// limits are mapped to kinds again, and policyRetryPeriod is how often
+	// at most when a synced kind is not known yet; see kindResolution.
+	policyResolvePeriod = 10 * time.Minute
+	policyRetryPeriod   = time.Minute
+	// probeTimeout and probeMaxAge are the timeout and the reuse period of
+	// the local probes, when the objects are kept in a manifest directory.
+	probeTimeout = 2 * time.Second
+	probeMaxAge  = 30 * time.Second
 )
 
 func RunSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) error {
@@ -68,6 +140,11 @@ func RunSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) err
 	// syncConfigInformerFactory to watch a certain syncConfig on upstream
 	syncConfigInformerFactory := edgeinformers.NewSharedScopedInformerFactoryWithOptions(syncConfigClientSet, resyncPeriod)
 	syncConfigAccess := syncConfigInformerFactory.Edge().V2alpha1().EdgeSyncConfigs()
+	if cfg.LowMemory {
+		if err := syncConfigAccess.Informer().SetTransform(TrimForCache); err != nil {
+			return err
+		}
+	}
 
 	syncConfigAccess.Lister().List(labels.Everything()) // TODO: Remove (for now, need to invoke List at once)
 
@@ -83,6 +160,11 @@ func RunSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) err
 	// syncerConfigInformerFactory to watch a certain syncConfig on upstream
 	syncerConfigInformerFactory := edgeinformers.NewSharedScopedInformerFactoryWithOptions(syncerConfigClientSet, resyncPeriod)
 	syncerConfigAccess := syncerConfigInformerFactory.Edge().V2alpha1().SyncerConfigs()
+	if cfg.LowMemory {
+		if err := syncerConfigAccess.Informer().SetTransform(TrimForCache); err != nil {
+			return err
+		}
+	}
 
 	syncerConfigAccess.Lister().List(labels.Everything()) // TODO: Remove (for now, need to invoke List at once)
 
@@ -100,18 +182,38 @@ func RunSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) err
 	if err != nil {
 		return err
 	}
+	upstreamClientFactory.SetOnDemandDiscovery(cfg.LowMemory)
 
-	downstreamConfig := rest.CopyConfig(cfg.DownstreamConfig)
-	rest.AddUserAgent(downstreamConfig, "kubestellar#syncer/"+kcpVersion)
-	downstreamDynamicClient, err := dynamic.NewForConfig(downstreamConfig)
-	if err != nil {
-		return err
+	var downstreamDynamicClient dynamic.Interface
+	var downstreamDiscoveryClient discovery.DiscoveryInterface
+	var podExecutor localprobes.Executor
+	if cfg.ManifestDir != "" {
+		store, err := manifestdir.NewStore(cfg.ManifestDir, manifestdir.NewProber(clock.RealClock{}, probeTimeout, probeMaxAge))
+		if err != nil {
+			return err
+		}
+		downstreamDynamicClient, downstreamDiscoveryClient = store.Dynamic(), store.Discovery()
+	} else {
+		downstreamConfig := rest.CopyConfig(cfg.DownstreamConfig)
+		rest.AddUserAgent(downstreamConfig, "kubestellar#syncer/"+kcpVersion)
+		downstreamDynamicClient, err = dynamic.NewForConfig(downstreamConfig)
+		if err != nil {
+			return err
+		}
+		downstreamDiscoveryClient = discovery.NewDiscoveryClientForConfigOrDie(downstreamConfig)
+		if cfg.LocalProbeMaxAge > 0 {
+			executor, err := localprobes.NewPodExecutor(downstreamConfig)
+			if err != nil {
+				return err
+			}
+			podExecutor = executor
+		}
 	}
-	downstreamDiscoveryClient := discovery.NewDiscoveryClientForConfigOrDie(downstreamConfig)
 	downstreamClientFactory, err := clientfactory.NewClientFactory(logger, downstreamDynamicClient, downstreamDiscoveryClient)
 	if err != nil {
 		return err
 	}
+	downstreamClientFactory.SetOnDemandDiscovery(cfg.LowMemory)
 
 	upSyncer, err := syncers.NewUpSyncer(logger, upstreamClientFactory, downstreamClientFactory, []edgev2alpha1.EdgeSyncConfigResource{}, []edgev2alpha1.EdgeSynConversion{})
 	if err != nil {
@@ -122,6 +224,35 @@ func RunSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) err
 		return err
 	}
 
+	if cfg.RevisionHistoryNamespace != "" {
+		upstreamKubeClient, err := kubernetes.NewForConfig(upstreamConfig)
+		if err != nil {
+			return err
+		}
+		revisionStore := revisions.Open(upstreamKubeClient.CoreV1(), cfg.RevisionHistoryNamespace, cfg.RevisionHistoryLimit)
+		if err := revisionStore.EnsureNamespace(ctx); err != nil {
+			return fmt.Errorf("failed to ensure revision history namespace %q: %w", cfg.RevisionHistoryNamespace, err)
+		}
+		downSyncer.SetRevisionStore(revisionStore, cfg.SyncTargetName)
+	}
+
+	statusLimiter := syncers.NewStatusLimiter(clock.RealClock{}, cfg.StatusLimit)
+	downSyncer.SetStatusLimiter(statusLimiter)
+
+	if cfg.LocalProbeMaxAge > 0 {
+		downSyncer.SetProbeRunner(localprobes.NewRunner(clock.RealClock{}, cfg.LocalProbeMaxAge, podExecutor))
+	}
+
+	// A manifest directory has no cluster identity or facts to report
+	if cfg.ManifestDir == "" {
+		go wait.UntilWithContext(ctx, func(ctx context.Context) {
+			publishClusterIdentity(ctx, cfg, downstreamDynamicClient, syncerConfigClient, syncerConfigAccess.Lister())
+			reportClusterFacts(ctx, downstreamDiscoveryClient, downstreamDynamicClient, syncerConfigClient, syncerConfigAccess.Lister())
+		}, clusterIdentityPeriod)
+	}
+
+	unbundler := syncers.NewUnbundler(logger, upstreamClientFactory, downstreamClientFactory)
+
 	syncConfigManager := controller.NewSyncConfigManager(logger)
 	syncConfigController, err := controller.NewEdgeSyncConfigController(logger, syncConfigClient, syncConfigAccess, syncConfigManager, upSyncer, downSyncer, 5*time.Second)
 	if err != nil {
@@ -136,17 +267,21 @@ func RunSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) err
 
 	go syncConfigController.Run(ctx, numSyncerThreads)
 	go syncerConfigController.Run(ctx, numSyncerThreads)
-	runSync(ctx, cfg, syncConfigManager, syncerConfigManager, upSyncer, downSyncer)
+	runSync(ctx, cfg, upstreamClientFactory, downstreamClientFactory, syncConfigManager, syncerConfigManager, upSyncer, downSyncer, unbundler, statusLimiter)
 	return nil
 }
 
-func runSync(ctx context.Context, cfg *SyncerConfig, syncConfigManager *controller.SyncConfigManager, syncerConfigManager *controller.SyncerConfigManager, upSyncer *syncers.UpSyncer, downSyncer *syncers.DownSyncer) {
+func runSync(ctx context.Context, cfg *SyncerConfig, upstreamClientFactory, downstreamClientFactory clientfactory.ClientFactory, syncConfigManager *controller.SyncConfigManager, syncerConfigManager *controller.SyncerConfigManager, upSyncer *syncers.UpSyncer, downSyncer *syncers.DownSyncer, unbundler *syncers.Unbundler, statusLimiter *syncers.StatusLimiter) {
 	logger := klog.FromContext(ctx)
 	logger.V(2).Info("Start sync")
 	interval := cfg.Interval
 	if interval < minimumInterval {
 		interval = defaultInterval
 	}
+	scheduler := newSyncScheduler(cfg.ResourcePolicies)
+	interval = scheduler.tickInterval(interval)
+	resolution := &kindResolution{maxAge: policyResolvePeriod, retryAge: policyRetryPeriod}
+	initialSyncDone := cfg.InitialSyncParallelism == 0
 	for {
 		select {
 		case <-ctx.Done():
@@ -161,15 +296,51 @@ func runSync(ctx context.Context, cfg *SyncerConfig, syncConfigManager *controll
 			conversions := syncConfigManager.GetConversions()
 			_ = downSyncer.ReInitializeClients(downSyncedResources, conversions)
 			_ = upSyncer.ReInitializeClients(upSyncedReousrces, conversions)
-			sync(logger.WithValues("actor", "DownSyncer:Sync"), downSyncer, downSyncedResources, conversions)
-			sync(logger.WithValues("actor", "DownSyncer:Unsync"), downSyncer, downUnsyncedResources, conversions)
-			syncStatus(logger.WithValues("actor", "DownSyncer:Sync"), downSyncer, downSyncedResources, conversions)
-			sync(logger.WithValues("actor", "UpSyncer:Sync"), upSyncer, upSyncedReousrces, conversions)
-			sync(logger.WithValues("actor", "UpSyncer:Unsync"), upSyncer, upUnsyncedReousrces, conversions)
+			if cfg.LocalOverridesName != "" {
+				if err := downSyncer.ReloadLocalOverrides(cfg.LocalOverridesNamespace, cfg.LocalOverridesName); err != nil {
+					logger.Error(err, "Problem with the local overrides ConfigMap", "namespace", cfg.LocalOverridesNamespace, "name", cfg.LocalOverridesName)
+				}
+			}
+			if (len(cfg.ResourcePolicies) > 0 || len(cfg.StatusLimits) > 0) &&
+				resolution.due(time.Now(), downSyncedResources, upSyncedReousrces) {
+				resolvePolicies(logger, cfg, scheduler, statusLimiter, resolution, upstreamClientFactory, downstreamClientFactory)
+			}
+			// Unbundle first, so that objects moving into bundles are taken over before the DownSyncer would delete them
+			if err := unbundler.Sync(); err != nil {
+				logger.V(1).Info(fmt.Sprintf("failed to unbundle: %v", err))
+			}
+			if !initialSyncDone && len(downSyncedResources) > 0 {
+				bulkSync(logger.WithValues("actor", "DownSyncer:BulkSync"), cfg, downSyncer, downSyncedResources, conversions)
+				initialSyncDone = true
+			}
+			now := time.Now()
+			sync(logger.WithValues("actor", "DownSyncer:Sync"), downSyncer, scheduler.due("DownSyncer:Sync", downSyncedResources, now), conversions)
+			sync(logger.WithValues("actor", "DownSyncer:Unsync"), downSyncer, scheduler.due("DownSyncer:Unsync", downUnsyncedResources, now), conversions)
+			syncStatus(logger.WithValues("actor", "DownSyncer:Sync"), downSyncer, scheduler.due("DownSyncer:Status", downSyncedResources, now), conversions)
+			sync(logger.WithValues("actor", "UpSyncer:Sync"), upSyncer, scheduler.due("UpSyncer:Sync", upSyncedReousrces, now), conversions)
+			sync(logger.WithValues("actor", "UpSyncer:Unsync"), upSyncer, scheduler.due("UpSyncer:Unsync", upUnsyncedReousrces, now), conversions)
 		}
 	}
 }
 
+// resolvePolicies maps the resource policies and status limits to kinds.
+// Both sides are consulted because upsynced resources may exist only downstream.
+func resolvePolicies(logger klog.Logger, cfg *SyncerConfig, scheduler *syncScheduler, statusLimiter *syncers.StatusLimiter, resolution *kindResolution, upstreamClientFactory, downstreamClientFactory clientfactory.ClientFactory) {
+	upstreamGroupResources, err := upstreamClientFactory.GetAPIGroupResources()
+	if err != nil {
+		logger.V(1).Info("failed to discover upstream resources, keeping previous resource policies", "err", err)
+		return
+	}
+	downstreamGroupResources, err := downstreamClientFactory.GetAPIGroupResources()
+	if err != nil {
+		logger.V(1).Info("failed to discover downstream resources, keeping previous resource policies", "err", err)
+		return
+	}
+	scheduler.resolve(upstreamGroupResources, downstreamGroupResources)
+	statusLimiter.SetKindLimits(byKind(cfg.StatusLimits, upstreamGroupResources, downstreamGroupResources))
+	resolution.done(time.Now(), upstreamGroupResources, downstreamGroupResources)
+}
+
 func sync(logger klog.Logger, syncer syncers.SyncerInterface, resources []edgev2alpha1.EdgeSyncConfigResource, conversions []edgev2alpha1.EdgeSynConversion) {
 	for _, resource := range resources {
 		if resource.Name == "*" || resource.Namespace == "*" {
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	. "github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
+)
+
+// BulkSyncResult summarizes a BulkSync.
+type BulkSyncResult struct {
+	Created, Updated, Unchanged, Failed int64
+}
+
+// BulkSync brings the downstream up to date with a snapshot of the whole
+// downsynced set, for a new edge cluster that would otherwise get its
+// objects one at a time from the periodic sync.
+// For each resource, the upstream and downstream are each listed a page
+// at a time and the differences are applied with the given parallelism.
+// Cluster-scoped resources (which include Namespaces and
+// CustomResourceDefinitions) are done before namespaced ones.
+// Nothing is deleted; that is left to the periodic sync.
+func (ds *DownSyncer) BulkSync(resources []edgev2alpha1.EdgeSyncConfigResource, conversions []edgev2alpha1.EdgeSynConversion, parallelism int, pageSize int64) BulkSyncResult {
+	logger := ds.logger.WithName("BulkSync")
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	var result BulkSyncResult
+	type task struct {
+		resourceForDown    edgev2alpha1.EdgeSyncConfigResource
+		downstreamClient   *Client
+		upstreamResource   *unstructured.Unstructured
+		downstreamResource *unstructured.Unstructured
+	}
+	runPhase := func(namespaced bool) {
+		tasks := make(chan task)
+		var wg sync.WaitGroup
+		for worker := 0; worker < parallelism; worker++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for task := range tasks {
+					atomic.AddInt64(ds.bulkApply(task.resourceForDown, task.downstreamClient, task.upstreamResource, task.downstreamResource, &result), 1)
+				}
+			}()
+		}
+		for _, resource := range resources {
+			upstreamClient, downstreamClient, err := ds.getClients(resource, conversions)
+			if err != nil {
+				logger.Error(err, "failed to get clients", "resource", resourceToString(resource))
+				continue
+			}
+			if upstreamClient.IsNamespaced() != namespaced {
+				continue
+			}
+			resourceForUp := convertToUpstream(resource, conversions)
+			resourceForDown := convertToDownstream(resource, conversions)
+			downstreamObjs := map[string]*unstructured.Unstructured{}
+			if err := downstreamClient.ListAll(resourceForDown, pageSize, func(obj *unstructured.Unstructured) {
+				downstreamObjs[obj.GetNamespace()+"/"+obj.GetName()] = obj
+			}); err != nil {
+				logger.Error(err, "failed to list downstream", "resource", resourceToString(resourceForDown))
+				continue
+			}
+			var count int
+			err = upstreamClient.ListAll(resourceForUp, pageSize, func(obj *unstructured.Unstructured) {
+				if resource.Name != "*" && obj.GetName() != resource.Name {
+					return
+				}
+				count++
+				tasks <- task{resourceForDown, downstreamClient, obj, downstreamObjs[obj.GetNamespace()+"/"+obj.GetName()]}
+			})
+			if err != nil {
+				logger.Error(err, "failed to list upstream", "resource", resourceToString(resourceForUp))
+			}
+			logger.V(3).Info("queued snapshot of resource", "resource", resourceToString(resourceForUp), "count", count)
+		}
+		close(tasks)
+		wg.Wait()
+	}
+	runPhase(false)
+	runPhase(true)
+	return result
+}
+
+// bulkApply makes the downstream object match the upstream one, and returns
+// the counter in the result to increment.
+func (ds *DownSyncer) bulkApply(resourceForDown edgev2alpha1.EdgeSyncConfigResource, downstreamClient *Client, upstreamResource, downstreamResource *unstructured.Unstructured, result *BulkSyncResult) *int64 {
+	resourceForDown.Namespace = upstreamResource.GetNamespace()
+	resourceForDown.Name = upstreamResource.GetName()
+	if downstreamResource == nil {
+		normalizeForCreate(upstreamResource)
+		setDownsyncAnnotation(upstreamResource)
+		applyConversion(upstreamResource, resourceForDown)
+		if _, err := downstreamClient.Create(resourceForDown, upstreamResource); err != nil {
+			err = classifyWriteFailure("create", err)
+			ds.logger.Error(err, fmt.Sprintf("failed to create resource to downstream %q", resourceToString(resourceForDown)), "reason", kserrors.ReasonOf(err))
+			return &result.Failed
+		}
+		ds.recordRevision(upstreamResource)
+		return &result.Created
+	}
+	if !mayUpdateDownstream(downstreamResource) {
+		return &result.Unchanged
+	}
+	normalizeForUpdate(upstreamResource, downstreamResource)
+	setDownsyncAnnotation(upstreamResource)
+	applyConversion(upstreamResource, resourceForDown)
+	updatedResource, noDiff := ds.computeUpdatedResource(upstreamResource, downstreamResource, dryRunner(downstreamClient, resourceForDown))
+	if noDiff {
+		return &result.Unchanged
+	}
+	if _, err := downstreamClient.Update(resourceForDown, updatedResource); err != nil {
+		err = classifyWriteFailure("update", err)
+		ds.logger.Error(err, fmt.Sprintf("failed to update resource on downstream %q", resourceToString(resourceForDown)), "reason", kserrors.ReasonOf(err))
+		return &result.Failed
+	}
+	ds.recordRevision(updatedResource)
+	return &result.Updated
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
+)
+
+func newConfigMap(name, value string, annotated bool) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": map[string]any{"namespace": "ns1", "name": name},
+		"data":     map[string]any{"key": value},
+	}}
+	if annotated {
+		setDownsyncAnnotation(obj)
+	}
+	return obj
+}
+
+func TestMayUpdateDownstream(t *testing.T) {
+	if !mayUpdateDownstream(newConfigMap("cm1", "v", true)) {
+		t.Error("expected an object with the downsync annotation to be updatable")
+	}
+	if mayUpdateDownstream(newConfigMap("cm1", "v", false)) != takeOverUnannotated {
+		t.Errorf("expected an object without the downsync annotation to be updatable=%v", takeOverUnannotated)
+	}
+}
+
+func TestBulkApply(t *testing.T) {
+	cmGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	downstream := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newConfigMap("same", "new", true),
+		newConfigMap("stale", "old", true),
+		newConfigMap("foreign", "old", false),
+	)
+	client := clientfactory.NewClient(downstream.Resource(cmGVR), meta.RESTScopeNamespace, false)
+	resource := edgev2alpha1.EdgeSyncConfigResource{Version: "v1", Kind: "ConfigMap"}
+	ds := &DownSyncer{logger: klog.Background()}
+	cmClient := downstream.Resource(cmGVR).Namespace("ns1")
+	var result BulkSyncResult
+	for _, name := range []string{"same", "stale", "foreign", "missing"} {
+		downstreamResource, err := cmClient.Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			downstreamResource = nil
+		}
+		*ds.bulkApply(resource, &client, newConfigMap(name, "new", false), downstreamResource, &result)++
+	}
+	expected := BulkSyncResult{Created: 1, Updated: 1, Unchanged: 1}
+	if takeOverUnannotated {
+		expected.Updated++
+	} else {
+		expected.Unchanged++
+	}
+	if result != expected {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+	for _, name := range []string{"stale", "missing"} {
+		obj, err := cmClient.Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", name, err)
+		}
+		if value, _, _ := unstructured.NestedString(obj.Object, "data", "key"); value != "new" || !hasDownsyncAnnotation(obj) {
+			t.Errorf("expected %s to have the upstream value and the downsync annotation, got %v", name, obj.Object)
+		}
+	}
+}
@@ -24,12 +24,35 @@ import (
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog/v2"
 
 	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	"github.com/kubestellar/kubestellar/pkg/normalize"
 	. "github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
 )
 
+var downstreamWriteFailures = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "kubestellar_syncer",
+	Name:           "downstream_write_failures_total",
+	Help:           "Number of failed writes to the edge cluster, by operation (create, update or delete) and reason",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"operation", "reason"})
+
+func init() {
+	legacyregistry.MustRegister(downstreamWriteFailures)
+}
+
+// classifyWriteFailure counts the given failure of the given operation
+// on the edge cluster and returns it classified by kserrors.
+func classifyWriteFailure(operation string, err error) error {
+	err = kserrors.Classify(err)
+	downstreamWriteFailures.WithLabelValues(operation, string(kserrors.ReasonOf(err))).Inc()
+	return err
+}
+
 func resourceToString(resource edgev2alpha1.EdgeSyncConfigResource) string {
 	return fmt.Sprintf("%s.%s/%s in %s", resource.Kind, resource.Group, resource.Name, resource.Namespace)
 }
@@ -151,7 +174,7 @@ func getClients(resource edgev2alpha1.EdgeSyncConfigResource, upstreamClients ma
 	upstreamClient, ok := upstreamClients[upstreamGk]
 	if !ok {
 		msg := fmt.Sprintf("upstreamClient for '%s.%s' is not registered", upstreamResource.Group, upstreamResource.Kind)
-		return nil, nil, errors.New(msg)
+		return nil, nil, kserrors.Wrap(kserrors.ReasonSchemaIncompatible, errors.New(msg))
 	}
 
 	downstreamResource := convertToDownstream(resource, conversions)
@@ -162,7 +185,7 @@ func getClients(resource edgev2alpha1.EdgeSyncConfigResource, upstreamClients ma
 	downstreamClient, ok := downstreamClients[downstreamGk]
 	if !ok {
 		msg := fmt.Sprintf("downstreamClient for '%s.%s' is not registered", downstreamResource.Group, downstreamResource.Kind)
-		return nil, nil, errors.New(msg)
+		return nil, nil, kserrors.Wrap(kserrors.ReasonSchemaIncompatible, errors.New(msg))
 	}
 	return upstreamClient, downstreamClient, nil
 }
@@ -220,6 +243,24 @@ func diff(logger klog.Logger, srcResourceList *unstructured.UnstructuredList, de
 	return newResources, updatedResources, deletedResources
 }
 
+// normalizeForCreate removes from an object about to be created downstream
+// the content that is specific to the upstream apiserver.
+func normalizeForCreate(resource *unstructured.Unstructured) {
+	normalize.Default.Normalize(resource)
+}
+
+// normalizeForUpdate removes from an object about to be written over
+// destResource downstream the content that is specific to the upstream
+// apiserver, and gives it the identity and status of destResource.
+func normalizeForUpdate(resource *unstructured.Unstructured, destResource *unstructured.Unstructured) {
+	normalize.Default.Normalize(resource)
+	resource.SetResourceVersion(destResource.GetResourceVersion())
+	resource.SetUID(destResource.GetUID())
+	if status, found, _ := unstructured.NestedFieldNoCopy(destResource.Object, "status"); found {
+		resource.Object["status"] = status
+	}
+}
+
 func setAnnotation(resource *unstructured.Unstructured, key string, value string) {
 	annotations := resource.GetAnnotations()
 	if annotations != nil {
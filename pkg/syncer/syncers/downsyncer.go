@@ -17,16 +17,24 @@ limitations under the License.
 package syncers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
 
 	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/equivalence"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	"github.com/kubestellar/kubestellar/pkg/localprobes"
+	"github.com/kubestellar/kubestellar/pkg/revisions"
+	"github.com/kubestellar/kubestellar/pkg/softdelete"
 	. "github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
 )
 
@@ -37,6 +45,23 @@ type DownSyncer struct {
 	downstreamClientFactory ClientFactory
 	upstreamClients         map[schema.GroupKind]*Client
 	downstreamClients       map[schema.GroupKind]*Client
+
+	// localOverrides are the rules from the WEC's local overrides ConfigMap.
+	localOverrides LocalOverrides
+
+	// revisionStore, if not nil, gets each object that is written downstream,
+	// as a revision for destination.
+	revisionStore *revisions.Store
+	destination   string
+
+	// equivalent caches the Equivalent verdicts of comparer.
+	equivalent equivalenceCache
+
+	// statusLimiter, if not nil, limits how often statuses are written upstream.
+	statusLimiter *StatusLimiter
+
+	// probeRunner, if not nil, runs the LocalProbes of the downsynced objects.
+	probeRunner *localprobes.Runner
 }
 
 func NewDownSyncer(logger klog.Logger, upstreamClientFactory ClientFactory, downstreamClientFactory ClientFactory, syncedResources []edgev2alpha1.EdgeSyncConfigResource, conversions []edgev2alpha1.EdgeSynConversion) (*DownSyncer, error) {
@@ -55,6 +80,41 @@ func NewDownSyncer(logger klog.Logger, upstreamClientFactory ClientFactory, down
 	return &downSyncer, nil
 }
 
+// SetRevisionStore makes the DownSyncer record the objects that it writes
+// downstream as revisions for the given destination.
+// Must be called before the DownSyncer is used.
+func (ds *DownSyncer) SetRevisionStore(store *revisions.Store, destination string) {
+	ds.revisionStore, ds.destination = store, destination
+}
+
+// SetStatusLimiter makes the DownSyncer hold back the status writes to the
+// upstream space that the given StatusLimiter does not allow.
+// Must be called before the DownSyncer is used.
+func (ds *DownSyncer) SetStatusLimiter(limiter *StatusLimiter) {
+	ds.statusLimiter = limiter
+}
+
+// statusWriteDue tells whether the given status should be written to the
+// given upstream object now. It is not when the upstream object already
+// has that status, nor when the StatusLimiter holds the write back.
+func (ds *DownSyncer) statusWriteDue(resource edgev2alpha1.EdgeSyncConfigResource, upstreamResource *unstructured.Unstructured, status map[string]interface{}) bool {
+	if current, found, _ := unstructured.NestedFieldNoCopy(upstreamResource.Object, "status"); found && equality.Semantic.DeepEqual(current, status) {
+		statusUpdatesHeld.WithLabelValues("unchanged").Inc()
+		return false
+	}
+	kind := schema.GroupKind{Group: resource.Group, Kind: resource.Kind}
+	return ds.statusLimiter.Allow(kind, upstreamResource.GetNamespace(), upstreamResource.GetName())
+}
+
+func (ds *DownSyncer) recordRevision(obj *unstructured.Unstructured) {
+	if ds.revisionStore == nil {
+		return
+	}
+	if _, _, err := ds.revisionStore.Record(context.Background(), revisions.RefTo(ds.destination, obj), obj, time.Now()); err != nil {
+		ds.logger.Error(err, "failed to record revision", "object", obj.GetNamespace()+"/"+obj.GetName(), "kind", obj.GetKind())
+	}
+}
+
 func (ds *DownSyncer) initializeClients(syncedResources []edgev2alpha1.EdgeSyncConfigResource, conversions []edgev2alpha1.EdgeSynConversion) error {
 	ds.upstreamClients = map[schema.GroupKind]*Client{}
 	ds.downstreamClients = map[schema.GroupKind]*Client{}
@@ -93,6 +153,10 @@ func (ds *DownSyncer) SyncOne(resource edgev2alpha1.EdgeSyncConfigResource, conv
 			ds.logger.Error(err, fmt.Sprintf("failed to get resource from upstream %q", resourceToString(resourceForUp)))
 			return err
 		}
+	} else if softdelete.Due(upstreamResource, time.Now()) {
+		ds.logger.V(3).Info(fmt.Sprintf("  %q in upstream is due for deletion", resourceToString(resourceForUp)))
+		ds.logger.V(3).Info(fmt.Sprintf("  delete %q from downstream", resourceToString(resourceForUp)))
+		isDeleted = true
 	}
 
 	resourceForDown := convertToDownstream(resource, conversions)
@@ -102,14 +166,15 @@ func (ds *DownSyncer) SyncOne(resource edgev2alpha1.EdgeSyncConfigResource, conv
 		if k8serrors.IsNotFound(err) {
 			if !isDeleted {
 				ds.logger.V(3).Info(fmt.Sprintf("  create %q in downstream since it's not found", resourceToString(resourceForDown)))
-				upstreamResource.SetResourceVersion("")
-				upstreamResource.SetUID("")
+				normalizeForCreate(upstreamResource)
 				setDownsyncAnnotation(upstreamResource)
 				applyConversion(upstreamResource, resourceForDown)
 				if _, err := downstreamClient.Create(resourceForDown, upstreamResource); err != nil {
-					ds.logger.Error(err, fmt.Sprintf("failed to create resource to downstream %q", resourceToString(resourceForDown)))
+					err = classifyWriteFailure("create", err)
+					ds.logger.Error(err, fmt.Sprintf("failed to create resource to downstream %q", resourceToString(resourceForDown)), "reason", kserrors.ReasonOf(err))
 					return err
 				}
+				ds.recordRevision(upstreamResource)
 			} else {
 				ds.logger.V(3).Info(fmt.Sprintf("  %q has already been deleted from downstream", resourceToString(resourceForDown)))
 			}
@@ -122,17 +187,18 @@ func (ds *DownSyncer) SyncOne(resource edgev2alpha1.EdgeSyncConfigResource, conv
 			if !isDeleted {
 				// update
 				ds.logger.V(3).Info(fmt.Sprintf("  update %q in downstream since it's found", resourceToString(resourceForDown)))
-				if true || hasDownsyncAnnotation(downstreamResource) {
-					upstreamResource.SetResourceVersion(downstreamResource.GetResourceVersion())
-					upstreamResource.SetUID(downstreamResource.GetUID())
+				if mayUpdateDownstream(downstreamResource) {
+					normalizeForUpdate(upstreamResource, downstreamResource)
 					setDownsyncAnnotation(upstreamResource)
 					applyConversion(upstreamResource, resourceForDown)
-					_updatedResource, noDiff := ds.computeUpdatedResource(upstreamResource, downstreamResource)
+					_updatedResource, noDiff := ds.computeUpdatedResource(upstreamResource, downstreamResource, dryRunner(downstreamClient, resourceForDown))
 					if !noDiff {
 						if _, err := downstreamClient.Update(resourceForDown, _updatedResource); err != nil {
-							ds.logger.Error(err, fmt.Sprintf("failed to update resource on downstream %q", resourceToString(resourceForDown)))
+							err = classifyWriteFailure("update", err)
+							ds.logger.Error(err, fmt.Sprintf("failed to update resource on downstream %q", resourceToString(resourceForDown)), "reason", kserrors.ReasonOf(err))
 							return err
 						}
+						ds.recordRevision(_updatedResource)
 					}
 				} else {
 					ds.logger.V(2).Info(fmt.Sprintf("  ignore updating %q in downstream since downsync annotation is not set", resourceToString(resourceForDown)))
@@ -142,7 +208,8 @@ func (ds *DownSyncer) SyncOne(resource edgev2alpha1.EdgeSyncConfigResource, conv
 				if hasDownsyncAnnotation(downstreamResource) {
 					if ds.checkDeletable(downstreamResource) {
 						if err := downstreamClient.Delete(resourceForDown, resourceForDown.Name); err != nil {
-							ds.logger.Error(err, fmt.Sprintf("failed to delete resource from downstream %q", resourceToString(resourceForDown)))
+							err = classifyWriteFailure("delete", err)
+							ds.logger.Error(err, fmt.Sprintf("failed to delete resource from downstream %q", resourceToString(resourceForDown)), "reason", kserrors.ReasonOf(err))
 							return err
 						}
 					}
@@ -181,14 +248,6 @@ func (ds *DownSyncer) BackStatusOne(resource edgev2alpha1.EdgeSyncConfigResource
 			return err
 		}
 	}
-	status, found, err := unstructured.NestedMap(downstreamResource.Object, "status")
-	if err != nil {
-		ds.logger.Error(err, fmt.Sprintf("failed to extract status from downstream object %q", resourceToString(resourceForDown)))
-		return err
-	} else if !found {
-		ds.logger.V(3).Info(fmt.Sprintf("  skip status upsync %q since no status field in it", resourceToString(resourceForDown)))
-		return nil
-	}
 	resourceForUp := convertToUpstream(resource, conversions)
 	upstreamResource, err := upstreamClient.Get(resourceForUp)
 	if err != nil {
@@ -201,11 +260,33 @@ func (ds *DownSyncer) BackStatusOne(resource edgev2alpha1.EdgeSyncConfigResource
 			return err
 		}
 	}
+	upstreamResource, err = ds.reportLocalOverrides(upstreamClient, resourceForUp, upstreamResource, downstreamResource)
+	if err != nil {
+		ds.logger.Error(err, fmt.Sprintf("failed to report local overrides on upstream %q", resourceToString(resourceForUp)))
+		return err
+	}
+	upstreamResource, err = ds.reportProbeResults(upstreamClient, resourceForUp, upstreamResource, downstreamResource)
+	if err != nil {
+		ds.logger.Error(err, fmt.Sprintf("failed to report probe results on upstream %q", resourceToString(resourceForUp)))
+		return err
+	}
+	status, found, err := unstructured.NestedMap(downstreamResource.Object, "status")
+	if err != nil {
+		ds.logger.Error(err, fmt.Sprintf("failed to extract status from downstream object %q", resourceToString(resourceForDown)))
+		return err
+	} else if !found {
+		ds.logger.V(3).Info(fmt.Sprintf("  skip status upsync %q since no status field in it", resourceToString(resourceForDown)))
+		return nil
+	}
 	_, found, err = unstructured.NestedMap(upstreamResource.Object, "status")
 	if err != nil {
 		ds.logger.Error(err, fmt.Sprintf("failed to extract status from upstream object %q", resourceToString(resourceForUp)))
 		return err
 	}
+	if !ds.statusWriteDue(resource, upstreamResource, status) {
+		ds.logger.V(3).Info(fmt.Sprintf("  hold status upsync %q", resourceToString(resourceForUp)))
+		return nil
+	}
 	upstreamResource.Object["status"] = status
 	applyConversion(upstreamResource, resourceForUp)
 	if _, err := updateStatusByResource(upstreamClient, resourceForUp, upstreamResource); err != nil {
@@ -235,6 +316,7 @@ func (ds *DownSyncer) SyncMany(resource edgev2alpha1.EdgeSyncConfigResource, con
 		}
 	}
 	logger.V(4).Info("  listed objects from upstream", "objects", upstreamResourceList)
+	upstreamResourceList.Items = dropDueForDeletion(logger, upstreamResourceList.Items, time.Now())
 
 	resourceForDown := convertToDownstream(resource, conversions)
 	logger.V(3).Info("  list resources from downstream")
@@ -249,7 +331,7 @@ func (ds *DownSyncer) SyncMany(resource edgev2alpha1.EdgeSyncConfigResource, con
 	newResources, updatedResources, deletedResources := diff(logger, upstreamResourceList, downstreamResourceList, setDownsyncAnnotation, hasDownsyncAnnotation)
 
 	logger.V(3).Info("  apply filter such as downsync-overwrite condition to updatedResources and deletedResources")
-	updatedResources = ds.computeUpdatedResources(downstreamResourceList, updatedResources)
+	updatedResources = ds.computeUpdatedResources(downstreamResourceList, updatedResources, dryRunner(downstreamClient, resourceForDown))
 	deletedResources = ds.computeDeletedResources(downstreamResourceList, deletedResources)
 
 	logger.V(3).Info("  final new/updated/deleted resource list")
@@ -259,21 +341,27 @@ func (ds *DownSyncer) SyncMany(resource edgev2alpha1.EdgeSyncConfigResource, con
 
 	logger.V(3).Info("  create resources in downstream")
 	for _, resource := range newResources {
+		normalizeForCreate(&resource)
 		applyConversion(&resource, resourceForDown)
 		logger.V(3).Info("  create " + resource.GetName())
 		if _, err := downstreamClient.Create(resourceForDown, &resource); err != nil {
 			logger.Error(err, "failed to create resource to downstream")
 			return err
 		}
+		ds.recordRevision(&resource)
 	}
 	logger.V(3).Info("  update resources in downstream")
 	for _, resource := range updatedResources {
+		if downstreamResource, found := findWithObject(resource, downstreamResourceList); found {
+			normalizeForUpdate(&resource, downstreamResource)
+		}
 		applyConversion(&resource, resourceForDown)
 		logger.V(3).Info("  update " + resource.GetName())
 		if _, err := downstreamClient.Update(resourceForDown, &resource); err != nil {
 			logger.Error(err, "failed to update resource on downstream")
 			return err
 		}
+		ds.recordRevision(&resource)
 	}
 	logger.V(3).Info("  delete resources from downstream")
 	for _, resource := range deletedResources {
@@ -289,8 +377,9 @@ func (ds *DownSyncer) SyncMany(resource edgev2alpha1.EdgeSyncConfigResource, con
 
 // Compute resource object to be pushed from upstreamResource and downstreamResource
 //   - updatedResource is the computed resource object to be pushed
-//   - noDiff is true if the computed updatedResource is no difference from the downstream resource.
-func (ds *DownSyncer) computeUpdatedResource(upstreamResource *unstructured.Unstructured, downstreamResource *unstructured.Unstructured) (updatedResource *unstructured.Unstructured, noDiff bool) {
+//   - noDiff is true if the computed updatedResource is no difference from the downstream resource,
+//     apart from what the downstream apiserver defaults (checked with dryRun).
+func (ds *DownSyncer) computeUpdatedResource(upstreamResource *unstructured.Unstructured, downstreamResource *unstructured.Unstructured, dryRun equivalence.DryRunFunc) (updatedResource *unstructured.Unstructured, noDiff bool) {
 	if !isDownsyncOverwrite(upstreamResource) {
 		ds.logger.V(2).Info(fmt.Sprintf("  downsync-overwrite of %q is marked as false", upstreamResource.GetName()))
 		annotations := downstreamResource.GetAnnotations()
@@ -306,6 +395,19 @@ func (ds *DownSyncer) computeUpdatedResource(upstreamResource *unstructured.Unst
 			return &_updatedResource, false
 		}
 	}
+	if overrides := ds.getLocalOverrides(); len(overrides) > 0 {
+		diverged := overrides.Apply(upstreamResource, downstreamResource)
+		if len(diverged) > 0 {
+			ds.logger.V(3).Info(fmt.Sprintf("  keep local values of %v in %q", diverged, upstreamResource.GetName()))
+		}
+		markLocalOverrides(upstreamResource, diverged)
+	} else {
+		markLocalOverrides(upstreamResource, nil)
+	}
+	if ds.downstreamUpToDate(upstreamResource, downstreamResource, dryRun) {
+		ds.logger.V(3).Info(fmt.Sprintf("  %q in downstream is up to date", upstreamResource.GetName()))
+		return downstreamResource, true
+	}
 	return upstreamResource, false
 }
 
@@ -319,11 +421,11 @@ func (ds *DownSyncer) checkDeletable(downstreamResource *unstructured.Unstructur
 	}
 }
 
-func (ds *DownSyncer) computeUpdatedResources(downstreamResourceList *unstructured.UnstructuredList, updatedResources []unstructured.Unstructured) []unstructured.Unstructured {
+func (ds *DownSyncer) computeUpdatedResources(downstreamResourceList *unstructured.UnstructuredList, updatedResources []unstructured.Unstructured, dryRun equivalence.DryRunFunc) []unstructured.Unstructured {
 	filteredUpdatedResources := []unstructured.Unstructured{}
 	for _, updatedResource := range updatedResources {
 		downstreamResource, _ := findWithObject(updatedResource, downstreamResourceList)
-		_updatedResource, noDiff := ds.computeUpdatedResource(&updatedResource, downstreamResource)
+		_updatedResource, noDiff := ds.computeUpdatedResource(&updatedResource, downstreamResource, dryRun)
 		if !noDiff {
 			filteredUpdatedResources = append(filteredUpdatedResources, *_updatedResource)
 		}
@@ -341,6 +443,21 @@ func (ds *DownSyncer) computeDeletedResources(downstreamResourceList *unstructur
 	return filteredDeletedResources
 }
 
+// dropDueForDeletion returns the given upstream objects without those
+// that are due for deletion (see package softdelete), which are
+// therefore deleted from downstream.
+func dropDueForDeletion(logger klog.Logger, objs []unstructured.Unstructured, now time.Time) []unstructured.Unstructured {
+	kept := objs[:0]
+	for _, obj := range objs {
+		if softdelete.Due(&obj, now) {
+			logger.V(3).Info("  upstream object is due for deletion", "name", obj.GetName(), "namespace", obj.GetNamespace())
+			continue
+		}
+		kept = append(kept, obj)
+	}
+	return kept
+}
+
 func (ds *DownSyncer) UnsyncMany(resource edgev2alpha1.EdgeSyncConfigResource, conversions []edgev2alpha1.EdgeSynConversion) error {
 	// It's OK to use same logic as SyncMany unless we execute specific actions for unsynced resources
 	return ds.SyncMany(resource, conversions)
@@ -370,6 +487,19 @@ func (ds *DownSyncer) BackStatusMany(resource edgev2alpha1.EdgeSyncConfigResourc
 	}
 
 	for _, downstreamResource := range downstreamResourceList.Items {
+		upstreamResource, ok := findWithObject(downstreamResource, upstreamResourceList)
+		if ok {
+			upstreamResource, err = ds.reportLocalOverrides(upstreamClient, resourceForUp, upstreamResource, &downstreamResource)
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("failed to report local overrides on upstream object: %s", downstreamResource.GetName()))
+				return err
+			}
+			upstreamResource, err = ds.reportProbeResults(upstreamClient, resourceForUp, upstreamResource, &downstreamResource)
+			if err != nil {
+				logger.Error(err, fmt.Sprintf("failed to report probe results on upstream object: %s", downstreamResource.GetName()))
+				return err
+			}
+		}
 		status, found, err := unstructured.NestedMap(downstreamResource.Object, "status")
 		if err != nil {
 			logger.Error(err, fmt.Sprintf("failed to extract status from downstream object: %s. Skip", downstreamResource.GetName()))
@@ -378,8 +508,11 @@ func (ds *DownSyncer) BackStatusMany(resource edgev2alpha1.EdgeSyncConfigResourc
 			logger.V(3).Info(fmt.Sprintf("  skip status upsync for since no status field in it: %s. Skip", downstreamResource.GetName()))
 			continue
 		}
-		upstreamResource, ok := findWithObject(downstreamResource, upstreamResourceList)
 		if ok {
+			if !ds.statusWriteDue(resource, upstreamResource, status) {
+				logger.V(3).Info(fmt.Sprintf("  hold status upsync for %s", downstreamResource.GetName()))
+				continue
+			}
 			resourceForUp := convertToUpstream(resource, conversions)
 			upstreamResource.Object["status"] = status
 			applyConversion(upstreamResource, resourceForUp)
@@ -428,6 +561,18 @@ func hasDownsyncAnnotation(resource *unstructured.Unstructured) bool {
 	return getAnnotation(resource, downsyncKey) == ownedValue
 }
 
+// takeOverUnannotated means that the syncer also writes over downstream
+// objects that lack the downsync annotation, such as ones created there
+// before the syncer. Deleting is always limited to annotated objects.
+const takeOverUnannotated = true
+
+// mayUpdateDownstream tells whether the syncer may write the upstream
+// content over the given existing downstream object. SyncOne and
+// BulkSync both ask this, so that they agree.
+func mayUpdateDownstream(downstreamResource *unstructured.Unstructured) bool {
+	return takeOverUnannotated || hasDownsyncAnnotation(downstreamResource)
+}
+
 func makeOwnedValue(object *unstructured.Unstructured) string {
 	gvk := object.GroupVersionKind()
 	return gvk.Kind + "/" + object.GetNamespace() + "/" + object.GetName()
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/bundle"
+	"github.com/kubestellar/kubestellar/pkg/equivalence"
+	"github.com/kubestellar/kubestellar/pkg/normalize"
+	. "github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
+)
+
+var updateComparisons = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "kubestellar_syncer",
+	Name:           "update_comparisons_total",
+	Help:           "Number of comparisons of a desired object with the downstream one before updating, by outcome (identical, equivalent, cached or different)",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"outcome"})
+
+func init() {
+	legacyregistry.MustRegister(updateComparisons)
+}
+
+// comparer decides whether a downstream object needs to be updated.
+var comparer = equivalence.NewComparer(normalize.Default)
+
+// outcomeCached labels a comparison answered from an equivalenceCache.
+const outcomeCached = "cached"
+
+// maxEquivalenceCacheSize bounds an equivalenceCache; when it is full it
+// starts over, which only costs some dry runs.
+const maxEquivalenceCacheSize = 10000
+
+// equivalenceCache remembers the downstream objects found Equivalent to
+// the desired ones, so that the dry-run update behind that verdict is not
+// repeated in every pass while neither side changes. A verdict is keyed
+// by the UID of the downstream object and holds its resourceVersion and
+// the hash of the desired object. The desired object is derived from the
+// upstream one by conversions and local overrides, so its hash stands for
+// the upstream resourceVersion and also covers changes in those.
+type equivalenceCache struct {
+	mutex    sync.Mutex
+	verdicts map[types.UID]equivalenceVerdict
+}
+
+type equivalenceVerdict struct {
+	desiredHash               string
+	downstreamResourceVersion string
+}
+
+func (ec *equivalenceCache) has(uid types.UID, verdict equivalenceVerdict) bool {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	return ec.verdicts[uid] == verdict
+}
+
+func (ec *equivalenceCache) put(uid types.UID, verdict equivalenceVerdict) {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	if ec.verdicts == nil || len(ec.verdicts) >= maxEquivalenceCacheSize {
+		ec.verdicts = map[types.UID]equivalenceVerdict{}
+	}
+	ec.verdicts[uid] = verdict
+}
+
+// dryRunner returns the function that makes dry-run updates downstream.
+func dryRunner(client *Client, resource edgev2alpha1.EdgeSyncConfigResource) equivalence.DryRunFunc {
+	return func(desired *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		return client.DryRunUpdate(resource, desired)
+	}
+}
+
+// downstreamUpToDate tells whether the downstream object already has the
+// desired content, apart from what the downstream apiserver defaults.
+func (ds *DownSyncer) downstreamUpToDate(desired, downstreamResource *unstructured.Unstructured, dryRun equivalence.DryRunFunc) bool {
+	var verdict equivalenceVerdict
+	uid := downstreamResource.GetUID()
+	if hash, err := bundle.ContentHash(desired); err == nil && uid != "" {
+		verdict = equivalenceVerdict{desiredHash: hash, downstreamResourceVersion: downstreamResource.GetResourceVersion()}
+		if ds.equivalent.has(uid, verdict) {
+			updateComparisons.WithLabelValues(outcomeCached).Inc()
+			return true
+		}
+	}
+	outcome, err := comparer.Compare(desired, downstreamResource, dryRun)
+	if err != nil {
+		ds.logger.V(3).Info("  dry-run update failed; updating anyway", "name", desired.GetName(), "err", err)
+	}
+	updateComparisons.WithLabelValues(string(outcome)).Inc()
+	if outcome == equivalence.Equivalent && verdict.desiredHash != "" {
+		ds.equivalent.put(uid, verdict)
+	}
+	return outcome != equivalence.Different
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+)
+
+func TestDownstreamUpToDateCachesEquivalence(t *testing.T) {
+	ds := &DownSyncer{logger: klog.Background()}
+	desired := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1", "kind": "Service",
+		"metadata": map[string]any{"namespace": "shop", "name": "web"},
+		"spec":     map[string]any{"ports": []any{map[string]any{"port": int64(80)}}},
+	}}
+	downstream := desired.DeepCopy()
+	downstream.SetUID("uid1")
+	downstream.SetResourceVersion("7")
+	unstructured.SetNestedField(downstream.Object, "ClusterIP", "spec", "type") // defaulted downstream
+	dryRuns := 0
+	dryRun := func(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+		dryRuns++
+		return downstream.DeepCopy(), nil
+	}
+
+	for _, step := range []struct {
+		name            string
+		resourceVersion string
+		expectDryRuns   int
+	}{
+		{"first comparison", "7", 1},
+		{"unchanged", "7", 1},
+		{"downstream changed", "8", 2},
+		{"unchanged again", "8", 2},
+	} {
+		downstream.SetResourceVersion(step.resourceVersion)
+		if !ds.downstreamUpToDate(desired.DeepCopy(), downstream, dryRun) {
+			t.Errorf("%s: expected the downstream object to be up to date", step.name)
+		}
+		if dryRuns != step.expectDryRuns {
+			t.Errorf("%s: expected %d dry runs in all, got %d", step.name, step.expectDryRuns, dryRuns)
+		}
+	}
+	changed := desired.DeepCopy()
+	unstructured.SetNestedField(changed.Object, "NodePort", "spec", "type")
+	if ds.downstreamUpToDate(changed, downstream, dryRun) {
+		t.Error("expected a changed desired object not to be up to date")
+	}
+}
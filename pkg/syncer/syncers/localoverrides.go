@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	. "github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
+)
+
+// LocalOverrideRule locks some fields of the matching downsynced objects to
+// the values they have in the WEC. Each value in the local overrides
+// ConfigMap is one rule, in YAML. For example:
+//
+//	apiGroup: apps
+//	kind: Deployment
+//	namespace: shop
+//	fields:
+//	- spec.replicas
+//	- metadata.labels
+type LocalOverrideRule struct {
+	APIGroup string `json:"apiGroup,omitempty"`
+	Kind     string `json:"kind"`
+
+	// Namespace and Name restrict the matching objects; empty or "*" means any.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+
+	// Fields are the paths of the locked fields, with the path elements
+	// separated by dots; a map key that contains a dot can not be given.
+	Fields []string `json:"fields"`
+}
+
+func (rule LocalOverrideRule) matches(obj *unstructured.Unstructured) bool {
+	return rule.APIGroup == obj.GroupVersionKind().Group && rule.Kind == obj.GetKind() &&
+		(rule.Namespace == "" || rule.Namespace == "*" || rule.Namespace == obj.GetNamespace()) &&
+		(rule.Name == "" || rule.Name == "*" || rule.Name == obj.GetName())
+}
+
+// LocalOverrides is the set of rules from the local overrides ConfigMap.
+type LocalOverrides []LocalOverrideRule
+
+// ParseLocalOverrides parses the data of the local overrides ConfigMap.
+// A malformed rule is skipped and reported in the returned error.
+func ParseLocalOverrides(data map[string]string) (LocalOverrides, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	ans := LocalOverrides{}
+	var problems []string
+	for _, key := range keys {
+		var rule LocalOverrideRule
+		if err := yaml.UnmarshalStrict([]byte(data[key]), &rule); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		if rule.Kind == "" || len(rule.Fields) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: kind and fields are required", key))
+			continue
+		}
+		ans = append(ans, rule)
+	}
+	if len(problems) > 0 {
+		return ans, fmt.Errorf("malformed local override rules: %s", strings.Join(problems, "; "))
+	}
+	return ans, nil
+}
+
+// Apply copies the locked fields of the given current (WEC) object into the
+// given desired object, removing from the desired object the locked fields
+// that the current object lacks. It returns the paths of the locked fields
+// where the desired value differed from the current one, sorted.
+func (overrides LocalOverrides) Apply(desired, current *unstructured.Unstructured) []string {
+	diverged := sets.NewString()
+	for _, rule := range overrides {
+		if !rule.matches(current) {
+			continue
+		}
+		for _, field := range rule.Fields {
+			path := strings.Split(field, ".")
+			currentVal, currentFound, _ := unstructured.NestedFieldNoCopy(current.Object, path...)
+			desiredVal, desiredFound, _ := unstructured.NestedFieldNoCopy(desired.Object, path...)
+			if currentFound != desiredFound || !reflect.DeepEqual(currentVal, desiredVal) {
+				diverged.Insert(field)
+			}
+			if currentFound {
+				_ = unstructured.SetNestedField(desired.Object, runtime.DeepCopyJSONValue(currentVal), path...)
+			} else {
+				unstructured.RemoveNestedField(desired.Object, path...)
+			}
+		}
+	}
+	return diverged.List()
+}
+
+// markLocalOverrides sets or removes the LocalOverridesKey annotation.
+func markLocalOverrides(obj *unstructured.Unstructured, diverged []string) {
+	if len(diverged) > 0 {
+		setAnnotation(obj, edgev2alpha1.LocalOverridesKey, strings.Join(diverged, ","))
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if _, found := annotations[edgev2alpha1.LocalOverridesKey]; found {
+		delete(annotations, edgev2alpha1.LocalOverridesKey)
+		obj.SetAnnotations(annotations)
+	}
+}
+
+// ReloadLocalOverrides reads the rules from the given ConfigMap in the WEC.
+// A missing ConfigMap means no rules. If the ConfigMap can not be read, the
+// previous rules stay in effect.
+func (ds *DownSyncer) ReloadLocalOverrides(namespace, name string) error {
+	client, err := ds.downstreamClientFactory.GetResourceClient("", "ConfigMap")
+	if err != nil {
+		return err
+	}
+	var overrides LocalOverrides
+	obj, err := client.Get(edgev2alpha1.EdgeSyncConfigResource{Version: "v1", Kind: "ConfigMap", Namespace: namespace, Name: name})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		data, _, _ := unstructured.NestedStringMap(obj.Object, "data")
+		overrides, err = ParseLocalOverrides(data)
+	}
+	ds.Lock()
+	defer ds.Unlock()
+	ds.localOverrides = overrides
+	return err
+}
+
+func (ds *DownSyncer) getLocalOverrides() LocalOverrides {
+	ds.Lock()
+	defer ds.Unlock()
+	return ds.localOverrides
+}
+
+// reportLocalOverrides copies the LocalOverridesKey annotation of the given
+// downstream object to the given upstream object, so that the divergence is
+// visible in the mailbox space as intentional.
+// It returns the upstream object as it now is.
+func (ds *DownSyncer) reportLocalOverrides(upstreamClient *Client, resourceForUp edgev2alpha1.EdgeSyncConfigResource, upstreamResource, downstreamResource *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	downValue, downFound := downstreamResource.GetAnnotations()[edgev2alpha1.LocalOverridesKey]
+	upValue, upFound := upstreamResource.GetAnnotations()[edgev2alpha1.LocalOverridesKey]
+	if downFound == upFound && downValue == upValue {
+		return upstreamResource, nil
+	}
+	var diverged []string
+	if downFound {
+		diverged = strings.Split(downValue, ",")
+	}
+	markLocalOverrides(upstreamResource, diverged)
+	resourceForUp.Namespace = upstreamResource.GetNamespace()
+	resourceForUp.Name = upstreamResource.GetName()
+	ds.logger.V(2).Info("report local overrides upstream", "resource", resourceToString(resourceForUp), "fields", downValue)
+	return upstreamClient.Update(resourceForUp, upstreamResource)
+}
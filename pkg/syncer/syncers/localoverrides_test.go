@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func TestLocalOverrides(t *testing.T) {
+	overrides, err := ParseLocalOverrides(map[string]string{
+		"replicas": "apiGroup: apps\nkind: Deployment\nnamespace: shop\nfields:\n- spec.replicas\n- metadata.labels.tier\n",
+		"broken":   "kind: Deployment\n",
+	})
+	if err == nil || len(overrides) != 1 {
+		t.Fatalf("expected one good rule and an error, got %v and %v", overrides, err)
+	}
+	newDeployment := func(replicas int64, labels map[string]any) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]any{"namespace": "shop", "name": "web"},
+			"spec":       map[string]any{"replicas": replicas},
+		}}
+		if labels != nil {
+			_ = unstructured.SetNestedField(obj.Object, labels, "metadata", "labels")
+		}
+		return obj
+	}
+	desired := newDeployment(3, map[string]any{"tier": "frontend", "app": "web"})
+	current := newDeployment(5, map[string]any{"app": "old"})
+	diverged := overrides.Apply(desired, current)
+	if fmt.Sprint(diverged) != "[metadata.labels.tier spec.replicas]" {
+		t.Errorf("unexpected divergence %v", diverged)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(desired.Object, "spec", "replicas"); replicas != 5 {
+		t.Errorf("expected the local replicas to be kept, got %d", replicas)
+	}
+	labels := desired.GetLabels()
+	if _, found := labels["tier"]; found || labels["app"] != "web" {
+		t.Errorf("expected only the locked label to follow the local object, got %v", labels)
+	}
+
+	markLocalOverrides(desired, diverged)
+	if value := desired.GetAnnotations()[edgev2alpha1.LocalOverridesKey]; value != "metadata.labels.tier,spec.replicas" {
+		t.Errorf("unexpected annotation value %q", value)
+	}
+	markLocalOverrides(desired, nil)
+	if _, found := desired.GetAnnotations()[edgev2alpha1.LocalOverridesKey]; found {
+		t.Errorf("expected the annotation to be removed")
+	}
+
+	other := newDeployment(3, nil)
+	other.SetNamespace("elsewhere")
+	if diverged := overrides.Apply(other, newDeployment(5, nil)); len(diverged) != 0 {
+		t.Errorf("expected no rule to match, got %v", diverged)
+	}
+}
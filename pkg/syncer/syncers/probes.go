@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/localprobes"
+	. "github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
+)
+
+// SetProbeRunner makes the DownSyncer run the LocalProbes of the downsynced
+// objects with the given Runner and report their results upstream.
+// Must be called before the DownSyncer is used.
+func (ds *DownSyncer) SetProbeRunner(runner *localprobes.Runner) {
+	ds.probeRunner = runner
+}
+
+// reportProbeResults runs the LocalProbes listed in the LocalProbesKey
+// annotation of the given upstream object, and reports their results in
+// its ProbeResultsKey annotation. An `exec` probe with no namespace of its
+// own looks for its pod in the namespace of the given downstream object.
+// It returns the upstream object as it now is.
+func (ds *DownSyncer) reportProbeResults(upstreamClient *Client, resourceForUp edgev2alpha1.EdgeSyncConfigResource, upstreamResource, downstreamResource *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if ds.probeRunner == nil {
+		return upstreamResource, nil
+	}
+	key := resourceForUp.Group + "/" + resourceForUp.Kind + "/" + upstreamResource.GetNamespace() + "/" + upstreamResource.GetName()
+	annotations := upstreamResource.GetAnnotations()
+	probesValue, hasProbes := annotations[edgev2alpha1.LocalProbesKey]
+	resultsValue, hasResults := annotations[edgev2alpha1.ProbeResultsKey]
+	if !hasProbes {
+		ds.probeRunner.Forget(key)
+		if !hasResults {
+			return upstreamResource, nil
+		}
+		delete(annotations, edgev2alpha1.ProbeResultsKey)
+		upstreamResource.SetAnnotations(annotations)
+	} else {
+		probes, err := localprobes.DecodeProbes(probesValue)
+		if err != nil {
+			ds.logger.Error(err, "ignoring local probes", "resource", resourceToString(resourceForUp), "namespace", upstreamResource.GetNamespace(), "name", upstreamResource.GetName())
+			return upstreamResource, nil
+		}
+		results := ds.probeRunner.Conditions(context.Background(), key, downstreamResource.GetNamespace(), probes, localprobes.DecodeResults(resultsValue))
+		newValue := localprobes.EncodeResults(results)
+		if hasResults && newValue == resultsValue {
+			return upstreamResource, nil
+		}
+		setAnnotation(upstreamResource, edgev2alpha1.ProbeResultsKey, newValue)
+	}
+	resourceForUp.Namespace = upstreamResource.GetNamespace()
+	resourceForUp.Name = upstreamResource.GetName()
+	ds.logger.V(2).Info("report probe results upstream", "resource", resourceToString(resourceForUp))
+	return upstreamClient.Update(resourceForUp, upstreamResource)
+}
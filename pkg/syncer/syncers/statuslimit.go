@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/utils/clock"
+)
+
+var statusUpdatesHeld = metrics.NewCounterVec(&metrics.CounterOpts{
+	Subsystem:      "kubestellar_syncer",
+	Name:           "status_updates_held_total",
+	Help:           "Number of status updates to the upstream space that were not made in a pass of the sync loop, by reason (unchanged, window or rate)",
+	StabilityLevel: metrics.ALPHA,
+}, []string{"reason"})
+
+func init() {
+	legacyregistry.MustRegister(statusUpdatesHeld)
+}
+
+// StatusLimit says how often the status of the objects of one kind may be
+// written to the upstream space.
+type StatusLimit struct {
+	// Window is the minimum time between two status writes of one object.
+	// The changes made in between go upstream together in the next write.
+	Window time.Duration
+
+	// QPS caps the status writes per second; zero means no cap.
+	QPS float32
+}
+
+// DefaultStatusLimit applies to the kinds that were given no StatusLimit.
+var DefaultStatusLimit = StatusLimit{Window: 5 * time.Second}
+
+// statusPruneInterval is how often the write times of objects that are
+// out of their window are forgotten.
+const statusPruneInterval = time.Minute
+
+// StatusLimiter decides whether the status of an object may be written
+// upstream now. The kinds that have their own StatusLimit each have their
+// own rate cap, the others share the one of the default limit.
+// A status write that is held back is not queued: the sync loop finds the
+// difference again in a later pass and then writes the latest status.
+type StatusLimiter struct {
+	sync.Mutex
+	clock        clock.PassiveClock
+	defaultLimit StatusLimit
+	kindLimits   map[schema.GroupKind]StatusLimit
+
+	// buckets holds the rate caps, keyed by kind; the shared one is
+	// under the zero GroupKind. A nil bucket means no cap.
+	buckets map[schema.GroupKind]flowcontrol.PassiveRateLimiter
+
+	lastWritten map[statusObjectKey]time.Time
+	lastPruned  time.Time
+}
+
+type statusObjectKey struct {
+	kind      schema.GroupKind
+	namespace string
+	name      string
+}
+
+func NewStatusLimiter(clk clock.PassiveClock, defaultLimit StatusLimit) *StatusLimiter {
+	sl := &StatusLimiter{
+		clock:        clk,
+		defaultLimit: defaultLimit,
+		kindLimits:   map[schema.GroupKind]StatusLimit{},
+		buckets:      map[schema.GroupKind]flowcontrol.PassiveRateLimiter{},
+		lastWritten:  map[statusObjectKey]time.Time{},
+		lastPruned:   clk.Now(),
+	}
+	sl.buckets[schema.GroupKind{}] = newStatusBucket(clk, defaultLimit)
+	return sl
+}
+
+func newStatusBucket(clk clock.PassiveClock, limit StatusLimit) flowcontrol.PassiveRateLimiter {
+	if limit.QPS <= 0 {
+		return nil
+	}
+	burst := int(limit.QPS)
+	if burst < 1 {
+		burst = 1
+	}
+	return flowcontrol.NewTokenBucketPassiveRateLimiterWithClock(limit.QPS, burst, clk)
+}
+
+// SetKindLimits replaces the per-kind limits.
+// The rate caps of the kinds whose limit is unchanged are kept.
+func (sl *StatusLimiter) SetKindLimits(kindLimits map[schema.GroupKind]StatusLimit) {
+	sl.Lock()
+	defer sl.Unlock()
+	for kind := range sl.kindLimits {
+		if limit, found := kindLimits[kind]; !found || limit != sl.kindLimits[kind] {
+			delete(sl.buckets, kind)
+		}
+	}
+	for kind, limit := range kindLimits {
+		if _, found := sl.buckets[kind]; !found {
+			sl.buckets[kind] = newStatusBucket(sl.clock, limit)
+		}
+	}
+	sl.kindLimits = kindLimits
+}
+
+func (sl *StatusLimiter) limitAndBucket(kind schema.GroupKind) (StatusLimit, flowcontrol.PassiveRateLimiter) {
+	if limit, found := sl.kindLimits[kind]; found {
+		return limit, sl.buckets[kind]
+	}
+	return sl.defaultLimit, sl.buckets[schema.GroupKind{}]
+}
+
+// Allow tells whether the status of the given object may be written now,
+// and if so counts it as written. A nil StatusLimiter allows everything.
+func (sl *StatusLimiter) Allow(kind schema.GroupKind, namespace, name string) bool {
+	if sl == nil {
+		return true
+	}
+	sl.Lock()
+	defer sl.Unlock()
+	now := sl.clock.Now()
+	sl.pruneLocked(now)
+	limit, bucket := sl.limitAndBucket(kind)
+	key := statusObjectKey{kind: kind, namespace: namespace, name: name}
+	if last, found := sl.lastWritten[key]; found && now.Sub(last) < limit.Window {
+		statusUpdatesHeld.WithLabelValues("window").Inc()
+		return false
+	}
+	if bucket != nil && !bucket.TryAccept() {
+		statusUpdatesHeld.WithLabelValues("rate").Inc()
+		return false
+	}
+	sl.lastWritten[key] = now
+	return true
+}
+
+func (sl *StatusLimiter) pruneLocked(now time.Time) {
+	if now.Sub(sl.lastPruned) < statusPruneInterval {
+		return
+	}
+	sl.lastPruned = now
+	for key, last := range sl.lastWritten {
+		if limit, _ := sl.limitAndBucket(key.kind); now.Sub(last) >= limit.Window {
+			delete(sl.lastWritten, key)
+		}
+	}
+}
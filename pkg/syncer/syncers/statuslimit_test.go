@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestStatusLimiter(t *testing.T) {
+	clk := clocktesting.NewFakePassiveClock(time.Now())
+	sl := NewStatusLimiter(clk, StatusLimit{Window: 5 * time.Second, QPS: 2})
+	jobs := schema.GroupKind{Group: "batch", Kind: "Job"}
+	pods := schema.GroupKind{Kind: "Pod"}
+	sl.SetKindLimits(map[schema.GroupKind]StatusLimit{jobs: {Window: time.Minute}})
+
+	if !sl.Allow(pods, "ns1", "a") {
+		t.Fatal("expected the first write of a to be allowed")
+	}
+	if sl.Allow(pods, "ns1", "a") {
+		t.Error("expected a second write of a within the window to be held")
+	}
+	if !sl.Allow(pods, "ns1", "b") {
+		t.Error("expected the first write of b to be allowed")
+	}
+	if sl.Allow(pods, "ns1", "c") {
+		t.Error("expected the write of c to be held by the rate cap")
+	}
+	// Jobs have their own limit, without a rate cap.
+	for _, name := range []string{"j1", "j2", "j3"} {
+		if !sl.Allow(jobs, "ns1", name) {
+			t.Errorf("expected the write of job %s to be allowed", name)
+		}
+	}
+
+	clk.SetTime(clk.Now().Add(6 * time.Second))
+	if !sl.Allow(pods, "ns1", "a") {
+		t.Error("expected a write of a after the window to be allowed")
+	}
+	if sl.Allow(jobs, "ns1", "j1") {
+		t.Error("expected a write of job j1 within its window to be held")
+	}
+
+	var nilLimiter *StatusLimiter
+	if !nilLimiter.Allow(pods, "ns1", "a") {
+		t.Error("expected a nil StatusLimiter to allow everything")
+	}
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/bundle"
+	kserrors "github.com/kubestellar/kubestellar/pkg/errors"
+	. "github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
+)
+
+// Unbundler unpacks the bundles that the placement translator writes into
+// the mailbox space and maintains the bundled objects downstream.
+// An unbundled object is marked with bundle.UnbundledAnnotationKey rather
+// than the downsync annotation, so the DownSyncer leaves it alone.
+type Unbundler struct {
+	logger                  klog.Logger
+	upstreamClientFactory   ClientFactory
+	downstreamClientFactory ClientFactory
+
+	// applied holds the objects that were in the bundles on the previous pass.
+	// It is recorded in the bundle.AppliedName ConfigMap in the upstream and
+	// loaded from there on the first pass, so that the objects dropped from
+	// the bundles while the syncer was not running are deleted too.
+	applied map[bundle.ObjectRef]struct{}
+
+	// appliedLoaded tells whether applied has been loaded from the upstream
+	appliedLoaded bool
+
+	// appliedDirty tells whether applied differs from what is recorded in the upstream
+	appliedDirty bool
+}
+
+func NewUnbundler(logger klog.Logger, upstreamClientFactory ClientFactory, downstreamClientFactory ClientFactory) *Unbundler {
+	return &Unbundler{
+		logger:                  logger.WithName("Unbundler"),
+		upstreamClientFactory:   upstreamClientFactory,
+		downstreamClientFactory: downstreamClientFactory,
+		applied:                 map[bundle.ObjectRef]struct{}{},
+	}
+}
+
+func refToResource(ref bundle.ObjectRef) edgev2alpha1.EdgeSyncConfigResource {
+	return edgev2alpha1.EdgeSyncConfigResource{Group: ref.Group, Version: ref.Version, Kind: ref.Kind, Namespace: ref.Namespace, Name: ref.Name}
+}
+
+// Sync makes the downstream objects match the current bundles.
+func (ub *Unbundler) Sync() error {
+	if !ub.appliedLoaded {
+		if err := ub.loadApplied(); err != nil {
+			ub.logger.Error(err, "failed to load the record of unbundled objects from upstream")
+			return err
+		}
+	}
+	desired, err := ub.readBundles()
+	if err != nil {
+		ub.logger.Error(err, "failed to read bundles from upstream")
+		return err
+	}
+	clients := map[schema.GroupVersionKind]*Client{}
+	getClient := func(factory ClientFactory, ref bundle.ObjectRef) (*Client, error) {
+		gvk := schema.GroupVersionKind{Group: ref.Group, Version: ref.Version, Kind: ref.Kind}
+		if client, ok := clients[gvk]; ok {
+			return client, nil
+		}
+		client, err := factory.GetVersionedResourceClient(ref.Group, ref.Version, ref.Kind)
+		if err != nil {
+			return nil, err
+		}
+		clients[gvk] = &client
+		return &client, nil
+	}
+	var lastErr error
+	for ref, obj := range desired {
+		client, err := getClient(ub.downstreamClientFactory, ref)
+		if err != nil {
+			ub.logger.Error(err, "failed to get downstream client", "object", ref)
+			lastErr = err
+			continue
+		}
+		if err := ub.apply(client, ref, obj); err != nil {
+			lastErr = err
+		}
+	}
+	for ref := range ub.applied {
+		if _, ok := desired[ref]; ok {
+			continue
+		}
+		// The object may have left the bundles because it is now projected
+		// individually, in which case the DownSyncer takes it over.
+		upstreamClient, err := ub.upstreamClientFactory.GetVersionedResourceClient(ref.Group, ref.Version, ref.Kind)
+		if err == nil {
+			if _, err := upstreamClient.Get(refToResource(ref)); err == nil {
+				ub.logger.V(3).Info("  leaving object that is now downsynced individually", "object", ref)
+				continue
+			}
+		}
+		client, err := getClient(ub.downstreamClientFactory, ref)
+		if err != nil {
+			ub.logger.Error(err, "failed to get downstream client", "object", ref)
+			lastErr = err
+			desired[ref] = nil // try again next time
+			continue
+		}
+		if err := ub.remove(client, ref); err != nil {
+			lastErr = err
+			desired[ref] = nil
+		}
+	}
+	applied := map[bundle.ObjectRef]struct{}{}
+	for ref := range desired {
+		applied[ref] = struct{}{}
+	}
+	if !apiequality.Semantic.DeepEqual(applied, ub.applied) {
+		ub.applied = applied
+		ub.appliedDirty = true
+	}
+	if ub.appliedDirty {
+		if err := ub.recordApplied(); err != nil {
+			ub.logger.Error(err, "failed to record unbundled objects in upstream")
+			lastErr = err
+		} else {
+			ub.appliedDirty = false
+		}
+	}
+	return lastErr
+}
+
+func (ub *Unbundler) getUpstreamConfigMap(cmClient *Client, name string) (*corev1.ConfigMap, error) {
+	cmU, err := cmClient.Get(edgev2alpha1.EdgeSyncConfigResource{Kind: "ConfigMap", Version: "v1", Namespace: bundle.Namespace, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	cm := &corev1.ConfigMap{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(cmU.Object, cm); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// loadApplied reads the record of the unbundled objects from the upstream.
+// A malformed record is ignored, after logging.
+func (ub *Unbundler) loadApplied() error {
+	cmClient, err := ub.upstreamClientFactory.GetResourceClient("", "ConfigMap")
+	if err != nil {
+		return err
+	}
+	appliedCM, err := ub.getUpstreamConfigMap(&cmClient, bundle.AppliedName)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		applied, err := bundle.ParseApplied(appliedCM)
+		if err != nil {
+			ub.logger.Error(err, "ignoring malformed record of unbundled objects")
+		} else {
+			ub.applied = applied
+		}
+	}
+	ub.appliedLoaded = true
+	ub.logger.V(3).Info("loaded record of unbundled objects", "objects", len(ub.applied))
+	return nil
+}
+
+// recordApplied writes the record of the unbundled objects into the upstream.
+func (ub *Unbundler) recordApplied() error {
+	cmClient, err := ub.upstreamClientFactory.GetResourceClient("", "ConfigMap")
+	if err != nil {
+		return err
+	}
+	appliedCM, err := bundle.NewApplied(ub.applied)
+	if err != nil {
+		return err
+	}
+	resource := edgev2alpha1.EdgeSyncConfigResource{Kind: "ConfigMap", Version: "v1", Namespace: bundle.Namespace, Name: bundle.AppliedName}
+	existing, err := cmClient.Get(resource)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+	if !found && len(ub.applied) == 0 {
+		// Nothing to record, and the namespace may not exist
+		return nil
+	}
+	appliedObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(appliedCM)
+	if err != nil {
+		return err
+	}
+	appliedU := &unstructured.Unstructured{Object: appliedObj}
+	if !found {
+		_, err = cmClient.Create(resource, appliedU)
+		return err
+	}
+	appliedU.SetResourceVersion(existing.GetResourceVersion())
+	_, err = cmClient.Update(resource, appliedU)
+	return err
+}
+
+// readBundles returns the objects in the current bundles in the upstream.
+func (ub *Unbundler) readBundles() (map[bundle.ObjectRef]*unstructured.Unstructured, error) {
+	desired := map[bundle.ObjectRef]*unstructured.Unstructured{}
+	cmClient, err := ub.upstreamClientFactory.GetResourceClient("", "ConfigMap")
+	if err != nil {
+		return nil, err
+	}
+	indexCM, err := ub.getUpstreamConfigMap(&cmClient, bundle.IndexName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return desired, nil
+		}
+		return nil, err
+	}
+	index, err := bundle.ParseIndex(indexCM)
+	if err != nil {
+		return nil, err
+	}
+	for _, indexPart := range index.Parts {
+		part, err := ub.getUpstreamConfigMap(&cmClient, indexPart.Name)
+		if err != nil {
+			// The index may have been replaced since it was read
+			return nil, fmt.Errorf("failed to get bundle part %s: %w", indexPart.Name, err)
+		}
+		objs, err := bundle.Unpack(part)
+		if err != nil {
+			return nil, err
+		}
+		if len(objs) != len(indexPart.Objects) {
+			return nil, fmt.Errorf("bundle part %s has %d objects but the index lists %d", indexPart.Name, len(objs), len(indexPart.Objects))
+		}
+		for idx, obj := range objs {
+			desired[indexPart.Objects[idx]] = obj
+		}
+	}
+	ub.logger.V(3).Info("read bundles", "parts", len(index.Parts), "objects", len(desired))
+	return desired, nil
+}
+
+func (ub *Unbundler) apply(client *Client, ref bundle.ObjectRef, obj *unstructured.Unstructured) error {
+	hash, err := bundle.ContentHash(obj)
+	if err != nil {
+		ub.logger.Error(err, "failed to hash bundled object", "object", ref)
+		return err
+	}
+	resource := refToResource(ref)
+	obj = obj.DeepCopy()
+	annotations := obj.GetAnnotations()
+	delete(annotations, downsyncKey)
+	obj.SetAnnotations(annotations)
+	setAnnotation(obj, bundle.UnbundledAnnotationKey, hash)
+	existing, err := client.Get(resource)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			ub.logger.Error(err, "failed to get object from downstream", "object", ref)
+			return err
+		}
+		ub.logger.V(3).Info("  create unbundled object in downstream", "object", ref)
+		normalizeForCreate(obj)
+		if _, err := client.Create(resource, obj); err != nil {
+			err = classifyWriteFailure("create", err)
+			ub.logger.Error(err, "failed to create unbundled object in downstream", "object", ref, "reason", kserrors.ReasonOf(err))
+			return err
+		}
+		return nil
+	}
+	if getAnnotation(existing, bundle.UnbundledAnnotationKey) == hash {
+		return nil
+	}
+	if !isDownsyncOverwrite(existing) {
+		ub.logger.V(2).Info("  ignore updating unbundled object since downsync-overwrite is false", "object", ref)
+		return nil
+	}
+	ub.logger.V(3).Info("  update unbundled object in downstream", "object", ref)
+	normalizeForUpdate(obj, existing)
+	if _, err := client.Update(resource, obj); err != nil {
+		err = classifyWriteFailure("update", err)
+		ub.logger.Error(err, "failed to update unbundled object in downstream", "object", ref, "reason", kserrors.ReasonOf(err))
+		return err
+	}
+	return nil
+}
+
+func (ub *Unbundler) remove(client *Client, ref bundle.ObjectRef) error {
+	resource := refToResource(ref)
+	existing, err := client.Get(resource)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		ub.logger.Error(err, "failed to get object from downstream", "object", ref)
+		return err
+	}
+	if getAnnotation(existing, bundle.UnbundledAnnotationKey) == "" || !isDownsyncOverwrite(existing) {
+		ub.logger.V(2).Info("  ignore deleting object that is not owned as unbundled", "object", ref)
+		return nil
+	}
+	ub.logger.V(3).Info("  delete unbundled object from downstream", "object", ref)
+	if err := client.Delete(resource, ref.Name); err != nil && !k8serrors.IsNotFound(err) {
+		err = classifyWriteFailure("delete", err)
+		ub.logger.Error(err, "failed to delete unbundled object from downstream", "object", ref, "reason", kserrors.ReasonOf(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/klog/v2"
+
+	"github.com/kubestellar/kubestellar/pkg/bundle"
+	"github.com/kubestellar/kubestellar/pkg/syncer/clientfactory"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func toUnstructured(t *testing.T, cm *corev1.ConfigMap) *unstructured.Unstructured {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func newTestClientFactory(t *testing.T, client *dynamicfake.FakeDynamicClient) clientfactory.ClientFactory {
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: metav1.Verbs{"get", "list", "create", "update", "delete"}}},
+	}}}}
+	factory, err := clientfactory.NewClientFactory(klog.Background(), client, discoveryClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return factory
+}
+
+func TestUnbundlerSync(t *testing.T) {
+	ctx := context.Background()
+	bundled := map[bundle.ObjectRef]*unstructured.Unstructured{}
+	for _, name := range []string{"a", "b"} {
+		obj := newConfigMap(name, "v", false)
+		bundled[bundle.RefOf("configmaps", obj)] = obj
+	}
+	indexCM, parts, err := bundle.Pack(bundled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "gone" left the bundles while the syncer was not running
+	gone := newConfigMap("gone", "v", false)
+	gone.SetAnnotations(map[string]string{bundle.UnbundledAnnotationKey: "somehash"})
+	recorded := map[bundle.ObjectRef]struct{}{bundle.RefOf("configmaps", gone): {}}
+	for ref := range bundled {
+		recorded[ref] = struct{}{}
+	}
+	appliedCM, err := bundle.NewApplied(recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstreamObjs := []runtime.Object{toUnstructured(t, indexCM), toUnstructured(t, appliedCM)}
+	for _, part := range parts {
+		upstreamObjs = append(upstreamObjs, toUnstructured(t, part))
+	}
+	upstream := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), upstreamObjs...)
+	downstream := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), gone)
+
+	ub := NewUnbundler(klog.Background(), newTestClientFactory(t, upstream), newTestClientFactory(t, downstream))
+	if err := ub.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		obj, err := downstream.Resource(configMapsGVR).Namespace("ns1").Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("expected %s to be unbundled: %v", name, err)
+		}
+		if obj.GetAnnotations()[bundle.UnbundledAnnotationKey] == "" {
+			t.Errorf("expected %s to bear the unbundled annotation", name)
+		}
+	}
+	if _, err := downstream.Resource(configMapsGVR).Namespace("ns1").Get(ctx, "gone", metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		t.Errorf("expected the object that left the bundles to be deleted, got %v", err)
+	}
+
+	appliedU, err := upstream.Resource(configMapsGVR).Namespace(bundle.Namespace).Get(ctx, bundle.AppliedName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appliedCM = &corev1.ConfigMap{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(appliedU.Object, appliedCM); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := bundle.ParseApplied(appliedCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[bundle.ObjectRef]struct{}{}
+	for ref := range bundled {
+		expected[ref] = struct{}{}
+	}
+	if !apiequality.Semantic.DeepEqual(applied, expected) {
+		t.Errorf("expected the record of unbundled objects to be %v, got %v", expected, applied)
+	}
+}
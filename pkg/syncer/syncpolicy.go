@@ -0,0 +1,234 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/restmapper"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/syncer/syncers"
+)
+
+// ResourcePolicy says how the syncer treats the objects of one resource.
+type ResourcePolicy struct {
+	// Priority orders the resources within each pass of the sync loop;
+	// higher goes first. Resources without a policy have priority zero.
+	Priority int
+
+	// Interval is the minimum time between syncs of the resource.
+	// Zero means every pass of the sync loop.
+	Interval time.Duration
+}
+
+// ParseResourcePolicy parses a policy of the form
+// `RESOURCE[.GROUP]=PRIORITY[:INTERVAL]`, for example `secrets=100:5s`
+// or `widgets.example.com=-10:10m`.
+func ParseResourcePolicy(spec string) (schema.GroupResource, ResourcePolicy, error) {
+	grStr, policyStr, found := strings.Cut(spec, "=")
+	if !found || grStr == "" || policyStr == "" {
+		return schema.GroupResource{}, ResourcePolicy{}, fmt.Errorf("resource policy %q does not have the form RESOURCE[.GROUP]=PRIORITY[:INTERVAL]", spec)
+	}
+	priorityStr, intervalStr, hasInterval := strings.Cut(policyStr, ":")
+	var policy ResourcePolicy
+	var err error
+	if policy.Priority, err = strconv.Atoi(priorityStr); err != nil {
+		return schema.GroupResource{}, ResourcePolicy{}, fmt.Errorf("resource policy %q has a malformed priority: %w", spec, err)
+	}
+	if hasInterval {
+		if policy.Interval, err = time.ParseDuration(intervalStr); err != nil {
+			return schema.GroupResource{}, ResourcePolicy{}, fmt.Errorf("resource policy %q has a malformed interval: %w", spec, err)
+		}
+		if policy.Interval < 0 {
+			return schema.GroupResource{}, ResourcePolicy{}, fmt.Errorf("resource policy %q has a negative interval", spec)
+		}
+	}
+	return schema.ParseGroupResource(grStr), policy, nil
+}
+
+// ParseStatusLimit parses a status limit of the form
+// `RESOURCE[.GROUP]=WINDOW[:QPS]`, for example `jobs.batch=30s:2`.
+func ParseStatusLimit(spec string) (schema.GroupResource, syncers.StatusLimit, error) {
+	grStr, limitStr, found := strings.Cut(spec, "=")
+	if !found || grStr == "" || limitStr == "" {
+		return schema.GroupResource{}, syncers.StatusLimit{}, fmt.Errorf("status limit %q does not have the form RESOURCE[.GROUP]=WINDOW[:QPS]", spec)
+	}
+	windowStr, qpsStr, hasQPS := strings.Cut(limitStr, ":")
+	var limit syncers.StatusLimit
+	var err error
+	if limit.Window, err = time.ParseDuration(windowStr); err != nil {
+		return schema.GroupResource{}, syncers.StatusLimit{}, fmt.Errorf("status limit %q has a malformed window: %w", spec, err)
+	}
+	if limit.Window < 0 {
+		return schema.GroupResource{}, syncers.StatusLimit{}, fmt.Errorf("status limit %q has a negative window", spec)
+	}
+	if hasQPS {
+		qps, err := strconv.ParseFloat(qpsStr, 32)
+		if err != nil {
+			return schema.GroupResource{}, syncers.StatusLimit{}, fmt.Errorf("status limit %q has a malformed QPS: %w", spec, err)
+		}
+		if qps < 0 {
+			return schema.GroupResource{}, syncers.StatusLimit{}, fmt.Errorf("status limit %q has a negative QPS", spec)
+		}
+		limit.QPS = float32(qps)
+	}
+	return schema.ParseGroupResource(grStr), limit, nil
+}
+
+// byKind maps values given per resource to the kinds of those resources,
+// using the given discovery results.
+func byKind[Value any](byResource map[schema.GroupResource]Value, groupResourcesLists ...[]*restmapper.APIGroupResources) map[schema.GroupKind]Value {
+	ans := map[schema.GroupKind]Value{}
+	for _, groupResourcesList := range groupResourcesLists {
+		for _, groupResources := range groupResourcesList {
+			for _, resources := range groupResources.VersionedResources {
+				for _, resource := range resources {
+					value, found := byResource[schema.GroupResource{Group: groupResources.Group.Name, Resource: resource.Name}]
+					if found {
+						ans[schema.GroupKind{Group: groupResources.Group.Name, Kind: resource.Kind}] = value
+					}
+				}
+			}
+		}
+	}
+	return ans
+}
+
+// kindResolution says when the mapping of policies to kinds has to be
+// redone. Discovery on both clusters is too costly for every pass of the
+// sync loop, so the mapping is redone only after maxAge, or when a synced
+// resource has a kind that the last discovery did not see, which is what
+// a newly installed CRD looks like. In the latter case it waits at least
+// retryAge, since the kind may not exist on either side yet.
+type kindResolution struct {
+	maxAge, retryAge time.Duration
+
+	resolvedAt time.Time
+	known      sets.String // GroupKind.String() of every discovered kind
+}
+
+// due returns whether the mapping has to be redone before syncing the given resources.
+func (kr *kindResolution) due(now time.Time, resourcesLists ...[]edgev2alpha1.EdgeSyncConfigResource) bool {
+	age := now.Sub(kr.resolvedAt)
+	if kr.known == nil || age >= kr.maxAge {
+		return true
+	}
+	if age < kr.retryAge {
+		return false
+	}
+	for _, resources := range resourcesLists {
+		for _, resource := range resources {
+			if !kr.known.Has(schema.GroupKind{Group: resource.Group, Kind: resource.Kind}.String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// done notes that the mapping was redone from the given discovery results.
+func (kr *kindResolution) done(now time.Time, groupResourcesLists ...[]*restmapper.APIGroupResources) {
+	kr.resolvedAt = now
+	kr.known = sets.NewString()
+	for _, groupResourcesList := range groupResourcesLists {
+		for _, groupResources := range groupResourcesList {
+			for _, resources := range groupResources.VersionedResources {
+				for _, resource := range resources {
+					kr.known.Insert(schema.GroupKind{Group: groupResources.Group.Name, Kind: resource.Kind}.String())
+				}
+			}
+		}
+	}
+}
+
+// syncScheduler decides which resources each pass of the sync loop
+// handles, and in what order, according to the ResourcePolicies.
+// The policies are given per resource but the sync loop deals in kinds,
+// so they are resolved through discovery.
+type syncScheduler struct {
+	policies   map[schema.GroupResource]ResourcePolicy
+	kindPolicy map[schema.GroupKind]ResourcePolicy
+
+	// lastSynced is when each resource was last handed out, per actor.
+	lastSynced map[string]time.Time
+}
+
+func newSyncScheduler(policies map[schema.GroupResource]ResourcePolicy) *syncScheduler {
+	return &syncScheduler{
+		policies:   policies,
+		kindPolicy: map[schema.GroupKind]ResourcePolicy{},
+		lastSynced: map[string]time.Time{},
+	}
+}
+
+// tickInterval returns the period of the sync loop, which is the given
+// one or the shortest policy interval, whichever is shorter.
+func (sched *syncScheduler) tickInterval(interval time.Duration) time.Duration {
+	for _, policy := range sched.policies {
+		if policy.Interval > 0 && policy.Interval < interval {
+			interval = policy.Interval
+		}
+	}
+	if interval < minimumInterval {
+		interval = minimumInterval
+	}
+	return interval
+}
+
+// resolve maps the policies to kinds, using the given discovery results.
+func (sched *syncScheduler) resolve(groupResourcesLists ...[]*restmapper.APIGroupResources) {
+	sched.kindPolicy = byKind(sched.policies, groupResourcesLists...)
+}
+
+// due returns the given resources that are due to be synced by the given
+// actor, highest priority first, and notes that they are being synced.
+// Namespaces always go first because the objects in them need them.
+func (sched *syncScheduler) due(actor string, resources []edgev2alpha1.EdgeSyncConfigResource, now time.Time) []edgev2alpha1.EdgeSyncConfigResource {
+	if len(sched.policies) == 0 {
+		return resources
+	}
+	ans := make([]edgev2alpha1.EdgeSyncConfigResource, 0, len(resources))
+	for _, resource := range resources {
+		policy := sched.kindPolicy[schema.GroupKind{Group: resource.Group, Kind: resource.Kind}]
+		key := fmt.Sprintf("%s %s.%s/%s in %s", actor, resource.Kind, resource.Group, resource.Name, resource.Namespace)
+		if last, found := sched.lastSynced[key]; found && now.Sub(last) < policy.Interval {
+			continue
+		}
+		sched.lastSynced[key] = now
+		ans = append(ans, resource)
+	}
+	rank := func(resource edgev2alpha1.EdgeSyncConfigResource) (bool, int) {
+		isNamespace := resource.Group == "" && resource.Kind == "Namespace"
+		return isNamespace, sched.kindPolicy[schema.GroupKind{Group: resource.Group, Kind: resource.Kind}].Priority
+	}
+	sort.SliceStable(ans, func(i, j int) bool {
+		iNamespace, iPriority := rank(ans[i])
+		jNamespace, jPriority := rank(ans[j])
+		if iNamespace != jNamespace {
+			return iNamespace
+		}
+		return iPriority > jPriority
+	})
+	return ans
+}
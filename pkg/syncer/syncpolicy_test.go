@@ -0,0 +1,160 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/syncer/syncers"
+)
+
+func TestParseResourcePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		spec     string
+		gr       schema.GroupResource
+		policy   ResourcePolicy
+		expectOK bool
+	}{
+		{"secrets=100:5s", schema.GroupResource{Resource: "secrets"}, ResourcePolicy{Priority: 100, Interval: 5 * time.Second}, true},
+		{"widgets.example.com=-10", schema.GroupResource{Group: "example.com", Resource: "widgets"}, ResourcePolicy{Priority: -10}, true},
+		{"secrets", schema.GroupResource{}, ResourcePolicy{}, false},
+		{"secrets=high", schema.GroupResource{}, ResourcePolicy{}, false},
+		{"secrets=1:soon", schema.GroupResource{}, ResourcePolicy{}, false},
+		{"secrets=1:-5s", schema.GroupResource{}, ResourcePolicy{}, false},
+	} {
+		gr, policy, err := ParseResourcePolicy(tc.spec)
+		if (err == nil) != tc.expectOK {
+			t.Errorf("%q: expected ok=%v, got err=%v", tc.spec, tc.expectOK, err)
+			continue
+		}
+		if tc.expectOK && (gr != tc.gr || policy != tc.policy) {
+			t.Errorf("%q: expected %v %+v, got %v %+v", tc.spec, tc.gr, tc.policy, gr, policy)
+		}
+	}
+}
+
+func TestParseStatusLimit(t *testing.T) {
+	for _, tc := range []struct {
+		spec     string
+		gr       schema.GroupResource
+		limit    syncers.StatusLimit
+		expectOK bool
+	}{
+		{"jobs.batch=30s:2", schema.GroupResource{Group: "batch", Resource: "jobs"}, syncers.StatusLimit{Window: 30 * time.Second, QPS: 2}, true},
+		{"pods=10s", schema.GroupResource{Resource: "pods"}, syncers.StatusLimit{Window: 10 * time.Second}, true},
+		{"pods=0.5:0.25", schema.GroupResource{}, syncers.StatusLimit{}, false},
+		{"pods", schema.GroupResource{}, syncers.StatusLimit{}, false},
+		{"pods=-1s", schema.GroupResource{}, syncers.StatusLimit{}, false},
+		{"pods=1s:fast", schema.GroupResource{}, syncers.StatusLimit{}, false},
+		{"pods=1s:-2", schema.GroupResource{}, syncers.StatusLimit{}, false},
+	} {
+		gr, limit, err := ParseStatusLimit(tc.spec)
+		if (err == nil) != tc.expectOK {
+			t.Errorf("%q: expected ok=%v, got err=%v", tc.spec, tc.expectOK, err)
+			continue
+		}
+		if tc.expectOK && (gr != tc.gr || limit != tc.limit) {
+			t.Errorf("%q: expected %v %+v, got %v %+v", tc.spec, tc.gr, tc.limit, gr, limit)
+		}
+	}
+}
+
+func TestSyncScheduler(t *testing.T) {
+	sched := newSyncScheduler(map[schema.GroupResource]ResourcePolicy{
+		{Resource: "secrets"}:                       {Priority: 100, Interval: 5 * time.Second},
+		{Group: "example.com", Resource: "widgets"}: {Priority: -10, Interval: time.Minute},
+	})
+	if actual := sched.tickInterval(15 * time.Second); actual != 5*time.Second {
+		t.Errorf("expected the tick interval to be 5s, got %v", actual)
+	}
+	sched.resolve([]*restmapper.APIGroupResources{
+		{Group: metav1.APIGroup{Name: ""}, VersionedResources: map[string][]metav1.APIResource{
+			"v1": {{Name: "secrets", Kind: "Secret"}, {Name: "configmaps", Kind: "ConfigMap"}}}},
+		{Group: metav1.APIGroup{Name: "example.com"}, VersionedResources: map[string][]metav1.APIResource{
+			"v1": {{Name: "widgets", Kind: "Widget"}}}},
+	})
+	resources := []edgev2alpha1.EdgeSyncConfigResource{
+		{Group: "example.com", Version: "v1", Kind: "Widget", Namespace: "ns1", Name: "*"},
+		{Version: "v1", Kind: "ConfigMap", Namespace: "ns1", Name: "*"},
+		{Version: "v1", Kind: "Secret", Namespace: "ns1", Name: "*"},
+		{Version: "v1", Kind: "Namespace", Name: "ns1"},
+	}
+	kinds := func(resources []edgev2alpha1.EdgeSyncConfigResource) []string {
+		ans := []string{}
+		for _, resource := range resources {
+			ans = append(ans, resource.Kind)
+		}
+		return ans
+	}
+	start := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	for _, step := range []struct {
+		offset   time.Duration
+		expected []string
+	}{
+		{0, []string{"Namespace", "Secret", "ConfigMap", "Widget"}},
+		{5 * time.Second, []string{"Namespace", "Secret", "ConfigMap"}},
+		{7 * time.Second, []string{"Namespace", "ConfigMap"}},
+		{time.Minute, []string{"Namespace", "Secret", "ConfigMap", "Widget"}},
+	} {
+		actual := kinds(sched.due("DownSyncer:Sync", resources, start.Add(step.offset)))
+		if len(actual) != len(step.expected) {
+			t.Errorf("at %v expected %v, got %v", step.offset, step.expected, actual)
+			continue
+		}
+		for idx := range actual {
+			if actual[idx] != step.expected[idx] {
+				t.Errorf("at %v expected %v, got %v", step.offset, step.expected, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestKindResolution(t *testing.T) {
+	kr := &kindResolution{maxAge: 10 * time.Minute, retryAge: time.Minute}
+	secrets := []edgev2alpha1.EdgeSyncConfigResource{{Version: "v1", Kind: "Secret", Namespace: "ns1", Name: "*"}}
+	widgets := []edgev2alpha1.EdgeSyncConfigResource{{Group: "example.com", Version: "v1", Kind: "Widget", Namespace: "ns1", Name: "*"}}
+	start := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	if !kr.due(start, secrets) {
+		t.Error("expected the first resolution to be due")
+	}
+	kr.done(start, []*restmapper.APIGroupResources{
+		{Group: metav1.APIGroup{Name: ""}, VersionedResources: map[string][]metav1.APIResource{
+			"v1": {{Name: "secrets", Kind: "Secret"}}}},
+	})
+	for _, step := range []struct {
+		offset    time.Duration
+		resources []edgev2alpha1.EdgeSyncConfigResource
+		expected  bool
+	}{
+		{time.Second, secrets, false},
+		{time.Second, widgets, false}, // unknown kind, but retried too recently
+		{2 * time.Minute, secrets, false},
+		{2 * time.Minute, widgets, true},
+		{10 * time.Minute, secrets, true},
+	} {
+		if actual := kr.due(start.Add(step.offset), step.resources); actual != step.expected {
+			t.Errorf("at %v with %s expected due=%v, got %v", step.offset, step.resources[0].Kind, step.expected, actual)
+		}
+	}
+}
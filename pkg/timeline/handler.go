@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultWindow is how far back a query reaches when it does not give `from`.
+	DefaultWindow = time.Hour
+
+	// DefaultLimit is the number of events returned when the query does not give `limit`.
+	DefaultLimit = 1000
+)
+
+// EventList is the body of a response from the Handler.
+type EventList struct {
+	Kind  string  `json:"kind"`
+	Items []Event `json:"items"`
+
+	// Truncated tells whether there are more events in the interval;
+	// to get them, query again from the time of the last returned event.
+	Truncated bool `json:"truncated"`
+}
+
+// Handler serves queries of the given Store.
+// The query parameters are `placement` (optional; default is all),
+// `from` and `to` (RFC 3339 times; default is the last DefaultWindow),
+// and `limit`.
+type Handler struct {
+	Store *Store
+	now   func() time.Time
+}
+
+var _ http.Handler = &Handler{}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "this API is read-only"})
+		return
+	}
+	now := time.Now
+	if handler.now != nil {
+		now = handler.now
+	}
+	query := req.URL.Query()
+	to, err := parseTime(query.Get("to"), now())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "to: " + err.Error()})
+		return
+	}
+	from, err := parseTime(query.Get("from"), to.Add(-DefaultWindow))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from: " + err.Error()})
+		return
+	}
+	if !from.Before(to) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "from must be before to"})
+		return
+	}
+	limit := DefaultLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+	}
+	events, truncated := handler.Store.Query(query.Get("placement"), from, to, limit)
+	writeJSON(w, http.StatusOK, EventList{Kind: "TimelineEventList", Items: events, Truncated: truncated})
+}
+
+func parseTime(timeStr string, dflt time.Time) (time.Time, error) {
+	if timeStr == "" {
+		return dflt, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, timeStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be an RFC 3339 time: %w", err)
+	}
+	return parsed, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeline
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/destination"
+)
+
+// placementState is what the timeline last said about a placement.
+type placementState struct {
+	uid          types.UID
+	generation   int64 // negative if unknown
+	destinations sets.String
+	conditions   map[string]metav1.Condition
+}
+
+// Recorder turns notifications about EdgePlacements and their
+// SinglePlacementSlices into timeline events.
+// Its notion of the current state is initialized by replaying the Store,
+// so that a restart (which re-notifies about every object) does not
+// produce spurious events.
+type Recorder struct {
+	logger klog.Logger
+	store  *Store
+	now    func() time.Time
+
+	mutex      sync.Mutex
+	placements map[string]*placementState
+}
+
+// NewRecorder makes a Recorder that appends to the given Store.
+func NewRecorder(logger klog.Logger, store *Store) *Recorder {
+	rec := &Recorder{logger: logger, store: store, now: time.Now, placements: map[string]*placementState{}}
+	for _, event := range store.Events() {
+		rec.apply(event)
+	}
+	return rec
+}
+
+// apply updates the state according to the given event.
+// Called with the mutex locked, or before the Recorder is shared.
+func (rec *Recorder) apply(event Event) {
+	if event.Type == EventDeleted {
+		delete(rec.placements, event.Placement)
+		return
+	}
+	state := rec.placements[event.Placement]
+	// The Created event may have been pruned away, leaving later events.
+	if state == nil || event.Type == EventCreated {
+		state = &placementState{uid: event.UID, generation: -1, destinations: sets.NewString(), conditions: map[string]metav1.Condition{}}
+		rec.placements[event.Placement] = state
+	}
+	switch event.Type {
+	case EventCreated, EventSpecChanged:
+		state.generation = event.Generation
+	case EventDestinationsChanged:
+		state.destinations.Insert(event.Added...)
+		state.destinations.Delete(event.Removed...)
+	case EventConditionChanged:
+		state.conditions[event.Condition] = metav1.Condition{Type: event.Condition, Status: metav1.ConditionStatus(event.Status), Reason: event.Reason}
+	}
+}
+
+func (rec *Recorder) record(event Event) {
+	rec.apply(event)
+	if err := rec.store.Append(event); err != nil {
+		rec.logger.Error(err, "Failed to append to timeline", "event", event)
+	}
+}
+
+// ObservePlacement records the changes to the given EdgePlacement since it was last observed.
+func (rec *Recorder) ObservePlacement(ep *edgev2alpha1.EdgePlacement) {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	now := metav1.NewTime(rec.now())
+	state := rec.placements[ep.Name]
+	if state == nil || state.uid != ep.UID {
+		if state != nil {
+			rec.record(Event{Time: now, Placement: ep.Name, UID: state.uid, Type: EventDeleted})
+		}
+		rec.record(Event{Time: ep.CreationTimestamp, Placement: ep.Name, UID: ep.UID, Type: EventCreated, Generation: ep.Generation})
+		state = rec.placements[ep.Name]
+	} else if state.generation < 0 {
+		state.generation = ep.Generation // not known from the timeline
+	} else if ep.Generation != state.generation {
+		rec.record(Event{Time: now, Placement: ep.Name, UID: ep.UID, Type: EventSpecChanged, Generation: ep.Generation})
+	}
+	for _, cond := range ep.Status.Conditions {
+		prev, have := state.conditions[cond.Type]
+		if have && prev.Status == cond.Status && prev.Reason == cond.Reason {
+			continue
+		}
+		when := cond.LastTransitionTime
+		if when.IsZero() {
+			when = now
+		}
+		rec.record(Event{Time: when, Placement: ep.Name, UID: ep.UID, Type: EventConditionChanged,
+			Condition: cond.Type, Status: string(cond.Status), Reason: cond.Reason, Message: cond.Message})
+	}
+}
+
+// ForgetPlacement records the deletion of the named EdgePlacement.
+func (rec *Recorder) ForgetPlacement(name string) {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	state := rec.placements[name]
+	if state == nil {
+		return
+	}
+	rec.record(Event{Time: metav1.NewTime(rec.now()), Placement: name, UID: state.uid, Type: EventDeleted})
+}
+
+// ObserveSlice records the changes in the destinations of the placement
+// that the given SinglePlacementSlice belongs to.
+// A slice with no destinations is equivalent to a deleted slice.
+func (rec *Recorder) ObserveSlice(sps *edgev2alpha1.SinglePlacementSlice) {
+	destinations := sets.NewString()
+	for _, dest := range sps.Destinations {
+		destinations.Insert(destination.Of(dest).Key())
+	}
+	rec.observeDestinations(owningPlacementName(sps), destinations)
+}
+
+func (rec *Recorder) observeDestinations(epName string, destinations sets.String) {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+	state := rec.placements[epName]
+	if state == nil {
+		// The placement has not been seen yet; its slice will be observed again.
+		return
+	}
+	upgradeLegacyDestinations(state.destinations, destinations)
+	added := destinations.Difference(state.destinations).List()
+	removed := state.destinations.Difference(destinations).List()
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	rec.record(Event{Time: metav1.NewTime(rec.now()), Placement: epName, UID: state.uid, Type: EventDestinationsChanged,
+		Added: added, Removed: removed})
+}
+
+// upgradeLegacyDestinations replaces, in the remembered destinations, each
+// bare SyncTarget name (as written by releases that did not include the
+// inventory space ID) with the Key of the current destination with that
+// SyncTarget name, if there is exactly one. This keeps a timeline that was
+// written by an older release from showing every destination as replaced.
+// The stored events are left as they are.
+func upgradeLegacyDestinations(remembered, current sets.String) {
+	for _, legacy := range remembered.UnsortedList() {
+		if strings.Contains(legacy, destination.Separator) {
+			continue
+		}
+		var match string
+		for key := range current {
+			if dest, err := destination.Parse(key); err == nil && dest.SyncTargetName == legacy {
+				if match != "" {
+					match = ""
+					break
+				}
+				match = key
+			}
+		}
+		if match != "" {
+			remembered.Delete(legacy)
+			remembered.Insert(match)
+		}
+	}
+}
+
+// PlacementHandler returns the notification handler for an EdgePlacement informer.
+func (rec *Recorder) PlacementHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { rec.ObservePlacement(obj.(*edgev2alpha1.EdgePlacement)) },
+		UpdateFunc: func(_, obj any) { rec.ObservePlacement(obj.(*edgev2alpha1.EdgePlacement)) },
+		DeleteFunc: func(obj any) {
+			if tomb, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tomb.Obj
+			}
+			if ep, ok := obj.(*edgev2alpha1.EdgePlacement); ok {
+				rec.ForgetPlacement(ep.Name)
+			}
+		},
+	}
+}
+
+// SliceHandler returns the notification handler for a SinglePlacementSlice informer.
+func (rec *Recorder) SliceHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { rec.ObserveSlice(obj.(*edgev2alpha1.SinglePlacementSlice)) },
+		UpdateFunc: func(_, obj any) { rec.ObserveSlice(obj.(*edgev2alpha1.SinglePlacementSlice)) },
+		DeleteFunc: func(obj any) {
+			if tomb, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tomb.Obj
+			}
+			if sps, ok := obj.(*edgev2alpha1.SinglePlacementSlice); ok {
+				rec.observeDestinations(owningPlacementName(sps), sets.NewString())
+			}
+		},
+	}
+}
+
+// owningPlacementName returns the name of the EdgePlacement that the
+// given slice belongs to.
+func owningPlacementName(sps *edgev2alpha1.SinglePlacementSlice) string {
+	for _, owner := range sps.OwnerReferences {
+		if owner.Kind == "EdgePlacement" && owner.APIVersion == edgev2alpha1.SchemeGroupVersion.String() {
+			return owner.Name
+		}
+	}
+	return sps.Name
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package timeline records the lifecycle events of EdgePlacements into a
+// queryable timeline, so that questions like "what changed between 2pm
+// and 3pm" can be answered.
+//
+// The Store keeps the events in memory and in an append-only file of JSON
+// lines, from which it is reloaded on restart. Events older than the
+// retention period are dropped periodically, which rewrites the file.
+package timeline
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// EventType identifies what happened to a placement.
+type EventType string
+
+const (
+	EventCreated             EventType = "Created"
+	EventSpecChanged         EventType = "SpecChanged"
+	EventDeleted             EventType = "Deleted"
+	EventDestinationsChanged EventType = "DestinationsChanged"
+	EventConditionChanged    EventType = "ConditionChanged"
+)
+
+// Event is one entry in the timeline.
+type Event struct {
+	Time      metav1.Time `json:"time"`
+	Placement string      `json:"placement"`
+	UID       types.UID   `json:"uid,omitempty"`
+	Type      EventType   `json:"type"`
+
+	// Generation is set for Created and SpecChanged.
+	Generation int64 `json:"generation,omitempty"`
+
+	// Added and Removed are set for DestinationsChanged; each destination
+	// is identified by its Key (see package destination).
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+
+	// Condition, Status, Reason and Message are set for ConditionChanged.
+	Condition string `json:"condition,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Store holds the timeline.
+type Store struct {
+	logger    klog.Logger
+	path      string
+	retention time.Duration
+
+	mutex     sync.Mutex
+	events    []Event // ordered by Time
+	file      *os.File
+	listeners []func(Event)
+}
+
+// Open loads the timeline in the given file, creating the file if necessary.
+// Events are kept for the given retention period; zero means forever.
+// If path is empty then the timeline is kept only in memory.
+func Open(logger klog.Logger, path string, retention time.Duration) (*Store, error) {
+	store := &Store{logger: logger.WithValues("timelineFile", path), path: path, retention: retention}
+	if path == "" {
+		return store, nil
+	}
+	if err := store.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	store.file = file
+	store.logger.V(2).Info("Loaded timeline", "events", len(store.events))
+	return store, nil
+}
+
+func (store *Store) load() error {
+	file, err := os.Open(store.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Most likely a line cut short by a crash
+			store.logger.Error(err, "Skipping malformed timeline line")
+			continue
+		}
+		store.events = append(store.events, event)
+	}
+	sort.SliceStable(store.events, func(i, j int) bool { return store.events[i].Time.Before(&store.events[j].Time) })
+	return scanner.Err()
+}
+
+// Append adds the given event to the timeline.
+func (store *Store) Append(event Event) error {
+	store.mutex.Lock()
+	idx := sort.Search(len(store.events), func(idx int) bool { return event.Time.Before(&store.events[idx].Time) })
+	store.events = append(store.events, Event{})
+	copy(store.events[idx+1:], store.events[idx:])
+	store.events[idx] = event
+	listeners := store.listeners
+	err := store.writeLocked(event)
+	store.mutex.Unlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+	return err
+}
+
+func (store *Store) writeLocked(event Event) error {
+	if store.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = store.file.Write(append(line, '\n'))
+	return err
+}
+
+// AddListener adds a function that is called with each event appended
+// from now on, after it is in the timeline. The function is called
+// synchronously, without the Store locked, so it should not block.
+func (store *Store) AddListener(listener func(Event)) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.listeners = append(store.listeners[:len(store.listeners):len(store.listeners)], listener)
+}
+
+// Query returns the events in the interval [from, to), oldest first,
+// optionally restricted to one placement (empty string means all).
+// At most limit events are returned (non-positive means no limit), and
+// the second result tells whether there were more.
+func (store *Store) Query(placement string, from, to time.Time, limit int) ([]Event, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	start := sort.Search(len(store.events), func(idx int) bool { return !store.events[idx].Time.Time.Before(from) })
+	ans := []Event{}
+	for idx := start; idx < len(store.events) && store.events[idx].Time.Time.Before(to); idx++ {
+		if placement != "" && store.events[idx].Placement != placement {
+			continue
+		}
+		if limit > 0 && len(ans) == limit {
+			return ans, true
+		}
+		ans = append(ans, store.events[idx])
+	}
+	return ans, false
+}
+
+// Events returns a copy of all the events, oldest first.
+func (store *Store) Events() []Event {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return append([]Event{}, store.events...)
+}
+
+// Prune drops the events that are older than the retention period.
+func (store *Store) Prune(now time.Time) error {
+	if store.retention == 0 {
+		return nil
+	}
+	cutoff := now.Add(-store.retention)
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	start := sort.Search(len(store.events), func(idx int) bool { return !store.events[idx].Time.Time.Before(cutoff) })
+	if start == 0 {
+		return nil
+	}
+	store.events = append([]Event{}, store.events[start:]...)
+	if store.file == nil {
+		return nil
+	}
+	if err := store.rewrite(); err != nil {
+		return err
+	}
+	store.logger.V(3).Info("Pruned timeline", "dropped", start, "kept", len(store.events))
+	return nil
+}
+
+// rewrite replaces the file with the current events, by way of a temporary
+// file in the same directory so that a crash does not lose the timeline.
+// Called with the mutex locked.
+func (store *Store) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(store.path), filepath.Base(store.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, event := range store.events {
+		if err := encoder.Encode(event); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), store.path); err != nil {
+		return err
+	}
+	store.file.Close()
+	store.file, err = os.OpenFile(store.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	return err
+}
+
+// Run prunes the timeline every period until the context is done,
+// then closes the file.
+func (store *Store) Run(ctx context.Context, period time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := store.Prune(time.Now()); err != nil {
+			store.logger.Error(err, "Failed to prune timeline")
+		}
+	}, period)
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.file != nil {
+		store.file.Close()
+		store.file = nil
+	}
+}
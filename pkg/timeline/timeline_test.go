@@ -0,0 +1,191 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeline
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func eventTypes(events []Event) []EventType {
+	ans := make([]EventType, len(events))
+	for idx, event := range events {
+		ans[idx] = event.Type
+	}
+	return ans
+}
+
+func TestRecorderAndRestart(t *testing.T) {
+	logger := klog.Background()
+	path := filepath.Join(t.TempDir(), "timeline.jsonl")
+	base := time.Date(2023, 9, 1, 14, 0, 0, 0, time.UTC)
+	clock := base
+	store, err := Open(logger, path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := NewRecorder(logger, store)
+	rec.now = func() time.Time { return clock }
+
+	ep := &edgev2alpha1.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: "ep1", UID: "uid1", Generation: 1, CreationTimestamp: metav1.NewTime(base)}}
+	sps := &edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: "ep1"},
+		Destinations: []edgev2alpha1.SinglePlacement{{Cluster: "c1", SyncTargetName: "st1"}}}
+	rec.ObservePlacement(ep)
+	clock = clock.Add(time.Minute)
+	rec.ObserveSlice(sps)
+	rec.ObserveSlice(sps) // no change
+	clock = base.Add(70 * time.Minute)
+	ep.Generation = 2
+	rec.ObservePlacement(ep)
+	sps.Destinations = []edgev2alpha1.SinglePlacement{{Cluster: "c1", SyncTargetName: "st2"}}
+	rec.ObserveSlice(sps)
+
+	expected := []EventType{EventCreated, EventDestinationsChanged, EventSpecChanged, EventDestinationsChanged}
+	if actual := eventTypes(store.Events()); !equalTypes(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+	last := store.Events()[3]
+	if len(last.Added) != 1 || last.Added[0] != "c1/st2" || len(last.Removed) != 1 || last.Removed[0] != "c1/st1" {
+		t.Errorf("unexpected destination change %+v", last)
+	}
+
+	// What changed between 3pm and 4pm?
+	events, truncated := store.Query("ep1", base.Add(time.Hour), base.Add(2*time.Hour), 0)
+	if truncated || !equalTypes(eventTypes(events), []EventType{EventSpecChanged, EventDestinationsChanged}) {
+		t.Errorf("unexpected query result %v, truncated=%v", events, truncated)
+	}
+
+	// After a restart, re-observing the same state adds nothing.
+	store2, err := Open(logger, path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec2 := NewRecorder(logger, store2)
+	rec2.ObservePlacement(ep)
+	rec2.ObserveSlice(sps)
+	if actual := eventTypes(store2.Events()); !equalTypes(actual, expected) {
+		t.Errorf("after restart expected %v, got %v", expected, actual)
+	}
+
+	// Retention drops the old events, from memory and file.
+	if err := store2.Prune(base.Add(24*time.Hour + 30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	store3, err := Open(logger, path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := eventTypes(store3.Events()); !equalTypes(actual, expected[2:]) {
+		t.Errorf("after pruning expected %v, got %v", expected[2:], actual)
+	}
+}
+
+func TestLegacyDestinations(t *testing.T) {
+	logger := klog.Background()
+	base := time.Date(2023, 9, 1, 14, 0, 0, 0, time.UTC)
+	store, err := Open(logger, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// As written by a release that identified destinations by SyncTarget name only.
+	for _, event := range []Event{
+		{Time: metav1.NewTime(base), Placement: "ep1", UID: "uid1", Type: EventCreated, Generation: 1},
+		{Time: metav1.NewTime(base.Add(time.Minute)), Placement: "ep1", UID: "uid1", Type: EventDestinationsChanged, Added: []string{"st1", "st2"}},
+	} {
+		if err := store.Append(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := NewRecorder(logger, store)
+	rec.now = func() time.Time { return base.Add(time.Hour) }
+	rec.ObservePlacement(&edgev2alpha1.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: "ep1", UID: "uid1", Generation: 1, CreationTimestamp: metav1.NewTime(base)}})
+	rec.ObserveSlice(&edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: "ep1"},
+		Destinations: []edgev2alpha1.SinglePlacement{{Cluster: "c1", SyncTargetName: "st1"}, {Cluster: "c1", SyncTargetName: "st3"}}})
+	events := store.Events()
+	if len(events) != 3 {
+		t.Fatalf("expected one more event, got %v", events)
+	}
+	last := events[2]
+	if len(last.Added) != 1 || last.Added[0] != "c1/st3" || len(last.Removed) != 1 || last.Removed[0] != "st2" {
+		t.Errorf("unexpected destination change %+v", last)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	store, err := Open(klog.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2023, 9, 1, 14, 0, 0, 0, time.UTC)
+	for idx := 0; idx < 5; idx++ {
+		if err := store.Append(Event{Time: metav1.NewTime(base.Add(time.Duration(idx) * 20 * time.Minute)), Placement: "ep1", Type: EventSpecChanged}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := &Handler{Store: store, now: func() time.Time { return base.Add(2 * time.Hour) }}
+	for _, tc := range []struct {
+		query     string
+		status    int
+		count     int
+		truncated bool
+	}{
+		{"", http.StatusOK, 2, false},
+		{"?from=2023-09-01T14:00:00Z&to=2023-09-01T15:00:00Z", http.StatusOK, 3, false},
+		{"?from=2023-09-01T14:00:00Z&to=2023-09-01T15:00:00Z&limit=2", http.StatusOK, 2, true},
+		{"?placement=ep2", http.StatusOK, 0, false},
+		{"?from=yesterday", http.StatusBadRequest, 0, false},
+		{"?from=2023-09-01T15:00:00Z&to=2023-09-01T14:00:00Z", http.StatusBadRequest, 0, false},
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/timeline"+tc.query, nil))
+		if recorder.Code != tc.status {
+			t.Errorf("query %q: expected status %d, got %d", tc.query, tc.status, recorder.Code)
+			continue
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var list EventList
+		if err := json.Unmarshal(recorder.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != tc.count || list.Truncated != tc.truncated {
+			t.Errorf("query %q: expected %d events (truncated=%v), got %d (truncated=%v)", tc.query, tc.count, tc.truncated, len(list.Items), list.Truncated)
+		}
+	}
+}
+
+func equalTypes(a, b []EventType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
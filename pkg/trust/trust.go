@@ -0,0 +1,432 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package trust generates the ConfigMaps and Secrets requested by the
+// `trust` of an EdgePlacement, aggregating CA certificates and image pull
+// credentials from objects in the workload management space, and
+// maintains them there, from where they are downsynced.
+package trust
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	k8sapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+)
+
+// DefaultKey is the key of the certificates in a source that does not say.
+const DefaultKey = "ca.crt"
+
+// ObjectName returns the name of the ConfigMap and of the Secret generated
+// for the named EdgePlacement.
+func ObjectName(epName string) string {
+	return naming.TrustObjectName(epName)
+}
+
+// Namespaces returns the namespaces that get generated trust objects
+// for the given spec, in sorted order; nil if the spec asks for no trust material.
+func Namespaces(spec *edgeapi.EdgePlacementSpec) []string {
+	if spec.Trust == nil {
+		return nil
+	}
+	namespaces := map[string]struct{}{}
+	if len(spec.Trust.Namespaces) > 0 {
+		for _, ns := range spec.Trust.Namespaces {
+			namespaces[ns] = struct{}{}
+		}
+	} else {
+		for _, objTest := range spec.Downsync {
+			for _, ns := range objTest.Namespaces {
+				if ns != "*" {
+					namespaces[ns] = struct{}{}
+				}
+			}
+		}
+	}
+	ans := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		ans = append(ans, ns)
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+// IsTrustFor tells whether the given object is a ConfigMap or Secret
+// generated for the named EdgePlacement.
+func IsTrustFor(group, resource string, objLabels map[string]string, epName string) bool {
+	return group == corev1.GroupName && (resource == "configmaps" || resource == "secrets") &&
+		objLabels[edgeapi.TrustForLabelKey] == naming.LabelValue(epName)
+}
+
+// IsSource tells whether the given object is one that the given trust
+// material is read from, so that a change to it calls for regeneration.
+func IsSource(trust *edgeapi.TrustDistribution, group, resource, namespace, name string) bool {
+	if trust == nil || group != corev1.GroupName {
+		return false
+	}
+	matches := func(ref *edgeapi.TrustObjectReference) bool {
+		return ref != nil && ref.Namespace == namespace && ref.Name == name
+	}
+	switch resource {
+	case "configmaps":
+		for _, source := range trust.CABundles {
+			if matches(source.ConfigMap) {
+				return true
+			}
+		}
+	case "secrets":
+		for _, source := range trust.CABundles {
+			if matches(source.Secret) {
+				return true
+			}
+		}
+		for idx := range trust.ImagePullSecrets {
+			if matches(&trust.ImagePullSecrets[idx]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AggregateCertificates returns the distinct certificates found in the
+// given PEM data, in order of first appearance, PEM-encoded.
+// Blocks that are not certificates are dropped.
+func AggregateCertificates(pems [][]byte) []byte {
+	var out bytes.Buffer
+	seen := map[string]struct{}{}
+	for _, rest := range pems {
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			if _, has := seen[string(block.Bytes)]; has {
+				continue
+			}
+			seen[string(block.Bytes)] = struct{}{}
+			pem.Encode(&out, &pem.Block{Type: block.Type, Bytes: block.Bytes})
+		}
+	}
+	return out.Bytes()
+}
+
+// dockerConfigJSON is the content of a Secret of type kubernetes.io/dockerconfigjson.
+// The entries are kept as they are.
+type dockerConfigJSON struct {
+	Auths map[string]json.RawMessage `json:"auths"`
+}
+
+// MergeDockerConfigs returns the content of a Secret of type
+// kubernetes.io/dockerconfigjson that holds the credentials of all the
+// given image pull Secrets; for a registry that appears in several,
+// the first one wins.
+func MergeDockerConfigs(secrets []*corev1.Secret) ([]byte, error) {
+	merged := dockerConfigJSON{Auths: map[string]json.RawMessage{}}
+	for _, secret := range secrets {
+		var auths map[string]json.RawMessage
+		switch secret.Type {
+		case corev1.SecretTypeDockerConfigJson:
+			var config dockerConfigJSON
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+				return nil, fmt.Errorf("failed to parse Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+			}
+			auths = config.Auths
+		case corev1.SecretTypeDockercfg:
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+				return nil, fmt.Errorf("failed to parse Secret %s/%s: %w", secret.Namespace, secret.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("Secret %s/%s has type %q, which does not hold image pull credentials", secret.Namespace, secret.Name, secret.Type)
+		}
+		for registry, auth := range auths {
+			if _, has := merged.Auths[registry]; !has {
+				merged.Auths[registry] = auth
+			}
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// material is the aggregated trust material of an EdgePlacement.
+type material struct {
+	caBundle  []byte
+	pullCreds []byte
+}
+
+// gather reads the sources of the given trust material.
+// A source that does not exist, or that holds nothing usable, is skipped.
+// Returns whether to retry.
+func gather(ctx context.Context, logger klog.Logger, client kubernetes.Interface, trust *edgeapi.TrustDistribution) (material, bool) {
+	var ans material
+	if len(trust.CABundles) > 0 {
+		pems := [][]byte{}
+		for _, source := range trust.CABundles {
+			var ref *edgeapi.TrustObjectReference
+			var data []byte
+			var err error
+			switch {
+			case source.ConfigMap != nil:
+				ref = source.ConfigMap
+				var cm *corev1.ConfigMap
+				cm, err = client.CoreV1().ConfigMaps(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+				if err == nil {
+					data = []byte(cm.Data[key(ref)])
+					if len(data) == 0 {
+						data = cm.BinaryData[key(ref)]
+					}
+				}
+			case source.Secret != nil:
+				ref = source.Secret
+				var secret *corev1.Secret
+				secret, err = client.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+				if err == nil {
+					data = secret.Data[key(ref)]
+				}
+			default:
+				logger.V(2).Info("Ignoring CA bundle source that names neither a ConfigMap nor a Secret")
+				continue
+			}
+			if k8sapierrors.IsNotFound(err) {
+				logger.V(2).Info("CA bundle source does not exist", "namespace", ref.Namespace, "name", ref.Name)
+				continue
+			} else if err != nil {
+				logger.Error(err, "Failed to read CA bundle source", "namespace", ref.Namespace, "name", ref.Name)
+				return ans, true
+			}
+			if len(data) == 0 {
+				logger.V(2).Info("CA bundle source lacks the key", "namespace", ref.Namespace, "name", ref.Name, "key", key(ref))
+			}
+			pems = append(pems, data)
+		}
+		ans.caBundle = AggregateCertificates(pems)
+	}
+	if len(trust.ImagePullSecrets) > 0 {
+		secrets := []*corev1.Secret{}
+		for _, ref := range trust.ImagePullSecrets {
+			secret, err := client.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+			if k8sapierrors.IsNotFound(err) {
+				logger.V(2).Info("Image pull Secret does not exist", "namespace", ref.Namespace, "name", ref.Name)
+				continue
+			} else if err != nil {
+				logger.Error(err, "Failed to read image pull Secret", "namespace", ref.Namespace, "name", ref.Name)
+				return ans, true
+			}
+			if _, err := MergeDockerConfigs([]*corev1.Secret{secret}); err != nil {
+				logger.Error(err, "Ignoring unusable image pull Secret")
+				continue
+			}
+			secrets = append(secrets, secret)
+		}
+		pullCreds, err := MergeDockerConfigs(secrets)
+		if err != nil { // impossible, each has been checked
+			logger.Error(err, "Failed to merge image pull Secrets")
+			return ans, false
+		}
+		ans.pullCreds = pullCreds
+	}
+	return ans, false
+}
+
+func key(ref *edgeapi.TrustObjectReference) string {
+	if ref.Key == "" {
+		return DefaultKey
+	}
+	return ref.Key
+}
+
+// GenerateConfigMap returns the ConfigMap holding the given aggregated
+// CA certificates in the given namespace.
+func GenerateConfigMap(epName, namespace string, caBundle []byte) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: objectMeta(epName, namespace),
+		Data:       map[string]string{edgeapi.TrustBundleKey: string(caBundle)},
+	}
+}
+
+// GenerateSecret returns the Secret holding the given merged image pull
+// credentials in the given namespace.
+func GenerateSecret(epName, namespace string, pullCreds []byte) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: objectMeta(epName, namespace),
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: pullCreds},
+	}
+}
+
+func objectMeta(epName, namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: namespace,
+		Name:      ObjectName(epName),
+		Labels:    map[string]string{edgeapi.TrustForLabelKey: naming.LabelValue(epName)},
+	}
+}
+
+// Reconcile makes the ConfigMaps and Secrets generated for the named
+// EdgePlacement match the given spec, which is nil if the EdgePlacement
+// does not exist, and the current content of their sources.
+// Returns whether to retry.
+func Reconcile(ctx context.Context, logger klog.Logger, client kubernetes.Interface, epName string, spec *edgeapi.EdgePlacementSpec) bool {
+	logger = logger.WithValues("edgePlacement", epName)
+	desiredCMs := map[string]*corev1.ConfigMap{}
+	desiredSecrets := map[string]*corev1.Secret{}
+	if spec != nil && spec.Trust != nil {
+		mat, retry := gather(ctx, logger, client, spec.Trust)
+		if retry {
+			return true
+		}
+		for _, ns := range Namespaces(spec) {
+			if len(spec.Trust.CABundles) > 0 {
+				desiredCMs[ns] = GenerateConfigMap(epName, ns, mat.caBundle)
+			}
+			if len(spec.Trust.ImagePullSecrets) > 0 {
+				desiredSecrets[ns] = GenerateSecret(epName, ns, mat.pullCreds)
+			}
+		}
+	}
+	selector := labels.SelectorFromSet(labels.Set{edgeapi.TrustForLabelKey: naming.LabelValue(epName)}).String()
+	retryCMs := reconcileConfigMaps(ctx, logger, client, selector, desiredCMs)
+	retrySecrets := reconcileSecrets(ctx, logger, client, selector, desiredSecrets)
+	return retryCMs || retrySecrets
+}
+
+func reconcileConfigMaps(ctx context.Context, logger klog.Logger, client kubernetes.Interface, selector string, desired map[string]*corev1.ConfigMap) bool {
+	existing, err := client.CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		logger.Error(err, "Failed to list generated trust ConfigMaps")
+		return true
+	}
+	retry := false
+	for idx := range existing.Items {
+		have := &existing.Items[idx]
+		want, wanted := desired[have.Namespace]
+		switch {
+		case !wanted || have.Name != want.Name:
+			err := client.CoreV1().ConfigMaps(have.Namespace).Delete(ctx, have.Name, metav1.DeleteOptions{})
+			if err != nil && !k8sapierrors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete stale trust ConfigMap", "namespace", have.Namespace, "name", have.Name)
+				retry = true
+			} else {
+				logger.V(2).Info("Deleted stale trust ConfigMap", "namespace", have.Namespace, "name", have.Name)
+			}
+		case !apiequality.Semantic.DeepEqual(have.Data, want.Data) || len(have.BinaryData) > 0:
+			have = have.DeepCopy()
+			have.Data, have.BinaryData = want.Data, nil
+			_, err := client.CoreV1().ConfigMaps(have.Namespace).Update(ctx, have, metav1.UpdateOptions{FieldManager: "kubestellar"})
+			if err != nil {
+				logger.Error(err, "Failed to update trust ConfigMap", "namespace", have.Namespace, "name", have.Name)
+				retry = true
+			} else {
+				logger.V(2).Info("Updated trust ConfigMap", "namespace", have.Namespace, "name", have.Name)
+			}
+			delete(desired, have.Namespace)
+		default:
+			delete(desired, have.Namespace)
+		}
+	}
+	for ns, want := range desired {
+		_, err := client.CoreV1().ConfigMaps(ns).Create(ctx, want, metav1.CreateOptions{FieldManager: "kubestellar"})
+		if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+			logger.Error(err, "Failed to create trust ConfigMap", "namespace", ns, "name", want.Name)
+			retry = true
+		} else {
+			logger.V(2).Info("Created trust ConfigMap", "namespace", ns, "name", want.Name)
+		}
+	}
+	return retry
+}
+
+func reconcileSecrets(ctx context.Context, logger klog.Logger, client kubernetes.Interface, selector string, desired map[string]*corev1.Secret) bool {
+	existing, err := client.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		logger.Error(err, "Failed to list generated trust Secrets")
+		return true
+	}
+	retry := false
+	for idx := range existing.Items {
+		have := &existing.Items[idx]
+		want, wanted := desired[have.Namespace]
+		switch {
+		case !wanted || have.Name != want.Name:
+			err := client.CoreV1().Secrets(have.Namespace).Delete(ctx, have.Name, metav1.DeleteOptions{})
+			if err != nil && !k8sapierrors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete stale trust Secret", "namespace", have.Namespace, "name", have.Name)
+				retry = true
+			} else {
+				logger.V(2).Info("Deleted stale trust Secret", "namespace", have.Namespace, "name", have.Name)
+			}
+		case have.Type != want.Type:
+			// The type of a Secret can not be changed, so the Secret is
+			// deleted here and created again on retry.
+			err := client.CoreV1().Secrets(have.Namespace).Delete(ctx, have.Name, metav1.DeleteOptions{})
+			if err != nil && !k8sapierrors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete trust Secret of wrong type", "namespace", have.Namespace, "name", have.Name)
+			} else {
+				logger.V(2).Info("Deleted trust Secret of wrong type", "namespace", have.Namespace, "name", have.Name)
+			}
+			retry = true
+			delete(desired, have.Namespace)
+		case !apiequality.Semantic.DeepEqual(have.Data, want.Data):
+			have = have.DeepCopy()
+			have.Data = want.Data
+			_, err := client.CoreV1().Secrets(have.Namespace).Update(ctx, have, metav1.UpdateOptions{FieldManager: "kubestellar"})
+			if err != nil {
+				logger.Error(err, "Failed to update trust Secret", "namespace", have.Namespace, "name", have.Name)
+				retry = true
+			} else {
+				logger.V(2).Info("Updated trust Secret", "namespace", have.Namespace, "name", have.Name)
+			}
+			delete(desired, have.Namespace)
+		default:
+			delete(desired, have.Namespace)
+		}
+	}
+	for ns, want := range desired {
+		_, err := client.CoreV1().Secrets(ns).Create(ctx, want, metav1.CreateOptions{FieldManager: "kubestellar"})
+		if err != nil && !k8sapierrors.IsAlreadyExists(err) {
+			logger.Error(err, "Failed to create trust Secret", "namespace", ns, "name", want.Name)
+			retry = true
+		} else {
+			logger.V(2).Info("Created trust Secret", "namespace", ns, "name", want.Name)
+		}
+	}
+	return retry
+}
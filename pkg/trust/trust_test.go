@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trust
+
+import (
+	"context"
+	"encoding/pem"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+// The certificates need not be valid; only their PEM framing matters.
+func cert(content string) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte(content)}))
+}
+
+func TestAggregateCertificates(t *testing.T) {
+	key := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("k")}))
+	got := string(AggregateCertificates([][]byte{[]byte(cert("a") + key + cert("b")), []byte(cert("b") + cert("c")), nil}))
+	if expected := cert("a") + cert("b") + cert("c"); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestMergeDockerConfigs(t *testing.T) {
+	first := &corev1.Secret{Type: corev1.SecretTypeDockerConfigJson, Data: map[string][]byte{
+		corev1.DockerConfigJsonKey: []byte(`{"auths":{"r1":{"auth":"one"},"r2":{"auth":"two"}}}`)}}
+	second := &corev1.Secret{Type: corev1.SecretTypeDockercfg, Data: map[string][]byte{
+		corev1.DockerConfigKey: []byte(`{"r2":{"auth":"other"},"r3":{"auth":"three"}}`)}}
+	got, err := MergeDockerConfigs([]*corev1.Secret{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"auths":{"r1":{"auth":"one"},"r2":{"auth":"two"},"r3":{"auth":"three"}}}`; string(got) != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if _, err := MergeDockerConfigs([]*corev1.Secret{{Type: corev1.SecretTypeOpaque}}); err == nil {
+		t.Error("Expected an Opaque Secret to be rejected")
+	}
+}
+
+func TestIsSource(t *testing.T) {
+	trust := &edgeapi.TrustDistribution{
+		CABundles:        []edgeapi.TrustSource{{Secret: &edgeapi.TrustObjectReference{Namespace: "certs", Name: "ca"}}},
+		ImagePullSecrets: []edgeapi.TrustObjectReference{{Namespace: "certs", Name: "pull"}},
+	}
+	for _, tc := range []struct {
+		resource, name string
+		expected       bool
+	}{{"secrets", "ca", true}, {"secrets", "pull", true}, {"configmaps", "ca", false}, {"secrets", "other", false}} {
+		if got := IsSource(trust, "", tc.resource, "certs", tc.name); got != tc.expected {
+			t.Errorf("IsSource(%s %s) = %v, expected %v", tc.resource, tc.name, got, tc.expected)
+		}
+	}
+	if IsSource(nil, "", "secrets", "certs", "ca") {
+		t.Error("Expected nothing to be a source of no trust material")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	logger := klog.Background()
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "certs", Name: "root"}, Data: map[string]string{"ca.crt": cert("root")}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "certs", Name: "issuer"}, Data: map[string][]byte{"tls-ca": []byte(cert("issuer") + cert("root"))}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "certs", Name: "pull"}, Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"r1":{"auth":"one"}}}`)}},
+	)
+	spec := &edgeapi.EdgePlacementSpec{
+		Downsync: []edgeapi.DownsyncObjectTest{{Namespaces: []string{"a", "b"}}},
+		Trust: &edgeapi.TrustDistribution{
+			CABundles: []edgeapi.TrustSource{
+				{ConfigMap: &edgeapi.TrustObjectReference{Namespace: "certs", Name: "root"}},
+				{Secret: &edgeapi.TrustObjectReference{Namespace: "certs", Name: "issuer", Key: "tls-ca"}},
+				{ConfigMap: &edgeapi.TrustObjectReference{Namespace: "certs", Name: "missing"}},
+			},
+			ImagePullSecrets: []edgeapi.TrustObjectReference{{Namespace: "certs", Name: "pull"}},
+		},
+	}
+	generated := func() ([]corev1.ConfigMap, []corev1.Secret) {
+		opts := metav1.ListOptions{LabelSelector: edgeapi.TrustForLabelKey + "=ep1"}
+		cms, err := client.CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			t.Fatalf("Failed to list ConfigMaps: %v", err)
+		}
+		secrets, err := client.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			t.Fatalf("Failed to list Secrets: %v", err)
+		}
+		return cms.Items, secrets.Items
+	}
+	if Reconcile(ctx, logger, client, "ep1", spec) {
+		t.Fatalf("Unexpected retry")
+	}
+	cms, secrets := generated()
+	if len(cms) != 2 || len(secrets) != 2 {
+		t.Fatalf("Expected 2 ConfigMaps and 2 Secrets, got %v and %v", cms, secrets)
+	}
+	for _, cm := range cms {
+		if cm.Name != ObjectName("ep1") || cm.Data[edgeapi.TrustBundleKey] != cert("root")+cert("issuer") {
+			t.Errorf("Unexpected ConfigMap %#v", cm)
+		}
+	}
+	for _, secret := range secrets {
+		if secret.Type != corev1.SecretTypeDockerConfigJson || string(secret.Data[corev1.DockerConfigJsonKey]) != `{"auths":{"r1":{"auth":"one"}}}` {
+			t.Errorf("Unexpected Secret %#v", secret)
+		}
+	}
+
+	// Rotation of a source is followed
+	_, err := client.CoreV1().ConfigMaps("certs").Update(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "certs", Name: "root"},
+		Data: map[string]string{"ca.crt": cert("root2")}}, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.Trust.Namespaces = []string{"b"}
+	spec.Trust.ImagePullSecrets = nil
+	if Reconcile(ctx, logger, client, "ep1", spec) {
+		t.Fatalf("Unexpected retry")
+	}
+	cms, secrets = generated()
+	if len(cms) != 1 || cms[0].Namespace != "b" || cms[0].Data[edgeapi.TrustBundleKey] != cert("root2")+cert("issuer")+cert("root") {
+		t.Errorf("Expected only a rotated ConfigMap in namespace b, got %v", cms)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("Expected no Secrets without image pull Secrets, got %v", secrets)
+	}
+
+	if Reconcile(ctx, logger, client, "ep1", nil) {
+		t.Fatalf("Unexpected retry")
+	}
+	if cms, secrets = generated(); len(cms) != 0 || len(secrets) != 0 {
+		t.Errorf("Expected no generated objects after the EdgePlacement is gone, got %v and %v", cms, secrets)
+	}
+}
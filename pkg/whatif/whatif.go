@@ -0,0 +1,221 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package whatif answers questions of the form "if these Locations and
+// SyncTargets went down, which EdgePlacements would fall below the number
+// of destinations that they need, and where would their workloads be?".
+// It does so by running the where-resolver on a snapshot of the
+// inventory, as it is and with the failed Locations and SyncTargets
+// removed, and comparing the results. Nothing is changed in any space.
+package whatif
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	whereresolver "github.com/kubestellar/kubestellar/pkg/where-resolver"
+)
+
+// MinDestinationsAnnotationKey is the annotation of an EdgePlacement that
+// says how many destinations it needs. Without it, an EdgePlacement is
+// taken to need as many destinations as it has before the failure.
+const MinDestinationsAnnotationKey = "edge.kubestellar.io/min-destinations"
+
+// Inventory is a snapshot of the objects of an inventory space.
+type Inventory struct {
+	Locations   []edgeapi.Location
+	SyncTargets []edgeapi.SyncTarget
+}
+
+// Failure says which Locations and SyncTargets go down.
+type Failure struct {
+	// Selector selects, by their labels, the failing Locations and
+	// SyncTargets. Nil selects nothing.
+	Selector labels.Selector
+
+	// LocationNames and SyncTargetNames name more failing objects.
+	LocationNames   []string
+	SyncTargetNames []string
+}
+
+// Destination is a SyncTarget, through the Locations that select it.
+type Destination struct {
+	SyncTarget string   `json:"syncTarget"`
+	Locations  []string `json:"locations"`
+}
+
+// Impact is what a Failure does to one EdgePlacement.
+type Impact struct {
+	Placement string `json:"placement"`
+
+	// Required is the number of destinations that the EdgePlacement needs.
+	Required int `json:"required"`
+
+	// Before and After are the destinations without and with the Failure.
+	// After is where the workload would be.
+	Before []Destination `json:"before"`
+	After  []Destination `json:"after"`
+
+	// Lost are the destinations in Before but not After, and Gained the
+	// ones in After but not Before.
+	Lost   []Destination `json:"lost,omitempty"`
+	Gained []Destination `json:"gained,omitempty"`
+
+	// BelowRequired tells whether After has fewer than Required destinations.
+	BelowRequired bool `json:"belowRequired"`
+
+	// Explanations say why SyncTargets of the selected Locations are not
+	// in After because of the EdgePlacement's requirements.
+	Explanations []string `json:"explanations,omitempty"`
+}
+
+// Report is the outcome of a simulation.
+type Report struct {
+	FailedLocations   []string `json:"failedLocations"`
+	FailedSyncTargets []string `json:"failedSyncTargets"`
+
+	// Impacts lists the EdgePlacements whose destinations change, in the
+	// order of their names.
+	Impacts []Impact `json:"impacts"`
+
+	// Unaffected counts the EdgePlacements whose destinations do not change.
+	Unaffected int `json:"unaffected"`
+}
+
+// BelowRequired returns the number of EdgePlacements that would have
+// fewer destinations than they need.
+func (report *Report) BelowRequired() int {
+	count := 0
+	for _, impact := range report.Impacts {
+		if impact.BelowRequired {
+			count++
+		}
+	}
+	return count
+}
+
+// Simulate computes what the given Failure of the given Inventory does to
+// the given EdgePlacements.
+func Simulate(inventory Inventory, placements []edgeapi.EdgePlacement, failure Failure) (*Report, error) {
+	selector := failure.Selector
+	if selector == nil {
+		selector = labels.Nothing()
+	}
+	locationNames := sets.NewString(failure.LocationNames...)
+	syncTargetNames := sets.NewString(failure.SyncTargetNames...)
+	report := &Report{FailedLocations: []string{}, FailedSyncTargets: []string{}, Impacts: []Impact{}}
+
+	var locsBefore, locsAfter []*edgeapi.Location
+	for idx := range inventory.Locations {
+		loc := &inventory.Locations[idx]
+		locsBefore = append(locsBefore, loc)
+		if locationNames.Has(loc.Name) || selector.Matches(labels.Set(loc.Labels)) {
+			report.FailedLocations = append(report.FailedLocations, loc.Name)
+		} else {
+			locsAfter = append(locsAfter, loc)
+		}
+	}
+	var stsBefore, stsAfter []*edgeapi.SyncTarget
+	for idx := range inventory.SyncTargets {
+		st := &inventory.SyncTargets[idx]
+		stsBefore = append(stsBefore, st)
+		if syncTargetNames.Has(st.Name) || selector.Matches(labels.Set(st.Labels)) {
+			report.FailedSyncTargets = append(report.FailedSyncTargets, st.Name)
+		} else {
+			stsAfter = append(stsAfter, st)
+		}
+	}
+	sort.Strings(report.FailedLocations)
+	sort.Strings(report.FailedSyncTargets)
+
+	placements = append([]edgeapi.EdgePlacement{}, placements...)
+	sort.Slice(placements, func(i, j int) bool { return placements[i].Name < placements[j].Name })
+	for idx := range placements {
+		ep := &placements[idx]
+		before, _, err := whereresolver.Resolve(ep, locsBefore, stsBefore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve EdgePlacement %q: %w", ep.Name, err)
+		}
+		after, explanations, err := whereresolver.Resolve(ep, locsAfter, stsAfter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve EdgePlacement %q: %w", ep.Name, err)
+		}
+		impact := Impact{
+			Placement: ep.Name,
+			Before:    destinations(before),
+			After:     destinations(after),
+		}
+		if len(explanations) > 0 {
+			impact.Explanations = explanations
+		}
+		impact.Lost = difference(impact.Before, impact.After)
+		impact.Gained = difference(impact.After, impact.Before)
+		impact.Required = len(impact.Before)
+		if value, ok := ep.Annotations[MinDestinationsAnnotationKey]; ok {
+			required, err := strconv.Atoi(value)
+			if err != nil || required < 0 {
+				return nil, fmt.Errorf("EdgePlacement %q has an invalid %s annotation %q", ep.Name, MinDestinationsAnnotationKey, value)
+			}
+			impact.Required = required
+		}
+		impact.BelowRequired = len(impact.After) < impact.Required
+		if len(impact.Lost) == 0 && len(impact.Gained) == 0 && !impact.BelowRequired {
+			report.Unaffected++
+			continue
+		}
+		report.Impacts = append(report.Impacts, impact)
+	}
+	return report, nil
+}
+
+// destinations groups the given SinglePlacements by SyncTarget.
+func destinations(singles []edgeapi.SinglePlacement) []Destination {
+	indexByName := map[string]int{}
+	ans := []Destination{}
+	for _, single := range singles {
+		if idx, ok := indexByName[single.SyncTargetName]; ok {
+			ans[idx].Locations = append(ans[idx].Locations, single.LocationName)
+			continue
+		}
+		indexByName[single.SyncTargetName] = len(ans)
+		ans = append(ans, Destination{SyncTarget: single.SyncTargetName, Locations: []string{single.LocationName}})
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].SyncTarget < ans[j].SyncTarget })
+	for idx := range ans {
+		sort.Strings(ans[idx].Locations)
+	}
+	return ans
+}
+
+// difference returns the destinations in a whose SyncTarget is not in b.
+func difference(a, b []Destination) []Destination {
+	inB := sets.NewString()
+	for _, dest := range b {
+		inB.Insert(dest.SyncTarget)
+	}
+	var ans []Destination
+	for _, dest := range a {
+		if !inB.Has(dest.SyncTarget) {
+			ans = append(ans, dest)
+		}
+	}
+	return ans
+}
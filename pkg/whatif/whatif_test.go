@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package whatif
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	edgeapi "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+func location(name string, lbls, instanceLabels map[string]string) edgeapi.Location {
+	return edgeapi.Location{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: lbls},
+		Spec:       edgeapi.LocationSpec{InstanceSelector: &metav1.LabelSelector{MatchLabels: instanceLabels}},
+	}
+}
+
+func syncTarget(name string, lbls map[string]string) edgeapi.SyncTarget {
+	return edgeapi.SyncTarget{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: lbls}}
+}
+
+func placement(name string, annotations map[string]string, locationLabels map[string]string) edgeapi.EdgePlacement {
+	return edgeapi.EdgePlacement{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Spec:       edgeapi.EdgePlacementSpec{LocationSelectors: []metav1.LabelSelector{{MatchLabels: locationLabels}}},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	inventory := Inventory{
+		Locations: []edgeapi.Location{
+			location("eu", map[string]string{"region": "eu-west", "env": "prod"}, map[string]string{"region": "eu-west"}),
+			location("us", map[string]string{"region": "us-east", "env": "prod"}, map[string]string{"region": "us-east"}),
+			location("all", map[string]string{"env": "test"}, map[string]string{}),
+		},
+		SyncTargets: []edgeapi.SyncTarget{
+			syncTarget("eu1", map[string]string{"region": "eu-west"}),
+			syncTarget("eu2", map[string]string{"region": "eu-west"}),
+			syncTarget("us1", map[string]string{"region": "us-east"}),
+		},
+	}
+	placements := []edgeapi.EdgePlacement{
+		placement("web", map[string]string{MinDestinationsAnnotationKey: "2"}, map[string]string{"env": "prod"}),
+		placement("eu-only", nil, map[string]string{"region": "eu-west"}),
+		placement("us-only", nil, map[string]string{"region": "us-east"}),
+		placement("test", nil, map[string]string{"env": "test"}),
+	}
+	report, err := Simulate(inventory, placements, Failure{Selector: labels.SelectorFromSet(labels.Set{"region": "eu-west"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Report{
+		FailedLocations:   []string{"eu"},
+		FailedSyncTargets: []string{"eu1", "eu2"},
+		Impacts: []Impact{
+			{
+				Placement:     "eu-only",
+				Required:      2,
+				Before:        []Destination{{SyncTarget: "eu1", Locations: []string{"eu"}}, {SyncTarget: "eu2", Locations: []string{"eu"}}},
+				After:         []Destination{},
+				Lost:          []Destination{{SyncTarget: "eu1", Locations: []string{"eu"}}, {SyncTarget: "eu2", Locations: []string{"eu"}}},
+				BelowRequired: true,
+			},
+			{
+				Placement:     "test",
+				Required:      3,
+				Before:        []Destination{{SyncTarget: "eu1", Locations: []string{"all"}}, {SyncTarget: "eu2", Locations: []string{"all"}}, {SyncTarget: "us1", Locations: []string{"all"}}},
+				After:         []Destination{{SyncTarget: "us1", Locations: []string{"all"}}},
+				Lost:          []Destination{{SyncTarget: "eu1", Locations: []string{"all"}}, {SyncTarget: "eu2", Locations: []string{"all"}}},
+				BelowRequired: true,
+			},
+			{
+				Placement:     "web",
+				Required:      2,
+				Before:        []Destination{{SyncTarget: "eu1", Locations: []string{"eu"}}, {SyncTarget: "eu2", Locations: []string{"eu"}}, {SyncTarget: "us1", Locations: []string{"us"}}},
+				After:         []Destination{{SyncTarget: "us1", Locations: []string{"us"}}},
+				Lost:          []Destination{{SyncTarget: "eu1", Locations: []string{"eu"}}, {SyncTarget: "eu2", Locations: []string{"eu"}}},
+				BelowRequired: true,
+			},
+		},
+		Unaffected: 1,
+	}
+	if diff := cmp.Diff(expected, report); diff != "" {
+		t.Errorf("Wrong report (-want +got):\n%s", diff)
+	}
+	if got := report.BelowRequired(); got != 3 {
+		t.Errorf("Expected 3 placements below their requirement, got %d", got)
+	}
+
+	// Losing one of two destinations is tolerable when only one is needed
+	placements[0].Annotations[MinDestinationsAnnotationKey] = "1"
+	report, err = Simulate(inventory, placements[:1], Failure{SyncTargetNames: []string{"eu1", "eu2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.FailedLocations) != 0 || len(report.Impacts) != 1 || report.Impacts[0].BelowRequired {
+		t.Errorf("Expected web to lose destinations but stay at or above its requirement, got %+v", report)
+	}
+
+	placements[0].Annotations[MinDestinationsAnnotationKey] = "many"
+	if _, err := Simulate(inventory, placements[:1], Failure{}); err == nil {
+		t.Error("Expected an invalid annotation to be rejected")
+	}
+}
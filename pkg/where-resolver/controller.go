@@ -18,7 +18,9 @@ package where_resolver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
@@ -29,10 +31,17 @@ import (
 	"k8s.io/klog/v2"
 
 	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
 	edgev2alpha1informers "github.com/kubestellar/kubestellar/pkg/client/informers/externalversions/edge/v2alpha1"
 	edgev2alpha1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+	"github.com/kubestellar/kubestellar/pkg/events"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
-	msclient "github.com/kubestellar/kubestellar/space-framework/pkg/msclientlib"
+	"github.com/kubestellar/kubestellar/pkg/labelttl"
+	"github.com/kubestellar/kubestellar/pkg/probes"
+	"github.com/kubestellar/kubestellar/pkg/recovery"
+	"github.com/kubestellar/kubestellar/pkg/spaceclient"
+	"github.com/kubestellar/kubestellar/pkg/spechash"
 )
 
 const (
@@ -45,6 +54,9 @@ const (
 	triggeringKindEdgePlacement triggeringKind = "EdgePlacement"
 	triggeringKindLocation      triggeringKind = "Location"
 	triggeringKindSyncTarget    triggeringKind = "SyncTarget"
+
+	// triggeringKindLabelExpiry is for removing the expired labels of a Location
+	triggeringKindLabelExpiry triggeringKind = "LabelExpiry"
 )
 
 type queueItem struct {
@@ -54,9 +66,9 @@ type queueItem struct {
 
 type controller struct {
 	context         context.Context
-	queue           workqueue.RateLimitingInterface
+	queue           *coalesce.Queue
 	kbSpaceRelation kbuser.KubeBindSpaceRelation
-	spaceClient     msclient.KubestellarSpaceInterface
+	spaceClients    *spaceclient.Factory
 	spaceProviderNs string
 
 	singlePlacementSliceLister  edgev2alpha1listers.SinglePlacementSliceLister
@@ -70,11 +82,20 @@ type controller struct {
 
 	synctargetLister  edgev2alpha1listers.SyncTargetLister
 	synctargetIndexer cache.Indexer
+
+	// orphanGCDryRun can change while the orphan GC runs; see SetOrphanGCDryRun.
+	orphanGCDryRun atomic.Bool
+
+	// watchdog, if not nil, tracks each processing of a queue item
+	watchdog *probes.Watchdog
+
+	// events, if not nil, records Events about EdgePlacements
+	events *events.Recorder
 }
 
 func NewController(
 	context context.Context,
-	spaceClient msclient.KubestellarSpaceInterface,
+	spaceClients *spaceclient.Factory,
 	spaceProviderNs string,
 	edgePlacementAccess edgev2alpha1informers.EdgePlacementInformer,
 	singlePlacementSliceAccess edgev2alpha1informers.SinglePlacementSliceInformer,
@@ -83,13 +104,14 @@ func NewController(
 	kbSpaceRelation kbuser.KubeBindSpaceRelation,
 ) (*controller, error) {
 	context = klog.NewContext(context, klog.FromContext(context).WithValues("controller", ControllerName))
-	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), ControllerName)
+	queue := coalesce.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), ControllerName, coalesce.Options{})
+	logger := klog.FromContext(context)
 
 	c := &controller{
 		context:         context,
 		queue:           queue,
 		kbSpaceRelation: kbSpaceRelation,
-		spaceClient:     spaceClient,
+		spaceClients:    spaceClients,
 		spaceProviderNs: spaceProviderNs,
 
 		edgePlacementLister:  edgePlacementAccess.Lister(),
@@ -105,40 +127,53 @@ func NewController(
 		synctargetIndexer: syncTargetAccess.Informer().GetIndexer(),
 	}
 
-	edgePlacementAccess.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    c.enqueueEdgePlacement,
-		UpdateFunc: func(_, newObj interface{}) { c.enqueueEdgePlacement(newObj) },
-		DeleteFunc: c.enqueueEdgePlacement,
-	})
+	edgePlacementAccess.Informer().AddEventHandler(recovery.Handler(logger, ControllerName, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueueEdgePlacement(obj, coalesce.Added) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if spechash.Changed(oldObj, newObj) {
+				c.enqueueEdgePlacement(newObj, coalesce.Updated)
+			} else {
+				logger.V(4).Info("Ignoring update that changes neither spec nor reprocess annotation", "edgePlacement", newObj.(*edgev2alpha1.EdgePlacement).Name)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { c.enqueueEdgePlacement(obj, coalesce.Deleted) },
+	}))
 
-	locationAccess.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: c.enqueueLocation,
+	locationAccess.Informer().AddEventHandler(recovery.Handler(logger, ControllerName, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.enqueueLocation(obj, coalesce.Added)
+			c.scheduleLabelExpiry(obj)
+		},
 		UpdateFunc: func(old, obj interface{}) {
 			oldLoc := old.(*edgev2alpha1.Location)
 			newLoc := obj.(*edgev2alpha1.Location)
+			if oldLoc.Annotations[labelttl.AnnotationKey] != newLoc.Annotations[labelttl.AnnotationKey] || !apiequality.Semantic.DeepEqual(oldLoc.Labels, newLoc.Labels) {
+				c.scheduleLabelExpiry(obj)
+			}
 			if !apiequality.Semantic.DeepEqual(oldLoc.Spec, newLoc.Spec) || !apiequality.Semantic.DeepEqual(oldLoc.Labels, newLoc.Labels) {
-				c.enqueueLocation(obj)
+				c.enqueueLocation(obj, coalesce.Updated)
 			}
 		},
-		DeleteFunc: c.enqueueLocation,
-	})
+		DeleteFunc: func(obj interface{}) { c.enqueueLocation(obj, coalesce.Deleted) },
+	}))
 
-	syncTargetAccess.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: c.enqueueSyncTarget,
+	syncTargetAccess.Informer().AddEventHandler(recovery.Handler(logger, ControllerName, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueueSyncTarget(obj, coalesce.Added) },
 		UpdateFunc: func(old, obj interface{}) {
 			oldST := old.(*edgev2alpha1.SyncTarget)
 			newST := obj.(*edgev2alpha1.SyncTarget)
-			if !apiequality.Semantic.DeepEqual(oldST.Spec, newST.Spec) || !apiequality.Semantic.DeepEqual(oldST.Labels, newST.Labels) {
-				c.enqueueSyncTarget((obj))
+			if !apiequality.Semantic.DeepEqual(oldST.Spec, newST.Spec) || !apiequality.Semantic.DeepEqual(oldST.Labels, newST.Labels) ||
+				!capabilitiesEqual(&oldST.Status, &newST.Status) {
+				c.enqueueSyncTarget(obj, coalesce.Updated)
 			}
 		},
-		DeleteFunc: c.enqueueSyncTarget,
-	})
+		DeleteFunc: func(obj interface{}) { c.enqueueSyncTarget(obj, coalesce.Deleted) },
+	}))
 
 	return c, nil
 }
 
-func (c *controller) enqueueEdgePlacement(obj interface{}) {
+func (c *controller) enqueueEdgePlacement(obj interface{}, event coalesce.Event) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
 		runtime.HandleError(err)
@@ -146,15 +181,16 @@ func (c *controller) enqueueEdgePlacement(obj interface{}) {
 	}
 
 	klog.FromContext(c.context).V(2).Info("queueing EdgePlacement", "key", key)
-	c.queue.Add(
+	c.queue.AddEvent(
 		queueItem{
 			triggeringKind: triggeringKindEdgePlacement,
 			key:            key,
 		},
+		event,
 	)
 }
 
-func (c *controller) enqueueLocation(obj interface{}) {
+func (c *controller) enqueueLocation(obj interface{}, event coalesce.Event) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
 		runtime.HandleError(err)
@@ -162,15 +198,16 @@ func (c *controller) enqueueLocation(obj interface{}) {
 	}
 
 	klog.FromContext(c.context).V(2).Info("queueing Location", "key", key)
-	c.queue.Add(
+	c.queue.AddEvent(
 		queueItem{
 			triggeringKind: triggeringKindLocation,
 			key:            key,
 		},
+		event,
 	)
 }
 
-func (c *controller) enqueueSyncTarget(obj interface{}) {
+func (c *controller) enqueueSyncTarget(obj interface{}, event coalesce.Event) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
 		runtime.HandleError(err)
@@ -178,14 +215,37 @@ func (c *controller) enqueueSyncTarget(obj interface{}) {
 	}
 
 	klog.FromContext(c.context).V(2).Info("queueing SyncTarget", "key", key)
-	c.queue.Add(
+	c.queue.AddEvent(
 		queueItem{
 			triggeringKind: triggeringKindSyncTarget,
 			key:            key,
 		},
+		event,
 	)
 }
 
+// SetWatchdog makes the given Watchdog track each processing of a queue item.
+// Must be called before Run.
+func (c *controller) SetWatchdog(watchdog *probes.Watchdog) {
+	c.watchdog = watchdog
+}
+
+// SetEventRecorder makes the controller record Events about EdgePlacements.
+// Must be called before Run.
+// edgeClientFor returns the edge clientset for the given consumer space.
+// The clients are shared through the factory, not made per call.
+func (c *controller) edgeClientFor(spaceID string) (edgeclientset.Interface, error) {
+	clients, err := c.spaceClients.For(spaceID, c.spaceProviderNs)
+	if err != nil {
+		return nil, err
+	}
+	return clients.Edge, nil
+}
+
+func (c *controller) SetEventRecorder(rcdr *events.Recorder) {
+	c.events = rcdr
+}
+
 // Run starts the controller, which stops when c.context.Done() is closed.
 func (c *controller) Run(numThreads int) {
 	defer runtime.HandleCrash()
@@ -222,8 +282,13 @@ func (c *controller) processNextWorkItem(ctx context.Context) bool {
 	// No matter what, tell the queue we're done with this key, to unblock
 	// other workers.
 	defer c.queue.Done(i)
+	defer c.watchdog.Track()()
 
-	if err := c.process(ctx, item); err != nil {
+	var err error
+	if recovery.Guard(klog.FromContext(ctx), ControllerName, item, func() { err = c.process(ctx, item) }) {
+		err = errors.New("panicked")
+	}
+	if err != nil {
 		runtime.HandleError(fmt.Errorf("%q controller didn't sync %q, err: %w", ControllerName, key, err))
 		c.queue.AddRateLimited(i)
 		return true
@@ -242,6 +307,8 @@ func (c *controller) process(ctx context.Context, item queueItem) error {
 		err = c.reconcileOnLocation(ctx, key)
 	case triggeringKindSyncTarget:
 		err = c.reconcileOnSyncTarget(ctx, key)
+	case triggeringKindLabelExpiry:
+		err = c.expireLabels(ctx, key)
 	}
 	return err
 }
@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package where_resolver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/labelttl"
+)
+
+var labelsExpired = metrics.NewCounter(&metrics.CounterOpts{
+	Subsystem:      "kubestellar_where_resolver",
+	Name:           "location_labels_expired_total",
+	Help:           "Number of Location labels removed because their time to live passed",
+	StabilityLevel: metrics.ALPHA,
+})
+
+func init() {
+	legacyregistry.MustRegister(labelsExpired)
+}
+
+// scheduleLabelExpiry queues the given provider's copy of a Location for
+// label expiry at the time its next label with a time to live expires,
+// if it has one; see package labelttl. Of two schedulings of the same
+// item the earlier wins, and a processing that comes too early just
+// schedules again.
+func (c *controller) scheduleLabelExpiry(obj any) {
+	loc, ok := obj.(*edgev2alpha1.Location)
+	if !ok {
+		return
+	}
+	logger := klog.FromContext(c.context)
+	now := time.Now()
+	expired, next, err := labelttl.Due(loc, now)
+	if err != nil {
+		logger.Error(err, "Ignoring label expiries of Location", "location", loc.Name)
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(loc)
+	if err != nil {
+		return
+	}
+	item := queueItem{triggeringKind: triggeringKindLabelExpiry, key: key}
+	switch {
+	case len(expired) > 0:
+		c.queue.Add(item)
+	case !next.IsZero():
+		logger.V(4).Info("Scheduling label expiry", "location", loc.Name, "at", next)
+		c.queue.AddAfter(item, next.Sub(now))
+	}
+}
+
+// expireLabels removes the expired labels of the consumer's Location
+// whose provider's copy has the given key, judging by the consumer's
+// object, which is what gets changed.
+func (c *controller) expireLabels(ctx context.Context, locKey string) error {
+	logger := klog.FromContext(ctx)
+	loc, err := c.locationLister.Get(locKey)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	_, originalName, kbSpaceID, err := kbuser.AnalyzeObjectID(loc)
+	if err != nil {
+		logger.Error(err, "Object does not appear to be a provider's copy of a consumer's object", "location", loc.Name)
+		return nil
+	}
+	spaceID := c.kbSpaceRelation.SpaceIDFromKubeBind(kbSpaceID)
+	if spaceID == "" {
+		return errors.New("failed to obtain space ID from kube-bind reference")
+	}
+	edgeClientset, err := c.edgeClientFor(spaceID)
+	if err != nil {
+		return err
+	}
+	locClient := edgeClientset.EdgeV2alpha1().Locations()
+	var removed []string
+	var next time.Time
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		original, err := locClient.Get(ctx, originalName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		var expired []string
+		removed = nil
+		expired, next, err = labelttl.Due(original, time.Now())
+		if err != nil || len(expired) == 0 {
+			return err
+		}
+		original = original.DeepCopy()
+		labelttl.Remove(original, expired)
+		if _, err := locClient.Update(ctx, original, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		removed = expired
+		return nil
+	})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) > 0 {
+		labelsExpired.Add(float64(len(removed)))
+		logger.V(2).Info("Removed expired labels of Location", "space", spaceID, "location", originalName, "labels", removed)
+	}
+	if !next.IsZero() {
+		// The provider's copy may lag behind the consumer's object, so do
+		// not count on its next notification.
+		c.queue.AddAfter(queueItem{triggeringKind: triggeringKindLabelExpiry, key: locKey}, time.Until(next))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package where_resolver
+
+import (
+	"context"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+)
+
+var (
+	orphanedSlices = metrics.NewGauge(&metrics.GaugeOpts{
+		Subsystem:      "kubestellar_where_resolver",
+		Name:           "orphaned_slices",
+		Help:           "Number of SinglePlacementSlices found orphaned in the latest garbage collection pass",
+		StabilityLevel: metrics.ALPHA,
+	})
+	orphanedSliceDeletions = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_where_resolver",
+		Name:           "orphaned_slice_deletions_total",
+		Help:           "Number of orphaned SinglePlacementSlices deleted",
+		StabilityLevel: metrics.ALPHA,
+	})
+	orphanedSliceDeletionFailures = metrics.NewCounter(&metrics.CounterOpts{
+		Subsystem:      "kubestellar_where_resolver",
+		Name:           "orphaned_slice_deletion_failures_total",
+		Help:           "Number of failed attempts to delete an orphaned SinglePlacementSlice",
+		StabilityLevel: metrics.ALPHA,
+	})
+)
+
+func init() {
+	legacyregistry.MustRegister(orphanedSlices, orphanedSliceDeletions, orphanedSliceDeletionFailures)
+}
+
+// orphanCollector deletes SinglePlacementSlices whose EdgePlacement no longer exists.
+// Normally such a slice is deleted by garbage collection of owned objects,
+// but that is missing in some spaces and does not cover a delete that was
+// missed while nothing was watching or an EdgePlacement that moved between spaces.
+//
+// A slice is only deleted after it has been seen orphaned in two consecutive
+// passes, and after the absence of its EdgePlacement has been confirmed in the
+// consumer's space. The generic ownership.Collector is not used because it
+// acts in one pass and on the evidence of a single lookup.
+type orphanCollector struct {
+	c *controller
+
+	// clientFor returns the client for the consumer's space with the given ID
+	clientFor func(spaceID string) (edgeclientset.Interface, error)
+
+	// suspects maps the name of each slice found orphaned in the previous pass to its UID
+	suspects map[string]types.UID
+}
+
+// RunOrphanGC periodically deletes orphaned SinglePlacementSlices, until the
+// controller's context is done. With dryRun, orphans are only logged and counted.
+func (c *controller) RunOrphanGC(period time.Duration, dryRun bool) {
+	c.orphanGCDryRun.Store(dryRun)
+	oc := &orphanCollector{c: c, clientFor: c.edgeClientFor, suspects: map[string]types.UID{}}
+	ctx := klog.NewContext(c.context, klog.FromContext(c.context).WithValues("actor", "orphan-gc"))
+	wait.UntilWithContext(ctx, oc.collect, period)
+}
+
+// SetOrphanGCDryRun changes whether the orphan GC only logs and counts
+// orphans. It may be called at any time and takes effect on the next pass.
+func (c *controller) SetOrphanGCDryRun(dryRun bool) {
+	c.orphanGCDryRun.Store(dryRun)
+}
+
+func (oc *orphanCollector) collect(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	slices, err := oc.c.singlePlacementSliceLister.List(labels.Everything())
+	if err != nil {
+		logger.Error(err, "Failed to list SinglePlacementSlices in local cache")
+		return
+	}
+	nextSuspects := map[string]types.UID{}
+	var orphans int
+	for _, sps := range slices {
+		_, spsName, kbSpaceID, err := kbuser.AnalyzeObjectID(sps)
+		if err != nil {
+			continue // not a provider's copy, so not ours to judge
+		}
+		epName := owningEdgePlacementName(sps, spsName)
+		_, err = oc.c.edgePlacementLister.Get(kbuser.ComposeClusterScopedName(kbSpaceID, epName))
+		if err == nil || !k8serrors.IsNotFound(err) {
+			continue
+		}
+		orphans++
+		if prevUID, suspected := oc.suspects[sps.Name]; !suspected || prevUID != sps.UID {
+			logger.V(3).Info("SinglePlacementSlice appears orphaned, will check again", "singlePlacementSlice", sps.Name, "edgePlacement", epName)
+			nextSuspects[sps.Name] = sps.UID
+			continue
+		}
+		if !oc.deleteOrphan(ctx, logger.WithValues("singlePlacementSlice", spsName, "edgePlacement", epName, "kbSpaceID", kbSpaceID), kbSpaceID, spsName, epName) {
+			nextSuspects[sps.Name] = sps.UID
+		}
+	}
+	oc.suspects = nextSuspects
+	orphanedSlices.Set(float64(orphans))
+}
+
+// owningEdgePlacementName returns the name, in the consumer's space, of the
+// EdgePlacement that the given slice belongs to. The virtual owner is
+// preferred because the provider's copy need not keep ownerReferences.
+func owningEdgePlacementName(sps *edgev2alpha1.SinglePlacementSlice, spsName string) string {
+	if owner, err := ownership.GetOwner(sps); err == nil && owner != nil && owner.Resource == "edgeplacements" {
+		return owner.Name
+	}
+	for _, owner := range sps.OwnerReferences {
+		if owner.Kind == "EdgePlacement" && owner.APIVersion == edgev2alpha1.SchemeGroupVersion.String() {
+			return owner.Name
+		}
+	}
+	return spsName
+}
+
+// deleteOrphan deletes the named slice from the consumer's space if its
+// EdgePlacement is confirmed absent there.
+// Returns whether the slice is done with, rather than still suspect.
+func (oc *orphanCollector) deleteOrphan(ctx context.Context, logger klog.Logger, kbSpaceID, spsName, epName string) bool {
+	spaceID := oc.c.kbSpaceRelation.SpaceIDFromKubeBind(kbSpaceID)
+	if spaceID == "" {
+		logger.V(2).Info("Can not yet map kube-bind ID to space ID")
+		return false
+	}
+	edgeClientset, err := oc.clientFor(spaceID)
+	if err != nil {
+		logger.Error(err, "Failed to get edge clientset for space", "spaceID", spaceID)
+		return false
+	}
+	_, err = edgeClientset.EdgeV2alpha1().EdgePlacements().Get(ctx, epName, metav1.GetOptions{})
+	if err == nil {
+		logger.V(2).Info("EdgePlacement still exists in consumer's space, slice is not orphaned")
+		return true
+	} else if !k8serrors.IsNotFound(err) {
+		logger.Error(err, "Failed to check for EdgePlacement in consumer's space")
+		return false
+	}
+	sps, err := edgeClientset.EdgeV2alpha1().SinglePlacementSlices().Get(ctx, spsName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			logger.V(3).Info("Orphaned SinglePlacementSlice is already gone from consumer's space")
+			return true
+		}
+		logger.Error(err, "Failed to get orphaned SinglePlacementSlice from consumer's space")
+		return false
+	}
+	if oc.c.orphanGCDryRun.Load() {
+		logger.Info("Would delete orphaned SinglePlacementSlice", "uid", sps.UID)
+		return false
+	}
+	err = edgeClientset.EdgeV2alpha1().SinglePlacementSlices().Delete(ctx, spsName,
+		metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &sps.UID}})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		orphanedSliceDeletionFailures.Inc()
+		logger.Error(err, "Failed to delete orphaned SinglePlacementSlice")
+		return false
+	}
+	orphanedSliceDeletions.Inc()
+	logger.Info("Deleted orphaned SinglePlacementSlice", "uid", sps.UID)
+	return true
+}
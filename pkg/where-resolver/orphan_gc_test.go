@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package where_resolver
+
+import (
+	"context"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	fakeedge "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/fake"
+	edgev2alpha1listers "github.com/kubestellar/kubestellar/pkg/client/listers/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+)
+
+type fixedSpaceRelation map[string]string
+
+func (rel fixedSpaceRelation) SpaceIDToKubeBind(spaceID string) string {
+	for kbSpaceID, candidate := range rel {
+		if candidate == spaceID {
+			return kbSpaceID
+		}
+	}
+	return ""
+}
+
+func (rel fixedSpaceRelation) SpaceIDFromKubeBind(kbSpaceID string) string {
+	return rel[kbSpaceID]
+}
+
+// newOrphanTest returns an orphanCollector whose provider's space holds a
+// copy of the consumer's slice "ep1" and, if epExists, of its EdgePlacement.
+func newOrphanTest(t *testing.T, epExists, dryRun bool) (*orphanCollector, edgeclientset.Interface) {
+	kbAnnotations := map[string]string{"kube-bind.io/cluster-namespace": "kb1"}
+	ep := &edgev2alpha1.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: "ep1", UID: "ep-uid"}}
+	consumerSPS := &edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: "ep1", UID: "sps-uid"}}
+	if err := ownership.SetOwner(consumerSPS, ownership.NewOwnerRef("space1", edgev2alpha1.SchemeGroupVersion.WithResource("edgeplacements"), ep)); err != nil {
+		t.Fatal(err)
+	}
+	providerSPS := consumerSPS.DeepCopy()
+	providerSPS.Name = "kb1-ep1"
+	providerSPS.UID = "copy-uid"
+	for key, val := range kbAnnotations {
+		providerSPS.Annotations[key] = val
+	}
+	slices := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	placements := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := slices.Add(providerSPS); err != nil {
+		t.Fatal(err)
+	}
+	consumerObjs := []runtime.Object{consumerSPS}
+	if epExists {
+		providerEP := ep.DeepCopy()
+		providerEP.Name = "kb1-ep1"
+		providerEP.Annotations = kbAnnotations
+		if err := placements.Add(providerEP); err != nil {
+			t.Fatal(err)
+		}
+		consumerObjs = append(consumerObjs, ep)
+	}
+	consumer := fakeedge.NewSimpleClientset(consumerObjs...)
+	c := &controller{
+		context:                    context.Background(),
+		kbSpaceRelation:            fixedSpaceRelation{"kb1": "space1"},
+		singlePlacementSliceLister: edgev2alpha1listers.NewSinglePlacementSliceLister(slices),
+		edgePlacementLister:        edgev2alpha1listers.NewEdgePlacementLister(placements),
+	}
+	c.orphanGCDryRun.Store(dryRun)
+	oc := &orphanCollector{
+		c: c,
+		clientFor: func(spaceID string) (edgeclientset.Interface, error) {
+			if spaceID != "space1" {
+				t.Errorf("Unexpected request for client of space %q", spaceID)
+			}
+			return consumer, nil
+		},
+		suspects: map[string]types.UID{},
+	}
+	return oc, consumer
+}
+
+func TestOrphanGC(t *testing.T) {
+	for _, testCase := range []struct {
+		name             string
+		epExists, dryRun bool
+		expectDeleted    bool
+	}{
+		{name: "orphan", expectDeleted: true},
+		{name: "orphan in dry run", dryRun: true},
+		{name: "owned", epExists: true},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx := context.Background()
+			oc, consumer := newOrphanTest(t, testCase.epExists, testCase.dryRun)
+			oc.collect(ctx)
+			if _, err := consumer.EdgeV2alpha1().SinglePlacementSlices().Get(ctx, "ep1", metav1.GetOptions{}); err != nil {
+				t.Fatalf("Slice deleted after one pass: %v", err)
+			}
+			oc.collect(ctx)
+			_, err := consumer.EdgeV2alpha1().SinglePlacementSlices().Get(ctx, "ep1", metav1.GetOptions{})
+			if deleted := k8serrors.IsNotFound(err); deleted != testCase.expectDeleted {
+				t.Errorf("Slice deleted=%v after two passes, expected %v (err=%v)", deleted, testCase.expectDeleted, err)
+			}
+		})
+	}
+}
+
+func TestOwningEdgePlacementName(t *testing.T) {
+	sps := &edgev2alpha1.SinglePlacementSlice{ObjectMeta: metav1.ObjectMeta{Name: "sps1"}}
+	if name := owningEdgePlacementName(sps, "sps1"); name != "sps1" {
+		t.Errorf("Expected the slice's own name without an owner, got %q", name)
+	}
+	sps.OwnerReferences = []metav1.OwnerReference{{APIVersion: edgev2alpha1.SchemeGroupVersion.String(), Kind: "EdgePlacement", Name: "ep-ref"}}
+	if name := owningEdgePlacementName(sps, "sps1"); name != "ep-ref" {
+		t.Errorf("Expected the name from the ownerReference, got %q", name)
+	}
+	ep := &edgev2alpha1.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: "ep-virtual"}}
+	if err := ownership.SetOwner(sps, ownership.NewOwnerRef("space1", edgev2alpha1.SchemeGroupVersion.WithResource("edgeplacements"), ep)); err != nil {
+		t.Fatal(err)
+	}
+	if name := owningEdgePlacementName(sps, "sps1"); name != "ep-virtual" {
+		t.Errorf("Expected the name from the virtual owner, got %q", name)
+	}
+}
@@ -20,15 +20,20 @@ import (
 	"context"
 	"errors"
 
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 
 	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
 	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/naming"
+	"github.com/kubestellar/kubestellar/pkg/ownership"
+	"github.com/kubestellar/kubestellar/pkg/spechash"
 )
 
 func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string) error {
@@ -52,6 +57,8 @@ func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string)
 
 		4) update apiserver
 
+		5) record in the consumer's EdgePlacement's status what was processed
+
 		Need data structure: none.
 	*/
 
@@ -77,13 +84,14 @@ func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string)
 		logger.Error(err, "failed to list Locations in all workspaces")
 		return err
 	}
-	locsFilteredByEp, err := filterLocsByEp(locsAll, ep)
-	if err != nil {
-		logger.Error(err, "failed to find Locations for EdgePlacement")
+	locsFilteredByEp, locErr := filterLocsByEp(locsAll, ep)
+	if locErr != nil {
+		logger.Error(locErr, "failed to find Locations for EdgePlacement")
 	}
 	locsSelecting := packLocKeys(locsFilteredByEp)
 
 	singles := []edgev2alpha1.SinglePlacement{}
+	explanations := []string{}
 	for _, loc := range locsFilteredByEp {
 		// 2)
 		allSts, err := c.synctargetLister.List(labels.Everything())
@@ -96,6 +104,8 @@ func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string)
 			logger.Error(err, "failed to find SyncTargets for Location", "location", loc.Name)
 			return err
 		}
+		stsSelecting, stsExplanations := filterStsByRequirements(stsSelecting, ep)
+		explanations = append(explanations, stsExplanations...)
 		singles = append(singles, c.makeSinglePlacementsForLoc(loc, stsSelecting)...)
 	}
 
@@ -126,11 +136,7 @@ func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string)
 		return relErr
 	}
 
-	spaceConfig, err := c.spaceClient.ConfigForSpace(spaceID, c.spaceProviderNs)
-	if err != nil {
-		return err
-	}
-	edgeClientset, err := edgeclientset.NewForConfig(spaceConfig)
+	edgeClientset, err := c.edgeClientFor(spaceID)
 	if err != nil {
 		return err
 	}
@@ -139,13 +145,22 @@ func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string)
 		logger.Error(err, "failed to get consumer's object", "edgePlacement", originalName)
 		return err
 	}
-	_, err = c.singlePlacementSliceLister.Get(epName)
+	if originalEP.ExternallyScheduled() {
+		logger.V(2).Info("Leaving destinations and status of EdgePlacement to its external scheduler", "scheduler", originalEP.SchedulerName())
+		return nil
+	}
+	if originalEP.Paused() {
+		logger.V(2).Info("Not changing destinations of paused EdgePlacement")
+		return updateStatus(ctx, edgeClientset, originalEP, len(locsFilteredByEp), locErr, explanations, spechash.Of(ep))
+	}
+	epOwner := ownership.NewOwnerRef(spaceID, edgev2alpha1.SchemeGroupVersion.WithResource("edgeplacements"), originalEP)
+	existingSPS, err := c.singlePlacementSliceLister.Get(epName)
 	if err != nil {
 		if k8serrors.IsNotFound(err) { // create
 			logger.V(1).Info("creating SinglePlacementSlice")
 			sps := &edgev2alpha1.SinglePlacementSlice{
 				ObjectMeta: metav1.ObjectMeta{
-					Name: originalName,
+					Name: naming.SinglePlacementSliceName(originalName),
 					OwnerReferences: []metav1.OwnerReference{
 						{
 							APIVersion: edgev2alpha1.SchemeGroupVersion.String(),
@@ -155,7 +170,10 @@ func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string)
 						},
 					},
 				},
-				Destinations: singles,
+				Destinations: sortedSinglePlacements(singles),
+			}
+			if err := ownership.SetOwner(sps, epOwner); err != nil {
+				return err
 			}
 			_, err = edgeClientset.EdgeV2alpha1().SinglePlacementSlices().Create(ctx, sps, metav1.CreateOptions{})
 			if err != nil {
@@ -165,6 +183,7 @@ func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string)
 				}
 			} else {
 				logger.V(1).Info("created SinglePlacementSlice")
+				c.events.PlacementScheduled(spaceID, originalEP, len(singles))
 			}
 		} else {
 			logger.Error(err, "failed getting SinglePlacementSlice")
@@ -176,7 +195,38 @@ func (c *controller) reconcileOnEdgePlacement(ctx context.Context, epKey string)
 			logger.Error(err, "failed updating SinglePlacementSlice")
 			return err
 		}
+		if !apiequality.Semantic.DeepEqual(existingSPS.Destinations, sortedSinglePlacements(singles)) {
+			c.events.PlacementScheduled(spaceID, originalEP, len(singles))
+		}
+		if owner, err := ownership.GetOwner(existingSPS); err != nil || owner == nil || *owner != epOwner {
+			if err := ensureSpsOwner(ctx, edgeClientset, naming.SinglePlacementSliceName(originalName), epOwner); err != nil {
+				logger.Error(err, "failed setting owner of SinglePlacementSlice")
+				return err
+			}
+		}
 	}
 
-	return nil
+	// 5)
+	return updateStatus(ctx, edgeClientset, originalEP, len(locsFilteredByEp), locErr, explanations, spechash.Of(ep))
+}
+
+// ensureSpsOwner makes the consumer's SinglePlacementSlice with the given
+// name a virtual dependent of the given EdgePlacement, if it is not already.
+// This covers slices created before virtual ownership and EdgePlacements
+// that were re-created.
+func ensureSpsOwner(ctx context.Context, edgeClientset edgeclientset.Interface, spsName string, epOwner ownership.OwnerRef) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		sps, err := edgeClientset.EdgeV2alpha1().SinglePlacementSlices().Get(ctx, spsName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if current, err := ownership.GetOwner(sps); err == nil && current != nil && *current == epOwner {
+			return nil
+		}
+		if err := ownership.SetOwner(sps, epOwner); err != nil {
+			return err
+		}
+		_, err = edgeClientset.EdgeV2alpha1().SinglePlacementSlices().Update(ctx, sps, metav1.UpdateOptions{})
+		return err
+	})
 }
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,8 +31,10 @@ import (
 	"k8s.io/klog/v2"
 
 	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
-	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+	"github.com/kubestellar/kubestellar/pkg/destination"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
+	"github.com/kubestellar/kubestellar/pkg/naming"
 )
 
 func (c *controller) reconcileOnLocation(ctx context.Context, locKey string) error {
@@ -147,7 +150,21 @@ func (c *controller) reconcileOnLocation(ctx context.Context, locKey string) err
 	}
 
 	// 4)
-	singles := c.makeSinglePlacementsForLoc(loc, stsFilteredByLoc)
+	// singlesForEp makes the SinglePlacements for the given EdgePlacement,
+	// honoring its requirements.
+	singlesForEp := func(epName string) ([]edgev2alpha1.SinglePlacement, error) {
+		epObj, err := c.edgePlacementLister.Get(epName)
+		if err != nil {
+			logger.Error(err, "failed to get EdgePlacement", "edgePlacement", epName)
+			return nil, err
+		}
+		if epObj.Spec.Requirements != nil {
+			// Let the EdgePlacement's reconciliation also update its RequirementsSatisfied condition
+			c.enqueueEdgePlacement(epObj, coalesce.Other)
+		}
+		stsForEp, _ := filterStsByRequirements(stsFilteredByLoc, epObj)
+		return c.makeSinglePlacementsForLoc(loc, stsForEp), nil
+	}
 
 	for ep := range epsSelectedLoc {
 		if _, ok := epsSelectingLoc[ep]; !ok {
@@ -203,6 +220,10 @@ func (c *controller) reconcileOnLocation(ctx context.Context, locKey string) err
 				logger.Error(err, "failed to get consumer space ID from a provider's copy", "singlePlacementSlice", name)
 				return err
 			}
+			singles, err := singlesForEp(name)
+			if err != nil {
+				return err
+			}
 			nextSPS := cleanSPSByLoc(currentSPS, locSpaceID, locOriginalName)
 			nextSPS = extendSPS(nextSPS, singles)
 			err = c.patchSpsDestinations(nextSPS.Destinations, spaceID, originalName)
@@ -238,6 +259,10 @@ func (c *controller) reconcileOnLocation(ctx context.Context, locKey string) err
 				return err
 			}
 
+			singles, err := singlesForEp(name)
+			if err != nil {
+				return err
+			}
 			nextSPS := cleanSPSByLoc(currentSPS, locSpaceID, locOriginalName)
 			nextSPS = extendSPS(nextSPS, singles)
 			err = c.patchSpsDestinations(nextSPS.Destinations, spaceID, originalName)
@@ -374,18 +399,39 @@ func (c *controller) getConsumerSpaceForSPS(sps *edgev2alpha1.SinglePlacementSli
 	return name, spaceID, nil
 }
 
-func (c *controller) patchSpsDestinations(destinations []edgev2alpha1.SinglePlacement, spaceID string, spsName string) error {
-	destBytes, err := json.Marshal(destinations)
+// sortedSinglePlacements returns a sorted copy of the given destinations,
+// so that an unchanged set of destinations is always written the same way.
+func sortedSinglePlacements(destinations []edgev2alpha1.SinglePlacement) []edgev2alpha1.SinglePlacement {
+	ans := make([]edgev2alpha1.SinglePlacement, len(destinations))
+	copy(ans, destinations)
+	sort.Slice(ans, func(i, j int) bool {
+		a, b := ans[i], ans[j]
+		if destA, destB := destination.Of(a), destination.Of(b); !destA.Equal(destB) {
+			return destA.Less(destB)
+		}
+		if a.LocationName != b.LocationName {
+			return a.LocationName < b.LocationName
+		}
+		return a.SyncTargetUID < b.SyncTargetUID
+	})
+	return ans
+}
+
+// patchSpsDestinations sets the destinations in the SinglePlacementSlice for the named EdgePlacement.
+// Nothing is done if the EdgePlacement is paused or externally scheduled.
+func (c *controller) patchSpsDestinations(destinations []edgev2alpha1.SinglePlacement, spaceID string, epName string) error {
+	if why := c.placementHeld(spaceID, epName); why != "" {
+		klog.FromContext(c.context).V(2).Info("Not changing destinations of EdgePlacement", "space", spaceID, "edgePlacement", epName, "reason", why)
+		return nil
+	}
+	spsName := naming.SinglePlacementSliceName(epName)
+	destBytes, err := json.Marshal(sortedSinglePlacements(destinations))
 	if err != nil {
 		return err
 	}
 	patch := []byte(fmt.Sprintf(`{"destinations": %s}`, destBytes))
 
-	spaceConfig, err := c.spaceClient.ConfigForSpace(spaceID, c.spaceProviderNs)
-	if err != nil {
-		return err
-	}
-	edgeClientset, err := edgeclientset.NewForConfig(spaceConfig)
+	edgeClientset, err := c.edgeClientFor(spaceID)
 	if err != nil {
 		return err
 	}
@@ -395,3 +441,25 @@ func (c *controller) patchSpsDestinations(destinations []edgev2alpha1.SinglePlac
 	}
 	return nil
 }
+
+// placementHeld tells why the where-resolver must not change the
+// destinations of the consumer's EdgePlacement with the given name in the
+// given space, judging by the provider's copy: "paused", "externally
+// scheduled", or "" if it may.
+func (c *controller) placementHeld(spaceID string, epName string) string {
+	kbSpaceID := c.kbSpaceRelation.SpaceIDToKubeBind(spaceID)
+	if kbSpaceID == "" {
+		return ""
+	}
+	ep, err := c.edgePlacementLister.Get(kbuser.ComposeClusterScopedName(kbSpaceID, epName))
+	if err != nil {
+		return ""
+	}
+	switch {
+	case ep.Paused():
+		return "paused"
+	case ep.ExternallyScheduled():
+		return "externally scheduled"
+	}
+	return ""
+}
@@ -27,6 +27,8 @@ import (
 	"k8s.io/klog/v2"
 
 	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	"github.com/kubestellar/kubestellar/pkg/coalesce"
+	"github.com/kubestellar/kubestellar/pkg/destination"
 	"github.com/kubestellar/kubestellar/pkg/kbuser"
 )
 
@@ -182,6 +184,14 @@ func (c *controller) reconcileOnSyncTarget(ctx context.Context, stKey string) er
 				logger.Error(err, "failed to find Locations selected by EdgePlacement", "edgePlacement", epObj.Name)
 				return err
 			}
+			if epObj.Spec.Requirements != nil {
+				// Let the EdgePlacement's reconciliation also update its RequirementsSatisfied condition
+				c.enqueueEdgePlacement(epObj, coalesce.Other)
+				if reasons := checkRequirements(epObj.Spec.Requirements, st); len(reasons) > 0 {
+					logger.V(1).Info("SyncTarget fails requirements of EdgePlacement", "edgePlacement", ep, "reasons", reasons)
+					locsFilteredByStAndEp = nil
+				}
+			}
 			additionalSingles := c.makeSinglePlacementsForSt(locsFilteredByStAndEp, st)
 			nextSPS = extendSPS(nextSPS, additionalSingles)
 
@@ -228,6 +238,14 @@ func (c *controller) reconcileOnSyncTarget(ctx context.Context, stKey string) er
 				logger.Error(err, "failed to find Locations selected by EdgePlacement", "edgePlacement", epObj.Name)
 				return err
 			}
+			if epObj.Spec.Requirements != nil {
+				// Let the EdgePlacement's reconciliation also update its RequirementsSatisfied condition
+				c.enqueueEdgePlacement(epObj, coalesce.Other)
+				if reasons := checkRequirements(epObj.Spec.Requirements, st); len(reasons) > 0 {
+					logger.V(1).Info("SyncTarget fails requirements of EdgePlacement", "edgePlacement", ep, "reasons", reasons)
+					locsFilteredByStAndEp = nil
+				}
+			}
 			additionalSingles := c.makeSinglePlacementsForSt(locsFilteredByStAndEp, st)
 			nextSPS = extendSPS(nextSPS, additionalSingles)
 
@@ -324,9 +342,10 @@ func packLocKeys(locs []*edgev2alpha1.Location) map[string]empty {
 
 // cleanSPSBySt removes all singleplacements that has the specified synctarget, from a singleplacementslice
 func cleanSPSBySt(sps *edgev2alpha1.SinglePlacementSlice, stSpaceID, stName string) *edgev2alpha1.SinglePlacementSlice {
+	removed := destination.New(stSpaceID, stName)
 	nextDests := []edgev2alpha1.SinglePlacement{}
 	for _, sp := range sps.Destinations {
-		if sp.Cluster != stSpaceID || sp.SyncTargetName != stName {
+		if !removed.Is(sp) {
 			nextDests = append(nextDests, sp)
 		}
 	}
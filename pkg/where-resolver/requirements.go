@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package where_resolver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog/v2"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgeclientset "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+	"github.com/kubestellar/kubestellar/pkg/kbuser"
+)
+
+// maxExplainedExclusions limits how many excluded SyncTargets are
+// explained in the RequirementsSatisfied condition.
+const maxExplainedExclusions = 5
+
+// checkRequirements returns the reasons why the given SyncTarget fails
+// the given requirements; empty means it satisfies them. A fact that the
+// SyncTarget does not report is unknown, and does not fail a requirement.
+func checkRequirements(reqs *edgev2alpha1.PlacementRequirements, st *edgev2alpha1.SyncTarget) []string {
+	if reqs == nil {
+		return nil
+	}
+	var reasons []string
+	if reqs.KubernetesVersion != "" && st.Status.KubernetesVersion != "" {
+		if ok, err := versionSatisfies(st.Status.KubernetesVersion, reqs.KubernetesVersion); err != nil {
+			reasons = append(reasons, err.Error())
+		} else if !ok {
+			reasons = append(reasons, fmt.Sprintf("Kubernetes version %s is not in %q", st.Status.KubernetesVersion, reqs.KubernetesVersion))
+		}
+	}
+	if len(reqs.Architectures) > 0 && len(st.Status.Architectures) > 0 && !anyInCommon(reqs.Architectures, st.Status.Architectures) {
+		reasons = append(reasons, fmt.Sprintf("has no nodes of architecture %v", reqs.Architectures))
+	}
+	for _, crdName := range reqs.RequiredCRDs {
+		if len(st.Status.CRDs) > 0 && !stringsContain(st.Status.CRDs, crdName) {
+			reasons = append(reasons, fmt.Sprintf("lacks CRD %s", crdName))
+		}
+	}
+	available := st.Status.Allocatable
+	if available == nil {
+		available = st.Status.Capacity
+	}
+	if len(reqs.MinimumCapacity) > 0 && available != nil {
+		resourceNames := make([]string, 0, len(reqs.MinimumCapacity))
+		for name := range reqs.MinimumCapacity {
+			resourceNames = append(resourceNames, string(name))
+		}
+		sort.Strings(resourceNames)
+		for _, name := range resourceNames {
+			minimum := reqs.MinimumCapacity[corev1.ResourceName(name)]
+			have, found := (*available)[corev1.ResourceName(name)]
+			if !found || have.Cmp(minimum) < 0 {
+				reasons = append(reasons, fmt.Sprintf("has %s %s, less than %s", name, have.String(), minimum.String()))
+			}
+		}
+	}
+	return reasons
+}
+
+// versionSatisfies tests the given version against a comma-separated list of constraints.
+func versionSatisfies(versionStr, constraints string) (bool, error) {
+	have, err := version.ParseGeneric(versionStr)
+	if err != nil {
+		return false, fmt.Errorf("reports unparseable Kubernetes version %q", versionStr)
+	}
+	for _, constraint := range strings.Split(constraints, ",") {
+		constraint = strings.TrimSpace(constraint)
+		if constraint == "" {
+			continue
+		}
+		op := strings.TrimRight(constraint, "v0123456789.")
+		bound, err := version.ParseGeneric(strings.TrimSpace(strings.TrimPrefix(constraint, op)))
+		if err != nil {
+			return false, fmt.Errorf("malformed version constraint %q", constraint)
+		}
+		var ok bool
+		switch strings.TrimSpace(op) {
+		case "=", "==", "":
+			ok = have.AtLeast(bound) && !have.GreaterThan(bound)
+		case "!=":
+			ok = !(have.AtLeast(bound) && !have.GreaterThan(bound))
+		case "<":
+			ok = have.LessThan(bound)
+		case "<=":
+			ok = !have.GreaterThan(bound)
+		case ">":
+			ok = have.GreaterThan(bound)
+		case ">=":
+			ok = have.AtLeast(bound)
+		default:
+			return false, fmt.Errorf("malformed version constraint %q", constraint)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func anyInCommon(a, b []string) bool {
+	for _, x := range a {
+		if stringsContain(b, x) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringsContain(slice []string, seek string) bool {
+	for _, elt := range slice {
+		if elt == seek {
+			return true
+		}
+	}
+	return false
+}
+
+// filterStsByRequirements returns those SyncTargets that satisfy the EdgePlacement's
+// requirements, and explanations for the ones that do not.
+func filterStsByRequirements(sts []*edgev2alpha1.SyncTarget, ep *edgev2alpha1.EdgePlacement) ([]*edgev2alpha1.SyncTarget, []string) {
+	if ep.Spec.Requirements == nil {
+		return sts, nil
+	}
+	filtered := []*edgev2alpha1.SyncTarget{}
+	explanations := []string{}
+	for _, st := range sts {
+		reasons := checkRequirements(ep.Spec.Requirements, st)
+		if len(reasons) == 0 {
+			filtered = append(filtered, st)
+			continue
+		}
+		_, stOriginalName, _, err := kbuser.AnalyzeObjectID(st)
+		if err != nil { // not a provider's copy; see Resolve
+			stOriginalName = st.Name
+		}
+		explanations = append(explanations, fmt.Sprintf("SyncTarget %s %s", stOriginalName, strings.Join(reasons, "; ")))
+	}
+	return filtered, explanations
+}
+
+// updateStatus sets the LocationsResolved condition on the consumer's
+// EdgePlacement according to the outcome of the Location selection, sets
+// the RequirementsSatisfied condition, if it has requirements, according to
+// the given explanations, and records the hash of the spec that was processed.
+func updateStatus(ctx context.Context, edgeClientset edgeclientset.Interface, originalEP *edgev2alpha1.EdgePlacement,
+	locCount int, locErr error, explanations []string, processedSpecHash string) error {
+	logger := klog.FromContext(ctx)
+	ep := originalEP.DeepCopy()
+	changed := ep.Status.ProcessedSpecHash != processedSpecHash
+	ep.Status.ProcessedSpecHash = processedSpecHash
+	locCond := metav1.Condition{
+		Type:               edgev2alpha1.EdgePlacementLocationsResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		Message:            fmt.Sprintf("%d Location(s) selected", locCount),
+		ObservedGeneration: ep.Generation,
+	}
+	if locErr != nil {
+		locCond.Status = metav1.ConditionFalse
+		locCond.Reason = "InvalidSelector"
+		locCond.Message = locErr.Error()
+	}
+	changed = conditions.Set(&ep.Status.Conditions, locCond, metav1.Now()) || changed
+	if ep.Spec.Requirements == nil {
+		changed = conditions.Remove(&ep.Status.Conditions, edgev2alpha1.EdgePlacementRequirementsSatisfied) || changed
+	} else {
+		cond := metav1.Condition{
+			Type:               edgev2alpha1.EdgePlacementRequirementsSatisfied,
+			Status:             metav1.ConditionTrue,
+			Reason:             "AllSatisfied",
+			ObservedGeneration: ep.Generation,
+		}
+		if len(explanations) > 0 {
+			sort.Strings(explanations)
+			shown := explanations
+			if len(shown) > maxExplainedExclusions {
+				shown = shown[:maxExplainedExclusions]
+			}
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = "SyncTargetsExcluded"
+			cond.Message = fmt.Sprintf("%d SyncTarget(s) excluded: %s", len(explanations), strings.Join(shown, ", "))
+		}
+		changed = conditions.Set(&ep.Status.Conditions, cond, metav1.Now()) || changed
+	}
+	if !changed {
+		return nil
+	}
+	_, err := edgeClientset.EdgeV2alpha1().EdgePlacements().UpdateStatus(ctx, ep, metav1.UpdateOptions{})
+	if err != nil {
+		logger.Error(err, "failed to update status", "edgePlacement", ep.Name)
+		return err
+	}
+	logger.V(2).Info("updated status", "edgePlacement", ep.Name, "excluded", len(explanations), "processedSpecHash", processedSpecHash)
+	return nil
+}
+
+// capabilitiesEqual compares the parts of SyncTarget status that requirements are checked against.
+func capabilitiesEqual(a, b *edgev2alpha1.SyncTargetStatus) bool {
+	return a.KubernetesVersion == b.KubernetesVersion &&
+		apiequality.Semantic.DeepEqual(a.Architectures, b.Architectures) &&
+		apiequality.Semantic.DeepEqual(a.CRDs, b.CRDs) &&
+		apiequality.Semantic.DeepEqual(a.Allocatable, b.Allocatable) &&
+		apiequality.Semantic.DeepEqual(a.Capacity, b.Capacity)
+}
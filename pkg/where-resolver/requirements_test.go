@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package where_resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+	edgefake "github.com/kubestellar/kubestellar/pkg/client/clientset/versioned/fake"
+	"github.com/kubestellar/kubestellar/pkg/conditions"
+)
+
+func TestVersionSatisfies(t *testing.T) {
+	for _, testCase := range []struct {
+		version, constraints string
+		expected             bool
+		expectErr            bool
+	}{
+		{"v1.27.3", ">=1.25, <1.29", true, false},
+		{"v1.29.0", ">=1.25, <1.29", false, false},
+		{"1.24.9", ">=1.25", false, false},
+		{"v1.26.0", "=1.26.0", true, false},
+		{"v1.26.0", "!=1.26.0", false, false},
+		{"v1.26.0", "<=1.26", true, false},
+		{"v1.26.0", "~1.26", false, true},
+		{"garbage", ">=1.25", false, true},
+	} {
+		actual, err := versionSatisfies(testCase.version, testCase.constraints)
+		if (err != nil) != testCase.expectErr {
+			t.Errorf("versionSatisfies(%q, %q) returned error %v", testCase.version, testCase.constraints, err)
+		} else if actual != testCase.expected {
+			t.Errorf("versionSatisfies(%q, %q) = %v", testCase.version, testCase.constraints, actual)
+		}
+	}
+}
+
+func TestCheckRequirements(t *testing.T) {
+	allocatable := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")}
+	st := &edgev2alpha1.SyncTarget{Status: edgev2alpha1.SyncTargetStatus{
+		KubernetesVersion: "v1.27.3",
+		Architectures:     []string{"arm64"},
+		CRDs:              []string{"widgets.example.com"},
+		Allocatable:       &allocatable,
+	}}
+	for _, testCase := range []struct {
+		name     string
+		reqs     *edgev2alpha1.PlacementRequirements
+		failures int
+	}{
+		{"none", nil, 0},
+		{"satisfied", &edgev2alpha1.PlacementRequirements{
+			KubernetesVersion: ">=1.25",
+			Architectures:     []string{"amd64", "arm64"},
+			RequiredCRDs:      []string{"widgets.example.com"},
+			MinimumCapacity:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+		}, 0},
+		{"violated", &edgev2alpha1.PlacementRequirements{
+			KubernetesVersion: ">=1.28",
+			Architectures:     []string{"amd64"},
+			RequiredCRDs:      []string{"gadgets.example.com"},
+			MinimumCapacity: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("8"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		}, 5},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			reasons := checkRequirements(testCase.reqs, st)
+			if len(reasons) != testCase.failures {
+				t.Errorf("Expected %d failures, got %v", testCase.failures, reasons)
+			}
+		})
+	}
+
+	// A SyncTarget that reports no facts is not excluded by requirements on them.
+	unknown := &edgev2alpha1.SyncTarget{}
+	reqs := &edgev2alpha1.PlacementRequirements{
+		KubernetesVersion: ">=1.28",
+		Architectures:     []string{"amd64"},
+		RequiredCRDs:      []string{"gadgets.example.com"},
+		MinimumCapacity:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")},
+	}
+	if reasons := checkRequirements(reqs, unknown); len(reasons) != 0 {
+		t.Errorf("Expected unknown facts not to fail, got %v", reasons)
+	}
+}
+
+func TestUpdateStatusLocationsResolved(t *testing.T) {
+	ctx := context.Background()
+	ep := &edgev2alpha1.EdgePlacement{ObjectMeta: metav1.ObjectMeta{Name: "ep1", Generation: 3}}
+	client := edgefake.NewSimpleClientset(ep)
+	if err := updateStatus(ctx, client, ep, 2, nil, nil, "hash1"); err != nil {
+		t.Fatalf("updateStatus failed: %v", err)
+	}
+	got, err := client.EdgeV2alpha1().EdgePlacements().Get(ctx, "ep1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond := conditions.Find(got.Status.Conditions, edgev2alpha1.EdgePlacementLocationsResolved)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.ObservedGeneration != 3 {
+		t.Errorf("expected LocationsResolved=True for generation 3, got %+v", cond)
+	}
+	if conditions.Find(got.Status.Conditions, edgev2alpha1.EdgePlacementRequirementsSatisfied) != nil {
+		t.Error("expected no RequirementsSatisfied condition without requirements")
+	}
+
+	if err := updateStatus(ctx, client, got, 0, errors.New("bad selector"), nil, "hash2"); err != nil {
+		t.Fatalf("updateStatus failed: %v", err)
+	}
+	got, err = client.EdgeV2alpha1().EdgePlacements().Get(ctx, "ep1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cond = conditions.Find(got.Status.Conditions, edgev2alpha1.EdgePlacementLocationsResolved)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Message != "bad selector" {
+		t.Errorf("expected LocationsResolved=False explaining the error, got %+v", cond)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package where_resolver
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	edgev2alpha1 "github.com/kubestellar/kubestellar/pkg/apis/edge/v2alpha1"
+)
+
+// Resolve computes the destinations of the given EdgePlacement among the
+// given Locations and SyncTargets the way that the controller does, but
+// without touching any apiserver. The objects are as their consumer sees
+// them (not kube-bind provider copies) and all in one inventory space,
+// whose ID is left for the caller to fill into the Cluster of each
+// returned SinglePlacement. Also returned are the explanations of why
+// the SyncTargets of selected Locations that fail the requirements do so.
+func Resolve(ep *edgev2alpha1.EdgePlacement, locs []*edgev2alpha1.Location, sts []*edgev2alpha1.SyncTarget) ([]edgev2alpha1.SinglePlacement, []string, error) {
+	locsSelected, err := filterLocsByEp(locs, ep)
+	if err != nil {
+		return nil, nil, err
+	}
+	singles := []edgev2alpha1.SinglePlacement{}
+	explanations := []string{}
+	for _, loc := range locsSelected {
+		selector, err := metav1.LabelSelectorAsSelector(loc.Spec.InstanceSelector)
+		if err != nil {
+			return nil, nil, err
+		}
+		stsSelected := []*edgev2alpha1.SyncTarget{}
+		for _, st := range sts {
+			if selector.Matches(labels.Set(st.Labels)) {
+				stsSelected = append(stsSelected, st)
+			}
+		}
+		stsSelected, stsExplanations := filterStsByRequirements(stsSelected, ep)
+		explanations = append(explanations, stsExplanations...)
+		for _, st := range stsSelected {
+			singles = append(singles, edgev2alpha1.SinglePlacement{
+				LocationName:   loc.Name,
+				SyncTargetName: st.Name,
+				SyncTargetUID:  st.UID,
+			})
+		}
+	}
+	return sortedSinglePlacements(singles), explanations, nil
+}
@@ -24,9 +24,10 @@ import (
 type SpaceProviderType string
 
 const (
-	KindProviderType     SpaceProviderType = "kind"
-	KubeflexProviderType SpaceProviderType = "kubeflex"
-	KcpProviderType      SpaceProviderType = "kcp"
+	KindProviderType      SpaceProviderType = "kind"
+	KubeflexProviderType  SpaceProviderType = "kubeflex"
+	KcpProviderType       SpaceProviderType = "kcp"
+	NamespaceProviderType SpaceProviderType = "namespace"
 )
 
 // SpaceProviderDesc represents a provider.
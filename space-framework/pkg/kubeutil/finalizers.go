@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kubeutil holds small helpers for working with Kubernetes API objects
+// that are shared by the controllers of KubeStellar.
+// It lives in the space-framework module so that both that module and the
+// main kubestellar module can use it.
+package kubeutil
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// HasFinalizer tells whether the given object's finalizers include the given one.
+func HasFinalizer(obj metav1.Object, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFinalizer adds the given finalizer to the given object in memory,
+// if it is not already there.
+// Returns whether the object was changed.
+func AddFinalizer(obj metav1.Object, finalizer string) bool {
+	if HasFinalizer(obj, finalizer) {
+		return false
+	}
+	obj.SetFinalizers(append(obj.GetFinalizers(), finalizer))
+	return true
+}
+
+// RemoveFinalizer removes every occurrence of the given finalizer from the
+// given object in memory.
+// Returns whether the object was changed.
+func RemoveFinalizer(obj metav1.Object, finalizer string) bool {
+	oldList := obj.GetFinalizers()
+	newList := make([]string, 0, len(oldList))
+	for _, f := range oldList {
+		if f != finalizer {
+			newList = append(newList, f)
+		}
+	}
+	if len(newList) == len(oldList) {
+		return false
+	}
+	obj.SetFinalizers(newList)
+	return true
+}
+
+// ObjectClient is the subset of a generated typed client (for one
+// resource and, if namespaced, one namespace) that is needed here.
+// Use UnstructuredClient to get one from a dynamic client.
+type ObjectClient[Obj metav1.Object] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (Obj, error)
+	Update(ctx context.Context, obj Obj, opts metav1.UpdateOptions) (Obj, error)
+}
+
+// UnstructuredClient adapts a dynamic client (for one resource and, if
+// namespaced, one namespace) to ObjectClient; the dynamic client's
+// Get and Update take variadic subresources, so it does not satisfy
+// ObjectClient directly.
+func UnstructuredClient(client dynamic.ResourceInterface) ObjectClient[*unstructured.Unstructured] {
+	return unstructuredClient{client}
+}
+
+type unstructuredClient struct {
+	client dynamic.ResourceInterface
+}
+
+func (uc unstructuredClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+	return uc.client.Get(ctx, name, opts)
+}
+
+func (uc unstructuredClient) Update(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return uc.client.Update(ctx, obj, opts)
+}
+
+// UpdateWithRetry reads the named object from the server, applies the
+// given mutation to it, and writes it back if the mutation reports a
+// change. Conflicts are handled by re-reading the object and trying
+// again, so the mutation must be safe to apply more than once.
+// Returns the latest known state of the object.
+func UpdateWithRetry[Obj metav1.Object](ctx context.Context, client ObjectClient[Obj], name string, mutate func(Obj) bool) (Obj, error) {
+	var ans Obj
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		ans = obj
+		if !mutate(obj) {
+			return nil
+		}
+		ans, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	})
+	return ans, err
+}
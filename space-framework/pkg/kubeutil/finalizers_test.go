@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeutil
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// conflictingClient holds one ConfigMap and fails the first Update with a conflict.
+type conflictingClient struct {
+	stored    *corev1.ConfigMap
+	gets      int
+	conflicts int
+}
+
+func (cc *conflictingClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.ConfigMap, error) {
+	cc.gets++
+	return cc.stored.DeepCopy(), nil
+}
+
+func (cc *conflictingClient) Update(ctx context.Context, obj *corev1.ConfigMap, opts metav1.UpdateOptions) (*corev1.ConfigMap, error) {
+	if cc.conflicts > 0 {
+		cc.conflicts--
+		return nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, obj.Name, nil)
+	}
+	cc.stored = obj.DeepCopy()
+	return obj, nil
+}
+
+func TestUpdateWithRetry(t *testing.T) {
+	client := &conflictingClient{stored: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm1"}}, conflicts: 1}
+	addFinalizer := func(obj *corev1.ConfigMap) bool { return AddFinalizer(obj, "example.com/f") }
+	got, err := UpdateWithRetry[*corev1.ConfigMap](context.Background(), client, "cm1", addFinalizer)
+	if err != nil {
+		t.Fatalf("UpdateWithRetry failed: %v", err)
+	}
+	if client.gets != 2 || !HasFinalizer(got, "example.com/f") || !HasFinalizer(client.stored, "example.com/f") {
+		t.Errorf("expected the finalizer to be added after one retry, got %d reads and %v", client.gets, client.stored.Finalizers)
+	}
+	got, err = UpdateWithRetry[*corev1.ConfigMap](context.Background(), client, "cm1", addFinalizer)
+	if err != nil || len(got.Finalizers) != 1 {
+		t.Errorf("expected no change from adding the finalizer again, got %v, %v", got.Finalizers, err)
+	}
+	if _, err := UpdateWithRetry[*corev1.ConfigMap](context.Background(), client, "cm1", func(obj *corev1.ConfigMap) bool { return RemoveFinalizer(obj, "example.com/f") }); err != nil {
+		t.Fatalf("UpdateWithRetry failed: %v", err)
+	}
+	if len(client.stored.Finalizers) != 0 {
+		t.Errorf("expected the finalizer to be removed, got %v", client.stored.Finalizers)
+	}
+}
+
+func TestUpdateWithRetryUnstructured(t *testing.T) {
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetNamespace("ns1")
+	cm.SetName("cm1")
+	cm.SetFinalizers([]string{"example.com/other"})
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	dClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "ConfigMapList"}, cm)
+	client := UnstructuredClient(dClient.Resource(gvr).Namespace("ns1"))
+	addFinalizer := func(obj *unstructured.Unstructured) bool { return AddFinalizer(obj, "example.com/f") }
+	if _, err := UpdateWithRetry[*unstructured.Unstructured](context.Background(), client, "cm1", addFinalizer); err != nil {
+		t.Fatalf("UpdateWithRetry failed: %v", err)
+	}
+	stored, err := dClient.Resource(gvr).Namespace("ns1").Get(context.Background(), "cm1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stored.GetFinalizers(); len(got) != 2 || !HasFinalizer(stored, "example.com/f") {
+		t.Errorf("expected the finalizer to be added next to the other one, got %v", got)
+	}
+	removeFinalizer := func(obj *unstructured.Unstructured) bool { return RemoveFinalizer(obj, "example.com/f") }
+	got, err := UpdateWithRetry[*unstructured.Unstructured](context.Background(), client, "cm1", removeFinalizer)
+	if err != nil {
+		t.Fatalf("UpdateWithRetry failed: %v", err)
+	}
+	if fins := got.GetFinalizers(); len(fins) != 1 || fins[0] != "example.com/other" {
+		t.Errorf("expected only the other finalizer to remain, got %v", fins)
+	}
+}
@@ -33,10 +33,12 @@ import (
 	"k8s.io/klog/v2"
 
 	spacev1alpha1apis "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+	"github.com/kubestellar/kubestellar/space-framework/pkg/kubeutil"
 	spaceprovider "github.com/kubestellar/kubestellar/space-framework/pkg/space-manager/providerclient"
 	providerkcp "github.com/kubestellar/kubestellar/space-framework/space-provider/kcp"
 	kindprovider "github.com/kubestellar/kubestellar/space-framework/space-provider/kind"
 	kflexprovider "github.com/kubestellar/kubestellar/space-framework/space-provider/kubeflex"
+	nsprovider "github.com/kubestellar/kubestellar/space-framework/space-provider/namespace"
 )
 
 // Each provider gets its own namespace named prefixNamespace+providerName
@@ -75,6 +77,8 @@ func newProviderClient(pType spacev1alpha1apis.SpaceProviderType, configStrs map
 		pClient, err = kflexprovider.New(configStrs)
 	case spacev1alpha1apis.KcpProviderType:
 		pClient, err = providerkcp.New(configStrs)
+	case spacev1alpha1apis.NamespaceProviderType:
+		pClient, err = nsprovider.New(configStrs)
 	default:
 		return nil
 	}
@@ -210,6 +214,7 @@ func (p *provider) processProviderWatchEvents() {
 				runtime.HandleError(errors.New("unexpected object type. expected SpaceProviderDesc"))
 				continue
 			}
+			refspace = refspace.DeepCopy() // do not modify the informer's cache
 		}
 
 		if !found || (found && refspace.Spec.Type != spacev1alpha1apis.SpaceTypeManaged) {
@@ -245,14 +250,17 @@ func (p *provider) processProviderWatchEvents() {
 					logger.Error(err, "Failed to create secrests for space "+p.name)
 					continue
 				}
-				if refspace.Spec.Type == spacev1alpha1apis.SpaceTypeManaged && !containsFinalizer(refspace, finalizerName) {
-					// When a physical space is removed we remove its finalizer
-					// from the space object. when the space returns, we
-					// need to restore the finalizer.
-					refspace.SetFinalizers(append(refspace.GetFinalizers(), finalizerName))
-				}
-				refspace.Status.Phase = spacev1alpha1apis.SpacePhaseReady
-				_, err = p.c.clientset.SpaceV1alpha1().Spaces(p.nameSpace).Update(ctx, refspace, metav1.UpdateOptions{})
+				_, err = kubeutil.UpdateWithRetry[*spacev1alpha1apis.Space](ctx, p.c.clientset.SpaceV1alpha1().Spaces(p.nameSpace), refspace.Name,
+					func(space *spacev1alpha1apis.Space) bool {
+						changed := false
+						if space.Spec.Type == spacev1alpha1apis.SpaceTypeManaged {
+							// When a physical space is removed we remove its finalizer
+							// from the space object. when the space returns, we
+							// need to restore the finalizer.
+							changed = kubeutil.AddFinalizer(space, finalizerName)
+						}
+						return setSpacePhase(space, spacev1alpha1apis.SpacePhaseReady) || changed
+					})
 				chkErrAndReturn(logger, err, "Detected New space. Couldn't update the corresponding Space status", "space name", spaceName)
 			}
 
@@ -264,18 +272,16 @@ func (p *provider) processProviderWatchEvents() {
 			}
 			if refspace.Spec.Type == spacev1alpha1apis.SpaceTypeManaged {
 				_ = p.deleteSpaceSecrets(refspace)
-				// If managed then we need to remove the finalizer.
-				finalizersList := refspace.GetFinalizers()
-				for i := 0; i < len(finalizersList); i++ {
-					if finalizersList[i] == finalizerName {
-						refspace.SetFinalizers(append(finalizersList[:i], finalizersList[i+1:]...))
-						i--
-					}
-				}
 			}
-			// If managed then we need to remove the finalizer.
-			refspace.Status.Phase = spacev1alpha1apis.SpacePhaseNotReady
-			_, err := p.c.clientset.SpaceV1alpha1().Spaces(p.nameSpace).Update(ctx, refspace, metav1.UpdateOptions{})
+			_, err := kubeutil.UpdateWithRetry[*spacev1alpha1apis.Space](ctx, p.c.clientset.SpaceV1alpha1().Spaces(p.nameSpace), refspace.Name,
+				func(space *spacev1alpha1apis.Space) bool {
+					changed := false
+					if space.Spec.Type == spacev1alpha1apis.SpaceTypeManaged {
+						// If managed then we need to remove the finalizer.
+						changed = kubeutil.RemoveFinalizer(space, finalizerName)
+					}
+					return setSpacePhase(space, spacev1alpha1apis.SpacePhaseNotReady) || changed
+				})
 			chkErrAndReturn(logger, err, "Space was removed, Couldn't update the Space status")
 
 		default:
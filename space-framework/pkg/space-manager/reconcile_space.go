@@ -22,6 +22,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+	"github.com/kubestellar/kubestellar/space-framework/pkg/kubeutil"
 	pclient "github.com/kubestellar/kubestellar/space-framework/pkg/space-manager/providerclient"
 )
 
@@ -31,17 +32,6 @@ const finalizerName = "SpaceFinalizer"
 // SpacePathAnnotationKey is the path under which space will reside(for providers that support hierarchy)
 const SpacePathAnnotationKey = "kubestellar.io/space-path"
 
-// containsFinalizer: returns true if the finalizer list contains the space finalizer
-func containsFinalizer(space *spacev1alpha1.Space, finalizer string) bool {
-	finalizersList := space.GetFinalizers()
-	for _, f := range finalizersList {
-		if f == finalizer {
-			return true
-		}
-	}
-	return false
-}
-
 func (c *controller) reconcileSpace(key string) error {
 	spaceObj, exists, err := c.spaceInformer.GetIndexer().GetByKey(key)
 	if err != nil {
@@ -123,18 +113,16 @@ func (c *controller) processAddOrUpdateSpace(space *spacev1alpha1.Space) error {
 			}
 
 			// Update status Initializing
-			if !containsFinalizer(space, finalizerName) {
-				space.ObjectMeta.Finalizers = append(space.ObjectMeta.Finalizers, finalizerName)
-			}
-			space.Status.Phase = spacev1alpha1.SpacePhaseInitializing
-			_, err = c.clientset.
-				SpaceV1alpha1().
-				Spaces(ProviderNS(providerInfo.Name)).
-				Update(c.ctx, space, v1.UpdateOptions{})
+			updated, err := kubeutil.UpdateWithRetry[*spacev1alpha1.Space](c.ctx, c.clientset.SpaceV1alpha1().Spaces(ProviderNS(providerInfo.Name)), space.Name,
+				func(space *spacev1alpha1.Space) bool {
+					changed := kubeutil.AddFinalizer(space, finalizerName)
+					return setSpacePhase(space, spacev1alpha1.SpacePhaseInitializing) || changed
+				})
 			if err != nil {
 				c.logger.V(2).Error(err, "processAddOrUpdateSpace", "name", space.Name)
 				return err
 			}
+			space = updated
 
 			opts := pclient.Options{}
 			path, ok := space.Annotations[SpacePathAnnotationKey]
@@ -146,11 +134,10 @@ func (c *controller) processAddOrUpdateSpace(space *spacev1alpha1.Space) error {
 			go providerClient.Create(space.Name, opts)
 		}
 	case spacev1alpha1.SpaceTypeImported:
-		space.Status.Phase = spacev1alpha1.SpacePhaseReady
-		_, err := c.clientset.
-			SpaceV1alpha1().
-			Spaces(space.Namespace).
-			Update(c.ctx, space, v1.UpdateOptions{})
+		_, err := kubeutil.UpdateWithRetry[*spacev1alpha1.Space](c.ctx, c.clientset.SpaceV1alpha1().Spaces(space.Namespace), space.Name,
+			func(space *spacev1alpha1.Space) bool {
+				return setSpacePhase(space, spacev1alpha1.SpacePhaseReady)
+			})
 		if err != nil {
 			c.logger.V(2).Error(err, "processAddOrUpdateSpace", "name", space.Name)
 			return err
@@ -171,6 +158,16 @@ func (c *controller) processAddOrUpdateSpace(space *spacev1alpha1.Space) error {
 	return nil
 }
 
+// setSpacePhase sets the phase of the given Space in memory and
+// returns whether that changed it.
+func setSpacePhase(space *spacev1alpha1.Space, phase spacev1alpha1.SpacePhaseType) bool {
+	if space.Status.Phase == phase {
+		return false
+	}
+	space.Status.Phase = phase
+	return true
+}
+
 // processDeleteSpace: process a space object delete event.
 // If the space is managed, then async delete the physical space.
 // For imported or unmanaged spaces, we don't delete the pSpace.
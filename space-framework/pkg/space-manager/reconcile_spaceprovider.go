@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/runtime"
 
 	spacev1alpha1 "github.com/kubestellar/kubestellar/space-framework/pkg/apis/space/v1alpha1"
+	"github.com/kubestellar/kubestellar/space-framework/pkg/kubeutil"
 )
 
 func (c *controller) reconcileSpaceProviderDesc(key string) error {
@@ -115,8 +116,10 @@ func (c *controller) handleDelete(providerName string) error {
 		space := spaceObj.(*spacev1alpha1.Space)
 		if space.Namespace == ns {
 			if space.Spec.Type == spacev1alpha1.SpaceTypeManaged {
-				space.Status.Phase = spacev1alpha1.SpacePhaseNotReady
-				_, err := c.clientset.SpaceV1alpha1().Spaces(ns).Update(c.ctx, space, metav1.UpdateOptions{})
+				_, err := kubeutil.UpdateWithRetry[*spacev1alpha1.Space](c.ctx, c.clientset.SpaceV1alpha1().Spaces(ns), space.Name,
+					func(space *spacev1alpha1.Space) bool {
+						return setSpacePhase(space, spacev1alpha1.SpacePhaseNotReady)
+					})
 				if err != nil {
 					runtime.HandleError(err)
 				}
@@ -142,10 +145,12 @@ func (c *controller) handleDelete(providerName string) error {
 }
 
 func (c *controller) setProviderStatus(provider *spacev1alpha1.SpaceProviderDesc, status spacev1alpha1.SpaceProviderDescPhaseType) (*spacev1alpha1.SpaceProviderDesc, error) {
-	provider.Status.Phase = status
-	updated, err := c.clientset.SpaceV1alpha1().SpaceProviderDescs().Update(c.ctx, provider, metav1.UpdateOptions{})
-	if err != nil {
-		return updated, err
-	}
-	return updated, nil
+	return kubeutil.UpdateWithRetry[*spacev1alpha1.SpaceProviderDesc](c.ctx, c.clientset.SpaceV1alpha1().SpaceProviderDescs(), provider.Name,
+		func(provider *spacev1alpha1.SpaceProviderDesc) bool {
+			if provider.Status.Phase == status {
+				return false
+			}
+			provider.Status.Phase = status
+			return true
+		})
 }
@@ -19,11 +19,13 @@ package kflexprovider
 import (
 	"context"
 	"encoding/base64"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -41,15 +43,34 @@ const (
 	CPVersion  = "v1alpha1"
 	CPKind     = "ControlPlane"
 	CPResource = "controlplanes"
+
+	// CP_TYPE_KEY is the optional key, in the provider's Secret, of the
+	// type of the ControlPlanes to create: CPTypeK8s (the default) or CPTypeVCluster.
+	CP_TYPE_KEY = "cptype"
+	// CP_BACKEND_KEY is the optional key, in the provider's Secret, of the
+	// backend of the ControlPlanes to create: "shared" (the default) or "dedicated".
+	CP_BACKEND_KEY = "backend"
+
+	CPTypeK8s      = "k8s"
+	CPTypeVCluster = "vcluster"
+	DefaultBackend = "shared"
 )
 
+var cpGVR = schema.GroupVersionResource{
+	Group:    CPGroup,
+	Version:  CPVersion,
+	Resource: CPResource,
+}
+
 // KflexClusterProvider is a kubeflex cluster provider
 type KflexClusterProvider struct {
 	logger     logr.Logger
 	ctx        context.Context
 	pConfig    string
 	dClient    dynamic.Interface
-	kubeClient *kubernetes.Clientset
+	kubeClient kubernetes.Interface
+	cpType     string
+	backend    string
 	watch      clusterprovider.Watcher
 }
 
@@ -78,23 +99,35 @@ func New(configStrs map[string]string) (KflexClusterProvider, error) {
 		return KflexClusterProvider{}, err
 	}
 
+	cpType := configStrs[CP_TYPE_KEY]
+	if cpType == "" {
+		cpType = CPTypeK8s
+	}
+	if cpType != CPTypeK8s && cpType != CPTypeVCluster {
+		err = fmt.Errorf("unsupported ControlPlane type %q", cpType)
+		logger.Error(err, "Bad provider config")
+		return KflexClusterProvider{}, err
+	}
+	backend := configStrs[CP_BACKEND_KEY]
+	if backend == "" {
+		backend = DefaultBackend
+	}
+
 	return KflexClusterProvider{
 		pConfig:    pConfig,
 		dClient:    dClient,
 		kubeClient: kubeClient,
+		cpType:     cpType,
+		backend:    backend,
 		logger:     logger,
 		ctx:        ctx,
 	}, nil
 }
 
+// Create creates the ControlPlane of the named space.
+// The space is not usable until the ControlPlane is Ready;
+// the Watcher reports it only then.
 func (k KflexClusterProvider) Create(name string, opts clusterprovider.Options) error {
-
-	cpGVR := schema.GroupVersionResource{
-		Group:    CPGroup,
-		Version:  CPVersion,
-		Resource: CPResource,
-	}
-
 	crUnstruct := &unstructured.Unstructured{Object: map[string]interface{}{
 		"apiVersion": CPGroup + "/" + CPVersion,
 		"kind":       CPKind,
@@ -102,14 +135,17 @@ func (k KflexClusterProvider) Create(name string, opts clusterprovider.Options)
 			"name": name,
 		},
 		"spec": map[string]interface{}{
-			"backend": "shared",
-			"type":    "k8s",
+			"backend": k.backend,
+			"type":    k.cpType,
 		},
 	}}
 
 	_, err := k.dClient.Resource(cpGVR).Create(k.ctx, crUnstruct, v1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		k.logger.V(2).Info("KubeFlex cluster already exists", "name", name)
+		return nil
+	}
 	if err != nil {
-		// TODO:  Need to differentiate between "already exists" and an error
 		k.logger.Error(err, "Failed to create cluster", "name", name)
 	}
 
@@ -120,15 +156,12 @@ func (k KflexClusterProvider) Delete(name string, opts clusterprovider.Options)
 	logger := klog.Background()
 	logger.V(2).Info("Deleting KubeFlex cluster", "name", name)
 
-	cpGVR := schema.GroupVersionResource{
-		Group:    CPGroup,
-		Version:  CPVersion,
-		Resource: CPResource,
-	}
-
 	err := k.dClient.Resource(cpGVR).Delete(k.ctx, name, v1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		logger.V(2).Info("KubeFlex cluster is already gone", "name", name)
+		return nil
+	}
 	if err != nil {
-		// TODO:  Need to differentiate between "already exists" and an error
 		k.logger.Error(err, "Failed to delete cluster", "name", name)
 	}
 
@@ -140,14 +173,9 @@ func (k KflexClusterProvider) Delete(name string, opts clusterprovider.Options)
 func (k KflexClusterProvider) ListSpacesNames() ([]string, error) {
 	var listClusterNames []string
 
-	listSpaces, err := k.dClient.Resource(schema.GroupVersionResource{
-		Group:    CPGroup,
-		Version:  CPVersion,
-		Resource: CPResource,
-	}).List(context.TODO(), v1.ListOptions{})
+	listSpaces, err := k.dClient.Resource(cpGVR).List(context.TODO(), v1.ListOptions{})
 
 	if err != nil {
-		// TODO:  Need to differentiate between "already exists" and an error
 		k.logger.Error(err, "Failed to list spaces")
 		return nil, err
 	}
@@ -171,17 +199,17 @@ func isSpaceReady(unspace unstructured.Unstructured) bool {
 
 	logger := klog.Background()
 
-	// Access the "status" field as a map.
+	// A ControlPlane that was just created has no status yet.
 	status, found, err := unstructured.NestedMap(unspace.Object, "status")
 	if err != nil || !found {
-		logger.Error(err, "can't find the status field in the KF ControlPlan object")
+		logger.V(4).Info("No status yet in the KF ControlPlane object", "name", unspace.GetName(), "err", err)
 		return false
 	}
 
 	// Access the "conditions" field as a slice of maps.
 	conditions, found, err := unstructured.NestedSlice(status, "conditions")
 	if err != nil || !found {
-		logger.Error(err, "can't find the conditions field in the KF ControlPlan status")
+		logger.V(4).Info("No conditions yet in the KF ControlPlane status", "name", unspace.GetName(), "err", err)
 		return false
 	}
 
@@ -203,28 +231,51 @@ func isSpaceReady(unspace unstructured.Unstructured) bool {
 	return false
 }
 
-// Get: obtains the kubeconfig for the given lcName cluster.
-// TODO: switch from cli to kube directives
+// Get: obtains the kubeconfigs for the given lcName cluster.
+// It fails if the ControlPlane is not Ready, so that a space is not
+// reported (and its Space object does not become Ready) before it can be used.
 func (k KflexClusterProvider) Get(lcName string) (clusterprovider.SpaceInfo, error) {
-
-	var externalConf, internalConf []byte
-	secret, err := k.kubeClient.CoreV1().Secrets(lcName+"-system").Get(k.ctx, "admin-kubeconfig", v1.GetOptions{})
+	cp, err := k.dClient.Resource(cpGVR).Get(k.ctx, lcName, v1.GetOptions{})
 	if err != nil {
 		return clusterprovider.SpaceInfo{}, err
 	}
-
-	externalConf, err = base64.StdEncoding.DecodeString(string(secret.Data["kubeconfig"]))
-	if err != nil {
-		//We assume this happen because the data was not encoded pring message and get the string
-		externalConf = secret.Data["kubeconfig"]
-		k.logger.Info("Provider secret was not encoded", "Secret", secret.Name)
+	if !isSpaceReady(*cp) {
+		return clusterprovider.SpaceInfo{}, fmt.Errorf("KubeFlex ControlPlane %s is not ready", lcName)
+	}
+	cpType, _, _ := unstructured.NestedString(cp.Object, "spec", "type")
+	if cpType == "" {
+		cpType = CPTypeK8s
 	}
 
-	internalConf, err = base64.StdEncoding.DecodeString(string(secret.Data["kubeconfig-incluster"]))
-	if err != nil {
-		//We assume this happen because the data was not encoded pring message and get the string
-		internalConf = secret.Data["kubeconfig-incluster"]
-		k.logger.Info("Provider secret was not encoded", "Secret", secret.Name)
+	var externalConf, internalConf []byte
+	switch cpType {
+	case CPTypeK8s:
+		secret, err := k.kubeClient.CoreV1().Secrets(lcName+"-system").Get(k.ctx, "admin-kubeconfig", v1.GetOptions{})
+		if err != nil {
+			return clusterprovider.SpaceInfo{}, err
+		}
+		externalConf = k.decode(secret.Name, secret.Data["kubeconfig"])
+		internalConf = k.decode(secret.Name, secret.Data["kubeconfig-incluster"])
+	case CPTypeVCluster:
+		// KubeFlex has the vcluster write a kubeconfig whose server is the
+		// external address of the ControlPlane; in-cluster clients use
+		// the vcluster Service instead.
+		secret, err := k.kubeClient.CoreV1().Secrets(lcName+"-system").Get(k.ctx, "vc-vcluster", v1.GetOptions{})
+		if err != nil {
+			return clusterprovider.SpaceInfo{}, err
+		}
+		externalConf = k.decode(secret.Name, secret.Data["config"])
+		if len(externalConf) != 0 {
+			internalConf, err = vclusterInClusterConfig(externalConf, lcName+"-system")
+			if err != nil {
+				return clusterprovider.SpaceInfo{}, err
+			}
+		}
+	default:
+		return clusterprovider.SpaceInfo{}, fmt.Errorf("KubeFlex ControlPlane %s has unsupported type %q", lcName, cpType)
+	}
+	if len(externalConf) == 0 || len(internalConf) == 0 {
+		return clusterprovider.SpaceInfo{}, fmt.Errorf("kubeconfig of KubeFlex ControlPlane %s is not available yet", lcName)
 	}
 
 	lcInfo := clusterprovider.SpaceInfo{
@@ -237,6 +288,29 @@ func (k KflexClusterProvider) Get(lcName string) (clusterprovider.SpaceInfo, err
 	return lcInfo, nil
 }
 
+func (k KflexClusterProvider) decode(secretName string, data []byte) []byte {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		//We assume this happen because the data was not encoded pring message and get the string
+		k.logger.V(4).Info("Provider secret was not encoded", "Secret", secretName)
+		return data
+	}
+	return decoded
+}
+
+// vclusterInClusterConfig returns a copy of the given vcluster kubeconfig
+// whose server is the vcluster Service in the given namespace.
+func vclusterInClusterConfig(kubeconfig []byte, namespace string) ([]byte, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range config.Clusters {
+		cluster.Server = "https://vcluster." + namespace + ".svc"
+	}
+	return clientcmd.Write(*config)
+}
+
 func (k KflexClusterProvider) ListSpaces() ([]clusterprovider.SpaceInfo, error) {
 	logger := klog.Background()
 	lcNames, _ := k.ListSpacesNames()
@@ -310,8 +384,6 @@ func (k *KflexWatcher) ResultChan() <-chan clusterprovider.WatchEvent {
 					for _, name := range newSetClusters.Difference(setClusters).UnsortedList() {
 						logger.V(2).Info("Processing KubeFlex cluster", "name", name)
 						spaceInfo, err := k.provider.Get(name)
-						logger.Error(err, "kflex Added")
-						logger.Error(err, name)
 						if err != nil {
 							logger.V(2).Info("KubeFlex cluster is not ready. Retrying", "cluster", name)
 							// Can't get the cluster info, so let's discover it again
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kflexprovider
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+
+	clusterprovider "github.com/kubestellar/kubestellar/space-framework/pkg/space-manager/providerclient"
+)
+
+func newControlPlane(name, cpType string, ready bool) *unstructured.Unstructured {
+	cp := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": CPGroup + "/" + CPVersion,
+		"kind":       CPKind,
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       map[string]interface{}{"type": cpType, "backend": DefaultBackend},
+	}}
+	if ready {
+		cp.Object["status"] = map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "reason": "Available", "status": "True"},
+		}}
+	}
+	return cp
+}
+
+func newKubeconfig(t *testing.T, server string) []byte {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["vcluster"] = &clientcmdapi.Cluster{Server: server}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: "token"}
+	config.Contexts["vcluster"] = &clientcmdapi.Context{Cluster: "vcluster", AuthInfo: "admin"}
+	config.CurrentContext = "vcluster"
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newTestProvider(kubeClient *fake.Clientset, cps ...runtime.Object) KflexClusterProvider {
+	dClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{cpGVR: CPKind + "List"}, cps...)
+	return KflexClusterProvider{
+		logger:     klog.Background(),
+		ctx:        context.Background(),
+		dClient:    dClient,
+		kubeClient: kubeClient,
+		cpType:     CPTypeVCluster,
+		backend:    DefaultBackend,
+	}
+}
+
+func serverOf(t *testing.T, kubeconfig string) string {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("kubeconfig does not parse: %v", err)
+	}
+	return config.Clusters[config.Contexts[config.CurrentContext].Cluster].Server
+}
+
+func TestGetVCluster(t *testing.T) {
+	external := "https://cp1.localtest.me:9443"
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "cp1-system", Name: "vc-vcluster"},
+		Data:       map[string][]byte{"config": newKubeconfig(t, external)},
+	})
+	provider := newTestProvider(kubeClient, newControlPlane("cp1", CPTypeVCluster, true))
+	info, err := provider.Get("cp1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if server := serverOf(t, info.Config[clusterprovider.EXTERNAL]); server != external {
+		t.Errorf("expected external kubeconfig to use %s, got %s", external, server)
+	}
+	if server := serverOf(t, info.Config[clusterprovider.INCLUSTER]); server != "https://vcluster.cp1-system.svc" {
+		t.Errorf("expected in-cluster kubeconfig to use the vcluster Service, got %s", server)
+	}
+}
+
+func TestGetNotReady(t *testing.T) {
+	provider := newTestProvider(fake.NewSimpleClientset(), newControlPlane("cp1", CPTypeVCluster, false))
+	if _, err := provider.Get("cp1"); err == nil {
+		t.Error("expected Get to fail for a ControlPlane that is not ready")
+	}
+	if names, err := provider.ListSpacesNames(); err != nil || len(names) != 0 {
+		t.Errorf("expected no ready spaces, got %v, %v", names, err)
+	}
+}
+
+func TestGetKubeconfigNotAvailable(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "cp1-system", Name: "vc-vcluster"},
+	})
+	provider := newTestProvider(kubeClient, newControlPlane("cp1", CPTypeVCluster, true))
+	if _, err := provider.Get("cp1"); err == nil {
+		t.Error("expected Get to fail while the vcluster kubeconfig is not written")
+	}
+}
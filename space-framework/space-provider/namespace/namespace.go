@@ -0,0 +1,349 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nsprovider is a space provider whose spaces are namespaces
+// of one plain Kubernetes cluster. It lets the mailbox and inventory
+// model run on a cluster that has neither kcp nor KubeFlex.
+//
+// Each space is a namespace plus a ServiceAccount that is bound, in that
+// namespace only, to a ClusterRole ("admin" by default). The kubeconfigs
+// of a space use that ServiceAccount's token and have the space's namespace
+// as their default.
+//
+// A namespace is not a complete pSpace: a space serves only namespaced
+// APIs. Because the binding is a RoleBinding, the ServiceAccount of a space
+// can reach neither the cluster-scoped objects nor the other spaces'
+// namespaces, whatever the ClusterRole. Cluster-scoped kinds, such as the
+// KubeStellar edge APIs, are not available in these spaces; the mailbox
+// controller refuses to use a provider of this type.
+package nsprovider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+
+	clusterprovider "github.com/kubestellar/kubestellar/space-framework/pkg/space-manager/providerclient"
+)
+
+const (
+	// CLUSTER_ROLE_KEY is the optional key, in the provider's Secret, of
+	// the name of the ClusterRole that a space's ServiceAccount gets
+	// in the space's namespace.
+	CLUSTER_ROLE_KEY = "clusterrole"
+
+	// SpaceLabelKey labels the namespace of a space with the space's name.
+	SpaceLabelKey = "space.kubestellar.io/space"
+
+	DefaultClusterRole = "admin"
+	NamespacePrefix    = "space-"
+	ServiceAccountName = "space-admin"
+	TokenSecretName    = "space-admin-token"
+	InClusterServer    = "https://kubernetes.default.svc"
+)
+
+// NamespaceClusterProvider is a namespace space provider
+type NamespaceClusterProvider struct {
+	logger      logr.Logger
+	ctx         context.Context
+	config      *rest.Config
+	kubeClient  kubernetes.Interface
+	clusterRole string
+	watch       clusterprovider.Watcher
+}
+
+// New creates a new NamespaceClusterProvider
+func New(configStrs map[string]string) (NamespaceClusterProvider, error) {
+	pConfig := configStrs[clusterprovider.PROVIDER_CONFIG_KEY]
+
+	ctx := context.Background()
+	logger := klog.FromContext(ctx)
+
+	config, err := clientcmd.RESTConfigFromKubeConfig([]byte(pConfig))
+	if err != nil {
+		logger.Error(err, "Error loading kubeconfig")
+		return NamespaceClusterProvider{}, err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		logger.Error(err, "Failed to create kube clientset")
+		return NamespaceClusterProvider{}, err
+	}
+
+	return newWithClient(ctx, config, kubeClient, configStrs[CLUSTER_ROLE_KEY]), nil
+}
+
+func newWithClient(ctx context.Context, config *rest.Config, kubeClient kubernetes.Interface, clusterRole string) NamespaceClusterProvider {
+	if clusterRole == "" {
+		clusterRole = DefaultClusterRole
+	}
+	return NamespaceClusterProvider{
+		config:      config,
+		kubeClient:  kubeClient,
+		clusterRole: clusterRole,
+		logger:      klog.FromContext(ctx),
+		ctx:         ctx,
+	}
+}
+
+// NamespaceName returns the name of the namespace of the named space.
+func NamespaceName(name string) string {
+	return NamespacePrefix + name
+}
+
+func (k NamespaceClusterProvider) Create(name string, opts clusterprovider.Options) error {
+	nsName := NamespaceName(name)
+	ns := &corev1.Namespace{ObjectMeta: v1.ObjectMeta{
+		Name:   nsName,
+		Labels: map[string]string{SpaceLabelKey: name},
+	}}
+	if _, err := k.kubeClient.CoreV1().Namespaces().Create(k.ctx, ns, v1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		k.logger.Error(err, "Failed to create namespace", "name", name, "namespace", nsName)
+		return err
+	}
+
+	sa := &corev1.ServiceAccount{ObjectMeta: v1.ObjectMeta{Name: ServiceAccountName}}
+	if _, err := k.kubeClient.CoreV1().ServiceAccounts(nsName).Create(k.ctx, sa, v1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		k.logger.Error(err, "Failed to create ServiceAccount", "name", name, "namespace", nsName)
+		return err
+	}
+
+	rb := &rbacv1.RoleBinding{
+		ObjectMeta: v1.ObjectMeta{Name: ServiceAccountName},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: k.clusterRole},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: ServiceAccountName, Namespace: nsName}},
+	}
+	if _, err := k.kubeClient.RbacV1().RoleBindings(nsName).Create(k.ctx, rb, v1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		k.logger.Error(err, "Failed to create RoleBinding", "name", name, "namespace", nsName)
+		return err
+	}
+
+	// Since Kubernetes 1.24 token Secrets are no longer made automatically
+	tokenSecret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        TokenSecretName,
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: ServiceAccountName},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	if _, err := k.kubeClient.CoreV1().Secrets(nsName).Create(k.ctx, tokenSecret, v1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		k.logger.Error(err, "Failed to create token Secret", "name", name, "namespace", nsName)
+		return err
+	}
+	return nil
+}
+
+func (k NamespaceClusterProvider) Delete(name string, opts clusterprovider.Options) error {
+	logger := klog.Background()
+	logger.V(2).Info("Deleting namespace space", "name", name)
+
+	err := k.kubeClient.CoreV1().Namespaces().Delete(k.ctx, NamespaceName(name), v1.DeleteOptions{})
+	if err != nil {
+		k.logger.Error(err, "Failed to delete namespace", "name", name)
+	}
+	return err
+}
+
+// ListSpacesNames: returns the names of the spaces whose namespace is
+// Active and whose ServiceAccount token has been issued.
+func (k NamespaceClusterProvider) ListSpacesNames() ([]string, error) {
+	var listSpaceNames []string
+
+	nsList, err := k.kubeClient.CoreV1().Namespaces().List(k.ctx, v1.ListOptions{LabelSelector: SpaceLabelKey})
+	if err != nil {
+		k.logger.Error(err, "Failed to list namespaces")
+		return nil, err
+	}
+
+	for _, ns := range nsList.Items {
+		name := ns.Labels[SpaceLabelKey]
+		if ns.Status.Phase != corev1.NamespaceActive || ns.Name != NamespaceName(name) {
+			continue
+		}
+		if _, err := k.tokenAndCA(ns.Name); err != nil {
+			continue
+		}
+		listSpaceNames = append(listSpaceNames, name)
+	}
+
+	return listSpaceNames, nil
+}
+
+// tokenAndCA returns the populated token Secret of the given namespace.
+func (k NamespaceClusterProvider) tokenAndCA(nsName string) (*corev1.Secret, error) {
+	secret, err := k.kubeClient.CoreV1().Secrets(nsName).Get(k.ctx, TokenSecretName, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(secret.Data[corev1.ServiceAccountTokenKey]) == 0 {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), TokenSecretName+"/"+corev1.ServiceAccountTokenKey)
+	}
+	return secret, nil
+}
+
+// Get: builds the kubeconfigs of the given space from its ServiceAccount token.
+func (k NamespaceClusterProvider) Get(name string) (clusterprovider.SpaceInfo, error) {
+	nsName := NamespaceName(name)
+	secret, err := k.tokenAndCA(nsName)
+	if err != nil {
+		return clusterprovider.SpaceInfo{}, err
+	}
+	token := string(secret.Data[corev1.ServiceAccountTokenKey])
+	caData := secret.Data[corev1.ServiceAccountRootCAKey]
+
+	externalConf, err := kubeconfigFor(name, k.config.Host, nsName, token, caData)
+	if err != nil {
+		return clusterprovider.SpaceInfo{}, err
+	}
+	internalConf, err := kubeconfigFor(name, InClusterServer, nsName, token, caData)
+	if err != nil {
+		return clusterprovider.SpaceInfo{}, err
+	}
+
+	return clusterprovider.SpaceInfo{
+		Name: name,
+		Config: map[string]string{
+			clusterprovider.EXTERNAL:  externalConf,
+			clusterprovider.INCLUSTER: internalConf,
+		},
+	}, nil
+}
+
+func kubeconfigFor(name, server, nsName, token string, caData []byte) (string, error) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: caData}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name, Namespace: nsName}
+	config.CurrentContext = name
+	bytes, err := clientcmd.Write(*config)
+	return string(bytes), err
+}
+
+func (k NamespaceClusterProvider) ListSpaces() ([]clusterprovider.SpaceInfo, error) {
+	logger := klog.Background()
+	names, _ := k.ListSpacesNames()
+
+	spInfoList := make([]clusterprovider.SpaceInfo, 0, len(names))
+
+	for _, name := range names {
+		spInfo, err := k.Get(name)
+		if err != nil {
+			logger.Error(err, "couldn't get space", "space", name)
+			continue
+		}
+		spInfoList = append(spInfoList, spInfo)
+	}
+
+	return spInfoList, nil
+}
+
+func (k NamespaceClusterProvider) Watch() (clusterprovider.Watcher, error) {
+	w := &NamespaceWatcher{
+		ch:       make(chan clusterprovider.WatchEvent),
+		provider: &k,
+		chClosed: false}
+	k.watch = w
+	return w, nil
+}
+
+type NamespaceWatcher struct {
+	init     sync.Once
+	wg       sync.WaitGroup
+	ch       chan clusterprovider.WatchEvent
+	cancel   context.CancelFunc
+	provider *NamespaceClusterProvider
+	chClosed bool
+}
+
+func (k *NamespaceWatcher) Stop() {
+	if k.cancel != nil {
+		k.cancel()
+	}
+	k.wg.Wait()
+	if !k.chClosed {
+		close(k.ch)
+		k.chClosed = true
+	}
+}
+
+func (k *NamespaceWatcher) ResultChan() <-chan clusterprovider.WatchEvent {
+	k.init.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		logger := klog.FromContext(ctx)
+		k.cancel = cancel
+		setSpaces := sets.NewString()
+
+		k.wg.Add(1)
+		go func() {
+			defer k.wg.Done()
+			for {
+				select {
+				// TODO replace the 2 with a param at the cluster-provider-client level
+				case <-time.After(2 * time.Second):
+					list, err := k.provider.ListSpacesNames()
+					if err != nil {
+						logger.Error(err, "Failed to list namespace spaces")
+						continue
+					}
+					newSetSpaces := sets.NewString(list...)
+					// Check for new spaces.
+					for _, name := range newSetSpaces.Difference(setSpaces).UnsortedList() {
+						logger.V(2).Info("Processing namespace space", "name", name)
+						spaceInfo, err := k.provider.Get(name)
+						if err != nil {
+							logger.V(2).Info("Namespace space is not ready. Retrying", "space", name)
+							// Can't get the space info, so let's discover it again
+							newSetSpaces.Delete(name)
+							continue
+						}
+						k.ch <- clusterprovider.WatchEvent{
+							Type:      clusterprovider.Added,
+							Name:      name,
+							SpaceInfo: spaceInfo,
+						}
+					}
+					// Check for deleted spaces.
+					for _, name := range setSpaces.Difference(newSetSpaces).UnsortedList() {
+						logger.V(2).Info("Processing namespace space delete", "name", name)
+						k.ch <- clusterprovider.WatchEvent{
+							Type: clusterprovider.Deleted,
+							Name: name,
+						}
+					}
+					setSpaces = newSetSpaces
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	})
+
+	return k.ch
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The KubeStellar Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nsprovider
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clusterprovider "github.com/kubestellar/kubestellar/space-framework/pkg/space-manager/providerclient"
+)
+
+func newTestProvider(t *testing.T) (NamespaceClusterProvider, *fake.Clientset) {
+	kubeClient := fake.NewSimpleClientset()
+	return newWithClient(context.Background(), &rest.Config{Host: "https://hosting.example.com"}, kubeClient, ""), kubeClient
+}
+
+// issueToken does what the token controller of a real cluster does.
+func issueToken(t *testing.T, kubeClient *fake.Clientset, name, token string) {
+	ctx := context.Background()
+	nsName := NamespaceName(name)
+	secret, err := kubeClient.CoreV1().Secrets(nsName).Get(ctx, TokenSecretName, v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("token Secret of space %s not created: %v", name, err)
+	}
+	secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte(token), corev1.ServiceAccountRootCAKey: []byte("ca")}
+	if _, err := kubeClient.CoreV1().Secrets(nsName).Update(ctx, secret, v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ns, err := kubeClient.CoreV1().Namespaces().Get(ctx, nsName, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns.Status.Phase = corev1.NamespaceActive
+	if _, err := kubeClient.CoreV1().Namespaces().Update(ctx, ns, v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateGetDelete(t *testing.T) {
+	ctx := context.Background()
+	provider, kubeClient := newTestProvider(t)
+	if err := provider.Create("s1", clusterprovider.Options{}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	// Creating again is not an error.
+	if err := provider.Create("s1", clusterprovider.Options{}); err != nil {
+		t.Fatalf("second Create failed: %v", err)
+	}
+	if _, err := provider.Get("s1"); err == nil {
+		t.Error("expected Get to fail before the token is issued")
+	}
+	if names, err := provider.ListSpacesNames(); err != nil || len(names) != 0 {
+		t.Errorf("expected no ready spaces before the token is issued, got %v, %v", names, err)
+	}
+	issueToken(t, kubeClient, "s1", "token1")
+
+	info, err := provider.Get("s1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	for _, key := range []string{clusterprovider.EXTERNAL, clusterprovider.INCLUSTER} {
+		config, err := clientcmd.Load([]byte(info.Config[key]))
+		if err != nil {
+			t.Fatalf("%s kubeconfig does not parse: %v", key, err)
+		}
+		kctx := config.Contexts[config.CurrentContext]
+		if kctx.Namespace != NamespaceName("s1") || config.AuthInfos[kctx.AuthInfo].Token != "token1" {
+			t.Errorf("unexpected %s kubeconfig %+v", key, kctx)
+		}
+	}
+	if names, err := provider.ListSpacesNames(); err != nil || len(names) != 1 || names[0] != "s1" {
+		t.Errorf("expected spaces [s1], got %v, %v", names, err)
+	}
+
+	if err := provider.Delete("s1", clusterprovider.Options{}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := kubeClient.CoreV1().Namespaces().Get(ctx, NamespaceName("s1"), v1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected namespace to be deleted, got %v", err)
+	}
+}
+
+func TestIsolation(t *testing.T) {
+	ctx := context.Background()
+	provider, kubeClient := newTestProvider(t)
+	for _, name := range []string{"s1", "s2"} {
+		if err := provider.Create(name, clusterprovider.Options{}); err != nil {
+			t.Fatalf("Create %s failed: %v", name, err)
+		}
+		issueToken(t, kubeClient, name, "token-"+name)
+	}
+	// Nothing cluster-wide is granted.
+	if crbs, err := kubeClient.RbacV1().ClusterRoleBindings().List(ctx, v1.ListOptions{}); err != nil || len(crbs.Items) != 0 {
+		t.Errorf("expected no ClusterRoleBindings, got %v, %v", crbs, err)
+	}
+	for _, name := range []string{"s1", "s2"} {
+		nsName := NamespaceName(name)
+		rbs, err := kubeClient.RbacV1().RoleBindings(nsName).List(ctx, v1.ListOptions{})
+		if err != nil || len(rbs.Items) != 1 {
+			t.Fatalf("expected one RoleBinding in %s, got %v, %v", nsName, rbs, err)
+		}
+		for _, subject := range rbs.Items[0].Subjects {
+			if subject.Namespace != nsName {
+				t.Errorf("RoleBinding in %s grants to a subject of another namespace: %+v", nsName, subject)
+			}
+		}
+		info, err := provider.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := clientcmd.Load([]byte(info.Config[clusterprovider.EXTERNAL]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token := config.AuthInfos[config.Contexts[config.CurrentContext].AuthInfo].Token; token != "token-"+name {
+			t.Errorf("space %s uses token %q", name, token)
+		}
+	}
+}